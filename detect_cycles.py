@@ -0,0 +1,26 @@
+#!/usr/bin/env python3
+"""
+Cycle Detection Command for Graph-Codebase-MCP
+
+This script reports import cycles between packages of the indexed knowledge graph,
+with the file-level imports behind every dependency of a cycle.
+The knowledge graph must be pre-populated by running src/main.py first.
+"""
+
+import os
+import sys
+from dotenv import load_dotenv
+
+# Add project root to Python path
+project_root = os.path.abspath(os.path.dirname(__file__))
+if project_root not in sys.path:
+    sys.path.insert(0, project_root)
+
+# Load environment variables
+load_dotenv()
+
+# Import and run the cycle detection command
+from src.analysis.cycles import main
+
+if __name__ == "__main__":
+    main()
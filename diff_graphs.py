@@ -0,0 +1,26 @@
+#!/usr/bin/env python3
+"""
+Graph Diff Command for Graph-Codebase-MCP
+
+This script compares two indexed versions (snapshots) of a codebase: package dependencies,
+symbols, edges and complexity added, removed or changed between them.
+Index both versions first, e.g. with `src/main.py --codebase-path . --ref main`.
+"""
+
+import os
+import sys
+from dotenv import load_dotenv
+
+# Add project root to Python path
+project_root = os.path.abspath(os.path.dirname(__file__))
+if project_root not in sys.path:
+    sys.path.insert(0, project_root)
+
+# Load environment variables
+load_dotenv()
+
+# Import and run the graph diff command
+from src.analysis.graph_diff import main
+
+if __name__ == "__main__":
+    main()
@@ -0,0 +1,26 @@
+#!/usr/bin/env python3
+"""
+Graph Export Command for Graph-Codebase-MCP
+
+This script dumps the indexed knowledge graph (or a scoped part of it) to GraphML or DOT
+for visualization in Gephi, yEd or Graphviz.
+The knowledge graph must be pre-populated by running src/main.py first.
+"""
+
+import os
+import sys
+from dotenv import load_dotenv
+
+# Add project root to Python path
+project_root = os.path.abspath(os.path.dirname(__file__))
+if project_root not in sys.path:
+    sys.path.insert(0, project_root)
+
+# Load environment variables
+load_dotenv()
+
+# Import and run the export command
+from src.export.graph_export import main
+
+if __name__ == "__main__":
+    main()
@@ -0,0 +1,26 @@
+#!/usr/bin/env python3
+"""
+Unreferenced Symbol Report for Graph-Codebase-MCP
+
+This script reports functions, methods and types of the indexed knowledge graph that nothing references,
+grouped by package with a confidence level.
+The knowledge graph must be pre-populated by running src/main.py first.
+"""
+
+import os
+import sys
+from dotenv import load_dotenv
+
+# Add project root to Python path
+project_root = os.path.abspath(os.path.dirname(__file__))
+if project_root not in sys.path:
+    sys.path.insert(0, project_root)
+
+# Load environment variables
+load_dotenv()
+
+# Import and run the unreferenced symbol report
+from src.analysis.unreferenced import main
+
+if __name__ == "__main__":
+    main()
@@ -0,0 +1,23 @@
+"""Whole-graph analyses (package dependency cycles, unreferenced symbols)."""
+
+from src.analysis.cycles import (
+    detect_cycles,
+    format_cycles_report,
+    strongly_connected_components,
+)
+from src.analysis.unreferenced import (
+    DEFAULT_SUPPRESSIONS,
+    find_unreferenced,
+    format_unreferenced_report,
+    load_config,
+)
+
+__all__ = [
+    'detect_cycles',
+    'format_cycles_report',
+    'strongly_connected_components',
+    'DEFAULT_SUPPRESSIONS',
+    'find_unreferenced',
+    'format_unreferenced_report',
+    'load_config',
+]
@@ -0,0 +1,333 @@
+"""
+Package dependency cycle detection.
+
+Every Package node and package DEPENDS_ON edge is pulled in two queries
+and the strongly connected components are computed in process with an
+iterative Tarjan pass, so the cost does not grow with one query per
+package. Each component with a cycle is reported with the shortest cycle
+through each of its packages, until every package is on a reported
+cycle. Each hop of a cycle lists the file-level IMPORTS edges behind it;
+the hop with the fewest imports is marked as the cheapest one to break.
+
+Usage:
+    python detect_cycles.py
+    python detect_cycles.py --scope internal/auth --min-length 3
+    python detect_cycles.py --storage memory --graph-file graph.json --format json
+"""
+
+import argparse
+import json
+import logging
+import os
+import sys
+from collections import deque
+from typing import Any, Dict, List, Optional, Set, Tuple
+
+sys.path.append(os.path.dirname(os.path.dirname(os.path.dirname(os.path.abspath(__file__)))))
+
+from src.export.graph_export import in_scope, scope_prefixes
+from src.graph_store import STORAGE_BACKENDS, InMemoryGraphStore, get_graph_file, get_storage_backend, same_repo_id
+
+logger = logging.getLogger(__name__)
+
+REPORT_FORMATS = ("text", "json")
+
+DEFAULT_MIN_LENGTH = 2
+
+# Cycles reported per component; a component of n packages needs at most n to cover it
+MAX_CYCLES_PER_COMPONENT = 20
+
+
+def strongly_connected_components(graph: Dict[str, List[str]]) -> List[List[str]]:
+    """
+    Strongly connected components of a directed graph (iterative Tarjan).
+    
+    Args:
+        graph: node -> successors; successors that are not keys are ignored
+    
+    Returns:
+        Components with sorted members, ordered by their first member
+    """
+    index: Dict[str, int] = {}
+    low: Dict[str, int] = {}
+    stack: List[str] = []
+    on_stack: Set[str] = set()
+    components: List[List[str]] = []
+    
+    def visit(node: str) -> None:
+        index[node] = low[node] = len(index)
+        stack.append(node)
+        on_stack.add(node)
+    
+    for root in sorted(graph):
+        if root in index:
+            continue
+        visit(root)
+        work = [(root, iter(graph[root]))]
+        while work:
+            node, successors = work[-1]
+            for successor in successors:
+                if successor not in graph:
+                    continue
+                if successor not in index:
+                    visit(successor)
+                    work.append((successor, iter(graph[successor])))
+                    break
+                if successor in on_stack:
+                    low[node] = min(low[node], index[successor])
+            else:
+                work.pop()
+                if work:
+                    parent = work[-1][0]
+                    low[parent] = min(low[parent], low[node])
+                if low[node] == index[node]:
+                    component = []
+                    while True:
+                        member = stack.pop()
+                        on_stack.discard(member)
+                        component.append(member)
+                        if member == node:
+                            break
+                    components.append(sorted(component))
+    
+    return sorted(components)
+
+
+def _shortest_cycle(graph: Dict[str, List[str]], members: Set[str], start: str) -> List[str]:
+    """Packages of the shortest cycle from start back to start inside one component (breadth-first)."""
+    parents: Dict[str, Optional[str]] = {start: None}
+    queue = deque([start])
+    while queue:
+        node = queue.popleft()
+        for successor in graph[node]:
+            if successor == start:
+                cycle = [node]
+                while parents[cycle[-1]] is not None:
+                    cycle.append(parents[cycle[-1]])
+                return cycle[::-1]
+            if successor in members and successor not in parents:
+                parents[successor] = node
+                queue.append(successor)
+    return []
+
+
+def _rotation_key(cycle: List[str]) -> Tuple[str, ...]:
+    """The same cycle found from different starting packages compares equal."""
+    start = cycle.index(min(cycle))
+    return tuple(cycle[start:] + cycle[:start])
+
+
+def package_label(properties: Dict[str, Any]) -> str:
+    """Display name of a package: its Go import path, else its directory."""
+    return properties.get("import_path") or properties.get("path") or properties["id"]
+
+
+def _in_package_scope(properties: Dict[str, Any], scope: str, prefixes: List[str]) -> bool:
+    """Whether the package directory is under the scope, or its Go import path starts with it."""
+    scope = scope.rstrip("/")
+    import_path = properties.get("import_path") or ""
+    return in_scope(properties.get("path"), prefixes) or import_path == scope or import_path.startswith(scope + "/")
+
+
+def _hop_imports(db, package_ids: List[str]) -> Dict[Tuple[str, str], List[Dict[str, Any]]]:
+    """(source package, target package) -> file-level imports, for the files of the given packages."""
+    package_of: Dict[str, str] = {}
+    files: Dict[str, Dict[str, Any]] = {}
+    for row in db.neighbors(package_ids, ["CONTAINS"], direction="out", label="File"):
+        file_id = row["node"]["properties"]["id"]
+        package_of[file_id] = row["origin_id"]
+        files[file_id] = row["node"]["properties"]
+    
+    imports: Dict[Tuple[str, str], List[Dict[str, Any]]] = {}
+    for row in db.neighbors(list(files), ["IMPORTS"], direction="out"):
+        target = row["node"]["properties"]
+        if "Package" in row["node"]["labels"]:
+            target_package = target["id"]
+        elif "File" in row["node"]["labels"]:
+            target_package = package_of.get(target["id"])
+        else:
+            target_package = package_of.get(same_repo_id(target["id"], f"file:{target.get('file_path')}"))
+        if target_package is None:
+            continue
+        edge = row["relationship"]["properties"]
+        imported = edge.get("import_path") or edge.get("module") or target.get("name")
+        if edge.get("symbol"):
+            imported = f"{imported}.{edge['symbol']}" if imported else edge["symbol"]
+        imports.setdefault((package_of[row["origin_id"]], target_package), []).append({
+            "file": files[row["origin_id"]].get("file_path"),
+            "line_no": edge.get("line_no"),
+            "imports": imported,
+            "target_id": target["id"],
+        })
+    for entries in imports.values():
+        entries.sort(key=lambda entry: (entry["file"] or "", entry["line_no"] or 0, entry["target_id"]))
+    return imports
+
+
+def detect_cycles(db, scope: Optional[str] = None, min_length: int = DEFAULT_MIN_LENGTH) -> Dict[str, Any]:
+    """
+    Find cycles in the package DEPENDS_ON graph.
+    
+    Args:
+        db: GraphStore backend
+        scope: Only report cycles through a package under this directory (or Go import path prefix)
+        min_length: Minimum number of packages in a reported cycle
+    
+    Returns:
+        {"status", "scope", "min_length", "package_count", "cycle_count", "components"}; every
+        component lists its packages and cycles, every cycle its packages in order and one hop per
+        DEPENDS_ON edge with the file imports behind it and a "break_candidate" flag
+    
+    Raises:
+        ValueError: for a min_length below 1
+    """
+    min_length = int(min_length)
+    if min_length < 1:
+        raise ValueError("min_length must be at least 1")
+    
+    packages = {record["properties"]["id"]: record["properties"] for record in db.find_nodes(label="Package")}
+    graph: Dict[str, List[str]] = {package_id: [] for package_id in packages}
+    for row in db.neighbors(list(packages), ["DEPENDS_ON"], direction="out", label="Package"):
+        graph[row["origin_id"]].append(row["node"]["properties"]["id"])
+    for successors in graph.values():
+        successors.sort()
+    
+    prefixes = scope_prefixes(scope) if scope else []
+    
+    def wanted(package_ids: List[str]) -> bool:
+        return not scope or any(_in_package_scope(packages[package_id], scope, prefixes) for package_id in package_ids)
+    
+    found: List[Tuple[List[str], List[List[str]], bool]] = []
+    for members in strongly_connected_components(graph):
+        if (len(members) == 1 and members[0] not in graph[members[0]]) or not wanted(members):
+            continue
+        member_set = set(members)
+        cycles: List[List[str]] = []
+        seen: Set[Tuple[str, ...]] = set()
+        covered: Set[str] = set()
+        truncated = False
+        for start in members:
+            if start in covered:
+                continue
+            cycle = _shortest_cycle(graph, member_set, start)
+            covered.update(cycle)
+            key = _rotation_key(cycle)
+            if len(cycle) < min_length or key in seen or not wanted(cycle):
+                continue
+            if len(cycles) == MAX_CYCLES_PER_COMPONENT:
+                truncated = True
+                break
+            seen.add(key)
+            cycles.append(list(key))
+        if cycles:
+            found.append((members, cycles, truncated))
+    
+    # File imports behind the hops, fetched once for every package on a reported cycle
+    on_cycles = sorted({package_id for _, cycles, _ in found for cycle in cycles for package_id in cycle})
+    hop_imports = _hop_imports(db, on_cycles) if on_cycles else {}
+    
+    components = []
+    for members, cycles, truncated in found:
+        component = {
+            "packages": [package_label(packages[package_id]) for package_id in members],
+            "cycles": [],
+        }
+        for cycle in cycles:
+            hops = []
+            for position, source in enumerate(cycle):
+                target = cycle[(position + 1) % len(cycle)]
+                hops.append({
+                    "from": package_label(packages[source]),
+                    "to": package_label(packages[target]),
+                    "imports": hop_imports.get((source, target), []),
+                })
+            cheapest = min(range(len(hops)), key=lambda position: len(hops[position]["imports"]))
+            for position, hop in enumerate(hops):
+                hop["break_candidate"] = position == cheapest
+            component["cycles"].append({
+                "packages": [package_label(packages[package_id]) for package_id in cycle],
+                "length": len(cycle),
+                "hops": hops,
+            })
+        if truncated:
+            component["truncated"] = True
+        components.append(component)
+    
+    return {
+        "status": "ok",
+        "scope": scope,
+        "min_length": min_length,
+        "package_count": len(packages),
+        "cycle_count": sum(len(component["cycles"]) for component in components),
+        "components": components,
+    }
+
+
+def _count(count: int, noun: str) -> str:
+    return f"{count} {noun}{'' if count == 1 else 's'}"
+
+
+def format_cycles_report(result: Dict[str, Any]) -> str:
+    """Human-readable report of a detect_cycles result."""
+    scope = f" under {result['scope']}" if result.get("scope") else ""
+    if not result["components"]:
+        return f"No package import cycles{scope} among {result['package_count']} packages.\n"
+    
+    lines = [
+        f"Found {_count(result['cycle_count'], 'package import cycle')}{scope} in "
+        f"{_count(len(result['components']), 'group')} of mutually dependent packages "
+        f"({result['package_count']} packages scanned).",
+    ]
+    for group, component in enumerate(result["components"], 1):
+        lines.append("")
+        lines.append(f"Group {group}: {', '.join(component['packages'])}")
+        for number, cycle in enumerate(component["cycles"], 1):
+            lines.append(f"  Cycle {number} (length {cycle['length']}): "
+                         f"{' -> '.join(cycle['packages'] + cycle['packages'][:1])}")
+            for hop in cycle["hops"]:
+                hint = "  <- fewest imports, break here" if hop["break_candidate"] else ""
+                lines.append(f"    {hop['from']} -> {hop['to']} ({_count(len(hop['imports']), 'import')}){hint}")
+                for entry in hop["imports"]:
+                    location = f"{entry['file']}:{entry['line_no']}" if entry.get("line_no") else entry["file"]
+                    lines.append(f"      {location} imports {entry['imports']}")
+        if component.get("truncated"):
+            lines.append(f"  ... more cycles not shown (limit {MAX_CYCLES_PER_COMPONENT})")
+    return "\n".join(lines) + "\n"
+
+
+def main():
+    """Cycle detection command entry point"""
+    parser = argparse.ArgumentParser(description="Detect cycles between packages of the code knowledge graph")
+    parser.add_argument("--scope", help="Only report cycles through a package under this directory, e.g. 'internal/auth'")
+    parser.add_argument("--min-length", type=int, default=DEFAULT_MIN_LENGTH,
+                        help=f"Minimum number of packages in a cycle (default: {DEFAULT_MIN_LENGTH})")
+    parser.add_argument("--format", choices=REPORT_FORMATS, default="text", help="Output format")
+    parser.add_argument("--storage", choices=STORAGE_BACKENDS, help="Storage backend (default: GRAPH_STORAGE or neo4j)")
+    parser.add_argument("--graph-file", help="JSON file of the memory backend (default: GRAPH_STORE_PATH)")
+    parser.add_argument("--neo4j-uri", help="Neo4j database URI")
+    parser.add_argument("--neo4j-user", help="Neo4j username")
+    parser.add_argument("--neo4j-password", help="Neo4j password")
+    
+    args = parser.parse_args()
+    
+    if get_storage_backend(args.storage) == "memory":
+        db = InMemoryGraphStore(get_graph_file(args.graph_file))
+    else:
+        from src.neo4j_storage.graph_db import Neo4jDatabase
+        
+        db = Neo4jDatabase(uri=args.neo4j_uri, user=args.neo4j_user, password=args.neo4j_password)
+    try:
+        result = detect_cycles(db, scope=args.scope, min_length=args.min_length)
+    except ValueError as e:
+        parser.error(str(e))
+    finally:
+        db.close()
+    
+    if args.format == "json":
+        sys.stdout.write(json.dumps(result, ensure_ascii=False, indent=2) + "\n")
+    else:
+        sys.stdout.write(format_cycles_report(result))
+
+
+if __name__ == "__main__":
+    main()
@@ -0,0 +1,563 @@
+"""
+Graph snapshot diffing.
+
+Compares two repositories of a store that hold versions of the same
+codebase, usually snapshots indexed with `src/main.py --ref` (see
+src/indexing/snapshots.py), to tell what a change did to the
+architecture:
+
+- package dependencies: DEPENDS_ON edges between packages, and onto
+  external packages, added and removed; the headline of the report
+- symbols (functions, methods and types) added, removed, renamed or
+  moved, and modified: their signature or body_hash changed
+- edges between symbols and files (calls, imports, inheritance, ...)
+  added and removed
+- metric deltas: functions and methods whose cyclomatic complexity grew
+  by more than complexity_threshold
+
+Symbols are matched by their symbol ID without the repository. The rest
+is paired the way incremental runs detect renames (src/indexing/renames.py):
+files moved by content hash, then the symbols of each file by type and
+name or by body_hash, then the symbols left on both sides by type and
+body_hash alone. A pair whose name or file differ is reported as renamed
+or moved rather than as removed and added, and edges are compared
+through the pairs, so the calls of a renamed function are not changes.
+
+Paths are relative to each repository's root. A path prefix scopes the
+report to the symbols and packages under it and the edges with an end
+under it.
+
+Usage:
+    python diff_graphs.py --base snap-main --head snap-feature
+    python diff_graphs.py --base snap-main --head snap-feature --path-prefix internal/auth --format json
+    python diff_graphs.py --base snap-main --head snap-feature --delete-snapshots
+"""
+
+import argparse
+import json
+import logging
+import os
+import sys
+from collections import Counter, defaultdict
+from typing import Any, Dict, Iterable, List, Optional, Set, Tuple
+
+sys.path.append(os.path.dirname(os.path.dirname(os.path.dirname(os.path.abspath(__file__)))))
+
+from src.ast_parser.parser import CodeNode
+from src.ast_parser.signatures import load_signature
+from src.export.graph_export import in_scope
+from src.graph_store import STORAGE_BACKENDS, InMemoryGraphStore, RepositoryStore, get_graph_file, get_storage_backend
+from src.graph_store.repository import get_repo_name, repository_id
+from src.indexing.incremental import PACKAGE_DEPENDENCY_RELATION, SYMBOL_NODE_TYPES
+from src.indexing.renames import match_symbols, unique_pairs
+from src.indexing.snapshots import SNAPSHOT_COMMIT_PROPERTY, SNAPSHOT_REF_PROPERTY, is_snapshot
+from src.indexing.symbol_ids import SYMBOL_ID_PROPERTY, local_symbol_id, relative_path
+from src.mcp.metrics import METRIC_NODE_TYPES, node_metrics
+
+logger = logging.getLogger(__name__)
+
+# Relationships compared between symbols and files; structure (CONTAINS, DEFINES) follows from the symbols
+EDGE_RELATIONS = [
+    "CALLS", "CROSS_LANG_CALLS", "IMPORTS", "IMPORTS_DEFINITION",
+    "EXTENDS", "IMPLEMENTS", "INHERITS_FROM", "EMBEDS", "CONSTRAINED_BY", "DECORATED_BY", "MIXES_IN",
+    "BELONGS_TO", "HAS_ONE", "HAS_MANY", "HAS_AND_BELONGS_TO_MANY", "TESTS",
+]
+
+REPORT_FORMATS = ("text", "json")
+
+DEFAULT_COMPLEXITY_THRESHOLD = 2
+DEFAULT_MAX_RESULTS = 500
+
+
+class _Side:
+    """The nodes and edges of one repository, keyed to be compared with another's."""
+    
+    def __init__(self, db, repo: str):
+        self.repo = get_repo_name(repo)
+        view = RepositoryStore(db, self.repo)
+        records = view.get_nodes([repository_id(self.repo)])
+        if not records:
+            raise ValueError(f"Repository '{self.repo}' is not indexed")
+        self.repository = records[0]["properties"]
+        self.root = self.repository.get("root")
+        
+        # File path relative to the root -> File properties
+        self.files: Dict[str, Dict[str, Any]] = {}
+        # Key -> {"key", "node_type", "name", "path", "properties"}
+        self.symbols: Dict[str, Dict[str, Any]] = {}
+        # Key -> package properties
+        self.packages: Dict[str, Dict[str, Any]] = {}
+        # Key -> display label and relative path of every node seen
+        self.labels: Dict[str, str] = {}
+        self.paths: Dict[str, str] = {}
+        
+        keys: Dict[str, str] = {}
+        for record in view.find_nodes(label="File"):
+            properties = record["properties"]
+            path = self._relative(properties.get("file_path"))
+            if path is None:
+                continue
+            keys[properties["id"]] = key = self.key(properties)
+            self.files[path] = properties
+            self.labels[key] = self.paths[key] = path
+        for node_type in SYMBOL_NODE_TYPES:
+            for record in view.find_nodes(label=node_type):
+                properties = record["properties"]
+                path = self._relative(properties.get("file_path"))
+                if path is None or properties.get("placeholder"):
+                    continue
+                keys[properties["id"]] = key = self.key(properties)
+                self.symbols[key] = {
+                    "key": key,
+                    "node_type": node_type,
+                    "name": properties.get("name"),
+                    "path": path,
+                    "properties": properties,
+                }
+                self.labels[key] = f"{properties.get('name')} ({path})"
+                self.paths[key] = path
+        package_keys: Dict[str, str] = {}
+        for record in view.find_nodes(label="Package"):
+            properties = record["properties"]
+            package_keys[properties["id"]] = key = self.key(properties)
+            self.packages[key] = properties
+            self.paths[key] = self._relative(properties.get("path")) or ""
+            self.labels[key] = properties.get("import_path") or self.paths[key] or properties.get("name") or key
+        
+        # (source key, relationship type, target key)
+        self.edges: Set[Tuple[str, str, str]] = set()
+        for row in view.neighbors(list(keys), EDGE_RELATIONS, direction="out"):
+            target = row["node"]["properties"]
+            target_key = self._neighbour(target)
+            self.edges.add((keys[row["origin_id"]], row["relationship"]["type"], target_key))
+        
+        # (source package key, target key) -> DEPENDS_ON properties; cross-repo links are derived, not code
+        self.dependencies: Dict[Tuple[str, str], Dict[str, Any]] = {}
+        for row in view.neighbors(list(package_keys), [PACKAGE_DEPENDENCY_RELATION], direction="out"):
+            dependency = row["relationship"]["properties"]
+            if not dependency.get("cross_repo"):
+                target_key = self._neighbour(row["node"]["properties"])
+                self.dependencies[(package_keys[row["origin_id"]], target_key)] = dependency
+    
+    def key(self, properties: Dict[str, Any]) -> str:
+        """Symbol ID without the repository, the node ID for nodes without one (placeholders)."""
+        symbol_id = properties.get(SYMBOL_ID_PROPERTY)
+        return local_symbol_id(symbol_id, self.repo) if symbol_id else properties["id"]
+    
+    def _neighbour(self, properties: Dict[str, Any]) -> str:
+        """Key of an edge's target, labelled on first sight; targets in other repositories keep their own IDs."""
+        key = self.key(properties)
+        if key not in self.labels:
+            self.labels[key] = (properties.get("import_path") or properties.get("module_path")
+                                or properties.get("qualified_name") or properties.get("name") or key)
+        return key
+    
+    def _relative(self, file_path: Optional[str]) -> Optional[str]:
+        return relative_path(file_path, self.root) if file_path else None
+    
+    def describe(self) -> Dict[str, Any]:
+        """Repository, ref and commit of the side, for the result."""
+        return {
+            "repo": self.repo,
+            "snapshot": is_snapshot(self.repository),
+            "ref": self.repository.get(SNAPSHOT_REF_PROPERTY),
+            "commit": self.repository.get(SNAPSHOT_COMMIT_PROPERTY),
+        }
+
+
+def _grouped(symbols: Iterable[Dict[str, Any]], key) -> Dict[Any, List[Dict[str, Any]]]:
+    grouped = defaultdict(list)
+    for symbol in symbols:
+        value = key(symbol)
+        if value is not None:
+            grouped[value].append(symbol)
+    return grouped
+
+
+def _body_key(symbol: Dict[str, Any]) -> Optional[Tuple[str, str]]:
+    digest = symbol["properties"].get("body_hash")
+    return (symbol["node_type"], digest) if digest else None
+
+
+def file_moves(base: _Side, head: _Side) -> Dict[str, str]:
+    """head path -> base path of the files only one side has, paired by a unique content hash."""
+    def by_hash(files: Dict[str, Dict[str, Any]], others: Dict[str, Dict[str, Any]]) -> Dict[str, List[str]]:
+        grouped = defaultdict(list)
+        for path, properties in files.items():
+            if path not in others and properties.get("content_hash"):
+                grouped[properties["content_hash"]].append(path)
+        return grouped
+    
+    return dict(unique_pairs(by_hash(head.files, base.files), by_hash(base.files, head.files)))
+
+
+def match_snapshot_symbols(base: _Side, head: _Side, moves: Dict[str, str]) -> Dict[str, str]:
+    """
+    Pair the symbols of two sides: by key, then per file by name or body, then by body across files.
+    
+    Args:
+        moves: head path -> base path of moved files, from file_moves
+    
+    Returns:
+        head key -> base key of every head symbol with a counterpart
+    """
+    pairs = {key: key for key in head.symbols if key in base.symbols}
+    
+    base_left = _grouped((symbol for key, symbol in base.symbols.items() if key not in pairs),
+                         lambda symbol: symbol["path"])
+    head_left = _grouped((symbol for key, symbol in head.symbols.items() if key not in pairs),
+                         lambda symbol: symbol["path"])
+    for path, symbols in head_left.items():
+        old_symbols = base_left.get(moves.get(path, path))
+        if not old_symbols:
+            continue
+        nodes = [
+            CodeNode(symbol["key"], symbol["node_type"], symbol["name"], path, symbol["properties"].get("line_no"),
+                     properties=symbol["properties"])
+            for symbol in symbols
+        ]
+        # Every base symbol counts as kept, so a unique type and name pairs like in a moved file
+        entries = [
+            [symbol["key"], symbol["node_type"], symbol["name"], symbol["properties"].get("body_hash"), True, None]
+            for symbol in old_symbols
+        ]
+        for new_key, (old_key, _) in match_symbols(nodes, entries, moved=True).items():
+            pairs[new_key] = old_key
+    
+    paired = set(pairs.values())
+    head_rest = _grouped((symbol for key, symbol in head.symbols.items() if key not in pairs), _body_key)
+    base_rest = _grouped((symbol for key, symbol in base.symbols.items() if key not in paired), _body_key)
+    for new, old in unique_pairs(head_rest, base_rest):
+        pairs[new["key"]] = old["key"]
+    return pairs
+
+
+def _symbol_entry(symbol: Dict[str, Any]) -> Dict[str, Any]:
+    properties = symbol["properties"]
+    return {
+        "symbol_id": properties.get(SYMBOL_ID_PROPERTY),
+        "node_type": symbol["node_type"],
+        "name": symbol["name"],
+        "file": symbol["path"],
+        "line_no": properties.get("line_no"),
+    }
+
+
+def _entry_sort_key(entry: Dict[str, Any]) -> Tuple[str, int, str]:
+    return entry["file"] or "", entry["line_no"] or 0, entry["name"] or ""
+
+
+def symbol_changes(before: Dict[str, Any], after: Dict[str, Any]) -> List[str]:
+    """What changed between two versions of a symbol: "signature" and/or "body"."""
+    changes = []
+    old_signature, new_signature = load_signature(before), load_signature(after)
+    if (old_signature or new_signature) and old_signature != new_signature:
+        changes.append("signature")
+    if before.get("body_hash") and after.get("body_hash") and before["body_hash"] != after["body_hash"]:
+        changes.append("body")
+    return changes
+
+
+def _edge_entry(side: _Side, edge: Tuple[str, str, str]) -> Dict[str, Any]:
+    source, relation_type, target = edge
+    return {"type": relation_type, "from": side.labels.get(source, source), "to": side.labels.get(target, target)}
+
+
+def _dependency_entry(side: _Side, dependency: Tuple[str, str]) -> Dict[str, Any]:
+    source, target = dependency
+    properties = side.dependencies[dependency]
+    return {
+        "from": side.labels.get(source, source),
+        "to": side.labels.get(target, target),
+        "imports": properties.get("imports"),
+        "files": properties.get("files"),
+    }
+
+
+def diff_graphs(db, base_repo: str, head_repo: str, path_prefix: Optional[str] = None,
+                complexity_threshold: float = DEFAULT_COMPLEXITY_THRESHOLD,
+                max_results: int = DEFAULT_MAX_RESULTS) -> Dict[str, Any]:
+    """
+    Compare two indexed versions of a codebase.
+    
+    Args:
+        db: The whole GraphStore, not a repository's view
+        base_repo: Repository of the old version (e.g. the snapshot of the target branch)
+        head_repo: Repository of the new version
+        path_prefix: Only report symbols, packages and edges under this directory, relative to the codebase root
+        complexity_threshold: Report functions whose cyclomatic complexity grew by more than this
+        max_results: Maximum number of entries of each reported list
+    
+    Returns:
+        {"status", "base", "head", "path_prefix", "complexity_threshold", "summary", "dependencies",
+        "symbols", "metrics", "edges", "truncated"}; summary counts every change, the lists hold
+        at most max_results entries each and "truncated" names the lists that were cut
+    
+    Raises:
+        ValueError: for a repository that is not indexed, the same repository twice or a max_results below 1
+    """
+    max_results = int(max_results)
+    if max_results < 1:
+        raise ValueError("max_results must be at least 1")
+    base, head = _Side(db, base_repo), _Side(db, head_repo)
+    if base.repo == head.repo:
+        raise ValueError("base_repo and head_repo must be different repositories")
+    
+    prefix = (path_prefix or "").replace("\\", "/").strip("/")
+    
+    def scoped(path: Optional[str]) -> bool:
+        return not prefix or in_scope(path, [prefix])
+    
+    def package_scoped(side: _Side, key: str) -> bool:
+        if key not in side.packages:
+            return False
+        import_path = side.packages[key].get("import_path") or ""
+        return scoped(side.paths.get(key)) or import_path == prefix or import_path.startswith(prefix + "/")
+    
+    def edge_scoped(side: _Side, edge: Tuple[str, str, str]) -> bool:
+        return not prefix or any(key in side.paths and scoped(side.paths[key]) for key in (edge[0], edge[2]))
+    
+    moves = file_moves(base, head)
+    pairs = match_snapshot_symbols(base, head, moves)
+    # Base keys under their head names, so the edges of renamed symbols and moved files compare equal
+    to_head = {old_key: new_key for new_key, old_key in pairs.items()}
+    for new_path, old_path in moves.items():
+        to_head[base.key(base.files[old_path])] = head.key(head.files[new_path])
+    
+    paired = set(pairs.values())
+    added = [_symbol_entry(symbol) for key, symbol in head.symbols.items() if key not in pairs and scoped(symbol["path"])]
+    removed = [_symbol_entry(symbol) for key, symbol in base.symbols.items()
+               if key not in paired and scoped(symbol["path"])]
+    renamed, modified, complexity = [], [], []
+    for new_key, old_key in pairs.items():
+        new, old = head.symbols[new_key], base.symbols[old_key]
+        if not (scoped(new["path"]) or scoped(old["path"])):
+            continue
+        changes = symbol_changes(old["properties"], new["properties"])
+        if new["name"] != old["name"] or new["path"] != old["path"]:
+            renamed.append({
+                "kind": "renamed" if new["name"] != old["name"] else "moved",
+                "from": _symbol_entry(old),
+                "to": _symbol_entry(new),
+                "changes": changes,
+            })
+        if changes:
+            entry = dict(_symbol_entry(new), changes=changes)
+            if "signature" in changes:
+                entry["signature"] = {"before": old["properties"].get("signature"),
+                                      "after": new["properties"].get("signature")}
+            modified.append(entry)
+        before, after = node_metrics(old["properties"]), node_metrics(new["properties"])
+        if new["node_type"] in METRIC_NODE_TYPES and before and after:
+            delta = (after["complexity"] or 0) - (before["complexity"] or 0)
+            if delta > complexity_threshold:
+                complexity.append(dict(_symbol_entry(new), before=before, after=after, complexity_delta=delta))
+    added.sort(key=_entry_sort_key)
+    removed.sort(key=_entry_sort_key)
+    modified.sort(key=_entry_sort_key)
+    renamed.sort(key=lambda entry: _entry_sort_key(entry["to"]))
+    complexity.sort(key=lambda entry: (-entry["complexity_delta"],) + _entry_sort_key(entry))
+    
+    base_edges = {(to_head.get(source, source), relation_type, to_head.get(target, target)): (source, relation_type, target)
+                  for source, relation_type, target in base.edges}
+    edges_added = [_edge_entry(head, edge) for edge in sorted(head.edges - base_edges.keys()) if edge_scoped(head, edge)]
+    edges_removed = [_edge_entry(base, base_edges[edge]) for edge in sorted(base_edges.keys() - head.edges)
+                     if edge_scoped(base, base_edges[edge])]
+    
+    dependencies_added = [
+        _dependency_entry(head, dependency) for dependency in sorted(head.dependencies.keys() - base.dependencies.keys())
+        if not prefix or any(package_scoped(head, key) for key in dependency)
+    ]
+    dependencies_removed = [
+        _dependency_entry(base, dependency) for dependency in sorted(base.dependencies.keys() - head.dependencies.keys())
+        if not prefix or any(package_scoped(base, key) for key in dependency)
+    ]
+    
+    summary = {
+        "dependencies_added": len(dependencies_added),
+        "dependencies_removed": len(dependencies_removed),
+        "symbols_added": len(added),
+        "symbols_removed": len(removed),
+        "symbols_renamed": len(renamed),
+        "symbols_modified": len(modified),
+        "complexity_increased": len(complexity),
+        "edges_added": len(edges_added),
+        "edges_removed": len(edges_removed),
+    }
+    edges = {
+        "added_by_type": dict(sorted(Counter(entry["type"] for entry in edges_added).items())),
+        "removed_by_type": dict(sorted(Counter(entry["type"] for entry in edges_removed).items())),
+        "added": edges_added,
+        "removed": edges_removed,
+    }
+    lists = {
+        "dependencies.added": dependencies_added,
+        "dependencies.removed": dependencies_removed,
+        "symbols.added": added,
+        "symbols.removed": removed,
+        "symbols.renamed": renamed,
+        "symbols.modified": modified,
+        "metrics.complexity_increased": complexity,
+        "edges.added": edges_added,
+        "edges.removed": edges_removed,
+    }
+    truncated = []
+    for name, entries in lists.items():
+        if len(entries) > max_results:
+            truncated.append(name)
+            del entries[max_results:]
+    
+    return {
+        "status": "ok",
+        "base": base.describe(),
+        "head": head.describe(),
+        "path_prefix": prefix or None,
+        "complexity_threshold": complexity_threshold,
+        "summary": summary,
+        "dependencies": {"added": dependencies_added, "removed": dependencies_removed},
+        "symbols": {"added": added, "removed": removed, "renamed": renamed, "modified": modified},
+        "metrics": {"complexity_increased": complexity},
+        "edges": edges,
+        "truncated": truncated,
+    }
+
+
+def delete_snapshots(db, repos: Iterable[str]) -> List[str]:
+    """
+    Delete the given repositories that are snapshots; any other (a working copy's index) is kept.
+    
+    Returns:
+        Names of the deleted repositories
+    """
+    deleted = []
+    for repo in dict.fromkeys(repos):
+        records = RepositoryStore(db, repo).get_nodes([repository_id(repo)])
+        if not records or not is_snapshot(records[0]["properties"]):
+            logger.info(f"Keeping repository {repo}, it is not a snapshot")
+            continue
+        count = db.delete_repository(repo)
+        logger.info(f"Deleted snapshot {repo} ({count} nodes)")
+        deleted.append(repo)
+    return deleted
+
+
+def _count(count: int, noun: str) -> str:
+    return f"{count} {noun}{'' if count == 1 else 's'}"
+
+
+def _side_label(side: Dict[str, Any]) -> str:
+    if side.get("ref"):
+        return f"{side['repo']} ({side['ref']} @ {(side.get('commit') or '')[:12]})"
+    return side["repo"]
+
+
+def _symbol_line(marker: str, entry: Dict[str, Any]) -> str:
+    location = f"{entry['file']}:{entry['line_no']}" if entry.get("line_no") else entry["file"]
+    return f"  {marker} {entry['node_type']} {entry['name']}  {location}"
+
+
+def format_diff_report(result: Dict[str, Any]) -> str:
+    """Human-readable report of a diff_graphs result."""
+    summary = result["summary"]
+    scope = f" under {result['path_prefix']}" if result.get("path_prefix") else ""
+    lines = [f"Graph diff {_side_label(result['base'])} -> {_side_label(result['head'])}{scope}"]
+    if not any(summary.values()):
+        lines.append("No architectural changes.")
+        if result.get("deleted"):
+            lines.append(f"Deleted snapshots: {', '.join(result['deleted'])}")
+        return "\n".join(lines) + "\n"
+    
+    dependencies = result["dependencies"]
+    lines.append("")
+    lines.append(f"Package dependencies: {summary['dependencies_added']} added, {summary['dependencies_removed']} removed")
+    for marker, entries in (("+", dependencies["added"]), ("-", dependencies["removed"])):
+        for entry in entries:
+            imports = f" ({_count(entry['imports'], 'import')})" if entry.get("imports") else ""
+            lines.append(f"  {marker} {entry['from']} -> {entry['to']}{imports}")
+    
+    symbols = result["symbols"]
+    lines.append("")
+    lines.append(f"Symbols: {summary['symbols_added']} added, {summary['symbols_removed']} removed, "
+                 f"{summary['symbols_renamed']} renamed or moved, {summary['symbols_modified']} modified")
+    lines += [_symbol_line("+", entry) for entry in symbols["added"]]
+    lines += [_symbol_line("-", entry) for entry in symbols["removed"]]
+    for entry in symbols["renamed"]:
+        old, new = entry["from"], entry["to"]
+        lines.append(f"  > {new['node_type']} {old['name']} ({old['file']}) {entry['kind']} to {new['name']} ({new['file']})")
+    for entry in symbols["modified"]:
+        lines.append(f"{_symbol_line('~', entry)}  ({', '.join(entry['changes'])} changed)")
+    
+    complexity = result["metrics"]["complexity_increased"]
+    if complexity:
+        lines.append("")
+        lines.append(f"Complexity increased by more than {result['complexity_threshold']}: "
+                     f"{_count(summary['complexity_increased'], 'function')}")
+        for entry in complexity:
+            lines.append(f"{_symbol_line('^', entry)}  {entry['before']['complexity']} -> "
+                         f"{entry['after']['complexity']} (+{entry['complexity_delta']})")
+    
+    edges = result["edges"]
+    lines.append("")
+    by_type = ", ".join(
+        f"{relation_type} +{edges['added_by_type'].get(relation_type, 0)} -{edges['removed_by_type'].get(relation_type, 0)}"
+        for relation_type in sorted(set(edges["added_by_type"]) | set(edges["removed_by_type"]))
+    )
+    lines.append(f"Edges: {summary['edges_added']} added, {summary['edges_removed']} removed"
+                 + (f" ({by_type})" if by_type else ""))
+    for marker, entries in (("+", edges["added"]), ("-", edges["removed"])):
+        for entry in entries:
+            lines.append(f"  {marker} {entry['from']} -{entry['type']}-> {entry['to']}")
+    
+    if result.get("truncated"):
+        lines.append("")
+        lines.append(f"Lists cut to the first entries: {', '.join(result['truncated'])}")
+    if result.get("deleted"):
+        lines.append("")
+        lines.append(f"Deleted snapshots: {', '.join(result['deleted'])}")
+    return "\n".join(lines) + "\n"
+
+
+def main():
+    """Graph diff command entry point"""
+    parser = argparse.ArgumentParser(description="Compare two indexed versions (snapshots) of a codebase")
+    parser.add_argument("--base", required=True, help="Repository of the old version, e.g. 'snap-main'")
+    parser.add_argument("--head", required=True, help="Repository of the new version, e.g. 'snap-feature'")
+    parser.add_argument("--path-prefix", help="Only report changes under this directory, relative to the codebase root")
+    parser.add_argument("--complexity-threshold", type=float, default=DEFAULT_COMPLEXITY_THRESHOLD,
+                        help=f"Report functions whose complexity grew by more than this (default: {DEFAULT_COMPLEXITY_THRESHOLD})")
+    parser.add_argument("--max-results", type=int, default=DEFAULT_MAX_RESULTS,
+                        help=f"Maximum number of entries of each list (default: {DEFAULT_MAX_RESULTS})")
+    parser.add_argument("--delete-snapshots", action="store_true", help="Delete both repositories afterwards if they are snapshots")
+    parser.add_argument("--format", choices=REPORT_FORMATS, default="text", help="Output format")
+    parser.add_argument("--storage", choices=STORAGE_BACKENDS, help="Storage backend (default: GRAPH_STORAGE or neo4j)")
+    parser.add_argument("--graph-file", help="JSON file of the memory backend (default: GRAPH_STORE_PATH)")
+    parser.add_argument("--neo4j-uri", help="Neo4j database URI")
+    parser.add_argument("--neo4j-user", help="Neo4j username")
+    parser.add_argument("--neo4j-password", help="Neo4j password")
+    
+    args = parser.parse_args()
+    
+    if get_storage_backend(args.storage) == "memory":
+        db = InMemoryGraphStore(get_graph_file(args.graph_file))
+    else:
+        from src.neo4j_storage.graph_db import Neo4jDatabase
+        
+        db = Neo4jDatabase(uri=args.neo4j_uri, user=args.neo4j_user, password=args.neo4j_password)
+    try:
+        result = diff_graphs(db, args.base, args.head, path_prefix=args.path_prefix,
+                             complexity_threshold=args.complexity_threshold, max_results=args.max_results)
+        if args.delete_snapshots:
+            result["deleted"] = delete_snapshots(db, [args.base, args.head])
+    except ValueError as e:
+        parser.error(str(e))
+    finally:
+        db.close()
+    
+    if args.format == "json":
+        sys.stdout.write(json.dumps(result, ensure_ascii=False, indent=2) + "\n")
+    else:
+        sys.stdout.write(format_diff_report(result))
+
+
+if __name__ == "__main__":
+    main()
@@ -0,0 +1,510 @@
+"""
+Unreferenced symbol report.
+
+Lists the functions, methods and types that no CALLS, IMPORTS or other
+reference edge points at from anywhere in the index, grouped by package.
+References from generated files count like any other, so helpers only
+generated code uses (registration functions, mocks) are never reported;
+a symbol's references to itself (recursion) do not count, and a type is
+referenced when any of its members is.
+
+Suppressed, and counted per reason instead of reported:
+- symbols declared in generated files (tagged generated at indexing,
+  "Code generated ... DO NOT EDIT" or "@generated" headers, protoc and
+  similar output names, see src/ast_parser/guards.py)
+- rules matching name, path, decorator, node type or language:
+  DEFAULT_SUPPRESSIONS (main and init functions, Go and pytest tests,
+  fixtures, Python dunder methods) plus the "suppressions" of the
+  UNREFERENCED_CONFIG JSON file, e.g.
+      {"public_api": ["pkg/sdk"],
+       "suppressions": [{"reason": "handler", "names": ["handle_*"], "paths": ["*/views/*"]}]}
+- exported symbols under a public API path (UNREFERENCED_PUBLIC_API,
+  comma-separated, plus the config file's "public_api")
+- methods implementing a method of an interface or base type reached
+  through IMPLEMENTS / EXTENDS, since they are called dynamically
+
+Every reported symbol has a confidence: "high" for unexported functions,
+lowered one step each for exported symbols (callers may live outside the
+index) and for methods, types and decorated symbols (called through
+dispatch, reflection or frameworks, or used in signatures only). This is
+a report for review, not a list of code that is safe to delete.
+
+Usage:
+    python find_unreferenced.py
+    python find_unreferenced.py --scope internal/auth --min-confidence medium
+    python find_unreferenced.py --public-api pkg/sdk --config unreferenced.json --format json
+"""
+
+import argparse
+import json
+import logging
+import os
+import sys
+from fnmatch import fnmatchcase
+from typing import Any, Dict, Iterable, List, Optional, Set
+
+sys.path.append(os.path.dirname(os.path.dirname(os.path.dirname(os.path.abspath(__file__)))))
+
+from src.analysis.cycles import package_label
+from src.ast_parser.guards import GENERATED_FILE_PATTERNS, GENERATED_HEADER
+from src.ast_parser.language_detector import detect_language
+from src.export.graph_export import in_scope, scope_prefixes
+from src.graph_store import STORAGE_BACKENDS, InMemoryGraphStore, get_graph_file, get_storage_backend, same_repo_id
+from src.mcp.references import node_type_from_labels
+
+logger = logging.getLogger(__name__)
+
+REPORT_FORMATS = ("text", "json")
+
+CONFIDENCE_LEVELS = ("low", "medium", "high")
+
+TYPE_LABELS = ("Class", "Interface", "Enum", "TypeAlias")
+SYMBOL_LABELS = ("Function", "Method") + TYPE_LABELS
+
+# Relations that make their target referenced
+REFERENCE_RELATIONS = [
+    "CALLS", "CROSS_LANG_CALLS", "IMPORTS", "IMPORTS_DEFINITION", "IMPORTS_FROM",
+    "EXTENDS", "IMPLEMENTS", "EMBEDS", "CONSTRAINED_BY", "DECORATED_BY", "MIXES_IN",
+    "BELONGS_TO", "HAS_ONE", "HAS_MANY", "HAS_AND_BELONGS_TO_MANY", "EXTENSION_OF",
+]
+
+# Relations to the interfaces and base types whose methods a type implements
+SUPERTYPE_RELATIONS = ["IMPLEMENTS", "EXTENDS", "INHERITS_FROM", "MIXES_IN"]
+
+DEFAULT_MAX_RESULTS = 500
+
+SUPPRESS_GENERATED = "generated"
+SUPPRESS_PUBLIC_API = "public_api"
+SUPPRESS_INTERFACE = "interface_implementation"
+
+# Suppression rules: every given condition must match, a list matches when any pattern does.
+# names and decorators are globs; paths are globs over the file path, over the file name
+# when they hold no "/"
+DEFAULT_SUPPRESSIONS: List[Dict[str, Any]] = [
+    {"reason": "entry_point", "names": ["main"]},
+    {"reason": "entry_point", "names": ["init"], "node_types": ["Function"], "languages": ["go"]},
+    {"reason": "test", "names": ["Test*", "Benchmark*", "Fuzz*", "Example*"], "paths": ["*_test.go"]},
+    {"reason": "test", "names": ["test_*", "Test*", "setUp*", "tearDown*"],
+     "paths": ["test_*.py", "*_test.py", "conftest.py"]},
+    {"reason": "test", "decorators": ["Test", "ParameterizedTest", "test", "tokio::test", "*fixture*"]},
+    {"reason": "dunder", "names": ["__*__"], "languages": ["python"]},
+]
+
+SUPPRESSION_KEYS = ("reason", "names", "paths", "decorators", "node_types", "languages")
+
+
+def _split_paths(value: Optional[str]) -> List[str]:
+    return [part.strip() for part in (value or "").split(",") if part.strip()]
+
+
+def validate_suppressions(rules: Iterable[Any]) -> List[Dict[str, Any]]:
+    """
+    Check suppression rules from a config file.
+    
+    Raises:
+        ValueError: for a rule without reason or conditions, or with an unknown key
+    """
+    checked = []
+    for rule in rules:
+        if not isinstance(rule, dict) or not rule.get("reason"):
+            raise ValueError(f"Suppression rule needs a reason: {rule!r}")
+        unknown = sorted(set(rule) - set(SUPPRESSION_KEYS))
+        if unknown:
+            raise ValueError(f"Unknown suppression rule key '{unknown[0]}', expected: {', '.join(SUPPRESSION_KEYS)}")
+        conditions = {key: rule[key] for key in SUPPRESSION_KEYS[1:] if key in rule}
+        if not conditions:
+            raise ValueError(f"Suppression rule '{rule['reason']}' has no condition")
+        for key, patterns in conditions.items():
+            if not isinstance(patterns, list) or not all(isinstance(pattern, str) for pattern in patterns):
+                raise ValueError(f"Suppression rule '{rule['reason']}': {key} must be a list of strings")
+        checked.append(dict(rule))
+    return checked
+
+
+def load_config(config_path: Optional[str] = None) -> Dict[str, List[Any]]:
+    """
+    Public API paths and extra suppression rules.
+    
+    Args:
+        config_path: JSON file with "public_api" and "suppressions", if None, get from UNREFERENCED_CONFIG
+    
+    Returns:
+        {"public_api": UNREFERENCED_PUBLIC_API paths plus the file's, "suppressions": the file's rules}
+    
+    Raises:
+        ValueError: for an unreadable config file or an invalid rule
+    """
+    config_path = config_path if config_path is not None else os.getenv("UNREFERENCED_CONFIG", "")
+    config: Dict[str, List[Any]] = {
+        "public_api": _split_paths(os.getenv("UNREFERENCED_PUBLIC_API", "")),
+        "suppressions": [],
+    }
+    if not config_path:
+        return config
+    try:
+        with open(config_path, "r", encoding="utf-8") as f:
+            document = json.load(f)
+    except (OSError, ValueError) as e:
+        raise ValueError(f"Cannot read unreferenced config {config_path}: {e}")
+    if not isinstance(document, dict):
+        raise ValueError(f"Unreferenced config {config_path} must hold a JSON object")
+    public_api = document.get("public_api", [])
+    if isinstance(public_api, str):
+        public_api = _split_paths(public_api)
+    config["public_api"].extend(path for path in public_api if path not in config["public_api"])
+    config["suppressions"] = validate_suppressions(document.get("suppressions", []))
+    return config
+
+
+def _path_matches(file_path: str, pattern: str) -> bool:
+    """Glob over the whole path, or over the file name for a pattern without "/"."""
+    if "/" not in pattern:
+        return fnmatchcase(os.path.basename(file_path), pattern)
+    return fnmatchcase(file_path, pattern) or fnmatchcase(file_path, "*/" + pattern.lstrip("/"))
+
+
+def under_path(file_path: str, path: str) -> bool:
+    """Whether a file is in a directory given as written or as an absolute path, or has it as consecutive path segments."""
+    segments = path.replace("\\", "/").strip("/")
+    if not file_path or not segments:
+        return False
+    return in_scope(file_path, scope_prefixes(path)) or f"/{segments}/" in "/" + file_path
+
+
+def is_generated_file(file_path: str, file_properties: Optional[Dict[str, Any]] = None) -> bool:
+    """Generated by its tag or name, or by a "Code generated" style notice in the File node's header or doc."""
+    properties = file_properties or {}
+    if properties.get("generated") or any(_path_matches(file_path, pattern) for pattern in GENERATED_FILE_PATTERNS):
+        return True
+    return any(GENERATED_HEADER.search(properties.get(key) or "") for key in ("header", "doc"))
+
+
+def _decorators(properties: Dict[str, Any]) -> List[str]:
+    """Decorators, annotations and attributes without "@" and arguments."""
+    texts = properties.get("decorators", []) + properties.get("annotations", []) + properties.get("attributes", [])
+    return [text.lstrip("@").split("(", 1)[0].strip() for text in texts]
+
+
+def rule_matches(rule: Dict[str, Any], symbol: Dict[str, Any]) -> bool:
+    if "names" in rule and not any(fnmatchcase(symbol["name"], pattern) for pattern in rule["names"]):
+        return False
+    if "paths" in rule and not any(_path_matches(symbol["file_path"], pattern) for pattern in rule["paths"]):
+        return False
+    if "decorators" in rule and not any(
+        fnmatchcase(decorator, pattern) for decorator in symbol["decorators"] for pattern in rule["decorators"]
+    ):
+        return False
+    if "node_types" in rule and symbol["node_type"] not in rule["node_types"]:
+        return False
+    if "languages" in rule and symbol["language"] not in rule["languages"]:
+        return False
+    return True
+
+
+def is_exported(name: str, properties: Dict[str, Any], language: Optional[str]) -> bool:
+    """Whether code outside its package or module may use the symbol."""
+    if language == "go":
+        return name[:1].isupper()
+    if language == "python":
+        return not name.startswith("_")
+    if language in ("java", "kotlin"):
+        return "private" not in properties.get("modifiers", [])
+    if language == "rust":
+        return properties.get("visibility", "").startswith("pub")
+    if language == "csharp":
+        # Members without an access modifier are private
+        return bool({"public", "protected", "internal"} & set(properties.get("modifiers", [])))
+    if language in ("javascript", "typescript"):
+        return bool(properties.get("exported"))
+    if language == "ruby":
+        return properties.get("visibility", "public") == "public"
+    return True
+
+
+def _member_names(record: Dict[str, Any]) -> Set[str]:
+    """Method names an interface records in "methods" (Go method signatures)."""
+    return {method.split("(", 1)[0].strip() for method in record["properties"].get("methods") or []}
+
+
+def _supertype_methods(db, owner_ids: List[str]) -> Dict[str, Dict[str, Any]]:
+    """
+    Owner type -> {"methods": method names of its interfaces and base types, "external": a supertype is not indexed}.
+    
+    IMPLEMENTS and EXTENDS are followed transitively, each type once.
+    """
+    supertypes: Dict[str, Set[str]] = {owner_id: set() for owner_id in owner_ids}
+    records: Dict[str, Dict[str, Any]] = {}
+    frontier = {owner_id: {owner_id} for owner_id in owner_ids}
+    while frontier:
+        next_frontier: Dict[str, Set[str]] = {}
+        for row in db.neighbors(list(frontier), SUPERTYPE_RELATIONS, direction="out"):
+            target_id = row["node"]["properties"]["id"]
+            records.setdefault(target_id, row["node"])
+            for owner_id in frontier[row["origin_id"]]:
+                if target_id not in supertypes[owner_id] and target_id != owner_id:
+                    supertypes[owner_id].add(target_id)
+                    next_frontier.setdefault(target_id, set()).add(owner_id)
+        frontier = next_frontier
+    
+    all_supertypes = sorted({type_id for type_ids in supertypes.values() for type_id in type_ids})
+    methods: Dict[str, Set[str]] = {type_id: _member_names(records[type_id]) for type_id in all_supertypes}
+    for row in db.neighbors(all_supertypes, ["DEFINES"], direction="out"):
+        methods[row["origin_id"]].add(row["node"]["properties"].get("name"))
+    for row in db.neighbors(all_supertypes, ["METHOD_OF"], direction="in"):
+        methods[row["origin_id"]].add(row["node"]["properties"].get("name"))
+    
+    result = {}
+    for owner_id, type_ids in supertypes.items():
+        result[owner_id] = {
+            "methods": set().union(*(methods[type_id] for type_id in type_ids)) if type_ids else set(),
+            "external": any(not records[type_id]["properties"].get("line_no") for type_id in type_ids),
+        }
+    return result
+
+
+def _owners(db, symbols: Dict[str, Dict[str, Any]]) -> Dict[str, str]:
+    """Member ID -> ID of the type defining it (DEFINES from the type, METHOD_OF to it)."""
+    type_ids = [symbol_id for symbol_id, symbol in symbols.items() if symbol["node_type"] in TYPE_LABELS]
+    method_ids = [symbol_id for symbol_id, symbol in symbols.items() if symbol["node_type"] == "Method"]
+    owners: Dict[str, str] = {}
+    for row in db.neighbors(type_ids, ["DEFINES"], direction="out"):
+        owners.setdefault(row["node"]["properties"]["id"], row["origin_id"])
+    for row in db.neighbors(method_ids, ["METHOD_OF"], direction="out"):
+        owners.setdefault(row["origin_id"], row["node"]["properties"]["id"])
+    return owners
+
+
+def _external_bases(properties: Dict[str, Any], resolved: int) -> bool:
+    """Whether a Python class names more bases than it has indexed EXTENDS edges."""
+    bases = [base for base in properties.get("bases", []) if base != "object"]
+    return len(bases) > resolved
+
+
+def find_unreferenced(db, scope: Optional[str] = None, min_confidence: str = "low",
+                      public_api: Optional[List[str]] = None, suppressions: Optional[List[Dict[str, Any]]] = None,
+                      config_path: Optional[str] = None, max_results: int = DEFAULT_MAX_RESULTS) -> Dict[str, Any]:
+    """
+    Find symbols without inbound references.
+    
+    Args:
+        db: GraphStore backend
+        scope: Only report symbols in files under this directory (references from anywhere count)
+        min_confidence: Lowest confidence reported, "low", "medium" or "high"
+        public_api: Directories whose exported symbols are public API, if None, from load_config
+        suppressions: Extra suppression rules added to DEFAULT_SUPPRESSIONS, if None, from load_config
+        config_path: JSON config file, if None, get from UNREFERENCED_CONFIG
+        max_results: Maximum number of reported symbols
+    
+    Returns:
+        {"status", "scope", "min_confidence", "symbol_count", "unreferenced_count", "suppressed", "packages"};
+        "suppressed" counts the unreferenced symbols left out per reason, every package lists its symbols
+        with file, line, node type, exported flag and confidence; "truncated" when max_results cut the list
+    
+    Raises:
+        ValueError: for an unknown min_confidence, a max_results below 1 or an invalid config
+    """
+    if min_confidence not in CONFIDENCE_LEVELS:
+        raise ValueError(f"Unknown confidence '{min_confidence}', expected one of: {', '.join(CONFIDENCE_LEVELS)}")
+    max_results = int(max_results)
+    if max_results < 1:
+        raise ValueError("max_results must be at least 1")
+    if public_api is None or suppressions is None:
+        config = load_config(config_path)
+        public_api = config["public_api"] if public_api is None else public_api
+        suppressions = config["suppressions"] if suppressions is None else suppressions
+    rules = DEFAULT_SUPPRESSIONS + validate_suppressions(suppressions)
+    
+    prefixes = scope_prefixes(scope) if scope else None
+    symbols: Dict[str, Dict[str, Any]] = {}
+    for label in SYMBOL_LABELS:
+        for record in db.find_nodes(label=label, path_prefixes=prefixes):
+            properties = record["properties"]
+            # Placeholders of symbols outside the index have no location
+            if not properties.get("file_path") or not properties.get("line_no") or properties["id"] in symbols:
+                continue
+            symbols[properties["id"]] = {
+                "id": properties["id"],
+                "symbol_id": properties.get("symbol_id"),
+                "name": properties.get("name") or "",
+                "node_type": node_type_from_labels(record["labels"]),
+                "file_path": properties["file_path"],
+                "line_no": properties.get("line_no"),
+                "language": detect_language(properties["file_path"]),
+                "decorators": _decorators(properties),
+                "properties": properties,
+            }
+    
+    referenced: Set[str] = set()
+    for row in db.neighbors(list(symbols), REFERENCE_RELATIONS, direction="in"):
+        if row["node"]["properties"]["id"] != row["origin_id"]:
+            referenced.add(row["origin_id"])
+    owners = _owners(db, symbols)
+    for member_id, owner_id in owners.items():
+        if member_id in referenced:
+            referenced.add(owner_id)
+    
+    unreferenced = [symbol for symbol_id, symbol in symbols.items() if symbol_id not in referenced]
+    # File IDs live in the namespace of the symbol's repository
+    file_ids = {symbol["id"]: same_repo_id(symbol["id"], f"file:{symbol['file_path']}") for symbol in unreferenced}
+    files = {record["properties"]["id"]: record["properties"]
+             for record in db.get_nodes(sorted(set(file_ids.values())))}
+    packages: Dict[str, str] = {}
+    for row in db.neighbors(list(files), ["CONTAINS"], direction="in", label="Package"):
+        packages.setdefault(row["origin_id"], package_label(row["node"]["properties"]))
+    
+    method_owners = sorted({owners[symbol["id"]] for symbol in unreferenced
+                            if symbol["node_type"] == "Method" and symbol["id"] in owners})
+    supertypes = _supertype_methods(db, method_owners) if method_owners else {}
+    extends_counts: Dict[str, int] = {}
+    for row in db.neighbors(method_owners, ["EXTENDS"], direction="out"):
+        extends_counts[row["origin_id"]] = extends_counts.get(row["origin_id"], 0) + 1
+    owner_records = {record["properties"]["id"]: record["properties"] for record in db.get_nodes(method_owners)}
+    
+    wanted = CONFIDENCE_LEVELS.index(min_confidence)
+    suppressed: Dict[str, int] = {}
+    reported: Dict[str, List[Dict[str, Any]]] = {}
+    for symbol in unreferenced:
+        properties = symbol["properties"]
+        exported = is_exported(symbol["name"], properties, symbol["language"])
+        owner_id = owners.get(symbol["id"]) if symbol["node_type"] == "Method" else None
+        inherited = supertypes.get(owner_id, {"methods": set(), "external": False})
+        
+        if is_generated_file(symbol["file_path"], files.get(file_ids[symbol["id"]])):
+            reason = SUPPRESS_GENERATED
+        else:
+            reason = next((rule["reason"] for rule in rules if rule_matches(rule, symbol)), None)
+        if reason is None and exported and any(under_path(symbol["file_path"], path) for path in public_api):
+            reason = SUPPRESS_PUBLIC_API
+        if reason is None and symbol["name"] in inherited["methods"]:
+            reason = SUPPRESS_INTERFACE
+        if reason is not None:
+            suppressed[reason] = suppressed.get(reason, 0) + 1
+            continue
+        
+        level = 1 if exported else 2
+        external_base = inherited["external"] or (
+            owner_id is not None and _external_bases(owner_records.get(owner_id, {}), extends_counts.get(owner_id, 0))
+        )
+        if symbol["node_type"] != "Function" or symbol["decorators"] or external_base:
+            level -= 1
+        if max(level, 0) < wanted:
+            continue
+        
+        package = packages.get(file_ids[symbol["id"]]) or os.path.dirname(symbol["file_path"])
+        reported.setdefault(package, []).append({
+            "id": symbol["id"],
+            "symbol_id": symbol["symbol_id"],
+            "name": symbol["name"],
+            "node_type": symbol["node_type"],
+            "file_path": symbol["file_path"],
+            "line_no": symbol["line_no"],
+            "exported": exported,
+            "confidence": CONFIDENCE_LEVELS[max(level, 0)],
+        })
+    
+    groups = []
+    count = 0
+    truncated = False
+    for package in sorted(reported):
+        entries = sorted(reported[package], key=lambda entry: (entry["file_path"], entry["line_no"] or 0, entry["id"]))
+        if count + len(entries) > max_results:
+            entries = entries[:max_results - count]
+            truncated = True
+        if entries:
+            groups.append({"package": package, "symbols": entries})
+            count += len(entries)
+        if truncated:
+            break
+    
+    result = {
+        "status": "ok",
+        "scope": scope,
+        "min_confidence": min_confidence,
+        "symbol_count": len(symbols),
+        "unreferenced_count": sum(len(entries) for entries in reported.values()),
+        "suppressed": dict(sorted(suppressed.items())),
+        "packages": groups,
+    }
+    if truncated:
+        result["truncated"] = True
+    return result
+
+
+def _count(count: int, noun: str) -> str:
+    return f"{count} {noun}{'' if count == 1 else 's'}"
+
+
+def format_unreferenced_report(result: Dict[str, Any]) -> str:
+    """Human-readable report of a find_unreferenced result."""
+    scope = f" under {result['scope']}" if result.get("scope") else ""
+    suppressed = ", ".join(f"{reason} {count}" for reason, count in result["suppressed"].items())
+    footer = f"Suppressed: {suppressed}." if suppressed else "Nothing suppressed."
+    if not result["packages"]:
+        return (f"No unreferenced symbols{scope} at {result['min_confidence']} confidence or above "
+                f"among {result['symbol_count']} symbols. {footer}\n")
+    
+    lines = [
+        f"Found {_count(result['unreferenced_count'], 'unreferenced symbol')}{scope} "
+        f"({_count(result['symbol_count'], 'symbol')} scanned, confidence {result['min_confidence']} or above). {footer}",
+    ]
+    for group in result["packages"]:
+        lines.append("")
+        lines.append(group["package"])
+        for symbol in group["symbols"]:
+            exported = ", exported" if symbol["exported"] else ""
+            lines.append(f"  [{symbol['confidence']}] {symbol['node_type']} {symbol['name']}{exported}  "
+                         f"{symbol['file_path']}:{symbol['line_no']}")
+    if result.get("truncated"):
+        lines.append("")
+        lines.append(f"... more symbols not shown (limit {sum(len(group['symbols']) for group in result['packages'])})")
+    return "\n".join(lines) + "\n"
+
+
+def main():
+    """Unreferenced symbol report command entry point"""
+    parser = argparse.ArgumentParser(description="Report symbols of the code knowledge graph that nothing references")
+    parser.add_argument("--scope", help="Only report symbols under this directory, e.g. 'internal/auth'")
+    parser.add_argument("--min-confidence", choices=CONFIDENCE_LEVELS, default="low",
+                        help="Lowest confidence reported (default: low)")
+    parser.add_argument("--public-api", help="Comma-separated directories whose exported symbols are public API "
+                                             "(default: UNREFERENCED_PUBLIC_API)")
+    parser.add_argument("--config", help="JSON file with public_api and suppressions (default: UNREFERENCED_CONFIG)")
+    parser.add_argument("--max-results", type=int, default=DEFAULT_MAX_RESULTS,
+                        help=f"Maximum number of reported symbols (default: {DEFAULT_MAX_RESULTS})")
+    parser.add_argument("--format", choices=REPORT_FORMATS, default="text", help="Output format")
+    parser.add_argument("--storage", choices=STORAGE_BACKENDS, help="Storage backend (default: GRAPH_STORAGE or neo4j)")
+    parser.add_argument("--graph-file", help="JSON file of the memory backend (default: GRAPH_STORE_PATH)")
+    parser.add_argument("--neo4j-uri", help="Neo4j database URI")
+    parser.add_argument("--neo4j-user", help="Neo4j username")
+    parser.add_argument("--neo4j-password", help="Neo4j password")
+    
+    args = parser.parse_args()
+    
+    try:
+        config = load_config(args.config)
+    except ValueError as e:
+        parser.error(str(e))
+    public_api = config["public_api"] + _split_paths(args.public_api)
+    
+    if get_storage_backend(args.storage) == "memory":
+        db = InMemoryGraphStore(get_graph_file(args.graph_file))
+    else:
+        from src.neo4j_storage.graph_db import Neo4jDatabase
+        
+        db = Neo4jDatabase(uri=args.neo4j_uri, user=args.neo4j_user, password=args.neo4j_password)
+    try:
+        result = find_unreferenced(db, scope=args.scope, min_confidence=args.min_confidence, public_api=public_api,
+                                   suppressions=config["suppressions"], max_results=args.max_results)
+    except ValueError as e:
+        parser.error(str(e))
+    finally:
+        db.close()
+    
+    if args.format == "json":
+        sys.stdout.write(json.dumps(result, ensure_ascii=False, indent=2) + "\n")
+    else:
+        sys.stdout.write(format_unreferenced_report(result))
+
+
+if __name__ == "__main__":
+    main()
@@ -3,17 +3,25 @@
 from .base_adapter import LanguageAdapter
 from .python_adapter import PythonAstGrepAdapter
 from .javascript_adapter import JavaScriptAstGrepAdapter
+from .typescript_adapter import TypeScriptAdapter
 from .java_adapter import JavaAdapter
 from .cpp_adapter import CppAdapter
 from .rust_adapter import RustAdapter
 from .go_adapter import GoAdapter
+from .csharp_adapter import CSharpAdapter
+from .ruby_adapter import RubyAdapter
+from .kotlin_adapter import KotlinAdapter
 
 __all__ = [
     "LanguageAdapter",
     "PythonAstGrepAdapter",
     "JavaScriptAstGrepAdapter",
+    "TypeScriptAdapter",
     "JavaAdapter",
     "CppAdapter",
     "RustAdapter",
     "GoAdapter",
+    "CSharpAdapter",
+    "RubyAdapter",
+    "KotlinAdapter",
 ]
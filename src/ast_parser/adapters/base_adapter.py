@@ -1,7 +1,7 @@
 """Base adapter interface for multi-language AST parsing using ast-grep."""
 
 from abc import ABC, abstractmethod
-from typing import Dict, List, Any, Tuple, Set
+from typing import Dict, List, Any, Optional, Tuple, Set
 import sys
 import os
 
@@ -9,6 +9,7 @@ import os
 sys.path.insert(0, os.path.abspath(os.path.join(os.path.dirname(__file__), "../..")))
 
 from ast_parser.parser import CodeNode, CodeRelation
+from ast_parser.doc_comments import leading_comments, prev_named
 
 
 class LanguageAdapter(ABC):
@@ -26,6 +27,11 @@ class LanguageAdapter(ABC):
     - established_relations: prevents duplicate relations
     """
     
+    # ParseDeadline of the file being parsed, set by parse_with_limits
+    parse_deadline = None
+    # Error the last parse_file swallowed, read by parse_with_limits
+    parse_error = None
+    
     def __init__(self, language: str):
         self.language = language
         # Core data structures
@@ -58,7 +64,10 @@ class LanguageAdapter(ABC):
         
         Format: "{node_type}:{file_path}:{name}:{line_no}"
         This ensures compatibility with existing code and tests.
+        Checks the parse deadline first (see ast_parser/guards.py).
         """
+        if self.parse_deadline is not None:
+            self.parse_deadline.check()
         return f"{node_type}:{file_path}:{name}:{line_no}"
     
     def _create_file_node(self, file_path: str) -> str:
@@ -96,8 +105,20 @@ class LanguageAdapter(ABC):
             # Symbol import: include symbol name in key
             symbol = relation.properties.get("symbol", "")
             relation_key = f"{relation.source_id}|{relation.relation_type}|{relation.target_id}|{symbol}"
+        elif relation.relation_type == "USES":
+            # Variable use: one relation per kind of use
+            relation_key = f"{relation_key}|{relation.properties.get('usage', '')}"
         
         # Only add if not already present
         if relation_key not in self.established_relations:
             self.relations.append(relation)
             self.established_relations.add(relation_key)
+    
+    def _prev_named(self, node: Any) -> Optional[Any]:
+        """Previous named sibling, skipping anonymous tokens such as Go's newline terminators."""
+        return prev_named(node)
+    
+    def _leading_comments(self, node: Any, comment_kinds: Tuple[str, ...] = ("comment",),
+                          skip_kinds: Tuple[str, ...] = ("decorator",)) -> List[Any]:
+        """Comment nodes of the block directly above a declaration (see doc_comments.leading_comments)."""
+        return leading_comments(node, comment_kinds, skip_kinds)
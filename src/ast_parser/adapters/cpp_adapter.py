@@ -1,13 +1,16 @@
 """C++ language adapter using ast-grep for AST parsing."""
 
 import os
-from typing import Dict, List, Tuple, Optional
+from typing import Any, Dict, List, Tuple, Optional
 
 from ast_grep_py import SgRoot, SgNode
 
 from .base_adapter import LanguageAdapter
+from ast_parser.metrics import C_METRIC_RULES, syntax_metrics
 from ast_parser.parser import CodeNode, CodeRelation
 
+CPP_PARAMETERS = ("parameter_declaration", "optional_parameter_declaration", "variadic_parameter_declaration")
+
 
 class CppAdapter(LanguageAdapter):
     """
@@ -18,6 +21,8 @@ class CppAdapter(LanguageAdapter):
     - Field (Variable) nodes
     - CONTAINS, DEFINES relations
     - Include tracking (#include directives)
+    - Parameter count ("arity") and complexity metrics of functions and
+      methods (see metrics.py)
     
     Supports C++ source files (.cpp, .cc, .cxx, .hpp, .h).
     """
@@ -63,6 +68,7 @@ class CppAdapter(LanguageAdapter):
             
         except Exception as e:
             print(f"Error parsing C++ file {file_path}: {e}")
+            self.parse_error = e
             return {}, []
     
     def _parse_includes(self, root: SgNode, file_node_id: str) -> None:
@@ -152,6 +158,8 @@ class CppAdapter(LanguageAdapter):
                 name=method_name,
                 file_path=self.current_file,
                 line_no=line_no,
+                end_line_no=method_node.range().end.line + 1,
+                properties=self._function_properties(method_node, declarator),
             )
             
             # Add DEFINES relation from class to method
@@ -181,6 +189,8 @@ class CppAdapter(LanguageAdapter):
                     name=func_name,
                     file_path=self.current_file,
                     line_no=line_no,
+                    end_line_no=func_node.range().end.line + 1,
+                    properties=self._function_properties(func_node, declarator),
                 )
                 
                 # Add CONTAINS relation from file to function
@@ -209,3 +219,19 @@ class CppAdapter(LanguageAdapter):
                     return name
         
         return None
+    
+    def _function_properties(self, func_node: SgNode, declarator: SgNode) -> Dict[str, Any]:
+        """Parameter count and complexity metrics of a function definition."""
+        function_declarator = declarator if declarator.kind() == "function_declarator" else \
+            declarator.find(kind="function_declarator")
+        parameters = function_declarator.field("parameters") if function_declarator else None
+        arity = 0
+        for child in parameters.children() if parameters is not None else []:
+            # `f(void)` takes no parameters
+            if child.kind() in CPP_PARAMETERS and child.text().strip() != "void":
+                arity += 1
+        line_no = func_node.range().start.line + 1
+        return {
+            "arity": arity,
+            **syntax_metrics(func_node, C_METRIC_RULES, line_no, func_node.range().end.line + 1),
+        }
@@ -0,0 +1,724 @@
+"""C# language adapter using ast-grep for AST parsing."""
+
+import os
+import re
+from typing import Any, Dict, List, Tuple, Optional
+
+from ast_grep_py import SgRoot, SgNode
+
+from .base_adapter import LanguageAdapter
+from ast_parser.parser import CodeNode, CodeRelation
+from ast_parser.doc_comments import is_license_header, normalize_comment
+from ast_parser.metrics import CSHARP_METRIC_RULES, syntax_metrics
+from ast_parser.signatures import parameter, signature_properties
+
+
+# Type declarations -> (node type, "kind" property)
+CSHARP_TYPE_DECLARATIONS = {
+    "class_declaration": ("Class", "class"),
+    "struct_declaration": ("Class", "struct"),
+    "record_declaration": ("Class", "record"),
+    "record_struct_declaration": ("Class", "record struct"),
+    "interface_declaration": ("Interface", "interface"),
+    "enum_declaration": ("Enum", "enum"),
+}
+
+# Members that become Method nodes
+CSHARP_METHOD_DECLARATIONS = ("method_declaration", "constructor_declaration")
+
+CSHARP_NAMESPACES = ("namespace_declaration", "file_scoped_namespace_declaration")
+
+# Parameter modifiers that tell overloads apart
+PARAMETER_MODIFIERS = ("ref", "out", "in", "params", "this")
+
+# Whitespace around type punctuation, dropped when normalizing a type
+TYPE_PUNCTUATION_SPACE = re.compile(r"\s*([<>,\[\]?])\s*")
+
+# Innermost type argument list, removed repeatedly to drop all of them
+TYPE_ARGUMENTS = re.compile(r"<[^<>]*>")
+
+ACCESSOR_KEYWORD = re.compile(r"\b(get|set|init)\b")
+
+# `where T : class, new()` clause of a generic method
+TYPE_CONSTRAINT = re.compile(r"^where\s+(\w+)\s*:\s*(.+)$", re.DOTALL)
+
+# Tags wrapping the text of an XML doc comment
+XML_DOC_WRAPPER = re.compile(r"</?(summary|remarks)>")
+
+
+class CSharpAdapter(LanguageAdapter):
+    """
+    C# adapter using ast-grep library.
+    
+    Extracts C# structures:
+    - File, Class (classes, structs, records), Interface, Enum, Method and
+      ClassVariable (fields and properties) nodes
+    - CONTAINS, DEFINES relations; the base types of a type are resolved in
+      the second pass: EXTENDS to an indexed class (or, from an interface,
+      an indexed interface), IMPLEMENTS to an indexed interface, and
+      INHERITS_FROM to an ExternalType placeholder for a base that is not
+      indexed, since the base list does not tell classes and interfaces
+      apart; DECORATED_BY for attributes whose class is declared in the
+      codebase
+    - Import tracking: `using` directives, aliases (`using Json =
+      Newtonsoft.Json;`) and static usings become IMPORTS edges to the
+      imported namespace's Package, the aliased or static type, or an
+      ExternalPackage placeholder
+    
+    Namespaces, block and file-scoped, play the part of Java packages: the
+    File node records its first namespace in "package" and "import_path",
+    and all of them in "namespaces" when it declares several, so it belongs
+    to the Package node of each. Types are indexed per namespace (see
+    package_key) by their name, nested types as "Outer+Inner". A simple
+    type name is looked up in the enclosing types, the enclosing namespaces
+    and then the namespaces of the file's usings, which apply to the whole
+    file.
+    
+    A `partial` type records the file of each part in "declared_in"; the
+    second pass merges its parts into one node (see
+    ASTParser._merge_partial_types), so the methods of every part hang off
+    a single Class node.
+    
+    Methods record an overload-distinguishing "signature" built from the
+    parameter types ("Add(int,ref string)") and their complexity metrics
+    (see metrics.py). Properties are ClassVariable nodes with "property",
+    "getter" and "setter" (and "init") flags, "auto" for auto-implemented
+    ones. Attributes are kept as texts in "attributes", modifiers in
+    "modifiers", `///` XML doc comments in "doc".
+    
+    Supports C# source files (.cs).
+    """
+    
+    def __init__(self):
+        super().__init__("csharp")
+        self.current_file: str = ""
+        self.current_namespace: str = ""
+        self.current_package_key: str = ""
+        # Namespaces the file declares, in source order
+        self.file_namespaces: List[str] = []
+        # Namespaces of the file's `using` directives
+        self.using_namespaces: List[str] = []
+        # Alias -> aliased namespace or type, as written
+        self.using_aliases: Dict[str, str] = {}
+    
+    @staticmethod
+    def package_key(namespace: str) -> str:
+        """
+        Build the module_definitions key for a C# namespace.
+        
+        The global namespace is shared by the whole codebase, like it is
+        by an assembly. The "csharp:" prefix keeps the key from colliding
+        with file-level module names used by the other adapters.
+        """
+        return f"csharp:{namespace}"
+    
+    def parse_file(self, file_path: str, build_index: bool = False) -> Tuple[Dict[str, CodeNode], List[CodeRelation]]:
+        """
+        Parse a C# file using ast-grep.
+        
+        Extracts the usings and the namespaces with their type
+        declarations, methods, fields and properties.
+        """
+        self.current_file = file_path
+        self.current_namespace = ""
+        self.current_package_key = self.package_key("")
+        self.file_namespaces = []
+        self.using_namespaces = []
+        self.using_aliases = {}
+        
+        try:
+            # Read source code
+            with open(file_path, "r", encoding="utf-8") as f:
+                source = f.read()
+            
+            # Parse with ast-grep (C# language)
+            root = SgRoot(source, "csharp").root()
+            
+            # Create file node
+            file_node_id = self._create_file_node(file_path)
+            
+            # Generate module name for indexing
+            module_name = os.path.splitext(os.path.basename(file_path))[0]
+            if build_index:
+                if module_name not in self.module_definitions:
+                    self.module_definitions[module_name] = {}
+                self.module_to_file[module_name] = file_node_id
+                self.module_definitions.setdefault(self.current_package_key, {})
+            
+            # Extract C# structures
+            self._parse_file_comments(root, file_node_id)
+            self._parse_usings(root, file_node_id)
+            self._parse_declarations(root, "", file_node_id, build_index, module_name)
+            
+            if self.file_namespaces:
+                self.nodes[file_node_id].properties["package"] = self.file_namespaces[0]
+                self.nodes[file_node_id].properties["import_path"] = self.file_namespaces[0]
+            if len(self.file_namespaces) > 1:
+                self.nodes[file_node_id].properties["namespaces"] = self.file_namespaces
+            
+            return self.nodes, self.relations
+        
+        except Exception as e:
+            print(f"Error parsing C# file {file_path}: {e}")
+            self.parse_error = e
+            return {}, []
+    
+    def _parse_file_comments(self, root: SgNode, file_node_id: str) -> None:
+        """Attach the license header opening the file to the File node ("header")."""
+        first = next((c for c in root.children() if c.kind() != "comment"), None)
+        header = [
+            c.text() for c in root.children()
+            if c.kind() == "comment" and is_license_header(c.text())
+            and (first is None or c.range().end.line < first.range().start.line)
+        ]
+        if header:
+            self.nodes[file_node_id].properties["header"] = normalize_comment("\n".join(header))
+    
+    def _attach_doc(self, node_id: str, declaration: SgNode) -> None:
+        """Store the `///` XML doc comment directly above a declaration on its node."""
+        comments = self._leading_comments(declaration, ("comment",))
+        lines = [XML_DOC_WRAPPER.sub("", c.text()) for c in comments if c.text().startswith("///")]
+        doc = normalize_comment("\n".join(lines))
+        if doc:
+            self.nodes[node_id].properties["doc"] = doc
+    
+    def _parse_usings(self, root: SgNode, file_node_id: str) -> None:
+        """
+        Extract using directives.
+        
+        `using A.B;` imports the namespace A.B (IMPORTS_MODULE); an alias
+        (`using J = A.B;`) and `using static A.B.C;` import a symbol
+        (IMPORTS_SYMBOL) that the second pass links to the type, else to
+        the namespace. `global using` directives are file-wide like the
+        others.
+        """
+        for using in root.find_all(kind="using_directive"):
+            text = " ".join(using.text().split()).rstrip(";").strip()
+            words = text.split(" ")
+            is_global = words[:1] == ["global"]
+            if is_global:
+                words = words[1:]
+            words = words[1:]
+            is_static = words[:1] == ["static"]
+            if is_static:
+                words = words[1:]
+            written = "".join(words)
+            alias = None
+            if "=" in written:
+                alias, written = written.split("=", 1)
+            written = written.replace("global::", "")
+            if not written:
+                continue
+            
+            name = written
+            while TYPE_ARGUMENTS.search(name):
+                name = TYPE_ARGUMENTS.sub("", name)
+            namespace, _, type_name = name.rpartition(".")
+            pending: Dict[str, Any] = {
+                "source_id": file_node_id,
+                "full_module_path": written,
+                "line_no": using.range().start.line + 1,
+            }
+            if is_global:
+                pending["global"] = True
+            if alias is not None:
+                # The target names a namespace or a type: the namespace's
+                # Package when it is indexed, else the type (see _add_symbol_import)
+                self.using_aliases[alias] = name
+                pending.update(type="IMPORTS_SYMBOL", imported_module=self.package_key(namespace),
+                               imported_name=type_name, import_path=name, package_key=self.package_key(name),
+                               alias=alias)
+            elif is_static:
+                pending.update(type="IMPORTS_SYMBOL", imported_module=self.package_key(namespace),
+                               imported_name=type_name, import_path=namespace,
+                               package_key=self.package_key(namespace), static=True)
+            else:
+                self.using_namespaces.append(name)
+                pending.update(type="IMPORTS_MODULE", imported_module=self.package_key(name), import_path=name,
+                               package_key=self.package_key(name))
+            self.pending_imports.append(pending)
+    
+    def _parse_declarations(self, parent: SgNode, namespace: str, file_node_id: str,
+                            build_index: bool, module_name: str) -> None:
+        """
+        Extract the namespaces and type declarations below a compilation unit or namespace body.
+        
+        A file-scoped namespace (`namespace A.B;`) holds the declarations
+        after it, whether the grammar nests them in the namespace node or
+        leaves them as its siblings.
+        """
+        for child in parent.children():
+            kind = child.kind()
+            if kind in CSHARP_NAMESPACES:
+                name_node = child.field("name")
+                if name_node is None:
+                    continue
+                inner = ".".join(part for part in (namespace, "".join(name_node.text().split())) if part)
+                if inner not in self.file_namespaces:
+                    self.file_namespaces.append(inner)
+                if build_index:
+                    self.module_definitions.setdefault(self.package_key(inner), {})
+                if kind == "file_scoped_namespace_declaration":
+                    namespace = inner
+                    self._parse_declarations(child, namespace, file_node_id, build_index, module_name)
+                else:
+                    body = self._body(child)
+                    if body is not None:
+                        self._parse_declarations(body, inner, file_node_id, build_index, module_name)
+            elif kind in CSHARP_TYPE_DECLARATIONS:
+                name_node = child.field("name")
+                if name_node is not None:
+                    self._enter_namespace(namespace)
+                    self._parse_type(child, name_node.text(), file_node_id, build_index, module_name)
+    
+    def _enter_namespace(self, namespace: str) -> None:
+        self.current_namespace = namespace
+        self.current_package_key = self.package_key(namespace)
+    
+    def _type_candidates(self, type_text: str, enclosing: Optional[str]) -> List[List[str]]:
+        """
+        (namespace key, type name) pairs a type reference may denote, in lookup order.
+        
+        Type arguments, nullable markers and array brackets are dropped.
+        A name is looked up as a type nested in the enclosing types, then
+        in the current namespace and the namespaces around it, then in the
+        namespaces of the usings; a qualified name tries every split into
+        namespace and (nested) type, relative to these namespaces too. The
+        second pass takes the first pair that is indexed.
+        """
+        name = "".join(type_text.split()).replace("global::", "")
+        while TYPE_ARGUMENTS.search(name):
+            name = TYPE_ARGUMENTS.sub("", name)
+        name = name.rstrip("[]?,")
+        parts = [part for part in name.split(".") if part]
+        if not parts:
+            return []
+        if parts[0] in self.using_aliases:
+            parts = self.using_aliases[parts[0]].split(".") + parts[1:]
+        
+        candidates: List[List[str]] = []
+        scope = enclosing
+        while scope:
+            candidates.append([self.current_package_key, f"{scope}+{'+'.join(parts)}"])
+            scope = scope.rpartition("+")[0]
+        
+        namespaces = []
+        namespace = self.current_namespace
+        while namespace:
+            namespaces.append(namespace)
+            namespace = namespace.rpartition(".")[0]
+        namespaces.append("")
+        namespaces.extend(self.using_namespaces)
+        for namespace in dict.fromkeys(namespaces):
+            for split in range(len(parts) - 1, -1, -1):
+                prefix = ".".join(part for part in [namespace] + parts[:split] if part)
+                candidates.append([self.package_key(prefix), "+".join(parts[split:])])
+        
+        unique: List[List[str]] = []
+        for candidate in candidates:
+            if candidate not in unique:
+                unique.append(candidate)
+        return unique
+    
+    def _queue_type_reference(self, relation_type: str, source_id: str, type_text: str,
+                              enclosing: Optional[str], **extra: Any) -> None:
+        """Queue a BASE_TYPE or DECORATED_BY relation to a type name."""
+        candidates = self._type_candidates(type_text, enclosing)
+        if not candidates:
+            return
+        pending = {
+            "type": relation_type,
+            "source_id": source_id,
+            "imported_module": candidates[0][0],
+            "imported_name": candidates[0][1],
+            "original_name": " ".join(type_text.split()),
+        }
+        if len(candidates) > 1:
+            pending["candidates"] = candidates
+        pending.update(extra)
+        self.pending_imports.append(pending)
+    
+    def _normalize_type(self, type_node: Optional[SgNode]) -> str:
+        """Type text with whitespace collapsed ("Dictionary<string, List<T>>" -> "Dictionary<string,List<T>>")."""
+        if type_node is None:
+            return ""
+        return TYPE_PUNCTUATION_SPACE.sub(r"\1", " ".join(type_node.text().split()))
+    
+    def _parse_modifiers(self, declaration: SgNode, node_id: str, enclosing: Optional[str]) -> None:
+        """
+        Record the modifiers and attributes of a declaration on its node.
+        
+        Attributes are kept as written, without the brackets; each one
+        also queues a DECORATED_BY relation, made when its class (`Name`
+        or `NameAttribute`) is indexed.
+        """
+        keywords: List[str] = []
+        attributes: List[str] = []
+        for child in declaration.children():
+            if child.kind() == "modifier":
+                keywords.append(child.text())
+            elif child.kind() == "attribute_list":
+                for attribute in child.children():
+                    if attribute.kind() != "attribute":
+                        continue
+                    text = " ".join(attribute.text().split())
+                    attributes.append(text)
+                    name_node = attribute.field("name")
+                    if name_node is None:
+                        continue
+                    name = name_node.text()
+                    line_no = attribute.range().start.line + 1
+                    candidates = self._type_candidates(name, enclosing)
+                    if not name.endswith("Attribute"):
+                        candidates += self._type_candidates(f"{name}Attribute", enclosing)
+                    if candidates:
+                        pending = {
+                            "type": "DECORATED_BY",
+                            "source_id": node_id,
+                            "imported_module": candidates[0][0],
+                            "imported_name": candidates[0][1],
+                            "decorator": text,
+                            "line_no": line_no,
+                        }
+                        if len(candidates) > 1:
+                            pending["candidates"] = candidates
+                        self.pending_imports.append(pending)
+        
+        properties = self.nodes[node_id].properties
+        if keywords:
+            properties["modifiers"] = keywords
+        if attributes:
+            properties["attributes"] = attributes
+    
+    def _add_type(self, node: SgNode, node_type: str, binary_name: str, properties: Dict[str, Any],
+                  parent_id: str, build_index: bool, module_name: str) -> str:
+        """
+        Create a type node and link it to its file (CONTAINS) or enclosing type (DEFINES).
+        
+        Returns the type node ID.
+        """
+        line_no = node.range().start.line + 1
+        node_id = self._get_node_id(node_type, binary_name, self.current_file, line_no)
+        properties["qualified_name"] = (
+            f"{self.current_namespace}.{binary_name}" if self.current_namespace else binary_name
+        )
+        self.nodes[node_id] = CodeNode(
+            node_id=node_id,
+            node_type=node_type,
+            name=binary_name,
+            file_path=self.current_file,
+            line_no=line_no,
+            end_line_no=node.range().end.line + 1,
+            properties=properties,
+        )
+        relation_type = "CONTAINS" if parent_id == f"file:{self.current_file}" else "DEFINES"
+        self._add_relation(CodeRelation(parent_id, node_id, relation_type))
+        
+        # Index the type for cross-file resolution
+        if build_index:
+            self.module_definitions[module_name][binary_name] = node_id
+            self.module_definitions[self.current_package_key][binary_name] = node_id
+        return node_id
+    
+    def _parse_type(self, declaration: SgNode, binary_name: str, parent_id: str,
+                    build_index: bool, module_name: str) -> str:
+        """Extract a class, struct, record, interface or enum with its members; returns its node ID."""
+        node_type, kind = CSHARP_TYPE_DECLARATIONS[declaration.kind()]
+        if kind == "record" and any(c.text() == "struct" for c in declaration.children() if not c.is_named()):
+            kind = "record struct"
+        type_id = self._add_type(declaration, node_type, binary_name, {"kind": kind},
+                                 parent_id, build_index, module_name)
+        self._attach_doc(type_id, declaration)
+        # Attributes and bases of a type are names of its enclosing scope
+        enclosing = binary_name.rpartition("+")[0] or None
+        self._parse_modifiers(declaration, type_id, enclosing)
+        self._parse_bases(declaration, type_id, enclosing)
+        
+        properties = self.nodes[type_id].properties
+        if "partial" in properties.get("modifiers", []):
+            properties["declared_in"] = [self.current_file]
+            self.pending_imports.append({
+                "type": "PARTIAL_TYPE",
+                "source_id": type_id,
+                "imported_module": self.current_package_key,
+                "imported_name": binary_name,
+            })
+        
+        # Positional record parameters are properties
+        parameters = declaration.field("parameters")
+        if parameters is None:
+            parameters = next((c for c in declaration.children() if c.kind() == "parameter_list"), None)
+        for record_parameter in (parameters.children() if parameters is not None else []):
+            name_node = record_parameter.field("name") if record_parameter.kind() == "parameter" else None
+            if name_node is not None:
+                self._add_field(record_parameter, name_node.text(),
+                                self._normalize_type(record_parameter.field("type")), type_id,
+                                {"property": True, "getter": True, "setter": False, "init": True, "auto": True,
+                                 "component": True})
+        
+        body = self._body(declaration)
+        if body is not None:
+            self._parse_members(body, type_id, binary_name, build_index, module_name)
+        return type_id
+    
+    def _body(self, declaration: SgNode) -> Optional[SgNode]:
+        """Declaration list of a namespace or type, enum member list of an enum."""
+        body = declaration.field("body")
+        if body is None:
+            body = next((c for c in declaration.children()
+                         if c.kind() in ("declaration_list", "enum_member_declaration_list")), None)
+        return body
+    
+    def _parse_bases(self, declaration: SgNode, type_id: str, enclosing: Optional[str]) -> None:
+        """
+        Queue a BASE_TYPE relation for each entry of the base list of a type.
+        
+        The names are kept as written in "bases"; whether each is a class
+        or an interface is only known once the second pass finds it.
+        Enum base types (`enum Color : byte`) are not bases.
+        """
+        base_list = declaration.field("bases")
+        if base_list is None:
+            base_list = next((c for c in declaration.children() if c.kind() == "base_list"), None)
+        if base_list is None or declaration.kind() == "enum_declaration":
+            return
+        names = []
+        for base in base_list.children():
+            if not base.is_named() or base.kind() == "comment":
+                continue
+            if base.kind() == "primary_constructor_base_type":
+                # record R(int X) : Base(X)
+                base = next((c for c in base.children() if c.is_named() and c.kind() != "argument_list"), base)
+            elif base.kind() == "argument_list":
+                continue
+            names.append(self._normalize_type(base))
+        if not names:
+            return
+        self.nodes[type_id].properties["bases"] = names
+        for name in names:
+            # Placeholder name of a base that is not indexed, without its type arguments
+            external_name = name
+            while TYPE_ARGUMENTS.search(external_name):
+                external_name = TYPE_ARGUMENTS.sub("", external_name)
+            self._queue_type_reference("BASE_TYPE", type_id, name, enclosing, external_name=external_name)
+    
+    def _parse_members(self, body: SgNode, type_id: str, binary_name: str,
+                       build_index: bool, module_name: str) -> None:
+        """
+        Extract the members of a type body.
+        
+        Interfaces also record their method signatures in "methods" and
+        enums their members in "members".
+        """
+        type_node = self.nodes[type_id]
+        for member in body.children():
+            kind = member.kind()
+            if kind in CSHARP_TYPE_DECLARATIONS:
+                name_node = member.field("name")
+                if name_node is not None:
+                    self._parse_type(member, f"{binary_name}+{name_node.text()}", type_id, build_index, module_name)
+            elif kind in CSHARP_METHOD_DECLARATIONS:
+                signature = self._parse_method(member, type_id, binary_name, build_index)
+                if signature and type_node.node_type == "Interface":
+                    type_node.properties.setdefault("methods", []).append(signature)
+            elif kind == "field_declaration":
+                self._parse_field(member, type_id, binary_name)
+            elif kind == "property_declaration":
+                self._parse_property(member, type_id, binary_name, type_node.node_type == "Interface")
+            elif kind == "enum_member_declaration":
+                name_node = member.field("name")
+                if name_node is not None:
+                    type_node.properties.setdefault("members", []).append(name_node.text())
+    
+    def _parse_method(self, method: SgNode, type_id: str, binary_name: str, build_index: bool) -> Optional[str]:
+        """
+        Extract a method or constructor and link it to its type with DEFINES.
+        
+        Returns the method signature, None for constructors.
+        """
+        name_node = method.field("name")
+        if name_node is None:
+            return None
+        
+        method_name = name_node.text()
+        line_no = method.range().start.line + 1
+        parameters = method.field("parameters")
+        entries = self._parameters(parameters)
+        signature = f"{method_name}({','.join(entry['signature_type'] for entry in entries)})"
+        
+        properties: Dict[str, Any] = {"signature": signature}
+        return_type = None
+        if method.kind() == "constructor_declaration":
+            properties["constructor"] = True
+        else:
+            return_type = self._normalize_type(method.field("returns") or method.field("type")) or None
+            if return_type:
+                properties["return_type"] = return_type
+        properties.update(signature_properties(
+            [parameter(entry["name"], entry["type"], entry["default"], variadic=entry["variadic"],
+                       **({"modifier": entry["modifier"]} if entry["modifier"] else {}))
+             for entry in entries],
+            [{"name": None, "type": return_type}] if return_type and return_type != "void" else [],
+            self._type_parameters(method),
+        ))
+        properties.update(syntax_metrics(method, CSHARP_METRIC_RULES, line_no, method.range().end.line + 1))
+        
+        # Create method node
+        method_node_id = self._get_node_id("Method", method_name, self.current_file, line_no)
+        self.nodes[method_node_id] = CodeNode(
+            node_id=method_node_id,
+            node_type="Method",
+            name=method_name,
+            file_path=self.current_file,
+            line_no=line_no,
+            end_line_no=method.range().end.line + 1,
+            properties=properties,
+        )
+        self._attach_doc(method_node_id, method)
+        self._parse_modifiers(method, method_node_id, binary_name)
+        
+        # Add DEFINES relation from type to method
+        self._add_relation(CodeRelation(type_id, method_node_id, "DEFINES"))
+        
+        # Overloads are told apart by signature, the plain name finds the first one
+        if build_index:
+            self.module_definitions[self.current_package_key][f"{binary_name}.{signature}"] = method_node_id
+            self.module_definitions[self.current_package_key].setdefault(f"{binary_name}.{method_name}",
+                                                                         method_node_id)
+        return None if properties.get("constructor") else signature
+    
+    def _parameters(self, parameters: Optional[SgNode]) -> List[Dict[str, Any]]:
+        """
+        Entries of a parameter list: name, type, default, modifier and variadic (`params`).
+        
+        "signature_type" is the type as it goes into the method signature,
+        with a `ref`, `out` or `in` modifier in front ("ref int").
+        """
+        entries: List[Dict[str, Any]] = []
+        for parameter_node in parameters.children() if parameters is not None else []:
+            if parameter_node.kind() not in ("parameter", "parameter_array"):
+                continue
+            children = parameter_node.children()
+            name = parameter_node.field("name")
+            type_name = self._normalize_type(parameter_node.field("type"))
+            if parameter_node.kind() == "parameter_array":
+                modifier = "params"
+            else:
+                modifier = next((c.text() for c in children if c.text() in PARAMETER_MODIFIERS
+                                 and c.kind() != "identifier"), None)
+            # `= value`, wrapped in an equals_value_clause by older grammars
+            default = next((c.text().lstrip("=").strip() for c in children if c.kind() == "equals_value_clause"), None)
+            equals = next((index for index, c in enumerate(children) if c.text() == "=" and not c.is_named()), None)
+            if default is None and equals is not None and equals + 1 < len(children):
+                default = children[equals + 1].text()
+            entries.append({
+                "name": name.text() if name is not None else None,
+                "type": type_name,
+                "default": default,
+                "modifier": modifier,
+                "variadic": modifier == "params",
+                "signature_type": f"{modifier} {type_name}" if modifier in ("ref", "out", "in") else type_name,
+            })
+        return entries
+    
+    def _type_parameters(self, method: SgNode) -> List[Dict[str, Any]]:
+        """Type parameters of a generic method with their `where` constraints."""
+        type_parameter_list = method.field("type_parameters")
+        if type_parameter_list is None:
+            return []
+        constraints: Dict[str, str] = {}
+        for clause in method.children():
+            if clause.kind() == "type_parameter_constraints_clause":
+                match = TYPE_CONSTRAINT.match(" ".join(clause.text().split()))
+                if match:
+                    constraints[match.group(1)] = match.group(2)
+        type_parameters = []
+        for type_parameter in type_parameter_list.children():
+            if type_parameter.kind() != "type_parameter":
+                continue
+            name = type_parameter.field("name")
+            if name is None:
+                name = next((c for c in type_parameter.children() if c.kind() == "identifier"), None)
+            if name is not None:
+                type_parameters.append({"name": name.text(), "constraint": constraints.get(name.text())})
+        return type_parameters
+    
+    def _parse_field(self, field_node: SgNode, type_id: str, binary_name: str) -> None:
+        """Extract a field declaration, one ClassVariable per declarator."""
+        declaration = next((c for c in field_node.children() if c.kind() == "variable_declaration"), None)
+        if declaration is None:
+            return
+        field_type = self._normalize_type(declaration.field("type"))
+        for declarator in declaration.children():
+            if declarator.kind() != "variable_declarator":
+                continue
+            name_node = declarator.field("name")
+            if name_node is None:
+                name_node = next((c for c in declarator.children() if c.kind() == "identifier"), None)
+            if name_node is None:
+                continue
+            var_node_id = self._add_field(declarator, name_node.text(), field_type, type_id, {})
+            self._attach_doc(var_node_id, field_node)
+            self._parse_modifiers(field_node, var_node_id, binary_name)
+    
+    def _parse_property(self, property_node: SgNode, type_id: str, binary_name: str, in_interface: bool) -> None:
+        """
+        Extract a property as a ClassVariable with its accessors.
+        
+        "getter", "setter" and "init" tell which accessors it has; an
+        expression-bodied property (`int X => 1;`) only has a getter.
+        "auto" marks auto-implemented properties, whose accessors have no
+        bodies; abstract and interface properties have none either, but
+        are not auto-implemented.
+        """
+        name_node = property_node.field("name")
+        if name_node is None:
+            return
+        accessors = property_node.field("accessors")
+        if accessors is None:
+            accessors = next((c for c in property_node.children() if c.kind() == "accessor_list"), None)
+        
+        keywords = set()
+        modifiers = {c.text() for c in property_node.children() if c.kind() == "modifier"}
+        auto = accessors is not None and not in_interface and not modifiers & {"abstract", "extern"}
+        for accessor in (accessors.children() if accessors is not None else []):
+            if accessor.kind() != "accessor_declaration":
+                continue
+            head = re.sub(r"\[[^\]]*\]", "", accessor.text()).split("{", 1)[0].split("=>", 1)[0]
+            keywords.update(ACCESSOR_KEYWORD.findall(head))
+            if "{" in accessor.text() or "=>" in accessor.text():
+                auto = False
+        
+        properties: Dict[str, Any] = {
+            "property": True,
+            "getter": "get" in keywords or accessors is None,
+            "setter": "set" in keywords,
+        }
+        if "init" in keywords:
+            properties["init"] = True
+        if auto:
+            properties["auto"] = True
+        var_node_id = self._add_field(property_node, name_node.text(),
+                                      self._normalize_type(property_node.field("type")), type_id, properties)
+        self._attach_doc(var_node_id, property_node)
+        self._parse_modifiers(property_node, var_node_id, binary_name)
+    
+    def _add_field(self, node: SgNode, name: str, field_type: str, type_id: str,
+                   properties: Dict[str, Any]) -> str:
+        """Create a ClassVariable node defined by a type; returns its ID."""
+        line_no = node.range().start.line + 1
+        if field_type:
+            properties["type"] = field_type
+        var_node_id = self._get_node_id("Variable", name, self.current_file, line_no)
+        self.nodes[var_node_id] = CodeNode(
+            node_id=var_node_id,
+            node_type="ClassVariable",
+            name=name,
+            file_path=self.current_file,
+            line_no=line_no,
+            properties=properties,
+        )
+        
+        # Add DEFINES relation from class to field
+        self._add_relation(CodeRelation(type_id, var_node_id, "DEFINES"))
+        return var_node_id
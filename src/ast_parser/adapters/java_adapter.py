@@ -1,40 +1,134 @@
 """Java language adapter using ast-grep for AST parsing."""
 
 import os
-from typing import Dict, List, Tuple, Optional
+import re
+from typing import Any, Dict, List, Tuple, Optional
 
 from ast_grep_py import SgRoot, SgNode
 
 from .base_adapter import LanguageAdapter
 from ast_parser.parser import CodeNode, CodeRelation
+from ast_parser.doc_comments import is_jsdoc, is_license_header, normalize_comment
+from ast_parser.metrics import JAVA_METRIC_RULES, syntax_metrics
+from ast_parser.signatures import parameter, signature_properties
+
+
+# Type declarations -> (node type, "kind" property)
+JAVA_TYPE_DECLARATIONS = {
+    "class_declaration": ("Class", "class"),
+    "record_declaration": ("Class", "record"),
+    "interface_declaration": ("Interface", "interface"),
+    "annotation_type_declaration": ("Interface", "annotation"),
+    "enum_declaration": ("Enum", "enum"),
+}
+
+# Members that become Method nodes
+JAVA_METHOD_DECLARATIONS = (
+    "method_declaration", "constructor_declaration", "compact_constructor_declaration",
+    "annotation_type_element_declaration",
+)
+
+JAVA_CONSTRUCTORS = ("constructor_declaration", "compact_constructor_declaration")
+
+JAVA_COMMENT_KINDS = ("line_comment", "block_comment", "comment")
+
+JAVA_ANNOTATIONS = ("marker_annotation", "annotation")
+
+# Whitespace around type punctuation, dropped when normalizing a type
+TYPE_PUNCTUATION_SPACE = re.compile(r"\s*([<>,\[\]])\s*")
 
 
 class JavaAdapter(LanguageAdapter):
     """
     Java adapter using ast-grep library.
     
-    Extracts minimal Java structures for proof of concept:
-    - File, Class, Method nodes
-    - Field (Variable) nodes
-    - CONTAINS, DEFINES relations
-    - Import tracking for cross-file dependency resolution
+    Extracts Java structures:
+    - File, Class (classes, records, anonymous classes), Interface
+      (interfaces, annotation types), Enum, Method and ClassVariable nodes
+    - CONTAINS, DEFINES relations; EXTENDS and IMPLEMENTS for the extends
+      and implements clauses; DECORATED_BY for annotations whose type is
+      declared in the codebase
+    - Import tracking (single-type, wildcard and static imports); the
+      second pass links each file to the imported type, its Package, or an
+      ExternalPackage placeholder
+    
+    Files belong to the package named by their `package` declaration, not
+    their directory: the File node records it in "package" and
+    "import_path", and types are indexed per package (see package_key) by
+    their binary name, so nested and anonymous classes are "Outer$Inner"
+    and "Outer$1". Nested types hang off their enclosing type with DEFINES.
+    A reference to a simple type name is looked up like javac does: types
+    of the file, single-type imports, the package, then wildcard imports.
+    
+    Methods record an overload-distinguishing "signature" built from the
+    parameter types ("setName(String,String)") and their complexity
+    metrics (see metrics.py). Annotations are kept as texts in
+    "annotations", modifiers in "modifiers", Javadoc in "doc".
     
     Supports Java source files (.java).
     """
     
+    # Comment kinds and package declaration of the grammar (the Kotlin adapter has its own)
+    COMMENT_KINDS: Tuple[str, ...] = JAVA_COMMENT_KINDS
+    PACKAGE_DECLARATION = "package_declaration"
+    
     def __init__(self):
         super().__init__("java")
         self.current_file: str = ""
-        self.current_class: Optional[str] = None
+        self.current_package: str = ""
+        self.current_package_key: str = ""
+        # Simple name -> binary name of the types declared in the current file
+        self.file_types: Dict[str, str] = {}
+        # Simple name -> (package key, binary name) of single-type imports
+        self.single_imports: Dict[str, Tuple[str, str]] = {}
+        # (package key, binary name prefix) of wildcard imports
+        self.wildcard_imports: List[Tuple[str, str]] = []
+        # Enclosing binary name -> number of anonymous classes so far
+        self.anonymous_counts: Dict[str, int] = {}
+        # (enclosing binary name, simple name) -> number of local classes so far
+        self.local_counts: Dict[Tuple[str, str], int] = {}
+    
+    @staticmethod
+    def package_key(file_path: str, package_name: str) -> str:
+        """
+        Build the module_definitions key for a Java package.
+        
+        Files without a package declaration share the unnamed package of
+        their directory. The "java:" prefix keeps the key from colliding
+        with file-level module names used by the other adapters.
+        """
+        if package_name:
+            return f"java:{package_name}"
+        return f"java:{os.path.dirname(file_path)}:"
+    
+    @staticmethod
+    def split_qualified_name(name: str) -> Tuple[str, str]:
+        """
+        Split a dotted name into its package and binary type name.
+        
+        Packages are lowercase and types capitalized by convention, so the
+        first capitalized segment starts the type:
+        "com.example.Outer.Inner" -> ("com.example", "Outer$Inner").
+        """
+        parts = name.split(".")
+        for index, part in enumerate(parts):
+            if part[:1].isupper():
+                return ".".join(parts[:index]), "$".join(parts[index:])
+        return name, ""
     
     def parse_file(self, file_path: str, build_index: bool = False) -> Tuple[Dict[str, CodeNode], List[CodeRelation]]:
         """
         Parse a Java file using ast-grep.
         
-        Extracts classes, methods, fields, and imports.
+        Extracts the package, imports and type declarations with their
+        methods, fields, nested and anonymous classes.
         """
         self.current_file = file_path
-        self.current_class = None
+        self.file_types = {}
+        self.single_imports = {}
+        self.wildcard_imports = []
+        self.anonymous_counts = {}
+        self.local_counts = {}
         
         try:
             # Read source code
@@ -54,128 +148,534 @@ class JavaAdapter(LanguageAdapter):
                     self.module_definitions[module_name] = {}
                 self.module_to_file[module_name] = file_node_id
             
+            # Package-level index shared by all files of the package
+            self.current_package = self._get_package_name(root)
+            self.current_package_key = self.package_key(file_path, self.current_package)
+            if self.current_package:
+                self.nodes[file_node_id].properties["package"] = self.current_package
+                self.nodes[file_node_id].properties["import_path"] = self.current_package
+            if build_index:
+                self.module_definitions.setdefault(self.current_package_key, {})
+            
             # Extract Java structures
+            self._parse_file_comments(root, file_node_id)
+            self._collect_type_names(root, None)
             self._parse_imports(root, file_node_id)
-            self._parse_classes(root, file_node_id, build_index, module_name)
+            for child in root.children():
+                name_node = child.field("name") if child.kind() in JAVA_TYPE_DECLARATIONS else None
+                if name_node is not None:
+                    self._parse_type(child, name_node.text(), file_node_id, build_index, module_name)
             
             return self.nodes, self.relations
-            
+        
         except Exception as e:
             print(f"Error parsing Java file {file_path}: {e}")
+            self.parse_error = e
             return {}, []
     
+    def _get_package_name(self, root: SgNode) -> str:
+        """Return the name from the file's package declaration ("" for the unnamed package)."""
+        for declaration in root.children():
+            if declaration.kind() != "package_declaration":
+                continue
+            for child in declaration.children():
+                if child.kind() in ("scoped_identifier", "identifier"):
+                    return "".join(child.text().split())
+        return ""
+    
+    def _parse_file_comments(self, root: SgNode, file_node_id: str) -> None:
+        """
+        Attach the comments opening the file to the File node.
+        
+        License headers become "header"; a Javadoc block directly above the
+        package declaration (package-info.java) is the package doc ("doc").
+        """
+        first = next((c for c in root.children() if c.kind() not in self.COMMENT_KINDS), None)
+        header = [
+            c.text() for c in root.children()
+            if c.kind() in self.COMMENT_KINDS and is_license_header(c.text())
+            and (first is None or c.range().end.line < first.range().start.line)
+        ]
+        if header:
+            self.nodes[file_node_id].properties["header"] = normalize_comment("\n".join(header))
+        if first is not None and first.kind() == self.PACKAGE_DECLARATION:
+            self._attach_javadoc(file_node_id, first)
+    
+    def _attach_javadoc(self, node_id: str, declaration: SgNode) -> None:
+        """Store the Javadoc block directly above a declaration on its node; `//` comments are ignored."""
+        comments = self._leading_comments(declaration, self.COMMENT_KINDS)
+        if not comments or not is_jsdoc(comments[-1].text()):
+            return
+        doc = normalize_comment(comments[-1].text())
+        if doc:
+            self.nodes[node_id].properties["doc"] = doc
+    
+    def _body_members(self, body: SgNode) -> List[SgNode]:
+        """Member declarations of a class, interface, enum or annotation body."""
+        members: List[SgNode] = []
+        for child in body.children():
+            if child.kind() == "enum_body_declarations":
+                members.extend(c for c in child.children() if c.is_named())
+            elif child.is_named():
+                members.append(child)
+        return members
+    
+    def _collect_type_names(self, parent: SgNode, enclosing: Optional[str]) -> None:
+        """
+        Map the simple names of the file's member types to their binary names.
+        
+        Collected before anything is parsed, since a type may be referenced
+        above its declaration. Top-level names win over nested ones.
+        """
+        declarations = parent.children() if enclosing is None else self._body_members(parent)
+        for declaration in declarations:
+            if declaration.kind() not in JAVA_TYPE_DECLARATIONS:
+                continue
+            name_node = declaration.field("name")
+            if name_node is None:
+                continue
+            binary_name = f"{enclosing}${name_node.text()}" if enclosing else name_node.text()
+            self.file_types.setdefault(name_node.text(), binary_name)
+            body = declaration.field("body")
+            if body is not None:
+                self._collect_type_names(body, binary_name)
+    
     def _parse_imports(self, root: SgNode, file_node_id: str) -> None:
-        """Extract import declarations."""
-        # Find all import_declaration nodes
+        """
+        Extract import declarations.
+        
+        `import a.b.C;` imports the type C (IMPORTS_SYMBOL), `import a.b.*;`
+        the package a.b (IMPORTS_MODULE) and `import a.b.C.*;` the nested
+        types of C. Static imports are tracked by the type whose members
+        they import.
+        """
         for import_node in root.find_all(kind="import_declaration"):
-            # Get the imported name (could be wildcard import or specific class)
-            # import_declaration contains either scoped_identifier or identifier
-            import_text = import_node.text().strip()
-            if import_text.startswith("import "):
-                import_text = import_text[7:].strip()
-                if import_text.endswith(";"):
-                    import_text = import_text[:-1].strip()
-                
-                # Extract module for tracking
-                parts = import_text.split(".")
-                if len(parts) > 0:
-                    root_module = parts[0]
-                    self.pending_imports.append({
-                        "type": "IMPORTS_MODULE",
-                        "source_id": file_node_id,
-                        "imported_module": root_module,
-                        "full_module_path": import_text,
-                    })
-    
-    def _parse_classes(self, root: SgNode, file_node_id: str, build_index: bool, module_name: str) -> None:
-        """Extract class declarations and their methods/fields."""
-        # Find all class_declaration nodes
-        for class_node in root.find_all(kind="class_declaration"):
-            # Get class name from field
-            name_field = class_node.field("name")
-            if not name_field:
-                continue
-            
-            class_name = name_field.text()
-            line_no = class_node.range().start.line + 1
+            text = " ".join(import_node.text().split())
+            text = text[len("import"):].strip().rstrip(";").strip()
+            is_static = text.startswith("static ")
+            if is_static:
+                text = text[len("static"):]
+            written = text.replace(" ", "")
+            name = written[:-2] if written.endswith(".*") else written
+            wildcard = name != written
             
-            # Create class node
-            class_node_id = self._get_node_id("Class", class_name, self.current_file, line_no)
-            self.nodes[class_node_id] = CodeNode(
-                node_id=class_node_id,
-                node_type="Class",
-                name=class_name,
-                file_path=self.current_file,
-                line_no=line_no,
-            )
+            member = None
+            if is_static and not wildcard and "." in name:
+                name, member = name.rsplit(".", 1)
+            package, type_name = self.split_qualified_name(name)
+            key = self.package_key(self.current_file, package)
             
-            # Add CONTAINS relation from file to class
-            self._add_relation(CodeRelation(file_node_id, class_node_id, "CONTAINS"))
-            
-            # Index the class for cross-file resolution
-            if build_index:
-                self.module_definitions[module_name][class_name] = class_node_id
+            pending: Dict[str, Any] = {
+                "source_id": file_node_id,
+                "imported_module": key,
+                "full_module_path": written,
+                "import_path": package,
+                "package_key": key,
+                "line_no": import_node.range().start.line + 1,
+            }
+            if wildcard:
+                pending["wildcard"] = True
+            if is_static:
+                pending["static"] = True
+            if member:
+                pending["member"] = member
             
-            # Set current class context
-            self.current_class = class_node_id
-            
-            # Extract methods and fields from class body
-            body = class_node.field("body")
-            if body:
-                self._parse_methods(body, class_node_id)
-                self._parse_fields(body, class_node_id)
-            
-            # Reset context
-            self.current_class = None
-    
-    def _parse_methods(self, class_body: SgNode, class_node_id: str) -> None:
-        """Extract method declarations from class body."""
-        # Find all method_declaration nodes within class body
-        for method_node in class_body.find_all(kind="method_declaration"):
-            # Get method name
-            name_field = method_node.field("name")
-            if not name_field:
+            if not type_name:
+                pending["type"] = "IMPORTS_MODULE"
+                if wildcard:
+                    self.wildcard_imports.append((key, ""))
+            else:
+                pending["type"] = "IMPORTS_SYMBOL"
+                pending["imported_name"] = type_name
+                if wildcard and not is_static:
+                    self.wildcard_imports.append((key, f"{type_name}$"))
+                elif not is_static:
+                    self.single_imports[type_name.rsplit("$", 1)[-1]] = (key, type_name)
+            self.pending_imports.append(pending)
+    
+    def _type_candidates(self, type_text: str) -> List[List[str]]:
+        """
+        (package key, binary name) pairs a type reference may denote, in lookup order.
+        
+        Type arguments, array brackets and varargs are dropped. Qualified
+        references name their package; simple ones are looked up in the
+        file's own types, the single-type imports, the current package and
+        then the wildcard imports. The second pass takes the first pair
+        that is indexed.
+        """
+        name = "".join(type_text.split()).split("<", 1)[0].rstrip("[].")
+        package, type_name = self.split_qualified_name(name)
+        if not type_name:
+            return []
+        if package:
+            return [[self.package_key(self.current_file, package), type_name]]
+        
+        first, _, rest = type_name.partition("$")
+        suffix = f"${rest}" if rest else ""
+        if first in self.file_types:
+            return [[self.current_package_key, self.file_types[first] + suffix]]
+        if first in self.single_imports:
+            key, binary_name = self.single_imports[first]
+            return [[key, binary_name + suffix]]
+        candidates = [[self.current_package_key, type_name]]
+        candidates.extend([key, prefix + type_name] for key, prefix in self.wildcard_imports)
+        return candidates
+    
+    def _queue_type_reference(self, relation_type: str, source_id: str, type_text: str, **extra: Any) -> None:
+        """Queue an EXTENDS, IMPLEMENTS or DECORATED_BY relation to a type name."""
+        candidates = self._type_candidates(type_text)
+        if not candidates:
+            return
+        pending = {
+            "type": relation_type,
+            "source_id": source_id,
+            "imported_module": candidates[0][0],
+            "imported_name": candidates[0][1],
+            "original_name": " ".join(type_text.split()),
+        }
+        if len(candidates) > 1:
+            pending["candidates"] = candidates
+        pending.update(extra)
+        self.pending_imports.append(pending)
+    
+    def _normalize_type(self, type_node: Optional[SgNode]) -> str:
+        """Type text with whitespace collapsed ("Map<String, List<T>>" -> "Map<String,List<T>>")."""
+        if type_node is None:
+            return ""
+        return TYPE_PUNCTUATION_SPACE.sub(r"\1", " ".join(type_node.text().split()))
+    
+    def _parse_modifiers(self, declaration: SgNode, node_id: str) -> None:
+        """
+        Record the modifiers and annotations of a declaration on its node.
+        
+        Annotations are kept as written, without the `@`; each one also
+        queues a DECORATED_BY relation, made when its type is indexed.
+        """
+        modifiers = next((c for c in declaration.children() if c.kind() == "modifiers"), None)
+        if modifiers is None:
+            return
+        keywords: List[str] = []
+        annotations: List[str] = []
+        for child in modifiers.children():
+            if child.kind() in JAVA_ANNOTATIONS:
+                text = " ".join(child.text().lstrip("@").split())
+                annotations.append(text)
+                name_node = child.field("name")
+                if name_node is not None:
+                    self._queue_type_reference("DECORATED_BY", node_id, name_node.text(), decorator=text,
+                                               line_no=child.range().start.line + 1)
+            elif not child.is_named():
+                keywords.append(child.text())
+        
+        properties = self.nodes[node_id].properties
+        if keywords:
+            properties["modifiers"] = keywords
+        if annotations:
+            properties["annotations"] = annotations
+    
+    def _add_type(self, node: SgNode, node_type: str, binary_name: str, properties: Dict[str, Any],
+                  parent_id: str, build_index: bool, module_name: str) -> str:
+        """
+        Create a type node and link it to its file (CONTAINS) or enclosing type (DEFINES).
+        
+        Returns the type node ID.
+        """
+        line_no = node.range().start.line + 1
+        node_id = self._get_node_id(node_type, binary_name, self.current_file, line_no)
+        properties["qualified_name"] = (
+            f"{self.current_package}.{binary_name}" if self.current_package else binary_name
+        )
+        self.nodes[node_id] = CodeNode(
+            node_id=node_id,
+            node_type=node_type,
+            name=binary_name,
+            file_path=self.current_file,
+            line_no=line_no,
+            end_line_no=node.range().end.line + 1,
+            properties=properties,
+        )
+        relation_type = "CONTAINS" if parent_id == f"file:{self.current_file}" else "DEFINES"
+        self._add_relation(CodeRelation(parent_id, node_id, relation_type))
+        
+        # Index the type for cross-file resolution
+        if build_index:
+            self.module_definitions[module_name][binary_name] = node_id
+            self.module_definitions[self.current_package_key][binary_name] = node_id
+        return node_id
+    
+    def _parse_type(self, declaration: SgNode, binary_name: str, parent_id: str,
+                    build_index: bool, module_name: str) -> str:
+        """Extract a class, record, interface, annotation type or enum with its members; returns its node ID."""
+        node_type, kind = JAVA_TYPE_DECLARATIONS[declaration.kind()]
+        type_id = self._add_type(declaration, node_type, binary_name, {"kind": kind},
+                                 parent_id, build_index, module_name)
+        self._attach_javadoc(type_id, declaration)
+        self._parse_modifiers(declaration, type_id)
+        self._parse_supertypes(declaration, type_id)
+        
+        # Record components are fields
+        if declaration.kind() == "record_declaration":
+            parameters = declaration.field("parameters")
+            for component in (parameters.children() if parameters is not None else []):
+                name_node = component.field("name") if component.kind() == "formal_parameter" else None
+                if name_node is not None:
+                    self._add_field(component, name_node.text(), self._normalize_type(component.field("type")),
+                                    type_id, {"component": True})
+        
+        body = declaration.field("body")
+        if body is not None:
+            self._parse_members(body, type_id, binary_name, build_index, module_name)
+        return type_id
+    
+    def _parse_supertypes(self, declaration: SgNode, type_id: str) -> None:
+        """
+        Queue EXTENDS and IMPLEMENTS for the extends and implements clauses of a type.
+        
+        Class `extends` and interface `extends` give EXTENDS; class, enum
+        and record `implements` give IMPLEMENTS.
+        """
+        clauses = {"superclass": "EXTENDS", "extends_interfaces": "EXTENDS", "super_interfaces": "IMPLEMENTS"}
+        for clause in declaration.children():
+            relation_type = clauses.get(clause.kind())
+            if relation_type is None:
+                continue
+            types = [c for c in clause.children() if c.is_named() and c.kind() not in JAVA_COMMENT_KINDS]
+            if types and types[0].kind() == "type_list":
+                types = [c for c in types[0].children() if c.is_named() and c.kind() not in JAVA_COMMENT_KINDS]
+            names = [self._normalize_type(type_node) for type_node in types]
+            property_name = "extends" if relation_type == "EXTENDS" else "implements"
+            self.nodes[type_id].properties[property_name] = names
+            for name in names:
+                self._queue_type_reference(relation_type, type_id, name)
+    
+    def _parse_members(self, body: SgNode, type_id: str, binary_name: str,
+                       build_index: bool, module_name: str) -> None:
+        """
+        Extract the members of a type body.
+        
+        Interfaces also record their method signatures in "methods" and
+        enums their constants in "members". Method bodies, initializers
+        and field values are searched for anonymous and local classes.
+        """
+        type_node = self.nodes[type_id]
+        for member in self._body_members(body):
+            kind = member.kind()
+            if kind in JAVA_TYPE_DECLARATIONS:
+                name_node = member.field("name")
+                if name_node is not None:
+                    self._parse_type(member, f"{binary_name}${name_node.text()}", type_id, build_index, module_name)
                 continue
             
-            method_name = name_field.text()
-            line_no = method_node.range().start.line + 1
-            
-            # Create method node
-            method_node_id = self._get_node_id("Method", method_name, self.current_file, line_no)
-            self.nodes[method_node_id] = CodeNode(
-                node_id=method_node_id,
-                node_type="Method",
-                name=method_name,
-                file_path=self.current_file,
-                line_no=line_no,
-            )
-            
-            # Add DEFINES relation from class to method
-            self._add_relation(CodeRelation(class_node_id, method_node_id, "DEFINES"))
-    
-    def _parse_fields(self, class_body: SgNode, class_node_id: str) -> None:
-        """Extract field declarations (class variables) from class body."""
-        # Find all field_declaration nodes within class body
-        for field_node in class_body.find_all(kind="field_declaration"):
-            # Field declarations can have multiple declarators
-            # field_declaration has a "declarator" field (can be multiple)
-            for child in field_node.children():
-                if child.kind() == "variable_declarator":
-                    # Get variable name
-                    name_field = child.field("name")
-                    if name_field:
-                        var_name = name_field.text()
-                        line_no = child.range().start.line + 1
-                        
-                        # Create variable node (class field)
-                        var_node_id = self._get_node_id("Variable", var_name, self.current_file, line_no)
-                        self.nodes[var_node_id] = CodeNode(
-                            node_id=var_node_id,
-                            node_type="ClassVariable",
-                            name=var_name,
-                            file_path=self.current_file,
-                            line_no=line_no,
-                        )
-                        
-                        # Add DEFINES relation from class to field
-                        self._add_relation(CodeRelation(class_node_id, var_node_id, "DEFINES"))
+            if kind in JAVA_METHOD_DECLARATIONS:
+                signature = self._parse_method(member, type_id, binary_name, build_index, module_name)
+                if signature and type_node.node_type == "Interface":
+                    type_node.properties.setdefault("methods", []).append(signature)
+            elif kind in ("field_declaration", "constant_declaration"):
+                self._parse_field(member, type_id)
+            elif kind == "enum_constant":
+                name_node = member.field("name")
+                if name_node is not None:
+                    type_node.properties.setdefault("members", []).append(name_node.text())
+                arguments = member.field("arguments")
+                if arguments is not None:
+                    self._parse_local_classes(arguments, binary_name, type_id, build_index, module_name)
+                # A constant with a body is an anonymous subclass of the enum
+                constant_body = member.field("body")
+                if constant_body is not None:
+                    self._parse_anonymous_class(member, constant_body, binary_name, binary_name, type_id,
+                                                build_index, module_name)
+                continue
+            self._parse_local_classes(member, binary_name, type_id, build_index, module_name)
+    
+    def _parse_method(self, method: SgNode, type_id: str, binary_name: str,
+                      build_index: bool, module_name: str) -> Optional[str]:
+        """
+        Extract a method or constructor and link it to its type with DEFINES.
+        
+        Returns the method signature, None for constructors.
+        """
+        name_node = method.field("name")
+        if name_node is None:
+            return None
+        
+        method_name = name_node.text()
+        line_no = method.range().start.line + 1
+        parameters = method.field("parameters")
+        if method.kind() == "compact_constructor_declaration":
+            # The parameters of a compact constructor are the record components
+            record = method.parent().parent() if method.parent() is not None else None
+            parameters = record.field("parameters") if record is not None else None
+        signature = f"{method_name}({','.join(self._parameter_types(parameters))})"
+        
+        properties: Dict[str, Any] = {"signature": signature}
+        if method.kind() in JAVA_CONSTRUCTORS:
+            properties["constructor"] = True
+        elif method.field("type") is not None:
+            properties["return_type"] = self._normalize_type(method.field("type"))
+        properties.update(self._structured_signature(method, parameters, properties.get("return_type")))
+        properties.update(syntax_metrics(method, JAVA_METRIC_RULES, line_no, method.range().end.line + 1))
+        
+        # Create method node
+        method_node_id = self._get_node_id("Method", method_name, self.current_file, line_no)
+        self.nodes[method_node_id] = CodeNode(
+            node_id=method_node_id,
+            node_type="Method",
+            name=method_name,
+            file_path=self.current_file,
+            line_no=line_no,
+            end_line_no=method.range().end.line + 1,
+            properties=properties,
+        )
+        self._attach_javadoc(method_node_id, method)
+        self._parse_modifiers(method, method_node_id)
+        
+        # Add DEFINES relation from type to method
+        self._add_relation(CodeRelation(type_id, method_node_id, "DEFINES"))
+        
+        # Overloads are told apart by signature, the plain name finds the first one
+        if build_index:
+            self.module_definitions[self.current_package_key][f"{binary_name}.{signature}"] = method_node_id
+            self.module_definitions[self.current_package_key].setdefault(f"{binary_name}.{method_name}",
+                                                                         method_node_id)
+        return None if properties.get("constructor") else signature
+    
+    def _structured_signature(self, method: SgNode, parameters: Optional[SgNode],
+                              return_type: Optional[str]) -> Dict[str, Any]:
+        """
+        signature_json and arity of a method or constructor (see signatures.py).
+        
+        The normalized "signature" stays as it is; void methods and
+        constructors have no returns.
+        """
+        entries = []
+        for parameter_node in parameters.children() if parameters is not None else []:
+            if parameter_node.kind() == "formal_parameter":
+                name = parameter_node.field("name")
+                entries.append(parameter(
+                    name.text() if name is not None else None,
+                    self._normalize_type(parameter_node.field("type"))
+                    + self._normalize_type(parameter_node.field("dimensions")),
+                ))
+            elif parameter_node.kind() == "spread_parameter":
+                type_node = next((c for c in parameter_node.children() if c.is_named()
+                                  and c.kind() not in ("modifiers", "variable_declarator")), None)
+                declarator = next((c for c in parameter_node.children() if c.kind() == "variable_declarator"), None)
+                name = declarator.field("name") if declarator is not None else None
+                entries.append(parameter(name.text() if name is not None else None,
+                                         self._normalize_type(type_node), variadic=True))
+        
+        returns = [{"name": None, "type": return_type}] if return_type and return_type != "void" else []
+        
+        # <T extends Comparable<T>> before the return type
+        type_parameters = []
+        type_parameter_list = method.field("type_parameters")
+        if type_parameter_list is not None:
+            for type_parameter in type_parameter_list.children():
+                if type_parameter.kind() != "type_parameter":
+                    continue
+                name = next((c for c in type_parameter.children() if c.kind() in ("type_identifier", "identifier")), None)
+                bound = next((c for c in type_parameter.children() if c.kind() == "type_bound"), None)
+                if name is None:
+                    continue
+                constraint = self._normalize_type(bound)
+                if constraint.startswith("extends "):
+                    constraint = constraint[len("extends "):]
+                type_parameters.append({"name": name.text(), "constraint": constraint or None})
+        return signature_properties(entries, returns, type_parameters)
+    
+    def _parameter_types(self, parameters: Optional[SgNode]) -> List[str]:
+        """Parameter types of a formal_parameters list, varargs as "T..." and C-style arrays as "T[]"."""
+        types: List[str] = []
+        if parameters is None:
+            return types
+        for parameter in parameters.children():
+            if parameter.kind() == "formal_parameter":
+                dimensions = parameter.field("dimensions")
+                types.append(self._normalize_type(parameter.field("type")) + self._normalize_type(dimensions))
+            elif parameter.kind() == "spread_parameter":
+                type_node = next((c for c in parameter.children() if c.is_named()
+                                  and c.kind() not in ("modifiers", "variable_declarator")), None)
+                types.append(f"{self._normalize_type(type_node)}...")
+        return types
+    
+    def _parse_field(self, field_node: SgNode, type_id: str) -> None:
+        """Extract a field (or interface constant) declaration, one ClassVariable per declarator."""
+        field_type = self._normalize_type(field_node.field("type"))
+        for declarator in field_node.children():
+            if declarator.kind() != "variable_declarator":
+                continue
+            name_node = declarator.field("name")
+            if name_node is None:
+                continue
+            var_node_id = self._add_field(declarator, name_node.text(),
+                                          field_type + self._normalize_type(declarator.field("dimensions")),
+                                          type_id, {})
+            self._attach_javadoc(var_node_id, field_node)
+            self._parse_modifiers(field_node, var_node_id)
+    
+    def _add_field(self, node: SgNode, name: str, field_type: str, type_id: str,
+                   properties: Dict[str, Any]) -> str:
+        """Create a ClassVariable node defined by a type; returns its ID."""
+        line_no = node.range().start.line + 1
+        if field_type:
+            properties["type"] = field_type
+        var_node_id = self._get_node_id("Variable", name, self.current_file, line_no)
+        self.nodes[var_node_id] = CodeNode(
+            node_id=var_node_id,
+            node_type="ClassVariable",
+            name=name,
+            file_path=self.current_file,
+            line_no=line_no,
+            properties=properties,
+        )
+        
+        # Add DEFINES relation from class to field
+        self._add_relation(CodeRelation(type_id, var_node_id, "DEFINES"))
+        return var_node_id
+    
+    def _parse_local_classes(self, node: SgNode, enclosing: str, enclosing_id: str,
+                             build_index: bool, module_name: str) -> None:
+        """
+        Find the anonymous and local classes below a member of a type.
+        
+        They are numbered in source order within the enclosing class, like
+        javac names them: "Outer$1" for an anonymous class, "Outer$1Local"
+        for the first local class named Local. Their own bodies are
+        searched by _parse_members.
+        """
+        for child in node.children():
+            kind = child.kind()
+            if kind == "object_creation_expression":
+                body = next((c for c in child.children() if c.kind() == "class_body"), None)
+                if body is not None:
+                    # Constructor arguments belong to the enclosing class
+                    for part in child.children():
+                        if part.kind() != "class_body":
+                            self._parse_local_classes(part, enclosing, enclosing_id, build_index, module_name)
+                    supertype = self._normalize_type(child.field("type"))
+                    self._parse_anonymous_class(child, body, supertype, enclosing, enclosing_id,
+                                                build_index, module_name)
+                    continue
+            elif kind in JAVA_TYPE_DECLARATIONS:
+                name_node = child.field("name")
+                if name_node is not None:
+                    local_key = (enclosing, name_node.text())
+                    index = self.local_counts.get(local_key, 0) + 1
+                    self.local_counts[local_key] = index
+                    local_id = self._parse_type(child, f"{enclosing}${index}{name_node.text()}", enclosing_id,
+                                                build_index, module_name)
+                    self.nodes[local_id].properties["local"] = True
+                continue
+            self._parse_local_classes(child, enclosing, enclosing_id, build_index, module_name)
+    
+    def _parse_anonymous_class(self, node: SgNode, body: SgNode, supertype: str, enclosing: str,
+                               enclosing_id: str, build_index: bool, module_name: str) -> None:
+        """Extract an anonymous class body as a Class node named after its enclosing class."""
+        index = self.anonymous_counts.get(enclosing, 0) + 1
+        self.anonymous_counts[enclosing] = index
+        binary_name = f"{enclosing}${index}"
+        properties: Dict[str, Any] = {"kind": "anonymous"}
+        if supertype:
+            properties["supertype"] = supertype
+        class_id = self._add_type(node, "Class", binary_name, properties, enclosing_id, build_index, module_name)
+        self._parse_members(body, class_id, binary_name, build_index, module_name)
@@ -6,16 +6,35 @@ This adapter extracts code entities from JavaScript and TypeScript files
 using the ast-grep-py library and produces output compatible with TypeScriptParser.
 """
 
+import ast
 import os
 import logging
-from typing import Dict, List, Optional, Any
+import re
+from typing import Dict, List, Optional, Any, Tuple
 from ast_grep_py import SgRoot, SgNode
 
 from src.ast_parser.parser import CodeNode, CodeRelation
+from src.ast_parser.doc_comments import js_file_comment_properties, jsdoc_properties
+from src.ast_parser.metrics import JAVASCRIPT_METRIC_RULES, syntax_metrics
+from src.ast_parser.signatures import typescript_signature
+from src.ast_parser.variables import literal_value
 from .base_adapter import LanguageAdapter
 
 logger = logging.getLogger(__name__)
 
+# Decimal, hex, octal and binary integer literals, without separators
+INTEGER_LITERAL = re.compile(r"-?(\d+|0[xX][\da-fA-F]+|0[oO][0-7]+|0[bB][01]+)")
+
+# Jest / Vitest / Mocha globals registering a test case and a group of them (fit, xdescribe, ... included)
+JEST_TEST_CALLS = ("it", "test", "fit", "xit", "xtest")
+JEST_SUITE_CALLS = ("describe", "fdescribe", "xdescribe")
+
+# Function node kinds a test callback or an enclosing function can be
+CALLBACK_KINDS = (
+    "function_declaration", "generator_function_declaration", "function_expression", "function",
+    "arrow_function", "method_definition",
+)
+
 
 class JavaScriptAstGrepAdapter(LanguageAdapter):
     """
@@ -24,6 +43,22 @@ class JavaScriptAstGrepAdapter(LanguageAdapter):
     Extracts: Functions, Classes, Methods, Variables, Imports, Exports
     Creates relations: CONTAINS, DEFINES, EXTENDS, IMPORTS
     
+    The JSDoc block above a declaration is stored in "doc", with its
+    @param and @returns tags parsed into "doc_params" and "doc_returns"
+    (JSON). License headers and other comments opening the file that do
+    not document the first statement go to the File node.
+    
+    Functions and methods record their parameters (defaults, rest
+    parameters, TypeScript types), return type and type parameters in
+    signature_json, with a display "signature" (see signatures.py), and
+    their complexity metrics (see metrics.py).
+    
+    The callbacks of Jest `it()` / `test()` calls (`.only`, `.skip`,
+    `.each(table)` included) registered at the top level or inside
+    `describe()` callbacks become Function nodes named after the describe
+    titles and the test title, and get CALLS relations to the functions
+    they call, closures included (see src/indexing/testcode.py).
+    
     Maintains parity with TypeScriptParser output format.
     """
 
@@ -87,16 +122,19 @@ class JavaScriptAstGrepAdapter(LanguageAdapter):
                 self.module_to_file[module_name] = file_node_id
             
             # Extract entities in order
+            self._parse_file_comments(root, file_node_id)
             self._parse_imports(root, file_node_id)
             self._parse_classes(root, file_node_id, build_index, module_name)
             self._parse_functions(root, file_node_id, build_index, module_name)
             self._parse_variables(root, file_node_id)
             self._parse_exports(root)
+            self._parse_jest_tests(root, file_node_id)
             
             return self.nodes, self.relations
             
         except Exception as e:
             logger.error(f"Error parsing file {file_path}: {e}")
+            self.parse_error = e
             return {}, []
 
     def _parse_imports(self, root: SgNode, file_node_id: str) -> None:
@@ -200,6 +238,7 @@ class JavaScriptAstGrepAdapter(LanguageAdapter):
                 properties={"language": self._get_language_from_file()},
             )
             self.nodes[node_id].code_snippet = class_node.text()
+            self._attach_jsdoc(node_id, class_node)
             
             # Create CONTAINS relation (file contains class)
             self._add_relation(CodeRelation(
@@ -308,9 +347,12 @@ class JavaScriptAstGrepAdapter(LanguageAdapter):
                     "parameters": params,
                     "language": self._get_language_from_file(),
                     "is_async": is_async,
+                    **typescript_signature(method_node, method_name),
+                    **syntax_metrics(method_node, JAVASCRIPT_METRIC_RULES, line_no, end_line_no),
                 },
             )
             self.nodes[node_id].code_snippet = method_node.text()
+            self._attach_jsdoc(node_id, method_node)
             
             # Create DEFINES relation (class defines method)
             self._add_relation(CodeRelation(
@@ -365,9 +407,12 @@ class JavaScriptAstGrepAdapter(LanguageAdapter):
                     "language": self._get_language_from_file(),
                     "function_style": "standard",
                     "is_async": is_async,
+                    **typescript_signature(func_node, func_name),
+                    **syntax_metrics(func_node, JAVASCRIPT_METRIC_RULES, line_no, end_line_no),
                 },
             )
             self.nodes[node_id].code_snippet = func_node.text()
+            self._attach_jsdoc(node_id, func_node)
             
             # Create CONTAINS relation (file contains function)
             self._add_relation(CodeRelation(
@@ -437,9 +482,12 @@ class JavaScriptAstGrepAdapter(LanguageAdapter):
                         "language": self._get_language_from_file(),
                         "function_style": "arrow",
                         "is_async": is_async,
+                        **typescript_signature(arrow_func, func_name),
+                        **syntax_metrics(arrow_func, JAVASCRIPT_METRIC_RULES, line_no, end_line_no),
                     },
                 )
                 self.nodes[node_id].code_snippet = arrow_func.text()
+                self._attach_jsdoc(node_id, lex_decl)
                 
                 # Create CONTAINS relation (file contains function)
                 self._add_relation(CodeRelation(
@@ -506,11 +554,12 @@ class JavaScriptAstGrepAdapter(LanguageAdapter):
                 processed_vars.add(var_name)
                 line_no = var_decl.range().start.line + 1
                 
-                # Create variable node
-                node_id = self._get_node_id("Variable", var_name, self.current_file, line_no)
+                # Create variable node; `const` bindings are Constant nodes (see variables.py)
+                node_type = "Constant" if decl_type == "const" else "Variable"
+                node_id = self._get_node_id(node_type, var_name, self.current_file, line_no)
                 self.nodes[node_id] = CodeNode(
                     node_id=node_id,
-                    node_type="Variable",
+                    node_type=node_type,
                     name=var_name,
                     file_path=self.current_file,
                     line_no=line_no,
@@ -519,6 +568,7 @@ class JavaScriptAstGrepAdapter(LanguageAdapter):
                         "language": self._get_language_from_file(),
                     },
                 )
+                self._set_literal_value(node_id, var_decl.field("value"))
                 
                 # Create CONTAINS relation (file contains variable)
                 self._add_relation(CodeRelation(
@@ -566,6 +616,7 @@ class JavaScriptAstGrepAdapter(LanguageAdapter):
                         "language": self._get_language_from_file(),
                     },
                 )
+                self._set_literal_value(node_id, var_decl.field("value"))
                 
                 # Create CONTAINS relation (file contains variable)
                 self._add_relation(CodeRelation(
@@ -574,6 +625,184 @@ class JavaScriptAstGrepAdapter(LanguageAdapter):
                     relation_type="CONTAINS"
                 ))
 
+    def _set_literal_value(self, node_id: str, value: Optional[SgNode]) -> None:
+        """
+        Store a simple literal initializer (number, string, boolean) as "value".
+        
+        Template strings count when they have no substitutions; BigInt and
+        any other expression are left out.
+        """
+        if value is None:
+            return
+        kind, text = value.kind(), value.text()
+        literal = None
+        try:
+            if kind in ("true", "false"):
+                literal = kind == "true"
+            elif kind == "string":
+                literal = ast.literal_eval(text)
+            elif kind == "template_string" and "${" not in text:
+                literal = text[1:-1]
+            elif kind == "number" or (kind == "unary_expression" and re.fullmatch(r"-\s*[\d.][\w.+-]*", text)):
+                digits = re.sub(r"\s|_", "", text)
+                literal = int(digits, 0) if INTEGER_LITERAL.fullmatch(digits) else float(digits)
+        except (SyntaxError, ValueError):
+            return
+        if literal_value(literal) is not None:
+            self.nodes[node_id].properties["value"] = literal_value(literal)
+    
+    def _parse_jest_tests(self, root: SgNode, file_node_id: str) -> None:
+        """
+        Create a Function node per Jest test case and queue the calls of its callback.
+        
+        A test nested in any function other than a describe callback (a
+        helper generating tests, say) is not registered when the file
+        loads and is skipped, as are tests without a callback (it.todo).
+        """
+        for call in root.find_all(kind="call_expression"):
+            test = self._jest_call(call)
+            if test is None or test[0] not in JEST_TEST_CALLS:
+                continue
+            _, modifiers, title, callback = test
+            if callback is None:
+                continue
+            describe = self._describe_path(call)
+            if describe is None:
+                continue
+            
+            name = " > ".join(describe + [title])
+            line_no = call.range().start.line + 1
+            end_line_no = call.range().end.line + 1
+            node_id = self._get_node_id("Function", name, self.current_file, line_no)
+            properties: Dict[str, Any] = {
+                "is_method": False,
+                "parameters": self._extract_function_params(callback),
+                "language": self._get_language_from_file(),
+                "function_style": "test",
+                "is_async": self._is_async_function(callback),
+                "test_framework": "jest",
+                "describe": describe,
+                **syntax_metrics(callback, JAVASCRIPT_METRIC_RULES, line_no, end_line_no),
+            }
+            if "each" in modifiers:
+                properties["parametrized"] = True
+            if "skip" in modifiers or test[0] in ("xit", "xtest"):
+                properties["skipped"] = True
+            self.nodes[node_id] = CodeNode(
+                node_id=node_id,
+                node_type="Function",
+                name=name,
+                file_path=self.current_file,
+                line_no=line_no,
+                end_line_no=end_line_no,
+                properties=properties,
+            )
+            self.nodes[node_id].code_snippet = call.text()
+            self._add_relation(CodeRelation(
+                source_id=file_node_id,
+                target_id=node_id,
+                relation_type="CONTAINS"
+            ))
+            self._queue_test_calls(node_id, callback)
+    
+    def _jest_call(self, call: SgNode) -> Optional[Tuple[str, List[str], str, Optional[SgNode]]]:
+        """
+        (global, modifiers, title, callback) of a Jest `it` / `test` / `describe` call, None for other calls.
+        
+        `it.only(...)` has the modifiers ["only"]; `it.each(table)(title, fn)`
+        is the call of a call and has ["each"].
+        """
+        callee = call.field("function")
+        arguments = call.field("arguments")
+        if callee is None or arguments is None:
+            return None
+        # .each(table) returns the function registering the tests
+        table = callee.kind() == "call_expression"
+        if table:
+            callee = callee.field("function")
+        modifiers: List[str] = []
+        while callee is not None and callee.kind() == "member_expression":
+            member = callee.field("property")
+            modifiers.insert(0, member.text() if member is not None else "")
+            callee = callee.field("object")
+        if callee is None or callee.kind() != "identifier" or callee.text() not in JEST_TEST_CALLS + JEST_SUITE_CALLS:
+            return None
+        if table != ("each" in modifiers):
+            return None
+        
+        args = [child for child in arguments.children() if child.is_named() and child.kind() != "comment"]
+        if not args:
+            return None
+        title_node = args[0]
+        if title_node.kind() in ("string", "template_string"):
+            title = title_node.text()[1:-1]
+        else:
+            title = title_node.text()
+        callback = next((arg for arg in args[1:] if arg.kind() in CALLBACK_KINDS), None)
+        return callee.text(), modifiers, " ".join(title.split()), callback
+    
+    def _describe_path(self, call: SgNode) -> Optional[List[str]]:
+        """Titles of the describe blocks around a test call, None when another function encloses it."""
+        titles: List[str] = []
+        current = call.parent()
+        while current is not None:
+            if current.kind() in CALLBACK_KINDS:
+                # Only a describe callback may enclose a test: arguments -> call_expression
+                arguments = current.parent()
+                suite_call = arguments.parent() if arguments is not None else None
+                suite = self._jest_call(suite_call) if suite_call is not None and arguments.kind() == "arguments" else None
+                if suite is None or suite[0] not in JEST_SUITE_CALLS:
+                    return None
+                titles.insert(0, suite[2])
+                current = suite_call
+            current = current.parent()
+        return titles
+    
+    def _queue_test_calls(self, node_id: str, callback: SgNode) -> None:
+        """Queue a CALLS relation per function a test callback calls, with the call lines."""
+        calls: Dict[Tuple[str, str], Tuple[str, List[int]]] = {}
+        for call in callback.find_all(kind="call_expression"):
+            callee = call.field("function")
+            if callee is None:
+                continue
+            target = self._symbol_target(callee)
+            if target is None:
+                continue
+            original_name, lines = calls.setdefault(target, (callee.text(), []))
+            lines.append(call.range().start.line + 1)
+        
+        for (module_name, name), (original_name, lines) in calls.items():
+            lines = sorted(set(lines))
+            self.pending_imports.append({
+                "type": "CALLS",
+                "source_id": node_id,
+                "imported_module": module_name,
+                "imported_name": name,
+                "original_name": original_name,
+                "line_no": lines[0],
+                "call_lines": lines,
+            })
+    
+    def _symbol_target(self, expression: SgNode) -> Optional[Tuple[str, str]]:
+        """
+        Module name and name a reference resolves to.
+        
+        Names imported from a relative path go to that file's module,
+        other plain names to the current file; members and names imported
+        from packages are not resolved.
+        """
+        if expression.kind() != "identifier":
+            return None
+        name = expression.text()
+        if name in JEST_TEST_CALLS + JEST_SUITE_CALLS:
+            return None
+        source_module = self.imports.get(name)
+        if source_module is None:
+            return os.path.splitext(os.path.basename(self.current_file))[0], name
+        if not source_module.startswith("."):
+            return None
+        return os.path.splitext(os.path.basename(source_module))[0], name
+    
     def _parse_exports(self, root: SgNode) -> None:
         """
         Extract export statements and mark exported entities.
@@ -725,6 +954,14 @@ class JavaScriptAstGrepAdapter(LanguageAdapter):
             current = current.parent()
         return True
 
+    def _parse_file_comments(self, root: SgNode, file_node_id: str) -> None:
+        """Attach the comments opening the file to the File node (see doc_comments.js_file_comment_properties)."""
+        self.nodes[file_node_id].properties.update(js_file_comment_properties(root))
+    
+    def _attach_jsdoc(self, node_id: str, declaration: SgNode) -> None:
+        """Store the JSDoc block directly above a declaration on its node (see doc_comments.jsdoc_properties)."""
+        self.nodes[node_id].properties.update(jsdoc_properties(declaration))
+    
     def _get_language_from_file(self) -> str:
         """
         Determine the language from the current file extension.
@@ -0,0 +1,604 @@
+"""Kotlin language adapter using ast-grep for AST parsing."""
+
+import os
+from typing import Any, Dict, List, Tuple, Optional
+
+from ast_grep_py import SgRoot, SgNode
+
+from .java_adapter import JavaAdapter
+from ast_parser.parser import CodeNode, CodeRelation
+from ast_parser.metrics import KOTLIN_METRIC_RULES, syntax_metrics
+from ast_parser.signatures import collapse, parameter, signature_properties
+
+
+# Declarations that become type nodes
+KOTLIN_TYPE_DECLARATIONS = ("class_declaration", "object_declaration", "companion_object")
+
+KOTLIN_COMMENT_KINDS = ("line_comment", "multiline_comment")
+
+# Kinds a type reference is parsed as
+KOTLIN_TYPE_KINDS = ("user_type", "nullable_type", "function_type", "parenthesized_type", "non_nullable_type")
+
+KOTLIN_ACCESSORS = ("getter", "setter")
+
+
+class KotlinAdapter(JavaAdapter):
+    """
+    Kotlin adapter using ast-grep library.
+    
+    Extracts Kotlin structures:
+    - File, Class (classes, data classes, objects and companion objects),
+      Interface (interfaces, annotation classes), Enum, TypeAlias,
+      Function (top-level and extension functions), Method (member
+      functions and constructors), Variable / Constant (top-level
+      properties) and ClassVariable (member and constructor properties)
+    - CONTAINS, DEFINES relations; EXTENDS, IMPLEMENTS or INHERITS_FROM
+      for each supertype; EXTENSION_OF from an extension function or
+      property to its receiver type; DECORATED_BY for annotations whose
+      type is declared in the codebase
+    - Import tracking like Java's, plus imports of top-level functions
+      and properties and `as` aliases
+    
+    Kotlin and Java share one symbol table: types are indexed per package
+    under the same keys as JavaAdapter's (see JavaAdapter.package_key) and
+    by binary name ("Outer$Inner", "Outer$Companion"), and type references
+    are looked up the way the Java adapter looks them up. So in the second
+    pass a Kotlin class extending a Java class gets EXTENDS to the Java
+    Class node, and a Java class extending or implementing a Kotlin type
+    gets EXTENDS or IMPLEMENTS to the Kotlin node.
+    
+    A supertype list does not say which entry is the superclass: an
+    indexed interface gets IMPLEMENTS, any other indexed type EXTENDS
+    (subclasses of a sealed class or interface included, the parent is
+    flagged "sealed"), and a type that is not indexed INHERITS_FROM to an
+    ExternalType placeholder. A property with a custom getter or setter
+    stays a single node flagged "custom_getter" / "custom_setter".
+    Functions record an overload-distinguishing "signature"
+    ("deposit(Long)"), their structured signature and complexity metrics;
+    extension functions and properties record "receiver_type".
+    
+    Supports Kotlin source and script files (.kt, .kts).
+    """
+    
+    COMMENT_KINDS = KOTLIN_COMMENT_KINDS
+    PACKAGE_DECLARATION = "package_header"
+    
+    def __init__(self):
+        super().__init__()
+        self.language = "kotlin"
+    
+    def parse_file(self, file_path: str, build_index: bool = False) -> Tuple[Dict[str, CodeNode], List[CodeRelation]]:
+        """
+        Parse a Kotlin file using ast-grep.
+        
+        Extracts the package, imports and top-level declarations with the
+        members, nested types and companion objects of each type.
+        """
+        self.current_file = file_path
+        self.file_types = {}
+        self.single_imports = {}
+        self.wildcard_imports = []
+        
+        try:
+            # Read source code
+            with open(file_path, "r", encoding="utf-8") as f:
+                source = f.read()
+            
+            # Parse with ast-grep (Kotlin language)
+            root = SgRoot(source, "kotlin").root()
+            
+            # Create file node
+            file_node_id = self._create_file_node(file_path)
+            
+            # Generate module name for indexing
+            module_name = os.path.splitext(os.path.basename(file_path))[0]
+            if build_index:
+                if module_name not in self.module_definitions:
+                    self.module_definitions[module_name] = {}
+                self.module_to_file[module_name] = file_node_id
+            
+            # Package-level index shared by the Kotlin and Java files of the package
+            self.current_package = self._get_package_name(root)
+            self.current_package_key = self.package_key(file_path, self.current_package)
+            if self.current_package:
+                self.nodes[file_node_id].properties["package"] = self.current_package
+                self.nodes[file_node_id].properties["import_path"] = self.current_package
+            if build_index:
+                self.module_definitions.setdefault(self.current_package_key, {})
+            
+            # Extract Kotlin structures
+            self._parse_file_comments(root, file_node_id)
+            self._collect_type_names(root, None)
+            self._parse_imports(root, file_node_id)
+            self._parse_declarations(root.children(), file_node_id, None, build_index, module_name)
+            
+            return self.nodes, self.relations
+        
+        except Exception as e:
+            print(f"Error parsing Kotlin file {file_path}: {e}")
+            self.parse_error = e
+            return {}, []
+    
+    def _get_package_name(self, root: SgNode) -> str:
+        """Return the name from the file's package header ("" for the default package)."""
+        for header in root.children():
+            if header.kind() != "package_header":
+                continue
+            for child in header.children():
+                if child.kind() == "identifier":
+                    return "".join(child.text().split())
+        return ""
+    
+    def _declaration_name(self, declaration: SgNode) -> Optional[str]:
+        """Name of a type or function declaration; an unnamed companion object is "Companion"."""
+        name_node = next((c for c in declaration.children() if c.kind() in ("type_identifier", "simple_identifier")),
+                         None)
+        if name_node is not None:
+            return name_node.text()
+        return "Companion" if declaration.kind() == "companion_object" else None
+    
+    def _type_body(self, declaration: SgNode) -> Optional[SgNode]:
+        return next((c for c in declaration.children() if c.kind() in ("class_body", "enum_class_body")), None)
+    
+    def _collect_type_names(self, parent: SgNode, enclosing: Optional[str]) -> None:
+        """
+        Map the simple names of the file's types to their binary names.
+        
+        Collected before anything is parsed, since a type may be referenced
+        above its declaration. Top-level names win over nested ones.
+        """
+        for declaration in parent.children():
+            if declaration.kind() not in KOTLIN_TYPE_DECLARATIONS:
+                continue
+            name = self._declaration_name(declaration)
+            if name is None:
+                continue
+            binary_name = f"{enclosing}${name}" if enclosing else name
+            self.file_types.setdefault(name, binary_name)
+            body = self._type_body(declaration)
+            if body is not None:
+                self._collect_type_names(body, binary_name)
+    
+    def _parse_imports(self, root: SgNode, file_node_id: str) -> None:
+        """
+        Extract import headers.
+        
+        `import a.b.C` imports the type C (IMPORTS_SYMBOL), `import a.b.*`
+        the package a.b (IMPORTS_MODULE) and `import a.b.C.*` the nested
+        types of C. A lowercase last segment is a top-level function or
+        property, `import a.b.format`, or a member of an object,
+        `import a.b.C.create`; `as` aliases are recorded in "alias".
+        """
+        for header in root.find_all(kind="import_header"):
+            identifier = next((c for c in header.children() if c.kind() == "identifier"), None)
+            if identifier is None:
+                continue
+            name = "".join(identifier.text().split())
+            wildcard = header.text().strip().rstrip(";").rstrip().endswith("*")
+            alias_node = next((c for c in header.children() if c.kind() == "import_alias"), None)
+            alias = None
+            if alias_node is not None:
+                alias_name = [c for c in alias_node.children() if c.is_named()]
+                alias = alias_name[-1].text() if alias_name else None
+            
+            member = None
+            if not wildcard and "." in name and not name.rsplit(".", 1)[1][:1].isupper():
+                name, member = name.rsplit(".", 1)
+            package, type_name = self.split_qualified_name(name)
+            key = self.package_key(self.current_file, package)
+            
+            pending: Dict[str, Any] = {
+                "source_id": file_node_id,
+                "imported_module": key,
+                "full_module_path": f"{name}.{member}" if member else f"{name}.*" if wildcard else name,
+                "import_path": package,
+                "package_key": key,
+                "line_no": header.range().start.line + 1,
+            }
+            if wildcard:
+                pending["wildcard"] = True
+            if alias:
+                pending["alias"] = alias
+            
+            if type_name:
+                pending["type"] = "IMPORTS_SYMBOL"
+                pending["imported_name"] = type_name
+                if member:
+                    pending["member"] = member
+                elif wildcard:
+                    self.wildcard_imports.append((key, f"{type_name}$"))
+                else:
+                    self.single_imports[alias or type_name.rsplit("$", 1)[-1]] = (key, type_name)
+            elif member:
+                pending["type"] = "IMPORTS_SYMBOL"
+                pending["imported_name"] = member
+            else:
+                pending["type"] = "IMPORTS_MODULE"
+                if wildcard:
+                    self.wildcard_imports.append((key, ""))
+            self.pending_imports.append(pending)
+    
+    def _type_child(self, node: SgNode) -> Optional[SgNode]:
+        """First child of node that is a type."""
+        return next((c for c in node.children() if c.kind() in KOTLIN_TYPE_KINDS), None)
+    
+    def _reference_name(self, type_node: SgNode) -> str:
+        """Type text looked up as a reference: "Shape?" -> "Shape"."""
+        return self._normalize_type(type_node).rstrip("?")
+    
+    def _parse_modifiers(self, declaration: SgNode, node_id: str) -> List[str]:
+        """
+        Record the modifiers and annotations of a declaration on its node.
+        
+        Annotations are kept as written, without the `@`; each one also
+        queues a DECORATED_BY relation, made when its type is indexed.
+        Returns the modifier keywords ("data", "sealed", "private", ...).
+        """
+        modifiers = next((c for c in declaration.children() if c.kind() == "modifiers"), None)
+        if modifiers is None:
+            return []
+        keywords: List[str] = []
+        annotations: List[str] = []
+        for child in modifiers.children():
+            if child.kind() == "annotation":
+                text = " ".join(child.text().lstrip("@").split())
+                annotations.append(text)
+                type_node = child.find(kind="user_type")
+                if type_node is not None:
+                    self._queue_type_reference("DECORATED_BY", node_id, self._normalize_type(type_node),
+                                               decorator=text, line_no=child.range().start.line + 1)
+            elif child.is_named() and child.kind() not in KOTLIN_COMMENT_KINDS:
+                keywords.append(child.text())
+        
+        properties = self.nodes[node_id].properties
+        if keywords:
+            properties["modifiers"] = keywords
+        if annotations:
+            properties["annotations"] = annotations
+        return keywords
+    
+    def _modifier_keywords(self, declaration: SgNode) -> List[str]:
+        modifiers = next((c for c in declaration.children() if c.kind() == "modifiers"), None)
+        if modifiers is None:
+            return []
+        return [c.text() for c in modifiers.children()
+                if c.is_named() and c.kind() not in ("annotation",) + KOTLIN_COMMENT_KINDS]
+    
+    def _type_kind(self, declaration: SgNode) -> Tuple[str, str]:
+        """(node type, "kind" property) of a type declaration."""
+        if declaration.kind() == "object_declaration":
+            return "Class", "object"
+        if declaration.kind() == "companion_object":
+            return "Class", "companion_object"
+        keywords = self._modifier_keywords(declaration)
+        if "annotation" in keywords:
+            return "Interface", "annotation"
+        if any(c.kind() == "interface" for c in declaration.children()):
+            return "Interface", "interface"
+        if "enum" in keywords:
+            return "Enum", "enum"
+        if "data" in keywords:
+            return "Class", "data_class"
+        return "Class", "class"
+    
+    def _parse_declarations(self, declarations: List[SgNode], parent_id: str, enclosing: Optional[str],
+                            build_index: bool, module_name: str) -> None:
+        """
+        Extract the declarations of a file (enclosing None) or of a type body.
+        
+        Interfaces also record their method signatures in "methods" and
+        enums their entries in "members". Accessors parsed as siblings of
+        their property belong to it.
+        """
+        parent = self.nodes[parent_id]
+        members = [c for c in declarations if c.is_named()]
+        for index, member in enumerate(members):
+            kind = member.kind()
+            if kind in KOTLIN_TYPE_DECLARATIONS:
+                name = self._declaration_name(member)
+                if name is not None:
+                    self._parse_type(member, f"{enclosing}${name}" if enclosing else name, parent_id,
+                                     build_index, module_name)
+            elif kind in ("function_declaration", "secondary_constructor"):
+                if kind == "secondary_constructor" and enclosing is None:
+                    continue
+                signature = self._parse_function(member, parent_id, enclosing, build_index, module_name)
+                if signature and parent.node_type == "Interface":
+                    parent.properties.setdefault("methods", []).append(signature)
+            elif kind == "property_declaration":
+                self._parse_property(member, self._accessors(member, members[index + 1:]), parent_id, enclosing,
+                                     build_index, module_name)
+            elif kind == "type_alias":
+                self._parse_type_alias(member, parent_id, build_index, module_name)
+            elif kind == "enum_entry":
+                name_node = next((c for c in member.children() if c.kind() == "simple_identifier"), None)
+                if name_node is not None:
+                    parent.properties.setdefault("members", []).append(name_node.text())
+    
+    def _accessors(self, declaration: SgNode, following: List[SgNode]) -> List[SgNode]:
+        """Getter and setter of a property, inside its declaration or right after it."""
+        accessors = [c for c in declaration.children() if c.kind() in KOTLIN_ACCESSORS]
+        for sibling in following:
+            if sibling.kind() in KOTLIN_COMMENT_KINDS:
+                continue
+            if sibling.kind() not in KOTLIN_ACCESSORS:
+                break
+            accessors.append(sibling)
+        return accessors
+    
+    def _parse_type(self, declaration: SgNode, binary_name: str, parent_id: str,
+                    build_index: bool, module_name: str) -> str:
+        """Extract a class, interface, object, companion object or enum with its members; returns its node ID."""
+        node_type, kind = self._type_kind(declaration)
+        type_id = self._add_type(declaration, node_type, binary_name, {"kind": kind},
+                                 parent_id, build_index, module_name)
+        self._attach_javadoc(type_id, declaration)
+        keywords = self._parse_modifiers(declaration, type_id)
+        if "sealed" in keywords:
+            self.nodes[type_id].properties["sealed"] = True
+        self._parse_supertypes(declaration, type_id)
+        
+        # The primary constructor: a Method, and a ClassVariable for each val / var parameter
+        constructor = next((c for c in declaration.children() if c.kind() == "primary_constructor"), None)
+        if constructor is not None:
+            parameters = next((c for c in constructor.children() if c.kind() == "class_parameters"), None)
+            self._add_function(constructor, self._declaration_name(declaration), parameters, None, None,
+                               type_id, binary_name, build_index, {"constructor": True, "primary": True})
+            for class_parameter in (parameters.children() if parameters is not None else []):
+                if class_parameter.kind() == "class_parameter":
+                    self._parse_constructor_property(class_parameter, type_id)
+        
+        body = self._type_body(declaration)
+        if body is not None:
+            self._parse_declarations(body.children(), type_id, binary_name, build_index, module_name)
+        return type_id
+    
+    def _parse_supertypes(self, declaration: SgNode, type_id: str) -> None:
+        """
+        Queue a BASE_TYPE relation for each entry of the supertype list.
+        
+        The entries are kept in "supertypes"; a delegated interface
+        (`Auditable by log`) is an entry like any other.
+        """
+        names: List[str] = []
+        for specifier in declaration.children():
+            if specifier.kind() == "annotated_delegation_specifier":
+                specifier = next((c for c in specifier.children() if c.kind() == "delegation_specifier"), specifier)
+            elif specifier.kind() != "delegation_specifier":
+                continue
+            type_node = specifier.find(kind="user_type")
+            if type_node is None:
+                continue
+            name = self._normalize_type(type_node)
+            names.append(name)
+            self._queue_type_reference("BASE_TYPE", type_id, name, external_name=name.split("<", 1)[0])
+        if names:
+            self.nodes[type_id].properties["supertypes"] = names
+    
+    def _parse_constructor_property(self, class_parameter: SgNode, type_id: str) -> None:
+        """A primary constructor parameter declared with val or var becomes a ClassVariable."""
+        binding = next((c.kind() for c in class_parameter.children() if c.kind() in ("val", "var")), None)
+        name_node = next((c for c in class_parameter.children() if c.kind() == "simple_identifier"), None)
+        if binding is None or name_node is None:
+            return
+        properties: Dict[str, Any] = {"constructor_property": True}
+        if binding == "var":
+            properties["mutable"] = True
+        var_node_id = self._add_field(class_parameter, name_node.text(),
+                                      self._normalize_type(self._type_child(class_parameter)), type_id, properties)
+        self._parse_modifiers(class_parameter, var_node_id)
+    
+    def _parse_function(self, function: SgNode, parent_id: str, enclosing: Optional[str],
+                        build_index: bool, module_name: str) -> Optional[str]:
+        """
+        Extract a function or secondary constructor.
+        
+        The receiver of an extension function is the type before its name,
+        the return type the one after its parameters. Returns the function
+        signature, None for constructors.
+        """
+        constructor = function.kind() == "secondary_constructor"
+        name = enclosing.rsplit("$", 1)[-1] if constructor else self._declaration_name(function)
+        if name is None:
+            return None
+        receiver, parameters, return_type = None, None, None
+        seen_name = constructor
+        for child in function.children():
+            if child.kind() == "simple_identifier":
+                seen_name = True
+            elif child.kind() == "function_value_parameters":
+                parameters = child
+            elif child.kind() in KOTLIN_TYPE_KINDS:
+                if not seen_name:
+                    receiver = child
+                elif parameters is not None:
+                    return_type = child
+        
+        properties: Dict[str, Any] = {"constructor": True} if constructor else {}
+        return self._add_function(function, name, parameters, receiver, return_type, parent_id, enclosing,
+                                  build_index, properties, module_name)
+    
+    def _add_function(self, function: SgNode, name: str, parameters: Optional[SgNode], receiver: Optional[SgNode],
+                      return_type: Optional[SgNode], parent_id: str, enclosing: Optional[str], build_index: bool,
+                      properties: Dict[str, Any], module_name: Optional[str] = None) -> Optional[str]:
+        """
+        Create a Function (top level) or Method node and link it to its file or type.
+        
+        Returns the signature, None for constructors.
+        """
+        line_no = function.range().start.line + 1
+        entries = self._parameter_entries(parameters)
+        types = [("vararg " if entry["variadic"] else "") + (entry["type"] or "") for entry in entries]
+        signature = f"{name}({','.join(types)})"
+        properties["signature"] = signature
+        if return_type is not None:
+            properties["return_type"] = self._normalize_type(return_type)
+        if receiver is not None:
+            properties["receiver_type"] = self._normalize_type(receiver)
+            properties["extension"] = True
+        returns = []
+        if properties.get("return_type") and properties["return_type"] != "Unit":
+            returns = [{"name": None, "type": properties["return_type"]}]
+        properties.update(signature_properties(entries, returns, self._type_parameters(function)))
+        properties.update(syntax_metrics(function, KOTLIN_METRIC_RULES, line_no, function.range().end.line + 1))
+        
+        node_type = "Method" if enclosing else "Function"
+        node_id = self._get_node_id(node_type, name, self.current_file, line_no)
+        self.nodes[node_id] = CodeNode(
+            node_id=node_id,
+            node_type=node_type,
+            name=name,
+            file_path=self.current_file,
+            line_no=line_no,
+            end_line_no=function.range().end.line + 1,
+            properties=properties,
+        )
+        if not properties.get("primary"):
+            self._attach_javadoc(node_id, function)
+            self._parse_modifiers(function, node_id)
+        self._add_relation(CodeRelation(parent_id, node_id, "DEFINES" if enclosing else "CONTAINS"))
+        if receiver is not None:
+            self._queue_type_reference("EXTENSION_OF", node_id, self._reference_name(receiver))
+        
+        # Overloads are told apart by signature, the plain name finds the first one
+        if build_index:
+            symbols = self.module_definitions[self.current_package_key]
+            prefix = f"{enclosing}." if enclosing else ""
+            symbols[f"{prefix}{signature}"] = node_id
+            symbols.setdefault(f"{prefix}{name}", node_id)
+            if not enclosing and module_name:
+                self.module_definitions[module_name].setdefault(name, node_id)
+        return None if properties.get("constructor") else signature
+    
+    def _parameter_entries(self, parameters: Optional[SgNode]) -> List[Dict[str, Any]]:
+        """
+        Parameter entries (see signatures.py) of function_value_parameters or class_parameters.
+        
+        `vararg` parameters are variadic; defaults follow their parameter,
+        or end a class parameter.
+        """
+        entries: List[Dict[str, Any]] = []
+        variadic = False
+        default_next = False
+        for child in parameters.children() if parameters is not None else []:
+            kind = child.kind()
+            if kind == "parameter_modifiers":
+                variadic = "vararg" in child.text().split()
+            elif kind in ("parameter", "class_parameter"):
+                name_node = next((c for c in child.children() if c.kind() == "simple_identifier"), None)
+                if kind == "class_parameter":
+                    variadic = "vararg" in self._modifier_keywords(child)
+                entries.append(parameter(name_node.text() if name_node is not None else None,
+                                         self._normalize_type(self._type_child(child)),
+                                         self._default_value(child), variadic=variadic))
+                variadic = False
+            elif kind == "=":
+                default_next = bool(entries)
+            elif default_next and child.is_named():
+                entries[-1]["default"] = collapse(child.text())
+                default_next = False
+        return entries
+    
+    def _default_value(self, node: SgNode) -> Optional[str]:
+        """Text of the expression after `=` among the children of node."""
+        children = node.children()
+        for index, child in enumerate(children):
+            if child.kind() == "=":
+                value = next((c for c in children[index + 1:] if c.is_named()), None)
+                return value.text() if value is not None else None
+        return None
+    
+    def _type_parameters(self, declaration: SgNode) -> List[Dict[str, Any]]:
+        """<T : Comparable<T>> of a function: {"name", "constraint"} entries."""
+        type_parameters = []
+        type_parameter_list = next((c for c in declaration.children() if c.kind() == "type_parameters"), None)
+        for type_parameter in type_parameter_list.children() if type_parameter_list is not None else []:
+            if type_parameter.kind() != "type_parameter":
+                continue
+            name = next((c for c in type_parameter.children() if c.kind() == "type_identifier"), None)
+            if name is None:
+                continue
+            bound = self._type_child(type_parameter)
+            type_parameters.append({"name": name.text(), "constraint": self._normalize_type(bound) or None})
+        return type_parameters
+    
+    def _parse_property(self, declaration: SgNode, accessors: List[SgNode], parent_id: str,
+                        enclosing: Optional[str], build_index: bool, module_name: str) -> None:
+        """
+        Extract a property: a ClassVariable in a type, a Variable or (const val) Constant at top level.
+        
+        The getter and setter are part of the node, flagged in
+        "custom_getter" and "custom_setter"; `by lazy { ... }` and other
+        delegates are kept in "delegate".
+        """
+        receiver = None
+        variables: List[SgNode] = []
+        for child in declaration.children():
+            if child.kind() == "variable_declaration":
+                variables.append(child)
+            elif child.kind() == "multi_variable_declaration":
+                variables.extend(c for c in child.children() if c.kind() == "variable_declaration")
+            elif child.kind() in KOTLIN_TYPE_KINDS and not variables:
+                receiver = child
+        
+        keywords = self._modifier_keywords(declaration)
+        if enclosing:
+            node_type = "ClassVariable"
+        else:
+            node_type = "Constant" if "const" in keywords else "Variable"
+        end_line_no = max([declaration.range().end.line] + [a.range().end.line for a in accessors]) + 1
+        delegate = next((c for c in declaration.children() if c.kind() == "property_delegate"), None)
+        
+        for variable in variables:
+            name_node = next((c for c in variable.children() if c.kind() == "simple_identifier"), None)
+            if name_node is None:
+                continue
+            name = name_node.text()
+            line_no = declaration.range().start.line + 1
+            properties: Dict[str, Any] = {}
+            type_node = self._type_child(variable)
+            if type_node is not None:
+                properties["type"] = self._normalize_type(type_node)
+            if any(c.kind() == "var" for c in declaration.children()):
+                properties["mutable"] = True
+            for accessor in accessors:
+                if any(c.kind() in ("function_body", "block") for c in accessor.children()):
+                    properties[f"custom_{accessor.kind()}"] = True
+            if delegate is not None:
+                properties["delegate"] = collapse(delegate.text()[len("by"):])
+            if receiver is not None:
+                properties["receiver_type"] = self._normalize_type(receiver)
+                properties["extension"] = True
+            
+            var_node_id = self._get_node_id("Variable" if enclosing else node_type, name, self.current_file, line_no)
+            self.nodes[var_node_id] = CodeNode(
+                node_id=var_node_id,
+                node_type=node_type,
+                name=name,
+                file_path=self.current_file,
+                line_no=line_no,
+                end_line_no=end_line_no,
+                properties=properties,
+            )
+            self._attach_javadoc(var_node_id, declaration)
+            self._parse_modifiers(declaration, var_node_id)
+            self._add_relation(CodeRelation(parent_id, var_node_id, "DEFINES" if enclosing else "CONTAINS"))
+            if receiver is not None:
+                self._queue_type_reference("EXTENSION_OF", var_node_id, self._reference_name(receiver))
+            if build_index and not enclosing:
+                self.module_definitions[self.current_package_key].setdefault(name, var_node_id)
+                self.module_definitions[module_name].setdefault(name, var_node_id)
+    
+    def _parse_type_alias(self, alias: SgNode, parent_id: str, build_index: bool, module_name: str) -> None:
+        """Extract a typealias as a TypeAlias node indexed like a type."""
+        name_node = next((c for c in alias.children() if c.kind() == "type_identifier"), None)
+        if name_node is None:
+            return
+        properties: Dict[str, Any] = {"kind": "typealias"}
+        aliased = [c for c in alias.children() if c.kind() in KOTLIN_TYPE_KINDS]
+        if aliased:
+            properties["type"] = self._normalize_type(aliased[-1])
+        alias_id = self._add_type(alias, "TypeAlias", name_node.text(), properties, parent_id, build_index,
+                                  module_name)
+        self._attach_javadoc(alias_id, alias)
+        self._parse_modifiers(alias, alias_id)
@@ -1,5 +1,7 @@
 """Python language adapter using ast-grep for AST parsing."""
 
+import ast
+import inspect
 import os
 import json
 from typing import Dict, List, Optional, Tuple, Any, Union, Set
@@ -8,6 +10,17 @@ from ast_grep_py import SgRoot, SgNode
 
 from .base_adapter import LanguageAdapter
 from ast_parser.parser import CodeNode, CodeRelation
+from ast_parser.decorators import annotation_properties, is_dataclass, python_decorators
+from ast_parser.metrics import python_metrics
+from ast_parser.signatures import python_signature
+from ast_parser.variables import python_assignments, python_uses, queue_uses
+
+# Statements whose definitions are marked conditional, as ASTParser's CONDITIONAL_BLOCKS
+CONDITIONAL_STATEMENTS = ("if_statement", "try_statement")
+# Clauses of a try statement, conditional like its body
+TRY_CLAUSES = ("except_clause", "except_group_clause", "else_clause", "finally_clause")
+
+DefinitionNode = Union[ast.FunctionDef, ast.AsyncFunctionDef, ast.ClassDef]
 
 
 class PythonAstGrepAdapter(LanguageAdapter):
@@ -15,9 +28,13 @@ class PythonAstGrepAdapter(LanguageAdapter):
     Python adapter using ast-grep library.
     
     Extracts identical information to ASTParser for parity:
-    - File, Class, Method, Function nodes
-    - ClassVariable, LocalVariable, GlobalVariable nodes
-    - CONTAINS, DEFINES, EXTENDS, CALLS relations
+    - File, Class, Method, Function nodes, including nested functions
+      ("nested") and definitions under if/try blocks ("conditional", with
+      the if conditions in "condition")
+    - ClassVariable nodes, Constant and Variable nodes for module-level names
+    - CONTAINS, DEFINES, EXTENDS, CALLS, USES, DECORATED_BY relations
+    - Docstrings of modules, classes and functions in "doc"
+    - "is_async", "decorators" and dataclass fields (see decorators.py)
     - Import tracking for cross-file dependency resolution
     """
     
@@ -29,6 +46,14 @@ class PythonAstGrepAdapter(LanguageAdapter):
         self.current_function: Optional[str] = None
         # Import tracking: maps alias -> full module path
         self.imports: Dict[str, str] = {}
+        # Aliases bound by `import x`, which name the module rather than a symbol in it
+        self.module_aliases: Set[str] = set()
+        self.current_module: str = ""
+        # Line of each `def` and `class` -> its ast node, for python_signature, python_metrics,
+        # python_uses and python_decorators
+        self.definitions: Dict[int, DefinitionNode] = {}
+        # (line, column) of each module-level assignment -> its ast node, for python_assignments
+        self.assignments: Dict[Tuple[int, int], Union[ast.Assign, ast.AnnAssign]] = {}
     
     def parse_file(self, file_path: str, build_index: bool = False) -> Tuple[Dict[str, CodeNode], List[CodeRelation]]:
         """
@@ -39,6 +64,7 @@ class PythonAstGrepAdapter(LanguageAdapter):
         print(f"Parsing file: {file_path}")
         self.current_file = file_path
         self.imports = {}
+        self.module_aliases = set()
         
         try:
             # Read source code
@@ -47,12 +73,17 @@ class PythonAstGrepAdapter(LanguageAdapter):
             
             # Parse with ast-grep
             root = SgRoot(source, "python").root()
+            tree = self._parse_tree(source)
+            self.definitions = self._index_definitions(tree)
+            self.assignments = self._index_assignments(tree)
             
             # Create file node
             file_node_id = self._create_file_node(file_path)
+            self._set_doc(file_node_id, root)
             
             # Generate module name for indexing
             module_name = os.path.splitext(os.path.basename(file_path))[0]
+            self.current_module = module_name
             if build_index:
                 if module_name not in self.module_definitions:
                     self.module_definitions[module_name] = {}
@@ -61,14 +92,13 @@ class PythonAstGrepAdapter(LanguageAdapter):
             
             # Extract all top-level entities
             self._parse_imports(root)
-            self._parse_classes(root, build_index, module_name)
-            self._parse_top_level_functions(root, build_index, module_name)
-            self._parse_global_variables(root, file_node_id)
+            self._parse_definitions(root, file_node_id, build_index, module_name, [])
             
             return self.nodes, self.relations
             
         except Exception as e:
             print(f"Error parsing file {file_path}: {e}")
+            self.parse_error = e
             return {}, []
     
     def _parse_imports(self, root: SgNode) -> None:
@@ -83,6 +113,7 @@ class PythonAstGrepAdapter(LanguageAdapter):
                     # Simple import: import module
                     import_name = child.text()
                     self.imports[import_name] = import_name
+                    self.module_aliases.add(import_name)
                     
                     root_module = import_name.split('.')[0]
                     self.pending_imports.append({
@@ -90,7 +121,8 @@ class PythonAstGrepAdapter(LanguageAdapter):
                         "source_id": file_node_id,
                         "imported_module": root_module,
                         "full_module_path": import_name,
-                        "alias": import_name
+                        "alias": import_name,
+                        "line_no": import_node.range().start.line + 1
                     })
                     
                 elif child.kind() == "aliased_import":
@@ -101,6 +133,7 @@ class PythonAstGrepAdapter(LanguageAdapter):
                         import_name = name_node.text()
                         alias_name = alias_node.text()
                         self.imports[alias_name] = import_name
+                        self.module_aliases.add(alias_name)
                         
                         root_module = import_name.split('.')[0]
                         self.pending_imports.append({
@@ -108,16 +141,24 @@ class PythonAstGrepAdapter(LanguageAdapter):
                             "source_id": file_node_id,
                             "imported_module": root_module,
                             "full_module_path": import_name,
-                            "alias": alias_name
+                            "alias": alias_name,
+                            "line_no": import_node.range().start.line + 1
                         })
         
         # Handle "from module import symbol" statements
         for import_from in root.find_all(kind="import_from_statement"):
             module_name_node = import_from.field("module_name")
-            module_name = module_name_node.text() if module_name_node else None
+            module_text = module_name_node.text() if module_name_node else ""
+            # Relative imports: the leading dots are the level, like ast.ImportFrom
+            level = len(module_text) - len(module_text.lstrip("."))
+            module_name = module_text.lstrip(".") or None
+            line_no = import_from.range().start.line + 1
             
             # Find imported names (can be dotted_name, aliased_import, or wildcard_import)
             for child in import_from.children():
+                if module_name_node is not None and child.range().start.index == module_name_node.range().start.index:
+                    # The module name is a dotted_name child too
+                    continue
                 if child.kind() == "dotted_name":
                     # from module import symbol
                     symbol_name = child.text()
@@ -132,7 +173,9 @@ class PythonAstGrepAdapter(LanguageAdapter):
                         "source_id": file_node_id,
                         "imported_module": module_name,
                         "imported_name": symbol_name,
-                        "alias": symbol_name
+                        "alias": symbol_name,
+                        "level": level,
+                        "line_no": line_no
                     })
                     
                 elif child.kind() == "aliased_import":
@@ -153,21 +196,105 @@ class PythonAstGrepAdapter(LanguageAdapter):
                             "source_id": file_node_id,
                             "imported_module": module_name,
                             "imported_name": symbol_name,
-                            "alias": alias_name
+                            "alias": alias_name,
+                            "level": level,
+                            "line_no": line_no
                         })
     
-    def _parse_classes(self, root: SgNode, build_index: bool, module_name: str) -> None:
-        """Extract all class definitions."""
-        for class_node in root.find_all(kind="class_definition"):
-            # Only process top-level classes (not nested classes)
-            # Check if parent is module (root)
-            parent = class_node.parent()
-            if parent and parent.kind() != "module":
-                continue
-            
-            self._parse_class(class_node, build_index, module_name)
+    def _parse_definitions(self, block: SgNode, file_node_id: str, build_index: bool, module_name: str,
+                           conditions: List[Optional[str]]) -> None:
+        """
+        Parse the classes, functions and assignments of a module-level block, as ASTParser does.
+        
+        Blocks of if/try statements add their condition (None for try) to
+        conditions; other compound statements (with, for, ...) are searched
+        as they are.
+        """
+        for child in block.children():
+            kind = child.kind()
+            definition = child.field("definition") if kind == "decorated_definition" else child
+            if definition.kind() == "class_definition":
+                self._parse_class(definition, build_index, module_name, conditions)
+            elif definition.kind() == "function_definition":
+                self._parse_function(definition, build_index, module_name, conditions)
+            elif kind == "expression_statement":
+                for expr_child in child.children():
+                    if expr_child.kind() == "assignment":
+                        self._parse_assignment(expr_child, file_node_id, build_index, module_name, conditions)
+            elif kind in CONDITIONAL_STATEMENTS:
+                for branch_conditions, branch in self._conditional_blocks(child):
+                    self._parse_definitions(branch, file_node_id, build_index, module_name,
+                                            conditions + branch_conditions)
+            elif kind == "block" or kind.endswith(("_statement", "_clause")):
+                self._parse_definitions(child, file_node_id, build_index, module_name, conditions)
+    
+    def _conditional_blocks(self, node: SgNode) -> List[Tuple[List[Optional[str]], SgNode]]:
+        """
+        The blocks of an if/try statement with the conditions they add, like ASTParser's.
+        
+        An elif or else block adds the negations of the conditions before it,
+        as the nested if in the else branch of ast.If does.
+        """
+        if node.kind() == "try_statement":
+            clauses = [node] + [child for child in node.children() if child.kind() in TRY_CLAUSES]
+            blocks = [([None], self._block(clause)) for clause in clauses]
+        else:
+            blocks = []
+            negations: List[Optional[str]] = []
+            clauses = [node] + [child for child in node.children() if child.kind() in ("elif_clause", "else_clause")]
+            for clause in clauses:
+                if clause.kind() == "else_clause":
+                    blocks.append((negations, self._block(clause)))
+                    continue
+                test = self._unparse(clause.field("condition"))
+                blocks.append((negations + [test], self._block(clause)))
+                negations = negations + [f"not ({test})"]
+        return [(conditions, block) for conditions, block in blocks if block is not None]
     
-    def _parse_class(self, class_node: SgNode, build_index: bool, module_name: str) -> str:
+    @staticmethod
+    def _block(clause: SgNode) -> Optional[SgNode]:
+        """The indented block of a statement or clause."""
+        return next((child for child in clause.children() if child.kind() == "block"), None)
+    
+    @staticmethod
+    def _unparse(node: SgNode) -> str:
+        """An expression written as ast.unparse writes it, so texts match ASTParser's."""
+        try:
+            return ast.unparse(ast.parse(node.text().strip(), mode="eval").body)
+        except (SyntaxError, ValueError):
+            return node.text()
+    
+    def _mark_definition_context(self, node_id: str, conditions: List[Optional[str]]) -> None:
+        """Flag a node defined under if/try blocks, recording the if conditions."""
+        if not conditions:
+            return
+        properties = self.nodes[node_id].properties
+        properties["conditional"] = True
+        tests = [condition for condition in conditions if condition]
+        if tests:
+            properties["condition"] = " and ".join(tests)
+    
+    def _parse_decorators(self, definition_node: SgNode, node_id: str) -> Optional[DefinitionNode]:
+        """
+        Record "is_async" and the decorators of a definition and queue DECORATED_BY resolution.
+        
+        Both come from the ast module node of the definition, as in
+        ASTParser, which is returned (None if ast could not parse the file).
+        """
+        definition = self.definitions.get(definition_node.range().start.line + 1)
+        if definition is None:
+            return None
+        properties = self.nodes[node_id].properties
+        if isinstance(definition, ast.AsyncFunctionDef):
+            properties["is_async"] = True
+        decorators, pending = python_decorators(definition, node_id, self.current_module, self.imports)
+        self.pending_imports.extend(pending)
+        if decorators:
+            properties["decorators"] = decorators
+        return definition
+    
+    def _parse_class(self, class_node: SgNode, build_index: bool, module_name: str,
+                     conditions: List[Optional[str]]) -> str:
         """Parse a class definition node."""
         # Get class name
         name_node = class_node.field("name")
@@ -190,6 +317,12 @@ class PythonAstGrepAdapter(LanguageAdapter):
             line_no=line_no,
             end_line_no=end_line_no,
         )
+        self._set_doc(node_id, class_node.field("body"))
+        self._mark_definition_context(node_id, conditions)
+        definition = self._parse_decorators(class_node, node_id)
+        dataclass = definition is not None and is_dataclass(definition)
+        if dataclass:
+            self.nodes[node_id].properties["dataclass"] = True
         
         # Create file CONTAINS class relation
         file_node_id = f"file:{self.current_file}"
@@ -208,6 +341,10 @@ class PythonAstGrepAdapter(LanguageAdapter):
         # Handle inheritance (base classes)
         superclasses = class_node.field("superclasses")
         if superclasses:
+            bases = [child.text() for child in superclasses.children()
+                     if child.is_named() and child.kind() not in ("keyword_argument", "comment")]
+            if bases:
+                self.nodes[node_id].properties["bases"] = bases
             for child in superclasses.children():
                 if child.kind() == "identifier":
                     base_name = child.text()
@@ -238,25 +375,29 @@ class PythonAstGrepAdapter(LanguageAdapter):
         
         body = class_node.field("body")
         if body:
-            # Find methods and class attributes
-            for child in body.children():
-                if child.kind() == "function_definition":
-                    self._parse_method(child)
-                elif child.kind() == "decorated_definition":
-                    # Decorated methods (@staticmethod, @classmethod, etc.)
-                    definition = child.field("definition")
-                    if definition and definition.kind() == "function_definition":
-                        self._parse_method(definition)
-                elif child.kind() == "expression_statement":
-                    # May contain assignments (class attributes)
-                    for expr_child in child.children():
-                        if expr_child.kind() == "assignment":
-                            self._parse_class_attribute(expr_child)
+            self._parse_class_body(body, dataclass, [])
         
         self.current_class = prev_class
         return node_id
     
-    def _parse_method(self, method_node: SgNode) -> None:
+    def _parse_class_body(self, block: SgNode, dataclass: bool, conditions: List[Optional[str]]) -> None:
+        """Parse the methods and attributes of a class body, including if/try blocks."""
+        for child in block.children():
+            kind = child.kind()
+            # Decorated methods (@staticmethod, @classmethod, etc.)
+            definition = child.field("definition") if kind == "decorated_definition" else child
+            if definition.kind() == "function_definition":
+                self._parse_method(definition, conditions)
+            elif kind == "expression_statement":
+                # May contain assignments (class attributes)
+                for expr_child in child.children():
+                    if expr_child.kind() == "assignment":
+                        self._parse_class_attribute(expr_child, dataclass, conditions)
+            elif kind in CONDITIONAL_STATEMENTS:
+                for branch_conditions, branch in self._conditional_blocks(child):
+                    self._parse_class_body(branch, dataclass, conditions + branch_conditions)
+    
+    def _parse_method(self, method_node: SgNode, conditions: List[Optional[str]]) -> None:
         """Parse a method definition inside a class."""
         name_node = method_node.field("name")
         if not name_node:
@@ -278,6 +419,9 @@ class PythonAstGrepAdapter(LanguageAdapter):
             end_line_no=end_line_no,
             properties={"is_method": True},
         )
+        self._set_doc(node_id, method_node.field("body"))
+        self._mark_definition_context(node_id, conditions)
+        self._parse_decorators(method_node, node_id)
         
         # Create class DEFINES method relation
         if self.current_class:
@@ -299,27 +443,13 @@ class PythonAstGrepAdapter(LanguageAdapter):
         body = method_node.field("body")
         if body:
             self._find_function_calls(body)
+        self._find_variable_uses(method_node)
         
         self.current_function = prev_function
     
-    def _parse_top_level_functions(self, root: SgNode, build_index: bool, module_name: str) -> None:
-        """Extract top-level function definitions."""
-        for func_node in root.find_all(kind="function_definition"):
-            # Only process top-level functions (not methods or nested functions)
-            parent = func_node.parent()
-            if parent and parent.kind() != "module":
-                # Check if it's a decorated definition at module level
-                if parent.kind() == "decorated_definition":
-                    grandparent = parent.parent()
-                    if grandparent and grandparent.kind() == "module":
-                        # This is a top-level decorated function
-                        self._parse_function(func_node, build_index, module_name)
-                continue
-            
-            self._parse_function(func_node, build_index, module_name)
-    
-    def _parse_function(self, func_node: SgNode, build_index: bool, module_name: str) -> str:
-        """Parse a top-level function definition."""
+    def _parse_function(self, func_node: SgNode, build_index: bool, module_name: str,
+                        conditions: List[Optional[str]], nested: bool = False) -> str:
+        """Parse a function definition; nested functions are defined by the enclosing function."""
         name_node = func_node.field("name")
         if not name_node:
             return ""
@@ -340,19 +470,33 @@ class PythonAstGrepAdapter(LanguageAdapter):
             end_line_no=end_line_no,
             properties={"is_method": False},
         )
+        self._set_doc(node_id, func_node.field("body"))
+        self._mark_definition_context(node_id, conditions)
+        self._parse_decorators(func_node, node_id)
         
-        # Create file CONTAINS function relation
-        file_node_id = f"file:{self.current_file}"
-        self.relations.append(
-            CodeRelation(
-                source_id=file_node_id,
-                target_id=node_id,
-                relation_type="CONTAINS",
+        if nested:
+            # The enclosing function defines the nested function
+            self.nodes[node_id].properties["nested"] = True
+            self.relations.append(
+                CodeRelation(
+                    source_id=self.current_function,
+                    target_id=node_id,
+                    relation_type="DEFINES",
+                )
+            )
+        else:
+            # Create file CONTAINS function relation
+            file_node_id = f"file:{self.current_file}"
+            self.relations.append(
+                CodeRelation(
+                    source_id=file_node_id,
+                    target_id=node_id,
+                    relation_type="CONTAINS",
+                )
             )
-        )
         
         # Index function for cross-file resolution
-        if build_index and module_name:
+        if build_index and module_name and not nested:
             self.module_definitions[module_name][func_name] = node_id
         
         # Parse function arguments
@@ -365,10 +509,33 @@ class PythonAstGrepAdapter(LanguageAdapter):
         body = func_node.field("body")
         if body:
             self._find_function_calls(body)
+        self._find_variable_uses(func_node)
         
         self.current_function = prev_function
         return node_id
     
+    def _set_doc(self, node_id: str, block: Optional[SgNode]) -> None:
+        """
+        Store the docstring of a module, class or function body as "doc".
+        
+        Like ast.get_docstring, only a plain string literal as the first
+        statement counts, and its indentation is cleaned.
+        """
+        if block is None:
+            return
+        first = next((c for c in block.children() if c.is_named() and c.kind() != "comment"), None)
+        if first is None or first.kind() != "expression_statement":
+            return
+        string = next((c for c in first.children() if c.is_named()), None)
+        if string is None or string.kind() != "string":
+            return
+        try:
+            value = ast.literal_eval(string.text())
+        except (ValueError, SyntaxError):
+            return
+        if isinstance(value, str) and value.strip():
+            self.nodes[node_id].properties["doc"] = inspect.cleandoc(value)
+    
     def _parse_function_args(self, func_node: SgNode, node_id: str) -> None:
         """Extract function/method parameters."""
         params_node = func_node.field("parameters")
@@ -400,102 +567,90 @@ class PythonAstGrepAdapter(LanguageAdapter):
         # Store args as JSON string (matching ASTParser behavior)
         if args:
             self.nodes[node_id].properties["args"] = json.dumps(args)
+        self._set_signature(func_node, node_id)
     
-    def _parse_global_variables(self, root: SgNode, file_node_id: str) -> None:
-        """Extract global-level variable assignments."""
-        # Find all assignments at module level (including in if __name__ == "__main__" blocks)
-        for assign_node in root.find_all(kind="assignment"):
-            # Check if this assignment is at global scope (not inside a function or class)
-            parent = assign_node.parent()
-            while parent:
-                parent_kind = parent.kind()
-                # Skip if inside a function or class definition
-                if parent_kind in ["function_definition", "class_definition"]:
-                    break
-                # If we reach module level, it's a global variable
-                if parent_kind == "module":
-                    self._parse_assignment(assign_node, file_node_id, is_global=True)
-                    break
-                parent = parent.parent()
+    @staticmethod
+    def _parse_tree(source: str) -> Optional[ast.Module]:
+        """The ast module tree of a file, None if ast cannot parse it."""
+        try:
+            return ast.parse(source)
+        except (SyntaxError, ValueError):
+            return None
     
-    def _parse_assignment(self, assign_node: SgNode, file_node_id: str, is_global: bool = False) -> None:
-        """Parse an assignment to extract variable nodes."""
-        # Get left-hand side (target)
-        left = assign_node.field("left")
-        if not left:
+    @staticmethod
+    def _index_definitions(tree: Optional[ast.Module]) -> Dict[int, DefinitionNode]:
+        """Function and class definitions of a file by the line of their `def` or `class`."""
+        if tree is None:
+            return {}
+        return {
+            node.lineno: node for node in ast.walk(tree)
+            if isinstance(node, (ast.FunctionDef, ast.AsyncFunctionDef, ast.ClassDef))
+        }
+    
+    @staticmethod
+    def _index_assignments(tree: Optional[ast.Module]) -> Dict[Tuple[int, int], Union[ast.Assign, ast.AnnAssign]]:
+        """Assignments outside any function or class by their (line, column)."""
+        assignments = {}
+        stack = list(tree.body) if tree is not None else []
+        while stack:
+            node = stack.pop()
+            if isinstance(node, (ast.Assign, ast.AnnAssign)):
+                assignments[(node.lineno, node.col_offset)] = node
+            elif not isinstance(node, (ast.FunctionDef, ast.AsyncFunctionDef, ast.ClassDef)):
+                stack.extend(child for child in ast.iter_child_nodes(node) if isinstance(child, ast.stmt))
+        return assignments
+    
+    def _set_signature(self, func_node: SgNode, node_id: str) -> None:
+        """Store the structured signature and metrics of a function or method, as ASTParser does."""
+        definition = self.definitions.get(func_node.range().start.line + 1)
+        if definition is not None:
+            node = self.nodes[node_id]
+            node.properties.update(python_signature(definition, is_method=node.node_type == "Method"))
+            node.properties.update(python_metrics(definition))
+    
+    def _find_variable_uses(self, func_node: SgNode) -> None:
+        """Queue USES of the module-level names a function or method uses, as ASTParser does."""
+        definition = self.definitions.get(func_node.range().start.line + 1)
+        if definition is not None:
+            uses = python_uses(definition, self.current_module, self.imports, self.module_aliases)
+            queue_uses(self.pending_imports, self.current_function, uses)
+    
+    def _parse_assignment(self, assign_node: SgNode, file_node_id: str, build_index: bool, module_name: str,
+                          conditions: List[Optional[str]]) -> None:
+        """A Constant or Variable node per name a module-level assignment binds, as ASTParser does."""
+        start = assign_node.range().start
+        # The right side of `a = b = 1` is an assignment too; the outer one binds both names
+        statement = self.assignments.get((start.line + 1, start.column))
+        if statement is None:
             return
-        
-        # Extract variable name(s)
-        var_names = []
-        if left.kind() == "identifier":
-            var_names.append(left.text())
-        elif left.kind() == "pattern_list":
-            # Multiple assignment: a, b = ...
-            for child in left.children():
-                if child.kind() == "identifier":
-                    var_names.append(child.text())
-        
-        line_no = assign_node.range().start.line + 1
+        line_no = start.line + 1
         end_line_no = assign_node.range().end.line + 1
         
-        for var_name in var_names:
-            if self.current_class and not self.current_function:
-                # Class attribute
-                node_id = self._get_node_id("ClassVariable", var_name, self.current_file, line_no)
-                self.nodes[node_id] = CodeNode(
-                    node_id=node_id,
-                    node_type="ClassVariable",
-                    name=var_name,
-                    file_path=self.current_file,
-                    line_no=line_no,
-                    end_line_no=end_line_no,
-                )
-                self.relations.append(
-                    CodeRelation(
-                        source_id=self.current_class,
-                        target_id=node_id,
-                        relation_type="DEFINES",
-                    )
-                )
-            elif self.current_function:
-                # Local variable
-                node_id = self._get_node_id("LocalVariable", var_name, self.current_file, line_no)
-                self.nodes[node_id] = CodeNode(
-                    node_id=node_id,
-                    node_type="LocalVariable",
-                    name=var_name,
-                    file_path=self.current_file,
-                    line_no=line_no,
-                    end_line_no=end_line_no,
-                )
-                self.relations.append(
-                    CodeRelation(
-                        source_id=self.current_function,
-                        target_id=node_id,
-                        relation_type="DEFINES",
-                    )
-                )
-            elif is_global:
-                # Global variable (use "Variable" prefix for ID to match legacy parser)
-                node_id = self._get_node_id("Variable", var_name, self.current_file, line_no)
-                self.nodes[node_id] = CodeNode(
-                    node_id=node_id,
-                    node_type="GlobalVariable",
-                    name=var_name,
-                    file_path=self.current_file,
-                    line_no=line_no,
-                    end_line_no=end_line_no,
-                )
-                self.relations.append(
-                    CodeRelation(
-                        source_id=file_node_id,
-                        target_id=node_id,
-                        relation_type="DEFINES",
-                    )
+        for var_name, node_type, properties in python_assignments(statement):
+            node_id = self._get_node_id(node_type, var_name, self.current_file, line_no)
+            self.nodes[node_id] = CodeNode(
+                node_id=node_id,
+                node_type=node_type,
+                name=var_name,
+                file_path=self.current_file,
+                line_no=line_no,
+                end_line_no=end_line_no,
+                properties=properties,
+            )
+            self._mark_definition_context(node_id, conditions)
+            self.relations.append(
+                CodeRelation(
+                    source_id=file_node_id,
+                    target_id=node_id,
+                    relation_type="DEFINES",
                 )
+            )
+            # A name assigned again keeps its first definition
+            if build_index and module_name:
+                self.module_definitions[module_name].setdefault(var_name, node_id)
     
-    def _parse_class_attribute(self, assign_node: SgNode) -> None:
-        """Parse class-level attribute assignments."""
+    def _parse_class_attribute(self, assign_node: SgNode, dataclass: bool, conditions: List[Optional[str]]) -> None:
+        """Parse class-level attribute assignments; annotated ones may be dataclass fields."""
         left = assign_node.field("left")
         if not left or left.kind() != "identifier":
             return
@@ -505,6 +660,7 @@ class PythonAstGrepAdapter(LanguageAdapter):
         end_line_no = assign_node.range().end.line + 1
         
         node_id = self._get_node_id("ClassVariable", var_name, self.current_file, line_no)
+        annotation = assign_node.field("type")
         
         self.nodes[node_id] = CodeNode(
             node_id=node_id,
@@ -514,6 +670,11 @@ class PythonAstGrepAdapter(LanguageAdapter):
             line_no=line_no,
             end_line_no=end_line_no,
         )
+        if annotation is not None:
+            # ASTParser marks only annotated attributes conditional
+            self.nodes[node_id].properties.update(annotation_properties(
+                self._unparse(annotation), assign_node.field("right") is not None, dataclass))
+            self._mark_definition_context(node_id, conditions)
         
         if self.current_class:
             self.relations.append(
@@ -525,10 +686,23 @@ class PythonAstGrepAdapter(LanguageAdapter):
             )
     
     def _find_function_calls(self, node: SgNode) -> None:
-        """Recursively find all function/method calls in a node."""
-        # Find all call expressions in this subtree
-        for call_node in node.find_all(kind="call"):
-            self._process_call(call_node)
+        """
+        Find all function/method calls in a function body.
+        
+        Nested functions get their own node, which owns the calls in their
+        body, as in ASTParser; their decorators are not searched.
+        """
+        stack = list(reversed(node.children()))
+        while stack:
+            child = stack.pop()
+            kind = child.kind()
+            definition = child.field("definition") if kind == "decorated_definition" else child
+            if definition.kind() == "function_definition":
+                self._parse_function(definition, False, "", [], nested=True)
+                continue
+            if kind == "call":
+                self._process_call(child)
+            stack.extend(reversed(child.children()))
     
     def _process_call(self, call_node: SgNode) -> None:
         """Process a single call node."""
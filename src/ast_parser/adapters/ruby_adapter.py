@@ -0,0 +1,739 @@
+"""Ruby language adapter using ast-grep for AST parsing."""
+
+import os
+import re
+from typing import Any, Dict, List, Optional, Tuple
+
+from ast_grep_py import SgRoot, SgNode
+
+from .base_adapter import LanguageAdapter
+from ast_parser.parser import RUBY_ASSOCIATIONS, CodeNode, CodeRelation
+from ast_parser.doc_comments import is_license_header, normalize_comment
+from ast_parser.metrics import RUBY_METRIC_RULES, syntax_metrics
+from ast_parser.signatures import collapse, parameter, signature_properties
+from ast_parser.variables import literal_value
+
+
+# module_definitions key of every Ruby constant (class, module, constant
+# and method), indexed by its qualified name
+RUBY_CONSTANTS = "ruby:"
+
+# attr_* macro -> (getter, setter)
+ATTRIBUTE_MACROS = {
+    "attr_accessor": (True, True),
+    "attr_reader": (True, False),
+    "attr_writer": (False, True),
+    "attr": (True, False),
+}
+
+MIXIN_CALLS = ("include", "extend", "prepend")
+
+# Associations named in the plural (has_many :posts -> Post)
+PLURAL_ASSOCIATIONS = ("has_many", "has_and_belongs_to_many")
+
+# Association options kept on the edge
+ASSOCIATION_OPTIONS = ("class_name", "through")
+
+VISIBILITIES = ("public", "private", "protected")
+
+# Entries of the directory a `require` looks for lib/ up to
+PROJECT_ROOT_MARKERS = ("Gemfile", ".git")
+
+TYPE_DECLARATIONS = ("class", "module")
+
+# Inflections of ActiveSupport's String#singularize, the common subset, first match wins
+SINGULAR_RULES = [(re.compile(pattern), replacement) for pattern, replacement in (
+    (r"(quiz)zes$", r"\1"),
+    (r"(matr|vert|ind)ices$", r"\1ix"),
+    (r"(alias|status|bus)es$", r"\1"),
+    (r"(x|ch|ss|sh)es$", r"\1"),
+    (r"(m)ovies$", r"\1ovie"),
+    (r"([^aeiouy]|qu)ies$", r"\1y"),
+    (r"([lr])ves$", r"\1f"),
+    (r"([^f])ves$", r"\1fe"),
+    (r"(ss)$", r"\1"),
+    (r"s$", ""),
+)]
+IRREGULAR_SINGULARS = {"people": "person", "men": "man", "women": "woman", "children": "child", "mice": "mouse"}
+UNCOUNTABLE = frozenset({"equipment", "information", "money", "news", "series", "sheep", "species", "fish", "rice"})
+
+
+def singularize(word: str) -> str:
+    """Singular of an association name ("categories" -> "category"), by the common ActiveSupport inflections."""
+    head, _, last = word.rpartition("_")
+    if last in UNCOUNTABLE:
+        return word
+    if last in IRREGULAR_SINGULARS:
+        singular = IRREGULAR_SINGULARS[last]
+    else:
+        singular = next((pattern.sub(replacement, last) for pattern, replacement in SINGULAR_RULES
+                         if pattern.search(last)), last)
+    return f"{head}_{singular}" if head else singular
+
+
+def camelize(name: str) -> str:
+    """Class name of an underscored name ("line_item" -> "LineItem", "admin/user" -> "Admin::User")."""
+    return "::".join("".join(part[:1].upper() + part[1:] for part in segment.split("_"))
+                     for segment in name.split("/"))
+
+
+def association_class_name(macro: str, name: str) -> str:
+    """Class a Rails association names by convention when it has no class_name option."""
+    return camelize(singularize(name) if macro in PLURAL_ASSOCIATIONS else name)
+
+
+def namespace_scopes(qualified_name: str) -> List[str]:
+    """Enclosing namespaces of a qualified name, outermost first ("A::B::C" -> ["A", "A::B", "A::B::C"])."""
+    parts = qualified_name.split("::")
+    return ["::".join(parts[:end]) for end in range(1, len(parts) + 1)]
+
+
+def constant_candidates(name: str, scopes: List[str]) -> List[str]:
+    """
+    Qualified names a constant reference may denote, in lookup order.
+    
+    A name is looked up in each enclosing scope, innermost first, then at
+    the top level, the way Ruby resolves constants lexically; `::Name`
+    only at the top level. Ancestors of the scopes are not searched.
+    """
+    name = "".join(name.split())
+    if name.startswith("::"):
+        return [name[2:]]
+    candidates = [f"{scope}::{name}" for scope in reversed(scopes)]
+    candidates.append(name)
+    return list(dict.fromkeys(candidates))
+
+
+class _Body:
+    """The top level of a file, or a class, module or `class << self` body, being parsed."""
+    
+    def __init__(self, owner_id: str, scopes: List[str], singleton: bool = False):
+        self.owner_id = owner_id
+        # Qualified names of the enclosing class and module declarations, innermost last
+        self.scopes = scopes
+        # Methods of a `class << self` body are class methods
+        self.singleton = singleton
+        # Visibility of the methods defined next, changed by a bare `private`
+        self.visibility = "public"
+        # Name -> node ID of the methods defined so far, for `private :name`
+        self.methods: Dict[str, str] = {}
+        self.class_methods: Dict[str, str] = {}
+    
+    @property
+    def qualified_name(self) -> Optional[str]:
+        """Qualified name of the class or module, None at the top level."""
+        return self.scopes[-1] if self.scopes else None
+
+
+class RubyAdapter(LanguageAdapter):
+    """
+    Ruby adapter using ast-grep library.
+    
+    Extracts Ruby structures:
+    - File, Class (classes, and modules with kind "module"), Method,
+      Function (top-level `def`), Constant and ClassVariable (attributes
+      of the attr_* macros) nodes
+    - CONTAINS, DEFINES relations; in the second pass EXTENDS for
+      `class Foo < Bar` (INHERITS_FROM to an ExternalType placeholder when
+      Bar is not indexed), MIXES_IN for `include`, `extend` and `prepend`
+      with the way in "mixin", and HAS_MANY, HAS_ONE, BELONGS_TO and
+      HAS_AND_BELONGS_TO_MANY for the Rails associations of a model
+    - Import tracking: `require_relative` becomes an IMPORTS edge to the
+      required file; `require` too when the path is found under a `lib/`
+      directory above the file, else an IMPORTS edge to an ExternalPackage
+      named after the gem (the first path segment)
+    
+    Classes, modules, constants and methods carry their qualified_name:
+    `module A; module B; class C` yields "A::B::C", compact `class A::B::C`
+    the same; instance methods are "A::B::C#name" and class methods (`def
+    self.name`, `class << self`) "A::B::C.name", flagged class_method. All
+    of them are indexed by that name (see RUBY_CONSTANTS), and constant
+    references are looked up the way Ruby resolves them lexically, through
+    the enclosing declarations and then the top level (see
+    constant_candidates). A class reopened in several files has a node per
+    declaration; references resolve to the first one indexed.
+    
+    Methods record their "visibility", set by `private` / `protected` /
+    `public` with or without arguments and by `private_class_method`.
+    `attr_accessor`, `attr_reader` and `attr_writer` become ClassVariable
+    nodes with property, getter and setter flags and the macro in
+    "macro"; `define_method(:name)` becomes a Method with dynamic: true.
+    Rails associations resolve the class named by convention
+    (`has_many :line_items` -> LineItem, `belongs_to :author` -> Author)
+    or by their class_name option, looked up in the namespaces of the model
+    first, as Rails does. The class keeps its superclass in "superclass",
+    the mixins in "mixins" ("include Trackable") and the association
+    macros in "associations", resolved or not; `#` comments directly
+    above a declaration are its "doc".
+    
+    Supports Ruby source files (.rb).
+    """
+    
+    def __init__(self):
+        super().__init__("ruby")
+        self.current_file: str = ""
+    
+    @staticmethod
+    def file_key(file_path: str) -> str:
+        """
+        Build the module_to_file key of a Ruby file by its path.
+        
+        `require_relative` names a file, not a module, so the IMPORTS
+        edges of the second pass find their target by this key.
+        """
+        return f"rubyfile:{os.path.normpath(file_path)}"
+    
+    def parse_file(self, file_path: str, build_index: bool = False) -> Tuple[Dict[str, CodeNode], List[CodeRelation]]:
+        """
+        Parse a Ruby file using ast-grep.
+        
+        Extracts the requires and the classes and modules with their
+        methods, constants, attributes, mixins and associations.
+        """
+        self.current_file = file_path
+        
+        try:
+            # Read source code
+            with open(file_path, "r", encoding="utf-8") as f:
+                source = f.read()
+            
+            # Parse with ast-grep (Ruby language)
+            root = SgRoot(source, "ruby").root()
+            
+            # Create file node
+            file_node_id = self._create_file_node(file_path)
+            
+            # Generate module name for indexing
+            module_name = os.path.splitext(os.path.basename(file_path))[0]
+            if build_index:
+                if module_name not in self.module_definitions:
+                    self.module_definitions[module_name] = {}
+                self.module_definitions.setdefault(RUBY_CONSTANTS, {})
+                self.module_to_file[module_name] = file_node_id
+                self.module_to_file[self.file_key(file_path)] = file_node_id
+            
+            # Extract Ruby structures
+            self._parse_file_comments(root, file_node_id)
+            self._parse_statements(root, _Body(file_node_id, []), file_node_id, build_index, module_name)
+            
+            return self.nodes, self.relations
+        
+        except Exception as e:
+            print(f"Error parsing Ruby file {file_path}: {e}")
+            self.parse_error = e
+            return {}, []
+    
+    def _parse_file_comments(self, root: SgNode, file_node_id: str) -> None:
+        """Attach the license header opening the file to the File node ("header")."""
+        first = next((c for c in root.children() if c.kind() != "comment"), None)
+        header = [
+            c.text() for c in root.children()
+            if c.kind() == "comment" and is_license_header(c.text())
+            and (first is None or c.range().end.line < first.range().start.line)
+        ]
+        if header:
+            self.nodes[file_node_id].properties["header"] = normalize_comment("\n".join(header))
+    
+    def _attach_doc(self, node_id: str, statement: SgNode) -> None:
+        """Store the `#` comment block directly above a declaration on its node."""
+        doc = normalize_comment("\n".join(c.text() for c in self._leading_comments(statement, ("comment",))))
+        if doc:
+            self.nodes[node_id].properties["doc"] = doc
+    
+    def _parse_statements(self, parent: Optional[SgNode], body: _Body, file_node_id: str,
+                          build_index: bool, module_name: str) -> None:
+        """Extract the declarations and macro calls among the statements of a file or body."""
+        for statement in (parent.children() if parent is not None else []):
+            if statement.is_named():
+                self._parse_statement(statement, statement, body, body.visibility, file_node_id,
+                                      build_index, module_name)
+    
+    def _parse_statement(self, node: SgNode, statement: SgNode, body: _Body, visibility: str,
+                         file_node_id: str, build_index: bool, module_name: str) -> None:
+        """
+        Extract one statement.
+        
+        node is the statement itself or, for `private def x` and `private
+        attr_reader :x`, the declaration passed to the visibility call;
+        statement is the one the doc comment sits above.
+        """
+        kind = node.kind()
+        if kind in TYPE_DECLARATIONS:
+            self._parse_type(node, body, file_node_id, build_index, module_name)
+        elif kind == "singleton_class":
+            # class << self: its methods are class methods
+            value = node.field("value")
+            if value is not None and value.text() == "self" and body.scopes:
+                self._parse_statements(self._body(node), _Body(body.owner_id, body.scopes, singleton=True),
+                                       file_node_id, build_index, module_name)
+        elif kind in ("method", "singleton_method"):
+            self._parse_method(node, statement, body, visibility, build_index, module_name)
+        elif kind == "assignment":
+            left = node.field("left")
+            if left is not None and left.kind() == "constant":
+                self._parse_constant(node, left.text(), body, build_index, module_name)
+        elif kind == "identifier" and node.text() in VISIBILITIES:
+            body.visibility = node.text()
+        elif kind == "call" and node.field("receiver") is None:
+            self._parse_call(node, statement, body, visibility, file_node_id, build_index, module_name)
+    
+    def _parse_call(self, call: SgNode, statement: SgNode, body: _Body, visibility: str,
+                    file_node_id: str, build_index: bool, module_name: str) -> None:
+        """Extract a call without receiver: visibility, attr_*, mixin, association, define_method or require."""
+        method = call.field("method")
+        if method is None:
+            return
+        name = method.text()
+        arguments = self._arguments(call)
+        line_no = call.range().start.line + 1
+        
+        if name in VISIBILITIES:
+            if not arguments:
+                body.visibility = name
+            for argument in arguments:
+                target = self._literal_name(argument)
+                if target is not None:
+                    # private :helper, protected "helper"
+                    if target in body.methods:
+                        self.nodes[body.methods[target]].properties["visibility"] = name
+                else:
+                    self._parse_statement(argument, statement, body, name, file_node_id, build_index, module_name)
+        elif name == "private_class_method":
+            for argument in arguments:
+                target = self._literal_name(argument)
+                if target in body.class_methods:
+                    self.nodes[body.class_methods[target]].properties["visibility"] = "private"
+        elif name in ATTRIBUTE_MACROS and body.scopes:
+            self._parse_attributes(call, statement, name, arguments, body, visibility)
+        elif name in MIXIN_CALLS and body.scopes:
+            self._parse_mixins(name, arguments, body, line_no)
+        elif name in RUBY_ASSOCIATIONS and body.scopes and not body.singleton:
+            self._parse_association(call, name, arguments, body, line_no)
+        elif name == "define_method" and body.scopes:
+            self._parse_defined_method(call, statement, arguments, body, build_index)
+        elif name in ("require", "require_relative"):
+            self._parse_require(name, arguments, file_node_id, line_no)
+    
+    def _body(self, declaration: SgNode) -> Optional[SgNode]:
+        """Statements of a class, module, `class << self` or block body."""
+        body = declaration.field("body")
+        if body is None:
+            body = next((c for c in declaration.children() if c.kind() in ("body_statement", "block_body")), None)
+        return body
+    
+    def _arguments(self, call: SgNode) -> List[SgNode]:
+        """Arguments of a call, the pairs of a braced hash argument flattened."""
+        argument_list = call.field("arguments")
+        arguments: List[SgNode] = []
+        for argument in (argument_list.children() if argument_list is not None else []):
+            if not argument.is_named() or argument.kind() == "comment":
+                continue
+            if argument.kind() == "hash":
+                arguments.extend(c for c in argument.children() if c.kind() == "pair")
+            else:
+                arguments.append(argument)
+        return arguments
+    
+    def _literal_name(self, node: Optional[SgNode]) -> Optional[str]:
+        """Text of a symbol or string without interpolation (`:posts`, `"posts"`), else None."""
+        if node is None:
+            return None
+        kind = node.kind()
+        if kind in ("simple_symbol", "hash_key_symbol"):
+            return node.text().lstrip(":")
+        if kind in ("string", "delimited_symbol"):
+            if any(c.kind() == "interpolation" for c in node.children()):
+                return None
+            content = next((c for c in node.children() if c.kind() == "string_content"), None)
+            return content.text() if content is not None else ""
+        return None
+    
+    def _options(self, arguments: List[SgNode]) -> Dict[str, SgNode]:
+        """Keyword options of a call (`dependent: :destroy`, `:class_name => "User"`): name -> value node."""
+        options: Dict[str, SgNode] = {}
+        for argument in arguments:
+            if argument.kind() != "pair":
+                continue
+            key = self._literal_name(argument.field("key"))
+            value = argument.field("value")
+            if key and value is not None:
+                options[key] = value
+        return options
+    
+    def _qualify(self, written: str, scopes: List[str]) -> str:
+        """Qualified name of a class or module declared as written (`C`, `B::C`, `::C`) in scopes."""
+        if written.startswith("::"):
+            return written[2:]
+        return f"{scopes[-1]}::{written}" if scopes else written
+    
+    def _parse_type(self, declaration: SgNode, body: _Body, file_node_id: str,
+                    build_index: bool, module_name: str) -> Optional[str]:
+        """
+        Extract a class or module with its body; returns its node ID.
+        
+        A top-level declaration is CONTAINed by its file, a nested one
+        DEFINEd by the enclosing class or module. The superclass is looked
+        up from the scopes around the class, not from the class itself.
+        """
+        name_node = declaration.field("name")
+        if name_node is None:
+            return None
+        written = "".join(name_node.text().split())
+        qualified_name = self._qualify(written, body.scopes)
+        line_no = declaration.range().start.line + 1
+        
+        properties: Dict[str, Any] = {"kind": declaration.kind(), "qualified_name": qualified_name}
+        type_id = self._get_node_id("Class", qualified_name, self.current_file, line_no)
+        self.nodes[type_id] = CodeNode(
+            node_id=type_id,
+            node_type="Class",
+            name=qualified_name.rpartition("::")[2],
+            file_path=self.current_file,
+            line_no=line_no,
+            end_line_no=declaration.range().end.line + 1,
+            properties=properties,
+        )
+        relation_type = "CONTAINS" if body.owner_id == file_node_id else "DEFINES"
+        self._add_relation(CodeRelation(body.owner_id, type_id, relation_type))
+        self._attach_doc(type_id, declaration)
+        
+        # Index the type for cross-file resolution
+        if build_index:
+            self.module_definitions[module_name].setdefault(qualified_name, type_id)
+            self.module_definitions[RUBY_CONSTANTS].setdefault(qualified_name, type_id)
+        
+        superclass = declaration.field("superclass")
+        if superclass is not None:
+            expression = next((c for c in superclass.children() if c.is_named()), None)
+            if expression is not None:
+                superclass_name = collapse(expression.text())
+                properties["superclass"] = superclass_name
+                if expression.kind() in ("constant", "scope_resolution"):
+                    # Struct.new(:x, :y) and other expressions are kept as written only
+                    self._queue_constant_reference("BASE_TYPE", type_id, superclass_name.replace(" ", ""),
+                                                   body.scopes)
+        
+        self._parse_statements(self._body(declaration), _Body(type_id, body.scopes + [qualified_name]),
+                               file_node_id, build_index, module_name)
+        return type_id
+    
+    def _queue_constant_reference(self, relation_type: str, source_id: str, written: str,
+                                  scopes: List[str], **extra: Any) -> None:
+        """Queue a BASE_TYPE or MIXIN relation to a constant, looked up from scopes."""
+        candidates = constant_candidates(written, scopes)
+        pending = {
+            "type": relation_type,
+            "source_id": source_id,
+            "imported_module": RUBY_CONSTANTS,
+            "imported_name": candidates[0],
+            "original_name": written,
+            # Placeholder name when the constant is not indexed
+            "external_name": written.lstrip(":"),
+        }
+        if len(candidates) > 1:
+            pending["candidates"] = [[RUBY_CONSTANTS, candidate] for candidate in candidates]
+        pending.update(extra)
+        self.pending_imports.append(pending)
+    
+    def _parse_mixins(self, mixin: str, arguments: List[SgNode], body: _Body, line_no: int) -> None:
+        """
+        Queue a MIXIN relation for each module an include, extend or prepend names.
+        
+        `include` in a `class << self` body extends the class itself, so
+        it is recorded as "extend".
+        """
+        if body.singleton and mixin == "include":
+            mixin = "extend"
+        for argument in arguments:
+            if argument.kind() not in ("constant", "scope_resolution"):
+                continue
+            written = "".join(argument.text().split())
+            self.nodes[body.owner_id].properties.setdefault("mixins", []).append(f"{mixin} {written}")
+            self._queue_constant_reference("MIXIN", body.owner_id, written, body.scopes,
+                                           mixin=mixin, line_no=line_no)
+    
+    def _parse_association(self, call: SgNode, macro: str, arguments: List[SgNode], body: _Body,
+                           line_no: int) -> None:
+        """
+        Queue an ASSOCIATION relation for a Rails association macro.
+        
+        The target is the class_name option or the class named after the
+        association, looked up in the model's namespaces from the model
+        outwards, then at the top level. Polymorphic `belongs_to` has no
+        target class and is only recorded in "associations".
+        """
+        self.nodes[body.owner_id].properties.setdefault("associations", []).append(collapse(call.text()))
+        name = self._literal_name(arguments[0]) if arguments else None
+        if not name:
+            return
+        options = self._options(arguments[1:])
+        polymorphic = options.get("polymorphic")
+        if polymorphic is not None and polymorphic.kind() == "true":
+            return
+        class_name = self._literal_name(options.get("class_name"))
+        target = class_name or association_class_name(macro, name)
+        candidates = constant_candidates(target, namespace_scopes(body.qualified_name))
+        
+        pending = {
+            "type": "ASSOCIATION",
+            "source_id": body.owner_id,
+            "imported_module": RUBY_CONSTANTS,
+            "imported_name": candidates[0],
+            "association": macro,
+            "name": name,
+            "line_no": line_no,
+        }
+        if len(candidates) > 1:
+            pending["candidates"] = [[RUBY_CONSTANTS, candidate] for candidate in candidates]
+        for option in ASSOCIATION_OPTIONS:
+            value = self._literal_name(options.get(option))
+            if value:
+                pending[option] = value
+        self.pending_imports.append(pending)
+    
+    def _parse_require(self, name: str, arguments: List[SgNode], file_node_id: str, line_no: int) -> None:
+        """
+        Queue the IMPORTS edge of a require or require_relative with a literal path.
+        
+        `require_relative` names a file relative to the requiring one;
+        `require` a file under a `lib/` directory above it, else a gem.
+        """
+        path = self._literal_name(arguments[0]) if arguments else None
+        if not path:
+            return
+        pending: Dict[str, Any] = {
+            "type": "IMPORTS_MODULE",
+            "source_id": file_node_id,
+            "full_module_path": path,
+            "line_no": line_no,
+        }
+        if name == "require_relative":
+            target = os.path.join(os.path.dirname(self.current_file), path)
+        else:
+            target = self._find_in_lib(path)
+        if target is not None:
+            if not target.endswith(".rb"):
+                target += ".rb"
+            pending["imported_module"] = self.file_key(target)
+        else:
+            # Gems are named after the first segment of their require paths
+            pending.update(imported_module=None, external_package=path.split("/")[0])
+        self.pending_imports.append(pending)
+    
+    def _find_in_lib(self, path: str) -> Optional[str]:
+        """
+        The file a `require` of path loads from a lib/ directory above the current file, if any.
+        
+        The search stops at the project root, the first directory with a
+        Gemfile or a .git entry.
+        """
+        file_name = path if path.endswith(".rb") else f"{path}.rb"
+        directory = os.path.dirname(os.path.abspath(self.current_file))
+        while True:
+            parent = os.path.dirname(directory)
+            if parent == directory:
+                return None
+            candidate = os.path.join(directory, "lib", file_name)
+            if os.path.isfile(candidate):
+                # Keep the path in the form the indexed file paths have
+                return candidate if os.path.isabs(self.current_file) else os.path.relpath(candidate)
+            if any(os.path.exists(os.path.join(directory, marker)) for marker in PROJECT_ROOT_MARKERS):
+                return None
+            directory = parent
+    
+    def _parse_method(self, method: SgNode, statement: SgNode, body: _Body, visibility: str,
+                      build_index: bool, module_name: str) -> Optional[str]:
+        """
+        Extract a `def` or `def self.` method; returns its node ID.
+        
+        Methods of a class or module are DEFINEd by it and record their
+        visibility; a top-level `def` is a Function CONTAINed by the file.
+        `def obj.name` on another object than self or the class is
+        skipped.
+        """
+        name_node = method.field("name")
+        if name_node is None:
+            return None
+        class_method = body.singleton
+        if method.kind() == "singleton_method":
+            target = method.field("object")
+            owner = body.qualified_name
+            if owner is None or target is None or target.text() not in ("self", owner.rpartition("::")[2]):
+                return None
+            class_method = True
+        
+        name = name_node.text()
+        properties: Dict[str, Any] = {}
+        if body.scopes:
+            separator = "." if class_method else "#"
+            properties["qualified_name"] = f"{body.qualified_name}{separator}{name}"
+            properties["visibility"] = visibility
+            if class_method:
+                properties["class_method"] = True
+        properties.update(signature_properties(self._parameters(method.field("parameters"))))
+        return self._add_method(method, statement, name, properties, body, class_method, build_index, module_name)
+    
+    def _parse_defined_method(self, call: SgNode, statement: SgNode, arguments: List[SgNode],
+                              body: _Body, build_index: bool) -> None:
+        """Extract `define_method(:name) do |args| ... end` as a Method with dynamic: true."""
+        name = self._literal_name(arguments[0]) if arguments else None
+        if not name:
+            return
+        block = call.field("block")
+        qualified_name = f"{body.qualified_name}{'.' if body.singleton else '#'}{name}"
+        properties: Dict[str, Any] = {"qualified_name": qualified_name, "visibility": body.visibility,
+                                      "dynamic": True}
+        if body.singleton:
+            properties["class_method"] = True
+        parameters = block.field("parameters") if block is not None else None
+        properties.update(signature_properties(self._parameters(parameters)))
+        self._add_method(block if block is not None else call, statement, name, properties, body,
+                         body.singleton, build_index, "")
+    
+    def _add_method(self, declaration: SgNode, statement: SgNode, name: str, properties: Dict[str, Any],
+                    body: _Body, class_method: bool, build_index: bool, module_name: str) -> str:
+        """Create a Method (Function at the top level) node with its metrics; returns its ID."""
+        line_no = statement.range().start.line + 1
+        end_line_no = statement.range().end.line + 1
+        properties.update(syntax_metrics(declaration, RUBY_METRIC_RULES, line_no, end_line_no))
+        node_type = "Method" if body.scopes else "Function"
+        node_id = self._get_node_id(node_type, name, self.current_file, line_no)
+        self.nodes[node_id] = CodeNode(
+            node_id=node_id,
+            node_type=node_type,
+            name=name,
+            file_path=self.current_file,
+            line_no=line_no,
+            end_line_no=end_line_no,
+            properties=properties,
+        )
+        self._attach_doc(node_id, statement)
+        
+        if body.scopes:
+            # Add DEFINES relation from class or module to method
+            self._add_relation(CodeRelation(body.owner_id, node_id, "DEFINES"))
+            (body.class_methods if class_method else body.methods)[name] = node_id
+            if build_index:
+                self.module_definitions[RUBY_CONSTANTS].setdefault(properties["qualified_name"], node_id)
+        else:
+            self._add_relation(CodeRelation(body.owner_id, node_id, "CONTAINS"))
+            if build_index:
+                self.module_definitions[module_name].setdefault(name, node_id)
+        return node_id
+    
+    def _parameters(self, parameters: Optional[SgNode]) -> List[Dict[str, Any]]:
+        """
+        Entries of a method or block parameter list.
+        
+        Keyword parameters (`key:`, `key: 1`) have keyword: true, `*args`
+        and `**opts` are variadic (opts keyword too), `&block` has block:
+        true.
+        """
+        entries: List[Dict[str, Any]] = []
+        for child in (parameters.children() if parameters is not None else []):
+            kind = child.kind()
+            name_node = child.field("name")
+            name = name_node.text() if name_node is not None else None
+            value = child.field("value")
+            default = value.text() if value is not None else None
+            if kind == "identifier":
+                entries.append(parameter(child.text()))
+            elif kind == "optional_parameter":
+                entries.append(parameter(name, default=default))
+            elif kind == "keyword_parameter":
+                entries.append(parameter(name, default=default, keyword=True))
+            elif kind == "splat_parameter":
+                entries.append(parameter(name, variadic=True))
+            elif kind == "hash_splat_parameter":
+                entries.append(parameter(name, variadic=True, keyword=True))
+            elif kind == "block_parameter":
+                entries.append(parameter(name, block=True))
+            elif kind in ("destructured_parameter", "forward_parameter"):
+                entries.append(parameter(collapse(child.text())))
+        return entries
+    
+    def _parse_attributes(self, call: SgNode, statement: SgNode, macro: str, arguments: List[SgNode],
+                          body: _Body, visibility: str) -> None:
+        """
+        Extract the attributes of an attr_accessor, attr_reader or attr_writer call.
+        
+        Each symbol becomes a ClassVariable with property, getter and
+        setter flags; attributes of a `class << self` body have
+        class_attribute: true.
+        """
+        getter, setter = ATTRIBUTE_MACROS[macro]
+        line_no = call.range().start.line + 1
+        for argument in arguments:
+            name = self._literal_name(argument)
+            if not name:
+                continue
+            properties: Dict[str, Any] = {
+                "property": True,
+                "getter": getter,
+                "setter": setter,
+                "macro": macro,
+                "visibility": visibility,
+            }
+            if body.singleton:
+                properties["class_attribute"] = True
+            var_node_id = self._get_node_id("Variable", name, self.current_file, line_no)
+            self.nodes[var_node_id] = CodeNode(
+                node_id=var_node_id,
+                node_type="ClassVariable",
+                name=name,
+                file_path=self.current_file,
+                line_no=line_no,
+                properties=properties,
+            )
+            self._attach_doc(var_node_id, statement)
+            
+            # Add DEFINES relation from class to attribute
+            self._add_relation(CodeRelation(body.owner_id, var_node_id, "DEFINES"))
+    
+    def _parse_constant(self, assignment: SgNode, name: str, body: _Body, build_index: bool,
+                        module_name: str) -> None:
+        """Extract a constant assignment as a Constant node DEFINEd by its class, module or file."""
+        line_no = assignment.range().start.line + 1
+        qualified_name = f"{body.qualified_name}::{name}" if body.scopes else name
+        properties: Dict[str, Any] = {"qualified_name": qualified_name}
+        value = literal_value(self._constant_value(assignment.field("right")))
+        if value is not None:
+            properties["value"] = value
+        node_id = self._get_node_id("Constant", name, self.current_file, line_no)
+        self.nodes[node_id] = CodeNode(
+            node_id=node_id,
+            node_type="Constant",
+            name=name,
+            file_path=self.current_file,
+            line_no=line_no,
+            end_line_no=assignment.range().end.line + 1,
+            properties=properties,
+        )
+        self._attach_doc(node_id, assignment)
+        self._add_relation(CodeRelation(body.owner_id, node_id, "DEFINES"))
+        if build_index:
+            self.module_definitions[RUBY_CONSTANTS].setdefault(qualified_name, node_id)
+            if not body.scopes:
+                self.module_definitions[module_name].setdefault(name, node_id)
+    
+    def _constant_value(self, expression: Optional[SgNode]) -> Optional[Any]:
+        """Value of a literal initializer: integer, float, string, symbol (":name") or boolean; else None."""
+        if expression is None:
+            return None
+        kind = expression.kind()
+        text = expression.text().replace("_", "") if kind in ("integer", "float") else expression.text()
+        try:
+            if kind == "integer":
+                # 0755 is octal like 0o755
+                return int(f"0o{text[1:]}", 0) if re.fullmatch(r"0[0-7]+", text) else int(text, 0)
+            if kind == "float":
+                return float(text)
+        except ValueError:
+            return None
+        if kind in ("true", "false"):
+            return kind == "true"
+        if kind == "simple_symbol":
+            return text
+        if kind == "string":
+            return self._literal_name(expression)
+        return None
@@ -1,39 +1,149 @@
 """Rust language adapter using ast-grep for AST parsing."""
 
 import os
-from typing import Dict, List, Tuple, Optional
+import re
+from dataclasses import dataclass, field, replace
+from typing import Any, Dict, List, Optional, Set, Tuple
 
 from ast_grep_py import SgRoot, SgNode
 
 from .base_adapter import LanguageAdapter
 from ast_parser.parser import CodeNode, CodeRelation
+from ast_parser.doc_comments import is_license_header, normalize_comment
+from ast_parser.packages import package_id
+from ast_parser.metrics import RUST_METRIC_RULES, syntax_metrics
+from ast_parser.signatures import collapse, parameter, signature_properties
+
+
+# Type items -> (node type, "kind" property)
+RUST_TYPE_ITEMS = {
+    "struct_item": ("Class", "struct"),
+    "union_item": ("Class", "union"),
+    "enum_item": ("Enum", "enum"),
+    "trait_item": ("Interface", "trait"),
+    "type_item": ("TypeAlias", "type"),
+}
+
+# Items whose names are declared in the enclosing module
+RUST_NAMED_ITEMS = tuple(RUST_TYPE_ITEMS) + (
+    "function_item", "mod_item", "const_item", "static_item", "macro_definition",
+)
+
+RUST_FUNCTIONS = ("function_item", "function_signature_item")
+
+RUST_COMMENT_KINDS = ("line_comment", "block_comment")
+
+RUST_ATTRIBUTES = ("attribute_item",)
+
+DERIVE_ATTRIBUTE = re.compile(r"^derive\s*\((?P<traits>.*)\)$", re.DOTALL)
+
+# Tokens of a use tree: path separators, groups, globs and names (raw identifiers included)
+USE_TREE_TOKEN = re.compile(r"::|[{},*]|r#\w+|\w+")
+
+# Innermost generic argument list, removed repeatedly to drop nested ones
+GENERIC_ARGUMENTS = re.compile(r"<[^<>]*>")
+
+MUT_REFERENCE = re.compile(r"&\s*('\w+\s+)?mut\b")
+
+
+def is_outer_doc(text: str) -> bool:
+    """Whether a comment documents the item below it (`///` or `/** */`, not `////` or `/***`)."""
+    stripped = text.strip()
+    if stripped.startswith("///"):
+        return not stripped.startswith("////")
+    return stripped.startswith("/**") and not stripped.startswith(("/***", "/**/"))
+
+
+def is_inner_doc(text: str) -> bool:
+    """Whether a comment documents the enclosing module (`//!` or `/*! */`)."""
+    return text.strip().startswith(("//!", "/*!"))
+
+
+@dataclass
+class RustScope:
+    """Names visible in one module: the module of a file or an inline `mod` block."""
+    path: List[str]
+    # Package node of an inline module, None for the module of the file
+    package_node_id: Optional[str] = None
+    # Names of the items and modules declared in the module
+    items: Set[str] = field(default_factory=set)
+    # Local name -> absolute path of what a `use` brings in
+    uses: Dict[str, List[str]] = field(default_factory=dict)
+    # Absolute paths of the modules of `use ...::*`
+    globs: List[List[str]] = field(default_factory=list)
+    # Path of the type of the impl (or trait) being parsed, what Self stands for
+    self_type: Optional[List[str]] = None
 
 
 class RustAdapter(LanguageAdapter):
     """
     Rust adapter using ast-grep library.
     
-    Extracts minimal Rust structures for proof of concept:
-    - File, Struct, Function, Method nodes
-    - CONTAINS, DEFINES relations
-    - Use declaration tracking (imports)
+    Extracts Rust structures:
+    - File, Class (structs, unions), Enum (variants in "members"),
+      Interface (traits), TypeAlias, Function, Method and ClassVariable
+      (named struct fields) nodes
+    - CONTAINS, DEFINES relations; methods of `impl` blocks get a METHOD_OF
+      edge to the type, `impl Trait for Type` an IMPLEMENTS edge from the
+      type to the trait and supertraits EXTENDS edges
+    - `use` declarations, with groups, globs and `as` renames; the second
+      pass links each file to the imported item, module or an
+      ExternalPackage placeholder
+    - CALLS from function bodies; macro invocations are skipped
+    
+    Modules follow the file layout of the crate. With the src/ directory
+    next to the nearest Cargo.toml as root, src/lib.rs and src/main.rs are
+    the crate module and src/geometry.rs or src/geometry/mod.rs the module
+    geometry. The File node records the module path ("shapes::geometry") in
+    "import_path", so every module has its Package node; inline
+    `mod name { ... }` blocks are Package nodes of their own, contained by
+    the file, and `mod name;` declarations are listed in "modules".
+    
+    Items are indexed per module (see module_key), modules by name in
+    their parent module and methods as "Type.method" in the module of the
+    impl block. A path resolves like rustc reads it: crate::, self:: and
+    super:: prefixes, names brought in by `use`, items of the module, then
+    glob imports. `pub use` re-exports the item from the module.
+    
+    Methods record receiver_kind ("value" for self, "ref" for &self,
+    "mut_ref" for &mut self; associated functions have none).
+    `#[derive(...)]` traits are kept in "derives", and linked with an
+    IMPLEMENTS edge marked derived when the trait is indexed; other outer
+    attributes are kept in "attributes". Generic parameters and lifetimes
+    are kept by name in "type_parameters".
     
     Supports Rust source files (.rs).
     """
     
+    # Cargo.toml lookups per directory: dir -> (crate_dir, crate_name) or None
+    _crate_cache: Dict[str, Optional[Tuple[str, str]]] = {}
+    
     def __init__(self):
         super().__init__("rust")
         self.current_file: str = ""
-        self.current_struct: Optional[str] = None
+        self.current_crate: str = ""
+        # Qualified name -> node ID of the types declared in the current file
+        self.file_types: Dict[str, str] = {}
+    
+    @staticmethod
+    def module_key(module_path: List[str]) -> str:
+        """
+        Build the module_definitions key for a Rust module.
+        
+        The "rust:" prefix keeps it from colliding with file-level module
+        names used by the other adapters.
+        """
+        return f"rust:{'::'.join(module_path)}"
     
     def parse_file(self, file_path: str, build_index: bool = False) -> Tuple[Dict[str, CodeNode], List[CodeRelation]]:
         """
         Parse a Rust file using ast-grep.
         
-        Extracts structs, functions, impl blocks, and use declarations.
+        Extracts the module of the file, use declarations, type items,
+        functions, impl blocks and inline modules.
         """
         self.current_file = file_path
-        self.current_struct = None
+        self.file_types = {}
         
         try:
             # Read source code
@@ -46,146 +156,803 @@ class RustAdapter(LanguageAdapter):
             # Create file node
             file_node_id = self._create_file_node(file_path)
             
-            # Generate module name for indexing
-            module_name = os.path.splitext(os.path.basename(file_path))[0]
+            # Module of the file, from the crate layout
+            module_path = self._module_path(file_path)
+            import_path = "::".join(module_path)
+            self.nodes[file_node_id].properties["package"] = module_path[-1]
+            self.nodes[file_node_id].properties["import_path"] = import_path
             if build_index:
-                if module_name not in self.module_definitions:
-                    self.module_definitions[module_name] = {}
-                self.module_to_file[module_name] = file_node_id
+                self.module_definitions.setdefault(self.module_key(module_path), {})
+                self.module_to_file[self.module_key(module_path)] = file_node_id
+                if len(module_path) > 1:
+                    parent = self.module_definitions.setdefault(self.module_key(module_path[:-1]), {})
+                    parent[module_path[-1]] = package_id("", import_path)
             
             # Extract Rust structures
-            self._parse_use_declarations(root, file_node_id)
-            self._parse_structs(root, file_node_id, build_index, module_name)
-            self._parse_functions(root, file_node_id)
-            self._parse_impl_blocks(root, build_index, module_name)
+            self._parse_file_comments(root, file_node_id)
+            self._parse_scope(root, RustScope(module_path), build_index)
             
             return self.nodes, self.relations
-            
+        
         except Exception as e:
             print(f"Error parsing Rust file {file_path}: {e}")
+            self.parse_error = e
             return {}, []
     
-    def _parse_use_declarations(self, root: SgNode, file_node_id: str) -> None:
-        """Extract use declarations (imports)."""
-        # Find all use_declaration nodes
-        for use_node in root.find_all(kind="use_declaration"):
-            # Get the use path
-            use_text = use_node.text().strip()
-            if use_text.startswith("use "):
-                use_text = use_text[4:].strip()
-                if use_text.endswith(";"):
-                    use_text = use_text[:-1].strip()
-                
-                # Extract root module
-                parts = use_text.split("::")
-                if len(parts) > 0:
-                    root_module = parts[0]
-                    self.pending_imports.append({
-                        "type": "IMPORTS_MODULE",
-                        "source_id": file_node_id,
-                        "imported_module": root_module,
-                        "full_module_path": use_text,
-                    })
-    
-    def _parse_structs(self, root: SgNode, file_node_id: str, build_index: bool, module_name: str) -> None:
-        """Extract struct declarations."""
-        # Find all struct_item nodes
-        for struct_node in root.find_all(kind="struct_item"):
-            # Get struct name
-            name_field = struct_node.field("name")
-            if not name_field:
+    # Modules
+    
+    def _module_path(self, file_path: str) -> List[str]:
+        """
+        Module path of a file: [crate, "geometry"] for src/geometry.rs or src/geometry/mod.rs.
+        
+        Files outside the src/ directory of a crate (tests, examples, build
+        scripts) and files without a Cargo.toml above them are modules of
+        their own directory; the crate is named after the directory then.
+        """
+        path = os.path.abspath(file_path)
+        directory = os.path.dirname(path)
+        crate = self._find_crate(directory)
+        source_dir = os.path.join(crate[0], "src") if crate else directory
+        if not path.startswith(source_dir + os.sep):
+            source_dir = directory
+        self.current_crate = crate[1] if crate else self._identifier(os.path.basename(directory))
+        
+        parts = os.path.splitext(os.path.relpath(path, source_dir))[0].split(os.sep)
+        if parts[-1] == "mod":
+            parts.pop()
+        if parts in (["lib"], ["main"]):
+            parts = []
+        return [self.current_crate] + [self._identifier(part) for part in parts]
+    
+    @staticmethod
+    def _identifier(name: str) -> str:
+        """Crate and file names as Rust paths spell them ("my-crate" -> "my_crate")."""
+        return name.replace("-", "_") or "crate"
+    
+    def _find_crate(self, directory: str) -> Optional[Tuple[str, str]]:
+        """Walk up from directory to the nearest Cargo.toml and read the crate name."""
+        if directory in self._crate_cache:
+            return self._crate_cache[directory]
+        
+        result = None
+        cargo_toml = os.path.join(directory, "Cargo.toml")
+        if os.path.isfile(cargo_toml):
+            names = {}
+            section = ""
+            try:
+                with open(cargo_toml, "r", encoding="utf-8") as f:
+                    for line in f:
+                        header = re.match(r"\s*\[([^\]]+)\]", line)
+                        if header:
+                            section = header.group(1).strip()
+                            continue
+                        match = re.match(r"\s*name\s*=\s*[\"']([^\"']+)[\"']", line)
+                        if match and section in ("package", "lib"):
+                            names[section] = match.group(1)
+            except (OSError, UnicodeDecodeError):
+                pass
+            # A [lib] name overrides the package name; a workspace root has neither
+            name = names.get("lib") or names.get("package") or os.path.basename(directory)
+            result = (directory, self._identifier(name))
+        else:
+            parent = os.path.dirname(directory)
+            if parent != directory:
+                result = self._find_crate(parent)
+        
+        self._crate_cache[directory] = result
+        return result
+    
+    def _parse_file_comments(self, root: SgNode, file_node_id: str) -> None:
+        """
+        Attach the comments opening the file to the File node.
+        
+        Inner doc comments (`//!`, `/*! */`) are the module doc ("doc");
+        license comments become "header".
+        """
+        docs: List[str] = []
+        header: List[str] = []
+        for child in root.children():
+            if child.kind() == "inner_attribute_item":
                 continue
+            if child.kind() not in RUST_COMMENT_KINDS:
+                break
+            if is_inner_doc(child.text()):
+                docs.append(child.text())
+            elif is_license_header(child.text()):
+                header.append(child.text())
+        if docs:
+            self._set_doc(file_node_id, normalize_comment("\n".join(docs)))
+        if header:
+            self.nodes[file_node_id].properties["header"] = normalize_comment("\n".join(header))
+    
+    def _parse_scope(self, container: SgNode, scope: RustScope, build_index: bool) -> None:
+        """
+        Extract the items of a module: the file, or the body of an inline `mod`.
+        
+        Names are collected first, since an item may be used above its
+        declaration; impl blocks come last so the types they extend exist.
+        """
+        items = [child for child in container.children() if child.is_named()]
+        for item in items:
+            name_node = item.field("name") if item.kind() in RUST_NAMED_ITEMS else None
+            if name_node is not None:
+                scope.items.add(name_node.text())
+        
+        for item in items:
+            if item.kind() == "use_declaration":
+                self._parse_use(item, scope)
+        
+        impls = []
+        for item in items:
+            kind = item.kind()
+            if kind in RUST_TYPE_ITEMS:
+                self._parse_type_item(item, scope, build_index)
+            elif kind == "function_item":
+                self._parse_function(item, scope, build_index)
+            elif kind == "mod_item":
+                self._parse_module(item, scope, build_index)
+            elif kind == "impl_item":
+                impls.append(item)
+        
+        for item in impls:
+            self._parse_impl(item, scope, build_index)
+    
+    def _parse_module(self, item: SgNode, scope: RustScope, build_index: bool) -> None:
+        """
+        Extract a `mod` item.
+        
+        `mod name;` declares the module of another file, which is indexed
+        on its own, so it is only listed in the File node's "modules". An
+        inline `mod name { ... }` becomes a Package node containing its items.
+        """
+        name_node = item.field("name")
+        if name_node is None:
+            return
+        name = name_node.text()
+        file_node_id = f"file:{self.current_file}"
+        body = item.field("body")
+        if body is None:
+            self.nodes[file_node_id].properties.setdefault("modules", []).append(name)
+            return
+        
+        module_path = scope.path + [name]
+        import_path = "::".join(module_path)
+        node_id = package_id("", import_path)
+        self.nodes[node_id] = CodeNode(
+            node_id=node_id,
+            node_type="Package",
+            name=name,
+            file_path="",
+            line_no=0,
+            properties={"path": os.path.dirname(self.current_file), "import_path": import_path, "inline": True},
+        )
+        self._set_doc(node_id, self._doc(item))
+        self._add_relation(CodeRelation(file_node_id, node_id, "CONTAINS"))
+        if scope.package_node_id:
+            self._add_relation(CodeRelation(scope.package_node_id, node_id, "CONTAINS"))
+        
+        if build_index:
+            self.module_definitions.setdefault(self.module_key(scope.path), {})[name] = node_id
+            self.module_definitions.setdefault(self.module_key(module_path), {})
+        self._parse_scope(body, RustScope(module_path, package_node_id=node_id), build_index)
+    
+    # Paths
+    
+    @staticmethod
+    def _path_segments(text: str) -> List[str]:
+        """Segments of a path with generic arguments dropped ("Vec::<T>::new" -> ["Vec", "new"])."""
+        text = "".join(text.split())
+        previous = None
+        while previous != text:
+            previous = text
+            text = GENERIC_ARGUMENTS.sub("", text)
+        return [segment for segment in text.split("::") if segment]
+    
+    @staticmethod
+    def _flatten_use_tree(text: str) -> List[Tuple[List[str], Optional[str]]]:
+        """
+        Flatten the argument of a use declaration into (path, alias) pairs.
+        
+        "a::{b::C as D, e::*, self}" -> [(["a", "b", "C"], "D"),
+        (["a", "e", "*"], None), (["a", "self"], None)].
+        """
+        tokens = USE_TREE_TOKEN.findall(text)
+        
+        def tree(index: int, prefix: List[str]) -> Tuple[List[Tuple[List[str], Optional[str]]], int]:
+            path = list(prefix)
+            while index < len(tokens) and tokens[index] == "::":
+                # Leading `::` of a 2015-edition absolute path
+                index += 1
+            while index < len(tokens):
+                token = tokens[index]
+                if token == "{":
+                    entries = []
+                    index += 1
+                    while index < len(tokens) and tokens[index] != "}":
+                        if tokens[index] == ",":
+                            index += 1
+                            continue
+                        group, index = tree(index, path)
+                        entries.extend(group)
+                    return entries, index + 1
+                if token == "*":
+                    return [(path + ["*"], None)], index + 1
+                path.append(token)
+                index += 1
+                if index < len(tokens) and tokens[index] == "::":
+                    index += 1
+                    continue
+                alias = None
+                if index + 1 < len(tokens) and tokens[index] == "as":
+                    alias = tokens[index + 1]
+                    index += 2
+                return [(path, alias)], index
+            return [], index
+        
+        return tree(0, [])[0]
+    
+    def _absolute_module(self, segments: List[str], scope: RustScope) -> List[str]:
+        """
+        Absolute path that the leading segments of a path name.
+        
+        Paths starting with a name that is neither imported nor declared in
+        the module name another crate and are returned as they are.
+        """
+        head = segments[0]
+        if head == "crate":
+            return [self.current_crate] + segments[1:]
+        if head in ("self", "super"):
+            path = list(scope.path)
+            rest = segments[1:] if head == "self" else segments
+            while rest and rest[0] == "super":
+                path = path[:-1] if len(path) > 1 else path
+                rest = rest[1:]
+            return path + rest
+        if head == "Self" and scope.self_type:
+            return self._absolute_module(scope.self_type, replace(scope, self_type=None)) + segments[1:]
+        if head in scope.uses:
+            return scope.uses[head] + segments[1:]
+        if head in scope.items:
+            return scope.path + segments
+        return list(segments)
+    
+    def _candidates(self, path: List[str], scope: RustScope) -> List[List[str]]:
+        """
+        [module key, name] pairs a path may denote, in lookup order.
+        
+        A single name is looked up in the `use` imports, the module and then
+        the glob imports; a path whose first segment is not bound tries the
+        glob imports before another crate. `Type::f` also tries the
+        "Type.f" entry of the type's module, where methods and associated
+        functions are indexed.
+        The second pass takes the first pair that is indexed.
+        """
+        if len(path) == 1:
+            name = path[0]
+            if name in scope.uses:
+                target = scope.uses[name]
+                return [[self.module_key(target[:-1] or target), target[-1]]]
+            candidates = [[self.module_key(scope.path), name]]
+            if name not in scope.items:
+                candidates.extend([self.module_key(glob), name] for glob in scope.globs)
+            return candidates
+        
+        modules = [self._absolute_module(path[:-1], scope)]
+        if not self._is_bound(path[0], scope):
+            # A name from a glob import, else another crate
+            modules[:0] = [glob + path[:-1] for glob in scope.globs]
+        candidates = []
+        for module in modules:
+            candidates.append([self.module_key(module), path[-1]])
+            if len(module) > 1:
+                candidates.append([self.module_key(module[:-1]), f"{module[-1]}.{path[-1]}"])
+        return candidates
+    
+    @staticmethod
+    def _is_bound(head: str, scope: RustScope) -> bool:
+        """Whether the first segment of a path names something of this crate without glob imports."""
+        return head in ("crate", "self", "super", "Self") or head in scope.uses or head in scope.items
+    
+    def _queue_reference(self, relation_type: str, source_id: Optional[str], path: List[str],
+                         scope: RustScope, **extra: Any) -> None:
+        """Queue a relation from source_id to the item a path names, resolved in the second pass."""
+        candidates = self._candidates(path, scope)
+        pending = {
+            "type": relation_type,
+            "source_id": source_id,
+            "imported_module": candidates[0][0],
+            "imported_name": candidates[0][1],
+            "original_name": "::".join(path),
+        }
+        if len(candidates) > 1:
+            pending["candidates"] = candidates
+        pending.update(extra)
+        self.pending_imports.append(pending)
+    
+    def _parse_use(self, use_node: SgNode, scope: RustScope) -> None:
+        """
+        Extract a use declaration.
+        
+        Each imported path becomes IMPORTS_SYMBOL (an item or module),
+        globs IMPORTS_MODULE with "wildcard"; `as` renames are kept in
+        "alias" and `as _` binds no name. `pub use` also re-exports the
+        names from the module.
+        """
+        argument = use_node.field("argument")
+        if argument is None:
+            return
+        file_node_id = f"file:{self.current_file}"
+        line_no = use_node.range().start.line + 1
+        public = any(child.kind() == "visibility_modifier" for child in use_node.children())
+        
+        for segments, alias in self._flatten_use_tree(argument.text()):
+            if segments and segments[-1] == "self":
+                # `use a::b::{self}` imports the module b
+                segments = segments[:-1]
+            if not segments or segments == ["*"]:
+                continue
+            written = "::".join(segments)
             
-            struct_name = name_field.text()
-            line_no = struct_node.range().start.line + 1
+            if segments[-1] == "*":
+                module = self._absolute_module(segments[:-1], scope)
+                scope.globs.append(module)
+                self.pending_imports.append({
+                    "type": "IMPORTS_MODULE",
+                    "source_id": file_node_id,
+                    "imported_module": self.module_key(module),
+                    "full_module_path": written,
+                    "import_path": "::".join(module),
+                    "package_key": self.module_key(module),
+                    "line_no": line_no,
+                    "wildcard": True,
+                })
+                if public:
+                    self._queue_reexport(module, "*", None, scope)
+                continue
+            
+            target = self._absolute_module(segments, scope)
+            if alias != "_":
+                scope.uses[alias or target[-1]] = target
+            if len(target) == 1:
+                # `use some_crate;`
+                self.pending_imports.append({
+                    "type": "IMPORTS_MODULE",
+                    "source_id": file_node_id,
+                    "imported_module": self.module_key(target),
+                    "full_module_path": written,
+                    "import_path": target[0],
+                    "package_key": self.module_key(target),
+                    "line_no": line_no,
+                    "alias": alias,
+                })
+                continue
             
-            # Create struct node (using "Class" type for consistency)
-            struct_node_id = self._get_node_id("Class", struct_name, self.current_file, line_no)
-            self.nodes[struct_node_id] = CodeNode(
-                node_id=struct_node_id,
-                node_type="Class",
-                name=struct_name,
+            module = target[:-1]
+            pending = {
+                "type": "IMPORTS_SYMBOL",
+                "source_id": file_node_id,
+                "imported_module": self.module_key(module),
+                "imported_name": target[-1],
+                "full_module_path": written,
+                "import_path": "::".join(module),
+                "package_key": self.module_key(module),
+                "line_no": line_no,
+            }
+            if alias:
+                pending["alias"] = alias
+            self.pending_imports.append(pending)
+            if public:
+                self._queue_reexport(module, target[-1], alias, scope)
+    
+    def _queue_reexport(self, module: List[str], name: str, alias: Optional[str], scope: RustScope) -> None:
+        """Queue a `pub use` re-export; a re-exported type takes its "Type.method" entries along."""
+        self.pending_imports.append({
+            "type": "REEXPORTS",
+            "source_id": f"file:{self.current_file}",
+            "module_key": self.module_key(scope.path),
+            "imported_module": self.module_key(module),
+            "imported_name": name,
+            "alias": alias,
+            "with_members": True,
+        })
+    
+    def _type_path(self, type_node: Optional[SgNode]) -> Optional[List[str]]:
+        """Path of a type reference, generic arguments and references dropped ("&'a shapes::Circle<T>" -> ["shapes", "Circle"])."""
+        if type_node is None:
+            return None
+        kind = type_node.kind()
+        if kind in ("generic_type", "reference_type", "pointer_type"):
+            return self._type_path(type_node.field("type"))
+        if kind in ("type_identifier", "scoped_type_identifier", "primitive_type", "identifier", "scoped_identifier"):
+            return self._path_segments(type_node.text()) or None
+        return None
+    
+    # Items
+    
+    def _doc(self, item: SgNode) -> str:
+        """Outer doc comments (`///`, `/** */`) above an item; plain comments and attributes in between are skipped."""
+        comments = self._leading_comments(item, RUST_COMMENT_KINDS, skip_kinds=RUST_ATTRIBUTES)
+        return normalize_comment("\n".join(c.text() for c in comments if is_outer_doc(c.text())))
+    
+    def _set_doc(self, node_id: str, doc: str) -> None:
+        """Store a non-empty doc on a node."""
+        if doc:
+            self.nodes[node_id].properties["doc"] = doc
+    
+    def _attributes(self, item: SgNode) -> List[str]:
+        """Outer attributes above an item, without `#[` and `]` ("derive(Debug)"), in source order."""
+        attributes: List[str] = []
+        sibling = self._prev_named(item)
+        while sibling is not None and sibling.kind() in RUST_ATTRIBUTES + RUST_COMMENT_KINDS:
+            if sibling.kind() in RUST_ATTRIBUTES:
+                text = collapse(sibling.text()) or ""
+                if text.startswith("#[") and text.endswith("]"):
+                    attributes.insert(0, text[2:-1].strip())
+            sibling = self._prev_named(sibling)
+        return attributes
+    
+    def _add_item(self, item: SgNode, node_type: str, name: str, scope: RustScope,
+                  properties: Dict[str, Any], build_index: bool, index_name: Optional[str] = None) -> str:
+        """
+        Create the node of an item with its doc, visibility and attributes.
+        
+        The file CONTAINS it, and so does an inline module. It is indexed
+        in its module under index_name (its own name by default). Returns
+        the node ID.
+        """
+        line_no = item.range().start.line + 1
+        node_id = self._get_node_id(node_type, name, self.current_file, line_no)
+        visibility = next((c for c in item.children() if c.kind() == "visibility_modifier"), None)
+        if visibility is not None:
+            properties["visibility"] = collapse(visibility.text())
+        attributes = [text for text in self._attributes(item) if not DERIVE_ATTRIBUTE.match(text)]
+        if attributes:
+            properties["attributes"] = attributes
+        self.nodes[node_id] = CodeNode(
+            node_id=node_id,
+            node_type=node_type,
+            name=name,
+            file_path=self.current_file,
+            line_no=line_no,
+            end_line_no=item.range().end.line + 1,
+            properties=properties,
+        )
+        self._set_doc(node_id, self._doc(item))
+        
+        self._add_relation(CodeRelation(f"file:{self.current_file}", node_id, "CONTAINS"))
+        if scope.package_node_id:
+            self._add_relation(CodeRelation(scope.package_node_id, node_id, "CONTAINS"))
+        if build_index:
+            self.module_definitions.setdefault(self.module_key(scope.path), {})[index_name or name] = node_id
+        return node_id
+    
+    def _type_parameters(self, node: Optional[SgNode]) -> List[Dict[str, Optional[str]]]:
+        """Generic parameters and lifetimes of a type_parameters list as {name, constraint}; bounds as written."""
+        entries: List[Dict[str, Optional[str]]] = []
+        if node is None:
+            return entries
+        for child in node.children():
+            kind = child.kind()
+            bounds = None
+            if kind in ("lifetime", "type_identifier"):
+                name = child.text()
+            elif kind == "constrained_type_parameter":
+                left = child.field("left")
+                name = left.text() if left is not None else None
+                bounds = child.field("bounds")
+            elif kind in ("type_parameter", "lifetime_parameter", "optional_type_parameter", "const_parameter"):
+                name_node = child.field("name")
+                if name_node is not None and name_node.kind() == "constrained_type_parameter":
+                    # `T: Bound = Default`
+                    bounds = name_node.field("bounds")
+                    name_node = name_node.field("left")
+                else:
+                    bounds = child.field("type") if kind == "const_parameter" else child.field("bounds")
+                name = name_node.text() if name_node is not None else None
+            else:
+                continue
+            if not name:
+                continue
+            constraint = collapse(bounds.text()) if bounds is not None else None
+            if constraint and constraint.startswith(":"):
+                constraint = collapse(constraint[1:])
+            entries.append({"name": name, "constraint": constraint})
+        return entries
+    
+    def _parse_type_item(self, item: SgNode, scope: RustScope, build_index: bool) -> Optional[str]:
+        """Extract a struct, union, enum, trait or type alias; returns its node ID."""
+        name_node = item.field("name")
+        if name_node is None:
+            return None
+        name = name_node.text()
+        node_type, kind = RUST_TYPE_ITEMS[item.kind()]
+        properties: Dict[str, Any] = {"kind": kind, "qualified_name": "::".join(scope.path + [name])}
+        type_parameters = self._type_parameters(item.field("type_parameters"))
+        if type_parameters:
+            properties["type_parameters"] = [entry["name"] for entry in type_parameters]
+        if item.kind() == "type_item" and item.field("type") is not None:
+            properties["type"] = collapse(item.field("type").text())
+        
+        type_id = self._add_item(item, node_type, name, scope, properties, build_index)
+        self.file_types[properties["qualified_name"]] = type_id
+        self._parse_derives(item, type_id, scope)
+        
+        body = item.field("body")
+        if item.kind() in ("struct_item", "union_item"):
+            self._parse_fields(body, type_id, name)
+        elif item.kind() == "enum_item" and body is not None:
+            members = [c.field("name").text() for c in body.children()
+                       if c.kind() == "enum_variant" and c.field("name") is not None]
+            properties["members"] = members
+        elif item.kind() == "trait_item":
+            self._parse_trait(item, type_id, name, scope, build_index)
+        return type_id
+    
+    def _parse_derives(self, item: SgNode, type_id: str, scope: RustScope) -> None:
+        """Record the `#[derive(...)]` traits of a type and queue IMPLEMENTS edges marked derived."""
+        derives: List[str] = []
+        for text in self._attributes(item):
+            match = DERIVE_ATTRIBUTE.match(text)
+            if match:
+                derives.extend(name.strip() for name in match.group("traits").split(",") if name.strip())
+        if not derives:
+            return
+        self.nodes[type_id].properties["derives"] = derives
+        for name in derives:
+            self._queue_reference("IMPLEMENTS", type_id, self._path_segments(name), scope, derived=True)
+    
+    def _parse_fields(self, body: Optional[SgNode], type_id: str, type_name: str) -> None:
+        """Extract the named fields of a struct or union, one ClassVariable each; tuple fields have no name."""
+        if body is None or body.kind() != "field_declaration_list":
+            return
+        for field_node in body.children():
+            name_node = field_node.field("name") if field_node.kind() == "field_declaration" else None
+            if name_node is None:
+                continue
+            line_no = field_node.range().start.line + 1
+            properties: Dict[str, Any] = {"parent_class": type_name}
+            if field_node.field("type") is not None:
+                properties["type"] = collapse(field_node.field("type").text())
+            visibility = next((c for c in field_node.children() if c.kind() == "visibility_modifier"), None)
+            if visibility is not None:
+                properties["visibility"] = collapse(visibility.text())
+            var_node_id = self._get_node_id("Variable", name_node.text(), self.current_file, line_no)
+            self.nodes[var_node_id] = CodeNode(
+                node_id=var_node_id,
+                node_type="ClassVariable",
+                name=name_node.text(),
                 file_path=self.current_file,
                 line_no=line_no,
+                properties=properties,
             )
+            self._set_doc(var_node_id, self._doc(field_node))
             
-            # Add CONTAINS relation from file to struct
-            self._add_relation(CodeRelation(file_node_id, struct_node_id, "CONTAINS"))
-            
-            # Index the struct for cross-file resolution
-            if build_index:
-                self.module_definitions[module_name][struct_name] = struct_node_id
-    
-    def _parse_functions(self, root: SgNode, file_node_id: str) -> None:
-        """Extract top-level function declarations."""
-        # Find all function_item nodes at top level (not in impl blocks)
-        for func_node in root.children():
-            if func_node.kind() == "function_item":
-                # Get function name
-                name_field = func_node.field("name")
-                if not name_field:
+            # Add DEFINES relation from struct to field
+            self._add_relation(CodeRelation(type_id, var_node_id, "DEFINES"))
+    
+    def _parse_trait(self, item: SgNode, trait_id: str, name: str, scope: RustScope, build_index: bool) -> None:
+        """
+        Extract the supertraits and methods of a trait.
+        
+        Supertraits give EXTENDS edges and "extends"; every method,
+        required or with a default body ("default"), is a Method node the
+        trait DEFINES, and its signature is listed in "methods".
+        """
+        properties = self.nodes[trait_id].properties
+        bounds = item.field("bounds")
+        supertraits = []
+        for bound in bounds.children() if bounds is not None else []:
+            path = self._type_path(bound)
+            if path:
+                supertraits.append("::".join(path))
+                self._queue_reference("EXTENDS", trait_id, path, scope)
+        if supertraits:
+            properties["extends"] = supertraits
+        
+        body = item.field("body")
+        trait_scope = replace(scope, self_type=[name])
+        for member in body.children() if body is not None else []:
+            if member.kind() not in RUST_FUNCTIONS:
+                continue
+            method_id = self._add_method(member, name, trait_scope, build_index, {"trait": name})
+            if method_id is None:
+                continue
+            if member.kind() == "function_item":
+                self.nodes[method_id].properties["default"] = True
+            properties.setdefault("methods", []).append(self.nodes[method_id].properties["signature"])
+            self._add_relation(CodeRelation(trait_id, method_id, "DEFINES"))
+    
+    # Functions
+    
+    def _signature(self, func: SgNode, name: str) -> Dict[str, Any]:
+        """
+        signature_json, arity and signature of a function or method (see signatures.py).
+        
+        The self parameter is left out, like a Go receiver; patterns are
+        kept as parameter names and C variadics (`...`) have no name.
+        """
+        entries = []
+        parameters = func.field("parameters")
+        for child in parameters.children() if parameters is not None else []:
+            kind = child.kind()
+            if kind == "parameter":
+                pattern = child.field("pattern")
+                name_text = collapse(pattern.text()) if pattern is not None else None
+                if name_text in ("self", "mut self"):
+                    # self: Box<Self>
                     continue
-                
-                func_name = name_field.text()
-                line_no = func_node.range().start.line + 1
-                
-                # Create function node
-                func_node_id = self._get_node_id("Function", func_name, self.current_file, line_no)
-                self.nodes[func_node_id] = CodeNode(
-                    node_id=func_node_id,
-                    node_type="Function",
-                    name=func_name,
-                    file_path=self.current_file,
-                    line_no=line_no,
-                )
-                
-                # Add CONTAINS relation from file to function
-                self._add_relation(CodeRelation(file_node_id, func_node_id, "CONTAINS"))
-    
-    def _parse_impl_blocks(self, root: SgNode, build_index: bool, module_name: str) -> None:
-        """Extract impl blocks and their methods."""
-        # Find all impl_item nodes
-        for impl_node in root.find_all(kind="impl_item"):
-            # Get the type this impl is for
-            type_field = impl_node.field("type")
-            if not type_field:
+                type_node = child.field("type")
+                entries.append(parameter(name_text, type_node.text() if type_node is not None else None))
+            elif kind == "variadic_parameter":
+                entries.append(parameter(None, "...", variadic=True))
+        
+        return_node = func.field("return_type")
+        return_type = collapse(return_node.text()) if return_node is not None else None
+        returns = [{"name": None, "type": return_type}] if return_type else []
+        type_parameters = self._type_parameters(func.field("type_parameters"))
+        
+        display = name
+        if type_parameters:
+            display += "<" + ", ".join(
+                f"{entry['name']}: {entry['constraint']}" if entry["constraint"] else entry["name"]
+                for entry in type_parameters
+            ) + ">"
+        display += "(" + ", ".join(
+            f"{entry['name']}: {entry['type']}" if entry["name"] else entry["type"] for entry in entries
+        ) + ")"
+        if return_type:
+            display += f" -> {return_type}"
+        return signature_properties(entries, returns, type_parameters, display=display)
+    
+    @staticmethod
+    def _metrics(func: SgNode) -> Dict[str, int]:
+        """Complexity metrics of a function with a body (see metrics.py)."""
+        return syntax_metrics(func, RUST_METRIC_RULES, func.range().start.line + 1, func.range().end.line + 1)
+    
+    @staticmethod
+    def _receiver_kind(func: SgNode) -> Optional[str]:
+        """"value" for self, "ref" for &self, "mut_ref" for &mut self, None for an associated function."""
+        parameters = func.field("parameters")
+        for child in parameters.children() if parameters is not None else []:
+            if child.kind() == "self_parameter":
+                text = child.text().strip()
+                if not text.startswith("&"):
+                    return "value"
+                return "mut_ref" if MUT_REFERENCE.match(text) else "ref"
+            if child.kind() == "parameter":
+                pattern = child.field("pattern")
+                if pattern is not None and collapse(pattern.text()) in ("self", "mut self"):
+                    return "value"
+        return None
+    
+    def _parse_function(self, item: SgNode, scope: RustScope, build_index: bool) -> Optional[str]:
+        """Extract a free function with its signature and calls; returns its node ID."""
+        name_node = item.field("name")
+        if name_node is None:
+            return None
+        name = name_node.text()
+        properties = {
+            "qualified_name": "::".join(scope.path + [name]),
+            **self._signature(item, name),
+            **self._metrics(item),
+        }
+        func_id = self._add_item(item, "Function", name, scope, properties, build_index)
+        self._parse_calls(item.field("body"), func_id, scope)
+        return func_id
+    
+    def _add_method(self, item: SgNode, owner: str, scope: RustScope, build_index: bool,
+                    properties: Dict[str, Any]) -> Optional[str]:
+        """Create the Method node of a function in an impl block or trait, indexed as "Owner.name"; returns its ID."""
+        name_node = item.field("name")
+        if name_node is None:
+            return None
+        name = name_node.text()
+        receiver_kind = self._receiver_kind(item)
+        if receiver_kind:
+            properties["receiver_kind"] = receiver_kind
+        properties.update(self._signature(item, name))
+        properties.update(self._metrics(item))
+        method_id = self._add_item(item, "Method", name, scope, properties, build_index, index_name=f"{owner}.{name}")
+        self._parse_calls(item.field("body"), method_id, scope)
+        return method_id
+    
+    def _parse_impl(self, item: SgNode, scope: RustScope, build_index: bool) -> None:
+        """
+        Extract an impl block.
+        
+        Each method gets METHOD_OF to the type (DEFINES the other way) and
+        "receiver_type"; the methods of `impl Trait for Type` also record
+        "trait", and the type gets an IMPLEMENTS edge to the trait. A type
+        declared in another file is resolved in the second pass, like the
+        trait; traits that are not indexed (e.g. fmt::Display) are still
+        listed in the type's "implements" when the type is in this file.
+        """
+        type_path = self._type_path(item.field("type"))
+        if not type_path:
+            return
+        type_name = type_path[-1]
+        type_candidates = self._candidates(type_path, scope)
+        type_id = next((
+            self.file_types[f"{key[len('rust:'):]}::{name}"] for key, name in type_candidates
+            if f"{key[len('rust:'):]}::{name}" in self.file_types
+        ), None)
+        
+        trait_path = self._type_path(item.field("trait"))
+        trait_name = "::".join(trait_path) if trait_path else None
+        if trait_path:
+            if type_id is not None:
+                self.nodes[type_id].properties.setdefault("implements", []).append(trait_name)
+                self._queue_reference("IMPLEMENTS", type_id, trait_path, scope)
+            else:
+                self._queue_reference("IMPLEMENTS", None, trait_path, scope, source_candidates=type_candidates)
+        
+        body = item.field("body")
+        impl_scope = replace(scope, self_type=type_path)
+        for member in body.children() if body is not None else []:
+            if member.kind() != "function_item":
                 continue
-            
-            type_name = type_field.text()
-            
-            # Find the corresponding struct node
-            struct_node_id = None
-            for node_id, node in self.nodes.items():
-                if node.node_type == "Class" and node.name == type_name:
-                    struct_node_id = node_id
-                    break
-            
-            # If struct not found, skip (might be external type)
-            if not struct_node_id:
+            properties: Dict[str, Any] = {"receiver_type": type_name}
+            if trait_name:
+                properties["trait"] = trait_name
+            method_id = self._add_method(member, type_name, impl_scope, build_index, properties)
+            if method_id is None:
                 continue
-            
-            # Extract methods from impl block body
-            body = impl_node.field("body")
-            if body:
-                for child in body.children():
-                    if child.kind() == "function_item":
-                        # Get method name
-                        name_field = child.field("name")
-                        if not name_field:
-                            continue
-                        
-                        method_name = name_field.text()
-                        line_no = child.range().start.line + 1
-                        
-                        # Create method node
-                        method_node_id = self._get_node_id("Method", method_name, self.current_file, line_no)
-                        self.nodes[method_node_id] = CodeNode(
-                            node_id=method_node_id,
-                            node_type="Method",
-                            name=method_name,
-                            file_path=self.current_file,
-                            line_no=line_no,
-                        )
-                        
-                        # Add DEFINES relation from struct to method
-                        self._add_relation(CodeRelation(struct_node_id, method_node_id, "DEFINES"))
+            receiver_kind = self.nodes[method_id].properties.get("receiver_kind")
+            if type_id is not None:
+                self._add_relation(CodeRelation(type_id, method_id, "DEFINES"))
+                self._add_relation(CodeRelation(method_id, type_id, "METHOD_OF", {"receiver_kind": receiver_kind}))
+            else:
+                # Type declared in another file, or not indexed at all
+                self.pending_imports.append({
+                    "type": "METHOD_OF",
+                    "source_id": method_id,
+                    "imported_module": type_candidates[0][0],
+                    "imported_name": type_candidates[0][1],
+                    "candidates": type_candidates,
+                    "receiver_kind": receiver_kind,
+                })
+    
+    # Calls
+    
+    def _call_path(self, function: Optional[SgNode], scope: RustScope) -> Optional[List[str]]:
+        """Path of the callee of a call: `f`, `a::f`, `Type::f`, `Self::f`, or `self.f` as ["Self", "f"]."""
+        if function is None:
+            return None
+        kind = function.kind()
+        if kind == "generic_function":
+            return self._call_path(function.field("function"), scope)
+        if kind in ("identifier", "scoped_identifier"):
+            return self._path_segments(function.text()) or None
+        if kind == "field_expression" and scope.self_type:
+            value = function.field("value")
+            name = function.field("field")
+            if value is not None and name is not None and value.text() == "self":
+                return ["Self", name.text()]
+        return None
+    
+    def _parse_calls(self, body: Optional[SgNode], caller_id: str, scope: RustScope) -> None:
+        """
+        Queue the CALLS edges of a function body.
+        
+        Calls are grouped per target, so each edge carries the first
+        call-site line in "line_no" and every call-site line in
+        "call_lines". Paths resolve through _candidates; other method calls
+        would need type inference and are skipped, as are macro arguments.
+        Calls into another crate fall back to a placeholder node.
+        """
+        if body is None:
+            return
+        calls: Dict[Tuple[str, ...], List[int]] = {}
+        for call in body.find_all(kind="call_expression"):
+            path = self._call_path(call.field("function"), scope)
+            if path:
+                calls.setdefault(tuple(path), []).append(call.range().start.line + 1)
+        
+        for path, lines in calls.items():
+            extra: Dict[str, Any] = {"line_no": lines[0], "call_lines": lines}
+            if len(path) > 1:
+                module = self._absolute_module(list(path[:-1]), scope)
+                # Prelude types (String::from) are not crates
+                if module[0] != self.current_crate and not module[0][:1].isupper():
+                    extra["external"] = {"import_path": "::".join(module), "name": path[-1]}
+            self._queue_reference("CALLS", caller_id, list(path), scope, **extra)
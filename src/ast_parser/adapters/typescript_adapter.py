@@ -0,0 +1,951 @@
+"""
+TypeScript/TSX adapter using ast-grep.
+
+使用 ast-grep 的 TypeScript/TSX 適配器
+Extends the JavaScript adapter with the declarations TypeScript adds on
+top of JavaScript: interfaces, type aliases, enums, abstract classes, class
+fields and decorators. Imports are resolved to the file they name when the
+file is parsed (see TsModuleResolver), so `import { X } from './y'` links
+to the node that defines X.
+"""
+
+import os
+import logging
+from typing import Dict, List, Optional, Set, Tuple
+from ast_grep_py import SgRoot, SgNode
+
+from src.ast_parser.packages import npm_package_name
+from src.ast_parser.parser import CodeNode, CodeRelation
+from src.ast_parser.metrics import JAVASCRIPT_METRIC_RULES, syntax_metrics
+from src.ast_parser.signatures import typescript_signature
+from src.ast_parser.ts_module_resolver import TsModuleResolver, module_key
+from src.ast_parser.variables import (
+    USAGE_COMPARE, USAGE_READ, USAGE_RETURN, USAGE_WRITE, VARIABLE_NODE_TYPES, queue_uses,
+)
+from .javascript_adapter import JavaScriptAstGrepAdapter
+
+logger = logging.getLogger(__name__)
+
+# Class declaration node kinds
+CLASS_KINDS = ("class_declaration", "abstract_class_declaration")
+
+# Node kinds that start a new function scope
+FUNCTION_KINDS = (
+    "function_declaration", "generator_function_declaration", "function_expression", "function",
+    "arrow_function", "method_definition",
+)
+
+# JSX node kinds that make a function a React component
+JSX_KINDS = ("jsx_element", "jsx_self_closing_element")
+
+# Declarations that can be exported by name
+TOP_LEVEL_TYPES = ("Class", "Interface", "TypeAlias", "Enum", "Function") + VARIABLE_NODE_TYPES
+
+# Binary operators whose operands are compared
+COMPARISON_OPERATORS = ("==", "===", "!=", "!==", "<", "<=", ">", ">=", "instanceof", "in")
+
+# Declarations that bind a local name: (node kind, field holding the name or pattern)
+LOCAL_BINDINGS = (
+    ("variable_declarator", "name"), ("required_parameter", "pattern"), ("optional_parameter", "pattern"),
+    ("arrow_function", "parameter"), ("catch_clause", "parameter"), ("for_in_statement", "left"),
+    ("function_declaration", "name"), ("class_declaration", "name"),
+)
+
+# Longest type alias text kept on the node
+TYPE_TEXT_LIMIT = 200
+
+
+class TypeScriptAdapter(JavaScriptAstGrepAdapter):
+    """
+    TypeScript adapter using ast-grep for parsing.
+    
+    Extracts: Classes (including abstract ones), Methods, ClassVariables
+    (fields and constructor parameter properties), Interfaces, TypeAliases,
+    Enums, Functions, Constants and Variables, Imports, Exports
+    Creates relations: CONTAINS, DEFINES, EXTENDS, IMPLEMENTS, IMPORTS_FROM,
+    IMPORTS_DEFINITION, DECORATED_BY, USES
+    
+    Symbols are indexed under the file's path (see module_key) as well as
+    its base name. Named, default and namespace imports are queued against
+    the path of the file they resolve to, and `export ... from` re-exports
+    are expanded in the second pass, so imports through an index file reach
+    the actual definition. Imports of packages are not linked.
+    
+    Decorators are recorded like Python decorators: their texts are kept in
+    the "decorators" property and decorators that resolve to indexed symbols
+    get DECORATED_BY relations. In .tsx files, functions with a capitalized
+    name that return JSX are flagged with "component". Functions and methods
+    get USES relations to the top-level `const` / `let` / `var` bindings of
+    the repository they use, imported or not (see variables.py).
+    
+    Supports TypeScript source files (.ts, .tsx).
+    """
+    
+    def __init__(self, use_tsx: bool = False):
+        """
+        Initialize the TypeScript adapter.
+        
+        Args:
+            use_tsx: Whether to use TSX parser (for React components)
+        """
+        super().__init__(use_tsx=use_tsx)
+        self.language = "typescript"
+        self.resolver = TsModuleResolver()
+        self.file_key: str = ""
+        # {local_name: (module_key, exported_name)}; "*" for namespace imports
+        self.import_bindings: Dict[str, Tuple[str, str]] = {}
+    
+    def parse_file(self, file_path: str, build_index: bool = False) -> Tuple[Dict[str, CodeNode], List[CodeRelation]]:
+        """
+        Parse a TypeScript file using ast-grep.
+        
+        Args:
+            file_path: Path to the file to parse
+            build_index: Whether to build module definition index
+        
+        Returns:
+            Tuple of (nodes dictionary, relations list)
+        """
+        self.current_file = file_path
+        self.imports = {}
+        self.import_bindings = {}
+        self.file_key = module_key(file_path)
+        
+        try:
+            with open(file_path, "r", encoding="utf-8") as f:
+                source_code = f.read()
+            
+            language = "tsx" if os.path.splitext(file_path)[1].lower() == ".tsx" else "typescript"
+            root = SgRoot(source_code, language).root()
+            
+            file_node_id = self._create_file_node(file_path)
+            
+            module_name = os.path.splitext(os.path.basename(file_path))[0]
+            if build_index:
+                self.module_definitions.setdefault(module_name, {})
+                self.module_definitions.setdefault(self.file_key, {})
+                self.module_to_file[module_name] = file_node_id
+                self.module_to_file[self.file_key] = file_node_id
+            
+            self._parse_file_comments(root, file_node_id)
+            self._parse_imports(root, file_node_id)
+            self._parse_classes(root, file_node_id, build_index, module_name)
+            self._parse_interfaces(root, file_node_id, build_index, module_name)
+            self._parse_type_aliases(root, file_node_id, build_index, module_name)
+            self._parse_enums(root, file_node_id, build_index, module_name)
+            self._parse_functions(root, file_node_id, build_index, module_name)
+            self._parse_variables(root, file_node_id)
+            
+            if build_index:
+                for node_id, node in self.nodes.items():
+                    if node.node_type in VARIABLE_NODE_TYPES and node.file_path == file_path:
+                        self.module_definitions[module_name].setdefault(node.name, node_id)
+                # Functions are indexed by the JavaScript adapter under the base name only
+                self.module_definitions[self.file_key].update(self.module_definitions[module_name])
+            
+            self._parse_exports(root, file_node_id, build_index)
+            self._parse_variable_uses(root)
+            self._parse_jest_tests(root, file_node_id)
+            
+            return self.nodes, self.relations
+        
+        except Exception as e:
+            logger.error(f"Error parsing file {file_path}: {e}")
+            self.parse_error = e
+            return {}, []
+    
+    def _parse_imports(self, root: SgNode, file_node_id: str) -> None:
+        """
+        Extract import statements and queue them against the resolved file.
+        
+        Handles:
+        - import { a, b as c } from './module'
+        - import Default from './module'
+        - import * as ns from './module'
+        - import type { T } from '@app/types'
+        - import './side-effect'
+        """
+        for import_node in root.find_all(kind="import_statement"):
+            source = import_node.field("source")
+            if source is None:
+                continue
+            specifier = self._string_value(source)
+            
+            # (local name, exported name)
+            bindings: List[Tuple[str, str]] = []
+            clause = next((c for c in import_node.children() if c.kind() == "import_clause"), None)
+            if clause is not None:
+                for child in clause.children():
+                    if child.kind() == "identifier":
+                        bindings.append((child.text(), "default"))
+                    elif child.kind() == "named_imports":
+                        for spec in child.find_all(kind="import_specifier"):
+                            name_node = spec.field("name")
+                            if name_node is None:
+                                continue
+                            alias_node = spec.field("alias")
+                            local = alias_node.text() if alias_node else name_node.text()
+                            bindings.append((local, name_node.text()))
+                    elif child.kind() == "namespace_import":
+                        identifiers = [c for c in child.children() if c.kind() == "identifier"]
+                        if identifiers:
+                            bindings.append((identifiers[0].text(), "*"))
+            
+            for local, _ in bindings:
+                self.imports[local] = specifier
+            
+            line_no = import_node.range().start.line + 1
+            target = self.resolver.resolve(specifier, self.current_file)
+            if target is None:
+                # Bare specifiers that do not resolve are npm packages
+                package_name = npm_package_name(specifier)
+                if package_name:
+                    self.pending_imports.append({
+                        "type": "IMPORTS_MODULE",
+                        "source_id": file_node_id,
+                        "imported_module": None,
+                        "full_module_path": specifier,
+                        "external_package": package_name,
+                        "line_no": line_no,
+                    })
+                continue
+            target_key = module_key(target)
+            
+            self.pending_imports.append({
+                "type": "IMPORTS_MODULE",
+                "source_id": file_node_id,
+                "imported_module": target_key,
+                "full_module_path": specifier,
+                "line_no": line_no,
+            })
+            for local, imported in bindings:
+                self.import_bindings[local] = (target_key, imported)
+                if imported == "*":
+                    continue
+                self.pending_imports.append({
+                    "type": "IMPORTS_SYMBOL",
+                    "source_id": file_node_id,
+                    "imported_module": target_key,
+                    "imported_name": imported,
+                    "alias": local if local != imported else None,
+                })
+    
+    def _parse_classes(self, root: SgNode, file_node_id: str, build_index: bool, module_name: str) -> None:
+        """
+        Extract class declarations with their members.
+        
+        Handles:
+        - class Dog extends Animal implements Pet { ... }
+        - abstract class Shape { abstract area(): number; }
+        - @Component({...}) export class AppComponent { ... }
+        """
+        for kind in CLASS_KINDS:
+            for class_node in root.find_all(kind=kind):
+                name_node = class_node.field("name")
+                if name_node is None:
+                    continue
+                
+                class_name = name_node.text()
+                line_no = class_node.range().start.line + 1
+                end_line_no = class_node.range().end.line + 1
+                
+                properties = {"language": self._get_language_from_file()}
+                if kind == "abstract_class_declaration":
+                    properties["is_abstract"] = True
+                
+                node_id = self._get_node_id("Class", class_name, self.current_file, line_no)
+                self.nodes[node_id] = CodeNode(
+                    node_id=node_id,
+                    node_type="Class",
+                    name=class_name,
+                    file_path=self.current_file,
+                    line_no=line_no,
+                    end_line_no=end_line_no,
+                    properties=properties,
+                )
+                self.nodes[node_id].code_snippet = class_node.text()
+                self._attach_jsdoc(node_id, class_node)
+                
+                self._add_relation(CodeRelation(
+                    source_id=file_node_id,
+                    target_id=node_id,
+                    relation_type="CONTAINS"
+                ))
+                
+                # `@Dec() export class X` attaches the decorator to the export statement
+                decorators = [c for c in class_node.children() if c.kind() == "decorator"]
+                parent = class_node.parent()
+                if parent is not None and parent.kind() == "export_statement":
+                    decorators = [c for c in parent.children() if c.kind() == "decorator"] + decorators
+                self._record_decorators(decorators, node_id)
+                
+                self._extract_class_heritage(class_node, node_id)
+                self._extract_class_members(class_node, node_id, class_name)
+                
+                if build_index and module_name:
+                    self.module_definitions[module_name][class_name] = node_id
+    
+    def _extract_class_heritage(self, class_node: SgNode, class_node_id: str) -> None:
+        """Queue EXTENDS and IMPLEMENTS for the extends and implements clauses of a class."""
+        heritage = next((c for c in class_node.children() if c.kind() == "class_heritage"), None)
+        if heritage is None:
+            return
+        
+        for clause in heritage.children():
+            if clause.kind() == "extends_clause":
+                value = clause.field("value")
+                if value is not None:
+                    self.nodes[class_node_id].properties["extends"] = value.text()
+                    self._queue_type_reference("EXTENDS", class_node_id, value)
+            elif clause.kind() == "implements_clause":
+                types = [c for c in clause.children() if c.is_named()]
+                self.nodes[class_node_id].properties["implements"] = [t.text() for t in types]
+                for type_node in types:
+                    self._queue_type_reference("IMPLEMENTS", class_node_id, type_node)
+    
+    def _extract_class_members(self, class_node: SgNode, class_node_id: str, class_name: str) -> None:
+        """
+        Extract methods and fields from a class body.
+        
+        Method decorators precede the method as siblings in the class body,
+        field decorators are children of the field.
+        """
+        body = class_node.field("body")
+        if body is None:
+            return
+        
+        pending_decorators: List[SgNode] = []
+        for member in body.children():
+            kind = member.kind()
+            if kind == "decorator":
+                pending_decorators.append(member)
+                continue
+            decorators = pending_decorators + [c for c in member.children() if c.kind() == "decorator"]
+            pending_decorators = []
+            
+            # method_signature in a class body is an overload of the method_definition that follows
+            if kind in ("method_definition", "abstract_method_signature"):
+                self._add_method(member, class_node_id, class_name, decorators)
+            elif kind == "public_field_definition":
+                name_node = member.field("name")
+                if name_node is not None:
+                    self._add_field(member, name_node.text(), class_node_id, class_name, decorators)
+    
+    def _add_method(self, method_node: SgNode, class_node_id: str, class_name: str,
+                    decorators: List[SgNode]) -> None:
+        """Create a Method node, and fields for constructor parameter properties."""
+        name_node = method_node.field("name")
+        if name_node is None:
+            return
+        
+        method_name = name_node.text()
+        line_no = method_node.range().start.line + 1
+        end_line_no = method_node.range().end.line + 1
+        
+        properties = {
+            "is_method": True,
+            "parent_class": class_name,
+            "parameters": self._extract_function_params(method_node),
+            "language": self._get_language_from_file(),
+            "is_async": self._is_async_function(method_node),
+            **typescript_signature(method_node, method_name),
+            **syntax_metrics(method_node, JAVASCRIPT_METRIC_RULES, line_no, end_line_no),
+        }
+        properties.update(self._member_modifiers(method_node))
+        if method_node.kind() == "abstract_method_signature":
+            properties["is_abstract"] = True
+        
+        node_id = self._get_node_id("Method", method_name, self.current_file, line_no)
+        self.nodes[node_id] = CodeNode(
+            node_id=node_id,
+            node_type="Method",
+            name=method_name,
+            file_path=self.current_file,
+            line_no=line_no,
+            end_line_no=end_line_no,
+            properties=properties,
+        )
+        self.nodes[node_id].code_snippet = method_node.text()
+        self._attach_jsdoc(node_id, method_node)
+        
+        self._add_relation(CodeRelation(
+            source_id=class_node_id,
+            target_id=node_id,
+            relation_type="DEFINES"
+        ))
+        self._record_decorators(decorators, node_id)
+        
+        # `constructor(private readonly repo: Repo)` declares a field
+        if method_name == "constructor":
+            parameters = method_node.field("parameters")
+            for param in parameters.children() if parameters is not None else []:
+                if param.kind() not in ("required_parameter", "optional_parameter"):
+                    continue
+                if not any(c.kind() in ("accessibility_modifier", "readonly") for c in param.children()):
+                    continue
+                pattern = param.field("pattern")
+                if pattern is not None and pattern.kind() == "identifier":
+                    param_decorators = [c for c in param.children() if c.kind() == "decorator"]
+                    self._add_field(param, pattern.text(), class_node_id, class_name, param_decorators,
+                                    parameter_property=True)
+    
+    def _add_field(self, field_node: SgNode, field_name: str, class_node_id: str, class_name: str,
+                   decorators: List[SgNode], parameter_property: bool = False) -> None:
+        """Create a ClassVariable node for a class field."""
+        line_no = field_node.range().start.line + 1
+        
+        properties = {"parent_class": class_name, "language": self._get_language_from_file()}
+        type_node = field_node.field("type")
+        if type_node is not None:
+            properties["annotation"] = type_node.text().lstrip(":").strip()
+        if field_node.field("value") is not None:
+            properties["has_default"] = True
+        if parameter_property:
+            properties["parameter_property"] = True
+        properties.update(self._member_modifiers(field_node))
+        
+        node_id = self._get_node_id("ClassVariable", field_name, self.current_file, line_no)
+        self.nodes[node_id] = CodeNode(
+            node_id=node_id,
+            node_type="ClassVariable",
+            name=field_name,
+            file_path=self.current_file,
+            line_no=line_no,
+            end_line_no=field_node.range().end.line + 1,
+            properties=properties,
+        )
+        if not parameter_property:
+            self._attach_jsdoc(node_id, field_node)
+        
+        self._add_relation(CodeRelation(
+            source_id=class_node_id,
+            target_id=node_id,
+            relation_type="DEFINES"
+        ))
+        self._record_decorators(decorators, node_id)
+    
+    def _member_modifiers(self, member: SgNode) -> Dict[str, object]:
+        """Accessibility, static and readonly modifiers of a class member."""
+        modifiers: Dict[str, object] = {}
+        for child in member.children():
+            if child.kind() == "accessibility_modifier":
+                modifiers["accessibility"] = child.text()
+            elif child.kind() == "static":
+                modifiers["is_static"] = True
+            elif child.kind() == "readonly":
+                modifiers["is_readonly"] = True
+            elif child.kind() == "abstract":
+                modifiers["is_abstract"] = True
+        return modifiers
+    
+    def _parse_interfaces(self, root: SgNode, file_node_id: str, build_index: bool, module_name: str) -> None:
+        """
+        Extract interface declarations.
+        
+        Method and property signatures are kept as properties of the
+        Interface node; extended interfaces become EXTENDS relations.
+        """
+        for interface_node in root.find_all(kind="interface_declaration"):
+            name_node = interface_node.field("name")
+            if name_node is None:
+                continue
+            
+            interface_name = name_node.text()
+            line_no = interface_node.range().start.line + 1
+            
+            methods: List[str] = []
+            members: List[str] = []
+            body = interface_node.field("body")
+            for member in body.children() if body is not None else []:
+                signature = " ".join(member.text().rstrip(";,").split())
+                if member.kind() == "method_signature":
+                    methods.append(signature)
+                elif member.kind() == "property_signature":
+                    members.append(signature)
+            
+            properties = {"language": self._get_language_from_file(), "type_kind": "interface"}
+            if methods:
+                properties["methods"] = methods
+            if members:
+                properties["properties"] = members
+            
+            node_id = self._get_node_id("Interface", interface_name, self.current_file, line_no)
+            self.nodes[node_id] = CodeNode(
+                node_id=node_id,
+                node_type="Interface",
+                name=interface_name,
+                file_path=self.current_file,
+                line_no=line_no,
+                end_line_no=interface_node.range().end.line + 1,
+                properties=properties,
+            )
+            self.nodes[node_id].code_snippet = interface_node.text()
+            self._attach_jsdoc(node_id, interface_node)
+            
+            self._add_relation(CodeRelation(
+                source_id=file_node_id,
+                target_id=node_id,
+                relation_type="CONTAINS"
+            ))
+            
+            extends = next((c for c in interface_node.children() if c.kind() == "extends_type_clause"), None)
+            if extends is not None:
+                types = [c for c in extends.children() if c.is_named()]
+                properties["extends"] = [t.text() for t in types]
+                for type_node in types:
+                    self._queue_type_reference("EXTENDS", node_id, type_node)
+            
+            if build_index and module_name:
+                self.module_definitions[module_name][interface_name] = node_id
+    
+    def _parse_type_aliases(self, root: SgNode, file_node_id: str, build_index: bool, module_name: str) -> None:
+        """Extract `type Name = ...` declarations."""
+        for alias_node in root.find_all(kind="type_alias_declaration"):
+            name_node = alias_node.field("name")
+            if name_node is None:
+                continue
+            
+            alias_name = name_node.text()
+            line_no = alias_node.range().start.line + 1
+            
+            properties = {"language": self._get_language_from_file()}
+            value = alias_node.field("value")
+            if value is not None:
+                properties["type"] = " ".join(value.text().split())[:TYPE_TEXT_LIMIT]
+            
+            node_id = self._get_node_id("TypeAlias", alias_name, self.current_file, line_no)
+            self.nodes[node_id] = CodeNode(
+                node_id=node_id,
+                node_type="TypeAlias",
+                name=alias_name,
+                file_path=self.current_file,
+                line_no=line_no,
+                end_line_no=alias_node.range().end.line + 1,
+                properties=properties,
+            )
+            self.nodes[node_id].code_snippet = alias_node.text()
+            self._attach_jsdoc(node_id, alias_node)
+            
+            self._add_relation(CodeRelation(
+                source_id=file_node_id,
+                target_id=node_id,
+                relation_type="CONTAINS"
+            ))
+            
+            if build_index and module_name:
+                self.module_definitions[module_name][alias_name] = node_id
+    
+    def _parse_enums(self, root: SgNode, file_node_id: str, build_index: bool, module_name: str) -> None:
+        """Extract enum declarations with their member names."""
+        for enum_node in root.find_all(kind="enum_declaration"):
+            name_node = enum_node.field("name")
+            if name_node is None:
+                continue
+            
+            enum_name = name_node.text()
+            line_no = enum_node.range().start.line + 1
+            
+            members: List[str] = []
+            body = enum_node.field("body")
+            for member in body.children() if body is not None else []:
+                if member.kind() == "enum_assignment":
+                    member = member.field("name") or member
+                if member.is_named():
+                    members.append(member.text())
+            
+            properties = {"language": self._get_language_from_file(), "members": members}
+            if any(c.kind() == "const" for c in enum_node.children()):
+                properties["is_const"] = True
+            
+            node_id = self._get_node_id("Enum", enum_name, self.current_file, line_no)
+            self.nodes[node_id] = CodeNode(
+                node_id=node_id,
+                node_type="Enum",
+                name=enum_name,
+                file_path=self.current_file,
+                line_no=line_no,
+                end_line_no=enum_node.range().end.line + 1,
+                properties=properties,
+            )
+            self.nodes[node_id].code_snippet = enum_node.text()
+            self._attach_jsdoc(node_id, enum_node)
+            
+            self._add_relation(CodeRelation(
+                source_id=file_node_id,
+                target_id=node_id,
+                relation_type="CONTAINS"
+            ))
+            
+            if build_index and module_name:
+                self.module_definitions[module_name][enum_name] = node_id
+    
+    def _parse_functions(self, root: SgNode, file_node_id: str, build_index: bool, module_name: str) -> None:
+        """Extract functions, flagging React function components in .tsx files."""
+        super()._parse_functions(root, file_node_id, build_index, module_name)
+        if self._get_language_from_file() != "tsx":
+            return
+        
+        candidates: List[Tuple[str, SgNode]] = []
+        for func_node in root.find_all(kind="function_declaration"):
+            name_node = func_node.field("name")
+            if name_node is not None:
+                candidates.append((name_node.text(), func_node))
+        for var_decl in root.find_all(kind="variable_declarator"):
+            name_node = var_decl.field("name")
+            arrow_func = var_decl.find(kind="arrow_function")
+            if name_node is not None and arrow_func is not None:
+                candidates.append((name_node.text(), arrow_func))
+        
+        for func_name, func_node in candidates:
+            line_no = func_node.range().start.line + 1
+            node_id = self._get_node_id("Function", func_name, self.current_file, line_no)
+            if node_id in self.nodes and func_name[:1].isupper() and self._returns_jsx(func_node):
+                self.nodes[node_id].properties["component"] = True
+    
+    def _returns_jsx(self, func_node: SgNode) -> bool:
+        """Whether a function returns JSX from its own body (not from a nested function)."""
+        body = func_node.field("body")
+        if body is None:
+            return False
+        if body.kind() != "statement_block":
+            return self._contains_jsx(body, func_node)
+        return any(
+            self._contains_jsx(ret, func_node)
+            for ret in body.find_all(kind="return_statement")
+            if self._same_node(self._owner_function(ret), func_node)
+        )
+    
+    def _contains_jsx(self, node: SgNode, func_node: SgNode) -> bool:
+        if node.kind() in JSX_KINDS:
+            return True
+        return any(
+            self._same_node(self._owner_function(jsx), func_node)
+            for kind in JSX_KINDS
+            for jsx in node.find_all(kind=kind)
+        )
+    
+    def _owner_function(self, node: SgNode) -> Optional[SgNode]:
+        """Nearest enclosing function of a node."""
+        current = node.parent()
+        while current is not None and current.kind() not in FUNCTION_KINDS:
+            current = current.parent()
+        return current
+    
+    @staticmethod
+    def _same_node(a: Optional[SgNode], b: Optional[SgNode]) -> bool:
+        if a is None or b is None:
+            return False
+        return (a.kind(), a.range().start.index, a.range().end.index) == (
+            b.kind(), b.range().start.index, b.range().end.index)
+    
+    def _parse_variable_uses(self, root: SgNode) -> None:
+        """
+        Queue USES relations from functions and methods to the bindings they use.
+        
+        A reference belongs to the nearest enclosing function that has a
+        node, so uses inside callbacks count for the function passing them.
+        Names bound in that function (parameters, declarations, catch and
+        for-of bindings) shadow the top-level ones; callees are left to
+        calls. Plain names and `ns.NAME` resolve like other references (see
+        _symbol_target); the second pass keeps Constant and Variable targets.
+        """
+        # (start, end) of each function with a node -> its node ID
+        owners: Dict[Tuple[int, int], str] = {}
+        for func_node in root.find_all(kind="function_declaration"):
+            name_node = func_node.field("name")
+            if name_node is not None and not self._is_inside_class(func_node):
+                owners[self._span(func_node)] = self._get_node_id(
+                    "Function", name_node.text(), self.current_file, func_node.range().start.line + 1)
+        for var_decl in root.find_all(kind="variable_declarator"):
+            name_node = var_decl.field("name")
+            arrow_func = var_decl.find(kind="arrow_function")
+            if name_node is not None and arrow_func is not None and not self._is_inside_class(var_decl):
+                owners[self._span(arrow_func)] = self._get_node_id(
+                    "Function", name_node.text(), self.current_file, arrow_func.range().start.line + 1)
+        for method_node in root.find_all(kind="method_definition"):
+            name_node = method_node.field("name")
+            if name_node is not None:
+                owners[self._span(method_node)] = self._get_node_id(
+                    "Method", name_node.text(), self.current_file, method_node.range().start.line + 1)
+        owners = {span: node_id for span, node_id in owners.items() if node_id in self.nodes}
+        
+        local_names: Dict[str, Set[str]] = {}
+        uses: Dict[str, Dict[Tuple[str, str, str], List[int]]] = {}
+        for identifier in root.find_all(kind="identifier"):
+            owner = self._owner_with_node(identifier, owners)
+            if owner is None:
+                continue
+            owner_node, owner_id = owner
+            if owner_id not in local_names:
+                local_names[owner_id] = self._local_names(owner_node)
+            name = identifier.text()
+            if name in local_names[owner_id] or self._is_callee(identifier):
+                continue
+            
+            expression = identifier
+            parent = identifier.parent()
+            binding = self.import_bindings.get(name)
+            if binding is not None and binding[1] == "*":
+                # ns.NAME through a namespace import
+                if parent is None or parent.kind() != "member_expression" or self._is_callee(parent):
+                    continue
+                expression = parent
+            target = self._symbol_target(expression)
+            if target is None:
+                continue
+            usage = self._usage(expression)
+            uses.setdefault(owner_id, {}).setdefault((target[0], target[1], usage), []).append(
+                expression.range().start.line + 1)
+        
+        for owner_id, owner_uses in uses.items():
+            queue_uses(self.pending_imports, owner_id, owner_uses)
+    
+    @staticmethod
+    def _span(node: SgNode) -> Tuple[int, int]:
+        return node.range().start.index, node.range().end.index
+    
+    def _owner_with_node(self, node: SgNode, owners: Dict[Tuple[int, int], str]) -> Optional[Tuple[SgNode, str]]:
+        """Nearest enclosing function of a node that has a Function or Method node, with that node's ID."""
+        current = self._owner_function(node)
+        while current is not None:
+            node_id = owners.get(self._span(current))
+            if node_id is not None:
+                return current, node_id
+            current = self._owner_function(current)
+        return None
+    
+    def _local_names(self, func_node: SgNode) -> Set[str]:
+        """Names bound inside a function, its own parameters included."""
+        names: Set[str] = set()
+        for kind, field in LOCAL_BINDINGS:
+            for binding in func_node.find_all(kind=kind):
+                target = binding.field(field)
+                if target is None:
+                    continue
+                if target.kind() in ("identifier", "type_identifier"):
+                    names.add(target.text())
+                elif binding.kind() in ("variable_declarator", "required_parameter", "optional_parameter",
+                                        "catch_clause", "for_in_statement"):
+                    # Destructuring patterns: { a, b: c }, [d, ...e]
+                    names.update(c.text() for c in target.find_all(kind="identifier"))
+                    names.update(c.text() for c in target.find_all(kind="shorthand_property_identifier_pattern"))
+        return names
+    
+    def _is_callee(self, expression: SgNode) -> bool:
+        """Whether an expression is the function of a call or the class of a `new`."""
+        parent = expression.parent()
+        if parent is None or parent.kind() not in ("call_expression", "new_expression"):
+            return False
+        callee = parent.field("function") if parent.kind() == "call_expression" else parent.field("constructor")
+        return self._same_node(callee, expression)
+    
+    def _usage(self, expression: SgNode) -> str:
+        """How an expression is used: compared, returned, written or read (see variables.py)."""
+        parent = expression.parent()
+        while parent is not None and parent.kind() == "parenthesized_expression":
+            expression, parent = parent, parent.parent()
+        if parent is None:
+            return USAGE_READ
+        kind = parent.kind()
+        if kind == "binary_expression" and parent.field("operator") is not None \
+                and parent.field("operator").text() in COMPARISON_OPERATORS:
+            return USAGE_COMPARE
+        if kind == "switch_case" and self._same_node(parent.field("value"), expression):
+            return USAGE_COMPARE
+        if kind == "return_statement" or (kind == "arrow_function" and self._same_node(parent.field("body"), expression)):
+            return USAGE_RETURN
+        if kind in ("assignment_expression", "augmented_assignment_expression") \
+                and self._same_node(parent.field("left"), expression):
+            return USAGE_WRITE
+        if kind == "update_expression":
+            return USAGE_WRITE
+        return USAGE_READ
+    
+    def _parse_exports(self, root: SgNode, file_node_id: str, build_index: bool) -> None:
+        """
+        Mark exported declarations and queue re-exports.
+        
+        Handles:
+        - export class / interface / type / enum / function / const
+        - export default Name, export default class Name { ... }
+        - export { a, b as c }
+        - export { a } from './module', export * from './module'
+        """
+        for export_node in root.find_all(kind="export_statement"):
+            source = export_node.field("source")
+            if source is not None:
+                self._parse_reexport(export_node, source, file_node_id)
+                continue
+            
+            is_default = any(c.kind() == "default" for c in export_node.children())
+            export_type = "default" if is_default else "named"
+            
+            names: List[str] = []
+            declaration = export_node.field("declaration")
+            value = export_node.field("value")
+            if declaration is not None:
+                if declaration.kind() == "lexical_declaration":
+                    names = [d.field("name").text() for d in declaration.children()
+                             if d.kind() == "variable_declarator" and d.field("name") is not None]
+                elif declaration.field("name") is not None:
+                    names = [declaration.field("name").text()]
+            elif value is not None and value.kind() == "identifier":
+                names = [value.text()]
+            
+            for name in names:
+                node_id = self._mark_exported(name, export_type)
+                if node_id and is_default and build_index:
+                    self.module_definitions[self.file_key]["default"] = node_id
+            
+            for clause in export_node.children():
+                if clause.kind() != "export_clause":
+                    continue
+                for spec in clause.find_all(kind="export_specifier"):
+                    name_node = spec.field("name")
+                    if name_node is None:
+                        continue
+                    alias_node = spec.field("alias")
+                    node_id = self._mark_exported(name_node.text(), "named")
+                    # `export { a as b }` makes the symbol importable as b
+                    if node_id and alias_node is not None and build_index:
+                        self.module_definitions[self.file_key][alias_node.text()] = node_id
+    
+    def _parse_reexport(self, export_node: SgNode, source: SgNode, file_node_id: str) -> None:
+        """Queue an `export ... from` statement: a file import plus re-exported names."""
+        target = self.resolver.resolve(self._string_value(source), self.current_file)
+        if target is None:
+            return
+        target_key = module_key(target)
+        
+        self.pending_imports.append({
+            "type": "IMPORTS_MODULE",
+            "source_id": file_node_id,
+            "imported_module": target_key,
+            "full_module_path": self._string_value(source),
+        })
+        
+        # (exported name, name in this module); `export * as ns` exports no single symbol
+        reexported: List[Tuple[str, Optional[str]]] = []
+        clause = next((c for c in export_node.children() if c.kind() == "export_clause"), None)
+        if clause is not None:
+            for spec in clause.find_all(kind="export_specifier"):
+                name_node = spec.field("name")
+                alias_node = spec.field("alias")
+                if name_node is not None:
+                    reexported.append((name_node.text(), alias_node.text() if alias_node else None))
+        elif not any(c.kind() == "namespace_export" for c in export_node.children()):
+            reexported.append(("*", None))
+        
+        for name, alias in reexported:
+            self.pending_imports.append({
+                "type": "REEXPORTS",
+                "source_id": file_node_id,
+                "module_key": self.file_key,
+                "imported_module": target_key,
+                "imported_name": name,
+                "alias": alias,
+            })
+    
+    def _mark_exported(self, name: str, export_type: str) -> Optional[str]:
+        """Flag the top-level declaration with this name as exported and return its ID."""
+        found = None
+        for node_id, code_node in self.nodes.items():
+            if (code_node.name == name and code_node.file_path == self.current_file
+                    and code_node.node_type in TOP_LEVEL_TYPES):
+                code_node.properties["exported"] = True
+                code_node.properties["export_type"] = export_type
+                found = found or node_id
+        return found
+    
+    def _record_decorators(self, decorators: List[SgNode], node_id: str) -> None:
+        """Record decorators on a node and queue DECORATED_BY resolution."""
+        texts = []
+        for decorator in decorators:
+            text = " ".join(decorator.text().lstrip("@").split())
+            texts.append(text)
+            
+            expression = next((c for c in decorator.children() if c.is_named()), None)
+            if expression is None:
+                continue
+            # `@Name(...)` resolves through the called name
+            if expression.kind() == "call_expression":
+                expression = expression.field("function") or expression
+            target = self._symbol_target(expression)
+            if target is None:
+                continue
+            
+            self.pending_imports.append({
+                "type": "DECORATED_BY",
+                "source_id": node_id,
+                "imported_module": target[0],
+                "imported_name": target[1],
+                "original_name": expression.text(),
+                "decorator": text,
+                "line_no": decorator.range().start.line + 1,
+            })
+        
+        if texts:
+            self.nodes[node_id].properties["decorators"] = texts
+    
+    def _queue_type_reference(self, relation_type: str, source_id: str, type_node: SgNode) -> None:
+        """Queue an EXTENDS or IMPLEMENTS relation to a class or interface name."""
+        target = self._symbol_target(type_node)
+        if target is None:
+            return
+        self.pending_imports.append({
+            "type": relation_type,
+            "source_id": source_id,
+            "imported_module": target[0],
+            "imported_name": target[1],
+            "original_name": type_node.text(),
+        })
+    
+    def _symbol_target(self, expression: SgNode) -> Optional[Tuple[str, str]]:
+        """
+        Module key and exported name a reference resolves to.
+        
+        Imported names go to the file they were imported from, `ns.Name`
+        goes through a namespace import, and other plain names are looked
+        up in the current file.
+        """
+        kind = expression.kind()
+        if kind == "generic_type":
+            name_node = expression.field("name")
+            return self._symbol_target(name_node) if name_node is not None else None
+        if kind in ("identifier", "type_identifier"):
+            name = expression.text()
+            binding = self.import_bindings.get(name)
+            if binding is None:
+                # Imported from a package: not part of the index
+                return None if name in self.imports else (self.file_key, name)
+            return None if binding[1] == "*" else binding
+        if kind in ("member_expression", "nested_type_identifier"):
+            namespace = expression.field("object") or expression.field("module")
+            member = expression.field("property") or expression.field("name")
+            if namespace is None or member is None:
+                return None
+            binding = self.import_bindings.get(namespace.text())
+            if binding is not None and binding[1] == "*":
+                return binding[0], member.text()
+        return None
+    
+    @staticmethod
+    def _string_value(string_node: SgNode) -> str:
+        return string_node.text().strip("\"'`")
+    
+    def _is_inside_class(self, node: SgNode) -> bool:
+        """
+        Check if a node is inside a class declaration, abstract classes included.
+        
+        Args:
+            node: ast-grep node to check
+        
+        Returns:
+            True if inside a class, False otherwise
+        """
+        current = node.parent()
+        while current:
+            if current.kind() in CLASS_KINDS:
+                return True
+            current = current.parent()
+        return False
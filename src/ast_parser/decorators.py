@@ -0,0 +1,94 @@
+"""
+Python decorators and dataclass fields.
+
+Functions, methods and classes keep the source text of their decorators,
+in order, in the "decorators" property ("app.route('/users')"). A
+decorator naming a symbol queues a DECORATED_BY relation for the second
+pass: an imported name resolves through its import, a plain name through
+the module being parsed, and `@name(...)` through the called name.
+Attributes of local objects (`@app.route`) cannot be resolved statically.
+
+Classes decorated with `@dataclass` get "dataclass", and their annotated
+attributes "dataclass_field" unless annotated ClassVar.
+
+ASTParser and the ast-grep adapter both build these from the ast module
+node of the definition, so the two paths record the same.
+"""
+
+import ast
+from typing import Any, Dict, List, Optional, Tuple
+
+
+def dotted_name(node: ast.expr) -> Optional[List[str]]:
+    """The names of an `a.b.c` expression, None for other expressions."""
+    parts = []
+    while isinstance(node, ast.Attribute):
+        parts.append(node.attr)
+        node = node.value
+    if not isinstance(node, ast.Name):
+        return None
+    parts.append(node.id)
+    return list(reversed(parts))
+
+
+def python_decorators(node: ast.AST, source_id: str, module_name: str,
+                      imports: Dict[str, str]) -> Tuple[List[str], List[Dict[str, Any]]]:
+    """
+    The decorator texts of a definition and the DECORATED_BY pending imports they queue.
+    
+    Args:
+        node: ast FunctionDef, AsyncFunctionDef or ClassDef
+        source_id: ID of the decorated node
+        module_name: module the definition is indexed under
+        imports: alias -> full name of the file's imports
+    """
+    decorators = []
+    pending = []
+    for decorator in node.decorator_list:
+        text = ast.unparse(decorator)
+        decorators.append(text)
+        
+        # `@name(...)` resolves through the called name
+        target = decorator.func if isinstance(decorator, ast.Call) else decorator
+        parts = dotted_name(target)
+        if not parts:
+            continue
+        if parts[0] in imports:
+            qualified = imports[parts[0]].split(".") + parts[1:]
+        elif len(parts) == 1:
+            # Decorator defined in the same module
+            qualified = [module_name, parts[0]]
+        else:
+            # Attributes of local objects (e.g. `@app.route`) cannot be resolved statically
+            continue
+        if len(qualified) < 2:
+            continue
+        
+        pending.append({
+            "type": "DECORATED_BY",
+            "source_id": source_id,
+            "imported_module": qualified[-2],
+            "imported_name": qualified[-1],
+            "original_name": ".".join(parts),
+            "decorator": text,
+            "line_no": decorator.lineno,
+        })
+    return decorators, pending
+
+
+def is_dataclass(node: ast.ClassDef) -> bool:
+    """Whether a class is decorated with dataclass, called or not."""
+    return any(
+        (dotted_name(d.func if isinstance(d, ast.Call) else d) or [""])[-1] == "dataclass"
+        for d in node.decorator_list
+    )
+
+
+def annotation_properties(annotation: str, has_default: bool, dataclass: bool) -> Dict[str, Any]:
+    """Properties of an annotated class attribute."""
+    properties = {"annotation": annotation}
+    if has_default:
+        properties["has_default"] = True
+    if dataclass and not annotation.split("[")[0].endswith("ClassVar"):
+        properties["dataclass_field"] = True
+    return properties
@@ -0,0 +1,319 @@
+"""
+Doc comment normalization shared by the language parsers.
+
+Parsers hand the raw text of a comment block (Go `//` lines, `/* */` and
+`/** */` blocks, Python `#` lines) to normalize_comment(), which strips the
+comment markers, a leading `*` on each line of a block and the common
+indentation. The result is stored as the node's "doc" property. JSDoc
+blocks are additionally split into their description, `@param` and
+`@returns` tags by parse_jsdoc().
+
+Comments at the top of a file that carry a license or a file-level tag
+(`@file`, `@fileoverview`, ...) describe the file, not the first
+declaration below them; is_license_header() and is_file_comment() let the
+parsers attach them to the File node instead.
+
+leading_comments(), jsdoc_properties() and js_file_comment_properties()
+read syntax nodes with the ast-grep node interface (kind, text, range,
+parent, prev, is_named, children), so the ast-grep adapters and the
+tree-sitter TypeScriptParser find the same comment blocks.
+
+MCP tools shorten docs with truncate_doc() to DOC_MAX_LENGTH characters
+(default 300).
+"""
+
+import json
+import logging
+import os
+import re
+import textwrap
+from typing import Any, Dict, List, Optional, Tuple
+
+logger = logging.getLogger(__name__)
+
+DEFAULT_DOC_MAX_LENGTH = 300
+
+# Appended to a shortened doc
+ELLIPSIS = "..."
+
+LINE_COMMENT_MARKERS = ("///", "//!", "//", "#")
+
+LICENSE_PATTERN = re.compile(r"\b(copyright|licen[cs]ed?|spdx-license-identifier)\b|©", re.IGNORECASE)
+
+# JSDoc / TSDoc tags that mark a comment as describing the whole file
+FILE_COMMENT_TAGS = re.compile(r"@(file|fileoverview|overview|module|packageDocumentation|license|copyright)\b")
+
+JSDOC_PARAM = re.compile(
+    r"@(?:param|arg|argument)\s+(?:\{(?P<type>[^}]*)\}\s*)?(?P<name>\[[^\]]*\]|\S+)\s*(?:-\s*)?(?P<description>.*)"
+)
+JSDOC_RETURNS = re.compile(r"@returns?\b\s*(?:\{(?P<type>[^}]*)\}\s*)?(?:-\s*)?(?P<description>.*)")
+
+
+def normalize_comment(text: str) -> str:
+    """
+    Strip comment markers and common indentation from a comment block.
+    
+    Handles consecutive `//`, `///`, `//!` and `#` line comments, `/* */`,
+    `/** */` and `/*! */` blocks with or without a leading `*` on each line, and any mix
+    of them. Trailing whitespace and surrounding blank lines are removed.
+    """
+    lines: List[str] = []
+    block: Optional[List[str]] = None
+    for raw in text.splitlines():
+        line = raw.rstrip()
+        if block is None:
+            stripped = line.lstrip()
+            if not stripped.startswith("/*"):
+                for marker in LINE_COMMENT_MARKERS:
+                    if stripped.startswith(marker):
+                        stripped = stripped[len(marker):]
+                        # `// text`: the space after the marker is not indentation
+                        stripped = stripped[1:] if stripped.startswith(" ") else stripped
+                        break
+                lines.append(stripped)
+                continue
+            block = []
+            line = stripped[3:] if is_jsdoc(stripped) or stripped.startswith("/*!") else stripped[2:]
+        closed = line.endswith("*/")
+        if closed:
+            line = line[:-2]
+        block.append(line)
+        if closed:
+            lines.extend(_block_body(block))
+            block = None
+    if block is not None:
+        lines.extend(_block_body(block))
+    
+    doc = textwrap.dedent("\n".join(line.rstrip() for line in lines))
+    return doc.strip("\n")
+
+
+def _block_body(block: List[str]) -> List[str]:
+    """
+    Lines of a /* */ block without the markers.
+    
+    The leading `*` is only stripped when every non-empty inner line has
+    one, so indented examples in blocks without stars keep their layout.
+    """
+    first, inner = block[0].strip(), block[1:]
+    if not inner:
+        return [first]
+    if all(line.lstrip().startswith("*") for line in inner if line.strip()):
+        inner = [line.lstrip()[1:] for line in inner]
+    return [first] + textwrap.dedent("\n".join(inner)).split("\n")
+
+
+def is_license_header(text: str) -> bool:
+    """Whether a comment is a copyright or license header."""
+    return bool(LICENSE_PATTERN.search(text))
+
+
+def is_file_comment(text: str) -> bool:
+    """Whether a comment describes the file: a license header or a file-level JSDoc tag."""
+    return is_license_header(text) or bool(FILE_COMMENT_TAGS.search(text))
+
+
+def is_jsdoc(text: str) -> bool:
+    """Whether a raw comment is a JSDoc block (`/** ... */`, not `/**/`)."""
+    stripped = text.strip()
+    return stripped.startswith("/**") and not stripped.startswith("/**/")
+
+
+def parse_jsdoc(doc: str) -> Dict[str, Any]:
+    """
+    Split a normalized JSDoc comment into description, params and returns.
+    
+    `@param {type} name - description` (also `@arg`, `@argument`, and
+    `[name=default]` for optional parameters) and `@returns {type}
+    description` (also `@return`) are parsed; a tag's description continues
+    on the following lines until the next tag. Other tags are left in the
+    doc text only.
+    
+    Returns:
+        {"description": str, "params": [{"name", "type", "description",
+        "optional", "default"}], "returns": {"type", "description"} or None}
+    """
+    description: List[str] = []
+    params: List[Dict[str, Any]] = []
+    returns: Optional[Dict[str, Any]] = None
+    current: Optional[Dict[str, Any]] = None
+    in_description = True
+    
+    for line in doc.splitlines():
+        stripped = line.strip()
+        if stripped.startswith("@"):
+            in_description = False
+            current = None
+            param = JSDOC_PARAM.match(stripped)
+            if param:
+                current = _jsdoc_param(param)
+                params.append(current)
+                continue
+            returned = JSDOC_RETURNS.match(stripped)
+            if returned:
+                current = returns = {
+                    "type": (returned.group("type") or "").strip() or None,
+                    "description": returned.group("description").strip(),
+                }
+            continue
+        if in_description:
+            description.append(line)
+        elif current is not None and stripped:
+            current["description"] = f"{current['description']} {stripped}".strip()
+    
+    return {
+        "description": "\n".join(description).strip(),
+        "params": params,
+        "returns": returns,
+    }
+
+
+def _jsdoc_param(match: re.Match) -> Dict[str, Any]:
+    """Param entry of a @param tag; `[name=default]` marks it optional."""
+    name = match.group("name")
+    param: Dict[str, Any] = {
+        "name": name,
+        "type": (match.group("type") or "").strip() or None,
+        "description": match.group("description").strip(),
+    }
+    if name.startswith("[") and name.endswith("]"):
+        name, _, default = name[1:-1].partition("=")
+        param["name"] = name.strip()
+        param["optional"] = True
+        if default:
+            param["default"] = default.strip()
+    return param
+
+
+def prev_named(node: Any) -> Optional[Any]:
+    """Previous named sibling, skipping anonymous tokens such as Go's newline terminators."""
+    sibling = node.prev()
+    while sibling is not None and not sibling.is_named():
+        sibling = sibling.prev()
+    return sibling
+
+
+def leading_comments(node: Any, comment_kinds: Tuple[str, ...] = ("comment",),
+                     skip_kinds: Tuple[str, ...] = ("decorator",)) -> List[Any]:
+    """
+    Comment nodes of the block directly above a declaration, in source order.
+    
+    Decorators (or other skip_kinds, e.g. Rust attributes) between the
+    comments and the declaration are skipped. The
+    block ends at a blank line or at a comment trailing code on its line.
+    Comments opening the file that are license headers or file-level
+    comments (see is_file_comment) describe the File node
+    and are left out, so they never end up on the first declaration.
+    """
+    start = node.range().start.line
+    sibling = prev_named(node)
+    while sibling is not None and sibling.kind() in skip_kinds:
+        start = sibling.range().start.line
+        sibling = prev_named(sibling)
+    
+    comments: List[Any] = []
+    while sibling is not None and sibling.kind() in comment_kinds and sibling.range().end.line >= start - 1:
+        previous = prev_named(sibling)
+        if (previous is not None and previous.kind() not in comment_kinds
+                and previous.range().end.line == sibling.range().start.line):
+            # Trailing comment of the previous statement
+            break
+        comments.insert(0, sibling)
+        start = sibling.range().start.line
+        sibling = previous
+    
+    parent = node.parent()
+    at_file_start = sibling is None and parent is not None and parent.parent() is None
+    while comments and at_file_start and is_file_comment(comments[0].text()):
+        comments.pop(0)
+    return comments
+
+
+def jsdoc_properties(declaration: Any) -> Dict[str, Any]:
+    """
+    "doc", "doc_params" and "doc_returns" of the JSDoc block directly above a JS/TS declaration.
+    
+    `export` statements wrap the declaration, so their comment is used.
+    Plain `//` comments are not doc comments and give no properties.
+    """
+    parent = declaration.parent()
+    if parent is not None and parent.kind() == "export_statement":
+        declaration = parent
+    comments = leading_comments(declaration)
+    if not comments or not is_jsdoc(comments[-1].text()):
+        return {}
+    
+    doc = normalize_comment(comments[-1].text())
+    if not doc:
+        return {}
+    properties: Dict[str, Any] = {"doc": doc}
+    parsed = parse_jsdoc(doc)
+    if parsed["params"]:
+        properties["doc_params"] = json.dumps(parsed["params"])
+    if parsed["returns"]:
+        properties["doc_returns"] = json.dumps(parsed["returns"])
+    return properties
+
+
+def js_file_comment_properties(root: Any) -> Dict[str, str]:
+    """
+    "doc" and "header" of the File node of a JS/TS file.
+    
+    Comments before the first statement that do not document it are
+    file-level: license headers become "header", the others (e.g. an
+    @fileoverview block) "doc".
+    """
+    top: List[Any] = []
+    first_statement = None
+    for child in root.children():
+        if child.kind() == "comment":
+            top.append(child)
+        elif child.is_named() and child.kind() != "hash_bang_line":
+            first_statement = child
+            break
+    
+    documenting = set()
+    if first_statement is not None and first_statement.kind() != "import_statement":
+        documenting = {c.range().start.line for c in leading_comments(first_statement)}
+    header: List[str] = []
+    doc: List[str] = []
+    for comment in top:
+        if comment.range().start.line in documenting:
+            continue
+        (header if is_license_header(comment.text()) else doc).append(comment.text())
+    
+    properties = {}
+    if doc:
+        properties["doc"] = normalize_comment("\n".join(doc))
+    if header:
+        properties["header"] = normalize_comment("\n".join(header))
+    return properties
+
+
+def get_doc_max_length() -> int:
+    """Read DOC_MAX_LENGTH (default 300)."""
+    value = os.getenv("DOC_MAX_LENGTH", "")
+    if value:
+        try:
+            return max(0, int(value))
+        except ValueError:
+            logger.warning(f"Invalid DOC_MAX_LENGTH value '{value}', using {DEFAULT_DOC_MAX_LENGTH}")
+    return DEFAULT_DOC_MAX_LENGTH
+
+
+def truncate_doc(doc: Optional[str], max_length: Optional[int] = None) -> Optional[str]:
+    """
+    Shorten a doc to max_length characters followed by an ellipsis marker.
+    
+    Args:
+        doc: Doc text, returned unchanged if None or short enough
+        max_length: Maximum length, if None, get from DOC_MAX_LENGTH (0 disables the doc)
+    """
+    if not doc:
+        return doc
+    max_length = get_doc_max_length() if max_length is None else max_length
+    if max_length <= 0:
+        return None
+    if len(doc) <= max_length:
+        return doc
+    return doc[:max_length].rstrip() + ELLIPSIS
@@ -0,0 +1,338 @@
+"""Heuristic fallback indexer for languages without a dedicated parser.
+
+Files of languages the indexer has no parser for (Lua, Perl, shell, SQL,
+Terraform, ...) are indexed by regular expressions, ctags style: every
+match of a pattern of the file's extension becomes a symbol node, so the
+files get File nodes, belong to their directory's Package like every
+other file, and show up in text search and outlines.
+
+The patterns are a table, FALLBACK_PATTERNS: extension -> list of
+{"name", "kind", "regex"}. "regex" is compiled with re.MULTILINE and
+must capture the symbol name in a group named "name"; a group named
+"scope" that matches is prefixed to it ("aws_s3_bucket.logs" for a
+Terraform resource). "kind" is the node type (one of FALLBACK_KINDS) and
+"name" the declaration it stands for, kept in the node's "declaration".
+The JSON file named by FALLBACK_PATTERNS_CONFIG adds patterns in the same
+shape and the language of new extensions, e.g.
+    {"patterns": {".tcl": [{"name": "proc", "kind": "Function",
+                            "regex": "^\\s*proc\\s+(?P<name>[\\w:]+)"}]},
+     "languages": {".tcl": "tcl"}}
+Extensions a dedicated parser handles (DEDICATED_EXTENSIONS) are never
+indexed by patterns, also when the config lists them. FALLBACK_INDEXING
+set to false turns the fallback off.
+
+Symbols and File nodes carry extraction "heuristic" and their CONTAINS
+edges the source "heuristic:fallback". Nothing is resolved: there are no
+imports, calls or other reference edges, and a symbol's line range ends
+where its pattern's match ends, not with its body. Stored graphs are not
+re-parsed when the patterns change; a full run picks them up.
+"""
+
+import bisect
+import json
+import logging
+import os
+import re
+from functools import lru_cache
+from typing import Any, Dict, List, NamedTuple, Optional, Pattern, Set, Tuple
+
+from src.ast_parser.language_detector import EXT_TO_LANG
+from src.ast_parser.parser import CodeNode, CodeRelation
+from src.ast_parser.proto_parser import PROTO_EXTENSIONS
+from src.ast_parser.provenance import CONFIDENCE_PROPERTY, SOURCE_PROPERTY
+
+logger = logging.getLogger(__name__)
+
+EXTRACTION_HEURISTIC = "heuristic"
+
+# Source and confidence of the CONTAINS edges of pattern-extracted symbols
+SOURCE_FALLBACK = "heuristic:fallback"
+FALLBACK_CONFIDENCE = 0.5
+
+# Node types a pattern may produce: those the outline lists at the top level of a file
+FALLBACK_KINDS = ("Class", "Interface", "Enum", "TypeAlias", "Function", "Constant", "Variable")
+
+# Extensions with a parser of their own, in either parser mode
+DEDICATED_EXTENSIONS = tuple(EXT_TO_LANG) + PROTO_EXTENSIONS
+
+PATTERN_KEYS = ("name", "kind", "regex")
+
+_LUA_PATTERNS = [
+    {"name": "function", "kind": "Function",
+     "regex": r"^[ \t]*(?:local[ \t]+)?function[ \t]+(?P<name>[A-Za-z_][\w.:]*)[ \t]*\("},
+    {"name": "function", "kind": "Function",
+     "regex": r"^[ \t]*(?:local[ \t]+)?(?P<name>[A-Za-z_][\w.]*)[ \t]*=[ \t]*function\b"},
+]
+
+_PERL_PATTERNS = [
+    {"name": "package", "kind": "Class", "regex": r"^[ \t]*package[ \t]+(?P<name>[A-Za-z_][\w:]*)"},
+    {"name": "sub", "kind": "Function", "regex": r"^[ \t]*sub[ \t]+(?P<name>[A-Za-z_][\w:]*)"},
+    {"name": "constant", "kind": "Constant", "regex": r"^[ \t]*use[ \t]+constant[ \t]+(?P<name>[A-Za-z_]\w*)"},
+]
+
+_SHELL_PATTERNS = [
+    {"name": "function", "kind": "Function",
+     "regex": r"^[ \t]*(?:function[ \t]+)?(?P<name>[A-Za-z_][\w.:-]*)[ \t]*\(\)"},
+    {"name": "function", "kind": "Function", "regex": r"^[ \t]*function[ \t]+(?P<name>[A-Za-z_][\w.:-]*)[ \t]*\{"},
+    {"name": "export", "kind": "Variable", "regex": r"^[ \t]*export[ \t]+(?P<name>[A-Za-z_]\w*)="},
+]
+
+_SQL_OBJECT = r"(?P<name>[\w$]+(?:\.[\w$]+)?|[\"`\[][^\"`\]\n]+[\"`\]](?:\.[\"`\[][^\"`\]\n]+[\"`\]])?)"
+
+_SQL_PATTERNS = [
+    {"name": "table", "kind": "Class",
+     "regex": r"(?i)^[ \t]*CREATE[ \t]+(?:OR[ \t]+REPLACE[ \t]+)?(?:(?:GLOBAL|LOCAL)[ \t]+)?(?:TEMP(?:ORARY)?[ \t]+)?"
+              r"(?:UNLOGGED[ \t]+)?TABLE[ \t]+(?:IF[ \t]+NOT[ \t]+EXISTS[ \t]+)?" + _SQL_OBJECT},
+    {"name": "view", "kind": "Class",
+     "regex": r"(?i)^[ \t]*CREATE[ \t]+(?:OR[ \t]+REPLACE[ \t]+)?(?:TEMP(?:ORARY)?[ \t]+)?(?:MATERIALIZED[ \t]+)?"
+              r"VIEW[ \t]+(?:IF[ \t]+NOT[ \t]+EXISTS[ \t]+)?" + _SQL_OBJECT},
+    {"name": "function", "kind": "Function",
+     "regex": r"(?i)^[ \t]*CREATE[ \t]+(?:OR[ \t]+REPLACE[ \t]+)?(?:FUNCTION|PROCEDURE)[ \t]+"
+              r"(?:IF[ \t]+NOT[ \t]+EXISTS[ \t]+)?" + _SQL_OBJECT},
+    {"name": "type", "kind": "TypeAlias", "regex": r"(?i)^[ \t]*CREATE[ \t]+TYPE[ \t]+" + _SQL_OBJECT},
+]
+
+_TERRAFORM_PATTERNS = [
+    {"name": "resource", "kind": "Class",
+     "regex": r"^[ \t]*resource[ \t]+\"(?P<scope>[\w-]+)\"[ \t]+\"(?P<name>[\w-]+)\""},
+    {"name": "data", "kind": "Class", "regex": r"^[ \t]*data[ \t]+\"(?P<scope>[\w-]+)\"[ \t]+\"(?P<name>[\w-]+)\""},
+    {"name": "module", "kind": "Class", "regex": r"^[ \t]*module[ \t]+\"(?P<name>[\w-]+)\""},
+    {"name": "variable", "kind": "Variable", "regex": r"^[ \t]*variable[ \t]+\"(?P<name>[\w-]+)\""},
+    {"name": "output", "kind": "Constant", "regex": r"^[ \t]*output[ \t]+\"(?P<name>[\w-]+)\""},
+]
+
+# Extension -> patterns of its declarations
+FALLBACK_PATTERNS: Dict[str, List[Dict[str, str]]] = {
+    ".lua": _LUA_PATTERNS,
+    ".pl": _PERL_PATTERNS,
+    ".pm": _PERL_PATTERNS,
+    ".sh": _SHELL_PATTERNS,
+    ".bash": _SHELL_PATTERNS,
+    ".zsh": _SHELL_PATTERNS,
+    ".sql": _SQL_PATTERNS,
+    ".tf": _TERRAFORM_PATTERNS,
+}
+
+# Extension -> language of its File nodes; the extension without its dot when not listed
+FALLBACK_LANGUAGES: Dict[str, str] = {
+    ".lua": "lua",
+    ".pl": "perl",
+    ".pm": "perl",
+    ".sh": "shell",
+    ".bash": "shell",
+    ".zsh": "shell",
+    ".sql": "sql",
+    ".tf": "terraform",
+}
+
+# Quotes and brackets around SQL identifiers
+_QUOTES = re.compile(r"[\"`\[\]]")
+
+
+class FallbackPattern(NamedTuple):
+    name: str
+    kind: str
+    regex: Pattern
+
+
+def compile_pattern(extension: str, pattern: Any) -> FallbackPattern:
+    """
+    Check and compile one pattern of the table or a config file.
+    
+    Raises:
+        ValueError: for an unknown key or kind, an invalid regex, or one without a "name" group
+    """
+    if not isinstance(pattern, dict) or not all(isinstance(pattern.get(key), str) for key in PATTERN_KEYS):
+        raise ValueError(f"Fallback pattern of {extension} needs string {', '.join(PATTERN_KEYS)}: {pattern!r}")
+    unknown = sorted(set(pattern) - set(PATTERN_KEYS))
+    if unknown:
+        raise ValueError(f"Unknown fallback pattern key '{unknown[0]}', expected: {', '.join(PATTERN_KEYS)}")
+    if pattern["kind"] not in FALLBACK_KINDS:
+        raise ValueError(f"Fallback pattern '{pattern['name']}' of {extension}: kind must be one of "
+                         f"{', '.join(FALLBACK_KINDS)}, got '{pattern['kind']}'")
+    try:
+        regex = re.compile(pattern["regex"], re.MULTILINE)
+    except re.error as e:
+        raise ValueError(f"Fallback pattern '{pattern['name']}' of {extension}: invalid regex: {e}")
+    if "name" not in regex.groupindex:
+        raise ValueError(f"Fallback pattern '{pattern['name']}' of {extension} has no (?P<name>...) group")
+    return FallbackPattern(pattern["name"], pattern["kind"], regex)
+
+
+def _extension(value: Any) -> str:
+    if not isinstance(value, str) or not value.strip("."):
+        raise ValueError(f"Invalid fallback extension {value!r}")
+    return "." + value.lower().lstrip(".")
+
+
+@lru_cache(maxsize=8)
+def load_fallback_patterns(config_path: str = "") -> Tuple[Dict[str, List[FallbackPattern]], Dict[str, str]]:
+    """
+    The compiled pattern table, with the patterns and languages of a config file added.
+    
+    Args:
+        config_path: JSON file with "patterns" and "languages", empty for the built-in table only
+    
+    Returns:
+        (extension -> patterns, extension -> language)
+    
+    Raises:
+        ValueError: for an unreadable config file or an invalid pattern
+    """
+    tables = {extension: list(patterns) for extension, patterns in FALLBACK_PATTERNS.items()}
+    languages = dict(FALLBACK_LANGUAGES)
+    if config_path:
+        try:
+            with open(config_path, "r", encoding="utf-8") as f:
+                document = json.load(f)
+        except (OSError, ValueError) as e:
+            raise ValueError(f"Cannot read fallback patterns config {config_path}: {e}")
+        if not isinstance(document, dict) or not isinstance(document.get("patterns", {}), dict) or \
+                not isinstance(document.get("languages", {}), dict):
+            raise ValueError(f"Fallback patterns config {config_path} must hold a JSON object "
+                             "with \"patterns\" and \"languages\" objects")
+        for extension, patterns in document.get("patterns", {}).items():
+            if not isinstance(patterns, list):
+                raise ValueError(f"Fallback patterns of {extension} must be a list")
+            tables.setdefault(_extension(extension), []).extend(patterns)
+        for extension, language in document.get("languages", {}).items():
+            if not isinstance(language, str) or not language:
+                raise ValueError(f"Fallback language of {extension} must be a string")
+            languages[_extension(extension)] = language
+    
+    compiled = {}
+    for extension, patterns in tables.items():
+        if extension in DEDICATED_EXTENSIONS:
+            logger.debug(f"Ignoring fallback patterns of {extension}, a dedicated parser handles it")
+            continue
+        compiled[extension] = [compile_pattern(extension, pattern) for pattern in patterns]
+        languages.setdefault(extension, extension.lstrip("."))
+    return compiled, languages
+
+
+def get_fallback_patterns() -> Tuple[Dict[str, List[FallbackPattern]], Dict[str, str]]:
+    """load_fallback_patterns with FALLBACK_PATTERNS_CONFIG; no patterns when FALLBACK_INDEXING is false."""
+    if os.getenv("FALLBACK_INDEXING", "true").lower() != "true":
+        return {}, {}
+    return load_fallback_patterns(os.getenv("FALLBACK_PATTERNS_CONFIG", ""))
+
+
+def fallback_extensions() -> Tuple[str, ...]:
+    """Extensions indexed by FallbackParser."""
+    return tuple(sorted(get_fallback_patterns()[0]))
+
+
+class FallbackParser:
+    """Pattern-based parser producing the node and relation format of ASTParser."""
+    
+    # ParseDeadline of the file being parsed, set by parse_with_limits
+    parse_deadline = None
+    # Error the last parse_file swallowed, read by parse_with_limits
+    parse_error = None
+    
+    def __init__(self, patterns: Optional[Dict[str, List[FallbackPattern]]] = None,
+                 languages: Optional[Dict[str, str]] = None):
+        """
+        Args:
+            patterns: Extension -> compiled patterns, if None, get from get_fallback_patterns
+            languages: Extension -> language, if None, get from get_fallback_patterns
+        """
+        if patterns is None or languages is None:
+            default_patterns, default_languages = get_fallback_patterns()
+            patterns = default_patterns if patterns is None else patterns
+            languages = default_languages if languages is None else languages
+        self.patterns = patterns
+        self.languages = languages
+        
+        self.nodes: Dict[str, CodeNode] = {}
+        self.relations: List[CodeRelation] = []
+        self.current_file: str = ""
+        # Nothing is resolved across files, the indexes stay empty
+        self.module_definitions: Dict[str, Dict[str, str]] = {}
+        self.pending_imports: List[Dict[str, Any]] = []
+        self.module_to_file: Dict[str, str] = {}
+        self.established_relations: Set[str] = set()
+    
+    def parse_file(self, file_path: str, build_index: bool = False) -> Tuple[Dict[str, CodeNode], List[CodeRelation]]:
+        """
+        Extract the symbols of a file with the patterns of its extension.
+        
+        Args:
+            file_path: Path to the file
+            build_index: Whether results accumulate across files (as for the other parsers)
+        
+        Returns:
+            Tuple of (nodes dictionary, relations list)
+        """
+        self.current_file = file_path
+        
+        # Reset nodes and relations if not building an index (standalone parse)
+        if not build_index:
+            self.nodes = {}
+            self.relations = []
+        
+        ext = os.path.splitext(file_path)[1].lower()
+        language = self.languages.get(ext, ext.lstrip("."))
+        file_node_id = f"file:{file_path}"
+        self.nodes[file_node_id] = CodeNode(
+            node_id=file_node_id,
+            node_type="File",
+            name=os.path.basename(file_path),
+            file_path=file_path,
+            line_no=0,
+            properties={"language": language, "extraction": EXTRACTION_HEURISTIC},
+        )
+        
+        try:
+            with open(file_path, "r", encoding="utf-8") as handle:
+                source = handle.read()
+        except (OSError, UnicodeDecodeError) as e:
+            logger.error(f"Error reading file {file_path}: {e}")
+            self.parse_error = e
+            return self.nodes, self.relations
+        
+        line_starts = [0] + [match.end() for match in re.finditer("\n", source)]
+        lines = source.split("\n")
+        for pattern in self.patterns.get(ext, []):
+            for match in pattern.regex.finditer(source):
+                self._add_symbol(file_node_id, language, pattern, match, line_starts, lines)
+        
+        return self.nodes, self.relations
+    
+    def _add_symbol(self, file_node_id: str, language: str, pattern: FallbackPattern, match: "re.Match",
+                    line_starts: List[int], lines: List[str]) -> None:
+        name = _QUOTES.sub("", match.group("name") or "").strip()
+        if not name:
+            return
+        scope = match.groupdict().get("scope")
+        if scope:
+            name = f"{_QUOTES.sub('', scope).strip()}.{name}"
+        # By the name's line: the match may start with blank lines skipped by a leading \s
+        line = bisect.bisect_right(line_starts, match.start("name"))
+        end_line = max(line, bisect.bisect_right(line_starts, max(match.end() - 1, match.start("name"))))
+        
+        if self.parse_deadline is not None:
+            self.parse_deadline.check()
+        node_id = f"{pattern.kind}:{self.current_file}:{name}:{line}"
+        if node_id in self.nodes:
+            # Two patterns of the extension match the same declaration
+            return
+        node = self.nodes[node_id] = CodeNode(
+            node_id=node_id,
+            node_type=pattern.kind,
+            name=name,
+            file_path=self.current_file,
+            line_no=line,
+            end_line_no=end_line,
+            properties={
+                "language": language,
+                "extraction": EXTRACTION_HEURISTIC,
+                "declaration": pattern.name,
+                "qualified_name": name,
+            },
+        )
+        node.code_snippet = "\n".join(lines[line - 1:end_line]).strip()
+        self.relations.append(CodeRelation(file_node_id, node_id, "CONTAINS", {
+            SOURCE_PROPERTY: SOURCE_FALLBACK,
+            CONFIDENCE_PROPERTY: FALLBACK_CONFIDENCE,
+        }))
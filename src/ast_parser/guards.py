@@ -0,0 +1,288 @@
+"""
+Safeguards against huge, slow and generated source files.
+
+Every file is parsed through parse_with_limits:
+
+- A file larger than INDEX_MAX_FILE_BYTES (or --max-file-bytes, default
+  1000000) is not parsed; it is indexed as a File node with
+  skipped_reason "too_large" and its size_bytes, without symbols.
+- A parse that runs past INDEX_PARSE_TIMEOUT seconds (or --parse-timeout,
+  default 60) is abandoned; the file is indexed like a too large one,
+  with skipped_reason "parse_timeout".
+- A generated file gets generated: true on its File node and on every
+  symbol node of the file, so query tools can leave it out. Generated
+  files are found by name (*.pb.go, *_gen.go, *_pb2.py, ...) or by a
+  comment line in the first GENERATED_HEAD_BYTES of the file holding the
+  usual notice ("Code generated ... DO NOT EDIT.", "@generated").
+
+A file whose parse fails is indexed as a lone File node too, with the
+failure in parse_error (the message), parse_error_category (syntax_error,
+unsupported_feature, encoding, timeout or parser_error),
+parse_error_line and parse_error_column when the error has a position,
+and parse_error_analyzer (the parser class). A timed out file gets the
+timeout recorded the same way. Such files are parsed again on the next
+incremental run (see src/indexing/incremental.py), and the
+get_index_diagnostics MCP tool lists them (see src/mcp/diagnostics.py).
+
+Either limit is disabled by 0. The timeout is cooperative: the parser
+carries a ParseDeadline in its parse_deadline attribute and checks it
+every time it creates a node (_get_node_id), so a parse stops at the next
+symbol after the deadline; a single call into a grammar is not interrupted.
+Parsers swallow their own errors, so the deadline records that it expired
+instead of relying on the ParseTimeout reaching parse_with_limits, and a
+parser keeps the error it swallowed in its parse_error attribute.
+"""
+
+import logging
+import os
+import re
+import time
+from fnmatch import fnmatchcase
+from typing import Any, Dict, List, Optional, Tuple
+
+from src.ast_parser.parser import CodeNode
+
+logger = logging.getLogger(__name__)
+
+DEFAULT_MAX_FILE_BYTES = 1_000_000
+DEFAULT_PARSE_TIMEOUT = 60.0
+
+SKIPPED_TOO_LARGE = "too_large"
+SKIPPED_PARSE_TIMEOUT = "parse_timeout"
+
+# parse_error_category of a failed file
+ERROR_SYNTAX = "syntax_error"
+ERROR_UNSUPPORTED = "unsupported_feature"
+ERROR_ENCODING = "encoding"
+ERROR_TIMEOUT = "timeout"
+ERROR_PARSER = "parser_error"
+ERROR_CATEGORIES = (ERROR_SYNTAX, ERROR_UNSUPPORTED, ERROR_ENCODING, ERROR_TIMEOUT, ERROR_PARSER)
+
+GENERATED_HEADER = re.compile(r"Code generated .* DO NOT EDIT|@generated|Generated by the protocol buffer compiler")
+GENERATED_FILE_PATTERNS = ("*.pb.go", "*_gen.go", "*.gen.go", "*_pb2.py", "*_pb2_grpc.py", "*.pb.cc", "*.pb.h",
+                           "*_generated.*")
+# Bytes read from the start of a file to look for a generated notice
+GENERATED_HEAD_BYTES = 4096
+
+_COMMENT_LINE = re.compile(r"^\s*(//|#|/\*|\*|--)")
+
+
+def get_max_file_bytes(max_file_bytes: Optional[int] = None) -> int:
+    """Size above which a file is not parsed, if None, get from INDEX_MAX_FILE_BYTES (default 1000000, 0: no limit)."""
+    if max_file_bytes is not None:
+        return max(0, max_file_bytes)
+    value = os.getenv("INDEX_MAX_FILE_BYTES", "")
+    if value:
+        try:
+            return max(0, int(value))
+        except ValueError:
+            logger.warning(f"Invalid INDEX_MAX_FILE_BYTES value '{value}', using {DEFAULT_MAX_FILE_BYTES}")
+    return DEFAULT_MAX_FILE_BYTES
+
+
+def get_parse_timeout(parse_timeout: Optional[float] = None) -> float:
+    """Seconds a file may take to parse, if None, get from INDEX_PARSE_TIMEOUT (default 60, 0: no limit)."""
+    if parse_timeout is not None:
+        return max(0.0, parse_timeout)
+    value = os.getenv("INDEX_PARSE_TIMEOUT", "")
+    if value:
+        try:
+            return max(0.0, float(value))
+        except ValueError:
+            logger.warning(f"Invalid INDEX_PARSE_TIMEOUT value '{value}', using {DEFAULT_PARSE_TIMEOUT:g}")
+    return DEFAULT_PARSE_TIMEOUT
+
+
+def is_generated_name(file_path: str) -> bool:
+    """Whether the file name is one of GENERATED_FILE_PATTERNS."""
+    file_name = os.path.basename(file_path)
+    return any(fnmatchcase(file_name, pattern) for pattern in GENERATED_FILE_PATTERNS)
+
+
+def has_generated_notice(head: str) -> bool:
+    """Whether a comment line of head holds a generated notice."""
+    return any(_COMMENT_LINE.match(line) and GENERATED_HEADER.search(line) for line in head.splitlines())
+
+
+def is_generated_source(file_path: str) -> bool:
+    """Generated by its name, or by a notice in a comment line near the top of the file."""
+    if is_generated_name(file_path):
+        return True
+    try:
+        with open(file_path, "rb") as handle:
+            head = handle.read(GENERATED_HEAD_BYTES)
+    except OSError:
+        return False
+    return has_generated_notice(head.decode("utf-8", errors="replace"))
+
+
+class ParseTimeout(Exception):
+    """Raised inside a parser once its ParseDeadline has passed."""
+
+
+class ParseDeadline:
+    """Deadline of one parse, checked by the parser between nodes."""
+    
+    def __init__(self, seconds: float):
+        self.seconds = seconds
+        self.at = time.monotonic() + seconds if seconds > 0 else None
+        self.expired = False
+    
+    def check(self) -> None:
+        """
+        Raises:
+            ParseTimeout: the deadline has passed
+        """
+        if self.at is not None and time.monotonic() > self.at:
+            self.expired = True
+            raise ParseTimeout(f"parse exceeded {self.seconds:g}s")
+
+
+def _file_size(file_path: str) -> Optional[int]:
+    try:
+        return os.path.getsize(file_path)
+    except OSError:
+        return None
+
+
+def classify_parse_error(error: BaseException) -> str:
+    """parse_error_category of an error raised while parsing a file."""
+    if isinstance(error, ParseTimeout):
+        return ERROR_TIMEOUT
+    # ast.parse rejects null bytes with a ValueError (a SyntaxError from Python 3.12)
+    if isinstance(error, UnicodeError) or "null bytes" in str(error):
+        return ERROR_ENCODING
+    if isinstance(error, SyntaxError):
+        return ERROR_SYNTAX
+    # Constructs the parser does not handle, or nested deeper than it can follow
+    if isinstance(error, (NotImplementedError, RecursionError)):
+        return ERROR_UNSUPPORTED
+    return ERROR_PARSER
+
+
+def _decode_position(file_path: str) -> Optional[Tuple[int, int]]:
+    """1-based line and column of the first byte of file_path that is not UTF-8."""
+    try:
+        with open(file_path, "rb") as handle:
+            content = handle.read()
+        content.decode("utf-8")
+    except OSError:
+        return None
+    except UnicodeDecodeError as e:
+        line_start = content.rfind(b"\n", 0, e.start) + 1
+        return content.count(b"\n", 0, e.start) + 1, e.start - line_start + 1
+    return None
+
+
+def parse_error_position(error: BaseException, file_path: str) -> Optional[Tuple[int, int]]:
+    """1-based line and column of a parse error, None when the error has no position."""
+    if isinstance(error, UnicodeDecodeError):
+        # The offsets of a decode error count from the chunk being read, not from the file start
+        return _decode_position(file_path)
+    if isinstance(error, SyntaxError) and error.lineno:
+        return error.lineno, error.offset or 1
+    return None
+
+
+def parse_error_properties(parser: Any, file_path: str, error: BaseException) -> Dict[str, Any]:
+    """parse_error properties of the File node of a file whose parse raised error."""
+    category = classify_parse_error(error)
+    message = error.msg if isinstance(error, SyntaxError) and error.msg else str(error)
+    properties: Dict[str, Any] = {
+        "parse_error": message or type(error).__name__,
+        "parse_error_category": category,
+        "parse_error_analyzer": type(parser).__name__,
+    }
+    position = parse_error_position(error, file_path)
+    if position is not None:
+        properties["parse_error_line"], properties["parse_error_column"] = position
+    return properties
+
+
+def count_parse_errors(nodes: Dict[str, Any]) -> Dict[str, int]:
+    """parse_error_category -> number of File nodes of nodes whose parse failed."""
+    counts: Dict[str, int] = {}
+    for node in nodes.values():
+        category = node.properties.get("parse_error_category") if node.node_type == "File" else None
+        if category:
+            counts[category] = counts.get(category, 0) + 1
+    return counts
+
+
+def _file_only(parser: Any, file_path: str, properties: Dict[str, Any], generated: bool,
+               size_bytes: Optional[int]) -> Tuple[Dict[str, Any], List[Any]]:
+    """Reset the parser to a lone File node of file_path with the given properties."""
+    for name, empty in (("nodes", dict), ("relations", list), ("module_definitions", dict),
+                        ("pending_imports", list), ("module_to_file", dict), ("established_relations", set)):
+        if hasattr(parser, name):
+            setattr(parser, name, empty())
+    properties = dict(properties)
+    if size_bytes is not None:
+        properties["size_bytes"] = size_bytes
+    if generated:
+        properties["generated"] = True
+    node_id = f"file:{file_path}"
+    parser.nodes[node_id] = CodeNode(
+        node_id=node_id,
+        node_type="File",
+        name=os.path.basename(file_path),
+        file_path=file_path,
+        line_no=0,
+        properties=properties,
+    )
+    return parser.nodes, parser.relations
+
+
+def parse_with_limits(parser: Any, file_path: str, build_index: bool = False,
+                      max_file_bytes: int = DEFAULT_MAX_FILE_BYTES,
+                      parse_timeout: float = DEFAULT_PARSE_TIMEOUT) -> Tuple[Dict[str, Any], List[Any]]:
+    """
+    parser.parse_file(file_path, build_index) within the size and time limits.
+    
+    A skipped or failed file leaves the parser holding only its File node,
+    so callers read the parser's nodes, relations and indexes as after any
+    parse. An error the parser raises instead of swallowing fails the file
+    the same way, so one bad file never aborts a run.
+    
+    Args:
+        parser: Fresh parser for the file (ASTParser, TypeScriptParser, an adapter, ...)
+        file_path: File to parse
+        build_index: Passed to parse_file
+        max_file_bytes: Size above which the file is not parsed, 0 for no limit
+        parse_timeout: Seconds the parse may take, 0 for no limit
+    
+    Returns:
+        The (nodes, relations) of parse_file, or of the skipped or failed file
+    """
+    generated = is_generated_source(file_path)
+    size_bytes = _file_size(file_path)
+    if max_file_bytes and size_bytes is not None and size_bytes > max_file_bytes:
+        logger.info(f"Skipping symbols of {file_path}: {size_bytes} bytes is above the {max_file_bytes} byte limit")
+        return _file_only(parser, file_path, {"skipped_reason": SKIPPED_TOO_LARGE}, generated, size_bytes)
+    
+    deadline = ParseDeadline(parse_timeout)
+    parser.parse_deadline = deadline
+    parser.parse_error = None
+    try:
+        nodes, relations = parser.parse_file(file_path, build_index=build_index)
+    except Exception as e:
+        parser.parse_error = e
+        nodes, relations = {}, []
+    finally:
+        parser.parse_deadline = None
+    if deadline.expired:
+        logger.warning(f"Skipping symbols of {file_path}: parsing took longer than {parse_timeout:g}s")
+        timeout = ParseTimeout(f"parse exceeded {parse_timeout:g}s")
+        properties = dict(parse_error_properties(parser, file_path, timeout), skipped_reason=SKIPPED_PARSE_TIMEOUT)
+        return _file_only(parser, file_path, properties, generated, size_bytes)
+    if parser.parse_error is not None:
+        properties = parse_error_properties(parser, file_path, parser.parse_error)
+        logger.warning(f"Indexing {file_path} without symbols: {properties['parse_error_category']} "
+                       f"({properties['parse_error']})")
+        return _file_only(parser, file_path, properties, generated, size_bytes)
+    
+    if generated:
+        for node in list(getattr(parser, "nodes", {}).values()) + list(nodes.values()):
+            if node.file_path == file_path:
+                node.properties["generated"] = True
+    return nodes, relations
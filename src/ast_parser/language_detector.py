@@ -24,6 +24,10 @@ EXT_TO_LANG = {
     ".hpp": "cpp",
     ".rs": "rust",
     ".go": "go",
+    ".cs": "csharp",
+    ".rb": "ruby",
+    ".kt": "kotlin",
+    ".kts": "kotlin",
 }
 
 
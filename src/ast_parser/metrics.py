@@ -0,0 +1,357 @@
+"""
+Complexity and size metrics of functions and methods.
+
+Function and Method nodes record, computed while their file is parsed:
+
+- cyclomatic_complexity: 1 plus one per decision point, see below
+- statement_count: statements in the body, at any depth
+- max_nesting: deepest nesting of control structures in the body, 0 for
+  straight-line code; an `else if` chain stays at the level of its first `if`
+- line_count: lines of the declaration, end line - start line + 1
+
+The parameter count is the arity of the signature (see signatures.py).
+
+Decision points, per language:
+- Python: if / elif, for, while, except, conditional expressions, every
+  `and` / `or` operand after the first, each `for` and `if` of a
+  comprehension, `case` clauses except the wildcard `case _`
+- Go: if, for, case clauses of expression, type and select switches
+  (not `default`), && and ||
+- Java: if, for, enhanced for, while, do, catch, case labels (not
+  `default`), ?: and && / ||
+- JavaScript / TypeScript: if, for, for-in / for-of, while, do, catch,
+  case clauses (not `default`), ?: and && / || / ??
+- Rust: if, while, for, match arms except the wildcard `_ =>`, && and ||
+- C / C++: if, for, range for, while, do, catch, case labels (not
+  `default`), ?: and && / ||
+- C#: if, for, foreach, while, do, catch, switch sections (not
+  `default`), switch expression arms except the discard `_ =>`, ?: and
+  && / || / ??
+- Ruby: if / elsif, unless, while, until, for and their modifier forms
+  (`x if y`), rescue and `rescue` modifiers, `when` and `in` clauses, ?:
+  and && / || / and / or
+- Kotlin: if, for, while, do-while, catch, `when` entries (not `else`),
+  the elvis operator ?: and && / ||
+
+The body of a nested named function or class is left to its own node, as
+a single statement; lambdas, closures and arrow functions count toward the
+function around them. Declarations without a body (abstract, interface
+and trait methods) record none. Python counts its loops, if, with, try and match as
+nesting, and its docstring is not a statement.
+
+Python is measured on the ast module tree (python_metrics), the other
+languages on their tree-sitter tree (syntax_metrics), for ast-grep nodes
+and tree-sitter nodes wrapped to the same interface, with the rules of
+their MetricRules. Both walk the body once, iteratively.
+"""
+
+import ast
+from dataclasses import dataclass
+from typing import Any, Callable, Dict, FrozenSet, List, Optional, Tuple
+
+METRIC_PROPERTIES = ("cyclomatic_complexity", "statement_count", "max_nesting", "line_count")
+
+
+def _metrics(complexity: int, statements: int, nesting: int, line_no: int, end_line_no: Optional[int]) -> Dict[str, int]:
+    return {
+        "cyclomatic_complexity": complexity,
+        "statement_count": statements,
+        "max_nesting": nesting,
+        "line_count": max((end_line_no or line_no) - line_no + 1, 1),
+    }
+
+
+# Python
+
+PYTHON_NESTING = (ast.If, ast.For, ast.AsyncFor, ast.While, ast.With, ast.AsyncWith, ast.Try, ast.Match)
+PYTHON_DEFINITIONS = (ast.FunctionDef, ast.AsyncFunctionDef, ast.ClassDef)
+if hasattr(ast, "TryStar"):
+    PYTHON_NESTING += (ast.TryStar,)
+
+
+def _python_decisions(node: ast.AST) -> int:
+    if isinstance(node, (ast.If, ast.For, ast.AsyncFor, ast.While, ast.ExceptHandler, ast.IfExp)):
+        return 1
+    if isinstance(node, ast.BoolOp):
+        return len(node.values) - 1
+    if isinstance(node, ast.comprehension):
+        return 1 + len(node.ifs)
+    if isinstance(node, ast.match_case):
+        wildcard = isinstance(node.pattern, ast.MatchAs) and node.pattern.pattern is None and node.guard is None
+        return 0 if wildcard else 1
+    return 0
+
+
+def _is_elif(node: ast.AST, parent: Optional[ast.AST]) -> bool:
+    """An `elif`: an If alone in the orelse of an If, starting where its `if` does (an `else:` block is indented)."""
+    return isinstance(node, ast.If) and isinstance(parent, ast.If) and parent.orelse == [node] \
+        and node.col_offset == parent.col_offset
+
+
+def python_metrics(node: ast.AST) -> Dict[str, int]:
+    """
+    Metrics of a Python function definition (ast.FunctionDef / ast.AsyncFunctionDef).
+    """
+    body = list(node.body)
+    if body and isinstance(body[0], ast.Expr) and isinstance(body[0].value, ast.Constant) \
+            and isinstance(body[0].value.value, str):
+        body = body[1:]
+    
+    complexity, statements, max_nesting = 1, 0, 0
+    # (node, nesting depth of the node, parent)
+    stack: List[Tuple[ast.AST, int, Optional[ast.AST]]] = [(child, 0, None) for child in reversed(body)]
+    while stack:
+        current, depth, parent = stack.pop()
+        if isinstance(current, ast.stmt):
+            statements += 1
+        if isinstance(current, PYTHON_DEFINITIONS):
+            continue
+        complexity += _python_decisions(current)
+        
+        inner = depth
+        if isinstance(current, PYTHON_NESTING):
+            inner = depth if _is_elif(current, parent) else depth + 1
+            max_nesting = max(max_nesting, inner)
+        for child in reversed(list(ast.iter_child_nodes(current))):
+            stack.append((child, inner, current))
+    
+    return _metrics(complexity, statements, max_nesting, node.lineno, getattr(node, "end_lineno", None))
+
+
+# Tree-sitter languages
+
+def _never(node: Any) -> bool:
+    return False
+
+
+def _starts_with_default(node: Any) -> bool:
+    """A `default:` label of a case kind that also covers `case`."""
+    return node.text().lstrip().startswith("default")
+
+
+def _wildcard_arm(node: Any) -> bool:
+    """A Rust `_ => ...` match arm without guard."""
+    pattern = node.field("pattern")
+    return pattern is not None and pattern.text().strip() == "_"
+
+
+def _default_section_or_discard_arm(node: Any) -> bool:
+    """A C# `default:` switch section or `_ => ...` switch expression arm."""
+    if node.kind() == "switch_expression_arm":
+        pattern = next((child for child in node.children() if child.is_named()), None)
+        return pattern is not None and pattern.text().strip() == "_"
+    return _starts_with_default(node)
+
+
+def _else_entry(node: Any) -> bool:
+    """A Kotlin `else ->` entry of a when expression."""
+    text = node.text().lstrip()
+    return text.startswith("else") and text[len("else"):].lstrip().startswith("->")
+
+
+@dataclass(frozen=True)
+class MetricRules:
+    """Node kinds that count for the metrics of one tree-sitter grammar."""
+    # Each adds a decision point
+    decisions: FrozenSet[str]
+    # Case labels or arms: a decision point unless is_default says they are the default one
+    cases: FrozenSet[str] = frozenset()
+    is_default: Callable[[Any], bool] = _never
+    # Binary expressions whose "operator" field may be a short-circuit operator
+    binary: FrozenSet[str] = frozenset({"binary_expression"})
+    boolean_operators: FrozenSet[str] = frozenset({"&&", "||"})
+    # Statements: these kinds, and kinds ending in "_statement" other than not_statements
+    statements: FrozenSet[str] = frozenset()
+    not_statements: FrozenSet[str] = frozenset({"empty_statement"})
+    # Blocks whose trailing expression is a statement too (Rust)
+    tail_blocks: FrozenSet[str] = frozenset()
+    # Control structures opening a nesting level
+    nesting: FrozenSet[str] = frozenset()
+    # `if` kinds, and the clauses wrapping their else branch: an `else if` stays on the level of its `if`
+    if_kinds: FrozenSet[str] = frozenset({"if_statement"})
+    else_clauses: FrozenSet[str] = frozenset({"else_clause"})
+    # Nested named functions and classes: one statement, not walked
+    definitions: FrozenSet[str] = frozenset()
+    # Bodies of nested declarations that are their own nodes, not walked
+    skipped: FrozenSet[str] = frozenset()
+    # Blocks whose named children are statements (Ruby), except the kinds in block_clauses
+    statement_blocks: FrozenSet[str] = frozenset()
+    block_clauses: FrozenSet[str] = frozenset()
+    body_field: str = "body"
+    # Child kinds holding the body, for grammars without a body field (Kotlin)
+    body_kinds: FrozenSet[str] = frozenset()
+
+
+GO_METRIC_RULES = MetricRules(
+    decisions=frozenset({"if_statement", "for_statement", "expression_case", "type_case", "communication_case"}),
+    statements=frozenset({"short_var_declaration", "var_declaration", "const_declaration", "type_declaration"}),
+    nesting=frozenset({"if_statement", "for_statement", "expression_switch_statement", "type_switch_statement",
+                       "select_statement"}),
+)
+
+JAVA_METRIC_RULES = MetricRules(
+    decisions=frozenset({"if_statement", "for_statement", "enhanced_for_statement", "while_statement",
+                         "do_statement", "catch_clause", "ternary_expression"}),
+    cases=frozenset({"switch_label"}),
+    is_default=_starts_with_default,
+    statements=frozenset({"local_variable_declaration", "local_class_declaration"}),
+    nesting=frozenset({"if_statement", "for_statement", "enhanced_for_statement", "while_statement",
+                       "do_statement", "switch_expression", "switch_statement", "try_statement",
+                       "try_with_resources_statement", "synchronized_statement"}),
+    definitions=frozenset({"class_declaration", "local_class_declaration", "record_declaration",
+                           "interface_declaration", "enum_declaration"}),
+    # Anonymous classes are indexed with their own methods
+    skipped=frozenset({"class_body"}),
+)
+
+JAVASCRIPT_METRIC_RULES = MetricRules(
+    decisions=frozenset({"if_statement", "for_statement", "for_in_statement", "while_statement", "do_statement",
+                         "catch_clause", "ternary_expression", "switch_case"}),
+    boolean_operators=frozenset({"&&", "||", "??"}),
+    statements=frozenset({"lexical_declaration", "variable_declaration"}),
+    nesting=frozenset({"if_statement", "for_statement", "for_in_statement", "while_statement", "do_statement",
+                       "switch_statement", "try_statement"}),
+    definitions=frozenset({"function_declaration", "generator_function_declaration", "class_declaration"}),
+)
+
+RUST_METRIC_RULES = MetricRules(
+    decisions=frozenset({"if_expression", "while_expression", "for_expression", "if_let_expression",
+                         "while_let_expression"}),
+    cases=frozenset({"match_arm"}),
+    is_default=_wildcard_arm,
+    statements=frozenset({"let_declaration"}),
+    tail_blocks=frozenset({"block"}),
+    nesting=frozenset({"if_expression", "if_let_expression", "while_expression", "while_let_expression",
+                       "for_expression", "loop_expression", "match_expression"}),
+    if_kinds=frozenset({"if_expression", "if_let_expression"}),
+    definitions=frozenset({"function_item", "struct_item", "enum_item", "impl_item", "trait_item", "mod_item"}),
+)
+
+C_METRIC_RULES = MetricRules(
+    decisions=frozenset({"if_statement", "for_statement", "for_range_loop", "while_statement", "do_statement",
+                         "catch_clause", "conditional_expression"}),
+    cases=frozenset({"case_statement"}),
+    is_default=_starts_with_default,
+    boolean_operators=frozenset({"&&", "||", "and", "or"}),
+    statements=frozenset({"declaration", "for_range_loop"}),
+    # Blocks and case labels are not statements of their own
+    not_statements=frozenset({"compound_statement", "case_statement"}),
+    nesting=frozenset({"if_statement", "for_statement", "for_range_loop", "while_statement", "do_statement",
+                       "switch_statement", "try_statement"}),
+    definitions=frozenset({"function_definition", "class_specifier", "struct_specifier"}),
+)
+
+CSHARP_METRIC_RULES = MetricRules(
+    decisions=frozenset({"if_statement", "for_statement", "foreach_statement", "while_statement", "do_statement",
+                         "catch_clause", "conditional_expression"}),
+    cases=frozenset({"switch_section", "switch_expression_arm"}),
+    is_default=_default_section_or_discard_arm,
+    boolean_operators=frozenset({"&&", "||", "??"}),
+    nesting=frozenset({"if_statement", "for_statement", "foreach_statement", "while_statement", "do_statement",
+                       "switch_statement", "switch_expression", "try_statement", "lock_statement",
+                       "using_statement"}),
+    definitions=frozenset({"local_function_statement", "class_declaration", "struct_declaration",
+                           "record_declaration", "interface_declaration", "enum_declaration"}),
+)
+
+# Ruby has no statement kinds: every expression of a body is one, nested
+# definitions included, so they are skipped rather than counted again
+RUBY_METRIC_RULES = MetricRules(
+    decisions=frozenset({"if", "elsif", "unless", "while", "until", "for", "if_modifier", "unless_modifier",
+                         "while_modifier", "until_modifier", "rescue", "rescue_modifier", "conditional"}),
+    cases=frozenset({"when", "in_clause"}),
+    binary=frozenset({"binary"}),
+    boolean_operators=frozenset({"&&", "||", "and", "or"}),
+    not_statements=frozenset({"body_statement", "empty_statement"}),
+    nesting=frozenset({"if", "elsif", "unless", "while", "until", "for", "case", "case_match", "begin",
+                       "do_block", "block"}),
+    if_kinds=frozenset({"if", "elsif", "unless"}),
+    else_clauses=frozenset(),
+    skipped=frozenset({"method", "singleton_method", "class", "module", "singleton_class"}),
+    statement_blocks=frozenset({"body_statement", "block_body", "then", "else", "do", "begin", "ensure"}),
+    block_clauses=frozenset({"comment", "empty_statement", "rescue", "else", "ensure"}),
+)
+
+# Kotlin blocks hold a `statements` node whose children are the statements,
+# so the loops are not counted again by their _statement kind. An `else if`
+# is an if_expression alone in the control_structure_body of its `if`
+KOTLIN_METRIC_RULES = MetricRules(
+    decisions=frozenset({"if_expression", "for_statement", "while_statement", "do_while_statement", "catch_block",
+                         "elvis_expression", "conjunction_expression", "disjunction_expression"}),
+    cases=frozenset({"when_entry"}),
+    is_default=_else_entry,
+    not_statements=frozenset({"for_statement", "while_statement", "do_while_statement"}),
+    nesting=frozenset({"if_expression", "for_statement", "while_statement", "do_while_statement",
+                       "when_expression", "try_expression"}),
+    if_kinds=frozenset({"if_expression"}),
+    else_clauses=frozenset({"control_structure_body"}),
+    skipped=frozenset({"function_declaration", "class_declaration", "object_declaration"}),
+    statement_blocks=frozenset({"statements"}),
+    block_clauses=frozenset({"line_comment", "multiline_comment"}),
+    body_kinds=frozenset({"function_body", "block"}),
+)
+
+
+def _is_statement(kind: str, rules: MetricRules) -> bool:
+    if kind in rules.statements:
+        return True
+    return kind.endswith("_statement") and kind not in rules.not_statements
+
+
+def syntax_metrics(func_node: Any, rules: MetricRules, line_no: int, end_line_no: Optional[int]) -> Dict[str, int]:
+    """
+    Metrics of a function or method of a tree-sitter language.
+    
+    Args:
+        func_node: ast-grep node, or any node with the same kind / field / children / text methods
+            (and is_named for rules with tail_blocks or statement_blocks)
+        rules: Counting rules of the grammar
+        line_no: First line of the declaration
+        end_line_no: Last line of the declaration
+    
+    Returns:
+        The metric properties, empty for declarations without a body
+    """
+    body = func_node.field(rules.body_field)
+    if body is None and rules.body_kinds:
+        body = next((child for child in func_node.children() if child.kind() in rules.body_kinds), None)
+    if body is None:
+        return {}
+    complexity, statements, max_nesting = 1, 0, 0
+    # (node, nesting depth of the node, kind of its parent)
+    stack: List[Tuple[Any, int, str]] = [(body, 0, "")]
+    while stack:
+        current, depth, parent_kind = stack.pop()
+        kind = current.kind()
+        if kind in rules.skipped:
+            continue
+        if kind in rules.definitions:
+            statements += 1
+            continue
+        if _is_statement(kind, rules):
+            statements += 1
+        if kind in rules.decisions:
+            complexity += 1
+        elif kind in rules.cases and not rules.is_default(current):
+            complexity += 1
+        elif kind in rules.binary:
+            operator = current.field("operator")
+            if operator is not None and operator.text() in rules.boolean_operators:
+                complexity += 1
+        
+        inner = depth
+        if kind in rules.nesting:
+            chained = kind in rules.if_kinds and (parent_kind in rules.if_kinds or parent_kind in rules.else_clauses)
+            inner = depth if chained else depth + 1
+            max_nesting = max(max_nesting, inner)
+        children = current.children()
+        if kind in rules.statement_blocks:
+            statements += sum(1 for child in children if child.is_named() and child.kind() not in rules.block_clauses)
+        if kind in rules.tail_blocks:
+            named = [child for child in children if child.is_named() and "comment" not in child.kind()]
+            if named and not _is_statement(named[-1].kind(), rules) and named[-1].kind() not in rules.definitions:
+                statements += 1
+        for child in reversed(children):
+            stack.append((child, inner, kind))
+    
+    return _metrics(complexity, statements, max_nesting, line_no, end_line_no)
@@ -6,17 +6,32 @@ Supports both legacy parsers and new ast-grep adapters.
 
 import os
 import logging
-from typing import Dict, List, Tuple, Any, Optional
+from typing import Callable, Dict, List, Tuple, Any, Optional
 
 from src.ast_parser.parser import ASTParser, CodeNode, CodeRelation
+from src.ast_parser.fallback_parser import FallbackParser, fallback_extensions
+from src.ast_parser.proto_parser import PROTO_EXTENSIONS, ProtoParser
 from src.ast_parser.typescript_parser import TypeScriptParser
 from src.ast_parser.adapters.python_adapter import PythonAstGrepAdapter
 from src.ast_parser.adapters.javascript_adapter import JavaScriptAstGrepAdapter
+from src.ast_parser.adapters.typescript_adapter import TypeScriptAdapter
 from src.ast_parser.adapters.java_adapter import JavaAdapter
 from src.ast_parser.adapters.cpp_adapter import CppAdapter
 from src.ast_parser.adapters.rust_adapter import RustAdapter
 from src.ast_parser.adapters.go_adapter import GoAdapter
+from src.ast_parser.adapters.csharp_adapter import CSharpAdapter
+from src.ast_parser.adapters.ruby_adapter import RubyAdapter
+from src.ast_parser.adapters.kotlin_adapter import KotlinAdapter
+from src.ast_parser.guards import (
+    DEFAULT_MAX_FILE_BYTES,
+    DEFAULT_PARSE_TIMEOUT,
+    ERROR_ENCODING,
+    ERROR_TIMEOUT,
+    classify_parse_error,
+    parse_with_limits,
+)
 from src.ast_parser.language_detector import detect_language
+from src.indexing.walker import walk_source_files
 
 logger = logging.getLogger(__name__)
 
@@ -27,17 +42,25 @@ class MultiLanguageParser:
     
     When use_ast_grep=True:
         - Python files (.py) -> PythonAstGrepAdapter
-        - JS/TS files (.js, .jsx, .ts, .tsx) -> JavaScriptAstGrepAdapter
+        - JS files (.js, .jsx) -> JavaScriptAstGrepAdapter
+        - TS files (.ts, .tsx) -> TypeScriptAdapter
     
     When use_ast_grep=False:
         - Python files (.py) -> ASTParser (legacy)
-        - JS/TS files -> TypeScriptParser (legacy)
+        - JS files (.js, .jsx) -> TypeScriptParser (legacy)
+        - TS files (.ts, .tsx) -> TypeScriptAdapter
+    
+    Protocol Buffers files (.proto) -> ProtoParser in both modes.
+    Extensions of the fallback patterns (.lua, .sh, .sql, .tf, ...) -> FallbackParser in both modes.
+    
+    Every file is parsed within the size and time limits of guards.py.
     
     Maintains compatibility with existing two-pass import resolution.
     """
     
     def __init__(self, use_ast_grep: bool = False, ast_grep_languages: Optional[List[str]] = None,
-                 ast_grep_fallback: bool = True):
+                 ast_grep_fallback: bool = True, max_file_bytes: int = DEFAULT_MAX_FILE_BYTES,
+                 parse_timeout: float = DEFAULT_PARSE_TIMEOUT):
         """
         Initialize the multi-language parser coordinator.
         
@@ -45,10 +68,14 @@ class MultiLanguageParser:
             use_ast_grep: If True, use ast-grep adapters when available
             ast_grep_languages: List of languages to enable for ast-grep (e.g., ['python', 'javascript'])
             ast_grep_fallback: If True, fall back to legacy parsers on error
+            max_file_bytes: Size above which a file is indexed without symbols, 0 for no limit
+            parse_timeout: Seconds a file may take to parse, 0 for no limit
         """
         self.use_ast_grep = use_ast_grep
         self.ast_grep_languages = set(ast_grep_languages or ['python', 'javascript', 'typescript'])
         self.ast_grep_fallback = ast_grep_fallback
+        self.max_file_bytes = max_file_bytes
+        self.parse_timeout = parse_timeout
         
         # Aggregated data structures for two-pass parsing
         self.nodes: Dict[str, CodeNode] = {}
@@ -84,11 +111,17 @@ class MultiLanguageParser:
         
         try:
             # Parse the file
-            nodes, relations = parser.parse_file(file_path, build_index=build_index)
+            nodes, relations = parse_with_limits(parser, file_path, build_index, self.max_file_bytes, self.parse_timeout)
+            
+            # An adapter error the legacy parser may not hit; timeouts and undecodable files would fail again
+            if parser.parse_error is not None and self._falls_back(language) and \
+                    classify_parse_error(parser.parse_error) not in (ERROR_TIMEOUT, ERROR_ENCODING):
+                logger.warning(f"Falling back to legacy parser for {file_path}")
+                return self._parse_with_fallback(file_path, ext, build_index)
             
             # Aggregate indices for two-pass resolution
             if hasattr(parser, 'module_definitions'):
-                self.module_definitions.update(parser.module_definitions)
+                self._merge_module_definitions(parser.module_definitions)
             if hasattr(parser, 'pending_imports'):
                 self.pending_imports.extend(parser.pending_imports)
             if hasattr(parser, 'module_to_file'):
@@ -106,13 +139,27 @@ class MultiLanguageParser:
             logger.error(f"Error parsing file {file_path}: {e}")
             
             # Try fallback if enabled and we were using ast-grep
-            if self.ast_grep_fallback and self.use_ast_grep and language in self.ast_grep_languages:
+            if self._falls_back(language):
                 logger.warning(f"Falling back to legacy parser for {file_path}")
                 return self._parse_with_fallback(file_path, ext, build_index)
             
             # Otherwise, return empty results
             return {}, []
     
+    def _falls_back(self, language: Optional[str]) -> bool:
+        """Whether a failed ast-grep parse of a file in language is retried with the legacy parser."""
+        return self.ast_grep_fallback and self.use_ast_grep and language in self.ast_grep_languages
+    
+    def _merge_module_definitions(self, module_definitions: Dict[str, Dict[str, str]]) -> None:
+        """
+        Merge a parser's module definition index into the aggregate.
+        
+        Keys can be shared by several files (e.g. all files of a Go package),
+        so symbol maps are merged instead of replaced.
+        """
+        for module_name, symbols in module_definitions.items():
+            self.module_definitions.setdefault(module_name, {}).update(symbols)
+    
     def _get_parser_for_file(self, file_path: str, language: Optional[str], ext: str):
         """
         Select the appropriate parser based on file extension and configuration.
@@ -125,8 +172,12 @@ class MultiLanguageParser:
         Returns:
             Parser instance or None if unsupported
         """
+        # Protocol Buffers files, parsed the same with and without ast-grep
+        if ext == '.proto':
+            return ProtoParser()
+        
         # Python files
-        if ext == '.py':
+        elif ext == '.py':
             if self.use_ast_grep and 'python' in self.ast_grep_languages:
                 # Use ast-grep Python adapter
                 return PythonAstGrepAdapter()
@@ -137,9 +188,15 @@ class MultiLanguageParser:
         # JavaScript/TypeScript files
         elif ext in ['.js', '.jsx', '.ts', '.tsx']:
             if self.use_ast_grep and (language in self.ast_grep_languages):
-                # Use ast-grep JavaScript adapter
                 use_tsx = (ext in ['.tsx', '.jsx'])
+                if ext in ['.ts', '.tsx']:
+                    # Use ast-grep TypeScript adapter
+                    return TypeScriptAdapter(use_tsx=use_tsx)
+                # Use ast-grep JavaScript adapter
                 return JavaScriptAstGrepAdapter(use_tsx=use_tsx)
+            elif not self.use_ast_grep and ext in ['.ts', '.tsx']:
+                # Interfaces, enums, tsconfig paths and components are only extracted by the adapter
+                return TypeScriptAdapter(use_tsx=(ext == '.tsx'))
             else:
                 # Use legacy TypeScriptParser
                 return TypeScriptParser()
@@ -176,6 +233,34 @@ class MultiLanguageParser:
                 logger.warning(f"Go parsing requires USE_AST_GREP=true and 'go' in AST_GREP_LANGUAGES")
                 return None
         
+        # C# files
+        elif ext == '.cs':
+            if self.use_ast_grep and 'csharp' in self.ast_grep_languages:
+                return CSharpAdapter()
+            else:
+                logger.warning(f"C# parsing requires USE_AST_GREP=true and 'csharp' in AST_GREP_LANGUAGES")
+                return None
+        
+        # Ruby files
+        elif ext == '.rb':
+            if self.use_ast_grep and 'ruby' in self.ast_grep_languages:
+                return RubyAdapter()
+            else:
+                logger.warning(f"Ruby parsing requires USE_AST_GREP=true and 'ruby' in AST_GREP_LANGUAGES")
+                return None
+        
+        # Kotlin files
+        elif ext in ['.kt', '.kts']:
+            if self.use_ast_grep and 'kotlin' in self.ast_grep_languages:
+                return KotlinAdapter()
+            else:
+                logger.warning(f"Kotlin parsing requires USE_AST_GREP=true and 'kotlin' in AST_GREP_LANGUAGES")
+                return None
+        
+        # Languages without a parser, indexed by the fallback patterns
+        elif ext in fallback_extensions():
+            return FallbackParser()
+        
         # Unsupported extension
         else:
             logger.warning(f"Unsupported file extension: {ext} for file {file_path}")
@@ -201,11 +286,11 @@ class MultiLanguageParser:
             else:
                 return {}, []
             
-            nodes, relations = parser.parse_file(file_path, build_index=build_index)
+            nodes, relations = parse_with_limits(parser, file_path, build_index, self.max_file_bytes, self.parse_timeout)
             
             # Aggregate indices
             if hasattr(parser, 'module_definitions'):
-                self.module_definitions.update(parser.module_definitions)
+                self._merge_module_definitions(parser.module_definitions)
             if hasattr(parser, 'pending_imports'):
                 self.pending_imports.extend(parser.pending_imports)
             if hasattr(parser, 'module_to_file'):
@@ -217,7 +302,9 @@ class MultiLanguageParser:
             logger.error(f"Fallback parser also failed for {file_path}: {e}")
             return {}, []
     
-    def parse_directory(self, directory_path: str, build_index: bool = True) -> Tuple[Dict[str, CodeNode], List[CodeRelation]]:
+    def parse_directory(self, directory_path: str, build_index: bool = True,
+                        source_files: Optional[List[str]] = None,
+                        on_file: Optional[Callable[[str], None]] = None) -> Tuple[Dict[str, CodeNode], List[CodeRelation]]:
         """
         Parse all supported source files in a directory.
         
@@ -227,13 +314,41 @@ class MultiLanguageParser:
         Args:
             directory_path: Path to the directory containing source files
             build_index: If True, build module definition index for cross-file resolution
+            source_files: Files to parse (e.g. already filtered by the caller), if None,
+                collect them from directory_path
+            on_file: Called with each file before it is parsed (progress reporting);
+                an exception it raises stops the parse
             
         Returns:
             Tuple of (all nodes dict, all relations list)
         """
-        # Collect all source files
-        source_files = []
+        if source_files is None:
+            source_files = self._collect_source_files(directory_path)
+        
+        logger.info(f"Found {len(source_files)} source files to parse")
+        
+        # Parse all files (first pass)
+        for file_path in source_files:
+            if on_file is not None:
+                on_file(file_path)
+            self.parse_file(file_path, build_index=build_index)
+        
+        # Second pass: process pending imports
+        if build_index:
+            self._process_pending_imports()
+        
+        return self.nodes, self.relations
+    
+    def _collect_source_files(self, directory_path: str) -> List[str]:
+        """
+        Collect supported source files, honouring .gitignore files.
         
+        Args:
+            directory_path: Path to the directory containing source files
+        
+        Returns:
+            List of source file paths
+        """
         # Determine which extensions to collect based on enabled languages
         if self.use_ast_grep:
             # Only collect files for languages we have adapters for
@@ -250,6 +365,12 @@ class MultiLanguageParser:
                 supported_extensions.append('.rs')
             if 'go' in self.ast_grep_languages:
                 supported_extensions.append('.go')
+            if 'csharp' in self.ast_grep_languages:
+                supported_extensions.append('.cs')
+            if 'ruby' in self.ast_grep_languages:
+                supported_extensions.append('.rb')
+            if 'kotlin' in self.ast_grep_languages:
+                supported_extensions.extend(['.kt', '.kts'])
             supported_extensions = tuple(supported_extensions)
         else:
             # Legacy mode: respect ENABLE_JS_TS_PARSING flag
@@ -257,25 +378,10 @@ class MultiLanguageParser:
             python_extensions = (".py",)
             js_ts_extensions = (".js", ".ts", ".jsx", ".tsx") if enable_js_ts else ()
             supported_extensions = python_extensions + js_ts_extensions
+        # Proto files and the languages of the fallback patterns are indexed in both modes
+        supported_extensions += PROTO_EXTENSIONS + fallback_extensions()
         
-        # Collect files
-        for root, _, files in os.walk(directory_path):
-            for file_name in files:
-                if file_name.endswith(supported_extensions):
-                    file_path = os.path.join(root, file_name)
-                    source_files.append(file_path)
-        
-        logger.info(f"Found {len(source_files)} source files to parse")
-        
-        # Parse all files (first pass)
-        for file_path in source_files:
-            self.parse_file(file_path, build_index=build_index)
-        
-        # Second pass: process pending imports
-        if build_index:
-            self._process_pending_imports()
-        
-        return self.nodes, self.relations
+        return walk_source_files(directory_path, supported_extensions)
     
     def _process_pending_imports(self):
         """
@@ -0,0 +1,49 @@
+"""
+Package nodes and import targets shared by the parsers and the indexer.
+
+Every indexed file belongs to a Package node: its Go package, keyed by
+import path when the file is inside a Go module, the Java package named by
+its `package` declaration, or otherwise its directory. Imports of code that is not indexed point at ExternalPackage
+placeholder nodes keyed by the module path as written (a Go import path,
+a dotted Python module, an npm package name).
+
+File -> target IMPORTS edges are aggregated into Package -> Package
+DEPENDS_ON edges by src.indexing.incremental.compute_package_dependencies().
+"""
+
+import os
+from typing import Optional
+
+PACKAGE_PREFIX = "package:"
+EXTERNAL_PACKAGE_PREFIX = "external_package:"
+
+
+def package_id(file_path: str, import_path: Optional[str] = None) -> str:
+    """Package node ID of a file: its Go import path or Java package, or its directory."""
+    if import_path:
+        return f"{PACKAGE_PREFIX}{import_path}"
+    return f"{PACKAGE_PREFIX}{os.path.dirname(file_path)}"
+
+
+def external_package_id(module_path: str) -> str:
+    """ExternalPackage node ID of a module that is not indexed."""
+    return f"{EXTERNAL_PACKAGE_PREFIX}{module_path}"
+
+
+def is_package_id(node_id: str) -> bool:
+    """Whether a node ID names a Package or ExternalPackage node."""
+    return node_id.startswith(PACKAGE_PREFIX) or node_id.startswith(EXTERNAL_PACKAGE_PREFIX)
+
+
+def npm_package_name(specifier: str) -> Optional[str]:
+    """
+    Package name of a bare import specifier ("lodash/fp" -> "lodash",
+    "@scope/pkg/x" -> "@scope/pkg"); None for relative and absolute paths.
+    """
+    if not specifier or specifier.startswith((".", "/")):
+        return None
+    parts = specifier.split("/")
+    if specifier.startswith("@"):
+        return "/".join(parts[:2]) if len(parts) > 1 else None
+    return parts[0]
+
@@ -3,6 +3,44 @@ import os
 from typing import Dict, List, Optional, Tuple, Any, Union, Set
 import json
 
+from src.ast_parser.decorators import annotation_properties, is_dataclass, python_decorators
+from src.ast_parser.packages import external_package_id, package_id
+from src.ast_parser.metrics import python_metrics
+from src.ast_parser.provenance import (
+    CONFIDENCE_PROPERTY,
+    INTERFACE_MATCH_CONFIDENCE,
+    PREMISES_PROPERTY,
+    SOURCE_INTERFACE_MATCH,
+    SOURCE_PROPERTY,
+    premise_files,
+)
+from src.ast_parser.signatures import python_signature
+from src.ast_parser.variables import (
+    VARIABLE_NODE_TYPES, is_variable_id, python_assignments, python_uses, queue_uses, uses_properties,
+)
+
+# 近似實作最多可缺少的方法數
+# Maximum number of missing methods for a NEAR_IMPLEMENTS edge
+NEAR_MISS_MAX_MISSING = 1
+
+# 函數定義節點（同步與非同步）
+# Function definition nodes (plain and async)
+FUNCTION_DEFS = (ast.FunctionDef, ast.AsyncFunctionDef)
+FunctionDefNode = Union[ast.FunctionDef, ast.AsyncFunctionDef]
+
+# 條件區塊：其中的定義標記為 conditional（例如 `if TYPE_CHECKING:`）
+# Blocks whose definitions are marked conditional (e.g. `if TYPE_CHECKING:`)
+CONDITIONAL_BLOCKS = (ast.If, ast.Try) + ((ast.TryStar,) if hasattr(ast, "TryStar") else ())
+
+# Rails 關聯巨集對應的關係類型（Ruby 適配器）
+# Relation type of each Rails association macro (Ruby adapter)
+RUBY_ASSOCIATIONS = {
+    "belongs_to": "BELONGS_TO",
+    "has_one": "HAS_ONE",
+    "has_many": "HAS_MANY",
+    "has_and_belongs_to_many": "HAS_AND_BELONGS_TO_MANY",
+}
+
 
 class CodeNode:
     """代表程式碼中的節點（類別、函數、變數等）"""
@@ -55,13 +93,27 @@ class ASTParser:
     """使用 Python AST 模組解析程式碼的解析器"""
     # Parser that uses the Python AST module to parse code
 
+    # 由 parse_with_limits 設定的解析期限
+    # ParseDeadline of the file being parsed, set by parse_with_limits
+    parse_deadline = None
+    # 上次解析失敗時捕獲的錯誤，由 parse_with_limits 讀取
+    # Error the last parse_file swallowed, read by parse_with_limits
+    parse_error = None
+    
     def __init__(self):
         self.nodes: Dict[str, CodeNode] = {}
         self.relations: List[CodeRelation] = []
         self.current_file: str = ""
         self.current_function: Optional[str] = None
         self.current_class: Optional[str] = None
+        self.current_module: str = ""
+        # 目前所在的條件區塊（None 表示 try 區塊）
+        # Enclosing conditional blocks (None for try blocks)
+        self.condition_stack: List[Optional[str]] = []
         self.imports: Dict[str, str] = {}
+        # `import x` 綁定的名稱（模組本身，而非其中的符號）
+        # Names bound by `import x`, which name the module rather than a symbol in it
+        self.module_aliases: Set[str] = set()
         # 用於追蹤模組中的定義
         # Used to track definitions within modules
         self.module_definitions: Dict[str, Dict[str, str]] = {}
@@ -74,6 +126,15 @@ class ASTParser:
         # 用於追蹤已建立的關係，避免重複
         # Used to track established relationships to avoid duplication
         self.established_relations: Set[str] = set()
+        # 是否為缺少少量方法的 Go 類型建立 NEAR_IMPLEMENTS 關係
+        # Whether to add NEAR_IMPLEMENTS edges for Go types missing a few interface methods
+        self.report_near_miss_implementations = os.getenv("GO_IMPLEMENTS_NEAR_MISS", "false").lower() == "true"
+        # 不在 nodes 中的節點所屬檔案（未重新解析的檔案）
+        # File of the nodes that are not in nodes (files resolved in another pass)
+        self.node_files: Dict[str, str] = {}
+        # 其他檔案已解析的介面嵌入關係（介面節點 ID -> 嵌入的介面節點 ID）
+        # Interface embeddings resolved from files of another pass (interface node ID -> embedded interface IDs)
+        self.interface_embeds: Dict[str, List[str]] = {}
 
     def parse_directory(self, directory_path: str) -> Tuple[Dict[str, CodeNode], List[CodeRelation]]:
         """解析目錄中的所有Python檔案"""
@@ -107,16 +168,24 @@ class ASTParser:
         print(f"Parsing file: {file_path}")
         self.current_file = file_path
         self.imports = {}
+        self.module_aliases = set()
+        self.condition_stack = []
 
         try:
             with open(file_path, "r", encoding="utf-8") as file:
                 file_content = file.read()
                 tree = ast.parse(file_content)
                 file_node_id = self._create_file_node(file_path)
+                # 模組文檔字串屬於檔案節點
+                # The module docstring belongs to the File node
+                doc = ast.get_docstring(tree)
+                if doc:
+                    self.nodes[file_node_id].properties["doc"] = doc
                 
                 # 生成模組名稱，用於索引
                 # Generate module name for indexing
                 module_name = os.path.splitext(os.path.basename(file_path))[0]
+                self.current_module = module_name
                 if build_index:
                     if module_name not in self.module_definitions:
                         self.module_definitions[module_name] = {}
@@ -132,6 +201,7 @@ class ASTParser:
             # Disabled Chinese error log above.
             print(f"Error parsing file {file_path}: {e}")
             # Error parsing file {file_path}: {e}
+            self.parse_error = e
             return {}, []
 
     def _create_file_node(self, file_path: str) -> str:
@@ -151,6 +221,10 @@ class ASTParser:
     def _get_node_id(self, node_type: str, name: str, file_path: str, line_no: int) -> str:
         """生成節點唯一標識符"""
         # Generates a unique identifier for the node
+        # 解析逾時檢查（見 guards.py）
+        # Checks the parse deadline first (see guards.py)
+        if self.parse_deadline is not None:
+            self.parse_deadline.check()
         return f"{node_type}:{file_path}:{name}:{line_no}"
 
     def _parse_ast(self, tree: ast.AST, build_index: bool = False, module_name: str = "") -> None:
@@ -161,17 +235,53 @@ class ASTParser:
                 node_id = self._parse_class(node)
                 if build_index and module_name:
                     self.module_definitions[module_name][node.name] = node_id
-            elif isinstance(node, ast.FunctionDef):
+            elif isinstance(node, FUNCTION_DEFS):
                 node_id = self._parse_function(node)
                 if build_index and module_name:
                     self.module_definitions[module_name][node.name] = node_id
             elif isinstance(node, ast.Import) or isinstance(node, ast.ImportFrom):
                 self._parse_import(node)
-            elif isinstance(node, ast.Assign):
-                self._parse_assignment(node)
+            elif isinstance(node, (ast.Assign, ast.AnnAssign)):
+                self._parse_assignment(node, build_index, module_name)
+            elif isinstance(node, CONDITIONAL_BLOCKS):
+                for condition, statements in self._conditional_blocks(node):
+                    self.condition_stack.append(condition)
+                    self._parse_ast(ast.Module(body=statements, type_ignores=[]), build_index, module_name)
+                    self.condition_stack.pop()
             else:
                 self._parse_ast(node, build_index, module_name)
 
+    def _conditional_blocks(self, node: ast.stmt) -> List[Tuple[Optional[str], List[ast.stmt]]]:
+        """列出條件區塊的各分支及其條件"""
+        # List the branches of an if/try block with their condition (None for try blocks)
+        if isinstance(node, ast.If):
+            test = ast.unparse(node.test)
+            return [(test, node.body), (f"not ({test})", node.orelse)]
+        blocks = [(None, node.body)]
+        blocks.extend((None, handler.body) for handler in node.handlers)
+        blocks.append((None, node.orelse))
+        blocks.append((None, node.finalbody))
+        return blocks
+    
+    def _mark_definition_context(self, node_id: str) -> None:
+        """標記定義於條件區塊中的節點"""
+        # Flag nodes defined inside if/try blocks, recording the if conditions
+        if not self.condition_stack:
+            return
+        properties = self.nodes[node_id].properties
+        properties["conditional"] = True
+        conditions = [condition for condition in self.condition_stack if condition]
+        if conditions:
+            properties["condition"] = " and ".join(conditions)
+    
+    def _parse_decorators(self, node: Union[FunctionDefNode, ast.ClassDef], node_id: str) -> None:
+        """記錄裝飾器並排入 DECORATED_BY 解析"""
+        # Record decorators on the node and queue DECORATED_BY resolution
+        decorators, pending = python_decorators(node, node_id, self.current_module, self.imports)
+        self.pending_imports.extend(pending)
+        if decorators:
+            self.nodes[node_id].properties["decorators"] = decorators
+    
     def _parse_class(self, node: ast.ClassDef) -> str:
         """解析類別定義"""
         # Parses class definitions
@@ -188,6 +298,17 @@ class ASTParser:
             line_no=node.lineno,
             end_line_no=getattr(node, "end_lineno", None),
         )
+        self._mark_definition_context(node_id)
+        self._parse_decorators(node, node_id)
+        dataclass = is_dataclass(node)
+        if dataclass:
+            self.nodes[node_id].properties["dataclass"] = True
+        doc = ast.get_docstring(node)
+        if doc:
+            self.nodes[node_id].properties["doc"] = doc
+        if node.bases:
+            # Base expressions as written, also the ones no EXTENDS edge is resolved for (e.g. module.Base)
+            self.nodes[node_id].properties["bases"] = [ast.unparse(base) for base in node.bases]
         
         # 創建檔案包含類別的關係
         # Create relationship that file contains class
@@ -230,22 +351,37 @@ class ASTParser:
         # 設置當前類別上下文
         # Set current class context
         self.current_class = node_id
+        prev_conditions = self.condition_stack
+        self.condition_stack = []
         
         # 解析類別內部成員
         # Parse class members
-        for item in node.body:
-            if isinstance(item, ast.FunctionDef):
-                self._parse_method(item)
-            elif isinstance(item, ast.Assign):
-                self._parse_class_attribute(item)
+        self._parse_class_body(node.body, dataclass)
         
         # 恢復上下文
         # Restore context
         self.current_class = prev_class
+        self.condition_stack = prev_conditions
         
         return node_id
 
-    def _parse_method(self, node: ast.FunctionDef) -> None:
+    def _parse_class_body(self, statements: List[ast.stmt], dataclass: bool) -> None:
+        """解析類別主體中的方法與屬性"""
+        # Parse methods and attributes of a class body, including if/try blocks
+        for item in statements:
+            if isinstance(item, FUNCTION_DEFS):
+                self._parse_method(item)
+            elif isinstance(item, ast.Assign):
+                self._parse_class_attribute(item)
+            elif isinstance(item, ast.AnnAssign):
+                self._parse_class_annotation(item, dataclass)
+            elif isinstance(item, CONDITIONAL_BLOCKS):
+                for condition, block in self._conditional_blocks(item):
+                    self.condition_stack.append(condition)
+                    self._parse_class_body(block, dataclass)
+                    self.condition_stack.pop()
+    
+    def _parse_method(self, node: FunctionDefNode) -> None:
         """解析類別方法"""
         # Parses class methods
         node_id = self._get_node_id("Method", node.name, self.current_file, node.lineno)
@@ -261,6 +397,10 @@ class ASTParser:
             end_line_no=getattr(node, "end_lineno", None),
             properties={"is_method": True},
         )
+        if isinstance(node, ast.AsyncFunctionDef):
+            self.nodes[node_id].properties["is_async"] = True
+        self._mark_definition_context(node_id)
+        self._parse_decorators(node, node_id)
         
         # 創建類別定義方法的關係
         # Create relationship that class defines method
@@ -275,7 +415,9 @@ class ASTParser:
         # 設置當前函數上下文並解析函數體
         # Set current function context and parse function body
         prev_function = self.current_function
+        prev_conditions = self.condition_stack
         self.current_function = node_id
+        self.condition_stack = []
         
         # 解析函數參數
         # Parse function arguments
@@ -287,19 +429,21 @@ class ASTParser:
         # Retrieve the docstring (compatible across Python versions)
         doc = ast.get_docstring(node)
         if doc:
-            self.nodes[node_id].properties["docstring"] = doc
+            self.nodes[node_id].properties["doc"] = doc
 
         # 尋找函數調用
         # Find function calls in the body
         for item in node.body:
             self._find_function_calls(item)
+        self._find_variable_uses(node)
         
         # 恢復上下文
         self.current_function = prev_function
+        self.condition_stack = prev_conditions
 
-    def _parse_function(self, node: ast.FunctionDef) -> str:
+    def _parse_function(self, node: FunctionDefNode, nested: bool = False) -> str:
         """解析函數定義"""
-        # Parse a top-level function definition
+        # Parse a function definition; nested functions are defined by the enclosing function
         node_id = self._get_node_id("Function", node.name, self.current_file, node.lineno)
 
         # 創建函數節點
@@ -313,22 +457,40 @@ class ASTParser:
             end_line_no=getattr(node, "end_lineno", None),
             properties={"is_method": False},
         )
+        if isinstance(node, ast.AsyncFunctionDef):
+            self.nodes[node_id].properties["is_async"] = True
+        self._mark_definition_context(node_id)
+        self._parse_decorators(node, node_id)
 
-        # 創建檔案包含函數的關係
-        # Create relationship that file contains function
-        file_node_id = f"file:{self.current_file}"
-        self.relations.append(
-            CodeRelation(
-                source_id=file_node_id,
-                target_id=node_id,
-                relation_type="CONTAINS",
+        if nested:
+            # 外層函數定義巢狀函數
+            # The enclosing function defines the nested function
+            self.nodes[node_id].properties["nested"] = True
+            self.relations.append(
+                CodeRelation(
+                    source_id=self.current_function,
+                    target_id=node_id,
+                    relation_type="DEFINES",
+                )
+            )
+        else:
+            # 創建檔案包含函數的關係
+            # Create relationship that file contains function
+            file_node_id = f"file:{self.current_file}"
+            self.relations.append(
+                CodeRelation(
+                    source_id=file_node_id,
+                    target_id=node_id,
+                    relation_type="CONTAINS",
+                )
             )
-        )
 
         # 設置當前函數上下文
         # Set current function context
         prev_function = self.current_function
+        prev_conditions = self.condition_stack
         self.current_function = node_id
+        self.condition_stack = []
 
         # 解析函數參數
         # Parse function arguments
@@ -340,20 +502,24 @@ class ASTParser:
         # Retrieve the docstring (compatible across Python versions)
         doc = ast.get_docstring(node)
         if doc:
-            self.nodes[node_id].properties["docstring"] = doc
+            self.nodes[node_id].properties["doc"] = doc
 
         # 尋找函數調用
         # Find function calls in the body
         for item in node.body:
             self._find_function_calls(item)
+        # 使用的模組層級變數與常數
+        # Module-level variables and constants the body uses
+        self._find_variable_uses(node)
 
         # 恢復上下文
         # Restore context
         self.current_function = prev_function
+        self.condition_stack = prev_conditions
 
         return node_id
 
-    def _parse_function_args(self, node: ast.FunctionDef, node_id: str) -> None:
+    def _parse_function_args(self, node: FunctionDefNode, node_id: str) -> None:
         """解析函數參數"""
         args = []
         
@@ -379,6 +545,15 @@ class ASTParser:
         # 將參數列表序列化為JSON字符串，而不是直接存儲字典
         # Serialize the argument list to a JSON string instead of storing the dict directly
         self.nodes[node_id].properties["args"] = json.dumps(args)
+        
+        # 結構化簽名：參數、回傳型別與型別參數
+        # Structured signature: parameters, return type and type parameters
+        self.nodes[node_id].properties.update(
+            python_signature(node, is_method=self.nodes[node_id].node_type == "Method")
+        )
+        # 複雜度與規模度量
+        # Complexity and size metrics
+        self.nodes[node_id].properties.update(python_metrics(node))
 
     def _parse_import(self, node: Union[ast.Import, ast.ImportFrom]) -> None:
         """解析導入語句"""
@@ -392,6 +567,9 @@ class ASTParser:
                 # 添加到導入映射
                 # Add to import mapping
                 self.imports[asname] = import_name
+                # 別名指向模組本身，而非模組中的符號
+                # The alias names the module itself, not a symbol in it
+                self.module_aliases.add(asname)
                 
                 # 模組名稱（取第一部分，例如 'package.module' -> 'package'）
                 # Root module name (take the first part, e.g. 'package.module' -> 'package')
@@ -404,7 +582,8 @@ class ASTParser:
                     "source_id": file_node_id,
                     "imported_module": root_module,
                     "full_module_path": import_name,
-                    "alias": asname
+                    "alias": asname,
+                    "line_no": node.lineno
                 })
         
         elif isinstance(node, ast.ImportFrom):
@@ -428,75 +607,42 @@ class ASTParser:
                     "source_id": file_node_id,
                     "imported_module": module_name,
                     "imported_name": import_name,
-                    "alias": asname
+                    "alias": asname,
+                    "level": node.level,
+                    "line_no": node.lineno
                 })
 
-    def _parse_assignment(self, node: ast.Assign) -> None:
-        """解析賦值語句"""
-        for target in node.targets:
-            if isinstance(target, ast.Name):
-                var_name = target.id
-                node_id = self._get_node_id("Variable", var_name, self.current_file, node.lineno)
-                
-                if self.current_class and not self.current_function:
-                    # 類別屬性
-                    # Class attribute
-                    self.nodes[node_id] = CodeNode(
-                        node_id=node_id,
-                        node_type="ClassVariable",
-                        name=var_name,
-                        file_path=self.current_file,
-                        line_no=node.lineno,
-                        end_line_no=getattr(node, "end_lineno", None),
-                    )
-                    
-                    self.relations.append(
-                        CodeRelation(
-                            source_id=self.current_class,
-                            target_id=node_id,
-                            relation_type="DEFINES",
-                        )
-                    )
-                elif self.current_function:
-                    # 局部變數
-                    # Local variable
-                    self.nodes[node_id] = CodeNode(
-                        node_id=node_id,
-                        node_type="LocalVariable",
-                        name=var_name,
-                        file_path=self.current_file,
-                        line_no=node.lineno,
-                        end_line_no=getattr(node, "end_lineno", None),
-                    )
-                    
-                    self.relations.append(
-                        CodeRelation(
-                            source_id=self.current_function,
-                            target_id=node_id,
-                            relation_type="DEFINES",
-                        )
-                    )
-                else:
-                    # 全局變數
-                    # Global variable
-                    self.nodes[node_id] = CodeNode(
-                        node_id=node_id,
-                        node_type="GlobalVariable",
-                        name=var_name,
-                        file_path=self.current_file,
-                        line_no=node.lineno,
-                        end_line_no=getattr(node, "end_lineno", None),
-                    )
-                    
-                    file_node_id = f"file:{self.current_file}"
-                    self.relations.append(
-                        CodeRelation(
-                            source_id=file_node_id,
-                            target_id=node_id,
-                            relation_type="DEFINES",
-                        )
-                    )
-
+    def _parse_assignment(self, node: Union[ast.Assign, ast.AnnAssign], build_index: bool = False,
+                          module_name: str = "") -> None:
+        """解析模組層級的賦值語句"""
+        # Module-level assignment: a Constant or Variable node per assigned name (see variables.py)
+        file_node_id = f"file:{self.current_file}"
+        for var_name, node_type, properties in python_assignments(node):
+            node_id = self._get_node_id(node_type, var_name, self.current_file, node.lineno)
+            
+            self.nodes[node_id] = CodeNode(
+                node_id=node_id,
+                node_type=node_type,
+                name=var_name,
+                file_path=self.current_file,
+                line_no=node.lineno,
+                end_line_no=getattr(node, "end_lineno", None),
+                properties=properties,
+            )
+            self._mark_definition_context(node_id)
+            
+            self.relations.append(
+                CodeRelation(
+                    source_id=file_node_id,
+                    target_id=node_id,
+                    relation_type="DEFINES",
+                )
+            )
+            # 重新賦值時保留第一個定義
+            # A name assigned again keeps its first definition
+            if build_index and module_name:
+                self.module_definitions[module_name].setdefault(var_name, node_id)
+    
     def _parse_class_attribute(self, node: ast.Assign) -> None:
         """解析類別屬性"""
         # 與 _parse_assignment 類似，但專門處理類別內部的屬性
@@ -522,9 +668,51 @@ class ASTParser:
                     )
                 )
 
+    def _parse_class_annotation(self, node: ast.AnnAssign, dataclass: bool) -> None:
+        """解析帶型別註解的類別屬性（例如 dataclass 欄位）"""
+        # Parse an annotated class attribute (e.g. a dataclass field)
+        if not isinstance(node.target, ast.Name):
+            return
+        var_name = node.target.id
+        node_id = self._get_node_id("ClassVariable", var_name, self.current_file, node.lineno)
+        # ClassVar 註解不是 dataclass 欄位
+        # ClassVar annotations are not dataclass fields
+        properties = annotation_properties(ast.unparse(node.annotation), node.value is not None, dataclass)
+        
+        self.nodes[node_id] = CodeNode(
+            node_id=node_id,
+            node_type="ClassVariable",
+            name=var_name,
+            file_path=self.current_file,
+            line_no=node.lineno,
+            end_line_no=getattr(node, "end_lineno", None),
+            properties=properties,
+        )
+        self._mark_definition_context(node_id)
+        
+        self.relations.append(
+            CodeRelation(
+                source_id=self.current_class,
+                target_id=node_id,
+                relation_type="DEFINES",
+            )
+        )
+    
+    def _find_variable_uses(self, node: FunctionDefNode) -> None:
+        """記錄函數讀取、比較、回傳或寫入的模組層級名稱"""
+        # Queue USES of the module-level names the function reads, compares, returns or writes
+        uses = python_uses(node, self.current_module, self.imports, self.module_aliases)
+        queue_uses(self.pending_imports, self.current_function, uses)
+    
     def _find_function_calls(self, node: ast.AST) -> None:
         """在AST節點中尋找函數調用"""
         # Search for function calls in AST nodes
+        if isinstance(node, FUNCTION_DEFS):
+            # 巢狀函數有自己的節點，其調用歸屬於該節點
+            # Nested functions get their own node, which owns the calls in their body
+            self._parse_function(node, nested=True)
+            return
+        
         if isinstance(node, ast.Call):
             func = node.func
             
@@ -537,13 +725,14 @@ class ASTParser:
                     # 處理導入的函數調用
                     # Handle calls to imported functions
                     imported_func = self.imports[func_name]
-                    # 將調用添加到待處理隊列
-                    # Add the call to the pending queue
-                    if self.current_function:
+                    # 將調用添加到待處理隊列；符號以其所在模組（倒數第二段）索引
+                    # Add the call to the pending queue; symbols are indexed
+                    # under the module defining them (the second-to-last part)
+                    if self.current_function and func_name not in self.module_aliases:
                         self.pending_imports.append({
                             "type": "CALLS",
                             "source_id": self.current_function,
-                            "imported_module": imported_func.split(".")[0] 
+                            "imported_module": imported_func.split(".")[-2]
                                 if "." in imported_func else imported_func,
                             "imported_name": imported_func.split(".")[-1] 
                                 if "." in imported_func else imported_func,
@@ -618,6 +807,18 @@ class ASTParser:
             # For symbol imports, include the symbol name in the key
             symbol = relation.properties.get("symbol", "")
             relation_key = f"{relation.source_id}|{relation.relation_type}|{relation.target_id}|{symbol}"
+        elif relation.relation_type == "USES":
+            # 同一函數對同一變數的每種用法各一條關係
+            # One relation per kind of use of the same variable by the same function
+            relation_key = f"{relation_key}|{relation.properties.get('usage', '')}"
+        elif relation.relation_type == "MIXES_IN":
+            # 同一模組以 include、extend 與 prepend 混入各一條關係
+            # One relation per way of mixing in the same module (include, extend, prepend)
+            relation_key = f"{relation_key}|{relation.properties.get('mixin', '')}"
+        elif relation.relation_type in RUBY_ASSOCIATIONS.values():
+            # 兩個模型之間的每個關聯各一條關係（has_many :posts 與 has_many :drafts）
+            # One relation per association between two models (has_many :posts and has_many :drafts)
+            relation_key = f"{relation_key}|{relation.properties.get('name', '')}"
         
         # 檢查是否已經存在相同的關係
         # Check whether the same relation already exists
@@ -625,9 +826,314 @@ class ASTParser:
             self.relations.append(relation)
             self.established_relations.add(relation_key)
 
+    def _get_external_node(self, external: Dict[str, str]) -> str:
+        """取得或建立外部符號的佔位節點"""
+        # Get or create the placeholder node for a symbol in an unindexed package
+        import_path = external["import_path"]
+        name = external["name"]
+        node_id = f"external:{import_path}:{name}"
+        
+        if node_id not in self.nodes:
+            self.nodes[node_id] = CodeNode(
+                node_id=node_id,
+                node_type="ExternalFunction",
+                name=name,
+                file_path="",
+                line_no=0,
+                properties={
+                    "import_path": import_path,
+                    "qualified_name": f"{import_path}.{name}",
+                    "placeholder": True,
+                },
+            )
+        return node_id
+    
+    def _get_external_type_node(self, name: str) -> str:
+        """取得或建立未索引型別的佔位節點"""
+        # Get or create the placeholder node of a base type that is not indexed (name as written)
+        node_id = f"external_type:{name}"
+        if node_id not in self.nodes:
+            self.nodes[node_id] = CodeNode(
+                node_id=node_id,
+                node_type="ExternalType",
+                name=name,
+                file_path="",
+                line_no=0,
+                properties={"qualified_name": name, "placeholder": True},
+            )
+        return node_id
+    
+    def _get_external_package_node(self, module_path: str) -> str:
+        """取得或建立未索引套件的佔位節點"""
+        # Get or create the placeholder node of a package that is not indexed
+        node_id = external_package_id(module_path)
+        if node_id not in self.nodes:
+            self.nodes[node_id] = CodeNode(
+                node_id=node_id,
+                node_type="ExternalPackage",
+                name=module_path,
+                file_path="",
+                line_no=0,
+                properties={"module_path": module_path, "placeholder": True},
+            )
+        return node_id
+    
+    def _add_import(self, source_id: str, target_id: str, import_info: Dict[str, Any], **properties: Any) -> None:
+        """建立檔案的 IMPORTS 關係"""
+        # Create an IMPORTS relation from a file; None properties are dropped
+        if "line_no" in import_info:
+            properties["line_no"] = import_info["line_no"]
+        self._add_relation(
+            CodeRelation(
+                source_id=source_id,
+                target_id=target_id,
+                relation_type="IMPORTS",
+                properties={key: value for key, value in properties.items() if value is not None},
+            )
+        )
+    
+    def _python_module_index(self) -> Dict[str, List[Tuple[str, str]]]:
+        """建立 Python 模組路徑索引"""
+        # Index Python files by every dotted suffix of their module path:
+        # "app/utils/text.py" is found as "text", "utils.text" and
+        # "app.utils.text", a package's __init__.py as the package. Files of
+        # other runs are known through module_to_file.
+        file_ids = [node_id for node_id, node in self.nodes.items() if node.node_type == "File"]
+        file_ids.extend(self.module_to_file.values())
+        
+        index: Dict[str, List[Tuple[str, str]]] = {}
+        for file_id in dict.fromkeys(file_ids):
+            path = file_id[len("file:"):].replace(os.sep, "/")
+            if not path.endswith(".py"):
+                continue
+            parts = [part for part in path[:-len(".py")].split("/") if part]
+            if parts and parts[-1] == "__init__":
+                parts.pop()
+            for start in range(len(parts)):
+                index.setdefault(".".join(parts[start:]), []).append((path, file_id))
+            # Exact paths, for relative imports ("/" keeps them apart from module names)
+            index.setdefault(path if path.startswith("/") else "/" + path, []).append((path, file_id))
+        return index
+    
+    def _resolve_python_module(self, python_modules: Dict[str, List[Tuple[str, str]]], module: Optional[str],
+                               source_path: str, level: int = 0) -> Optional[str]:
+        """解析 Python 模組名稱對應的檔案節點"""
+        # Resolve a (possibly relative) Python module to its File node ID.
+        #
+        # Relative imports must match the path exactly. An absolute name
+        # matching several files picks the one closest to the importing
+        # file, then the shortest path.
+        source_path = source_path.replace(os.sep, "/")
+        parts = module.split(".") if module else []
+        if level:
+            base = os.path.dirname(source_path)
+            for _ in range(level - 1):
+                base = os.path.dirname(base)
+            stem = "/".join([base] + parts) if base else "/".join(parts)
+            for candidate in (f"{stem}.py", f"{stem}/__init__.py"):
+                matches = python_modules.get(candidate if candidate.startswith("/") else "/" + candidate)
+                if matches:
+                    return matches[0][1]
+            return None
+        
+        matches = python_modules.get(module or "", [])
+        if not matches:
+            return None
+        
+        def closeness(match: Tuple[str, str]) -> Tuple[int, int, str]:
+            common = os.path.commonprefix([os.path.dirname(match[0]) + "/", os.path.dirname(source_path) + "/"])
+            return (-common.count("/"), len(match[0]), match[0])
+        
+        return min(matches, key=closeness)[1]
+    
+    def _add_module_import(self, source_id: str, import_info: Dict[str, Any],
+                           python_modules: Dict[str, List[Tuple[str, str]]]) -> None:
+        """為模組導入建立 IMPORTS 關係"""
+        # IMPORTS edge of a module import: to the Go or Java package or C#
+        # namespace, the Python, TypeScript or Ruby file, or an
+        # ExternalPackage when it is not indexed
+        source = self.nodes.get(source_id)
+        if source is None:
+            return
+        
+        if "import_path" in import_info:
+            # Go 與 Java 萬用字元導入、C# using：匯入路徑屬於已索引套件時連到套件節點
+            # Go and Java wildcard imports, C# usings: link to the Package node when the import path is indexed
+            import_path = import_info["import_path"]
+            self._add_import(source_id, self._imported_package_node(import_info), import_info, import_path=import_path,
+                             alias=import_info.get("alias"), dot=import_info.get("dot"), blank=import_info.get("blank"),
+                             wildcard=import_info.get("wildcard"), static=import_info.get("static"),
+                             **{"global": import_info.get("global")})
+            return
+        
+        module_path = import_info.get("full_module_path") or import_info["imported_module"]
+        if source.file_path.endswith(".py"):
+            target_id = self._resolve_python_module(python_modules, module_path, source.file_path)
+            if target_id is None:
+                target_id = self._get_external_package_node(module_path)
+            alias = import_info.get("alias")
+            self._add_import(source_id, target_id, import_info, module=module_path,
+                             alias=alias if alias != module_path else None)
+            return
+        
+        if import_info["imported_module"] in self.module_to_file:
+            self._add_import(source_id, self.module_to_file[import_info["imported_module"]], import_info,
+                             module=module_path)
+        elif import_info.get("external_package"):
+            self._add_import(source_id, self._get_external_package_node(import_info["external_package"]),
+                             import_info, module=module_path)
+    
+    def _imported_package_node(self, import_info: Dict[str, Any]) -> str:
+        """匯入路徑的套件節點（已索引）或外部套件佔位節點"""
+        # Package node of an import path when it is indexed, else its ExternalPackage placeholder
+        import_path = import_info["import_path"]
+        if import_info.get("package_key") in self.module_definitions:
+            return package_id("", import_path)
+        return self._get_external_package_node(import_path)
+    
+    def _add_symbol_import(self, source_id: str, import_info: Dict[str, Any],
+                           python_modules: Dict[str, List[Tuple[str, str]]]) -> None:
+        """為 Python `from x import y` 建立 IMPORTS 關係"""
+        # IMPORTS edge of a Python `from x import y`: to the symbol y when it
+        # is indexed, else to the submodule x.y, else to the module x, else
+        # to an ExternalPackage for x. A Java or Kotlin import, Rust `use`
+        # or C# alias and static using links to the item, else to its
+        # package, module or namespace.
+        source = self.nodes.get(source_id)
+        module = import_info["imported_module"]
+        name = import_info["imported_name"]
+        if source is not None and source.file_path.endswith((".java", ".kt", ".kts", ".rs", ".cs")):
+            target_id = self.module_definitions.get(module, {}).get(name) or self._imported_package_node(import_info)
+            self._add_import(source_id, target_id, import_info, import_path=import_info["import_path"],
+                             symbol=name, member=import_info.get("member"), wildcard=import_info.get("wildcard"),
+                             static=import_info.get("static"), alias=import_info.get("alias"),
+                             **{"global": import_info.get("global")})
+            return
+        if source is None or not source.file_path.endswith(".py"):
+            return
+        level = import_info.get("level") or 0
+        written = "." * level + (module or "")
+        alias = import_info.get("alias")
+        properties = {"module": written, "symbol": name, "alias": alias if alias != name else None}
+        
+        module_file = self._resolve_python_module(python_modules, module, source.file_path, level)
+        if module_file is not None:
+            module_name = os.path.splitext(os.path.basename(module_file))[0]
+            symbol_id = self.module_definitions.get(module_name, {}).get(name)
+            symbol = self.nodes.get(symbol_id) if symbol_id else None
+            symbol_file = symbol.file_path if symbol is not None else self.node_files.get(symbol_id)
+            if symbol_id and (symbol_file is None or f"file:{symbol_file}" == module_file):
+                self._add_import(source_id, symbol_id, import_info, **properties)
+                return
+        
+        submodule = self._resolve_python_module(python_modules, f"{module}.{name}" if module else name,
+                                                source.file_path, level)
+        target_id = submodule or module_file
+        if target_id is None and not level and module:
+            target_id = self._get_external_package_node(module)
+        if target_id is not None:
+            self._add_import(source_id, target_id, import_info, **properties)
+    
+    def _resolve_packages(self) -> None:
+        """建立套件節點並以 CONTAINS 連結其檔案"""
+        # Create the Package node of every file and link it with CONTAINS.
+        #
+        # Go files belong to their package (by import path inside a module),
+        # other files to their directory. A C# file declaring several
+        # namespaces lists them in "namespaces" and belongs to each. Package
+        # nodes have no file, so incremental runs keep them and only add the
+        # CONTAINS edges.
+        for node_id, node in list(self.nodes.items()):
+            if node.node_type != "File" or not node.file_path:
+                continue
+            directory = os.path.dirname(node.file_path)
+            namespaces = node.properties.get("namespaces")
+            for import_path in namespaces or [node.properties.get("import_path")]:
+                package_node_id = package_id(node.file_path, import_path)
+                name = (import_path if namespaces else node.properties.get("package")) or \
+                    os.path.basename(directory) or directory
+                package = self.nodes.get(package_node_id)
+                if package is None:
+                    properties = {"path": directory}
+                    if import_path:
+                        properties["import_path"] = import_path
+                    package = self.nodes[package_node_id] = CodeNode(
+                        node_id=package_node_id,
+                        node_type="Package",
+                        name=name,
+                        file_path="",
+                        line_no=0,
+                        properties=properties,
+                    )
+                elif package.name.endswith("_test") and not name.endswith("_test"):
+                    # Go external test packages share the directory
+                    package.name = name
+                self._add_relation(CodeRelation(package_node_id, node_id, "CONTAINS"))
+    
+    def _interface_method_target(self, module_name: str, symbol: str,
+                                 interface_embeds: Dict[str, List[str]]) -> Tuple[Optional[str], List[str]]:
+        """查找 "Interface.Method" 調用所屬的介面節點"""
+        # Find the interface node of an "Interface.Method" call.
+        #
+        # The method must be in the interface's method set: its own methods
+        # or those of the interfaces it embeds (interface node ID -> IDs of
+        # the embedded interfaces in interface_embeds). Also returns the
+        # files of the interfaces looked at, the premises of the call edge.
+        if "." not in symbol or module_name not in self.module_definitions:
+            return None, []
+        type_name, method_name = symbol.rsplit(".", 1)
+        interface_id = self.module_definitions[module_name].get(type_name)
+        
+        seen = set()
+        pending = [interface_id]
+        while pending:
+            node_id = pending.pop()
+            node = self.nodes.get(node_id)
+            if node is None or node.node_type != "Interface" or node_id in seen:
+                continue
+            seen.add(node_id)
+            if any(signature.split("(", 1)[0] == method_name for signature in node.properties.get("methods", [])):
+                return interface_id, premise_files(self._node_file(seen_id) for seen_id in seen)
+            pending.extend(interface_embeds.get(node_id, []))
+        return None, []
+    
+    def _node_file(self, node_id: str) -> Optional[str]:
+        """節點所在檔案"""
+        # File of a node, parsed or restored from the index state
+        node = self.nodes.get(node_id)
+        if node is not None and node.file_path:
+            return node.file_path
+        return self.node_files.get(node_id)
+    
+    def _collect_interface_embeds(self) -> Dict[str, List[str]]:
+        """收集介面嵌入關係"""
+        # Interface embeddings of the pending EMBEDS entries and of interface_embeds,
+        # so calls through an interface can look up its method set
+        interface_embeds = {node_id: list(embeds) for node_id, embeds in self.interface_embeds.items()}
+        for import_info in self.pending_imports:
+            if import_info.get("type") == "EMBEDS":
+                embedded_id = self.module_definitions.get(import_info["imported_module"], {}).get(import_info["imported_name"])
+                if embedded_id:
+                    interface_embeds.setdefault(import_info["source_id"], []).append(embedded_id)
+        return interface_embeds
+    
     def _process_pending_imports(self) -> None:
         """處理所有待處理的導入關係"""
-        # Process all pending import relationships
+        # Process all pending import relationships, then derive the Package
+        # nodes and Go interface implementations from the complete index
+        self._resolve_pending_imports()
+        
+        # 每個檔案所屬的套件節點
+        # The Package node of every file
+        self._resolve_packages()
+        
+        # 隱式實作：需要完整的方法索引，因此最後處理
+        # Implicit implementations need the complete method index, so they come last
+        self._resolve_interface_implementations()
+    
+    def _resolve_pending_imports(self) -> None:
+        """解析待處理的導入關係"""
+        # Resolve the pending imports, calls and type references against the index
         # print(f"處理跨檔案依賴關係，共 {len(self.pending_imports)} 項")
         # Disabled Chinese log above.
         print(f"Processing cross-file dependencies, total {len(self.pending_imports)} items")
@@ -642,11 +1148,45 @@ class ASTParser:
                 file_node = self.nodes[file_node_id]
                 file_node.properties["module_name"] = module_name
         
+        # C# 部分類型先合併為單一節點，其餘關係才指向合併後的節點
+        # C# partial types are merged into one node first, so the other relations point at it
+        self._merge_partial_types()
+        
+        # 重新匯出在符號導入之前展開（TypeScript 的 index 檔案）
+        # Re-exports are expanded before symbol imports (TypeScript index files)
+        self._resolve_reexports()
+        
+        # Java、Kotlin 與 C# 型別名稱及 Ruby 常數：取第一個定義該名稱的候選（依編譯器或直譯器的查找順序）
+        # Java, Kotlin and C# type names and Ruby constants: take the first candidate defining the name (compiler or interpreter lookup order)
+        for import_info in self.pending_imports:
+            for module_name, name in import_info.get("candidates", ()):
+                if name in self.module_definitions.get(module_name, {}):
+                    import_info["imported_module"], import_info["imported_name"] = module_name, name
+                    break
+            # 來源在其他檔案宣告時同樣取第一個候選（Rust `impl Trait for Type`）
+            # A source declared in another file takes its first indexed candidate too (Rust `impl Trait for Type`)
+            if import_info.get("source_id") is None:
+                for module_name, name in import_info.get("source_candidates", ()):
+                    if name in self.module_definitions.get(module_name, {}):
+                        import_info["source_id"] = self.module_definitions[module_name][name]
+                        break
+        
+        # 介面嵌入關係，供經由介面的方法調用查找方法集
+        # Interface embeddings, so calls through an interface can look up its method set
+        interface_embeds = self._collect_interface_embeds()
+        
+        # Python 模組路徑索引，解析 IMPORTS 的目標檔案
+        # Python module path index, resolves the target files of IMPORTS edges
+        python_modules = self._python_module_index()
+        
         # 按模組分組處理導入信息
         # Group pending import information by source module/file
         imports_by_source_module = {}
         for import_info in self.pending_imports:
             source_id = import_info["source_id"]
+            if source_id is None:
+                # Source that is not indexed
+                continue
             if source_id not in imports_by_source_module:
                 imports_by_source_module[source_id] = []
             imports_by_source_module[source_id].append(import_info)
@@ -665,6 +1205,7 @@ class ASTParser:
                     # 檔案導入整個模組的情況
                     # Case: the file imports a module
                     module_name = import_info["imported_module"]
+                    self._add_module_import(source_id, import_info, python_modules)
                     
                     # 避免重複處理相同模組的導入
                     # Avoid processing the same module import multiple times
@@ -696,6 +1237,7 @@ class ASTParser:
                     # Case: importing a specific symbol from a module
                     module_name = import_info["imported_module"]
                     symbol_name = import_info["imported_name"]
+                    self._add_symbol_import(source_id, import_info, python_modules)
                     
                     # 檢查模組定義索引
                     # Check module definitions index
@@ -758,17 +1300,109 @@ class ASTParser:
                             )
                         )
                 
+                elif import_type == "BASE_TYPE":
+                    # C# 基底型別清單、Kotlin 超類型與 Ruby 父類別：依目標節點種類決定關係
+                    # C# base list entry, Kotlin supertype or Ruby superclass: the relation depends on the kind of the target
+                    self._add_base_type(source_id, import_info)
+                
+                elif import_type == "MIXIN":
+                    # Ruby include / extend / prepend：連到混入的模組，未索引時連到佔位節點
+                    # Ruby include / extend / prepend: link to the mixed-in module, or a placeholder when it is not indexed
+                    target_id = self.module_definitions.get(import_info["imported_module"], {}).get(
+                        import_info["imported_name"]
+                    ) or self._get_external_type_node(import_info.get("external_name") or import_info["imported_name"])
+                    self._add_relation(
+                        CodeRelation(
+                            source_id=source_id,
+                            target_id=target_id,
+                            relation_type="MIXES_IN",
+                            properties={
+                                "mixin": import_info["mixin"],
+                                "original_name": import_info.get("original_name"),
+                                "line_no": import_info.get("line_no")
+                            }
+                        )
+                    )
+                
+                elif import_type == "ASSOCIATION":
+                    # Rails 關聯：連到依慣例命名的模型類別（has_many :posts -> Post）
+                    # Rails association: link to the model class named by convention (has_many :posts -> Post)
+                    target_id = self.module_definitions.get(import_info["imported_module"], {}).get(
+                        import_info["imported_name"]
+                    )
+                    if target_id:
+                        properties = {
+                            key: import_info[key] for key in ("name", "class_name", "through", "line_no")
+                            if import_info.get(key) is not None
+                        }
+                        self._add_relation(
+                            CodeRelation(
+                                source_id=source_id,
+                                target_id=target_id,
+                                relation_type=RUBY_ASSOCIATIONS[import_info["association"]],
+                                properties=properties
+                            )
+                        )
+                
+                elif import_type == "IMPLEMENTS":
+                    # 明確宣告的介面實作（TypeScript `implements`、Rust `impl Trait for Type` 與 `#[derive]`）
+                    # Explicitly declared interface implementation (TypeScript `implements`,
+                    # Rust `impl Trait for Type` and `#[derive]`)
+                    module_name = import_info["imported_module"]
+                    interface_name = import_info["imported_name"]
+                    
+                    if module_name in self.module_definitions and interface_name in self.module_definitions[module_name]:
+                        properties = {"original_name": import_info.get("original_name"), "explicit": True}
+                        if import_info.get("derived"):
+                            properties["derived"] = True
+                        self._add_relation(
+                            CodeRelation(
+                                source_id=source_id,
+                                target_id=self.module_definitions[module_name][interface_name],
+                                relation_type="IMPLEMENTS",
+                                properties=properties
+                            )
+                        )
+                
                 elif import_type == "CALLS":
                     # 函數調用關係
                     # Function call relationship
                     module_name = import_info["imported_module"]
                     func_name = import_info["imported_name"]
                     
+                    properties = {"original_name": import_info.get("original_name")}
+                    # 調用位置行號（Go 適配器提供）
+                    # Call-site line numbers (provided by the Go adapter)
+                    if "line_no" in import_info:
+                        properties["line_no"] = import_info["line_no"]
+                        properties["call_lines"] = import_info.get("call_lines", [import_info["line_no"]])
+                    
+                    target_node_id = None
+                    interface_node_id, interface_files = self._interface_method_target(
+                        module_name, func_name, interface_embeds
+                    )
                     # 檢查模組定義索引
                     # Check module definitions index
                     if module_name in self.module_definitions and func_name in self.module_definitions[module_name]:
                         target_node_id = self.module_definitions[module_name][func_name]
-                        
+                    elif interface_node_id:
+                        # 經由介面值的方法調用：連到介面節點並記錄方法名稱
+                        # Method call through an interface value: link to the
+                        # interface node and record the method name
+                        target_node_id = interface_node_id
+                        properties["method"] = func_name.rsplit(".", 1)[1]
+                        properties["via_interface"] = True
+                        # 方法可能宣告於其他檔案的嵌入介面，記錄為前提
+                        # The method may be declared by an embedded interface in
+                        # another file, recorded as a premise
+                        properties[PREMISES_PROPERTY] = interface_files
+                    elif import_info.get("external"):
+                        # 未索引套件的調用：建立佔位節點，保留導入路徑以便之後連結
+                        # Call into a package that is not indexed: create a placeholder
+                        # node that keeps the import path so it can be linked later
+                        target_node_id = self._get_external_node(import_info["external"])
+                    
+                    if target_node_id:
                         # 創建調用關係
                         # Create a CALLS relation
                         self._add_relation(
@@ -776,7 +1410,7 @@ class ASTParser:
                                 source_id=source_id,
                                 target_id=target_node_id,
                                 relation_type="CALLS",
-                                properties={"original_name": import_info.get("original_name")}
+                                properties=properties
                             )
                         )
                 
@@ -817,6 +1451,342 @@ class ASTParser:
                                     )
                                     break
 
+                elif import_type == "METHOD_OF":
+                    # 方法與其接收者類型（同一套件的其他檔案）
+                    # Method whose receiver type is declared in another file of the same package
+                    package_key = import_info["imported_module"]
+                    type_name = import_info["imported_name"]
+                    
+                    if package_key in self.module_definitions and type_name in self.module_definitions[package_key]:
+                        type_node_id = self.module_definitions[package_key][type_name]
+                        
+                        self._add_relation(
+                            CodeRelation(
+                                source_id=type_node_id,
+                                target_id=source_id,
+                                relation_type="DEFINES"
+                            )
+                        )
+                        self._add_relation(
+                            CodeRelation(
+                                source_id=source_id,
+                                target_id=type_node_id,
+                                relation_type="METHOD_OF",
+                                properties={"receiver_kind": import_info.get("receiver_kind")}
+                            )
+                        )
+                
+                elif import_type == "EXTENSION_OF":
+                    # Kotlin 擴充函數與屬性：連到已索引的接收者類型（Kotlin 或 Java）
+                    # Kotlin extension function or property: link to its receiver type when it is indexed (Kotlin or Java)
+                    target_id = self.module_definitions.get(import_info["imported_module"], {}).get(
+                        import_info["imported_name"]
+                    )
+                    if target_id:
+                        self._add_relation(
+                            CodeRelation(
+                                source_id=source_id,
+                                target_id=target_id,
+                                relation_type="EXTENSION_OF",
+                                properties={"original_name": import_info.get("original_name")}
+                            )
+                        )
+                
+                elif import_type == "DECORATED_BY":
+                    # 裝飾器解析至程式碼庫中的符號
+                    # Decorator that resolves to a symbol in the codebase
+                    module_name = import_info["imported_module"]
+                    decorator_name = import_info["imported_name"]
+                    
+                    if module_name in self.module_definitions and decorator_name in self.module_definitions[module_name]:
+                        self._add_relation(
+                            CodeRelation(
+                                source_id=source_id,
+                                target_id=self.module_definitions[module_name][decorator_name],
+                                relation_type="DECORATED_BY",
+                                properties={
+                                    "decorator": import_info.get("decorator"),
+                                    "line_no": import_info.get("line_no")
+                                }
+                            )
+                        )
+                
+                elif import_type == "EMBEDS":
+                    # 介面嵌入其他介面，或結構體嵌入其他類型（Go）
+                    # Interface embedding another interface, or struct embedding another type (Go)
+                    module_name = import_info["imported_module"]
+                    interface_name = import_info["imported_name"]
+                    
+                    if module_name in self.module_definitions and interface_name in self.module_definitions[module_name]:
+                        properties = {"original_name": import_info.get("original_name")}
+                        if import_info.get("embed_kind"):
+                            properties["embed_kind"] = import_info["embed_kind"]
+                        self._add_relation(
+                            CodeRelation(
+                                source_id=source_id,
+                                target_id=self.module_definitions[module_name][interface_name],
+                                relation_type="EMBEDS",
+                                properties=properties
+                            )
+                        )
+                
+                elif import_type == "CONSTRAINED_BY":
+                    # 泛型型別參數的約束介面（Go）
+                    # Constraint interface of a generic type parameter (Go)
+                    module_name = import_info["imported_module"]
+                    constraint_name = import_info["imported_name"]
+                    
+                    if module_name in self.module_definitions and constraint_name in self.module_definitions[module_name]:
+                        self._add_relation(
+                            CodeRelation(
+                                source_id=source_id,
+                                target_id=self.module_definitions[module_name][constraint_name],
+                                relation_type="CONSTRAINED_BY",
+                                properties={
+                                    "type_parameters": import_info.get("type_parameters"),
+                                    "original_name": import_info.get("original_name")
+                                }
+                            )
+                        )
+                
+                elif import_type == "USES":
+                    # 函數使用套件層級的變數或常數
+                    # Function using a package-level variable or constant
+                    target_id = self.module_definitions.get(import_info["imported_module"], {}).get(import_info["imported_name"])
+                    # 同名的函數或類型不算；未重新解析的目標以節點 ID 前綴判斷
+                    # A function or type of that name does not count; a target
+                    # whose file was not re-parsed is judged by its ID prefix
+                    target = self.nodes.get(target_id)
+                    if is_variable_id(target_id) and (target is None or target.node_type in VARIABLE_NODE_TYPES):
+                        self._add_relation(
+                            CodeRelation(
+                                source_id=source_id,
+                                target_id=target_id,
+                                relation_type="USES",
+                                properties=uses_properties(import_info)
+                            )
+                        )
+    
+    def _add_base_type(self, source_id: str, import_info: Dict[str, Any]) -> None:
+        """為 C# 基底型別建立 EXTENDS、IMPLEMENTS 或 INHERITS_FROM 關係"""
+        # Link a C# type to an entry of its base list, a Kotlin type to one
+        # of its supertypes, or a Ruby class to its superclass.
+        #
+        # The base list does not say which entries are classes: an indexed
+        # interface gets IMPLEMENTS (EXTENDS from an interface), any other
+        # indexed type EXTENDS, and a base that is not indexed INHERITS_FROM
+        # to an ExternalType placeholder. The kind of a target whose file
+        # was not re-parsed is the prefix of its node ID.
+        target_id = self.module_definitions.get(import_info["imported_module"], {}).get(import_info["imported_name"])
+        properties = {"original_name": import_info.get("original_name")}
+        if target_id is None:
+            external_id = self._get_external_type_node(import_info.get("external_name") or import_info["imported_name"])
+            self._add_relation(CodeRelation(source_id, external_id, "INHERITS_FROM", properties))
+            return
+        target = self.nodes.get(target_id)
+        target_type = target.node_type if target is not None else target_id.split(":", 1)[0]
+        source = self.nodes.get(source_id)
+        if target_type == "Interface" and (source is None or source.node_type != "Interface"):
+            self._add_relation(CodeRelation(source_id, target_id, "IMPLEMENTS", dict(properties, explicit=True)))
+        else:
+            self._add_relation(CodeRelation(source_id, target_id, "EXTENDS", properties))
+    
+    def _merge_partial_types(self) -> None:
+        """合併 C# 部分類型的各個宣告"""
+        # Merge the declarations of every C# partial type into one node.
+        #
+        # Each `partial` declaration is parsed into a node of its own file.
+        # The part in the first file (by path, then line) becomes the type's
+        # node: the relations and pending entries of the other parts move
+        # over to it and their nodes are dropped, so the members of every
+        # part hang off one node and the File of each part CONTAINS it.
+        # "declared_in" lists the files of all parts; modifiers, attributes,
+        # bases and interface methods are united.
+        parts: Dict[Tuple[str, str], List[str]] = {}
+        for import_info in self.pending_imports:
+            if import_info["type"] == "PARTIAL_TYPE" and import_info["source_id"] in self.nodes:
+                key = (import_info["imported_module"], import_info["imported_name"])
+                parts.setdefault(key, []).append(import_info["source_id"])
+        
+        merged: Dict[str, str] = {}
+        for (module_name, name), node_ids in parts.items():
+            node_ids = sorted(set(node_ids), key=lambda node_id: (self.nodes[node_id].file_path,
+                                                                  self.nodes[node_id].line_no))
+            if len(node_ids) < 2:
+                continue
+            target = self.nodes[node_ids[0]]
+            for node_id in node_ids[1:]:
+                part = self.nodes.pop(node_id)
+                merged[node_id] = target.node_id
+                for key in ("modifiers", "attributes", "bases", "methods", "declared_in"):
+                    values = part.properties.get(key)
+                    if values:
+                        target.properties[key] = list(dict.fromkeys(target.properties.get(key, []) + values))
+            target.properties["declared_in"].sort()
+            self.module_definitions.setdefault(module_name, {})[name] = target.node_id
+        if not merged:
+            return
+        
+        for symbols in self.module_definitions.values():
+            for symbol, node_id in symbols.items():
+                if node_id in merged:
+                    symbols[symbol] = merged[node_id]
+        for import_info in self.pending_imports:
+            if import_info.get("source_id") in merged:
+                import_info["source_id"] = merged[import_info["source_id"]]
+        relations = []
+        for relation in self.relations:
+            if relation.source_id in merged or relation.target_id in merged:
+                relation.source_id = merged.get(relation.source_id, relation.source_id)
+                relation.target_id = merged.get(relation.target_id, relation.target_id)
+                # Nested partial types declared in several parts of their enclosing type
+                relation_key = f"{relation.source_id}|{relation.relation_type}|{relation.target_id}"
+                if relation_key in self.established_relations:
+                    continue
+                self.established_relations.add(relation_key)
+            relations.append(relation)
+        self.relations[:] = relations
+    
+    def _resolve_reexports(self) -> None:
+        """展開 `export ... from` 重新匯出的符號"""
+        # Expand symbols re-exported with `export ... from`.
+        #
+        # A re-exported name is copied from the target module into the
+        # re-exporting one, so `import { X } from './y'` links to the file
+        # that defines X even when ./y/index.ts only re-exports it. Chains of
+        # index files are followed by repeating until nothing changes;
+        # `export *` copies every name except the default export. A Rust
+        # `pub use` sets "with_members", so the "Type.method" entries of a
+        # re-exported type come along.
+        reexports = [info for info in self.pending_imports if info["type"] == "REEXPORTS"]
+        changed = True
+        while changed:
+            changed = False
+            for info in reexports:
+                symbols = self.module_definitions.get(info["imported_module"])
+                if not symbols:
+                    continue
+                exporting = self.module_definitions.setdefault(info["module_key"], {})
+                if info["imported_name"] == "*":
+                    names = {name: name for name in symbols if name != "default"}
+                elif info["imported_name"] in symbols:
+                    names = {info["imported_name"]: info.get("alias") or info["imported_name"]}
+                else:
+                    continue
+                for name, exported_as in names.items():
+                    entries = {exported_as: symbols[name]}
+                    if info.get("with_members"):
+                        entries.update({
+                            exported_as + key[len(name):]: node_id for key, node_id in symbols.items()
+                            if key.startswith(name + ".")
+                        })
+                    for key, node_id in entries.items():
+                        if key not in exporting:
+                            exporting[key] = node_id
+                            changed = True
+    
+    def _resolve_interface_implementations(self) -> None:
+        """連結 Go 類型與其滿足的介面"""
+        # Link Go types to the interfaces they satisfy.
+        #
+        # Go interfaces are satisfied implicitly: an interface's method set
+        # (its own methods plus those of interfaces reached through EMBEDS)
+        # is compared by name and normalized signature with the method set
+        # of every type. Value receiver methods belong to both T and *T,
+        # pointer receiver methods only to *T, and the "via" property of the
+        # IMPLEMENTS edge records whether T itself or only *T satisfies the
+        # interface. Empty and constraint interfaces, and interfaces embedding
+        # one that is not indexed, are skipped.
+        #
+        # The edges are derived from the whole index rather than a single
+        # file, so they are marked "structural" for incremental re-indexing.
+        # Their premises are the files of the type, of its methods and of
+        # the interface and the interfaces it embeds: a change to any of
+        # them may add or remove the edge.
+        interfaces = {
+            node_id: node for node_id, node in self.nodes.items()
+            if node.node_type == "Interface" and node.file_path.endswith(".go")
+        }
+        if not interfaces:
+            return
+        
+        embedded: Dict[str, List[str]] = {}
+        # type node ID -> receiver kind -> method name -> signature
+        method_sets: Dict[str, Dict[str, Dict[str, str]]] = {}
+        # type node ID -> files of the type and its methods
+        method_files: Dict[str, Set[str]] = {}
+        for relation in self.relations:
+            if relation.relation_type == "EMBEDS" and relation.source_id in interfaces and relation.target_id in interfaces:
+                embedded.setdefault(relation.source_id, []).append(relation.target_id)
+            elif relation.relation_type == "METHOD_OF":
+                method = self.nodes.get(relation.source_id)
+                if method is None or "signature" not in method.properties or not method.file_path.endswith(".go"):
+                    continue
+                receiver_kind = method.properties.get("receiver_kind") or "value"
+                method_sets.setdefault(relation.target_id, {"value": {}, "pointer": {}})[receiver_kind][method.name] = (
+                    method.properties["signature"]
+                )
+                method_files.setdefault(relation.target_id, {self._node_file(relation.target_id)}).add(method.file_path)
+        
+        flattened: Dict[str, Optional[Dict[str, str]]] = {}
+        # interface node ID -> files of the interface and the interfaces it embeds
+        interface_files: Dict[str, Set[str]] = {}
+        
+        def flatten(interface_id: str, visiting: frozenset) -> Optional[Dict[str, str]]:
+            if interface_id in flattened:
+                return flattened[interface_id]
+            node = interfaces[interface_id]
+            embeds = embedded.get(interface_id, [])
+            files = interface_files.setdefault(interface_id, {node.file_path})
+            methods: Optional[Dict[str, str]] = None
+            if (interface_id not in visiting and not node.properties.get("constraint")
+                    and len(embeds) >= len(node.properties.get("embeds", []))):
+                methods = {signature.split("(", 1)[0]: signature for signature in node.properties.get("methods", [])}
+                for embedded_id in embeds:
+                    inner = flatten(embedded_id, visiting | {interface_id})
+                    files.update(interface_files.get(embedded_id, ()))
+                    if inner is None:
+                        methods = None
+                        break
+                    methods.update(inner)
+            flattened[interface_id] = methods
+            return methods
+        
+        provenance = {SOURCE_PROPERTY: SOURCE_INTERFACE_MATCH, CONFIDENCE_PROPERTY: INTERFACE_MATCH_CONFIDENCE}
+        for interface_id in interfaces:
+            required = flatten(interface_id, frozenset())
+            if not required:
+                continue
+            
+            for type_id, receivers in method_sets.items():
+                premises = premise_files(interface_files[interface_id] | method_files[type_id])
+                pointer_methods = {**receivers["value"], **receivers["pointer"]}
+                missing = sorted(
+                    signature for name, signature in required.items() if pointer_methods.get(name) != signature
+                )
+                if not missing:
+                    via_value = all(receivers["value"].get(name) == signature for name, signature in required.items())
+                    self._add_relation(
+                        CodeRelation(
+                            source_id=type_id,
+                            target_id=interface_id,
+                            relation_type="IMPLEMENTS",
+                            properties={"via": "value" if via_value else "pointer", "structural": True,
+                                        PREMISES_PROPERTY: premises, **provenance}
+                        )
+                    )
+                elif (self.report_near_miss_implementations
+                      and len(missing) <= NEAR_MISS_MAX_MISSING and len(missing) < len(required)):
+                    self._add_relation(
+                        CodeRelation(
+                            source_id=type_id,
+                            target_id=interface_id,
+                            relation_type="NEAR_IMPLEMENTS",
+                            properties={"missing_methods": missing, "structural": True,
+                                        PREMISES_PROPERTY: premises, **provenance}
+                        )
+                    )
+
 
 # 使用範例
 # Usage example
@@ -0,0 +1,436 @@
+"""Protocol Buffers (.proto) parser.
+
+Protobuf files have no ast-grep grammar, so they are read with a small
+tokenizer and a recursive descent over the declarations the indexer
+needs. The parser works the same with and without USE_AST_GREP.
+
+Nodes:
+    - File: "syntax", the proto "package", "imports" and the go_package /
+      java_package options
+    - Service, with its rpcs listed in "rpcs"
+    - Rpc: one per rpc of a service, with "full_method"
+      ("/pkg.Service/Method", the path generated stubs call),
+      "request" / "response" and their streaming flags
+    - Message, with its fields listed in "fields"; nested messages hang
+      under their message
+    - Enum, with its values in "members"
+
+Relations:
+    - File -[:DEFINES_SERVICE]-> Service -[:DEFINES]-> Rpc
+    - File -[:DEFINES_MESSAGE]-> Message -[:DEFINES]-> nested Message or Enum
+    - File -[:CONTAINS]-> top-level Enum
+
+Generated stubs are linked to the rpcs they call by src.linking.
+"""
+
+import logging
+import os
+import re
+from typing import Any, Dict, List, Optional, Set, Tuple
+
+from src.ast_parser.doc_comments import normalize_comment
+from src.ast_parser.parser import CodeNode, CodeRelation
+
+logger = logging.getLogger(__name__)
+
+PROTO_EXTENSIONS = (".proto",)
+
+# Comments, strings, identifiers (dotted, possibly fully qualified), numbers and punctuation
+_TOKEN = re.compile(
+    r"(?P<comment>//[^\n]*|/\*.*?\*/)"
+    r"|(?P<string>\"(?:[^\"\\\n]|\\.)*\"|'(?:[^'\\\n]|\\.)*')"
+    r"|(?P<ident>\.?[A-Za-z_][\w]*(?:\.[A-Za-z_][\w]*)*)"
+    r"|(?P<number>-?[0-9][\w.+-]*)"
+    r"|(?P<symbol>[{}()\[\]<>;=,:])"
+    r"|(?P<space>\s+)"
+    r"|(?P<other>.)",
+    re.DOTALL,
+)
+
+# File options kept on the File node
+FILE_OPTIONS = ("go_package", "java_package")
+
+
+class _Token:
+    __slots__ = ("kind", "text", "line", "end_line")
+    
+    def __init__(self, kind: str, text: str, line: int, end_line: int):
+        self.kind = kind
+        self.text = text
+        self.line = line
+        self.end_line = end_line
+
+
+def tokenize(source: str) -> Tuple[List[_Token], List[_Token]]:
+    """Split a proto file into (tokens, comments), each with 1-based start and end lines."""
+    tokens: List[_Token] = []
+    comments: List[_Token] = []
+    line = 1
+    for match in _TOKEN.finditer(source):
+        kind = match.lastgroup
+        text = match.group()
+        end_line = line + text.count("\n")
+        if kind == "comment":
+            comments.append(_Token(kind, text, line, end_line))
+        elif kind != "space":
+            tokens.append(_Token(kind, text, line, end_line))
+        line = end_line
+    return tokens, comments
+
+
+class ProtoParser:
+    """Parser for .proto files, producing the node and relation format of ASTParser."""
+    
+    # Error the last parse_file swallowed, read by parse_with_limits
+    parse_error = None
+    
+    def __init__(self):
+        self.nodes: Dict[str, CodeNode] = {}
+        self.relations: List[CodeRelation] = []
+        self.current_file: str = ""
+        self.module_definitions: Dict[str, Dict[str, str]] = {}
+        self.pending_imports: List[Dict[str, Any]] = []
+        self.module_to_file: Dict[str, str] = {}
+        self.established_relations: Set[str] = set()
+        
+        self._tokens: List[_Token] = []
+        self._position = 0
+        self._comments: Dict[int, _Token] = {}
+        self._package = ""
+    
+    def parse_file(self, file_path: str, build_index: bool = False) -> Tuple[Dict[str, CodeNode], List[CodeRelation]]:
+        """
+        Parse a single .proto file.
+        
+        Args:
+            file_path: Path to the file
+            build_index: Whether results accumulate across files (as for the other parsers)
+        
+        Returns:
+            Tuple of (nodes dictionary, relations list)
+        """
+        self.current_file = file_path
+        
+        # Reset nodes and relations if not building an index (standalone parse)
+        if not build_index:
+            self.nodes = {}
+            self.relations = []
+        
+        try:
+            with open(file_path, "r", encoding="utf-8") as handle:
+                source = handle.read()
+        except (OSError, UnicodeDecodeError) as e:
+            logger.error(f"Error reading proto file {file_path}: {e}")
+            self.parse_error = e
+            return self.nodes, self.relations
+        
+        self._tokens, comments = tokenize(source)
+        self._comments = {comment.end_line: comment for comment in comments}
+        self._position = 0
+        self._package = ""
+        
+        file_node_id = f"file:{file_path}"
+        self.nodes[file_node_id] = CodeNode(
+            node_id=file_node_id,
+            node_type="File",
+            name=os.path.basename(file_path),
+            file_path=file_path,
+            line_no=0,
+            properties={"language": "proto"},
+        )
+        # Proto packages are not Python modules: nothing is added to module_definitions,
+        # so imports of the other parsers never resolve to proto declarations
+        self._parse_declarations(file_node_id)
+        
+        return self.nodes, self.relations
+    
+    # -- Token stream --------------------------------------------------------
+    
+    def _peek(self, offset: int = 0) -> Optional[_Token]:
+        index = self._position + offset
+        return self._tokens[index] if index < len(self._tokens) else None
+    
+    def _next(self) -> Optional[_Token]:
+        token = self._peek()
+        if token is not None:
+            self._position += 1
+        return token
+    
+    def _accept(self, text: str) -> bool:
+        token = self._peek()
+        if token is not None and token.text == text:
+            self._position += 1
+            return True
+        return False
+    
+    def _skip_statement(self) -> int:
+        """Skip to the end of the current statement or block; return its last line."""
+        depth = 0
+        last_line = self._peek().line if self._peek() else 0
+        while True:
+            token = self._next()
+            if token is None:
+                return last_line
+            last_line = token.end_line
+            if token.text in ("{", "[", "(", "<"):
+                depth += 1
+            elif token.text in ("}", "]", ")", ">"):
+                depth -= 1
+                if depth <= 0 and token.text == "}":
+                    return last_line
+            elif token.text == ";" and depth == 0:
+                return last_line
+    
+    def _skip_options(self) -> None:
+        """Skip a `[...]` option list."""
+        if self._peek() is not None and self._peek().text == "[":
+            depth = 0
+            while True:
+                token = self._next()
+                if token is None:
+                    return
+                if token.text == "[":
+                    depth += 1
+                elif token.text == "]":
+                    depth -= 1
+                    if depth == 0:
+                        return
+    
+    def _doc(self, line: int) -> Optional[str]:
+        """Comment block that ends on the line above `line`."""
+        block = []
+        comment = self._comments.get(line - 1)
+        while comment is not None:
+            block.insert(0, comment.text)
+            comment = self._comments.get(comment.line - 1)
+        doc = normalize_comment("\n".join(block)) if block else ""
+        return doc or None
+    
+    # -- Declarations --------------------------------------------------------
+    
+    def _qualified(self, *names: str) -> str:
+        return ".".join(part for part in (self._package,) + names if part)
+    
+    def _add_node(self, node_type: str, name: str, line: int, end_line: int, parents: Tuple[str, ...],
+                  properties: Dict[str, Any]) -> str:
+        node_id = f"{node_type}:{self.current_file}:{name}:{line}"
+        properties["qualified_name"] = self._qualified(*parents, name)
+        doc = self._doc(line)
+        if doc:
+            properties["doc"] = doc
+        self.nodes[node_id] = CodeNode(
+            node_id=node_id,
+            node_type=node_type,
+            name=name,
+            file_path=self.current_file,
+            line_no=line,
+            end_line_no=end_line,
+            properties=properties,
+        )
+        return node_id
+    
+    def _parse_declarations(self, file_node_id: str) -> str:
+        """Top-level statements; returns the proto package."""
+        file_node = self.nodes[file_node_id]
+        imports: List[str] = []
+        while self._peek() is not None:
+            token = self._peek()
+            if token.text == "syntax" or token.text == "edition":
+                self._next()
+                self._accept("=")
+                value = self._next()
+                if value is not None and value.kind == "string":
+                    file_node.properties[token.text] = value.text[1:-1]
+                self._skip_statement()
+            elif token.text == "package":
+                self._next()
+                name = self._next()
+                if name is not None and name.kind == "ident":
+                    self._package = name.text
+                    file_node.properties["package"] = name.text
+                self._skip_statement()
+            elif token.text == "import":
+                self._next()
+                if self._peek() is not None and self._peek().text in ("public", "weak"):
+                    self._next()
+                path = self._next()
+                if path is not None and path.kind == "string":
+                    imports.append(path.text[1:-1])
+                self._skip_statement()
+            elif token.text == "option":
+                self._next()
+                name = self._next()
+                self._accept("=")
+                value = self._next()
+                if name is not None and name.text in FILE_OPTIONS and value is not None and value.kind == "string":
+                    file_node.properties[name.text] = value.text[1:-1]
+                self._skip_statement()
+            elif token.text == "message":
+                self._parse_message(file_node_id, "DEFINES_MESSAGE", ())
+            elif token.text == "enum":
+                self._parse_enum(file_node_id, "CONTAINS", ())
+            elif token.text == "service":
+                self._parse_service(file_node_id)
+            else:
+                self._skip_statement()
+        if imports:
+            file_node.properties["imports"] = imports
+        return self._package
+    
+    def _parse_message(self, parent_id: str, relation_type: str, parents: Tuple[str, ...]) -> None:
+        keyword = self._next()
+        name = self._next()
+        if name is None or name.kind != "ident" or not self._accept("{"):
+            self._skip_statement()
+            return
+        node_id = self._add_node("Message", name.text, keyword.line, keyword.line, parents, {})
+        self._add_relation(CodeRelation(parent_id, node_id, relation_type))
+        
+        fields: List[str] = []
+        end_line = self._parse_message_body(node_id, parents + (name.text,), fields)
+        node = self.nodes[node_id]
+        node.end_line_no = end_line
+        node.properties["fields"] = fields
+    
+    def _parse_message_body(self, node_id: str, scope: Tuple[str, ...], fields: List[str]) -> int:
+        """Fields, oneofs and nested declarations up to the closing brace; returns its line."""
+        while self._peek() is not None:
+            token = self._peek()
+            if token.text == "}":
+                self._next()
+                return token.line
+            if token.text == "message":
+                self._parse_message(node_id, "DEFINES", scope)
+            elif token.text == "enum":
+                self._parse_enum(node_id, "DEFINES", scope)
+            elif token.text == "oneof" and self._peek(2) is not None and self._peek(2).text == "{":
+                self._position += 3
+                self._parse_message_body(node_id, scope, fields)
+            elif token.text in ("option", "reserved", "extensions", "extend", ";"):
+                self._skip_statement()
+            else:
+                field = self._parse_field()
+                if field:
+                    fields.append(field)
+        return self._tokens[-1].end_line if self._tokens else 0
+    
+    def _parse_field(self) -> Optional[str]:
+        """One field declaration as written, without options ("repeated string names = 2")."""
+        parts: List[str] = []
+        while self._peek() is not None and self._peek().text not in ("=", ";", "{", "}"):
+            token = self._next()
+            if token.text in ("<", ">", ","):
+                parts[-1:] = [(parts[-1] if parts else "") + (", " if token.text == "," else token.text)]
+            elif parts and parts[-1].endswith(("<", ", ")):
+                parts[-1] += token.text
+            else:
+                parts.append(token.text)
+        if not self._accept("="):
+            self._skip_statement()
+            return None
+        number = self._next()
+        self._skip_options()
+        self._accept(";")
+        if len(parts) < 2 or number is None:
+            return None
+        return f"{' '.join(parts)} = {number.text}"
+    
+    def _parse_enum(self, parent_id: str, relation_type: str, parents: Tuple[str, ...]) -> None:
+        keyword = self._next()
+        name = self._next()
+        if name is None or name.kind != "ident" or not self._accept("{"):
+            self._skip_statement()
+            return
+        members: List[str] = []
+        end_line = keyword.line
+        while self._peek() is not None:
+            token = self._next()
+            if token.text == "}":
+                end_line = token.line
+                break
+            if token.text in ("option", "reserved"):
+                self._skip_statement()
+            elif token.kind == "ident" and self._accept("="):
+                members.append(token.text)
+                self._skip_statement()
+        node_id = self._add_node("Enum", name.text, keyword.line, end_line, parents, {"members": members})
+        self._add_relation(CodeRelation(parent_id, node_id, relation_type))
+    
+    def _parse_service(self, file_node_id: str) -> None:
+        keyword = self._next()
+        name = self._next()
+        if name is None or name.kind != "ident" or not self._accept("{"):
+            self._skip_statement()
+            return
+        service_id = self._add_node("Service", name.text, keyword.line, keyword.line, (), {})
+        self._add_relation(CodeRelation(file_node_id, service_id, "DEFINES_SERVICE"))
+        service = self.nodes[service_id]
+        
+        rpcs: List[str] = []
+        while self._peek() is not None:
+            token = self._peek()
+            if token.text == "}":
+                self._next()
+                service.end_line_no = token.line
+                break
+            if token.text == "rpc":
+                rpc = self._parse_rpc(service_id, name.text)
+                if rpc:
+                    rpcs.append(rpc)
+            else:
+                self._skip_statement()
+        service.properties["rpcs"] = rpcs
+    
+    def _parse_rpc(self, service_id: str, service_name: str) -> Optional[str]:
+        """One rpc of a service; returns its display signature."""
+        keyword = self._next()
+        name = self._next()
+        if name is None or name.kind != "ident":
+            self._skip_statement()
+            return None
+        request, client_streaming = self._rpc_message()
+        if not self._accept("returns"):
+            self._skip_statement()
+            return None
+        response, server_streaming = self._rpc_message()
+        end_line = self._skip_statement() if self._peek() is not None and self._peek().text in ("{", ";") else keyword.line
+        if request is None or response is None:
+            return None
+        
+        signature = (f"{name.text}({'stream ' if client_streaming else ''}{request}) "
+                     f"returns ({'stream ' if server_streaming else ''}{response})")
+        properties = {
+            "service": self._qualified(service_name),
+            "full_method": f"/{self._qualified(service_name)}/{name.text}",
+            "request": request,
+            "response": response,
+            "signature": signature,
+        }
+        if client_streaming:
+            properties["client_streaming"] = True
+        if server_streaming:
+            properties["server_streaming"] = True
+        rpc_id = self._add_node("Rpc", name.text, keyword.line, end_line, (service_name,), properties)
+        self._add_relation(CodeRelation(service_id, rpc_id, "DEFINES"))
+        return signature
+    
+    def _rpc_message(self) -> Tuple[Optional[str], bool]:
+        """`( [stream] Type )` of an rpc: the message type and whether it is streamed."""
+        if not self._accept("("):
+            return None, False
+        streaming = False
+        if self._peek() is not None and self._peek().text == "stream" and self._peek(1) is not None \
+                and self._peek(1).text != ")":
+            self._next()
+            streaming = True
+        message = self._next()
+        self._accept(")")
+        if message is None or message.kind != "ident":
+            return None, streaming
+        return message.text, streaming
+    
+    def _add_relation(self, relation: CodeRelation) -> None:
+        key = f"{relation.source_id}|{relation.relation_type}|{relation.target_id}"
+        if key not in self.established_relations:
+            self.established_relations.add(key)
+            self.relations.append(relation)
@@ -0,0 +1,145 @@
+"""
+Edge provenance.
+
+Every relationship records which indexer pass created it:
+- source: "ast" for facts read from the syntax tree, "derived:<pass>"
+  for edges aggregated from other edges (package and file dependencies),
+  "heuristic:<pass>" for the passes that match by name or signature
+  (Go interface satisfaction, cross-language and cross-repository
+  linking, test links), and "heuristic:fallback" for the CONTAINS edges
+  of symbols the fallback indexer matched by regex
+- confidence: in [0, 1]; 1.0 for AST and derived facts
+- indexer_version: the INDEX_STATE_VERSION of the indexer that wrote it
+
+Edges a pass derives from more than their endpoints' files also record
+"premises", the files whose contents they follow from: the files of a
+Go type's methods and of the interface and the interfaces it embeds for
+IMPLEMENTS, the files of both ends and of every same-name service or
+exporter for CROSS_LANG_CALLS, the importing files of a package
+DEPENDS_ON edge. An
+incremental run deletes the stored structural edges whose premises
+include a changed, deleted or re-resolved file and writes the recomputed
+ones in their place (see src/indexing/incremental.py); a CALLS edge
+through a Go interface records the files of the interfaces its method
+was looked up in, so a change to an embedded interface re-resolves the
+call.
+
+Graphs written before provenance existed have none of these properties;
+edge_provenance infers them from the relationship type and properties.
+"""
+
+import json
+from typing import Any, Dict, Iterable, List, Optional
+
+SOURCE_PROPERTY = "source"
+CONFIDENCE_PROPERTY = "confidence"
+INDEXER_VERSION_PROPERTY = "indexer_version"
+PREMISES_PROPERTY = "premises"
+
+PROVENANCE_PROPERTIES = (SOURCE_PROPERTY, CONFIDENCE_PROPERTY, INDEXER_VERSION_PROPERTY)
+
+SOURCE_AST = "ast"
+SOURCE_INTERFACE_MATCH = "heuristic:interface_match"
+SOURCE_TEST_LINK = "heuristic:test_link"
+SOURCE_REPO_LINK = "heuristic:repo_link"
+SOURCE_PACKAGE_IMPORTS = "derived:package_imports"
+SOURCE_FILE_DEPENDENCIES = "derived:file_dependencies"
+
+# Go types satisfy interfaces by method names and normalized signatures
+INTERFACE_MATCH_CONFIDENCE = 0.9
+
+# Cross-repository edges through an import path, and through a module name prefix
+REPO_LINK_CONFIDENCE = 1.0
+REPO_PREFIX_LINK_CONFIDENCE = 0.8
+
+# Confidence of a TESTS edge by the label get_tests_for reports; edges written before provenance stored the label
+TEST_LINK_CONFIDENCE = {"high": 0.9, "low": 0.5}
+
+
+def link_source(matcher: str) -> str:
+    """Source of the CROSS_LANG_CALLS edges of a matcher, e.g. "heuristic:grpc_link"."""
+    return f"heuristic:{matcher}_link"
+
+
+def premise_files(file_paths: Iterable[Optional[str]]) -> List[str]:
+    """Sorted distinct file paths for the premises property, empty ones left out."""
+    return sorted({file_path for file_path in file_paths if file_path})
+
+
+def edge_premises(properties: Dict[str, Any]) -> Optional[List[str]]:
+    """Premise files of a stored relationship, a JSON string like every list property; None when it has none."""
+    value = properties.get(PREMISES_PROPERTY)
+    if isinstance(value, str):
+        try:
+            value = json.loads(value)
+        except ValueError:
+            return None
+    return list(value) if isinstance(value, list) else None
+
+
+def with_provenance(properties: Dict[str, Any], indexer_version: int) -> Dict[str, Any]:
+    """Copy of relationship properties with the AST defaults for the provenance a pass did not set."""
+    stamped = dict(properties)
+    stamped.setdefault(SOURCE_PROPERTY, SOURCE_AST)
+    stamped.setdefault(CONFIDENCE_PROPERTY, 1.0)
+    stamped[INDEXER_VERSION_PROPERTY] = indexer_version
+    return stamped
+
+
+def _legacy_source(relation_type: str, properties: Dict[str, Any]) -> str:
+    if relation_type == "CROSS_LANG_CALLS":
+        return link_source(properties.get("matcher") or "cross_lang")
+    if relation_type == "TESTS":
+        return SOURCE_TEST_LINK
+    if relation_type == "DEPENDS_ON_FILE":
+        return SOURCE_FILE_DEPENDENCIES
+    if relation_type == "DEPENDS_ON" and properties.get("cross_repo"):
+        return SOURCE_REPO_LINK
+    if relation_type == "DEPENDS_ON" and properties.get("structural"):
+        return SOURCE_PACKAGE_IMPORTS
+    if relation_type in ("IMPLEMENTS", "NEAR_IMPLEMENTS") and properties.get("structural"):
+        return SOURCE_INTERFACE_MATCH
+    return SOURCE_AST
+
+
+def edge_confidence(properties: Dict[str, Any]) -> float:
+    """Confidence of a stored relationship, 1.0 when it records none."""
+    value = properties.get(CONFIDENCE_PROPERTY)
+    if isinstance(value, bool):
+        return 1.0
+    if isinstance(value, (int, float)):
+        return float(value)
+    if isinstance(value, str):
+        return TEST_LINK_CONFIDENCE.get(value, 1.0)
+    return 1.0
+
+
+def edge_provenance(relationship: Dict[str, Any]) -> Dict[str, Any]:
+    """
+    Provenance fields of a relationship record, for tool output.
+    
+    Returns:
+        {"source", "confidence", "indexer_version"}; the version is None for edges written without it
+    """
+    properties = relationship.get("properties") or {}
+    return {
+        SOURCE_PROPERTY: properties.get(SOURCE_PROPERTY) or _legacy_source(relationship.get("type", ""), properties),
+        CONFIDENCE_PROPERTY: edge_confidence(properties),
+        INDEXER_VERSION_PROPERTY: properties.get(INDEXER_VERSION_PROPERTY),
+    }
+
+
+def check_min_confidence(min_confidence: Any) -> float:
+    """
+    Validate a min_confidence filter.
+    
+    Raises:
+        ValueError: for a value that is not a number in [0, 1]
+    """
+    try:
+        value = float(min_confidence)
+    except (TypeError, ValueError):
+        raise ValueError(f"min_confidence must be a number between 0 and 1, got {min_confidence!r}")
+    if not 0.0 <= value <= 1.0:
+        raise ValueError("min_confidence must be between 0 and 1")
+    return value
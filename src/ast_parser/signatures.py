@@ -0,0 +1,270 @@
+"""
+Structured signatures of functions and methods.
+
+Function and Method nodes record their declaration in three properties:
+
+- signature_json: JSON object with the ordered "parameters" ({name, type,
+  default, variadic}; TypeScript optional parameters add optional), the
+  "returns" ({name, type}; Go results can be named, or several) and the
+  "type_parameters" ({name, constraint}) of Go and TypeScript generics and
+  Java type parameters. Types and defaults are the source text with
+  whitespace collapsed, None where the source gives none.
+- arity: number of parameters, so arity filters need no JSON decoding
+- signature: the declaration on one line for display, e.g.
+  "load(path: str, retries: int = 3) -> Config". Go methods and Java
+  methods keep their normalized signature (parameter types only, Go types
+  qualified by import path), which IMPLEMENTS edges and overload lookups
+  compare.
+
+The bound self / cls parameter of a Python method is left out, like the
+receiver of a Go method, so parameter positions mean the same in every
+language.
+
+The parsers build these with python_signature, typescript_signature (for
+ast-grep nodes and tree-sitter nodes wrapped to the same interface) or
+signature_properties directly; matches_signature filters nodes by them.
+"""
+
+import ast
+import json
+from typing import Any, Dict, Iterable, List, Optional
+
+# Decorators after which a Python method has no bound first parameter
+UNBOUND_DECORATORS = ("staticmethod",)
+
+
+def collapse(text: Optional[str]) -> Optional[str]:
+    """Source text on one line, None for missing or blank text."""
+    if text is None:
+        return None
+    return " ".join(text.split()) or None
+
+
+def parameter(name: Optional[str], type: Optional[str] = None, default: Optional[str] = None,
+              variadic: bool = False, **extra: Any) -> Dict[str, Any]:
+    """One entry of the "parameters" list."""
+    entry = {"name": name, "type": collapse(type), "default": collapse(default), "variadic": variadic}
+    entry.update(extra)
+    return entry
+
+
+def signature_properties(parameters: List[Dict[str, Any]], returns: Iterable[Dict[str, Any]] = (),
+                         type_parameters: Iterable[Dict[str, Any]] = (),
+                         display: Optional[str] = None) -> Dict[str, Any]:
+    """
+    Node properties of a signature: signature_json, arity and, given a display line, signature.
+    
+    Args:
+        parameters: Ordered parameter entries, see parameter()
+        returns: Result entries, {"name", "type"}
+        type_parameters: Type parameter entries, {"name", "constraint"}
+        display: The declaration on one line
+    """
+    properties: Dict[str, Any] = {
+        "signature_json": json.dumps({
+            "parameters": parameters,
+            "returns": list(returns),
+            "type_parameters": list(type_parameters),
+        }),
+        "arity": len(parameters),
+    }
+    if display:
+        properties["signature"] = display
+    return properties
+
+
+def load_signature(properties: Dict[str, Any]) -> Optional[Dict[str, Any]]:
+    """The decoded signature_json of a node, None if it has none."""
+    raw = properties.get("signature_json")
+    if not raw:
+        return None
+    try:
+        signature = json.loads(raw)
+    except (TypeError, ValueError):
+        return None
+    return signature if isinstance(signature, dict) else None
+
+
+def matches_signature(properties: Dict[str, Any], arity: Optional[int] = None, param_type: Optional[str] = None,
+                      param_index: Optional[int] = None) -> bool:
+    """
+    Whether a node's signature passes the filters of find_symbol.
+    
+    Args:
+        properties: Node properties
+        arity: Exact number of parameters
+        param_type: Substring of a parameter type, e.g. "context.Context"
+        param_index: Position (from 0) of the parameter param_type must match, any position if None
+    """
+    signature = load_signature(properties)
+    if signature is None:
+        return False
+    parameters = signature.get("parameters") or []
+    if arity is not None and len(parameters) != arity:
+        return False
+    if param_type:
+        candidates = parameters if param_index is None else parameters[param_index:param_index + 1]
+        return any(param_type in (entry.get("type") or "") for entry in candidates)
+    return True
+
+
+def _format_parameters(parameters: List[Dict[str, Any]], pep8_defaults: bool = False) -> str:
+    """Parameter list for display; pep8_defaults writes untyped defaults as name=value."""
+    parts = []
+    for entry in parameters:
+        text = entry["name"] or ""
+        if entry.get("optional"):
+            text += "?"
+        if entry["type"]:
+            text = f"{text}: {entry['type']}" if text else entry["type"]
+        if entry["default"] is not None:
+            text += f"={entry['default']}" if pep8_defaults and not entry["type"] else f" = {entry['default']}"
+        parts.append(text)
+    return ", ".join(parts)
+
+
+# Python
+
+def _unparse(node: Optional[ast.AST]) -> Optional[str]:
+    return ast.unparse(node) if node is not None else None
+
+
+def python_signature(node: ast.AST, is_method: bool = False) -> Dict[str, Any]:
+    """
+    Signature properties of a Python function definition.
+    
+    Args:
+        node: ast.FunctionDef or ast.AsyncFunctionDef
+        is_method: The function is defined in a class, so its first parameter is bound
+            (unless it is a staticmethod)
+    """
+    args = node.args
+    positional = list(args.posonlyargs) + list(args.args)
+    defaults: List[Optional[ast.expr]] = [None] * (len(positional) - len(args.defaults)) + list(args.defaults)
+    
+    parameters = []
+    display = []
+    bound = is_method and not any(
+        isinstance(decorator, ast.Name) and decorator.id in UNBOUND_DECORATORS for decorator in node.decorator_list
+    )
+    for index, (arg, default) in enumerate(zip(positional, defaults)):
+        if index > 0 or not bound:
+            parameters.append(parameter(arg.arg, _unparse(arg.annotation), _unparse(default)))
+            display.append(parameters[-1])
+        if args.posonlyargs and index == len(args.posonlyargs) - 1 and display:
+            display.append(None)
+    if args.vararg is not None:
+        parameters.append(parameter(args.vararg.arg, _unparse(args.vararg.annotation), variadic=True))
+        display.append(dict(parameters[-1], name="*" + args.vararg.arg))
+    elif args.kwonlyargs:
+        display.append({"name": "*", "type": None, "default": None})
+    for arg, default in zip(args.kwonlyargs, args.kw_defaults):
+        parameters.append(parameter(arg.arg, _unparse(arg.annotation), _unparse(default)))
+        display.append(parameters[-1])
+    if args.kwarg is not None:
+        parameters.append(parameter(args.kwarg.arg, _unparse(args.kwarg.annotation), variadic=True))
+        display.append(dict(parameters[-1], name="**" + args.kwarg.arg))
+    
+    returns = [{"name": None, "type": collapse(_unparse(node.returns))}] if node.returns is not None else []
+    type_parameters = [
+        {"name": type_param.name, "constraint": collapse(_unparse(getattr(type_param, "bound", None)))}
+        for type_param in getattr(node, "type_params", None) or []
+    ]
+    
+    text = node.name
+    if type_parameters:
+        text += "[" + ", ".join(
+            f"{entry['name']}: {entry['constraint']}" if entry["constraint"] else entry["name"]
+            for entry in type_parameters
+        ) + "]"
+    entries = [entry if entry is not None else {"name": "/", "type": None, "default": None} for entry in display]
+    text += f"({_format_parameters(entries, pep8_defaults=True)})"
+    if returns:
+        text += f" -> {returns[0]['type']}"
+    return signature_properties(parameters, returns, type_parameters, display=text)
+
+
+# TypeScript / JavaScript
+
+def _annotation(node) -> Optional[str]:
+    """Type of a type_annotation node (": T"), or the text of any other type node."""
+    if node is None:
+        return None
+    text = collapse(node.text())
+    if text and node.kind() in ("type_annotation", "asserts_annotation", "type_predicate_annotation"):
+        text = collapse(text[1:]) if text.startswith(":") else text
+    return text
+
+
+def _typescript_parameter(node) -> Optional[Dict[str, Any]]:
+    kind = node.kind()
+    value = None
+    type_node = None
+    if kind in ("required_parameter", "optional_parameter"):
+        pattern = node.field("pattern")
+        type_node = node.field("type")
+        value = node.field("value")
+    elif kind == "assignment_pattern":
+        pattern = node.field("left")
+        value = node.field("right")
+    elif kind in ("identifier", "rest_pattern", "object_pattern", "array_pattern"):
+        pattern = node
+    else:
+        return None
+    if pattern is None:
+        return None
+    
+    name = collapse(pattern.text())
+    variadic = pattern.kind() == "rest_pattern"
+    if variadic and name.startswith("..."):
+        name = name[3:]
+    extra = {"optional": True} if kind == "optional_parameter" else {}
+    return parameter(name, _annotation(type_node), value.text() if value is not None else None, variadic, **extra)
+
+
+def typescript_signature(func_node, name: str) -> Dict[str, Any]:
+    """
+    Signature properties of a TypeScript or JavaScript function, method or arrow function.
+    
+    Args:
+        func_node: ast-grep node, or any node with the same kind / field / children / text methods
+        name: Name of the function
+    """
+    parameters = []
+    parameters_node = func_node.field("parameters")
+    if parameters_node is not None:
+        for child in parameters_node.children():
+            entry = _typescript_parameter(child)
+            if entry is not None:
+                parameters.append(entry)
+    elif func_node.field("parameter") is not None:
+        # `x => ...` has a bare identifier instead of a parameter list
+        parameters.append(parameter(collapse(func_node.field("parameter").text())))
+    
+    return_type = _annotation(func_node.field("return_type"))
+    returns = [{"name": None, "type": return_type}] if return_type else []
+    
+    type_parameters = []
+    type_parameters_node = func_node.field("type_parameters")
+    if type_parameters_node is not None:
+        for child in type_parameters_node.children():
+            if child.kind() != "type_parameter" or child.field("name") is None:
+                continue
+            constraint = child.field("constraint")
+            constraint_text = collapse(constraint.text()) if constraint is not None else None
+            if constraint_text and constraint_text.startswith("extends "):
+                constraint_text = constraint_text[len("extends "):]
+            type_parameters.append({"name": child.field("name").text(), "constraint": constraint_text})
+    
+    display = name
+    if type_parameters:
+        display += "<" + ", ".join(
+            f"{entry['name']} extends {entry['constraint']}" if entry["constraint"] else entry["name"]
+            for entry in type_parameters
+        ) + ">"
+    display += "(" + _format_parameters([
+        dict(entry, name="..." + entry["name"]) if entry["variadic"] else entry for entry in parameters
+    ]) + ")"
+    if return_type:
+        display += f": {return_type}"
+    return signature_properties(parameters, returns, type_parameters, display=display)
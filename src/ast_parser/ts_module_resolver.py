@@ -0,0 +1,170 @@
+"""
+TypeScript module specifier resolution.
+
+TypeScript 模組路徑解析
+Maps the specifier of an import (`./y`, `../lib`, `@app/models`) to the
+file it refers to, following the compiler's lookup rules closely enough
+for indexing:
+
+- relative specifiers are resolved against the importing file, trying the
+  TypeScript and JavaScript extensions and then `index.*` in a directory
+- `.js` specifiers written for ESM output also find the `.ts` source
+- non-relative specifiers go through the nearest tsconfig.json
+  (`compilerOptions.paths`, then `baseUrl`), following relative `extends`
+- anything else is a package import and stays unresolved
+"""
+
+import json
+import os
+import re
+from typing import Any, Dict, List, Optional, Tuple
+
+# Extensions tried, in order, for a specifier without one
+RESOLVE_EXTENSIONS = (".ts", ".tsx", ".d.ts", ".js", ".jsx")
+
+# Extensions an ESM-style specifier may name instead of the TypeScript source
+JS_TO_TS_EXTENSIONS = {".js": (".ts", ".tsx"), ".jsx": (".tsx",), ".mjs": (".mts",), ".cjs": (".cts",)}
+
+# Strings, or the comments and trailing commas tsconfig.json allows around them
+_JSONC_TOKENS = re.compile(r'"(?:\\.|[^"\\])*"|//[^\n]*|/\*.*?\*/|,(?=\s*[}\]])', re.DOTALL)
+
+
+def module_key(file_path: str) -> str:
+    """Build the module_definitions key for a TypeScript file by its path."""
+    return f"tsfile:{os.path.normpath(file_path)}"
+
+
+def load_jsonc(text: str) -> Any:
+    """Parse JSON that may contain comments and trailing commas."""
+    return json.loads(_JSONC_TOKENS.sub(lambda m: m.group(0) if m.group(0).startswith('"') else "", text))
+
+
+class TsModuleResolver:
+    """
+    Resolve import specifiers to files on disk.
+    
+    tsconfig.json lookups are cached per directory for the whole run, like
+    the go.mod lookups of the Go adapter.
+    """
+    
+    # dir -> effective compiler options of the nearest tsconfig.json, or None
+    _tsconfig_cache: Dict[str, Optional[Dict[str, Any]]] = {}
+    
+    def resolve(self, specifier: str, importer: str) -> Optional[str]:
+        """
+        Resolve a specifier imported by a file.
+        
+        Args:
+            specifier: The string after `from` (or inside `import(...)`)
+            importer: Path of the importing file
+        
+        Returns:
+            Normalized path of the target file, or None for package imports
+            and specifiers that do not match a file
+        """
+        if not specifier:
+            return None
+        if specifier.startswith(("./", "../")) or specifier in (".", ".."):
+            return self._resolve_path(os.path.join(os.path.dirname(importer), specifier))
+        if os.path.isabs(specifier):
+            return self._resolve_path(specifier)
+        
+        options = self._find_tsconfig(os.path.dirname(os.path.abspath(importer)))
+        if options is None:
+            return None
+        for target in self._path_alias_targets(specifier, options):
+            resolved = self._resolve_path(target)
+            if resolved:
+                return resolved
+        if options.get("baseUrl"):
+            return self._resolve_path(os.path.join(options["baseUrl"], specifier))
+        return None
+    
+    def _resolve_path(self, path: str) -> Optional[str]:
+        """Find the file a path without (or with a JavaScript) extension stands for."""
+        path = os.path.normpath(path)
+        stem, ext = os.path.splitext(path)
+        candidates = [stem + ts_ext for ts_ext in JS_TO_TS_EXTENSIONS.get(ext, ())]
+        candidates.append(path)
+        candidates.extend(path + resolve_ext for resolve_ext in RESOLVE_EXTENSIONS)
+        candidates.extend(os.path.join(path, "index" + resolve_ext) for resolve_ext in RESOLVE_EXTENSIONS)
+        for candidate in candidates:
+            if os.path.isfile(candidate):
+                return candidate
+        return None
+    
+    def _path_alias_targets(self, specifier: str, options: Dict[str, Any]) -> List[str]:
+        """Expand `compilerOptions.paths` for a specifier; the longest matching prefix wins."""
+        best: Optional[Tuple[int, str, List[str]]] = None
+        for pattern, targets in (options.get("paths") or {}).items():
+            if "*" not in pattern:
+                if pattern == specifier:
+                    best = (len(pattern) + 1, "", targets)
+                    break
+                continue
+            prefix, suffix = pattern.split("*", 1)
+            if (specifier.startswith(prefix) and specifier.endswith(suffix)
+                    and len(specifier) >= len(prefix) + len(suffix)
+                    and (best is None or len(prefix) > best[0])):
+                best = (len(prefix), specifier[len(prefix):len(specifier) - len(suffix)], targets)
+        if best is None:
+            return []
+        
+        _, star, targets = best
+        base = options.get("baseUrl") or options["paths_dir"]
+        return [os.path.join(base, target.replace("*", star)) for target in targets if isinstance(target, str)]
+    
+    def _find_tsconfig(self, directory: str) -> Optional[Dict[str, Any]]:
+        """Walk up from directory to the nearest tsconfig.json and read its compiler options."""
+        if directory in self._tsconfig_cache:
+            return self._tsconfig_cache[directory]
+        
+        tsconfig = os.path.join(directory, "tsconfig.json")
+        if os.path.isfile(tsconfig):
+            result = self._load_compiler_options(tsconfig, set())
+        else:
+            parent = os.path.dirname(directory)
+            result = self._find_tsconfig(parent) if parent != directory else None
+        
+        self._tsconfig_cache[directory] = result
+        return result
+    
+    def _load_compiler_options(self, config_path: str, seen: set) -> Optional[Dict[str, Any]]:
+        """
+        Read baseUrl and paths from a tsconfig file and the configs it extends.
+        
+        Both are made absolute relative to the file that declares them; paths
+        without a baseUrl are relative to their own config (paths_dir).
+        """
+        config_path = os.path.abspath(config_path)
+        if config_path in seen:
+            return None
+        seen.add(config_path)
+        try:
+            with open(config_path, "r", encoding="utf-8") as f:
+                config = load_jsonc(f.read())
+        except (OSError, ValueError):
+            return None
+        if not isinstance(config, dict):
+            return None
+        
+        options: Dict[str, Any] = {"baseUrl": None, "paths": None, "paths_dir": None}
+        config_dir = os.path.dirname(config_path)
+        
+        # Package configs (`@tsconfig/node18`) are not resolved, only relative ones
+        extends = config.get("extends")
+        if isinstance(extends, str) and extends.startswith("."):
+            parent_path = os.path.join(config_dir, extends)
+            if not parent_path.endswith(".json"):
+                parent_path += ".json"
+            parent = self._load_compiler_options(parent_path, seen)
+            if parent:
+                options.update(parent)
+        
+        compiler_options = config.get("compilerOptions") or {}
+        if isinstance(compiler_options.get("baseUrl"), str):
+            options["baseUrl"] = os.path.normpath(os.path.join(config_dir, compiler_options["baseUrl"]))
+        if isinstance(compiler_options.get("paths"), dict):
+            options["paths"] = compiler_options["paths"]
+            options["paths_dir"] = config_dir
+        return options
@@ -1,15 +1,64 @@
 import os
 import logging
-from typing import Dict, List, Optional, Tuple, Any, Set
+from typing import Dict, List, NamedTuple, Optional, Tuple, Any, Set
 import tree_sitter_javascript
 import tree_sitter_typescript
 from tree_sitter import Language, Parser, Node, Query, QueryCursor
 
 from src.ast_parser.parser import CodeNode, CodeRelation
+from src.ast_parser.doc_comments import js_file_comment_properties, jsdoc_properties
+from src.ast_parser.metrics import JAVASCRIPT_METRIC_RULES, syntax_metrics
+from src.ast_parser.signatures import typescript_signature
 
 logger = logging.getLogger(__name__)
 
 
+class _Position(NamedTuple):
+    line: int
+    column: int
+
+
+class _Range(NamedTuple):
+    start: _Position
+    end: _Position
+
+
+class _SyntaxNode:
+    """ast-grep style view of a tree-sitter node, the interface typescript_signature, syntax_metrics and doc_comments read."""
+    
+    def __init__(self, node: Node, source_code: str):
+        self._node = node
+        self._source_code = source_code
+    
+    def kind(self) -> str:
+        return self._node.type
+    
+    def field(self, name: str) -> Optional["_SyntaxNode"]:
+        child = self._node.child_by_field_name(name)
+        return _SyntaxNode(child, self._source_code) if child is not None else None
+    
+    def children(self) -> List["_SyntaxNode"]:
+        return [_SyntaxNode(child, self._source_code) for child in self._node.children]
+    
+    def text(self) -> str:
+        return self._source_code[self._node.start_byte:self._node.end_byte]
+    
+    def range(self) -> _Range:
+        return _Range(_Position(*self._node.start_point), _Position(*self._node.end_point))
+    
+    def parent(self) -> Optional["_SyntaxNode"]:
+        return self._wrap(self._node.parent)
+    
+    def prev(self) -> Optional["_SyntaxNode"]:
+        return self._wrap(self._node.prev_sibling)
+    
+    def is_named(self) -> bool:
+        return self._node.is_named
+    
+    def _wrap(self, node: Optional[Node]) -> Optional["_SyntaxNode"]:
+        return _SyntaxNode(node, self._source_code) if node is not None else None
+
+
 class TypeScriptParser:
     """Parser for JavaScript and TypeScript files using tree-sitter.
     
@@ -18,6 +67,11 @@ class TypeScriptParser:
     the Python ASTParser, ensuring compatibility with the rest of the system.
     """
 
+    # ParseDeadline of the file being parsed, set by parse_with_limits
+    parse_deadline = None
+    # Error the last parse_file swallowed, read by parse_with_limits
+    parse_error = None
+    
     def __init__(self):
         """Initialize the TypeScript/JavaScript parser."""
         self.nodes: Dict[str, CodeNode] = {}
@@ -100,6 +154,9 @@ class TypeScriptParser:
                 tree = parser.parse(bytes(file_content, "utf8"))
                 
                 file_node_id = self._create_file_node(file_path)
+                # License headers and @fileoverview blocks describe the file
+                self.nodes[file_node_id].properties.update(
+                    js_file_comment_properties(_SyntaxNode(tree.root_node, file_content)))
                 
                 # Generate module name for indexing
                 module_name = os.path.splitext(os.path.basename(file_path))[0]
@@ -116,6 +173,7 @@ class TypeScriptParser:
         except Exception as e:
             logger.error(f"Error parsing file {file_path}: {e}")
             print(f"Error parsing file {file_path}: {e}")
+            self.parse_error = e
             return {}, []
 
     def _get_parser_for_file(self, file_path: str) -> Parser:
@@ -166,7 +224,12 @@ class TypeScriptParser:
             
         Returns:
             Unique node ID
+        
+        Raises:
+            ParseTimeout: the parse deadline has passed (see ast_parser/guards.py)
         """
+        if self.parse_deadline is not None:
+            self.parse_deadline.check()
         return f"{node_type}:{file_path}:{name}:{line_no}"
 
     def _parse_tree(self, root_node: Node, source_code: str, build_index: bool = False, module_name: str = "") -> None:
@@ -251,10 +314,13 @@ class TypeScriptParser:
                                 "language": self._get_language_from_file(),
                                 "function_style": "standard",
                                 "is_async": is_async,
+                                **typescript_signature(_SyntaxNode(func_node, source_code), func_name),
+                                **syntax_metrics(_SyntaxNode(func_node, source_code), JAVASCRIPT_METRIC_RULES, line_no, end_line_no),
                             },
                         )
                         
                         self.nodes[node_id].code_snippet = self._get_node_text(func_node, source_code)
+                        self._attach_jsdoc(node_id, func_node, source_code)
                         
                         file_node_id = f"file:{self.current_file}"
                         self.relations.append(
@@ -312,10 +378,14 @@ class TypeScriptParser:
                                 "language": self._get_language_from_file(),
                                 "function_style": "arrow",
                                 "is_async": is_async,
+                                **typescript_signature(_SyntaxNode(arrow_node, source_code), func_name),
+                                **syntax_metrics(_SyntaxNode(arrow_node, source_code), JAVASCRIPT_METRIC_RULES, line_no, end_line_no),
                             },
                         )
                         
                         self.nodes[node_id].code_snippet = self._get_node_text(arrow_node, source_code)
+                        # The JSDoc block documents the `const` declaration holding the function
+                        self._attach_jsdoc(node_id, arrow_node.parent.parent, source_code)
                         
                         file_node_id = f"file:{self.current_file}"
                         self.relations.append(
@@ -384,6 +454,7 @@ class TypeScriptParser:
                         
                         # Add code snippet
                         self.nodes[node_id].code_snippet = self._get_node_text(node, source_code)
+                        self._attach_jsdoc(node_id, node, source_code)
                         
                         # Create relationship: file contains class
                         file_node_id = f"file:{self.current_file}"
@@ -497,11 +568,14 @@ class TypeScriptParser:
                                         "parameters": params,
                                         "language": self._get_language_from_file(),
                                         "is_async": is_async,
+                                        **typescript_signature(_SyntaxNode(method_node, source_code), method_name),
+                                        **syntax_metrics(_SyntaxNode(method_node, source_code), JAVASCRIPT_METRIC_RULES, line_no, end_line_no),
                                     },
                                 )
                                 
                                 # Add code snippet
                                 self.nodes[node_id].code_snippet = self._get_node_text(method_node, source_code)
+                                self._attach_jsdoc(node_id, method_node, source_code)
                                 
                                 # Create relationship: class defines method
                                 self.relations.append(
@@ -584,12 +658,14 @@ class TypeScriptParser:
                                             decl_type = child.type
                                             break
                                     
-                                    node_id = self._get_node_id("Variable", var_name, self.current_file, line_no)
+                                    # `const` bindings are Constant nodes (see variables.py)
+                                    node_type = "Constant" if decl_type == "const" else "Variable"
+                                    node_id = self._get_node_id(node_type, var_name, self.current_file, line_no)
                                     
                                     # Create variable node
                                     self.nodes[node_id] = CodeNode(
                                         node_id=node_id,
-                                        node_type="Variable",
+                                        node_type=node_type,
                                         name=var_name,
                                         file_path=self.current_file,
                                         line_no=line_no,
@@ -875,6 +951,16 @@ class TypeScriptParser:
             current = current.parent
         return False
 
+    def _attach_jsdoc(self, node_id: str, declaration: Node, source_code: str) -> None:
+        """Store the JSDoc block directly above a declaration on its node (see doc_comments.jsdoc_properties).
+        
+        Args:
+            node_id: Node ID of the declared entity
+            declaration: Tree-sitter node of the declaration
+            source_code: Source code of the file
+        """
+        self.nodes[node_id].properties.update(jsdoc_properties(_SyntaxNode(declaration, source_code)))
+    
     def _get_node_text(self, node: Node, source_code: str) -> str:
         """Get the text content of a tree-sitter node.
         
@@ -0,0 +1,224 @@
+"""
+Package-level variables and constants, and the USES edges to them.
+
+Top-level declarations that are neither functions nor types become
+nodes: Constant for Go `const` identifiers, Python module-level names
+written in upper case (or annotated `Final`) and TypeScript `const`
+bindings; Variable for Go package `var`s, the other Python module-level
+names and TypeScript `let` / `var`. A grouped Go declaration yields one
+node per identifier, with iota resolved. A simple literal initializer
+(number, string, boolean) is stored in "value"; other initializers are not
+evaluated. Variables local to a function body are not indexed.
+
+A function or method referencing one of them gets a USES edge per kind
+of use, in "usage":
+- "compare": operand of a comparison (`==`, `!=`, `<`, `is`, `in`, ...),
+  a switch or match case value, or an argument of Go's `errors.Is`
+- "return": returned as is, alone or as one of several results
+- "write": assigned to (a Python `global`, a Go package variable)
+- "read": any other reference
+Each edge carries the first line of that use in "line_no" and all of them
+in "use_lines". References resolve like calls, through the module or
+package the parsers index the names under (the second pass only links
+Constant and Variable targets); a name declared locally shadows them.
+"""
+
+import ast
+import builtins
+import re
+from typing import Any, Dict, List, Optional, Set, Tuple, Union
+
+USAGE_READ = "read"
+USAGE_COMPARE = "compare"
+USAGE_RETURN = "return"
+USAGE_WRITE = "write"
+USAGE_KINDS = (USAGE_READ, USAGE_COMPARE, USAGE_RETURN, USAGE_WRITE)
+
+VARIABLE_NODE_TYPES = ("Constant", "Variable")
+
+# Longest string stored in "value"
+VALUE_MAX_LENGTH = 200
+
+# Neo4j integers are 64-bit
+INT_MIN, INT_MAX = -2 ** 63, 2 ** 63 - 1
+
+CONSTANT_NAME = re.compile(r"^_*[A-Z][A-Z0-9_]*$")
+
+# Python names that never resolve to a module-level definition
+PYTHON_BUILTINS = frozenset(dir(builtins))
+
+
+def is_constant_name(name: str) -> bool:
+    """Upper-case names such as MAX_RETRIES, the convention for constants where the language has none."""
+    return bool(CONSTANT_NAME.match(name))
+
+
+def is_variable_id(node_id: Optional[str]) -> bool:
+    """Whether a node ID names a Constant or Variable node; the parsers prefix their IDs with the node type."""
+    return bool(node_id) and node_id.startswith(tuple(f"{node_type}:" for node_type in VARIABLE_NODE_TYPES))
+
+
+def literal_value(value: Any) -> Optional[Any]:
+    """value if it can be stored in "value": a boolean, a 64-bit integer, a float or a short string."""
+    if isinstance(value, bool) or isinstance(value, float):
+        return value
+    if isinstance(value, int):
+        return value if INT_MIN <= value <= INT_MAX else None
+    if isinstance(value, str):
+        return value if len(value) <= VALUE_MAX_LENGTH else None
+    return None
+
+
+def queue_uses(pending_imports: List[Dict[str, Any]], source_id: str,
+               uses: Dict[Tuple[str, str, str], List[int]]) -> None:
+    """
+    Queue the USES edges of one function for the second pass.
+    
+    Args:
+        uses: (module key, name, usage) -> lines of that use
+    """
+    for (module_name, name, usage), lines in uses.items():
+        lines = sorted(set(lines))
+        pending_imports.append({
+            "type": "USES",
+            "source_id": source_id,
+            "imported_module": module_name,
+            "imported_name": name,
+            "usage": usage,
+            "line_no": lines[0],
+            "use_lines": lines,
+        })
+
+
+def uses_properties(import_info: Dict[str, Any]) -> Dict[str, Any]:
+    """Properties of the USES edge of a queued use."""
+    return {
+        "usage": import_info["usage"],
+        "line_no": import_info["line_no"],
+        "use_lines": import_info["use_lines"],
+    }
+
+
+def python_literal(value: Optional[ast.expr]) -> Optional[Any]:
+    """Value of a simple literal initializer (number, string, boolean), None for other expressions."""
+    if not isinstance(value, (ast.Constant, ast.UnaryOp)):
+        return None
+    try:
+        return literal_value(ast.literal_eval(value))
+    except (ValueError, TypeError, SyntaxError):
+        return None
+
+
+def python_assignments(node: Union[ast.Assign, ast.AnnAssign]) -> List[Tuple[str, str, Dict[str, Any]]]:
+    """
+    (name, node type, properties) of each name a module-level assignment binds.
+    
+    `A, B = 1, 2` pairs the names with the values. A name is a Constant
+    when it is written in upper case or annotated `Final`.
+    """
+    annotation = ast.unparse(node.annotation) if isinstance(node, ast.AnnAssign) else None
+    final = annotation is not None and annotation.split("[")[0].split(".")[-1] == "Final"
+    targets = [node.target] if isinstance(node, ast.AnnAssign) else node.targets
+    
+    assigned: List[Tuple[str, Optional[ast.expr]]] = []
+    for target in targets:
+        if isinstance(target, ast.Name):
+            assigned.append((target.id, node.value))
+        elif isinstance(target, ast.Tuple):
+            values = node.value.elts if isinstance(node.value, ast.Tuple) else []
+            values = values if len(values) == len(target.elts) else [None] * len(target.elts)
+            assigned.extend((elt.id, value) for elt, value in zip(target.elts, values) if isinstance(elt, ast.Name))
+    
+    result = []
+    for name, value in assigned:
+        properties: Dict[str, Any] = {}
+        if annotation is not None:
+            properties["annotation"] = annotation
+        literal = python_literal(value)
+        if literal is not None:
+            properties["value"] = literal
+        result.append((name, "Constant" if final or is_constant_name(name) else "Variable", properties))
+    return result
+
+
+def python_uses(node: ast.AST, module_name: str, imports: Dict[str, str],
+                module_aliases: Set[str]) -> Dict[Tuple[str, str, str], List[int]]:
+    """
+    Module-level names a Python function uses, for queue_uses.
+    
+    Nested functions and classes are left to their own nodes. A name bound
+    in the body (assigned, imported, caught) is local unless the body
+    declares it `global`; builtins and callees are not uses.
+    
+    Args:
+        node: FunctionDef or AsyncFunctionDef
+        module_name: Module key of the function's own file
+        imports: alias -> dotted path of the file's imports
+        module_aliases: Aliases bound by `import x`, which name a module rather than a symbol in it
+    """
+    arguments = node.args
+    local_names = {arg.arg for arg in arguments.posonlyargs + arguments.args + arguments.kwonlyargs}
+    local_names.update(arg.arg for arg in (arguments.vararg, arguments.kwarg) if arg is not None)
+    body: List[ast.AST] = []
+    stack: List[ast.AST] = list(node.body)
+    while stack:
+        current = stack.pop()
+        if isinstance(current, (ast.FunctionDef, ast.AsyncFunctionDef, ast.ClassDef)):
+            local_names.add(current.name)
+            continue
+        body.append(current)
+        stack.extend(ast.iter_child_nodes(current))
+    
+    declared_global: Set[str] = set()
+    compared, returned, called = set(), set(), set()
+    for current in body:
+        if isinstance(current, (ast.Global, ast.Nonlocal)):
+            declared_global.update(current.names)
+        elif isinstance(current, ast.Name) and not isinstance(current.ctx, ast.Load):
+            local_names.add(current.id)
+        elif isinstance(current, (ast.Import, ast.ImportFrom)):
+            local_names.update((alias.asname or alias.name).split(".")[0] for alias in current.names)
+        elif isinstance(current, ast.ExceptHandler) and current.name:
+            local_names.add(current.name)
+        elif isinstance(current, ast.Compare):
+            compared.update(id(operand) for operand in [current.left] + current.comparators)
+        elif isinstance(current, ast.MatchValue):
+            compared.add(id(current.value))
+        elif isinstance(current, ast.Return) and current.value is not None:
+            results = current.value.elts if isinstance(current.value, ast.Tuple) else [current.value]
+            returned.update(id(result) for result in results)
+        elif isinstance(current, ast.Call):
+            called.add(id(current.func))
+    local_names -= declared_global
+    
+    def target(expr: Union[ast.Name, ast.Attribute]) -> Optional[Tuple[str, str]]:
+        # A name of this module, a symbol imported from another one, or `module.NAME`
+        if isinstance(expr, ast.Name):
+            if expr.id in local_names or expr.id in PYTHON_BUILTINS:
+                return None
+            imported = imports.get(expr.id)
+            if imported is None:
+                return module_name, expr.id
+            parts = imported.split(".")
+            return (parts[-2], parts[-1]) if len(parts) > 1 and expr.id not in module_aliases else None
+        if isinstance(expr.value, ast.Name) and expr.value.id not in local_names and expr.value.id in imports:
+            return imports[expr.value.id].split(".")[-1], expr.attr
+        return None
+    
+    uses: Dict[Tuple[str, str, str], List[int]] = {}
+    for current in body:
+        if not isinstance(current, (ast.Name, ast.Attribute)) or id(current) in called:
+            continue
+        resolved = target(current)
+        if resolved is None:
+            continue
+        if not isinstance(current.ctx, ast.Load):
+            usage = USAGE_WRITE
+        elif id(current) in compared:
+            usage = USAGE_COMPARE
+        elif id(current) in returned:
+            usage = USAGE_RETURN
+        else:
+            usage = USAGE_READ
+        uses.setdefault((resolved[0], resolved[1], usage), []).append(current.lineno)
+    return uses
@@ -1,8 +1,37 @@
-from typing import List, Optional
+from typing import Callable, List, Optional
+import logging
 import os
+import time
 from .base import EmbeddingProvider
 from .openai_compatible import OpenAICompatibleProvider
 
+logger = logging.getLogger(__name__)
+
+DEFAULT_MAX_RETRIES = 3
+DEFAULT_BATCH_SIZE = 20
+
+
+def get_embedding_batch_size() -> int:
+    """Read EMBEDDING_BATCH_SIZE, texts per provider request (default 20)."""
+    value = os.environ.get("EMBEDDING_BATCH_SIZE", "")
+    if value:
+        try:
+            return max(1, int(value))
+        except ValueError:
+            logger.warning(f"Invalid EMBEDDING_BATCH_SIZE value '{value}', using {DEFAULT_BATCH_SIZE}")
+    return DEFAULT_BATCH_SIZE
+
+
+def get_max_retries() -> int:
+    """Read EMBEDDING_MAX_RETRIES (default 3)."""
+    value = os.environ.get("EMBEDDING_MAX_RETRIES", "")
+    if value:
+        try:
+            return max(0, int(value))
+        except ValueError:
+            logger.warning(f"Invalid EMBEDDING_MAX_RETRIES value '{value}', using {DEFAULT_MAX_RETRIES}")
+    return DEFAULT_MAX_RETRIES
+
 
 class OpenAIEmbeddings(EmbeddingProvider):
     """Compatibility wrapper exposing a simple OpenAI-like embeddings API.
@@ -26,6 +55,10 @@ class OpenAIEmbeddings(EmbeddingProvider):
     def dimension(self) -> int:
         return self._provider.dimension
 
+    @property
+    def model(self) -> str:
+        return self._provider.model
+
     def embed_text(self, text: str) -> List[float]:
         return self._provider.embed_text(text)
 
@@ -37,11 +70,16 @@ class CodeEmbedder:
     """Processor for generating embeddings for code elements.
 
     The CodeEmbedder formats code snippets with their node type and name
-    before delegating to the underlying embedding provider.
+    before delegating to the underlying embedding provider. Failed batch
+    requests are retried with exponential backoff.
     """
 
-    def __init__(self, provider: EmbeddingProvider):
+    def __init__(self, provider: EmbeddingProvider, max_retries: Optional[int] = None,
+                 backoff_seconds: float = 1.0, sleep: Callable[[float], None] = time.sleep):
         self.provider = provider
+        self.max_retries = get_max_retries() if max_retries is None else max_retries
+        self.backoff_seconds = backoff_seconds
+        self._sleep = sleep
 
     def prepare_code_text(self, code_text: str, node_type: str, name: str) -> str:
         """Prepare a code snippet for embedding by adding context about the node."""
@@ -56,4 +94,17 @@ class CodeEmbedder:
             raise ValueError("code_texts, node_types, and names must have the same length")
 
         prepared = [self.prepare_code_text(c, t, n) for c, t, n in zip(code_texts, node_types, names)]
-        return self.provider.embed_batch(prepared)
\ No newline at end of file
+        return self._embed_batch_with_retry(prepared)
+
+    def _embed_batch_with_retry(self, texts: List[str]) -> List[List[float]]:
+        """Call provider.embed_batch, retrying up to max_retries times (delay doubles each time)."""
+        delay = self.backoff_seconds
+        for attempt in range(self.max_retries + 1):
+            try:
+                return self.provider.embed_batch(texts)
+            except Exception as e:
+                if attempt == self.max_retries:
+                    raise
+                logger.warning(f"Embedding batch of {len(texts)} texts failed ({e}), retrying in {delay:.1f}s")
+                self._sleep(delay)
+                delay *= 2
\ No newline at end of file
@@ -1,9 +1,20 @@
 import os
 from .base import EmbeddingProvider
+from .ollama import OllamaProvider
 from .openai_compatible import OpenAICompatibleProvider
 
 def get_embedding_provider() -> EmbeddingProvider:
     provider = os.environ.get("EMBEDDING_PROVIDER", "openai").lower()
+
+    if provider == "ollama":
+        # Local embeddings, no API key
+        dimension = os.environ.get("EMBEDDING_DIMENSION")
+        return OllamaProvider(
+            base_url=os.environ.get("OLLAMA_BASE_URL", "http://localhost:11434"),
+            model=os.environ.get("EMBEDDING_MODEL", "nomic-embed-text"),
+            dimension=int(dimension) if dimension else None,
+        )
+
     model = os.environ.get("EMBEDDING_MODEL", "text-embedding-3-small")
 
     if provider == "openai":
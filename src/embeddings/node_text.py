@@ -0,0 +1,130 @@
+"""
+Text representation of graph nodes for embedding.
+
+Each Function / Method / Class node is embedded from its signature, its
+doc comment or docstring ("doc") and the start of its body
+(EMBEDDING_BODY_CHARS, default 1200 characters), so a query like "validate
+email addresses" can match a function named `checkAddr` through its doc
+and code. Bodies come from
+the node's code_snippet when the parser recorded one, otherwise from the
+source file between line_no and end_line_no. File nodes are embedded from
+their path and their file-level doc.
+
+embedding_key() hashes the embedded text together with the provider's model,
+so incremental runs can reuse the stored vector of a node whose text did not
+change instead of embedding it again.
+"""
+
+import hashlib
+import json
+import logging
+import os
+from typing import Any, Dict, List, Optional
+
+logger = logging.getLogger(__name__)
+
+DEFAULT_BODY_CHARS = 1200
+
+# Node types that get an embedding
+EMBEDDED_NODE_TYPES = ("Function", "Method", "Class", "File")
+
+
+def get_body_char_limit() -> int:
+    """Read EMBEDDING_BODY_CHARS (default 1200)."""
+    value = os.getenv("EMBEDDING_BODY_CHARS", "")
+    if value:
+        try:
+            return max(0, int(value))
+        except ValueError:
+            logger.warning(f"Invalid EMBEDDING_BODY_CHARS value '{value}', using {DEFAULT_BODY_CHARS}")
+    return DEFAULT_BODY_CHARS
+
+
+def format_signature(node) -> str:
+    """
+    Signature line of a node, e.g. "async check_addr(value: str) -> bool" or "Read([]byte) (int, error)".
+
+    Uses the node's signature when the parser recorded one (see
+    src.ast_parser.signatures), otherwise the Python parsers' args list;
+    falls back to the name.
+    """
+    props = node.properties
+    owner = props.get("receiver_type")
+    prefix = f"{owner}." if owner else ""
+    if props.get("is_async"):
+        prefix = "async " + prefix
+    if props.get("signature"):
+        return prefix + props["signature"]
+
+    signature = node.name
+    args = props.get("args")
+    if isinstance(args, str):
+        try:
+            args = json.loads(args)
+        except ValueError:
+            args = None
+    if isinstance(args, list):
+        params = [f"{arg['name']}: {arg['type']}" if arg.get("type") else arg["name"]
+                  for arg in args if isinstance(arg, dict) and arg.get("name")]
+        signature = f"{node.name}({', '.join(params)})"
+    return prefix + signature
+
+
+class SourceLines:
+    """Caches the lines of source files so bodies of many nodes cost one read per file."""
+
+    def __init__(self):
+        self._files: Dict[str, List[str]] = {}
+
+    def get(self, file_path: str, start: int, end: int) -> str:
+        """Return lines start..end (1-based, inclusive), or "" if the file cannot be read."""
+        if file_path not in self._files:
+            try:
+                with open(file_path, "r", encoding="utf-8", errors="replace") as f:
+                    self._files[file_path] = f.read().splitlines()
+            except OSError:
+                self._files[file_path] = []
+        return "\n".join(self._files[file_path][start - 1:end])
+
+
+def node_body(node, source_lines: Optional[SourceLines] = None) -> str:
+    """The node's source code: code_snippet, or its line range read from the file."""
+    if getattr(node, "code_snippet", ""):
+        return node.code_snippet
+    if source_lines is None or not node.file_path or not node.line_no:
+        return ""
+    return source_lines.get(node.file_path, node.line_no, node.end_line_no or node.line_no)
+
+
+def build_node_text(node, source_lines: Optional[SourceLines] = None, body_chars: Optional[int] = None) -> str:
+    """
+    Build the text embedded for a node: signature, doc and truncated body.
+
+    Args:
+        node: CodeNode
+        source_lines: Cache used to read bodies that are not on the node
+        body_chars: Maximum body length, if None, get from EMBEDDING_BODY_CHARS
+    """
+    if node.node_type == "File":
+        text = f"File: {node.file_path or node.name}"
+        doc = node.properties.get("doc")
+        return f"{text}\n{doc.strip()}" if doc else text
+
+    body_chars = get_body_char_limit() if body_chars is None else body_chars
+    parts = [format_signature(node)]
+    doc = node.properties.get("doc")
+    if doc:
+        parts.append(doc.strip())
+    body = node_body(node, source_lines)
+    if body and body_chars:
+        parts.append(body[:body_chars])
+    return "\n".join(parts)
+
+
+def embedding_key(text: str, node_type: str, name: str, model: Any) -> str:
+    """Hash identifying an embedding: same text, node and model give the same vector."""
+    digest = hashlib.sha256()
+    for part in (str(model), node_type, name, text):
+        digest.update(part.encode("utf-8", errors="replace"))
+        digest.update(b"\0")
+    return digest.hexdigest()
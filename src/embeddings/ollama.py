@@ -0,0 +1,50 @@
+import json
+import logging
+import urllib.request
+from typing import List, Optional
+
+from .base import EmbeddingProvider
+
+logger = logging.getLogger(__name__)
+
+
+class OllamaProvider(EmbeddingProvider):
+    """Local embeddings served by Ollama (POST /api/embed).
+
+    No API key is needed. The vector dimension is taken from EMBEDDING_DIMENSION
+    when configured, otherwise from the first response. Errors are raised so
+    the caller's retry/backoff applies.
+    """
+
+    def __init__(self, base_url: str = "http://localhost:11434", model: str = "nomic-embed-text",
+                 dimension: Optional[int] = None, timeout: float = 60.0):
+        self.base_url = base_url.rstrip("/")
+        self.model = model
+        self.timeout = timeout
+        self._dimension = dimension
+
+    @property
+    def dimension(self) -> int:
+        if self._dimension is None:
+            self._dimension = len(self.embed_text("dimension probe"))
+        return self._dimension
+
+    def embed_text(self, text: str) -> List[float]:
+        return self.embed_batch([text])[0]
+
+    def embed_batch(self, texts: List[str]) -> List[List[float]]:
+        if not texts:
+            return []
+        request = urllib.request.Request(
+            f"{self.base_url}/api/embed",
+            data=json.dumps({"model": self.model, "input": texts}).encode("utf-8"),
+            headers={"Content-Type": "application/json"},
+            method="POST",
+        )
+        with urllib.request.urlopen(request, timeout=self.timeout) as response:
+            embeddings = json.loads(response.read().decode("utf-8"))["embeddings"]
+        if len(embeddings) != len(texts):
+            raise ValueError(f"Ollama returned {len(embeddings)} embeddings for {len(texts)} inputs")
+        if self._dimension is None and embeddings:
+            self._dimension = len(embeddings[0])
+        return embeddings
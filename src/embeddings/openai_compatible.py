@@ -47,31 +47,24 @@ class OpenAICompatibleProvider(EmbeddingProvider):
         if not filtered_texts:
             return [[0.0] * self.dimension for _ in texts]
 
-        try:
-            response = self.client.embeddings.create(
-                model=self.model,
-                input=filtered_texts,
-                encoding_format="float"
-            )
-            
-            embedding_dict = {emb_data.index: emb_data.embedding for emb_data in response.data}
-            
-            result = []
-            filtered_index = 0
-            for text in texts:
-                if text and text.strip():
-                    result.append(embedding_dict.get(filtered_index, [0.0] * self.dimension))
-                    filtered_index += 1
-                else:
-                    result.append([0.0] * self.dimension)
-            return result
-        except RateLimitError:
-            print("Rate limit reached, waiting...")
-            time.sleep(60)
-            return self.embed_batch(texts)
-        except Exception as e:
-            print(f"Error generating batch embeddings: {e}")
-            return [[0.0] * self.dimension for _ in texts]
+        # Errors (including rate limits) propagate, CodeEmbedder retries the batch with backoff
+        response = self.client.embeddings.create(
+            model=self.model,
+            input=filtered_texts,
+            encoding_format="float"
+        )
+        
+        embedding_dict = {emb_data.index: emb_data.embedding for emb_data in response.data}
+        
+        result = []
+        filtered_index = 0
+        for text in texts:
+            if text and text.strip():
+                result.append(embedding_dict.get(filtered_index, [0.0] * self.dimension))
+                filtered_index += 1
+            else:
+                result.append([0.0] * self.dimension)
+        return result
 
     def _infer_dimension_from_model(self, model_name: str) -> int:
         """Infer embedding dimensionality from the model name using common mappings.
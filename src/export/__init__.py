@@ -0,0 +1,17 @@
+"""Graph export to visualization formats (GraphML, DOT)."""
+
+from src.export.graph_export import (
+    EXPORT_FORMATS,
+    collect_subgraph,
+    export_graph,
+    to_dot,
+    to_graphml,
+)
+
+__all__ = [
+    'EXPORT_FORMATS',
+    'collect_subgraph',
+    'export_graph',
+    'to_dot',
+    'to_graphml',
+]
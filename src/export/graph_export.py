@@ -0,0 +1,324 @@
+"""
+Export the code graph to GraphML (Gephi, yEd) and DOT (Graphviz).
+
+The exported subgraph is scoped by a directory subtree, by the nodes
+within K hops of a symbol, or both. Node labels show symbol kind and
+name, edge labels the relationship type, and every node carries its
+file path and line as attributes. Round-trip fidelity is not a goal:
+only scalar properties are exported and lists become JSON strings.
+
+Usage:
+    python export_graph.py --format dot --path src/api --output api.dot
+    python export_graph.py --format graphml --symbol jsonutil.Parse --hops 2 --output parse.graphml
+    python export_graph.py --storage memory --graph-file graph.json --format dot --output graph.dot
+"""
+
+import argparse
+import json
+import logging
+import os
+import re
+import sys
+from typing import Any, Dict, List, Optional, Set, Tuple
+
+sys.path.append(os.path.dirname(os.path.dirname(os.path.dirname(os.path.abspath(__file__)))))
+
+from src.graph_store import STORAGE_BACKENDS, InMemoryGraphStore, get_graph_file, get_storage_backend
+from src.mcp.references import find_symbol_candidates, node_type_from_labels, qualified_name
+
+logger = logging.getLogger(__name__)
+
+EXPORT_FORMATS = ("graphml", "dot")
+
+# Node properties exported as attributes, in output order
+NODE_ATTRIBUTES = ("kind", "name", "file_path", "line_no", "end_line_no", "doc")
+
+NODE_ATTRIBUTE_TYPES = {"line_no": "int", "end_line_no": "int"}
+
+# Characters outside the XML 1.0 Char production; control characters also trip up Graphviz
+_INVALID_CHARS = re.compile("[^\u0009\u000a\u000d\u0020-\ud7ff\ue000-\ufffd\U00010000-\U0010ffff]")
+
+_DOT_ID = re.compile(r"^[A-Za-z_][A-Za-z0-9_]*$")
+
+
+def scope_prefixes(path: str) -> List[str]:
+    """The scope path as given and as an absolute path, since stored paths follow the indexing call."""
+    prefixes = []
+    for prefix in (os.path.normpath(path), os.path.abspath(path)):
+        prefix = prefix.replace("\\", "/").rstrip("/")
+        if prefix and prefix not in prefixes:
+            prefixes.append(prefix)
+    return prefixes
+
+
+def in_scope(file_path: Optional[str], prefixes: List[str]) -> bool:
+    return bool(file_path) and any(file_path == prefix or file_path.startswith(prefix + "/") for prefix in prefixes)
+
+
+def _neighbourhood(db, start_id: str, hops: int) -> Set[str]:
+    """Node IDs within `hops` edges of start_id, following edges in either direction."""
+    seen = {start_id}
+    frontier = [start_id]
+    for _ in range(hops):
+        if not frontier:
+            break
+        rows = db.neighbors(frontier, direction="both")
+        frontier = sorted({row["node"]["properties"]["id"] for row in rows} - seen)
+        seen.update(frontier)
+    return seen
+
+
+def resolve_export_symbol(db, symbol: str) -> Dict[str, Any]:
+    """
+    Resolve the symbol a hop-scoped export starts from.
+    
+    Raises:
+        ValueError: when no symbol or several symbols match
+    """
+    candidates = find_symbol_candidates(db, symbol)
+    if not candidates:
+        raise ValueError(f"Symbol '{symbol}' not found")
+    if len(candidates) > 1:
+        names = ", ".join(sorted(f"{qualified_name(c)} ({c['symbol_id'] or c['id']})" for c in candidates))
+        raise ValueError(f"Symbol '{symbol}' is ambiguous, use a qualified name, symbol ID or node id: {names}")
+    return candidates[0]
+
+
+def collect_subgraph(db, path: Optional[str] = None, symbol: Optional[str] = None,
+                     hops: int = 1) -> Tuple[List[Dict[str, Any]], List[Dict[str, Any]]]:
+    """
+    Fetch the nodes and edges selected by the scope filters.
+    
+    Args:
+        db: GraphStore backend
+        path: Only export nodes defined under this file or directory
+        symbol: Only export nodes within `hops` edges of this symbol (name, qualified name or node id)
+        hops: Neighbourhood radius for `symbol`
+    
+    Returns:
+        (nodes, edges), sorted by file and line, with edges between exported nodes only
+    """
+    if hops < 0:
+        raise ValueError("hops must be >= 0")
+    
+    prefixes = scope_prefixes(path) if path else []
+    if symbol:
+        start = resolve_export_symbol(db, symbol)
+        records = db.get_nodes(sorted(_neighbourhood(db, start["id"], hops)))
+        if prefixes:
+            records = [record for record in records if in_scope(record["properties"].get("file_path"), prefixes)]
+    else:
+        records = db.find_nodes(path_prefixes=prefixes or None)
+    
+    nodes = []
+    for record in records:
+        properties = record["properties"]
+        nodes.append({
+            "id": properties["id"],
+            "kind": node_type_from_labels(record["labels"]),
+            "name": properties.get("name"),
+            "file_path": properties.get("file_path"),
+            "line_no": properties.get("line_no"),
+            "end_line_no": properties.get("end_line_no"),
+            "doc": properties.get("doc"),
+        })
+    nodes.sort(key=lambda node: (node.get("file_path") or "", node.get("line_no") or 0, node["id"]))
+    
+    edges = [
+        {"source": rel["start_node_id"], "target": rel["end_node_id"], "type": rel["type"],
+         "properties": rel["properties"]}
+        for rel in db.edges_between([node["id"] for node in nodes])
+    ]
+    edges.sort(key=lambda edge: (edge["source"], edge["target"], edge["type"]))
+    
+    logger.info(f"Collected {len(nodes)} nodes and {len(edges)} edges for export")
+    return nodes, edges
+
+
+def node_label(node: Dict[str, Any]) -> str:
+    """Display label: symbol kind and name, e.g. "Function parse"."""
+    return " ".join(part for part in (node.get("kind"), node.get("name") or node["id"]) if part)
+
+
+def _edge_attributes(edge: Dict[str, Any]) -> Dict[str, Any]:
+    """Scalar edge properties; lists and maps become JSON strings."""
+    attributes = {}
+    for key, value in sorted((edge.get("properties") or {}).items()):
+        if value is None:
+            continue
+        if not isinstance(value, (str, int, float, bool)):
+            value = json.dumps(value, ensure_ascii=False)
+        attributes[key] = value
+    return attributes
+
+
+def _graphml_type(value: Any) -> str:
+    if isinstance(value, bool):
+        return "boolean"
+    if isinstance(value, int):
+        return "long"
+    if isinstance(value, float):
+        return "double"
+    return "string"
+
+
+def escape_xml(value: Any) -> str:
+    """Escape text for XML content and attribute values, dropping characters XML cannot carry."""
+    if isinstance(value, bool):
+        value = "true" if value else "false"
+    text = _INVALID_CHARS.sub("", str(value))
+    return (text.replace("&", "&amp;").replace("<", "&lt;").replace(">", "&gt;")
+            .replace('"', "&quot;").replace("'", "&apos;"))
+
+
+def to_graphml(nodes: List[Dict[str, Any]], edges: List[Dict[str, Any]]) -> str:
+    """Serialize nodes and edges as a directed GraphML document."""
+    # Edge attribute keys are declared from the properties present; mixed types fall back to string
+    edge_key_types: Dict[str, str] = {}
+    edge_attributes = [_edge_attributes(edge) for edge in edges]
+    for attributes in edge_attributes:
+        for key, value in attributes.items():
+            value_type = _graphml_type(value)
+            if edge_key_types.setdefault(key, value_type) != value_type:
+                edge_key_types[key] = "string"
+    
+    lines = [
+        '<?xml version="1.0" encoding="UTF-8"?>',
+        '<graphml xmlns="http://graphml.graphdrawing.org/xmlns"',
+        '         xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance"',
+        '         xsi:schemaLocation="http://graphml.graphdrawing.org/xmlns http://graphml.graphdrawing.org/xmlns/1.0/graphml.xsd">',
+        '  <key id="label" for="node" attr.name="label" attr.type="string"/>',
+    ]
+    for name in NODE_ATTRIBUTES:
+        lines.append(f'  <key id="{name}" for="node" attr.name="{name}" attr.type="{NODE_ATTRIBUTE_TYPES.get(name, "string")}"/>')
+    lines.append('  <key id="edge_label" for="edge" attr.name="label" attr.type="string"/>')
+    for name, value_type in sorted(edge_key_types.items()):
+        lines.append(f'  <key id="edge_{escape_xml(name)}" for="edge" attr.name="{escape_xml(name)}" attr.type="{value_type}"/>')
+    lines.append('  <graph id="code_graph" edgedefault="directed">')
+    
+    for node in nodes:
+        lines.append(f'    <node id="{escape_xml(node["id"])}">')
+        lines.append(f'      <data key="label">{escape_xml(node_label(node))}</data>')
+        for name in NODE_ATTRIBUTES:
+            value = node.get(name)
+            if value is None and name == "file_path":
+                value = ""
+            if value is not None:
+                lines.append(f'      <data key="{name}">{escape_xml(value)}</data>')
+        lines.append('    </node>')
+    
+    for index, (edge, attributes) in enumerate(zip(edges, edge_attributes)):
+        lines.append(
+            f'    <edge id="e{index}" source="{escape_xml(edge["source"])}" target="{escape_xml(edge["target"])}">'
+        )
+        lines.append(f'      <data key="edge_label">{escape_xml(edge["type"])}</data>')
+        for name, value in attributes.items():
+            lines.append(f'      <data key="edge_{escape_xml(name)}">{escape_xml(value)}</data>')
+        lines.append('    </edge>')
+    
+    lines.append('  </graph>')
+    lines.append('</graphml>')
+    return "\n".join(lines) + "\n"
+
+
+def quote_dot(value: Any) -> str:
+    """Quote a DOT ID; backslashes, quotes and line breaks are escaped so labels stay literal."""
+    if isinstance(value, bool):
+        value = "true" if value else "false"
+    text = _INVALID_CHARS.sub("", str(value)).replace("\\", "\\\\").replace('"', '\\"')
+    text = text.replace("\r\n", "\n").replace("\r", "\n").replace("\n", "\\n")
+    return f'"{text}"'
+
+
+def _dot_attributes(attributes: Dict[str, Any]) -> str:
+    parts = []
+    for name, value in attributes.items():
+        key = name if _DOT_ID.match(name) else quote_dot(name)
+        parts.append(f"{key}={quote_dot(value)}")
+    return ", ".join(parts)
+
+
+def to_dot(nodes: List[Dict[str, Any]], edges: List[Dict[str, Any]]) -> str:
+    """Serialize nodes and edges as a Graphviz digraph."""
+    lines = ["digraph code_graph {", "  node [shape=box];"]
+    
+    for node in nodes:
+        attributes = {"label": node_label(node)}
+        for name in NODE_ATTRIBUTES:
+            value = node.get(name)
+            if value is None and name == "file_path":
+                value = ""
+            if value is not None:
+                attributes[name] = value
+        lines.append(f"  {quote_dot(node['id'])} [{_dot_attributes(attributes)}];")
+    
+    for edge in edges:
+        attributes = {"label": edge["type"]}
+        attributes.update(_edge_attributes(edge))
+        lines.append(f"  {quote_dot(edge['source'])} -> {quote_dot(edge['target'])} [{_dot_attributes(attributes)}];")
+    
+    lines.append("}")
+    return "\n".join(lines) + "\n"
+
+
+def export_graph(db, format: str = "graphml", path: Optional[str] = None, symbol: Optional[str] = None,
+                 hops: int = 1) -> Tuple[str, int, int]:
+    """
+    Export the scoped subgraph.
+    
+    Returns:
+        (document, node_count, edge_count)
+    
+    Raises:
+        ValueError: for an unknown format, a negative hop count or an unresolved symbol
+    """
+    format = (format or "").lower()
+    if format not in EXPORT_FORMATS:
+        raise ValueError(f"Unknown export format '{format}', expected one of: {', '.join(EXPORT_FORMATS)}")
+    
+    nodes, edges = collect_subgraph(db, path=path, symbol=symbol, hops=hops)
+    document = to_graphml(nodes, edges) if format == "graphml" else to_dot(nodes, edges)
+    return document, len(nodes), len(edges)
+
+
+def main():
+    """Export command entry point"""
+    parser = argparse.ArgumentParser(description="Export the code knowledge graph to GraphML or DOT")
+    parser.add_argument("--format", choices=EXPORT_FORMATS, default="graphml", help="Output format")
+    parser.add_argument("--output", help="Output file (default: stdout)")
+    parser.add_argument("--path", help="Only export nodes under this file or directory, e.g. 'src/api'")
+    parser.add_argument("--symbol", help="Only export nodes within --hops edges of this symbol (name, qualified name or node id)")
+    parser.add_argument("--hops", type=int, default=1, help="Neighbourhood radius for --symbol (default: 1)")
+    parser.add_argument("--storage", choices=STORAGE_BACKENDS, help="Storage backend (default: GRAPH_STORAGE or neo4j)")
+    parser.add_argument("--graph-file", help="JSON file of the memory backend (default: GRAPH_STORE_PATH)")
+    parser.add_argument("--neo4j-uri", help="Neo4j database URI")
+    parser.add_argument("--neo4j-user", help="Neo4j username")
+    parser.add_argument("--neo4j-password", help="Neo4j password")
+    
+    args = parser.parse_args()
+    
+    if get_storage_backend(args.storage) == "memory":
+        db = InMemoryGraphStore(get_graph_file(args.graph_file))
+    else:
+        from src.neo4j_storage.graph_db import Neo4jDatabase
+        
+        db = Neo4jDatabase(uri=args.neo4j_uri, user=args.neo4j_user, password=args.neo4j_password)
+    try:
+        document, node_count, edge_count = export_graph(
+            db, format=args.format, path=args.path, symbol=args.symbol, hops=args.hops
+        )
+    except ValueError as e:
+        parser.error(str(e))
+    finally:
+        db.close()
+    
+    if args.output:
+        with open(args.output, "w", encoding="utf-8") as f:
+            f.write(document)
+        logger.info(f"Exported {node_count} nodes and {edge_count} edges to {args.output}")
+    else:
+        sys.stdout.write(document)
+
+
+if __name__ == "__main__":
+    main()
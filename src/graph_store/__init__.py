@@ -0,0 +1,59 @@
+"""Graph storage backends (Neo4j, in-memory) behind the GraphStore interface, and per-repository views."""
+
+from src.graph_store.base import (
+    DEFAULT_REPO,
+    DIRECTIONS,
+    GraphStore,
+    UnsupportedQueryError,
+    node_repo,
+    node_sort_key,
+)
+from src.graph_store.factory import (
+    STORAGE_BACKENDS,
+    get_graph_file,
+    get_storage_backend,
+)
+from src.graph_store.memory_store import InMemoryGraphStore
+from src.graph_store.migrations import (
+    MIGRATIONS,
+    SCHEMA_VERSION,
+    MigrationError,
+    SchemaVersionError,
+    ensure_schema,
+    migrate_graph,
+    read_schema_version,
+)
+from src.graph_store.repository import (
+    ALL_REPOS,
+    RepositoryStore,
+    get_repo_name,
+    same_repo_id,
+    scoped_id,
+    split_id,
+)
+
+__all__ = [
+    'DEFAULT_REPO',
+    'DIRECTIONS',
+    'GraphStore',
+    'UnsupportedQueryError',
+    'node_repo',
+    'node_sort_key',
+    'STORAGE_BACKENDS',
+    'get_graph_file',
+    'get_storage_backend',
+    'InMemoryGraphStore',
+    'MIGRATIONS',
+    'SCHEMA_VERSION',
+    'MigrationError',
+    'SchemaVersionError',
+    'ensure_schema',
+    'migrate_graph',
+    'read_schema_version',
+    'ALL_REPOS',
+    'RepositoryStore',
+    'get_repo_name',
+    'same_repo_id',
+    'scoped_id',
+    'split_id',
+]
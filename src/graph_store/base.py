@@ -0,0 +1,334 @@
+"""
+Storage interface of the code graph.
+
+圖譜儲存介面
+The indexer writes through GraphStore and the MCP tools read through its
+query primitives (lookup, neighbors, shortest paths), so any backend that
+implements it can serve every tool. Neo4jDatabase is the default backend;
+InMemoryGraphStore keeps the graph in adjacency maps for one-off analysis
+and CI.
+
+Records use the format the indexer already writes:
+
+- node: {'labels': ['Base', 'Function'], 'properties': {'id': ..., 'name': ..., ...}}
+- relationship: {'start_node_id': ..., 'end_node_id': ..., 'type': 'CALLS', 'properties': {...}}
+
+Node records returned by the query primitives leave out the `embedding`
+vector; only the search methods work on it.
+
+Every node belongs to a repository, named by its `repo` property (nodes
+written before repositories existed have none and belong to
+DEFAULT_REPO). Methods taking `repo` only touch the nodes of that
+repository (relationships: by their start node), or of every repository
+when it is None; src/graph_store/repository.py has the one-repository
+view the indexer writes through.
+"""
+
+from abc import ABC, abstractmethod
+from typing import Any, Dict, List, Optional, Set, Tuple
+
+# Node label every node carries
+BASE_LABEL = "Base"
+
+# Property holding a node's vector
+EMBEDDING_PROPERTY = "embedding"
+
+# Directions of neighbors() and shortest_paths(), relative to the given nodes
+DIRECTIONS = ("out", "in", "both")
+
+# Property naming the repository of a node, and the repository of nodes without it
+REPO_PROPERTY = "repo"
+DEFAULT_REPO = "default"
+
+
+class UnsupportedQueryError(Exception):
+    """The backend cannot run raw queries (e.g. Cypher on the in-memory store)."""
+
+
+def node_sort_key(node: Dict[str, Any]) -> Tuple[str, int, str]:
+    """Order of node records returned by the query primitives: file, line, id."""
+    properties = node["properties"]
+    return (properties.get("file_path") or "", properties.get("line_no") or 0, properties.get("id") or "")
+
+
+def node_repo(properties: Dict[str, Any]) -> str:
+    """Repository of a node, given its properties."""
+    return properties.get(REPO_PROPERTY) or DEFAULT_REPO
+
+
+def relationship_confidence(properties: Dict[str, Any]) -> Optional[float]:
+    """`confidence` of a relationship, 1.0 when it has none and None when it is not a number."""
+    value = properties.get("confidence", 1.0)
+    if isinstance(value, (int, float)) and not isinstance(value, bool):
+        return float(value)
+    return None
+
+
+def check_direction(direction: str) -> str:
+    """Return direction if it is one of DIRECTIONS, otherwise raise ValueError."""
+    if direction not in DIRECTIONS:
+        raise ValueError(f"Unknown direction '{direction}', expected one of: {', '.join(DIRECTIONS)}")
+    return direction
+
+
+class GraphStore(ABC):
+    """
+    Backend of the code graph.
+    
+    Write methods are used by the indexer (through GraphBatchWriter), index
+    state methods by incremental runs, query primitives by the MCP tools.
+    Schema methods are optional and do nothing by default.
+    """
+    
+    # Lifecycle
+    
+    @abstractmethod
+    def verify_connection(self) -> bool:
+        """Whether the backend is reachable."""
+        raise NotImplementedError
+    
+    @abstractmethod
+    def close(self) -> None:
+        """Persist pending state and release resources."""
+        raise NotImplementedError
+    
+    def flush(self) -> None:
+        """Persist pending state; backends that write through do nothing."""
+    
+    # Schema (optional)
+    
+    def create_schema_constraints(self) -> None:
+        """Create constraints and lookup indexes."""
+    
+    def create_vector_index(self, index_name: str, node_label: str, property_name: str, dimension: int = 1536) -> None:
+        """Create a vector index for similarity search."""
+    
+    def create_full_text_index(self, index_name: str, node_labels: List[str], properties: List[str]) -> None:
+        """Create a full-text index over node properties."""
+    
+    # Writes
+    
+    @abstractmethod
+    def batch_create_nodes(self, nodes: List[Dict[str, Any]], batch_size: Optional[int] = None) -> None:
+        """Add or replace nodes (merged on properties['id'], labels are added)."""
+        raise NotImplementedError
+    
+    @abstractmethod
+    def batch_create_relationships(self, relationships: List[Dict[str, Any]], batch_size: Optional[int] = None) -> None:
+        """Add relationships; those with a missing endpoint are skipped."""
+        raise NotImplementedError
+    
+    @abstractmethod
+    def clear_database(self) -> None:
+        """Delete every node and relationship."""
+        raise NotImplementedError
+    
+    @abstractmethod
+    def delete_file_scope(self, file_paths: List[str], repo: Optional[str] = None) -> int:
+        """Delete the nodes whose file_path is one of file_paths, with their relationships; return the count."""
+        raise NotImplementedError
+    
+    @abstractmethod
+    def delete_structural_relationships(self, repo: Optional[str] = None) -> int:
+        """Delete relationships flagged `structural` (derived from the whole index); return the count."""
+        raise NotImplementedError
+    
+    @abstractmethod
+    def delete_stale_structural_relationships(self, premise_files: List[str], keys: List[Tuple[str, str, str]],
+                                              repo: Optional[str] = None) -> List[Tuple[str, str, str]]:
+        """
+        Delete the structural relationships an incremental run recomputed.
+        
+        A relationship is stale when its `premises` (JSON list of file
+        paths) include one of premise_files, when it has no premises, or
+        when its (start id, type, end id) is one of keys. Every structural
+        relationship sharing the key of a stale one is deleted too.
+        
+        Returns:
+            (start id, type, end id) of the deleted relationships, sorted
+        """
+        raise NotImplementedError
+    
+    @abstractmethod
+    def delete_cross_repo_relationships(self) -> int:
+        """Delete relationships flagged `cross_repo` (derived from every repository's index); return the count."""
+        raise NotImplementedError
+    
+    @abstractmethod
+    def delete_orphan_placeholders(self, repo: Optional[str] = None) -> int:
+        """Delete `placeholder` and Package nodes without relationships; return the count."""
+        raise NotImplementedError
+    
+    @abstractmethod
+    def update_file_mtimes(self, updates: List[Tuple[str, float]], repo: Optional[str] = None) -> None:
+        """Set the mtime of File nodes, given (file_path, mtime) pairs."""
+        raise NotImplementedError
+    
+    @abstractmethod
+    def update_file_index_states(self, updates: List[Tuple[str, str]], repo: Optional[str] = None) -> None:
+        """Set the index_state of File nodes, given (file_path, index_state) pairs."""
+        raise NotImplementedError
+    
+    @abstractmethod
+    def update_node_properties(self, updates: List[Tuple[str, Dict[str, Any]]]) -> int:
+        """
+        Set properties of existing nodes, other properties are kept.
+        
+        Args:
+            updates: (node_id, properties) pairs; a None value removes the property, "id" is ignored
+        
+        Returns:
+            Number of nodes updated
+        """
+        raise NotImplementedError
+    
+    @abstractmethod
+    def delete_repository(self, repo: str) -> int:
+        """Delete every node of a repository, with its relationships; return the count."""
+        raise NotImplementedError
+    
+    # Index state
+    
+    @abstractmethod
+    def get_file_states(self, repo: Optional[str] = None) -> Dict[str, Dict[str, Any]]:
+        """file_path -> content_hash, mtime, size, index_state and parse_error of every File node."""
+        raise NotImplementedError
+    
+    @abstractmethod
+    def get_dependent_files(self, file_paths: List[str], repo: Optional[str] = None) -> List[str]:
+        """Paths of files with a DEPENDS_ON_FILE relationship into one of file_paths."""
+        raise NotImplementedError
+    
+    @abstractmethod
+    def get_existing_node_ids(self, node_ids: List[str]) -> Set[str]:
+        """The subset of node_ids present in the store."""
+        raise NotImplementedError
+    
+    @abstractmethod
+    def get_node_embeddings(self, file_paths: List[str], repo: Optional[str] = None) -> Dict[str, List[float]]:
+        """embedding_key -> vector of the embedded nodes in file_paths."""
+        raise NotImplementedError
+    
+    # Query primitives
+    
+    @abstractmethod
+    def get_nodes(self, node_ids: List[str]) -> List[Dict[str, Any]]:
+        """Node records for the ids that exist, in the order of node_ids."""
+        raise NotImplementedError
+    
+    @abstractmethod
+    def find_nodes(self, name: Optional[str] = None, label: Optional[str] = None,
+                   properties: Optional[Dict[str, Any]] = None, path_prefixes: Optional[List[str]] = None,
+                   limit: Optional[int] = None, repo: Optional[str] = None) -> List[Dict[str, Any]]:
+        """
+        Node records matching every given filter, ordered by node_sort_key.
+        
+        Args:
+            name: Exact name
+            label: Label the node must carry (e.g. "Class")
+            properties: Exact property values (e.g. {"path": "src/app.py"})
+            path_prefixes: file_path equal to, or inside the directory of, one of these
+            limit: Maximum number of records
+            repo: Repository the node belongs to
+        """
+        raise NotImplementedError
+    
+    @abstractmethod
+    def node_names(self, labels: List[str], limit: Optional[int] = None, repo: Optional[str] = None) -> List[str]:
+        """
+        Distinct names of the nodes carrying one of labels, sorted.
+        
+        Args:
+            labels: Labels a node may carry (e.g. ["Function", "Method"])
+            limit: Maximum number of names
+            repo: Repository the nodes belong to
+        """
+        raise NotImplementedError
+    
+    @abstractmethod
+    def neighbors(self, node_ids: List[str], relation_types: Optional[List[str]] = None, direction: str = "out",
+                  label: Optional[str] = None) -> List[Dict[str, Any]]:
+        """
+        Relationships of the given nodes and the nodes at their other end.
+        
+        Args:
+            node_ids: Nodes to start from
+            relation_types: Only these relationship types (default: all)
+            direction: "out" (node is the start), "in" (node is the end) or "both"
+            label: Label the neighbour must carry
+        
+        Returns:
+            [{'origin_id': ..., 'relationship': relationship record, 'node': neighbour node record}],
+            ordered by origin, node_sort_key of the neighbour and relationship type
+        """
+        raise NotImplementedError
+    
+    @abstractmethod
+    def shortest_paths(self, source_id: str, target_id: str, relation_types: List[str], direction: str = "out",
+                       max_depth: int = 10, limit: int = 1, min_confidence: float = 0.0) -> List[Dict[str, Any]]:
+        """
+        Shortest paths of at least one hop from source to target.
+        
+        Args:
+            relation_types: Relationship types a path may use
+            direction: "out" follows relationships forward, "in" backward, "both" either way
+            max_depth: Maximum number of hops
+            limit: Number of paths; above 1 returns the equally short alternatives
+            min_confidence: Above 0, only relationships whose `confidence` (1.0 when missing) is at least this
+        
+        Returns:
+            [{'nodes': [node records from source to target], 'relationships': [relationship records in path order]}]
+        """
+        raise NotImplementedError
+    
+    @abstractmethod
+    def edges_between(self, node_ids: List[str]) -> List[Dict[str, Any]]:
+        """Relationship records whose endpoints are both in node_ids."""
+        raise NotImplementedError
+    
+    @abstractmethod
+    def count_by_type(self, repo: Optional[str] = None) -> Dict[str, Dict[str, int]]:
+        """
+        Node counts by label and relationship counts by type.
+        
+        Returns:
+            {'nodes': {label: count}, 'relationships': {type: count}}; every node
+            carries BASE_LABEL, so its count is the number of nodes
+        """
+        raise NotImplementedError
+    
+    # Search
+    
+    @abstractmethod
+    def search_similar_nodes(self, vector: List[float], node_labels: List[str], limit: int = 10) -> List[Dict[str, Any]]:
+        """Nodes of node_labels closest to vector: [{'node': properties, 'labels': [...], 'score': ...}], best first."""
+        raise NotImplementedError
+    
+    @abstractmethod
+    def search_code_by_vector(self, vector: List[float], node_label: str, limit: int = 10) -> List[Dict[str, Any]]:
+        """Nodes of one label by cosine similarity: [{'node': properties, 'score': ...}], best first."""
+        raise NotImplementedError
+    
+    @abstractmethod
+    def search_code_by_text(self, query: str, limit: int = 10) -> List[Dict[str, Any]]:
+        """Nodes whose name or code matches the query text: [{'node': properties, 'score': ...}], best first."""
+        raise NotImplementedError
+    
+    def execute_cypher(self, query: str, parameters: Dict = None) -> List[Dict[str, Any]]:
+        """Run a raw Cypher query; only backends that speak Cypher support it."""
+        raise UnsupportedQueryError(
+            f"{type(self).__name__} does not run Cypher queries, use the graph tools or the neo4j storage backend"
+        )
+    
+    def execute_read_query(self, query: str, parameters: Dict = None, max_rows: Optional[int] = None,
+                           timeout: Optional[float] = None) -> List[Dict[str, Any]]:
+        """
+        Run a raw Cypher query in a read transaction, so a query that writes fails.
+        
+        Args:
+            max_rows: Rows fetched at most; the rest of the result is discarded
+            timeout: Seconds after which the database cancels the query
+        """
+        raise UnsupportedQueryError(
+            f"{type(self).__name__} does not run Cypher queries, use the graph tools or the neo4j storage backend"
+        )
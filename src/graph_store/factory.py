@@ -0,0 +1,35 @@
+"""
+Storage backend selection.
+
+GRAPH_STORAGE (or --storage) picks the backend: "neo4j" (default) or
+"memory". GRAPH_STORE_PATH (or --graph-file) is the JSON file the
+in-memory store loads from and saves to.
+"""
+
+import os
+from typing import Optional
+
+STORAGE_BACKENDS = ("neo4j", "memory")
+
+DEFAULT_STORAGE = "neo4j"
+
+
+def get_storage_backend(storage: Optional[str] = None) -> str:
+    """
+    Resolve the storage backend name.
+    
+    Args:
+        storage: Backend name, if None, get from GRAPH_STORAGE (default: neo4j)
+    
+    Raises:
+        ValueError: for an unknown backend
+    """
+    storage = (storage or os.getenv("GRAPH_STORAGE") or DEFAULT_STORAGE).strip().lower()
+    if storage not in STORAGE_BACKENDS:
+        raise ValueError(f"Unknown storage backend '{storage}', expected one of: {', '.join(STORAGE_BACKENDS)}")
+    return storage
+
+
+def get_graph_file(graph_file: Optional[str] = None) -> Optional[str]:
+    """JSON file of the in-memory store, if None, get from GRAPH_STORE_PATH (default: not persisted)."""
+    return graph_file or os.getenv("GRAPH_STORE_PATH") or None
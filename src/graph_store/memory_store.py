@@ -0,0 +1,589 @@
+"""
+In-memory graph store.
+
+記憶體圖譜儲存
+Keeps nodes in a dict by id and relationships in outgoing / incoming
+adjacency maps, with name and file indexes for lookups. Nothing needs to
+be running, which suits one-off analysis and CI. With a path, the graph
+is loaded from a JSON file on start-up and written back on flush() and
+close(), so a graph built in CI can be saved as an artifact and served
+later:
+    
+    python src/main.py --codebase-path . --storage memory --graph-file graph.json
+    python src/mcp/server.py --storage memory --graph-file graph.json
+
+All methods take one lock, so the background graph writer, the watcher
+and MCP tool calls can share a store.
+"""
+
+import json
+import logging
+import math
+import os
+import re
+import threading
+from collections import defaultdict
+from typing import Any, Dict, Iterable, List, Optional, Set, Tuple
+
+from src.graph_store.base import (
+    EMBEDDING_PROPERTY,
+    GraphStore,
+    check_direction,
+    node_repo,
+    node_sort_key,
+    relationship_confidence,
+)
+
+logger = logging.getLogger(__name__)
+
+# Version of the JSON file layout
+FILE_FORMAT_VERSION = 1
+
+_WORD = re.compile(r"\w+")
+
+
+def _cosine(a: List[float], b: List[float]) -> float:
+    dot = sum(x * y for x, y in zip(a, b))
+    norm = math.sqrt(sum(x * x for x in a)) * math.sqrt(sum(y * y for y in b))
+    return dot / norm if norm else 0.0
+
+
+class InMemoryGraphStore(GraphStore):
+    """Graph store backed by adjacency maps, optionally persisted to a JSON file."""
+    
+    def __init__(self, path: Optional[str] = None):
+        """
+        Args:
+            path: JSON file to load on start-up (if it exists) and to write on flush/close
+        """
+        self.path = path
+        self._lock = threading.RLock()
+        self._nodes: Dict[str, Dict[str, Any]] = {}
+        self._out: Dict[str, List[Dict[str, Any]]] = defaultdict(list)
+        self._in: Dict[str, List[Dict[str, Any]]] = defaultdict(list)
+        self._by_name: Dict[str, Set[str]] = defaultdict(set)
+        self._by_file: Dict[str, Set[str]] = defaultdict(set)
+        self._dirty = False
+        if path and os.path.exists(path):
+            self.load(path)
+    
+    # Lifecycle
+    
+    def verify_connection(self) -> bool:
+        return True
+    
+    def close(self) -> None:
+        self.flush()
+    
+    def flush(self) -> None:
+        """Write the graph to the store's file if anything changed since the last write."""
+        with self._lock:
+            if self.path and self._dirty:
+                self.save(self.path)
+    
+    def save(self, path: str) -> None:
+        """Write every node and relationship to a JSON file (atomically replaced)."""
+        with self._lock:
+            document = {
+                "version": FILE_FORMAT_VERSION,
+                "nodes": list(self._nodes.values()),
+                "relationships": [rel for rels in self._out.values() for rel in rels],
+            }
+            directory = os.path.dirname(os.path.abspath(path))
+            os.makedirs(directory, exist_ok=True)
+            temp_path = f"{path}.tmp"
+            with open(temp_path, "w", encoding="utf-8") as f:
+                json.dump(document, f, ensure_ascii=False)
+            os.replace(temp_path, path)
+            if path == self.path:
+                self._dirty = False
+            logger.info(f"Saved {len(document['nodes'])} nodes and {len(document['relationships'])} relationships to {path}")
+    
+    def load(self, path: str) -> None:
+        """Replace the graph with the contents of a JSON file written by save()."""
+        with open(path, "r", encoding="utf-8") as f:
+            document = json.load(f)
+        if document.get("version") != FILE_FORMAT_VERSION:
+            raise ValueError(f"Unsupported graph file version {document.get('version')!r} in {path}")
+        with self._lock:
+            self._reset()
+            self._add_nodes(document.get("nodes") or [])
+            self._add_relationships(document.get("relationships") or [])
+            self._dirty = path != self.path
+        logger.info(f"Loaded {len(self._nodes)} nodes from {path}")
+    
+    # Writes
+    
+    def batch_create_nodes(self, nodes: List[Dict[str, Any]], batch_size: Optional[int] = None) -> None:
+        with self._lock:
+            self._add_nodes(nodes)
+            self._dirty = True
+    
+    def batch_create_relationships(self, relationships: List[Dict[str, Any]], batch_size: Optional[int] = None) -> None:
+        with self._lock:
+            self._add_relationships(relationships)
+            self._dirty = True
+    
+    def clear_database(self) -> None:
+        with self._lock:
+            self._reset()
+            self._dirty = True
+    
+    def delete_file_scope(self, file_paths: List[str], repo: Optional[str] = None) -> int:
+        with self._lock:
+            node_ids = {node_id for path in file_paths for node_id in self._by_file.get(path, ())
+                        if _in_repo(self._nodes[node_id], repo)}
+            for node_id in node_ids:
+                self._delete_node(node_id)
+            if node_ids:
+                self._dirty = True
+            return len(node_ids)
+    
+    def delete_structural_relationships(self, repo: Optional[str] = None) -> int:
+        with self._lock:
+            deleted = self._delete_relationships(lambda rel: (
+                rel["properties"].get("structural") is True and _in_repo(self._nodes[rel["start_node_id"]], repo)
+            ))
+            if deleted:
+                self._dirty = True
+            return deleted
+    
+    def delete_stale_structural_relationships(self, premise_files: List[str], keys: List[Tuple[str, str, str]],
+                                              repo: Optional[str] = None) -> List[Tuple[str, str, str]]:
+        files = set(premise_files)
+        wanted = set(map(tuple, keys))
+        
+        def key_of(rel):
+            return rel["start_node_id"], rel["type"], rel["end_node_id"]
+        
+        def structural(rel):
+            return rel["properties"].get("structural") is True and _in_repo(self._nodes[rel["start_node_id"]], repo)
+        
+        with self._lock:
+            stale = set()
+            for rels in self._out.values():
+                for rel in rels:
+                    if not structural(rel):
+                        continue
+                    premises = _premises(rel)
+                    if key_of(rel) in wanted or not premises or files.intersection(premises):
+                        stale.add(key_of(rel))
+            if self._delete_relationships(lambda rel: structural(rel) and key_of(rel) in stale):
+                self._dirty = True
+            return sorted(stale)
+    
+    def delete_cross_repo_relationships(self) -> int:
+        with self._lock:
+            deleted = self._delete_relationships(lambda rel: rel["properties"].get("cross_repo") is True)
+            if deleted:
+                self._dirty = True
+            return deleted
+    
+    def delete_orphan_placeholders(self, repo: Optional[str] = None) -> int:
+        with self._lock:
+            orphans = [
+                node_id for node_id, node in self._nodes.items()
+                if (node["properties"].get("placeholder") is True or "Package" in node["labels"])
+                and not self._out.get(node_id) and not self._in.get(node_id) and _in_repo(node, repo)
+            ]
+            for node_id in orphans:
+                self._delete_node(node_id)
+            if orphans:
+                self._dirty = True
+            return len(orphans)
+    
+    def update_file_mtimes(self, updates: List[Tuple[str, float]], repo: Optional[str] = None) -> None:
+        with self._lock:
+            for path, mtime in updates:
+                for node in self._file_nodes([path], repo):
+                    node["properties"]["mtime"] = mtime
+                    self._dirty = True
+    
+    def update_file_index_states(self, updates: List[Tuple[str, str]], repo: Optional[str] = None) -> None:
+        with self._lock:
+            for path, index_state in updates:
+                for node in self._file_nodes([path], repo):
+                    node["properties"]["index_state"] = index_state
+                    self._dirty = True
+    
+    def update_node_properties(self, updates: List[Tuple[str, Dict[str, Any]]]) -> int:
+        with self._lock:
+            updated = 0
+            for node_id, properties in updates:
+                node = self._nodes.get(node_id)
+                if node is None:
+                    continue
+                self._unindex(node)
+                for key, value in properties.items():
+                    if key == "id":
+                        continue
+                    if value is None:
+                        node["properties"].pop(key, None)
+                    else:
+                        node["properties"][key] = value
+                self._index(node)
+                updated += 1
+            if updated:
+                self._dirty = True
+            return updated
+    
+    def delete_repository(self, repo: str) -> int:
+        with self._lock:
+            node_ids = [node_id for node_id, node in self._nodes.items() if node_repo(node["properties"]) == repo]
+            for node_id in node_ids:
+                self._delete_node(node_id)
+            if node_ids:
+                self._dirty = True
+            return len(node_ids)
+    
+    # Index state
+    
+    def get_file_states(self, repo: Optional[str] = None) -> Dict[str, Dict[str, Any]]:
+        with self._lock:
+            return {
+                node["properties"]["file_path"]: {
+                    "content_hash": node["properties"].get("content_hash"),
+                    "mtime": node["properties"].get("mtime"),
+                    "size": node["properties"].get("size"),
+                    "index_state": node["properties"].get("index_state"),
+                    "parse_error": node["properties"].get("parse_error"),
+                }
+                for node in self._nodes.values()
+                if "File" in node["labels"] and node["properties"].get("file_path") is not None and _in_repo(node, repo)
+            }
+    
+    def get_dependent_files(self, file_paths: List[str], repo: Optional[str] = None) -> List[str]:
+        with self._lock:
+            dependents = []
+            for target in self._file_nodes(file_paths, repo):
+                for rel in self._in.get(target["properties"]["id"], ()):
+                    source = self._nodes[rel["start_node_id"]]
+                    path = source["properties"].get("file_path")
+                    if rel["type"] == "DEPENDS_ON_FILE" and "File" in source["labels"] and path not in dependents:
+                        dependents.append(path)
+            return dependents
+    
+    def get_existing_node_ids(self, node_ids: List[str]) -> Set[str]:
+        with self._lock:
+            return {node_id for node_id in node_ids if node_id in self._nodes}
+    
+    def get_node_embeddings(self, file_paths: List[str], repo: Optional[str] = None) -> Dict[str, List[float]]:
+        with self._lock:
+            embeddings = {}
+            for path in file_paths:
+                for node_id in self._by_file.get(path, ()):
+                    properties = self._nodes[node_id]["properties"]
+                    if not _in_repo(self._nodes[node_id], repo):
+                        continue
+                    if properties.get("embedding_key") is not None and properties.get(EMBEDDING_PROPERTY) is not None:
+                        embeddings[properties["embedding_key"]] = list(properties[EMBEDDING_PROPERTY])
+            return embeddings
+    
+    # Query primitives
+    
+    def get_nodes(self, node_ids: List[str]) -> List[Dict[str, Any]]:
+        with self._lock:
+            return [self._record(self._nodes[node_id]) for node_id in dict.fromkeys(node_ids) if node_id in self._nodes]
+    
+    def find_nodes(self, name: Optional[str] = None, label: Optional[str] = None,
+                   properties: Optional[Dict[str, Any]] = None, path_prefixes: Optional[List[str]] = None,
+                   limit: Optional[int] = None, repo: Optional[str] = None) -> List[Dict[str, Any]]:
+        with self._lock:
+            candidates: Iterable[Dict[str, Any]]
+            if name is not None:
+                candidates = [self._nodes[node_id] for node_id in self._by_name.get(name, ())]
+            else:
+                candidates = self._nodes.values()
+            matches = [
+                self._record(node) for node in candidates
+                if (label is None or label in node["labels"])
+                and all(node["properties"].get(key) == value for key, value in (properties or {}).items())
+                and (not path_prefixes or _under_prefix(node["properties"].get("file_path"), path_prefixes))
+                and _in_repo(node, repo)
+            ]
+        matches.sort(key=node_sort_key)
+        return matches[:limit] if limit is not None else matches
+    
+    def node_names(self, labels: List[str], limit: Optional[int] = None, repo: Optional[str] = None) -> List[str]:
+        wanted = set(labels)
+        with self._lock:
+            names = sorted(
+                name for name, node_ids in self._by_name.items()
+                if name and any(wanted.intersection(self._nodes[node_id]["labels"]) and _in_repo(self._nodes[node_id], repo)
+                                for node_id in node_ids)
+            )
+        return names[:limit] if limit is not None else names
+    
+    def neighbors(self, node_ids: List[str], relation_types: Optional[List[str]] = None, direction: str = "out",
+                  label: Optional[str] = None) -> List[Dict[str, Any]]:
+        check_direction(direction)
+        types = set(relation_types) if relation_types is not None else None
+        results = []
+        with self._lock:
+            for origin_id in dict.fromkeys(node_ids):
+                found = []
+                for rel, other_id in self._adjacent(origin_id, direction):
+                    if types is not None and rel["type"] not in types:
+                        continue
+                    other = self._nodes[other_id]
+                    if label is not None and label not in other["labels"]:
+                        continue
+                    found.append({"origin_id": origin_id, "relationship": _copy_relationship(rel), "node": self._record(other)})
+                found.sort(key=lambda item: (node_sort_key(item["node"]), item["relationship"]["type"]))
+                results.extend(found)
+        return results
+    
+    def shortest_paths(self, source_id: str, target_id: str, relation_types: List[str], direction: str = "out",
+                       max_depth: int = 10, limit: int = 1, min_confidence: float = 0.0) -> List[Dict[str, Any]]:
+        check_direction(direction)
+        types = set(relation_types)
+        
+        def traversable(rel):
+            if rel["type"] not in types:
+                return False
+            if min_confidence <= 0:
+                return True
+            confidence = relationship_confidence(rel["properties"])
+            return confidence is not None and confidence >= min_confidence
+        
+        with self._lock:
+            if source_id not in self._nodes or target_id not in self._nodes:
+                return []
+            
+            # Breadth-first by levels; every node keeps all (previous node, relationship) steps that reach it at its depth
+            depth_of = {source_id: 0}
+            steps_into: Dict[str, List[Tuple[str, Dict[str, Any]]]] = defaultdict(list)
+            frontier = [source_id]
+            depth = 0
+            found = False
+            while frontier and depth < max_depth and not found:
+                depth += 1
+                next_frontier = []
+                for node_id in frontier:
+                    for rel, other_id in self._adjacent(node_id, direction):
+                        if not traversable(rel):
+                            continue
+                        if other_id == target_id:
+                            found = True
+                        if depth_of.get(other_id, depth) == depth:
+                            if other_id not in depth_of:
+                                depth_of[other_id] = depth
+                                next_frontier.append(other_id)
+                            steps_into[other_id].append((node_id, rel))
+                frontier = next_frontier
+            if not found:
+                return []
+            
+            paths: List[Tuple[List[str], List[Dict[str, Any]]]] = []
+            self._collect_paths(source_id, target_id, steps_into, [target_id], [], paths, limit)
+            paths.sort(key=lambda path: path[0])
+            return [
+                {
+                    "nodes": [self._record(self._nodes[node_id]) for node_id in node_ids],
+                    "relationships": [_copy_relationship(rel) for rel in rels],
+                }
+                for node_ids, rels in paths
+            ]
+    
+    def edges_between(self, node_ids: List[str]) -> List[Dict[str, Any]]:
+        wanted = set(node_ids)
+        with self._lock:
+            return [
+                _copy_relationship(rel)
+                for node_id in node_ids if node_id in self._nodes
+                for rel in self._out.get(node_id, ())
+                if rel["end_node_id"] in wanted
+            ]
+    
+    def count_by_type(self, repo: Optional[str] = None) -> Dict[str, Dict[str, int]]:
+        nodes: Dict[str, int] = defaultdict(int)
+        relationships: Dict[str, int] = defaultdict(int)
+        with self._lock:
+            for node_id, node in self._nodes.items():
+                if not _in_repo(node, repo):
+                    continue
+                for label in node["labels"]:
+                    nodes[label] += 1
+                for rel in self._out.get(node_id, ()):
+                    relationships[rel["type"]] += 1
+        return {"nodes": dict(sorted(nodes.items())), "relationships": dict(sorted(relationships.items()))}
+    
+    # Search
+    
+    def search_similar_nodes(self, vector: List[float], node_labels: List[str], limit: int = 10) -> List[Dict[str, Any]]:
+        """Cosine similarity on the vector index scale, (1 + cosine) / 2."""
+        scored = [
+            {"node": self._record(node)["properties"], "labels": list(node["labels"]), "score": (1 + similarity) / 2}
+            for node, similarity in self._vector_matches(vector, node_labels)
+        ]
+        scored.sort(key=lambda item: item["score"], reverse=True)
+        return scored[:limit]
+    
+    def search_code_by_vector(self, vector: List[float], node_label: str, limit: int = 10) -> List[Dict[str, Any]]:
+        """Raw cosine similarity, like gds.similarity.cosine."""
+        scored = [
+            {"node": dict(node["properties"]), "score": similarity}
+            for node, similarity in self._vector_matches(vector, [node_label])
+        ]
+        scored.sort(key=lambda item: item["score"], reverse=True)
+        return scored[:limit]
+    
+    def search_code_by_text(self, query: str, limit: int = 10) -> List[Dict[str, Any]]:
+        """Score nodes by the share of query words found in their name and code snippet."""
+        words = {word.lower() for word in _WORD.findall(query)}
+        if not words:
+            return []
+        labels = ("Function", "Method", "Class", "File")
+        results = []
+        with self._lock:
+            for node in self._nodes.values():
+                if not any(label in node["labels"] for label in labels):
+                    continue
+                properties = node["properties"]
+                text = f"{properties.get('name') or ''} {properties.get('code_snippet') or ''}"
+                found = words & {word.lower() for word in _WORD.findall(text)}
+                if found:
+                    results.append({"node": dict(properties), "score": len(found) / len(words)})
+        results.sort(key=lambda item: (-item["score"], item["node"].get("id") or ""))
+        return results[:limit]
+    
+    # Internals
+    
+    def _vector_matches(self, vector: List[float], node_labels: List[str]) -> List[Tuple[Dict[str, Any], float]]:
+        with self._lock:
+            return [
+                (node, _cosine(vector, node["properties"][EMBEDDING_PROPERTY]))
+                for node in self._nodes.values()
+                if node["properties"].get(EMBEDDING_PROPERTY) and any(label in node["labels"] for label in node_labels)
+            ]
+    
+    def _reset(self) -> None:
+        self._nodes.clear()
+        self._out.clear()
+        self._in.clear()
+        self._by_name.clear()
+        self._by_file.clear()
+    
+    def _add_nodes(self, nodes: Iterable[Dict[str, Any]]) -> None:
+        for node in nodes:
+            properties = dict(node["properties"])
+            node_id = properties["id"]
+            existing = self._nodes.get(node_id)
+            if existing is not None:
+                # Same as MERGE ... SET n = row: properties are replaced, labels added
+                self._unindex(existing)
+                labels = list(dict.fromkeys(existing["labels"] + list(node["labels"])))
+            else:
+                labels = list(dict.fromkeys(node["labels"]))
+            stored = {"labels": labels, "properties": properties}
+            self._nodes[node_id] = stored
+            self._index(stored)
+    
+    def _add_relationships(self, relationships: Iterable[Dict[str, Any]]) -> None:
+        for rel in relationships:
+            if rel["start_node_id"] not in self._nodes or rel["end_node_id"] not in self._nodes:
+                continue
+            stored = _copy_relationship(rel)
+            self._out[stored["start_node_id"]].append(stored)
+            self._in[stored["end_node_id"]].append(stored)
+    
+    def _index(self, node: Dict[str, Any]) -> None:
+        properties = node["properties"]
+        if properties.get("name") is not None:
+            self._by_name[properties["name"]].add(properties["id"])
+        if properties.get("file_path") is not None:
+            self._by_file[properties["file_path"]].add(properties["id"])
+    
+    def _unindex(self, node: Dict[str, Any]) -> None:
+        properties = node["properties"]
+        self._by_name.get(properties.get("name"), set()).discard(properties["id"])
+        self._by_file.get(properties.get("file_path"), set()).discard(properties["id"])
+    
+    def _delete_node(self, node_id: str) -> None:
+        node = self._nodes.pop(node_id, None)
+        if node is None:
+            return
+        self._unindex(node)
+        for rel in self._out.pop(node_id, []):
+            self._in[rel["end_node_id"]] = [r for r in self._in.get(rel["end_node_id"], []) if r is not rel]
+        for rel in self._in.pop(node_id, []):
+            self._out[rel["start_node_id"]] = [r for r in self._out.get(rel["start_node_id"], []) if r is not rel]
+    
+    def _delete_relationships(self, predicate) -> int:
+        deleted = 0
+        for node_id in list(self._out):
+            kept = [rel for rel in self._out[node_id] if not predicate(rel)]
+            deleted += len(self._out[node_id]) - len(kept)
+            self._out[node_id] = kept
+        for node_id in list(self._in):
+            self._in[node_id] = [rel for rel in self._in[node_id] if not predicate(rel)]
+        return deleted
+    
+    def _file_nodes(self, file_paths: Iterable[str], repo: Optional[str] = None) -> List[Dict[str, Any]]:
+        return [
+            self._nodes[node_id]
+            for path in file_paths
+            for node_id in sorted(self._by_file.get(path, ()))
+            if "File" in self._nodes[node_id]["labels"] and _in_repo(self._nodes[node_id], repo)
+        ]
+    
+    def _adjacent(self, node_id: str, direction: str):
+        """(relationship, other end) pairs of a node in one direction."""
+        if direction in ("out", "both"):
+            for rel in self._out.get(node_id, ()):
+                yield rel, rel["end_node_id"]
+        if direction in ("in", "both"):
+            for rel in self._in.get(node_id, ()):
+                yield rel, rel["start_node_id"]
+    
+    def _collect_paths(self, source_id, node_id, steps_into, node_ids, rels, paths, limit) -> None:
+        """Walk the recorded steps back from node_id to the source, keeping at most limit simple paths."""
+        if len(paths) >= limit:
+            return
+        if node_id == source_id and rels:
+            paths.append((list(reversed(node_ids)), list(reversed(rels))))
+            return
+        for previous_id, rel in sorted(steps_into.get(node_id, ()), key=lambda step: (step[0], step[1]["type"])):
+            if previous_id in node_ids:
+                continue
+            node_ids.append(previous_id)
+            rels.append(rel)
+            self._collect_paths(source_id, previous_id, steps_into, node_ids, rels, paths, limit)
+            node_ids.pop()
+            rels.pop()
+    
+    @staticmethod
+    def _record(node: Dict[str, Any]) -> Dict[str, Any]:
+        properties = {key: value for key, value in node["properties"].items() if key != EMBEDDING_PROPERTY}
+        return {"labels": list(node["labels"]), "properties": properties}
+
+
+def _copy_relationship(rel: Dict[str, Any]) -> Dict[str, Any]:
+    return {
+        "start_node_id": rel["start_node_id"],
+        "end_node_id": rel["end_node_id"],
+        "type": rel["type"],
+        "properties": dict(rel.get("properties") or {}),
+    }
+
+
+def _in_repo(node: Dict[str, Any], repo: Optional[str]) -> bool:
+    return repo is None or node_repo(node["properties"]) == repo
+
+
+def _premises(rel: Dict[str, Any]) -> List[str]:
+    """Premise files of a relationship, stored as a JSON list; empty when it has none."""
+    value = rel["properties"].get("premises")
+    if isinstance(value, str):
+        try:
+            value = json.loads(value)
+        except ValueError:
+            return []
+    return value if isinstance(value, list) else []
+
+
+def _under_prefix(file_path: Optional[str], prefixes: List[str]) -> bool:
+    if not file_path:
+        return False
+    file_path = file_path.replace("\\", "/")
+    return any(file_path == prefix or file_path.startswith(prefix.rstrip("/") + "/") for prefix in prefixes)
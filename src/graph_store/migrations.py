@@ -0,0 +1,242 @@
+"""
+Graph schema versioning and migrations.
+
+The node and relationship layout the indexer writes changes between
+versions (new properties, renamed ones). A graph records the layout it
+holds in the schema_version of its SchemaMetadata node; a graph without
+one was written before versioning and holds version 0. MIGRATIONS lists
+the steps that bring a graph from one version to the next, in order.
+Each step works through the GraphStore interface, so it runs on every
+backend, and is idempotent: it only touches the nodes still in the old
+layout, so a step that ran halfway can simply run again.
+
+ensure_schema runs at startup of the indexer and the MCP server. An empty
+graph is current, and the indexer records the current version on the
+graphs it writes. An older graph is migrated when GRAPH_AUTO_MIGRATE is
+on (the default); with it off, the server refuses to start and names the
+fix: `python src/main.py --migrate`, or re-indexing with --clear-db. A
+graph written by a newer version is always refused. After every step the
+SchemaMetadata node records the new version; a step that fails records
+its version and error in migration_failed_step and migration_error, and
+the next run starts again from that step.
+
+The SchemaMetadata node belongs to no single repository (its repo is
+"all"), so clearing or deleting a repository keeps it.
+"""
+
+import logging
+import os
+from dataclasses import dataclass
+from datetime import datetime, timezone
+from typing import Any, Callable, Dict, List, Optional
+
+from src.graph_store.base import BASE_LABEL, DEFAULT_REPO, REPO_PROPERTY, GraphStore, node_repo
+from src.graph_store.repository import ALL_REPOS, REPOSITORY_LABEL
+
+logger = logging.getLogger(__name__)
+
+SCHEMA_LABEL = "SchemaMetadata"
+SCHEMA_NODE_ID = "schema:graph"
+
+# Labels of the symbol nodes that stored their documentation in `docstring`
+DOC_LABELS = ("Function", "Method", "Class")
+
+# Labels of the nodes recording a codebase root, the later one wins
+ROOT_LABELS = ("IndexMetadata", REPOSITORY_LABEL)
+
+
+class SchemaVersionError(Exception):
+    """The graph's schema version cannot be served: older with migrations off, or newer than this code."""
+
+
+class MigrationError(Exception):
+    """A migration step failed; the graph is left at the version before it."""
+    
+    def __init__(self, version: int, description: str, cause: Exception):
+        super().__init__(f"Migration to schema version {version} ({description}) failed: {cause}")
+        self.version = version
+        self.cause = cause
+
+
+@dataclass(frozen=True)
+class Migration:
+    """One step: up() brings a graph at version - 1 to version and returns the number of nodes it changed."""
+    version: int
+    description: str
+    up: Callable[[GraphStore], int]
+
+
+def _doc_replaces_docstring(store: GraphStore) -> int:
+    updates = []
+    for label in DOC_LABELS:
+        for node in store.find_nodes(label=label):
+            properties = node["properties"]
+            if properties.get("docstring") is None:
+                continue
+            doc = properties.get("doc") or properties["docstring"]
+            updates.append((properties["id"], {"doc": doc, "docstring": None}))
+    return store.update_node_properties(updates)
+
+
+def _repo_on_every_node(store: GraphStore) -> int:
+    updates = [
+        (node["properties"]["id"], {REPO_PROPERTY: DEFAULT_REPO})
+        for node in store.find_nodes(repo=DEFAULT_REPO)
+        if node["properties"].get(REPO_PROPERTY) is None
+    ]
+    return store.update_node_properties(updates)
+
+
+def _symbol_id_on_every_symbol(store: GraphStore) -> int:
+    # Imported here, the parser modules are not needed to open a store
+    from src.ast_parser.parser import CodeNode, CodeRelation
+    from src.indexing.symbol_ids import SCOPE_RELATIONS, SYMBOL_ID_PROPERTY, annotate_symbol_ids
+    
+    roots = {}
+    for label in ROOT_LABELS:
+        for node in store.find_nodes(label=label):
+            if node["properties"].get("root"):
+                roots[node_repo(node["properties"])] = node["properties"]["root"]
+    
+    updates = []
+    for repo, root in sorted(roots.items()):
+        nodes = {}
+        for record in store.find_nodes(repo=repo):
+            properties = dict(record["properties"])
+            node_type = next((label for label in record["labels"] if label != BASE_LABEL), BASE_LABEL)
+            nodes[properties["id"]] = CodeNode(properties["id"], node_type, properties.get("name") or "",
+                                               properties.get("file_path") or "", properties.get("line_no") or 0,
+                                               properties=properties)
+        stored = {node_id for node_id, node in nodes.items() if node.properties.get(SYMBOL_ID_PROPERTY)}
+        relations = [
+            CodeRelation(row["origin_id"], row["node"]["properties"]["id"], row["relationship"]["type"])
+            for row in store.neighbors(list(nodes), list(SCOPE_RELATIONS), direction="out")
+        ]
+        annotate_symbol_ids(nodes, relations, repo, root)
+        updates.extend(
+            (node_id, {SYMBOL_ID_PROPERTY: node.properties[SYMBOL_ID_PROPERTY]})
+            for node_id, node in nodes.items()
+            if node_id not in stored and node.properties.get(SYMBOL_ID_PROPERTY)
+        )
+    return store.update_node_properties(updates)
+
+
+MIGRATIONS: List[Migration] = [
+    Migration(1, "doc replaces docstring", _doc_replaces_docstring),
+    Migration(2, "repo on every node", _repo_on_every_node),
+    Migration(3, "symbol_id on every symbol node", _symbol_id_on_every_symbol),
+]
+
+# Version of the layout this code writes
+SCHEMA_VERSION = MIGRATIONS[-1].version
+
+
+def _utc_now() -> str:
+    return datetime.now(timezone.utc).isoformat(timespec="seconds")
+
+
+def get_auto_migrate(auto_migrate: Optional[bool] = None) -> bool:
+    """Whether older graphs are migrated at startup, if None, get from GRAPH_AUTO_MIGRATE (default: true)."""
+    if auto_migrate is not None:
+        return auto_migrate
+    return os.getenv("GRAPH_AUTO_MIGRATE", "true").lower() == "true"
+
+
+def schema_metadata(store: GraphStore) -> Dict[str, Any]:
+    """Properties of the SchemaMetadata node, empty if the graph has none."""
+    records = store.get_nodes([SCHEMA_NODE_ID])
+    return records[0]["properties"] if records else {}
+
+
+def read_schema_version(store: GraphStore) -> Optional[int]:
+    """Schema version of the graph: None for an empty graph, 0 for one written before versioning."""
+    version = schema_metadata(store).get("schema_version")
+    if version is not None:
+        return version
+    if store.find_nodes(limit=1):
+        return 0
+    return None
+
+
+def write_schema_version(store: GraphStore, version: int = SCHEMA_VERSION, **properties: Any) -> None:
+    """Record version on the SchemaMetadata node, clearing a previous failure unless properties set one."""
+    metadata = {"migration_failed_step": None, "migration_error": None}
+    metadata.update(properties)
+    metadata["schema_version"] = version
+    if schema_metadata(store):
+        store.update_node_properties([(SCHEMA_NODE_ID, metadata)])
+        return
+    node = {"id": SCHEMA_NODE_ID, "name": "schema", REPO_PROPERTY: ALL_REPOS}
+    node.update({key: value for key, value in metadata.items() if value is not None})
+    store.batch_create_nodes([{"labels": ["Base", SCHEMA_LABEL], "properties": node}])
+
+
+def migrate_graph(store: GraphStore, migrations: Optional[List[Migration]] = None) -> List[int]:
+    """
+    Run the steps above the graph's version, in order.
+    
+    Args:
+        migrations: Steps to run from, if None, MIGRATIONS
+    
+    Returns:
+        Versions of the steps that ran; an empty graph is stamped with the current version and runs none
+    
+    Raises:
+        MigrationError: a step failed; the SchemaMetadata node records which
+        SchemaVersionError: the graph is newer than the last step
+    """
+    migrations = MIGRATIONS if migrations is None else migrations
+    target = migrations[-1].version if migrations else 0
+    current = read_schema_version(store)
+    if current is None:
+        write_schema_version(store, target)
+        return []
+    if current > target:
+        raise SchemaVersionError(
+            f"The graph has schema version {current}, newer than version {target} of this indexer; upgrade it"
+        )
+    
+    applied = []
+    for migration in migrations:
+        if migration.version <= current:
+            continue
+        logger.info(f"Migrating the graph to schema version {migration.version}: {migration.description}")
+        try:
+            changed = migration.up(store)
+        except Exception as e:
+            write_schema_version(store, current, migration_failed_step=migration.version, migration_error=str(e))
+            store.flush()
+            raise MigrationError(migration.version, migration.description, e) from e
+        current = migration.version
+        write_schema_version(store, current, migrated_at=_utc_now())
+        applied.append(current)
+        logger.info(f"Schema version {current}: {changed} nodes updated")
+    store.flush()
+    return applied
+
+
+def ensure_schema(store: GraphStore, auto_migrate: Optional[bool] = None) -> Dict[str, Any]:
+    """
+    Check the graph's schema version at startup, migrating it when allowed.
+    
+    Args:
+        auto_migrate: Migrate an older graph, if None, get from GRAPH_AUTO_MIGRATE (default: true)
+    
+    Returns:
+        {'from_version': version found (None: empty graph), 'schema_version': version now, 'applied': [versions]}
+    
+    Raises:
+        SchemaVersionError: the graph is older and migrations are off, or it is newer than this code
+        MigrationError: a migration step failed
+    """
+    current = read_schema_version(store)
+    if current is None or current == SCHEMA_VERSION:
+        return {"from_version": current, "schema_version": SCHEMA_VERSION, "applied": []}
+    if current < SCHEMA_VERSION and not get_auto_migrate(auto_migrate):
+        raise SchemaVersionError(
+            f"The graph has schema version {current}, this version needs {SCHEMA_VERSION}. "
+            f"Run `python src/main.py --migrate` to migrate it, or re-index with --clear-db "
+            f"(or set GRAPH_AUTO_MIGRATE=true to migrate at startup)"
+        )
+    applied = migrate_graph(store)
+    return {"from_version": current, "schema_version": SCHEMA_VERSION, "applied": applied}
@@ -0,0 +1,283 @@
+"""
+Repository namespaces in one graph.
+
+One store can hold several repositories (backend, frontend, infra). The
+indexer writes every node with the name of its repository in `repo`
+(--repo or INDEX_REPO, default "default"), and prefixes the node IDs of
+every repository but the default one with that name
+("frontend@file:internal/util/util.go"), so repositories sharing relative
+paths keep separate nodes. Default repository IDs are unprefixed, so
+graphs indexed before repositories existed keep their IDs.
+
+RepositoryStore is the view of one repository over a store. The indexer
+writes through it with the IDs its parsers build, and MCP tools called
+with a `repo` read through it: that repository's nodes come back under
+unprefixed IDs, nodes of other repositories reached over a cross-repository
+edge (see src/linking/repositories.py) keep their prefixed ones.
+"""
+
+import os
+import re
+from typing import Any, Callable, Dict, List, Optional, Set, Tuple
+
+from src.graph_store.base import DEFAULT_REPO, REPO_PROPERTY, GraphStore, node_repo
+
+# `repo` argument of the MCP tools that reads every repository
+ALL_REPOS = "all"
+
+# Node label of the node recording a repository's root and the module names it publishes
+REPOSITORY_LABEL = "Repository"
+
+# Candidate window growth per retry while other repositories crowd out the search results
+FETCH_GROWTH = 4
+
+_REPO_NAME = re.compile(r"^[A-Za-z0-9][A-Za-z0-9._-]*$")
+# Local IDs start with "kind:", so a name and "@" before any ":" is a repository prefix
+_REPO_PREFIX = re.compile(r"^([A-Za-z0-9][A-Za-z0-9._-]*)@")
+
+
+def get_repo_name(repo: Optional[str] = None) -> str:
+    """
+    Resolve the repository name.
+    
+    Args:
+        repo: Repository name, if None, get from INDEX_REPO (default: "default")
+    
+    Raises:
+        ValueError: for a name that is not letters, digits, ".", "_" and "-", or is "all"
+    """
+    repo = (repo or os.getenv("INDEX_REPO") or DEFAULT_REPO).strip()
+    if not _REPO_NAME.match(repo) or repo == ALL_REPOS:
+        raise ValueError(f"Invalid repository name '{repo}': expected letters, digits, '.', '_' and '-', "
+                         f"other than '{ALL_REPOS}'")
+    return repo
+
+
+def scoped_id(repo: str, node_id: str) -> str:
+    """Stored ID of a node of repo, given the ID the parsers build."""
+    if repo == DEFAULT_REPO or _REPO_PREFIX.match(node_id):
+        return node_id
+    return f"{repo}@{node_id}"
+
+
+def split_id(node_id: str) -> Tuple[str, str]:
+    """(repository, local ID) of a stored node ID."""
+    match = _REPO_PREFIX.match(node_id)
+    if match is None:
+        return DEFAULT_REPO, node_id
+    return match.group(1), node_id[match.end():]
+
+
+def same_repo_id(node_id: str, local_id: str) -> str:
+    """ID of local_id in the namespace node_id is written in (prefixed or not)."""
+    match = _REPO_PREFIX.match(node_id)
+    return f"{match.group(0)}{local_id}" if match else local_id
+
+
+def repository_id(repo: str) -> str:
+    """Local ID of a repository's Repository node."""
+    return f"repository:{repo}"
+
+
+class RepositoryStore(GraphStore):
+    """
+    View of one repository of a GraphStore.
+    
+    Node writes get the repository's `repo` and prefixed IDs, index state
+    methods and find_nodes only see its nodes, and records come back with
+    its IDs unprefixed. IDs given to the view may be prefixed already.
+    clear_database deletes the repository only; close flushes the
+    underlying store, which the view does not own. execute_cypher and
+    execute_read_query run on the whole store.
+    """
+    
+    def __init__(self, store: GraphStore, repo: Optional[str] = None):
+        """
+        Args:
+            store: Underlying store
+            repo: Repository name, if None, get from INDEX_REPO (default: "default")
+        """
+        self.store = store
+        self.repo = get_repo_name(repo)
+        self.prefix = "" if self.repo == DEFAULT_REPO else f"{self.repo}@"
+    
+    # IDs and records
+    
+    def scope(self, node_id: str) -> str:
+        return scoped_id(self.repo, node_id)
+    
+    def unscope(self, node_id: str) -> str:
+        if self.prefix and node_id.startswith(self.prefix):
+            return node_id[len(self.prefix):]
+        return node_id
+    
+    def _node(self, record: Dict[str, Any]) -> Dict[str, Any]:
+        properties = dict(record["properties"])
+        properties["id"] = self.unscope(properties["id"])
+        return dict(record, properties=properties)
+    
+    def _relationship(self, record: Dict[str, Any]) -> Dict[str, Any]:
+        return dict(record, start_node_id=self.unscope(record["start_node_id"]),
+                    end_node_id=self.unscope(record["end_node_id"]))
+    
+    def _match(self, match: Dict[str, Any]) -> Dict[str, Any]:
+        return dict(match, node=dict(match["node"], id=self.unscope(match["node"]["id"])))
+    
+    def _own_matches(self, search: Callable[[int], List[Dict[str, Any]]], limit: int) -> List[Dict[str, Any]]:
+        """Up to limit results of search(n) on this repository's nodes, growing n until enough are found."""
+        fetch = limit
+        while True:
+            candidates = search(fetch)
+            kept = [self._match(match) for match in candidates if node_repo(match["node"]) == self.repo]
+            if len(kept) >= limit or len(candidates) < fetch:
+                return kept[:limit]
+            fetch *= FETCH_GROWTH
+    
+    # Lifecycle
+    
+    def verify_connection(self) -> bool:
+        return self.store.verify_connection()
+    
+    def close(self) -> None:
+        self.store.flush()
+    
+    def flush(self) -> None:
+        self.store.flush()
+    
+    # Schema
+    
+    def create_schema_constraints(self) -> None:
+        self.store.create_schema_constraints()
+    
+    def create_vector_index(self, index_name: str, node_label: str, property_name: str, dimension: int = 1536) -> None:
+        self.store.create_vector_index(index_name, node_label, property_name, dimension)
+    
+    def create_full_text_index(self, index_name: str, node_labels: List[str], properties: List[str]) -> None:
+        self.store.create_full_text_index(index_name, node_labels, properties)
+    
+    # Writes
+    
+    def batch_create_nodes(self, nodes: List[Dict[str, Any]], batch_size: Optional[int] = None) -> None:
+        scoped = []
+        for node in nodes:
+            properties = dict(node["properties"])
+            properties["id"] = self.scope(properties["id"])
+            properties[REPO_PROPERTY] = self.repo
+            scoped.append(dict(node, properties=properties))
+        self.store.batch_create_nodes(scoped, batch_size)
+    
+    def batch_create_relationships(self, relationships: List[Dict[str, Any]], batch_size: Optional[int] = None) -> None:
+        self.store.batch_create_relationships([
+            dict(rel, start_node_id=self.scope(rel["start_node_id"]), end_node_id=self.scope(rel["end_node_id"]))
+            for rel in relationships
+        ], batch_size)
+    
+    def clear_database(self) -> None:
+        self.store.delete_repository(self.repo)
+    
+    def delete_file_scope(self, file_paths: List[str], repo: Optional[str] = None) -> int:
+        return self.store.delete_file_scope(file_paths, repo=self.repo)
+    
+    def delete_structural_relationships(self, repo: Optional[str] = None) -> int:
+        return self.store.delete_structural_relationships(repo=self.repo)
+    
+    def delete_stale_structural_relationships(self, premise_files: List[str], keys: List[Tuple[str, str, str]],
+                                              repo: Optional[str] = None) -> List[Tuple[str, str, str]]:
+        deleted = self.store.delete_stale_structural_relationships(
+            premise_files, [(self.scope(start), rel_type, self.scope(end)) for start, rel_type, end in keys],
+            repo=self.repo,
+        )
+        return [(self.unscope(start), rel_type, self.unscope(end)) for start, rel_type, end in deleted]
+    
+    def delete_cross_repo_relationships(self) -> int:
+        return self.store.delete_cross_repo_relationships()
+    
+    def delete_orphan_placeholders(self, repo: Optional[str] = None) -> int:
+        return self.store.delete_orphan_placeholders(repo=self.repo)
+    
+    def update_file_mtimes(self, updates: List[Tuple[str, float]], repo: Optional[str] = None) -> None:
+        self.store.update_file_mtimes(updates, repo=self.repo)
+    
+    def update_file_index_states(self, updates: List[Tuple[str, str]], repo: Optional[str] = None) -> None:
+        self.store.update_file_index_states(updates, repo=self.repo)
+    
+    def update_node_properties(self, updates: List[Tuple[str, Dict[str, Any]]]) -> int:
+        return self.store.update_node_properties([(self.scope(node_id), properties) for node_id, properties in updates])
+    
+    def delete_repository(self, repo: str) -> int:
+        return self.store.delete_repository(repo)
+    
+    # Index state
+    
+    def get_file_states(self, repo: Optional[str] = None) -> Dict[str, Dict[str, Any]]:
+        return self.store.get_file_states(repo=self.repo)
+    
+    def get_dependent_files(self, file_paths: List[str], repo: Optional[str] = None) -> List[str]:
+        return self.store.get_dependent_files(file_paths, repo=self.repo)
+    
+    def get_existing_node_ids(self, node_ids: List[str]) -> Set[str]:
+        given = {self.scope(node_id): node_id for node_id in node_ids}
+        return {given[node_id] for node_id in self.store.get_existing_node_ids(list(given))}
+    
+    def get_node_embeddings(self, file_paths: List[str], repo: Optional[str] = None) -> Dict[str, List[float]]:
+        return self.store.get_node_embeddings(file_paths, repo=self.repo)
+    
+    # Query primitives
+    
+    def get_nodes(self, node_ids: List[str]) -> List[Dict[str, Any]]:
+        return [self._node(record) for record in self.store.get_nodes([self.scope(node_id) for node_id in node_ids])]
+    
+    def find_nodes(self, name: Optional[str] = None, label: Optional[str] = None,
+                   properties: Optional[Dict[str, Any]] = None, path_prefixes: Optional[List[str]] = None,
+                   limit: Optional[int] = None, repo: Optional[str] = None) -> List[Dict[str, Any]]:
+        records = self.store.find_nodes(name=name, label=label, properties=properties, path_prefixes=path_prefixes,
+                                        limit=limit, repo=self.repo)
+        return [self._node(record) for record in records]
+    
+    def node_names(self, labels: List[str], limit: Optional[int] = None, repo: Optional[str] = None) -> List[str]:
+        return self.store.node_names(labels, limit=limit, repo=self.repo)
+    
+    def neighbors(self, node_ids: List[str], relation_types: Optional[List[str]] = None, direction: str = "out",
+                  label: Optional[str] = None) -> List[Dict[str, Any]]:
+        # origin_id is the ID as given, prefixed or not
+        given = {self.scope(node_id): node_id for node_id in node_ids}
+        return [
+            {"origin_id": given[row["origin_id"]], "relationship": self._relationship(row["relationship"]),
+             "node": self._node(row["node"])}
+            for row in self.store.neighbors(list(given), relation_types, direction, label)
+        ]
+    
+    def shortest_paths(self, source_id: str, target_id: str, relation_types: List[str], direction: str = "out",
+                       max_depth: int = 10, limit: int = 1, min_confidence: float = 0.0) -> List[Dict[str, Any]]:
+        paths = self.store.shortest_paths(self.scope(source_id), self.scope(target_id), relation_types, direction,
+                                          max_depth, limit, min_confidence)
+        return [
+            {"nodes": [self._node(node) for node in path["nodes"]],
+             "relationships": [self._relationship(rel) for rel in path["relationships"]]}
+            for path in paths
+        ]
+    
+    def edges_between(self, node_ids: List[str]) -> List[Dict[str, Any]]:
+        relationships = self.store.edges_between([self.scope(node_id) for node_id in node_ids])
+        return [self._relationship(rel) for rel in relationships]
+    
+    def count_by_type(self, repo: Optional[str] = None) -> Dict[str, Dict[str, int]]:
+        return self.store.count_by_type(repo=self.repo)
+    
+    # Search
+    
+    def search_similar_nodes(self, vector: List[float], node_labels: List[str], limit: int = 10) -> List[Dict[str, Any]]:
+        return self._own_matches(lambda count: self.store.search_similar_nodes(vector, node_labels, count), limit)
+    
+    def search_code_by_vector(self, vector: List[float], node_label: str, limit: int = 10) -> List[Dict[str, Any]]:
+        return self._own_matches(lambda count: self.store.search_code_by_vector(vector, node_label, count), limit)
+    
+    def search_code_by_text(self, query: str, limit: int = 10) -> List[Dict[str, Any]]:
+        return self._own_matches(lambda count: self.store.search_code_by_text(query, count), limit)
+    
+    def execute_cypher(self, query: str, parameters: Dict = None) -> List[Dict[str, Any]]:
+        return self.store.execute_cypher(query, parameters)
+    
+    def execute_read_query(self, query: str, parameters: Dict = None, max_rows: Optional[int] = None,
+                           timeout: Optional[float] = None) -> List[Dict[str, Any]]:
+        return self.store.execute_read_query(query, parameters, max_rows, timeout)
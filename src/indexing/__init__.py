@@ -0,0 +1,103 @@
+"""Indexing pipeline helpers (source file discovery, incremental re-indexing)."""
+
+from src.indexing.incremental import (
+    IncrementalPlan,
+    plan_incremental_update,
+    build_file_index_states,
+    annotate_file_nodes,
+    load_index_context,
+    compute_file_dependencies,
+    compute_package_dependencies,
+    load_package_imports,
+    select_incremental_writes,
+    select_structural_writes,
+    serialize_index_state,
+    stale_structural_keys,
+)
+from src.indexing.renames import (
+    annotate_body_hashes,
+    apply_renames,
+    detect_file_moves,
+    find_renamed_symbols,
+    previous_file_paths,
+)
+from src.indexing.source import (
+    STORE_SOURCE_MODES,
+    annotate_sources,
+    get_source_max_bytes,
+    get_store_source,
+)
+from src.indexing.streaming import (
+    SpillBuffer,
+    StreamingRun,
+    get_memory_budget_mb,
+    get_stream_batch_files,
+    get_streaming,
+)
+from src.indexing.summaries import (
+    compute_package_summaries,
+    load_package_docs,
+)
+from src.indexing.symbol_ids import (
+    SYMBOL_ID_PROPERTY,
+    SYMBOL_ID_SCHEMES,
+    annotate_symbol_ids,
+    get_symbol_id_scheme,
+)
+from src.indexing.testcode import (
+    annotate_tests,
+    is_test_file,
+    link_tests,
+)
+from src.indexing.walker import (
+    SourceFileWalker,
+    WalkStats,
+    walk_source_files,
+)
+from src.indexing.watcher import (
+    IndexWatcher,
+    watch_codebase,
+)
+
+__all__ = [
+    'IncrementalPlan',
+    'plan_incremental_update',
+    'build_file_index_states',
+    'annotate_file_nodes',
+    'load_index_context',
+    'compute_file_dependencies',
+    'compute_package_dependencies',
+    'load_package_imports',
+    'select_incremental_writes',
+    'select_structural_writes',
+    'serialize_index_state',
+    'stale_structural_keys',
+    'annotate_body_hashes',
+    'apply_renames',
+    'detect_file_moves',
+    'find_renamed_symbols',
+    'previous_file_paths',
+    'STORE_SOURCE_MODES',
+    'annotate_sources',
+    'get_source_max_bytes',
+    'get_store_source',
+    'SpillBuffer',
+    'StreamingRun',
+    'get_memory_budget_mb',
+    'get_stream_batch_files',
+    'get_streaming',
+    'compute_package_summaries',
+    'load_package_docs',
+    'SYMBOL_ID_PROPERTY',
+    'SYMBOL_ID_SCHEMES',
+    'annotate_symbol_ids',
+    'get_symbol_id_scheme',
+    'annotate_tests',
+    'is_test_file',
+    'link_tests',
+    'SourceFileWalker',
+    'WalkStats',
+    'walk_source_files',
+    'IndexWatcher',
+    'watch_codebase',
+]
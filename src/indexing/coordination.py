@@ -0,0 +1,265 @@
+"""
+Coordination of the index runs and queries sharing one graph store.
+
+Watch mode, index jobs and the reindex tool each run process_codebase on
+a thread of their own. Two runs over the same root would read the same
+stored file states and write over each other's deletes and inserts, so a
+change applied by one could be lost, or a file's nodes written twice.
+GraphCoordinator serializes them with a write lock per root (the
+repository and the real path of the root): a second run of a root waits
+for the first to finish, while runs of different roots go on side by side.
+The watcher does not wait, it keeps the changes that arrive while another
+run holds the lock queued and syncs them once the lock is free (see
+watcher.py).
+
+The parse and resolve phases of a run do not touch the graph. Its writes
+(removing the nodes of changed files, embedding and inserting their new
+nodes and edges, the index metadata) are applied as one step that MCP
+query tools are kept out of: queries wait until no apply is in progress,
+an apply waits for the queries running to finish, and queries arriving
+meanwhile wait behind it, so a steady stream of them cannot hold an
+apply off. A query thus sees the graph before or after a run's changes,
+never a file without its nodes. Queries do not wait for each other, and
+applies of different roots may overlap.
+
+Streaming runs and runs that clear the repository first write as they
+parse and are not applied as one step; while one runs, queries may see a
+partial index, as before. The coordinator lives in the process: a CLI run
+or another server writing into the same Neo4j database is not seen.
+
+Every write is also reported to the coordinator's listeners as a
+GraphChange, the query cache among them (see src/mcp/query_cache.py).
+An apply reports its change before queries are let in again; a change
+says which files a run re-indexed and which nodes its writes touched, or
+is unscoped when that is not known (full and streaming runs, cleared or
+deleted repositories, failed applies).
+"""
+
+import logging
+import os
+import threading
+from contextlib import contextmanager
+from dataclasses import dataclass, field
+from typing import Any, Callable, Dict, Iterable, Iterator, List, Optional, Set, Tuple
+
+from src.graph_store.repository import RepositoryStore
+
+logger = logging.getLogger(__name__)
+
+# Seconds between the cancellation checks of a run waiting for a write lock
+WAIT_POLL_SECONDS = 0.2
+
+_attach_lock = threading.Lock()
+
+
+@dataclass
+class GraphChange:
+    """
+    What one write did to the graph.
+    
+    Attributes:
+        repo: Repository written
+        files: Files whose nodes were removed or re-written, or whose edges were written; None when
+            the change is unscoped and may have touched anything
+        keys: IDs and string property values (name, symbol_id, path...) of the nodes written, and IDs
+            of the endpoints of the edges written or deleted
+        labels: Labels of the nodes written
+        shared: Nodes without a file (packages, placeholders) were deleted or linked across repositories
+    """
+    repo: str
+    files: Optional[Set[str]] = None
+    keys: Set[str] = field(default_factory=set)
+    labels: Set[str] = field(default_factory=set)
+    shared: bool = False
+    
+    @property
+    def scoped(self) -> bool:
+        return self.files is not None
+    
+    def add_writes(self, nodes: Dict[str, Any], relations: Iterable[Any], known_nodes: Dict[str, Any],
+                   node_files: Optional[Dict[str, str]] = None) -> None:
+        """
+        Add what an incremental apply wrote to a scoped change.
+        
+        Args:
+            nodes: CodeNodes written, by node ID
+            relations: CodeRelations written
+            known_nodes: CodeNodes the relations may end on, by node ID
+            node_files: File of the stored nodes the relations may end on, by node ID
+        """
+        for node in nodes.values():
+            self.keys.add(node.node_id)
+            values = dict(node.properties, name=node.name, file_path=node.file_path)
+            self.keys.update(value for value in values.values() if isinstance(value, str))
+            self.labels.add(node.node_type)
+            if node.file_path:
+                self.files.add(node.file_path)
+        node_files = node_files or {}
+        for relation in relations:
+            for node_id in (relation.source_id, relation.target_id):
+                if node_id is None:
+                    continue
+                self.keys.add(node_id)
+                node = known_nodes.get(node_id)
+                file_path = node.file_path if node is not None else node_files.get(node_id)
+                if file_path:
+                    self.files.add(file_path)
+
+
+class GraphCoordinator:
+    """
+    Write locks per root and the exclusion of queries from applies, for one graph store.
+    
+    Usage:
+        coordinator = get_coordinator(store)
+        with coordinator.write_lock(root, repo):
+            ...parse...
+            with coordinator.applying():
+                ...delete and insert...
+        
+        with coordinator.reading():
+            ...query...
+    """
+    
+    def __init__(self):
+        self._cond = threading.Condition()
+        # (repo, root) -> (owning thread ident, owning thread name, depth)
+        self._owners: Dict[Tuple[str, str], Tuple[int, str, int]] = {}
+        self._readers = 0
+        self._applying = 0
+        self._waiting_applies = 0
+        self._listeners: List[Callable[[GraphChange], None]] = []
+    
+    @staticmethod
+    def _key(root: str, repo: str) -> Tuple[str, str]:
+        return repo, os.path.realpath(root)
+    
+    @contextmanager
+    def write_lock(self, root: str, repo: str, check: Optional[Callable[[], None]] = None) -> Iterator[None]:
+        """
+        Hold the write lock of a root; reentrant for the thread holding it.
+        
+        Args:
+            root: Indexed root
+            repo: Repository the root is indexed as
+            check: Called while waiting, e.g. IndexProgress.check_cancelled; an exception it raises
+                ends the wait without the lock
+        """
+        key = self._key(root, repo)
+        thread = threading.current_thread()
+        with self._cond:
+            while True:
+                owner = self._owners.get(key)
+                if owner is None or owner[0] == thread.ident:
+                    break
+                if check is not None:
+                    check()
+                self._cond.wait(WAIT_POLL_SECONDS)
+            depth = owner[2] if owner is not None else 0
+            self._owners[key] = (thread.ident, thread.name, depth + 1)
+        try:
+            yield
+        finally:
+            with self._cond:
+                ident, name, depth = self._owners[key]
+                if depth > 1:
+                    self._owners[key] = (ident, name, depth - 1)
+                else:
+                    del self._owners[key]
+                self._cond.notify_all()
+    
+    def holder(self, root: str, repo: str) -> Optional[str]:
+        """Name of the thread holding the write lock of a root, None if it is free or held by the caller."""
+        with self._cond:
+            owner = self._owners.get(self._key(root, repo))
+        if owner is None or owner[0] == threading.get_ident():
+            return None
+        return owner[1]
+    
+    @contextmanager
+    def applying(self, change: Optional[GraphChange] = None, repo: Optional[str] = None) -> Iterator[None]:
+        """
+        Apply the writes of a run; waits for the running queries, new ones wait until it ends.
+        
+        The change is reported to the listeners before queries are let in
+        again; without one, or when the block raises, an unscoped change of
+        repo is reported instead.
+        """
+        with self._cond:
+            self._waiting_applies += 1
+            while self._readers:
+                self._cond.wait()
+            self._waiting_applies -= 1
+            self._applying += 1
+        applied = False
+        try:
+            yield
+            applied = True
+        finally:
+            try:
+                if applied and change is not None:
+                    self.changed(change)
+                else:
+                    self.changed(GraphChange(change.repo if change is not None else repo or ""))
+            finally:
+                with self._cond:
+                    self._applying -= 1
+                    self._cond.notify_all()
+    
+    def add_listener(self, listener: Callable[[GraphChange], None]) -> None:
+        """Call listener with every GraphChange reported from now on."""
+        with self._cond:
+            self._listeners.append(listener)
+    
+    def remove_listener(self, listener: Callable[[GraphChange], None]) -> None:
+        with self._cond:
+            if listener in self._listeners:
+                self._listeners.remove(listener)
+    
+    def changed(self, change: GraphChange) -> None:
+        """Report a write to the listeners; one that fails is logged and does not fail the write."""
+        with self._cond:
+            listeners = list(self._listeners)
+        for listener in listeners:
+            try:
+                listener(change)
+            except Exception as e:
+                logger.warning(f"Graph change listener {listener!r} failed: {e}")
+    
+    def acquire_read(self) -> None:
+        """Start a query, once no apply is in progress."""
+        with self._cond:
+            while self._applying or self._waiting_applies:
+                self._cond.wait()
+            self._readers += 1
+    
+    def release_read(self) -> None:
+        """End a query started with acquire_read."""
+        with self._cond:
+            self._readers -= 1
+            self._cond.notify_all()
+    
+    @contextmanager
+    def reading(self) -> Iterator[None]:
+        """Run a query between applies."""
+        self.acquire_read()
+        try:
+            yield
+        finally:
+            self.release_read()
+
+
+def get_coordinator(store) -> GraphCoordinator:
+    """
+    Coordinator of a graph store, shared by everything in the process that uses the store.
+    
+    A RepositoryStore shares the coordinator of the store it is a view of.
+    """
+    if isinstance(store, RepositoryStore):
+        store = store.store
+    with _attach_lock:
+        coordinator = getattr(store, "_graph_coordinator", None)
+        if not isinstance(coordinator, GraphCoordinator):
+            coordinator = GraphCoordinator()
+            store._graph_coordinator = coordinator
+    return coordinator
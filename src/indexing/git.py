@@ -0,0 +1,259 @@
+"""
+Git metadata for indexing.
+
+When the codebase is inside a git work tree, an index run records:
+- on the IndexMetadata node: the repository root, the indexed commit
+  (git_commit), its branch (git_branch, None on a detached HEAD, as in
+  most CI checkouts) and whether the clone is shallow
+- on every File node it writes: the last commit that touched the file
+  (last_commit, last_author, last_commit_date)
+
+changed_files() lists the files a `git diff --name-status base..HEAD`
+reports, which is what the --changed-only mode re-indexes. A diff is
+taken between the two commits only, so it needs no history in between
+and works in shallow clones as long as the base commit was fetched.
+
+Every git call goes through run_git, which raises GitError when git is
+missing, the directory is not a repository or the command fails; callers
+log a warning and index without git metadata.
+"""
+
+import logging
+import os
+import subprocess
+from dataclasses import dataclass, field
+from typing import Any, Dict, Iterable, List, Optional, Tuple
+
+from src.indexing.incremental import IncrementalPlan
+
+logger = logging.getLogger(__name__)
+
+# Seconds a single git command may take
+GIT_TIMEOUT_SECONDS = 120
+
+# Separates the fields of a commit header in `git log` output
+_FIELD_SEPARATOR = "\x1f"
+_COMMIT_MARKER = "\x1e"
+
+
+class GitError(Exception):
+    """A git command could not be run or failed."""
+
+
+def run_git(repo_path: str, *args: str) -> str:
+    """
+    Output of `git -C repo_path <args>`.
+    
+    Raises:
+        GitError: git is not installed, timed out or exited with an error
+    """
+    command = ["git", "-C", repo_path, "-c", "core.quotePath=false", *args]
+    try:
+        result = subprocess.run(command, capture_output=True, text=True, encoding="utf-8", errors="replace",
+                                timeout=GIT_TIMEOUT_SECONDS)
+    except (OSError, subprocess.TimeoutExpired) as e:
+        raise GitError(f"git {' '.join(args)} failed: {e}") from e
+    if result.returncode != 0:
+        raise GitError(f"git {' '.join(args)} failed: {result.stderr.strip() or result.returncode}")
+    return result.stdout
+
+
+def find_repo_root(path: str) -> Optional[str]:
+    """Root of the git work tree containing path, None if there is none."""
+    try:
+        return os.path.realpath(run_git(path, "rev-parse", "--show-toplevel").strip())
+    except GitError:
+        return None
+
+
+@dataclass
+class GitHead:
+    """The checked-out commit of a repository."""
+    root: str
+    commit: str
+    branch: Optional[str]
+    shallow: bool
+    
+    def metadata(self) -> Dict[str, object]:
+        """Properties of the IndexMetadata node."""
+        return {
+            "git_root": self.root,
+            "git_commit": self.commit,
+            "git_branch": self.branch,
+            "git_detached": self.branch is None,
+            "git_shallow": self.shallow,
+        }
+
+
+def read_head(repo_root: str) -> GitHead:
+    """
+    Commit, branch and shallowness of HEAD.
+    
+    Raises:
+        GitError: HEAD cannot be resolved (e.g. a repository without commits)
+    """
+    commit = run_git(repo_root, "rev-parse", "HEAD").strip()
+    try:
+        branch = run_git(repo_root, "symbolic-ref", "--quiet", "--short", "HEAD").strip() or None
+    except GitError:
+        # Detached HEAD
+        branch = None
+    try:
+        shallow = run_git(repo_root, "rev-parse", "--is-shallow-repository").strip() == "true"
+    except GitError:
+        shallow = os.path.exists(os.path.join(repo_root, ".git", "shallow"))
+    return GitHead(repo_root, commit, branch, shallow)
+
+
+@dataclass
+class GitChanges:
+    """Files changed between two commits, as absolute paths."""
+    added: List[str] = field(default_factory=list)
+    modified: List[str] = field(default_factory=list)
+    deleted: List[str] = field(default_factory=list)
+    # (old path, new path)
+    renamed: List[Tuple[str, str]] = field(default_factory=list)
+    
+    @property
+    def changed(self) -> List[str]:
+        """Files to re-parse: added, modified and the new side of renames."""
+        return self.added + self.modified + [new for _, new in self.renamed]
+    
+    @property
+    def removed(self) -> List[str]:
+        """Files whose nodes go away: deleted and the old side of renames."""
+        return self.deleted + [old for old, _ in self.renamed]
+
+
+def changed_files(repo_root: str, base_ref: str, head_ref: str = "HEAD") -> GitChanges:
+    """
+    Files changed in base_ref..head_ref, from `git diff --name-status`.
+    
+    Copies count as added files, type changes as modified ones.
+    
+    Raises:
+        GitError: base_ref is unknown, e.g. not fetched into a shallow clone
+    """
+    output = run_git(repo_root, "diff", "--name-status", "-z", "-M", "--no-ext-diff", f"{base_ref}..{head_ref}", "--")
+    fields = output.split("\0")
+    changes = GitChanges()
+    index = 0
+    while index < len(fields) and fields[index]:
+        status = fields[index][0]
+        if status in ("R", "C"):
+            old, new = fields[index + 1], fields[index + 2]
+            index += 3
+            if status == "R":
+                changes.renamed.append((os.path.join(repo_root, old), os.path.join(repo_root, new)))
+            else:
+                changes.added.append(os.path.join(repo_root, new))
+            continue
+        path = os.path.join(repo_root, fields[index + 1])
+        index += 2
+        if status == "A":
+            changes.added.append(path)
+        elif status == "D":
+            changes.deleted.append(path)
+        else:
+            changes.modified.append(path)
+    return changes
+
+
+def plan_changed_only(
+    changes: GitChanges,
+    source_files: Iterable[str],
+    stored_states: Dict[str, Dict[str, Any]],
+) -> Tuple[IncrementalPlan, Dict[str, str]]:
+    """
+    Incremental plan that re-indexes exactly the files of a git diff.
+    
+    Changed files the walker skips (excluded, ignored or unsupported) are
+    left out; every other indexed file counts as unchanged, whatever its
+    stored state says.
+    
+    Args:
+        changes: Files of the diff, from changed_files
+        source_files: Files found by the walker
+        stored_states: file_path -> stored states
+    
+    Returns:
+        (plan, renames) where renames maps new path -> old path of renamed
+        files, for rename detection
+    """
+    source_files = list(source_files)
+    source_by_real = {os.path.realpath(path): path for path in source_files}
+    stored_by_real = {os.path.realpath(path): path for path in stored_states}
+    
+    plan = IncrementalPlan()
+    changed = set()
+    for path in changes.changed:
+        source_path = source_by_real.get(path)
+        if source_path is not None and source_path not in changed:
+            changed.add(source_path)
+            plan.changed.append(source_path)
+    for path in changes.removed:
+        if path in stored_by_real and path not in source_by_real:
+            plan.deleted.append(stored_by_real[path])
+    plan.unchanged = [path for path in source_files if path not in changed and path in stored_states]
+    
+    renames = {
+        source_by_real[new]: stored_by_real[old]
+        for old, new in changes.renamed
+        if new in source_by_real and old in stored_by_real
+    }
+    return plan, renames
+
+
+def last_commits(repo_root: str, file_paths: Iterable[str]) -> Dict[str, Dict[str, str]]:
+    """
+    Last commit that touched each file, walking `git log` from HEAD.
+    
+    The log is read only until every tracked file was seen. In a shallow
+    clone, files not changed since the oldest fetched commit get that commit.
+    
+    Args:
+        repo_root: Root of the work tree
+        file_paths: Files to look up; untracked files are left out of the result
+    
+    Returns:
+        file path -> {"last_commit", "last_author", "last_commit_date"}
+    """
+    by_relative = {}
+    for file_path in file_paths:
+        relative = os.path.relpath(os.path.realpath(file_path), repo_root).replace(os.sep, "/")
+        if not relative.startswith("../"):
+            by_relative.setdefault(relative, []).append(file_path)
+    if not by_relative:
+        return {}
+    tracked = set(run_git(repo_root, "ls-files", "-z").split("\0"))
+    pending = {relative for relative in by_relative if relative in tracked}
+    
+    commits: Dict[str, Dict[str, str]] = {}
+    if not pending:
+        return commits
+    log_format = f"--format={_COMMIT_MARKER}%H{_FIELD_SEPARATOR}%an <%ae>{_FIELD_SEPARATOR}%aI"
+    command = ["git", "-C", repo_root, "-c", "core.quotePath=false", "log", log_format, "--name-only",
+               "--no-renames", "HEAD", "--"]
+    try:
+        process = subprocess.Popen(command, stdout=subprocess.PIPE, stderr=subprocess.DEVNULL, text=True,
+                                   encoding="utf-8", errors="replace")
+    except OSError as e:
+        raise GitError(f"git log failed: {e}") from e
+    
+    current = None
+    try:
+        for line in process.stdout:
+            line = line.rstrip("\n")
+            if line.startswith(_COMMIT_MARKER):
+                sha, author, date = (line[1:].split(_FIELD_SEPARATOR) + ["", ""])[:3]
+                current = {"last_commit": sha, "last_author": author, "last_commit_date": date}
+            elif line and current is not None and line in pending:
+                pending.discard(line)
+                for file_path in by_relative[line]:
+                    commits[file_path] = current
+                if not pending:
+                    break
+    finally:
+        process.kill()
+        process.wait()
+    return commits
@@ -0,0 +1,561 @@
+"""
+Incremental re-indexing support.
+
+A full index stores, on every File node:
+- content_hash / mtime / size: change detection (mtime + size is the fast
+  check, the SHA-256 content hash is authoritative)
+- index_state: the file's share of the two-pass resolution index
+  (module_definitions, module_to_file, and DEFINES class->method links),
+  so changed files can be resolved against unchanged ones without
+  re-parsing them, plus the Go interfaces and method signatures of the
+  file, from which IMPLEMENTS edges are derived
+
+and a DEPENDS_ON_FILE edge from each file to every file it has a
+cross-file relation into. That edge set is the persisted reverse-dependency
+map: when a file changes, the files pointing at it are re-resolved so
+their CALLS / IMPORTS / EXTENDS edges into it are recreated.
+
+Relations marked "structural" (Go IMPLEMENTS, package DEPENDS_ON) depend
+on the whole codebase rather than on a single file, so they are left out
+of the dependency map and recomputed on every run. Package DEPENDS_ON
+edges are aggregated from the IMPORTS edges of every file; the
+index_state keeps each file's package and imported packages for that.
+Each structural relation lists its premises, the files it follows from
+(see src/ast_parser/provenance.py): a run replaces only the stored ones
+whose premises include a changed, deleted or re-resolved file, or whose
+recomputed version does, and keeps the others as stored. The premises of
+other relations (e.g. a call through an interface declared in an
+embedded interface's file) add to the dependency map.
+
+index_state also lists the file's symbols with their body_hash, so the
+next run can tell a renamed symbol or a moved file from a deleted one
+(see renames.py), and the link facts of the file: the nodes and relations
+the cross-language matchers read (see src/linking), so CROSS_LANG_CALLS
+edges, which are structural too, can be relinked without re-parsing it.
+Files carrying their package's doc keep it too, for the package summaries
+(see summaries.py).
+
+index_state records the INDEX_STATE_VERSION it was written with. Files
+stored by an older version count as changed and are re-parsed once, so
+node properties added since (e.g. signature_json) reach existing graphs
+on the next incremental run without a migration. Files whose last parse
+failed (parse_error on the File node, see src/ast_parser/guards.py) count
+as changed too.
+"""
+
+import hashlib
+import json
+import logging
+import os
+from dataclasses import dataclass, field
+from typing import Any, Dict, Iterable, List, Optional, Set, Tuple
+
+from src.ast_parser.packages import is_package_id, package_id
+from src.ast_parser.parser import CodeNode, CodeRelation
+from src.ast_parser.provenance import (
+    PREMISES_PROPERTY,
+    SOURCE_FILE_DEPENDENCIES,
+    SOURCE_PACKAGE_IMPORTS,
+    SOURCE_PROPERTY,
+    premise_files,
+)
+from src.indexing.summaries import package_doc_entry
+
+logger = logging.getLogger(__name__)
+
+# Relation type of the persisted file -> file dependency map
+FILE_DEPENDENCY_RELATION = "DEPENDS_ON_FILE"
+
+# Relation type of the package -> package dependencies aggregated from file imports
+PACKAGE_DEPENDENCY_RELATION = "DEPENDS_ON"
+
+# Relations and node properties kept in index_state so structural relations can be recomputed
+METHOD_SET_RELATIONS = ("METHOD_OF", "EMBEDS")
+METHOD_SET_PROPERTIES = ("methods", "embeds", "constraint", "signature", "receiver_kind")
+
+# Node types listed in index_state for rename detection
+SYMBOL_NODE_TYPES = ("Function", "Method", "Class", "Interface", "Enum", "TypeAlias")
+
+# Version of the parsed node data; bump it when parsers add node properties
+# (2: structured signatures, 3: Go line ranges and struct fields, 4: Rust adapter, 5: link facts,
+# 6: complexity metrics, 7: generated and skipped files, 8: test tags, 9: repositories, 10: Go generics,
+# 11: C# adapter, 12: constants and USES edges, 13: Jest test nodes and TESTS edges, 14: package docs,
+# 15: edge provenance, 16: Ruby adapter, 17: Kotlin adapter)
+INDEX_STATE_VERSION = 17
+
+
+def _empty_index_state() -> Dict[str, Any]:
+    return {
+        "version": INDEX_STATE_VERSION,
+        "module_definitions": {},
+        "module_to_file": {},
+        "defines": [],
+        "method_sets": {"nodes": [], "relations": []},
+        "package_imports": {},
+        "symbols": [],
+        "link_facts": {"nodes": [], "relations": []},
+        "package_doc": None,
+    }
+
+
+def is_structural(relation: CodeRelation) -> bool:
+    """Whether a relation is derived from the whole index (see module docstring)."""
+    return bool(relation.properties.get("structural"))
+
+
+def _relation_key(relation: CodeRelation) -> Tuple[str, str, str]:
+    return relation.source_id, relation.relation_type, relation.target_id
+
+
+def _is_method_set_node(node: CodeNode) -> bool:
+    if node.node_type == "Interface":
+        return True
+    return node.node_type == "Method" and "signature" in node.properties
+
+
+def _stored_version(stored: Dict[str, Any]) -> int:
+    """INDEX_STATE_VERSION a file was stored with; states from before versioning are 1."""
+    raw_state = stored.get("index_state")
+    if not raw_state:
+        # Nothing was recorded for the file, so there is nothing to upgrade
+        return INDEX_STATE_VERSION
+    try:
+        return int(json.loads(raw_state).get("version", 1))
+    except (TypeError, ValueError, AttributeError):
+        return 1
+
+
+def compute_content_hash(file_path: str) -> str:
+    """Return the SHA-256 hex digest of a file's bytes."""
+    digest = hashlib.sha256()
+    with open(file_path, "rb") as f:
+        for chunk in iter(lambda: f.read(65536), b""):
+            digest.update(chunk)
+    return digest.hexdigest()
+
+
+def get_file_fingerprint(file_path: str) -> Dict[str, Any]:
+    """Return the change-detection properties stored on a File node."""
+    stat = os.stat(file_path)
+    return {
+        "content_hash": compute_content_hash(file_path),
+        "mtime": stat.st_mtime,
+        "size": stat.st_size,
+    }
+
+
+@dataclass
+class IncrementalPlan:
+    """Outcome of comparing the working tree with the stored file states."""
+    changed: List[str] = field(default_factory=list)
+    unchanged: List[str] = field(default_factory=list)
+    deleted: List[str] = field(default_factory=list)
+    # Files whose content is identical but whose mtime moved: (path, new mtime)
+    touched: List[Tuple[str, float]] = field(default_factory=list)
+    
+    @property
+    def has_changes(self) -> bool:
+        return bool(self.changed or self.deleted)
+
+
+def plan_incremental_update(source_files: Iterable[str], stored_states: Dict[str, Dict[str, Any]]) -> IncrementalPlan:
+    """
+    Classify source files against the states stored in the graph.
+    
+    Args:
+        source_files: Files found by the walker
+        stored_states: file_path -> {"content_hash", "mtime", "size", "parse_error", ...}
+    
+    Returns:
+        IncrementalPlan with changed (new, modified or failed to parse last time), unchanged and deleted files
+    """
+    plan = IncrementalPlan()
+    seen = set()
+    
+    for file_path in source_files:
+        try:
+            stat = os.stat(file_path)
+        except FileNotFoundError:
+            # Deleted since the walk found it: planned as deleted, like a file the walk no longer finds
+            continue
+        seen.add(file_path)
+        stored = stored_states.get(file_path)
+        # Files that failed to parse are retried, the failure may be transient or fixed by a parser upgrade
+        if (not stored or not stored.get("content_hash") or _stored_version(stored) < INDEX_STATE_VERSION
+                or stored.get("parse_error")):
+            plan.changed.append(file_path)
+            continue
+        
+        if stored.get("mtime") == stat.st_mtime and stored.get("size") == stat.st_size:
+            plan.unchanged.append(file_path)
+            continue
+        
+        if compute_content_hash(file_path) == stored["content_hash"]:
+            plan.unchanged.append(file_path)
+            plan.touched.append((file_path, stat.st_mtime))
+        else:
+            plan.changed.append(file_path)
+    
+    plan.deleted = sorted(path for path in stored_states if path not in seen)
+    return plan
+
+
+def _package_of(node_id: str, nodes: Dict[str, CodeNode], node_files: Dict[str, str]) -> Optional[str]:
+    """Package (or ExternalPackage) node ID of the package a node belongs to."""
+    if is_package_id(node_id):
+        return node_id
+    node = nodes.get(node_id)
+    file_path = node.file_path if node is not None else node_files.get(node_id)
+    if not file_path and node_id.startswith("file:"):
+        file_path = node_id[len("file:"):]
+    if not file_path:
+        return None
+    file_node = nodes.get(f"file:{file_path}")
+    return package_id(file_path, file_node.properties.get("import_path") if file_node is not None else None)
+
+
+def collect_package_imports(
+    relations: Iterable[CodeRelation],
+    nodes: Dict[str, CodeNode],
+    node_files: Optional[Dict[str, str]] = None,
+) -> Dict[str, Dict[str, Any]]:
+    """
+    Group the IMPORTS edges of each file by imported package.
+    
+    Returns:
+        file_path -> {"package": package node ID, "targets": {package node ID: number of imports}}
+    """
+    node_files = node_files or {}
+    imports: Dict[str, Dict[str, Any]] = {}
+    for relation in relations:
+        if relation.relation_type != "IMPORTS":
+            continue
+        source = nodes.get(relation.source_id)
+        if source is None or source.node_type != "File":
+            continue
+        import_path = relation.properties.get("import_path")
+        if import_path and not is_package_id(relation.target_id):
+            # Java type imports name the type's package, whose file may not have been parsed
+            target = package_id("", import_path)
+        else:
+            target = _package_of(relation.target_id, nodes, node_files)
+        if target is None:
+            continue
+        entry = imports.setdefault(source.file_path, {
+            "package": package_id(source.file_path, source.properties.get("import_path")),
+            "targets": {},
+        })
+        entry["targets"][target] = entry["targets"].get(target, 0) + 1
+    return imports
+
+
+def compute_package_dependencies(file_imports: Dict[str, Dict[str, Any]]) -> List[CodeRelation]:
+    """
+    Aggregate per-file package imports into DEPENDS_ON edges between packages.
+    
+    Each edge counts the imports ("imports") and the importing files
+    ("files") behind it, which are its premises. Imports within a package
+    are dropped.
+    """
+    totals: Dict[Tuple[str, str], List[int]] = {}
+    importers: Dict[Tuple[str, str], List[str]] = {}
+    for file_path, entry in file_imports.items():
+        for target, count in entry.get("targets", {}).items():
+            if target == entry["package"]:
+                continue
+            total = totals.setdefault((entry["package"], target), [0, 0])
+            total[0] += count
+            total[1] += 1
+            importers.setdefault((entry["package"], target), []).append(file_path)
+    
+    return [
+        CodeRelation(source, target, PACKAGE_DEPENDENCY_RELATION, properties={
+            "imports": imports, "files": files, "structural": True,
+            SOURCE_PROPERTY: SOURCE_PACKAGE_IMPORTS, PREMISES_PROPERTY: premise_files(importers[(source, target)]),
+        })
+        for (source, target), (imports, files) in sorted(totals.items())
+    ]
+
+
+def load_package_imports(stored_states: Dict[str, Dict[str, Any]], file_paths: Iterable[str]) -> Dict[str, Dict[str, Any]]:
+    """Per-file package imports (see collect_package_imports) stored in index_state."""
+    imports: Dict[str, Dict[str, Any]] = {}
+    for file_path in file_paths:
+        raw_state = (stored_states.get(file_path) or {}).get("index_state")
+        if not raw_state:
+            continue
+        try:
+            entry = json.loads(raw_state).get("package_imports")
+        except (TypeError, ValueError):
+            continue
+        if entry:
+            imports[file_path] = entry
+    return imports
+
+
+def build_file_index_states(
+    nodes: Dict[str, CodeNode],
+    relations: List[CodeRelation],
+    module_definitions: Dict[str, Dict[str, str]],
+    module_to_file: Dict[str, str],
+    node_files: Optional[Dict[str, str]] = None,
+    kept_ids: Optional[Set[str]] = None,
+    link_facts: Optional[Dict[str, Dict[str, List[Any]]]] = None,
+) -> Dict[str, Dict[str, Any]]:
+    """
+    Split the resolution index into per-file shares.
+    
+    Each symbol entry is attributed to the file of the node it points to,
+    so a changed file's stale entries disappear with its File node.
+    Interface and method-set entries are attributed to the file declaring
+    the interface or method, package imports to the importing file.
+    node_files locates import targets of files that were not parsed.
+    kept_ids are the node IDs a rename or move kept from an earlier run.
+    link_facts are the per-file facts of src.linking.collect_link_facts.
+    """
+    kept_ids = kept_ids or set()
+    states: Dict[str, Dict[str, Any]] = {}
+    
+    def state_for(file_path: str) -> Dict[str, Any]:
+        return states.setdefault(file_path, _empty_index_state())
+    
+    for module_name, symbols in module_definitions.items():
+        for symbol, node_id in symbols.items():
+            node = nodes.get(node_id)
+            if node is None or not node.file_path:
+                continue
+            state_for(node.file_path)["module_definitions"].setdefault(module_name, {})[symbol] = node_id
+    
+    for module_name, file_node_id in module_to_file.items():
+        node = nodes.get(file_node_id)
+        if node is not None:
+            state_for(node.file_path)["module_to_file"][module_name] = file_node_id
+    
+    # CALLS_METHOD resolution walks DEFINES edges from class nodes
+    for relation in relations:
+        if relation.relation_type != "DEFINES":
+            continue
+        source = nodes.get(relation.source_id)
+        target = nodes.get(relation.target_id)
+        if source is None or target is None or target.node_type != "Method":
+            continue
+        state_for(target.file_path)["defines"].append(
+            [relation.source_id, relation.target_id, target.name, target.file_path]
+        )
+    
+    # Inputs of the IMPLEMENTS computation
+    for node_id, node in nodes.items():
+        if node.file_path and _is_method_set_node(node):
+            properties = {key: node.properties[key] for key in METHOD_SET_PROPERTIES if key in node.properties}
+            state_for(node.file_path)["method_sets"]["nodes"].append([node_id, node.node_type, node.name, properties])
+    for relation in relations:
+        if relation.relation_type not in METHOD_SET_RELATIONS:
+            continue
+        source = nodes.get(relation.source_id)
+        if source is None or not source.file_path or not _is_method_set_node(source):
+            continue
+        state_for(source.file_path)["method_sets"]["relations"].append(
+            [relation.source_id, relation.target_id, relation.relation_type, relation.properties]
+        )
+    
+    # Inputs of the package DEPENDS_ON aggregation
+    for file_path, entry in collect_package_imports(relations, nodes, node_files).items():
+        state_for(file_path)["package_imports"] = entry
+    
+    # Inputs of the package summaries (see summaries.py)
+    for node in nodes.values():
+        entry = package_doc_entry(node)
+        if entry is not None:
+            state_for(node.file_path)["package_doc"] = entry
+    
+    # Inputs of the rename detection of the next run
+    for node_id, node in nodes.items():
+        if node.file_path and node.node_type in SYMBOL_NODE_TYPES and node.line_no:
+            state_for(node.file_path)["symbols"].append([
+                node_id, node.node_type, node.name, node.properties.get("body_hash"),
+                node_id in kept_ids, node.properties.get("renamed_from"),
+            ])
+        elif node.node_type == "File" and node.properties.get("renamed_from"):
+            state_for(node.file_path)["renamed_from"] = node.properties["renamed_from"]
+    for state in states.values():
+        state["symbols"].sort(key=lambda entry: entry[0])
+    
+    # Inputs of the cross-language linking
+    for file_path, facts in (link_facts or {}).items():
+        state_for(file_path)["link_facts"] = facts
+    
+    return states
+
+
+def serialize_index_state(index_states: Dict[str, Dict[str, Any]], file_path: str) -> str:
+    """The index_state property of a file."""
+    return json.dumps(index_states.get(file_path, _empty_index_state()), sort_keys=True)
+
+
+def annotate_file_nodes(nodes: Dict[str, CodeNode], index_states: Dict[str, Dict[str, Any]]) -> None:
+    """Store fingerprint and index_state properties on File nodes."""
+    for node in nodes.values():
+        if node.node_type != "File" or not node.file_path or not os.path.isfile(node.file_path):
+            continue
+        node.properties.update(get_file_fingerprint(node.file_path))
+        node.properties["index_state"] = serialize_index_state(index_states, node.file_path)
+
+
+def load_index_context(
+    stored_states: Dict[str, Dict[str, Any]],
+    file_paths: Iterable[str],
+) -> Tuple[Dict[str, Dict[str, str]], Dict[str, str], Dict[str, CodeNode], List[CodeRelation], Dict[str, str]]:
+    """
+    Rebuild the resolution index contributed by files that are not re-parsed.
+    
+    Returns:
+        (module_definitions, module_to_file, stub_nodes, stub_relations, node_files)
+        where stub nodes/relations stand in for DEFINES links needed by
+        CALLS_METHOD resolution, for the interfaces and method sets
+        IMPLEMENTS edges are computed from and for the link facts of the
+        cross-language matchers, and node_files maps each known node ID
+        to its file.
+    """
+    module_definitions: Dict[str, Dict[str, str]] = {}
+    module_to_file: Dict[str, str] = {}
+    stub_nodes: Dict[str, CodeNode] = {}
+    stub_relations: List[CodeRelation] = []
+    node_files: Dict[str, str] = {}
+    
+    for file_path in file_paths:
+        raw_state = (stored_states.get(file_path) or {}).get("index_state")
+        if not raw_state:
+            continue
+        try:
+            state = json.loads(raw_state)
+        except (TypeError, ValueError):
+            logger.warning(f"Ignoring unreadable index_state for {file_path}")
+            continue
+        
+        for module_name, symbols in state.get("module_definitions", {}).items():
+            module_definitions.setdefault(module_name, {}).update(symbols)
+            for node_id in symbols.values():
+                node_files[node_id] = file_path
+        
+        for module_name, file_node_id in state.get("module_to_file", {}).items():
+            module_to_file[module_name] = file_node_id
+            node_files[file_node_id] = file_path
+        
+        for class_id, method_id, method_name, method_file in state.get("defines", []):
+            stub_nodes[method_id] = CodeNode(method_id, "Method", method_name, method_file, 0)
+            stub_relations.append(CodeRelation(class_id, method_id, "DEFINES"))
+            node_files[method_id] = method_file
+        
+        method_sets = state.get("method_sets", {})
+        for node_id, node_type, name, properties in method_sets.get("nodes", []):
+            stub_nodes[node_id] = CodeNode(node_id, node_type, name, file_path, 0, properties=properties)
+            node_files[node_id] = file_path
+        for source_id, target_id, relation_type, properties in method_sets.get("relations", []):
+            stub_relations.append(CodeRelation(source_id, target_id, relation_type, properties))
+        
+        link_facts = state.get("link_facts", {})
+        for node_id, node_type, name, properties in link_facts.get("nodes", []):
+            if node_id in stub_nodes:
+                # Also a method-set or DEFINES stub: one node with both sets of properties
+                stub_nodes[node_id].properties.update(properties)
+            else:
+                stub_nodes[node_id] = CodeNode(node_id, node_type, name, file_path, 0, properties=dict(properties))
+            node_files[node_id] = file_path
+        for source_id, target_id, relation_type, properties in link_facts.get("relations", []):
+            stub_relations.append(CodeRelation(source_id, target_id, relation_type, properties))
+    
+    return module_definitions, module_to_file, stub_nodes, stub_relations, node_files
+
+
+def compute_file_dependencies(
+    relations: Iterable[CodeRelation],
+    nodes: Dict[str, CodeNode],
+    node_files: Optional[Dict[str, str]] = None,
+) -> List[CodeRelation]:
+    """
+    Derive DEPENDS_ON_FILE edges (source file -> target file) from cross-file relations.
+    
+    A relation's premises count as target files too. Nodes without a file
+    (external placeholders) and structural relations do not create
+    dependencies.
+    """
+    node_files = node_files or {}
+    
+    def file_of(node_id: str) -> Optional[str]:
+        node = nodes.get(node_id)
+        if node is not None:
+            return node.file_path or None
+        return node_files.get(node_id)
+    
+    pairs: Set[Tuple[str, str]] = set()
+    for relation in relations:
+        if relation.relation_type == FILE_DEPENDENCY_RELATION or is_structural(relation):
+            continue
+        source_file = file_of(relation.source_id)
+        target_files = [file_of(relation.target_id)] + list(relation.properties.get(PREMISES_PROPERTY) or [])
+        for target_file in target_files:
+            if source_file and target_file and source_file != target_file:
+                pairs.add((source_file, target_file))
+    
+    return [
+        CodeRelation(f"file:{source}", f"file:{target}", FILE_DEPENDENCY_RELATION,
+                     properties={SOURCE_PROPERTY: SOURCE_FILE_DEPENDENCIES})
+        for source, target in sorted(pairs)
+    ]
+
+
+def stale_structural_keys(relations: Iterable[CodeRelation], invalidated_files: Set[str]) -> List[Tuple[str, str, str]]:
+    """
+    (source, type, target) of the recomputed structural relations to write.
+    
+    Those are the ones with a premise among invalidated_files (changed,
+    deleted and re-resolved files) and the ones without premises. The
+    caller deletes the stored relations with these keys or with such a
+    premise (GraphStore.delete_stale_structural_relationships), then
+    writes the recomputed relations of every deleted key.
+    """
+    return sorted({
+        _relation_key(relation) for relation in relations
+        if is_structural(relation) and (
+            not relation.properties.get(PREMISES_PROPERTY)
+            or invalidated_files.intersection(relation.properties[PREMISES_PROPERTY])
+        )
+    })
+
+
+def select_structural_writes(relations: List[CodeRelation], keys: Iterable[Tuple[str, str, str]]) -> List[CodeRelation]:
+    """The relations without the structural ones whose key is not in keys, which are stored already."""
+    keys = set(keys)
+    return [relation for relation in relations if not is_structural(relation) or _relation_key(relation) in keys]
+
+
+def select_incremental_writes(
+    nodes: Dict[str, CodeNode],
+    relations: List[CodeRelation],
+    changed_files: Set[str],
+    existing_shared_ids: Set[str],
+    stub_relations: Iterable[CodeRelation] = (),
+) -> Tuple[Dict[str, CodeNode], List[CodeRelation]]:
+    """
+    Pick the nodes and relations an incremental run must insert.
+    
+    Nodes of changed files were deleted from the graph and are re-inserted.
+    Shared nodes without a file (external placeholders) are inserted only
+    if they do not exist yet. A relation is inserted when at least one of
+    its endpoints is inserted; every other relation is already in the graph.
+    Structural relations are all kept, select_structural_writes narrows
+    them down to the stale ones.
+    """
+    nodes_to_write = {
+        node_id: node for node_id, node in nodes.items()
+        if (node.file_path in changed_files) or (not node.file_path and node_id not in existing_shared_ids)
+    }
+    
+    stub_ids = {id(relation) for relation in stub_relations}
+    relations_to_write = [
+        relation for relation in relations
+        if id(relation) not in stub_ids
+        and (is_structural(relation) or relation.source_id in nodes_to_write or relation.target_id in nodes_to_write)
+    ]
+    
+    return nodes_to_write, relations_to_write
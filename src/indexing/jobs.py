@@ -0,0 +1,260 @@
+"""
+Background index jobs with progress reporting and cancellation.
+
+An IndexProgress is handed to CodebaseKnowledgeGraph.process_codebase,
+which reports its phase (walking, parsing, resolving, embedding, writing)
+and the items processed in that phase, and checks for cancellation
+between files and write batches. Cancelling raises IndexCancelled out of
+process_codebase at the next check.
+
+A cancelled run leaves the graph consistent: cancelled before anything
+was written, the previous graph is untouched; cancelled later, the graph
+holds what was written so far and its IndexMetadata node says
+index_complete: false until a run of the same root completes.
+
+IndexJobManager runs index jobs on background threads, one per root:
+jobs on different roots run concurrently, a second job on a root that is
+still being indexed is rejected with JobConflictError. A job may name the
+repository (see src/graph_store/repository.py) its root is indexed as.
+Runs of a root outside the manager (watch syncs) take turns with its jobs
+under the root's write lock (see coordination.py).
+"""
+
+import logging
+import os
+import threading
+import time
+import uuid
+from datetime import datetime, timezone
+from typing import Any, Callable, Dict, List, Optional
+
+logger = logging.getLogger(__name__)
+
+# Phases of an index run, in order
+PHASES = ("queued", "walking", "parsing", "resolving", "embedding", "writing", "done")
+
+# Errors listed in a snapshot; the count is always exact
+STATUS_ERROR_LIMIT = 20
+
+
+def _utc_now() -> str:
+    return datetime.now(timezone.utc).isoformat(timespec="seconds")
+
+
+class IndexCancelled(Exception):
+    """Raised out of process_codebase when its IndexProgress was cancelled."""
+
+
+class JobConflictError(Exception):
+    """A job for the same root is still running; job_id names it."""
+    
+    def __init__(self, job: "IndexJob"):
+        super().__init__(f"Job {job.job_id} is already indexing {job.root}")
+        self.job_id = job.job_id
+
+
+class IndexProgress:
+    """
+    Progress of one index run, updated by the indexer and read from other threads.
+    
+    processed / total count the items of the current phase: files while
+    walking and parsing, nodes while embedding, relationships while writing.
+    eta_seconds extrapolates the rate of the current phase, so it covers the
+    rest of that phase only.
+    """
+    
+    def __init__(self, on_update: Optional[Callable[["IndexProgress"], None]] = None):
+        self._lock = threading.Lock()
+        self._cancelled = threading.Event()
+        self.on_update = on_update
+        self.phase = "queued"
+        self.processed = 0
+        self.total: Optional[int] = None
+        self.files_total: Optional[int] = None
+        self.current_file: Optional[str] = None
+        self.errors: List[str] = []
+        self.error_count = 0
+        self.started_at = time.monotonic()
+        self._phase_started = self.started_at
+    
+    def set_phase(self, phase: str, total: Optional[int] = None) -> None:
+        """Enter a phase with `total` items to process (None if unknown)."""
+        with self._lock:
+            self.phase = phase
+            self.processed = 0
+            self.total = total
+            self.current_file = None
+            self._phase_started = time.monotonic()
+        self._notify()
+    
+    def set_files_total(self, count: int) -> None:
+        with self._lock:
+            self.files_total = count
+    
+    def advance(self, count: int = 1, current_file: Optional[str] = None) -> None:
+        """Count processed items of the current phase."""
+        with self._lock:
+            self.processed += count
+            if current_file is not None:
+                self.current_file = current_file
+        self._notify()
+    
+    def add_error(self, message: str) -> None:
+        with self._lock:
+            self.error_count += 1
+            if len(self.errors) < STATUS_ERROR_LIMIT:
+                self.errors.append(message)
+    
+    def cancel(self) -> None:
+        """Ask the run to stop at its next check."""
+        self._cancelled.set()
+    
+    @property
+    def cancelled(self) -> bool:
+        return self._cancelled.is_set()
+    
+    def check_cancelled(self) -> None:
+        """Raise IndexCancelled if cancel() was called."""
+        if self._cancelled.is_set():
+            raise IndexCancelled(f"Index run cancelled during {self.phase}")
+    
+    def snapshot(self) -> Dict[str, Any]:
+        """Current state as a JSON-serializable dict."""
+        with self._lock:
+            now = time.monotonic()
+            eta = None
+            if self.total and self.processed:
+                rate = self.processed / max(now - self._phase_started, 1e-6)
+                eta = round(max(self.total - self.processed, 0) / rate, 1)
+            return {
+                "phase": self.phase,
+                "processed": self.processed,
+                "total": self.total,
+                "files_total": self.files_total,
+                "current_file": self.current_file,
+                "error_count": self.error_count,
+                "errors": list(self.errors),
+                "elapsed_seconds": round(now - self.started_at, 2),
+                "eta_seconds": eta,
+                "cancel_requested": self._cancelled.is_set(),
+            }
+    
+    def _notify(self) -> None:
+        if self.on_update is None:
+            return
+        try:
+            self.on_update(self)
+        except Exception as e:
+            logger.debug(f"Progress callback failed: {e}")
+
+
+class IndexJob:
+    """One background index run; `state` is running, completed, failed or cancelled."""
+    
+    def __init__(self, job_id: str, root: str, incremental: bool, repo: Optional[str] = None):
+        self.job_id = job_id
+        self.root = root
+        self.incremental = incremental
+        self.repo = repo
+        self.progress = IndexProgress()
+        self.state = "running"
+        self.result: Optional[Dict[str, Any]] = None
+        self.error: Optional[str] = None
+        self.started_at = _utc_now()
+        self.finished_at: Optional[str] = None
+        self.done = threading.Event()
+    
+    @property
+    def running(self) -> bool:
+        return not self.done.is_set()
+    
+    def status(self) -> Dict[str, Any]:
+        """State of the job with its progress snapshot."""
+        return {
+            "job_id": self.job_id,
+            "root": self.root,
+            "repo": self.repo,
+            "incremental": self.incremental,
+            "state": self.state,
+            "started_at": self.started_at,
+            "finished_at": self.finished_at,
+            "progress": self.progress.snapshot(),
+            "result": self.result,
+            "error": self.error,
+        }
+
+
+class IndexJobManager:
+    """
+    Runs index jobs on background threads.
+    
+    Usage:
+        manager = IndexJobManager(run)
+        job = manager.start("/path/to/repo", incremental=True)
+        manager.get(job.job_id).status()
+        manager.cancel(job.job_id)
+    
+    `run(root, incremental, progress, repo)` does the indexing and returns
+    the job result; it is expected to raise IndexCancelled once
+    progress.cancel() was called. repo is the repository the job was
+    started for, None for the runner's default.
+    """
+    
+    def __init__(self, run: Callable[[str, bool, IndexProgress, Optional[str]], Dict[str, Any]]):
+        self._run = run
+        self._lock = threading.Lock()
+        self._jobs: Dict[str, IndexJob] = {}
+    
+    def start(self, root: str, incremental: bool = True, repo: Optional[str] = None) -> IndexJob:
+        """
+        Start indexing root in the background, as repository repo.
+        
+        Raises:
+            JobConflictError: A job for the same root is still running
+        """
+        root = os.path.realpath(root)
+        with self._lock:
+            for job in self._jobs.values():
+                if job.root == root and job.running:
+                    raise JobConflictError(job)
+            job = IndexJob(uuid.uuid4().hex[:12], root, incremental, repo)
+            self._jobs[job.job_id] = job
+        
+        thread = threading.Thread(target=self._execute, args=(job,), name=f"index-job-{job.job_id}", daemon=True)
+        thread.start()
+        return job
+    
+    def get(self, job_id: str) -> IndexJob:
+        """The job with job_id (KeyError if unknown)."""
+        with self._lock:
+            return self._jobs[job_id]
+    
+    def jobs(self) -> List[IndexJob]:
+        """Every job started by this manager, oldest first."""
+        with self._lock:
+            return list(self._jobs.values())
+    
+    def cancel(self, job_id: str) -> IndexJob:
+        """Ask a job to stop; a finished job is returned unchanged (KeyError if unknown)."""
+        job = self.get(job_id)
+        if job.running:
+            job.progress.cancel()
+        return job
+    
+    def _execute(self, job: IndexJob) -> None:
+        try:
+            job.result = self._run(job.root, job.incremental, job.progress, job.repo)
+            job.state = "completed"
+        except IndexCancelled as e:
+            job.state = "cancelled"
+            job.error = str(e)
+            logger.info(f"Index job {job.job_id} cancelled: {e}")
+        except Exception as e:
+            job.state = "failed"
+            job.error = str(e)
+            logger.error(f"Index job {job.job_id} failed: {e}")
+        finally:
+            job.finished_at = _utc_now()
+            if job.state == "completed":
+                job.progress.set_phase("done")
+            job.done.set()
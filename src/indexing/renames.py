@@ -0,0 +1,278 @@
+"""
+Rename and move detection for incremental re-indexing.
+
+Node IDs are built from a symbol's file, name and line, so a renamed
+function or a moved file comes back from the parser with new IDs, and
+whatever was attached to the old ones (annotations, cached analysis,
+IDs saved by a client) would be lost. Before an incremental run writes
+its nodes, the symbols of every re-parsed file are matched against the
+"symbols" entries its index_state recorded on the previous run:
+
+- a symbol that disappeared and one that appeared in the same file, with
+  the same node type and body_hash, are a rename: the new symbol keeps
+  the old node ID and records the old name in renamed_from
+- a new file whose content hash equals the stored hash of a deleted file
+  is a move: its File node records the old path in renamed_from and its
+  symbols keep their old node IDs
+- a symbol whose ID was kept this way keeps it on later runs, as long as
+  its type and name are unique in the file
+
+body_hash hashes a symbol's code tokens with comments, whitespace and the
+symbol's own name left out, so reformatting or renaming alone does not
+change it. Matching is best-effort: when a hash, a name or a file content
+has two candidates on either side, the symbols keep their parsed IDs
+(delete + create). A full rebuild always assigns parsed IDs.
+"""
+
+import hashlib
+import json
+import logging
+import re
+from collections import defaultdict
+from typing import Any, Dict, Iterable, List, Optional, Set, Tuple
+
+from src.ast_parser.parser import CodeNode, CodeRelation
+from src.embeddings.node_text import SourceLines, node_body
+from src.indexing.incremental import SYMBOL_NODE_TYPES, compute_content_hash
+
+logger = logging.getLogger(__name__)
+
+# Line and block comments of the supported languages
+_COMMENT_PATTERN = re.compile(r"//[^\n]*|/\*.*?\*/|#[^\n]*", re.DOTALL)
+_TOKEN_PATTERN = re.compile(r"\w+|[^\w\s]")
+
+# Stands in for the symbol's own name in the hashed tokens
+_NAME_TOKEN = "\0"
+
+
+def body_hash(code: str, name: str) -> Optional[str]:
+    """Hash of the normalized tokens of a symbol's code, or None if it has no tokens."""
+    # Nested Java types are named Outer$Inner but declared as Inner
+    short_name = re.split(r"[$.]", name)[-1]
+    tokens = [
+        _NAME_TOKEN if token == short_name else token
+        for token in _TOKEN_PATTERN.findall(_COMMENT_PATTERN.sub(" ", code))
+    ]
+    if not tokens:
+        return None
+    return hashlib.sha256("\x1f".join(tokens).encode("utf-8", errors="replace")).hexdigest()[:16]
+
+
+def is_symbol(node: CodeNode) -> bool:
+    """Whether a node takes part in rename detection."""
+    return node.node_type in SYMBOL_NODE_TYPES and bool(node.file_path)
+
+
+def annotate_body_hashes(nodes: Dict[str, CodeNode]) -> None:
+    """Store body_hash on the symbol nodes whose code can be read."""
+    source_lines = SourceLines()
+    for node in nodes.values():
+        if not is_symbol(node) or not node.line_no:
+            continue
+        digest = body_hash(node_body(node, source_lines), node.name)
+        if digest:
+            node.properties["body_hash"] = digest
+
+
+def _stored_state(stored_states: Dict[str, Dict[str, Any]], file_path: str) -> Dict[str, Any]:
+    raw_state = (stored_states.get(file_path) or {}).get("index_state")
+    if not raw_state:
+        return {}
+    try:
+        return json.loads(raw_state)
+    except (TypeError, ValueError):
+        return {}
+
+
+def _symbol_entry(entry: List[Any]) -> Tuple[str, str, str, Optional[str], bool, Optional[str]]:
+    """(node_id, node_type, name, body_hash, kept, renamed_from) of a stored "symbols" entry."""
+    node_id, node_type, name, digest, kept, renamed_from = (list(entry) + [None] * 6)[:6]
+    return node_id, node_type, name, digest, bool(kept), renamed_from
+
+
+def unique_pairs(left: Dict[Any, list], right: Dict[Any, list]) -> Iterable[Tuple[Any, Any]]:
+    """Items of left and right filed under the same key, for keys with one item on each side."""
+    for key, items in left.items():
+        others = right.get(key, [])
+        if len(items) == 1 and len(others) == 1:
+            yield items[0], others[0]
+
+
+def detect_file_moves(
+    changed_files: Iterable[str],
+    deleted_files: Iterable[str],
+    stored_states: Dict[str, Dict[str, Any]],
+) -> Dict[str, str]:
+    """
+    Pair new files with the deleted files whose stored content hash they match.
+    
+    Args:
+        changed_files: Changed files of the plan; only those without a stored state are new
+        deleted_files: Deleted files of the plan
+        stored_states: file_path -> stored states
+    
+    Returns:
+        new path -> old path, for hashes shared by exactly one new and one deleted file
+    """
+    deleted_by_hash = defaultdict(list)
+    for file_path in deleted_files:
+        digest = (stored_states.get(file_path) or {}).get("content_hash")
+        if digest:
+            deleted_by_hash[digest].append(file_path)
+    if not deleted_by_hash:
+        return {}
+    
+    added_by_hash = defaultdict(list)
+    for file_path in changed_files:
+        if file_path not in stored_states:
+            added_by_hash[compute_content_hash(file_path)].append(file_path)
+    
+    return {new_path: old_path for new_path, old_path in unique_pairs(added_by_hash, deleted_by_hash)}
+
+
+def match_symbols(
+    new_symbols: List[CodeNode],
+    old_symbols: List[List[Any]],
+    moved: bool = False,
+) -> Dict[str, Tuple[str, Optional[str]]]:
+    """
+    Match the parsed symbols of one file against the symbols stored for it.
+    
+    Args:
+        new_symbols: Symbol nodes parsed from the file
+        old_symbols: The "symbols" entries of the file's stored index_state
+        moved: The file was moved, so every stored ID is kept, not only those kept before
+    
+    Returns:
+        parsed node ID -> (stored node ID, renamed_from)
+    """
+    new_by_name = defaultdict(list)
+    for node in new_symbols:
+        new_by_name[(node.node_type, node.name)].append(node)
+    old_by_name = defaultdict(list)
+    for entry in map(_symbol_entry, old_symbols):
+        old_by_name[(entry[1], entry[2])].append(entry)
+    
+    matches = {}
+    # Same type and name: IDs kept on an earlier run stay kept
+    for node, (old_id, _, _, _, kept, renamed_from) in unique_pairs(new_by_name, old_by_name):
+        if moved or kept:
+            matches[node.node_id] = (old_id, renamed_from)
+    
+    # Renames: a symbol gone and one added with the same type and body
+    added_by_body = defaultdict(list)
+    for key, nodes in new_by_name.items():
+        for node in nodes:
+            if key not in old_by_name and node.properties.get("body_hash"):
+                added_by_body[(node.node_type, node.properties["body_hash"])].append(node)
+    gone_by_body = defaultdict(list)
+    for key, entries in old_by_name.items():
+        for entry in entries:
+            if key not in new_by_name and entry[3]:
+                gone_by_body[(entry[1], entry[3])].append(entry)
+    for node, (old_id, _, old_name, _, _, _) in unique_pairs(added_by_body, gone_by_body):
+        matches[node.node_id] = (old_id, old_name)
+    
+    return matches
+
+
+def find_renamed_symbols(
+    nodes: Dict[str, CodeNode],
+    file_paths: Iterable[str],
+    stored_states: Dict[str, Dict[str, Any]],
+    moves: Dict[str, str],
+) -> Dict[str, Tuple[str, Optional[str]]]:
+    """
+    Match the symbols of re-parsed files against their stored index_state.
+    
+    Args:
+        nodes: Nodes of the run
+        file_paths: Re-parsed files
+        stored_states: file_path -> stored states
+        moves: new path -> old path, from detect_file_moves
+    
+    Returns:
+        parsed node ID -> (stored node ID, renamed_from), see match_symbols
+    """
+    symbols_by_file = defaultdict(list)
+    for node in nodes.values():
+        if is_symbol(node):
+            symbols_by_file[node.file_path].append(node)
+    
+    matches = {}
+    for file_path in sorted(file_paths):
+        old_path = moves.get(file_path, file_path)
+        old_symbols = _stored_state(stored_states, old_path).get("symbols")
+        if old_symbols:
+            matches.update(match_symbols(symbols_by_file.get(file_path, []), old_symbols,
+                                         moved=old_path != file_path))
+    return matches
+
+
+def previous_file_paths(
+    file_paths: Iterable[str],
+    stored_states: Dict[str, Dict[str, Any]],
+    moves: Dict[str, str],
+) -> Dict[str, str]:
+    """renamed_from of the File nodes of re-parsed files: the path moved from, or the one stored before."""
+    previous = {}
+    for file_path in file_paths:
+        renamed_from = moves.get(file_path) or _stored_state(stored_states, file_path).get("renamed_from")
+        if renamed_from:
+            previous[file_path] = renamed_from
+    return previous
+
+
+def apply_renames(
+    matches: Dict[str, Tuple[str, Optional[str]]],
+    previous_paths: Dict[str, str],
+    nodes: Dict[str, CodeNode],
+    relations: List[CodeRelation],
+    module_definitions: Dict[str, Dict[str, str]],
+) -> Set[str]:
+    """
+    Give matched symbols their stored IDs and rewrite every reference to them.
+    
+    Args:
+        matches: parsed node ID -> (stored node ID, renamed_from), from find_renamed_symbols
+        previous_paths: file path -> renamed_from of its File node, from previous_file_paths
+        nodes: Nodes of the run, re-keyed in place
+        relations: Relations of the run, endpoints rewritten in place
+        module_definitions: Resolution index, node IDs rewritten in place
+    
+    Returns:
+        The kept node IDs
+    """
+    remap = {}
+    kept = set()
+    for new_id, (old_id, renamed_from) in matches.items():
+        node = nodes.get(new_id)
+        # The stored ID may belong to another symbol of this run
+        if node is None or (old_id != new_id and old_id in nodes) or old_id in kept:
+            continue
+        if renamed_from:
+            node.properties["renamed_from"] = renamed_from
+        if old_id != new_id:
+            remap[new_id] = old_id
+        kept.add(old_id)
+    
+    for new_id, old_id in remap.items():
+        node = nodes.pop(new_id)
+        node.node_id = old_id
+        nodes[old_id] = node
+    for relation in relations:
+        relation.source_id = remap.get(relation.source_id, relation.source_id)
+        relation.target_id = remap.get(relation.target_id, relation.target_id)
+    for symbols in module_definitions.values():
+        for symbol, node_id in symbols.items():
+            if node_id in remap:
+                symbols[symbol] = remap[node_id]
+    
+    for file_path, renamed_from in previous_paths.items():
+        file_node = nodes.get(f"file:{file_path}")
+        if file_node is not None:
+            file_node.properties["renamed_from"] = renamed_from
+    
+    if remap or previous_paths:
+        logger.info(f"Kept the node IDs of {len(remap)} renamed or moved symbols")
+    return kept
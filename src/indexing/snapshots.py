@@ -0,0 +1,101 @@
+"""
+Snapshots: a codebase indexed as of a git ref.
+
+`src/main.py --codebase-path . --ref main` checks the ref out into a
+temporary detached worktree (`git worktree add --detach`), indexes it as
+a repository of its own and removes the worktree again; the working copy
+and its checked-out branch are left alone. The repository is named by
+--repo, "snap-<ref>" by default, and its Repository node records the
+ref, its commit and the codebase it was taken of (snapshot_ref,
+snapshot_commit, snapshot_of).
+
+Two snapshots of a codebase (or a snapshot and the working copy's own
+index) are compared with diff_graphs (see src/analysis/graph_diff.py).
+They take no part in cross-repository linking, so they do not duplicate
+the targets of the imports of other repositories, and the
+delete_repository tool (or diff_graphs --delete-snapshots) drops one in
+batched deletes once the comparison is done.
+
+File paths of a snapshot point into the removed worktree: its symbols
+and edges are all there, but tools that read source from disk find
+nothing for it unless it was indexed with --store-source.
+"""
+
+import logging
+import os
+import re
+import shutil
+import tempfile
+from contextlib import contextmanager
+from typing import Any, Dict, Iterator, Tuple
+
+from src.graph_store.repository import get_repo_name
+from src.indexing.git import GitError, find_repo_root, run_git
+
+logger = logging.getLogger(__name__)
+
+# Repository name of a snapshot indexed without --repo: the prefix and the ref
+SNAPSHOT_REPO_PREFIX = "snap-"
+
+# Repository node properties of a snapshot
+SNAPSHOT_REF_PROPERTY = "snapshot_ref"
+SNAPSHOT_COMMIT_PROPERTY = "snapshot_commit"
+SNAPSHOT_OF_PROPERTY = "snapshot_of"
+
+_INVALID_NAME_CHARACTERS = re.compile(r"[^A-Za-z0-9._-]+")
+
+
+def snapshot_repo_name(ref: str) -> str:
+    """Default repository name of a snapshot of ref, e.g. "snap-feature-login" for "feature/login"."""
+    return get_repo_name(SNAPSHOT_REPO_PREFIX + (_INVALID_NAME_CHARACTERS.sub("-", ref).strip("-") or "ref"))
+
+
+def is_snapshot(repository: Dict[str, Any]) -> bool:
+    """Whether Repository node properties are those of a snapshot."""
+    return bool(repository.get(SNAPSHOT_REF_PROPERTY))
+
+
+@contextmanager
+def checkout_snapshot(codebase_path: str, ref: str) -> Iterator[Tuple[str, str]]:
+    """
+    Check a ref of the git repository holding a codebase out into a temporary worktree.
+    
+    Args:
+        codebase_path: Codebase directory, the repository root or a directory inside it
+        ref: Branch, tag or commit
+    
+    Yields:
+        (the codebase directory inside the worktree, the commit checked out)
+    
+    Raises:
+        GitError: the codebase is not in a git repository, or ref does not name a commit
+    """
+    repo_root = find_repo_root(codebase_path)
+    if repo_root is None:
+        raise GitError(f"{codebase_path} is not in a git repository")
+    try:
+        commit = run_git(repo_root, "rev-parse", "--verify", "--quiet", f"{ref}^{{commit}}").strip()
+    except GitError:
+        raise GitError(f"'{ref}' does not name a commit of {repo_root}")
+    relative = os.path.relpath(os.path.realpath(codebase_path), repo_root)
+    
+    # Named like the repository, so the snapshot's root directory name matches the working copy's
+    parent = os.path.realpath(tempfile.mkdtemp(prefix="graph-snapshot-"))
+    worktree = os.path.join(parent, os.path.basename(repo_root))
+    try:
+        run_git(repo_root, "worktree", "add", "--detach", worktree, commit)
+    except GitError:
+        shutil.rmtree(parent, ignore_errors=True)
+        raise
+    try:
+        yield os.path.normpath(os.path.join(worktree, relative)), commit
+    finally:
+        try:
+            run_git(repo_root, "worktree", "remove", "--force", worktree)
+        except GitError as e:
+            logger.warning(f"Cannot remove the snapshot worktree {worktree}: {e}")
+        shutil.rmtree(parent, ignore_errors=True)
+        try:
+            run_git(repo_root, "worktree", "prune")
+        except GitError:
+            pass
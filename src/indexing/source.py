@@ -0,0 +1,207 @@
+"""
+Symbol source stored on graph nodes.
+
+With INDEX_STORE_SOURCE (or --store-source) set, every symbol node
+(functions, methods, types) gets a "source" property holding its code,
+so MCP clients can read it without a second filesystem read:
+
+- "true": the whole declaration, body included
+- "signatures_only": the declaration header only, up to the line that
+  opens the body (`{` or a trailing `:`) or ends it (`;`)
+- "false" (default): nothing is stored
+
+The source is capped at INDEX_SOURCE_MAX_BYTES (default 8192) of UTF-8;
+a cut source has source_truncated set. The cut backs off to the start of
+the rune it falls in, so a multi-byte character is never split.
+
+Each file is read once per write batch and the symbols are sliced out
+of it by byte offset, instead of re-reading the file for every symbol.
+"""
+
+import logging
+import os
+from typing import Any, Dict, Iterable, List, Optional
+
+from src.indexing.incremental import SYMBOL_NODE_TYPES
+
+logger = logging.getLogger(__name__)
+
+STORE_SOURCE_MODES = ("false", "true", "signatures_only")
+
+DEFAULT_SOURCE_MAX_BYTES = 8192
+
+# Header lines kept at most by signatures_only, for declarations without a recognizable body start
+SIGNATURE_MAX_LINES = 20
+
+_OPENING = "([{"
+_CLOSING = ")]}"
+
+
+def get_store_source(store_source: Optional[str] = None) -> str:
+    """
+    Resolve the store_source mode.
+    
+    Args:
+        store_source: "true", "false" or "signatures_only", if None, get from INDEX_STORE_SOURCE (default: false)
+    
+    Raises:
+        ValueError: for an unknown mode
+    """
+    mode = (store_source or os.getenv("INDEX_STORE_SOURCE") or "false").strip().lower()
+    if mode not in STORE_SOURCE_MODES:
+        raise ValueError(f"Unknown store_source mode '{mode}', expected one of: {', '.join(STORE_SOURCE_MODES)}")
+    return mode
+
+
+def get_source_max_bytes(max_bytes: Optional[int] = None) -> int:
+    """Byte cap of a stored source, if None, get from INDEX_SOURCE_MAX_BYTES (default 8192)."""
+    if max_bytes is not None:
+        return max(1, max_bytes)
+    value = os.getenv("INDEX_SOURCE_MAX_BYTES", "")
+    if value:
+        try:
+            return max(1, int(value))
+        except ValueError:
+            logger.warning(f"Invalid INDEX_SOURCE_MAX_BYTES value '{value}', using {DEFAULT_SOURCE_MAX_BYTES}")
+    return DEFAULT_SOURCE_MAX_BYTES
+
+
+def truncate_utf8(data: bytes, max_bytes: int) -> bytes:
+    """Cut data to at most max_bytes without splitting a UTF-8 sequence."""
+    if len(data) <= max_bytes:
+        return data
+    end = max_bytes
+    # Back off over continuation bytes (10xxxxxx) to the lead byte of the cut rune
+    while end > 0 and (data[end] & 0xC0) == 0x80:
+        end -= 1
+    return data[:end]
+
+
+def _line_offsets(data: bytes) -> List[int]:
+    """Byte offset of the start of every line, plus the end of the data."""
+    offsets = [0]
+    position = data.find(b"\n")
+    while position != -1:
+        offsets.append(position + 1)
+        position = data.find(b"\n", position + 1)
+    if offsets[-1] != len(data):
+        offsets.append(len(data))
+    return offsets
+
+
+def _header_line_count(lines: List[bytes]) -> int:
+    """Number of leading lines that make up the declaration header."""
+    depth = 0
+    for index, raw in enumerate(lines[:SIGNATURE_MAX_LINES]):
+        line = raw.decode("utf-8", errors="replace").rstrip()
+        if not line:
+            continue
+        for char in line[:-1]:
+            if char in _OPENING:
+                depth += 1
+            elif char in _CLOSING:
+                depth = max(0, depth - 1)
+        last = line[-1]
+        if depth == 0 and last in "{:;":
+            return index + 1
+        if last in _OPENING:
+            depth += 1
+        elif last in _CLOSING:
+            depth = max(0, depth - 1)
+    return min(len(lines), SIGNATURE_MAX_LINES)
+
+
+def slice_source(data: bytes, offsets: List[int], line_no: int, end_line_no: Optional[int],
+                 mode: str, max_bytes: int) -> Optional[Dict[str, Any]]:
+    """
+    Source of the lines line_no..end_line_no (1-based, inclusive) of a file.
+    
+    Args:
+        data: File content
+        offsets: Line start offsets of data, see _line_offsets
+        line_no: First line of the declaration
+        end_line_no: Last line, if None, the declaration is its first line
+        mode: "true" or "signatures_only"
+        max_bytes: Byte cap of the source
+    
+    Returns:
+        {"source", "source_truncated"}, or None when the lines are outside the file
+    """
+    line_count = len(offsets) - 1
+    if not line_no or line_no < 1 or line_no > line_count:
+        return None
+    last = min(max(end_line_no or line_no, line_no), line_count)
+    if mode == "signatures_only":
+        lines = [data[offsets[i]:offsets[i + 1]] for i in range(line_no - 1, min(last, line_no - 1 + SIGNATURE_MAX_LINES))]
+        last = line_no - 1 + _header_line_count(lines)
+    
+    chunk = data[offsets[line_no - 1]:offsets[last]].rstrip(b"\r\n")
+    cut = truncate_utf8(chunk, max_bytes)
+    return {
+        "source": cut.decode("utf-8", errors="replace"),
+        "source_truncated": len(cut) < len(chunk),
+    }
+
+
+def annotate_sources(nodes: Iterable[Any], mode: str, max_bytes: int) -> int:
+    """
+    Set source (and source_truncated) on the symbol nodes.
+    
+    Nodes are grouped by file, so each file is read once; a node whose
+    file cannot be read is left without a source. A source set by an
+    earlier run of a node is replaced or removed.
+    
+    Args:
+        nodes: CodeNode objects
+        mode: "true", "false" or "signatures_only"
+        max_bytes: Byte cap of a source
+    
+    Returns:
+        Number of nodes that got a source
+    """
+    if mode == "false":
+        return 0
+    by_file: Dict[str, List[Any]] = {}
+    for node in nodes:
+        if node.node_type in SYMBOL_NODE_TYPES and node.file_path and node.line_no:
+            by_file.setdefault(node.file_path, []).append(node)
+    
+    annotated = 0
+    for file_path, file_nodes in by_file.items():
+        try:
+            with open(file_path, "rb") as handle:
+                data = handle.read()
+        except OSError as e:
+            logger.debug(f"Skipping stored source of {file_path}: {e}")
+            continue
+        offsets = _line_offsets(data)
+        for node in file_nodes:
+            node.properties.pop("source", None)
+            node.properties.pop("source_truncated", None)
+            fields = slice_source(data, offsets, node.line_no, node.end_line_no, mode, max_bytes)
+            if fields is None:
+                continue
+            node.properties["source"] = fields["source"]
+            if fields["source_truncated"]:
+                node.properties["source_truncated"] = True
+            annotated += 1
+    return annotated
+
+
+def source_fields(properties: Dict[str, Any]) -> Dict[str, Any]:
+    """source and source_truncated of a stored node for a tool result; source is None when none is stored."""
+    return {
+        "source": properties.get("source"),
+        "source_truncated": bool(properties.get("source_truncated", False)),
+    }
+
+
+def stored_source_line(properties: Dict[str, Any], line_no: Optional[int]) -> Optional[str]:
+    """Line line_no of a file, read from a node's stored source when it spans that line."""
+    source, start = properties.get("source"), properties.get("line_no")
+    if not source or not start or not line_no or line_no < start:
+        return None
+    lines = source.split("\n")
+    if line_no - start >= len(lines):
+        return None
+    return lines[line_no - start].strip() or None
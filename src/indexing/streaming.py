@@ -0,0 +1,225 @@
+"""
+Memory-bounded (streaming) full indexing.
+
+A full index normally holds every parsed node and relation until the
+cross-file pass has run, so its peak memory grows with the codebase.
+With INDEX_STREAMING (or --streaming) set, a full run works in two
+bounded passes instead (see CodebaseKnowledgeGraph._process_codebase_streaming):
+
+1. Files are parsed INDEX_STREAM_BATCH_FILES at a time. Each batch is
+   annotated (body hashes, symbol IDs, test tags, fingerprints), embedded
+   and written right away, File nodes carrying the batch's share of the
+   resolution index in index_state (see incremental.py). What the second
+   pass needs of each file, its light nodes, first-pass relations and
+   pending imports, goes into a SpillBuffer.
+2. The resolution index is read back from the stored index_state of
+   every file, the way incremental runs read the files they do not
+   re-parse, and the buffered files are resolved against it one batch at
+   a time. Their relations are written and their index_state updated.
+   Relations derived from the whole index (Go IMPLEMENTS, CROSS_LANG_CALLS,
+   package DEPENDS_ON) are computed last from the stored states.
+
+The SpillBuffer holds at most INDEX_MEMORY_BUDGET_MB (or
+--memory-budget-mb) of records and spills to a temporary file beyond that.
+What stays in memory for the whole run is the compact part: the
+resolution index (module_definitions, module_to_file and the index_state
+stubs), one light File node per file, and the Package nodes. The files
+declaring C# partial types are the exception: their parts are merged
+into one node before anything is written, so they are held whole and
+written in the second pass.
+
+The graph comes out the same as that of a run without streaming.
+"""
+
+import logging
+import os
+import pickle
+import tempfile
+from dataclasses import dataclass, field
+from typing import Any, Dict, Iterable, Iterator, List, Optional, Set, Tuple
+
+from src.ast_parser.parser import CodeNode, CodeRelation
+
+logger = logging.getLogger(__name__)
+
+DEFAULT_MEMORY_BUDGET_MB = 256
+
+DEFAULT_STREAM_BATCH_FILES = 200
+
+# Node properties the second pass does not read; light nodes leave them out
+HEAVY_PROPERTIES = ("doc", "embedding", "source", "index_state")
+
+# Pending import types resolved for every file before the first batch
+PRESCAN_IMPORT_TYPES = ("REEXPORTS", "EMBEDS")
+
+# (file_path, nodes, relations, pending_imports, held)
+StreamRecord = Tuple[str, Dict[str, CodeNode], List[CodeRelation], List[Dict[str, Any]], bool]
+
+
+def get_streaming(streaming: Optional[bool] = None) -> bool:
+    """Whether full runs stream, if None, get from INDEX_STREAMING (default: false)."""
+    if streaming is not None:
+        return streaming
+    return os.getenv("INDEX_STREAMING", "false").lower() == "true"
+
+
+def get_memory_budget_mb(budget_mb: Optional[int] = None) -> int:
+    """Megabytes the SpillBuffer holds in memory, if None, get from INDEX_MEMORY_BUDGET_MB (default 256)."""
+    if budget_mb is not None:
+        return max(0, budget_mb)
+    value = os.getenv("INDEX_MEMORY_BUDGET_MB", "")
+    if value:
+        try:
+            return max(0, int(value))
+        except ValueError:
+            logger.warning(f"Invalid INDEX_MEMORY_BUDGET_MB value '{value}', using {DEFAULT_MEMORY_BUDGET_MB}")
+    return DEFAULT_MEMORY_BUDGET_MB
+
+
+def get_stream_batch_files() -> int:
+    """Read INDEX_STREAM_BATCH_FILES (default 200), the files parsed and resolved per batch."""
+    value = os.getenv("INDEX_STREAM_BATCH_FILES", "")
+    if value:
+        try:
+            return max(1, int(value))
+        except ValueError:
+            logger.warning(f"Invalid INDEX_STREAM_BATCH_FILES value '{value}', using {DEFAULT_STREAM_BATCH_FILES}")
+    return DEFAULT_STREAM_BATCH_FILES
+
+
+def iter_batches(items: Iterable[Any], size: int) -> Iterator[List[Any]]:
+    """Consecutive lists of up to size items."""
+    batch: List[Any] = []
+    for item in items:
+        batch.append(item)
+        if len(batch) >= size:
+            yield batch
+            batch = []
+    if batch:
+        yield batch
+
+
+def light_node(node: CodeNode) -> CodeNode:
+    """Copy of a node without its code snippet and HEAVY_PROPERTIES."""
+    return CodeNode(
+        node_id=node.node_id,
+        node_type=node.node_type,
+        name=node.name,
+        file_path=node.file_path,
+        line_no=node.line_no,
+        end_line_no=node.end_line_no,
+        properties={key: value for key, value in node.properties.items() if key not in HEAVY_PROPERTIES},
+    )
+
+
+def holds_partial_types(pending_imports: Iterable[Dict[str, Any]]) -> bool:
+    """Whether a file declares a C# partial type, so it is held until the second pass."""
+    return any(import_info["type"] == "PARTIAL_TYPE" for import_info in pending_imports)
+
+
+class SpillBuffer:
+    """
+    Append-only buffer of StreamRecords with a memory budget.
+    
+    Records are kept pickled. When they exceed budget_bytes they are
+    moved to a temporary file, so the buffer holds at most budget_bytes
+    (plus the last record) in memory. Iterating yields every record in the
+    order it was appended; the buffer can be iterated several times, but
+    not appended to meanwhile.
+    
+    Usage:
+        with SpillBuffer(64 * 1024 * 1024) as buffer:
+            buffer.append(record)
+            for record in buffer:
+                ...
+    """
+    
+    def __init__(self, budget_bytes: int, directory: Optional[str] = None):
+        """
+        Args:
+            budget_bytes: Bytes of records held in memory before spilling
+            directory: Directory of the temporary file, if None, the system default
+        """
+        self.budget_bytes = budget_bytes
+        self.directory = directory
+        self.records = 0
+        self.spilled_records = 0
+        self.spilled_bytes = 0
+        self._pending: List[bytes] = []
+        self._pending_bytes = 0
+        self._file = None
+    
+    def __enter__(self):
+        return self
+    
+    def __exit__(self, exc_type, exc_val, exc_tb):
+        self.close()
+    
+    def __len__(self) -> int:
+        return self.records
+    
+    @property
+    def memory_bytes(self) -> int:
+        """Bytes of records currently held in memory."""
+        return self._pending_bytes
+    
+    def append(self, record: StreamRecord) -> None:
+        data = pickle.dumps(record, protocol=pickle.HIGHEST_PROTOCOL)
+        self._pending.append(data)
+        self._pending_bytes += len(data)
+        self.records += 1
+        if self._pending_bytes > self.budget_bytes:
+            self._spill()
+    
+    def _spill(self) -> None:
+        if self._file is None:
+            self._file = tempfile.TemporaryFile(prefix="graph-index-", suffix=".spill", dir=self.directory)
+            logger.info(f"Unresolved references exceed {self.budget_bytes} bytes, spilling them to disk")
+        self._file.seek(0, os.SEEK_END)
+        for data in self._pending:
+            self._file.write(data)
+        self._file.flush()
+        self.spilled_records += len(self._pending)
+        self.spilled_bytes += self._pending_bytes
+        self._pending = []
+        self._pending_bytes = 0
+    
+    def __iter__(self) -> Iterator[StreamRecord]:
+        if self._file is not None:
+            self._file.seek(0)
+            for _ in range(self.spilled_records):
+                yield pickle.load(self._file)
+        for data in self._pending:
+            yield pickle.loads(data)
+    
+    def close(self) -> None:
+        """Delete the temporary file and drop the records."""
+        if self._file is not None:
+            self._file.close()
+            self._file = None
+        self._pending = []
+        self._pending_bytes = 0
+
+
+@dataclass
+class StreamingRun:
+    """What a streaming run keeps across batches."""
+    # Light File node of every indexed file, in source order
+    file_nodes: Dict[str, CodeNode] = field(default_factory=dict)
+    # Serialized first-pass index_state of the held files, which have no File node in the store yet
+    held_states: Dict[str, str] = field(default_factory=dict)
+    # IDs of the placeholder nodes (no file) written so far
+    placeholders: Set[str] = field(default_factory=set)
+    # (source file, target file) of the DEPENDS_ON_FILE edges written so far
+    dependency_pairs: Set[Tuple[str, str]] = field(default_factory=set)
+    # Resolution index of every file, read back from the store for the second pass
+    module_definitions: Dict[str, Dict[str, str]] = field(default_factory=dict)
+    module_to_file: Dict[str, str] = field(default_factory=dict)
+    stub_nodes: Dict[str, CodeNode] = field(default_factory=dict)
+    stub_relations: List[CodeRelation] = field(default_factory=list)
+    node_files: Dict[str, str] = field(default_factory=dict)
+    interface_embeds: Dict[str, List[str]] = field(default_factory=dict)
+    # CONTAINS edges of the Package nodes, written once every File node is
+    package_relations: List[CodeRelation] = field(default_factory=list)
+    nodes_written: int = 0
+    relations_written: int = 0
@@ -0,0 +1,300 @@
+"""
+Package summaries.
+
+Every Package node gets a "summary" of what the package is for, built
+from:
+- the package doc: the doc of the File node of doc.go, else of the first
+  other Go file with one (the comment above `package foo`), of
+  __init__.py (its docstring) or of package-info.java
+- the first heading and paragraph of the README.md (or README.rst) of
+  the package directory, without badges, images, HTML and link targets
+
+Either part is cut to PACKAGE_SUMMARY_MAX_LENGTH characters (default
+1000); "summary_sources" lists the files they come from.
+
+READMEs are not parsed as code, so summaries are not tied to a File
+node: each file's package doc is kept in its index_state, and every run,
+incremental ones included, recomputes the summaries of all packages from
+the stored states and the READMEs on disk, and updates the Package nodes
+whose summary changed.
+"""
+
+import json
+import logging
+import os
+import re
+from typing import Any, Dict, Iterable, List, Optional, Tuple
+
+from src.ast_parser.doc_comments import truncate_doc
+from src.ast_parser.packages import package_id
+
+logger = logging.getLogger(__name__)
+
+DEFAULT_SUMMARY_MAX_LENGTH = 1000
+
+# README files read for a package, in order of preference (matched in any case)
+README_NAMES = ("readme.md", "readme.markdown", "readme.rst")
+
+# Bytes of a README read, its first paragraph is near the top
+README_MAX_BYTES = 64 * 1024
+
+# File name -> rank of its doc as the package doc (lower wins)
+PACKAGE_DOC_FILES = {"doc.go": 0, "__init__.py": 0, "package-info.java": 0}
+
+# Rank of the doc of any other Go file
+GO_FILE_RANK = 1
+
+SUMMARY_PROPERTIES = ("summary", "summary_sources")
+
+_MD_IMAGE = re.compile(r"!\[[^\]]*\]\([^)]*\)|!\[[^\]]*\]\[[^\]]*\]")
+_MD_LINK = re.compile(r"\[([^\]]*)\]\([^)]*\)|\[([^\]]*)\]\[[^\]]*\]")
+_MD_ATX_HEADING = re.compile(r"^#{1,6}\s+(.*?)\s*#*\s*$")
+_MD_SETEXT_UNDERLINE = re.compile(r"^(=+|-+)\s*$")
+_MD_FENCE = re.compile(r"^(```|~~~)")
+_MD_RULE = re.compile(r"^([-*_]\s*){3,}$")
+_MD_EMPHASIS = re.compile(r"\*\*|(?<!\w)\*(?=\S)|(?<=\S)\*(?!\w)")
+_MD_LIST_ITEM = re.compile(r"^([-*+]|\d+[.)])\s")
+_HTML_HEADING = re.compile(r"^<h[1-6][^>]*>(.*?)</h[1-6]>$", re.IGNORECASE)
+_HTML_COMMENT = re.compile(r"<!--.*?-->", re.DOTALL)
+_HTML_TAG = re.compile(r"</?[A-Za-z][^>]*>")
+
+_RST_UNDERLINE = re.compile(r"^([=\-~^\"'`#*+:.])\1+\s*$")
+_RST_DIRECTIVE = re.compile(r"^\.\.\s")
+_RST_LINK = re.compile(r"`([^`<]*?)\s*<[^>]*>`_{1,2}")
+_RST_ROLE = re.compile(r":[\w-]+:`([^`]*)`")
+_RST_SUBSTITUTION = re.compile(r"\|[^|\s][^|]*\|_{0,2}")
+_RST_REFERENCE = re.compile(r"`([^`]*)`_{1,2}")
+
+
+def get_summary_max_length() -> int:
+    """Read PACKAGE_SUMMARY_MAX_LENGTH (default 1000)."""
+    value = os.getenv("PACKAGE_SUMMARY_MAX_LENGTH", "")
+    if value:
+        try:
+            return max(0, int(value))
+        except ValueError:
+            logger.warning(f"Invalid PACKAGE_SUMMARY_MAX_LENGTH value '{value}', using {DEFAULT_SUMMARY_MAX_LENGTH}")
+    return DEFAULT_SUMMARY_MAX_LENGTH
+
+
+def is_readme(file_name: str) -> bool:
+    """Whether a file name is a README a package summary is read from."""
+    return file_name.lower() in README_NAMES
+
+
+def package_doc_entry(node) -> Optional[Dict[str, Any]]:
+    """
+    The package doc a File node carries, for its index_state.
+    
+    Returns:
+        {"package": Package node ID, "rank", "doc"}, None when the file's doc is not a package doc
+    """
+    doc = (node.properties.get("doc") or "").strip()
+    if node.node_type != "File" or not node.file_path or not doc:
+        return None
+    file_name = os.path.basename(node.file_path)
+    rank = PACKAGE_DOC_FILES.get(file_name)
+    if rank is None and file_name.endswith(".go") and not file_name.endswith("_test.go"):
+        rank = GO_FILE_RANK
+    if rank is None:
+        return None
+    return {"package": package_id(node.file_path, node.properties.get("import_path")), "rank": rank, "doc": doc}
+
+
+def load_package_docs(stored_states: Dict[str, Dict[str, Any]], file_paths: Iterable[str]) -> Dict[str, Dict[str, Any]]:
+    """Per-file package docs (see package_doc_entry) stored in index_state."""
+    docs: Dict[str, Dict[str, Any]] = {}
+    for file_path in file_paths:
+        raw_state = (stored_states.get(file_path) or {}).get("index_state")
+        if not raw_state:
+            continue
+        try:
+            entry = json.loads(raw_state).get("package_doc")
+        except (TypeError, ValueError):
+            continue
+        if entry:
+            docs[file_path] = entry
+    return docs
+
+
+def _clean_markdown(line: str) -> str:
+    line = _MD_IMAGE.sub("", line)
+    line = _MD_LINK.sub(lambda match: match.group(1) if match.group(1) is not None else match.group(2), line)
+    line = _HTML_TAG.sub("", line)
+    line = _MD_EMPHASIS.sub("", line)
+    return line.replace("`", "").strip()
+
+
+def _markdown_blocks(text: str) -> List[Tuple[str, str]]:
+    """("heading" | "paragraph", text) blocks of a Markdown document, code and tables left out."""
+    lines = _HTML_COMMENT.sub("", text).splitlines()
+    # YAML front matter
+    if lines and lines[0].strip() == "---":
+        end = next((i for i, line in enumerate(lines[1:], 1) if line.strip() == "---"), None)
+        if end is not None:
+            lines = lines[end + 1:]
+    
+    blocks: List[Tuple[str, str]] = []
+    paragraph: List[str] = []
+    fenced = False
+    
+    def flush() -> None:
+        cleaned = " ".join(part for part in (_clean_markdown(line) for line in paragraph) if part)
+        if cleaned:
+            blocks.append(("paragraph", cleaned))
+        paragraph.clear()
+    
+    for raw in lines:
+        line = raw.strip()
+        if _MD_FENCE.match(line):
+            flush()
+            fenced = not fenced
+            continue
+        if fenced:
+            continue
+        heading = _MD_ATX_HEADING.match(line) or _HTML_HEADING.match(line)
+        if heading:
+            flush()
+            blocks.append(("heading", _clean_markdown(heading.group(1))))
+            continue
+        if paragraph and _MD_SETEXT_UNDERLINE.match(line) and len(paragraph) == 1:
+            blocks.append(("heading", _clean_markdown(paragraph.pop())))
+            continue
+        # Lists, tables and indented code are not the paragraph describing the package
+        if (not line or _MD_RULE.match(line) or _MD_LIST_ITEM.match(line) or line.startswith("|")
+                or raw.startswith(("    ", "\t"))):
+            flush()
+            continue
+        paragraph.append(line.lstrip("> ").strip() if line.startswith(">") else line)
+    flush()
+    return [(kind, text) for kind, text in blocks if text]
+
+
+def _clean_rst(line: str) -> str:
+    line = _RST_LINK.sub(r"\1", line)
+    line = _RST_ROLE.sub(r"\1", line)
+    line = _RST_SUBSTITUTION.sub("", line)
+    line = _RST_REFERENCE.sub(r"\1", line)
+    line = _HTML_TAG.sub("", line)
+    return line.replace("``", "").replace("**", "").strip()
+
+
+def _rst_blocks(text: str) -> List[Tuple[str, str]]:
+    """("heading" | "paragraph", text) blocks of a reStructuredText document, directives left out."""
+    blocks: List[Tuple[str, str]] = []
+    paragraph: List[str] = []
+    in_directive = False
+    
+    def flush() -> None:
+        cleaned = " ".join(part for part in (_clean_rst(line) for line in paragraph) if part)
+        if cleaned:
+            blocks.append(("paragraph", cleaned))
+        paragraph.clear()
+    
+    for raw in text.splitlines():
+        line = raw.strip()
+        if in_directive:
+            # Options and content of a directive are indented
+            if not line or raw.startswith((" ", "\t")):
+                continue
+            in_directive = False
+        if _RST_DIRECTIVE.match(line):
+            flush()
+            in_directive = True
+            continue
+        if _RST_UNDERLINE.match(line):
+            if len(paragraph) == 1 and len(line) >= len(paragraph[0]):
+                blocks.append(("heading", _clean_rst(paragraph.pop())))
+            elif not paragraph:
+                # An overline, the title follows
+                continue
+            else:
+                flush()
+            continue
+        if not line or raw.startswith((" ", "\t")):
+            flush()
+            continue
+        paragraph.append(line)
+    flush()
+    return [(kind, text) for kind, text in blocks if text]
+
+
+def readme_excerpt(text: str, rst: bool = False) -> Optional[str]:
+    """First heading and the first paragraph after it ("Heading\\nParagraph") of a README."""
+    blocks = _rst_blocks(text) if rst else _markdown_blocks(text)
+    heading_index = next((i for i, (kind, _) in enumerate(blocks) if kind == "heading"), None)
+    start = 0 if heading_index is None else heading_index + 1
+    paragraph = next((block for kind, block in blocks[start:] if kind == "paragraph"), None)
+    heading = blocks[heading_index][1] if heading_index is not None else None
+    parts = [part for part in (heading, paragraph) if part]
+    return "\n".join(parts) if parts else None
+
+
+def find_readme(directory: str) -> Optional[str]:
+    """Path of the README of a directory, None when it has none."""
+    try:
+        names = {name.lower(): name for name in os.listdir(directory)}
+    except OSError:
+        return None
+    for readme_name in README_NAMES:
+        name = names.get(readme_name)
+        if name and os.path.isfile(os.path.join(directory, name)):
+            return os.path.join(directory, name)
+    return None
+
+
+def read_readme(directory: str) -> Optional[Tuple[str, str]]:
+    """(path, excerpt) of the README of a directory, None when it has none or nothing to summarize."""
+    path = find_readme(directory)
+    if path is None:
+        return None
+    try:
+        with open(path, "r", encoding="utf-8", errors="replace") as handle:
+            text = handle.read(README_MAX_BYTES)
+    except OSError as e:
+        logger.debug(f"Cannot read {path} for the package summary: {e}")
+        return None
+    excerpt = readme_excerpt(text, rst=path.lower().endswith(".rst"))
+    return (path, excerpt) if excerpt else None
+
+
+def compute_package_summaries(
+    packages: Dict[str, str],
+    package_docs: Dict[str, Dict[str, Any]],
+    max_length: Optional[int] = None,
+) -> Dict[str, Dict[str, Any]]:
+    """
+    Summary properties of every package.
+    
+    Args:
+        packages: Package node ID -> directory
+        package_docs: file_path -> package doc entry (see package_doc_entry)
+        max_length: Length each part is cut to, if None, get from PACKAGE_SUMMARY_MAX_LENGTH
+    
+    Returns:
+        Package node ID -> {"summary", "summary_sources"}, both None for a package with nothing to summarize
+    """
+    max_length = get_summary_max_length() if max_length is None else max_length
+    best: Dict[str, Tuple[int, str, str]] = {}
+    for file_path, entry in package_docs.items():
+        candidate = (entry["rank"], file_path, entry["doc"])
+        if entry["package"] not in best or candidate < best[entry["package"]]:
+            best[entry["package"]] = candidate
+    
+    summaries: Dict[str, Dict[str, Any]] = {}
+    for node_id, directory in packages.items():
+        parts, sources = [], []
+        if node_id in best:
+            _, file_path, doc = best[node_id]
+            parts.append(truncate_doc(doc, max_length))
+            sources.append(file_path)
+        readme = read_readme(directory) if directory else None
+        if readme is not None:
+            parts.append(truncate_doc(readme[1], max_length))
+            sources.append(readme[0])
+        parts = [part for part in parts if part]
+        summaries[node_id] = {
+            "summary": "\n\n".join(parts) if parts else None,
+            "summary_sources": sources if parts else None,
+        }
+    return summaries
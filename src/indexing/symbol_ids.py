@@ -0,0 +1,209 @@
+"""
+Stable symbol IDs.
+
+Node IDs carry a symbol's line number, so they change whenever code above
+the symbol moves, and a full rebuild assigns parsed IDs again. A reference
+an agent saved earlier (in a plan, a ticket, a cache) needs something that
+survives re-indexing: every file, package and symbol node also gets a
+symbol_id,
+    
+    repo:path:kind:qualified_name[:signature_hash]
+
+- repo: repository the node was indexed as (see src.graph_store.repository)
+- path: file relative to the codebase root, with "/"; for a package its
+  directory, or nothing when it has an import path (its name)
+- kind: node type in lower case ("function", "method", "class", ...)
+- qualified_name: the name within the file, prefixed by the types and
+  functions enclosing it ("Person.GetName", "outer.inner"); Go methods are
+  qualified by their receiver type, and nodes whose parser records a
+  qualified_name (Java, C#, Rust, proto) use it
+- signature_hash: first SIGNATURE_HASH_LENGTH hex digits of a hash of the
+  parameter and result types (names where no type is given)
+
+The ID depends only on the file's own content, so it comes out the same
+whatever order files are indexed in, sequential or parallel, full or
+incremental. The hash is only added where two nodes of a file would
+otherwise share an ID (overloaded methods in Java and C#, a function
+redefined in one file), unless SYMBOL_ID_SCHEME (or --symbol-id-scheme)
+is "signature", which adds it to every function and method. Nodes still
+sharing an ID after that (same name and signature) are told apart by
+their order in the file. IDs are opaque: look them up, do not parse them.
+"""
+
+import hashlib
+import json
+import os
+from collections import defaultdict
+from typing import Any, Dict, Iterable, List, Optional
+
+from src.ast_parser.parser import CodeNode, CodeRelation
+from src.ast_parser.signatures import load_signature
+
+SYMBOL_ID_PROPERTY = "symbol_id"
+
+# "qualified": signature hash only on collisions, "signature": on every function and method
+SYMBOL_ID_SCHEMES = ("qualified", "signature")
+
+SIGNATURE_HASH_LENGTH = 8
+
+CALLABLE_NODE_TYPES = ("Function", "Method")
+
+# Relations from a node to the nodes declared inside it
+SCOPE_RELATIONS = ("DEFINES", "CONTAINS")
+
+# Nodes that hold declarations without qualifying their names
+UNQUALIFIED_SCOPES = ("File", "Package")
+
+
+def get_symbol_id_scheme(scheme: Optional[str] = None) -> str:
+    """
+    Resolve the symbol ID scheme.
+    
+    Args:
+        scheme: "qualified" or "signature", if None, get from SYMBOL_ID_SCHEME (default: qualified)
+    
+    Raises:
+        ValueError: for an unknown scheme
+    """
+    scheme = (scheme or os.getenv("SYMBOL_ID_SCHEME") or "qualified").strip().lower()
+    if scheme not in SYMBOL_ID_SCHEMES:
+        raise ValueError(f"Unknown symbol ID scheme '{scheme}', expected one of: {', '.join(SYMBOL_ID_SCHEMES)}")
+    return scheme
+
+
+def format_symbol_id(repo: str, path: str, kind: str, qualified_name: str,
+                     signature_hash: Optional[str] = None) -> str:
+    """The symbol ID of its parts."""
+    parts = [repo, path, kind, qualified_name]
+    if signature_hash:
+        parts.append(signature_hash)
+    return ":".join(parts)
+
+
+def local_symbol_id(symbol_id: str, repo: str) -> str:
+    """A symbol ID without its repository, the same for a symbol in every snapshot of a codebase."""
+    prefix = f"{repo}:"
+    return symbol_id[len(prefix):] if symbol_id.startswith(prefix) else symbol_id
+
+
+def relative_path(file_path: str, root: Optional[str]) -> str:
+    """file_path relative to root with "/" separators; kept whole when it is outside root."""
+    path = file_path
+    if root:
+        relative = os.path.relpath(os.path.abspath(file_path), os.path.abspath(root))
+        if relative != ".." and not relative.startswith(".." + os.sep):
+            path = relative
+    return path.replace(os.sep, "/")
+
+
+def signature_hash(properties: Dict[str, Any], salt: str = "") -> str:
+    """
+    Hash of a node's parameter and result types.
+    
+    Parameters without a type count by name, so untyped Python overloads
+    still differ; nodes without signature_json hash their signature line.
+    """
+    signature = load_signature(properties)
+    if signature is not None:
+        text = json.dumps([
+            [(entry.get("type") or entry.get("name"), bool(entry.get("variadic")))
+             for entry in signature.get("parameters") or []],
+            [entry.get("type") for entry in signature.get("returns") or []],
+            [entry.get("name") for entry in signature.get("type_parameters") or []],
+        ])
+    else:
+        text = properties.get("signature") or ""
+    return hashlib.sha1(f"{text}{salt}".encode("utf-8")).hexdigest()[:SIGNATURE_HASH_LENGTH]
+
+
+def qualified_names(nodes: Dict[str, CodeNode], relations: Iterable[CodeRelation]) -> Dict[str, str]:
+    """Qualified name of every node in a file, by node ID (see the module docstring)."""
+    parents: Dict[str, str] = {}
+    for relation in relations:
+        if relation.relation_type not in SCOPE_RELATIONS:
+            continue
+        parent = nodes.get(relation.source_id)
+        child = nodes.get(relation.target_id)
+        if parent is None or child is None or parent.node_type in UNQUALIFIED_SCOPES:
+            continue
+        if parent.file_path and parent.file_path == child.file_path:
+            parents.setdefault(relation.target_id, relation.source_id)
+    
+    names: Dict[str, str] = {}
+    
+    def qualify(node_id: str, seen: frozenset) -> str:
+        if node_id in names:
+            return names[node_id]
+        node = nodes[node_id]
+        name = node.properties.get("qualified_name")
+        if not name:
+            name = node.name or ""
+            parent_id = parents.get(node_id)
+            if parent_id is not None and parent_id not in seen:
+                name = f"{qualify(parent_id, seen | {node_id})}.{name}"
+            elif node.properties.get("receiver_type"):
+                name = f"{node.properties['receiver_type']}.{name}"
+        names[node_id] = name
+        return name
+    
+    for node_id in nodes:
+        qualify(node_id, frozenset([node_id]))
+    return names
+
+
+def _symbol_path(node: CodeNode, root: Optional[str]) -> Optional[str]:
+    """Path component of a node's symbol ID, None for nodes that get none (placeholders)."""
+    if node.file_path and not node.properties.get("placeholder"):
+        return relative_path(node.file_path, root)
+    if node.node_type != "Package" or node.properties.get("path") is None:
+        return None
+    # A package named by import path (Go, Java, C#) may span directories; the name alone identifies it
+    if node.properties.get("import_path"):
+        return ""
+    return relative_path(node.properties["path"], root) if node.properties["path"] else "."
+
+
+def annotate_symbol_ids(nodes: Dict[str, CodeNode], relations: Iterable[CodeRelation], repo: str,
+                        root: Optional[str], scheme: Optional[str] = None) -> int:
+    """
+    Set symbol_id on every file, package and symbol node.
+    
+    Args:
+        nodes: Nodes of whole files; other files' nodes may be included
+        relations: Relations of those files, for the enclosing scopes
+        repo: Repository the nodes are indexed as
+        root: Codebase root the paths are relative to
+        scheme: "qualified" or "signature", if None, get from SYMBOL_ID_SCHEME
+    
+    Returns:
+        Number of nodes given a symbol_id
+    """
+    scheme = get_symbol_id_scheme(scheme)
+    names = qualified_names(nodes, relations)
+    
+    groups: Dict[str, List[str]] = defaultdict(list)
+    for node_id, node in nodes.items():
+        path = _symbol_path(node, root)
+        if path is None:
+            continue
+        name = node.properties.get("import_path") if node.node_type == "Package" else names[node_id]
+        groups[format_symbol_id(repo, path, node.node_type.lower(), name or node.name or "")].append(node_id)
+    
+    count = 0
+    for base_id, node_ids in groups.items():
+        hashed = len(node_ids) > 1
+        symbol_ids: Dict[str, List[str]] = defaultdict(list)
+        for node_id in sorted(node_ids, key=lambda node_id: (nodes[node_id].line_no or 0, node_id)):
+            node = nodes[node_id]
+            if hashed or (scheme == "signature" and node.node_type in CALLABLE_NODE_TYPES):
+                symbol_ids[f"{base_id}:{signature_hash(node.properties)}"].append(node_id)
+            else:
+                symbol_ids[base_id].append(node_id)
+        for symbol_id, same in symbol_ids.items():
+            nodes[same[0]].properties[SYMBOL_ID_PROPERTY] = symbol_id
+            # Same name and signature: the later declarations hash their position too
+            for ordinal, node_id in enumerate(same[1:], 1):
+                properties = nodes[node_id].properties
+                properties[SYMBOL_ID_PROPERTY] = f"{base_id}:{signature_hash(properties, f'#{ordinal}')}"
+            count += len(same)
+    return count
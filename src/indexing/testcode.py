@@ -0,0 +1,233 @@
+"""
+Tagging of test code.
+
+annotate_tests sets is_test: true on the nodes of test code, so analysis
+tools such as analyze_impact and get_tests_for can tell which tests reach
+a symbol:
+
+- Go: Test*, Benchmark*, Fuzz* and Example* functions and methods of
+  *_test.go files
+- Python (pytest, unittest): test* functions of test_*.py and *_test.py
+  files, and test* methods of classes named Test* or deriving from a
+  TestCase; tests with @pytest.mark.parametrize also get parametrized
+- JavaScript/TypeScript (Jest, Vitest, Mocha): the it() / test() callbacks
+  the ast-grep adapters turn into Function nodes, named after their
+  describe() blocks ("UserService > create > rejects duplicates")
+- Java and Kotlin: methods annotated @Test, @ParameterizedTest,
+  @RepeatedTest, @TestFactory or @TestTemplate
+- Rust: functions with a #[test] attribute (#[tokio::test], ... included)
+- C#: methods with an NUnit [Test] / [TestCase], xUnit [Fact] / [Theory]
+  or MSTest [TestMethod] / [DataTestMethod] attribute
+- Ruby (Minitest, Test::Unit): test_* methods of *_test.rb and test_*.rb
+  files
+
+The File nodes of test files get is_test as well: the files above, the
+files Jest runs by default (__tests__/ directories, *.test.js, *.spec.ts,
+...) and RSpec's *_spec.rb files. The legacy JavaScript parser keeps no nodes for it() callbacks, so
+there the file stands for them.
+
+link_tests then adds a TESTS edge from each test to every production
+function or method it calls directly (a Go test's calls include those in
+its t.Run closures and table loops). Calls that may not reach the real
+code are kept with low confidence (0.5, 0.9 otherwise) and a reason:
+"interface" for a call through an interface value (the edge points at
+the interface, with the method name), "mocked" for a symbol the test
+replaces with @patch / @patch.object. Each edge copies the premises of
+its call. The tests reaching a symbol over several call
+hops (COVERED_BY) are computed at query time, see src/mcp/coverage.py.
+"""
+
+import os
+import re
+from fnmatch import fnmatchcase
+from typing import Any, Dict, List, Optional, Set, Tuple
+
+from src.ast_parser.parser import CodeNode, CodeRelation
+from src.ast_parser.provenance import (
+    CONFIDENCE_PROPERTY,
+    PREMISES_PROPERTY,
+    SOURCE_PROPERTY,
+    SOURCE_TEST_LINK,
+    TEST_LINK_CONFIDENCE,
+)
+from src.graph_store.repository import split_id
+
+TEST_NODE_TYPES = ("Function", "Method")
+
+# Confidence labels of a TESTS edge, stored as TEST_LINK_CONFIDENCE numbers
+CONFIDENCE_HIGH = "high"
+CONFIDENCE_LOW = "low"
+
+GO_TEST_FUNCTION = re.compile(r"^(Test|Benchmark|Fuzz|Example)([^a-z].*)?$")
+PYTEST_FILE_PATTERNS = ("test_*.py", "*_test.py")
+JEST_FILE_PATTERNS = ("*.test.js", "*.test.jsx", "*.test.ts", "*.test.tsx", "*.test.mjs", "*.test.cjs",
+                      "*.spec.js", "*.spec.jsx", "*.spec.ts", "*.spec.tsx", "*.spec.mjs", "*.spec.cjs")
+JS_EXTENSIONS = (".js", ".jsx", ".ts", ".tsx", ".mjs", ".cjs")
+MINITEST_FILE_PATTERNS = ("*_test.rb", "test_*.rb")
+RSPEC_FILE_PATTERNS = ("*_spec.rb",)
+JAVA_TEST_ANNOTATION = re.compile(r"^([\w.]+\.)?(Test|ParameterizedTest|RepeatedTest|TestFactory|TestTemplate)(\(|$)")
+RUST_TEST_ATTRIBUTE = re.compile(r"^(\w+::)*test(\s*\(.*\))?$")
+CSHARP_TEST_ATTRIBUTE = re.compile(
+    r"^([\w.]+\.)?(Test|TestCase|TestCaseSource|Fact|Theory|TestMethod|DataTestMethod)(Attribute)?(\(|$)"
+)
+PYTEST_PARAMETRIZE = re.compile(r"^@?([\w.]+\.)?parametrize\(")
+# @patch("app.mail.send") and @patch.object(Mailer, "send"): (qualifier, name) of the replaced symbol
+PATCH_TARGET = re.compile(r"""^@?([\w.]+\.)?patch\(\s*['"]([\w.]+)\.(\w+)['"]""")
+PATCH_OBJECT = re.compile(r"""^@?([\w.]+\.)?patch\.object\(\s*([\w.]+)\s*,\s*['"](\w+)['"]""")
+
+
+def is_test_file(file_path: str) -> bool:
+    """Whether a Go, pytest, Jest, Minitest or RSpec test runner picks the file up by its path."""
+    file_name = os.path.basename(file_path)
+    if file_name.endswith("_test.go"):
+        return True
+    if any(fnmatchcase(file_name, pattern) for pattern in PYTEST_FILE_PATTERNS + JEST_FILE_PATTERNS
+           + MINITEST_FILE_PATTERNS + RSPEC_FILE_PATTERNS):
+        return True
+    parts = file_path.replace("\\", "/").split("/")
+    return "__tests__" in parts[:-1] and file_name.endswith(JS_EXTENSIONS)
+
+
+def _is_test_class(node: Optional[CodeNode]) -> bool:
+    if node is None or node.node_type != "Class":
+        return False
+    if node.name.startswith("Test"):
+        return True
+    return any(base.split(".")[-1].endswith("TestCase") for base in node.properties.get("bases") or [])
+
+
+def _is_test_symbol(node: CodeNode, owner: Optional[CodeNode]) -> bool:
+    file_name = os.path.basename(node.file_path or "")
+    if file_name.endswith("_test.go"):
+        return bool(GO_TEST_FUNCTION.match(node.name))
+    if file_name.endswith(".py"):
+        if not node.name.startswith("test"):
+            return False
+        if node.node_type == "Method" or owner is not None:
+            return _is_test_class(owner)
+        return any(fnmatchcase(file_name, pattern) for pattern in PYTEST_FILE_PATTERNS)
+    if file_name.endswith((".java", ".kt")):
+        return any(JAVA_TEST_ANNOTATION.match(text) for text in node.properties.get("annotations") or [])
+    if file_name.endswith(".rs"):
+        return any(RUST_TEST_ATTRIBUTE.match(text) for text in node.properties.get("attributes") or [])
+    if file_name.endswith(".cs"):
+        return any(CSHARP_TEST_ATTRIBUTE.match(text) for text in node.properties.get("attributes") or [])
+    if file_name.endswith(".rb"):
+        return (node.node_type == "Method" and node.name.startswith("test_")
+                and any(fnmatchcase(file_name, pattern) for pattern in MINITEST_FILE_PATTERNS))
+    if file_name.endswith(JS_EXTENSIONS):
+        return bool(node.properties.get("test_framework"))
+    return False
+
+
+def annotate_tests(nodes: Dict[str, CodeNode], relations: List[CodeRelation]) -> Set[str]:
+    """
+    Set is_test on the test functions, test methods and test files among nodes.
+    
+    Returns:
+        The IDs of the tagged nodes
+    """
+    owners: Dict[str, CodeNode] = {}
+    for relation in relations:
+        if relation.relation_type == "DEFINES" and relation.source_id in nodes:
+            owners.setdefault(relation.target_id, nodes[relation.source_id])
+    
+    tagged = set()
+    for node_id, node in nodes.items():
+        if not node.file_path:
+            continue
+        if node.node_type == "File":
+            test = is_test_file(node.file_path)
+        elif node.node_type in TEST_NODE_TYPES:
+            test = _is_test_symbol(node, owners.get(node_id))
+        else:
+            continue
+        if test:
+            node.properties["is_test"] = True
+            if any(PYTEST_PARAMETRIZE.match(text) for text in node.properties.get("decorators") or []):
+                node.properties["parametrized"] = True
+            tagged.add(node_id)
+    return tagged
+
+
+def _node_type(node_id: str) -> str:
+    """Node type an ID starts with, "{node_type}:{path}:{name}:{line}"."""
+    return split_id(node_id)[1].split(":", 1)[0]
+
+
+def _node_name(node_id: str) -> str:
+    """Node name in an ID, "{node_type}:{path}:{name}:{line}"."""
+    parts = split_id(node_id)[1].rsplit(":", 2)
+    return parts[1] if len(parts) == 3 else ""
+
+
+def _patched(node: CodeNode) -> Set[Tuple[str, str]]:
+    """(qualifier, name) of the symbols a test replaces through @patch / @patch.object decorators."""
+    patched = set()
+    for text in node.properties.get("decorators") or []:
+        match = PATCH_TARGET.match(text) or PATCH_OBJECT.match(text)
+        if match:
+            patched.add((match.group(2).split(".")[-1], match.group(3)))
+    return patched
+
+
+def link_tests(nodes: Dict[str, CodeNode], relations: List[CodeRelation],
+               node_files: Optional[Dict[str, str]] = None) -> List[CodeRelation]:
+    """
+    TESTS edges from the tests among nodes (see annotate_tests) to the production code they call.
+    
+    A target is production code when it is a Function or Method that is
+    not itself a test and is not declared in a test file, or an interface
+    called through (low confidence). Each edge copies the call's line_no,
+    call_lines and premises.
+    
+    Args:
+        node_files: node ID -> file of the nodes that are not in nodes (those of files not re-parsed)
+    """
+    node_files = node_files or {}
+    owners: Dict[str, str] = {}
+    for relation in relations:
+        if relation.relation_type == "DEFINES":
+            owners.setdefault(relation.target_id, relation.source_id)
+    
+    def owner_name(node_id: str) -> Optional[str]:
+        owner_id = owners.get(node_id)
+        if owner_id is None:
+            return None
+        return nodes[owner_id].name if owner_id in nodes else _node_name(owner_id)
+    
+    tests_relations = []
+    seen = set()
+    for relation in relations:
+        if relation.relation_type != "CALLS":
+            continue
+        key = (relation.source_id, relation.target_id, relation.properties.get("method"))
+        if key in seen:
+            continue
+        test = nodes.get(relation.source_id)
+        if test is None or not test.properties.get("is_test"):
+            continue
+        target = nodes.get(relation.target_id)
+        node_type = target.node_type if target is not None else _node_type(relation.target_id)
+        file_path = target.file_path if target is not None else node_files.get(relation.target_id)
+        if not file_path or is_test_file(file_path) or (target is not None and target.properties.get("is_test")):
+            continue
+        
+        properties: Dict[str, Any] = {SOURCE_PROPERTY: SOURCE_TEST_LINK,
+                                      CONFIDENCE_PROPERTY: TEST_LINK_CONFIDENCE[CONFIDENCE_HIGH]}
+        for key in ("line_no", "call_lines", PREMISES_PROPERTY):
+            if relation.properties.get(key) is not None:
+                properties[key] = relation.properties[key]
+        low = {CONFIDENCE_PROPERTY: TEST_LINK_CONFIDENCE[CONFIDENCE_LOW]}
+        if node_type == "Interface" and relation.properties.get("method"):
+            properties.update(low, reason="interface", method=relation.properties["method"])
+        elif node_type not in TEST_NODE_TYPES:
+            continue
+        else:
+            name = target.name if target is not None else _node_name(relation.target_id)
+            qualifiers = {os.path.splitext(os.path.basename(file_path))[0], owner_name(relation.target_id)}
+            if any(patched_name == name and qualifier in qualifiers for qualifier, patched_name in _patched(test)):
+                properties.update(low, reason="mocked")
+        seen.add(key)
+        tests_relations.append(CodeRelation(relation.source_id, relation.target_id, "TESTS", properties))
+    return tests_relations
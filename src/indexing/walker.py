@@ -0,0 +1,356 @@
+"""
+Source file discovery for indexing.
+
+Walks a codebase and collects source files, skipping:
+- paths matched by .gitignore files at any directory level (nested files
+  take precedence over their parents, later lines over earlier ones, and
+  `!` re-includes a path)
+- paths matched by extra `exclude` patterns (gitignore syntax, relative to the root)
+- files outside the `include_only` allowlist, when one is given
+- symlinked directories, unless `follow_symlinks` is set for targets outside the root
+
+Skipped files and directories are counted per reason and logged as a summary.
+"""
+
+import logging
+import os
+import re
+from collections import Counter
+from dataclasses import dataclass, field
+from typing import Iterable, List, Optional, Sequence, Tuple
+
+logger = logging.getLogger(__name__)
+
+# Directories that are never indexed, whatever the ignore files say
+ALWAYS_SKIPPED_DIRS = {".git", ".hg", ".svn"}
+
+# Skip reasons reported in the walk summary
+SKIP_GITIGNORE = "gitignore"
+SKIP_EXCLUDE = "exclude"
+SKIP_INCLUDE_ONLY = "include_only"
+SKIP_VCS = "vcs_directory"
+SKIP_SYMLINK_OUTSIDE = "symlink_outside_root"
+SKIP_SYMLINK_INSIDE = "symlink_inside_root"
+SKIP_SYMLINK_LOOP = "symlink_loop"
+
+
+@dataclass
+class IgnoreRule:
+    """One compiled gitignore-style pattern."""
+    pattern: str
+    regex: "re.Pattern"
+    base: str = ""
+    negate: bool = False
+    dir_only: bool = False
+    
+    def matches(self, rel_path: str, is_dir: bool) -> bool:
+        """Check a root-relative POSIX path against the rule."""
+        if self.dir_only and not is_dir:
+            return False
+        if self.base:
+            if not rel_path.startswith(self.base + "/"):
+                return False
+            rel_path = rel_path[len(self.base) + 1:]
+        return self.regex.match(rel_path) is not None
+
+
+def _translate_glob(pattern: str) -> str:
+    """Translate a gitignore glob (already anchored) into a regex body."""
+    i = 0
+    n = len(pattern)
+    out = []
+    while i < n:
+        if pattern.startswith("**/", i):
+            out.append("(?:.*/)?")
+            i += 3
+        elif pattern.startswith("/**", i) and i + 3 == n:
+            out.append("/.*")
+            i += 3
+        elif pattern.startswith("**", i):
+            out.append(".*")
+            i += 2
+        elif pattern[i] == "*":
+            out.append("[^/]*")
+            i += 1
+        elif pattern[i] == "?":
+            out.append("[^/]")
+            i += 1
+        elif pattern[i] == "[":
+            end = pattern.find("]", i + 2 if pattern.startswith("[!", i) or pattern.startswith("[^", i) else i + 1)
+            if end == -1:
+                out.append(re.escape("["))
+                i += 1
+                continue
+            body = pattern[i + 1:end]
+            if body[:1] in ("!", "^"):
+                body = "^" + body[1:]
+            out.append("[" + body.replace("\\", "\\\\") + "]")
+            i = end + 1
+        elif pattern[i] == "\\" and i + 1 < n:
+            out.append(re.escape(pattern[i + 1]))
+            i += 2
+        else:
+            out.append(re.escape(pattern[i]))
+            i += 1
+    return "".join(out)
+
+
+def compile_pattern(line: str, base: str = "") -> Optional[IgnoreRule]:
+    """
+    Compile one gitignore line.
+    
+    Args:
+        line: Raw line from a .gitignore file (or an exclude pattern)
+        base: Root-relative POSIX directory the pattern is relative to ("" for the root)
+    
+    Returns:
+        IgnoreRule, or None for blank lines and comments
+    """
+    # Trailing spaces are ignored unless escaped
+    stripped = line.rstrip("\n").rstrip("\r")
+    while stripped.endswith(" ") and not stripped.endswith("\\ "):
+        stripped = stripped[:-1]
+    if not stripped or stripped.startswith("#"):
+        return None
+    
+    negate = False
+    if stripped.startswith("!"):
+        negate = True
+        stripped = stripped[1:]
+    elif stripped.startswith("\\!") or stripped.startswith("\\#"):
+        stripped = stripped[1:]
+    
+    dir_only = stripped.endswith("/")
+    stripped = stripped.rstrip("/")
+    if not stripped:
+        return None
+    
+    # A slash anywhere but at the end anchors the pattern to its .gitignore directory
+    anchored = "/" in stripped
+    body = stripped.lstrip("/") if anchored else "**/" + stripped
+    
+    return IgnoreRule(
+        pattern=line.strip(),
+        regex=re.compile("^" + _translate_glob(body) + "$"),
+        base=base,
+        negate=negate,
+        dir_only=dir_only,
+    )
+
+
+def compile_patterns(lines: Iterable[str], base: str = "") -> List[IgnoreRule]:
+    """Compile a sequence of gitignore lines, dropping blanks and comments."""
+    return [rule for rule in (compile_pattern(line, base) for line in lines) if rule is not None]
+
+
+def is_ignored(rules: Sequence[IgnoreRule], rel_path: str, is_dir: bool) -> bool:
+    """Evaluate rules in order; the last matching rule decides."""
+    ignored = False
+    for rule in rules:
+        if rule.matches(rel_path, is_dir):
+            ignored = not rule.negate
+    return ignored
+
+
+def _split_patterns(value: Optional[object]) -> List[str]:
+    """Accept a list of patterns or a comma-separated string."""
+    if not value:
+        return []
+    if isinstance(value, str):
+        return [part.strip() for part in value.split(",") if part.strip()]
+    return [str(part).strip() for part in value if str(part).strip()]
+
+
+@dataclass
+class WalkStats:
+    """Counts collected during a walk."""
+    collected: int = 0
+    skipped_files: Counter = field(default_factory=Counter)
+    skipped_dirs: Counter = field(default_factory=Counter)
+    
+    def summary(self) -> str:
+        reasons = self.skipped_files + self.skipped_dirs
+        details = ", ".join(f"{reason}: {count}" for reason, count in sorted(reasons.items()))
+        return (
+            f"collected {self.collected} files, skipped {sum(self.skipped_files.values())} files "
+            f"and {sum(self.skipped_dirs.values())} directories" + (f" ({details})" if details else "")
+        )
+
+
+class SourceFileWalker:
+    """Gitignore-aware directory walker used by the indexer."""
+    
+    def __init__(
+        self,
+        extensions: Sequence[str],
+        exclude: Optional[object] = None,
+        include_only: Optional[object] = None,
+        respect_gitignore: bool = True,
+        follow_symlinks: bool = False,
+    ):
+        """
+        Args:
+            extensions: File extensions to collect (e.g. ('.py', '.go'))
+            exclude: Extra gitignore-style patterns relative to the root (list or comma-separated string)
+            include_only: Allowlist of patterns; when given, only matching files are collected
+            respect_gitignore: Read .gitignore files while walking
+            follow_symlinks: Follow symlinked directories that point outside the root
+        """
+        self.extensions = tuple(extensions)
+        self.exclude_rules = compile_patterns(_split_patterns(exclude))
+        self.include_rules = compile_patterns(_split_patterns(include_only))
+        self.respect_gitignore = respect_gitignore
+        self.follow_symlinks = follow_symlinks
+        self.stats = WalkStats()
+    
+    def walk(self, root: str, log_summary: bool = True) -> List[str]:
+        """
+        Collect source files under root.
+        
+        Args:
+            root: Directory to walk
+            log_summary: Log the skip summary (off for repeated walks, e.g. the watcher's polling)
+        
+        Returns:
+            File paths (joined onto root, like os.walk), in walk order
+        """
+        self.stats = WalkStats()
+        root_real = os.path.realpath(root)
+        visited = {root_real}
+        files: List[str] = []
+        
+        # (directory path, root-relative POSIX path, inherited gitignore rules)
+        stack: List[Tuple[str, str, List[IgnoreRule]]] = [(root, "", [])]
+        while stack:
+            dir_path, rel_dir, inherited = stack.pop()
+            rules = inherited + self._read_gitignore(dir_path, rel_dir) if self.respect_gitignore else inherited
+            
+            try:
+                entries = sorted(os.scandir(dir_path), key=lambda entry: entry.name)
+            except OSError as e:
+                logger.warning(f"Cannot read directory {dir_path}: {e}")
+                continue
+            
+            subdirs = []
+            for entry in entries:
+                rel_path = f"{rel_dir}/{entry.name}" if rel_dir else entry.name
+                try:
+                    is_dir = entry.is_dir()
+                except OSError:
+                    continue
+                
+                if is_dir:
+                    reason = self._check_directory(entry, rel_path, rules, root_real, visited)
+                    if reason:
+                        self.stats.skipped_dirs[reason] += 1
+                    else:
+                        subdirs.append((entry.path, rel_path, rules))
+                    continue
+                
+                if not entry.name.endswith(self.extensions):
+                    continue
+                reason = self._check_file(rel_path, rules)
+                if reason:
+                    self.stats.skipped_files[reason] += 1
+                else:
+                    files.append(entry.path)
+            
+            # Reverse so directories are visited in name order
+            stack.extend(reversed(subdirs))
+        
+        self.stats.collected = len(files)
+        if log_summary:
+            logger.info(f"Directory walk of {root}: {self.stats.summary()}")
+        return files
+    
+    def accepts(self, root: str, path: str, is_dir: bool = False) -> bool:
+        """
+        Check whether walk(root) would collect path (or descend into it, for a directory).
+        
+        The path does not have to exist (used for deleted or renamed files);
+        its parent directories are checked with the same rules as walk, reading
+        any .gitignore files along the way.
+        """
+        rel_path = os.path.relpath(path, root).replace(os.sep, "/")
+        if rel_path in (".", "..") or rel_path.startswith("../"):
+            return is_dir and rel_path == "."
+        if not is_dir and not rel_path.endswith(self.extensions):
+            return False
+        
+        parts = rel_path.split("/")
+        root_real = os.path.realpath(root)
+        rules = self._read_gitignore(root, "") if self.respect_gitignore else []
+        dir_path = root
+        for depth, name in enumerate(parts if is_dir else parts[:-1], start=1):
+            rel_dir = "/".join(parts[:depth])
+            dir_path = os.path.join(dir_path, name)
+            if name in ALWAYS_SKIPPED_DIRS:
+                return False
+            if is_ignored(rules, rel_dir, True) or is_ignored(self.exclude_rules, rel_dir, True):
+                return False
+            if os.path.islink(dir_path):
+                target = os.path.realpath(dir_path)
+                if target == root_real or target.startswith(root_real + os.sep) or not self.follow_symlinks:
+                    return False
+            if self.respect_gitignore:
+                rules = rules + self._read_gitignore(dir_path, rel_dir)
+        return is_dir or self._check_file(rel_path, rules) is None
+    
+    def _read_gitignore(self, dir_path: str, rel_dir: str) -> List[IgnoreRule]:
+        gitignore_path = os.path.join(dir_path, ".gitignore")
+        if not os.path.isfile(gitignore_path):
+            return []
+        try:
+            with open(gitignore_path, "r", encoding="utf-8", errors="replace") as f:
+                return compile_patterns(f.readlines(), base=rel_dir)
+        except OSError as e:
+            logger.warning(f"Cannot read {gitignore_path}: {e}")
+            return []
+    
+    def _check_directory(self, entry: os.DirEntry, rel_path: str, rules: List[IgnoreRule],
+                         root_real: str, visited: set) -> Optional[str]:
+        """Return the reason a directory is skipped, or None to descend into it."""
+        if entry.name in ALWAYS_SKIPPED_DIRS:
+            return SKIP_VCS
+        if is_ignored(rules, rel_path, True):
+            return SKIP_GITIGNORE
+        if is_ignored(self.exclude_rules, rel_path, True):
+            return SKIP_EXCLUDE
+        
+        if entry.is_symlink():
+            target = os.path.realpath(entry.path)
+            inside_root = target == root_real or target.startswith(root_real + os.sep)
+            if inside_root:
+                # The target is indexed under its real path already
+                return SKIP_SYMLINK_INSIDE
+            if not self.follow_symlinks:
+                return SKIP_SYMLINK_OUTSIDE
+            if target in visited:
+                return SKIP_SYMLINK_LOOP
+            visited.add(target)
+        return None
+    
+    def _check_file(self, rel_path: str, rules: List[IgnoreRule]) -> Optional[str]:
+        """Return the reason a file is skipped, or None to collect it."""
+        if is_ignored(rules, rel_path, False):
+            return SKIP_GITIGNORE
+        if is_ignored(self.exclude_rules, rel_path, False):
+            return SKIP_EXCLUDE
+        if self.include_rules and not self._is_included(rel_path):
+            return SKIP_INCLUDE_ONLY
+        return None
+    
+    def _is_included(self, rel_path: str) -> bool:
+        """A file is allowed when it, or one of its parent directories, matches include_only."""
+        parts = rel_path.split("/")
+        for depth in range(len(parts), 0, -1):
+            candidate = "/".join(parts[:depth])
+            is_dir = depth < len(parts)
+            if any(rule.matches(candidate, is_dir) for rule in self.include_rules):
+                return True
+        return False
+
+
+def walk_source_files(root: str, extensions: Sequence[str], **options) -> List[str]:
+    """Collect source files under root with a SourceFileWalker (see its options)."""
+    return SourceFileWalker(extensions, **options).walk(root)
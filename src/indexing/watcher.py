@@ -0,0 +1,405 @@
+"""
+Watch mode: keep the graph in sync with the files under an indexed root.
+
+File events are filtered with the indexer's SourceFileWalker, so paths the
+walk skips (.gitignore matches, exclude patterns, VCS directories) never
+trigger a sync. Accepted paths are collected into a pending set that is
+flushed once no new event arrived for the debounce window (default 500ms,
+INDEX_WATCH_DEBOUNCE_MS), so a burst of saves results in a single sync.
+
+A sync is an incremental index of the root: changed files are deleted and
+re-inserted, deleted or renamed-away files lose their nodes, and files with
+a DEPENDS_ON_FILE edge into a removed file are re-resolved, so no cross-file
+edge is left pointing at a node that no longer exists. While another run
+of the root holds its write lock (an index job or the reindex tool, see
+coordination.py), the sync is deferred: the changed paths stay queued,
+events arriving meanwhile join them, and they are synced together once
+the other run is done.
+
+Events come from watchdog (inotify / FSEvents / ReadDirectoryChangesW) when
+it is installed, otherwise the root is polled. Editors that save by writing
+a temp file and renaming it over the original produce a move event whose
+destination is the source file; both ends of a move are queued, and the
+temp file itself is dropped by the extension and ignore filters. READMEs
+are not indexed but are watched too, they feed the package summaries
+(see summaries.py).
+"""
+
+import logging
+import os
+import threading
+import time
+from datetime import datetime, timezone
+from typing import Any, Callable, Dict, List, Optional, Set, Tuple
+
+from src.indexing.coordination import get_coordinator
+from src.indexing.summaries import find_readme, is_readme
+
+logger = logging.getLogger(__name__)
+
+DEFAULT_DEBOUNCE_MS = 500
+
+# Delay before a failed sync is retried
+RETRY_DELAY_SECONDS = 5.0
+
+# Delay before a deferred sync checks again whether it can run
+DEFER_DELAY_SECONDS = 0.2
+
+# Pending paths listed in status(); the count is always exact
+STATUS_PATH_LIMIT = 50
+
+# watchdog event types that never change file contents
+_IGNORED_EVENT_TYPES = {"opened", "closed_no_write"}
+
+
+class SyncDeferred(Exception):
+    """Raised by a sync function that cannot run yet; its paths stay queued and it is retried shortly."""
+
+
+def get_debounce_seconds() -> float:
+    """Read INDEX_WATCH_DEBOUNCE_MS (default 500)."""
+    value = os.getenv("INDEX_WATCH_DEBOUNCE_MS", "")
+    if value:
+        try:
+            return max(0, int(value)) / 1000.0
+        except ValueError:
+            logger.warning(f"Invalid INDEX_WATCH_DEBOUNCE_MS value '{value}', using {DEFAULT_DEBOUNCE_MS}")
+    return DEFAULT_DEBOUNCE_MS / 1000.0
+
+
+def _utc_now() -> str:
+    return datetime.now(timezone.utc).isoformat(timespec="seconds")
+
+
+class IndexWatcher:
+    """
+    Debounced file watcher that calls `sync` after changes under root.
+    
+    Usage:
+        watcher = IndexWatcher(root, sync, accepts, list_files=list_files)
+        watcher.start()
+        ...
+        watcher.stop()
+    
+    Syncs run on a single background thread, one at a time. A failed sync
+    puts its paths back into the pending set and is retried after
+    RETRY_DELAY_SECONDS; one that raised SyncDeferred does the same after
+    DEFER_DELAY_SECONDS, without counting as an error.
+    """
+    
+    def __init__(
+        self,
+        root: str,
+        sync: Callable[[List[str]], Optional[Dict[str, Any]]],
+        accepts: Callable[[str, bool], bool],
+        list_files: Optional[Callable[[], List[str]]] = None,
+        debounce_seconds: Optional[float] = None,
+        poll_interval: float = 1.0,
+        use_polling: Optional[bool] = None,
+    ):
+        """
+        Args:
+            root: Directory to watch
+            sync: Called with the pending paths; returns run statistics shown in status()
+            accepts: accepts(path, is_dir), whether a path is part of the index
+            list_files: Returns the indexed files, used by the polling fallback
+            debounce_seconds: Quiet period before a sync, if None, get from INDEX_WATCH_DEBOUNCE_MS
+            poll_interval: Seconds between snapshots in polling mode
+            use_polling: Force polling (True) or watchdog (False); None uses watchdog when installed
+        """
+        self.root = root
+        self.debounce_seconds = get_debounce_seconds() if debounce_seconds is None else debounce_seconds
+        self.poll_interval = poll_interval
+        self.use_polling = use_polling
+        self.mode: Optional[str] = None
+        self.syncs = 0
+        self.last_event_time: Optional[str] = None
+        self.last_sync_time: Optional[str] = None
+        self.last_sync_stats: Optional[Dict[str, Any]] = None
+        self.last_error: Optional[str] = None
+        self.deferred: Optional[str] = None
+        self.deferred_syncs = 0
+        
+        self._sync = sync
+        self._accepts = accepts
+        self._list_files = list_files
+        self._root_abs = os.path.abspath(root)
+        self._cond = threading.Condition()
+        self._pending: Set[str] = set()
+        self._sync_requested = False
+        self._syncing = False
+        self._due_at: Optional[float] = None
+        self._stopping = False
+        self._worker: Optional[threading.Thread] = None
+        self._poller: Optional[threading.Thread] = None
+        self._observer = None
+    
+    @property
+    def running(self) -> bool:
+        return self._worker is not None and self._worker.is_alive()
+    
+    def start(self, initial_sync: bool = False) -> None:
+        """
+        Start watching.
+        
+        Args:
+            initial_sync: Sync once right away, e.g. when the graph may be older than the files
+        """
+        if self.running:
+            return
+        self._stopping = False
+        self._worker = threading.Thread(target=self._run, name="index-watcher", daemon=True)
+        self._worker.start()
+        
+        if self.use_polling or not self._start_observer():
+            self._start_poller()
+        if initial_sync:
+            self.request_sync()
+        logger.info(f"Watching {self.root} ({self.mode}, debounce {self.debounce_seconds * 1000:.0f}ms)")
+    
+    def stop(self) -> None:
+        """Stop watching; a sync in progress is finished first, pending paths are dropped."""
+        with self._cond:
+            self._stopping = True
+            self._cond.notify_all()
+        if self._observer is not None:
+            self._observer.stop()
+            self._observer.join()
+            self._observer = None
+        for thread in (self._poller, self._worker):
+            if thread is not None:
+                thread.join()
+        self._poller = None
+        self._worker = None
+    
+    def notify(self, path: str, is_dir: bool = False) -> bool:
+        """
+        Queue a changed, created or deleted path.
+        
+        Returns:
+            True if the path is indexed (or is a .gitignore that may change what is, or a README
+            summarized into its package) and was queued
+        """
+        path = os.fsdecode(path)
+        name = os.path.basename(path)
+        if name == ".gitignore" or is_readme(name):
+            parent = os.path.dirname(path)
+            relevant = os.path.abspath(parent) == self._root_abs or self._accepts(parent, True)
+        else:
+            relevant = self._accepts(path, is_dir)
+        if not relevant:
+            return False
+        
+        with self._cond:
+            self._pending.add(path)
+            self._schedule(self.debounce_seconds)
+        return True
+    
+    def request_sync(self) -> None:
+        """Schedule a sync even if no file event arrived."""
+        with self._cond:
+            self._sync_requested = True
+            self._schedule(self.debounce_seconds)
+    
+    def status(self) -> Dict[str, Any]:
+        """Current state; `stale` is True while changes are pending or being synced."""
+        with self._cond:
+            pending = sorted(self._pending)
+            syncing = self._syncing
+            requested = self._sync_requested
+        return {
+            "running": self.running,
+            "mode": self.mode,
+            "root": self.root,
+            "debounce_ms": int(self.debounce_seconds * 1000),
+            "pending_files": len(pending),
+            "pending_paths": pending[:STATUS_PATH_LIMIT],
+            "syncing": syncing,
+            "deferred": self.deferred,
+            "deferred_syncs": self.deferred_syncs,
+            "stale": bool(pending) or syncing or requested,
+            "syncs": self.syncs,
+            "last_event_time": self.last_event_time,
+            "last_sync_time": self.last_sync_time,
+            "last_sync_stats": self.last_sync_stats,
+            "last_error": self.last_error,
+        }
+    
+    def on_event(self, event_type: str, src_path: str, dest_path: str = "", is_dir: bool = False) -> None:
+        """Handle one watchdog event (also usable without watchdog, e.g. from tests)."""
+        if event_type in _IGNORED_EVENT_TYPES:
+            return
+        # Created or modified directories hold no content of their own; their files report separately
+        if is_dir and event_type not in ("deleted", "moved"):
+            return
+        for path in (src_path, dest_path):
+            if path:
+                self.notify(path, is_dir)
+    
+    def _schedule(self, delay: float) -> None:
+        # Caller holds self._cond
+        self._due_at = time.monotonic() + delay
+        self.last_event_time = _utc_now()
+        self._cond.notify_all()
+    
+    def _run(self) -> None:
+        while True:
+            with self._cond:
+                while not self._stopping:
+                    if self._pending or self._sync_requested:
+                        remaining = self._due_at - time.monotonic()
+                        if remaining <= 0:
+                            break
+                        self._cond.wait(remaining)
+                    else:
+                        self._cond.wait()
+                if self._stopping:
+                    return
+                paths = sorted(self._pending)
+                self._pending.clear()
+                self._sync_requested = False
+                self._syncing = True
+            
+            deferred = None
+            try:
+                stats = self._sync(paths)
+                error = None
+            except SyncDeferred as e:
+                stats = None
+                error = None
+                deferred = str(e)
+            except Exception as e:
+                logger.error(f"Watch sync of {len(paths)} files failed, retrying in {RETRY_DELAY_SECONDS:.0f}s: {e}")
+                stats = None
+                error = str(e)
+            
+            with self._cond:
+                self._syncing = False
+                if deferred is not None:
+                    if self.deferred is None:
+                        logger.info(f"Watch sync of {len(paths)} paths deferred: {deferred}")
+                        self.deferred_syncs += 1
+                    self.deferred = deferred
+                    self._pending.update(paths)
+                    self._sync_requested = True
+                    self._due_at = time.monotonic() + DEFER_DELAY_SECONDS
+                    continue
+                self.deferred = None
+                if error is None:
+                    self.syncs += 1
+                    self.last_sync_time = _utc_now()
+                    self.last_sync_stats = stats
+                    self.last_error = None
+                else:
+                    self.last_error = error
+                    self._pending.update(paths)
+                    self._sync_requested = True
+                    self._due_at = time.monotonic() + max(self.debounce_seconds, RETRY_DELAY_SECONDS)
+    
+    def _start_observer(self) -> bool:
+        """Start a watchdog observer; returns False if watchdog is not installed."""
+        try:
+            from watchdog.events import FileSystemEventHandler
+            from watchdog.observers import Observer
+        except ImportError:
+            if self.use_polling is False:
+                raise
+            logger.info("watchdog is not installed, polling for changes instead")
+            return False
+        
+        watcher = self
+        
+        class _Handler(FileSystemEventHandler):
+            def on_any_event(self, event):
+                watcher.on_event(event.event_type, event.src_path, getattr(event, "dest_path", ""), event.is_directory)
+        
+        self._observer = Observer()
+        self._observer.schedule(_Handler(), self.root, recursive=True)
+        self._observer.daemon = True
+        self._observer.start()
+        self.mode = "watchdog"
+        return True
+    
+    def _start_poller(self) -> None:
+        if self._list_files is None:
+            raise ValueError("Polling mode needs list_files")
+        self.mode = "polling"
+        # Taken before start() returns, so changes made right after it are not missed
+        baseline = self._snapshot()
+        self._poller = threading.Thread(target=self._poll, args=(baseline,), name="index-watcher-poll", daemon=True)
+        self._poller.start()
+    
+    def _snapshot(self) -> Dict[str, Tuple[int, int, int]]:
+        snapshot = {}
+        for file_path in self._list_files():
+            try:
+                stat = os.stat(file_path)
+            except OSError:
+                continue
+            snapshot[file_path] = (stat.st_mtime_ns, stat.st_size, stat.st_ino)
+        return snapshot
+    
+    def _poll(self, previous: Dict[str, Tuple[int, int, int]]) -> None:
+        while True:
+            with self._cond:
+                if self._stopping:
+                    return
+                self._cond.wait(self.poll_interval)
+                if self._stopping:
+                    return
+            try:
+                current = self._snapshot()
+            except Exception as e:
+                logger.warning(f"Polling {self.root} failed: {e}")
+                continue
+            # An atomic-rename save keeps the path but changes the inode
+            for file_path in set(previous) | set(current):
+                if previous.get(file_path) != current.get(file_path):
+                    self.notify(file_path)
+            previous = current
+
+
+def watch_codebase(kg, codebase_path: str, debounce_seconds: Optional[float] = None,
+                   use_polling: Optional[bool] = None) -> IndexWatcher:
+    """
+    Create an IndexWatcher that keeps the graph of a CodebaseKnowledgeGraph in sync.
+    
+    Each sync runs kg.process_codebase(codebase_path, incremental=True); pass the
+    same path the codebase was indexed with, node IDs are derived from it. A sync
+    is deferred while another thread holds the write lock of the root.
+    
+    Args:
+        kg: CodebaseKnowledgeGraph owning the database connection
+        codebase_path: Indexed root
+        debounce_seconds: Quiet period before a sync, if None, get from INDEX_WATCH_DEBOUNCE_MS
+        use_polling: See IndexWatcher
+    
+    Returns:
+        Watcher, not started yet
+    """
+    walker = kg.create_source_walker()
+    
+    def list_files() -> List[str]:
+        files = walker.walk(codebase_path, log_summary=False)
+        # READMEs are not indexed, but feed the package summaries
+        readmes = (find_readme(directory) for directory in sorted({os.path.dirname(path) for path in files}))
+        return files + [path for path in readmes if path]
+    
+    coordinator = get_coordinator(kg.db)
+    
+    def sync(paths: List[str]) -> Dict[str, Any]:
+        holder = coordinator.holder(codebase_path, kg.repo)
+        if holder is not None:
+            raise SyncDeferred(f"{holder} is indexing {codebase_path}")
+        logger.info(f"Syncing {len(paths)} changed paths under {codebase_path}")
+        kg.process_codebase(codebase_path, incremental=True)
+        return dict(kg.last_run_stats)
+    
+    return IndexWatcher(
+        codebase_path,
+        sync,
+        accepts=lambda path, is_dir=False: walker.accepts(codebase_path, path, is_dir),
+        list_files=list_files,
+        debounce_seconds=debounce_seconds,
+        use_polling=use_polling,
+    )
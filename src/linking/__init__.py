@@ -0,0 +1,34 @@
+"""
+Cross-language linking (CROSS_LANG_CALLS edges between gRPC clients, services and servers, FFI calls)
+and cross-repository linking (DEPENDS_ON edges into other indexed repositories).
+"""
+
+from src.linking.base import (
+    CROSS_LANG_RELATION,
+    CandidateEdge,
+    CrossLanguageMatcher,
+    SymbolTable,
+    collect_link_facts,
+    get_matchers,
+    link_cross_language,
+    prepare_link_facts,
+)
+from src.linking.ffi import FfiMatcher
+from src.linking.grpc import GrpcMatcher
+from src.linking.repositories import link_repositories, read_module_names, repository_node
+
+__all__ = [
+    'CROSS_LANG_RELATION',
+    'CandidateEdge',
+    'CrossLanguageMatcher',
+    'FfiMatcher',
+    'GrpcMatcher',
+    'SymbolTable',
+    'collect_link_facts',
+    'get_matchers',
+    'link_cross_language',
+    'link_repositories',
+    'prepare_link_facts',
+    'read_module_names',
+    'repository_node',
+]
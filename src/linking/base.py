@@ -0,0 +1,302 @@
+"""
+Cross-language linking.
+
+After every parser has finished, matchers look at the whole symbol table
+for calls that cross a language boundary, which no single-language parser
+can resolve, and propose CROSS_LANG_CALLS edges with a confidence score.
+
+A matcher implements CrossLanguageMatcher:
+- prepare(symbols): derive facts from the nodes parsed in this run, e.g.
+  the exported symbols a Python function calls through ctypes, and store
+  them as node properties (listed in fact_properties)
+- facts(symbols): IDs of the nodes its matches depend on
+- match(symbols): the candidate edges
+
+The fact nodes, their fact_properties and their DEFINES / METHOD_OF /
+EXTENDS / IMPLEMENTS / EMBEDS relations are kept in the index_state of
+their file, so an incremental run can link changed files against files
+it does not re-parse (src.indexing.incremental restores them as stubs).
+Nodes restored that way have line_no 0 and are not prepared again.
+
+CROSS_LANG_CALLS edges are structural: they are recomputed on every run.
+Each carries the matcher name, its confidence in [0, 1] and the source
+"heuristic:<matcher>_link"; when two matchers propose the same pair, the
+higher confidence wins. Their premises are the files of both ends and of
+the nodes a matcher names in premise_ids (e.g. the other services of the
+same name, which split the confidence), so an incremental run replaces
+only the edges one of whose files changed.
+
+CROSS_LANG_MATCHERS (comma-separated, default "grpc,ffi") selects the
+matchers; an empty value disables linking.
+"""
+
+import logging
+import os
+from abc import ABC, abstractmethod
+from dataclasses import dataclass, field
+from typing import Any, Dict, Iterable, List, Optional, Sequence, Set, Tuple
+
+from src.ast_parser.language_detector import detect_language
+from src.ast_parser.parser import CodeNode, CodeRelation
+from src.ast_parser.provenance import (
+    CONFIDENCE_PROPERTY,
+    PREMISES_PROPERTY,
+    SOURCE_PROPERTY,
+    link_source,
+    premise_files,
+)
+
+logger = logging.getLogger(__name__)
+
+CROSS_LANG_RELATION = "CROSS_LANG_CALLS"
+
+DEFAULT_MATCHERS = "grpc,ffi"
+
+# Relations of fact nodes kept in index_state, so members and owners resolve without re-parsing
+LINK_FACT_RELATIONS = ("DEFINES", "METHOD_OF", "EXTENDS", "IMPLEMENTS", "EMBEDS")
+
+# Callable symbols matchers link from and to
+CALLABLE_NODE_TYPES = ("Function", "Method")
+
+
+@dataclass
+class CandidateEdge:
+    """A proposed CROSS_LANG_CALLS edge."""
+    source_id: str
+    target_id: str
+    confidence: float
+    properties: Dict[str, Any] = field(default_factory=dict)
+    # Nodes besides the two ends whose files the match depends on
+    premise_ids: List[str] = field(default_factory=list)
+
+
+class SymbolTable:
+    """Lookups over the nodes and relations of the whole index, shared by all matchers."""
+    
+    def __init__(self, nodes: Dict[str, CodeNode], relations: Iterable[CodeRelation]):
+        self.nodes = nodes
+        self.relations = list(relations)
+        self._by_type: Dict[str, List[CodeNode]] = {}
+        self._members: Dict[str, List[str]] = {}
+        self._owners: Dict[str, str] = {}
+        self._outgoing: Dict[Tuple[str, str], List[CodeRelation]] = {}
+        self._lines: Dict[str, Optional[List[str]]] = {}
+        
+        for node in nodes.values():
+            self._by_type.setdefault(node.node_type, []).append(node)
+        for relation in self.relations:
+            self._outgoing.setdefault((relation.source_id, relation.relation_type), []).append(relation)
+            if relation.relation_type == "DEFINES":
+                self._add_member(relation.source_id, relation.target_id)
+            elif relation.relation_type == "METHOD_OF":
+                self._add_member(relation.target_id, relation.source_id)
+    
+    def _add_member(self, owner_id: str, member_id: str) -> None:
+        members = self._members.setdefault(owner_id, [])
+        if member_id not in members:
+            members.append(member_id)
+        self._owners.setdefault(member_id, owner_id)
+    
+    def of_type(self, *node_types: str) -> List[CodeNode]:
+        """Nodes of the given types, in index order."""
+        return [node for node_type in node_types for node in self._by_type.get(node_type, [])]
+    
+    def members(self, node_id: str) -> List[CodeNode]:
+        """Nodes a type or service defines (DEFINES out, METHOD_OF in)."""
+        return [self.nodes[member_id] for member_id in self._members.get(node_id, []) if member_id in self.nodes]
+    
+    def owner(self, node_id: str) -> Optional[CodeNode]:
+        """Node defining node_id, e.g. the class of a method."""
+        owner_id = self._owners.get(node_id)
+        return self.nodes.get(owner_id) if owner_id else None
+    
+    def outgoing(self, node_id: str, relation_type: str) -> List[CodeRelation]:
+        return self._outgoing.get((node_id, relation_type), [])
+    
+    def file_lines(self, file_path: str) -> Optional[List[str]]:
+        """Lines of a file, read once per linking pass; None when it cannot be read."""
+        if file_path not in self._lines:
+            try:
+                with open(file_path, "r", encoding="utf-8", errors="replace") as handle:
+                    self._lines[file_path] = handle.read().split("\n")
+            except OSError as e:
+                logger.debug(f"Cannot read {file_path} for linking: {e}")
+                self._lines[file_path] = None
+        return self._lines[file_path]
+    
+    def source(self, node: CodeNode) -> str:
+        """Source text of a node parsed in this run, empty for stubs and unreadable files."""
+        if not node.line_no or not node.file_path:
+            return ""
+        lines = self.file_lines(node.file_path)
+        if lines is None:
+            return ""
+        return "\n".join(lines[node.line_no - 1:node.end_line_no or node.line_no])
+
+
+def language_of(node: CodeNode) -> Optional[str]:
+    """Language of a node, from its file extension ("proto" for .proto files)."""
+    if node.file_path.endswith(".proto"):
+        return "proto"
+    return detect_language(node.file_path) if node.file_path else None
+
+
+def is_parsed(node: CodeNode) -> bool:
+    """Whether a node comes from a file parsed in this run (not a restored stub)."""
+    return bool(node.line_no) and bool(node.file_path)
+
+
+class CrossLanguageMatcher(ABC):
+    """Proposes CROSS_LANG_CALLS edges from the whole symbol table."""
+    
+    # Matcher name, stored on the edges it proposes
+    name: str = ""
+    # Node properties its matches read, kept in index_state for fact nodes
+    fact_properties: Sequence[str] = ()
+    # Node properties prepare() sets
+    prepared_properties: Sequence[str] = ()
+    
+    def prepare(self, symbols: SymbolTable) -> None:
+        """Derive facts on the nodes parsed in this run (default: none)."""
+    
+    @abstractmethod
+    def facts(self, symbols: SymbolTable) -> Set[str]:
+        """IDs of the nodes match() depends on."""
+        raise NotImplementedError
+    
+    @abstractmethod
+    def match(self, symbols: SymbolTable) -> List[CandidateEdge]:
+        """Candidate edges, confidence in [0, 1]."""
+        raise NotImplementedError
+
+
+def _registry() -> Dict[str, type]:
+    from src.linking.ffi import FfiMatcher
+    from src.linking.grpc import GrpcMatcher
+    return {GrpcMatcher.name: GrpcMatcher, FfiMatcher.name: FfiMatcher}
+
+
+def get_matchers(names: Optional[str] = None) -> List[CrossLanguageMatcher]:
+    """
+    Resolve the cross-language matchers.
+    
+    Args:
+        names: Comma-separated matcher names, if None, get from CROSS_LANG_MATCHERS (default: grpc,ffi);
+            an empty string disables linking
+    
+    Raises:
+        ValueError: for an unknown matcher
+    """
+    if names is None:
+        names = os.getenv("CROSS_LANG_MATCHERS", DEFAULT_MATCHERS)
+    registry = _registry()
+    matchers = []
+    for name in (part.strip().lower() for part in names.split(",")):
+        if not name:
+            continue
+        if name not in registry:
+            raise ValueError(f"Unknown cross-language matcher '{name}', expected one of: {', '.join(registry)}")
+        matchers.append(registry[name]())
+    return matchers
+
+
+def prepare_link_facts(
+    nodes: Dict[str, CodeNode],
+    relations: Iterable[CodeRelation],
+    matchers: Sequence[CrossLanguageMatcher],
+) -> SymbolTable:
+    """Run the matchers' prepare over the nodes; return the symbol table they saw."""
+    symbols = SymbolTable(nodes, relations)
+    for matcher in matchers:
+        matcher.prepare(symbols)
+    return symbols
+
+
+def link_cross_language(
+    nodes: Dict[str, CodeNode],
+    relations: Iterable[CodeRelation],
+    matchers: Sequence[CrossLanguageMatcher],
+) -> List[CodeRelation]:
+    """
+    Run the matchers over the whole index.
+    
+    Returns:
+        Structural CROSS_LANG_CALLS relations, one per (source, target) pair,
+        with matcher, confidence, source, premises and the matcher's own properties
+    """
+    if not matchers:
+        return []
+    symbols = prepare_link_facts(nodes, relations, matchers)
+    
+    best: Dict[Tuple[str, str], Tuple[CandidateEdge, str]] = {}
+    # Every candidate of a pair is a premise: a change to a losing one may make it win
+    premises: Dict[Tuple[str, str], Set[str]] = {}
+    for matcher in matchers:
+        for edge in matcher.match(symbols):
+            if edge.source_id == edge.target_id or edge.source_id not in nodes or edge.target_id not in nodes:
+                continue
+            key = (edge.source_id, edge.target_id)
+            premises.setdefault(key, set()).update(
+                nodes[node_id].file_path for node_id in [edge.source_id, edge.target_id, *edge.premise_ids]
+                if node_id in nodes
+            )
+            if key not in best or edge.confidence > best[key][0].confidence:
+                best[key] = (edge, matcher.name)
+    
+    linked = []
+    for (source_id, target_id), (edge, matcher_name) in sorted(best.items()):
+        properties = dict(edge.properties)
+        properties.update({
+            "matcher": matcher_name,
+            CONFIDENCE_PROPERTY: round(min(max(edge.confidence, 0.0), 1.0), 3),
+            SOURCE_PROPERTY: link_source(matcher_name),
+            PREMISES_PROPERTY: premise_files(premises[(source_id, target_id)]),
+            "structural": True,
+        })
+        linked.append(CodeRelation(source_id, target_id, CROSS_LANG_RELATION, properties))
+    logger.info(f"Cross-language linking: {len(linked)} {CROSS_LANG_RELATION} edges")
+    return linked
+
+
+def collect_link_facts(
+    nodes: Dict[str, CodeNode],
+    relations: Iterable[CodeRelation],
+    matchers: Sequence[CrossLanguageMatcher],
+) -> Dict[str, Dict[str, List[Any]]]:
+    """
+    The link facts of each file, for its index_state.
+    
+    Returns:
+        {file_path: {"nodes": [[id, type, name, properties]], "relations": [[source, target, type, properties]]}}
+    """
+    relations = list(relations)
+    symbols = SymbolTable(nodes, relations)
+    fact_ids: Set[str] = set()
+    fact_properties: List[str] = []
+    for matcher in matchers:
+        fact_ids |= matcher.facts(symbols)
+        fact_properties.extend(key for key in matcher.fact_properties if key not in fact_properties)
+    
+    facts: Dict[str, Dict[str, List[Any]]] = {}
+    for node_id in sorted(fact_ids):
+        node = nodes.get(node_id)
+        if node is None or not node.file_path:
+            continue
+        properties = {key: node.properties[key] for key in fact_properties if key in node.properties}
+        facts.setdefault(node.file_path, {"nodes": [], "relations": []})["nodes"].append(
+            [node_id, node.node_type, node.name, properties]
+        )
+    for relation in relations:
+        if relation.relation_type not in LINK_FACT_RELATIONS or relation.source_id not in fact_ids:
+            continue
+        if relation.properties.get("structural"):
+            # Recomputed on every run (e.g. Go IMPLEMENTS)
+            continue
+        source = nodes.get(relation.source_id)
+        if source is None or not source.file_path:
+            continue
+        facts[source.file_path]["relations"].append(
+            [relation.source_id, relation.target_id, relation.relation_type, relation.properties]
+        )
+    return facts
+
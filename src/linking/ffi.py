@@ -0,0 +1,129 @@
+"""
+FFI matcher.
+
+Links calls through a C ABI to the function exporting the symbol, by name:
+
+Exports:
+- Go functions with a cgo `//export Name` directive (export_name), 0.9
+- Rust functions with `#[no_mangle]` (or `#[export_name = "..."]`), 0.9
+- C and C++ functions, 0.6 (any of them may be the one linked in)
+
+Callers (ffi_calls, set by prepare):
+- Python functions calling `lib.name(...)` on a ctypes library loaded in
+  their module (`lib = ctypes.CDLL(...)`, `cdll.LoadLibrary(...)`, ...)
+- Go functions calling `C.name(...)` through cgo
+
+Only pairs in different languages are linked; a symbol exported by
+several functions splits the confidence between them, and the files of
+all of them are premises of each edge.
+"""
+
+import re
+from typing import Dict, List, Optional, Set, Tuple
+
+from src.ast_parser.parser import CodeNode
+from src.linking.base import (
+    CALLABLE_NODE_TYPES,
+    CandidateEdge,
+    CrossLanguageMatcher,
+    SymbolTable,
+    is_parsed,
+    language_of,
+)
+
+# `lib = ctypes.CDLL("libfoo.so")`, `self.lib = cdll.LoadLibrary(path)`
+CTYPES_LIBRARY = re.compile(
+    r"^\s*(?:self\.)?([A-Za-z_]\w*)\s*(?::[^=]+)?=\s*(?:ctypes\.)?"
+    r"(?:CDLL|PyDLL|WinDLL|OleDLL|(?:cdll|pydll|windll|oledll)\.LoadLibrary)\s*\(",
+    re.MULTILINE,
+)
+
+EXPORT_NAME_ATTRIBUTE = re.compile(r'^(?:unsafe\()?export_name\s*=\s*"([^"]+)"\)?$')
+
+EXPORT_CONFIDENCE = {"go": 0.9, "rust": 0.9, "c": 0.6, "cpp": 0.6}
+
+
+def _rust_export(node: CodeNode) -> Optional[str]:
+    """C symbol of a Rust function, None when it is not exported unmangled."""
+    for attribute in node.properties.get("attributes", []):
+        if attribute in ("no_mangle", "unsafe(no_mangle)"):
+            return node.name
+        export = EXPORT_NAME_ATTRIBUTE.match(attribute)
+        if export:
+            return export.group(1)
+    return None
+
+
+class FfiMatcher(CrossLanguageMatcher):
+    """ctypes and cgo calls to exported C symbols."""
+    
+    name = "ffi"
+    fact_properties = ("export_name", "attributes", "ffi_calls")
+    prepared_properties = ("ffi_calls",)
+    
+    def prepare(self, symbols: SymbolTable) -> None:
+        """Set ffi_calls (C symbol names) on the Python and Go functions calling through an FFI."""
+        libraries: Dict[str, List[str]] = {}
+        for node in symbols.of_type(*CALLABLE_NODE_TYPES):
+            if not is_parsed(node):
+                continue
+            language = language_of(node)
+            calls: Set[str] = set()
+            if language == "python":
+                if node.file_path not in libraries:
+                    lines = symbols.file_lines(node.file_path) or []
+                    libraries[node.file_path] = CTYPES_LIBRARY.findall("\n".join(lines))
+                source = symbols.source(node)
+                for library in libraries[node.file_path]:
+                    calls.update(re.findall(rf"\b{re.escape(library)}\.([A-Za-z_]\w*)\s*\(", source))
+            elif language == "go":
+                for relation in symbols.outgoing(node.node_id, "CALLS"):
+                    target = symbols.nodes.get(relation.target_id)
+                    if target is not None and target.node_type == "ExternalFunction" \
+                            and target.properties.get("import_path") == "C":
+                        calls.add(target.name)
+            else:
+                continue
+            node.properties.pop("ffi_calls", None)
+            if calls:
+                node.properties["ffi_calls"] = sorted(calls)
+    
+    def _exports(self, symbols: SymbolTable, wanted: Set[str]) -> Dict[str, List[Tuple[CodeNode, float]]]:
+        """C symbol -> [(exporting function, confidence)], C and C++ functions only when in wanted."""
+        exports: Dict[str, List[Tuple[CodeNode, float]]] = {}
+        for node in symbols.of_type(*CALLABLE_NODE_TYPES):
+            language = language_of(node)
+            if language == "go":
+                symbol = node.properties.get("export_name")
+            elif language == "rust":
+                symbol = _rust_export(node)
+            elif language in ("c", "cpp") and node.node_type == "Function":
+                symbol = node.name if node.name in wanted else None
+            else:
+                continue
+            if symbol:
+                exports.setdefault(symbol, []).append((node, EXPORT_CONFIDENCE[language]))
+        return exports
+    
+    def facts(self, symbols: SymbolTable) -> Set[str]:
+        callers = [node for node in symbols.of_type(*CALLABLE_NODE_TYPES) if node.properties.get("ffi_calls")]
+        wanted = {name for node in callers for name in node.properties["ffi_calls"]}
+        exports = self._exports(symbols, wanted)
+        return {node.node_id for node in callers} | {
+            node.node_id for entries in exports.values() for node, _ in entries
+        }
+    
+    def match(self, symbols: SymbolTable) -> List[CandidateEdge]:
+        callers = [node for node in symbols.of_type(*CALLABLE_NODE_TYPES) if node.properties.get("ffi_calls")]
+        exports = self._exports(symbols, {name for node in callers for name in node.properties["ffi_calls"]})
+        edges = []
+        for caller in callers:
+            language = language_of(caller)
+            for symbol in caller.properties["ffi_calls"]:
+                targets = [(node, confidence) for node, confidence in exports.get(symbol, [])
+                           if language_of(node) != language]
+                premise_ids = [node.node_id for node, _ in targets]
+                for target, confidence in targets:
+                    edges.append(CandidateEdge(caller.node_id, target.node_id, confidence / len(targets),
+                                               {"symbol": symbol}, premise_ids=premise_ids))
+        return edges
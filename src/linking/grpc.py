@@ -0,0 +1,235 @@
+"""
+gRPC matcher.
+
+Links the callers of a gRPC service to the Rpc nodes of its .proto file
+(see ProtoParser), and those to the server implementations, so the path
+client -> Rpc -> handler crosses the language boundary through the proto:
+    
+    client -[:CROSS_LANG_CALLS {role: "client"}]-> Rpc
+    Rpc -[:CROSS_LANG_CALLS {role: "server"}]-> implementation
+
+Clients:
+- generated stubs: the `{Service}Stub` class of a `*_pb2_grpc.py` file,
+  the methods of the `{service}Client` type of a `*_grpc.pb.go` file
+  (0.95 when the file holds the rpc's "/pkg.Service/Method" path)
+- application code: Python or Go functions that use the stub
+  (`{Service}Stub`, `{Service}Client`) and call `.Method(` on it (0.8)
+
+Servers (outside generated files):
+- Python classes with a `{Service}Servicer` base, Go types embedding
+  `Unimplemented{Service}Server`: their method named like the rpc (0.9)
+- any other Go method named like the rpc whose signature mentions its
+  request and response types (0.5)
+
+Services are matched by name, so several services of the same name
+(e.g. two proto packages) split the confidence between them; their files
+are premises of each edge, with the file of a server method's type.
+"""
+
+import re
+from typing import Dict, List, Optional, Set, Tuple
+
+from src.ast_parser.parser import CodeNode
+from src.linking.base import (
+    CALLABLE_NODE_TYPES,
+    CandidateEdge,
+    CrossLanguageMatcher,
+    SymbolTable,
+    is_parsed,
+    language_of,
+)
+
+# Files written by protoc plugins
+GENERATED_SUFFIXES = ("_pb2_grpc.py", "_grpc.pb.go")
+
+CLIENT_LANGUAGES = ("python", "go")
+
+# `.Name(`: a member call
+_MEMBER_CALL = re.compile(r"\.([A-Za-z_]\w*)\s*\(")
+
+
+def is_generated(node: CodeNode) -> bool:
+    return node.file_path.endswith(GENERATED_SUFFIXES)
+
+
+def _last_segment(type_name: str) -> str:
+    """`pb.UnimplementedGreeterServer` / `*Foo` -> the bare type name."""
+    return type_name.lstrip("*&").rsplit(".", 1)[-1]
+
+
+def _lower_first(name: str) -> str:
+    return name[:1].lower() + name[1:]
+
+
+def _stub_marker(language: str, service: str) -> "re.Pattern[str]":
+    """How code refers to the client stub of a service."""
+    if language == "python":
+        return re.compile(rf"\b{re.escape(service)}Stub\b")
+    # NewGreeterClient(...), pb.GreeterClient
+    return re.compile(rf"{re.escape(service)}Client\b")
+
+
+class GrpcMatcher(CrossLanguageMatcher):
+    """Client stubs and server implementations of the services of indexed .proto files."""
+    
+    name = "grpc"
+    fact_properties = (
+        "qualified_name", "service", "full_method", "request", "response",
+        "bases", "embeds", "receiver_type", "signature", "grpc_calls",
+    )
+    prepared_properties = ("grpc_calls",)
+    
+    def _services(self, symbols: SymbolTable) -> Dict[str, List[Tuple[CodeNode, List[CodeNode]]]]:
+        """Service name -> [(Service node, its Rpc nodes)]."""
+        services: Dict[str, List[Tuple[CodeNode, List[CodeNode]]]] = {}
+        for service in symbols.of_type("Service"):
+            rpcs = [member for member in symbols.members(service.node_id) if member.node_type == "Rpc"]
+            services.setdefault(service.name, []).append((service, rpcs))
+        return services
+    
+    def prepare(self, symbols: SymbolTable) -> None:
+        """Set grpc_calls ("/pkg.Service/Method" paths) on the application functions calling a stub."""
+        services = self._services(symbols)
+        if not services:
+            return
+        for node in symbols.of_type(*CALLABLE_NODE_TYPES):
+            language = language_of(node)
+            if not is_parsed(node) or language not in CLIENT_LANGUAGES or is_generated(node):
+                continue
+            node.properties.pop("grpc_calls", None)
+            source = symbols.source(node)
+            called = set(_MEMBER_CALL.findall(source))
+            if not called:
+                continue
+            calls = set()
+            for service_name, entries in services.items():
+                if not _stub_marker(language, service_name).search(source):
+                    continue
+                for _, rpcs in entries:
+                    calls.update(rpc.properties["full_method"] for rpc in rpcs if rpc.name in called)
+            if calls:
+                node.properties["grpc_calls"] = sorted(calls)
+    
+    def facts(self, symbols: SymbolTable) -> Set[str]:
+        return self._scan(symbols)[1]
+    
+    def match(self, symbols: SymbolTable) -> List[CandidateEdge]:
+        return self._scan(symbols)[0]
+    
+    def _scan(self, symbols: SymbolTable) -> Tuple[List[CandidateEdge], Set[str]]:
+        """(candidate edges, IDs of every node considered)"""
+        services = self._services(symbols)
+        edges: List[CandidateEdge] = []
+        considered: Set[str] = set()
+        if not services:
+            return edges, considered
+        rpcs_by_method = {
+            rpc.properties.get("full_method"): (service, rpc)
+            for entries in services.values() for service, rpcs in entries for rpc in rpcs
+        }
+        for entries in services.values():
+            for service, rpcs in entries:
+                considered.add(service.node_id)
+                considered.update(rpc.node_id for rpc in rpcs)
+        
+        def namesakes(service: CodeNode) -> List[str]:
+            """The services of the same name, which split the confidence."""
+            return [entry.node_id for entry, _ in services[service.name]]
+        
+        def client(source: CodeNode, service: CodeNode, rpc: CodeNode, confidence: float) -> None:
+            considered.add(source.node_id)
+            edges.append(CandidateEdge(source.node_id, rpc.node_id, confidence, {
+                "role": "client", "service": service.properties.get("qualified_name", service.name), "rpc": rpc.name,
+            }, premise_ids=namesakes(service)))
+        
+        def server(target: CodeNode, service: CodeNode, rpc: CodeNode, confidence: float,
+                   owner: Optional[CodeNode] = None) -> None:
+            considered.add(target.node_id)
+            premise_ids = namesakes(service) + ([owner.node_id] if owner is not None else [])
+            edges.append(CandidateEdge(rpc.node_id, target.node_id, confidence, {
+                "role": "server", "service": service.properties.get("qualified_name", service.name), "rpc": rpc.name,
+            }, premise_ids=premise_ids))
+        
+        def generated_confidence(node: CodeNode, rpc: CodeNode, count: int) -> float:
+            lines = symbols.file_lines(node.file_path) or []
+            if any(rpc.properties.get("full_method", "\0") in line for line in lines):
+                return 0.95
+            return 0.6 / count
+        
+        # Generated client stubs
+        for node in symbols.of_type("Class"):
+            if not node.file_path.endswith("_pb2_grpc.py") or not node.name.endswith("Stub"):
+                continue
+            entries = services.get(node.name[:-len("Stub")], [])
+            for service, rpcs in entries:
+                for rpc in rpcs:
+                    client(node, service, rpc, generated_confidence(node, rpc, len(entries)))
+        for node in symbols.of_type("Method"):
+            receiver = _last_segment(node.properties.get("receiver_type", ""))
+            if not node.file_path.endswith("_grpc.pb.go") or not receiver.endswith("Client"):
+                continue
+            for entries_name, entries in services.items():
+                if receiver != f"{_lower_first(entries_name)}Client":
+                    continue
+                for service, rpcs in entries:
+                    for rpc in rpcs:
+                        if rpc.name == node.name:
+                            client(node, service, rpc, generated_confidence(node, rpc, len(entries)))
+        
+        # Application code calling a stub
+        for node in symbols.of_type(*CALLABLE_NODE_TYPES):
+            for full_method in node.properties.get("grpc_calls", []):
+                if full_method not in rpcs_by_method:
+                    continue
+                service, rpc = rpcs_by_method[full_method]
+                client(node, service, rpc, 0.8 / len(services[service.name]))
+        
+        # Servers extending the generated base class
+        implemented: Set[Tuple[str, str]] = set()
+        for node in symbols.of_type("Class"):
+            if is_generated(node):
+                continue
+            language = language_of(node)
+            if language == "python":
+                bases = [_last_segment(base) for base in node.properties.get("bases", [])]
+                names = [base[:-len("Servicer")] for base in bases if base.endswith("Servicer")]
+            elif language == "go":
+                embeds = [_last_segment(embed) for embed in node.properties.get("embeds", [])]
+                names = [embed[len("Unimplemented"):-len("Server")] for embed in embeds
+                         if embed.startswith("Unimplemented") and embed.endswith("Server")]
+            else:
+                continue
+            for service_name in names:
+                entries = services.get(service_name, [])
+                if not entries:
+                    continue
+                considered.add(node.node_id)
+                methods = {m.name: m for m in symbols.members(node.node_id) if m.node_type == "Method"}
+                considered.update(m.node_id for m in methods.values())
+                for service, rpcs in entries:
+                    for rpc in rpcs:
+                        if rpc.name in methods:
+                            server(methods[rpc.name], service, rpc, 0.9 / len(entries), owner=node)
+                            implemented.add((rpc.node_id, node.node_id))
+        
+        # Go methods that look like handlers: name, request and response types
+        rpcs_by_name: Dict[str, List[Tuple[CodeNode, CodeNode, int]]] = {}
+        for entries in services.values():
+            for service, rpcs in entries:
+                for rpc in rpcs:
+                    rpcs_by_name.setdefault(rpc.name, []).append((service, rpc, len(entries)))
+        for node in symbols.of_type("Method"):
+            if node.name not in rpcs_by_name or language_of(node) != "go" or is_generated(node):
+                continue
+            owner = symbols.owner(node.node_id)
+            signature = node.properties.get("signature", "")
+            for service, rpc, count in rpcs_by_name[node.name]:
+                if owner is not None and (rpc.node_id, owner.node_id) in implemented:
+                    continue
+                request, response = rpc.properties.get("request", ""), rpc.properties.get("response", "")
+                considered.add(node.node_id)
+                if request and response and re.search(rf"\b{re.escape(_last_segment(request))}\b", signature) \
+                        and re.search(rf"\b{re.escape(_last_segment(response))}\b", signature):
+                    server(node, service, rpc, 0.5 / count, owner=owner)
+        
+        return edges, considered
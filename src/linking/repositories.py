@@ -0,0 +1,201 @@
+"""
+Cross-repository linking.
+
+Imports of code that is not indexed point at ExternalPackage placeholders
+of the importing repository (see src/ast_parser/packages.py). Once the
+imported code is indexed as another repository of the same store, the
+placeholder's module path names it:
+
+- a Package of the other repository with that import path (Go packages),
+  or else
+- the Repository node of the other repository publishing the module:
+  its go.mod module, package.json name, Cargo crate or pyproject project,
+  matched as the module itself or a prefix of it ("@org/types/user" is
+  published by "@org/types", "github.com/org/lib/util" by
+  "github.com/org/lib", "shop.cart" by "shop")
+
+link_repositories adds a DEPENDS_ON edge flagged cross_repo from every
+package depending on such a placeholder to what it resolves to, with the
+repository and module it resolves through and the imports and files
+counts of the package's DEPENDS_ON edge into the placeholder. Its source
+is "heuristic:repo_link", with confidence 1.0 through a Package and 0.8
+through a module name. Cross-repo edges are derived from every
+repository's index, so the indexer drops and recomputes all of them
+after each run; deleting a repository removes its end of them.
+Snapshots (see src/indexing/snapshots.py) are past copies of another
+repository, and are neither linked nor linked to.
+"""
+
+import json
+import logging
+import os
+import re
+import threading
+from typing import Any, Dict, List, Optional, Tuple
+
+from src.ast_parser.provenance import (
+    CONFIDENCE_PROPERTY,
+    REPO_LINK_CONFIDENCE,
+    REPO_PREFIX_LINK_CONFIDENCE,
+    SOURCE_PROPERTY,
+    SOURCE_REPO_LINK,
+    with_provenance,
+)
+from src.graph_store.base import REPO_PROPERTY, node_repo
+from src.graph_store.repository import REPOSITORY_LABEL, repository_id
+from src.indexing.incremental import INDEX_STATE_VERSION, PACKAGE_DEPENDENCY_RELATION
+from src.indexing.snapshots import is_snapshot
+
+logger = logging.getLogger(__name__)
+
+# One pass at a time, index jobs of different repositories may finish together
+_LINK_LOCK = threading.Lock()
+
+_GO_MODULE = re.compile(r"\s*module\s+(\S+)")
+_TOML_HEADER = re.compile(r"\s*\[([^\]]+)\]")
+_TOML_NAME = re.compile(r"\s*name\s*=\s*[\"']([^\"']+)[\"']")
+
+
+def _read(path: str) -> Optional[str]:
+    try:
+        with open(path, "r", encoding="utf-8") as f:
+            return f.read()
+    # A manifest that is not UTF-8 publishes no module names, it does not fail the run
+    except (OSError, UnicodeDecodeError):
+        return None
+
+
+def _toml_names(text: str, sections: Tuple[str, ...]) -> List[str]:
+    """`name` values of the given TOML sections, in section order."""
+    names: Dict[str, str] = {}
+    section = ""
+    for line in text.splitlines():
+        header = _TOML_HEADER.match(line)
+        if header:
+            section = header.group(1).strip()
+            continue
+        match = _TOML_NAME.match(line)
+        if match and section in sections:
+            names.setdefault(section, match.group(1))
+    return [names[section] for section in sections if section in names]
+
+
+def read_module_names(root: str) -> List[str]:
+    """Module names the manifests at the root of a codebase publish, as importers spell them."""
+    modules = []
+    go_mod = _read(os.path.join(root, "go.mod"))
+    if go_mod:
+        match = next((m for m in map(_GO_MODULE.match, go_mod.splitlines()) if m), None)
+        if match:
+            modules.append(match.group(1).strip('"'))
+    package_json = _read(os.path.join(root, "package.json"))
+    if package_json:
+        try:
+            name = json.loads(package_json).get("name")
+        except (ValueError, AttributeError):
+            name = None
+        if isinstance(name, str) and name:
+            modules.append(name)
+    cargo_toml = _read(os.path.join(root, "Cargo.toml"))
+    if cargo_toml:
+        # A [lib] name overrides the package name; Rust paths spell "-" as "_"
+        modules += [name.replace("-", "_") for name in _toml_names(cargo_toml, ("lib", "package"))[:1]]
+    pyproject = _read(os.path.join(root, "pyproject.toml"))
+    if pyproject:
+        modules += [name.replace("-", "_") for name in _toml_names(pyproject, ("project", "tool.poetry"))[:1]]
+    return list(dict.fromkeys(modules))
+
+
+def repository_node(repo: str, root: str) -> Dict[str, Any]:
+    """Repository node record of a codebase, with its local ID (to write through the repository's view)."""
+    root = os.path.realpath(root)
+    return {
+        "labels": ["Base", REPOSITORY_LABEL],
+        "properties": {
+            "id": repository_id(repo),
+            "name": repo,
+            "root": root,
+            "modules": read_module_names(root),
+        },
+    }
+
+
+def _publishes(name: str, module_path: str) -> bool:
+    return module_path == name or any(module_path.startswith(name + separator) for separator in ("/", ".", "::"))
+
+
+def _resolve(module_path: str, source_repo: str, packages: Dict[str, List[Dict[str, Any]]],
+             repositories: List[Dict[str, Any]]) -> List[Tuple[Dict[str, Any], str]]:
+    """(target properties, module) pairs of another repository a module path resolves to."""
+    exact = [package for package in packages.get(module_path, []) if node_repo(package) != source_repo]
+    if exact:
+        return [(package, module_path) for package in exact]
+    best: Dict[str, Tuple[Dict[str, Any], str]] = {}
+    for repository in repositories:
+        if node_repo(repository) == source_repo:
+            continue
+        for name in repository.get("modules") or []:
+            current = best.get(repository["id"])
+            if _publishes(name, module_path) and (current is None or len(name) > len(current[1])):
+                best[repository["id"]] = (repository, name)
+    return list(best.values())
+
+
+def link_repositories(store) -> int:
+    """
+    Recompute the cross-repo DEPENDS_ON edges of a store.
+    
+    Args:
+        store: The whole GraphStore, not a repository's view
+    
+    Returns:
+        Number of edges written
+    """
+    with _LINK_LOCK:
+        store.delete_cross_repo_relationships()
+        repositories = []
+        snapshots = set()
+        for record in store.find_nodes(label=REPOSITORY_LABEL):
+            if is_snapshot(record["properties"]):
+                snapshots.add(node_repo(record["properties"]))
+            else:
+                repositories.append(record["properties"])
+        if len({node_repo(repository) for repository in repositories}) < 2:
+            return 0
+        
+        packages: Dict[str, List[Dict[str, Any]]] = {}
+        for record in store.find_nodes(label="Package"):
+            import_path = record["properties"].get("import_path")
+            if import_path and node_repo(record["properties"]) not in snapshots:
+                packages.setdefault(import_path, []).append(record["properties"])
+        
+        targets: Dict[str, List[Tuple[Dict[str, Any], str]]] = {}
+        for record in store.find_nodes(label="ExternalPackage"):
+            properties = record["properties"]
+            if node_repo(properties) in snapshots:
+                continue
+            module_path = properties.get("module_path") or properties.get("name")
+            resolved = _resolve(module_path, node_repo(properties), packages, repositories) if module_path else []
+            if resolved:
+                targets[properties["id"]] = resolved
+        if not targets:
+            return 0
+        
+        edges = []
+        for row in store.neighbors(list(targets), [PACKAGE_DEPENDENCY_RELATION], direction="in", label="Package"):
+            dependency = row["relationship"]["properties"]
+            for target, module in targets[row["origin_id"]]:
+                properties = {"cross_repo": True, REPO_PROPERTY: node_repo(target), "module": module}
+                properties.update({key: dependency[key] for key in ("imports", "files") if key in dependency})
+                # A package matched by import path is certain, a repository publishing a module prefix less so
+                confidence = REPO_LINK_CONFIDENCE if target.get("import_path") == module else REPO_PREFIX_LINK_CONFIDENCE
+                properties.update({SOURCE_PROPERTY: SOURCE_REPO_LINK, CONFIDENCE_PROPERTY: confidence})
+                edges.append({
+                    "start_node_id": row["node"]["properties"]["id"],
+                    "end_node_id": target["id"],
+                    "type": PACKAGE_DEPENDENCY_RELATION,
+                    "properties": with_provenance(properties, INDEX_STATE_VERSION),
+                })
+        store.batch_create_relationships(edges)
+        logger.info(f"Linked {len(edges)} package dependencies across repositories")
+        return len(edges)
@@ -3,10 +3,11 @@ import sys
 import argparse
 import logging
 import time
+from contextlib import ExitStack
+from datetime import datetime, timezone
 from typing import Dict, List, Any, Tuple, Optional
 from dotenv import load_dotenv
 import json
-from concurrent.futures import as_completed
 
 # Add project root to Python path
 project_root = os.path.abspath(os.path.join(os.path.dirname(__file__), '..'))
@@ -14,10 +15,89 @@ if project_root not in sys.path:
     sys.path.insert(0, project_root)
 
 from src.ast_parser.parser import ASTParser
+from src.ast_parser.guards import count_parse_errors, get_max_file_bytes, get_parse_timeout, parse_with_limits
 from src.ast_parser.multi_parser import MultiLanguageParser
+from src.ast_parser.fallback_parser import fallback_extensions
+from src.ast_parser.proto_parser import PROTO_EXTENSIONS
+from src.ast_parser.provenance import with_provenance
 from src.embeddings.factory import get_embedding_provider
-from src.embeddings.embedder import CodeEmbedder, OpenAIEmbeddings
+from src.embeddings.base import EmbeddingProvider
+from src.embeddings.embedder import CodeEmbedder, OpenAIEmbeddings, get_embedding_batch_size
+from src.embeddings.node_text import EMBEDDED_NODE_TYPES, SourceLines, build_node_text, embedding_key
+from src.indexing import (
+    STORE_SOURCE_MODES,
+    SYMBOL_ID_SCHEMES,
+    SourceFileWalker,
+    SpillBuffer,
+    StreamingRun,
+    annotate_sources,
+    annotate_body_hashes,
+    annotate_symbol_ids,
+    annotate_tests,
+    annotate_file_nodes,
+    apply_renames,
+    build_file_index_states,
+    compute_file_dependencies,
+    compute_package_dependencies,
+    compute_package_summaries,
+    detect_file_moves,
+    find_renamed_symbols,
+    get_memory_budget_mb,
+    get_source_max_bytes,
+    get_store_source,
+    get_stream_batch_files,
+    get_streaming,
+    get_symbol_id_scheme,
+    link_tests,
+    load_index_context,
+    load_package_docs,
+    load_package_imports,
+    plan_incremental_update,
+    previous_file_paths,
+    select_incremental_writes,
+    select_structural_writes,
+    serialize_index_state,
+    stale_structural_keys,
+    watch_codebase,
+)
+from src.indexing.incremental import INDEX_STATE_VERSION
+from src.indexing.git import (
+    GitChanges,
+    GitError,
+    GitHead,
+    changed_files,
+    find_repo_root,
+    last_commits,
+    plan_changed_only,
+    read_head,
+)
+from src.indexing.coordination import GraphChange, get_coordinator
+from src.indexing.jobs import IndexCancelled, IndexProgress
+from src.indexing.snapshots import (
+    SNAPSHOT_COMMIT_PROPERTY,
+    SNAPSHOT_OF_PROPERTY,
+    SNAPSHOT_REF_PROPERTY,
+    checkout_snapshot,
+    snapshot_repo_name,
+)
+from src.indexing.streaming import PRESCAN_IMPORT_TYPES, holds_partial_types, iter_batches, light_node
+from src.indexing.summaries import SUMMARY_PROPERTIES
+from src.graph_store import (
+    STORAGE_BACKENDS,
+    GraphStore,
+    InMemoryGraphStore,
+    RepositoryStore,
+    get_graph_file,
+    get_storage_backend,
+)
+from src.graph_store.migrations import ensure_schema, get_auto_migrate, migrate_graph, write_schema_version
+from src.graph_store.repository import REPOSITORY_LABEL, repository_id
+from src.linking import (
+    collect_link_facts, get_matchers, link_cross_language, link_repositories, prepare_link_facts, repository_node,
+)
+from src.neo4j_storage.batch_writer import GraphBatchWriter
 from src.neo4j_storage.graph_db import Neo4jDatabase
+from src.parallel.pipeline import ParserSettings, create_parser, iter_parse_results, parse_source_file
 from src.parallel.pool_manager import get_processing_pool
 from src.utils.runtime_detection import log_runtime_info
 
@@ -37,6 +117,25 @@ class CodebaseKnowledgeGraph:
         neo4j_user: Optional[str] = None,
         neo4j_password: Optional[str] = None,
         openai_api_key: Optional[str] = None,
+        exclude: Optional[List[str]] = None,
+        include_only: Optional[List[str]] = None,
+        follow_symlinks: Optional[bool] = None,
+        max_workers: Optional[int] = None,
+        write_batch_size: Optional[int] = None,
+        embedding_provider: Optional[EmbeddingProvider] = None,
+        storage: Optional[str] = None,
+        graph_file: Optional[str] = None,
+        store: Optional[GraphStore] = None,
+        store_source: Optional[str] = None,
+        source_max_bytes: Optional[int] = None,
+        link_matchers: Optional[str] = None,
+        max_file_bytes: Optional[int] = None,
+        parse_timeout: Optional[float] = None,
+        repo: Optional[str] = None,
+        auto_migrate: Optional[bool] = None,
+        symbol_id_scheme: Optional[str] = None,
+        streaming: Optional[bool] = None,
+        memory_budget_mb: Optional[int] = None,
     ):
         """Initialize the Codebase Knowledge Graph
         
@@ -44,6 +143,25 @@ class CodebaseKnowledgeGraph:
             neo4j_uri: Neo4j database URI, if None, get from environment variables
             neo4j_user: Neo4j username, if None, get from environment variables
             neo4j_password: Neo4j password, if None, get from environment variables
+            exclude: Extra gitignore-style patterns to skip, if None, get from INDEX_EXCLUDE
+            include_only: Only index files matching these patterns, if None, get from INDEX_INCLUDE_ONLY
+            follow_symlinks: Follow symlinked directories outside the codebase, if None, get from INDEX_FOLLOW_SYMLINKS
+            max_workers: Number of parser workers, if None, get from MAX_WORKERS (default: CPU count)
+            write_batch_size: Nodes/relationships per write transaction, if None, get from INDEX_WRITE_BATCH_SIZE
+            embedding_provider: Embedding provider to use instead of the one configured by environment variables
+            storage: Storage backend, "neo4j" or "memory", if None, get from GRAPH_STORAGE (default: neo4j)
+            graph_file: JSON file of the memory backend, if None, get from GRAPH_STORE_PATH
+            store: Existing GraphStore to write to (e.g. the MCP server's); it is flushed, not closed, by close()
+            store_source: Source stored on symbol nodes, "true", "false" or "signatures_only", if None, get from INDEX_STORE_SOURCE
+            source_max_bytes: Byte cap of a stored source, if None, get from INDEX_SOURCE_MAX_BYTES (default: 8192)
+            link_matchers: Comma-separated cross-language matchers, if None, get from CROSS_LANG_MATCHERS (default: grpc,ffi)
+            max_file_bytes: Size above which a file is indexed without symbols, if None, get from INDEX_MAX_FILE_BYTES (default: 1000000, 0: no limit)
+            parse_timeout: Seconds a file may take to parse, if None, get from INDEX_PARSE_TIMEOUT (default: 60, 0: no limit)
+            repo: Repository the codebase is indexed as, if None, get from INDEX_REPO (default: "default")
+            auto_migrate: Migrate a graph of an older schema version before writing, if None, get from GRAPH_AUTO_MIGRATE (default: true)
+            symbol_id_scheme: "qualified" or "signature", if None, get from SYMBOL_ID_SCHEME (default: qualified)
+            streaming: Run full indexes in memory-bounded batches, if None, get from INDEX_STREAMING (default: false)
+            memory_budget_mb: Megabytes of buffered references before they spill to disk, if None, get from INDEX_MEMORY_BUDGET_MB (default: 256)
         """
         self.neo4j_uri = neo4j_uri or os.environ.get("NEO4J_URI")
         self.neo4j_user = neo4j_user or os.environ.get("NEO4J_USER")
@@ -52,14 +170,26 @@ class CodebaseKnowledgeGraph:
         # Validate configuration
         self._validate_configuration()
         
-        # Initialize Neo4j database connection with connection pooling
-        max_pool_size = self._get_neo4j_pool_size()
-        self.db = Neo4jDatabase(
-            uri=self.neo4j_uri or "",
-            user=self.neo4j_user or "",
-            password=self.neo4j_password or "",
-            max_connection_pool_size=max_pool_size
-        )
+        # Initialize the storage backend (Neo4j with connection pooling by default)
+        self.owns_store = store is None
+        if store is not None:
+            self.db = store
+        elif get_storage_backend(storage) == "memory":
+            self.db = InMemoryGraphStore(get_graph_file(graph_file))
+        else:
+            max_pool_size = self._get_neo4j_pool_size()
+            self.db = Neo4jDatabase(
+                uri=self.neo4j_uri or "",
+                user=self.neo4j_user or "",
+                password=self.neo4j_password or "",
+                max_connection_pool_size=max_pool_size
+            )
+        # The indexer reads and writes one repository of the store (see src.graph_store.repository)
+        self.repo_db = RepositoryStore(self.db, repo)
+        self.repo = self.repo_db.repo
+        self.coordinator = get_coordinator(self.db)
+        # Whether an older graph is migrated or refused (see src.graph_store.migrations)
+        self.auto_migrate = get_auto_migrate(auto_migrate)
         
         # Initialize code parser
         self.parser = ASTParser()
@@ -68,15 +198,70 @@ class CodebaseKnowledgeGraph:
         self.use_ast_grep = os.getenv("USE_AST_GREP", "false").lower() == "true"
         self.ast_grep_languages = os.getenv("AST_GREP_LANGUAGES", "python,javascript,typescript").split(',')
         self.ast_grep_fallback = os.getenv("AST_GREP_FALLBACK_TO_LEGACY", "true").lower() == "true"
+        # Per-file size and parse time limits (see src.ast_parser.guards)
+        self.max_file_bytes = get_max_file_bytes(max_file_bytes)
+        self.parse_timeout = get_parse_timeout(parse_timeout)
+        # Picklable copy of the flags for parser workers
+        self.parser_settings = ParserSettings(
+            use_ast_grep=self.use_ast_grep,
+            ast_grep_languages=tuple(self.ast_grep_languages),
+            ast_grep_fallback=self.ast_grep_fallback,
+            max_file_bytes=self.max_file_bytes,
+            parse_timeout=self.parse_timeout,
+        )
+        
+        # Parallel parse and batched write options
+        self.max_workers = max_workers
+        self.write_batch_size = write_batch_size
+        
+        # Directory walk options (.gitignore handling, exclude/include patterns, symlinks)
+        self.exclude = exclude if exclude is not None else os.getenv("INDEX_EXCLUDE", "")
+        self.include_only = include_only if include_only is not None else os.getenv("INDEX_INCLUDE_ONLY", "")
+        self.respect_gitignore = os.getenv("INDEX_RESPECT_GITIGNORE", "true").lower() == "true"
+        if follow_symlinks is None:
+            follow_symlinks = os.getenv("INDEX_FOLLOW_SYMLINKS", "false").lower() == "true"
+        self.follow_symlinks = follow_symlinks
+        
+        # Source stored on symbol nodes, capped at source_max_bytes (see src.indexing.source)
+        self.store_source = get_store_source(store_source)
+        self.source_max_bytes = get_source_max_bytes(source_max_bytes)
+        
+        # Matchers of the cross-language linking pass (see src.linking)
+        self.link_matchers = get_matchers(link_matchers)
+        
+        # When symbol IDs carry a signature hash (see src.indexing.symbol_ids)
+        self.symbol_id_scheme = get_symbol_id_scheme(symbol_id_scheme)
+        
+        # Memory-bounded full runs (see src.indexing.streaming)
+        self.streaming = get_streaming(streaming)
+        self.memory_budget_mb = get_memory_budget_mb(memory_budget_mb)
+        self.stream_batch_files = get_stream_batch_files()
         
         # Initialize embedding handler
-        # If an explicit API key is provided prefer the wrapper, otherwise use the factory
-        if openai_api_key:
+        # An injected provider wins, then an explicit API key (wrapper), otherwise the factory
+        if embedding_provider is not None:
+            self.embedder = embedding_provider
+        elif openai_api_key:
             self.embedder = OpenAIEmbeddings(api_key=openai_api_key)
         else:
             self.embedder = get_embedding_provider()
 
         self.code_embedder = CodeEmbedder(self.embedder)
+        
+        # Resolution index (module_definitions, module_to_file) of the last full parse
+        self.last_index: Tuple[Dict[str, Dict[str, str]], Dict[str, str]] = ({}, {})
+        # Summary of the last process_codebase run
+        self.last_run_stats: Dict[str, Any] = {}
+        # Skip counts of the last directory walk
+        self.last_walk_stats = None
+        # Nodes embedded, reused (unchanged embedding_key) and left without a vector by the last write
+        self.last_embedding_stats: Dict[str, int] = {"embedded": 0, "reused": 0, "failed": 0}
+        # Phase, counts and cancellation flag of the current run (see src.indexing.jobs)
+        self.progress = IndexProgress()
+        # Whether the current run has changed the graph yet, so a cancellation leaves it partial
+        self._graph_modified = False
+        # Repository node properties of the snapshot being indexed (see src.indexing.snapshots)
+        self._snapshot: Dict[str, Any] = {}
     
     def _validate_configuration(self) -> None:
         """Validate configuration parameters
@@ -142,16 +327,92 @@ class CodebaseKnowledgeGraph:
         logger.info(f"Using default Neo4j connection pool size: {default_size}")
         return default_size
     
-    def process_codebase(self, codebase_path: str, clear_db: bool = False) -> Tuple[int, int]:
+    def process_codebase(self, codebase_path: str, clear_db: bool = False, incremental: bool = False,
+                         progress: Optional[IndexProgress] = None, changed_since: Optional[str] = None) -> Tuple[int, int]:
         """Process the entire codebase, parse and import into the knowledge graph
         
+        A run that completes marks the codebase's IndexMetadata node with
+        index_complete: true. A run cancelled through progress raises
+        IndexCancelled; if it had already changed the graph, the node is
+        marked index_complete: false and the next incremental run of the
+        codebase falls back to a full index.
+        
+        Inside a git work tree, the IndexMetadata node also records the
+        indexed commit and branch, and File nodes the last commit that
+        touched them (see src/indexing/git.py).
+        
+        Runs of the same codebase and repository in this process take turns:
+        a second one waits for the first to finish (cancellable meanwhile).
+        The graph changes of a run are applied while no MCP query runs (see
+        src/indexing/coordination.py).
+        
         Args:
             codebase_path: Directory path of the codebase
-            clear_db: Whether to clear the database
+            clear_db: Whether to delete the repository's nodes first (other repositories are kept)
+            incremental: Only re-parse files whose content changed since the last run
+                (ignored when clear_db is set or the graph holds no file states yet)
+            progress: Receives the phase and counts of the run and carries its cancellation flag
+            changed_since: Git ref; re-index exactly the files of `git diff base..HEAD`
+                (implies incremental; a full index if the codebase is not in a git repository)
             
         Returns:
             Number of nodes and relationships processed
+        
+        Raises:
+            IndexCancelled: progress was cancelled before the run finished
         """
+        self.progress = progress or IndexProgress()
+        self._graph_modified = False
+        # Writes made outside an apply (cleared repository, migration, streaming), reported as one unscoped change
+        self._unscoped_writes = False
+        with self.coordinator.write_lock(codebase_path, self.repo, self.progress.check_cancelled):
+            try:
+                return self._index_codebase(codebase_path, clear_db, incremental, changed_since)
+            except IndexCancelled:
+                logger.warning(f"Indexing of {codebase_path} cancelled during {self.progress.phase}")
+                if self._graph_modified:
+                    self._unscoped_writes = True
+                    self._write_index_metadata(codebase_path, complete=False)
+                self.db.flush()
+                raise
+            finally:
+                if self._unscoped_writes:
+                    self.coordinator.changed(GraphChange(self.repo))
+    
+    def process_snapshot(self, codebase_path: str, ref: str,
+                         progress: Optional[IndexProgress] = None) -> Tuple[int, int]:
+        """Index the codebase as of a git ref into this repository, replacing what it held
+        
+        The ref is checked out into a temporary worktree, which is removed
+        after the run (see src/indexing/snapshots.py); the working copy is
+        not touched.
+        
+        Args:
+            codebase_path: Directory path of the codebase, inside a git work tree
+            ref: Branch, tag or commit to index
+            progress: Receives the phase and counts of the run and carries its cancellation flag
+        
+        Returns:
+            Number of nodes and relationships processed
+        
+        Raises:
+            GitError: the codebase is not in a git repository, or ref does not name a commit
+        """
+        with checkout_snapshot(codebase_path, ref) as (snapshot_path, commit):
+            logger.info(f"Indexing {codebase_path} at {ref} ({commit[:12]}) as repository {self.repo}")
+            self._snapshot = {
+                SNAPSHOT_REF_PROPERTY: ref,
+                SNAPSHOT_COMMIT_PROPERTY: commit,
+                SNAPSHOT_OF_PROPERTY: os.path.realpath(codebase_path),
+            }
+            try:
+                return self.process_codebase(snapshot_path, clear_db=True, progress=progress)
+            finally:
+                self._snapshot = {}
+    
+    def _index_codebase(self, codebase_path: str, clear_db: bool, incremental: bool,
+                        changed_since: Optional[str] = None) -> Tuple[int, int]:
+        """Body of process_codebase"""
         start_time = time.time()
         logger.info(f"Starting to process codebase: {codebase_path}")
         
@@ -160,20 +421,65 @@ class CodebaseKnowledgeGraph:
         
         # Verify database connection
         if not self.db.verify_connection():
-            raise ConnectionError("Cannot connect to Neo4j database, please check connection settings")
+            raise ConnectionError("Cannot connect to the graph database, please check connection settings")
         
-        # Clear the database (if needed)
+        # Clear the repository (if needed); other repositories of the store are kept
         if clear_db:
-            logger.info("Clearing database...")
-            self.db.clear_database()
+            logger.info(f"Clearing repository {self.repo}...")
+            self._graph_modified = True
+            self._unscoped_writes = True
+            self.repo_db.clear_database()
+        
+        # Migrate a graph of an older schema version, or refuse to write into it; an empty graph gets the current one
+        schema = ensure_schema(self.db, self.auto_migrate)
+        if schema["from_version"] is None:
+            write_schema_version(self.db)
+        if schema["applied"]:
+            self._unscoped_writes = True
         
         # Create database schema
         logger.info("Creating database schema...")
         self.db.create_schema_constraints()
+        self._check_repository_root(codebase_path)
         
         # Collect all source files (Python, JS, TS)
+        self.progress.set_phase("walking")
         source_files = self._collect_source_files(codebase_path)
         logger.info(f"Found {len(source_files)} source code files")
+        self.progress.set_files_total(len(source_files))
+        self.progress.check_cancelled()
+        
+        git_head = self._read_git_head(codebase_path)
+        git_changes = None
+        if changed_since:
+            incremental = True
+            if git_head is None:
+                logger.warning(f"--changed-only {changed_since}: {codebase_path} is not in a git repository, running a full index")
+                incremental = False
+            else:
+                try:
+                    git_changes = changed_files(git_head.root, changed_since)
+                except GitError as e:
+                    hint = " (the base commit may be missing from this shallow clone)" if git_head.shallow else ""
+                    logger.warning(f"--changed-only {changed_since}: {e}{hint}, running a full index")
+                    incremental = False
+        
+        if incremental and not clear_db and self._index_complete(codebase_path) is False:
+            logger.info("The last index run of this codebase did not complete, running a full index")
+            incremental = False
+        
+        index_metadata = self._index_metadata(codebase_path)
+        stored_scheme = index_metadata.get("symbol_id_scheme")
+        if incremental and not clear_db and stored_scheme not in (None, self.symbol_id_scheme):
+            logger.info(f"Symbol IDs were built with the {stored_scheme} scheme, running a full index")
+            incremental = False
+        
+        if incremental and not clear_db:
+            result = self._process_codebase_incremental(source_files, start_time, codebase_path, git_head, git_changes)
+            if result is not None:
+                self.db.flush()
+                return result
+            logger.info("No stored file states found, running a full index")
         
         # Get configuration for parallel processing
         parallel_enabled = os.getenv("PARALLEL_INDEXING_ENABLED", "true").lower() == "true"
@@ -182,30 +488,717 @@ class CodebaseKnowledgeGraph:
         # Determine if we should use parallel processing
         use_parallel = parallel_enabled and len(source_files) >= min_files_for_parallel
         
+        if self.streaming:
+            return self._process_codebase_streaming(source_files, start_time, codebase_path, use_parallel,
+                                                    clear_db, index_metadata, git_head)
+        
+        self.progress.set_phase("parsing", total=len(source_files))
         if use_parallel:
             logger.info(f"Using parallel processing mode to process {len(source_files)} files")
             nodes, relations = self._process_files_parallel(source_files, codebase_path)
         else:
             logger.info(f"Using sequential processing mode to process {len(source_files)} files")
             # For sequential mode, we still need to use the router
-            nodes, relations = self._process_directory_with_routing(codebase_path)
+            nodes, relations = self._process_directory_with_routing(codebase_path, source_files)
         
         logger.info(f"Total parsed {len(nodes)} nodes and {len(relations)} relationships")
+        parse_errors = self._report_parse_errors(nodes)
         
-        # Generate embedding vectors for nodes
-        logger.info("Generating embedding vectors for nodes...")
-        self._generate_embeddings(nodes)
+        # Store per-file hashes, resolution index and the file dependency map for incremental runs
+        module_definitions, module_to_file = self.last_index
+        annotate_body_hashes(nodes)
+        annotate_symbol_ids(nodes, relations, self.repo, codebase_path, self.symbol_id_scheme)
+        annotate_tests(nodes, relations)
+        relations = relations + link_tests(nodes, relations)
+        relations = relations + link_cross_language(nodes, relations, self.link_matchers)
+        index_states = build_file_index_states(nodes, relations, module_definitions, module_to_file,
+                                               link_facts=collect_link_facts(nodes, relations, self.link_matchers))
+        annotate_file_nodes(nodes, index_states)
+        self._annotate_last_commits(nodes, git_head)
+        relations = relations + compute_file_dependencies(relations, nodes) + compute_package_dependencies(
+            {path: state["package_imports"] for path, state in index_states.items() if state["package_imports"]}
+        )
+        
+        # A full run may change anything: the apply reports an unscoped change
+        with self.coordinator.applying(repo=self.repo):
+            # Nodes of an earlier index whose IDs changed would keep the symbol IDs written now
+            if not clear_db and index_metadata:
+                self._graph_modified = True
+                self.repo_db.delete_file_scope(sorted(set(self.repo_db.get_file_states()) | set(source_files)))
+            
+            self._write_graph(nodes, relations)
+            self._refresh_package_summaries(
+                {path: state["package_doc"] for path, state in index_states.items() if state.get("package_doc")}
+            )
+            self._create_search_indexes()
+            self._write_index_metadata(codebase_path, complete=True, git_head=git_head)
+            self._link_repository(codebase_path)
+        self._unscoped_writes = False
+        
+        elapsed_time = time.time() - start_time
+        self.last_run_stats = {
+            "mode": "full",
+            "files": len(source_files),
+            "parse_errors": parse_errors,
+            "embeddings": dict(self.last_embedding_stats),
+            "elapsed_seconds": round(elapsed_time, 2),
+        }
+        logger.info(f"Codebase processing complete! Time taken: {elapsed_time:.2f} seconds (Parallel mode: {use_parallel})")
+        self.db.flush()
+        return len(nodes), len(relations)
+    
+    def _process_codebase_incremental(self, source_files: List[str], start_time: float, codebase_path: str,
+                                      git_head: Optional[GitHead] = None,
+                                      git_changes: Optional[GitChanges] = None) -> Optional[Tuple[int, int]]:
+        """Re-index only the files that changed since the last run
+        
+        Changed and deleted files have their nodes removed from the graph.
+        Changed files are re-parsed, and files with a DEPENDS_ON_FILE edge into
+        them are re-parsed too so their cross-file edges are resolved again.
+        Every other file contributes its stored index_state instead of being parsed.
+        Structural relations are recomputed from the combined index; those whose
+        premises include a changed, deleted or dependent file replace the stored
+        ones, the others are kept as stored. Renamed symbols and moved files keep
+        their stored node IDs (see src/indexing/renames.py).
+        
+        Given git_changes, the files of that diff are the changed and deleted
+        files instead of those whose content hash changed, and its renames
+        count as moves even when the content changed too.
+        
+        Args:
+            source_files: Files found by the walker
+            start_time: Start time of the run, for the elapsed time log
+            codebase_path: Directory path of the codebase, for the IndexMetadata node
+            git_head: Checked-out commit, None outside a git work tree
+            git_changes: GitChanges of a --changed-only run
+        
+        Returns:
+            Number of nodes and relationships written, or None if the graph has no file states
+        """
+        stored_states = self.repo_db.get_file_states()
+        if not stored_states:
+            return None
+        
+        git_renames = {}
+        if git_changes is not None:
+            plan, git_renames = plan_changed_only(git_changes, source_files, stored_states)
+        else:
+            plan = plan_incremental_update(source_files, stored_states)
+        if plan.touched:
+            self.repo_db.update_file_mtimes(plan.touched)
+        
+        if not plan.has_changes:
+            elapsed_time = time.time() - start_time
+            self.last_run_stats = {
+                "mode": "incremental",
+                "changed": 0,
+                "deleted": 0,
+                "dependents": 0,
+                "elapsed_seconds": round(elapsed_time, 2),
+            }
+            logger.info(f"No changes detected in {len(source_files)} files. Time taken: {elapsed_time:.2f} seconds")
+            # READMEs are not indexed files, a package summary may still have changed
+            self._refresh_package_summaries(load_package_docs(stored_states, plan.unchanged))
+            # A new commit without changes to indexed files still moves the recorded commit
+            if git_head is not None and self._index_metadata(codebase_path).get("git_commit") != git_head.commit:
+                self._write_index_metadata(codebase_path, complete=True, git_head=git_head)
+            return 0, 0
+        
+        changed_files = set(plan.changed)
+        removed_files = changed_files | set(plan.deleted)
+        dependent_files = set(self.repo_db.get_dependent_files(sorted(removed_files))) - removed_files
+        dependent_files &= set(plan.unchanged)
+        logger.info(
+            f"Incremental update: {len(plan.changed)} changed, {len(plan.deleted)} deleted, "
+            f"{len(dependent_files)} dependent files to re-resolve"
+        )
+        
+        # Resolution index of the files that are not re-parsed
+        context_files = [path for path in plan.unchanged if path not in dependent_files]
+        module_definitions, module_to_file, stub_nodes, stub_relations, node_files = load_index_context(
+            stored_states, context_files
+        )
+        
+        # First pass: changed and dependent files only
+        all_nodes = {}
+        all_relations = []
+        all_pending_imports = []
+        self.progress.set_phase("parsing", total=len(changed_files | dependent_files))
+        for file_path in sorted(changed_files | dependent_files):
+            self.progress.check_cancelled()
+            self.progress.advance(current_file=file_path)
+            nodes, relations, module_defs, pending, module_files = self._parse_single_file(file_path)
+            all_nodes.update(nodes)
+            all_relations.extend(relations)
+            for module_name, symbols in module_defs.items():
+                module_definitions.setdefault(module_name, {}).update(symbols)
+            all_pending_imports.extend(pending)
+            module_to_file.update(module_files)
+        parse_errors = self._report_parse_errors(all_nodes)
+        
+        parsed_relations = list(all_relations)
+        for node_id, node in stub_nodes.items():
+            all_nodes.setdefault(node_id, node)
+        
+        # Second pass against the combined index
+        self.progress.set_phase("resolving")
+        final_parser = ASTParser()
+        final_parser.nodes = all_nodes
+        final_parser.relations = all_relations + stub_relations
+        final_parser.established_relations = {
+            f"{r.source_id}|{r.relation_type}|{r.target_id}" for r in final_parser.relations
+        }
+        final_parser.module_definitions = module_definitions
+        final_parser.pending_imports = all_pending_imports
+        final_parser.module_to_file = module_to_file
+        final_parser.node_files = node_files
+        final_parser._process_pending_imports()
+        
+        # Renamed symbols and moved files keep their stored node IDs; best-effort, never fatal
+        moves, matches, previous_paths = {}, {}, {}
+        try:
+            moves = detect_file_moves(plan.changed, plan.deleted, stored_states)
+            moves.update(git_renames)
+            annotate_body_hashes(all_nodes)
+            reparsed_files = changed_files | dependent_files
+            matches = find_renamed_symbols(all_nodes, reparsed_files, stored_states, moves)
+            previous_paths = previous_file_paths(reparsed_files, stored_states, moves)
+        except Exception as e:
+            logger.warning(f"Rename detection failed, symbols keep their parsed IDs: {e}")
+            matches, previous_paths = {}, {}
+        kept_ids = apply_renames(matches, previous_paths, all_nodes, final_parser.relations, module_definitions)
+        annotate_symbol_ids(all_nodes, final_parser.relations, self.repo, codebase_path, self.symbol_id_scheme)
+        annotate_tests(all_nodes, final_parser.relations)
+        final_parser.relations.extend(link_tests(all_nodes, final_parser.relations, node_files))
+        
+        # Cross-language edges, linked against the stored facts of the files not re-parsed
+        final_parser.relations.extend(link_cross_language(all_nodes, final_parser.relations, self.link_matchers))
+        
+        # Placeholder nodes (no file) are shared between files and may already exist
+        shared_ids = [node_id for node_id, node in all_nodes.items() if not node.file_path]
+        existing_shared_ids = self.repo_db.get_existing_node_ids(shared_ids) if shared_ids else set()
+        
+        nodes_to_write, relations_to_write = select_incremental_writes(
+            all_nodes, final_parser.relations, changed_files, existing_shared_ids, stub_relations
+        )
+        stub_ids = {id(relation) for relation in stub_relations}
+        resolved_relations = [r for r in final_parser.relations if id(r) not in stub_ids]
+        index_states = build_file_index_states(all_nodes, resolved_relations, module_definitions, module_to_file,
+                                               node_files, kept_ids,
+                                               collect_link_facts(all_nodes, final_parser.relations, self.link_matchers))
+        annotate_file_nodes(nodes_to_write, index_states)
+        self._annotate_last_commits(nodes_to_write, git_head)
+        relations_to_write += compute_file_dependencies(relations_to_write, all_nodes, node_files)
+        
+        # Package dependencies: stored imports of the files not re-parsed, fresh ones of the others
+        package_imports = load_package_imports(stored_states, context_files)
+        for file_path in changed_files | dependent_files:
+            if index_states.get(file_path, {}).get("package_imports"):
+                package_imports[file_path] = index_states[file_path]["package_imports"]
+        relations_to_write += compute_package_dependencies(package_imports)
+        
+        # Structural relations (Go IMPLEMENTS, package DEPENDS_ON, CROSS_LANG_CALLS) were recomputed over the
+        # whole index; only those whose premises include a removed or re-resolved file replace stored ones
+        invalidated_files = removed_files | dependent_files
+        stale_keys = stale_structural_keys(relations_to_write, invalidated_files)
+        
+        # Vectors of the changed files' nodes, reused where the embedded text is unchanged
+        reusable_embeddings = self.repo_db.get_node_embeddings(sorted(changed_files | set(moves.values())))
+        
+        self.progress.check_cancelled()
+        # What the apply writes, for the query cache to drop only the results it may change
+        change = GraphChange(self.repo, files=removed_files | dependent_files)
+        with self.coordinator.applying(change):
+            logger.info(f"Removing stale nodes of {len(removed_files)} files...")
+            self._graph_modified = True
+            self.repo_db.delete_file_scope(sorted(removed_files))
+            deleted_keys = self.repo_db.delete_stale_structural_relationships(sorted(invalidated_files), stale_keys)
+            relations_to_write = select_structural_writes(relations_to_write, stale_keys + deleted_keys)
+            
+            self._write_graph(nodes_to_write, relations_to_write, reusable_embeddings)
+            # Dependent files keep their nodes, but their imports may resolve to other targets now
+            self.repo_db.update_file_index_states([
+                (file_path, serialize_index_state(index_states, file_path)) for file_path in sorted(dependent_files)
+            ])
+            orphans = self.repo_db.delete_orphan_placeholders()
+            
+            # Package summaries: stored docs of the files not re-parsed, fresh ones of the others
+            package_docs = load_package_docs(stored_states, context_files)
+            for file_path in changed_files | dependent_files:
+                if index_states.get(file_path, {}).get("package_doc"):
+                    package_docs[file_path] = index_states[file_path]["package_doc"]
+            self._refresh_package_summaries(package_docs, change)
+            self._write_index_metadata(codebase_path, complete=True, git_head=git_head)
+            linked = self._link_repository(codebase_path)
+            
+            change.add_writes(nodes_to_write, relations_to_write, all_nodes, node_files)
+            change.keys.update(node_id for key in deleted_keys for node_id in (key[0], key[2]))
+            change.keys.update((self._index_metadata_id(codebase_path), repository_id(self.repo)))
+            change.labels.update(("IndexMetadata", REPOSITORY_LABEL))
+            change.shared = bool(orphans or linked)
+        
+        elapsed_time = time.time() - start_time
+        self.last_run_stats = {
+            "mode": "incremental",
+            "changed": len(plan.changed),
+            "deleted": len(plan.deleted),
+            "dependents": len(dependent_files),
+            "parse_errors": parse_errors,
+            "embeddings": dict(self.last_embedding_stats),
+            "elapsed_seconds": round(elapsed_time, 2),
+        }
+        logger.info(f"Incremental update complete! Time taken: {elapsed_time:.2f} seconds")
+        return len(nodes_to_write), len(relations_to_write)
+    
+    def _process_codebase_streaming(self, source_files: List[str], start_time: float, codebase_path: str,
+                                    use_parallel: bool, clear_db: bool, index_metadata: Dict[str, Any],
+                                    git_head: Optional[GitHead] = None) -> Tuple[int, int]:
+        """Full index in memory-bounded batches (see src/indexing/streaming.py)
+        
+        The first pass parses, embeds and writes the nodes of one batch of
+        files at a time and buffers what the second pass needs of them. The
+        second pass resolves the buffered files batch by batch against the
+        resolution index stored on the File nodes, then the relations derived
+        from the whole index are computed from the stored states.
+        
+        Args:
+            source_files: Files found by the walker
+            start_time: Start time of the run, for the elapsed time log
+            codebase_path: Directory path of the codebase
+            use_parallel: Parse on the processing pool
+            clear_db: Whether the repository was cleared before the run
+            index_metadata: Properties of the codebase's IndexMetadata node before the run
+            git_head: Checked-out commit, None outside a git work tree
+        
+        Returns:
+            Number of nodes and relationships written
+        """
+        logger.info(f"Streaming {len(source_files)} files in batches of {self.stream_batch_files} "
+                    f"(memory budget {self.memory_budget_mb} MB)")
+        # Batches are written as they are parsed, outside an apply
+        self._unscoped_writes = True
+        # Nodes of an earlier index whose IDs changed would keep the symbol IDs written now
+        if not clear_db and index_metadata:
+            self._graph_modified = True
+            self.repo_db.delete_file_scope(sorted(set(self.repo_db.get_file_states()) | set(source_files)))
+        
+        self.last_embedding_stats = {"embedded": 0, "reused": 0, "failed": 0}
+        run = StreamingRun()
+        with SpillBuffer(self.memory_budget_mb * 1024 * 1024) as buffer:
+            self._stream_parse(source_files, use_parallel, buffer, run, codebase_path, git_head)
+            parse_errors = self._report_parse_errors(run.file_nodes)
+            self._stream_resolve(source_files, buffer, run, codebase_path, git_head)
+            spilled_records = buffer.spilled_records
+        self._stream_structural_relations(source_files, run)
+        
+        self.last_index = (run.module_definitions, run.module_to_file)
+        self._create_search_indexes()
+        self._write_index_metadata(codebase_path, complete=True, git_head=git_head)
+        self._link_repository(codebase_path)
+        
+        elapsed_time = time.time() - start_time
+        self.last_run_stats = {
+            "mode": "full",
+            "streaming": True,
+            "files": len(source_files),
+            "spilled_files": spilled_records,
+            "parse_errors": parse_errors,
+            "embeddings": dict(self.last_embedding_stats),
+            "elapsed_seconds": round(elapsed_time, 2),
+        }
+        logger.info(f"Codebase processing complete! Time taken: {elapsed_time:.2f} seconds (streaming, "
+                    f"{spilled_records} of {len(source_files)} files spilled to disk)")
+        self.db.flush()
+        return run.nodes_written, run.relations_written
+    
+    def _stream_parse(self, source_files: List[str], use_parallel: bool, buffer: SpillBuffer, run: StreamingRun,
+                      codebase_path: str, git_head: Optional[GitHead]) -> None:
+        """First streaming pass: parse and write the nodes of a batch of files at a time"""
+        self.progress.set_phase("parsing", total=len(source_files))
+        with ExitStack() as stack:
+            if use_parallel:
+                pool = stack.enter_context(get_processing_pool(max_workers=self.max_workers))
+                results = iter_parse_results(pool, source_files, self.parser_settings, max_in_flight=pool.max_workers * 2)
+            else:
+                results = ((file_path, self._parse_single_file(file_path)) for file_path in source_files)
+            with GraphBatchWriter(self.repo_db, batch_size=self.write_batch_size) as writer:
+                for batch in iter_batches(results, self.stream_batch_files):
+                    self._stream_parse_batch(batch, writer, buffer, run, codebase_path, git_head)
+    
+    def _stream_parse_batch(self, batch: List[Tuple[str, Any]], writer: GraphBatchWriter, buffer: SpillBuffer,
+                            run: StreamingRun, codebase_path: str, git_head: Optional[GitHead]) -> None:
+        """Annotate and write one batch of parse results, and buffer them for the second pass"""
+        nodes, relations, module_definitions, module_to_file = {}, [], {}, {}
+        for file_path, (file_nodes, file_relations, module_defs, pending, module_files) in batch:
+            self.progress.check_cancelled()
+            self.progress.advance(current_file=file_path)
+            nodes.update(file_nodes)
+            relations.extend(file_relations)
+            for module_name, symbols in module_defs.items():
+                module_definitions.setdefault(module_name, {}).update(symbols)
+            module_to_file.update(module_files)
+        
+        # Per-file annotations need nothing outside the batch
+        annotate_body_hashes(nodes)
+        annotate_symbol_ids(nodes, relations, self.repo, codebase_path, self.symbol_id_scheme)
+        annotate_tests(nodes, relations)
+        index_states = build_file_index_states(nodes, relations, module_definitions, module_to_file,
+                                               link_facts=collect_link_facts(nodes, relations, self.link_matchers))
+        
+        # Files with C# partial types are held whole: their parts are merged before they are written
+        held_files = {file_path for file_path, result in batch if holds_partial_types(result[3])}
+        for file_path in held_files:
+            run.held_states[file_path] = serialize_index_state(index_states, file_path)
+        nodes_to_write = {node_id: node for node_id, node in nodes.items() if node.file_path not in held_files}
+        annotate_file_nodes(nodes_to_write, index_states)
+        self._annotate_last_commits(nodes_to_write, git_head)
+        self._queue_nodes(writer, nodes_to_write)
+        run.nodes_written += len(nodes_to_write)
+        run.placeholders.update(node_id for node_id, node in nodes_to_write.items() if not node.file_path)
+        
+        for node_id, node in nodes.items():
+            if node.node_type == "File" and node.file_path:
+                run.file_nodes[node_id] = light_node(node)
+        for file_path, (file_nodes, file_relations, _, pending, _) in batch:
+            held = file_path in held_files
+            record_nodes = {node_id: node if held else light_node(node) for node_id, node in file_nodes.items()}
+            buffer.append((file_path, record_nodes, file_relations, pending, held))
+    
+    def _stream_resolve(self, source_files: List[str], buffer: SpillBuffer, run: StreamingRun,
+                        codebase_path: str, git_head: Optional[GitHead]) -> None:
+        """Second streaming pass: resolve the buffered files a batch at a time against the stored index"""
+        self.progress.set_phase("resolving", total=len(source_files))
+        stored_states = self.repo_db.get_file_states()
+        for file_path, index_state in run.held_states.items():
+            stored_states[file_path] = {"index_state": index_state}
+        (run.module_definitions, run.module_to_file, run.stub_nodes, run.stub_relations,
+         run.node_files) = load_index_context(stored_states, source_files)
+        del stored_states
+        
+        # Re-exports and interface embeddings of every file, before the first batch resolves against them
+        held_records = []
+        prescan = ASTParser()
+        prescan.module_definitions = run.module_definitions
+        for record in buffer:
+            prescan.pending_imports.extend(
+                import_info for import_info in record[3] if import_info["type"] in PRESCAN_IMPORT_TYPES
+            )
+            if record[4]:
+                held_records.append(record)
+        prescan._resolve_reexports()
+        run.interface_embeds = prescan._collect_interface_embeds()
+        
+        # Package nodes of every file (they have no file, so nothing else refers to them by file)
+        packages = ASTParser()
+        packages.nodes = dict(run.file_nodes)
+        packages._resolve_packages()
+        package_nodes = {node_id: node for node_id, node in packages.nodes.items() if node.node_type == "Package"}
+        annotate_symbol_ids(package_nodes, [], self.repo, codebase_path, self.symbol_id_scheme)
+        run.package_relations = packages.relations
+        with GraphBatchWriter(self.repo_db, batch_size=self.write_batch_size) as writer:
+            self._queue_nodes(writer, package_nodes)
+        run.nodes_written += len(package_nodes)
+        
+        # The held files first: merging their partial types updates the index the other batches resolve against
+        if held_records:
+            self._stream_resolve_batch(held_records, run, codebase_path, git_head)
+        records = (record for record in buffer if not record[4])
+        for batch in iter_batches(records, self.stream_batch_files):
+            self._stream_resolve_batch(batch, run, codebase_path, git_head)
+    
+    def _stream_resolve_batch(self, records: List[Any], run: StreamingRun, codebase_path: str,
+                              git_head: Optional[GitHead]) -> None:
+        """Resolve, write and update the index_state of one batch of buffered files"""
+        self.progress.check_cancelled()
+        batch_files = [record[0] for record in records]
+        held = records[0][4]
         
-        # Convert nodes to Neo4j format and import into the database
-        logger.info("Importing nodes into database...")
-        neo4j_nodes = self._convert_nodes_to_neo4j_format(nodes)
-        self.db.batch_create_nodes(neo4j_nodes)
+        # Every File node takes part, the Python module index is built from them
+        nodes = dict(run.file_nodes)
+        relations = []
+        pending_imports = []
+        for _, record_nodes, record_relations, pending, _ in records:
+            nodes.update(record_nodes)
+            relations.extend(record_relations)
+            pending_imports.extend(pending)
+        for node_id, node in run.stub_nodes.items():
+            nodes.setdefault(node_id, node)
         
-        # Convert relationships to Neo4j format and import into the database
-        logger.info("Importing relationships into database...")
-        neo4j_relations = self._convert_relations_to_neo4j_format(relations)
-        self.db.batch_create_relationships(neo4j_relations)
+        parser = ASTParser()
+        parser.nodes = nodes
+        parser.relations = relations + run.stub_relations
+        parser.established_relations = {
+            f"{r.source_id}|{r.relation_type}|{r.target_id}" for r in parser.relations
+        }
+        parser.module_definitions = run.module_definitions
+        parser.pending_imports = pending_imports
+        parser.module_to_file = run.module_to_file
+        parser.node_files = run.node_files
+        parser.interface_embeds = run.interface_embeds
+        parser._resolve_pending_imports()
         
+        paths = set(batch_files)
+        stub_ids = {id(relation) for relation in run.stub_relations}
+        resolved = [relation for relation in parser.relations if id(relation) not in stub_ids]
+        batch_nodes = {node_id: node for node_id, node in nodes.items() if node.file_path in paths}
+        if held:
+            annotate_body_hashes(batch_nodes)
+            annotate_symbol_ids(batch_nodes, resolved, self.repo, codebase_path, self.symbol_id_scheme)
+            annotate_tests(batch_nodes, resolved)
+        tests = [relation for relation in link_tests(nodes, parser.relations, run.node_files)
+                 if nodes[relation.source_id].file_path in paths]
+        parser.relations.extend(tests)
+        resolved.extend(tests)
+        # Matchers derive their facts (e.g. grpc_calls) against the whole index; the batch's own stubs are stale
+        prepare_link_facts(nodes, parser.relations, self.link_matchers)
+        link_relations = resolved + [relation for relation in run.stub_relations
+                                     if relation.source_id not in nodes or nodes[relation.source_id].file_path not in paths]
+        index_states = build_file_index_states(nodes, resolved, run.module_definitions, run.module_to_file,
+                                               run.node_files,
+                                               link_facts=collect_link_facts(nodes, link_relations, self.link_matchers))
+        
+        dependencies = []
+        for relation in compute_file_dependencies(resolved, nodes, run.node_files):
+            pair = (relation.source_id, relation.target_id)
+            if pair not in run.dependency_pairs:
+                run.dependency_pairs.add(pair)
+                dependencies.append(relation)
+        
+        # Placeholders are shared between files; the first batch referring to one writes it
+        nodes_to_write = {node_id: node for node_id, node in nodes.items()
+                          if not node.file_path and node_id not in run.placeholders}
+        run.placeholders.update(nodes_to_write)
+        if held:
+            annotate_file_nodes(batch_nodes, index_states)
+            self._annotate_last_commits(batch_nodes, git_head)
+            nodes_to_write.update(batch_nodes)
+        relations_to_write = resolved + dependencies
+        with GraphBatchWriter(self.repo_db, batch_size=self.write_batch_size) as writer:
+            self._queue_nodes(writer, nodes_to_write)
+            writer.add_relationships(self._convert_relations_to_neo4j_format(relations_to_write))
+        run.nodes_written += len(nodes_to_write)
+        run.relations_written += len(relations_to_write)
+        
+        if not held:
+            # The File nodes were written by the first pass, with the batch's share of the index only
+            self.repo_db.update_file_index_states([
+                (file_path, serialize_index_state(index_states, file_path)) for file_path in batch_files
+            ])
+            self.repo_db.update_node_properties([
+                (node_id, {"module_name": node.properties["module_name"]}) for node_id, node in batch_nodes.items()
+                if node.node_type == "File" and "module_name" in node.properties
+            ])
+            prepared_keys = {key for matcher in self.link_matchers for key in matcher.prepared_properties}
+            prepared = {node_id: node for node_id, node in batch_nodes.items() if prepared_keys & node.properties.keys()}
+            self.repo_db.update_node_properties([
+                (record["properties"]["id"], {key: record["properties"][key] for key in prepared_keys
+                                              if key in record["properties"]})
+                for record in self._convert_nodes_to_neo4j_format(prepared)
+            ])
+        self.progress.advance(len(records))
+    
+    def _stream_structural_relations(self, source_files: List[str], run: StreamingRun) -> None:
+        """Write the relations and package summaries derived from the whole index, computed from the stored file states"""
+        self.progress.set_phase("writing")
+        stored_states = self.repo_db.get_file_states()
+        _, _, stub_nodes, stub_relations, _ = load_index_context(stored_states, source_files)
+        package_imports = load_package_imports(stored_states, source_files)
+        package_docs = load_package_docs(stored_states, source_files)
+        del stored_states
+        
+        parser = ASTParser()
+        parser.nodes = stub_nodes
+        parser.relations = list(stub_relations)
+        parser.established_relations = {
+            f"{r.source_id}|{r.relation_type}|{r.target_id}" for r in parser.relations
+        }
+        parser._resolve_interface_implementations()
+        relations = parser.relations[len(stub_relations):]
+        relations += link_cross_language(stub_nodes, parser.relations, self.link_matchers)
+        relations += compute_package_dependencies(package_imports)
+        relations += run.package_relations
+        with GraphBatchWriter(self.repo_db, batch_size=self.write_batch_size) as writer:
+            writer.add_relationships(self._convert_relations_to_neo4j_format(relations))
+        run.relations_written += len(relations)
+        self._refresh_package_summaries(package_docs)
+    
+    def _refresh_package_summaries(self, package_docs: Dict[str, Dict[str, Any]],
+                                   change: Optional[GraphChange] = None) -> int:
+        """Update the summary of the Package nodes whose package doc or README changed
+        
+        Args:
+            package_docs: Package doc entry of every indexed file carrying one (see src/indexing/summaries.py)
+            change: GraphChange of the run, gets the IDs of the updated Package nodes
+        
+        Returns:
+            Number of Package nodes updated
+        """
+        records = self.repo_db.find_nodes(label="Package")
+        summaries = compute_package_summaries(
+            {record["properties"]["id"]: record["properties"].get("path") or "" for record in records}, package_docs
+        )
+        updates = []
+        for record in records:
+            properties = record["properties"]
+            summary = summaries[properties["id"]]
+            if summary["summary_sources"] is not None:
+                # Stored like every other list property (see _convert_nodes_to_neo4j_format)
+                summary["summary_sources"] = json.dumps(summary["summary_sources"])
+            if any(properties.get(key) != summary[key] for key in SUMMARY_PROPERTIES):
+                updates.append((properties["id"], summary))
+        if updates:
+            self._graph_modified = True
+            self.repo_db.update_node_properties(updates)
+            if change is not None:
+                change.keys.update(node_id for node_id, _ in updates)
+            logger.info(f"Updated the summaries of {len(updates)} packages")
+        return len(updates)
+    
+    def _report_parse_errors(self, nodes: Dict[str, Any]) -> Dict[str, int]:
+        """Add the files of nodes whose parse failed to the job errors
+        
+        Returns:
+            Number of failed files by parse_error_category
+        """
+        for node in nodes.values():
+            if node.node_type == "File" and node.properties.get("parse_error_category"):
+                self.progress.add_error(f"{node.file_path}: {node.properties['parse_error_category']}: "
+                                        f"{node.properties['parse_error']}")
+        counts = count_parse_errors(nodes)
+        if counts:
+            summary = ", ".join(f"{count} {category}" for category, count in sorted(counts.items()))
+            logger.warning(f"{sum(counts.values())} files were indexed without symbols ({summary}), "
+                           f"see get_index_diagnostics")
+        return counts
+    
+    def _write_graph(self, nodes: Dict[str, Any], relations: List[Any],
+                     reusable_embeddings: Optional[Dict[str, List[float]]] = None) -> None:
+        """Generate embeddings and import nodes and relationships into the database
+        
+        Nodes are embedded one write batch at a time and handed to a background
+        GraphBatchWriter, so database transactions overlap with embedding requests.
+        With store_source enabled, symbol sources are read per batch as well and
+        dropped from the nodes once converted, so they are not all held at once.
+        
+        Args:
+            nodes: Node dictionary
+            relations: Relationship list
+            reusable_embeddings: Stored vectors by embedding_key, reused for unchanged nodes
+        """
+        logger.info("Generating embedding vectors and importing nodes into database...")
+        self.last_embedding_stats = {"embedded": 0, "reused": 0, "failed": 0}
+        node_items = list(nodes.items())
+        self.progress.set_phase("embedding", total=len(node_items))
+        with GraphBatchWriter(self.repo_db, batch_size=self.write_batch_size) as writer:
+            for i in range(0, len(node_items), writer.batch_size):
+                self.progress.check_cancelled()
+                chunk = dict(node_items[i:i + writer.batch_size])
+                self._queue_node_batch(writer, chunk, reusable_embeddings)
+                self.progress.advance(len(chunk))
+            
+            # Relationships are written after every queued node
+            self.progress.check_cancelled()
+            self.progress.set_phase("writing", total=len(relations))
+            logger.info("Importing relationships into database...")
+            writer.add_relationships(self._convert_relations_to_neo4j_format(relations))
+        self.progress.advance(len(relations))
+    
+    def _queue_node_batch(self, writer: GraphBatchWriter, chunk: Dict[str, Any],
+                          reusable_embeddings: Optional[Dict[str, List[float]]] = None) -> None:
+        """Embed one write batch of nodes and queue it for the writer"""
+        # Generate embedding vectors for nodes
+        self._generate_embeddings(chunk, reusable_embeddings)
+        
+        # Convert nodes to Neo4j format and queue them for the writer
+        annotate_sources(chunk.values(), self.store_source, self.source_max_bytes)
+        self._graph_modified = True
+        writer.add_nodes(self._convert_nodes_to_neo4j_format(chunk))
+        for node in chunk.values():
+            node.properties.pop("source", None)
+    
+    def _queue_nodes(self, writer: GraphBatchWriter, nodes: Dict[str, Any]) -> None:
+        """Queue nodes for the writer one write batch at a time"""
+        node_items = list(nodes.items())
+        for i in range(0, len(node_items), writer.batch_size):
+            self._queue_node_batch(writer, dict(node_items[i:i + writer.batch_size]))
+    
+    def _index_metadata_id(self, codebase_path: str) -> str:
+        return f"index:{os.path.realpath(codebase_path)}"
+    
+    def _index_metadata(self, codebase_path: str) -> Dict[str, Any]:
+        """Properties of the codebase's IndexMetadata node, empty if it has none"""
+        records = self.repo_db.get_nodes([self._index_metadata_id(codebase_path)])
+        return records[0]["properties"] if records else {}
+    
+    def _index_complete(self, codebase_path: str) -> Optional[bool]:
+        """index_complete of the codebase's IndexMetadata node, None if it has none"""
+        return self._index_metadata(codebase_path).get("index_complete")
+    
+    def _write_index_metadata(self, codebase_path: str, complete: bool, git_head: Optional[GitHead] = None) -> None:
+        """Mark whether the graph holds a complete index of the codebase, and of which commit"""
+        root = os.path.realpath(codebase_path)
+        properties = {
+            "id": self._index_metadata_id(codebase_path),
+            "name": os.path.basename(root),
+            "root": root,
+            "index_complete": complete,
+            "symbol_id_scheme": self.symbol_id_scheme,
+            "indexed_at": datetime.now(timezone.utc).isoformat(timespec="seconds"),
+        }
+        if git_head is not None:
+            properties.update(git_head.metadata())
+        self.repo_db.batch_create_nodes([{"labels": ["Base", "IndexMetadata"], "properties": properties}])
+    
+    def _check_repository_root(self, codebase_path: str) -> None:
+        """Warn when the repository already holds the index of another root"""
+        records = self.repo_db.get_nodes([repository_id(self.repo)])
+        stored_root = records[0]["properties"].get("root") if records else None
+        root = os.path.realpath(codebase_path)
+        if stored_root and stored_root != root:
+            logger.warning(
+                f"Repository '{self.repo}' already holds an index of {stored_root}; "
+                f"pass --repo (or INDEX_REPO) to index {root} as a repository of its own"
+            )
+    
+    def _link_repository(self, codebase_path: str) -> int:
+        """Record the repository's root and modules, and link imports across the repositories of the store
+        
+        Returns:
+            Number of cross-repository edges written
+        """
+        node = repository_node(self.repo, codebase_path)
+        node["properties"].update(self._snapshot)
+        self.repo_db.batch_create_nodes([node])
+        return link_repositories(self.db)
+    
+    def _read_git_head(self, codebase_path: str) -> Optional[GitHead]:
+        """Checked-out commit of the git work tree holding the codebase, None outside one"""
+        repo_root = find_repo_root(codebase_path)
+        if repo_root is None:
+            logger.info(f"{codebase_path} is not in a git repository, indexing without git metadata")
+            return None
+        try:
+            return read_head(repo_root)
+        except GitError as e:
+            logger.warning(f"Cannot read HEAD of {repo_root}, indexing without git metadata: {e}")
+            return None
+    
+    def _annotate_last_commits(self, nodes: Dict[str, Any], git_head: Optional[GitHead]) -> None:
+        """Store the last commit that touched each file on its File node"""
+        if git_head is None:
+            return
+        file_nodes = [node for node in nodes.values() if node.node_type == "File" and node.file_path]
+        try:
+            commits = last_commits(git_head.root, [node.file_path for node in file_nodes])
+        except GitError as e:
+            logger.warning(f"Cannot read the last commits of indexed files: {e}")
+            return
+        for node in file_nodes:
+            if node.file_path in commits:
+                node.properties.update(commits[node.file_path])
+    
+    def _create_search_indexes(self) -> None:
+        """Create the vector and full-text search indexes"""
         # Create vector index (for similarity search)
         logger.info("Creating vector indexes...")
         try:
@@ -232,22 +1225,31 @@ class CodebaseKnowledgeGraph:
             )
         except Exception as e:
             logger.error(f"Error creating full-text search index: {e}")
-        
-        elapsed_time = time.time() - start_time
-        logger.info(f"Codebase processing complete! Time taken: {elapsed_time:.2f} seconds (Parallel mode: {use_parallel})")
-        return len(nodes), len(relations)
     
     def _collect_source_files(self, directory_path: str) -> List[str]:
         """Collect all source code files in the directory (supports 7 languages)
         
+        Paths matched by .gitignore files or exclude patterns, files outside
+        include_only and symlinked directories (unless followed) are skipped.
+        
         Args:
             directory_path: Directory path
             
         Returns:
             List of source code file paths
         """
-        source_files = []
+        walker = self.create_source_walker()
+        source_files = walker.walk(directory_path)
+        self.last_walk_stats = walker.stats
+        
+        return source_files
+    
+    def create_source_walker(self) -> SourceFileWalker:
+        """Create the directory walker used for indexing (extensions and walk options of this instance)
         
+        Returns:
+            SourceFileWalker, also used by the watcher to filter file events
+        """
         # When USE_AST_GREP is enabled, collect files based on AST_GREP_LANGUAGES
         if self.use_ast_grep:
             supported_extensions = []
@@ -263,6 +1265,12 @@ class CodebaseKnowledgeGraph:
                 supported_extensions.append('.rs')
             if 'go' in self.ast_grep_languages:
                 supported_extensions.append('.go')
+            if 'csharp' in self.ast_grep_languages:
+                supported_extensions.append('.cs')
+            if 'ruby' in self.ast_grep_languages:
+                supported_extensions.append('.rb')
+            if 'kotlin' in self.ast_grep_languages:
+                supported_extensions.extend(['.kt', '.kts'])
             supported_extensions = tuple(supported_extensions)
             
             logger.info(f"ast-grep mode enabled languages: {', '.join(self.ast_grep_languages)}")
@@ -277,14 +1285,16 @@ class CodebaseKnowledgeGraph:
                 logger.info("Multi-language support enabled: Python, JavaScript, TypeScript")
             else:
                 logger.info("Only Python support enabled")
+        # Proto files and the languages of the fallback patterns are indexed in both modes
+        supported_extensions += PROTO_EXTENSIONS + fallback_extensions()
         
-        for root, _, files in os.walk(directory_path):
-            for file_name in files:
-                if file_name.endswith(supported_extensions):
-                    file_path = os.path.join(root, file_name)
-                    source_files.append(file_path)
-        
-        return source_files
+        return SourceFileWalker(
+            supported_extensions,
+            exclude=self.exclude,
+            include_only=self.include_only,
+            respect_gitignore=self.respect_gitignore,
+            follow_symlinks=self.follow_symlinks
+        )
     
     def _get_parser_for_file(self, file_path: str):
         """Select the appropriate parser based on file extension
@@ -293,34 +1303,16 @@ class CodebaseKnowledgeGraph:
             file_path: File path
             
         Returns:
-            Parser instance (ASTParser, TypeScriptParser, or MultiLanguageParser), None if unsupported
+            Parser instance (ASTParser, TypeScriptAdapter, TypeScriptParser, or MultiLanguageParser), None if unsupported
         """
-        # When USE_AST_GREP is enabled, use MultiLanguageParser coordinator
-        if self.use_ast_grep:
-            return MultiLanguageParser(
-                use_ast_grep=True,
-                ast_grep_languages=self.ast_grep_languages,
-                ast_grep_fallback=self.ast_grep_fallback
-            )
-        
-        # Otherwise, use legacy routing
-        ext = os.path.splitext(file_path)[1].lower()
-        
-        if ext == '.py':
-            from src.ast_parser.parser import ASTParser
-            return ASTParser()
-        elif ext in ['.js', '.ts', '.jsx', '.tsx']:
-            from src.ast_parser.typescript_parser import TypeScriptParser
-            return TypeScriptParser()
-        else:
-            logger.warning(f"Unsupported file extension: {ext} ({file_path})")
-            return None
+        return create_parser(file_path, self.parser_settings)
     
-    def _process_directory_with_routing(self, directory_path: str) -> Tuple[Dict[str, Any], List[Any]]:
+    def _process_directory_with_routing(self, directory_path: str, source_files: Optional[List[str]] = None) -> Tuple[Dict[str, Any], List[Any]]:
         """Process directory with parser routing (sequential mode)
         
         Args:
             directory_path: Directory path
+            source_files: Files to parse, if None, collect them from directory_path
             
         Returns:
             Node dictionary and relationship list
@@ -330,46 +1322,69 @@ class CodebaseKnowledgeGraph:
             coordinator = MultiLanguageParser(
                 use_ast_grep=True,
                 ast_grep_languages=self.ast_grep_languages,
-                ast_grep_fallback=self.ast_grep_fallback
+                ast_grep_fallback=self.ast_grep_fallback,
+                max_file_bytes=self.max_file_bytes,
+                parse_timeout=self.parse_timeout
             )
-            return coordinator.parse_directory(directory_path, build_index=True)
+            nodes, relations = coordinator.parse_directory(directory_path, build_index=True, source_files=source_files,
+                                                           on_file=self._on_parse_file)
+            self.last_index = (coordinator.module_definitions, coordinator.module_to_file)
+            return nodes, relations
         
         # Legacy routing (USE_AST_GREP=false)
         all_nodes = {}
+        all_relations = []
         all_module_definitions = {}
         all_pending_imports = []
         all_module_to_file = {}
         
         # Collect all source files
-        source_files = self._collect_source_files(directory_path)
+        if source_files is None:
+            source_files = self._collect_source_files(directory_path)
         
         # First pass: Parse all files and build index
         for file_path in source_files:
+            self.progress.check_cancelled()
+            self.progress.advance(current_file=file_path)
             try:
                 parser = self._get_parser_for_file(file_path)
                 if parser:
-                    nodes, _ = parser.parse_file(file_path, build_index=True)
+                    nodes, relations = parse_with_limits(parser, file_path, True, self.max_file_bytes, self.parse_timeout)
                     
                     # Merge results
                     all_nodes.update(nodes)
-                    all_module_definitions.update(parser.module_definitions)
+                    all_relations.extend(relations)
+                    for module_name, symbols in parser.module_definitions.items():
+                        all_module_definitions.setdefault(module_name, {}).update(symbols)
                     all_pending_imports.extend(parser.pending_imports)
                     all_module_to_file.update(parser.module_to_file)
             except Exception as e:
                 logger.error(f"Error parsing file {file_path}: {e}")
+                self.progress.add_error(f"{file_path}: {e}")
         
         # Second pass: Process pending imports
         # Create a temporary parser to process imports
+        self.progress.set_phase("resolving")
         from src.ast_parser.parser import ASTParser
         temp_parser = ASTParser()
         temp_parser.nodes = all_nodes
+        temp_parser.relations = all_relations
+        temp_parser.established_relations = {
+            f"{r.source_id}|{r.relation_type}|{r.target_id}" for r in all_relations
+        }
         temp_parser.module_definitions = all_module_definitions
         temp_parser.pending_imports = all_pending_imports
         temp_parser.module_to_file = all_module_to_file
         temp_parser._process_pending_imports()
         
+        self.last_index = (all_module_definitions, all_module_to_file)
         return all_nodes, temp_parser.relations
     
+    def _on_parse_file(self, file_path: str) -> None:
+        """Progress and cancellation check before each file of a sequential parse"""
+        self.progress.check_cancelled()
+        self.progress.advance(current_file=file_path)
+    
     def _process_files_parallel(self, source_files: List[str], codebase_path: str) -> Tuple[Dict[str, Any], List[Any]]:
         """Process source files using parallel processing mode (multi-language support)
         
@@ -380,9 +1395,6 @@ class CodebaseKnowledgeGraph:
         Returns:
             Node dictionary and relationship list
         """
-        from src.ast_parser.parser import ASTParser
-        from src.ast_parser.typescript_parser import TypeScriptParser
-        
         try:
             # First pass: Parse all files in parallel to build module definition index
             logger.info("First pass: Parsing all files in parallel...")
@@ -390,86 +1402,45 @@ class CodebaseKnowledgeGraph:
             # Create a shared parser for collecting results
             # Each worker will create its own parser instance
             all_nodes = {}
+            all_relations = []
             all_module_definitions = {}
             all_pending_imports = []
             all_module_to_file = {}
             
-            def parse_file_worker(file_path: str) -> Tuple[Dict, Dict, List, Dict]:
-                """Worker function to parse a single file
-                
-                Args:
-                    file_path: Path to the source file
-                    
-                Returns:
-                    Tuple of (nodes, module_definitions, pending_imports, module_to_file)
-                """
-                try:
-                    # When USE_AST_GREP is enabled, use MultiLanguageParser
-                    if self.use_ast_grep:
-                        parser = MultiLanguageParser(
-                            use_ast_grep=True,
-                            ast_grep_languages=self.ast_grep_languages,
-                            ast_grep_fallback=self.ast_grep_fallback
-                        )
-                    else:
-                        # Legacy routing: Select parser based on file extension
-                        ext = os.path.splitext(file_path)[1].lower()
-                        
-                        if ext == '.py':
-                            parser = ASTParser()
-                        elif ext in ['.js', '.ts', '.jsx', '.tsx']:
-                            parser = TypeScriptParser()
-                        else:
-                            logger.warning(f"Unsupported file extension: {ext} ({file_path})")
-                            return ({}, {}, [], {})
-                    
-                    parser.parse_file(file_path, build_index=True)
-                    
-                    return (
-                        dict(parser.nodes),
-                        dict(parser.module_definitions),
-                        list(parser.pending_imports),
-                        dict(parser.module_to_file)
-                    )
-                except Exception as e:
-                    logger.error(f"Error parsing file {file_path}: {e}")
-                    return ({}, {}, [], {})
-            
-            # Use the processing pool manager to process files in parallel
-            with get_processing_pool() as pool:
-                # Submit all file parsing tasks
-                futures = [pool.submit(parse_file_worker, file_path) for file_path in source_files]
-                
-                # Filter out None values (though submit() should always return a Future)
-                futures = [f for f in futures if f is not None]
-                
-                # Collect results as they complete
+            # Files flow through a bounded window of worker tasks and come back in
+            # input order, so the merged index does not depend on worker scheduling
+            with get_processing_pool(max_workers=self.max_workers) as pool:
                 completed = 0
-                for future in as_completed(futures):
-                    try:
-                        nodes, module_defs, pending, module_files = future.result()
-                        
-                        # Merge results
-                        all_nodes.update(nodes)
-                        all_module_definitions.update(module_defs)
-                        all_pending_imports.extend(pending)
-                        all_module_to_file.update(module_files)
-                        
-                        completed += 1
-                        if completed % 10 == 0:
-                            logger.info(f"Completed {completed}/{len(source_files)} files")
-                    except Exception as e:
-                        logger.error(f"Error processing file result: {e}")
+                results = iter_parse_results(pool, source_files, self.parser_settings, max_in_flight=pool.max_workers * 2)
+                for file_path, (nodes, relations, module_defs, pending, module_files) in results:
+                    self.progress.check_cancelled()
+                    self.progress.advance(current_file=file_path)
+                    # Merge results
+                    all_nodes.update(nodes)
+                    all_relations.extend(relations)
+                    for module_name, symbols in module_defs.items():
+                        all_module_definitions.setdefault(module_name, {}).update(symbols)
+                    all_pending_imports.extend(pending)
+                    all_module_to_file.update(module_files)
+                    
+                    completed += 1
+                    if completed % 10 == 0:
+                        logger.info(f"Completed {completed}/{len(source_files)} files")
             
             logger.info(f"First pass complete: Parsed {len(all_nodes)} nodes")
             
             # Second pass: Process pending imports sequentially
             # This must be sequential because it requires the complete module definition index
             logger.info("Second pass: Processing pending imports...")
+            self.progress.set_phase("resolving")
             
             # Create a parser with the aggregated data to process imports
             final_parser = ASTParser()
             final_parser.nodes = all_nodes
+            final_parser.relations = all_relations
+            final_parser.established_relations = {
+                f"{r.source_id}|{r.relation_type}|{r.target_id}" for r in all_relations
+            }
             final_parser.module_definitions = all_module_definitions
             final_parser.pending_imports = all_pending_imports
             final_parser.module_to_file = all_module_to_file
@@ -479,8 +1450,11 @@ class CodebaseKnowledgeGraph:
             
             logger.info(f"Second pass complete: Processed {len(final_parser.relations)} relationships")
             
+            self.last_index = (all_module_definitions, all_module_to_file)
             return final_parser.nodes, final_parser.relations
             
+        except IndexCancelled:
+            raise
         except Exception as e:
             # Graceful degradation: Fall back to sequential processing
             logger.error(f"Parallel processing failed: {e}")
@@ -491,71 +1465,74 @@ class CodebaseKnowledgeGraph:
             logger.debug(f"Parallel processing error details:\n{traceback.format_exc()}")
             
             # Use sequential processing with routing
-            return self._process_directory_with_routing(codebase_path)
+            self.progress.set_phase("parsing", total=len(source_files))
+            return self._process_directory_with_routing(codebase_path, source_files)
     
-    def _generate_embeddings(self, nodes: Dict[str, Any]) -> None:
+    def _parse_single_file(self, file_path: str) -> Tuple[Dict, List, Dict, List, Dict]:
+        """Parse a single file for the first pass
+        
+        Args:
+            file_path: Path to the source file
+        
+        Returns:
+            Tuple of (nodes, relations, module_definitions, pending_imports, module_to_file)
+        """
+        return parse_source_file(file_path, self.parser_settings)
+    
+    def _generate_embeddings(self, nodes: Dict[str, Any], reusable: Optional[Dict[str, List[float]]] = None) -> None:
         """Generate embedding vectors for nodes
         
+        Each node is embedded from its signature, doc and truncated body
+        (see src.embeddings.node_text) and gets an embedding_key. Nodes whose key
+        matches a stored vector reuse it instead of calling the provider, so
+        incremental runs only embed nodes whose text changed.
+        
         Args:
             nodes: Node dictionary
+            reusable: Stored vectors by embedding_key (incremental runs)
         """
-        batch_size = 20  # Batch size to avoid API limits
-        
-        # Only generate embeddings for specific node types
-        target_types = ["Function", "Method", "Class", "File"]
-        
-        nodes_to_embed = [
-            (node_id, node) for node_id, node in nodes.items()
-            if node.node_type in target_types
-        ]
-        
-        for i in range(0, len(nodes_to_embed), batch_size):
-            batch = nodes_to_embed[i:i+batch_size]
-            
-            # Reset batch data
-            code_texts = []
-            node_types = []
-            names = []
-            node_ids = []
-            
-            # Collect batch data
-            for node_id, node in batch:
-                # For file types, use the file name as the code text
-                if node.node_type == "File":
-                    code_text = f"File: {node.name}"
-                else:
-                    code_text = node.code_snippet or f"{node.node_type}: {node.name}"
-                
-                code_texts.append(code_text)
-                node_types.append(node.node_type)
-                names.append(node.name)
-                node_ids.append(node_id)
-            
-            # Only generate embeddings if there is data to process
-            if code_texts:
-                # Batch generate embedding vectors
+        batch_size = get_embedding_batch_size()
+        reusable = reusable or {}
+        model = getattr(self.embedder, "model", type(self.embedder).__name__)
+        source_lines = SourceLines()
+        
+        pending = []
+        for node_id, node in nodes.items():
+            if node.node_type not in EMBEDDED_NODE_TYPES:
+                continue
+            text = build_node_text(node, source_lines)
+            key = embedding_key(text, node.node_type, node.name, model)
+            node.properties["embedding_key"] = key
+            if key in reusable:
+                node.properties["embedding"] = reusable[key]
+                self.last_embedding_stats["reused"] += 1
+            else:
+                pending.append((node, text))
+        
+        for i in range(0, len(pending), batch_size):
+            batch = pending[i:i + batch_size]
+            try:
+                # Retries with backoff happen inside the embedder
                 embeddings = self.code_embedder.embed_code_nodes_batch(
-                    code_texts=code_texts,
-                    node_types=node_types,
-                    names=names
+                    code_texts=[text for _, text in batch],
+                    node_types=[node.node_type for node, _ in batch],
+                    names=[node.name for node, _ in batch]
                 )
-                
-                # Ensure embedding vector matches node list length
-                if len(embeddings) >= len(node_ids):
-                    # Add embedding vector to node
-                    for i, node_id in enumerate(node_ids):
-                        nodes[node_id].properties["embedding"] = embeddings[i]
-                    
-                    logger.debug(f"Generated embeddings for {len(batch)} nodes")
+                if len(embeddings) < len(batch):
+                    logger.warning(f"Number of embeddings ({len(embeddings)}) is less than number of nodes ({len(batch)})")
+            except Exception as e:
+                logger.warning(f"Embedding {len(batch)} nodes failed, storing them without vectors: {e}")
+                embeddings = []
+            
+            for j, (node, _) in enumerate(batch):
+                if j < len(embeddings):
+                    node.properties["embedding"] = embeddings[j]
+                    self.last_embedding_stats["embedded"] += 1
                 else:
-                    # Handle cases where the number of embedding vectors is insufficient
-                    logger.warning(f"Number of embeddings ({len(embeddings)}) is less than number of nodes ({len(node_ids)}), using zero vectors as substitute")
-                    for i, node_id in enumerate(node_ids):
-                        if i < len(embeddings):
-                            nodes[node_id].properties["embedding"] = embeddings[i]
-                        else:
-                            # Use zero vectors as a substitute
-                            nodes[node_id].properties["embedding"] = [0.0] * getattr(self.embedder, 'dimension', 1536)
+                    # No key without a vector, the node is embedded again next time
+                    node.properties.pop("embedding_key", None)
+                    self.last_embedding_stats["failed"] += 1
+            logger.debug(f"Generated embeddings for {len(batch)} nodes")
     
     def _convert_nodes_to_neo4j_format(self, nodes: Dict[str, Any]) -> List[Dict[str, Any]]:
         """Convert nodes to Neo4j bulk import format
@@ -628,7 +1605,8 @@ class CodebaseKnowledgeGraph:
             # Ensure properties are Neo4j compatible
             processed_properties = {}
             
-            for key, value in relation.properties.items():
+            # Every edge records the pass and indexer version that created it (see src/ast_parser/provenance.py)
+            for key, value in with_provenance(relation.properties, INDEX_STATE_VERSION).items():
                 # Check property value type, ensure it's a Neo4j-supported primitive type or array thereof
                 if isinstance(value, (str, int, float, bool)) or (
                     isinstance(value, list) and all(isinstance(item, (int, float)) for item in value)
@@ -653,24 +1631,68 @@ class CodebaseKnowledgeGraph:
         return neo4j_relations
     
     def close(self) -> None:
-        """Close resources (database connections, etc.)"""
-        self.db.close()
+        """Close resources (database connections, etc.); a store passed in is only flushed"""
+        if self.owns_store:
+            self.db.close()
+        else:
+            self.db.flush()
 
 
 def main():
     """Main function"""
     parser = argparse.ArgumentParser(description="Codebase Knowledge Graph Creation Tool")
-    parser.add_argument("--codebase-path", required=True, help="Codebase path")
+    parser.add_argument("--codebase-path", help="Codebase path (required unless --migrate)")
     parser.add_argument("--clear-db", action="store_true", help="Clear database")
+    parser.add_argument("--incremental", action="store_true", help="Only re-index files that changed since the last run")
+    parser.add_argument("--changed-only", metavar="BASE_REF", help="Only re-index the files of `git diff BASE_REF..HEAD` (implies --incremental)")
+    parser.add_argument("--exclude", action="append", help="Extra gitignore-style pattern to skip (repeatable)")
+    parser.add_argument("--include-only", action="append", help="Only index files matching this pattern (repeatable), e.g. 'src/**'")
+    parser.add_argument("--follow-symlinks", action="store_true", default=None, help="Follow symlinked directories that point outside the codebase")
+    parser.add_argument("--workers", type=int, help="Number of parser workers (default: MAX_WORKERS or the CPU count)")
+    parser.add_argument("--write-batch-size", type=int, help="Nodes/relationships per write transaction (default: INDEX_WRITE_BATCH_SIZE or 1000)")
+    parser.add_argument("--store-source", choices=STORE_SOURCE_MODES, help="Store symbol source on graph nodes (default: INDEX_STORE_SOURCE or false)")
+    parser.add_argument("--source-max-bytes", type=int, help="Byte cap of a stored source (default: INDEX_SOURCE_MAX_BYTES or 8192)")
+    parser.add_argument("--max-file-bytes", type=int, help="Index larger files without symbols, 0 for no limit (default: INDEX_MAX_FILE_BYTES or 1000000)")
+    parser.add_argument("--parse-timeout", type=float, help="Seconds a file may take to parse, 0 for no limit (default: INDEX_PARSE_TIMEOUT or 60)")
+    parser.add_argument("--link-matchers", help="Comma-separated cross-language matchers, empty to disable (default: CROSS_LANG_MATCHERS or grpc,ffi)")
+    parser.add_argument("--ref", help="Index the codebase as of this git ref, checked out into a temporary worktree, as a snapshot repository (default name: snap-<ref>)")
+    parser.add_argument("--repo", help="Repository name to index the codebase as, so one store holds several (default: INDEX_REPO or default)")
+    parser.add_argument("--symbol-id-scheme", choices=SYMBOL_ID_SCHEMES, help="Add a signature hash to symbol IDs on collisions only, or to every function and method (default: SYMBOL_ID_SCHEME or qualified)")
+    parser.add_argument("--streaming", action="store_true", default=None, help="Run full indexes in memory-bounded batches, for very large repositories")
+    parser.add_argument("--memory-budget-mb", type=int, help="Megabytes of buffered references a streaming run holds before spilling to disk (default: INDEX_MEMORY_BUDGET_MB or 256)")
+    parser.add_argument("--storage", choices=STORAGE_BACKENDS, help="Storage backend (default: GRAPH_STORAGE or neo4j)")
+    parser.add_argument("--graph-file", help="JSON file the memory backend loads and saves (default: GRAPH_STORE_PATH)")
     parser.add_argument("--neo4j-uri", help="Neo4j database URI")
     parser.add_argument("--neo4j-user", help="Neo4j username")
     parser.add_argument("--neo4j-password", help="Neo4j password")
     parser.add_argument("--openai-api-key", help="OpenAI API key")
+    parser.add_argument("--migrate", action="store_true", help="Migrate the graph to the current schema version and exit")
+    parser.add_argument("--watch", action="store_true", help="Keep watching the codebase after indexing and sync changes incrementally")
     parser.add_argument("--start-mcp-server", action="store_true", help="Start MCP server after building knowledge graph")
-    parser.add_argument("--mcp-transport", choices=["stdio", "sse"], default="stdio", help="MCP transport protocol")
-    parser.add_argument("--mcp-port", type=int, default=8080, help="MCP server port number (only for SSE transport)")
+    parser.add_argument("--mcp-transport", choices=["stdio", "http", "sse"], default="stdio", help="MCP transport protocol")
+    parser.add_argument("--mcp-port", type=int, help="MCP server port number (only for HTTP/SSE transport, default: MCP_SERVER_PORT or 8080)")
+    parser.add_argument("--mcp-listen", help="Address the HTTP/SSE transport listens on, host:port or :port for every interface")
     
     args = parser.parse_args()
+    if not args.codebase_path and not args.migrate:
+        parser.error("--codebase-path is required")
+    if args.changed_only and args.clear_db:
+        parser.error("--changed-only cannot be combined with --clear-db")
+    if args.ref and (args.incremental or args.changed_only or args.watch or args.migrate):
+        parser.error("--ref always runs a full index, it cannot be combined with --incremental, --changed-only, --watch or --migrate")
+    repo = args.repo
+    if args.ref and not repo:
+        try:
+            repo = snapshot_repo_name(args.ref)
+        except ValueError as e:
+            parser.error(str(e))
+    mcp_host, mcp_port = None, args.mcp_port
+    if args.mcp_listen:
+        from src.mcp.transport import parse_listen
+        try:
+            mcp_host, mcp_port = parse_listen(args.mcp_listen)
+        except ValueError as e:
+            parser.error(str(e))
     # --- AST-grep integration feature flags ---
     use_ast_grep = os.getenv("USE_AST_GREP", "false").lower() == "true"
     ast_grep_languages = os.getenv("AST_GREP_LANGUAGES", "python,javascript,typescript").split(',')
@@ -682,15 +1704,44 @@ def main():
         neo4j_uri=args.neo4j_uri,
         neo4j_user=args.neo4j_user,
         neo4j_password=args.neo4j_password,
-        openai_api_key=args.openai_api_key
+        openai_api_key=args.openai_api_key,
+        exclude=args.exclude,
+        include_only=args.include_only,
+        follow_symlinks=args.follow_symlinks,
+        max_workers=args.workers,
+        write_batch_size=args.write_batch_size,
+        storage=args.storage,
+        graph_file=args.graph_file,
+        store_source=args.store_source,
+        source_max_bytes=args.source_max_bytes,
+        link_matchers=args.link_matchers,
+        max_file_bytes=args.max_file_bytes,
+        parse_timeout=args.parse_timeout,
+        repo=repo,
+        symbol_id_scheme=args.symbol_id_scheme,
+        streaming=args.streaming,
+        memory_budget_mb=args.memory_budget_mb
     )
     
     try:
+        if args.migrate:
+            applied = migrate_graph(kg.db)
+            logger.info(f"Graph schema is up to date, migrations run: {applied or 'none'}")
+            return
+        
         # Process codebase
-        num_nodes, num_relations = kg.process_codebase(
-            codebase_path=args.codebase_path,
-            clear_db=args.clear_db
-        )
+        if args.ref:
+            try:
+                num_nodes, num_relations = kg.process_snapshot(args.codebase_path, args.ref)
+            except GitError as e:
+                parser.error(f"--ref {args.ref}: {e}")
+        else:
+            num_nodes, num_relations = kg.process_codebase(
+                codebase_path=args.codebase_path,
+                clear_db=args.clear_db,
+                incremental=args.incremental,
+                changed_since=args.changed_only
+            )
         
         logger.info(f"Successfully processed codebase, imported {num_nodes} nodes and {num_relations} relationships")
         
@@ -701,15 +1752,32 @@ def main():
             # Import MCP server module
             from src.mcp.server import CodebaseKnowledgeGraphMCP
             
-            # Create and start MCP server
+            # Create and start MCP server (it runs the watcher itself, so get_watch_status can report it);
+            # it serves the store just written, which an in-memory graph needs
             server = CodebaseKnowledgeGraphMCP(
                 neo4j_uri=args.neo4j_uri,
                 neo4j_user=args.neo4j_user,
                 neo4j_password=args.neo4j_password,
-                openai_api_key=args.openai_api_key
+                server_host=mcp_host,
+                server_port=mcp_port,
+                codebase_path=args.codebase_path,
+                watch=args.watch,
+                store=kg.db,
+                repo=kg.repo
             )
             
-            server.start(port=args.mcp_port, transport=args.mcp_transport)
+            server.start(port=mcp_port, transport=args.mcp_transport)
+        elif args.watch:
+            watcher = watch_codebase(kg, args.codebase_path)
+            watcher.start()
+            logger.info("Watching for changes, press Ctrl+C to stop")
+            try:
+                while True:
+                    time.sleep(1)
+            except KeyboardInterrupt:
+                pass
+            finally:
+                watcher.stop()
     finally:
         # Close resources
         kg.close()
@@ -0,0 +1,302 @@
+"""
+Helpers for the get_call_hierarchy MCP tool.
+
+Builds a nested tree of the callers or callees of a function or method
+from CALLS edges, one GraphStore query per level. A call that leads back
+to a function already on the path from the root is marked with "cycle"
+and not expanded again, and a node with more calls than max_children
+keeps the first ones and is marked "truncated".
+
+Calls through a Go interface value point at the interface node with the
+called method in the edge's "method" property. They are expanded to the
+methods of every type implementing the interface and flagged
+"via_interface", since the concrete callee is not known statically; in
+the callers direction a method's callers include the calls through the
+interfaces its type implements.
+
+Every call carries the provenance of its edge (see
+src/ast_parser/provenance.py); a call through an interface carries the
+weaker of the CALLS edge and the IMPLEMENTS edge, which a name and
+signature match put there. min_confidence leaves out the edges below it.
+"""
+
+from typing import Any, Dict, List, Optional, Set, Tuple
+
+from src.ast_parser.provenance import (
+    CONFIDENCE_PROPERTY,
+    check_min_confidence,
+    edge_confidence,
+    edge_provenance,
+)
+from src.mcp.references import find_symbol_candidates, qualified_name, symbol_candidates
+
+# Hierarchy direction -> GraphStore direction of the CALLS edges
+DIRECTIONS = {
+    "callers": "in",
+    "callees": "out",
+}
+
+DEFAULT_MAX_DEPTH = 3
+MAX_DEPTH_LIMIT = 10
+DEFAULT_MAX_CHILDREN = 50
+
+
+def store_direction(direction: str, max_depth: int, max_children: int) -> str:
+    """
+    Validate the traversal options and return the GraphStore direction.
+    
+    Raises:
+        ValueError: for an unknown direction, a depth outside 1..MAX_DEPTH_LIMIT or max_children below 1
+    """
+    if direction not in DIRECTIONS:
+        raise ValueError(f"Unknown direction '{direction}', expected one of: {', '.join(DIRECTIONS)}")
+    if not 1 <= max_depth <= MAX_DEPTH_LIMIT:
+        raise ValueError(f"max_depth must be between 1 and {MAX_DEPTH_LIMIT}")
+    if max_children < 1:
+        raise ValueError("max_children must be at least 1")
+    return DIRECTIONS[direction]
+
+
+def symbol_summary(candidate: Dict[str, Any]) -> Dict[str, Any]:
+    return {
+        "id": candidate["id"],
+        "symbol_id": candidate.get("symbol_id"),
+        "name": candidate.get("name"),
+        "qualified_name": qualified_name(candidate),
+        "node_type": candidate.get("node_type"),
+        "file_path": candidate.get("file_path"),
+        "line_no": candidate.get("line_no"),
+    }
+
+
+def _call_lines(relationship: Dict[str, Any]) -> List[int]:
+    properties = relationship["properties"]
+    if properties.get("call_lines"):
+        return list(properties["call_lines"])
+    return [properties["line_no"]] if properties.get("line_no") else []
+
+
+def _node_id(record: Dict[str, Any]) -> str:
+    return record["properties"]["id"]
+
+
+def _implementations(db, dispatched: Set[Tuple[str, str]],
+                     min_confidence: float = 0.0) -> Dict[Tuple[str, str], List[Tuple[Dict[str, Any], Dict[str, Any]]]]:
+    """(interface ID, method name) -> (method record, IMPLEMENTS edge) of the types implementing the interface."""
+    interface_ids = list(dict.fromkeys(interface_id for interface_id, _ in dispatched))
+    if not interface_ids:
+        return {}
+    implementers: Dict[str, List[Tuple[str, Dict[str, Any]]]] = {}
+    for row in db.neighbors(interface_ids, ["IMPLEMENTS"], direction="in"):
+        if edge_confidence(row["relationship"]["properties"]) >= min_confidence:
+            implementers.setdefault(row["origin_id"], []).append((_node_id(row["node"]), row["relationship"]))
+    
+    type_ids = list(dict.fromkeys(type_id for edges in implementers.values() for type_id, _ in edges))
+    methods: Dict[Tuple[str, str], Dict[str, Any]] = {}
+    for row in db.neighbors(type_ids, ["DEFINES"], direction="out", label="Method"):
+        methods.setdefault((row["origin_id"], row["node"]["properties"].get("name")), row["node"])
+    
+    return {
+        (interface_id, method): [
+            (methods[(type_id, method)], implements) for type_id, implements in implementers.get(interface_id, [])
+            if (type_id, method) in methods
+        ]
+        for interface_id, method in dispatched
+    }
+
+
+def _interface_callers(db, method_records: List[Dict[str, Any]],
+                       min_confidence: float = 0.0) -> Dict[str, List[Dict[str, Any]]]:
+    """
+    Calls through an interface that may reach each method.
+    
+    Follows method -> owning type -> implemented interfaces -> CALLS edges
+    whose "method" is the method's name. Returns method ID -> calls with
+    the caller record, the edge, the interface record and the IMPLEMENTS
+    edge.
+    """
+    if not method_records:
+        return {}
+    names = {_node_id(record): record["properties"].get("name") for record in method_records}
+    owners: Dict[str, List[str]] = {}
+    for row in db.neighbors(list(names), ["DEFINES"], direction="in"):
+        owners.setdefault(_node_id(row["node"]), []).append(row["origin_id"])
+    interfaces: Dict[str, Dict[str, Any]] = {}
+    methods_of_interface: Dict[str, Dict[str, Dict[str, Any]]] = {}
+    for row in db.neighbors(list(owners), ["IMPLEMENTS"], direction="out", label="Interface"):
+        if edge_confidence(row["relationship"]["properties"]) < min_confidence:
+            continue
+        interface_id = _node_id(row["node"])
+        interfaces[interface_id] = row["node"]
+        for method_id in owners[row["origin_id"]]:
+            methods_of_interface.setdefault(interface_id, {}).setdefault(method_id, row["relationship"])
+    
+    callers: Dict[str, List[Dict[str, Any]]] = {}
+    for row in db.neighbors(list(interfaces), ["CALLS"], direction="in"):
+        method = row["relationship"]["properties"].get("method")
+        if edge_confidence(row["relationship"]["properties"]) < min_confidence:
+            continue
+        for method_id, implements in methods_of_interface[row["origin_id"]].items():
+            if names[method_id] == method:
+                callers.setdefault(method_id, []).append({
+                    "node": row["node"],
+                    "relationship": row["relationship"],
+                    "interface": interfaces[row["origin_id"]],
+                    "implements": implements,
+                })
+    return callers
+
+
+def call_provenance(call: Dict[str, Any]) -> Dict[str, Any]:
+    """Provenance of a find_calls call, the IMPLEMENTS edge's when it is weaker than the CALLS edge."""
+    provenance = edge_provenance(call["relationship"])
+    if call.get("implements") is not None:
+        implements = edge_provenance(call["implements"])
+        if implements[CONFIDENCE_PROPERTY] < provenance[CONFIDENCE_PROPERTY]:
+            return implements
+    return provenance
+
+
+def find_calls(db, records: List[Dict[str, Any]], traversal: str,
+               min_confidence: float = 0.0) -> Dict[str, List[Dict[str, Any]]]:
+    """
+    Calls of each node in one direction, with interface dispatch expanded.
+    
+    Returns node ID -> [{"node", "relationship", "interface", "implements",
+    "method"}]; "interface" and "implements" (the IMPLEMENTS edge of the
+    implementing type) are set for calls through an interface and "method"
+    for an interface method without any known implementation. Edges with a
+    confidence below min_confidence are not followed.
+    """
+    calls: Dict[str, List[Dict[str, Any]]] = {}
+    dispatched = []
+    for row in db.neighbors([_node_id(record) for record in records], ["CALLS"], direction=traversal):
+        if edge_confidence(row["relationship"]["properties"]) < min_confidence:
+            continue
+        method = row["relationship"]["properties"].get("method")
+        if traversal == "out" and method and "Interface" in row["node"]["labels"]:
+            dispatched.append((row, method))
+            continue
+        calls.setdefault(row["origin_id"], []).append({"node": row["node"], "relationship": row["relationship"]})
+    
+    if traversal == "out":
+        implementations = _implementations(db, {(_node_id(row["node"]), method) for row, method in dispatched},
+                                           min_confidence)
+        for row, method in dispatched:
+            implementers = implementations[(_node_id(row["node"]), method)]
+            for implementer, implements in implementers:
+                calls.setdefault(row["origin_id"], []).append({"node": implementer, "relationship": row["relationship"],
+                                                               "interface": row["node"], "implements": implements})
+            if not implementers:
+                calls.setdefault(row["origin_id"], []).append(
+                    {"node": row["node"], "relationship": row["relationship"], "interface": row["node"], "method": method}
+                )
+    else:
+        methods = [record for record in records if "Method" in record["labels"]]
+        for method_id, interface_calls in _interface_callers(db, methods, min_confidence).items():
+            calls.setdefault(method_id, []).extend(interface_calls)
+    return calls
+
+
+def _children(calls: List[Dict[str, Any]], candidates: Dict[str, Dict[str, Any]]) -> List[Dict[str, Any]]:
+    """Tree nodes of one node's calls, one per called or calling symbol, ordered by location."""
+    children: Dict[Tuple[str, Optional[str]], Dict[str, Any]] = {}
+    for call in calls:
+        node_id = _node_id(call["node"])
+        key = (node_id, call.get("method"))
+        child = children.get(key)
+        if child is None:
+            child = children[key] = dict(symbol_summary(candidates[node_id]), call_lines=[])
+            if call.get("method"):
+                child["method"] = child["name"] = call["method"]
+                child["qualified_name"] = f"{child['qualified_name']}.{call['method']}"
+            if call.get("interface") is not None:
+                child["via_interface"] = True
+                child["interface"] = qualified_name(candidates[_node_id(call["interface"])])
+        elif call.get("interface") is None:
+            # A direct call is not an over-approximation
+            child.pop("via_interface", None)
+            child.pop("interface", None)
+        provenance = call_provenance(call)
+        if provenance[CONFIDENCE_PROPERTY] > child.get(CONFIDENCE_PROPERTY, -1.0):
+            child.update(provenance)
+        child["call_lines"] = sorted(set(child["call_lines"]) | set(_call_lines(call["relationship"])))
+    return sorted(children.values(), key=lambda child: (child["file_path"] or "", child["line_no"] or 0,
+                                                        child["name"] or "", child["id"]))
+
+
+def call_hierarchy(db, symbol: str, direction: str = "callers", max_depth: int = DEFAULT_MAX_DEPTH,
+                   max_children: int = DEFAULT_MAX_CHILDREN, min_confidence: float = 0.0) -> Dict[str, Any]:
+    """
+    Tree of the callers or callees of a function or method.
+    
+    Every tree node has the symbol's id, name, qualified name, type and
+    location; below the root, "call_lines" are the lines of the calls in
+    the calling function's file and source, confidence and indexer_version
+    the provenance of the most confident of them. Nodes that were expanded have "children";
+    nodes at max_depth and nodes marked "cycle" have none.
+    
+    Args:
+        db: GraphStore backend
+        symbol: Function or method (name, qualified name or node id)
+        direction: "callers" (who calls the symbol) or "callees" (what the symbol calls)
+        max_depth: Number of levels below the root
+        max_children: Maximum number of children per node; extra ones are cut and the node marked "truncated"
+        min_confidence: Edges with a lower confidence are not followed (1.0: exact facts only)
+    
+    Returns:
+        Structured result with status "ok", "ambiguous" or "not_found"
+    """
+    traversal = store_direction(direction, int(max_depth), int(max_children))
+    max_depth, max_children = int(max_depth), int(max_children)
+    min_confidence = check_min_confidence(min_confidence)
+    
+    candidates = find_symbol_candidates(db, symbol)
+    if not candidates:
+        return {"status": "not_found", "symbol": symbol, "message": f"No symbol matches '{symbol}'"}
+    if len(candidates) > 1:
+        return {"status": "ambiguous", "symbol": symbol,
+                "message": f"Several symbols match '{symbol}'; call again with a symbol_id, qualified_name or id",
+                "candidates": [symbol_summary(c) for c in candidates]}
+    
+    root = symbol_summary(candidates[0])
+    records = {root["id"]: db.get_nodes([root["id"]])[0]}
+    frontier: List[Tuple[Dict[str, Any], frozenset]] = [(root, frozenset([root["id"]]))]
+    for _ in range(max_depth):
+        if not frontier:
+            break
+        calls = find_calls(db, [records[node["id"]] for node, _ in frontier], traversal, min_confidence)
+        
+        # Owners for the qualified names, every record looked up once
+        found = {}
+        for node_calls in calls.values():
+            for call in node_calls:
+                for record in (call["node"], call.get("interface")):
+                    if record is not None:
+                        found.setdefault(_node_id(record), record)
+        records.update(found)
+        summaries = {candidate["id"]: candidate for candidate in symbol_candidates(db, list(found.values()))}
+        
+        next_frontier = []
+        for node, ancestors in frontier:
+            children = _children(calls.get(node["id"], []), summaries)
+            if len(children) > max_children:
+                node["truncated"] = True
+                node["total_children"] = len(children)
+                children = children[:max_children]
+            node["children"] = children
+            for child in children:
+                if child["id"] in ancestors:
+                    child["cycle"] = True
+                elif "method" not in child:
+                    next_frontier.append((child, ancestors | {child["id"]}))
+        frontier = next_frontier
+    
+    return {
+        "status": "ok",
+        "direction": direction,
+        "max_depth": max_depth,
+        "max_children": max_children,
+        "min_confidence": min_confidence,
+        "tree": root,
+    }
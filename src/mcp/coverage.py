@@ -0,0 +1,335 @@
+"""
+Helpers for the get_tests_for and get_untested MCP tools.
+
+A test covers a symbol when it reaches it within max_depth call hops:
+directly through a TESTS edge (distance 1, see src/indexing/testcode.py),
+or through the production code it calls, walking CALLS edges breadth-first
+with interface dispatch expanded (see call_hierarchy.find_calls). This
+COVERED_BY relationship is computed at query time from the stored graph
+rather than materialized, so it follows max_depth and never goes stale.
+
+Coverage that may not exercise the real code is kept with confidence
+"low" and the reason:
+- "interface": a hop of the path is a call through an interface value,
+  which may dispatch to another implementation
+- "mocked": the test replaces a symbol of the path with @patch
+- "import": a test file imports the closure but no test node calls into
+  it (JavaScript indexed without Jest test nodes)
+Each test is counted at its shortest distance and with its best path, a
+high-confidence one when it has any.
+"""
+
+import os
+from typing import Any, Dict, List, Optional, Set, Tuple
+
+from src.analysis.cycles import package_label
+from src.analysis.unreferenced import is_exported, is_generated_file
+from src.ast_parser.language_detector import detect_language
+from src.export.graph_export import scope_prefixes
+from src.indexing.testcode import CONFIDENCE_HIGH, CONFIDENCE_LOW, is_test_file
+from src.mcp.call_hierarchy import DEFAULT_MAX_DEPTH, MAX_DEPTH_LIMIT, find_calls, symbol_summary
+from src.mcp.impact import IMPORT_RELATIONS, _file_id
+from src.mcp.references import find_symbol_candidates, symbol_candidates
+
+DEFAULT_MAX_RESULTS = 100
+
+TESTED_LABELS = ("Function", "Method")
+
+
+def _node_id(record: Dict[str, Any]) -> str:
+    return record["properties"]["id"]
+
+
+def _check_limits(max_depth: int, max_results: Optional[int] = None) -> None:
+    if not 1 <= max_depth <= MAX_DEPTH_LIMIT:
+        raise ValueError(f"max_depth must be between 1 and {MAX_DEPTH_LIMIT}")
+    if max_results is not None and max_results < 1:
+        raise ValueError("max_results must be at least 1")
+
+
+def _test_edges(db, test_ids: List[str]) -> Dict[Tuple[str, str], Dict[str, Any]]:
+    """(test ID, target ID) -> properties of the TESTS edges of the tests."""
+    edges = {}
+    for row in db.neighbors(test_ids, ["TESTS"], direction="out"):
+        edges[(row["origin_id"], _node_id(row["node"]))] = row["relationship"]["properties"]
+    return edges
+
+
+def _stronger(reason: Optional[str], best: Optional[str]) -> bool:
+    """Whether a path with this low-confidence reason (None: high) beats the best one so far."""
+    return best is not None and reason is None
+
+
+def get_tests_for(db, symbol: str, max_depth: int = DEFAULT_MAX_DEPTH,
+                  max_results: int = DEFAULT_MAX_RESULTS) -> Dict[str, Any]:
+    """
+    Tests covering a function or method within max_depth call hops.
+    
+    Every test has the symbol's id, name, qualified name, type and
+    location, its distance, confidence ("high" or "low", with a reason)
+    and "through": the symbol it calls directly when that is not the
+    symbol itself. Parametrized tests (pytest parametrize, Jest .each) are
+    flagged.
+    
+    Args:
+        db: GraphStore backend
+        symbol: Function or method (name, qualified name or node id)
+        max_depth: Number of call hops between a test and the symbol
+        max_results: Maximum number of tests listed; the closest, most confident ones are kept
+    
+    Returns:
+        Structured result with status "ok", "ambiguous" or "not_found"
+    
+    Raises:
+        ValueError: for a depth outside 1..MAX_DEPTH_LIMIT or max_results below 1
+    """
+    max_depth, max_results = int(max_depth), int(max_results)
+    _check_limits(max_depth, max_results)
+    
+    candidates = find_symbol_candidates(db, symbol)
+    if not candidates:
+        return {"status": "not_found", "symbol": symbol, "message": f"No symbol matches '{symbol}'"}
+    if len(candidates) > 1:
+        return {"status": "ambiguous", "symbol": symbol,
+                "message": f"Several symbols match '{symbol}'; call again with a symbol_id, qualified_name or id",
+                "candidates": [symbol_summary(c) for c in candidates]}
+    
+    root = symbol_summary(candidates[0])
+    root_record = db.get_nodes([root["id"]])[0]
+    records = {root["id"]: root_record}
+    # Node ID -> (distance, low-confidence reason or None, node it calls toward the symbol)
+    reached: Dict[str, Tuple[int, Optional[str], str]] = {root["id"]: (0, None, root["id"])}
+    frontier = [root_record]
+    for distance in range(1, max_depth + 1):
+        found: Dict[str, Dict[str, Any]] = {}
+        for callee_id, node_calls in find_calls(db, frontier, "in").items():
+            callee_reason = reached[callee_id][1]
+            for call in node_calls:
+                node_id = _node_id(call["node"])
+                reason = callee_reason or ("interface" if call.get("interface") is not None else None)
+                if node_id in reached and not (reached[node_id][0] == distance
+                                               and _stronger(reason, reached[node_id][1])):
+                    continue
+                reached[node_id] = (distance, reason, callee_id)
+                # Tests end the walk: what calls a test is not covered by it
+                if not call["node"]["properties"].get("is_test"):
+                    found[node_id] = call["node"]
+                records[node_id] = call["node"]
+        if not found:
+            break
+        frontier = list(found.values())
+    
+    test_ids = [node_id for node_id, record in records.items()
+                if node_id != root["id"] and record["properties"].get("is_test")]
+    edges = _test_edges(db, test_ids)
+    tests: Dict[str, Dict[str, Any]] = {}
+    for test_id in test_ids:
+        distance, reason, callee_id = reached[test_id]
+        edge = edges.get((test_id, callee_id)) or {}
+        # Only low-confidence TESTS edges have a reason
+        if not reason:
+            reason = edge.get("reason")
+        tests[test_id] = {"distance": distance, "reason": reason, "through": callee_id}
+    
+    # Test files importing a node of the closure, when none of their test nodes call into it
+    targets: Dict[str, int] = {}
+    for node_id, (distance, _, _) in reached.items():
+        if node_id in tests:
+            continue
+        file_path = records[node_id]["properties"].get("file_path")
+        targets.setdefault(node_id, distance)
+        if file_path:
+            file_id = _file_id(node_id, file_path)
+            targets[file_id] = min(targets.get(file_id, distance), distance)
+    tested_files = {records[test_id]["properties"].get("file_path") for test_id in tests}
+    for row in db.neighbors(list(targets), IMPORT_RELATIONS, direction="in", label="File"):
+        properties = row["node"]["properties"]
+        if not properties.get("is_test") or properties.get("file_path") in tested_files:
+            continue
+        file_id = _node_id(row["node"])
+        distance = min(tests.get(file_id, {}).get("distance", max_depth + 1), targets[row["origin_id"]] + 1)
+        tests[file_id] = {"distance": distance, "reason": "import", "through": None}
+        records[file_id] = row["node"]
+    
+    summaries = {candidate["id"]: symbol_summary(candidate)
+                 for candidate in symbol_candidates(db, [records[node_id] for node_id in tests] + [
+                     records[test["through"]] for test in tests.values() if test["through"] in records])}
+    entries = []
+    for test_id, test in tests.items():
+        entry = dict(summaries[test_id], distance=test["distance"],
+                     confidence=CONFIDENCE_LOW if test["reason"] else CONFIDENCE_HIGH)
+        if test["reason"]:
+            entry["reason"] = test["reason"]
+        if test["through"] and test["through"] != root["id"]:
+            entry["through"] = summaries[test["through"]]["qualified_name"]
+        if records[test_id]["properties"].get("parametrized"):
+            entry["parametrized"] = True
+        entries.append(entry)
+    entries.sort(key=lambda entry: (entry["distance"], entry["confidence"] != CONFIDENCE_HIGH,
+                                    entry["file_path"] or "", entry["line_no"] or 0, entry["id"]))
+    return {
+        "status": "ok",
+        "symbol": root,
+        "max_depth": max_depth,
+        "total": len(entries),
+        "high_confidence": sum(1 for entry in entries if entry["confidence"] == CONFIDENCE_HIGH),
+        "tests": entries[:max_results],
+        "more": max(0, len(entries) - max_results),
+        "truncated": len(entries) > max_results,
+    }
+
+
+def match_packages(db, package: str) -> List[Dict[str, Any]]:
+    """
+    Package records a name selects.
+    
+    A Package matches by Go import path (or Java / C# package), or by
+    directory, given as stored, as an absolute path or as trailing path
+    segments.
+    """
+    prefixes = scope_prefixes(package)
+    segments = "/" + package.replace("\\", "/").strip("/")
+    packages = []
+    for record in db.find_nodes(label="Package"):
+        properties = record["properties"]
+        path = properties.get("path") or ""
+        if properties.get("import_path") == package or path in prefixes or (
+                segments != "/" and ("/" + path).endswith(segments)):
+            packages.append(record)
+    return packages
+
+
+def _package_files(db, package: str) -> Tuple[List[str], Set[str]]:
+    """
+    Display names and file paths of the packages a name selects (see match_packages).
+    
+    Without a matching Package, the files under the directory are used.
+    """
+    packages = match_packages(db, package)
+    if not packages:
+        prefixes = scope_prefixes(package)
+        files = {record["properties"]["file_path"] for record in db.find_nodes(label="File", path_prefixes=prefixes)
+                 if record["properties"].get("file_path")}
+        return ([prefixes[0]] if files else []), files
+    
+    files = set()
+    for row in db.neighbors([_node_id(record) for record in packages], ["CONTAINS"], direction="out", label="File"):
+        if row["node"]["properties"].get("file_path"):
+            files.add(row["node"]["properties"]["file_path"])
+    return sorted({package_label(record["properties"]) for record in packages}), files
+
+
+def get_untested(db, package: str, max_depth: int = DEFAULT_MAX_DEPTH) -> Dict[str, Any]:
+    """
+    Exported functions and methods of a package that no test covers within max_depth call hops.
+    
+    Test code and generated files are left out. Symbols covered only
+    with low confidence (see get_tests_for) are listed apart, with their
+    reasons, since their tests may not run the real code.
+    
+    Args:
+        db: GraphStore backend
+        package: Go import path, package name or directory
+        max_depth: Number of call hops between a test and a symbol
+    
+    Returns:
+        {"status", "packages", "max_depth", "symbol_count", "covered", "untested", "low_confidence"};
+        status "not_found" when no package or file matches
+    
+    Raises:
+        ValueError: for a depth outside 1..MAX_DEPTH_LIMIT
+    """
+    max_depth = int(max_depth)
+    _check_limits(max_depth)
+    
+    labels, files = _package_files(db, package)
+    if not files:
+        return {"status": "not_found", "package": package, "message": f"No package or directory matches '{package}'"}
+    file_records = {}
+    for record in db.find_nodes(label="File", path_prefixes=sorted({os.path.dirname(path) for path in files})):
+        if record["properties"].get("file_path") in files:
+            file_records[record["properties"]["file_path"]] = record["properties"]
+    
+    symbols: Dict[str, Dict[str, Any]] = {}
+    for label in TESTED_LABELS:
+        for record in db.find_nodes(label=label, path_prefixes=sorted({os.path.dirname(path) for path in files})):
+            properties = record["properties"]
+            file_path = properties.get("file_path")
+            if file_path not in files or properties.get("is_test") or is_test_file(file_path):
+                continue
+            if properties.get("generated") or is_generated_file(file_path, file_records.get(file_path)):
+                continue
+            if not is_exported(properties.get("name") or "", properties, detect_language(file_path)):
+                continue
+            symbols[properties["id"]] = record
+    
+    # Walk from the symbols towards the tests; a node keeps the best reason per symbol it reaches
+    reasons: Dict[str, Dict[str, Optional[str]]] = {symbol_id: {symbol_id: None} for symbol_id in symbols}
+    records = dict(symbols)
+    # (test ID, node it calls) -> symbol ID -> reason
+    test_hits: Dict[Tuple[str, str], Dict[str, Optional[str]]] = {}
+    frontier = dict(reasons)
+    for _ in range(max_depth):
+        next_frontier: Dict[str, Dict[str, Optional[str]]] = {}
+        for callee_id, node_calls in find_calls(db, [records[node_id] for node_id in frontier], "in").items():
+            for call in node_calls:
+                node_id = _node_id(call["node"])
+                records.setdefault(node_id, call["node"])
+                known = reasons.setdefault(node_id, {})
+                for symbol_id, reason in list(frontier[callee_id].items()):
+                    reason = reason or ("interface" if call.get("interface") is not None else None)
+                    if symbol_id in known and not _stronger(reason, known[symbol_id]):
+                        continue
+                    known[symbol_id] = reason
+                    if call["node"]["properties"].get("is_test"):
+                        test_hits.setdefault((node_id, callee_id), {})[symbol_id] = reason
+                    else:
+                        next_frontier.setdefault(node_id, {})[symbol_id] = reason
+        if not next_frontier:
+            break
+        frontier = next_frontier
+    
+    coverage: Dict[str, Optional[str]] = {}
+    
+    def cover(symbol_id: str, reason: Optional[str]) -> None:
+        if symbol_id not in coverage or _stronger(reason, coverage[symbol_id]):
+            coverage[symbol_id] = reason
+    
+    edges = _test_edges(db, sorted({test_id for test_id, _ in test_hits}))
+    for (test_id, callee_id), hits in test_hits.items():
+        edge = edges.get((test_id, callee_id)) or {}
+        for symbol_id, reason in hits.items():
+            cover(symbol_id, reason or edge.get("reason"))
+    
+    # Test files importing a reached node, or a file defining one
+    targets: Dict[str, Set[str]] = {}
+    for node_id, known in reasons.items():
+        if records[node_id]["properties"].get("is_test"):
+            continue
+        targets.setdefault(node_id, set()).update(known)
+        file_path = records[node_id]["properties"].get("file_path")
+        if file_path:
+            targets.setdefault(_file_id(node_id, file_path), set()).update(known)
+    for row in db.neighbors(list(targets), IMPORT_RELATIONS, direction="in", label="File"):
+        if row["node"]["properties"].get("is_test"):
+            for symbol_id in targets[row["origin_id"]]:
+                cover(symbol_id, "import")
+    
+    summaries = {candidate["id"]: symbol_summary(candidate)
+                 for candidate in symbol_candidates(db, list(symbols.values()))}
+    
+    def order(summary):
+        return summary["file_path"] or "", summary["line_no"] or 0, summary["id"]
+    
+    untested = sorted((summaries[symbol_id] for symbol_id in symbols if symbol_id not in coverage), key=order)
+    low_confidence = sorted((dict(summaries[symbol_id], reason=reason)
+                             for symbol_id, reason in coverage.items() if reason), key=order)
+    return {
+        "status": "ok",
+        "packages": labels,
+        "max_depth": max_depth,
+        "symbol_count": len(symbols),
+        "covered": sum(1 for reason in coverage.values() if not reason),
+        "untested": untested,
+        "low_confidence": low_confidence,
+    }
@@ -0,0 +1,99 @@
+"""
+Helpers for the get_index_diagnostics MCP tool.
+
+A file the indexer could not parse is stored as a File node without
+symbols that records the failure (see src/ast_parser/guards.py): the
+message in parse_error, the category in parse_error_category (syntax_error,
+unsupported_feature, encoding, timeout or parser_error), its position in
+parse_error_line and parse_error_column when the error has one, and the
+parser that failed in parse_error_analyzer. Such files are reported with
+severity "error". Files left unparsed for their size (skipped_reason
+"too_large") did not fail; they are reported with severity "warning".
+
+The next incremental run parses failed files again, so a file drops out
+of the report once it parses.
+"""
+
+from typing import Any, Dict, List, Optional
+
+from src.analysis.unreferenced import under_path
+from src.ast_parser.guards import SKIPPED_TOO_LARGE
+from src.ast_parser.language_detector import detect_language
+from src.graph_store.base import node_repo
+
+SEVERITY_ERROR = "error"
+SEVERITY_WARNING = "warning"
+SEVERITIES = (SEVERITY_ERROR, SEVERITY_WARNING)
+
+
+def file_diagnostic(properties: Dict[str, Any]) -> Optional[Dict[str, Any]]:
+    """Diagnostic of a File node, None for a file that was parsed."""
+    file_path = properties.get("file_path")
+    diagnostic = {
+        "file_path": file_path,
+        "repo": node_repo(properties),
+        "language": detect_language(file_path) if file_path else None,
+    }
+    if properties.get("parse_error"):
+        return dict(
+            diagnostic,
+            severity=SEVERITY_ERROR,
+            category=properties.get("parse_error_category"),
+            message=properties["parse_error"],
+            line=properties.get("parse_error_line"),
+            column=properties.get("parse_error_column"),
+            analyzer=properties.get("parse_error_analyzer"),
+        )
+    if properties.get("skipped_reason") == SKIPPED_TOO_LARGE:
+        return dict(
+            diagnostic,
+            severity=SEVERITY_WARNING,
+            category=SKIPPED_TOO_LARGE,
+            message=f"{properties.get('size_bytes')} bytes is above the file size limit, indexed without symbols",
+        )
+    return None
+
+
+def index_diagnostics(db, severity: Optional[str] = None, path_prefix: Optional[str] = None) -> Dict[str, Any]:
+    """
+    Files the last index runs failed to parse or skipped.
+    
+    Args:
+        db: GraphStore to query
+        severity: Only diagnostics of this severity, "error" or "warning" (default: both)
+        path_prefix: Only files under this path, given as indexed, absolute, or as consecutive path segments
+    
+    Returns:
+        {"status": "ok", "total", "by_severity", "by_category", "diagnostics"}; diagnostics are sorted by
+        severity then path, each with its file_path, repo, language, severity, category and message, and
+        for failed files line, column and analyzer
+    
+    Raises:
+        ValueError: for an unknown severity
+    """
+    if severity is not None and severity not in SEVERITIES:
+        raise ValueError(f"Unknown severity '{severity}', expected one of: {', '.join(SEVERITIES)}")
+    
+    diagnostics: List[Dict[str, Any]] = []
+    for record in db.find_nodes(label="File"):
+        properties = record["properties"]
+        if path_prefix and not under_path(properties.get("file_path") or "", path_prefix):
+            continue
+        diagnostic = file_diagnostic(properties)
+        if diagnostic is None or (severity is not None and diagnostic["severity"] != severity):
+            continue
+        diagnostics.append(diagnostic)
+    
+    diagnostics.sort(key=lambda diagnostic: (SEVERITIES.index(diagnostic["severity"]), diagnostic["file_path"] or ""))
+    by_severity: Dict[str, int] = {}
+    by_category: Dict[str, int] = {}
+    for diagnostic in diagnostics:
+        by_severity[diagnostic["severity"]] = by_severity.get(diagnostic["severity"], 0) + 1
+        by_category[diagnostic["category"]] = by_category.get(diagnostic["category"], 0) + 1
+    return {
+        "status": "ok",
+        "total": len(diagnostics),
+        "by_severity": by_severity,
+        "by_category": by_category,
+        "diagnostics": diagnostics,
+    }
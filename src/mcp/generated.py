@@ -0,0 +1,39 @@
+"""
+Leaving generated code out of MCP search results.
+
+Nodes of generated files carry generated: true (see
+src/ast_parser/guards.py). The search tools drop them unless called with
+include_generated, asking the store for more candidates until `limit`
+hand-written matches are found or the candidates run out.
+"""
+
+from typing import Any, Callable, Dict, List
+
+# Candidate window growth per retry while generated matches crowd out the others
+FETCH_GROWTH = 4
+
+
+def is_generated_node(properties: Dict[str, Any]) -> bool:
+    return bool(properties.get("generated"))
+
+
+def without_generated(search: Callable[[int], List[Any]], limit: int, include_generated: bool = False,
+                      properties: Callable[[Any], Dict[str, Any]] = lambda match: match["node"]) -> List[Any]:
+    """
+    Up to `limit` results of search, generated nodes left out unless include_generated.
+    
+    Args:
+        search: Returns the best `n` candidates for search(n)
+        limit: Maximum number of results
+        include_generated: Keep nodes of generated files
+        properties: Node properties of a candidate (default: its "node" entry)
+    """
+    if include_generated:
+        return search(limit)
+    fetch = limit
+    while True:
+        candidates = search(fetch)
+        kept = [candidate for candidate in candidates if not is_generated_node(properties(candidate))]
+        if len(kept) >= limit or len(candidates) < fetch:
+            return kept[:limit]
+        fetch *= FETCH_GROWTH
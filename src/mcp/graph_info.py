@@ -0,0 +1,59 @@
+"""
+Helpers for the get_graph_info MCP tool.
+
+Summarizes what a store holds without raw queries: the schema version of
+the graph next to the one this code writes (see
+src/graph_store/migrations.py), node counts by label and relationship
+counts by type, the repositories (Repository nodes) and the indexed roots
+with the time and commit of their last index run (IndexMetadata nodes).
+"""
+
+from typing import Any, Dict, Optional
+
+from src.graph_store.base import BASE_LABEL, node_repo
+from src.graph_store.migrations import SCHEMA_VERSION, read_schema_version, schema_metadata
+
+# IndexMetadata properties reported per indexed root
+INDEX_PROPERTIES = ("root", "index_complete", "indexed_at", "git_commit", "git_branch")
+
+
+def graph_info(db, repo: Optional[str] = None) -> Dict[str, Any]:
+    """
+    Schema version, counts, repositories and index runs of a store.
+    
+    Args:
+        db: GraphStore of the whole graph
+        repo: Only count and list this repository
+    """
+    version = read_schema_version(db)
+    metadata = schema_metadata(db)
+    schema = {
+        "version": version,
+        "current_version": SCHEMA_VERSION,
+        "up_to_date": version is None or version == SCHEMA_VERSION,
+    }
+    for key in ("migrated_at", "migration_failed_step", "migration_error"):
+        if metadata.get(key) is not None:
+            schema[key] = metadata[key]
+    
+    counts = db.count_by_type(repo=repo)
+    nodes = dict(counts["nodes"])
+    repositories = sorted(
+        ({key: node["properties"].get(key) for key in ("name", "root")}
+         for node in db.find_nodes(label="Repository", repo=repo)),
+        key=lambda repository: repository["name"] or "",
+    )
+    indexes = sorted(
+        (dict({key: node["properties"].get(key) for key in INDEX_PROPERTIES}, repo=node_repo(node["properties"]))
+         for node in db.find_nodes(label="IndexMetadata", repo=repo)),
+        key=lambda index: (index["repo"], index["root"] or ""),
+    )
+    return {
+        "schema": schema,
+        "node_count": nodes.pop(BASE_LABEL, 0),
+        "relationship_count": sum(counts["relationships"].values()),
+        "nodes_by_label": nodes,
+        "relationships_by_type": counts["relationships"],
+        "repositories": repositories,
+        "indexes": indexes,
+    }
@@ -0,0 +1,247 @@
+"""
+Helpers for the analyze_impact MCP tool.
+
+Collects what may break when a function or method changes, walking
+inbound CALLS edges breadth-first (calls through a Go interface
+included, see call_hierarchy.find_calls) up to max_depth:
+
+- callers: every function or method reaching the symbol, bucketed by
+  distance (1: direct callers, 2: their callers, ...) and split into the
+  symbol's own package and other packages; a cross-package dependent
+  uses the symbol as API, so a signature change breaks it
+- interfaces: interfaces and base types of the method's type that
+  declare a method of the same name, whose contract the change may break
+- tests: test functions (is_test, see src/indexing/testcode.py) among the
+  callers, and test files importing the symbol, a caller or a file
+  defining one, bucketed by distance; for Jest files indexed without
+  test nodes (legacy parser), the importing test file is the test
+
+Each node is counted at its shortest distance. A section holding more
+than max_results entries keeps the closest ones and reports how many
+more exist. A dependent's "confidence" is that of the weakest edge on its
+most confident shortest path (see src/ast_parser/provenance.py), and
+min_confidence leaves out the edges below it.
+"""
+
+import os
+from typing import Any, Callable, Dict, List, Set
+
+from src.analysis.cycles import package_label
+from src.ast_parser.provenance import CONFIDENCE_PROPERTY, check_min_confidence
+from src.analysis.unreferenced import SUPERTYPE_RELATIONS
+from src.graph_store import same_repo_id
+from src.mcp.call_hierarchy import DEFAULT_MAX_DEPTH, MAX_DEPTH_LIMIT, call_provenance, find_calls, symbol_summary
+from src.mcp.references import find_symbol_candidates, symbol_candidates
+
+DEFAULT_MAX_RESULTS = 100
+
+# File -> symbol or file edges through which a test file reaches code it does not call
+IMPORT_RELATIONS = ["IMPORTS", "IMPORTS_DEFINITION", "IMPORTS_FROM"]
+
+
+def _node_id(record: Dict[str, Any]) -> str:
+    return record["properties"]["id"]
+
+
+def _file_id(node_id: str, file_path: str) -> str:
+    """ID of the File node of a node's file, in the node's repository."""
+    return same_repo_id(node_id, f"file:{file_path}")
+
+
+def _packages(db, file_ids: Dict[str, str]) -> Dict[str, str]:
+    """File ID -> label of its package, the file's directory when no Package contains it."""
+    packages: Dict[str, str] = {}
+    for row in db.neighbors(list(file_ids), ["CONTAINS"], direction="in", label="Package"):
+        packages.setdefault(row["origin_id"], package_label(row["node"]["properties"]))
+    for file_id, file_path in file_ids.items():
+        packages.setdefault(file_id, os.path.dirname(file_path))
+    return packages
+
+
+def _member_names(record: Dict[str, Any]) -> Set[str]:
+    """Method names an interface records in "methods" (Go method signatures)."""
+    return {method.split("(", 1)[0].strip() for method in record["properties"].get("methods") or []}
+
+
+def _contracts(db, method: Dict[str, Any]) -> List[Dict[str, Any]]:
+    """Interfaces and base types, transitively, of the method's type that declare a method of its name."""
+    name = method["properties"].get("name")
+    method_id = _node_id(method)
+    owner_ids = [_node_id(row["node"]) for row in db.neighbors([method_id], ["DEFINES"], direction="in")]
+    owner_ids += [_node_id(row["node"]) for row in db.neighbors([method_id], ["METHOD_OF"], direction="out")]
+    
+    seen = set(owner_ids)
+    supertypes: Dict[str, Dict[str, Any]] = {}
+    frontier = list(dict.fromkeys(owner_ids))
+    while frontier:
+        next_frontier = []
+        for row in db.neighbors(frontier, SUPERTYPE_RELATIONS, direction="out"):
+            type_id = _node_id(row["node"])
+            if type_id not in seen:
+                seen.add(type_id)
+                supertypes[type_id] = row["node"]
+                next_frontier.append(type_id)
+        frontier = next_frontier
+    
+    declaring = {type_id for type_id, record in supertypes.items() if name in _member_names(record)}
+    for relation, direction in (("DEFINES", "out"), ("METHOD_OF", "in")):
+        for row in db.neighbors(list(supertypes), [relation], direction=direction):
+            if row["node"]["properties"].get("name") == name:
+                declaring.add(row["origin_id"])
+    return [supertypes[type_id] for type_id in supertypes if type_id in declaring]
+
+
+def _order(entry: Dict[str, Any]):
+    return entry["distance"], entry["file_path"] or "", entry["line_no"] or 0, entry["name"] or "", entry["id"]
+
+
+def _by_distance(entries: List[Dict[str, Any]], keys: Dict[str, Callable[[Dict[str, Any]], bool]],
+                 max_results: int) -> Dict[str, Any]:
+    """
+    The closest max_results entries in one bucket per distance, split by keys.
+    
+    Returns {"total", "more": entries left out, "by_distance": [{"distance", key: [...], ...}]}
+    """
+    entries = sorted(entries, key=_order)
+    buckets: Dict[int, Dict[str, Any]] = {}
+    for entry in entries[:max_results]:
+        bucket = buckets.setdefault(entry["distance"], {"distance": entry["distance"], **{key: [] for key in keys}})
+        key = next(key for key, matches in keys.items() if matches(entry))
+        bucket[key].append(entry)
+    return {
+        "total": len(entries),
+        "more": max(0, len(entries) - max_results),
+        "by_distance": [buckets[distance] for distance in sorted(buckets)],
+    }
+
+
+def analyze_impact(db, symbol: str, max_depth: int = DEFAULT_MAX_DEPTH,
+                   max_results: int = DEFAULT_MAX_RESULTS, min_confidence: float = 0.0) -> Dict[str, Any]:
+    """
+    Transitive dependents of a function or method, bucketed by distance.
+    
+    Every dependent has the symbol's id, name, qualified name, type,
+    location, package, distance and confidence; a caller reached only through calls
+    on an interface value is flagged "via_interface", and a test has "via"
+    "call" (a test function calling into the closure) or "import" (a test
+    file importing it).
+    
+    Args:
+        db: GraphStore backend
+        symbol: Function or method (name, qualified name or node id)
+        max_depth: Number of caller levels followed
+        max_results: Maximum number of callers and of tests listed; the closest ones are kept
+        min_confidence: Edges with a lower confidence are not followed (1.0: exact facts only)
+    
+    Returns:
+        Structured result with status "ok", "ambiguous" or "not_found"
+    
+    Raises:
+        ValueError: for a depth outside 1..MAX_DEPTH_LIMIT, max_results below 1 or min_confidence outside 0..1
+    """
+    max_depth, max_results = int(max_depth), int(max_results)
+    if not 1 <= max_depth <= MAX_DEPTH_LIMIT:
+        raise ValueError(f"max_depth must be between 1 and {MAX_DEPTH_LIMIT}")
+    if max_results < 1:
+        raise ValueError("max_results must be at least 1")
+    min_confidence = check_min_confidence(min_confidence)
+    
+    candidates = find_symbol_candidates(db, symbol)
+    if not candidates:
+        return {"status": "not_found", "symbol": symbol, "message": f"No symbol matches '{symbol}'"}
+    if len(candidates) > 1:
+        return {"status": "ambiguous", "symbol": symbol,
+                "message": f"Several symbols match '{symbol}'; call again with a symbol_id, qualified_name or id",
+                "candidates": [symbol_summary(c) for c in candidates]}
+    
+    root = symbol_summary(candidates[0])
+    root_record = db.get_nodes([root["id"]])[0]
+    records = {root["id"]: root_record}
+    distances = {root["id"]: 0}
+    confidences = {root["id"]: 1.0}
+    via_interface: Dict[str, bool] = {}
+    frontier = [root_record]
+    for distance in range(1, max_depth + 1):
+        found: Dict[str, Dict[str, Any]] = {}
+        for callee_id, node_calls in find_calls(db, frontier, "in", min_confidence).items():
+            for call in node_calls:
+                node_id = _node_id(call["node"])
+                if node_id in distances:
+                    continue
+                found.setdefault(node_id, call["node"])
+                confidence = min(confidences[callee_id], call_provenance(call)[CONFIDENCE_PROPERTY])
+                confidences[node_id] = max(confidences.get(node_id, 0.0), confidence)
+                # One direct call of this level is enough to not be an over-approximation
+                via_interface[node_id] = via_interface.get(node_id, True) and call.get("interface") is not None
+        if not found:
+            break
+        distances.update((node_id, distance) for node_id in found)
+        records.update(found)
+        frontier = list(found.values())
+    
+    # Test files importing a node of the closure, or a file defining one, one level further
+    targets: Dict[str, int] = {}
+    target_confidences: Dict[str, float] = {}
+    for node_id, distance in distances.items():
+        targets.setdefault(node_id, distance)
+        target_confidences.setdefault(node_id, confidences[node_id])
+        file_path = records[node_id]["properties"].get("file_path")
+        if file_path:
+            file_id = _file_id(node_id, file_path)
+            targets[file_id] = min(targets.get(file_id, distance), distance)
+            target_confidences[file_id] = max(target_confidences.get(file_id, 0.0), confidences[node_id])
+    importers: Dict[str, Dict[str, Any]] = {}
+    import_distances: Dict[str, int] = {}
+    for row in db.neighbors(list(targets), IMPORT_RELATIONS, direction="in", label="File"):
+        file_id = _node_id(row["node"])
+        if row["node"]["properties"].get("is_test") and file_id not in distances:
+            importers[file_id] = row["node"]
+            import_distances[file_id] = min(import_distances.get(file_id, max_depth + 1), targets[row["origin_id"]] + 1)
+            confidences[file_id] = max(confidences.get(file_id, 0.0), target_confidences[row["origin_id"]])
+    distances.update(import_distances)
+    records.update(importers)
+    
+    contracts = _contracts(db, root_record) if "Method" in root_record["labels"] else []
+    summaries = {candidate["id"]: symbol_summary(candidate)
+                 for candidate in symbol_candidates(db, list(records.values()) + contracts)}
+    file_ids = {summary["id"]: _file_id(summary["id"], summary["file_path"])
+                for summary in summaries.values() if summary["file_path"]}
+    packages = _packages(db, {file_ids[node_id]: summaries[node_id]["file_path"] for node_id in file_ids})
+    for summary in summaries.values():
+        summary["package"] = packages.get(file_ids.get(summary["id"]))
+    root = summaries[root["id"]]
+    
+    callers, tests = [], []
+    for node_id, distance in distances.items():
+        if node_id == root["id"]:
+            continue
+        entry = dict(summaries[node_id], distance=distance, confidence=confidences[node_id])
+        if via_interface.get(node_id):
+            entry["via_interface"] = True
+        if node_id in importers:
+            tests.append(dict(entry, via="import"))
+        elif records[node_id]["properties"].get("is_test"):
+            tests.append(dict(entry, via="call"))
+        else:
+            callers.append(entry)
+    # A test file whose test functions are listed is not listed again
+    tested_files = {test["file_path"] for test in tests if test["via"] == "call"}
+    tests = [test for test in tests if test["via"] == "call" or test["file_path"] not in tested_files]
+    
+    def same_package(entry):
+        return entry["package"] == root["package"]
+    
+    caller_result = _by_distance(callers, {"same_package": same_package,
+                                           "cross_package": lambda entry: not same_package(entry)}, max_results)
+    same_total = sum(1 for entry in callers if same_package(entry))
+    test_result = _by_distance(tests, {"tests": lambda entry: True}, max_results)
+    return {
+        "status": "ok",
+        "symbol": root,
+        "max_depth": max_depth,
+        "min_confidence": min_confidence,
+        "callers": dict(caller_result, same_package=same_total, cross_package=len(callers) - same_total),
+        "interfaces": [summaries[_node_id(record)] for record in contracts],
+        "tests": test_result,
+        "truncated": bool(caller_result["more"] or test_result["more"]),
+    }
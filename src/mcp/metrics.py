@@ -0,0 +1,143 @@
+"""
+Helpers for the query_metrics MCP tool.
+
+Functions and methods carry the complexity and size metrics computed
+while their file was parsed (see ast_parser/metrics.py). The tool filters
+them by directory, node type and metric thresholds and ranks them by one
+metric, e.g. the 20 most complex functions under services/payments, or
+every function longer than 200 lines.
+
+Metrics are named for the tool and map to node properties:
+complexity -> cyclomatic_complexity, statements -> statement_count,
+lines -> line_count, parameters -> arity, nesting -> max_nesting.
+
+Nodes indexed before the metrics existed have none; they are counted in
+"unmeasured" and left out until the next reindex. Functions of generated
+files are left out unless include_generated is set.
+"""
+
+from typing import Any, Dict, List, Optional
+
+from src.analysis.unreferenced import under_path
+from src.ast_parser.language_detector import detect_language
+from src.mcp.generated import is_generated_node
+from src.mcp.references import node_type_from_labels
+
+# Metric name -> node property
+METRICS = {
+    "complexity": "cyclomatic_complexity",
+    "statements": "statement_count",
+    "lines": "line_count",
+    "parameters": "arity",
+    "nesting": "max_nesting",
+}
+
+METRIC_NODE_TYPES = ("Function", "Method")
+ORDERS = ("desc", "asc")
+DEFAULT_LIMIT = 20
+MAX_LIMIT = 1000
+
+
+def _thresholds(values: Optional[Dict[str, Any]], argument: str) -> Dict[str, float]:
+    """Validate an at_least / at_most argument: metric name -> number."""
+    thresholds = {}
+    for metric, value in (values or {}).items():
+        if metric not in METRICS:
+            raise ValueError(f"Unknown metric '{metric}' in {argument}, expected one of: {', '.join(METRICS)}")
+        if isinstance(value, bool) or not isinstance(value, (int, float)):
+            raise ValueError(f"{argument}['{metric}'] must be a number")
+        thresholds[metric] = value
+    return thresholds
+
+
+def node_metrics(properties: Dict[str, Any]) -> Optional[Dict[str, Any]]:
+    """Metrics of a Function or Method node by metric name, None for a node indexed without them."""
+    if properties.get("cyclomatic_complexity") is None:
+        return None
+    return {metric: properties.get(prop) for metric, prop in METRICS.items()}
+
+
+def query_metrics(db, scope: Optional[str] = None, node_type: Optional[str] = None,
+                  at_least: Optional[Dict[str, Any]] = None, at_most: Optional[Dict[str, Any]] = None,
+                  sort_by: str = "complexity", order: str = "desc", limit: int = DEFAULT_LIMIT,
+                  offset: int = 0, include_generated: bool = False) -> Dict[str, Any]:
+    """
+    Functions and methods ranked by a metric.
+    
+    Args:
+        db: GraphStore to query
+        scope: Only symbols under this directory, given as indexed, absolute, or as consecutive path segments
+        node_type: "Function" or "Method" (default: both)
+        at_least: Metric name -> lowest value kept, e.g. {"lines": 200}
+        at_most: Metric name -> highest value kept
+        sort_by: Metric to rank by
+        order: "desc" (largest first) or "asc"
+        limit: Maximum number of results
+        offset: Number of ranked results to skip, for paging
+        include_generated: Keep functions of generated files
+    
+    Returns:
+        {'status': 'ok', 'total': ..., 'unmeasured': ..., 'results': [...]}, every result with its
+        location and metrics; 'total' counts the matches before limit and offset
+    
+    Raises:
+        ValueError: for an unknown metric, node type or order, or a limit or offset out of range
+    """
+    if sort_by not in METRICS:
+        raise ValueError(f"Unknown metric '{sort_by}', expected one of: {', '.join(METRICS)}")
+    if order not in ORDERS:
+        raise ValueError(f"Unknown order '{order}', expected one of: {', '.join(ORDERS)}")
+    if node_type is not None and node_type not in METRIC_NODE_TYPES:
+        raise ValueError(f"Unknown node type '{node_type}', expected one of: {', '.join(METRIC_NODE_TYPES)}")
+    if not 1 <= limit <= MAX_LIMIT:
+        raise ValueError(f"limit must be between 1 and {MAX_LIMIT}")
+    if offset < 0:
+        raise ValueError("offset must not be negative")
+    minimums = _thresholds(at_least, "at_least")
+    maximums = _thresholds(at_most, "at_most")
+    
+    matches: List[Dict[str, Any]] = []
+    seen = set()
+    unmeasured = 0
+    for label in [node_type] if node_type else METRIC_NODE_TYPES:
+        for record in db.find_nodes(label=label):
+            properties = record["properties"]
+            file_path = properties.get("file_path")
+            # Placeholders of symbols outside the index have no location
+            if not file_path or properties["id"] in seen or (scope and not under_path(file_path, scope)):
+                continue
+            if not include_generated and is_generated_node(properties):
+                continue
+            seen.add(properties["id"])
+            metrics = node_metrics(properties)
+            if metrics is None:
+                unmeasured += 1
+                continue
+            if any(metrics[metric] is None or metrics[metric] < value for metric, value in minimums.items()):
+                continue
+            if any(metrics[metric] is None or metrics[metric] > value for metric, value in maximums.items()):
+                continue
+            matches.append({
+                "id": properties["id"],
+                "symbol_id": properties.get("symbol_id"),
+                "name": properties.get("name"),
+                "node_type": node_type_from_labels(record["labels"]),
+                "file_path": file_path,
+                "line_no": properties.get("line_no"),
+                "language": detect_language(file_path),
+                "metrics": metrics,
+            })
+    
+    # Location breaks ties, so pages are stable; a missing metric ranks last
+    matches.sort(key=lambda match: (match["file_path"], match["line_no"] or 0))
+    ranked = [match for match in matches if match["metrics"][sort_by] is not None]
+    ranked.sort(key=lambda match: match["metrics"][sort_by], reverse=order == "desc")
+    ranked += [match for match in matches if match["metrics"][sort_by] is None]
+    return {
+        "status": "ok",
+        "sort_by": sort_by,
+        "order": order,
+        "total": len(ranked),
+        "unmeasured": unmeasured,
+        "results": ranked[offset:offset + limit],
+    }
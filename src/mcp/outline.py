@@ -0,0 +1,216 @@
+"""
+Helpers for the get_file_outline MCP tool.
+
+The outline of a file lists its declarations as a tree: types with their
+methods, fields and nested types, then functions, methods of types
+declared in other files, constants and variables. Every entry carries the
+line range of the whole declaration, body included, and the first
+paragraph of its doc on one line.
+
+The outline is read from the graph when the file is indexed. Otherwise
+the file is parsed on the fly with the indexer's parser settings, so
+files excluded from indexing have an outline too; "source" says which
+path was taken ("graph" or "parse").
+
+Members hang under the type that DEFINES them. A Go method whose
+receiver type is declared in another file of the package has no type in
+the outline, it is listed under "methods" with its owner.
+"""
+
+import os
+from typing import Any, Dict, List, Optional
+
+from src.ast_parser.doc_comments import truncate_doc
+from src.mcp.references import node_type_from_labels
+from src.parallel.pipeline import ParserSettings, create_parser
+
+TYPE_NODE_TYPES = ("Class", "Interface", "Enum", "TypeAlias")
+MEMBER_NODE_TYPES = ("Method", "ClassVariable")
+# GlobalVariable: Python module-level names in graphs indexed before Constant nodes
+VARIABLE_NODE_TYPES = ("Constant", "GlobalVariable", "Variable")
+OUTLINE_NODE_TYPES = TYPE_NODE_TYPES + ("Function",) + MEMBER_NODE_TYPES + VARIABLE_NODE_TYPES
+
+
+def doc_summary(doc: Optional[str]) -> Optional[str]:
+    """First paragraph of a doc on one line, cut to DOC_MAX_LENGTH."""
+    if not doc or not doc.strip():
+        return None
+    return truncate_doc(" ".join(doc.strip().split("\n\n")[0].split()))
+
+
+def is_constant(symbol: Dict[str, Any]) -> bool:
+    """Constant nodes, JS/TS `const` bindings and upper-case Python globals of older graphs."""
+    properties = symbol["properties"]
+    if symbol["kind"] == "Constant" or properties.get("declaration_type") == "const":
+        return True
+    return symbol["kind"] == "GlobalVariable" and symbol["name"].isupper()
+
+
+def _symbol(node_id: str, kind: str, properties: Dict[str, Any]) -> Dict[str, Any]:
+    return {
+        "id": node_id,
+        "kind": kind,
+        "name": properties.get("name") or "",
+        "line_no": properties.get("line_no"),
+        "end_line_no": properties.get("end_line_no") or properties.get("line_no"),
+        "properties": properties,
+    }
+
+
+def _entry(symbol: Dict[str, Any]) -> Dict[str, Any]:
+    properties = symbol["properties"]
+    entry = {
+        "id": symbol["id"],
+        "symbol_id": properties.get("symbol_id"),
+        "name": symbol["name"],
+        "kind": symbol["kind"],
+        "line_no": symbol["line_no"],
+        "end_line_no": symbol["end_line_no"],
+        "doc": doc_summary(properties.get("doc")),
+    }
+    if properties.get("signature"):
+        entry["signature"] = properties["signature"]
+    if properties.get("type"):
+        entry["type"] = properties["type"]
+    return entry
+
+
+def _location(symbol: Dict[str, Any]):
+    return symbol["line_no"] or 0, symbol["name"]
+
+
+def build_outline(symbols: List[Dict[str, Any]], defines: List[tuple],
+                  owners: Optional[Dict[str, str]] = None) -> Dict[str, List[Dict[str, Any]]]:
+    """
+    Nest the symbols of one file.
+    
+    Args:
+        symbols: Symbols of the file, see _symbol
+        defines: (source ID, target ID) of the DEFINES edges between them
+        owners: Method ID -> name of its type, for methods of types in other files
+    """
+    owners = owners or {}
+    by_id = {symbol["id"]: symbol for symbol in symbols}
+    parent_of = {}
+    for source_id, target_id in defines:
+        source, target = by_id.get(source_id), by_id.get(target_id)
+        if source is None or target is None or source["kind"] not in TYPE_NODE_TYPES:
+            continue
+        if target["kind"] in MEMBER_NODE_TYPES + TYPE_NODE_TYPES:
+            parent_of.setdefault(target_id, source_id)
+    
+    def type_entry(symbol: Dict[str, Any]) -> Dict[str, Any]:
+        children = sorted((by_id[child_id] for child_id, parent_id in parent_of.items() if parent_id == symbol["id"]),
+                          key=_location)
+        entry = _entry(symbol)
+        entry["fields"] = [_entry(child) for child in children if child["kind"] == "ClassVariable"]
+        entry["methods"] = [_entry(child) for child in children if child["kind"] == "Method"]
+        nested = [type_entry(child) for child in children if child["kind"] in TYPE_NODE_TYPES]
+        if nested:
+            entry["types"] = nested
+        return entry
+    
+    outline: Dict[str, List[Dict[str, Any]]] = {
+        "types": [], "functions": [], "methods": [], "constants": [], "variables": [],
+    }
+    for symbol in sorted(symbols, key=_location):
+        if symbol["id"] in parent_of:
+            continue
+        kind = symbol["kind"]
+        if kind in TYPE_NODE_TYPES:
+            outline["types"].append(type_entry(symbol))
+        elif kind == "Function":
+            # Nested functions belong to the body of their enclosing function
+            if not symbol["properties"].get("nested"):
+                outline["functions"].append(_entry(symbol))
+        elif kind == "Method":
+            entry = _entry(symbol)
+            entry["owner"] = owners.get(symbol["id"]) or symbol["properties"].get("receiver_type")
+            outline["methods"].append(entry)
+        elif kind in VARIABLE_NODE_TYPES:
+            outline["constants" if is_constant(symbol) else "variables"].append(_entry(symbol))
+    return outline
+
+
+def _indexed_path(db, file_path: str, codebase_path: Optional[str]) -> Optional[str]:
+    """The form of file_path the graph stores its File node under, None if it is not indexed."""
+    candidates = [file_path, os.path.abspath(file_path), os.path.realpath(file_path)]
+    if codebase_path and not os.path.isabs(file_path):
+        joined = os.path.join(codebase_path, file_path)
+        candidates += [joined, os.path.abspath(joined), os.path.realpath(joined)]
+    for candidate in dict.fromkeys(candidates):
+        # By property, the File node ID of another repository is prefixed
+        if db.find_nodes(label="File", properties={"file_path": candidate}, limit=1):
+            return candidate
+    return None
+
+
+def outline_from_graph(db, file_path: str) -> Dict[str, List[Dict[str, Any]]]:
+    """Outline of an indexed file."""
+    symbols = []
+    for record in db.find_nodes(path_prefixes=[file_path]):
+        properties = record["properties"]
+        kind = node_type_from_labels(record["labels"])
+        if kind in OUTLINE_NODE_TYPES and properties.get("file_path") == file_path:
+            symbols.append(_symbol(properties["id"], kind, properties))
+    
+    type_ids = [symbol["id"] for symbol in symbols if symbol["kind"] in TYPE_NODE_TYPES]
+    defines = [
+        (row["origin_id"], row["node"]["properties"]["id"])
+        for row in (db.neighbors(type_ids, ["DEFINES"], direction="out") if type_ids else [])
+    ]
+    defined = {target_id for _, target_id in defines}
+    method_ids = [symbol["id"] for symbol in symbols if symbol["kind"] == "Method" and symbol["id"] not in defined]
+    owners = {}
+    for row in (db.neighbors(method_ids, ["DEFINES", "METHOD_OF"], direction="both") if method_ids else []):
+        if node_type_from_labels(row["node"]["labels"]) in TYPE_NODE_TYPES:
+            owners.setdefault(row["origin_id"], row["node"]["properties"].get("name"))
+    return build_outline(symbols, defines, owners)
+
+
+def outline_from_parse(file_path: str, settings: ParserSettings) -> Dict[str, List[Dict[str, Any]]]:
+    """
+    Outline of a file parsed on the fly.
+    
+    Raises:
+        ValueError: no parser handles the file's extension
+    """
+    parser = create_parser(file_path, settings)
+    if parser is None:
+        raise ValueError(f"No parser for {file_path}")
+    nodes, relations = parser.parse_file(file_path, build_index=False)
+    
+    symbols = []
+    for node in nodes.values():
+        if node.node_type in OUTLINE_NODE_TYPES and node.file_path == file_path:
+            properties = dict(node.properties, name=node.name, line_no=node.line_no, end_line_no=node.end_line_no)
+            symbols.append(_symbol(node.node_id, node.node_type, properties))
+    defines = [(r.source_id, r.target_id) for r in relations if r.relation_type == "DEFINES"]
+    return build_outline(symbols, defines)
+
+
+def file_outline(db, file_path: str, settings: ParserSettings, codebase_path: Optional[str] = None) -> Dict[str, Any]:
+    """
+    Outline of a file, from the graph when it is indexed, otherwise parsed.
+    
+    Args:
+        db: GraphStore
+        file_path: File path, absolute or relative to codebase_path
+        settings: Parser routing options for files that are not indexed
+        codebase_path: Root relative paths are resolved against
+    
+    Returns:
+        {"file_path", "source": "graph" or "parse", "outline"}, or {"error"}
+        when the file is neither indexed nor readable
+    """
+    indexed_path = _indexed_path(db, file_path, codebase_path)
+    if indexed_path is not None:
+        return {"file_path": indexed_path, "source": "graph", "outline": outline_from_graph(db, indexed_path)}
+    
+    path = file_path
+    if codebase_path and not os.path.isabs(path) and not os.path.isfile(path):
+        path = os.path.join(codebase_path, path)
+    if not os.path.isfile(path):
+        return {"error": f"File not found: {file_path}"}
+    path = os.path.abspath(path)
+    return {"file_path": path, "source": "parse", "outline": outline_from_parse(path, settings)}
@@ -0,0 +1,164 @@
+"""
+Helpers for the get_package_overview MCP tool.
+
+The overview of a package is a one-call orientation: its summary (see
+src/indexing/summaries.py), its top-level exported symbols, the packages
+it depends on and the packages depending on it, from the package
+DEPENDS_ON edges.
+
+Top-level symbols are the types, functions, constants and variables the
+package's File nodes CONTAIN; test code and generated files are left
+out, and exported is read per language (see unreferenced.is_exported).
+"""
+
+import json
+from typing import Any, Dict, List
+
+from src.analysis.cycles import package_label
+from src.analysis.unreferenced import is_exported, is_generated_file
+from src.ast_parser.language_detector import detect_language
+from src.ast_parser.provenance import edge_provenance
+from src.export.graph_export import scope_prefixes
+from src.indexing.symbol_ids import SYMBOL_ID_PROPERTY
+from src.indexing.testcode import is_test_file
+from src.mcp.coverage import match_packages
+from src.mcp.outline import TYPE_NODE_TYPES, VARIABLE_NODE_TYPES, doc_summary
+from src.mcp.references import node_type_from_labels
+
+DEFAULT_MAX_SYMBOLS = 50
+
+OVERVIEW_NODE_TYPES = TYPE_NODE_TYPES + ("Function",) + VARIABLE_NODE_TYPES
+
+
+def _node_id(record: Dict[str, Any]) -> str:
+    return record["properties"]["id"]
+
+
+def _name(record: Dict[str, Any]) -> str:
+    """Display name of a DEPENDS_ON end: a package, an external package or a repository."""
+    properties = record["properties"]
+    if "Package" in record["labels"]:
+        return package_label(properties)
+    return properties.get("module_path") or properties.get("name") or properties["id"]
+
+
+def _summary_sources(value: Any) -> List[str]:
+    """summary_sources as a list, stored as a JSON string like every list property."""
+    if isinstance(value, str):
+        try:
+            value = json.loads(value)
+        except ValueError:
+            return [value]
+    return list(value or [])
+
+
+def _symbols(db, package_id: str) -> List[Dict[str, Any]]:
+    """Top-level exported symbols of the package's production files, in file and line order."""
+    files = {}
+    for row in db.neighbors([package_id], ["CONTAINS"], direction="out", label="File"):
+        properties = row["node"]["properties"]
+        file_path = properties.get("file_path")
+        if not file_path or properties.get("is_test") or is_test_file(file_path):
+            continue
+        if is_generated_file(file_path, properties):
+            continue
+        files[properties["id"]] = file_path
+    if not files:
+        return []
+    
+    symbols = []
+    for row in db.neighbors(list(files), ["CONTAINS"], direction="out"):
+        properties = row["node"]["properties"]
+        node_type = node_type_from_labels(row["node"]["labels"])
+        if node_type not in OVERVIEW_NODE_TYPES or properties.get("is_test") or properties.get("generated"):
+            continue
+        file_path = properties.get("file_path") or files[row["origin_id"]]
+        if not is_exported(properties.get("name") or "", properties, detect_language(file_path)):
+            continue
+        symbols.append({
+            "id": properties["id"],
+            "symbol_id": properties.get(SYMBOL_ID_PROPERTY),
+            "name": properties.get("name"),
+            "node_type": node_type,
+            "file_path": file_path,
+            "line_no": properties.get("line_no"),
+            "signature": properties.get("signature"),
+            "doc": doc_summary(properties.get("doc")),
+        })
+    unique = {symbol["id"]: symbol for symbol in symbols}
+    return sorted(unique.values(), key=lambda symbol: (symbol["file_path"], symbol["line_no"] or 0, symbol["id"]))
+
+
+def _dependencies(db, package_id: str, direction: str) -> List[Dict[str, Any]]:
+    """Ends of the package's DEPENDS_ON edges in one direction, with the imports behind each and the edge's provenance."""
+    entries = []
+    for row in db.neighbors([package_id], ["DEPENDS_ON"], direction=direction):
+        properties = row["relationship"]["properties"]
+        entry = {
+            "id": _node_id(row["node"]),
+            "package": _name(row["node"]),
+            "node_type": node_type_from_labels(row["node"]["labels"]),
+            "imports": properties.get("imports"),
+            "files": properties.get("files"),
+            **edge_provenance(row["relationship"]),
+        }
+        if properties.get("cross_repo"):
+            entry["cross_repo"] = True
+            entry["repo"] = properties.get("repo")
+        entries.append(entry)
+    return sorted(entries, key=lambda entry: (entry["package"], entry["id"]))
+
+
+def get_package_overview(db, package: str, max_symbols: int = DEFAULT_MAX_SYMBOLS) -> Dict[str, Any]:
+    """
+    Summary, top-level exported symbols, dependencies and dependents of a package.
+    
+    Args:
+        db: GraphStore backend
+        package: Go import path (or Java / C# package) or directory, as stored,
+            as an absolute path or as trailing path segments
+        max_symbols: Maximum number of symbols listed, "more_symbols" counts the rest
+    
+    Returns:
+        {"status": "ok", "package", "id", "path", "import_path", "summary", "summary_sources",
+        "symbols", "more_symbols", "dependencies", "dependents"}; status "ambiguous" with the
+        "candidates" when several packages match and none exactly, "not_found" when none does
+    
+    Raises:
+        ValueError: for a max_symbols below 1
+    """
+    max_symbols = int(max_symbols)
+    if max_symbols < 1:
+        raise ValueError("max_symbols must be at least 1")
+    
+    packages = match_packages(db, package)
+    if not packages:
+        return {"status": "not_found", "package": package, "message": f"No package matches '{package}'"}
+    if len(packages) > 1:
+        prefixes = scope_prefixes(package)
+        exact = [record for record in packages if record["properties"].get("import_path") == package
+                 or (record["properties"].get("path") or "") in prefixes]
+        if len(exact) != 1:
+            return {
+                "status": "ambiguous",
+                "package": package,
+                "candidates": sorted(package_label(record["properties"]) for record in packages),
+            }
+        packages = exact
+    
+    properties = packages[0]["properties"]
+    package_id = properties["id"]
+    symbols = _symbols(db, package_id)
+    return {
+        "status": "ok",
+        "package": package_label(properties),
+        "id": package_id,
+        "path": properties.get("path"),
+        "import_path": properties.get("import_path"),
+        "summary": properties.get("summary"),
+        "summary_sources": _summary_sources(properties.get("summary_sources")),
+        "symbols": symbols[:max_symbols],
+        "more_symbols": max(0, len(symbols) - max_symbols),
+        "dependencies": _dependencies(db, package_id, "out"),
+        "dependents": [entry for entry in _dependencies(db, package_id, "in") if entry["node_type"] == "Package"],
+    }
@@ -0,0 +1,180 @@
+"""
+Helpers for the find_path MCP tool.
+
+Finds the shortest dependency paths between two symbols with the storage
+backend's shortest_paths (shortestPath / allShortestPaths on Neo4j, a
+breadth-first search in memory), over a chosen set of relation types and
+bounded by a maximum depth so queries stay cheap on dense graphs. Each hop
+is annotated with its relation type and the location it comes from (the
+call site for CALLS edges, otherwise the hop's source node) and with the
+edge's provenance (see src/ast_parser/provenance.py); min_confidence
+keeps paths on edges at least that confident, 1.0 on exact facts.
+"""
+
+from typing import Any, Dict, List, Optional
+
+from src.ast_parser.provenance import check_min_confidence, edge_provenance
+from src.mcp.references import find_symbol_candidates, node_type_from_labels, qualified_name
+
+# Relations traversed when no edge types are given
+DEFAULT_PATH_RELATIONS = ("CALLS", "IMPORTS_FROM", "IMPORTS_DEFINITION", "METHOD_OF", "CROSS_LANG_CALLS")
+
+# Relation types a path may use
+PATH_RELATIONS = (
+    "CALLS", "IMPORTS", "IMPORTS_FROM", "IMPORTS_DEFINITION", "METHOD_OF", "CONTAINS", "DEFINES",
+    "EXTENDS", "IMPLEMENTS", "EMBEDS", "CONSTRAINED_BY", "DECORATED_BY", "DEPENDS_ON_FILE", "DEPENDS_ON",
+    "CROSS_LANG_CALLS", "DEFINES_SERVICE", "DEFINES_MESSAGE", "INHERITS_FROM", "MIXES_IN",
+    "BELONGS_TO", "HAS_ONE", "HAS_MANY", "HAS_AND_BELONGS_TO_MANY", "EXTENSION_OF",
+)
+
+# Shorthand edge types accepted by the tool
+PATH_RELATION_ALIASES = {"IMPORTS": ["IMPORTS", "IMPORTS_FROM", "IMPORTS_DEFINITION"]}
+
+# Traversal direction -> GraphStore direction
+DIRECTIONS = {
+    "forward": "out",
+    "reverse": "in",
+    "undirected": "both",
+}
+
+DEFAULT_MAX_DEPTH = 10
+MAX_DEPTH_LIMIT = 25
+MAX_PATHS = 20
+
+
+def path_relation_types(edge_types: Optional[List[str]]) -> List[str]:
+    """
+    Expand and validate an edge type filter.
+    
+    Raises:
+        ValueError: for a relation type that cannot be traversed
+    """
+    if not edge_types:
+        return list(DEFAULT_PATH_RELATIONS)
+    relation_types: List[str] = []
+    for edge_type in edge_types:
+        edge_type = edge_type.strip().upper()
+        expanded = PATH_RELATION_ALIASES.get(edge_type, [edge_type])
+        unknown = [relation for relation in expanded if relation not in PATH_RELATIONS]
+        if unknown:
+            raise ValueError(f"Unknown edge type '{edge_type}', expected one of: "
+                             f"{', '.join(dict.fromkeys(list(PATH_RELATIONS) + list(PATH_RELATION_ALIASES)))}")
+        relation_types.extend(expanded)
+    return list(dict.fromkeys(relation_types))
+
+
+def store_direction(direction: str, max_depth: int) -> str:
+    """
+    Validate the traversal options and return the GraphStore direction.
+    
+    Raises:
+        ValueError: for an unknown direction or a depth outside 1..MAX_DEPTH_LIMIT
+    """
+    if direction not in DIRECTIONS:
+        raise ValueError(f"Unknown direction '{direction}', expected one of: {', '.join(DIRECTIONS)}")
+    if not 1 <= max_depth <= MAX_DEPTH_LIMIT:
+        raise ValueError(f"max_depth must be between 1 and {MAX_DEPTH_LIMIT}")
+    return DIRECTIONS[direction]
+
+
+def _endpoint(node: Dict[str, Any]) -> Dict[str, Any]:
+    return {
+        "id": node.get("id"),
+        "symbol_id": node.get("symbol_id"),
+        "name": node.get("name"),
+        "node_type": node.get("node_type") or node_type_from_labels(node.get("labels")),
+        "file_path": node.get("file_path"),
+        "line_no": node.get("line_no"),
+    }
+
+
+def _path_node(record: Dict[str, Any]) -> Dict[str, Any]:
+    return dict(record["properties"], labels=record["labels"])
+
+
+def format_path(path: Dict[str, Any]) -> Dict[str, Any]:
+    """
+    Turn a GraphStore path into hops in traversal order.
+    
+    A hop whose edge points against the traversal (reverse or undirected
+    mode) is marked with "backward"; its location is still taken from the
+    edge's source node, where the call or import is written.
+    """
+    nodes = [_path_node(record) for record in path["nodes"]]
+    by_id = {node["id"]: node for node in nodes}
+    hops = []
+    for index, relation in enumerate(path["relationships"]):
+        here, there = nodes[index], nodes[index + 1]
+        origin = by_id.get(relation["start_node_id"], here)
+        hops.append({
+            "from": _endpoint(here),
+            "to": _endpoint(there),
+            "relation_type": relation["type"],
+            "backward": relation["start_node_id"] != here["id"],
+            "file_path": origin.get("file_path"),
+            "line_no": relation["properties"].get("line_no") or origin.get("line_no"),
+            **edge_provenance(relation),
+        })
+    return {"length": len(hops), "hops": hops}
+
+
+def _candidate_summary(candidate: Dict[str, Any]) -> Dict[str, Any]:
+    return dict(_endpoint(candidate), qualified_name=qualified_name(candidate))
+
+
+def find_paths(db, source: str, target: str, edge_types: Optional[List[str]] = None,
+               direction: str = "forward", max_depth: int = DEFAULT_MAX_DEPTH, limit: int = 1,
+               min_confidence: float = 0.0) -> Dict[str, Any]:
+    """
+    Shortest paths from source to target.
+    
+    Args:
+        db: GraphStore backend
+        source: Start symbol (name, qualified name or node id)
+        target: End symbol (name, qualified name or node id)
+        edge_types: Relation types to traverse (default: CALLS, IMPORTS_*, METHOD_OF, CROSS_LANG_CALLS)
+        direction: "forward" (source reaches target), "reverse" (target reaches source) or "undirected"
+        max_depth: Maximum number of hops
+        limit: Number of paths; more than one returns the equally short alternatives
+        min_confidence: Edges with a lower confidence are not traversed (1.0: exact facts only)
+    
+    Returns:
+        Structured result with status "ok", "no_path", "ambiguous" or "not_found"
+    """
+    relation_types = path_relation_types(edge_types)
+    limit = max(1, min(int(limit), MAX_PATHS))
+    traversal = store_direction(direction, int(max_depth))
+    min_confidence = check_min_confidence(min_confidence)
+    
+    endpoints = {}
+    for role, symbol in (("source", source), ("target", target)):
+        candidates = find_symbol_candidates(db, symbol)
+        if not candidates:
+            return {"status": "not_found", "endpoint": role, "symbol": symbol,
+                    "message": f"No symbol matches {role} '{symbol}'"}
+        if len(candidates) > 1:
+            return {"status": "ambiguous", "endpoint": role, "symbol": symbol,
+                    "message": f"Several symbols match {role} '{symbol}'; call again with a symbol_id, qualified_name or id",
+                    "candidates": [_candidate_summary(c) for c in candidates]}
+        endpoints[role] = candidates[0]
+    
+    result = {
+        "source": _candidate_summary(endpoints["source"]),
+        "target": _candidate_summary(endpoints["target"]),
+        "direction": direction,
+        "edge_types": relation_types,
+        "max_depth": int(max_depth),
+        "min_confidence": min_confidence,
+    }
+    if endpoints["source"]["id"] == endpoints["target"]["id"]:
+        return dict(result, status="ok", paths=[{"length": 0, "hops": []}])
+    
+    paths = db.shortest_paths(endpoints["source"]["id"], endpoints["target"]["id"], relation_types,
+                              direction=traversal, max_depth=int(max_depth), limit=limit,
+                              min_confidence=min_confidence)
+    if not paths:
+        message = f"No path of at most {int(max_depth)} hops over {', '.join(relation_types)} ({direction})"
+        if min_confidence > 0:
+            message += f" on edges with confidence >= {min_confidence}"
+        return dict(result, status="no_path", paths=[], message=message)
+    return dict(result, status="ok", paths=[format_path(path) for path in paths])
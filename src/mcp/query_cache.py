@@ -0,0 +1,442 @@
+"""
+Result cache of the MCP query tools.
+
+A result is keyed by the tool name, its parameters as canonical JSON and
+the graph generation, a counter bumped by every unscoped change (see
+GraphChange in src/indexing/coordination.py): a full re-index, a cleared,
+migrated or deleted repository, or a failed apply empties the cache and
+starts a new generation.
+
+An incremental run only invalidates the results it may have changed. The
+tool runs against a ScopeRecorder wrapped around the store, which records
+what the result was read from:
+
+- files: the file_path of every node record read
+- keys: the IDs of the nodes read and the values looked up (names, other
+  property values, IDs asked for)
+- prefixes: the path_prefixes looked up
+- labels: the labels listed without any other filter ("*" for any label)
+- shared: a node without a file (package, placeholder) was read
+- whole_graph: a store method the recorder cannot scope was called
+  (searches, queries), so any change may change the result
+
+A shortest_paths result is scoped to the nodes on the paths returned and
+their files, its endpoints included: a change re-indexing any of them
+drops it, so does a new edge out of the source or into the target. A
+shorter path made only of edges written elsewhere is not seen until then.
+No path found reads the whole graph, as any edge written may connect the
+two.
+
+A change invalidates an entry when one of its files is a file of the
+entry, or lies under one of its prefixes, when one of its keys or labels
+is one of the entry's, when it deleted or linked nodes without a file and
+the entry read one, or when the entry reads the whole graph. IDs compare
+by their local part (src/graph_store/repository.py), so a change of one
+repository may invalidate a result of another sharing an ID, never the
+other way round. A run that starts before a change stores nothing.
+
+With QUERY_CACHE_PATH the entries are also written to a SQLite file and
+loaded at start-up, so a restarted server keeps them. The file records a
+stamp of the graph (the IndexMetadata of every root and the schema
+version): when it does not match the graph the server opens, because
+something re-indexed it meanwhile, the stored entries are dropped.
+"""
+
+import hashlib
+import json
+import logging
+import os
+import sqlite3
+import threading
+import time
+from collections import OrderedDict
+from typing import Any, Callable, Dict, Iterable, List, Optional, Set
+
+from src.graph_store.migrations import read_schema_version
+from src.graph_store.repository import split_id
+from src.indexing.coordination import GraphChange
+
+logger = logging.getLogger(__name__)
+
+DEFAULT_MAX_ENTRIES = 1000
+
+# Label of a listing without a label filter
+ANY_LABEL = "*"
+
+_SCHEMA = """
+CREATE TABLE IF NOT EXISTS entries (key TEXT PRIMARY KEY, tool TEXT, result TEXT, scope TEXT, created REAL);
+CREATE TABLE IF NOT EXISTS meta (name TEXT PRIMARY KEY, value TEXT);
+"""
+
+
+def get_query_cache_enabled(enabled: Optional[bool] = None) -> bool:
+    """Whether query results are cached, if None, get from QUERY_CACHE (default: true)."""
+    if enabled is not None:
+        return enabled
+    return os.getenv("QUERY_CACHE", "true").lower() == "true"
+
+
+def get_query_cache_path(path: Optional[str] = None) -> Optional[str]:
+    """SQLite file keeping the cache across restarts, if None, get from QUERY_CACHE_PATH (default: memory only)."""
+    return path or os.getenv("QUERY_CACHE_PATH") or None
+
+
+def get_query_cache_max_entries(max_entries: Optional[int] = None) -> int:
+    """Most results kept (least recently used go first), if None, get from QUERY_CACHE_MAX_ENTRIES (default 1000)."""
+    if max_entries is not None:
+        return max(1, max_entries)
+    value = os.getenv("QUERY_CACHE_MAX_ENTRIES", "")
+    if value:
+        try:
+            return max(1, int(value))
+        except ValueError:
+            logger.warning(f"Invalid QUERY_CACHE_MAX_ENTRIES value '{value}', using {DEFAULT_MAX_ENTRIES}")
+    return DEFAULT_MAX_ENTRIES
+
+
+def _local(key: str) -> str:
+    return split_id(key)[1]
+
+
+def _under(file_path: str, prefix: str) -> bool:
+    prefix = prefix.rstrip("/")
+    return not prefix or file_path == prefix or file_path.startswith(prefix + "/")
+
+
+class Scope:
+    """What a cached result was read from, see the module docstring."""
+    
+    def __init__(self):
+        self.files: Set[str] = set()
+        self.keys: Set[str] = set()
+        self.prefixes: Set[str] = set()
+        self.labels: Set[str] = set()
+        self.shared = False
+        self.whole_graph = False
+    
+    def add_key(self, key: Any) -> None:
+        if isinstance(key, str):
+            self.keys.add(_local(key))
+    
+    def add_record(self, record: Optional[Dict[str, Any]]) -> None:
+        """Node record read."""
+        if not record:
+            return
+        properties = record.get("properties") or {}
+        self.add_key(properties.get("id"))
+        if properties.get("file_path"):
+            self.files.add(properties["file_path"])
+        else:
+            self.shared = True
+    
+    def touched_by(self, change: GraphChange) -> bool:
+        """Whether the change may change a result read from this scope."""
+        if self.whole_graph or not change.scoped:
+            return True
+        if self.shared and change.shared:
+            return True
+        if self.files & change.files:
+            return True
+        if self.keys and self.keys & {_local(key) for key in change.keys}:
+            return True
+        if self.labels and (ANY_LABEL in self.labels and change.labels or self.labels & change.labels):
+            return True
+        return any(_under(file_path, prefix) for prefix in self.prefixes for file_path in change.files)
+    
+    def to_dict(self) -> Dict[str, Any]:
+        return {
+            "files": sorted(self.files),
+            "keys": sorted(self.keys),
+            "prefixes": sorted(self.prefixes),
+            "labels": sorted(self.labels),
+            "shared": self.shared,
+            "whole_graph": self.whole_graph,
+        }
+    
+    @classmethod
+    def from_dict(cls, data: Dict[str, Any]) -> "Scope":
+        scope = cls()
+        scope.files = set(data.get("files") or [])
+        scope.keys = set(data.get("keys") or [])
+        scope.prefixes = set(data.get("prefixes") or [])
+        scope.labels = set(data.get("labels") or [])
+        scope.shared = bool(data.get("shared"))
+        scope.whole_graph = bool(data.get("whole_graph"))
+        return scope
+
+
+class ScopeRecorder:
+    """
+    GraphStore a cached tool reads through; records the Scope of its result.
+    
+    The query primitives get_nodes, find_nodes, node_names, neighbors,
+    shortest_paths and edges_between record what they are asked and what
+    they return; any other method is passed to the store and marks the
+    scope whole_graph.
+    """
+    
+    def __init__(self, store, scope: Optional[Scope] = None):
+        self._store = store
+        self.scope = scope or Scope()
+    
+    def get_nodes(self, node_ids: List[str]) -> List[Dict[str, Any]]:
+        for node_id in node_ids:
+            self.scope.add_key(node_id)
+        return self._records(self._store.get_nodes(node_ids))
+    
+    def find_nodes(self, name: Optional[str] = None, label: Optional[str] = None,
+                   properties: Optional[Dict[str, Any]] = None, path_prefixes: Optional[List[str]] = None,
+                   limit: Optional[int] = None, repo: Optional[str] = None) -> List[Dict[str, Any]]:
+        # A node written later matches the most selective filter given
+        if name is not None or properties:
+            self.scope.add_key(name)
+            for value in (properties or {}).values():
+                self.scope.add_key(value if isinstance(value, str) else json.dumps(value))
+        elif path_prefixes:
+            self.scope.prefixes.update(path_prefixes)
+        else:
+            self.scope.labels.add(label or ANY_LABEL)
+        return self._records(self._store.find_nodes(name=name, label=label, properties=properties,
+                                                    path_prefixes=path_prefixes, limit=limit, repo=repo))
+    
+    def node_names(self, labels: List[str], limit: Optional[int] = None, repo: Optional[str] = None) -> List[str]:
+        self.scope.labels.update(labels or [ANY_LABEL])
+        return self._store.node_names(labels, limit=limit, repo=repo)
+    
+    def neighbors(self, node_ids: List[str], relation_types: Optional[List[str]] = None, direction: str = "out",
+                  label: Optional[str] = None) -> List[Dict[str, Any]]:
+        for node_id in node_ids:
+            self.scope.add_key(node_id)
+        rows = self._store.neighbors(node_ids, relation_types=relation_types, direction=direction, label=label)
+        for row in rows:
+            self.scope.add_record(row.get("node"))
+        return rows
+    
+    def shortest_paths(self, source_id: str, target_id: str, relation_types: List[str], direction: str = "out",
+                       max_depth: int = 10, limit: int = 1, min_confidence: float = 0.0) -> List[Dict[str, Any]]:
+        self.scope.add_key(source_id)
+        self.scope.add_key(target_id)
+        paths = self._store.shortest_paths(source_id, target_id, relation_types, direction=direction,
+                                           max_depth=max_depth, limit=limit, min_confidence=min_confidence)
+        if not paths:
+            self.scope.whole_graph = True
+        for path in paths:
+            self._records(path.get("nodes") or [])
+        return paths
+    
+    def edges_between(self, node_ids: List[str]) -> List[Dict[str, Any]]:
+        for node_id in node_ids:
+            self.scope.add_key(node_id)
+        return self._store.edges_between(node_ids)
+    
+    def close(self) -> None:
+        """The store is the server's; a tool closing it when done (as the CLIs do) leaves it open."""
+    
+    def _records(self, records: List[Dict[str, Any]]) -> List[Dict[str, Any]]:
+        for record in records:
+            self.scope.add_record(record)
+        return records
+    
+    def __getattr__(self, name: str) -> Any:
+        value = getattr(self._store, name)
+        if not callable(value):
+            return value
+        
+        def call(*args, **kwargs):
+            self.scope.whole_graph = True
+            return value(*args, **kwargs)
+        return call
+
+
+class QueryCache:
+    """
+    Results of the cached MCP tools, in process and optionally in a SQLite file.
+    
+    Thread-safe; tools run on worker threads and the change listener on the
+    thread of the index run.
+    """
+    
+    def __init__(self, store=None, path: Optional[str] = None, max_entries: Optional[int] = None,
+                 enabled: Optional[bool] = None):
+        """
+        Args:
+            store: GraphStore the results are read from; its IndexMetadata stamps the SQLite file
+            path: SQLite file keeping the entries across restarts, if None, get from QUERY_CACHE_PATH (memory only)
+            max_entries: Most results kept, if None, get from QUERY_CACHE_MAX_ENTRIES (default 1000)
+            enabled: Whether results are cached, if None, get from QUERY_CACHE (default: true)
+        """
+        self.store = store
+        self.enabled = get_query_cache_enabled(enabled)
+        self.path = get_query_cache_path(path) if self.enabled else None
+        self.max_entries = get_query_cache_max_entries(max_entries)
+        self.generation = 0
+        self.hits = 0
+        self.misses = 0
+        self.invalidations = 0
+        # Bumped by every change: a result computed across one is not stored
+        self._epoch = 0
+        self._entries: "OrderedDict[str, Dict[str, Any]]" = OrderedDict()
+        self._lock = threading.RLock()
+        self._db: Optional[sqlite3.Connection] = None
+        if self.path:
+            self._open(self.path)
+    
+    def key(self, tool: str, params: Dict[str, Any]) -> str:
+        """Cache key of a call: tool name, canonical JSON of its parameters and the graph generation."""
+        canonical = json.dumps(params, sort_keys=True, separators=(",", ":"), ensure_ascii=False, default=str)
+        return hashlib.sha256(f"{tool}\0{canonical}\0{self.generation}".encode("utf-8")).hexdigest()
+    
+    def run(self, tool: str, params: Dict[str, Any], store, compute: Callable[[Any], Any],
+            refresh: bool = False) -> Any:
+        """
+        Cached result of a tool call, computed on a miss.
+        
+        Args:
+            tool: Tool name
+            params: Every parameter the result depends on
+            store: GraphStore the tool reads
+            compute: Computes the result (JSON-serializable) from the store given
+            refresh: Compute it even when cached (no_cache), storing the fresh result
+        """
+        if not self.enabled:
+            return compute(store)
+        with self._lock:
+            key = self.key(tool, params)
+            entry = None if refresh else self._entries.get(key)
+            if entry is not None:
+                self._entries.move_to_end(key)
+                self.hits += 1
+                return json.loads(entry["result"])
+            self.misses += 1
+            epoch = self._epoch
+        
+        recorder = ScopeRecorder(store)
+        result = compute(recorder)
+        entry = {"tool": tool, "result": json.dumps(result, ensure_ascii=False), "scope": recorder.scope,
+                 "created": time.time()}
+        with self._lock:
+            if epoch == self._epoch:
+                self._put(key, entry)
+        return result
+    
+    def invalidate(self, change: GraphChange) -> int:
+        """Drop the entries the change may have changed (coordinator listener); return the count."""
+        with self._lock:
+            self._epoch += 1
+            if not change.scoped:
+                dropped = len(self._entries)
+                self.generation += 1
+                self._entries.clear()
+                self._execute("DELETE FROM entries")
+            else:
+                stale = [key for key, entry in self._entries.items() if entry["scope"].touched_by(change)]
+                for key in stale:
+                    del self._entries[key]
+                self._executemany("DELETE FROM entries WHERE key = ?", [(key,) for key in stale])
+                dropped = len(stale)
+            self.invalidations += dropped
+            self._save_meta()
+        if dropped:
+            logger.info(f"Query cache: {dropped} results invalidated by a change of repository {change.repo!r}")
+        return dropped
+    
+    def clear(self) -> None:
+        """Drop every entry and start a new generation."""
+        self.invalidate(GraphChange(""))
+    
+    def stats(self) -> Dict[str, Any]:
+        with self._lock:
+            return {
+                "enabled": self.enabled,
+                "entries": len(self._entries),
+                "max_entries": self.max_entries,
+                "hits": self.hits,
+                "misses": self.misses,
+                "invalidations": self.invalidations,
+                "generation": self.generation,
+                "persistent_path": self.path,
+            }
+    
+    def close(self) -> None:
+        with self._lock:
+            if self._db is not None:
+                self._db.close()
+                self._db = None
+    
+    def _put(self, key: str, entry: Dict[str, Any]) -> None:
+        self._entries[key] = entry
+        self._entries.move_to_end(key)
+        evicted = []
+        while len(self._entries) > self.max_entries:
+            evicted.append((self._entries.popitem(last=False)[0],))
+        self._execute("INSERT OR REPLACE INTO entries (key, tool, result, scope, created) VALUES (?, ?, ?, ?, ?)",
+                      (key, entry["tool"], entry["result"], json.dumps(entry["scope"].to_dict()), entry["created"]))
+        self._executemany("DELETE FROM entries WHERE key = ?", evicted)
+    
+    # Persistent layer
+    
+    def _open(self, path: str) -> None:
+        try:
+            directory = os.path.dirname(os.path.abspath(path))
+            os.makedirs(directory, exist_ok=True)
+            self._db = sqlite3.connect(path, check_same_thread=False)
+            self._db.executescript(_SCHEMA)
+            meta = dict(self._db.execute("SELECT name, value FROM meta").fetchall())
+            self.generation = int(meta.get("generation") or 0)
+            stamp = self._stamp()
+            if stamp is not None and meta.get("stamp") != stamp:
+                if meta.get("stamp") is not None:
+                    logger.info(f"Query cache {path} was written for another state of the graph, dropping it")
+                self.generation += 1
+                self._db.execute("DELETE FROM entries")
+                self._save_meta(stamp)
+            rows = self._db.execute("SELECT key, tool, result, scope, created FROM entries ORDER BY created").fetchall()
+            for key, tool, result, scope, created in rows[-self.max_entries:]:
+                self._entries[key] = {"tool": tool, "result": result, "scope": Scope.from_dict(json.loads(scope)),
+                                      "created": created}
+            self._db.commit()
+            logger.info(f"Query cache {path}: {len(self._entries)} results loaded")
+        except (sqlite3.Error, OSError, ValueError) as e:
+            logger.warning(f"Cannot open the query cache {path}, caching in memory only: {e}")
+            if self._db is not None:
+                self._db.close()
+            self._db = None
+            self._entries.clear()
+    
+    def _stamp(self) -> Optional[str]:
+        """State of the graph the entries were computed on, None without a store."""
+        if self.store is None:
+            return None
+        try:
+            runs = sorted(
+                (str(record["properties"].get("repo")), str(record["properties"].get("root")),
+                 str(record["properties"].get("indexed_at")), str(record["properties"].get("index_complete")))
+                for record in self.store.find_nodes(label="IndexMetadata")
+            )
+            return json.dumps({"schema_version": read_schema_version(self.store), "runs": runs})
+        except Exception as e:
+            logger.warning(f"Cannot read the index metadata for the query cache stamp: {e}")
+            return None
+    
+    def _save_meta(self, stamp: Optional[str] = None) -> None:
+        if self._db is None:
+            return
+        stamp = stamp if stamp is not None else self._stamp()
+        values = [("generation", str(self.generation))]
+        if stamp is not None:
+            values.append(("stamp", stamp))
+        self._executemany("INSERT OR REPLACE INTO meta (name, value) VALUES (?, ?)", values)
+    
+    def _execute(self, statement: str, parameters: Iterable[Any] = ()) -> None:
+        self._executemany(statement, [tuple(parameters)])
+    
+    def _executemany(self, statement: str, rows: List[Any]) -> None:
+        if self._db is None or not rows:
+            return
+        try:
+            self._db.executemany(statement, rows)
+            self._db.commit()
+        except sqlite3.Error as e:
+            logger.warning(f"Query cache {self.path}: write failed, caching in memory only: {e}")
+            self._db.close()
+            self._db = None
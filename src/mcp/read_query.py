@@ -0,0 +1,151 @@
+"""
+Helpers for the run_query MCP tool.
+
+run_query runs a Cypher query the fixed tools do not cover, read-only on
+two levels:
+
+- check_read_only rejects a query with a clause that can write: CREATE,
+  MERGE, DELETE, DETACH, SET, REMOVE, FOREACH, LOAD CSV, the schema and
+  administration commands (DROP, ALTER, GRANT, ...) and a CALL of any
+  procedure outside READ_PROCEDURES. Keywords count outside string
+  literals, comments and backtick-quoted names, and not as property keys
+  (`n.set`), labels (`:Set`) or parameters (`$create`)
+- the backend runs the query in a read transaction, so a write that slips
+  past the check still fails in the database
+
+At most `limit` rows come back (default 500, at most QUERY_MAX_ROWS) and
+the database cancels a query running longer than QUERY_TIMEOUT seconds.
+The in-memory backend does not speak Cypher and raises
+UnsupportedQueryError.
+"""
+
+import logging
+import os
+import re
+import time
+from typing import Any, Dict, Optional
+
+logger = logging.getLogger(__name__)
+
+DEFAULT_LIMIT = 500
+DEFAULT_MAX_ROWS = 5000
+DEFAULT_TIMEOUT = 30.0
+
+# Clauses and commands that write data, schema or settings
+WRITE_KEYWORDS = ("CREATE", "MERGE", "DELETE", "DETACH", "SET", "REMOVE", "FOREACH", "DROP", "ALTER", "RENAME",
+                  "GRANT", "DENY", "REVOKE", "START", "STOP", "TERMINATE")
+
+# Procedures a read-only query may CALL, by full name or by namespace
+READ_PROCEDURES = (
+    "db.labels",
+    "db.relationshipTypes",
+    "db.propertyKeys",
+    "db.indexes",
+    "db.constraints",
+    "db.schema.visualization",
+    "db.schema.nodeTypeProperties",
+    "db.schema.relTypeProperties",
+    "db.index.fulltext.queryNodes",
+    "db.index.fulltext.queryRelationships",
+    "db.index.vector.queryNodes",
+)
+READ_PROCEDURE_NAMESPACES = ("apoc.meta.", "apoc.path.")
+
+# String literals, comments and backtick-quoted names, blanked out before keywords are looked for
+_LITERALS = re.compile(r"'(?:\\.|[^'\\])*'|\"(?:\\.|[^\"\\])*\"|`[^`]*`|//[^\n]*|/\*.*?\*/", re.S)
+_WRITE_KEYWORD = re.compile(r"(?<![\w.$:])(" + "|".join(WRITE_KEYWORDS) + r"|LOAD\s+CSV)(?!\w)", re.I)
+_CALL = re.compile(r"(?<![\w.$])CALL\s+([A-Za-z_][\w.]*)", re.I)
+
+
+def get_max_rows() -> int:
+    """Read QUERY_MAX_ROWS (default 5000), the highest run_query limit."""
+    value = os.getenv("QUERY_MAX_ROWS", "")
+    if value:
+        try:
+            return max(1, int(value))
+        except ValueError:
+            logger.warning(f"Invalid QUERY_MAX_ROWS value '{value}', using {DEFAULT_MAX_ROWS}")
+    return DEFAULT_MAX_ROWS
+
+
+def get_query_timeout() -> float:
+    """Read QUERY_TIMEOUT (default 30), the seconds a run_query query may run."""
+    value = os.getenv("QUERY_TIMEOUT", "")
+    if value:
+        try:
+            timeout = float(value)
+            if timeout > 0:
+                return timeout
+        except ValueError:
+            pass
+        logger.warning(f"Invalid QUERY_TIMEOUT value '{value}', using {DEFAULT_TIMEOUT:g}")
+    return DEFAULT_TIMEOUT
+
+
+def _is_read_procedure(name: str) -> bool:
+    return name in READ_PROCEDURES or name.startswith(READ_PROCEDURE_NAMESPACES)
+
+
+def check_read_only(query: str) -> None:
+    """
+    Reject a query that may write.
+    
+    Raises:
+        ValueError: naming the first write clause or the procedure that is not known to be read-only
+    """
+    code = _LITERALS.sub(lambda match: " " * len(match.group(0)), query)
+    if not code.strip():
+        raise ValueError("Empty query")
+    match = _WRITE_KEYWORD.search(code)
+    if match:
+        clause = " ".join(match.group(1).upper().split())
+        raise ValueError(f"run_query is read-only, the query contains {clause}")
+    for match in _CALL.finditer(code):
+        if not _is_read_procedure(match.group(1)):
+            raise ValueError(f"run_query is read-only, {match.group(1)} is not a known read-only procedure")
+
+
+def run_query(db, query: str, parameters: Optional[Dict[str, Any]] = None, limit: int = DEFAULT_LIMIT,
+              timeout: Optional[float] = None) -> Dict[str, Any]:
+    """
+    Run a read-only Cypher query.
+    
+    Args:
+        db: GraphStore backend
+        query: Cypher query
+        parameters: Query parameters
+        limit: Rows returned at most, 1..QUERY_MAX_ROWS
+        timeout: Seconds the query may run, if None, get from QUERY_TIMEOUT (at most QUERY_TIMEOUT)
+    
+    Returns:
+        {"rows", "row_count", "truncated", "metadata": {"query", "parameters", "limit", "timeout_seconds",
+        "elapsed_ms"}}
+    
+    Raises:
+        ValueError: for a query that may write, or a limit outside 1..QUERY_MAX_ROWS
+        UnsupportedQueryError: on a backend that does not run Cypher
+    """
+    max_rows = get_max_rows()
+    limit = int(limit)
+    if not 1 <= limit <= max_rows:
+        raise ValueError(f"limit must be between 1 and {max_rows} (QUERY_MAX_ROWS)")
+    max_timeout = get_query_timeout()
+    timeout = max_timeout if timeout is None else min(max(float(timeout), 0.001), max_timeout)
+    check_read_only(query)
+    
+    started = time.perf_counter()
+    # One row more than the limit tells whether the result was cut
+    rows = db.execute_read_query(query, parameters or {}, max_rows=limit + 1, timeout=timeout)
+    elapsed_ms = (time.perf_counter() - started) * 1000
+    return {
+        "rows": rows[:limit],
+        "row_count": min(len(rows), limit),
+        "truncated": len(rows) > limit,
+        "metadata": {
+            "query": query,
+            "parameters": parameters or {},
+            "limit": limit,
+            "timeout_seconds": timeout,
+            "elapsed_ms": round(elapsed_ms, 1),
+        },
+    }
@@ -0,0 +1,192 @@
+"""
+Helpers for the find_references MCP tool.
+
+Resolves a stable symbol ID, a node id or a (possibly qualified) symbol
+name to candidate nodes and formats referencing nodes with a snippet of
+the referencing line.
+"""
+
+import os
+from typing import Any, Dict, List, Optional, Tuple
+
+from src.indexing.symbol_ids import SYMBOL_ID_PROPERTY
+
+# Reference kind -> relation types pointing at the referenced symbol
+REFERENCE_KINDS: Dict[str, List[str]] = {
+    "calls": ["CALLS"],
+    "imports": ["IMPORTS_DEFINITION", "IMPORTS_FROM"],
+    "implements": ["IMPLEMENTS", "EXTENDS", "MIXES_IN"],
+    "type_usage": ["METHOD_OF", "EXTENSION_OF"],
+    "decorators": ["DECORATED_BY"],
+    "uses": ["USES"],
+}
+
+# Maximum length of a returned snippet
+SNIPPET_MAX_LENGTH = 200
+
+def relation_types_for_kind(kind: Optional[str]) -> List[str]:
+    """
+    Map a reference kind filter to relation types.
+    
+    Raises:
+        ValueError: for an unknown kind
+    """
+    if not kind:
+        return [relation for relations in REFERENCE_KINDS.values() for relation in relations]
+    if kind not in REFERENCE_KINDS:
+        raise ValueError(f"Unknown reference kind '{kind}', expected one of: {', '.join(REFERENCE_KINDS)}")
+    return list(REFERENCE_KINDS[kind])
+
+
+def split_qualified_name(symbol: str) -> Tuple[Optional[str], str]:
+    """
+    Split "qualifier.Name" into (qualifier, name).
+    
+    The qualifier may be a module, package, import path or owning type,
+    e.g. "utils.parse", "github.com/acme/api.NewClient", "Person.GetName".
+    """
+    if "." not in symbol:
+        return None, symbol
+    qualifier, name = symbol.rsplit(".", 1)
+    return (qualifier or None), name
+
+
+def _dotted_path(path: str) -> str:
+    return ".".join(part for part in path.replace("\\", "/").split("/") if part)
+
+
+def _ends_with_segments(dotted: str, qualifier: str) -> bool:
+    return dotted == qualifier or dotted.endswith("." + qualifier)
+
+
+def _module_matches(candidate: Dict[str, Any], qualifier: str) -> bool:
+    """Check a module/package qualifier against the candidate's file or import path."""
+    import_path = candidate.get("import_path")
+    if import_path and (import_path == qualifier or import_path.endswith("/" + qualifier)):
+        return True
+    
+    file_path = candidate.get("file_path") or ""
+    if not file_path:
+        return False
+    qualifier = qualifier.replace("/", ".")
+    module_path = _dotted_path(os.path.splitext(file_path)[0])
+    package_path = _dotted_path(os.path.dirname(file_path))
+    return _ends_with_segments(module_path, qualifier) or _ends_with_segments(package_path, qualifier)
+
+
+def qualifier_matches(candidate: Dict[str, Any], qualifier: str) -> bool:
+    """
+    Check whether a qualifier selects the candidate.
+    
+    Accepted forms: owner type ("Person"), module or package ("utils",
+    "src.utils", "api/v1"), import path, or module plus owner ("models.Person").
+    """
+    owner = candidate.get("owner")
+    if owner:
+        if qualifier == owner:
+            return True
+        if qualifier.endswith("." + owner) and _module_matches(candidate, qualifier[:-len(owner) - 1]):
+            return True
+    return _module_matches(candidate, qualifier)
+
+
+def module_label(candidate: Dict[str, Any]) -> Optional[str]:
+    """Short module/package label of a candidate (Go: package directory, others: file stem)."""
+    if candidate.get("import_path"):
+        return candidate["import_path"]
+    file_path = candidate.get("file_path") or ""
+    if not file_path:
+        return None
+    if file_path.endswith(".go"):
+        return os.path.basename(os.path.dirname(file_path)) or None
+    return os.path.splitext(os.path.basename(file_path))[0]
+
+
+def qualified_name(candidate: Dict[str, Any]) -> str:
+    """Name that can be passed back to find_references to select this candidate."""
+    parts = [module_label(candidate), candidate.get("owner"), candidate.get("name")]
+    return ".".join(part for part in parts if part)
+
+
+def read_source_line(file_path: Optional[str], line_no: Optional[int], cache: Optional[Dict[str, List[str]]] = None) -> Optional[str]:
+    """
+    Return the stripped source line (1-based), truncated to SNIPPET_MAX_LENGTH.
+    
+    Returns None when the file cannot be read or the line does not exist.
+    """
+    if not file_path or not line_no or line_no < 1:
+        return None
+    cache = cache if cache is not None else {}
+    if file_path not in cache:
+        try:
+            with open(file_path, "r", encoding="utf-8", errors="replace") as f:
+                cache[file_path] = f.read().splitlines()
+        except OSError:
+            cache[file_path] = []
+    lines = cache[file_path]
+    if line_no > len(lines):
+        return None
+    line = lines[line_no - 1].strip()
+    if len(line) > SNIPPET_MAX_LENGTH:
+        line = line[:SNIPPET_MAX_LENGTH - 3] + "..."
+    return line
+
+
+def node_type_from_labels(labels: Optional[List[str]]) -> Optional[str]:
+    """First label other than Base."""
+    for label in labels or []:
+        if label != "Base":
+            return label
+    return None
+
+
+def symbol_candidates(db, nodes: List[Dict[str, Any]]) -> List[Dict[str, Any]]:
+    """Candidate rows of node records, with the class defining them as owner."""
+    owners: Dict[str, str] = {}
+    for row in db.neighbors([node["properties"]["id"] for node in nodes], ["DEFINES"], direction="in", label="Class"):
+        owners.setdefault(row["origin_id"], row["node"]["properties"].get("name"))
+    candidates = []
+    for node in nodes:
+        properties = node["properties"]
+        candidates.append({
+            "id": properties["id"],
+            "symbol_id": properties.get(SYMBOL_ID_PROPERTY),
+            "name": properties.get("name"),
+            "node_type": node_type_from_labels(node["labels"]),
+            "file_path": properties.get("file_path"),
+            "line_no": properties.get("line_no"),
+            "import_path": properties.get("import_path"),
+            "owner": owners.get(properties["id"]),
+        })
+    return candidates
+
+
+def find_named_nodes(db, name: Optional[str], label: Optional[str] = None,
+                     properties: Optional[Dict[str, Any]] = None, limit: Optional[int] = None) -> List[Dict[str, Any]]:
+    """Node records named name, or the one whose symbol ID name is; the other filters as in find_nodes."""
+    if name and ":" in name:
+        nodes = db.find_nodes(label=label, properties=dict(properties or {}, **{SYMBOL_ID_PROPERTY: name}), limit=1)
+        if nodes:
+            return nodes
+    return db.find_nodes(name=name, label=label, properties=properties, limit=limit)
+
+
+def find_symbol_candidates(db, symbol: str) -> List[Dict[str, Any]]:
+    """
+    Nodes selected by a symbol ID, a node id or a (possibly qualified) symbol name.
+    
+    A symbol ID or node id match wins; otherwise candidates are filtered by
+    the qualifier. More than one result means the symbol is ambiguous.
+    """
+    exact = db.get_nodes([symbol])
+    if not exact and ":" in symbol:
+        exact = db.find_nodes(properties={SYMBOL_ID_PROPERTY: symbol}, limit=1)
+    if exact:
+        return symbol_candidates(db, exact)
+    
+    qualifier, name = split_qualified_name(symbol)
+    candidates = symbol_candidates(db, db.find_nodes(name=name))
+    return [
+        c for c in candidates
+        if c["name"] == name and (not qualifier or qualifier_matches(c, qualifier))
+    ]
@@ -0,0 +1,229 @@
+"""
+Helpers for the resolve_symbol MCP tool.
+
+Turns a description of a symbol as a person writes it ("Person.GetName
+in the sample package", "the parse function of utils", "Parser::new")
+into the stable symbol IDs of the nodes it may mean (see
+src.indexing.symbol_ids), best match first. The description is read as:
+
+- the name: the last identifier before " in " / " of " / " from ";
+  written dotted or with "::", "#" or "->", the part before the name is a
+  qualifier (owner type, module or package)
+- scope hints: the identifiers after that word, matched against the
+  candidate's owner, module, package, import path or file path
+- a kind hint: "function", "method", "class", "struct", "interface"...
+
+The candidates are the nodes with exactly that name, or when there are
+none, the text search hits with that name in another case. A candidate's
+score is the share of the description it matches (name, qualifier, scope
+hints, kind), from 0 to 1; an exact symbol ID or node id scores 1.
+"""
+
+import os
+import re
+from typing import Any, Dict, List, Optional, Tuple
+
+from src.indexing.symbol_ids import SYMBOL_ID_PROPERTY
+from src.mcp.generated import is_generated_node
+from src.mcp.references import qualified_name, qualifier_matches, symbol_candidates
+
+DEFAULT_LIMIT = 10
+
+# Text search hits fetched when no node has the exact name
+TEXT_SEARCH_LIMIT = 200
+
+# Weight of each part of the description in the score
+NAME_WEIGHT = 0.4
+QUALIFIER_WEIGHT = 0.3
+SCOPE_WEIGHT = 0.2
+KIND_WEIGHT = 0.1
+
+# Share of the name weight a match in another case earns
+OTHER_CASE_SHARE = 0.75
+
+# Kind words -> node types they select
+KIND_WORDS: Dict[str, Tuple[str, ...]] = {
+    "function": ("Function",),
+    "func": ("Function",),
+    "method": ("Method",),
+    "class": ("Class",),
+    "struct": ("Class",),
+    "record": ("Class",),
+    "type": ("Class", "Interface", "Enum", "TypeAlias"),
+    "interface": ("Interface",),
+    "trait": ("Interface",),
+    "enum": ("Enum",),
+    "constant": ("Constant",),
+    "const": ("Constant",),
+    "variable": ("Variable", "GlobalVariable", "ClassVariable"),
+    "var": ("Variable", "GlobalVariable", "ClassVariable"),
+    "field": ("ClassVariable",),
+    "property": ("ClassVariable",),
+    "file": ("File",),
+}
+
+# Words that are neither a name nor a scope
+STOP_WORDS = {
+    "the", "a", "an", "this", "that", "in", "of", "from", "inside", "within", "under", "on", "for",
+    "called", "named", "symbol", "package", "module", "directory", "dir", "folder", "namespace",
+    "crate", "repo", "repository", "file",
+}
+
+_SCOPE_SEPARATOR = re.compile(r"\s+(?:in|of|from|inside|within|under)\s+", re.IGNORECASE)
+_IDENTIFIER = re.compile(r"[A-Za-z_$][\w$]*(?:(?:\.|::|#|->|/)[A-Za-z_$][\w$]*)*")
+_QUALIFIER_SEPARATOR = re.compile(r"::|#|->")
+
+
+def parse_query(query: str) -> Dict[str, Any]:
+    """
+    Split a description into {"name", "qualifier", "scopes", "kinds"}.
+    
+    Raises:
+        ValueError: when the description names no identifier
+    """
+    parts = _SCOPE_SEPARATOR.split(query.strip(), maxsplit=1)
+    symbol_part, scope_part = parts[0], (parts[1] if len(parts) > 1 else "")
+    
+    kinds: List[str] = []
+    identifiers: List[str] = []
+    for token in _IDENTIFIER.findall(symbol_part):
+        lowered = token.lower()
+        if lowered in KIND_WORDS:
+            kinds.extend(KIND_WORDS[lowered])
+        elif lowered not in STOP_WORDS:
+            identifiers.append(token)
+    scopes = [token for token in _IDENTIFIER.findall(scope_part)
+              if token.lower() not in STOP_WORDS and token.lower() not in KIND_WORDS]
+    if not identifiers:
+        # "the parser package": the only identifier is the scope, nothing names a symbol
+        if not scopes:
+            raise ValueError(f"No symbol name in '{query}'")
+        identifiers, scopes = scopes[-1:], scopes[:-1]
+    
+    dotted = _QUALIFIER_SEPARATOR.sub(".", identifiers[-1])
+    qualifier, _, name = dotted.rpartition(".")
+    return {
+        "name": name,
+        "qualifier": qualifier or None,
+        # Other identifiers before the name ("GetName Person") narrow it down like a scope
+        "scopes": identifiers[:-1] + scopes,
+        "kinds": list(dict.fromkeys(kinds)),
+    }
+
+
+def _segments(candidate: Dict[str, Any]) -> List[str]:
+    """Lower-case directory names, file stem, import path segments and owner of a candidate."""
+    segments = []
+    file_path = (candidate.get("file_path") or "").replace("\\", "/")
+    if file_path:
+        directory, file_name = os.path.split(file_path)
+        segments.extend(part for part in directory.split("/") if part)
+        segments.append(os.path.splitext(file_name)[0])
+    segments.extend(part for part in (candidate.get("import_path") or "").split("/") if part)
+    if candidate.get("owner"):
+        segments.append(candidate["owner"])
+    return [segment.lower() for segment in segments]
+
+
+def scope_matches(candidate: Dict[str, Any], scope: str) -> bool:
+    """Whether a qualifier or scope hint fits the candidate, in any case."""
+    if qualifier_matches(candidate, scope):
+        return True
+    segments = _segments(candidate)
+    parts = [part.lower() for part in re.split(r"[./]", scope) if part]
+    # Every part in order, e.g. "api.v1" against .../api/v1/client.go
+    for start in range(len(segments) - len(parts) + 1):
+        if segments[start:start + len(parts)] == parts:
+            return True
+    return False
+
+
+def score_candidate(candidate: Dict[str, Any], parsed: Dict[str, Any]) -> Tuple[float, List[str]]:
+    """Score from 0 to 1 and the parts of the description the candidate matches."""
+    possible = NAME_WEIGHT
+    earned = NAME_WEIGHT if candidate.get("name") == parsed["name"] else NAME_WEIGHT * OTHER_CASE_SHARE
+    matched = ["name"]
+    if parsed["qualifier"]:
+        possible += QUALIFIER_WEIGHT
+        if scope_matches(candidate, parsed["qualifier"]):
+            earned += QUALIFIER_WEIGHT
+            matched.append("qualifier")
+    if parsed["scopes"]:
+        possible += SCOPE_WEIGHT
+        found = sum(1 for scope in parsed["scopes"] if scope_matches(candidate, scope))
+        if found:
+            earned += SCOPE_WEIGHT * found / len(parsed["scopes"])
+            matched.append("scope")
+    if parsed["kinds"]:
+        possible += KIND_WEIGHT
+        if candidate.get("node_type") in parsed["kinds"]:
+            earned += KIND_WEIGHT
+            matched.append("kind")
+    return round(earned / possible, 3), matched
+
+
+def _match(candidate: Dict[str, Any], score: float, matched: List[str]) -> Dict[str, Any]:
+    return {
+        "symbol_id": candidate.get("symbol_id"),
+        "id": candidate["id"],
+        "name": candidate.get("name"),
+        "qualified_name": qualified_name(candidate),
+        "node_type": candidate.get("node_type"),
+        "file_path": candidate.get("file_path"),
+        "line_no": candidate.get("line_no"),
+        "score": score,
+        "matched": matched,
+    }
+
+
+def _other_case(db, name: str) -> List[Dict[str, Any]]:
+    """Node records whose name is name in another case, from the text search."""
+    hits = db.search_code_by_text(name, limit=TEXT_SEARCH_LIMIT)
+    ids = [hit["node"]["id"] for hit in hits if (hit["node"].get("name") or "").lower() == name.lower()]
+    return db.get_nodes(list(dict.fromkeys(ids)))
+
+
+def resolve_symbol(db, query: str, limit: int = DEFAULT_LIMIT) -> Dict[str, Any]:
+    """
+    Stable symbol IDs matching a description, best first.
+    
+    Args:
+        db: GraphStore to query
+        query: Description of the symbol, a qualified name, a symbol ID or a node id
+        limit: Maximum number of matches
+    
+    Returns:
+        {"status": "ok" | "not_found", "query", "parsed", "total", "matches"}; each match has its
+        symbol_id, id, name, qualified_name, node_type, location, score and the parts it matched
+    
+    Raises:
+        ValueError: when the query names no identifier
+    """
+    limit = max(1, int(limit))
+    query = query.strip()
+    exact = db.get_nodes([query]) if query else []
+    if not exact and ":" in query:
+        exact = db.find_nodes(properties={SYMBOL_ID_PROPERTY: query}, limit=1)
+    if exact:
+        (candidate,) = symbol_candidates(db, exact[:1])
+        return {"status": "ok", "query": query, "parsed": None, "total": 1,
+                "matches": [_match(candidate, 1.0, ["id"])]}
+    
+    parsed = parse_query(query)
+    records = db.find_nodes(name=parsed["name"]) or _other_case(db, parsed["name"])
+    # Tests and generated code break ties after the symbols they exercise or were generated from
+    demoted = {record["properties"]["id"] for record in records
+               if record["properties"].get("is_test") or is_generated_node(record["properties"])}
+    ranked = []
+    for candidate in symbol_candidates(db, records) if records else []:
+        score, matched = score_candidate(candidate, parsed)
+        ranked.append((score, matched, candidate))
+    ranked.sort(key=lambda item: (-item[0], item[2]["id"] in demoted, item[2].get("file_path") or "",
+                                  item[2].get("line_no") or 0, item[2]["id"]))
+    return {
+        "status": "ok" if ranked else "not_found",
+        "query": query,
+        "parsed": parsed,
+        "total": len(ranked),
+        "matches": [_match(candidate, score, matched) for score, matched, candidate in ranked[:limit]],
+    }
@@ -0,0 +1,78 @@
+"""
+Helpers for the semantic_search MCP tool.
+
+Embeds a natural-language query with the configured provider and returns
+the nearest Function / Method / Class / File nodes from the vector indexes,
+with their score and location. Nodes of generated files are left out
+unless include_generated is set (see src.mcp.generated).
+"""
+
+from typing import Any, Dict, List, Optional
+
+from src.ast_parser.doc_comments import truncate_doc
+from src.embeddings.node_text import EMBEDDED_NODE_TYPES
+from src.indexing.source import source_fields
+from src.mcp.generated import without_generated
+from src.mcp.references import node_type_from_labels
+
+# Node types searched when no filter is given (File nodes only carry their path)
+DEFAULT_SEARCH_TYPES = ("Function", "Method", "Class")
+
+
+def search_node_types(node_types: Optional[List[str]]) -> List[str]:
+    """
+    Validate a node type filter.
+    
+    Raises:
+        ValueError: If a type has no embeddings
+    """
+    if not node_types:
+        return list(DEFAULT_SEARCH_TYPES)
+    unknown = [node_type for node_type in node_types if node_type not in EMBEDDED_NODE_TYPES]
+    if unknown:
+        raise ValueError(f"Unknown node types {unknown}, expected a subset of {list(EMBEDDED_NODE_TYPES)}")
+    return list(dict.fromkeys(node_types))
+
+
+def format_match(match: Dict[str, Any], include_source: bool = False) -> Dict[str, Any]:
+    """Compact result entry: identity, location, score and the doc shortened to DOC_MAX_LENGTH, plus the stored source if asked."""
+    node = match["node"]
+    entry = {
+        "id": node.get("id"),
+        "symbol_id": node.get("symbol_id"),
+        "name": node.get("name"),
+        "node_type": node_type_from_labels(match.get("labels")),
+        "file_path": node.get("file_path"),
+        "line_no": node.get("line_no"),
+        "end_line_no": node.get("end_line_no"),
+        "score": round(float(match["score"]), 4),
+        "doc": truncate_doc(node.get("doc")),
+    }
+    if include_source:
+        entry.update(source_fields(node))
+    return entry
+
+
+def semantic_search(db, provider, query: str, limit: int = 10,
+                    node_types: Optional[List[str]] = None, include_source: bool = False,
+                    include_generated: bool = False) -> List[Dict[str, Any]]:
+    """
+    Return the top `limit` nodes by cosine similarity to the query.
+    
+    Args:
+        db: GraphStore backend exposing search_similar_nodes
+        provider: EmbeddingProvider used at indexing time
+        query: Natural-language description, e.g. "where do we validate email addresses"
+        limit: Maximum number of results
+        node_types: Restrict to these node types (default: Function, Method, Class)
+        include_source: Add the source stored at indexing time (see src.indexing.source)
+        include_generated: Keep nodes of generated files
+    """
+    if not query or not query.strip():
+        raise ValueError("query must not be empty")
+    if limit < 1:
+        raise ValueError("limit must be at least 1")
+    labels = search_node_types(node_types)
+    vector = provider.embed_text(query.strip())
+    matches = without_generated(lambda count: db.search_similar_nodes(vector, labels, count), limit, include_generated)
+    return [format_match(match, include_source) for match in matches]
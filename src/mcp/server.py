@@ -2,6 +2,7 @@ import os
 import argparse
 import logging
 import asyncio
+import functools
 from typing import Dict, List, Any, Optional, Tuple
 from mcp.server.fastmcp import FastMCP, Context
 from mcp.server.models import InitializationOptions
@@ -10,19 +11,92 @@ import json
 
 sys.path.append(os.path.dirname(os.path.dirname(os.path.dirname(os.path.abspath(__file__)))))
 
+from src.graph_store import (
+    ALL_REPOS,
+    STORAGE_BACKENDS,
+    InMemoryGraphStore,
+    RepositoryStore,
+    UnsupportedQueryError,
+    get_graph_file,
+    get_repo_name,
+    get_storage_backend,
+)
+from src.graph_store.migrations import MigrationError, SchemaVersionError, ensure_schema
 from src.neo4j_storage.graph_db import Neo4jDatabase
+from src.ast_parser.doc_comments import truncate_doc
+from src.ast_parser.provenance import edge_provenance
+from src.ast_parser.signatures import matches_signature
+from src.ast_parser.variables import USAGE_KINDS
 from src.embeddings.factory import get_embedding_provider
 from src.embeddings.embedder import CodeEmbedder
+from src.analysis.cycles import detect_cycles as find_package_cycles, format_cycles_report
+from src.analysis.graph_diff import (
+    DEFAULT_COMPLEXITY_THRESHOLD,
+    DEFAULT_MAX_RESULTS as DIFF_DEFAULT_MAX_RESULTS,
+    delete_snapshots as delete_snapshot_repos,
+    diff_graphs as diff_snapshot_graphs,
+    format_diff_report,
+)
+from src.analysis.unreferenced import find_unreferenced as find_unreferenced_symbols, format_unreferenced_report
+from src.export.graph_export import export_graph as export_subgraph
+from src.indexing.coordination import GraphChange, get_coordinator
+from src.indexing.jobs import IndexJobManager, IndexProgress, JobConflictError
+from src.indexing.source import source_fields, stored_source_line
+from src.linking import link_repositories
+from src.parallel.pipeline import ParserSettings
+from src.mcp.references import (
+    find_named_nodes,
+    find_symbol_candidates,
+    node_type_from_labels,
+    qualified_name,
+    read_source_line,
+    relation_types_for_kind,
+)
+from src.mcp.call_hierarchy import call_hierarchy
+from src.mcp.coverage import get_tests_for as find_covering_tests, get_untested as find_untested_symbols
+from src.mcp.diagnostics import index_diagnostics
+from src.mcp.generated import is_generated_node, without_generated
+from src.mcp.graph_info import graph_info
+from src.mcp.impact import analyze_impact as analyze_symbol_impact
+from src.mcp.metrics import query_metrics as query_function_metrics
+from src.mcp.outline import file_outline
+from src.mcp.overview import get_package_overview as package_overview
+from src.mcp.paths import find_paths as find_dependency_paths
+from src.mcp.query_cache import QueryCache
+from src.mcp.read_query import DEFAULT_LIMIT as QUERY_DEFAULT_LIMIT, run_query as run_read_query
+from src.mcp.resolve import resolve_symbol as resolve_symbol_ids
+from src.mcp.semantic_search import semantic_search as search_similar
+from src.mcp.symbol_search import DEFAULT_MAX_EDITS, rank_symbols
+from src.mcp.transport import (
+    HTTP_TRANSPORTS,
+    InFlightCalls,
+    get_auth_token,
+    get_shutdown_timeout,
+    parse_listen,
+    serve_http,
+    track_tool_calls,
+)
+from src.mcp.type_members import collect_promoted_members, declared_members
 
 # 設定日誌
 logging.basicConfig(level=logging.INFO, format='%(asctime)s - %(name)s - %(levelname)s - %(message)s')
 logger = logging.getLogger(__name__)
 
+# 等待索引工作時的進度通知間隔 / Interval of progress notifications while waiting for an index job
+PROGRESS_INTERVAL_SECONDS = 0.5
+
+# 不等待索引套用的工具：啟動、追蹤索引執行，或自行套用寫入
+# Tools that do not wait for index applies: they start or follow index runs, or apply their own writes
+UNGATED_TOOLS = ("reindex", "index_repository", "get_index_status", "cancel_index", "get_watch_status",
+                 "delete_repository")
+
 
 class CodebaseKnowledgeGraphMCP:
     """Codebase知識圖譜的MCP服務器實現"""
     
-    def __init__(self, neo4j_uri=None, neo4j_user=None, neo4j_password=None, server_host=None, server_port=None):
+    def __init__(self, neo4j_uri=None, neo4j_user=None, neo4j_password=None, server_host=None, server_port=None,
+                 codebase_path=None, watch=None, embedding_provider=None, storage=None, graph_file=None, store=None,
+                 repo=None, auth_token=None, shutdown_timeout=None, query_cache=None):
         """初始化MCP服務器
         
         Args:
@@ -31,6 +105,26 @@ class CodebaseKnowledgeGraphMCP:
             neo4j_password: Neo4j密碼，若為None則從環境變數取得
             server_host: MCP服務器主機地址，用於HTTP/SSE傳輸
             server_port: MCP服務器端口，用於HTTP/SSE傳輸
+            codebase_path: reindex 工具預設索引的程式碼庫路徑，若為None則從環境變數取得
+                / Default codebase for the reindex tool, falls back to CODEBASE_PATH
+            watch: 啟動後監看程式碼庫並自動增量同步，若為None則從INDEX_WATCH取得
+                / Watch the codebase after start-up and sync changes incrementally, falls back to INDEX_WATCH
+            embedding_provider: 查詢用的嵌入提供者，若為None則由工廠依環境變數建立
+                / Embedding provider for queries, if None, created by the factory from environment variables
+            storage: 儲存後端 "neo4j" 或 "memory"，若為None則從GRAPH_STORAGE取得
+                / Storage backend, "neo4j" or "memory", falls back to GRAPH_STORAGE (default: neo4j)
+            graph_file: 記憶體後端載入與保存的JSON檔案，若為None則從GRAPH_STORE_PATH取得
+                / JSON file the memory backend loads and saves, falls back to GRAPH_STORE_PATH
+            store: 直接使用的 GraphStore（例如剛完成索引的記憶體圖譜）
+                / GraphStore to serve directly (e.g. the in-memory graph that was just indexed)
+            repo: reindex 與監看寫入的儲存庫名稱，若為None則從INDEX_REPO取得
+                / Repository reindex and watch mode write, falls back to INDEX_REPO (default: "default")
+            auth_token: HTTP/SSE 客戶端須帶的 Bearer token，若為None則從MCP_AUTH_TOKEN取得，空值為不驗證
+                / Bearer token HTTP/SSE clients must send, falls back to MCP_AUTH_TOKEN, empty for no auth
+            shutdown_timeout: 關閉時等待執行中工具呼叫的秒數，若為None則從MCP_SHUTDOWN_TIMEOUT取得
+                / Seconds a shutdown waits for the tool calls in flight, falls back to MCP_SHUTDOWN_TIMEOUT (default 30)
+            query_cache: 查詢工具的結果快取，若為None則依QUERY_CACHE、QUERY_CACHE_PATH與QUERY_CACHE_MAX_ENTRIES建立
+                / Result cache of the query tools, if None, built from QUERY_CACHE, QUERY_CACHE_PATH and QUERY_CACHE_MAX_ENTRIES
         """
         self.neo4j_uri = neo4j_uri or os.environ.get("NEO4J_URI")
         self.neo4j_user = neo4j_user or os.environ.get("NEO4J_USER")
@@ -40,23 +134,56 @@ class CodebaseKnowledgeGraphMCP:
         # 從參數、環境變數獲取服務器配置或使用默認值
         self.server_host = server_host or os.environ.get("MCP_SERVER_HOST", "127.0.0.1")
         self.server_port = server_port or int(os.environ.get("MCP_SERVER_PORT", "8080"))
+        self.codebase_path = codebase_path or os.environ.get("CODEBASE_PATH", ".")
+        self.auth_token = get_auth_token(auth_token)
+        self.shutdown_timeout = get_shutdown_timeout(shutdown_timeout)
+        if watch is None:
+            watch = os.environ.get("INDEX_WATCH", "false").lower() == "true"
+        self.watch = watch
+        self.repo = get_repo_name(repo)
+        # 監看模式的 IndexWatcher，start() 時建立
+        # IndexWatcher of watch mode, created by start()
+        self.watcher = None
+        # 背景索引工作，每個根目錄同時只有一個
+        # Background index jobs, at most one per root at a time
+        self.index_jobs = IndexJobManager(self._run_index_job)
         
-        # 初始化FastMCP (配置 host 和 port)
+        # 初始化FastMCP (配置 host 和 port)，工具只註冊一次，所有傳輸協議共用
+        # FastMCP with host and port; tools are registered once and shared by every transport
         self.mcp = FastMCP(
             name="Codebase KG Server",
             host=self.server_host,
             port=self.server_port
         )
+        # 執行中的工具呼叫，HTTP 傳輸關閉時等待它們完成
+        # Tool calls in flight, an HTTP transport waits for them when shutting down
+        self.tool_calls = InFlightCalls()
+        track_tool_calls(self.mcp, self.tool_calls)
         
-        # 初始化Neo4j資料庫
-        self.db = Neo4jDatabase(
-            uri=self.neo4j_uri,
-            user=self.neo4j_user,
-            password=self.neo4j_password
-        )
+        # 初始化儲存後端，預設為Neo4j資料庫
+        # Storage backend, Neo4j unless configured otherwise; the tools only use the GraphStore interface
+        if store is not None:
+            self.db = store
+        elif get_storage_backend(storage) == "memory":
+            self.db = InMemoryGraphStore(get_graph_file(graph_file))
+        else:
+            self.db = Neo4jDatabase(
+                uri=self.neo4j_uri,
+                user=self.neo4j_user,
+                password=self.neo4j_password
+            )
+        # 查詢工具等待進行中的索引套用，不會看到只寫入一半的檔案
+        # Query tools wait for the index applies in progress, so they never see a half-applied file
+        self.coordinator = get_coordinator(self.db)
+        self._gate_query_tools()
+        # 查詢結果快取，索引寫入時只失效受影響檔案的結果
+        # Query result cache; an index write only drops the results read from what it changed
+        self.query_cache = query_cache if query_cache is not None else QueryCache(self.db)
+        self.coordinator.add_listener(self.query_cache.invalidate)
         
         # 初始化嵌入處理器 (使用工廠模式支持多種提供商)
-        embedding_provider = get_embedding_provider()
+        # Embedding handler, the provider is injectable (tests pass a deterministic fake)
+        embedding_provider = embedding_provider or get_embedding_provider()
         self.code_embedder = CodeEmbedder(embedding_provider)
         
         # 註冊MCP工具
@@ -68,22 +195,57 @@ class CodebaseKnowledgeGraphMCP:
         # 註冊MCP資源
         self._register_resources()
     
+    def _gate_query_tools(self):
+        """查詢工具在索引套用之間執行 / Run the query tools between index applies
+        
+        Every tool registered from now on but UNGATED_TOOLS waits until no index
+        run is applying its changes (see src/indexing/coordination.py). The
+        wrapper keeps the tool's name, doc and signature; the wait runs on a
+        worker thread, so the event loop keeps serving other calls meanwhile.
+        """
+        register = self.mcp.tool
+        coordinator = self.coordinator
+        
+        def tool(*args, **kwargs):
+            decorator = register(*args, **kwargs)
+            
+            def gate(func):
+                if func.__name__ in UNGATED_TOOLS:
+                    return decorator(func)
+                
+                @functools.wraps(func)
+                async def call(*call_args, **call_kwargs):
+                    await asyncio.to_thread(coordinator.acquire_read)
+                    try:
+                        return await func(*call_args, **call_kwargs)
+                    finally:
+                        coordinator.release_read()
+                decorator(call)
+                return call
+            return gate
+        
+        self.mcp.tool = tool
+    
     def _register_tools(self):
         """註冊MCP工具"""
         
         @self.mcp.tool()
-        async def search_code(query: str, limit: int = 10, search_type: str = "vector") -> str:
+        async def search_code(query: str, limit: int = 10, search_type: str = "vector",
+                              include_generated: bool = False, repo: str = "all") -> str:
             """搜索程式碼
             
             Args:
                 query: 搜索查詢
                 limit: 返回結果的最大數量
                 search_type: 搜索類型，可選 "vector" 或 "text"
+                include_generated: 是否包含生成的程式碼 / Include nodes of generated files (default: false)
+                repo: 只查詢此儲存庫，"all" 查詢全部 / Only this repository, "all" (default) for every repository
                 
             Returns:
                 搜索結果的JSON字符串
             """
             try:
+                db = self._repo_db(repo)
                 results = []
                 
                 if search_type == "vector":
@@ -91,16 +253,20 @@ class CodebaseKnowledgeGraphMCP:
                     vector = self.code_embedder.provider.embed_text(query)
                     
                     # 使用向量搜索
-                    for node_label in ["Function", "Method", "Class", "File"]:
-                        node_results = self.db.search_code_by_vector(vector, node_label, limit)
-                        results.extend(node_results)
+                    def search_vectors(count):
+                        matches = []
+                        for node_label in ["Function", "Method", "Class", "File"]:
+                            matches.extend(db.search_code_by_vector(vector, node_label, count))
+                        
+                        # 根據分數排序
+                        return sorted(matches, key=lambda x: x["score"], reverse=True)[:count]
                     
-                    # 根據分數排序
-                    results = sorted(results, key=lambda x: x["score"], reverse=True)[:limit]
+                    results = without_generated(search_vectors, limit, include_generated)
                     
                 elif search_type == "text":
                     # 使用全文檢索
-                    results = self.db.search_code_by_text(query, limit)
+                    results = without_generated(lambda count: db.search_code_by_text(query, count), limit,
+                                                include_generated)
                 
                 return json.dumps(results, ensure_ascii=False)
                 
@@ -108,75 +274,132 @@ class CodebaseKnowledgeGraphMCP:
                 logger.error(f"搜索程式碼時發生錯誤: {e}")
                 return json.dumps({"error": str(e)})
         
+        @self.mcp.tool()
+        async def semantic_search(query: str, limit: int = 10, node_types: List[str] = None,
+                                  include_source: bool = False, include_generated: bool = False,
+                                  repo: str = "all") -> str:
+            """以自然語言語意搜索符號
+            Find functions, methods and classes by meaning (embedding cosine similarity)
+            
+            Args:
+                query: 自然語言描述 / Natural-language description, e.g. "where do we validate email addresses"
+                limit: 返回結果的最大數量 / Maximum number of results
+                node_types: 節點類型篩選 / Node types to search ("Function", "Method", "Class", "File"),
+                    預設為 Function、Method、Class / defaults to Function, Method and Class
+                include_source: 是否附上索引時儲存的原始碼 (需以 store_source 索引)
+                    / Add the source stored at indexing time (the graph must be indexed with store_source)
+                include_generated: 是否包含生成的程式碼 / Include nodes of generated files (default: false)
+                repo: 只查詢此儲存庫，"all" 查詢全部 / Only this repository, "all" (default) for every repository
+            
+            Returns:
+                依分數排序的節點與檔案位置的JSON字符串，doc 截斷至 DOC_MAX_LENGTH；include_source 時含 source 與 source_truncated
+                / JSON list of nodes with score, file path, line range and doc (cut to DOC_MAX_LENGTH), best match first;
+                with include_source, source and source_truncated (source is null when none is stored)
+            """
+            try:
+                db = self._repo_db(repo)
+                results = await asyncio.to_thread(
+                    search_similar, db, self.code_embedder.provider, query, limit, node_types, include_source,
+                    include_generated
+                )
+                return json.dumps({"query": query, "results": results}, ensure_ascii=False)
+            except Exception as e:
+                logger.error(f"語意搜索時發生錯誤 / Error in semantic search: {e}")
+                return json.dumps({"error": str(e)})
+        
         @self.mcp.tool()
         async def execute_cypher_query(query: str, parameters: Dict = None) -> str:
             """執行Cypher查詢
+            Run a Cypher query (neo4j storage backend only); every node has a `repo` property to filter on
+            
+            與 run_query 相同的唯讀檢查、讀取交易與逾時，最多返回 500 列
+            / Same read-only check, read transaction and timeout as run_query, at most 500 rows;
+            use run_query for another limit and to see whether rows were left out
             
             Args:
-                query: Cypher查詢語句
-                parameters: 查詢參數
+                query: Cypher查詢語句 / Cypher query
+                parameters: 查詢參數 / Query parameters
                 
             Returns:
-                查詢結果的JSON字符串
+                查詢結果列的JSON字符串 / JSON list of the result rows
             """
             try:
-                results = self.db.execute_cypher(query, parameters)
-                return json.dumps(results, ensure_ascii=False)
+                result = await asyncio.to_thread(run_read_query, self.db, query, parameters)
+                return json.dumps(result["rows"], ensure_ascii=False, default=str)
             except Exception as e:
-                logger.error(f"執行Cypher查詢時發生錯誤: {e}")
+                logger.error(f"執行Cypher查詢時發生錯誤 / Error running Cypher query: {e}")
                 return json.dumps({"error": str(e)})
         
         @self.mcp.tool()
-        async def get_code_by_name(name: str, node_type: str = None) -> str:
+        async def run_query(query: str, parameters: Dict = None, limit: int = QUERY_DEFAULT_LIMIT,
+                            timeout: float = None) -> str:
+            """執行唯讀的Cypher查詢
+            Run a read-only Cypher query for what the other tools do not cover (neo4j storage backend only)
+            
+            寫入子句 (CREATE、MERGE、DELETE、SET、REMOVE、DETACH、FOREACH、LOAD CSV) 與非唯讀程序的 CALL 會被拒絕，
+            查詢在讀取交易中執行 / Write clauses (CREATE, MERGE, DELETE, SET, REMOVE, DETACH, FOREACH, LOAD CSV)
+            and CALLs of procedures not known to be read-only are rejected, and the query runs in a read transaction
+            
+            Args:
+                query: Cypher查詢語句 / Cypher query
+                parameters: 查詢參數 / Query parameters
+                limit: 返回的最大列數 (預設 500，上限 QUERY_MAX_ROWS) / Maximum rows (default 500, at most QUERY_MAX_ROWS)
+                timeout: 查詢逾時秒數，預設與上限為 QUERY_TIMEOUT / Seconds the query may run, QUERY_TIMEOUT by default and at most
+            
+            Returns:
+                結構化JSON：rows、row_count、truncated (超過 limit 的列被略去)，metadata 含執行的查詢與 elapsed_ms
+                / Structured JSON: rows, row_count, truncated (rows beyond limit were left out), metadata with the
+                executed query, its parameters, limit, timeout_seconds and elapsed_ms
+            """
+            try:
+                result = await asyncio.to_thread(run_read_query, self.db, query, parameters, limit, timeout)
+                return json.dumps(result, ensure_ascii=False, default=str)
+            except UnsupportedQueryError as e:
+                return json.dumps({"error": f"run_query is unsupported for this backend: {e}", "unsupported": True})
+            except Exception as e:
+                logger.error(f"執行唯讀查詢時發生錯誤 / Error running read-only query: {e}")
+                return json.dumps({"error": str(e)})
+        
+        @self.mcp.tool()
+        async def get_code_by_name(name: str, node_type: str = None, repo: str = "all") -> str:
             """根據名稱獲取程式碼
             
             Args:
-                name: 程式碼名稱(類別名、函數名等)
+                name: 程式碼名稱(類別名、函數名等)或符號ID / Name (class, function...) or symbol ID
                 node_type: 節點類型，可選 "Function", "Method", "Class", "File"
+                repo: 只查詢此儲存庫，"all" 查詢全部 / Only this repository, "all" (default) for every repository
                 
             Returns:
                 程式碼的JSON字符串
             """
             try:
-                query = """
-                MATCH (n)
-                WHERE n.name = $name
-                """
-                
-                if node_type:
-                    query += f" AND n:{node_type}"
-                
-                query += " RETURN n LIMIT 10"
-                
-                results = self.db.execute_cypher(query, {"name": name})
+                db = self._repo_db(repo)
+                nodes = find_named_nodes(db, name, label=node_type, limit=10)
+                results = [{"n": node["properties"]} for node in nodes]
                 return json.dumps(results, ensure_ascii=False)
             except Exception as e:
                 logger.error(f"獲取程式碼時發生錯誤: {e}")
                 return json.dumps({"error": str(e)})
         
         @self.mcp.tool()
-        async def find_function_callers(function_name: str, limit: int = 10) -> str:
+        async def find_function_callers(function_name: str, limit: int = 10, repo: str = "all") -> str:
             """查找調用特定函數的所有位置
             
             Args:
-                function_name: 函數名稱
+                function_name: 函數名稱或符號ID / Function name or symbol ID
                 limit: 返回結果的最大數量
+                repo: 只查詢此儲存庫，"all" 查詢全部 / Only this repository, "all" (default) for every repository
                 
             Returns:
-                調用者的JSON字符串
+                調用者的JSON字符串；edge 為調用邊的來源、可信度與索引器版本
+                / JSON with the callers, edge holding the call's source, confidence and indexer_version
             """
             try:
-                query = """
-                MATCH (caller)-[:CALLS]->(callee)
-                WHERE callee.name = $function_name
-                RETURN caller
-                LIMIT $limit
-                """
-                
-                results = self.db.execute_cypher(query, {
-                    "function_name": function_name,
-                    "limit": limit
-                })
+                db = self._repo_db(repo)
+                callees = [node["properties"]["id"] for node in find_named_nodes(db, function_name)]
+                rows = db.neighbors(callees, ["CALLS"], direction="in")
+                results = [{"caller": row["node"]["properties"], "edge": edge_provenance(row["relationship"])}
+                           for row in rows[:limit]]
                 
                 return json.dumps(results, ensure_ascii=False)
             except Exception as e:
@@ -184,28 +407,24 @@ class CodebaseKnowledgeGraphMCP:
                 return json.dumps({"error": str(e)})
         
         @self.mcp.tool()
-        async def find_function_callees(function_name: str, limit: int = 10) -> str:
+        async def find_function_callees(function_name: str, limit: int = 10, repo: str = "all") -> str:
             """查找特定函數調用的所有函數
             
             Args:
-                function_name: 函數名稱
+                function_name: 函數名稱或符號ID / Function name or symbol ID
                 limit: 返回結果的最大數量
+                repo: 只查詢此儲存庫，"all" 查詢全部 / Only this repository, "all" (default) for every repository
                 
             Returns:
-                被調用函數的JSON字符串
+                被調用函數的JSON字符串；edge 為調用邊的來源、可信度與索引器版本
+                / JSON with the callees, edge holding the call's source, confidence and indexer_version
             """
             try:
-                query = """
-                MATCH (caller)-[:CALLS]->(callee)
-                WHERE caller.name = $function_name
-                RETURN callee
-                LIMIT $limit
-                """
-                
-                results = self.db.execute_cypher(query, {
-                    "function_name": function_name,
-                    "limit": limit
-                })
+                db = self._repo_db(repo)
+                callers = [node["properties"]["id"] for node in find_named_nodes(db, function_name)]
+                rows = db.neighbors(callers, ["CALLS"], direction="out")
+                results = [{"callee": row["node"]["properties"], "edge": edge_provenance(row["relationship"])}
+                           for row in rows[:limit]]
                 
                 return json.dumps(results, ensure_ascii=False)
             except Exception as e:
@@ -213,35 +432,34 @@ class CodebaseKnowledgeGraphMCP:
                 return json.dumps({"error": str(e)})
         
         @self.mcp.tool()
-        async def find_class_inheritance(class_name: str) -> str:
+        async def find_class_inheritance(class_name: str, repo: str = "all") -> str:
             """查找類別的繼承關係
             
             Args:
-                class_name: 類別名稱
+                class_name: 類別名稱或符號ID / Class name or symbol ID
+                repo: 只查詢此儲存庫，"all" 查詢全部 / Only this repository, "all" (default) for every repository
                 
             Returns:
-                繼承關係的JSON字符串
+                繼承關係的JSON字符串；edge 為 EXTENDS 邊的來源、可信度與索引器版本
+                / JSON with superclasses and subclasses, edge holding the EXTENDS edge's provenance
             """
             try:
-                # 查找超類
-                superclasses_query = """
-                MATCH (sub:Class {name: $class_name})-[:EXTENDS]->(super:Class)
-                RETURN super
-                """
+                db = self._repo_db(repo)
+                classes = [node["properties"]["id"] for node in find_named_nodes(db, class_name, label="Class")]
                 
-                superclasses = self.db.execute_cypher(superclasses_query, {
-                    "class_name": class_name
-                })
+                # 查找超類
+                # Superclasses
+                superclasses = [
+                    {"super": row["node"]["properties"], "edge": edge_provenance(row["relationship"])}
+                    for row in db.neighbors(classes, ["EXTENDS"], direction="out", label="Class")
+                ]
                 
                 # 查找子類
-                subclasses_query = """
-                MATCH (sub:Class)-[:EXTENDS]->(super:Class {name: $class_name})
-                RETURN sub
-                """
-                
-                subclasses = self.db.execute_cypher(subclasses_query, {
-                    "class_name": class_name
-                })
+                # Subclasses
+                subclasses = [
+                    {"sub": row["node"]["properties"], "edge": edge_provenance(row["relationship"])}
+                    for row in db.neighbors(classes, ["EXTENDS"], direction="in", label="Class")
+                ]
                 
                 return json.dumps({
                     "superclasses": superclasses,
@@ -253,37 +471,44 @@ class CodebaseKnowledgeGraphMCP:
                 return json.dumps({"error": str(e)})
         
         @self.mcp.tool()
-        async def find_file_dependencies(file_path: str) -> str:
+        async def find_file_dependencies(file_path: str, repo: str = "all") -> str:
             """查找檔案的依賴關係
+            Find what a file imports and which files import it
             
             Args:
-                file_path: 檔案路徑
+                file_path: 檔案路徑 / File path
+                repo: 只查詢此儲存庫，"all" 查詢全部 / Only this repository, "all" (default) for every repository
                 
             Returns:
-                依賴關係的JSON字符串
+                依賴關係的JSON字符串；IMPORTS 邊的屬性（alias、dot、blank、symbol 等）放在 edge
+                / JSON with imports and imported_by; IMPORTS edge properties (alias, dot, blank, symbol...) are in edge
             """
             try:
-                # 查找該檔案導入的模組
-                imports_query = """
-                MATCH (f:File {path: $file_path})-[:IMPORTS]->(m:Module)
-                RETURN m
-                """
-                
-                imports = self.db.execute_cypher(imports_query, {
-                    "file_path": file_path
-                })
-                
-                # 查找導入該檔案的檔案
-                imported_by_query = """
-                MATCH (f:File)-[:IMPORTS]->(m:Module)
-                WHERE m.name = $file_name
-                RETURN f
-                """
+                db = self._repo_db(repo)
+                # 查找該檔案導入的模組、檔案、符號與套件
+                # Modules, files, symbols and packages the file imports
+                files = [node["properties"]["id"] for node in db.find_nodes(label="File", properties={"path": file_path})]
+                files += [node["properties"]["id"] for node in db.find_nodes(label="File", properties={"file_path": file_path})]
+                files = list(dict.fromkeys(files))
+                imports = [
+                    {"m": row["node"]["properties"], "node_type": node_type_from_labels(row["node"]["labels"]),
+                     "edge": row["relationship"]["properties"]}
+                    for row in db.neighbors(files, ["IMPORTS"], direction="out")
+                ]
                 
+                # 查找導入該檔案的檔案：依模組名稱，或 IMPORTS 邊指向檔案本身及其符號
+                # Files importing it, by module name or by IMPORTS edges into the file and its symbols
                 file_name = os.path.basename(file_path).split(".")[0]
-                imported_by = self.db.execute_cypher(imported_by_query, {
-                    "file_name": file_name
-                })
+                modules = [node["properties"]["id"] for node in db.find_nodes(name=file_name, label="Module")]
+                symbols = [
+                    node["properties"]["id"] for node in db.find_nodes(path_prefixes=[file_path])
+                    if node["properties"].get("file_path") == file_path
+                ]
+                importers = {}
+                for row in db.neighbors(list(dict.fromkeys(modules + files + symbols)), ["IMPORTS"],
+                                             direction="in", label="File"):
+                    importers.setdefault(row["node"]["properties"]["id"], row["node"]["properties"])
+                imported_by = [{"f": properties} for properties in importers.values()]
                 
                 return json.dumps({
                     "imports": imports,
@@ -293,6 +518,950 @@ class CodebaseKnowledgeGraphMCP:
             except Exception as e:
                 logger.error(f"查找檔案依賴關係時發生錯誤: {e}")
                 return json.dumps({"error": str(e)})
+        
+        @self.mcp.tool()
+        async def find_symbol(name: str = None, node_type: str = None, limit: int = 10, arity: int = None,
+                              param_type: str = None, param_index: int = None, include_source: bool = False,
+                              include_generated: bool = False, repo: str = "all", match: str = "auto",
+                              package: str = None, max_edits: int = DEFAULT_MAX_EDITS, offset: int = 0) -> str:
+            """根據名稱或簽名查找符號及其所屬類型
+            Find symbols by name or signature together with their owning type
+            
+            名稱可模糊匹配：不分大小寫、camelCase/snake_case 正規化、前綴與子字串、單字、拼寫錯誤 (編輯距離)
+            與 fzf 式子序列；結果依綜合分數排序，精確匹配、匯出的符號、較短的名稱、非測試非生成檔案的符號
+            以及符合 package 提示的符號排在前面
+            The name may match fuzzily: in any case, camelCase/snake_case normalized, as a prefix or substring,
+            by its words, with typos (edit distance) or as an fzf-style subsequence. Results are ranked by a
+            composite score that favours exact matches, exported symbols, shorter names, symbols outside
+            test and generated files and symbols in the package hint
+            
+            Args:
+                name: 符號名稱或符號ID / Symbol name or symbol ID
+                node_type: 節點類型，可選 "Function", "Method", "Class", "Interface" / Optional node type filter
+                limit: 返回結果的最大數量 / Maximum number of results
+                offset: 跳過的排序結果數，用於分頁 / Ranked results to skip, for paging
+                arity: 參數個數，不含 self 與 Go 接收者 / Number of parameters, without self or a Go receiver
+                param_type: 參數類型包含的子字串，如 "context.Context" / Substring of a parameter type, e.g. "context.Context"
+                param_index: param_type 須匹配的參數位置（從 0 起），預設任意位置
+                    / Position (from 0) of the parameter param_type must match, any position by default
+                include_source: 是否附上索引時儲存的原始碼 (需以 store_source 索引)
+                    / Add the source stored at indexing time (the graph must be indexed with store_source)
+                include_generated: 是否包含生成的程式碼 / Include symbols of generated files (default: false)
+                repo: 只查詢此儲存庫，"all" 查詢全部 / Only this repository, "all" (default) for every repository
+                match: "exact" 只要同名符號，"auto" 無同名時才模糊匹配，"fuzzy" 一律模糊匹配
+                    / "exact" for symbols with that name only, "auto" (default) to match fuzzily when none has it,
+                    "fuzzy" to always match fuzzily
+                package: 套件提示，如 "api/v1" 或 "utils"；符合的符號排在前面
+                    / Package hint such as "api/v1" or "utils"; symbols in it rank higher
+                max_edits: 拼寫錯誤容許的最多編輯次數，0 為關閉 / Most edits a typo may be away, 0 to turn typo matching off
+            
+            Returns:
+                符號列表的JSON字符串，方法包含 owner 與 receiver_kind，doc 截斷至 DOC_MAX_LENGTH；include_source 時含 source 與 source_truncated；
+                依名稱查詢時每筆含 score (0 到 1) 與 match (exact、id、case_insensitive、normalized、prefix、substring、words、edit_distance、subsequence)
+                / JSON list of symbols; methods include owner and receiver_kind, doc is cut to DOC_MAX_LENGTH;
+                with include_source, source and source_truncated (source is null when none is stored).
+                When searching by name, each has its score (0 to 1) and the match strategy that fired (exact, id,
+                case_insensitive, normalized, prefix, substring, words, edit_distance or subsequence)
+            """
+            if name is None and arity is None and not param_type:
+                return json.dumps({"error": "需要 name、arity 或 param_type / name, arity or param_type is required"})
+            try:
+                db = self._repo_db(repo)
+                # arity 直接以屬性查詢，param_type 需解碼 signature_json 後過濾
+                # arity is a property lookup, param_type filters the decoded signature_json
+                by_signature = arity is not None or bool(param_type)
+                offset = max(offset, 0)
+                
+                if name is not None:
+                    # 名稱查詢先取得有上限的候選，排序後再分頁
+                    # A name search ranks a bounded candidate set, then pages through it
+                    def accept(node):
+                        if not include_generated and is_generated_node(node["properties"]):
+                            return False
+                        return not by_signature or matches_signature(node["properties"], arity, param_type, param_index)
+                    
+                    ranked = rank_symbols(db, name, node_type=node_type, match=match, package=package,
+                                          max_edits=max_edits, accept=accept)
+                    ranked = ranked[offset:offset + limit]
+                    nodes = [entry["node"] for entry in ranked]
+                else:
+                    def lookup(count):
+                        nodes = find_named_nodes(db, name, label=node_type,
+                                                 properties={"arity": arity} if arity is not None else None,
+                                                 limit=None if param_type else count)
+                        if by_signature:
+                            nodes = [
+                                node for node in nodes
+                                if matches_signature(node["properties"], arity, param_type, param_index)
+                            ][:count]
+                        return nodes
+                    
+                    ranked = []
+                    nodes = without_generated(lookup, offset + limit, include_generated,
+                                              properties=lambda node: node["properties"])[offset:]
+                ids = [node["properties"]["id"] for node in nodes]
+                
+                # METHOD_OF 的接收者優先，其次為定義它的類別
+                # The METHOD_OF receiver wins over the class that DEFINES it
+                owners = {}
+                for row in db.neighbors(ids, ["DEFINES"], direction="in", label="Class"):
+                    owners.setdefault(row["origin_id"], (row["node"]["properties"].get("name"), None))
+                receivers = {}
+                for row in db.neighbors(ids, ["METHOD_OF"], direction="out"):
+                    receivers.setdefault(row["origin_id"], (row["node"]["properties"].get("name"),
+                                                            row["relationship"]["properties"].get("receiver_kind")))
+                
+                symbols = []
+                for node in nodes:
+                    properties = node["properties"]
+                    owner, receiver_kind = receivers.get(properties["id"]) or owners.get(properties["id"]) or (None, None)
+                    symbol = {
+                        "id": properties["id"],
+                        "symbol_id": properties.get("symbol_id"),
+                        "name": properties.get("name"),
+                        "file_path": properties.get("file_path"),
+                        "line_no": properties.get("line_no"),
+                        "owner": owner,
+                        "receiver_kind": receiver_kind,
+                        "node_type": node_type_from_labels(node["labels"]),
+                        "signature": properties.get("signature"),
+                        "doc": truncate_doc(properties.get("doc")),
+                    }
+                    if include_source:
+                        symbol.update(source_fields(properties))
+                    symbols.append(symbol)
+                for symbol, entry in zip(symbols, ranked):
+                    symbol.update(score=entry["score"], match=entry["match"])
+                
+                return json.dumps(symbols, ensure_ascii=False)
+            except Exception as e:
+                logger.error(f"查找符號時發生錯誤 / Error finding symbol: {e}")
+                return json.dumps({"error": str(e)})
+        
+        @self.mcp.tool()
+        async def resolve_symbol(query: str, limit: int = 10, repo: str = "all") -> str:
+            """將自然語言描述解析為穩定的符號ID
+            Resolve a description of a symbol to stable symbol IDs, best match first
+            
+            Args:
+                query: 符號描述，如 "Person.GetName in the sample package"、"the parse function of utils"，或符號ID、節點ID
+                    / Description such as "Person.GetName in the sample package" or "the parse function of utils",
+                    a qualified name, a symbol ID or a node id
+                limit: 返回結果的最大數量 / Maximum number of matches
+                repo: 只查詢此儲存庫，"all" 查詢全部 / Only this repository, "all" (default) for every repository
+            
+            Returns:
+                JSON：matches 依 score (0 到 1) 排序，每筆含 symbol_id、qualified_name、位置與 matched (名稱、限定、範圍、種類)；
+                symbol_id 可傳給任何接受符號的工具，重新索引後仍然有效
+                / JSON with "matches" ranked by score (0 to 1), each with its symbol_id, qualified_name, location
+                and the parts of the query it matched; a symbol_id can be passed to every tool taking a symbol
+                and stays valid across re-indexing. Status "not_found" when nothing matches
+            """
+            try:
+                db = self._repo_db(repo)
+                result = await asyncio.to_thread(resolve_symbol_ids, db, query, limit)
+                return json.dumps(result, ensure_ascii=False)
+            except Exception as e:
+                logger.error(f"解析符號時發生錯誤 / Error resolving symbol: {e}")
+                return json.dumps({"error": str(e)})
+        
+        @self.mcp.tool()
+        async def get_type_members(type_name: str, include_promoted: bool = True, repo: str = "all") -> str:
+            """獲取類型（類別、結構體）的成員
+            Get the members (methods and fields) of a class or struct
+            
+            Args:
+                type_name: 類型名稱或符號ID / Type name or symbol ID
+                include_promoted: 是否沿 EMBEDS 遞移加入嵌入類型的提升方法 (Go) / Follow EMBEDS transitively and add promoted methods of embedded types (Go)
+                repo: 只查詢此儲存庫，"all" 查詢全部 / Only this repository, "all" (default) for every repository
+            
+            Returns:
+                每個同名類型一筆的JSON列表，方法包含 receiver_kind；embeds 列出直接嵌入的類型，
+                promoted 列出提升的方法及其來源 (promoted_from, via, depth)
+                / JSON list with one entry per matching type; methods include receiver_kind; embeds lists
+                the directly embedded types, promoted the promoted methods and where they come from
+            """
+            try:
+                db = self._repo_db(repo)
+                types = find_named_nodes(db, type_name, label="Class")
+                members = declared_members(db, [node["properties"]["id"] for node in types])
+                results = [
+                    {
+                        "id": node["properties"]["id"],
+                        "symbol_id": node["properties"].get("symbol_id"),
+                        "name": node["properties"].get("name"),
+                        "file_path": node["properties"].get("file_path"),
+                        "line_no": node["properties"].get("line_no"),
+                        "members": members[node["properties"]["id"]],
+                    }
+                    for node in types
+                ]
+                if include_promoted:
+                    for row in results:
+                        row.update(collect_promoted_members(db, row["id"], row.get("members") or []))
+                return json.dumps(results, ensure_ascii=False)
+            except Exception as e:
+                logger.error(f"獲取類型成員時發生錯誤 / Error getting type members: {e}")
+                return json.dumps({"error": str(e)})
+        
+        @self.mcp.tool()
+        async def get_file_outline(file_path: str, repo: str = "all") -> str:
+            """取得單一檔案的符號大綱
+            Get the symbol outline of a single file
+            
+            Args:
+                file_path: 檔案路徑，絕對路徑或相對於程式碼庫 / File path, absolute or relative to the codebase
+                repo: 只查詢此儲存庫，"all" 查詢全部 / Only this repository, "all" (default) for every repository
+            
+            Returns:
+                巢狀大綱的JSON：types（含 fields、methods）、functions、methods（在其他檔案宣告的類型的方法）、
+                constants、variables，每項含涵蓋完整宣告的 line_no / end_line_no 與一行文件摘要；
+                source 為 "graph"（已索引）或 "parse"（即時解析未索引的檔案）
+                / JSON outline: types (with fields and methods), functions, methods (of types declared in other files),
+                constants and variables, each with line_no / end_line_no covering the whole declaration and a one-line
+                doc; source is "graph" (indexed) or "parse" (file not indexed, parsed on the fly)
+            """
+            try:
+                db = self._repo_db(repo)
+                result = file_outline(db, file_path, ParserSettings.from_env(), self.codebase_path)
+                return json.dumps(result, ensure_ascii=False)
+            except Exception as e:
+                logger.error(f"取得檔案大綱時發生錯誤 / Error getting file outline: {e}")
+                return json.dumps({"error": str(e)})
+        
+        @self.mcp.tool()
+        async def find_references(symbol: str, kind: str = None, limit: int = 50, offset: int = 0,
+                                  include_source: bool = False, repo: str = "all", usage: str = None) -> str:
+            """查找引用某符號的所有位置
+            Find every node that references a symbol
+            
+            Args:
+                symbol: 符號名稱，可加上模組/套件/類型限定，或直接使用符號ID、節點ID
+                    / Symbol name, optionally qualified ("utils.parse", "api/v1.NewClient", "Person.GetName"),
+                    or a symbol ID (see resolve_symbol) or node id
+                kind: 引用類型過濾，可選 "calls", "imports", "implements", "type_usage", "decorators", "uses"
+                    / Optional reference kind filter; "uses" lists the functions using a constant or variable
+                limit: 每頁返回結果的最大數量 / Page size
+                offset: 跳過的結果數量 / Number of references to skip
+                include_source: 是否為目標與每個引用節點附上索引時儲存的原始碼 (需以 store_source 索引)
+                    / Add the source stored at indexing time to the target and every referencing node
+                    (the graph must be indexed with store_source)
+                repo: 只查詢此儲存庫，"all" 查詢全部 / Only this repository, "all" (default) for every repository
+                usage: 只列出此種用法的 USES 引用："read"、"compare"、"return" 或 "write"
+                    / Only USES references of this kind of use: "read", "compare", "return" or "write"
+            
+            Returns:
+                結構化JSON：status 為 "ok"（含 references，每個引用含邊的 source、confidence 與 indexer_version）、
+                "ambiguous"（含 candidates）或 "not_found"；檔案無法讀取時 snippet 取自儲存的原始碼
+                / Structured JSON: status "ok" with references (each with its edge's source, confidence and
+                indexer_version), "ambiguous" with candidates, or "not_found"; snippet falls back to the stored
+                source when the file cannot be read
+            """
+            try:
+                db = self._repo_db(repo)
+                relation_types = relation_types_for_kind(kind)
+                if usage is not None and usage not in USAGE_KINDS:
+                    raise ValueError(f"Unknown usage '{usage}', expected one of: {', '.join(USAGE_KINDS)}")
+                limit = max(1, min(int(limit), 1000))
+                offset = max(0, int(offset))
+                
+                # 符號ID或節點ID優先，其次依限定名稱過濾
+                # A symbol ID or node id wins; otherwise filter by the qualifier
+                candidates = find_symbol_candidates(db, symbol)
+                
+                if not candidates:
+                    return json.dumps({"symbol": symbol, "status": "not_found", "total": 0, "references": []},
+                                      ensure_ascii=False)
+                
+                if len(candidates) > 1:
+                    return json.dumps({
+                        "symbol": symbol,
+                        "status": "ambiguous",
+                        "message": "Several symbols match; call again with a symbol_id, qualified_name or id from candidates",
+                        "candidates": [
+                            {
+                                "id": c["id"],
+                                "symbol_id": c["symbol_id"],
+                                "name": c["name"],
+                                "node_type": c["node_type"],
+                                "qualified_name": qualified_name(c),
+                                "file_path": c["file_path"],
+                                "line_no": c["line_no"],
+                            }
+                            for c in candidates
+                        ],
+                    }, ensure_ascii=False)
+                
+                target = candidates[0]
+                
+                # 依檔案、引用行與ID排序後分頁
+                # Page through the references ordered by file, referencing line and id
+                rows = []
+                for row in db.neighbors([target["id"]], relation_types, direction="in"):
+                    source = row["node"]["properties"]
+                    relation = row["relationship"]["properties"]
+                    # 用法過濾只保留 USES 引用
+                    # A usage filter keeps USES references only
+                    if usage is not None and (row["relationship"]["type"] != "USES" or relation.get("usage") != usage):
+                        continue
+                    rows.append({
+                        "id": source["id"],
+                        "symbol_id": source.get("symbol_id"),
+                        "name": source.get("name"),
+                        "labels": row["node"]["labels"],
+                        "file_path": source.get("file_path"),
+                        "line_no": relation.get("line_no") or source.get("line_no"),
+                        "relation_type": row["relationship"]["type"],
+                        "call_lines": relation.get("call_lines"),
+                        "usage": relation.get("usage"),
+                        "use_lines": relation.get("use_lines"),
+                        "provenance": edge_provenance(row["relationship"]),
+                        "properties": source,
+                    })
+                rows.sort(key=lambda row: (row["file_path"] or "", row["line_no"] or 0, row["id"]))
+                total = len(rows)
+                rows = rows[offset:offset + limit]
+                
+                line_cache = {}
+                references = []
+                for row in rows:
+                    reference = {
+                        "id": row["id"],
+                        "symbol_id": row["symbol_id"],
+                        "name": row["name"],
+                        "node_type": node_type_from_labels(row.get("labels")),
+                        "relation_type": row["relation_type"],
+                        "file_path": row["file_path"],
+                        "line_no": row["line_no"],
+                        "snippet": (read_source_line(row["file_path"], row["line_no"], line_cache)
+                                    or stored_source_line(row["properties"], row["line_no"])),
+                        **row["provenance"],
+                    }
+                    if row.get("call_lines"):
+                        reference["call_lines"] = row["call_lines"]
+                    if row.get("usage"):
+                        reference["usage"] = row["usage"]
+                        reference["use_lines"] = row["use_lines"]
+                    if include_source:
+                        reference.update(source_fields(row["properties"]))
+                    references.append(reference)
+                
+                target_entry = {
+                    "id": target["id"],
+                    "symbol_id": target["symbol_id"],
+                    "name": target["name"],
+                    "node_type": target["node_type"],
+                    "qualified_name": qualified_name(target),
+                    "file_path": target["file_path"],
+                    "line_no": target["line_no"],
+                }
+                if include_source:
+                    records = db.get_nodes([target["id"]])
+                    target_entry.update(source_fields(records[0]["properties"] if records else {}))
+                
+                return json.dumps({
+                    "symbol": symbol,
+                    "status": "ok",
+                    "target": target_entry,
+                    "kind": kind,
+                    "usage": usage,
+                    "total": total,
+                    "offset": offset,
+                    "limit": limit,
+                    "has_more": offset + len(references) < total,
+                    "references": references,
+                }, ensure_ascii=False)
+            except Exception as e:
+                logger.error(f"查找引用時發生錯誤 / Error finding references: {e}")
+                return json.dumps({"error": str(e)})
+        
+        @self.mcp.tool()
+        async def find_path(source: str, target: str, edge_types: List[str] = None, direction: str = "forward",
+                            max_depth: int = 10, limit: int = 1, min_confidence: float = 0.0, repo: str = "all",
+                            no_cache: bool = False) -> str:
+            """查找兩個符號之間的最短依賴路徑
+            Find the shortest dependency path between two symbols
+            
+            Args:
+                source: 起點符號，可加限定名稱或使用符號ID、節點ID / Start symbol, optionally qualified, or a symbol ID or node id
+                target: 終點符號，可加限定名稱或使用符號ID、節點ID / End symbol, optionally qualified, or a symbol ID or node id
+                edge_types: 可經過的關係類型，預設 CALLS、IMPORTS、METHOD_OF、CROSS_LANG_CALLS / Relation types to traverse (default: CALLS, IMPORTS, METHOD_OF, CROSS_LANG_CALLS)
+                direction: "forward"（source 到達 target）、"reverse" 或 "undirected" / "forward" (source reaches target), "reverse" or "undirected"
+                max_depth: 最大跳數 (預設 10) / Maximum number of hops (default 10)
+                limit: 返回路徑數量，大於1時返回同樣最短的路徑 / Number of paths; above 1 returns equally short alternatives
+                min_confidence: 只經過可信度不低於此值的邊，1.0 只經過確切事實 / Only traverse edges at least this confident; 1.0 keeps exact facts only
+                repo: 只查詢此儲存庫，"all" 查詢全部 / Only this repository, "all" (default) for every repository
+                no_cache: 不使用快取的結果，重新計算並更新快取 / Compute afresh instead of using a cached result, updating the cache
+            
+            Returns:
+                結構化JSON：status 為 "ok"（含 paths，每一跳含關係類型、位置、source、confidence 與 indexer_version）、"no_path"、"ambiguous" 或 "not_found"
+                / Structured JSON: status "ok" with paths (each hop has its relation type, location, source, confidence and
+                indexer_version), "no_path", "ambiguous" or "not_found"
+            """
+            try:
+                params = {"source": source, "target": target, "edge_types": edge_types, "direction": direction,
+                          "max_depth": max_depth, "limit": limit, "min_confidence": min_confidence, "repo": repo}
+                result = await self._cached("find_path", params, repo, no_cache, lambda db: find_dependency_paths(
+                    db, source, target, edge_types=edge_types, direction=direction, max_depth=max_depth, limit=limit,
+                    min_confidence=min_confidence))
+                return json.dumps(result, ensure_ascii=False)
+            except Exception as e:
+                logger.error(f"查找路徑時發生錯誤 / Error finding path: {e}")
+                return json.dumps({"error": str(e)})
+        
+        @self.mcp.tool()
+        async def get_call_hierarchy(symbol: str, direction: str = "callers", max_depth: int = 3,
+                                     max_children: int = 50, min_confidence: float = 0.0, repo: str = "all",
+                                     no_cache: bool = False) -> str:
+            """取得函數或方法的調用階層樹
+            Get the call hierarchy tree of a function or method
+            
+            Args:
+                symbol: 函數或方法名稱，可加限定名稱或使用符號ID、節點ID / Function or method, optionally qualified, or a symbol ID or node id
+                direction: "callers"（誰調用它）或 "callees"（它調用誰） / "callers" (who calls it) or "callees" (what it calls)
+                max_depth: 最大層數 (預設 3) / Maximum number of levels (default 3)
+                max_children: 每個節點最多返回的子節點數，超過時標記 truncated / Maximum children per node, more are cut and marked truncated
+                min_confidence: 只經過可信度不低於此值的邊，1.0 只經過確切事實 / Only follow edges at least this confident; 1.0 keeps exact facts only
+                repo: 只查詢此儲存庫，"all" 查詢全部 / Only this repository, "all" (default) for every repository
+                no_cache: 不使用快取的結果，重新計算並更新快取 / Compute afresh instead of using a cached result, updating the cache
+            
+            Returns:
+                結構化JSON：status 為 "ok"（含巢狀 tree，每個節點含檔案與行號及調用邊的 source、confidence，循環標記 cycle，經由介面的調用標記 via_interface）、"ambiguous" 或 "not_found"
+                / Structured JSON: status "ok" with a nested tree (file, line and the call's source and confidence at each
+                node, cycles marked "cycle", calls through interfaces marked "via_interface"), "ambiguous" or "not_found"
+            """
+            try:
+                params = {"symbol": symbol, "direction": direction, "max_depth": max_depth,
+                          "max_children": max_children, "min_confidence": min_confidence, "repo": repo}
+                result = await self._cached("get_call_hierarchy", params, repo, no_cache, lambda db: call_hierarchy(
+                    db, symbol, direction=direction, max_depth=max_depth, max_children=max_children,
+                    min_confidence=min_confidence))
+                return json.dumps(result, ensure_ascii=False)
+            except Exception as e:
+                logger.error(f"取得調用階層時發生錯誤 / Error getting call hierarchy: {e}")
+                return json.dumps({"error": str(e)})
+        
+        @self.mcp.tool()
+        async def detect_cycles(scope: str = None, min_length: int = 2, repo: str = "all",
+                                no_cache: bool = False) -> str:
+            """偵測套件之間的循環依賴
+            Detect import cycles between packages
+            
+            Args:
+                scope: 只報告經過此目錄（或 Go 匯入路徑前綴）下套件的循環 / Only cycles through a package under this directory (or Go import path prefix)
+                min_length: 循環最少包含的套件數 (預設 2) / Minimum number of packages in a cycle (default 2)
+                repo: 只查詢此儲存庫，"all" 查詢全部 / Only this repository, "all" (default) for every repository
+                no_cache: 不使用快取的結果，重新計算並更新快取 / Compute afresh instead of using a cached result, updating the cache
+            
+            Returns:
+                結構化JSON：每組互相依賴的套件及其循環，每個依賴附上造成它的檔案導入，break_candidate 標記導入最少的一步；report 為文字報告
+                / Structured JSON: each group of mutually dependent packages with its cycles, every dependency with the
+                file imports behind it and the one with the fewest imports marked break_candidate; "report" is a text report
+            """
+            try:
+                params = {"scope": scope, "min_length": min_length, "repo": repo}
+                result = await self._cached("detect_cycles", params, repo, no_cache,
+                                            lambda db: find_package_cycles(db, scope, min_length))
+                result["report"] = format_cycles_report(result)
+                return json.dumps(result, ensure_ascii=False)
+            except Exception as e:
+                logger.error(f"偵測循環依賴時發生錯誤 / Error detecting cycles: {e}")
+                return json.dumps({"error": str(e)})
+        
+        @self.mcp.tool()
+        async def find_unreferenced(scope: str = None, min_confidence: str = "low", max_results: int = 500,
+                                    repo: str = "all", no_cache: bool = False) -> str:
+            """列出沒有任何調用或引用的符號（死碼報告）
+            Report functions, methods and types nothing in the indexed codebase references (dead code candidates)
+            
+            Args:
+                scope: 只報告此目錄下的符號，來自任何位置的引用都會計算 / Only symbols under this directory; references from anywhere count
+                min_confidence: 最低信心等級 "low"、"medium" 或 "high" (預設 "low") / Lowest confidence reported (default "low")
+                max_results: 最多返回的符號數 (預設 500) / Maximum number of reported symbols (default 500)
+                repo: 只查詢此儲存庫，"all" 查詢全部 / Only this repository, "all" (default) for every repository
+                no_cache: 不使用快取的結果，重新計算並更新快取 / Compute afresh instead of using a cached result, updating the cache
+            
+            Returns:
+                結構化JSON：依套件分組的符號（檔案、行號、exported、confidence），suppressed 為各抑制原因略過的數量；report 為文字報告
+                / Structured JSON: symbols grouped by package (file, line, exported, confidence), "suppressed" counts the
+                symbols left out per suppression reason; "report" is a text report. Suppressions and public API paths
+                come from UNREFERENCED_CONFIG and UNREFERENCED_PUBLIC_API
+            """
+            try:
+                # 抑制設定來自環境變數，也是快取鍵的一部分
+                # Suppressions come from the environment, so they key the cache too
+                params = {"scope": scope, "min_confidence": min_confidence, "max_results": max_results, "repo": repo,
+                          "config": os.getenv("UNREFERENCED_CONFIG"),
+                          "public_api": os.getenv("UNREFERENCED_PUBLIC_API")}
+                result = await self._cached("find_unreferenced", params, repo, no_cache,
+                                            lambda db: find_unreferenced_symbols(db, scope, min_confidence,
+                                                                                 max_results=max_results))
+                result["report"] = format_unreferenced_report(result)
+                return json.dumps(result, ensure_ascii=False)
+            except Exception as e:
+                logger.error(f"查找未引用符號時發生錯誤 / Error finding unreferenced symbols: {e}")
+                return json.dumps({"error": str(e)})
+        
+        @self.mcp.tool()
+        async def query_metrics(scope: str = None, node_type: str = None, at_least: Dict[str, float] = None,
+                                at_most: Dict[str, float] = None, sort_by: str = "complexity", order: str = "desc",
+                                limit: int = 20, offset: int = 0, include_generated: bool = False,
+                                repo: str = "all") -> str:
+            """依複雜度與規模度量查詢函數和方法
+            Query functions and methods by complexity and size metrics
+            
+            度量名稱 / Metrics: complexity（圈複雜度 / cyclomatic complexity）、statements（語句數 / statements）、
+            lines（行數 / lines）、parameters（參數數 / parameters）、nesting（最大巢狀深度 / maximum nesting depth）
+            
+            Args:
+                scope: 只查詢此目錄下的符號 / Only symbols under this directory, e.g. "services/payments"
+                node_type: "Function" 或 "Method" (預設兩者) / "Function" or "Method" (default: both)
+                at_least: 度量下限，例如 {"lines": 200} / Lowest value per metric, e.g. {"lines": 200}
+                at_most: 度量上限 / Highest value per metric
+                sort_by: 排序依據的度量 (預設 complexity) / Metric to rank by (default complexity)
+                order: "desc"（由大到小）或 "asc" / "desc" (largest first) or "asc"
+                limit: 最多返回的結果數 (預設 20) / Maximum number of results (default 20)
+                offset: 分頁時略過的結果數 / Number of results to skip, for paging
+                include_generated: 是否包含生成的程式碼 / Include functions of generated files (default: false)
+                repo: 只查詢此儲存庫，"all" 查詢全部 / Only this repository, "all" (default) for every repository
+            
+            Returns:
+                結構化JSON：results 含每個符號的位置與度量，total 為符合條件的總數，unmeasured 為尚無度量（需重新索引）的符號數
+                / Structured JSON: "results" with the location and metrics of each symbol, "total" matches before
+                paging, "unmeasured" symbols indexed without metrics (reindex to compute them)
+            """
+            try:
+                db = self._repo_db(repo)
+                result = await asyncio.to_thread(query_function_metrics, db, scope, node_type, at_least, at_most,
+                                                 sort_by, order, limit, offset, include_generated)
+                return json.dumps(result, ensure_ascii=False)
+            except Exception as e:
+                logger.error(f"查詢度量時發生錯誤 / Error querying metrics: {e}")
+                return json.dumps({"error": str(e)})
+        
+        @self.mcp.tool()
+        async def analyze_impact(symbol: str, max_depth: int = 3, max_results: int = 100, min_confidence: float = 0.0,
+                                 repo: str = "all", no_cache: bool = False) -> str:
+            """分析修改函數或方法的影響範圍
+            Analyze what depends on a function or method, transitively
+            
+            Args:
+                symbol: 函數或方法名稱，可加限定名稱或使用符號ID、節點ID / Function or method, optionally qualified, or a symbol ID or node id
+                max_depth: 追蹤的調用者層數 (預設 3) / Number of caller levels followed (default 3)
+                max_results: 調用者與測試各自最多返回的數量，保留最近者 (預設 100) / Maximum callers and tests listed each, closest first (default 100)
+                min_confidence: 只經過可信度不低於此值的邊，1.0 只經過確切事實 / Only follow edges at least this confident; 1.0 keeps exact facts only
+                repo: 只查詢此儲存庫，"all" 查詢全部 / Only this repository, "all" (default) for every repository
+                no_cache: 不使用快取的結果，重新計算並更新快取 / Compute afresh instead of using a cached result, updating the cache
+            
+            Returns:
+                結構化JSON：callers 依距離分組並分為同套件 (same_package) 與跨套件 (cross_package，即 API 破壞)，
+                interfaces 為宣告此方法的介面與基底類型，tests 為可達此符號的測試；more 為超過 max_results 而省略的數量，
+                confidence 為路徑上最弱一條邊的可信度
+                / Structured JSON: "callers" bucketed by distance and split into same_package and cross_package
+                (API breakage), "interfaces" declaring the method, "tests" reaching the symbol; "more" counts the
+                entries left out beyond max_results and "confidence" is the weakest edge's on the way. Status "ambiguous" or "not_found" as for get_call_hierarchy
+            """
+            try:
+                params = {"symbol": symbol, "max_depth": max_depth, "max_results": max_results,
+                          "min_confidence": min_confidence, "repo": repo}
+                result = await self._cached("analyze_impact", params, repo, no_cache,
+                                            lambda db: analyze_symbol_impact(db, symbol, max_depth, max_results,
+                                                                             min_confidence))
+                return json.dumps(result, ensure_ascii=False)
+            except Exception as e:
+                logger.error(f"分析影響範圍時發生錯誤 / Error analyzing impact: {e}")
+                return json.dumps({"error": str(e)})
+        
+        @self.mcp.tool()
+        async def get_tests_for(symbol: str, max_depth: int = 3, max_results: int = 100, repo: str = "all",
+                                no_cache: bool = False) -> str:
+            """查找涵蓋函數或方法的測試
+            Find the tests covering a function or method within some call hops
+            
+            測試直接調用 (TESTS 關係，距離 1) 或經由其調用的產品程式碼到達此符號；經由介面調用、被 @patch 替換
+            或僅由測試檔導入的涵蓋標示為低信心度並附原因 / A test reaches the symbol directly (TESTS edge,
+            distance 1) or through the production code it calls; coverage through an interface call, a symbol
+            replaced with @patch or a test file import only is flagged low confidence, with the reason
+            
+            Args:
+                symbol: 函數或方法名稱，可加限定名稱或使用符號ID、節點ID / Function or method, optionally qualified, or a symbol ID or node id
+                max_depth: 測試與符號間最多的調用層數 (預設 3) / Maximum call hops between a test and the symbol (default 3)
+                max_results: 最多返回的測試數，保留最近者 (預設 100) / Maximum tests listed, closest first (default 100)
+                repo: 只查詢此儲存庫，"all" 查詢全部 / Only this repository, "all" (default) for every repository
+                no_cache: 不使用快取的結果，重新計算並更新快取 / Compute afresh instead of using a cached result, updating the cache
+            
+            Returns:
+                結構化JSON：tests 含每個測試的位置、距離、confidence (high / low) 與 reason (interface、mocked、import)，
+                through 為測試直接調用的符號 / Structured JSON: "tests" with the location, distance, confidence and
+                reason of each test, "through" the symbol it calls directly. Status "ambiguous" or "not_found" as
+                for get_call_hierarchy
+            """
+            try:
+                params = {"symbol": symbol, "max_depth": max_depth, "max_results": max_results, "repo": repo}
+                result = await self._cached("get_tests_for", params, repo, no_cache,
+                                            lambda db: find_covering_tests(db, symbol, max_depth, max_results))
+                return json.dumps(result, ensure_ascii=False)
+            except Exception as e:
+                logger.error(f"查找測試時發生錯誤 / Error finding tests: {e}")
+                return json.dumps({"error": str(e)})
+        
+        @self.mcp.tool()
+        async def get_untested(package: str, max_depth: int = 3, repo: str = "all", no_cache: bool = False) -> str:
+            """列出套件中沒有測試涵蓋的導出函數與方法
+            List the exported functions and methods of a package that no test covers
+            
+            測試程式碼與生成檔案不列入；只有低信心度涵蓋的符號另列於 low_confidence
+            / Test code and generated files are left out; symbols covered with low confidence only are listed
+            apart in "low_confidence"
+            
+            Args:
+                package: Go 導入路徑、套件名稱或目錄 / Go import path, package name or directory, e.g. "internal/auth"
+                max_depth: 測試與符號間最多的調用層數 (預設 3) / Maximum call hops between a test and a symbol (default 3)
+                repo: 只查詢此儲存庫，"all" 查詢全部 / Only this repository, "all" (default) for every repository
+                no_cache: 不使用快取的結果，重新計算並更新快取 / Compute afresh instead of using a cached result, updating the cache
+            
+            Returns:
+                結構化JSON：untested 為沒有測試的符號，low_confidence 含原因，covered 為高信心度涵蓋的數量
+                / Structured JSON: "untested" symbols, "low_confidence" ones with their reason, "covered" counts the
+                symbols covered with high confidence; status "not_found" when no package matches
+            """
+            try:
+                params = {"package": package, "max_depth": max_depth, "repo": repo}
+                result = await self._cached("get_untested", params, repo, no_cache,
+                                            lambda db: find_untested_symbols(db, package, max_depth))
+                return json.dumps(result, ensure_ascii=False)
+            except Exception as e:
+                logger.error(f"列出未測試符號時發生錯誤 / Error listing untested symbols: {e}")
+                return json.dumps({"error": str(e)})
+        
+        @self.mcp.tool()
+        async def get_package_overview(package: str, max_symbols: int = 50, repo: str = "all") -> str:
+            """取得套件概覽：摘要、頂層導出符號、直接依賴與被依賴
+            Get an overview of a package: its summary, top-level exported symbols, direct dependencies and dependents
+            
+            摘要來自套件文件（Go doc.go 或 package 上方的註解、__init__.py 的 docstring）與目錄中 README 的
+            第一個標題與段落；測試程式碼與生成檔案不列入符號
+            / The summary comes from the package doc (Go doc.go or the comment above `package`, the __init__.py
+            docstring) and the first heading and paragraph of the directory's README; test code and generated
+            files are left out of the symbols
+            
+            Args:
+                package: Go 導入路徑、套件名稱或目錄 / Go import path, package name or directory, e.g. "internal/auth"
+                max_symbols: 最多列出的符號數 (預設 50) / Maximum number of symbols listed (default 50)
+                repo: 只查詢此儲存庫，"all" 查詢全部 / Only this repository, "all" (default) for every repository
+            
+            Returns:
+                結構化JSON：summary、summary_sources、symbols（more_symbols 為未列出的數量）、dependencies 與 dependents
+                / Structured JSON: "summary", "summary_sources", "symbols" ("more_symbols" counts the ones not listed),
+                "dependencies" and "dependents" with their import counts; status "ambiguous" with the "candidates"
+                when several packages match, "not_found" when none does
+            """
+            try:
+                db = self._repo_db(repo)
+                result = await asyncio.to_thread(package_overview, db, package, max_symbols)
+                return json.dumps(result, ensure_ascii=False)
+            except Exception as e:
+                logger.error(f"取得套件概覽時發生錯誤 / Error getting the package overview: {e}")
+                return json.dumps({"error": str(e)})
+        
+        @self.mcp.tool()
+        async def get_index_diagnostics(severity: str = None, path_prefix: str = None, repo: str = "all") -> str:
+            """列出索引時解析失敗或略過的檔案
+            List the files indexing failed to parse or skipped
+            
+            解析失敗的檔案 (severity "error") 依類別分組：syntax_error（語法錯誤 / syntax error）、unsupported_feature
+            （不支援的語言功能 / unsupported language feature）、encoding（編碼問題 / encoding problem）、timeout（逾時 / timeout）、
+            parser_error（解析器錯誤 / parser error）；因大小略過的檔案為 "warning"。下次增量索引會重試失敗的檔案
+            / Failed files (severity "error") are grouped by category; files skipped for their size are "warning".
+            The next incremental index retries the failed files
+            
+            Args:
+                severity: 只列出此嚴重度，"error" 或 "warning" (預設兩者) / Only this severity, "error" or "warning" (default: both)
+                path_prefix: 只列出此路徑下的檔案 / Only files under this path, e.g. "services/payments"
+                repo: 只查詢此儲存庫，"all" 查詢全部 / Only this repository, "all" (default) for every repository
+            
+            Returns:
+                結構化JSON：diagnostics 含每個檔案的路徑、嚴重度、類別、訊息、行列位置與解析器，by_severity 與 by_category 為計數
+                / Structured JSON: "diagnostics" with the path, severity, category, message, line, column and analyzer
+                of each file, "by_severity" and "by_category" counts
+            """
+            try:
+                db = self._repo_db(repo)
+                result = await asyncio.to_thread(index_diagnostics, db, severity, path_prefix)
+                return json.dumps(result, ensure_ascii=False)
+            except Exception as e:
+                logger.error(f"獲取索引診斷時發生錯誤 / Error getting index diagnostics: {e}")
+                return json.dumps({"error": str(e)})
+        
+        @self.mcp.tool()
+        async def get_graph_info(repo: str = "all") -> str:
+            """獲取圖譜內容概覽
+            Get what the graph holds: schema version, node and relationship counts, repositories and index runs
+            
+            Args:
+                repo: 只計算此儲存庫，"all" 計算全部 / Only this repository, "all" (default) for every repository
+            
+            Returns:
+                結構化JSON：schema 含圖譜與目前程式的 schema 版本 (up_to_date、遷移失敗的步驟)，nodes_by_label 與
+                relationships_by_type 為計數，repositories 與 indexes 列出儲存庫及各根目錄最後一次索引的時間與提交
+                / Structured JSON: "schema" with the graph's and this code's schema version (up_to_date, a failed
+                migration step), "nodes_by_label" and "relationships_by_type" counts, "repositories", and "indexes"
+                with the time and commit of each root's last index run; "query_cache" with the entries, hits,
+                misses and invalidations of the query result cache
+            """
+            try:
+                repo_filter = None if repo in (None, ALL_REPOS) else get_repo_name(repo)
+                result = await asyncio.to_thread(graph_info, self.db, repo_filter)
+                result["query_cache"] = self.query_cache.stats()
+                return json.dumps(result, ensure_ascii=False)
+            except Exception as e:
+                logger.error(f"獲取圖譜資訊時發生錯誤 / Error getting graph info: {e}")
+                return json.dumps({"error": str(e)})
+        
+        @self.mcp.tool()
+        async def export_graph(format: str = "graphml", path: str = None, symbol: str = None, hops: int = 1,
+                               output_path: str = None, repo: str = "all") -> str:
+            """匯出知識圖譜為 GraphML 或 DOT 以便視覺化
+            Export the graph to GraphML (Gephi) or DOT (Graphviz) for visualization
+            
+            Args:
+                format: 輸出格式，可選 "graphml", "dot" / Output format
+                path: 只匯出此檔案或目錄下的節點 / Only export nodes under this file or directory
+                symbol: 只匯出距此符號 hops 步內的節點 / Only export nodes within `hops` edges of this symbol
+                hops: symbol 的鄰域半徑 / Neighbourhood radius for `symbol`
+                output_path: 寫入檔案而非直接返回內容 / Write the document to this file instead of returning it
+                repo: 只查詢此儲存庫，"all" 查詢全部 / Only this repository, "all" (default) for every repository
+            
+            Returns:
+                節點與邊數量及匯出內容（或輸出檔案路徑）的JSON字符串
+                / JSON with node and edge counts and the document (or the output file path)
+            """
+            try:
+                db = self._repo_db(repo)
+                document, node_count, edge_count = await asyncio.to_thread(
+                    export_subgraph, db, format, path, symbol, int(hops)
+                )
+                result = {"format": format.lower(), "node_count": node_count, "edge_count": edge_count}
+                if output_path:
+                    with open(output_path, "w", encoding="utf-8") as f:
+                        f.write(document)
+                    result["output_path"] = output_path
+                else:
+                    result["content"] = document
+                return json.dumps(result, ensure_ascii=False)
+            except Exception as e:
+                logger.error(f"匯出圖譜時發生錯誤 / Error exporting graph: {e}")
+                return json.dumps({"error": str(e)})
+        
+        @self.mcp.tool()
+        async def reindex(codebase_path: str = None, incremental: bool = True, ctx: Context = None,
+                          repo: str = None) -> str:
+            """重新索引程式碼庫並等待完成
+            Re-index the codebase into the knowledge graph and wait for the run to finish
+            
+            Args:
+                codebase_path: 程式碼庫路徑，預設為服務器啟動時的路徑 / Codebase path, defaults to the server's codebase
+                incremental: 只重新解析內容變更的檔案 / Only re-parse files whose content changed
+                repo: 寫入的儲存庫名稱，預設為服務器的儲存庫 / Repository to write, defaults to the server's repository
+            
+            Returns:
+                寫入的節點與關係數量及執行統計的JSON字符串
+                / JSON with the number of nodes and relationships written and run statistics
+            """
+            path = codebase_path or self.codebase_path
+            try:
+                job = self.index_jobs.start(path, incremental, repo)
+                await self._wait_for_job(job, ctx)
+                if job.state != "completed":
+                    return json.dumps({"error": job.error, "job_id": job.job_id, "state": job.state})
+                return json.dumps(job.result, ensure_ascii=False)
+            except JobConflictError as e:
+                return json.dumps({"error": str(e), "job_id": e.job_id})
+            except Exception as e:
+                logger.error(f"重新索引時發生錯誤 / Error re-indexing: {e}")
+                return json.dumps({"error": str(e)})
+        
+        @self.mcp.tool()
+        async def index_repository(codebase_path: str = None, incremental: bool = True, wait: bool = False,
+                                   ctx: Context = None, repo: str = None) -> str:
+            """在背景索引程式碼庫，立即返回工作 ID
+            Index a codebase in the background and return a job ID right away
+            
+            Use get_index_status to follow the job and cancel_index to stop it.
+            Jobs on different roots run concurrently; a second job on a root that
+            is still being indexed is rejected.
+            
+            Args:
+                codebase_path: 程式碼庫路徑，預設為服務器啟動時的路徑 / Codebase path, defaults to the server's codebase
+                incremental: 只重新解析內容變更的檔案 / Only re-parse files whose content changed
+                wait: 等待完成，期間發送 MCP 進度通知 / Wait for the job, sending MCP progress notifications meanwhile
+                repo: 寫入的儲存庫名稱，預設為服務器的儲存庫 / Repository to write, defaults to the server's repository
+            
+            Returns:
+                工作狀態的JSON字符串 (job_id, state, progress)
+                / JSON with the job status (job_id, state, progress)
+            """
+            path = codebase_path or self.codebase_path
+            try:
+                job = self.index_jobs.start(path, incremental, repo)
+                if wait:
+                    await self._wait_for_job(job, ctx)
+                return json.dumps(job.status(), ensure_ascii=False)
+            except JobConflictError as e:
+                return json.dumps({"error": str(e), "job_id": e.job_id})
+            except Exception as e:
+                logger.error(f"啟動索引工作時發生錯誤 / Error starting index job: {e}")
+                return json.dumps({"error": str(e)})
+        
+        @self.mcp.tool()
+        async def get_index_status(job_id: str = None) -> str:
+            """獲取索引工作的狀態
+            Get the status of an index job: phase, items processed and total, current file, errors and ETA
+            
+            Args:
+                job_id: index_repository 返回的工作 ID，省略時列出所有工作 / Job ID from index_repository, omit to list every job
+            
+            Returns:
+                工作狀態的JSON字符串；progress 含 phase (walking/parsing/resolving/embedding/writing/done)、
+                processed、total、files_total、current_file、errors 與 eta_seconds (目前階段 / current phase)
+                / JSON with the job status; progress has phase, processed, total, files_total, current_file,
+                errors and eta_seconds (for the current phase)
+            """
+            if job_id is None:
+                jobs = [job.status() for job in reversed(self.index_jobs.jobs())]
+                return json.dumps({"jobs": jobs}, ensure_ascii=False)
+            try:
+                return json.dumps(self.index_jobs.get(job_id).status(), ensure_ascii=False)
+            except KeyError:
+                return json.dumps({"error": f"Unknown index job: {job_id}"})
+        
+        @self.mcp.tool()
+        async def cancel_index(job_id: str) -> str:
+            """取消執行中的索引工作
+            Cancel a running index job
+            
+            The job stops at its next file or write batch. Cancelled before writing,
+            the graph keeps its previous state; cancelled later, the written part stays
+            and the IndexMetadata node of the root says index_complete: false until
+            a later run completes.
+            
+            Args:
+                job_id: index_repository 返回的工作 ID / Job ID from index_repository
+            
+            Returns:
+                工作狀態的JSON字符串 / JSON with the job status
+            """
+            try:
+                job = self.index_jobs.cancel(job_id)
+            except KeyError:
+                return json.dumps({"error": f"Unknown index job: {job_id}"})
+            return json.dumps(job.status(), ensure_ascii=False)
+        
+        @self.mcp.tool()
+        async def list_repositories() -> str:
+            """列出圖譜中的儲存庫
+            Get the repositories indexed into the graph
+            
+            Returns:
+                儲存庫列表的JSON字符串，含名稱、根目錄與發佈的模組名稱；快照另附 ref、commit 與原始碼庫
+                / JSON list of repositories with their name, root and the module names they publish;
+                snapshots also give their ref, commit and the codebase they were taken of
+            """
+            try:
+                repositories = [
+                    {key: node["properties"].get(key) for key in ("name", "root", "modules", "snapshot_ref",
+                                                                "snapshot_commit", "snapshot_of")}
+                    for node in self.db.find_nodes(label="Repository")
+                ]
+                repositories.sort(key=lambda repository: repository["name"] or "")
+                return json.dumps(repositories, ensure_ascii=False)
+            except Exception as e:
+                logger.error(f"列出儲存庫時發生錯誤 / Error listing repositories: {e}")
+                return json.dumps({"error": str(e)})
+        
+        @self.mcp.tool()
+        async def delete_repository(repo: str) -> str:
+            """從圖譜刪除一個儲存庫
+            Delete one repository from the graph, leaving the other repositories as they are
+            
+            Args:
+                repo: 儲存庫名稱 / Repository name
+            
+            Returns:
+                刪除的節點數量的JSON字符串 / JSON with the number of deleted nodes
+            """
+            try:
+                repo = get_repo_name(repo)
+                if any(job.running and (job.repo or self.repo) == repo for job in self.index_jobs.jobs()):
+                    return json.dumps({"error": f"Repository '{repo}' is being indexed"})
+                
+                def delete():
+                    with self.coordinator.applying(repo=repo):
+                        deleted = self.db.delete_repository(repo)
+                        # 其他儲存庫指向它的跨儲存庫依賴隨之消失 / Cross-repo dependencies into it go with it
+                        link_repositories(self.db)
+                    self.db.flush()
+                    return deleted
+                
+                deleted = await asyncio.to_thread(delete)
+                return json.dumps({"repo": repo, "nodes_deleted": deleted}, ensure_ascii=False)
+            except Exception as e:
+                logger.error(f"刪除儲存庫時發生錯誤 / Error deleting repository: {e}")
+                return json.dumps({"error": str(e)})
+        
+        @self.mcp.tool()
+        async def diff_graphs(base_repo: str, head_repo: str, path_prefix: str = None,
+                              complexity_threshold: float = DEFAULT_COMPLEXITY_THRESHOLD,
+                              max_results: int = DIFF_DEFAULT_MAX_RESULTS, delete_snapshots: bool = False) -> str:
+            """比較同一程式碼庫的兩個索引版本（快照）
+            Compare two indexed versions (snapshots) of a codebase: what a change did to the architecture
+            
+            Args:
+                base_repo: 舊版本的儲存庫，例如 src/main.py --ref main 建立的 "snap-main" / Repository of the old version, e.g. "snap-main" from src/main.py --ref main
+                head_repo: 新版本的儲存庫 / Repository of the new version
+                path_prefix: 只報告此目錄下的變更（相對於程式碼庫根目錄）/ Only changes under this directory, relative to the codebase root
+                complexity_threshold: 報告循環複雜度增加超過此值的函數 (預設 2) / Report functions whose cyclomatic complexity grew by more than this (default 2)
+                max_results: 每個列表最多返回的項目數 (預設 500) / Maximum number of entries of each list (default 500)
+                delete_snapshots: 比較後刪除兩個快照（非快照的儲存庫保留）/ Delete both repositories afterwards if they are snapshots
+            
+            Returns:
+                結構化JSON：新增與移除的套件依賴（重點）、新增、移除、重新命名或搬移及修改（簽名或 body_hash）的符號、
+                新增與移除的邊、複雜度增加的函數；report 為文字報告
+                / Structured JSON: package dependencies added and removed (the headline), symbols added, removed,
+                renamed or moved and modified (signature or body_hash), edges added and removed, and functions whose
+                complexity grew; "report" is a text report
+            """
+            try:
+                def diff():
+                    result = diff_snapshot_graphs(self.db, base_repo, head_repo, path_prefix, complexity_threshold,
+                                                  max_results)
+                    if delete_snapshots:
+                        result["deleted"] = delete_snapshot_repos(self.db, [base_repo, head_repo])
+                        self.db.flush()
+                        # 在查詢閘門內刪除，不經過套用 / Deleted under the query gate, not in an apply
+                        for deleted_repo in result["deleted"]:
+                            self.coordinator.changed(GraphChange(deleted_repo))
+                    return result
+                
+                result = await asyncio.to_thread(diff)
+                result["report"] = format_diff_report(result)
+                return json.dumps(result, ensure_ascii=False)
+            except Exception as e:
+                logger.error(f"比較圖譜時發生錯誤 / Error diffing graphs: {e}")
+                return json.dumps({"error": str(e)})
+        
+        @self.mcp.tool()
+        async def get_watch_status() -> str:
+            """獲取檔案監看狀態
+            Get the file watcher status, to tell whether graph results may be stale
+            
+            Returns:
+                監看狀態的JSON字符串，包含待同步檔案數、延後同步的原因與最後同步時間
+                / JSON with the watcher state, including pending files, why a sync is deferred and the last sync time
+            """
+            if self.watcher is None:
+                return json.dumps({"running": False, "stale": None, "reason": "watch mode is not enabled"})
+            return json.dumps(self.watcher.status(), ensure_ascii=False)
     
     def _register_prompts(self):
         """註冊MCP提示詞"""
@@ -360,29 +1529,179 @@ class CodebaseKnowledgeGraphMCP:
             
             節點類型:
             - File: 代表程式碼檔案
-              - 屬性: id, path, name
+              - 屬性: id, path, name, content_hash, mtime, size, index_state (增量索引用 / used by incremental indexing),
+                renamed_from (移動前的路徑 / path before a move),
+                last_commit, last_author, last_commit_date (最後修改此檔案的提交 / last commit that touched the file, git);
+                Go, Java, Kotlin: package (套件名稱 / package name), import_path;
+                Rust: package, import_path (模組路徑 / module path, e.g. shapes::geometry), modules (`mod x;` 宣告 / declarations), doc, header;
+                C#: package, import_path (第一個命名空間 / first namespace), namespaces (多於一個時 / when there are several)
             - Class: 代表類別定義
-              - 屬性: id, name, file_path, line_no, end_line_no, code_snippet
+              - 屬性: id, name, file_path, line_no, end_line_no, code_snippet (Python: decorators, dataclass; Go: type_kind, embeds (嵌入欄位 / embedded fields), type_parameters (泛型 / generics);
+                TypeScript: decorators, is_abstract, extends, implements, exported;
+                Java: kind (class/record/anonymous), qualified_name (巢狀類別為 Outer$Inner / nested classes are Outer$Inner),
+                modifiers, annotations, extends, implements, supertype (匿名類別 / anonymous classes), local;
+                Rust: kind (struct/union), qualified_name, visibility, type_parameters, derives (`#[derive]` 的 trait / derived traits),
+                attributes, implements;
+                C#: kind (class/struct/record/record struct), qualified_name (巢狀類型為 Outer+Inner / nested types are Outer+Inner),
+                modifiers, attributes, bases, doc, declared_in (partial 類型所有部分的檔案 / files of every part of a partial type);
+                Ruby: kind (class/module), qualified_name (A::B::C), superclass, mixins (如 / e.g. "include Trackable"),
+                associations (Rails 關聯巨集 / Rails association macros), doc;
+                Kotlin: kind (class/data_class/object/companion_object), qualified_name (同 Java / as in Java, Outer$Companion),
+                modifiers, annotations, supertypes, sealed, doc)
             - Function: 代表全局函數定義
-              - 屬性: id, name, file_path, line_no, end_line_no, code_snippet
+              - 屬性: id, name, file_path, line_no, end_line_no, code_snippet,
+                signature_json (參數、回傳值與型別參數 / parameters, returns and type parameters), arity, signature (單行宣告 / one-line declaration)
+                cyclomatic_complexity, statement_count, line_count, max_nesting (度量，見 query_metrics / metrics, see query_metrics)
+                (Python: is_async, decorators, nested (巢狀函數 / nested function), conditional, condition (if/try 區塊 / if/try blocks);
+                TSX: component (回傳 JSX 的 React 元件 / React component returning JSX);
+                Kotlin: signature, return_type, modifiers, annotations, receiver_type 與 extension (擴充函數 / extension functions))
             - Method: 代表類別方法
-              - 屬性: id, name, file_path, line_no, end_line_no, code_snippet
-            - Variable: 代表變數定義
-              - 屬性: id, name, file_path, line_no
+              - 屬性: id, name, file_path, line_no, end_line_no, code_snippet, signature_json, arity, signature,
+                cyclomatic_complexity, statement_count, line_count, max_nesting
+                (Go: receiver_type, receiver_kind, signature (正規化 / normalized); Python: is_async, decorators, conditional;
+                Java: signature (區分多載 / tells overloads apart), return_type, constructor, modifiers, annotations;
+                Rust: receiver_type, receiver_kind (value/ref/mut_ref, 關聯函數為空 / empty for associated functions), trait, default (trait 預設實作 / trait default);
+                C#: signature, return_type, constructor, modifiers, attributes;
+                Ruby: qualified_name (實例方法為 A::B#name，類別方法為 A::B.name / A::B#name for instance methods, A::B.name for class methods),
+                class_method, visibility, dynamic (define_method);
+                Kotlin: signature, return_type, constructor, primary (主建構函數 / primary constructor), modifiers, annotations)
+            - Variable: 代表變數定義 / Package-level variable (Python module-level names, Go `var`, TypeScript `let` / `var`)
+              - 屬性: id, name, file_path, line_no, value (簡單常值的初始值 / simple literal initializer);
+                Python: annotation; Go: type; TypeScript: declaration_type, exported;
+                Kotlin: type, mutable (var), custom_getter, custom_setter, delegate (`by lazy`), receiver_type, extension
+            - Constant: 代表常數 / Package-level constant (Go `const`, upper-case or `Final` Python names, TypeScript `const`, Ruby constants,
+              Kotlin `const val`)
+              - 屬性: 同 Variable / as Variable; Go: value (iota 與常數運算式已求值 / iota and constant expressions evaluated); Ruby: qualified_name
             - Module: 代表導入的模組
               - 屬性: id, name
+            - Interface: 代表介面定義 / Interface declaration (Go, TypeScript, Java, Rust traits, C#)
+              - 屬性: id, name, file_path, line_no, methods (方法簽名 / method signatures), embeds, constraint, type_set (型別集合 / type set, e.g. ["~int | ~int64"]),
+                type_parameters (Go), properties, extends (TypeScript);
+                Java: kind (interface/annotation), qualified_name, modifiers, annotations, extends;
+                Rust: kind (trait), qualified_name, visibility, extends (supertrait);
+                C#: kind (interface), qualified_name, modifiers, attributes, bases, declared_in;
+                Kotlin: kind (interface/annotation), qualified_name, modifiers, annotations, supertypes, sealed
+            - TypeAlias: 代表型別別名 / Type alias (TypeScript, Rust, Kotlin)
+              - 屬性: id, name, file_path, line_no, type
+            - Enum: 代表列舉 / Enum (TypeScript, Java, Rust, C#, Kotlin)
+              - 屬性: id, name, file_path, line_no, members (Rust: 變體 / variants), is_const (Java, Rust: qualified_name, implements)
+            - ClassVariable: 代表類別屬性 / Class attribute or field (Python, TypeScript, Java, Go, Rust, C#, Ruby attr_*)
+              - 屬性: id, name, file_path, line_no, annotation, decorators (TypeScript: accessibility, parameter_property;
+                Java: type, modifiers, annotations, component (record 元件 / record component);
+                Go: parent_class, type, tag (結構欄位 / struct fields); Rust: parent_class, type, visibility;
+                C#: type, modifiers, attributes, property, getter, setter, init, auto (自動實作屬性 / auto-implemented property), component;
+                Ruby: property, getter, setter, macro (attr_accessor/attr_reader/attr_writer), visibility, class_attribute;
+                Kotlin: type, mutable, constructor_property (主建構函數的 val/var 參數 / val or var parameter of the primary constructor),
+                custom_getter, custom_setter, delegate, modifiers, annotations)
+            - ExternalFunction: 未索引套件中被調用符號的佔位節點 / Placeholder for a called symbol in an unindexed package
+              - 屬性: id, name, import_path, qualified_name, placeholder
+            - ExternalType: 未索引的基底類型的佔位節點 / Placeholder for a base type that is not indexed (C#, Kotlin, Ruby superclasses and mixins)
+              - 屬性: id, name, qualified_name, placeholder
+            - Package: 檔案所屬的套件（Go 套件或目錄）/ Package of a file (Go package, otherwise its directory)
+              - 屬性: id, name, path (目錄 / directory), import_path (Go 匯入路徑或 Java 套件 / Go import path or Java package)
+              - Rust: 每個模組一個，含內嵌 `mod x { ... }`（inline: true）/ one per module, inline `mod x { ... }` included (inline: true)
+              - C#: 每個命名空間一個 / one per namespace
+              - summary (套件文件與 README 的摘要 / summary from the package doc and README), summary_sources (其來源檔案 / files it comes from)，
+                見 get_package_overview / see get_package_overview
+            - ExternalPackage: 未索引的被導入套件的佔位節點 / Placeholder for an imported package that is not indexed
+              - 屬性: id, name, module_path (Go 匯入路徑、Python 模組或 npm 套件 / Go import path, Python module or npm package), placeholder
+            - IndexMetadata: 每個已索引根目錄一個 / One per indexed root
+              - 屬性: id, name, root, index_complete (false: 最近一次執行被取消 / the last run was cancelled),
+                indexed_at (最近一次執行的時間 / time of the last run), symbol_id_scheme (qualified 或 signature / qualified or signature);
+                git 儲存庫內 / inside a git repository: git_root, git_commit (已索引的提交 / indexed commit),
+                git_branch (分離 HEAD 時為空 / empty on a detached HEAD), git_detached, git_shallow
+            - SchemaMetadata: 圖譜的 schema 版本，見 get_graph_info / Schema version of the graph, see get_graph_info
+              - 屬性: id, schema_version, migrated_at, migration_failed_step 與 migration_error (遷移失敗時 / when a migration failed)
+            - Repository: 每個已索引儲存庫一個 / One per indexed repository
+              - 屬性: id, name, root, modules (go.mod、package.json、Cargo.toml、pyproject.toml 發佈的模組名稱 / module names its manifests publish)
+              - 快照 (src/main.py --ref) / Snapshots: snapshot_ref, snapshot_commit, snapshot_of (原始碼庫根目錄 / root of the codebase it was taken of)
+            - 所有節點有 repo (所屬儲存庫 / repository it belongs to)；"default" 以外儲存庫的節點 ID 以 "<repo>@" 開頭
+              / Every node has repo; node IDs of repositories other than "default" start with "<repo>@"
+            - File、Package 與符號節點有 symbol_id：穩定的符號ID `repo:path:kind:qualified_name[:signature_hash]`，
+              重新索引後不變，見 resolve_symbol / File, Package and symbol nodes have symbol_id, the stable symbol ID
+              (unique, indexed) that survives re-indexing, see resolve_symbol; signature_hash tells overloads apart
+            - Function / Method / Class / File 節點另有 embedding (向量 / vector) 與 embedding_key (文字與模型的雜湊 / hash of text and model),
+              供 semantic_search 使用 / used by semantic_search
+            - Function / Method / Class / Interface / Enum / TypeAlias 節點另有 body_hash (正規化程式碼的雜湊 / hash of the normalized code)
+              與 renamed_from (重新命名前的名稱，節點 ID 保持不變 / name before a rename, the node ID is kept)
+            - 生成檔案的 File 與符號節點另有 generated: true，搜索工具預設排除 (include_generated)
+              / File and symbol nodes of generated files have generated: true, left out by the search tools unless include_generated
+            - 測試程式碼的 Function / Method / File 節點另有 is_test: true（Go Test*、pytest、JUnit @Test、Rust #[test]、C# [Fact]/[Test]、Minitest test_*、Jest 測試檔）
+              / Function, Method and File nodes of test code have is_test: true (Go Test*, pytest, JUnit @Test, Rust #[test], C# [Fact]/[Test], Minitest test_*, Jest test files)
+              - Jest 的 it() / test() 回呼是 Function 節點，名稱含 describe 路徑，另有 test_framework 與 describe
+                / Jest it() / test() callbacks are Function nodes named after their describe path, with test_framework and describe
+              - 參數化的 pytest 測試另有 parametrized: true / Parametrized pytest tests have parametrized: true
+            - 未擷取符號的 File 節點有 skipped_reason (too_large: 超過 INDEX_MAX_FILE_BYTES，另有 size_bytes / above INDEX_MAX_FILE_BYTES, with size_bytes;
+              parse_timeout: 解析超過 INDEX_PARSE_TIMEOUT / parsing took longer than INDEX_PARSE_TIMEOUT) / File nodes indexed without symbols
+            - 解析失敗的 File 節點有 parse_error (錯誤訊息 / message)、parse_error_category (syntax_error、unsupported_feature、
+              encoding、timeout、parser_error)、parse_error_line 與 parse_error_column (有位置時 / when known) 及 parse_error_analyzer
+              (失敗的解析器 / parser that failed)，見 get_index_diagnostics / File nodes of files that failed to parse
             
             關係類型:
             - CONTAINS: 表示一個檔案包含某個程式碼元素
-              - 例如: (File)-[:CONTAINS]->(Function)
+              - 例如: (File)-[:CONTAINS]->(Function), (Package)-[:CONTAINS]->(File)
             - DEFINES: 表示一個類別定義了一個方法或屬性
               - 例如: (Class)-[:DEFINES]->(Method)
+            - METHOD_OF: 表示方法屬於其接收者類型 / Method belongs to its receiver type (Go, Rust `impl` blocks)
+              - 例如: (Method)-[:METHOD_OF {receiver_kind: "pointer"|"value"}]->(Class)
             - CALLS: 表示函數調用關係
               - 例如: (Function)-[:CALLS]->(Function)
+              - 屬性: line_no, call_lines (調用位置行號 / call-site line numbers, Go)
+              - 經由介面值的調用 / Calls through an interface value (Go): (Function)-[:CALLS {method, via_interface: true}]->(Interface)
+            - TESTS: 表示測試函數直接調用的產品程式碼 / Test calls production code directly
+              - 例如: (Function|Method)-[:TESTS {confidence, line_no}]->(Function|Method)
+              - confidence: 0.9 或 0.5，0.5 另有 reason / 0.5 edges have a reason: interface (經由介面調用 / called
+                through an interface, the target is the Interface, with method), mocked (被 @patch 替換 / replaced with @patch)
+              - 多跳覆蓋 (COVERED_BY) 於查詢時計算，見 get_tests_for / Coverage over several hops is computed at query time, see get_tests_for
             - EXTENDS: 表示類別的繼承關係
-              - 例如: (Class)-[:EXTENDS]->(Class)
-            - IMPORTS: 表示檔案導入了某個模組
-              - 例如: (File)-[:IMPORTS]->(Module)
+              - 例如: (Class)-[:EXTENDS]->(Class), (Interface)-[:EXTENDS]->(Interface)
+              - Kotlin 與 Java 互相解析：Kotlin 類別可繼承 Java 類別，反之亦然 / Kotlin and Java resolve each other: a Kotlin class may
+                extend a Java class and the other way round; Kotlin 超類型依目標種類為 EXTENDS 或 IMPLEMENTS / Kotlin supertypes
+                get EXTENDS or IMPLEMENTS by the kind of the target
+            - EXTENSION_OF: 表示 Kotlin 擴充函數或屬性的接收者類型 / Kotlin extension function or property and its receiver type
+              - 例如: (Function|Variable)-[:EXTENSION_OF {original_name}]->(Class|Interface)
+            - INHERITS_FROM: 表示基底類型不在程式碼庫中 / Base type that is not indexed (C#, Kotlin, Ruby)
+              - 例如: (Class|Interface)-[:INHERITS_FROM]->(ExternalType)
+            - MIXES_IN: 表示類別或模組混入模組 / Class or module mixes in a module (Ruby include, extend, prepend)
+              - 例如: (Class)-[:MIXES_IN {mixin: "include"|"extend"|"prepend", original_name, line_no}]->(Class|ExternalType)
+            - BELONGS_TO、HAS_ONE、HAS_MANY、HAS_AND_BELONGS_TO_MANY: Rails 模型關聯 / Rails model associations (Ruby)
+              - 例如: (Class)-[:HAS_MANY {name: "posts", class_name, through, line_no}]->(Class)
+            - USES: 表示函數使用套件層級的常數或變數 / Function uses a package-level constant or variable (Python, Go, TypeScript)
+              - 例如: (Function|Method)-[:USES {usage, line_no, use_lines}]->(Constant|Variable)
+              - usage: read、compare (比較、case 值、errors.Is / comparisons, case values, errors.Is)、return、write；每種用法一條關係
+                / one relation per kind of use
+            - DECORATED_BY: 表示函數或類別使用程式碼庫中定義的裝飾器 / Function or class uses a decorator defined in the codebase (Python, TypeScript; Java annotations, C# attributes)
+              - 例如: (Function)-[:DECORATED_BY {decorator, line_no}]->(Function)
+            - IMPLEMENTS: 表示類型滿足介面（依方法簽名推導）/ Type satisfies an interface, derived from method signatures (Go)
+              - 例如: (Class)-[:IMPLEMENTS {via: "value"|"pointer"}]->(Interface)
+              - TypeScript 與 Java 的 `implements` 子句、C# 基底清單 / TypeScript and Java `implements` clauses, C# base lists: (Class)-[:IMPLEMENTS {explicit: true}]->(Interface)
+              - Rust `impl Trait for Type` 與 `#[derive]`（derived: true）/ Rust `impl Trait for Type` and `#[derive]` (derived: true)
+            - NEAR_IMPLEMENTS: 只缺少少量方法（需啟用 GO_IMPLEMENTS_NEAR_MISS）/ Type is missing few methods (GO_IMPLEMENTS_NEAR_MISS)
+              - 屬性: missing_methods
+            - EMBEDS: 表示介面嵌入其他介面，或結構體嵌入其他類型 / Interface embeds an interface, or struct embeds a type (Go)
+              - 例如: (Interface)-[:EMBEDS]->(Interface), (Class)-[:EMBEDS {embed_kind: "pointer"|"value"}]->(Class|Interface)
+              - 嵌入類型的方法被提升，由 get_type_members 查詢 / Methods of embedded types are promoted, see get_type_members
+            - CONSTRAINED_BY: 表示泛型型別參數受程式碼庫中的介面約束 / Type parameter constrained by an interface or type of the codebase (Go)
+              - 例如: (Function|Class|Interface)-[:CONSTRAINED_BY {type_parameters: ["N"]}]->(Interface)
+            - IMPORTS: 表示檔案導入了某個模組 / File imports a file, symbol or package
+              - 例如: (File)-[:IMPORTS]->(File|Package|ExternalPackage), Python `from x import y`: (File)-[:IMPORTS]->(Function|Class),
+                Java `import a.b.C`, Rust `use a::b::C`, C# `using X = a.b.C`: (File)-[:IMPORTS]->(Class|Interface|Enum)
+              - 屬性: line_no; Go: import_path, alias, dot, blank (點導入與空白導入 / dot and blank imports);
+                Python: module, symbol, alias; Java: import_path, symbol, member, wildcard, static; Rust: import_path, symbol, alias, wildcard;
+                C#: import_path, symbol, alias, static, global; Kotlin: import_path, symbol, member, wildcard, alias; Ruby: module (require 與 require_relative 的路徑 / path of require and require_relative)
+            - DEPENDS_ON: 由檔案導入彙總的套件依賴 / Package dependency aggregated from file imports
+              - 例如: (Package)-[:DEPENDS_ON {imports, files}]->(Package|ExternalPackage)
+              - 跨儲存庫 / Across repositories: (Package)-[:DEPENDS_ON {cross_repo: true, repo, module, imports, files}]->(Package|Repository)
+            - DEPENDS_ON_FILE: 表示檔案有跨檔案關係指向另一個檔案 / File has a cross-file relation into another file
+              - 例如: (File)-[:DEPENDS_ON_FILE]->(File)
+            - 所有關係有來源資訊 / Every relationship has provenance:
+              - source: ast (語法樹的事實 / syntax tree facts)、derived:package_imports、derived:file_dependencies、
+                heuristic:interface_match、heuristic:grpc_link、heuristic:ffi_link、heuristic:repo_link、heuristic:test_link
+              - confidence: 0 到 1，ast 與 derived 為 1.0 / between 0 and 1, 1.0 for ast and derived edges;
+                find_path、get_call_hierarchy 與 analyze_impact 的 min_confidence 過濾 / filtered with min_confidence
+              - indexer_version: 寫入此關係的索引器版本 / version of the indexer that wrote the relationship
+              - premises: 推導此關係所依據的檔案，增量索引時據此失效 / files the relationship was derived from,
+                used by incremental indexing to invalidate it (IMPLEMENTS、DEPENDS_ON、CROSS_LANG_CALLS、TESTS)
             """
         
         @self.mcp.resource("cypher://examples")
@@ -431,46 +1750,186 @@ class CodebaseKnowledgeGraphMCP:
             ```
             """
     
+    async def _cached(self, tool: str, params: Dict[str, Any], repo: Optional[str], no_cache: bool, compute) -> Any:
+        """快取的工具結果，未命中時在工作執行緒中計算
+        Cached result of a tool call, computed on a worker thread on a miss (see src/mcp/query_cache.py)
+        
+        Args:
+            tool: 工具名稱 / Tool name
+            params: 結果所依賴的全部參數 / Every parameter the result depends on
+            repo: 查詢的儲存庫 / Repository queried
+            no_cache: 重新計算並更新快取 / Compute afresh, updating the cache
+            compute: 由 GraphStore 計算結果 / Computes the result from a GraphStore
+        """
+        return await asyncio.to_thread(self.query_cache.run, tool, params, self._repo_db(repo), compute, no_cache)
+    
+    def _repo_db(self, repo: Optional[str]):
+        """查詢工具使用的儲存：整個圖譜或單一儲存庫的視圖
+        GraphStore a query tool reads: the whole graph for "all", else the view of one repository
+        """
+        if repo is None or repo == ALL_REPOS:
+            return self.db
+        return RepositoryStore(self.db, repo)
+    
+    def _run_index_job(self, root: str, incremental: bool, progress: IndexProgress,
+                       repo: Optional[str] = None) -> Dict[str, Any]:
+        """在背景執行緒中索引一個根目錄 / Index one root, on an index job thread
+        
+        Returns:
+            寫入的節點與關係數量及執行統計 / Nodes and relationships written and run statistics
+        """
+        # 延遲導入，避免服務器啟動時載入解析器
+        # Imported lazily so the parsers are not loaded at server start-up
+        from src.main import CodebaseKnowledgeGraph
+        
+        # 寫入服務器使用中的儲存後端 / Write into the store the server reads from
+        kg = CodebaseKnowledgeGraph(
+            neo4j_uri=self.neo4j_uri,
+            neo4j_user=self.neo4j_user,
+            neo4j_password=self.neo4j_password,
+            store=self.db,
+            repo=repo or self.repo
+        )
+        try:
+            num_nodes, num_relations = kg.process_codebase(root, False, incremental, progress=progress)
+            stats = kg.last_run_stats
+        finally:
+            kg.close()
+        return {
+            "codebase_path": root,
+            "repo": kg.repo,
+            "nodes_written": num_nodes,
+            "relationships_written": num_relations,
+            "stats": stats
+        }
+    
+    async def _wait_for_job(self, job, ctx: Context = None) -> None:
+        """等待索引工作結束，客戶端支援時發送進度通知
+        Wait for an index job, sending progress notifications when the client asked for them
+        """
+        while not await asyncio.to_thread(job.done.wait, PROGRESS_INTERVAL_SECONDS):
+            if ctx is None:
+                continue
+            snapshot = job.progress.snapshot()
+            try:
+                await ctx.report_progress(snapshot["processed"], snapshot["total"])
+            except Exception as e:
+                logger.debug(f"無法發送進度通知 / Could not send a progress notification: {e}")
+    
+    def start_watcher(self):
+        """啟動檔案監看，變更時增量同步知識圖譜
+        Start watching the codebase and sync changes into the graph incrementally
+        
+        Returns:
+            IndexWatcher
+        """
+        if self.watcher is None:
+            # 延遲導入，避免未啟用監看時載入解析器
+            # Imported lazily so the parsers are only loaded in watch mode
+            from src.main import CodebaseKnowledgeGraph
+            from src.indexing.watcher import watch_codebase
+            
+            kg = CodebaseKnowledgeGraph(
+                neo4j_uri=self.neo4j_uri,
+                neo4j_user=self.neo4j_user,
+                neo4j_password=self.neo4j_password,
+                store=self.db,
+                repo=self.repo
+            )
+            self.watcher = watch_codebase(kg, self.codebase_path)
+        # 啟動時先同步一次，圖譜可能比檔案舊
+        # Sync once at start-up, the graph may be older than the files
+        self.watcher.start(initial_sync=True)
+        return self.watcher
+    
     def start(self, port=None, transport="stdio"):
         """啟動MCP服務器
         
         Args:
             port: HTTP服務器端口號 (ignored, port is set during initialization)
             transport: 傳輸協議，可選 "stdio", "http" (streamable-http) 或 "sse"
+                / "stdio" (default), "http" (streamable HTTP) or "sse"; HTTP transports serve several
+                clients at once and shut down gracefully on SIGINT / SIGTERM (see src/mcp/transport.py)
+        
+        Raises:
+            SchemaVersionError: 圖譜的 schema 版本較舊且未啟用自動遷移，或較新
+                / The graph's schema version is older and GRAPH_AUTO_MIGRATE is off, or newer
+            MigrationError: 遷移步驟失敗 / A migration step failed
         """
-        if transport == "http":
-            logger.info(f"MCP服務器以HTTP模式啟動，監聽於 http://{self.server_host}:{self.server_port}/mcp")
-            self.mcp.run(transport="streamable-http")
-        elif transport == "sse":
-            logger.info(f"MCP服務器以SSE模式啟動，監聽於 http://{self.server_host}:{self.server_port}/sse")
-            self.mcp.run(transport="sse")
-        else:
-            logger.info("MCP服務器以stdio模式啟動")
-            self.mcp.run(transport="stdio")
+        # 啟動前檢查圖譜的 schema 版本 (見 src/graph_store/migrations.py)
+        # Check the graph's schema version before serving it (see src/graph_store/migrations.py)
+        schema = ensure_schema(self.db)
+        if schema["applied"]:
+            logger.info(f"圖譜已遷移至 schema 版本 {schema['schema_version']} / Graph migrated to schema version {schema['schema_version']}")
+        
+        if self.watch:
+            self.start_watcher()
+        
+        try:
+            if transport in HTTP_TRANSPORTS:
+                path = "/mcp" if transport == "http" else "/sse"
+                auth = "，需 Bearer token" if self.auth_token else ""
+                logger.info(f"MCP服務器以{transport.upper()}模式啟動，監聽於 http://{self.server_host}:{self.server_port}{path}{auth}")
+                serve_http(self.mcp, self.tool_calls, transport, self.server_host, self.server_port,
+                           auth_token=self.auth_token, shutdown_timeout=self.shutdown_timeout)
+            else:
+                logger.info("MCP服務器以stdio模式啟動")
+                self.mcp.run(transport="stdio")
+        finally:
+            # 關閉時停止監看，進行中的同步會先完成
+            # Stop watching on shutdown, a sync in progress is finished first
+            if self.watcher is not None:
+                self.watcher.stop()
 
 
 def main():
     """主函數"""
     parser = argparse.ArgumentParser(description="Codebase知識圖譜MCP服務器")
     parser.add_argument("--codebase-path", help="程式碼庫路徑", default=".")
-    parser.add_argument("--transport", choices=["stdio", "http", "sse"], default="stdio", help="MCP傳輸協議")
-    parser.add_argument("--port", type=int, help="HTTP服務器端口號（僅用於HTTP/SSE傳輸）", default=8080)
+    parser.add_argument("--transport", choices=["stdio", *HTTP_TRANSPORTS], default="stdio", help="MCP傳輸協議")
+    parser.add_argument("--port", type=int, help="HTTP服務器端口號（僅用於HTTP/SSE傳輸）")
+    parser.add_argument("--listen",
+                        help="HTTP/SSE 監聽位址 / Address HTTP/SSE listen on, host:port or :port for every interface "
+                             "(default: MCP_SERVER_HOST and MCP_SERVER_PORT)")
+    parser.add_argument("--storage", choices=STORAGE_BACKENDS,
+                        help="儲存後端 / Storage backend (default: GRAPH_STORAGE or neo4j)")
+    parser.add_argument("--graph-file", help="記憶體後端的JSON檔案 / JSON file of the memory backend (default: GRAPH_STORE_PATH)")
     parser.add_argument("--neo4j-uri", help="Neo4j資料庫URI")
     parser.add_argument("--neo4j-user", help="Neo4j使用者名稱")
     parser.add_argument("--neo4j-password", help="Neo4j密碼")
+    parser.add_argument("--watch", action="store_true", default=None,
+                        help="監看程式碼庫並自動增量同步 / Watch the codebase and keep the graph in sync")
+    parser.add_argument("--repo",
+                        help="reindex 與監看寫入的儲存庫 / Repository reindex and watch mode write (default: INDEX_REPO)")
     
     args = parser.parse_args()
+    host, port = None, args.port
+    if args.listen:
+        try:
+            host, port = parse_listen(args.listen)
+        except ValueError as e:
+            parser.error(str(e))
     
     # 創建MCP服務器
     server = CodebaseKnowledgeGraphMCP(
         neo4j_uri=args.neo4j_uri,
         neo4j_user=args.neo4j_user,
         neo4j_password=args.neo4j_password,
-        server_port=args.port
+        server_host=host,
+        server_port=port,
+        codebase_path=args.codebase_path,
+        watch=args.watch,
+        storage=args.storage,
+        graph_file=args.graph_file,
+        repo=args.repo
     )
     
     # 啟動服務器
-    server.start(transport=args.transport)
+    try:
+        server.start(transport=args.transport)
+    except (SchemaVersionError, MigrationError) as e:
+        logger.error(str(e))
+        sys.exit(1)
 
 
 if __name__ == "__main__":
@@ -0,0 +1,284 @@
+"""
+Fuzzy name matching and ranking for the find_symbol MCP tool.
+
+A query is matched against a symbol name with these strategies, each
+earning a share of the name score when it fits:
+
+- exact: the same name, 1.0 (id when the query is the symbol ID)
+- case_insensitive: the same name in another case, 0.95
+- normalized: the same words once camelCase, snake_case and kebab-case
+  are split, e.g. getUserByID and get_user_by_id, 0.9
+- prefix / substring: the normalized query starts or is part of the
+  normalized name, more for the larger share of the name it covers
+- words: at least half of the query's words are words of the name, in
+  any order, e.g. user_by_id against find_user_by_id
+- edit_distance: the normalized names are at most max_edits edits apart
+  (Damerau-Levenshtein, one edit per four characters of the query), for
+  typos such as getUsr
+- subsequence: the query's characters appear in order in the name, fzf
+  style, with bonuses for characters at word starts and in runs, e.g.
+  gubi against getUserByID
+
+The best strategy earns the name score. The ranking score is the share of
+these parts a symbol earns, from 0 to 1: the name score, whether the
+symbol is exported or public in its language, whether it is outside test
+and generated code, how little longer than the query its name is, and
+when a package hint is given, whether the symbol's package, module, file
+or owner matches it (as resolve_symbol reads scope hints).
+
+Candidates are fetched in two bounded steps: the distinct names of the
+symbol labels (up to NAME_FETCH_LIMIT), matched here, then the nodes of
+the CANDIDATE_NAME_LIMIT best names, up to CANDIDATE_LIMIT nodes. The
+ranked list is then paginated by the caller.
+"""
+
+import re
+from typing import Any, Callable, Dict, List, Optional, Tuple
+
+from src.analysis.unreferenced import is_exported
+from src.ast_parser.language_detector import detect_language
+from src.indexing.testcode import is_test_file
+from src.mcp.generated import is_generated_node
+from src.mcp.references import find_named_nodes, symbol_candidates
+from src.mcp.resolve import scope_matches
+
+# find_symbol match modes: exact names only, exact names if any else fuzzy, always fuzzy
+MATCH_MODES = ("exact", "auto", "fuzzy")
+
+DEFAULT_MAX_EDITS = 2
+
+# Labels fuzzy matching looks at when no node type is given
+SYMBOL_LABELS = [
+    "Function", "Method", "Class", "Interface", "Enum", "TypeAlias",
+    "Constant", "Variable", "GlobalVariable", "ClassVariable",
+]
+
+# Distinct names fetched from the store for fuzzy matching
+NAME_FETCH_LIMIT = 100000
+
+# Best matching names whose nodes are fetched, and the nodes fetched at most
+CANDIDATE_NAME_LIMIT = 100
+CANDIDATE_LIMIT = 500
+
+# Name score of each strategy; prefix, substring, words and subsequence earn
+# between their base and base plus span, by how much of the name they cover
+EXACT_SCORE = 1.0
+CASE_INSENSITIVE_SCORE = 0.95
+NORMALIZED_SCORE = 0.9
+PREFIX_SCORE, PREFIX_SPAN = 0.6, 0.2
+SUBSTRING_SCORE, SUBSTRING_SPAN = 0.45, 0.2
+WORDS_SCORE, WORDS_SPAN = 0.3, 0.4
+EDIT_SCORE, EDIT_PENALTY = 0.8, 0.1
+SUBSEQUENCE_SCORE, SUBSEQUENCE_SPAN = 0.2, 0.3
+
+# Query characters per edit edit_distance allows
+CHARACTERS_PER_EDIT = 4
+
+# Weight of each part of the ranking score
+NAME_WEIGHT = 0.6
+PACKAGE_WEIGHT = 0.15
+EXPORTED_WEIGHT = 0.1
+SOURCE_WEIGHT = 0.1
+LENGTH_WEIGHT = 0.05
+
+_WORD = re.compile(r"[A-Z]+(?=[A-Z][a-z]|[0-9]|$)|[A-Z]?[a-z]+|[A-Z]+|[0-9]+")
+_SEPARATOR = re.compile(r"[^A-Za-z0-9]+")
+
+
+def split_words(name: str) -> List[str]:
+    """Lower-case words of a camelCase, PascalCase, snake_case or kebab-case name."""
+    words = []
+    for part in _SEPARATOR.split(name):
+        words.extend(word.lower() for word in _WORD.findall(part))
+    return words
+
+
+def _edit_distance(a: str, b: str, bound: int) -> int:
+    """Damerau-Levenshtein distance of a and b (adjacent transpositions count once), or bound + 1 past bound."""
+    if abs(len(a) - len(b)) > bound:
+        return bound + 1
+    previous, current = None, list(range(len(b) + 1))
+    for i in range(1, len(a) + 1):
+        before, previous, current = previous, current, [i] + [0] * len(b)
+        for j in range(1, len(b) + 1):
+            cost = 0 if a[i - 1] == b[j - 1] else 1
+            current[j] = min(previous[j] + 1, current[j - 1] + 1, previous[j - 1] + cost)
+            if i > 1 and j > 1 and a[i - 1] == b[j - 2] and a[i - 2] == b[j - 1]:
+                current[j] = min(current[j], before[j - 2] + 1)
+        if min(current) > bound:
+            return bound + 1
+    return current[-1]
+
+
+def _subsequence_quality(query: str, words: List[str]) -> Optional[float]:
+    """
+    How well query matches the joined words as a subsequence, from 0 to 1, None if it does not.
+    
+    Each query character is matched greedily, leftmost first; one at the start
+    of a word or right after the previous match earns a bonus.
+    """
+    text = "".join(words)
+    starts, offset = set(), 0
+    for word in words:
+        starts.add(offset)
+        offset += len(word)
+    bonus, position, last = 0, 0, -2
+    for char in query:
+        position = text.find(char, position)
+        if position < 0:
+            return None
+        bonus += (position in starts) + (position == last + 1)
+        last = position
+        position += 1
+    return bonus / (2 * len(query))
+
+
+def match_name(query: str, name: str, max_edits: int = DEFAULT_MAX_EDITS) -> Optional[Tuple[str, float]]:
+    """
+    Best strategy matching query against name and its name score, None when none does.
+    
+    Args:
+        query: Name the user asked for
+        name: Symbol name
+        max_edits: Most edits edit_distance allows, 0 to turn it off
+    """
+    if not query or not name:
+        return None
+    if name == query:
+        return "exact", EXACT_SCORE
+    if name.lower() == query.lower():
+        return "case_insensitive", CASE_INSENSITIVE_SCORE
+    query_words, name_words = split_words(query), split_words(name)
+    query_key, name_key = "".join(query_words), "".join(name_words)
+    if not query_key or not name_key:
+        return None
+    if query_key == name_key:
+        return "normalized", NORMALIZED_SCORE
+    
+    matches = []
+    cover = len(query_key) / len(name_key)
+    if name_key.startswith(query_key):
+        matches.append(("prefix", PREFIX_SCORE + PREFIX_SPAN * cover))
+    elif query_key in name_key:
+        matches.append(("substring", SUBSTRING_SCORE + SUBSTRING_SPAN * cover))
+    if len(query_words) > 1:
+        shared = sum(1 for word in query_words if word in name_words)
+        if shared * 2 >= len(query_words):
+            matches.append(("words", WORDS_SCORE + WORDS_SPAN * shared / max(len(query_words), len(name_words))))
+    allowed = min(max_edits, len(query_key) // CHARACTERS_PER_EDIT)
+    if allowed > 0:
+        distance = _edit_distance(query_key, name_key, allowed)
+        if distance <= allowed:
+            matches.append(("edit_distance", EDIT_SCORE - EDIT_PENALTY * distance))
+    quality = _subsequence_quality(query_key, name_words)
+    if quality is not None:
+        matches.append(("subsequence", SUBSEQUENCE_SCORE + SUBSEQUENCE_SPAN * quality))
+    if not matches:
+        return None
+    # Ties go to the strategy listed first
+    return max(matches, key=lambda match: match[1])
+
+
+def _is_exported(properties: Dict[str, Any]) -> bool:
+    name = properties.get("name") or ""
+    file_path = properties.get("file_path")
+    return is_exported(name, properties, detect_language(file_path) if file_path else None)
+
+
+def _in_source(properties: Dict[str, Any]) -> bool:
+    """Outside test code and generated files."""
+    if properties.get("is_test") or is_generated_node(properties):
+        return False
+    file_path = properties.get("file_path")
+    return not (file_path and is_test_file(file_path))
+
+
+def score_symbol(node: Dict[str, Any], candidate: Dict[str, Any], query: str, name_score: float,
+                 package: Optional[str] = None) -> float:
+    """Ranking score of a symbol from 0 to 1, see the module docstring."""
+    properties = node["properties"]
+    name = properties.get("name") or ""
+    possible = NAME_WEIGHT + EXPORTED_WEIGHT + SOURCE_WEIGHT + LENGTH_WEIGHT
+    earned = NAME_WEIGHT * name_score
+    if _is_exported(properties):
+        earned += EXPORTED_WEIGHT
+    if _in_source(properties):
+        earned += SOURCE_WEIGHT
+    earned += LENGTH_WEIGHT * len(query) / max(len(name), len(query))
+    if package:
+        possible += PACKAGE_WEIGHT
+        if scope_matches(candidate, package):
+            earned += PACKAGE_WEIGHT
+    return round(earned / possible, 3)
+
+
+def _exact_nodes(db, name: str, node_type: Optional[str]) -> List[Tuple[Dict[str, Any], str, float]]:
+    nodes = find_named_nodes(db, name, label=node_type, limit=CANDIDATE_LIMIT)
+    return [(node, "exact" if node["properties"].get("name") == name else "id", EXACT_SCORE) for node in nodes]
+
+
+def _fuzzy_nodes(db, name: str, node_type: Optional[str], max_edits: int) -> List[Tuple[Dict[str, Any], str, float]]:
+    labels = [node_type] if node_type else SYMBOL_LABELS
+    matches = []
+    for candidate in db.node_names(labels, limit=NAME_FETCH_LIMIT):
+        match = match_name(name, candidate, max_edits)
+        if match is not None:
+            matches.append((match[1], candidate, match[0]))
+    matches.sort(key=lambda match: (-match[0], match[1]))
+    
+    nodes = _exact_nodes(db, name, node_type) if ":" in name else []
+    for name_score, candidate, strategy in matches[:CANDIDATE_NAME_LIMIT]:
+        if len(nodes) >= CANDIDATE_LIMIT:
+            break
+        for node in db.find_nodes(name=candidate, label=node_type, limit=CANDIDATE_LIMIT - len(nodes)):
+            if node_type or set(node["labels"]) & set(SYMBOL_LABELS):
+                nodes.append((node, strategy, name_score))
+    return nodes
+
+
+def rank_symbols(db, name: str, node_type: Optional[str] = None, match: str = "auto",
+                 package: Optional[str] = None, max_edits: int = DEFAULT_MAX_EDITS,
+                 accept: Optional[Callable[[Dict[str, Any]], bool]] = None) -> List[Dict[str, Any]]:
+    """
+    Symbols matching a name, best first.
+    
+    Args:
+        db: GraphStore to query
+        name: Symbol name or symbol ID
+        node_type: Only nodes with this label
+        match: "exact" for the nodes with that name, "auto" for them or the fuzzy matches when
+            there are none, "fuzzy" for every strategy
+        package: Package hint, e.g. "api/v1" or "utils"; matching symbols rank higher
+        max_edits: Most edits the edit_distance strategy allows, 0 to turn it off
+        accept: Keeps a node record when it returns True (signature and generated filters)
+    
+    Returns:
+        {"node": node record, "score": ranking score, "match": strategy} entries; the strategy of a
+        symbol ID hit is "id"
+    
+    Raises:
+        ValueError: for an unknown match mode
+    """
+    if match not in MATCH_MODES:
+        raise ValueError(f"match must be one of {', '.join(MATCH_MODES)}")
+    found = []
+    if match != "fuzzy":
+        found = [entry for entry in _exact_nodes(db, name, node_type) if accept is None or accept(entry[0])]
+    if match == "fuzzy" or (match == "auto" and not found):
+        found = [entry for entry in _fuzzy_nodes(db, name, node_type, max_edits) if accept is None or accept(entry[0])]
+    
+    unique = {}
+    for node, strategy, name_score in found:
+        unique.setdefault(node["properties"]["id"], (node, strategy, name_score))
+    nodes = [entry[0] for entry in unique.values()]
+    candidates = {candidate["id"]: candidate for candidate in symbol_candidates(db, nodes)}
+    ranked = []
+    for node_id, (node, strategy, name_score) in unique.items():
+        ranked.append({
+            "node": node,
+            "score": score_symbol(node, candidates[node_id], name, name_score, package),
+            "match": strategy,
+        })
+    ranked.sort(key=lambda entry: (-entry["score"], entry["node"]["properties"].get("file_path") or "",
+                                   entry["node"]["properties"].get("line_no") or 0, entry["node"]["properties"]["id"]))
+    return ranked
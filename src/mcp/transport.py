@@ -0,0 +1,239 @@
+"""
+HTTP transports of the MCP server.
+
+stdio stays the default: one client, started by the client itself. With
+`--transport http` the server speaks MCP over streamable HTTP at /mcp, and
+with `--transport sse` over the older HTTP+SSE transport at /sse (messages
+posted to /messages/); `--listen host:port` or `:port` (every interface)
+picks the address. Both serve the app FastMCP builds from the tools,
+prompts and resources registered once in CodebaseKnowledgeGraphMCP, so
+the transports share them.
+
+Several clients can be connected at once, each in its own MCP session
+against the same graph. The tools keep no state between calls, pagination
+included (limit / offset), so nothing of one session shows in another;
+index jobs belong to the server, so a job_id started in one session can be
+polled or cancelled from any other.
+
+With MCP_AUTH_TOKEN set, every HTTP request needs an
+`Authorization: Bearer <token>` header and is refused with 401 otherwise.
+On SIGINT / SIGTERM the server stops accepting connections, refuses new
+tool calls and waits up to MCP_SHUTDOWN_TIMEOUT seconds (default 30) for
+the calls in flight to finish before closing the remaining streams.
+"""
+
+import asyncio
+import functools
+import hmac
+import json
+import logging
+import os
+from typing import Any, Awaitable, Callable, Dict, Optional, Tuple
+
+from mcp.server.fastmcp import FastMCP
+
+logger = logging.getLogger(__name__)
+
+HTTP_TRANSPORTS = ("http", "sse")
+
+# Host of a listen address given as ":port"
+ALL_INTERFACES = "0.0.0.0"
+LOOPBACK_HOSTS = ("127.0.0.1", "localhost", "::1")
+
+DEFAULT_SHUTDOWN_TIMEOUT = 30.0
+
+# Seconds left to the responses of finished calls before open streams are closed
+RESPONSE_GRACE_SECONDS = 2
+
+Scope = Dict[str, Any]
+Receive = Callable[[], Awaitable[Dict[str, Any]]]
+Send = Callable[[Dict[str, Any]], Awaitable[None]]
+
+
+class ShuttingDownError(Exception):
+    """A tool call arrived after the server started shutting down."""
+
+
+def parse_listen(listen: str) -> Tuple[str, int]:
+    """
+    Host and port of a listen address: "host:port", "[::1]:port", ":port" (every interface).
+    
+    Raises:
+        ValueError: the address has no valid port
+    """
+    host, separator, port = listen.strip().rpartition(":")
+    if not separator:
+        raise ValueError(f"Invalid listen address '{listen}', expected host:port or :port")
+    host = host.strip("[]") or ALL_INTERFACES
+    try:
+        number = int(port)
+    except ValueError:
+        number = -1
+    if not 0 < number < 65536:
+        raise ValueError(f"Invalid port in listen address '{listen}'")
+    return host, number
+
+
+def get_auth_token(token: Optional[str] = None) -> Optional[str]:
+    """Bearer token HTTP clients must send, if None, get from MCP_AUTH_TOKEN; empty means no auth."""
+    if token is None:
+        token = os.getenv("MCP_AUTH_TOKEN", "")
+    return token or None
+
+
+def get_shutdown_timeout(timeout: Optional[float] = None) -> float:
+    """Seconds a shutdown waits for the tool calls in flight, if None, get from MCP_SHUTDOWN_TIMEOUT (default 30)."""
+    if timeout is not None:
+        return max(0.0, timeout)
+    value = os.getenv("MCP_SHUTDOWN_TIMEOUT", "")
+    if value:
+        try:
+            return max(0.0, float(value))
+        except ValueError:
+            logger.warning(f"Invalid MCP_SHUTDOWN_TIMEOUT value '{value}', using {DEFAULT_SHUTDOWN_TIMEOUT}")
+    return DEFAULT_SHUTDOWN_TIMEOUT
+
+
+class InFlightCalls:
+    """Counts the tool calls running; once draining, new ones are refused."""
+    
+    def __init__(self):
+        self.count = 0
+        self.draining = False
+        self._idle = asyncio.Event()
+        self._idle.set()
+    
+    def start(self) -> None:
+        """Count a call in.
+        
+        Raises:
+            ShuttingDownError: the server is draining
+        """
+        if self.draining:
+            raise ShuttingDownError("The server is shutting down")
+        self.count += 1
+        self._idle.clear()
+    
+    def finish(self) -> None:
+        """Count a call out."""
+        self.count -= 1
+        if self.count == 0:
+            self._idle.set()
+    
+    async def drain(self, timeout: float) -> bool:
+        """Refuse new calls and wait up to timeout seconds for the running ones; False if some are still running."""
+        self.draining = True
+        if self.count == 0:
+            return True
+        try:
+            await asyncio.wait_for(self._idle.wait(), timeout)
+        except asyncio.TimeoutError:
+            return False
+        return True
+
+
+def track_tool_calls(mcp: FastMCP, calls: InFlightCalls) -> None:
+    """
+    Count every tool mcp registers from now on in calls.
+    
+    The wrapper keeps the tool's name, doc and signature, which FastMCP
+    builds the tool schema from; a call refused while draining reaches the
+    client as a tool error.
+    """
+    register = mcp.tool
+    
+    def tool(*args, **kwargs):
+        decorator = register(*args, **kwargs)
+        
+        def track(func):
+            @functools.wraps(func)
+            async def call(*call_args, **call_kwargs):
+                calls.start()
+                try:
+                    return await func(*call_args, **call_kwargs)
+                finally:
+                    calls.finish()
+            decorator(call)
+            return call
+        return track
+    
+    mcp.tool = tool
+
+
+class BearerTokenMiddleware:
+    """ASGI middleware refusing HTTP requests without `Authorization: Bearer <token>` (401)."""
+    
+    def __init__(self, app: Callable, token: str):
+        self.app = app
+        self.token = token.encode()
+    
+    def authorized(self, scope: Scope) -> bool:
+        for key, value in scope.get("headers") or []:
+            if key.lower() != b"authorization":
+                continue
+            scheme, _, credentials = value.decode("latin-1").partition(" ")
+            return scheme.lower() == "bearer" and hmac.compare_digest(credentials.strip().encode(), self.token)
+        return False
+    
+    async def __call__(self, scope: Scope, receive: Receive, send: Send) -> None:
+        if scope["type"] == "lifespan" or self.authorized(scope):
+            await self.app(scope, receive, send)
+            return
+        if scope["type"] == "websocket":
+            await send({"type": "websocket.close", "code": 1008})
+            return
+        body = json.dumps({"error": "Missing or invalid bearer token"}).encode()
+        await send({
+            "type": "http.response.start",
+            "status": 401,
+            "headers": [
+                (b"content-type", b"application/json"),
+                (b"content-length", str(len(body)).encode()),
+                (b"www-authenticate", b'Bearer realm="mcp"'),
+            ],
+        })
+        await send({"type": "http.response.body", "body": body})
+
+
+def serve_http(mcp: FastMCP, calls: InFlightCalls, transport: str, host: str, port: int,
+               auth_token: Optional[str] = None, shutdown_timeout: float = DEFAULT_SHUTDOWN_TIMEOUT) -> None:
+    """
+    Serve mcp over HTTP until SIGINT / SIGTERM, then shut down gracefully.
+    
+    Args:
+        calls: Tool calls of mcp, see track_tool_calls
+        transport: "http" (streamable HTTP at /mcp) or "sse" (/sse)
+        auth_token: Bearer token every request needs, None for no auth
+        shutdown_timeout: Seconds to wait for the tool calls in flight
+    """
+    # Imported lazily, stdio needs neither
+    import anyio
+    import uvicorn
+    
+    app = mcp.streamable_http_app() if transport == "http" else mcp.sse_app()
+    if auth_token:
+        app = BearerTokenMiddleware(app, auth_token)
+    elif host not in LOOPBACK_HOSTS:
+        logger.warning(f"Listening on {host} without MCP_AUTH_TOKEN, any client that can reach it can query the graph")
+    
+    class DrainingServer(uvicorn.Server):
+        async def shutdown(self, sockets=None):
+            # Stop accepting connections, then let the calls in flight finish before the streams are closed
+            for server in self.servers:
+                server.close()
+            for sock in sockets or []:
+                sock.close()
+            if calls.count:
+                logger.info(f"Waiting up to {shutdown_timeout:.0f}s for {calls.count} tool call(s) in flight")
+            if not await calls.drain(shutdown_timeout):
+                logger.warning(f"Shutting down with {calls.count} tool call(s) still running")
+            await super().shutdown(sockets)
+    
+    config = uvicorn.Config(
+        app,
+        host=host,
+        port=port,
+        log_level=mcp.settings.log_level.lower(),
+        timeout_graceful_shutdown=RESPONSE_GRACE_SECONDS,
+    )
+    anyio.run(DrainingServer(config).serve)
@@ -0,0 +1,141 @@
+"""
+Helpers for the get_type_members MCP tool.
+
+Go types compose through embedding rather than inheritance: the methods of
+an embedded struct or interface are promoted to the outer type. Promoted
+methods are not stored as nodes, they are collected here by following
+EMBEDS edges breadth-first from the outer type.
+
+Promotion follows the Go selector rules: a member declared at a shallower
+depth shadows deeper ones, and two members with the same name at the same
+depth are ambiguous and not promoted. Every embedded type is visited once,
+so embedding cycles terminate.
+"""
+
+from typing import Any, Dict, List, Optional
+
+from src.mcp.references import node_type_from_labels
+
+def declared_members(db, type_ids: List[str]) -> Dict[str, List[Dict[str, Any]]]:
+    """
+    Members each type DEFINES, ordered by location.
+    
+    Methods carry the receiver_kind of their METHOD_OF edge to the type.
+    """
+    rows = db.neighbors(type_ids, ["DEFINES"], direction="out")
+    receivers = {
+        (row["origin_id"], row["node"]["properties"]["id"]): row["relationship"]["properties"].get("receiver_kind")
+        for row in db.neighbors([row["node"]["properties"]["id"] for row in rows], ["METHOD_OF"], direction="out")
+    }
+    members: Dict[str, List[Dict[str, Any]]] = {type_id: [] for type_id in type_ids}
+    for row in rows:
+        member = row["node"]["properties"]
+        members[row["origin_id"]].append({
+            "id": member["id"],
+            "symbol_id": member.get("symbol_id"),
+            "name": member.get("name"),
+            "kind": node_type_from_labels(row["node"]["labels"]),
+            "file_path": member.get("file_path"),
+            "line_no": member.get("line_no"),
+            "receiver_kind": receivers.get((member["id"], row["origin_id"])),
+        })
+    return members
+
+
+def _embedded_types(db, type_ids: List[str]) -> List[Dict[str, Any]]:
+    """Types embedded by a set of types, with their declared members."""
+    rows = db.neighbors(type_ids, ["EMBEDS"], direction="out")
+    members = declared_members(db, list(dict.fromkeys(row["node"]["properties"]["id"] for row in rows)))
+    embedded = []
+    for row in rows:
+        inner = row["node"]["properties"]
+        embedded.append({
+            "outer_id": row["origin_id"],
+            "id": inner["id"],
+            "name": inner.get("name"),
+            "labels": row["node"]["labels"],
+            "file_path": inner.get("file_path"),
+            "line_no": inner.get("line_no"),
+            "methods": inner.get("methods"),
+            "embed_kind": row["relationship"]["properties"].get("embed_kind"),
+            "original_name": row["relationship"]["properties"].get("original_name"),
+            "members": members[inner["id"]],
+        })
+    return embedded
+
+
+def _interface_members(row: Dict[str, Any]) -> List[Dict[str, Any]]:
+    """Members of an embedded interface, taken from its method signatures."""
+    return [
+        {"name": signature.split("(", 1)[0], "kind": "Method", "signature": signature,
+         "file_path": row.get("file_path"), "line_no": row.get("line_no")}
+        for signature in row.get("methods") or []
+    ]
+
+
+def collect_promoted_members(db, type_id: str, declared: List[Dict[str, Any]],
+                             max_depth: Optional[int] = None) -> Dict[str, List[Dict[str, Any]]]:
+    """
+    Follow EMBEDS edges transitively from a type and collect promoted members.
+    
+    Args:
+        db: GraphStore backend
+        type_id: ID of the outer type
+        declared: Members declared on the outer type itself (they shadow promoted ones)
+        max_depth: Stop after this many embedding levels (default: no limit)
+    
+    Returns:
+        {"embeds": [...], "promoted": [...]}; each promoted member records the
+        embedded type it comes from ("promoted_from"), the chain of embedded
+        type names from the outer type ("via") and its depth.
+    """
+    visited = {type_id}
+    shadowed = {member["name"] for member in declared if member.get("name")}
+    paths: Dict[str, List[str]] = {type_id: []}
+    embeds: List[Dict[str, Any]] = []
+    promoted: List[Dict[str, Any]] = []
+    
+    frontier = [type_id]
+    depth = 0
+    while frontier and (max_depth is None or depth < max_depth):
+        depth += 1
+        level: Dict[str, List[Dict[str, Any]]] = {}
+        next_frontier = []
+        for row in _embedded_types(db, frontier):
+            inner_id = row["id"]
+            node_type = node_type_from_labels(row.get("labels"))
+            via = paths[row["outer_id"]] + [row["name"]]
+            if row["outer_id"] == type_id:
+                embeds.append({
+                    "id": inner_id,
+                    "name": row["name"],
+                    "kind": node_type,
+                    "embed_kind": row.get("embed_kind"),
+                    "original_name": row.get("original_name"),
+                })
+            # Reached twice (diamond or cycle): the first, shallowest path wins
+            if inner_id in visited:
+                continue
+            visited.add(inner_id)
+            paths[inner_id] = via
+            next_frontier.append(inner_id)
+            
+            members = row.get("members") or []
+            if node_type == "Interface":
+                members = members + _interface_members(row)
+            for member in members:
+                if not member or not member.get("name") or member["name"] in shadowed:
+                    continue
+                level.setdefault(member["name"], []).append(
+                    {**member, "promoted_from": row["name"], "via": via, "depth": depth}
+                )
+        
+        for name, candidates in level.items():
+            # The same name twice at one depth is an ambiguous selector in Go
+            if len({candidate["promoted_from"] for candidate in candidates}) == 1:
+                promoted.append(candidates[0])
+            shadowed.add(name)
+        frontier = next_frontier
+    
+    promoted.sort(key=lambda member: (member["depth"], member["name"]))
+    return {"embeds": embeds, "promoted": promoted}
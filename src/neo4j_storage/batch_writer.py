@@ -0,0 +1,143 @@
+"""
+Single background writer that batches graph inserts.
+
+Producers (embedding generation, the indexer) hand nodes and relationships
+to the writer as they become ready; a single thread accumulates them and
+flushes every `batch_size` items through the database's batch methods,
+one transaction per batch (graph_db writes a batch with one UNWIND
+statement per label set or relationship type, see write_queries). Nodes
+are always flushed before relationships queued after them, so a
+relationship batch never refers to nodes that are still buffered.
+"""
+
+import logging
+import os
+import queue
+import threading
+from typing import Any, Dict, List, Optional
+
+logger = logging.getLogger(__name__)
+
+DEFAULT_WRITE_BATCH_SIZE = 1000
+
+# Sentinel that tells the writer thread to flush and exit
+_CLOSE = object()
+
+
+def get_write_batch_size() -> int:
+    """Read INDEX_WRITE_BATCH_SIZE (default 1000)."""
+    value = os.getenv("INDEX_WRITE_BATCH_SIZE", "")
+    if value:
+        try:
+            return max(1, int(value))
+        except ValueError:
+            logger.warning(f"Invalid INDEX_WRITE_BATCH_SIZE value '{value}', using {DEFAULT_WRITE_BATCH_SIZE}")
+    return DEFAULT_WRITE_BATCH_SIZE
+
+
+class GraphBatchWriter:
+    """
+    Background writer for Neo4j-format nodes and relationships.
+    
+    Usage:
+        with GraphBatchWriter(db) as writer:
+            writer.add_nodes(nodes)
+            writer.add_relationships(relationships)
+    
+    Leaving the block flushes the remaining items and re-raises the first
+    write error, if any.
+    """
+    
+    def __init__(self, db, batch_size: Optional[int] = None, queue_size: int = 64):
+        """
+        Args:
+            db: Database exposing batch_create_nodes / batch_create_relationships
+            batch_size: Items per transaction, if None, get from INDEX_WRITE_BATCH_SIZE
+            queue_size: Maximum number of pending add_* calls before producers block
+        """
+        self.db = db
+        self.batch_size = batch_size or get_write_batch_size()
+        self.nodes_written = 0
+        self.relationships_written = 0
+        self._queue: "queue.Queue" = queue.Queue(maxsize=queue_size)
+        self._nodes: List[Dict[str, Any]] = []
+        self._relationships: List[Dict[str, Any]] = []
+        self._error: Optional[BaseException] = None
+        self._thread = threading.Thread(target=self._run, name="graph-writer", daemon=True)
+        self._thread.start()
+    
+    def __enter__(self):
+        return self
+    
+    def __exit__(self, exc_type, exc_val, exc_tb):
+        self.close()
+        return False
+    
+    def add_nodes(self, nodes: List[Dict[str, Any]]) -> None:
+        """Queue nodes in Neo4j format ({'labels': [...], 'properties': {...}})."""
+        self._put(("nodes", nodes))
+    
+    def add_relationships(self, relationships: List[Dict[str, Any]]) -> None:
+        """Queue relationships in Neo4j format ({'start_node_id', 'end_node_id', 'type', 'properties'})."""
+        self._put(("relationships", relationships))
+    
+    def close(self) -> None:
+        """Flush everything still buffered, stop the thread and re-raise a write error."""
+        if self._thread.is_alive():
+            self._queue.put(_CLOSE)
+            self._thread.join()
+        if self._error is not None:
+            raise self._error
+    
+    def _put(self, item) -> None:
+        if self._error is not None:
+            raise self._error
+        if item[1]:
+            self._queue.put(item)
+    
+    def _run(self) -> None:
+        while True:
+            item = self._queue.get()
+            if item is _CLOSE:
+                break
+            if self._error is not None:
+                # Drain the queue so producers never block on a failed writer
+                continue
+            kind, items = item
+            try:
+                if kind == "nodes":
+                    self._nodes.extend(items)
+                    while len(self._nodes) >= self.batch_size:
+                        self._flush_nodes(self.batch_size)
+                else:
+                    # Relationships may point at buffered nodes
+                    self._flush_nodes()
+                    self._relationships.extend(items)
+                    while len(self._relationships) >= self.batch_size:
+                        self._flush_relationships(self.batch_size)
+            except BaseException as e:
+                self._error = e
+        
+        if self._error is None:
+            try:
+                self._flush_nodes()
+                self._flush_relationships()
+            except BaseException as e:
+                self._error = e
+        logger.info(f"Graph writer done: {self.nodes_written} nodes, {self.relationships_written} relationships")
+    
+    def _flush_nodes(self, limit: Optional[int] = None) -> None:
+        if not self._nodes:
+            return
+        limit = limit or len(self._nodes)
+        batch, self._nodes = self._nodes[:limit], self._nodes[limit:]
+        self.db.batch_create_nodes(batch, batch_size=self.batch_size)
+        self.nodes_written += len(batch)
+    
+    def _flush_relationships(self, limit: Optional[int] = None) -> None:
+        if not self._relationships:
+            return
+        limit = limit or len(self._relationships)
+        batch, self._relationships = self._relationships[:limit], self._relationships[limit:]
+        self.db.batch_create_relationships(batch, batch_size=self.batch_size)
+        self.relationships_written += len(batch)
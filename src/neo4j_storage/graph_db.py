@@ -1,14 +1,63 @@
+import json
 import os
 from typing import Dict, List, Any, Optional, Tuple, Set
-from neo4j import GraphDatabase, Driver
+from neo4j import READ_ACCESS, GraphDatabase, Driver, Query
 import logging
 
+from src.graph_store.base import DEFAULT_REPO, EMBEDDING_PROPERTY, GraphStore, check_direction, node_sort_key
+from src.neo4j_storage.batch_writer import get_write_batch_size
+from src.neo4j_storage.write_queries import (
+    GraphWriteError,
+    check_identifier,
+    group_nodes,
+    group_relationships,
+    node_batch_query,
+    offending_nodes,
+    offending_relationships,
+    relationship_batch_query,
+)
+
 # 設定日誌
 logging.basicConfig(level=logging.INFO, format='%(asctime)s - %(name)s - %(levelname)s - %(message)s')
 logger = logging.getLogger(__name__)
 
 
-class Neo4jDatabase:
+# 鄰居查詢的關係模式 / Relationship pattern of a neighbors() direction
+NEIGHBOR_PATTERNS = {
+    "out": "(o:Base)-[r]->(m:Base{label})",
+    "in": "(o:Base)<-[r]-(m:Base{label})",
+    "both": "(o:Base)-[r]-(m:Base{label})",
+}
+
+# 最短路徑的關係模式 / Relationship pattern of a shortest_paths() direction
+PATH_PATTERNS = {
+    "out": "-[{rel}]->",
+    "in": "<-[{rel}]-",
+    "both": "-[{rel}]-",
+}
+
+# 不含向量的節點投影 / Node projection without the vector
+NODE_PROJECTION = "{{labels: labels({var}), properties: {var} {{.*, embedding: null}}}}"
+
+# 節點屬於 $repo 的條件，$repo 為 null 時不過濾 / Node belongs to $repo, every node when $repo is null
+REPO_CONDITION = f"($repo IS NULL OR coalesce({{var}}.repo, '{DEFAULT_REPO}') = $repo)"
+
+
+def _node_record(record: Dict[str, Any]) -> Dict[str, Any]:
+    properties = {key: value for key, value in (record["properties"] or {}).items() if key != EMBEDDING_PROPERTY}
+    return {"labels": list(record["labels"]), "properties": properties}
+
+
+def _relationship_record(record: Dict[str, Any]) -> Dict[str, Any]:
+    return {
+        "start_node_id": record["start_node_id"],
+        "end_node_id": record["end_node_id"],
+        "type": record["type"],
+        "properties": dict(record["properties"] or {}),
+    }
+
+
+class Neo4jDatabase(GraphStore):
     """Neo4j圖形資料庫操作類 / Neo4j graph database operations class"""
     
     def __init__(
@@ -147,25 +196,36 @@ class Neo4jDatabase:
                     "SHOW CONSTRAINTS"
                 ).data()
                 
-                # 檢查特定約束是否存在
-                constraint_exists = False
-                for constraint in existing_constraints:
-                    if 'name' in constraint and "file_path_constraint" in constraint['name']:
-                        constraint_exists = True
-                        break
+                constraint_names = [c.get('name', '') for c in existing_constraints if 'name' in c]
+                
+                # Base.id 是節點鍵（類型、路徑、名稱、行號），批量寫入以它合併節點與匹配關係端點
+                # Base.id is the node key (kind, path, name, line); batched writes MERGE nodes
+                # and match relationship endpoints on it. IDs outside the default repository
+                # carry its name, and a file path is unique within its repository.
+                # Base.symbol_id 是穩定的符號ID，MCP 工具以它查找節點
+                # Base.symbol_id is the stable symbol ID MCP tools look nodes up by (see src.indexing.symbol_ids)
+                constraint_configs = [
+                    {"name": "base_id_constraint", "label": "Base", "property": "id"},
+                    {"name": "file_repo_path_constraint", "label": "File", "properties": ["repo", "file_path"]},
+                    {"name": "base_symbol_id_constraint", "label": "Base", "property": "symbol_id"},
+                ]
                 
                 # 只有在約束不存在時才創建
-                if not constraint_exists:
-                    try:
-                        session.run(
-                            """
-                            CREATE CONSTRAINT file_path_constraint
-                            FOR (f:File) REQUIRE f.path IS UNIQUE
-                            """
-                        )
-                        logger.info("已創建 File.path 唯一性約束")
-                    except Exception as constraint_error:
-                        logger.warning(f"創建約束時出現警告: {constraint_error}")
+                for config in constraint_configs:
+                    if config["name"] not in constraint_names:
+                        properties = ", ".join(f"n.{prop}" for prop in config.get("properties", [config.get("property")]))
+                        if "properties" in config:
+                            properties = f"({properties})"
+                        try:
+                            session.run(
+                                f"CREATE CONSTRAINT {config['name']} "
+                                f"FOR (n:{config['label']}) REQUIRE {properties} IS UNIQUE"
+                            )
+                            logger.info(f"已創建 {config['label']} {properties} 唯一性約束")
+                        except Exception as constraint_error:
+                            # 既有圖中有重複ID時無法建立；使用 --clear 重新索引
+                            # Fails when the stored graph already has duplicate ids; re-index with --clear
+                            logger.warning(f"創建約束時出現警告 / Constraint warning: {constraint_error}")
                 
                 # 檢查索引是否已存在
                 existing_indexes = session.run("SHOW INDEXES").data()
@@ -178,14 +238,20 @@ class Neo4jDatabase:
                     {"name": "function_name_idx", "label": "Function", "property": "name"},
                     {"name": "method_name_idx", "label": "Method", "property": "name"},
                     {"name": "variable_name_idx", "label": "Variable", "property": "name"},
-                    {"name": "module_name_idx", "label": "Module", "property": "name"}
+                    {"name": "constant_name_idx", "label": "Constant", "property": "name"},
+                    {"name": "module_name_idx", "label": "Module", "property": "name"},
+                    # 依檔案與名稱查找符號 / Symbol lookups by file and name
+                    {"name": "base_symbol_idx", "label": "Base", "properties": ["file_path", "name"]},
+                    # 依儲存庫查找與刪除 / Repository filters and deletion
+                    {"name": "base_repo_idx", "label": "Base", "property": "repo"}
                 ]
                 
                 for config in index_configs:
                     if config["name"] not in index_names:
+                        properties = ", ".join(f"n.{prop}" for prop in config.get("properties", [config.get("property")]))
                         try:
                             session.run(
-                                f"CREATE INDEX {config['name']} FOR (n:{config['label']}) ON (n.{config['property']})"
+                                f"CREATE INDEX {config['name']} FOR (n:{config['label']}) ON ({properties})"
                             )
                             logger.info(f"已創建索引: {config['name']}")
                         except Exception as index_error:
@@ -245,99 +311,94 @@ class Neo4jDatabase:
             logger.error(f"創建向量索引時發生錯誤: {e}")
             raise
     
-    def batch_create_nodes(self, nodes: List[Dict[str, Any]]):
-        """批量創建節點
+    def batch_create_nodes(self, nodes: List[Dict[str, Any]], batch_size: Optional[int] = None):
+        """批量創建節點，每批次一個交易 / Create nodes in batches, one transaction per batch
+        
+        每個批次依標籤分組，每組以一個 UNWIND 語句寫入；節點以 Base.id 合併
+        / Each batch is grouped by label set and written with one UNWIND statement
+        per group; nodes are merged on Base.id
         
         Args:
             nodes: 節點列表，每個節點為一個字典，包含標籤和屬性
                   格式: [{'labels': ['Label1', 'Label2'], 'properties': {...}}]
+            batch_size: 每個交易的節點數量，若為None則從環境變數INDEX_WRITE_BATCH_SIZE取得
+                       / Nodes per transaction, if None get from INDEX_WRITE_BATCH_SIZE
+        
+        Raises:
+            GraphWriteError: 寫入失敗時，指出出錯的標籤與檔案/符號
+                            / When a write fails, naming the labels and the offending files/symbols
         """
         if not nodes:
             return
         
+        batch_size = batch_size or get_write_batch_size()
         try:
             with self.driver.session(database=self.database) as session:
-                batch_size = 1000  # 設定適當的批次大小
-                
                 for i in range(0, len(nodes), batch_size):
-                    batch = nodes[i:i+batch_size]
+                    groups = group_nodes(nodes[i:i+batch_size])
                     created = 0
                     
-                    # 對每個節點單獨處理
-                    for node in batch:
-                        labels = node['labels']
-                        properties = node['properties']
-                        
-                        # 構建標籤字串，例如 `:Label1:Label2`
-                        labels_str = ''.join([f":{label}" for label in labels])
-                        
-                        # 構建屬性字串，例如 `{id: 'test1', name: 'Test 1'}`
-                        props_str = "{"
-                        props_str += ", ".join([f"{k}: ${k}" for k in properties.keys()])
-                        props_str += "}"
-                        
-                        # 創建節點查詢
-                        query = f"""
-                        CREATE (n{labels_str} {props_str})
-                        RETURN n
-                        """
-                        
-                        # 執行查詢
-                        session.run(query, properties)
-                        created += 1
+                    # 整個批次在同一個交易中提交，每個標籤組一次往返
+                    # Commit the whole batch in one transaction, one round trip per label set
+                    with session.begin_transaction() as tx:
+                        for labels, rows in groups.items():
+                            try:
+                                tx.run(node_batch_query(labels), {"batch": rows})
+                            except Exception as e:
+                                raise GraphWriteError(
+                                    "nodes", ":".join(labels), len(rows), offending_nodes(rows, e), e
+                                ) from e
+                            created += len(rows)
+                        tx.commit()
                     
-                    logger.info(f"已創建 {created} 個節點")
+                    logger.info(f"已創建 {created} 個節點 / Created {created} nodes")
         except Exception as e:
-            logger.error(f"批量創建節點時發生錯誤: {e}")
+            logger.error(f"批量創建節點時發生錯誤 / Error creating nodes: {e}")
             raise
     
-    def batch_create_relationships(self, relationships: List[Dict[str, Any]]):
-        """批量創建關係
+    def batch_create_relationships(self, relationships: List[Dict[str, Any]], batch_size: Optional[int] = None):
+        """批量創建關係，每批次一個交易 / Create relationships in batches, one transaction per batch
+        
+        每個批次依關係類型分組，每組以一個 UNWIND 語句寫入
+        / Each batch is grouped by relationship type and written with one UNWIND statement per type
         
         Args:
             relationships: 關係列表，每個關係為一個字典
                           格式: [{'start_node_id': '...', 'end_node_id': '...', 
                                 'type': '...', 'properties': {...}}]
+            batch_size: 每個交易的關係數量，若為None則從環境變數INDEX_WRITE_BATCH_SIZE取得
+                       / Relationships per transaction, if None get from INDEX_WRITE_BATCH_SIZE
+        
+        Raises:
+            GraphWriteError: 寫入失敗時，指出出錯的關係類型與端點
+                            / When a write fails, naming the relationship type and the offending endpoints
         """
         if not relationships:
             return
         
+        batch_size = batch_size or get_write_batch_size()
         try:
             with self.driver.session(database=self.database) as session:
-                batch_size = 1000  # 設定適當的批次大小
-                
                 for i in range(0, len(relationships), batch_size):
-                    batch = relationships[i:i+batch_size]
+                    groups = group_relationships(relationships[i:i+batch_size])
                     processed = 0
                     
-                    # 對每個關係單獨處理，避免生成動態Cypher查詢
-                    for rel in batch:
-                        start_id = rel['start_node_id']
-                        end_id = rel['end_node_id']
-                        rel_type = rel['type']
-                        properties = rel['properties'] or {}
-                        
-                        # 使用參數化查詢
-                        query = f"""
-                        MATCH (start:Base {{id: $start_id}})
-                        MATCH (end:Base {{id: $end_id}})
-                        CREATE (start)-[r:{rel_type}]->(end)
-                        SET r = $props
-                        RETURN r
-                        """
-                        
-                        params = {
-                            "start_id": start_id,
-                            "end_id": end_id,
-                            "props": properties
-                        }
-                        
-                        session.run(query, params)
-                        processed += 1
+                    # 整個批次在同一個交易中提交，每個關係類型一次往返
+                    # Commit the whole batch in one transaction, one round trip per relationship type
+                    with session.begin_transaction() as tx:
+                        for rel_type, rows in groups.items():
+                            try:
+                                tx.run(relationship_batch_query(rel_type), {"batch": rows})
+                            except Exception as e:
+                                raise GraphWriteError(
+                                    "relationships", rel_type, len(rows), offending_relationships(rows, e), e
+                                ) from e
+                            processed += len(rows)
+                        tx.commit()
                     
-                    logger.info(f"已處理 {processed} 個關係")
+                    logger.info(f"已處理 {processed} 個關係 / Processed {processed} relationships")
         except Exception as e:
-            logger.error(f"批量創建關係時發生錯誤: {e}")
+            logger.error(f"批量創建關係時發生錯誤 / Error creating relationships: {e}")
             raise
     
     def create_full_text_index(self, index_name: str, node_labels: List[str], properties: List[str]):
@@ -457,6 +518,635 @@ class Neo4jDatabase:
             logger.error(f"向量相似度搜索時發生錯誤: {e}")
             raise
     
+    def get_file_states(self, repo: Optional[str] = None) -> Dict[str, Dict[str, Any]]:
+        """獲取所有檔案節點的增量索引狀態 / Get incremental index state of all File nodes
+        
+        Args:
+            repo: 只取此儲存庫的檔案 / Only the files of this repository
+        
+        Returns:
+            file_path -> {content_hash, mtime, size, index_state, parse_error}
+        """
+        try:
+            with self.driver.session(database=self.database) as session:
+                result = session.run(
+                    f"""
+                    MATCH (f:File)
+                    WHERE f.file_path IS NOT NULL AND {REPO_CONDITION.format(var='f')}
+                    RETURN f.file_path AS file_path, f.content_hash AS content_hash,
+                           f.mtime AS mtime, f.size AS size, f.index_state AS index_state,
+                           f.parse_error AS parse_error
+                    """,
+                    {"repo": repo}
+                )
+                return {
+                    record["file_path"]: {
+                        "content_hash": record["content_hash"],
+                        "mtime": record["mtime"],
+                        "size": record["size"],
+                        "index_state": record["index_state"],
+                        "parse_error": record["parse_error"],
+                    }
+                    for record in result
+                }
+        except Exception as e:
+            logger.error(f"獲取檔案狀態時發生錯誤 / Error getting file states: {e}")
+            raise
+    
+    def get_dependent_files(self, file_paths: List[str], repo: Optional[str] = None) -> List[str]:
+        """查找依賴指定檔案的檔案（反向依賴） / Find files that depend on the given files
+        
+        Args:
+            file_paths: 被依賴的檔案路徑 / Paths of the depended-on files
+            repo: 被依賴檔案所在的儲存庫 / Repository of the depended-on files
+        
+        Returns:
+            依賴這些檔案的檔案路徑 / Paths of files with a DEPENDS_ON_FILE edge into them
+        """
+        if not file_paths:
+            return []
+        
+        try:
+            with self.driver.session(database=self.database) as session:
+                result = session.run(
+                    f"""
+                    MATCH (d:File)-[:DEPENDS_ON_FILE]->(f:File)
+                    WHERE f.file_path IN $file_paths AND {REPO_CONDITION.format(var='f')}
+                    RETURN DISTINCT d.file_path AS file_path
+                    """,
+                    {"file_paths": file_paths, "repo": repo}
+                )
+                return [record["file_path"] for record in result]
+        except Exception as e:
+            logger.error(f"查找反向依賴時發生錯誤 / Error finding dependent files: {e}")
+            raise
+    
+    def delete_file_scope(self, file_paths: List[str], repo: Optional[str] = None) -> int:
+        """刪除屬於指定檔案的所有節點及其關係 / Delete all nodes (and their edges) scoped to the given files
+        
+        Args:
+            file_paths: 檔案路徑列表 / File paths
+            repo: 檔案所在的儲存庫 / Repository of the files
+        
+        Returns:
+            刪除的節點數量 / Number of deleted nodes
+        """
+        if not file_paths:
+            return 0
+        
+        try:
+            with self.driver.session(database=self.database) as session:
+                record = session.run(
+                    f"""
+                    MATCH (n:Base)
+                    WHERE n.file_path IN $file_paths AND {REPO_CONDITION.format(var='n')}
+                    DETACH DELETE n
+                    RETURN count(n) AS deleted
+                    """,
+                    {"file_paths": file_paths, "repo": repo}
+                ).single()
+                deleted = record["deleted"] if record else 0
+                logger.info(f"已刪除 {deleted} 個節點 / Deleted {deleted} nodes for {len(file_paths)} files")
+                return deleted
+        except Exception as e:
+            logger.error(f"刪除檔案範圍時發生錯誤 / Error deleting file scope: {e}")
+            raise
+    
+    def get_existing_node_ids(self, node_ids: List[str]) -> Set[str]:
+        """返回已存在於資料庫中的節點ID / Return the subset of node IDs already in the database"""
+        if not node_ids:
+            return set()
+        
+        try:
+            with self.driver.session(database=self.database) as session:
+                result = session.run(
+                    "MATCH (n:Base) WHERE n.id IN $ids RETURN n.id AS id",
+                    {"ids": node_ids}
+                )
+                return {record["id"] for record in result}
+        except Exception as e:
+            logger.error(f"查詢節點ID時發生錯誤 / Error checking node IDs: {e}")
+            raise
+    
+    def get_node_embeddings(self, file_paths: List[str], repo: Optional[str] = None) -> Dict[str, List[float]]:
+        """返回檔案中已嵌入節點的向量，以 embedding_key 為鍵
+        / Return the stored vectors of embedded nodes in the given files, keyed by embedding_key
+        
+        Args:
+            file_paths: 檔案路徑列表 / File paths
+            repo: 檔案所在的儲存庫 / Repository of the files
+        """
+        if not file_paths:
+            return {}
+        
+        try:
+            with self.driver.session(database=self.database) as session:
+                result = session.run(
+                    f"""
+                    MATCH (n:Base)
+                    WHERE n.file_path IN $paths AND n.embedding_key IS NOT NULL AND n.embedding IS NOT NULL
+                      AND {REPO_CONDITION.format(var='n')}
+                    RETURN n.embedding_key AS key, n.embedding AS embedding
+                    """,
+                    {"paths": file_paths, "repo": repo}
+                )
+                return {record["key"]: list(record["embedding"]) for record in result}
+        except Exception as e:
+            logger.error(f"查詢節點向量時發生錯誤 / Error loading node embeddings: {e}")
+            raise
+    
+    def get_nodes(self, node_ids: List[str]) -> List[Dict[str, Any]]:
+        """依ID返回節點記錄 / Return the node records of the given IDs, in their order"""
+        if not node_ids:
+            return []
+        
+        try:
+            rows = self.execute_cypher(
+                f"MATCH (n:Base) WHERE n.id IN $ids RETURN {NODE_PROJECTION.format(var='n')} AS node",
+                {"ids": list(node_ids)}
+            )
+        except Exception as e:
+            logger.error(f"查詢節點時發生錯誤 / Error getting nodes: {e}")
+            raise
+        by_id = {row["node"]["properties"]["id"]: _node_record(row["node"]) for row in rows}
+        return [by_id[node_id] for node_id in dict.fromkeys(node_ids) if node_id in by_id]
+    
+    def find_nodes(self, name: Optional[str] = None, label: Optional[str] = None,
+                   properties: Optional[Dict[str, Any]] = None, path_prefixes: Optional[List[str]] = None,
+                   limit: Optional[int] = None, repo: Optional[str] = None) -> List[Dict[str, Any]]:
+        """依名稱、標籤、屬性或路徑前綴查找節點 / Find nodes by name, label, property values or path prefix
+        
+        Args:
+            name: 名稱 / Exact name
+            label: 節點標籤 / Label the node must carry
+            properties: 屬性值 / Exact property values
+            path_prefixes: file_path 等於或位於其下 / file_path equal to or under one of these
+            limit: 返回結果的最大數量 / Maximum number of records
+            repo: 節點所屬的儲存庫 / Repository the node belongs to
+        """
+        label_clause = f":`{check_identifier(label, 'label')}`" if label else ""
+        conditions = []
+        params: Dict[str, Any] = {}
+        if name is not None:
+            conditions.append("n.name = $name")
+            params["name"] = name
+        for index, (key, value) in enumerate((properties or {}).items()):
+            conditions.append(f"n.`{check_identifier(key, 'property')}` = $value_{index}")
+            params[f"value_{index}"] = value
+        if path_prefixes:
+            conditions.append("any(prefix IN $prefixes WHERE n.file_path = prefix OR n.file_path STARTS WITH prefix + '/')")
+            params["prefixes"] = [prefix.replace("\\", "/").rstrip("/") for prefix in path_prefixes]
+        if repo is not None:
+            conditions.append(REPO_CONDITION.format(var="n"))
+            params["repo"] = repo
+        
+        query = f"MATCH (n:Base{label_clause})\n"
+        if conditions:
+            query += "WHERE " + " AND ".join(conditions) + "\n"
+        query += f"RETURN {NODE_PROJECTION.format(var='n')} AS node\n"
+        query += "ORDER BY coalesce(n.file_path, ''), coalesce(n.line_no, 0), n.id"
+        if limit is not None:
+            query += "\nLIMIT $limit"
+            params["limit"] = int(limit)
+        
+        try:
+            rows = self.execute_cypher(query, params)
+        except Exception as e:
+            logger.error(f"查找節點時發生錯誤 / Error finding nodes: {e}")
+            raise
+        return sorted((_node_record(row["node"]) for row in rows), key=node_sort_key)
+    
+    def node_names(self, labels: List[str], limit: Optional[int] = None, repo: Optional[str] = None) -> List[str]:
+        """帶有其中一個標籤的節點的不重複名稱 / Distinct names of the nodes carrying one of labels, sorted
+        
+        Args:
+            labels: 節點可帶的標籤 / Labels a node may carry
+            limit: 返回名稱的最大數量 / Maximum number of names
+            repo: 節點所屬的儲存庫 / Repository the nodes belong to
+        """
+        query = f"""
+        MATCH (n:Base)
+        WHERE any(label IN labels(n) WHERE label IN $labels) AND n.name IS NOT NULL AND n.name <> ''
+          AND {REPO_CONDITION.format(var='n')}
+        RETURN DISTINCT n.name AS name
+        ORDER BY name
+        """
+        params: Dict[str, Any] = {"labels": list(labels), "repo": repo}
+        if limit is not None:
+            query += "LIMIT $limit"
+            params["limit"] = int(limit)
+        try:
+            rows = self.execute_cypher(query, params)
+        except Exception as e:
+            logger.error(f"列出節點名稱時發生錯誤 / Error listing node names: {e}")
+            raise
+        return [row["name"] for row in rows]
+    
+    def neighbors(self, node_ids: List[str], relation_types: Optional[List[str]] = None, direction: str = "out",
+                  label: Optional[str] = None) -> List[Dict[str, Any]]:
+        """返回節點的關係及另一端的節點 / Return the relationships of nodes and the nodes at their other end
+        
+        Args:
+            node_ids: 起點節點ID / Nodes to start from
+            relation_types: 關係類型，None 表示全部 / Relationship types, None for all
+            direction: "out", "in" 或 "both" / "out", "in" or "both"
+            label: 鄰居節點必須具有的標籤 / Label the neighbour must carry
+        """
+        if not node_ids:
+            return []
+        
+        label_clause = f":`{check_identifier(label, 'label')}`" if label else ""
+        pattern = NEIGHBOR_PATTERNS[check_direction(direction)].format(label=label_clause)
+        query = f"""
+        MATCH {pattern}
+        WHERE o.id IN $ids AND ($types IS NULL OR type(r) IN $types)
+        RETURN o.id AS origin_id,
+               {{type: type(r), start_node_id: startNode(r).id, end_node_id: endNode(r).id,
+                properties: properties(r)}} AS relationship,
+               {NODE_PROJECTION.format(var='m')} AS node
+        """
+        try:
+            rows = self.execute_cypher(query, {"ids": list(node_ids), "types": relation_types})
+        except Exception as e:
+            logger.error(f"查詢鄰居節點時發生錯誤 / Error getting neighbors: {e}")
+            raise
+        
+        order = {node_id: index for index, node_id in enumerate(dict.fromkeys(node_ids))}
+        results = [
+            {"origin_id": row["origin_id"], "relationship": _relationship_record(row["relationship"]),
+             "node": _node_record(row["node"])}
+            for row in rows
+        ]
+        results.sort(key=lambda item: (order[item["origin_id"]], node_sort_key(item["node"]), item["relationship"]["type"]))
+        return results
+    
+    def shortest_paths(self, source_id: str, target_id: str, relation_types: List[str], direction: str = "out",
+                       max_depth: int = 10, limit: int = 1, min_confidence: float = 0.0) -> List[Dict[str, Any]]:
+        """以 shortestPath / allShortestPaths 查找最短路徑 / Shortest paths via shortestPath / allShortestPaths
+        
+        Args:
+            source_id: 起點節點ID / Start node ID
+            target_id: 終點節點ID / End node ID
+            relation_types: 可經過的關係類型 / Relationship types a path may use
+            direction: "out", "in" 或 "both" / "out", "in" or "both"
+            max_depth: 最大跳數 / Maximum number of hops
+            limit: 路徑數量，大於1時使用 allShortestPaths / Number of paths, above 1 uses allShortestPaths
+            min_confidence: 大於0時只經過 confidence 不低於此值的關係 / Above 0, only relationships whose confidence is at least this
+        """
+        # 變長上限不能作為參數 / Variable-length bounds cannot be parameters
+        rel = f":{'|'.join(check_identifier(t, 'relationship type') for t in relation_types)}*..{int(max_depth)}"
+        pattern = PATH_PATTERNS[check_direction(direction)].format(rel=rel)
+        function = "allShortestPaths" if limit > 1 else "shortestPath"
+        # 缺少 confidence 的關係視為 1.0 / Relationships without a confidence count as 1.0
+        confidence_filter = (
+            "WHERE all(r IN relationships(p) WHERE coalesce(r.confidence, 1.0) >= $min_confidence)"
+            if min_confidence > 0 else ""
+        )
+        query = f"""
+        MATCH (a:Base {{id: $source_id}}), (b:Base {{id: $target_id}})
+        MATCH p = {function}((a){pattern}(b))
+        {confidence_filter}
+        RETURN [n IN nodes(p) | {NODE_PROJECTION.format(var='n')}] AS nodes,
+               [r IN relationships(p) | {{type: type(r), start_node_id: startNode(r).id, end_node_id: endNode(r).id,
+                                          properties: properties(r)}}] AS relationships
+        LIMIT $limit
+        """
+        try:
+            rows = self.execute_cypher(query, {"source_id": source_id, "target_id": target_id, "limit": int(limit),
+                                               "min_confidence": float(min_confidence)})
+        except Exception as e:
+            logger.error(f"查找最短路徑時發生錯誤 / Error finding shortest paths: {e}")
+            raise
+        
+        paths = [
+            {"nodes": [_node_record(node) for node in row["nodes"]],
+             "relationships": [_relationship_record(rel) for rel in row["relationships"]]}
+            for row in rows
+        ]
+        paths.sort(key=lambda path: [node["properties"]["id"] for node in path["nodes"]])
+        return paths
+    
+    def edges_between(self, node_ids: List[str]) -> List[Dict[str, Any]]:
+        """返回兩端都在節點集合內的關係 / Return the relationships whose endpoints are both in node_ids"""
+        if not node_ids:
+            return []
+        
+        try:
+            rows = self.execute_cypher(
+                """
+                MATCH (a:Base)-[r]->(b:Base)
+                WHERE a.id IN $ids AND b.id IN $ids
+                RETURN a.id AS start_node_id, b.id AS end_node_id, type(r) AS type, properties(r) AS properties
+                """,
+                {"ids": list(node_ids)}
+            )
+        except Exception as e:
+            logger.error(f"查詢子圖關係時發生錯誤 / Error getting edges between nodes: {e}")
+            raise
+        return [_relationship_record(row) for row in rows]
+    
+    def count_by_type(self, repo: Optional[str] = None) -> Dict[str, Dict[str, int]]:
+        """依標籤與關係類型計數 / Node counts by label and relationship counts by type
+        
+        Args:
+            repo: 只計算此儲存庫的節點（關係依起點）/ Only nodes of this repository (relationships by their start node)
+        """
+        try:
+            label_rows = self.execute_cypher(
+                f"""
+                MATCH (n:Base)
+                WHERE {REPO_CONDITION.format(var='n')}
+                UNWIND labels(n) AS label
+                RETURN label, count(*) AS count
+                """,
+                {"repo": repo}
+            )
+            type_rows = self.execute_cypher(
+                f"""
+                MATCH (n:Base)-[r]->()
+                WHERE {REPO_CONDITION.format(var='n')}
+                RETURN type(r) AS type, count(r) AS count
+                """,
+                {"repo": repo}
+            )
+        except Exception as e:
+            logger.error(f"計數節點與關係時發生錯誤 / Error counting nodes and relationships: {e}")
+            raise
+        return {
+            "nodes": {row["label"]: row["count"] for row in sorted(label_rows, key=lambda row: row["label"])},
+            "relationships": {row["type"]: row["count"] for row in sorted(type_rows, key=lambda row: row["type"])},
+        }
+    
+    def search_similar_nodes(self, vector: List[float], node_labels: List[str], limit: int = 10) -> List[Dict[str, Any]]:
+        """以向量索引查詢最相似的節點 / Query the vector indexes for the most similar nodes
+        
+        Args:
+            vector: 查詢向量 / Query vector
+            node_labels: 要搜索的節點標籤，每個標籤使用 <label>_vector_index / Labels to search, each via <label>_vector_index
+            limit: 返回結果的最大數量 / Maximum number of results
+        
+        Returns:
+            依分數排序的節點屬性（不含向量）與 score / Node properties (without the vector) and score, best first
+        """
+        results = []
+        try:
+            with self.driver.session(database=self.database) as session:
+                for label in node_labels:
+                    records = session.run(
+                        """
+                        CALL db.index.vector.queryNodes($index_name, $limit, $vector)
+                        YIELD node, score
+                        RETURN node {.*, embedding: null} AS node, labels(node) AS labels, score
+                        """,
+                        {"index_name": f"{label.lower()}_vector_index", "limit": limit, "vector": vector}
+                    )
+                    results.extend(
+                        {"node": dict(record["node"]), "labels": record["labels"], "score": record["score"]}
+                        for record in records
+                    )
+        except Exception as e:
+            logger.error(f"向量索引搜索時發生錯誤 / Error querying vector indexes: {e}")
+            raise
+        results.sort(key=lambda item: item["score"], reverse=True)
+        return results[:limit]
+    
+    def update_file_mtimes(self, updates: List[Tuple[str, float]], repo: Optional[str] = None) -> None:
+        """更新內容未變檔案的修改時間 / Update mtime of files whose content did not change
+        
+        Args:
+            updates: (file_path, mtime) 列表 / List of (file_path, mtime)
+            repo: 檔案所在的儲存庫 / Repository of the files
+        """
+        if not updates:
+            return
+        
+        try:
+            with self.driver.session(database=self.database) as session:
+                session.run(
+                    f"""
+                    UNWIND $updates AS u
+                    MATCH (f:File {{file_path: u.file_path}})
+                    WHERE {REPO_CONDITION.format(var='f')}
+                    SET f.mtime = u.mtime
+                    """,
+                    {"updates": [{"file_path": path, "mtime": mtime} for path, mtime in updates], "repo": repo}
+                )
+        except Exception as e:
+            logger.error(f"更新檔案修改時間時發生錯誤 / Error updating file mtimes: {e}")
+            raise
+    
+    def update_file_index_states(self, updates: List[Tuple[str, str]], repo: Optional[str] = None) -> None:
+        """更新重新解析但未變更檔案的解析索引 / Update index_state of files re-resolved without changes
+        
+        Args:
+            updates: (file_path, index_state) 列表 / List of (file_path, index_state)
+            repo: 檔案所在的儲存庫 / Repository of the files
+        """
+        if not updates:
+            return
+        
+        try:
+            with self.driver.session(database=self.database) as session:
+                session.run(
+                    f"""
+                    UNWIND $updates AS u
+                    MATCH (f:File {{file_path: u.file_path}})
+                    WHERE {REPO_CONDITION.format(var='f')}
+                    SET f.index_state = u.index_state
+                    """,
+                    {"updates": [{"file_path": path, "index_state": state} for path, state in updates], "repo": repo}
+                )
+        except Exception as e:
+            logger.error(f"更新檔案解析索引時發生錯誤 / Error updating file index states: {e}")
+            raise
+    
+    def update_node_properties(self, updates: List[Tuple[str, Dict[str, Any]]]) -> int:
+        """設定節點屬性，其他屬性保留 / Set properties of existing nodes, other properties are kept
+        
+        Args:
+            updates: (node_id, properties) 列表，值為 None 時刪除屬性 / List of (node_id, properties), None removes one
+        
+        Returns:
+            更新的節點數量 / Number of nodes updated
+        """
+        rows = [
+            {"id": node_id, "props": {key: value for key, value in properties.items() if key != "id"}}
+            for node_id, properties in updates
+        ]
+        if not rows:
+            return 0
+        
+        try:
+            with self.driver.session(database=self.database) as session:
+                # SET += 時 null 值會刪除屬性 / null values remove the property with SET +=
+                record = session.run(
+                    """
+                    UNWIND $rows AS row
+                    MATCH (n:Base {id: row.id})
+                    SET n += row.props
+                    RETURN count(n) AS updated
+                    """,
+                    {"rows": rows}
+                ).single()
+                return record["updated"] if record else 0
+        except Exception as e:
+            logger.error(f"更新節點屬性時發生錯誤 / Error updating node properties: {e}")
+            raise
+    
+    def delete_orphan_placeholders(self, repo: Optional[str] = None) -> int:
+        """刪除沒有任何關係的外部佔位節點與套件節點 / Delete external placeholder and Package nodes left without edges
+        
+        Args:
+            repo: 只刪除此儲存庫的節點 / Only delete the nodes of this repository
+        
+        Returns:
+            刪除的節點數量 / Number of deleted nodes
+        """
+        try:
+            with self.driver.session(database=self.database) as session:
+                record = session.run(
+                    f"""
+                    MATCH (n:Base)
+                    WHERE (n.placeholder = true OR n:Package) AND NOT (n)--() AND {REPO_CONDITION.format(var='n')}
+                    DELETE n
+                    RETURN count(n) AS deleted
+                    """,
+                    {"repo": repo}
+                ).single()
+                return record["deleted"] if record else 0
+        except Exception as e:
+            logger.error(f"刪除佔位節點時發生錯誤 / Error deleting placeholder nodes: {e}")
+            raise
+    
+    def delete_structural_relationships(self, repo: Optional[str] = None) -> int:
+        """刪除由整個索引推導出的關係（如 Go IMPLEMENTS）/ Delete relationships derived from the whole index (e.g. Go IMPLEMENTS)
+        
+        Args:
+            repo: 只刪除起點在此儲存庫的關係 / Only delete the relationships starting in this repository
+        
+        Returns:
+            刪除的關係數量 / Number of deleted relationships
+        """
+        try:
+            with self.driver.session(database=self.database) as session:
+                record = session.run(
+                    f"""
+                    MATCH (a)-[r]->()
+                    WHERE r.structural = true AND {REPO_CONDITION.format(var='a')}
+                    DELETE r
+                    RETURN count(r) AS deleted
+                    """,
+                    {"repo": repo}
+                ).single()
+                return record["deleted"] if record else 0
+        except Exception as e:
+            logger.error(f"刪除結構關係時發生錯誤 / Error deleting structural relationships: {e}")
+            raise
+    
+    def delete_stale_structural_relationships(self, premise_files: List[str], keys: List[Tuple[str, str, str]],
+                                              repo: Optional[str] = None) -> List[Tuple[str, str, str]]:
+        """刪除前提檔案已變更的結構關係 / Delete the structural relationships whose premises changed
+        
+        Args:
+            premise_files: 已變更的檔案；premises 含其中之一的關係過時 / Changed files; relationships whose premises include one are stale
+            keys: 重新計算的關係 (起點ID, 類型, 終點ID) / (start id, type, end id) of recomputed relationships
+            repo: 只刪除起點在此儲存庫的關係 / Only delete the relationships starting in this repository
+        
+        Returns:
+            刪除的關係 (起點ID, 類型, 終點ID) / (start id, type, end id) of the deleted relationships
+        """
+        # premises 以 JSON 字串儲存，比對 JSON 編碼的路徑
+        # premises are stored as a JSON string, matched against the JSON-encoded paths
+        encoded = [json.dumps(file_path) for file_path in premise_files]
+        key_strings = ["|".join(key) for key in keys]
+        try:
+            with self.driver.session(database=self.database) as session:
+                stale = session.run(
+                    f"""
+                    MATCH (a)-[r]->(b)
+                    WHERE r.structural = true AND {REPO_CONDITION.format(var='a')}
+                      AND (r.premises IS NULL OR r.premises = '[]'
+                           OR any(f IN $files WHERE r.premises CONTAINS f)
+                           OR a.id + '|' + type(r) + '|' + b.id IN $keys)
+                    RETURN DISTINCT a.id AS start_id, type(r) AS type, b.id AS end_id
+                    """,
+                    {"repo": repo, "files": encoded, "keys": key_strings}
+                ).data()
+                deleted = sorted({(row["start_id"], row["type"], row["end_id"]) for row in stale})
+                if deleted:
+                    session.run(
+                        f"""
+                        MATCH (a)-[r]->(b)
+                        WHERE r.structural = true AND {REPO_CONDITION.format(var='a')}
+                          AND a.id + '|' + type(r) + '|' + b.id IN $keys
+                        DELETE r
+                        """,
+                        {"repo": repo, "keys": ["|".join(key) for key in deleted]}
+                    ).consume()
+                return deleted
+        except Exception as e:
+            logger.error(f"刪除過時結構關係時發生錯誤 / Error deleting stale structural relationships: {e}")
+            raise
+    
+    def delete_cross_repo_relationships(self) -> int:
+        """刪除儲存庫之間的關係 / Delete the relationships between repositories
+        
+        Returns:
+            刪除的關係數量 / Number of deleted relationships
+        """
+        try:
+            with self.driver.session(database=self.database) as session:
+                record = session.run(
+                    """
+                    MATCH ()-[r]->()
+                    WHERE r.cross_repo = true
+                    DELETE r
+                    RETURN count(r) AS deleted
+                    """
+                ).single()
+                return record["deleted"] if record else 0
+        except Exception as e:
+            logger.error(f"刪除跨儲存庫關係時發生錯誤 / Error deleting cross-repository relationships: {e}")
+            raise
+    
+    def delete_repository(self, repo: str) -> int:
+        """刪除一個儲存庫的所有節點及其關係 / Delete every node of a repository, with its relationships
+        
+        分批刪除（每個交易 INDEX_WRITE_BATCH_SIZE 個節點），刪除大型儲存庫或快照不會形成單一巨大交易
+        Deletes in batches of INDEX_WRITE_BATCH_SIZE nodes per transaction, so dropping a large
+        repository or snapshot does not build one huge transaction
+        
+        Args:
+            repo: 儲存庫名稱 / Repository name
+        
+        Returns:
+            刪除的節點數量 / Number of deleted nodes
+        """
+        batch_size = get_write_batch_size()
+        try:
+            with self.driver.session(database=self.database) as session:
+                deleted = 0
+                while True:
+                    record = session.run(
+                        f"""
+                        MATCH (n:Base)
+                        WHERE {REPO_CONDITION.format(var='n')}
+                        WITH n LIMIT $batch_size
+                        DETACH DELETE n
+                        RETURN count(n) AS deleted
+                        """,
+                        {"repo": repo, "batch_size": batch_size}
+                    ).single()
+                    batch = record["deleted"] if record else 0
+                    deleted += batch
+                    if batch < batch_size:
+                        break
+                logger.info(f"已刪除儲存庫 {repo} 的 {deleted} 個節點 / Deleted {deleted} nodes of repository {repo}")
+                return deleted
+        except Exception as e:
+            logger.error(f"刪除儲存庫時發生錯誤 / Error deleting repository: {e}")
+            raise
+    
     def execute_cypher(self, query: str, parameters: Dict = None):
         """執行Cypher查詢
         
@@ -474,6 +1164,35 @@ class Neo4jDatabase:
         except Exception as e:
             logger.error(f"執行Cypher查詢時發生錯誤: {e}")
             raise
+    
+    def execute_read_query(self, query: str, parameters: Dict = None, max_rows: Optional[int] = None,
+                           timeout: Optional[float] = None) -> List[Dict[str, Any]]:
+        """在讀取交易中執行Cypher查詢
+        Run a Cypher query in a read transaction: the database rejects any write it attempts
+        
+        Args:
+            query: Cypher查詢語句 / Cypher query
+            parameters: 查詢參數 / Query parameters
+            max_rows: 最多讀取的列數 / Rows fetched at most, the rest of the result is discarded
+            timeout: 查詢逾時秒數 / Seconds after which the database cancels the query
+        
+        Returns:
+            查詢結果 / Result rows
+        """
+        def read(tx):
+            rows = []
+            for record in tx.run(Query(query, timeout=timeout), parameters or {}):
+                if max_rows is not None and len(rows) >= max_rows:
+                    break
+                rows.append(record.data())
+            return rows
+        
+        try:
+            with self.driver.session(database=self.database, default_access_mode=READ_ACCESS) as session:
+                return session.execute_read(read)
+        except Exception as e:
+            logger.error(f"執行唯讀查詢時發生錯誤 / Error running read-only query: {e}")
+            raise
 
 
 # 使用範例
@@ -0,0 +1,211 @@
+"""
+Batched Cypher writes.
+
+批量 Cypher 寫入
+Each flushed slice of nodes or relationships is grouped by label set or
+relationship type and written with one parameterized `UNWIND $batch`
+statement per group, so a batch of 1000 items costs a handful of round
+trips instead of 1000. Nodes are MERGEd on `Base.id` (backed by the
+base_id_constraint uniqueness constraint), relationship endpoints are
+matched through the same index.
+
+Labels and relationship types cannot be parameters in Cypher, so they are
+checked against IDENTIFIER before being placed in the query text.
+
+When a statement fails, GraphWriteError names the label or type and the
+files and symbols of the rows that most likely caused it.
+"""
+
+import re
+from typing import Any, Dict, Iterable, List, Optional, Tuple
+
+# Labels and relationship types that may be placed into query text
+IDENTIFIER = re.compile(r"^[A-Za-z_][A-Za-z0-9_]*$")
+
+# Label every node carries; its id is the node key
+BASE_LABEL = "Base"
+
+# Rows named in a GraphWriteError message
+MAX_REPORTED_ITEMS = 5
+
+# Values Neo4j accepts as properties (and homogeneous lists of them)
+_PRIMITIVES = (str, int, float, bool)
+
+# Quoted value in a Neo4j constraint violation, e.g. "property `id` = 'function:a:f:3'"
+_VIOLATION_VALUE = re.compile(r"property `?(\w+)`? = '((?:[^'\\]|\\.)*)'")
+
+
+class GraphWriteError(Exception):
+    """
+    A batched node or relationship write failed.
+    
+    Attributes:
+        kind: "nodes" or "relationships"
+        group: The label set (e.g. "Base:Function") or relationship type of the failed statement
+        items: Descriptions of the rows that caused (or, when no row stands out, started) the batch
+        cause: The original database error
+    """
+    
+    def __init__(self, kind: str, group: str, batch_size: int, items: List[str], cause: Exception):
+        self.kind = kind
+        self.group = group
+        self.items = items
+        self.cause = cause
+        message = f"Failed to write {batch_size} {kind} ({group}): {cause}"
+        if items:
+            message += "; offending items: " + "; ".join(items)
+        super().__init__(message)
+
+
+def check_identifier(value: str, what: str) -> str:
+    """Return value if it is safe to use as a label or relationship type, otherwise raise ValueError."""
+    if not isinstance(value, str) or not IDENTIFIER.match(value):
+        raise ValueError(f"Invalid {what} {value!r}")
+    return value
+
+
+def group_nodes(nodes: Iterable[Dict[str, Any]]) -> Dict[Tuple[str, ...], List[Dict[str, Any]]]:
+    """
+    Group Neo4j-format nodes by label set, keeping first-seen order.
+    
+    Args:
+        nodes: [{'labels': [...], 'properties': {...}}]
+    
+    Returns:
+        label tuple -> list of property maps
+    """
+    groups: Dict[Tuple[str, ...], List[Dict[str, Any]]] = {}
+    for node in nodes:
+        labels = tuple(node["labels"])
+        groups.setdefault(labels, []).append(node["properties"])
+    return groups
+
+
+def group_relationships(relationships: Iterable[Dict[str, Any]]) -> Dict[str, List[Dict[str, Any]]]:
+    """
+    Group Neo4j-format relationships by type, keeping first-seen order.
+    
+    Args:
+        relationships: [{'start_node_id': ..., 'end_node_id': ..., 'type': ..., 'properties': {...}}]
+    
+    Returns:
+        relationship type -> list of {start_id, end_id, props} rows
+    """
+    groups: Dict[str, List[Dict[str, Any]]] = {}
+    for rel in relationships:
+        groups.setdefault(rel["type"], []).append({
+            "start_id": rel["start_node_id"],
+            "end_id": rel["end_node_id"],
+            "props": rel.get("properties") or {},
+        })
+    return groups
+
+
+def node_batch_query(labels: Tuple[str, ...]) -> str:
+    """
+    Build the UNWIND statement for nodes that share a label set.
+    
+    Rows are MERGEd on Base.id, so re-running a write replaces the stored
+    properties instead of creating duplicates.
+    """
+    extra = [check_identifier(label, "label") for label in labels if label != BASE_LABEL]
+    set_labels = f"\nSET n{''.join(f':`{label}`' for label in extra)}" if extra else ""
+    return (
+        "UNWIND $batch AS row\n"
+        f"MERGE (n:{BASE_LABEL} {{id: row.id}})\n"
+        "SET n = row"
+        f"{set_labels}"
+    )
+
+
+def relationship_batch_query(rel_type: str) -> str:
+    """Build the UNWIND statement for relationships of one type."""
+    check_identifier(rel_type, "relationship type")
+    return (
+        "UNWIND $batch AS row\n"
+        f"MATCH (start:{BASE_LABEL} {{id: row.start_id}})\n"
+        f"MATCH (end:{BASE_LABEL} {{id: row.end_id}})\n"
+        f"CREATE (start)-[r:`{rel_type}`]->(end)\n"
+        "SET r = row.props"
+    )
+
+
+def describe_node(properties: Dict[str, Any]) -> str:
+    """Describe a node row by symbol and location, e.g. "'save' at app/models.py:12 (id ...)"."""
+    name = properties.get("name")
+    location = properties.get("file_path") or ""
+    if location and properties.get("line_no"):
+        location += f":{properties['line_no']}"
+    parts = [repr(name) if name is not None else "<unnamed>"]
+    if location:
+        parts.append(f"at {location}")
+    parts.append(f"(id {properties.get('id')!r})")
+    return " ".join(parts)
+
+
+def describe_relationship(row: Dict[str, Any]) -> str:
+    """Describe a relationship row by its endpoints and source line."""
+    text = f"{row.get('start_id')!r} -> {row.get('end_id')!r}"
+    line_no = (row.get("props") or {}).get("line_no")
+    if line_no:
+        text += f" (line {line_no})"
+    return text
+
+
+def invalid_property(properties: Dict[str, Any]) -> Optional[str]:
+    """Return the first key whose value Neo4j cannot store as a property, or None."""
+    for key, value in properties.items():
+        if value is None or isinstance(value, _PRIMITIVES):
+            continue
+        if isinstance(value, (list, tuple)):
+            kinds = {type(item) for item in value}
+            if all(isinstance(item, _PRIMITIVES) for item in value) and len(kinds) <= 1:
+                continue
+        return key
+    return None
+
+
+def offending_nodes(rows: List[Dict[str, Any]], cause: Exception) -> List[str]:
+    """
+    Pick the node rows behind a failed statement.
+    
+    In order: rows holding the value quoted in a constraint violation, rows
+    without an id, rows with property values Neo4j rejects. When none stand
+    out the first rows of the batch are named.
+    """
+    culprits = _rows_with_violation_value(rows, cause)
+    if not culprits:
+        culprits = [row for row in rows if row.get("id") is None]
+    if not culprits:
+        described = []
+        for row in rows:
+            key = invalid_property(row)
+            if key is not None:
+                described.append(f"{describe_node(row)} property {key!r}")
+        if described:
+            return described[:MAX_REPORTED_ITEMS]
+        culprits = rows
+    return [describe_node(row) for row in culprits[:MAX_REPORTED_ITEMS]]
+
+
+def offending_relationships(rows: List[Dict[str, Any]], cause: Exception) -> List[str]:
+    """Pick the relationship rows behind a failed statement, like offending_nodes."""
+    culprits = [row for row in rows if row.get("start_id") is None or row.get("end_id") is None]
+    if not culprits:
+        described = []
+        for row in rows:
+            key = invalid_property(row.get("props") or {})
+            if key is not None:
+                described.append(f"{describe_relationship(row)} property {key!r}")
+        if described:
+            return described[:MAX_REPORTED_ITEMS]
+        culprits = rows
+    return [describe_relationship(row) for row in culprits[:MAX_REPORTED_ITEMS]]
+
+
+def _rows_with_violation_value(rows: List[Dict[str, Any]], cause: Exception) -> List[Dict[str, Any]]:
+    match = _VIOLATION_VALUE.search(str(cause))
+    if not match:
+        return []
+    key, value = match.groups()
+    return [row for row in rows if str(row.get(key)) == value]
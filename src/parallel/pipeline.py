@@ -0,0 +1,172 @@
+"""
+Bounded parse pipeline for the first indexing pass.
+
+File paths are fed to the pool's workers through a bounded window of
+in-flight tasks, so discovery never gets far ahead of parsing and at most
+`max_in_flight` results are held before being consumed. Results are
+yielded in input order (tasks that finish early wait in the window),
+which keeps the merged index and relation order identical to a
+sequential run regardless of how the workers are scheduled.
+
+The worker is a module-level function that only receives picklable
+ParserSettings, so it runs in both ThreadPoolExecutor and
+ProcessPoolExecutor without shipping the indexer (database driver,
+embedding client) to each process.
+"""
+
+import logging
+import os
+from collections import deque
+from dataclasses import dataclass
+from typing import Any, Dict, Iterable, Iterator, List, Tuple
+
+from src.ast_parser.fallback_parser import FallbackParser, fallback_extensions
+from src.ast_parser.guards import (
+    DEFAULT_MAX_FILE_BYTES,
+    DEFAULT_PARSE_TIMEOUT,
+    get_max_file_bytes,
+    get_parse_timeout,
+    parse_with_limits,
+)
+
+logger = logging.getLogger(__name__)
+
+# (nodes, relations, module_definitions, pending_imports, module_to_file)
+ParseResult = Tuple[Dict[str, Any], List[Any], Dict[str, Dict[str, str]], List[Dict[str, Any]], Dict[str, str]]
+
+EMPTY_RESULT: ParseResult = ({}, [], {}, [], {})
+
+
+@dataclass(frozen=True)
+class ParserSettings:
+    """Parser routing options and per-file limits (see src.ast_parser.guards), passed to every worker."""
+    use_ast_grep: bool = False
+    ast_grep_languages: Tuple[str, ...] = ("python", "javascript", "typescript")
+    ast_grep_fallback: bool = True
+    max_file_bytes: int = DEFAULT_MAX_FILE_BYTES
+    parse_timeout: float = DEFAULT_PARSE_TIMEOUT
+    
+    @classmethod
+    def from_env(cls) -> "ParserSettings":
+        """Settings from USE_AST_GREP, AST_GREP_LANGUAGES, AST_GREP_FALLBACK_TO_LEGACY, INDEX_MAX_FILE_BYTES
+        and INDEX_PARSE_TIMEOUT, like the indexer."""
+        return cls(
+            use_ast_grep=os.getenv("USE_AST_GREP", "false").lower() == "true",
+            ast_grep_languages=tuple(os.getenv("AST_GREP_LANGUAGES", "python,javascript,typescript").split(',')),
+            ast_grep_fallback=os.getenv("AST_GREP_FALLBACK_TO_LEGACY", "true").lower() == "true",
+            max_file_bytes=get_max_file_bytes(),
+            parse_timeout=get_parse_timeout(),
+        )
+
+
+def create_parser(file_path: str, settings: ParserSettings):
+    """
+    Select the parser for a file.
+    
+    Returns:
+        ProtoParser for .proto files, FallbackParser for the extensions of the
+        fallback patterns, MultiLanguageParser when ast-grep is enabled,
+        otherwise ASTParser, TypeScriptAdapter (.ts/.tsx) or TypeScriptParser
+        (.js/.jsx) by extension; None for unsupported extensions
+    """
+    ext = os.path.splitext(file_path)[1].lower()
+    if ext == '.proto':
+        # Same parser in both modes, there is no ast-grep grammar for protobuf
+        from src.ast_parser.proto_parser import ProtoParser
+        return ProtoParser()
+    if ext in fallback_extensions():
+        # Languages without a parser, matched by patterns in both modes
+        return FallbackParser()
+    
+    if settings.use_ast_grep:
+        from src.ast_parser.multi_parser import MultiLanguageParser
+        return MultiLanguageParser(
+            use_ast_grep=True,
+            ast_grep_languages=list(settings.ast_grep_languages),
+            ast_grep_fallback=settings.ast_grep_fallback,
+            max_file_bytes=settings.max_file_bytes,
+            parse_timeout=settings.parse_timeout
+        )
+    
+    if ext == '.py':
+        from src.ast_parser.parser import ASTParser
+        return ASTParser()
+    if ext in ('.ts', '.tsx'):
+        # Interfaces, enums, tsconfig paths and components are only extracted by the adapter
+        from src.ast_parser.adapters.typescript_adapter import TypeScriptAdapter
+        return TypeScriptAdapter(use_tsx=ext == '.tsx')
+    if ext in ('.js', '.jsx'):
+        from src.ast_parser.typescript_parser import TypeScriptParser
+        return TypeScriptParser()
+    
+    logger.warning(f"Unsupported file extension: {ext} ({file_path})")
+    return None
+
+
+def parse_source_file(file_path: str, settings: ParserSettings) -> ParseResult:
+    """
+    Parse one file for the first pass (pool worker).
+    
+    Errors are logged and yield an empty result, so one bad file does not
+    abort the run. Files too large or too slow to parse yield their File
+    node only (see src.ast_parser.guards).
+    """
+    try:
+        parser = create_parser(file_path, settings)
+        if parser is None:
+            return EMPTY_RESULT
+        
+        ext = os.path.splitext(file_path)[1].lower()
+        if settings.use_ast_grep and ext != '.proto' and ext not in fallback_extensions():
+            # MultiLanguageParser (see create_parser) applies the limits to the parser it routes the file to
+            parser.parse_file(file_path, build_index=True)
+        else:
+            parse_with_limits(parser, file_path, True, settings.max_file_bytes, settings.parse_timeout)
+        
+        return (
+            dict(parser.nodes),
+            list(parser.relations),
+            dict(parser.module_definitions),
+            list(parser.pending_imports),
+            dict(parser.module_to_file)
+        )
+    except Exception as e:
+        logger.error(f"Error parsing file {file_path}: {e}")
+        return EMPTY_RESULT
+
+
+def iter_parse_results(pool, file_paths: Iterable[str], settings: ParserSettings,
+                       max_in_flight: int) -> Iterator[Tuple[str, ParseResult]]:
+    """
+    Parse files on the pool and yield (file_path, result) in input order.
+    
+    Args:
+        pool: ProcessingPoolManager (sequential pools run each task on submit)
+        file_paths: Files to parse; consumed lazily
+        settings: Parser routing options
+        max_in_flight: Upper bound on submitted but not yet yielded tasks
+    """
+    max_in_flight = max(1, max_in_flight)
+    window = deque()
+    paths = iter(file_paths)
+    
+    def submit_next() -> bool:
+        file_path = next(paths, None)
+        if file_path is None:
+            return False
+        window.append((file_path, pool.submit(parse_source_file, file_path, settings)))
+        return True
+    
+    while len(window) < max_in_flight and submit_next():
+        pass
+    
+    while window:
+        # Waiting on the oldest task keeps the output order; later tasks keep running meanwhile
+        file_path, future = window.popleft()
+        try:
+            result = future.result()
+        except Exception as e:
+            logger.error(f"Error parsing file {file_path}: {e}")
+            result = EMPTY_RESULT
+        submit_next()
+        yield file_path, result
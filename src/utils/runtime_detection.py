@@ -75,7 +75,7 @@ def get_optimal_worker_count(max_workers: Optional[int] = None) -> int:
     Args:
         max_workers: Optional maximum number of workers. If None, uses
                     environment variable MAX_WORKERS or defaults to
-                    min(os.cpu_count(), 8).
+                    os.cpu_count().
     
     Returns:
         int: Optimal worker count (at least 1).
@@ -93,9 +93,8 @@ def get_optimal_worker_count(max_workers: Optional[int] = None) -> int:
                 f"Invalid MAX_WORKERS value '{env_workers}', using default"
             )
     
-    # Default: min(cpu_count, 8)
-    cpu_count = os.cpu_count() or 4  # Fallback to 4 if cpu_count() returns None
-    return min(cpu_count, 8)
+    # Default: one worker per CPU core
+    return os.cpu_count() or 4  # Fallback to 4 if cpu_count() returns None
 
 
 def get_runtime_info() -> dict:
@@ -0,0 +1,120 @@
+"""
+Shared fixtures for the MCP tool and indexing tests.
+
+The server registers its tools on CapturingFastMCP instead of FastMCP, so
+tests can call the tool coroutines directly without a transport.
+ConstantEmbeddingProvider stands in for the embedding service when a test
+indexes a tree but does not search by vector.
+
+Indexing tests write their sources with write_tree(), select the legacy
+Python parser with the legacy_env fixture and index with index_tree().
+"""
+
+import os
+import sys
+from unittest.mock import MagicMock, patch
+
+import pytest
+
+# Add project root to Python path
+sys.path.insert(0, os.path.dirname(os.path.dirname(os.path.abspath(__file__))))
+
+from src.graph_store import InMemoryGraphStore
+
+
+class CapturingFastMCP:
+    """Keeps registered tools so tests can call them directly."""
+    
+    def __init__(self, *args, **kwargs):
+        self.tools = {}
+    
+    def tool(self, *args, **kwargs):
+        def decorator(func):
+            self.tools[func.__name__] = func
+            return func
+        return decorator
+    
+    def prompt(self, *args, **kwargs):
+        return lambda func: func
+    
+    def resource(self, *args, **kwargs):
+        return lambda func: func
+
+
+class ConstantEmbeddingProvider:
+    """Embeds every text to the same vector, for tests that index without an embedding service."""
+    
+    dimension = 1
+    model = "one"
+    
+    def embed_text(self, text):
+        return [1.0]
+    
+    def embed_batch(self, texts):
+        return [[1.0] for _ in texts]
+    
+    def get_dimension(self):
+        return self.dimension
+
+
+def write_tree(root, files):
+    """Write {relative path: text or bytes} under root, creating directories."""
+    for path, content in files.items():
+        target = root / path
+        target.parent.mkdir(parents=True, exist_ok=True)
+        if isinstance(content, bytes):
+            target.write_bytes(content)
+        else:
+            target.write_text(content, encoding="utf-8")
+
+
+def index_tree(path, store=None, incremental=False, **options):
+    """
+    Index a directory with CodebaseKnowledgeGraph and return the indexer.
+    
+    Args:
+        path: Directory to index
+        store: Store to index into, if None, a new InMemoryGraphStore
+        incremental: Incremental run over the earlier index of the store
+        options: Other constructor arguments (max_file_bytes, streaming, ...)
+    """
+    from src.main import CodebaseKnowledgeGraph
+    
+    kg = CodebaseKnowledgeGraph(store=store or InMemoryGraphStore(), embedding_provider=ConstantEmbeddingProvider(),
+                                **options)
+    kg.process_codebase(str(path), incremental=incremental)
+    return kg
+
+
+@pytest.fixture
+def legacy_env(monkeypatch):
+    """Legacy Python parser, sequential mode, default cross-language matchers."""
+    monkeypatch.setenv("USE_AST_GREP", "false")
+    monkeypatch.setenv("ENABLE_JS_TS_PARSING", "false")
+    monkeypatch.setenv("PARALLEL_INDEXING_ENABLED", "false")
+    monkeypatch.delenv("CROSS_LANG_MATCHERS", raising=False)
+
+
+@pytest.fixture
+def capturing_mcp():
+    """A bare CapturingFastMCP, for tests that register tools themselves."""
+    return CapturingFastMCP()
+
+
+@pytest.fixture
+def make_server():
+    """Builds CodebaseKnowledgeGraphMCP on CapturingFastMCP; keyword arguments go to the constructor."""
+    pytest.importorskip("mcp.server.fastmcp")
+    
+    def build(**kwargs):
+        with patch("src.mcp.server.FastMCP", CapturingFastMCP), \
+             patch("src.mcp.server.get_embedding_provider", return_value=MagicMock()):
+            from src.mcp.server import CodebaseKnowledgeGraphMCP
+            return CodebaseKnowledgeGraphMCP(**kwargs)
+    return build
+
+
+@pytest.fixture
+def server(make_server):
+    """A server over an empty InMemoryGraphStore."""
+    return make_server(store=InMemoryGraphStore())
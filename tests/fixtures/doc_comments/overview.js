@@ -0,0 +1,8 @@
+/**
+ * @fileoverview Helpers for formatting.
+ */
+
+/** Formats a value. */
+function format(value) {
+  return String(value);
+}
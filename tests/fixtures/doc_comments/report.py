@@ -0,0 +1,19 @@
+# Copyright 2024 Example
+"""Reports about people."""
+
+
+class Report:
+    """
+    A report.
+
+        Indented example.
+    """
+
+    def render(self):
+        """Render the report."""
+        return ""
+
+
+def build():
+    """Build a report."""
+    return Report()
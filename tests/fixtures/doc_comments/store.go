@@ -0,0 +1,44 @@
+// Copyright 2024 The Example Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style license.
+
+//go:build linux
+
+// Package store keeps people in memory.
+package store
+
+// Person represents a user
+// of the system.
+type Person struct {
+	Name string
+}
+
+type (
+	// ID identifies a person.
+	ID string
+	Age int
+)
+
+// Greeter greets people.
+type Greeter interface {
+	Greet(p Person) string
+}
+
+// NewPerson creates a Person.
+//
+//	p := NewPerson("ann")
+//
+//go:noinline
+func NewPerson(name string) *Person {
+	return &Person{Name: name}
+}
+
+var count = 0 // number of people
+
+func Undocumented() {}
+
+/*
+GetName returns the name.
+*/
+func (p *Person) GetName() string {
+	return p.Name
+}
@@ -0,0 +1,34 @@
+/**
+ * @license MIT
+ * Copyright (c) 2024 Example
+ */
+/**
+ * Renders a widget.
+ * @param {string} label - Text shown on the widget
+ * @param {number} [width=10] Width in
+ *   characters
+ * @returns {string} The rendered widget
+ */
+export function render(label, width) {
+  return label.padEnd(width ?? 10);
+}
+
+// Not a doc comment
+export const plain = () => 1;
+
+/**
+ * Formats a label.
+ * @param {string} label
+ */
+const shout = (label) => label.toUpperCase();
+
+/** Builds widgets. */
+export class Factory {
+  /**
+   * Builds one widget.
+   * @returns the widget
+   */
+  build() {
+    return { label: "x" };
+  }
+}
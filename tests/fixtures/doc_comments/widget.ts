@@ -0,0 +1,38 @@
+/**
+ * @license MIT
+ * Copyright (c) 2024 Example
+ */
+/**
+ * Renders a widget.
+ * @param {string} label - Text shown on the widget
+ * @param {number} [width=10] Width in
+ *   characters
+ * @returns {string} The rendered widget
+ */
+export function render(label: string, width?: number): string {
+  return label.padEnd(width ?? 10);
+}
+
+// Not a doc comment
+export const plain = (): number => 1;
+
+/** Shape of a widget. */
+export interface Widget {
+  label: string;
+}
+
+/** Builds widgets. */
+@Injectable()
+export class Factory {
+  /** Number of widgets built. */
+  count = 0;
+
+  /**
+   * Builds one widget.
+   * @returns the widget
+   */
+  @Log()
+  build(): Widget {
+    return { label: "x" };
+  }
+}
@@ -0,0 +1,19 @@
+// Package server implements the Greeter service.
+package server
+
+import (
+	"context"
+
+	pb "example.com/greeter/go/greeterpb"
+)
+
+// Server answers Greeter calls.
+type Server struct {
+	pb.UnimplementedGreeterServer
+	Prefix string
+}
+
+// SayHello greets the caller by name.
+func (s *Server) SayHello(ctx context.Context, in *pb.HelloRequest) (*pb.HelloReply, error) {
+	return &pb.HelloReply{Message: s.Prefix + in.GetName()}, nil
+}
@@ -0,0 +1,13 @@
+"""Command line client of the Greeter service."""
+import grpc
+
+import greeter_pb2
+import greeter_pb2_grpc
+
+
+def greet(address, name):
+    """Send one SayHello and return the reply message."""
+    with grpc.insecure_channel(address) as channel:
+        stub = greeter_pb2_grpc.GreeterStub(channel)
+        reply = stub.SayHello(greeter_pb2.HelloRequest(name=name))
+    return reply.message
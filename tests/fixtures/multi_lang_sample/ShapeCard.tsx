@@ -0,0 +1,26 @@
+import { Shape } from './sample';
+
+interface ShapeCardProps {
+    shape: Shape;
+}
+
+export const ShapeCard = ({ shape }: ShapeCardProps) => (
+    <div className="shape">{shape.name}</div>
+);
+
+export default function ShapeList({ shapes }: { shapes: Shape[] }) {
+    const label = (shape: Shape) => <span>{shape.kind}</span>;
+    if (shapes.length === 0) {
+        return null;
+    }
+    return (
+        <ul>
+            {shapes.map((shape) => <ShapeCard key={shape.id} shape={shape} />)}
+        </ul>
+    );
+}
+
+export function AreaText(shape: Shape): string {
+    const render = () => <b>{shape.area()}</b>;
+    return `${shape.area()}`;
+}
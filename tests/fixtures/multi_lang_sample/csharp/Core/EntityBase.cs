@@ -0,0 +1,31 @@
+using System;
+
+namespace Shop.Core
+{
+    /// <summary>
+    /// Base class of every persisted entity.
+    /// </summary>
+    public abstract class EntityBase
+    {
+        public Guid Id { get; protected set; }
+
+        public abstract void Validate();
+    }
+
+    public interface IAuditable
+    {
+        DateTime ModifiedAt { get; }
+
+        void Touch(string user);
+    }
+
+    [AttributeUsage(AttributeTargets.Class)]
+    public sealed class AggregateRootAttribute : Attribute
+    {
+    }
+
+    namespace Money
+    {
+        public readonly record struct Amount(decimal Value, string Currency);
+    }
+}
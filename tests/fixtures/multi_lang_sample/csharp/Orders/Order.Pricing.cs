@@ -0,0 +1,31 @@
+using static System.Math;
+
+namespace Shop.Orders
+{
+    [Obsolete("Use the pricing service")]
+    public partial class Order : IDiscountable
+    {
+        public decimal Total()
+        {
+            decimal total = 0;
+            foreach (var line in _lines)
+            {
+                total += line.Price.Value * line.Quantity;
+            }
+            return Round(total, 2);
+        }
+
+        public decimal Total(decimal discount) => Total() - discount;
+
+        public bool TryApply(string code, out decimal discount, params string[] tags)
+        {
+            discount = code == "TEN" ? 10 : 0;
+            return discount > 0;
+        }
+    }
+
+    internal interface IDiscountable
+    {
+        bool TryApply(string code, out decimal discount, params string[] tags);
+    }
+}
@@ -0,0 +1,64 @@
+using System;
+using System.Collections.Generic;
+using Shop.Core;
+using Money = Shop.Core.Money.Amount;
+
+namespace Shop.Orders;
+
+/// <summary>An order and its lines.</summary>
+[AggregateRoot]
+[Serializable]
+public partial class Order : EntityBase, IAuditable, IComparable<Order>
+{
+    private readonly List<OrderLine> _lines = new();
+
+    public string Customer { get; init; }
+
+    public DateTime ModifiedAt { get; private set; }
+
+    public int LineCount => _lines.Count;
+
+    public Status State
+    {
+        get { return _state; }
+        set { _state = value; }
+    }
+
+    private Status _state;
+
+    public Order(string customer)
+    {
+        Customer = customer;
+    }
+
+    public void Touch(string user)
+    {
+        ModifiedAt = DateTime.UtcNow;
+    }
+
+    public override void Validate()
+    {
+        if (_lines.Count == 0 || Customer == null)
+        {
+            throw new InvalidOperationException("empty order");
+        }
+    }
+
+    public int CompareTo(Order other) => Total().CompareTo(other.Total());
+
+    public enum Status
+    {
+        Open,
+        Paid,
+        Shipped,
+    }
+}
+
+public class OrderLine
+{
+    public string Sku { get; set; }
+
+    public int Quantity { get; set; }
+
+    public Money Price { get; set; }
+}
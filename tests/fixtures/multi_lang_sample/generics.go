@@ -0,0 +1,70 @@
+// Generic functions, generic types and constraint interfaces
+package main
+
+// Integer is satisfied by every type whose underlying type is a signed integer
+type Integer interface {
+	~int | ~int8 | ~int16 | ~int32 | ~int64
+}
+
+// Number adds the floating-point types to Integer
+type Number interface {
+	Integer | ~float32 | ~float64
+}
+
+// Map applies f to every element of xs
+func Map[T, U any](xs []T, f func(T) U) []U {
+	out := make([]U, 0, len(xs))
+	for _, x := range xs {
+		out = append(out, f(x))
+	}
+	return out
+}
+
+// Sum adds up a slice of numbers
+func Sum[N Number](xs []N) N {
+	var total N
+	for _, x := range xs {
+		total += x
+	}
+	return total
+}
+
+// Stack is a last-in, first-out container
+type Stack[T any] struct {
+	items []T
+}
+
+func NewStack[T any]() *Stack[T] {
+	return &Stack[T]{}
+}
+
+func (s *Stack[T]) Push(item T) {
+	s.items = append(s.items, item)
+}
+
+func (s *Stack[T]) Pop() (T, bool) {
+	var zero T
+	if len(s.items) == 0 {
+		return zero, false
+	}
+	item := s.items[len(s.items)-1]
+	s.items = s.items[:len(s.items)-1]
+	return item, true
+}
+
+// Pair holds a key and a numeric value
+type Pair[K comparable, V Number] struct {
+	Key   K
+	Value V
+}
+
+// Totals instantiates the generic functions explicitly and by inference
+func Totals(words []string) (int, int) {
+	lengths := Map[string, int](words, func(w string) int { return len(w) })
+	doubled := Map(lengths, func(n int) int { return n * 2 })
+	stack := NewStack[int]()
+	stack.Push(Sum[int](lengths))
+	stack.Push(Sum(doubled))
+	top, _ := stack.Pop()
+	return top, len(doubled)
+}
@@ -0,0 +1,6 @@
+package com.example.people;
+
+/** Anything with a name, like the Named interface in shapes.go. */
+public interface Named {
+    String getName();
+}
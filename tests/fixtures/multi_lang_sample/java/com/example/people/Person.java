@@ -0,0 +1,56 @@
+// Java counterpart of the Person example in sample.go and person_methods.go
+package com.example.people;
+
+import com.example.people.annotations.*;
+import java.util.Comparator;
+
+/**
+ * A person with a name and an age.
+ */
+@Audited("people")
+public class Person implements Named {
+    private String name;
+    private int age;
+
+    public Person(String name, int age) {
+        this.name = name;
+        this.age = age;
+    }
+
+    @Override
+    public String getName() {
+        return this.name;
+    }
+
+    public void setName(String name) {
+        this.name = name;
+    }
+
+    public void setName(String first, String last) {
+        this.name = first + " " + last;
+    }
+
+    public int getAge() {
+        return this.age;
+    }
+
+    @Override
+    public String toString() {
+        return name + " (" + age + ")";
+    }
+
+    public static Comparator<Person> byAge() {
+        return new Comparator<Person>() {
+            @Override
+            public int compare(Person a, Person b) {
+                return Integer.compare(a.age, b.age);
+            }
+        };
+    }
+
+    /** Where a person lives. */
+    public static class Address {
+        @Audited
+        private String city;
+    }
+}
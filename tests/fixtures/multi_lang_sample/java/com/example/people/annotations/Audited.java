@@ -0,0 +1,6 @@
+package com.example.people.annotations;
+
+/** Marks types and fields whose changes are audited. */
+public @interface Audited {
+    String value() default "";
+}
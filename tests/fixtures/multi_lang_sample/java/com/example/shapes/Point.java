@@ -0,0 +1,13 @@
+package com.example.shapes;
+
+public record Point(double x, double y) {
+    public Point {
+        if (Double.isNaN(x) || Double.isNaN(y)) {
+            throw new IllegalArgumentException("NaN coordinate");
+        }
+    }
+
+    public double distance(Point other) {
+        return Math.hypot(x - other.x, y - other.y);
+    }
+}
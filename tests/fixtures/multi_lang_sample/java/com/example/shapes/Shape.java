@@ -0,0 +1,9 @@
+package com.example.shapes;
+
+import com.example.people.Named;
+
+public interface Shape extends Named {
+    double area();
+
+    double perimeter();
+}
@@ -0,0 +1,52 @@
+package com.example.shapes;
+
+abstract class Polygon implements Shape {
+    abstract int sides();
+}
+
+public class Square extends Polygon implements Comparable<Square> {
+    private final double side;
+
+    public Square(double side) {
+        this.side = side;
+    }
+
+    @Override
+    public double area() {
+        return side * side;
+    }
+
+    @Override
+    public double perimeter() {
+        return 4 * side;
+    }
+
+    @Override
+    public String getName() {
+        return "square";
+    }
+
+    @Override
+    int sides() {
+        return 4;
+    }
+
+    @Override
+    public int compareTo(Square other) {
+        return Double.compare(side, other.side);
+    }
+
+    public enum Color {
+        RED,
+        GREEN {
+            @Override
+            Color next() {
+                return RED;
+            }
+        };
+
+        Color next() {
+            return GREEN;
+        }
+    }
+}
@@ -0,0 +1,24 @@
+// Declares com.example.shapes although it lives in a legacy/ subdirectory
+package com.example.shapes;
+
+class Triangle extends Polygon {
+    @Override
+    int sides() {
+        return 3;
+    }
+
+    @Override
+    public double area() {
+        return 0;
+    }
+
+    @Override
+    public double perimeter() {
+        return 0;
+    }
+
+    @Override
+    public String getName() {
+        return "triangle";
+    }
+}
@@ -0,0 +1,14 @@
+package com.example.billing;
+
+/**
+ * Base class of the accounts, extended from Kotlin.
+ */
+public abstract class Account {
+    protected final String id;
+
+    protected Account(String id) {
+        this.id = id;
+    }
+
+    public abstract long balance();
+}
@@ -0,0 +1,10 @@
+package com.example.billing
+
+/** Something that keeps an audit trail, implemented from Java. */
+interface Auditable {
+    fun auditTrail(): List<String> = emptyList()
+}
+
+open class Ledger {
+    open fun entries(): Int = 0
+}
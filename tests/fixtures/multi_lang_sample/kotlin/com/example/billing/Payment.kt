@@ -0,0 +1,18 @@
+package com.example.billing
+
+/** Outcome of a payment. */
+sealed class Payment {
+    abstract val amount: Long
+
+    data class Settled(override val amount: Long, val reference: String) : Payment()
+
+    object Declined : Payment() {
+        override val amount: Long = 0
+    }
+}
+
+sealed interface Event
+
+class Charged(val payment: Payment) : Event
+
+typealias PaymentHandler = (Payment) -> Unit
@@ -0,0 +1,27 @@
+package com.example.billing
+
+class SavingsAccount(id: String, private var cents: Long = 0) : Account(id), Auditable {
+    val isEmpty: Boolean
+        get() = cents == 0L
+
+    override fun balance(): Long = cents
+
+    fun deposit(amount: Long) {
+        if (amount <= 0) {
+            throw IllegalArgumentException("amount must be positive")
+        }
+        cents += amount
+    }
+
+    companion object {
+        const val MIN_BALANCE = 0L
+
+        fun open(id: String): SavingsAccount = SavingsAccount(id, MIN_BALANCE)
+    }
+}
+
+/** Extension on the Java base class. */
+fun Account.describe(): String = "$id: ${balance()}"
+
+val SavingsAccount.overdrawn: Boolean
+    get() = balance() < SavingsAccount.MIN_BALANCE
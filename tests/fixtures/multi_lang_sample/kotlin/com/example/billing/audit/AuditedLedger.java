@@ -0,0 +1,11 @@
+package com.example.billing.audit;
+
+import com.example.billing.Auditable;
+import com.example.billing.Ledger;
+
+public class AuditedLedger extends Ledger implements Auditable {
+    @Override
+    public int entries() {
+        return 1;
+    }
+}
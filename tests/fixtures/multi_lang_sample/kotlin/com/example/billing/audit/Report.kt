@@ -0,0 +1,9 @@
+package com.example.billing.audit
+
+import com.example.billing.SavingsAccount
+import com.example.billing.describe
+import com.example.billing.Ledger as BaseLedger
+
+fun report(account: SavingsAccount, vararg notes: String): String = account.describe() + notes.joinToString()
+
+class MonthlyLedger : BaseLedger()
@@ -0,0 +1,20 @@
+// Methods and calls that reference declarations in sample.go
+package main
+
+import "strconv"
+
+type ID string
+
+func (p Person) String() string {
+	return p.Name + " (" + strconv.Itoa(p.Age) + ")"
+}
+
+func (id ID) IsEmpty() bool {
+	return id == ""
+}
+
+func Introduce(name string, age int) string {
+	p := &Person{Name: name, Age: age}
+	Greet(p.GetName())
+	return p.String()
+}
@@ -0,0 +1,22 @@
+# Records who touched a model and when.
+module Trackable
+  TRACKED_COLUMNS = 2
+  
+  def touch_by(user)
+    @touched_by = user
+  end
+  
+  # Class-level helpers added with extend.
+  module ClassMethods
+    def tracked?
+      true
+    end
+  end
+end
+
+module Auditing
+  def save(*args, **options)
+    audit(:save)
+    super
+  end
+end
@@ -0,0 +1,17 @@
+require_relative "application_record"
+
+module Blog
+  class Post < ApplicationRecord
+    include Trackable
+    
+    belongs_to :author, class_name: "Accounts::User"
+    has_many :taggings
+    
+    attr_accessor :draft
+    
+    def publish!
+      self.draft = false
+      save
+    end
+  end
+end
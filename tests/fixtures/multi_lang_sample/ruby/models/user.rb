@@ -0,0 +1,50 @@
+require "json"
+require_relative "application_record"
+require_relative "../concerns/trackable"
+
+module Blog
+  module Accounts
+    # A registered author.
+    class User < ApplicationRecord
+      include Trackable
+      extend Trackable::ClassMethods
+      prepend Auditing
+      
+      ROLES = %w[admin editor].freeze
+      MAX_POSTS = 100
+      
+      attr_accessor :nickname, :bio
+      attr_reader :token
+      attr_writer :password
+      
+      has_many :posts, foreign_key: :author_id
+      has_one :profile
+      
+      def self.find_by_nickname(nickname)
+        where(nickname: nickname).first
+      end
+      
+      class << self
+        def roles
+          ROLES
+        end
+      end
+      
+      def display_name(prefix = nil, upcase: false)
+        name = nickname || "anonymous"
+        name = name.upcase if upcase
+        prefix ? "#{prefix} #{name}" : name
+      end
+      
+      def to_json(*args)
+        JSON.generate(nickname: nickname)
+      end
+      
+      private
+      
+      def generate_token
+        @token = SecureRandom.hex
+      end
+    end
+  end
+end
@@ -0,0 +1,32 @@
+use super::{Describe, Shape};
+
+/// A circle around the origin.
+#[derive(Debug, Clone, PartialEq)]
+#[non_exhaustive]
+pub struct Circle {
+    /// Distance from the center to the edge.
+    pub radius: f64,
+}
+
+impl Circle {
+    pub fn new(radius: f64) -> Self {
+        Circle { radius }
+    }
+
+    /// A circle with twice the radius.
+    pub fn doubled(self) -> Circle {
+        Self::new(self.radius * 2.0)
+    }
+}
+
+impl Shape for Circle {
+    fn area(&self) -> f64 {
+        std::f64::consts::PI * self.radius * self.radius
+    }
+}
+
+impl Describe for Circle {
+    fn name(&self) -> String {
+        format!("circle of radius {}", self.radius)
+    }
+}
@@ -0,0 +1,27 @@
+//! Plane figures.
+
+mod circle;
+pub mod square;
+
+pub use self::circle::Circle;
+pub use square::Square;
+
+/// Something with a printable name.
+pub trait Describe {
+    fn name(&self) -> String;
+}
+
+/// A closed plane figure.
+pub trait Shape: Describe {
+    /// Area in square units.
+    fn area(&self) -> f64;
+
+    fn scaled_area(&self, factor: f64) -> f64 {
+        self.area() * factor * factor
+    }
+}
+
+/// Sum of the areas of the shapes.
+pub fn total_area(shapes: &[&dyn Shape]) -> f64 {
+    shapes.iter().map(|shape| shape.area()).sum()
+}
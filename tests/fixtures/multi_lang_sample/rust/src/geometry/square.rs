@@ -0,0 +1,38 @@
+use std::fmt;
+
+use super::{Describe, Shape};
+
+/// A square with a label borrowed from its caller.
+#[derive(Debug)]
+pub struct Square<'a, T = f64> {
+    pub side: T,
+    label: &'a str,
+}
+
+impl<'a> Square<'a> {
+    pub fn new(side: f64, label: &'a str) -> Self {
+        Square { side, label }
+    }
+
+    pub fn grow(&mut self, by: f64) {
+        self.side += by;
+    }
+}
+
+impl<'a> Shape for Square<'a> {
+    fn area(&self) -> f64 {
+        self.side * self.side
+    }
+}
+
+impl<'a> Describe for Square<'a> {
+    fn name(&self) -> String {
+        self.label.to_string()
+    }
+}
+
+impl<'a> fmt::Display for Square<'a> {
+    fn fmt(&self, f: &mut fmt::Formatter<'_>) -> fmt::Result {
+        write!(f, "{} ({})", self.label, self.side)
+    }
+}
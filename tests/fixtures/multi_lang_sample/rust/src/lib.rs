@@ -0,0 +1,8 @@
+//! Shapes and the reports built from them.
+//!
+//! Fixture crate for the Rust adapter tests.
+
+pub mod geometry;
+pub mod report;
+
+pub use geometry::Shape;
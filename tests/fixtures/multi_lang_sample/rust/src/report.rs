@@ -0,0 +1,33 @@
+use crate::geometry::square::*;
+use crate::geometry::{self, Circle, Shape as Area};
+
+/// One line of a report.
+pub enum Line {
+    Title(String),
+    Entry { name: String, area: f64 },
+    Blank,
+}
+
+/// Report over a circle and a square.
+pub fn summary(label: &str) -> Vec<Line> {
+    let circle = unit_circle();
+    let square = Square::new(2.0, label);
+    let total = geometry::total_area(&[&circle, &square]);
+    vec![
+        Line::Title(String::from("Shapes")),
+        Line::Entry { name: label.to_string(), area: total },
+        Line::Blank,
+    ]
+}
+
+pub fn unit_circle() -> Circle {
+    Circle::new(1.0)
+}
+
+/// The shape with the largest area.
+pub fn largest<'a, T: Area + ?Sized>(shapes: &[&'a T]) -> Option<&'a T> {
+    shapes
+        .iter()
+        .copied()
+        .max_by(|a, b| a.area().total_cmp(&b.area()))
+}
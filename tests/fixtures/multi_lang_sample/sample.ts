@@ -0,0 +1,42 @@
+// Sample TypeScript file for ast-grep parsing
+import { Entity, Repository } from './ts_lib';
+import { Logger } from '@lib/logger';
+import * as decorators from '@lib/decorators';
+import { Component } from '@angular/core';
+
+export type ShapeKind = 'circle' | 'square';
+
+export enum Color {
+    Red = 'red',
+    Green = 'green',
+    Blue,
+}
+
+export interface Named {
+    name: string;
+}
+
+export interface Shape extends Named, Entity {
+    kind: ShapeKind;
+    area(): number;
+}
+
+@decorators.Injectable()
+export class ShapeRepository extends Repository<Shape> {
+    @decorators.Column('shape_color')
+    color: Color = Color.Red;
+
+    constructor(private readonly logger: Logger) {
+        super();
+    }
+
+    validate(item: Shape): boolean {
+        this.logger.log(item.name);
+        return item.area() > 0;
+    }
+}
+
+@Component({ selector: 'app-shape' })
+export class ShapeComponent implements Named {
+    name = 'shape';
+}
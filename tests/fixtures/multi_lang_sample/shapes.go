@@ -0,0 +1,91 @@
+// Interfaces satisfied implicitly by value, pointer and partial implementations
+package main
+
+import (
+	"fmt"
+	"math"
+)
+
+type Shape interface {
+	Area() float64
+	Perimeter() float64
+}
+
+type Named interface {
+	Name() string
+}
+
+// NamedShape only embeds, its method set is Shape's plus Named's
+type NamedShape interface {
+	Shape
+	Named
+}
+
+type Resizer interface {
+	Resize(factor float64) (ok bool, err error)
+}
+
+// Describer embeds an interface from a package that is not indexed
+type Describer interface {
+	fmt.Stringer
+	Describe() string
+}
+
+// Square implements everything with value receivers
+type Square struct {
+	Side float64
+}
+
+func (s Square) Area() float64 {
+	return s.Side * s.Side
+}
+
+func (s Square) Perimeter() float64 {
+	return 4 * s.Side
+}
+
+func (s Square) Name() string {
+	return "square"
+}
+
+// Circle only has pointer receivers, so only *Circle satisfies the interfaces
+type Circle struct {
+	Radius float64
+}
+
+func (c *Circle) Area() float64 {
+	return math.Pi * c.Radius * c.Radius
+}
+
+func (c *Circle) Perimeter() float64 {
+	return 2 * math.Pi * c.Radius
+}
+
+func (c *Circle) Name() string {
+	return "circle"
+}
+
+func (c *Circle) Resize(f float64) (bool, error) {
+	c.Radius *= f
+	return true, nil
+}
+
+// Triangle is one method short of Shape and NamedShape
+type Triangle struct {
+	Base, Height float64
+}
+
+func (t Triangle) Area() float64 {
+	return t.Base * t.Height / 2
+}
+
+func (t Triangle) Name() string {
+	return "triangle"
+}
+
+// Resize has the wrong result types for Resizer
+func (t *Triangle) Resize(factor float64) error {
+	t.Base *= factor
+	t.Height *= factor
+	return nil
+}
@@ -0,0 +1,7 @@
+export function Injectable(): ClassDecorator {
+    return () => undefined;
+}
+
+export function Column(name?: string): PropertyDecorator {
+    return () => undefined;
+}
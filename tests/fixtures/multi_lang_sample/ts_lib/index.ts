@@ -0,0 +1,3 @@
+// Barrel file: re-exports the library modules
+export { Entity } from './entity';
+export * from './repository';
@@ -0,0 +1,13 @@
+import { Entity } from './entity';
+
+export abstract class Repository<T extends Entity> {
+    protected items: Map<string, T> = new Map();
+
+    abstract validate(item: T): boolean;
+
+    save(item: T): void {
+        if (this.validate(item)) {
+            this.items.set(item.id, item);
+        }
+    }
+}
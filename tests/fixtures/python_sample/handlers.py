@@ -0,0 +1,69 @@
+"""Async handlers, decorators, dataclasses, nested and conditional definitions."""
+
+from dataclasses import dataclass, field
+from typing import TYPE_CHECKING, ClassVar, List
+
+import registry
+from registry import traced
+
+if TYPE_CHECKING:
+    from collections.abc import Iterable
+
+    def type_only_helper(items: "Iterable[str]") -> List[str]:
+        return list(items)
+
+
+class App:
+    def route(self, path):
+        return lambda func: func
+
+
+app = App()
+
+
+@dataclass(frozen=True)
+class Request:
+    """A parsed request."""
+
+    path: str
+    headers: List[str] = field(default_factory=list)
+    retries: int = 0
+    MAX_RETRIES: ClassVar[int] = 3
+
+
+@app.route("/users")
+@traced
+async def list_users(request: Request):
+    """List users."""
+    return await fetch_users(request)
+
+
+@registry.register("fetch")
+async def fetch_users(request):
+    def build_query(path):
+        return f"SELECT * FROM users WHERE path = '{path}'"
+
+    return build_query(request.path)
+
+
+def local_decorator(func):
+    return func
+
+
+@local_decorator
+def decorated_locally():
+    return 1
+
+
+class UserService:
+    @staticmethod
+    def create():
+        return UserService()
+
+    @traced
+    async def load(self, user_id):
+        return user_id
+
+    if TYPE_CHECKING:
+        def debug(self) -> str:
+            return "debug"
@@ -0,0 +1,20 @@
+"""Decorators defined in the codebase, used by handlers.py."""
+
+import functools
+
+HANDLERS = {}
+
+
+def register(name):
+    """Register a handler under a name."""
+    def decorator(func):
+        HANDLERS[name] = func
+        return func
+    return decorator
+
+
+def traced(func):
+    @functools.wraps(func)
+    async def wrapper(*args, **kwargs):
+        return await func(*args, **kwargs)
+    return wrapper
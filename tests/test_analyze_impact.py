@@ -0,0 +1,325 @@
+"""
+analyze_impact and test tagging tests.
+
+annotate_tests is checked on hand-built nodes of each language. The
+impact tests seed a Go call graph into an InMemoryGraphStore: Store.Save
+implements the Saver interface, app/service.go calls it directly and
+through the interface, cmd/ calls the service from another package and
+app/service_test.go tests it. The end-to-end tests index a small Python
+codebase with pytest and unittest tests.
+"""
+
+import asyncio
+import json
+import os
+import sys
+from unittest.mock import MagicMock
+
+import pytest
+
+# Add project root to Python path
+sys.path.insert(0, os.path.dirname(os.path.dirname(os.path.abspath(__file__))))
+
+from src.ast_parser.parser import CodeNode, CodeRelation
+from src.graph_store import InMemoryGraphStore
+from src.indexing.testcode import annotate_tests, is_test_file
+from src.mcp.impact import analyze_impact
+
+
+def _node(node_type, name, file_path, **properties):
+    return CodeNode(f"{node_type.lower()}:{file_path}:{name}", node_type, name, file_path, 1, properties=properties)
+
+
+class TestAnnotateTests:
+    
+    def test_test_files(self):
+        for path in ("pkg/store_test.go", "tests/test_store.py", "store_test.py", "web/cart.test.ts",
+                     "web/cart.spec.jsx", "web/__tests__/cart.js", "test/store_test.rb", "spec/store_spec.rb"):
+            assert is_test_file(path), path
+        for path in ("pkg/store.go", "tests/conftest.py", "web/cart.ts", "__tests__/README.md", "testdata/x.go",
+                     "spec/spec_helper.rb"):
+            assert not is_test_file(path), path
+    
+    def test_go_functions(self):
+        nodes = {node.node_id: node for node in (
+            _node("Function", "TestSave", "pkg/store_test.go"),
+            _node("Function", "BenchmarkSave", "pkg/store_test.go"),
+            _node("Function", "Example", "pkg/store_test.go"),
+            _node("Function", "Testify", "pkg/store_test.go"),
+            _node("Function", "newFixture", "pkg/store_test.go"),
+            _node("Function", "TestSave", "pkg/store.go"),
+            _node("File", "store_test.go", "pkg/store_test.go"),
+        )}
+        
+        tagged = annotate_tests(nodes, [])
+        
+        assert sorted(nodes[node_id].name for node_id in tagged) == ["BenchmarkSave", "Example", "TestSave",
+                                                                     "store_test.go"]
+        assert nodes["function:pkg/store.go:TestSave"].properties.get("is_test") is None
+    
+    def test_python_functions_and_methods(self):
+        suite = _node("Class", "StoreSuite", "tests/suite.py", bases=["unittest.TestCase"])
+        pytest_class = _node("Class", "TestStore", "tests/test_store.py")
+        helper = _node("Class", "Helper", "tests/test_store.py")
+        methods = [_node("Method", "test_save", "tests/suite.py"), _node("Method", "test_load", "tests/test_store.py"),
+                   _node("Method", "test_mode", "tests/test_store.py"), _node("Method", "setUp", "tests/suite.py")]
+        nodes = {node.node_id: node for node in [suite, pytest_class, helper] + methods + [
+            _node("Function", "test_round_trip", "tests/test_store.py"),
+            _node("Function", "test_connection", "app/db.py"),
+        ]}
+        relations = [CodeRelation(owner.node_id, method.node_id, "DEFINES")
+                     for owner, method in ((suite, methods[0]), (pytest_class, methods[1]), (helper, methods[2]),
+                                           (suite, methods[3]))]
+        
+        tagged = annotate_tests(nodes, relations)
+        
+        assert tagged == {methods[0].node_id, methods[1].node_id, "function:tests/test_store.py:test_round_trip"}
+    
+    def test_java_and_rust_annotations(self):
+        nodes = {node.node_id: node for node in (
+            _node("Method", "saves", "src/StoreTest.java", annotations=["Test"]),
+            _node("Method", "loads", "src/StoreTest.java", annotations=["org.junit.jupiter.api.ParameterizedTest"]),
+            _node("Method", "setUp", "src/StoreTest.java", annotations=["BeforeEach"]),
+            _node("Function", "saves", "src/store.rs", attributes=["test"]),
+            _node("Function", "loads", "src/store.rs", attributes=["tokio::test(flavor = \"multi_thread\")"]),
+            _node("Function", "fixture", "src/store.rs", attributes=["cfg(test)"]),
+        )}
+        
+        tagged = annotate_tests(nodes, [])
+        
+        assert sorted(tagged) == ["function:src/store.rs:loads", "function:src/store.rs:saves",
+                                  "method:src/StoreTest.java:loads", "method:src/StoreTest.java:saves"]
+    
+    def test_kotlin_annotations(self):
+        nodes = {node.node_id: node for node in (
+            _node("Method", "saves", "src/StoreTest.kt", annotations=["Test"]),
+            _node("Method", "loads", "src/StoreTest.kt", annotations=["ParameterizedTest", "ValueSource(ints = [1])"]),
+            _node("Method", "setUp", "src/StoreTest.kt", annotations=["BeforeEach"]),
+        )}
+        
+        tagged = annotate_tests(nodes, [])
+        
+        assert sorted(tagged) == ["method:src/StoreTest.kt:loads", "method:src/StoreTest.kt:saves"]
+    
+    def test_csharp_attributes(self):
+        nodes = {node.node_id: node for node in (
+            _node("Method", "Saves", "tests/StoreTests.cs", attributes=["Fact"]),
+            _node("Method", "Loads", "tests/StoreTests.cs", attributes=["TestCase(1, \"a\")"]),
+            _node("Method", "Runs", "tests/StoreTests.cs", attributes=["Microsoft.VisualStudio.TestTools.TestMethod"]),
+            _node("Method", "SetUp", "tests/StoreTests.cs", attributes=["SetUp"]),
+        )}
+        
+        tagged = annotate_tests(nodes, [])
+        
+        assert sorted(tagged) == ["method:tests/StoreTests.cs:Loads", "method:tests/StoreTests.cs:Runs",
+                                  "method:tests/StoreTests.cs:Saves"]
+    
+    def test_ruby_minitest_methods(self):
+        nodes = {node.node_id: node for node in (
+            _node("Method", "test_saves", "test/store_test.rb"),
+            _node("Method", "setup", "test/store_test.rb"),
+            _node("Method", "test_mode", "app/models/store.rb"),
+        )}
+        
+        tagged = annotate_tests(nodes, [])
+        
+        assert sorted(tagged) == ["method:test/store_test.rb:test_saves"]
+
+
+NODES = {
+    "interface:app/store.go:Saver:3": ("Saver", "Interface", "app/store.go", {"methods": ["Save(key string) error"]}),
+    "class:app/store.go:Store:7": ("Store", "Class", "app/store.go", {}),
+    "method:app/store.go:Save:9": ("Save", "Method", "app/store.go", {}),
+    "function:app/service.go:Persist:3": ("Persist", "Function", "app/service.go", {}),
+    "function:app/service.go:Flush:9": ("Flush", "Function", "app/service.go", {}),
+    "function:app/service_test.go:TestPersist:5": ("TestPersist", "Function", "app/service_test.go",
+                                                   {"is_test": True}),
+    "function:cmd/main.go:main:5": ("main", "Function", "cmd/main.go", {}),
+    "function:cmd/main.go:run:9": ("run", "Function", "cmd/main.go", {}),
+    "file:web/store.test.js": ("store.test.js", "File", "web/store.test.js", {"is_test": True}),
+    "file:web/client.js": ("client.js", "File", "web/client.js", {}),
+    "package:app": ("app", "Package", None, {"import_path": "example.com/app"}),
+    "package:cmd": ("cmd", "Package", None, {"import_path": "example.com/cmd"}),
+}
+
+EDGES = [
+    ("class:app/store.go:Store:7", "method:app/store.go:Save:9", "DEFINES", {}),
+    ("class:app/store.go:Store:7", "interface:app/store.go:Saver:3", "IMPLEMENTS", {"via": "pointer"}),
+    ("function:app/service.go:Persist:3", "method:app/store.go:Save:9", "CALLS", {"line_no": 4}),
+    ("function:app/service.go:Flush:9", "interface:app/store.go:Saver:3", "CALLS",
+     {"line_no": 10, "method": "Save", "via_interface": True}),
+    ("function:app/service_test.go:TestPersist:5", "function:app/service.go:Persist:3", "CALLS", {"line_no": 6}),
+    ("function:cmd/main.go:main:5", "function:app/service.go:Persist:3", "CALLS", {"line_no": 6}),
+    ("function:cmd/main.go:run:9", "function:cmd/main.go:main:5", "CALLS", {"line_no": 10}),
+    ("file:web/store.test.js", "function:cmd/main.go:run:9", "IMPORTS", {}),
+    ("file:web/client.js", "function:app/service.go:Persist:3", "IMPORTS", {}),
+    ("package:app", "file:app/store.go", "CONTAINS", {}),
+    ("package:app", "file:app/service.go", "CONTAINS", {}),
+    ("package:app", "file:app/service_test.go", "CONTAINS", {}),
+    ("package:cmd", "file:cmd/main.go", "CONTAINS", {}),
+]
+
+
+def _impact_store():
+    """A store holding NODES, EDGES and a File node per file."""
+    store = InMemoryGraphStore()
+    file_paths = {file_path for _, _, file_path, _ in NODES.values() if file_path}
+    store.batch_create_nodes([
+        {"labels": ["Base", "File"], "properties": {"id": f"file:{path}", "name": os.path.basename(path),
+                                                    "file_path": path, "line_no": 0}}
+        for path in sorted(file_paths) if f"file:{path}" not in NODES
+    ] + [
+        {"labels": ["Base", node_type],
+         "properties": dict(properties, id=node_id, name=name, file_path=file_path, line_no=int(node_id.split(":")[-1])
+                            if node_id.split(":")[-1].isdigit() else 0)}
+        for node_id, (name, node_type, file_path, properties) in NODES.items()
+    ])
+    store.batch_create_relationships([
+        {"start_node_id": source, "end_node_id": target, "type": rel_type, "properties": properties}
+        for source, target, rel_type, properties in EDGES
+    ])
+    return store
+
+
+def _names(entries):
+    return [entry["name"] for entry in entries]
+
+
+class TestAnalyzeImpact:
+    
+    def test_callers_by_distance_and_package(self):
+        result = analyze_impact(_impact_store(), "Store.Save")
+        
+        assert result["status"] == "ok"
+        assert result["symbol"]["package"] == "example.com/app"
+        callers = result["callers"]
+        assert (callers["total"], callers["same_package"], callers["cross_package"], callers["more"]) == (4, 2, 2, 0)
+        first, second, third = callers["by_distance"]
+        assert (first["distance"], _names(first["same_package"]), first["cross_package"]) == (1, ["Persist", "Flush"], [])
+        assert first["same_package"][1]["via_interface"] is True
+        assert "via_interface" not in first["same_package"][0]
+        assert (_names(second["cross_package"]), second["same_package"]) == (["main"], [])
+        assert second["cross_package"][0]["package"] == "example.com/cmd"
+        assert _names(third["cross_package"]) == ["run"]
+        assert result["truncated"] is False
+    
+    def test_interfaces_declaring_the_method(self):
+        result = analyze_impact(_impact_store(), "Store.Save")
+        
+        (interface,) = result["interfaces"]
+        assert (interface["name"], interface["node_type"], interface["package"]) == ("Saver", "Interface",
+                                                                                    "example.com/app")
+        assert analyze_impact(_impact_store(), "Persist")["interfaces"] == []
+    
+    def test_tests_reaching_the_symbol(self):
+        result = analyze_impact(_impact_store(), "Store.Save")
+        
+        tests = result["tests"]
+        assert [(bucket["distance"], _names(bucket["tests"])) for bucket in tests["by_distance"]] == [
+            (2, ["TestPersist"]), (4, ["store.test.js"]),
+        ]
+        assert tests["by_distance"][0]["tests"][0]["via"] == "call"
+        assert tests["by_distance"][1]["tests"][0]["via"] == "import"
+        # Test functions are not listed as callers; plain importers are not tests
+        assert "TestPersist" not in str(result["callers"]) and "client.js" not in str(result)
+    
+    def test_depth_limits_the_closure(self):
+        result = analyze_impact(_impact_store(), "Store.Save", max_depth=1)
+        
+        assert result["callers"]["total"] == 2
+        assert result["tests"]["total"] == 0
+    
+    def test_results_are_capped_closest_first(self):
+        result = analyze_impact(_impact_store(), "Store.Save", max_results=3)
+        
+        callers = result["callers"]
+        assert (callers["total"], callers["more"]) == (4, 1)
+        assert [bucket["distance"] for bucket in callers["by_distance"]] == [1, 2]
+        assert result["truncated"] is True
+    
+    def test_invalid_arguments_and_unknown_symbols(self):
+        db = _impact_store()
+        
+        with pytest.raises(ValueError, match="max_depth"):
+            analyze_impact(db, "Persist", max_depth=0)
+        with pytest.raises(ValueError, match="max_results"):
+            analyze_impact(db, "Persist", max_results=0)
+        assert analyze_impact(db, "Delete")["status"] == "not_found"
+
+
+CODEBASE = {
+    "shop/__init__.py": "",
+    "shop/cart.py": (
+        "def price(items):\n"
+        "    return sum(items)\n"
+        "\n"
+        "\n"
+        "def checkout(items):\n"
+        "    return price(items) * 2\n"
+    ),
+    "tests/__init__.py": "",
+    "tests/test_cart.py": (
+        "import unittest\n"
+        "from shop.cart import checkout\n"
+        "\n"
+        "\n"
+        "def test_checkout():\n"
+        "    assert checkout([1]) == 2\n"
+        "\n"
+        "\n"
+        "class CartCase(unittest.TestCase):\n"
+        "    def test_empty(self):\n"
+        "        self.assertEqual(checkout([]), 0)\n"
+    ),
+}
+
+
+@pytest.fixture
+def kg(monkeypatch, tmp_path):
+    monkeypatch.setenv("USE_AST_GREP", "false")
+    monkeypatch.setenv("ENABLE_JS_TS_PARSING", "false")
+    monkeypatch.setenv("PARALLEL_INDEXING_ENABLED", "false")
+    from src.main import CodebaseKnowledgeGraph
+    
+    for path, source in CODEBASE.items():
+        (tmp_path / path).parent.mkdir(parents=True, exist_ok=True)
+        (tmp_path / path).write_text(source, encoding="utf-8")
+    kg = CodebaseKnowledgeGraph(store=InMemoryGraphStore(), embedding_provider=MagicMock())
+    kg.process_codebase(str(tmp_path))
+    return kg
+
+
+class TestIndexedImpact:
+    
+    def test_python_tests_are_tagged(self, kg):
+        tagged = {record["properties"]["name"] for label in ("Function", "Method", "File")
+                  for record in kg.db.find_nodes(label=label) if record["properties"].get("is_test")}
+        
+        assert tagged == {"test_checkout", "test_empty", "test_cart.py"}
+    
+    def test_tests_calling_the_symbol(self, kg):
+        result = analyze_impact(kg.db, "checkout")
+        
+        (bucket,) = result["tests"]["by_distance"]
+        assert bucket["distance"] == 1
+        assert [(test["name"], test["via"]) for test in bucket["tests"]] == [("test_checkout", "call"),
+                                                                             ("test_empty", "call")]
+        # Calls of imported functions resolve, so the importing test file is not listed again
+        assert result["callers"]["total"] == 0
+
+
+class TestAnalyzeImpactTool:
+    
+    @pytest.fixture
+    def tool(self, make_server):
+        server = make_server(store=_impact_store())
+        return lambda **kwargs: json.loads(asyncio.run(server.mcp.tools["analyze_impact"](**kwargs)))
+    
+    def test_structured_result(self, tool):
+        result = tool(symbol="Persist", max_depth=1)
+        
+        assert result["status"] == "ok"
+        assert result["callers"]["cross_package"] == 1
+    
+    def test_errors_are_returned(self, tool):
+        assert "error" in tool(symbol="Persist", max_depth=20)
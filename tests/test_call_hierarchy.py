@@ -0,0 +1,195 @@
+"""
+get_call_hierarchy tests.
+
+The call graph is seeded into an InMemoryGraphStore. render calls
+Shape.Area through an interface value, which Square and Circle implement;
+walk and visit call each other, and log is called from every function.
+"""
+
+import asyncio
+import json
+import os
+import sys
+
+import pytest
+
+# Add project root to Python path
+sys.path.insert(0, os.path.dirname(os.path.dirname(os.path.abspath(__file__))))
+
+from src.ast_parser.parser import ASTParser, CodeNode
+from src.graph_store import InMemoryGraphStore
+from src.mcp.call_hierarchy import call_hierarchy, store_direction
+
+
+NODES = {
+    "function:cmd/main.go:main:5": ("main", "Function", "cmd/main.go", 5),
+    "function:app/render.go:render:3": ("render", "Function", "app/render.go", 3),
+    "function:app/walk.go:walk:3": ("walk", "Function", "app/walk.go", 3),
+    "function:app/walk.go:visit:9": ("visit", "Function", "app/walk.go", 9),
+    "function:app/log.go:log:1": ("log", "Function", "app/log.go", 1),
+    "interface:app/shapes.go:Shape:3": ("Shape", "Interface", "app/shapes.go", 3),
+    "interface:app/shapes.go:Closer:7": ("Closer", "Interface", "app/shapes.go", 7),
+    "class:app/shapes.go:Square:10": ("Square", "Class", "app/shapes.go", 10),
+    "method:app/shapes.go:Area:14": ("Area", "Method", "app/shapes.go", 14),
+    "class:app/shapes.go:Circle:20": ("Circle", "Class", "app/shapes.go", 20),
+    "method:app/shapes.go:Area:24": ("Area", "Method", "app/shapes.go", 24),
+}
+
+# (source, target, type, properties)
+EDGES = [
+    ("function:cmd/main.go:main:5", "function:app/render.go:render:3", "CALLS", {"line_no": 8, "call_lines": [8, 11]}),
+    ("function:cmd/main.go:main:5", "function:app/walk.go:walk:3", "CALLS", {"line_no": 9}),
+    ("function:cmd/main.go:main:5", "function:app/log.go:log:1", "CALLS", {"line_no": 10}),
+    ("function:app/render.go:render:3", "interface:app/shapes.go:Shape:3", "CALLS",
+     {"line_no": 5, "method": "Area", "via_interface": True}),
+    ("function:app/render.go:render:3", "interface:app/shapes.go:Closer:7", "CALLS",
+     {"line_no": 6, "method": "Close", "via_interface": True}),
+    ("function:app/walk.go:walk:3", "function:app/walk.go:visit:9", "CALLS", {"line_no": 4}),
+    ("function:app/walk.go:visit:9", "function:app/walk.go:walk:3", "CALLS", {"line_no": 10}),
+    ("function:app/walk.go:visit:9", "function:app/log.go:log:1", "CALLS", {"line_no": 11}),
+    ("method:app/shapes.go:Area:24", "function:app/log.go:log:1", "CALLS", {"line_no": 25}),
+    ("class:app/shapes.go:Square:10", "method:app/shapes.go:Area:14", "DEFINES", {}),
+    ("class:app/shapes.go:Circle:20", "method:app/shapes.go:Area:24", "DEFINES", {}),
+    ("class:app/shapes.go:Square:10", "interface:app/shapes.go:Shape:3", "IMPLEMENTS", {"via": "value"}),
+    ("class:app/shapes.go:Circle:20", "interface:app/shapes.go:Shape:3", "IMPLEMENTS", {"via": "pointer"}),
+]
+
+
+def _call_store():
+    """A store holding NODES and EDGES."""
+    store = InMemoryGraphStore()
+    store.batch_create_nodes([
+        {"labels": ["Base", node_type],
+         "properties": {"id": node_id, "name": name, "file_path": file_path, "line_no": line_no}}
+        for node_id, (name, node_type, file_path, line_no) in NODES.items()
+    ])
+    store.batch_create_relationships([
+        {"start_node_id": source, "end_node_id": target, "type": rel_type, "properties": properties}
+        for source, target, rel_type, properties in EDGES
+    ])
+    return store
+
+
+def _names(node):
+    return [child["qualified_name"] for child in node["children"]]
+
+
+class TestOptions:
+    
+    def test_direction_depth_and_fan_out_bounds(self):
+        assert store_direction("callers", 3, 50) == "in"
+        assert store_direction("callees", 10, 1) == "out"
+        with pytest.raises(ValueError, match="direction"):
+            store_direction("up", 3, 50)
+        with pytest.raises(ValueError, match="max_depth"):
+            store_direction("callers", 11, 50)
+        with pytest.raises(ValueError, match="max_children"):
+            store_direction("callers", 3, 0)
+
+
+class TestCallees:
+    
+    def test_tree_with_call_sites(self):
+        result = call_hierarchy(_call_store(), "main", direction="callees", max_depth=1)
+        
+        assert result["status"] == "ok"
+        tree = result["tree"]
+        assert (tree["name"], tree["file_path"], tree["line_no"]) == ("main", "cmd/main.go", 5)
+        assert _names(tree) == ["app.log", "app.render", "app.walk"]
+        render = tree["children"][1]
+        assert render["call_lines"] == [8, 11]
+        # Nodes at max_depth are not expanded
+        assert "children" not in render
+    
+    def test_interface_dispatch_lists_implementers(self):
+        result = call_hierarchy(_call_store(), "render", direction="callees", max_depth=2)
+        
+        children = result["tree"]["children"]
+        assert [(child["qualified_name"], child["line_no"]) for child in children] == [
+            ("app.Closer.Close", 7), ("app.Square.Area", 14), ("app.Circle.Area", 24),
+        ]
+        assert all(child["via_interface"] for child in children)
+        assert {child["interface"] for child in children} == {"app.Closer", "app.Shape"}
+        # An interface method without implementations is a leaf
+        assert children[0]["method"] == "Close" and "children" not in children[0]
+        assert _names(children[2]) == ["app.log"]
+    
+    def test_cycles_are_marked_not_expanded(self):
+        result = call_hierarchy(_call_store(), "walk", direction="callees", max_depth=5)
+        
+        (visit,) = result["tree"]["children"]
+        walk = visit["children"][1]
+        assert (walk["name"], walk["cycle"]) == ("walk", True)
+        assert "children" not in walk
+        assert "cycle" not in visit["children"][0]
+
+
+class TestCallers:
+    
+    def test_callers_include_calls_through_interfaces(self):
+        result = call_hierarchy(_call_store(), "Circle.Area", max_depth=2)
+        
+        (render,) = result["tree"]["children"]
+        assert (render["name"], render["call_lines"], render["interface"]) == ("render", [5], "app.Shape")
+        assert render["via_interface"] is True
+        assert _names(render) == ["cmd.main"]
+        assert "via_interface" not in render["children"][0]
+    
+    def test_fan_out_is_truncated(self):
+        result = call_hierarchy(_call_store(), "log", max_depth=1, max_children=2)
+        
+        tree = result["tree"]
+        assert (tree["truncated"], tree["total_children"]) == (True, 3)
+        assert _names(tree) == ["app.Circle.Area", "app.visit"]
+        assert "truncated" not in call_hierarchy(_call_store(), "log", max_depth=1)["tree"]
+    
+    def test_ambiguous_and_missing_symbols(self):
+        db = _call_store()
+        
+        ambiguous = call_hierarchy(db, "Area")
+        assert ambiguous["status"] == "ambiguous"
+        assert {c["qualified_name"] for c in ambiguous["candidates"]} == {"app.Square.Area", "app.Circle.Area"}
+        assert call_hierarchy(db, "draw")["status"] == "not_found"
+
+
+class TestInterfaceCallResolution:
+    """The parser links calls through an interface value to the interface node."""
+    
+    def test_call_through_embedded_interface(self):
+        parser = ASTParser()
+        for node_id, name, methods in (("interface:Shape", "Shape", ["Area() float64"]),
+                                       ("interface:Solid", "Solid", [])):
+            parser.nodes[node_id] = CodeNode(node_id, "Interface", name, "app.go", 1,
+                                             properties={"methods": methods})
+        parser.nodes["function:render"] = CodeNode("function:render", "Function", "render", "render.go", 1)
+        parser.module_definitions["shapes"] = {"Shape": "interface:Shape", "Solid": "interface:Solid"}
+        parser.pending_imports = [
+            {"type": "EMBEDS", "source_id": "interface:Solid", "imported_module": "shapes",
+             "imported_name": "Shape", "original_name": "Shape"},
+            {"type": "CALLS", "source_id": "function:render", "imported_module": "shapes",
+             "imported_name": "Solid.Area", "original_name": "Solid.Area", "line_no": 4},
+            {"type": "CALLS", "source_id": "function:render", "imported_module": "shapes",
+             "imported_name": "Solid.Volume", "original_name": "Solid.Volume", "line_no": 5},
+        ]
+        parser._process_pending_imports()
+        
+        (call,) = [r for r in parser.relations if r.relation_type == "CALLS"]
+        assert call.target_id == "interface:Solid"
+        assert (call.properties["method"], call.properties["via_interface"]) == ("Area", True)
+
+
+class TestCallHierarchyTool:
+    
+    @pytest.fixture
+    def get_call_hierarchy(self, make_server):
+        server = make_server(store=_call_store())
+        return server.mcp.tools["get_call_hierarchy"]
+    
+    def test_tree_and_error(self, get_call_hierarchy):
+        result = json.loads(asyncio.run(get_call_hierarchy("visit", direction="callers")))
+        assert result["status"] == "ok"
+        assert _names(result["tree"]) == ["app.walk"]
+        assert result["tree"]["children"][0]["children"][0]["cycle"] is True
+        
+        error = json.loads(asyncio.run(get_call_hierarchy("visit", max_depth=0)))
+        assert "max_depth" in error["error"]
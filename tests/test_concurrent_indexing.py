@@ -0,0 +1,331 @@
+"""
+Concurrent indexing tests.
+
+GraphCoordinator is tested on its own: write locks per root, reentrancy,
+cancellation while waiting, and queries kept out of applies. The watcher
+defers a sync while another run holds the root's write lock and syncs
+the paths queued meanwhile once it is free. The stress test copies the
+python_sample fixture and hammers it with index runs on several threads,
+watcher events and a query thread, then checks that no query saw a
+half-applied file and that the final graph equals a clean
+single-threaded index of the final files. MCP query tools are checked
+to wait for an apply, the indexing tools not to.
+"""
+
+import asyncio
+import json
+import os
+import shutil
+import sys
+import threading
+import time
+from pathlib import Path
+from unittest.mock import MagicMock
+
+import pytest
+
+# Add project root to Python path
+sys.path.insert(0, os.path.dirname(os.path.dirname(os.path.abspath(__file__))))
+
+from src.graph_store import InMemoryGraphStore, RepositoryStore
+from src.indexing.coordination import GraphCoordinator, get_coordinator
+from src.indexing.jobs import IndexCancelled, IndexProgress
+
+FIXTURES = Path(__file__).parent / "fixtures"
+
+# Threads re-indexing the codebase side by side with the watcher, and file edits while they run
+REINDEX_THREADS = 3
+EDITS = 18
+
+# Properties that differ between any two runs
+RUN_PROPERTIES = ("indexed_at",)
+
+
+def _wait_for(condition, timeout=10.0):
+    deadline = time.monotonic() + timeout
+    while time.monotonic() < deadline:
+        if condition():
+            return True
+        time.sleep(0.01)
+    return False
+
+
+def _in_thread(target):
+    thread = threading.Thread(target=target, daemon=True)
+    thread.start()
+    return thread
+
+
+@pytest.fixture
+def codebase(tmp_path):
+    root = tmp_path / "codebase"
+    shutil.copytree(FIXTURES / "python_sample", root)
+    return root
+
+
+def _graph(store, **kwargs):
+    from src.main import CodebaseKnowledgeGraph
+    
+    kg = CodebaseKnowledgeGraph(store=store, embedding_provider=MagicMock(), **kwargs)
+    kg._generate_embeddings = lambda *args, **kwargs: None
+    return kg
+
+
+def _snapshot(store):
+    """Every node and relationship of the store, sorted."""
+    nodes = []
+    for record in store.find_nodes():
+        properties = {key: value for key, value in record["properties"].items() if key not in RUN_PROPERTIES}
+        nodes.append(json.dumps({"labels": sorted(record["labels"]), "properties": properties}, sort_keys=True))
+    node_ids = [record["properties"]["id"] for record in store.find_nodes()]
+    relationships = [json.dumps(rel, sort_keys=True) for rel in store.edges_between(node_ids)]
+    return sorted(nodes), sorted(relationships)
+
+
+def _half_applied_files(store):
+    """Files with symbols but no File node, or a File node but no symbols; each fixture file defines some."""
+    files = {record["properties"]["file_path"] for record in store.find_nodes(label="File")}
+    symbol_files = {record["properties"].get("file_path") for record in store.find_nodes()
+                    if "File" not in record["labels"] and record["properties"].get("file_path")}
+    return files ^ symbol_files
+
+
+class TestGraphCoordinator:
+    
+    def test_write_lock_excludes_other_threads(self, tmp_path):
+        coordinator = GraphCoordinator()
+        seen = []
+        
+        def second_run():
+            seen.append(coordinator.holder(str(tmp_path), "default"))
+            with coordinator.write_lock(str(tmp_path), "default"):
+                seen.append("locked")
+        
+        with coordinator.write_lock(str(tmp_path), "default"):
+            # The lock is reentrant, and its holder is not told to wait for itself
+            with coordinator.write_lock(str(tmp_path / "."), "default"):
+                assert coordinator.holder(str(tmp_path), "default") is None
+            thread = _in_thread(second_run)
+            time.sleep(0.3)
+            assert seen == [threading.current_thread().name]
+        thread.join(5)
+        
+        assert seen == [threading.current_thread().name, "locked"]
+        assert coordinator.holder(str(tmp_path), "default") is None
+    
+    def test_other_roots_and_repositories_do_not_wait(self, tmp_path):
+        coordinator = GraphCoordinator()
+        done = []
+        
+        def run(root, repo):
+            with coordinator.write_lock(root, repo):
+                done.append(repo)
+        
+        with coordinator.write_lock(str(tmp_path), "default"):
+            for root, repo in ((str(tmp_path / "other"), "default"), (str(tmp_path), "web")):
+                thread = threading.Thread(target=run, args=(root, repo), daemon=True)
+                thread.start()
+                thread.join(5)
+        
+        assert done == ["default", "web"]
+    
+    def test_cancelled_while_waiting(self, tmp_path):
+        coordinator = GraphCoordinator()
+        locked, release = threading.Event(), threading.Event()
+        
+        def holder():
+            with coordinator.write_lock(str(tmp_path), "default"):
+                locked.set()
+                release.wait(5)
+        
+        thread = _in_thread(holder)
+        assert locked.wait(5)
+        progress = IndexProgress()
+        threading.Timer(0.1, progress.cancel).start()
+        try:
+            with pytest.raises(IndexCancelled):
+                with coordinator.write_lock(str(tmp_path), "default", progress.check_cancelled):
+                    pass
+        finally:
+            release.set()
+            thread.join(5)
+    
+    def test_queries_and_applies_exclude_each_other(self):
+        coordinator = GraphCoordinator()
+        events = []
+        release = threading.Event()
+        
+        def apply():
+            with coordinator.applying():
+                events.append("apply")
+                release.wait(5)
+            events.append("applied")
+        
+        def query():
+            with coordinator.reading():
+                events.append("query")
+        
+        coordinator.acquire_read()
+        applier = _in_thread(apply)
+        time.sleep(0.2)
+        # The apply waits for the running query, a new query waits behind the apply
+        querier = _in_thread(query)
+        time.sleep(0.2)
+        assert events == []
+        coordinator.release_read()
+        assert _wait_for(lambda: events == ["apply"])
+        time.sleep(0.2)
+        assert events == ["apply"]
+        release.set()
+        applier.join(5)
+        querier.join(5)
+        
+        assert events == ["apply", "applied", "query"]
+    
+    def test_shared_per_store(self):
+        store = InMemoryGraphStore()
+        
+        assert get_coordinator(store) is get_coordinator(RepositoryStore(store, "web"))
+        assert get_coordinator(store) is not get_coordinator(InMemoryGraphStore())
+
+
+def test_watcher_defers_while_another_run_holds_the_root(codebase, legacy_env):
+    from src.indexing.watcher import watch_codebase
+    
+    store = InMemoryGraphStore()
+    kg = _graph(store)
+    kg.process_codebase(str(codebase))
+    watcher = watch_codebase(kg, str(codebase), debounce_seconds=0.02, use_polling=True)
+    watcher.poll_interval = 60
+    locked, release = threading.Event(), threading.Event()
+    
+    def other_run():
+        with get_coordinator(store).write_lock(str(codebase), kg.repo):
+            locked.set()
+            release.wait(10)
+    
+    thread = _in_thread(other_run)
+    assert locked.wait(5)
+    watcher.start()
+    try:
+        (codebase / "first.py").write_text("def first():\n    return 1\n")
+        watcher.notify(str(codebase / "first.py"))
+        assert _wait_for(lambda: watcher.status()["deferred"] is not None)
+        # Events arriving meanwhile join the deferred sync
+        (codebase / "second.py").write_text("def second():\n    return 2\n")
+        watcher.notify(str(codebase / "second.py"))
+        time.sleep(0.3)
+        status = watcher.status()
+        assert (status["syncs"], status["pending_files"], status["stale"]) == (0, 2, True)
+        assert status["deferred"].endswith(f"is indexing {codebase}")
+        
+        release.set()
+        assert _wait_for(lambda: watcher.status()["syncs"] == 1)
+    finally:
+        release.set()
+        watcher.stop()
+        thread.join(5)
+    
+    status = watcher.status()
+    assert (status["deferred"], status["deferred_syncs"], status["last_error"]) == (None, 1, None)
+    assert status["last_sync_stats"]["changed"] == 2
+    assert {record["properties"]["name"] for record in store.find_nodes(label="Function")} >= {"first", "second"}
+
+
+def _edit(root, step):
+    """Change, add or delete a file; returns the paths touched."""
+    if step % 3 == 0:
+        path = root / "registry.py"
+        source = path.read_text() + f"\n\ndef helper_{step}():\n    return {step}\n"
+    elif step % 3 == 1:
+        path = root / f"extra_{step}.py"
+        source = f"from registry import register\n\n\n@register('extra_{step}')\ndef handler_{step}():\n    return {step}\n"
+    else:
+        path = root / f"extra_{step - 1}.py"
+        path.unlink()
+        return [str(path)]
+    # Written atomically, as editors save, so no run reads half a file
+    temp = path.with_name(f".{path.name}.tmp")
+    temp.write_text(source)
+    os.replace(temp, path)
+    return [str(path)]
+
+
+def test_concurrent_runs_match_a_clean_index(codebase, legacy_env):
+    from src.indexing.watcher import watch_codebase
+    
+    store = InMemoryGraphStore()
+    _graph(store).process_codebase(str(codebase))
+    coordinator = get_coordinator(store)
+    watcher = watch_codebase(_graph(store, write_batch_size=2), str(codebase), debounce_seconds=0.01,
+                             use_polling=True)
+    watcher.poll_interval = 60
+    stop = threading.Event()
+    errors, half_applied = [], []
+    
+    def reindex():
+        runner = _graph(store, write_batch_size=2)
+        while not stop.is_set():
+            try:
+                runner.process_codebase(str(codebase), incremental=True)
+            except Exception as e:
+                errors.append(e)
+                return
+    
+    def query():
+        while not stop.is_set():
+            with coordinator.reading():
+                half_applied.extend(_half_applied_files(store))
+            time.sleep(0.001)
+    
+    watcher.start()
+    threads = [_in_thread(reindex) for _ in range(REINDEX_THREADS)] + [_in_thread(query)]
+    try:
+        for step in range(EDITS):
+            for path in _edit(codebase, step):
+                watcher.notify(path)
+            time.sleep(0.02)
+    finally:
+        stop.set()
+        for thread in threads:
+            thread.join(30)
+    try:
+        assert _wait_for(lambda: not watcher.status()["stale"], timeout=30)
+    finally:
+        watcher.stop()
+    
+    assert errors == [], [repr(e) for e in errors]
+    assert half_applied == [], sorted(set(half_applied))
+    assert watcher.status()["last_error"] is None
+    clean = InMemoryGraphStore()
+    _graph(clean).process_codebase(str(codebase))
+    assert _snapshot(store) == _snapshot(clean)
+
+
+def test_query_tools_wait_for_applies(server):
+    tools = server.mcp.tools
+    applying, release = threading.Event(), threading.Event()
+    
+    def apply():
+        with get_coordinator(server.db).applying():
+            applying.set()
+            release.wait(5)
+    
+    async def calls():
+        query = asyncio.create_task(tools["list_repositories"]())
+        status = json.loads(await tools["get_watch_status"]())
+        await asyncio.sleep(0.2)
+        waited = not query.done()
+        release.set()
+        return status, waited, json.loads(await query)
+    
+    thread = _in_thread(apply)
+    assert applying.wait(5)
+    try:
+        status, waited, repositories = asyncio.run(calls())
+    finally:
+        release.set()
+        thread.join(5)
+    
+    assert status["running"] is False
+    assert waited and repositories == []
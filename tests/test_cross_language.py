@@ -0,0 +1,376 @@
+"""
+Cross-language linking tests.
+
+tests/fixtures/grpc_sample is a Greeter service: proto/greeter.proto, the
+generated Python stub (python/greeter_pb2_grpc.py) with a client using it,
+and the generated Go code (go/greeterpb) with a server embedding
+UnimplementedGreeterServer. Without ast-grep the Go files are not parsed,
+so the gRPC matcher tests add the Go nodes the Go adapter emits for them
+by hand; the end-to-end test parses them for real.
+"""
+
+import json
+import os
+import shutil
+import sys
+
+import pytest
+
+# Add project root to Python path
+sys.path.insert(0, os.path.dirname(os.path.dirname(os.path.abspath(__file__))))
+
+from src.ast_parser.multi_parser import MultiLanguageParser
+from src.ast_parser.parser import CodeNode, CodeRelation
+from src.ast_parser.proto_parser import ProtoParser
+from src.graph_store import InMemoryGraphStore
+from src.indexing import build_file_index_states, load_index_context
+from src.linking import FfiMatcher, GrpcMatcher, collect_link_facts, get_matchers, link_cross_language
+from src.mcp.paths import find_paths
+from conftest import ConstantEmbeddingProvider
+
+
+FIXTURE_DIR = os.path.join(os.path.dirname(os.path.abspath(__file__)), "fixtures", "grpc_sample")
+PROTO_FILE = os.path.join(FIXTURE_DIR, "proto", "greeter.proto")
+GO_STUB = os.path.join(FIXTURE_DIR, "go", "greeterpb", "greeter_grpc.pb.go")
+GO_SERVER = os.path.join(FIXTURE_DIR, "go", "server", "server.go")
+
+
+def _by_name(nodes, node_type, name):
+    (node,) = [n for n in nodes.values() if n.node_type == node_type and n.name == name]
+    return node
+
+
+def _edges(relations, nodes):
+    """{(source name, target name): properties} of the CROSS_LANG_CALLS edges."""
+    return {(nodes[r.source_id].name, nodes[r.target_id].name, r.properties["role"]): r.properties
+            for r in relations if r.relation_type == "CROSS_LANG_CALLS"}
+
+
+class TestProtoParser:
+    
+    @pytest.fixture
+    def proto(self):
+        return ProtoParser().parse_file(PROTO_FILE)
+    
+    def test_file_options(self, proto):
+        nodes, _ = proto
+        file_node = nodes[f"file:{PROTO_FILE}"]
+        
+        assert file_node.properties == {
+            "language": "proto",
+            "syntax": "proto3",
+            "package": "greeter.v1",
+            "imports": ["google/protobuf/timestamp.proto"],
+            "go_package": "example.com/greeter/go/greeterpb",
+        }
+    
+    def test_services_and_rpcs(self, proto):
+        nodes, relations = proto
+        service = _by_name(nodes, "Service", "Greeter")
+        
+        assert (service.line_no, service.end_line_no) == (10, 18)
+        assert service.properties["qualified_name"] == "greeter.v1.Greeter"
+        assert service.properties["doc"] == "Greeter says hello."
+        assert [r.target_id for r in relations if r.relation_type == "DEFINES_SERVICE"] == [service.node_id]
+        
+        say_hello = _by_name(nodes, "Rpc", "SayHello")
+        assert say_hello.properties["full_method"] == "/greeter.v1.Greeter/SayHello"
+        assert (say_hello.properties["request"], say_hello.properties["response"]) == ("HelloRequest", "HelloReply")
+        assert "server_streaming" not in say_hello.properties
+        watch = _by_name(nodes, "Rpc", "WatchGreetings")
+        assert watch.properties["server_streaming"] is True
+        # The option block belongs to the rpc
+        assert (watch.line_no, watch.end_line_no) == (15, 17)
+        assert service.properties["rpcs"] == [
+            "SayHello(HelloRequest) returns (HelloReply)",
+            "WatchGreetings(WatchRequest) returns (stream HelloReply)",
+        ]
+    
+    def test_messages_and_enums(self, proto):
+        nodes, relations = proto
+        request = _by_name(nodes, "Message", "HelloRequest")
+        
+        assert request.properties["fields"] == [
+            "string name = 1", "repeated string tags = 2", "map<string, string> labels = 3", "Tone tone = 4",
+        ]
+        # Fields of a oneof belong to the message
+        reply = _by_name(nodes, "Message", "HelloReply")
+        assert reply.properties["fields"][-2:] == ["Metadata metadata = 3", "string note = 4"]
+        
+        defines = {(nodes[r.source_id].name, nodes[r.target_id].name) for r in relations if r.relation_type == "DEFINES"}
+        assert {("HelloRequest", "Tone"), ("HelloReply", "Metadata")} <= defines
+        assert _by_name(nodes, "Message", "Metadata").properties["qualified_name"] == "greeter.v1.HelloReply.Metadata"
+        assert _by_name(nodes, "Enum", "Tone").properties["members"] == ["TONE_UNSPECIFIED", "TONE_FORMAL"]
+        
+        top_level = {nodes[r.target_id].name for r in relations if r.relation_type == "DEFINES_MESSAGE"}
+        assert top_level == {"HelloRequest", "HelloReply", "WatchRequest"}
+        (status,) = [r for r in relations if r.relation_type == "CONTAINS"]
+        assert nodes[status.target_id].name == "Status"
+
+
+def _go_nodes():
+    """The nodes and relations the Go adapter emits for the generated client and the server."""
+    server = CodeNode(f"Class:{GO_SERVER}:Server:11", "Class", "Server", GO_SERVER, 11, 14,
+                      properties={"kind": "struct", "embeds": ["pb.UnimplementedGreeterServer"]})
+    handler = CodeNode(f"Method:{GO_SERVER}:SayHello:17", "Method", "SayHello", GO_SERVER, 17, 19, properties={
+        "receiver_type": "Server", "receiver_kind": "pointer",
+        "signature": "SayHello(ctx context.Context, in *pb.HelloRequest) (*pb.HelloReply, error)",
+    })
+    client = CodeNode(f"Method:{GO_STUB}:SayHello:32", "Method", "SayHello", GO_STUB, 32, 39, properties={
+        "receiver_type": "greeterClient", "receiver_kind": "pointer",
+        "signature": "SayHello(ctx context.Context, in *HelloRequest, opts ...grpc.CallOption) (*HelloReply, error)",
+    })
+    nodes = {node.node_id: node for node in (server, handler, client)}
+    relations = [
+        CodeRelation(server.node_id, handler.node_id, "DEFINES"),
+        CodeRelation(handler.node_id, server.node_id, "METHOD_OF", {"receiver_kind": "pointer"}),
+    ]
+    return nodes, relations
+
+
+def _parse_fixture():
+    """Proto and Python files parsed without ast-grep, plus the Go nodes."""
+    nodes, relations = MultiLanguageParser(use_ast_grep=False).parse_directory(FIXTURE_DIR)
+    go_nodes, go_relations = _go_nodes()
+    nodes.update(go_nodes)
+    return nodes, relations + go_relations
+
+
+class TestGrpcMatcher:
+    
+    def test_client_stub_rpc_and_server_are_linked(self):
+        nodes, relations = _parse_fixture()
+        
+        edges = _edges(link_cross_language(nodes, relations, [GrpcMatcher()]), nodes)
+        
+        assert set(edges) == {
+            ("GreeterStub", "SayHello", "client"),
+            ("GreeterStub", "WatchGreetings", "client"),
+            ("greet", "SayHello", "client"),
+            ("SayHello", "SayHello", "client"),
+            ("SayHello", "SayHello", "server"),
+        }
+        # The generated stubs name the rpc path, the application client only uses the stub
+        assert edges[("GreeterStub", "SayHello", "client")]["confidence"] == 0.95
+        assert edges[("greet", "SayHello", "client")]["confidence"] == 0.8
+        server = edges[("SayHello", "SayHello", "server")]
+        assert (server["confidence"], server["service"], server["matcher"]) == (0.9, "greeter.v1.Greeter", "grpc")
+        assert server["structural"] is True
+    
+    def test_generated_servicer_and_unimplemented_server_are_not_handlers(self):
+        nodes, relations = _parse_fixture()
+        
+        linked = link_cross_language(nodes, relations, [GrpcMatcher()])
+        
+        targets = {nodes[r.target_id].file_path for r in linked if r.properties["role"] == "server"}
+        assert targets == {GO_SERVER}
+        # Base classes as written, the servicer subclasses are recognized by them
+        assert _by_name(nodes, "Class", "GreeterServicer").properties["bases"] == ["object"]
+        assert nodes[f"Function:{FIXTURE_DIR}/python/client.py:greet:8"].properties["grpc_calls"] == [
+            "/greeter.v1.Greeter/SayHello",
+        ]
+    
+    def test_handler_by_signature_and_ambiguous_services(self, tmp_path):
+        other = tmp_path / "other.proto"
+        other.write_text(
+            "syntax = \"proto3\";\n"
+            "package greeter.v2;\n"
+            "service Greeter {\n"
+            "  rpc SayHello(HelloRequest) returns (HelloReply);\n"
+            "}\n"
+        )
+        nodes, relations = _parse_fixture()
+        proto_nodes, proto_relations = ProtoParser().parse_file(str(other))
+        nodes.update(proto_nodes)
+        server = f"Class:{GO_SERVER}:Server:11"
+        # Without the embedded base the handler is only recognized by its signature
+        nodes[server].properties["embeds"] = []
+        
+        edges = [r for r in link_cross_language(nodes, relations + proto_relations, [GrpcMatcher()])
+                 if r.properties["role"] == "server"]
+        
+        assert sorted((r.properties["service"], r.properties["confidence"]) for r in edges) == [
+            ("greeter.v1.Greeter", 0.25), ("greeter.v2.Greeter", 0.25),
+        ]
+    
+    def test_find_path_crosses_the_proto(self, monkeypatch, tmp_path):
+        kg = _index(monkeypatch, tmp_path)
+        (handler,) = [record["properties"] for record in kg.db.find_nodes(name="SayHello", label="Method")
+                      if record["properties"]["file_path"].endswith("server.py")]
+        
+        result = find_paths(kg.db, "greet", handler["id"])
+        
+        assert result["status"] == "ok"
+        (path,) = result["paths"]
+        assert [(hop["relation_type"], hop["to"]["node_type"]) for hop in path["hops"]] == [
+            ("CROSS_LANG_CALLS", "Rpc"), ("CROSS_LANG_CALLS", "Method"),
+        ]
+        assert path["hops"][-1]["to"]["file_path"] == str(tmp_path / "python" / "server.py")
+
+
+SERVER_SOURCE = (
+    "import greeter_pb2\n"
+    "import greeter_pb2_grpc\n"
+    "\n"
+    "\n"
+    "class Greeter(greeter_pb2_grpc.GreeterServicer):\n"
+    "    def SayHello(self, request, context):\n"
+    "        return greeter_pb2.HelloReply(message='hello ' + request.name)\n"
+)
+
+
+def _index(monkeypatch, tmp_path, store=None):
+    """Index the proto and Python files of the fixture, plus a Python servicer, without ast-grep."""
+    monkeypatch.setenv("USE_AST_GREP", "false")
+    monkeypatch.setenv("PARALLEL_INDEXING_ENABLED", "false")
+    monkeypatch.delenv("CROSS_LANG_MATCHERS", raising=False)
+    from src.main import CodebaseKnowledgeGraph
+    
+    if not (tmp_path / "proto").exists():
+        shutil.copytree(os.path.join(FIXTURE_DIR, "proto"), tmp_path / "proto")
+        shutil.copytree(os.path.join(FIXTURE_DIR, "python"), tmp_path / "python")
+        (tmp_path / "python" / "server.py").write_text(SERVER_SOURCE)
+    kg = CodebaseKnowledgeGraph(store=store or InMemoryGraphStore(), embedding_provider=ConstantEmbeddingProvider())
+    kg.process_codebase(str(tmp_path), incremental=store is not None)
+    return kg
+
+
+def _stored_links(db):
+    """(source name, target name, role) of the stored CROSS_LANG_CALLS edges."""
+    names = {record["properties"]["id"]: record["properties"]["name"] for record in db.find_nodes()}
+    return {(names[rel["start_node_id"]], names[rel["end_node_id"]], rel["properties"]["role"])
+            for rels in db._out.values() for rel in rels if rel["type"] == "CROSS_LANG_CALLS"}
+
+
+class TestIncrementalLinking:
+    
+    def test_unchanged_files_are_linked_from_their_facts(self, monkeypatch, tmp_path):
+        kg = _index(monkeypatch, tmp_path)
+        full = _stored_links(kg.db)
+        assert ("greet", "SayHello", "client") in full
+        assert ("SayHello", "SayHello", "server") in full
+        
+        # Only the client is re-parsed: the proto and the servicer come from index_state
+        client = tmp_path / "python" / "client.py"
+        client.write_text(client.read_text() + "\n\ndef close():\n    pass\n")
+        kg = _index(monkeypatch, tmp_path, store=kg.db)
+        
+        assert kg.last_run_stats["changed"] == 1
+        assert _stored_links(kg.db) == full
+        
+        # A renamed handler is no longer linked
+        server = tmp_path / "python" / "server.py"
+        server.write_text(SERVER_SOURCE.replace("def SayHello", "def say_hello"))
+        kg = _index(monkeypatch, tmp_path, store=kg.db)
+        
+        assert _stored_links(kg.db) == full - {("SayHello", "SayHello", "server")}
+    
+    def test_link_facts_round_trip(self):
+        nodes, relations = _parse_fixture()
+        matchers = [GrpcMatcher()]
+        expected = link_cross_language(nodes, relations, matchers)
+        
+        states = build_file_index_states(nodes, relations, {}, {},
+                                         link_facts=collect_link_facts(nodes, relations, matchers))
+        stored = {path: {"index_state": json.dumps(state)} for path, state in states.items()}
+        _, _, stub_nodes, stub_relations, _ = load_index_context(stored, list(states))
+        
+        relinked = link_cross_language(stub_nodes, stub_relations, matchers)
+        assert [(r.source_id, r.target_id, r.properties) for r in relinked] == \
+            [(r.source_id, r.target_id, r.properties) for r in expected]
+
+
+CTYPES_SOURCE = (
+    "import ctypes\n"
+    "\n"
+    "lib = ctypes.CDLL('./libmath.so')\n"
+    "\n"
+    "\n"
+    "def add(a, b):\n"
+    "    return lib.Add(a, b)\n"
+    "\n"
+    "\n"
+    "def scale(x):\n"
+    "    lib.scale.argtypes = [ctypes.c_double]\n"
+    "    return lib.scale(x)\n"
+)
+
+
+class TestFfiMatcher:
+    
+    def test_ctypes_calls_to_exported_symbols(self, tmp_path):
+        caller = tmp_path / "math_client.py"
+        caller.write_text(CTYPES_SOURCE)
+        functions = [
+            CodeNode(f"Function:{caller}:add:6", "Function", "add", str(caller), 6, 7),
+            CodeNode(f"Function:{caller}:scale:10", "Function", "scale", str(caller), 10, 12),
+            # cgo `//export Add`
+            CodeNode("Function:math.go:add:8", "Function", "add", "math.go", 8, 10, properties={"export_name": "Add"}),
+            CodeNode("Function:scale.rs:scale:2", "Function", "scale", "scale.rs", 2, 4,
+                     properties={"attributes": ["no_mangle"]}),
+            CodeNode("Function:scale.c:scale:1", "Function", "scale", "scale.c", 1, 3),
+            # Same language as the caller: never an FFI target
+            CodeNode("Function:util.py:scale:1", "Function", "scale", "util.py", 1, 2),
+        ]
+        nodes = {node.node_id: node for node in functions}
+        
+        linked = link_cross_language(nodes, [], [FfiMatcher()])
+        
+        assert nodes[f"Function:{caller}:scale:10"].properties["ffi_calls"] == ["scale"]
+        assert [(r.source_id.split(":")[-2], r.target_id, r.properties["confidence"]) for r in linked] == [
+            ("add", "Function:math.go:add:8", 0.9),
+            ("scale", "Function:scale.c:scale:1", 0.3),
+            ("scale", "Function:scale.rs:scale:2", 0.45),
+        ]
+        assert {r.properties["matcher"] for r in linked} == {"ffi"}
+    
+    def test_cgo_calls(self):
+        go_caller = CodeNode("Function:main.go:run:5", "Function", "run", "main.go", 5, 7)
+        external = CodeNode("external:C:checksum", "ExternalFunction", "checksum", "", 0,
+                            properties={"import_path": "C", "placeholder": True})
+        c_function = CodeNode("Function:checksum.c:checksum:3", "Function", "checksum", "checksum.c", 3, 9)
+        nodes = {node.node_id: node for node in (go_caller, external, c_function)}
+        
+        (edge,) = link_cross_language(nodes, [CodeRelation(go_caller.node_id, external.node_id, "CALLS")],
+                                      [FfiMatcher()])
+        
+        assert (edge.source_id, edge.target_id, edge.properties["symbol"]) == (
+            go_caller.node_id, c_function.node_id, "checksum",
+        )
+
+
+class TestMatcherSelection:
+    
+    def test_matcher_names(self, monkeypatch):
+        monkeypatch.delenv("CROSS_LANG_MATCHERS", raising=False)
+        
+        assert [m.name for m in get_matchers()] == ["grpc", "ffi"]
+        assert [m.name for m in get_matchers("ffi")] == ["ffi"]
+        assert get_matchers("") == []
+        with pytest.raises(ValueError, match="Unknown cross-language matcher"):
+            get_matchers("thrift")
+
+
+class TestEndToEnd:
+    """The whole fixture, Go included, parsed with the ast-grep adapters."""
+    
+    def test_python_client_to_go_handler(self, monkeypatch, tmp_path):
+        pytest.importorskip("ast_grep_py")
+        monkeypatch.setenv("USE_AST_GREP", "true")
+        monkeypatch.setenv("AST_GREP_LANGUAGES", "python,go")
+        monkeypatch.setenv("PARALLEL_INDEXING_ENABLED", "false")
+        monkeypatch.delenv("CROSS_LANG_MATCHERS", raising=False)
+        from src.main import CodebaseKnowledgeGraph
+        
+        shutil.copytree(FIXTURE_DIR, tmp_path / "grpc_sample")
+        kg = CodebaseKnowledgeGraph(store=InMemoryGraphStore(), embedding_provider=ConstantEmbeddingProvider())
+        kg.process_codebase(str(tmp_path / "grpc_sample"))
+        
+        (handler,) = [record["properties"] for record in kg.db.find_nodes(name="SayHello", label="Method")
+                      if record["properties"]["file_path"].endswith(os.path.join("server", "server.go"))]
+        result = find_paths(kg.db, "greet", handler["id"])
+        
+        assert result["status"] == "ok"
+        hops = result["paths"][0]["hops"]
+        assert [hop["to"]["node_type"] for hop in hops] == ["Rpc", "Method"]
+        assert ("greet", "SayHello", "client") in _stored_links(kg.db)
@@ -0,0 +1,275 @@
+"""
+C# adapter tests.
+
+Parses the C# files in tests/fixtures/multi_lang_sample through
+MultiLanguageParser (so the second pass runs). The csharp/ tree holds the
+namespaces Shop.Core (with a nested Shop.Core.Money) and Shop.Orders;
+Order is a partial class split over Order.cs and Order.Pricing.cs.
+TestPartialMerge runs the second pass on hand-built nodes, so it does not
+need ast-grep.
+"""
+
+import os
+import sys
+import pytest
+
+# Add project root to Python path
+sys.path.insert(0, os.path.dirname(os.path.dirname(os.path.abspath(__file__))))
+
+from src.ast_parser.parser import ASTParser, CodeNode, CodeRelation
+
+
+FIXTURE_DIR = os.path.join(os.path.dirname(os.path.abspath(__file__)), "fixtures", "multi_lang_sample")
+CSHARP_DIR = os.path.join(FIXTURE_DIR, "csharp")
+
+
+def _parse(languages):
+    pytest.importorskip("ast_grep_py")
+    from src.ast_parser.multi_parser import MultiLanguageParser
+    
+    coordinator = MultiLanguageParser(
+        use_ast_grep=True,
+        ast_grep_languages=languages,
+        ast_grep_fallback=False
+    )
+    return coordinator.parse_directory(FIXTURE_DIR, build_index=True)
+
+
+def _type_node(nodes, qualified_name):
+    matches = [n for n in nodes.values() if n.properties.get("qualified_name") == qualified_name]
+    assert len(matches) == 1, f"expected one {qualified_name} node, got {len(matches)}"
+    return matches[0]
+
+
+def _targets(nodes, relations, source, relation_type):
+    """Names of the nodes source points at with relation_type."""
+    return {nodes[r.target_id].name for r in relations
+            if r.source_id == source.node_id and r.relation_type == relation_type}
+
+
+def _members(nodes, relations, type_node, node_type):
+    return [nodes[r.target_id] for r in relations
+            if r.source_id == type_node.node_id and r.relation_type == "DEFINES"
+            and nodes[r.target_id].node_type == node_type]
+
+
+@pytest.fixture
+def csharp_results():
+    """Parse the fixture tree with the C# adapter enabled."""
+    return _parse(['csharp'])
+
+
+class TestCSharpDeclarations:
+    """Types, members, properties and attributes."""
+    
+    def test_type_kinds(self, csharp_results):
+        nodes, relations = csharp_results
+        
+        auditable = _type_node(nodes, "Shop.Core.IAuditable")
+        assert (auditable.node_type, auditable.properties["kind"]) == ("Interface", "interface")
+        assert auditable.properties["methods"] == ["Touch(string)"]
+        entity = _type_node(nodes, "Shop.Core.EntityBase")
+        assert entity.properties["modifiers"] == ["public", "abstract"]
+        assert entity.properties["doc"] == "Base class of every persisted entity."
+        
+        amount = _type_node(nodes, "Shop.Core.Money.Amount")
+        assert (amount.node_type, amount.properties["kind"]) == ("Class", "record struct")
+        components = _members(nodes, relations, amount, "ClassVariable")
+        assert [(f.name, f.properties["type"], f.properties.get("component")) for f in components] == [
+            ("Value", "decimal", True), ("Currency", "string", True),
+        ]
+        
+        status = _type_node(nodes, "Shop.Orders.Order+Status")
+        assert (status.node_type, status.name) == ("Enum", "Order+Status")
+        assert status.properties["members"] == ["Open", "Paid", "Shipped"]
+    
+    def test_methods_and_overloads(self, csharp_results):
+        nodes, relations = csharp_results
+        order = _type_node(nodes, "Shop.Orders.Order")
+        methods = _members(nodes, relations, order, "Method")
+        
+        assert sorted(m.properties["signature"] for m in methods) == [
+            "CompareTo(Order)", "Order(string)", "Total()", "Total(decimal)", "Touch(string)",
+            "TryApply(string,out decimal,params string[])", "Validate()",
+        ]
+        assert len({m.node_id for m in methods if m.name == "Total"}) == 2
+        assert [m.name for m in methods if m.properties.get("constructor")] == ["Order"]
+        validate = next(m for m in methods if m.name == "Validate")
+        assert (validate.properties["return_type"], validate.properties["modifiers"]) == ("void", ["public", "override"])
+    
+    def test_properties_and_fields(self, csharp_results):
+        nodes, relations = csharp_results
+        order = _type_node(nodes, "Shop.Orders.Order")
+        members = {f.name: f.properties for f in _members(nodes, relations, order, "ClassVariable")}
+        
+        assert "property" not in members["_lines"] and members["_lines"]["modifiers"] == ["private", "readonly"]
+        assert (members["Customer"]["getter"], members["Customer"]["init"], members["Customer"]["auto"]) == \
+            (True, True, True)
+        assert members["ModifiedAt"]["setter"] is True and members["ModifiedAt"]["auto"] is True
+        # Expression-bodied: a getter and nothing else
+        assert (members["LineCount"]["getter"], members["LineCount"].get("setter")) == (True, None)
+        assert members["State"]["setter"] is True and members["State"].get("auto") is None
+        
+        # Interface properties have no accessor bodies but are not auto-implemented
+        modified = next(f for f in _members(nodes, relations, _type_node(nodes, "Shop.Core.IAuditable"),
+                                            "ClassVariable") if f.name == "ModifiedAt")
+        assert modified.properties["getter"] is True and modified.properties.get("auto") is None
+    
+    def test_attributes(self, csharp_results):
+        nodes, relations = csharp_results
+        order = _type_node(nodes, "Shop.Orders.Order")
+        
+        assert order.properties["attributes"] == [
+            'Obsolete("Use the pricing service")', "AggregateRoot", "Serializable",
+        ]
+        # AggregateRoot resolves to AggregateRootAttribute through the using of Shop.Core
+        assert _targets(nodes, relations, order, "DECORATED_BY") == {"AggregateRootAttribute"}
+
+
+class TestCSharpPartialTypes:
+    """A partial class is one node declared in several files."""
+    
+    def test_parts_are_merged(self, csharp_results):
+        nodes, relations = csharp_results
+        order = _type_node(nodes, "Shop.Orders.Order")
+        
+        assert [os.path.basename(path) for path in order.properties["declared_in"]] == [
+            "Order.Pricing.cs", "Order.cs",
+        ]
+        assert order.properties["modifiers"] == ["public", "partial"]
+        assert set(order.properties["bases"]) == {"EntityBase", "IAuditable", "IComparable<Order>", "IDiscountable"}
+        # Nested types of every part hang off the merged node
+        assert "Order+Status" in _targets(nodes, relations, order, "DEFINES")
+        
+        contains = {os.path.basename(nodes[r.source_id].file_path) for r in relations
+                    if r.target_id == order.node_id and r.relation_type == "CONTAINS"}
+        assert contains == {"Order.Pricing.cs", "Order.cs"}
+
+
+class TestCSharpHierarchy:
+    """EXTENDS, IMPLEMENTS and INHERITS_FROM edges."""
+    
+    def test_bases(self, csharp_results):
+        nodes, relations = csharp_results
+        order = _type_node(nodes, "Shop.Orders.Order")
+        
+        assert _targets(nodes, relations, order, "EXTENDS") == {"EntityBase"}
+        assert _targets(nodes, relations, order, "IMPLEMENTS") == {"IAuditable", "IDiscountable"}
+        # System.IComparable<T> is not indexed
+        (external,) = [r for r in relations if r.source_id == order.node_id and r.relation_type == "INHERITS_FROM"]
+        assert external.target_id == "external_type:IComparable"
+        assert nodes[external.target_id].node_type == "ExternalType"
+
+
+class TestCSharpNamespaces:
+    """Package membership and IMPORTS edges."""
+    
+    def test_namespaces_are_packages(self, csharp_results):
+        nodes, relations = csharp_results
+        
+        def package_files(package_id):
+            return {os.path.basename(nodes[r.target_id].file_path) for r in relations
+                    if r.source_id == package_id and r.relation_type == "CONTAINS"}
+        
+        assert package_files("package:Shop.Orders") == {"Order.cs", "Order.Pricing.cs"}
+        # A file with nested namespaces belongs to each of them
+        assert package_files("package:Shop.Core") == package_files("package:Shop.Core.Money") == {"EntityBase.cs"}
+        entity_file = nodes[f"file:{os.path.join(CSHARP_DIR, 'Core', 'EntityBase.cs')}"]
+        assert entity_file.properties["namespaces"] == ["Shop.Core", "Shop.Core.Money"]
+    
+    def test_usings(self, csharp_results):
+        nodes, relations = csharp_results
+        
+        def imports(file_name):
+            file_id = f"file:{os.path.join(CSHARP_DIR, 'Orders', file_name)}"
+            return {r.target_id: r.properties for r in relations
+                    if r.source_id == file_id and r.relation_type == "IMPORTS"}
+        
+        order_imports = imports("Order.cs")
+        assert {"package:Shop.Core", "external_package:System"} <= set(order_imports)
+        # The alias points at the record struct itself
+        amount = _type_node(nodes, "Shop.Core.Money.Amount")
+        assert order_imports[amount.node_id]["alias"] == "Money"
+        assert imports("Order.Pricing.cs")["external_package:System"]["static"] is True
+
+
+class TestPartialMerge:
+    """The second pass on hand-built nodes, the way the adapter leaves them."""
+    
+    PRICING = "/repo/Orders/Order.Pricing.cs"
+    ORDER = "/repo/Orders/Order.cs"
+    CORE = "/repo/Core/EntityBase.cs"
+    
+    def _node(self, parser, node_type, name, file_path, line_no, **properties):
+        node = CodeNode(f"{node_type.lower()}:{file_path}:{name}:{line_no}", node_type, name, file_path, line_no,
+                        properties=properties)
+        parser.nodes[node.node_id] = node
+        return node.node_id
+    
+    def _base(self, source_id, name, candidates):
+        return {"type": "BASE_TYPE", "source_id": source_id, "imported_module": candidates[0][0],
+                "imported_name": candidates[0][1], "candidates": candidates, "original_name": name,
+                "external_name": name.split("<")[0]}
+    
+    @pytest.fixture
+    def merged(self):
+        parser = ASTParser()
+        for file_path, namespace in ((self.PRICING, "Shop.Orders"), (self.ORDER, "Shop.Orders"),
+                                     (self.CORE, "Shop.Core")):
+            parser.nodes[f"file:{file_path}"] = CodeNode(f"file:{file_path}", "File", os.path.basename(file_path),
+                                                         file_path, 0,
+                                                         properties={"package": namespace, "import_path": namespace})
+        pricing = self._node(parser, "Class", "Order", self.PRICING, 6, modifiers=["public", "partial"],
+                             declared_in=[self.PRICING], bases=["IDiscountable"])
+        order = self._node(parser, "Class", "Order", self.ORDER, 11, modifiers=["public", "partial"],
+                           declared_in=[self.ORDER], bases=["EntityBase", "IComparable<Order>"])
+        total = self._node(parser, "Method", "Total", self.PRICING, 8)
+        touch = self._node(parser, "Method", "Touch", self.ORDER, 30)
+        entity = self._node(parser, "Class", "EntityBase", self.CORE, 8)
+        discountable = self._node(parser, "Interface", "IDiscountable", self.PRICING, 27)
+        for source, target, relation_type in ((f"file:{self.PRICING}", pricing, "CONTAINS"),
+                                              (f"file:{self.ORDER}", order, "CONTAINS"),
+                                              (pricing, total, "DEFINES"), (order, touch, "DEFINES"),
+                                              (f"file:{self.CORE}", entity, "CONTAINS"),
+                                              (f"file:{self.PRICING}", discountable, "CONTAINS")):
+            parser._add_relation(CodeRelation(source, target, relation_type))
+        parser.module_definitions = {
+            "csharp:Shop.Orders": {"Order": order, "IDiscountable": discountable},
+            "csharp:Shop.Core": {"EntityBase": entity},
+            "Order": {"Order": order}, "Order.Pricing": {"Order": pricing},
+        }
+        parser.pending_imports = [
+            {"type": "PARTIAL_TYPE", "source_id": pricing, "imported_module": "csharp:Shop.Orders",
+             "imported_name": "Order"},
+            {"type": "PARTIAL_TYPE", "source_id": order, "imported_module": "csharp:Shop.Orders",
+             "imported_name": "Order"},
+            self._base(pricing, "IDiscountable", [["csharp:Shop.Orders", "Order+IDiscountable"],
+                                                  ["csharp:Shop.Orders", "IDiscountable"]]),
+            self._base(order, "EntityBase", [["csharp:Shop.Orders", "EntityBase"], ["csharp:Shop.Core", "EntityBase"]]),
+            self._base(order, "IComparable<Order>", [["csharp:Shop.Orders", "IComparable"],
+                                                     ["csharp:System", "IComparable"]]),
+        ]
+        parser._process_pending_imports()
+        return parser, pricing, order
+    
+    def test_first_part_is_canonical(self, merged):
+        parser, pricing, order = merged
+        
+        assert order not in parser.nodes
+        canonical = parser.nodes[pricing]
+        assert canonical.properties["declared_in"] == [self.PRICING, self.ORDER]
+        assert canonical.properties["bases"] == ["IDiscountable", "EntityBase", "IComparable<Order>"]
+        assert parser.module_definitions["csharp:Shop.Orders"]["Order"] == pricing
+        assert parser.module_definitions["Order"]["Order"] == pricing
+    
+    def test_edges_move_to_the_canonical_node(self, merged):
+        parser, pricing, order = merged
+        edges = {(r.source_id, r.target_id, r.relation_type) for r in parser.relations}
+        
+        assert not any(order in (source, target) for source, target, _ in edges)
+        assert (f"file:{self.ORDER}", pricing, "CONTAINS") in edges
+        assert (pricing, f"method:{self.ORDER}:Touch:30", "DEFINES") in edges
+        assert (pricing, f"class:{self.CORE}:EntityBase:8", "EXTENDS") in edges
+        assert (pricing, f"interface:{self.PRICING}:IDiscountable:27", "IMPLEMENTS") in edges
+        assert (pricing, "external_type:IComparable", "INHERITS_FROM") in edges
+        assert parser.nodes["external_type:IComparable"].properties["placeholder"] is True
@@ -0,0 +1,177 @@
+"""
+detect_cycles tests.
+
+The package graph is seeded into an InMemoryGraphStore: api -> auth ->
+users -> api is a cycle of three, users <-> store one of two, and billing
+only depends on other packages. The end-to-end test indexes a Python tree
+whose packages import each other.
+"""
+
+import asyncio
+import json
+import os
+import sys
+import time
+
+import pytest
+
+# Add project root to Python path
+sys.path.insert(0, os.path.dirname(os.path.dirname(os.path.abspath(__file__))))
+
+from src.analysis import detect_cycles, format_cycles_report, strongly_connected_components
+from src.graph_store import InMemoryGraphStore
+from conftest import ConstantEmbeddingProvider
+
+PACKAGES = ("internal/api", "internal/auth", "internal/users", "internal/store", "internal/billing")
+
+# (file, package, imported package, line)
+IMPORTS = [
+    ("internal/api/routes.go", "internal/api", "internal/auth", 4),
+    ("internal/api/handlers.go", "internal/api", "internal/auth", 5),
+    ("internal/auth/token.go", "internal/auth", "internal/users", 3),
+    ("internal/auth/session.go", "internal/auth", "internal/users", 6),
+    ("internal/users/notify.go", "internal/users", "internal/api", 7),
+    ("internal/users/repo.go", "internal/users", "internal/store", 3),
+    ("internal/store/cache.go", "internal/store", "internal/users", 8),
+    ("internal/billing/invoice.go", "internal/billing", "internal/api", 3),
+]
+
+
+def _cycle_store():
+    """A store holding PACKAGES, their files, the IMPORTS and the aggregated DEPENDS_ON edges."""
+    store = InMemoryGraphStore()
+    store.batch_create_nodes(
+        [{"labels": ["Base", "Package"],
+          "properties": {"id": f"package:example.com/shop/{path}", "name": os.path.basename(path), "file_path": "",
+                         "path": path, "import_path": f"example.com/shop/{path}"}}
+         for path in PACKAGES]
+        + [{"labels": ["Base", "File"],
+            "properties": {"id": f"file:{file_path}", "name": os.path.basename(file_path), "file_path": file_path}}
+           for file_path, _, _, _ in IMPORTS]
+    )
+    relationships = []
+    counts = {}
+    for file_path, package, imported, line_no in IMPORTS:
+        source, target = f"package:example.com/shop/{package}", f"package:example.com/shop/{imported}"
+        relationships.append({"start_node_id": source, "end_node_id": f"file:{file_path}", "type": "CONTAINS",
+                              "properties": {}})
+        relationships.append({"start_node_id": f"file:{file_path}", "end_node_id": target, "type": "IMPORTS",
+                              "properties": {"import_path": f"example.com/shop/{imported}", "line_no": line_no}})
+        counts[(source, target)] = counts.get((source, target), 0) + 1
+    for (source, target), count in counts.items():
+        relationships.append({"start_node_id": source, "end_node_id": target, "type": "DEPENDS_ON",
+                              "properties": {"imports": count, "files": count, "structural": True}})
+    store.batch_create_relationships(relationships)
+    return store
+
+
+def _short(label):
+    return label.replace("example.com/shop/internal/", "")
+
+
+def _cycles(result):
+    return [[_short(label) for label in cycle["packages"]]
+            for component in result["components"] for cycle in component["cycles"]]
+
+
+class TestComponents:
+    
+    def test_tarjan(self):
+        graph = {"a": ["b"], "b": ["c"], "c": ["a", "d"], "d": ["e"], "e": ["d"], "f": ["a", "missing"]}
+        assert strongly_connected_components(graph) == [["a", "b", "c"], ["d", "e"], ["f"]]
+    
+    def test_large_graph_without_recursion(self):
+        # A ring of 5000 packages plus chords would overflow a recursive implementation
+        count = 5000
+        graph = {f"p{i:05d}": [f"p{(i + 1) % count:05d}", f"p{(i * 7) % count:05d}"] for i in range(count)}
+        started = time.monotonic()
+        (component,) = strongly_connected_components(graph)
+        assert len(component) == count
+        assert time.monotonic() - started < 5
+
+
+class TestDetectCycles:
+    
+    def test_cycles_with_file_imports(self):
+        result = detect_cycles(_cycle_store())
+        
+        assert result["status"] == "ok"
+        assert (result["package_count"], result["cycle_count"]) == (5, 2)
+        (component,) = result["components"]
+        assert [_short(label) for label in component["packages"]] == ["api", "auth", "store", "users"]
+        assert _cycles(result) == [["api", "auth", "users"], ["store", "users"]]
+        
+        hops = component["cycles"][0]["hops"]
+        assert [(_short(hop["from"]), _short(hop["to"])) for hop in hops] == [
+            ("api", "auth"), ("auth", "users"), ("users", "api"),
+        ]
+        assert [(entry["file"], entry["line_no"]) for entry in hops[0]["imports"]] == [
+            ("internal/api/handlers.go", 5), ("internal/api/routes.go", 4),
+        ]
+        # users -> api rests on a single import, the cheapest one to break
+        assert [hop["break_candidate"] for hop in hops] == [False, False, True]
+        assert hops[2]["imports"][0]["imports"] == "example.com/shop/internal/api"
+    
+    def test_scope_and_min_length(self):
+        db = _cycle_store()
+        
+        assert _cycles(detect_cycles(db, scope="internal/store")) == [["store", "users"]]
+        assert _cycles(detect_cycles(db, scope="example.com/shop/internal/api")) == [["api", "auth", "users"]]
+        assert detect_cycles(db, scope="internal/billing")["components"] == []
+        assert _cycles(detect_cycles(db, min_length=3)) == [["api", "auth", "users"]]
+        with pytest.raises(ValueError, match="min_length"):
+            detect_cycles(db, min_length=0)
+    
+    def test_text_report(self):
+        report = format_cycles_report(detect_cycles(_cycle_store()))
+        
+        assert report.startswith("Found 2 package import cycles in 1 group of")
+        assert ("  Cycle 1 (length 3): example.com/shop/internal/api -> example.com/shop/internal/auth -> "
+                "example.com/shop/internal/users -> example.com/shop/internal/api") in report
+        assert "internal/users/notify.go:7 imports example.com/shop/internal/api" in report
+        assert "(1 import)  <- fewest imports, break here" in report
+        assert format_cycles_report(detect_cycles(InMemoryGraphStore())) == \
+            "No package import cycles among 0 packages.\n"
+
+
+class TestIndexedCodebase:
+    
+    def test_python_packages_importing_each_other(self, tmp_path, monkeypatch):
+        monkeypatch.setenv("USE_AST_GREP", "false")
+        monkeypatch.setenv("ENABLE_JS_TS_PARSING", "false")
+        monkeypatch.setenv("PARALLEL_INDEXING_ENABLED", "false")
+        for path, source in {
+            "orders/__init__.py": "",
+            "orders/service.py": "from payments.gateway import charge\n",
+            "payments/__init__.py": "",
+            "payments/gateway.py": "from orders.service import refund\n\n\ndef charge():\n    return 1\n",
+        }.items():
+            (tmp_path / path).parent.mkdir(parents=True, exist_ok=True)
+            (tmp_path / path).write_text(source)
+        
+        from src.main import CodebaseKnowledgeGraph
+        
+        store = InMemoryGraphStore()
+        CodebaseKnowledgeGraph(store=store, embedding_provider=ConstantEmbeddingProvider()).process_codebase(str(tmp_path))
+        
+        result = detect_cycles(store, scope=str(tmp_path / "orders"))
+        (cycle,) = [cycle for component in result["components"] for cycle in component["cycles"]]
+        assert [os.path.basename(label) for label in cycle["packages"]] == ["orders", "payments"]
+        assert {hop["imports"][0]["imports"] for hop in cycle["hops"]} == {
+            "payments.gateway.charge", "orders.service.refund",
+        }
+
+
+class TestDetectCyclesTool:
+    
+    @pytest.fixture
+    def tool(self, make_server):
+        server = make_server(store=_cycle_store())
+        return server.mcp.tools["detect_cycles"]
+    
+    def test_json_with_report(self, tool):
+        result = json.loads(asyncio.run(tool(scope="internal/store")))
+        assert _cycles(result) == [["store", "users"]]
+        assert result["report"].startswith("Found 1 package import cycle under internal/store")
+        
+        assert "min_length" in json.loads(asyncio.run(tool(min_length=0)))["error"]
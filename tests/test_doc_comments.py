@@ -0,0 +1,248 @@
+"""
+Doc comment tests.
+
+Checks the shared comment normalization and JSDoc parsing, the "doc"
+properties the parsers attach for the files in tests/fixtures/doc_comments
+(license headers and file-level comments on the File node, not on the
+first declaration) and the truncated docs returned by find_symbol and
+semantic_search. JavaScript files are parsed both by the parser of the
+default USE_AST_GREP=false and by the ast-grep adapter.
+"""
+
+import asyncio
+import json
+import os
+import sys
+
+import pytest
+
+# Add project root to Python path
+sys.path.insert(0, os.path.dirname(os.path.dirname(os.path.abspath(__file__))))
+
+from src.ast_parser.doc_comments import normalize_comment, parse_jsdoc, truncate_doc
+from src.ast_parser.parser import ASTParser
+from src.graph_store import InMemoryGraphStore
+from src.parallel.pipeline import ParserSettings, create_parser
+from conftest import ConstantEmbeddingProvider
+
+FIXTURE_DIR = os.path.join(os.path.dirname(os.path.abspath(__file__)), "fixtures", "doc_comments")
+
+
+def _find(nodes, name, node_type):
+    matches = [n for n in nodes.values() if n.name == name and n.node_type == node_type]
+    assert len(matches) == 1, f"expected one {node_type} named {name}, found {len(matches)}"
+    return matches[0]
+
+
+def _file(nodes, file_name):
+    return _find(nodes, file_name, "File")
+
+
+class TestNormalization:
+    
+    def test_line_comments(self):
+        assert normalize_comment("// Person represents a user.\n// It has a name.") == \
+            "Person represents a user.\nIt has a name."
+        # The space after the marker goes, deeper indentation (Go code blocks) stays
+        assert normalize_comment("// Example:\n//\n//\tp := New()\n//") == "Example:\n\n\tp := New()"
+        assert normalize_comment("#   hello\n#     world") == "hello\n  world"
+        # Rust inner doc comments
+        assert normalize_comment("//! Shapes.\n//!\n//! More.") == "Shapes.\n\nMore."
+    
+    def test_block_comments(self):
+        assert normalize_comment("/**\n * Adds numbers.\n *   indented\n */") == "Adds numbers.\n  indented"
+        assert normalize_comment("/** one line */") == "one line"
+        assert normalize_comment("/*\n    Block\n      code\n*/") == "Block\n  code"
+        assert normalize_comment("/**/") == ""
+        assert normalize_comment("/*! Module doc. */") == "Module doc."
+    
+    def test_jsdoc_tags(self):
+        doc = normalize_comment(
+            "/**\n * Renders a widget.\n * @param {string} label - Text shown\n"
+            " * @param {number} [width=10] Width in\n *   characters\n * @arg extra\n"
+            " * @returns {string} The widget\n */"
+        )
+        parsed = parse_jsdoc(doc)
+        assert parsed["description"] == "Renders a widget."
+        assert parsed["params"] == [
+            {"name": "label", "type": "string", "description": "Text shown"},
+            {"name": "width", "type": "number", "description": "Width in characters",
+             "optional": True, "default": "10"},
+            {"name": "extra", "type": None, "description": ""},
+        ]
+        assert parsed["returns"] == {"type": "string", "description": "The widget"}
+        assert parse_jsdoc("Just text.")["returns"] is None
+    
+    def test_truncate(self, monkeypatch):
+        assert truncate_doc("abcdef", 4) == "abcd..."
+        assert truncate_doc("abc", 4) == "abc"
+        assert truncate_doc(None) is None
+        monkeypatch.setenv("DOC_MAX_LENGTH", "2")
+        assert truncate_doc("abcdef") == "ab..."
+        monkeypatch.setenv("DOC_MAX_LENGTH", "0")
+        assert truncate_doc("abcdef") is None
+
+
+class TestPythonDocs:
+    
+    def test_module_class_and_function_docstrings(self):
+        parser = ASTParser()
+        nodes, _ = parser.parse_file(os.path.join(FIXTURE_DIR, "report.py"))
+        
+        assert _file(nodes, "report.py").properties["doc"] == "Reports about people."
+        assert _find(nodes, "Report", "Class").properties["doc"] == "A report.\n\n    Indented example."
+        assert _find(nodes, "render", "Method").properties["doc"] == "Render the report."
+        assert _find(nodes, "build", "Function").properties["doc"] == "Build a report."
+        assert "docstring" not in _find(nodes, "build", "Function").properties
+    
+    def test_ast_grep_adapter_matches_legacy_parser(self):
+        pytest.importorskip("ast_grep_py")
+        from src.ast_parser.adapters.python_adapter import PythonAstGrepAdapter
+        
+        legacy, _ = ASTParser().parse_file(os.path.join(FIXTURE_DIR, "report.py"))
+        nodes, _ = PythonAstGrepAdapter().parse_file(os.path.join(FIXTURE_DIR, "report.py"))
+        docs = lambda parsed: {(n.node_type, n.name): n.properties.get("doc") for n in parsed.values()
+                               if n.node_type in ("File", "Class", "Method", "Function")}
+        assert docs(nodes) == docs(legacy)
+
+
+class TestGoDocs:
+    
+    @pytest.fixture
+    def nodes(self):
+        pytest.importorskip("ast_grep_py")
+        from src.ast_parser.adapters.go_adapter import GoAdapter
+        
+        nodes, _ = GoAdapter().parse_file(os.path.join(FIXTURE_DIR, "store.go"))
+        return nodes
+    
+    def test_declaration_docs(self, nodes):
+        assert _find(nodes, "Person", "Class").properties["doc"] == "Person represents a user\nof the system."
+        assert _find(nodes, "Greeter", "Interface").properties["doc"] == "Greeter greets people."
+        assert _find(nodes, "GetName", "Method").properties["doc"] == "GetName returns the name."
+        # Directives are dropped, the indented example stays
+        assert _find(nodes, "NewPerson", "Function").properties["doc"] == \
+            'NewPerson creates a Person.\n\n\tp := NewPerson("ann")'
+    
+    def test_grouped_types_and_undocumented(self, nodes):
+        assert _find(nodes, "ID", "Class").properties["doc"] == "ID identifies a person."
+        assert "doc" not in _find(nodes, "Age", "Class").properties
+        # The trailing comment of `var count` is separated by a blank line
+        assert "doc" not in _find(nodes, "Undocumented", "Function").properties
+    
+    def test_license_header_goes_to_file(self, nodes):
+        file_node = _file(nodes, "store.go")
+        assert file_node.properties["doc"] == "Package store keeps people in memory."
+        assert file_node.properties["header"] == (
+            "Copyright 2024 The Example Authors. All rights reserved.\n"
+            "Use of this source code is governed by a BSD-style license."
+        )
+        assert not any("Copyright" in (n.properties.get("doc") or "") for n in nodes.values()
+                       if n.node_type != "File")
+
+
+class TestTypeScriptDocs:
+    
+    @pytest.fixture
+    def nodes(self):
+        pytest.importorskip("ast_grep_py")
+        from src.ast_parser.adapters.typescript_adapter import TypeScriptAdapter
+        
+        nodes, _ = TypeScriptAdapter().parse_file(os.path.join(FIXTURE_DIR, "widget.ts"))
+        return nodes
+    
+    def test_jsdoc_fields(self, nodes):
+        render = _find(nodes, "render", "Function")
+        assert render.properties["doc"].startswith("Renders a widget.\n@param {string} label")
+        assert [p["name"] for p in json.loads(render.properties["doc_params"])] == ["label", "width"]
+        assert json.loads(render.properties["doc_returns"]) == {"type": "string", "description": "The rendered widget"}
+    
+    def test_decorated_and_exported_declarations(self, nodes):
+        assert _find(nodes, "Factory", "Class").properties["doc"] == "Builds widgets."
+        assert _find(nodes, "build", "Method").properties["doc"] == "Builds one widget.\n@returns the widget"
+        assert _find(nodes, "count", "ClassVariable").properties["doc"] == "Number of widgets built."
+        assert _find(nodes, "Widget", "Interface").properties["doc"] == "Shape of a widget."
+        # `//` comments are not JSDoc
+        assert "doc" not in _find(nodes, "plain", "Function").properties
+    
+    def test_license_header_goes_to_file(self, nodes):
+        file_node = _file(nodes, "widget.ts")
+        assert file_node.properties["header"] == "@license MIT\nCopyright (c) 2024 Example"
+        assert "doc" not in file_node.properties
+    
+    def test_file_overview(self):
+        pytest.importorskip("ast_grep_py")
+        from src.ast_parser.adapters.javascript_adapter import JavaScriptAstGrepAdapter
+        
+        nodes, _ = JavaScriptAstGrepAdapter().parse_file(os.path.join(FIXTURE_DIR, "overview.js"))
+        assert _file(nodes, "overview.js").properties["doc"] == "@fileoverview Helpers for formatting."
+        assert _find(nodes, "format", "Function").properties["doc"] == "Formats a value."
+
+
+class TestJavaScriptDocs:
+    
+    @pytest.fixture(params=["default", "ast-grep"])
+    def parse_file(self, request):
+        """parse_file of the parser USE_AST_GREP=false selects, or of the ast-grep adapter."""
+        def parse(file_name):
+            file_path = os.path.join(FIXTURE_DIR, file_name)
+            if request.param == "default":
+                parser = create_parser(file_path, ParserSettings())
+            else:
+                from src.ast_parser.adapters.javascript_adapter import JavaScriptAstGrepAdapter
+                parser = JavaScriptAstGrepAdapter()
+            nodes, _ = parser.parse_file(file_path)
+            return nodes
+        
+        pytest.importorskip("tree_sitter_javascript" if request.param == "default" else "ast_grep_py")
+        return parse
+    
+    def test_jsdoc_fields(self, parse_file):
+        nodes = parse_file("widget.js")
+        render = _find(nodes, "render", "Function")
+        
+        assert render.properties["doc"].startswith("Renders a widget.\n@param {string} label")
+        assert [p["name"] for p in json.loads(render.properties["doc_params"])] == ["label", "width"]
+        assert json.loads(render.properties["doc_returns"]) == {"type": "string", "description": "The rendered widget"}
+        # The block above `const shout = (...) => ...` documents the arrow function
+        assert json.loads(_find(nodes, "shout", "Function").properties["doc_params"]) == [
+            {"name": "label", "type": "string", "description": ""}]
+    
+    def test_classes_methods_and_plain_comments(self, parse_file):
+        nodes = parse_file("widget.js")
+        
+        assert _find(nodes, "Factory", "Class").properties["doc"] == "Builds widgets."
+        assert _find(nodes, "build", "Method").properties["doc"] == "Builds one widget.\n@returns the widget"
+        # `//` comments are not JSDoc
+        assert "doc" not in _find(nodes, "plain", "Function").properties
+    
+    def test_file_comments(self, parse_file):
+        file_node = _file(parse_file("widget.js"), "widget.js")
+        assert file_node.properties["header"] == "@license MIT\nCopyright (c) 2024 Example"
+        assert "doc" not in file_node.properties
+        
+        nodes = parse_file("overview.js")
+        assert _file(nodes, "overview.js").properties["doc"] == "@fileoverview Helpers for formatting."
+        assert _find(nodes, "format", "Function").properties["doc"] == "Formats a value."
+
+
+class TestToolDocs:
+    
+    @pytest.fixture
+    def server(self, make_server):
+        store = InMemoryGraphStore()
+        store.batch_create_nodes([{
+            "labels": ["Base", "Function"],
+            "properties": {"id": "function:a.py:charge:1", "name": "charge", "file_path": "a.py", "line_no": 1,
+                           "doc": "Charge the customer for the order.", "embedding": [1.0]},
+        }])
+        # Every text embeds to the vector of the node, so semantic_search matches it
+        return make_server(store=store, embedding_provider=ConstantEmbeddingProvider())
+    
+    def test_find_symbol_and_semantic_search_truncate_docs(self, server, monkeypatch):
+        monkeypatch.setenv("DOC_MAX_LENGTH", "10")
+        (symbol,) = json.loads(asyncio.run(server.mcp.tools["find_symbol"]("charge")))
+        assert symbol["doc"] == "Charge the..."
+        
+        found = json.loads(asyncio.run(server.mcp.tools["semantic_search"]("charging")))
+        assert [result["doc"] for result in found["results"]] == ["Charge the..."]
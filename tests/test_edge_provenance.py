@@ -0,0 +1,273 @@
+"""
+Edge provenance tests.
+
+The helpers of src/ast_parser/provenance.py are checked directly. The
+end-to-end tests index small Python codebases into an InMemoryGraphStore:
+every stored edge carries its provenance, and incremental runs replace
+the heuristic and derived edges whose premise files changed while the
+others are kept as stored. The min_confidence tests seed a Go call graph
+with a call through an interface.
+"""
+
+import json
+import os
+import shutil
+import sys
+
+import pytest
+
+# Add project root to Python path
+sys.path.insert(0, os.path.dirname(os.path.dirname(os.path.abspath(__file__))))
+
+from src.ast_parser.parser import CodeRelation
+from src.ast_parser.provenance import check_min_confidence, edge_premises, edge_provenance, with_provenance
+from src.graph_store import InMemoryGraphStore
+from src.indexing import stale_structural_keys
+from src.indexing.incremental import INDEX_STATE_VERSION
+from src.mcp.call_hierarchy import call_hierarchy
+from src.mcp.impact import analyze_impact
+from src.mcp.paths import find_paths
+from conftest import index_tree, write_tree
+
+FIXTURE_DIR = os.path.join(os.path.dirname(os.path.abspath(__file__)), "fixtures", "grpc_sample")
+
+SERVER_SOURCE = (
+    "from greeter_pb2_grpc import GreeterServicer\n"
+    "\n"
+    "\n"
+    "class Greeter(GreeterServicer):\n"
+    "    def SayHello(self, request, context):\n"
+    "        return request\n"
+)
+
+
+class TestProvenanceHelpers:
+    
+    def test_ast_defaults(self):
+        stamped = with_provenance({"line_no": 3}, 7)
+        assert stamped == {"line_no": 3, "source": "ast", "confidence": 1.0, "indexer_version": 7}
+        
+        heuristic = with_provenance({"source": "heuristic:interface_match", "confidence": 0.9}, 7)
+        assert (heuristic["source"], heuristic["confidence"]) == ("heuristic:interface_match", 0.9)
+    
+    def test_legacy_edges(self):
+        assert edge_provenance({"type": "CALLS", "properties": {}}) == {
+            "source": "ast", "confidence": 1.0, "indexer_version": None,
+        }
+        link = edge_provenance({"type": "CROSS_LANG_CALLS", "properties": {"matcher": "grpc", "confidence": 0.6}})
+        assert (link["source"], link["confidence"]) == ("heuristic:grpc_link", 0.6)
+        tests = edge_provenance({"type": "TESTS", "properties": {"confidence": "low", "reason": "mocked"}})
+        assert (tests["source"], tests["confidence"]) == ("heuristic:test_link", 0.5)
+        assert edge_premises({"premises": json.dumps(["a.go", "b.go"])}) == ["a.go", "b.go"]
+        assert edge_premises({}) is None
+    
+    def test_min_confidence_is_checked(self):
+        assert check_min_confidence("0.5") == 0.5
+        for value in (-0.1, 1.5, "exact", None):
+            with pytest.raises(ValueError, match="min_confidence"):
+                check_min_confidence(value)
+    
+    def test_stale_structural_keys(self):
+        relations = [
+            CodeRelation("a", "b", "IMPLEMENTS", {"structural": True, "premises": ["a.go", "b.go"]}),
+            CodeRelation("c", "d", "IMPLEMENTS", {"structural": True, "premises": ["c.go"]}),
+            CodeRelation("e", "f", "DEPENDS_ON", {"structural": True}),
+            CodeRelation("g", "h", "CALLS", {"premises": ["b.go"]}),
+        ]
+        
+        assert stale_structural_keys(relations, {"b.go"}) == [("a", "IMPLEMENTS", "b"), ("e", "DEPENDS_ON", "f")]
+
+
+def _edges(db, rel_type=None):
+    return [rel for rels in db._out.values() for rel in rels if rel_type is None or rel["type"] == rel_type]
+
+
+def _names(db):
+    return {record["properties"]["id"]: record["properties"]["name"] for record in db.find_nodes()}
+
+
+CODEBASE = {
+    "shop/__init__.py": "",
+    "shop/cart.py": "from billing.tax import rate\n\n\ndef checkout(total):\n    return total * rate()\n",
+    "billing/__init__.py": "",
+    "billing/tax.py": "def rate():\n    return 1.2\n",
+    "mail/__init__.py": "",
+    "mail/send.py": "def send():\n    return True\n",
+}
+
+
+def _package_dependencies(db):
+    """(importer, imported, imports, files, source) of the stored package DEPENDS_ON edges, duplicates kept."""
+    names = _names(db)
+    return sorted(
+        (names[rel["start_node_id"]], names[rel["end_node_id"]], rel["properties"].get("imports"),
+         rel["properties"].get("files"), rel["properties"].get("source"))
+        for rel in _edges(db, "DEPENDS_ON")
+    )
+
+
+class TestIndexedProvenance:
+    
+    def test_every_edge_has_provenance(self, legacy_env, tmp_path):
+        write_tree(tmp_path, CODEBASE)
+        db = index_tree(tmp_path).db
+        
+        edges = _edges(db)
+        assert edges
+        for rel in edges:
+            properties = rel["properties"]
+            assert properties["indexer_version"] == INDEX_STATE_VERSION, rel
+            assert 0.0 <= properties["confidence"] <= 1.0, rel
+            assert properties["source"], rel
+        sources = {rel["type"]: rel["properties"]["source"] for rel in edges}
+        assert sources["CALLS"] == "ast"
+        assert sources["DEPENDS_ON"] == "derived:package_imports"
+        assert sources["DEPENDS_ON_FILE"] == "derived:file_dependencies"
+    
+    def test_package_dependencies_follow_their_importers(self, legacy_env, tmp_path):
+        write_tree(tmp_path, CODEBASE)
+        store = InMemoryGraphStore()
+        index_tree(tmp_path, store)
+        (dependency,) = [rel for rel in _edges(store, "DEPENDS_ON")]
+        assert edge_premises(dependency["properties"]) == [str(tmp_path / "shop/cart.py")]
+        
+        # mail starts importing billing too
+        write_tree(tmp_path, {"mail/send.py": "from billing.tax import rate\n\n\ndef send():\n    return rate()\n"})
+        kg = index_tree(tmp_path, store, incremental=True)
+        assert _package_dependencies(kg.db) == _package_dependencies(index_tree(tmp_path).db)
+        assert [(importer, imported) for importer, imported, *_ in _package_dependencies(kg.db)] == [
+            ("mail", "billing"), ("shop", "billing"),
+        ]
+        
+        # The only importer of shop -> billing stops importing it
+        write_tree(tmp_path, {"shop/cart.py": "def checkout(total):\n    return total\n"})
+        kg = index_tree(tmp_path, store, incremental=True)
+        assert _package_dependencies(kg.db) == _package_dependencies(index_tree(tmp_path).db)
+        assert [(importer, imported) for importer, imported, *_ in _package_dependencies(kg.db)] == [
+            ("mail", "billing"),
+        ]
+
+
+def _grpc_codebase(tmp_path):
+    shutil.copytree(os.path.join(FIXTURE_DIR, "proto"), tmp_path / "proto")
+    shutil.copytree(os.path.join(FIXTURE_DIR, "python"), tmp_path / "python")
+    (tmp_path / "python" / "server.py").write_text(SERVER_SOURCE)
+
+
+def _links(db):
+    names = _names(db)
+    return {(names[rel["start_node_id"]], names[rel["end_node_id"]], rel["properties"]["role"]): rel
+            for rel in _edges(db, "CROSS_LANG_CALLS")}
+
+
+class TestHeuristicInvalidation:
+    
+    def test_links_are_replaced_when_their_premises_change(self, legacy_env, tmp_path):
+        _grpc_codebase(tmp_path)
+        store = InMemoryGraphStore()
+        index_tree(tmp_path, store)
+        links = _links(store)
+        client, server = links[("greet", "SayHello", "client")], links[("SayHello", "SayHello", "server")]
+        assert client["properties"]["source"] == "heuristic:grpc_link"
+        assert str(tmp_path / "python" / "server.py") in edge_premises(server["properties"])
+        assert str(tmp_path / "python" / "server.py") not in edge_premises(client["properties"])
+        # Marks the stored client edge: an edge that is rewritten loses it
+        client["properties"]["kept"] = True
+        
+        server_file = tmp_path / "python" / "server.py"
+        server_file.write_text(SERVER_SOURCE.replace("def SayHello", "def say_hello"))
+        kg = index_tree(tmp_path, store, incremental=True)
+        
+        links = _links(kg.db)
+        assert ("SayHello", "SayHello", "server") not in links
+        assert links[("greet", "SayHello", "client")]["properties"].get("kept") is True
+        
+        server_file.write_text(SERVER_SOURCE)
+        kg = index_tree(tmp_path, store, incremental=True)
+        
+        links = _links(kg.db)
+        assert set(links) == set(_links(index_tree(tmp_path).db))
+        assert links[("greet", "SayHello", "client")]["properties"].get("kept") is True
+        assert len(_edges(kg.db, "CROSS_LANG_CALLS")) == len(links)
+
+
+SAVER = "interface:app/store.go:Saver:3"
+STORE = "class:app/store.go:Store:7"
+SAVE = "method:app/store.go:Save:9"
+PERSIST = "function:app/service.go:Persist:3"
+FLUSH = "function:app/service.go:Flush:9"
+MAIN = "function:cmd/main.go:main:5"
+
+
+def _node(label, node_id, name, file_path):
+    return {"labels": ["Base", label], "properties": {"id": node_id, "name": name, "file_path": file_path,
+                                                      "line_no": int(node_id.split(":")[-1])}}
+
+
+def _rel(rel_type, start, end, **properties):
+    return {"start_node_id": start, "end_node_id": end, "type": rel_type, "properties": properties}
+
+
+def _go_store():
+    """Flush calls Save only through the Saver interface, whose IMPLEMENTS edge is a signature match."""
+    store = InMemoryGraphStore()
+    store.batch_create_nodes([
+        _node("Interface", SAVER, "Saver", "app/store.go"),
+        _node("Class", STORE, "Store", "app/store.go"),
+        _node("Method", SAVE, "Save", "app/store.go"),
+        _node("Function", PERSIST, "Persist", "app/service.go"),
+        _node("Function", FLUSH, "Flush", "app/service.go"),
+        _node("Function", MAIN, "main", "cmd/main.go"),
+    ])
+    store.batch_create_relationships([
+        _rel("DEFINES", STORE, SAVE, source="ast", confidence=1.0),
+        _rel("IMPLEMENTS", STORE, SAVER, structural=True, source="heuristic:interface_match", confidence=0.9),
+        _rel("CALLS", PERSIST, SAVE, line_no=4, source="ast", confidence=1.0),
+        _rel("CALLS", FLUSH, SAVER, line_no=10, method="Save", via_interface=True, source="ast", confidence=1.0),
+        _rel("CALLS", MAIN, FLUSH, line_no=6, source="ast", confidence=1.0),
+    ])
+    return store
+
+
+class TestMinConfidence:
+    
+    def test_analyze_impact(self):
+        result = analyze_impact(_go_store(), "Store.Save")
+        
+        (first, second) = result["callers"]["by_distance"]
+        assert [(entry["name"], entry["confidence"]) for entry in first["same_package"]] == [
+            ("Persist", 1.0), ("Flush", 0.9),
+        ]
+        # The weakest edge on the way counts
+        assert [(entry["name"], entry["confidence"]) for entry in second["cross_package"]] == [("main", 0.9)]
+        
+        exact = analyze_impact(_go_store(), "Store.Save", min_confidence=1.0)
+        assert exact["min_confidence"] == 1.0
+        assert [[entry["name"] for entry in bucket["same_package"]] for bucket in exact["callers"]["by_distance"]] == [
+            ["Persist"],
+        ]
+        with pytest.raises(ValueError, match="min_confidence"):
+            analyze_impact(_go_store(), "Store.Save", min_confidence=2)
+    
+    def test_call_hierarchy(self):
+        (child,) = call_hierarchy(_go_store(), "Flush", direction="callees")["tree"]["children"]
+        assert (child["name"], child["via_interface"]) == ("Save", True)
+        assert (child["source"], child["confidence"]) == ("heuristic:interface_match", 0.9)
+        
+        # Without the signature match only the interface method is known
+        (child,) = call_hierarchy(_go_store(), "Flush", direction="callees", min_confidence=1.0)["tree"]["children"]
+        assert (child["id"], child["method"], child["confidence"]) == (SAVER, "Save", 1.0)
+    
+    def test_find_path(self):
+        store = _go_store()
+        
+        (path,) = find_paths(store, "main", "Store", edge_types=["CALLS", "IMPLEMENTS"], direction="undirected")["paths"]
+        assert [(hop["relation_type"], hop["source"], hop["confidence"]) for hop in path["hops"]] == [
+            ("CALLS", "ast", 1.0), ("CALLS", "ast", 1.0), ("IMPLEMENTS", "heuristic:interface_match", 0.9),
+        ]
+        assert path["hops"][0]["indexer_version"] is None
+        
+        result = find_paths(store, "main", "Store", edge_types=["CALLS", "IMPLEMENTS"], direction="undirected",
+                            min_confidence=1.0)
+        assert result["status"] == "no_path"
+        assert "confidence >= 1.0" in result["message"]
@@ -0,0 +1,196 @@
+"""
+Fallback indexer tests.
+
+FallbackParser is run on small Lua, Perl, shell, SQL and Terraform files,
+the pattern table is extended from a config file, and a mixed directory
+is indexed into an InMemoryGraphStore in both parser modes to check that
+every file gets its File node and Package, and that dedicated parsers
+keep their extensions.
+"""
+
+import json
+import os
+import re
+import sys
+
+import pytest
+
+# Add project root to Python path
+sys.path.insert(0, os.path.dirname(os.path.dirname(os.path.abspath(__file__))))
+
+from src.ast_parser.fallback_parser import (
+    DEDICATED_EXTENSIONS,
+    FallbackParser,
+    fallback_extensions,
+    load_fallback_patterns,
+)
+from src.graph_store import InMemoryGraphStore
+from src.mcp.outline import file_outline
+from src.parallel.pipeline import ParserSettings, create_parser
+from conftest import index_tree, write_tree
+
+SOURCES = {
+    "scripts/init.lua": (
+        "local M = {}\n\n"
+        "function M.setup(opts)\n  return opts\nend\n\n"
+        "local function helper()\nend\n\n"
+        "M.run = function() end\n"
+    ),
+    "lib/Shop/Cart.pm": (
+        "package Shop::Cart;\nuse constant MAX_ITEMS => 10;\n\n"
+        "sub new {\n    return bless {}, shift;\n}\n\nsub total { 0 }\n"
+    ),
+    "bin/deploy.sh": (
+        "#!/bin/sh\nexport TARGET=prod\n\n"
+        "build() {\n  make\n}\n\nfunction release {\n  build\n}\n"
+    ),
+    "db/schema.sql": (
+        "CREATE TABLE IF NOT EXISTS \"public\".\"users\" (\n  id serial\n);\n\n"
+        "create or replace function add_user(name text) returns void as $$ $$;\n"
+        "CREATE MATERIALIZED VIEW active_users AS SELECT 1;\n"
+    ),
+    "infra/main.tf": (
+        "resource \"aws_s3_bucket\" \"logs\" {\n  bucket = \"logs\"\n}\n\n"
+        "module \"vpc\" {\n  source = \"./vpc\"\n}\n\n"
+        "variable \"region\" {}\noutput \"bucket\" { value = 1 }\n"
+    ),
+    "app/main.py": "def main():\n    return 1\n",
+    "README.txt": "no patterns for text files\n",
+}
+
+
+def _symbols(file_path):
+    nodes, relations = FallbackParser().parse_file(str(file_path))
+    symbols = sorted((node.line_no, node.node_type, node.name)
+                     for node in nodes.values() if node.node_type != "File")
+    return symbols, nodes, relations
+
+
+@pytest.fixture
+def codebase(tmp_path):
+    write_tree(tmp_path, SOURCES)
+    return tmp_path
+
+
+class TestFallbackParser:
+    
+    def test_lua(self, codebase):
+        symbols, _, _ = _symbols(codebase / "scripts" / "init.lua")
+        
+        assert symbols == [(3, "Function", "M.setup"), (7, "Function", "helper"), (10, "Function", "M.run")]
+    
+    def test_perl(self, codebase):
+        symbols, _, _ = _symbols(codebase / "lib" / "Shop" / "Cart.pm")
+        
+        assert symbols == [(1, "Class", "Shop::Cart"), (2, "Constant", "MAX_ITEMS"), (4, "Function", "new"),
+                           (8, "Function", "total")]
+    
+    def test_shell(self, codebase):
+        symbols, _, _ = _symbols(codebase / "bin" / "deploy.sh")
+        
+        assert symbols == [(2, "Variable", "TARGET"), (4, "Function", "build"), (8, "Function", "release")]
+    
+    def test_sql(self, codebase):
+        symbols, nodes, _ = _symbols(codebase / "db" / "schema.sql")
+        
+        assert symbols == [(1, "Class", "public.users"), (5, "Function", "add_user"), (6, "Class", "active_users")]
+        declarations = {node.name: node.properties["declaration"] for node in nodes.values() if node.node_type != "File"}
+        assert declarations == {"public.users": "table", "add_user": "function", "active_users": "view"}
+    
+    def test_terraform(self, codebase):
+        symbols, nodes, relations = _symbols(codebase / "infra" / "main.tf")
+        
+        assert symbols == [(1, "Class", "aws_s3_bucket.logs"), (5, "Class", "vpc"), (9, "Variable", "region"),
+                           (10, "Constant", "bucket")]
+        (file_node,) = [node for node in nodes.values() if node.node_type == "File"]
+        assert file_node.properties == {"language": "terraform", "extraction": "heuristic"}
+        bucket = next(node for node in nodes.values() if node.name == "aws_s3_bucket.logs")
+        assert bucket.properties == {"language": "terraform", "extraction": "heuristic", "declaration": "resource",
+                                     "qualified_name": "aws_s3_bucket.logs"}
+        assert bucket.code_snippet == "resource \"aws_s3_bucket\" \"logs\" {"
+        # Containment only, no reference edges
+        assert {relation.relation_type for relation in relations} == {"CONTAINS"}
+        assert all(relation.source_id == file_node.node_id for relation in relations)
+        assert relations[0].properties == {"source": "heuristic:fallback", "confidence": 0.5}
+    
+    def test_unreadable_file(self, tmp_path):
+        path = tmp_path / "broken.sql"
+        path.write_bytes(b"CREATE TABLE \xff\xfe (id int);")
+        parser = FallbackParser()
+        
+        nodes, _ = parser.parse_file(str(path))
+        
+        assert [node.node_type for node in nodes.values()] == ["File"]
+        assert isinstance(parser.parse_error, UnicodeDecodeError)
+
+
+class TestPatternTable:
+    
+    def test_dedicated_parsers_take_precedence(self, tmp_path):
+        config = tmp_path / "patterns.json"
+        config.write_text(json.dumps({
+            "patterns": {
+                "tcl": [{"name": "proc", "kind": "Function", "regex": r"^\s*proc\s+(?P<name>[\w:]+)"}],
+                ".py": [{"name": "def", "kind": "Function", "regex": r"^def (?P<name>\w+)"}],
+                ".sql": [{"name": "index", "kind": "Constant", "regex": r"(?i)^CREATE INDEX (?P<name>\w+)"}],
+            },
+            "languages": {".tcl": "tcl"},
+        }))
+        
+        patterns, languages = load_fallback_patterns(str(config))
+        
+        assert ".py" not in patterns and ".py" in DEDICATED_EXTENSIONS
+        assert [pattern.name for pattern in patterns[".tcl"]] == ["proc"] and languages[".tcl"] == "tcl"
+        assert [pattern.name for pattern in patterns[".sql"]][-1] == "index"
+        source = tmp_path / "build.tcl"
+        source.write_text("proc ::app::build {} {\n}\n")
+        nodes, _ = FallbackParser(patterns, languages).parse_file(str(source))
+        assert sorted(node.name for node in nodes.values()) == ["::app::build", "build.tcl"]
+    
+    @pytest.mark.parametrize("pattern, message", [
+        ({"name": "x", "kind": "Method", "regex": "(?P<name>x)"}, "kind must be one of"),
+        ({"name": "x", "kind": "Function", "regex": "(x)"}, "no (?P<name>...) group"),
+        ({"name": "x", "kind": "Function", "regex": "(?P<name>x"}, "invalid regex"),
+        ({"name": "x", "kind": "Function", "regex": "(?P<name>x)", "flags": "i"}, "Unknown fallback pattern key"),
+    ])
+    def test_invalid_patterns(self, tmp_path, pattern, message):
+        config = tmp_path / "patterns.json"
+        config.write_text(json.dumps({"patterns": {".lua": [pattern]}}))
+        
+        with pytest.raises(ValueError, match=re.escape(message)):
+            load_fallback_patterns(str(config))
+    
+    def test_environment(self, monkeypatch, tmp_path):
+        assert {".lua", ".pl", ".pm", ".sh", ".sql", ".tf"} <= set(fallback_extensions())
+        settings = ParserSettings(use_ast_grep=True)
+        assert isinstance(create_parser(str(tmp_path / "main.tf"), settings), FallbackParser)
+        assert not isinstance(create_parser(str(tmp_path / "main.rb"), settings), FallbackParser)
+        
+        monkeypatch.setenv("FALLBACK_INDEXING", "false")
+        assert fallback_extensions() == ()
+        assert create_parser(str(tmp_path / "main.tf"), ParserSettings()) is None
+
+
+@pytest.mark.parametrize("use_ast_grep", [False, True])
+def test_indexing_covers_every_file(monkeypatch, codebase, use_ast_grep):
+    monkeypatch.setenv("USE_AST_GREP", str(use_ast_grep).lower())
+    monkeypatch.setenv("AST_GREP_LANGUAGES", "python")
+    monkeypatch.setenv("PARALLEL_INDEXING_ENABLED", "false")
+    store = InMemoryGraphStore()
+    index_tree(codebase, store).close()
+    
+    files = {os.path.relpath(record["properties"]["file_path"], str(codebase)): record["properties"]
+             for record in store.find_nodes(label="File")}
+    heuristic = sorted(path for path, properties in files.items() if properties.get("extraction") == "heuristic")
+    assert heuristic == sorted(path for path in SOURCES if path.endswith((".lua", ".pm", ".sh", ".sql", ".tf")))
+    assert "README.txt" not in files
+    assert files.get("app/main.py", {}).get("extraction") is None
+    packages = {record["properties"]["name"] for record in store.find_nodes(label="Package")}
+    assert {"scripts", "Shop", "bin", "db", "infra"} <= packages
+    (users,) = store.search_code_by_text("users", 10)
+    assert users["node"]["name"] == "public.users"
+    
+    outline = file_outline(store, str(codebase / "infra" / "main.tf"), ParserSettings())
+    assert outline["source"] == "graph"
+    assert [entry["name"] for entry in outline["outline"]["types"]] == ["aws_s3_bucket.logs", "vpc"]
+    assert [entry["name"] for entry in outline["outline"]["variables"]] == ["region"]
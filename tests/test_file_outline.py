@@ -0,0 +1,165 @@
+"""
+get_file_outline tests.
+
+build_outline is checked on hand-made symbols; the end-to-end tests index
+Python files into an InMemoryGraphStore and outline an indexed file from
+the graph and an excluded one by parsing it.
+"""
+
+import asyncio
+import json
+import os
+import sys
+
+import pytest
+
+# Add project root to Python path
+sys.path.insert(0, os.path.dirname(os.path.dirname(os.path.abspath(__file__))))
+
+from src.graph_store import InMemoryGraphStore
+from src.mcp.outline import build_outline, doc_summary, file_outline
+from src.parallel.pipeline import ParserSettings
+from conftest import ConstantEmbeddingProvider
+
+
+def _symbol(node_id, kind, name, line_no, end_line_no=None, **properties):
+    return {
+        "id": node_id, "kind": kind, "name": name, "line_no": line_no,
+        "end_line_no": end_line_no or line_no, "properties": dict(properties, name=name),
+    }
+
+
+class TestBuildOutline:
+    
+    def test_members_nest_under_their_type(self):
+        symbols = [
+            _symbol("c", "Class", "Person", 3, 12, doc="A person.\n\nLonger text."),
+            _symbol("f", "ClassVariable", "Name", 4, type="string"),
+            _symbol("m", "Method", "GetName", 8, 10, signature="GetName() string"),
+            _symbol("g", "Function", "NewPerson", 14, 16),
+        ]
+        
+        outline = build_outline(symbols, [("c", "f"), ("c", "m")])
+        
+        (person,) = outline["types"]
+        assert (person["line_no"], person["end_line_no"], person["doc"]) == (3, 12, "A person.")
+        assert [(f["name"], f["type"]) for f in person["fields"]] == [("Name", "string")]
+        assert [(m["name"], m["signature"]) for m in person["methods"]] == [("GetName", "GetName() string")]
+        assert [f["name"] for f in outline["functions"]] == ["NewPerson"]
+    
+    def test_nested_types_and_functions(self):
+        symbols = [
+            _symbol("outer", "Class", "Outer", 1, 9),
+            _symbol("inner", "Class", "Inner", 2, 4),
+            _symbol("run", "Function", "run", 11, 14),
+            _symbol("helper", "Function", "helper", 12, 13, nested=True),
+        ]
+        
+        outline = build_outline(symbols, [("outer", "inner")])
+        
+        assert [t["name"] for t in outline["types"]] == ["Outer"]
+        assert [t["name"] for t in outline["types"][0]["types"]] == ["Inner"]
+        assert [f["name"] for f in outline["functions"]] == ["run"]
+    
+    def test_orphan_methods_constants_and_variables(self):
+        symbols = [
+            _symbol("m", "Method", "String", 5, 7, receiver_type="Person"),
+            _symbol("k", "GlobalVariable", "MAX_SIZE", 1),
+            _symbol("v", "GlobalVariable", "cache", 2),
+            _symbol("j", "Variable", "limit", 3, declaration_type="const"),
+        ]
+        
+        outline = build_outline(symbols, [])
+        
+        assert [(m["name"], m["owner"]) for m in outline["methods"]] == [("String", "Person")]
+        assert [c["name"] for c in outline["constants"]] == ["MAX_SIZE", "limit"]
+        assert [v["name"] for v in outline["variables"]] == ["cache"]
+    
+    def test_doc_summary(self):
+        assert doc_summary("Load the\n  config.\n\nDetails.") == "Load the config."
+        assert doc_summary("  ") is None
+
+
+SERVICE = (
+    "MAX_RETRIES = 3\n"
+    "\n"
+    "\n"
+    "class Service:\n"
+    "    \"\"\"Talks to the backend.\n"
+    "\n"
+    "    Not thread-safe.\n"
+    "    \"\"\"\n"
+    "\n"
+    "    def fetch(self, key: str):\n"
+    "        return key\n"
+    "\n"
+    "\n"
+    "def connect():\n"
+    "    def retry():\n"
+    "        pass\n"
+    "    return Service()\n"
+)
+
+CODEBASE = {
+    "app/__init__.py": "",
+    "app/service.py": SERVICE,
+    "scripts/tool.py": SERVICE,
+}
+
+
+@pytest.fixture
+def kg(monkeypatch, tmp_path):
+    monkeypatch.setenv("USE_AST_GREP", "false")
+    monkeypatch.setenv("ENABLE_JS_TS_PARSING", "false")
+    monkeypatch.setenv("PARALLEL_INDEXING_ENABLED", "false")
+    monkeypatch.setenv("INDEX_EXCLUDE", "scripts/")
+    from src.main import CodebaseKnowledgeGraph
+    
+    for path, source in CODEBASE.items():
+        (tmp_path / path).parent.mkdir(parents=True, exist_ok=True)
+        (tmp_path / path).write_text(source)
+    kg = CodebaseKnowledgeGraph(store=InMemoryGraphStore(), embedding_provider=ConstantEmbeddingProvider())
+    kg.process_codebase(str(tmp_path))
+    return kg
+
+
+def _check_service_outline(outline):
+    assert [c["name"] for c in outline["constants"]] == ["MAX_RETRIES"]
+    (service,) = outline["types"]
+    assert (service["name"], service["line_no"], service["end_line_no"]) == ("Service", 4, 11)
+    assert service["doc"] == "Talks to the backend."
+    assert [(m["name"], m["line_no"], m["end_line_no"]) for m in service["methods"]] == [("fetch", 10, 11)]
+    assert [f["name"] for f in outline["functions"]] == ["connect"]
+
+
+class TestFileOutline:
+    
+    def test_indexed_file_is_read_from_the_graph(self, kg, tmp_path):
+        result = file_outline(kg.db, "app/service.py", ParserSettings(), str(tmp_path))
+        
+        assert result["source"] == "graph"
+        assert result["file_path"] == str(tmp_path / "app" / "service.py")
+        _check_service_outline(result["outline"])
+    
+    def test_excluded_file_is_parsed(self, kg, tmp_path):
+        assert not kg.db.get_nodes([f"file:{tmp_path / 'scripts' / 'tool.py'}"])
+        
+        result = file_outline(kg.db, str(tmp_path / "scripts" / "tool.py"), ParserSettings())
+        
+        assert result["source"] == "parse"
+        _check_service_outline(result["outline"])
+    
+    def test_missing_file(self, kg, tmp_path):
+        assert "error" in file_outline(kg.db, "app/missing.py", ParserSettings(), str(tmp_path))
+
+
+class TestGetFileOutlineTool:
+    
+    def test_tool_reports_its_source(self, kg, tmp_path, make_server):
+        server = make_server(store=kg.db, codebase_path=str(tmp_path))
+        
+        indexed = json.loads(asyncio.run(server.mcp.tools["get_file_outline"]("app/service.py")))
+        parsed = json.loads(asyncio.run(server.mcp.tools["get_file_outline"]("scripts/tool.py")))
+        
+        assert (indexed["source"], parsed["source"]) == ("graph", "parse")
+        _check_service_outline(parsed["outline"])
@@ -0,0 +1,149 @@
+"""
+find_path tests.
+
+The graph is seeded into an InMemoryGraphStore, whose breadth-first
+shortest_paths stands in for Neo4j's shortestPath / allShortestPaths.
+"""
+
+import asyncio
+import json
+import os
+import sys
+
+import pytest
+
+# Add project root to Python path
+sys.path.insert(0, os.path.dirname(os.path.dirname(os.path.abspath(__file__))))
+
+from src.graph_store import InMemoryGraphStore
+from src.mcp.paths import find_paths, path_relation_types, store_direction
+
+
+NODES = {
+    "function:handlers:handle_checkout:10": ("handle_checkout", "Function", "app/handlers.py", 10),
+    "function:orders:create_order:5": ("create_order", "Function", "app/orders.py", 5),
+    "function:payments:charge:7": ("charge", "Function", "app/payments.py", 7),
+    "function:billing:write_invoice:3": ("write_invoice", "Function", "app/billing.py", 3),
+    "function:reports:render:1": ("render", "Function", "app/reports.py", 1),
+    "file:app/billing.py": ("billing.py", "File", "app/billing.py", 0),
+    "file:app/reports.py": ("reports.py", "File", "app/reports.py", 0),
+}
+
+# (source, target, type, line_no)
+EDGES = [
+    ("function:handlers:handle_checkout:10", "function:orders:create_order:5", "CALLS", 14),
+    ("function:handlers:handle_checkout:10", "function:payments:charge:7", "CALLS", 15),
+    ("function:orders:create_order:5", "function:billing:write_invoice:3", "CALLS", 9),
+    ("function:payments:charge:7", "function:billing:write_invoice:3", "CALLS", 12),
+    ("file:app/reports.py", "file:app/billing.py", "IMPORTS_FROM", None),
+    ("file:app/reports.py", "function:reports:render:1", "CONTAINS", None),
+]
+
+
+def _path_store():
+    """A store holding NODES and EDGES."""
+    store = InMemoryGraphStore()
+    store.batch_create_nodes([
+        {"labels": ["Base", node_type],
+         "properties": {"id": node_id, "name": name, "file_path": file_path, "line_no": line_no}}
+        for node_id, (name, node_type, file_path, line_no) in NODES.items()
+    ])
+    store.batch_create_relationships([
+        {"start_node_id": source, "end_node_id": target, "type": rel_type,
+         "properties": {"line_no": line_no} if line_no else {}}
+        for source, target, rel_type, line_no in EDGES
+    ])
+    return store
+
+
+class TestPathHelpers:
+    """Edge type and query validation."""
+    
+    def test_edge_type_aliases(self):
+        assert path_relation_types(None) == ["CALLS", "IMPORTS_FROM", "IMPORTS_DEFINITION", "METHOD_OF", "CROSS_LANG_CALLS"]
+        assert path_relation_types(["calls", "IMPORTS"]) == ["CALLS", "IMPORTS", "IMPORTS_FROM", "IMPORTS_DEFINITION"]
+        with pytest.raises(ValueError, match="Unknown edge type"):
+            path_relation_types(["CALLS]->(x) DETACH DELETE x //"])
+    
+    def test_direction_and_depth_bounds(self):
+        assert [store_direction(d, 3) for d in ("forward", "reverse", "undirected")] == ["out", "in", "both"]
+        with pytest.raises(ValueError, match="max_depth"):
+            store_direction("forward", 500)
+        with pytest.raises(ValueError, match="direction"):
+            store_direction("sideways", 3)
+
+
+class TestFindPaths:
+    """Path search between two symbols."""
+    
+    def test_forward_path_with_hop_locations(self):
+        result = find_paths(_path_store(), "handle_checkout", "write_invoice")
+        
+        assert result["status"] == "ok"
+        assert len(result["paths"]) == 1
+        hops = result["paths"][0]["hops"]
+        assert [(hop["from"]["name"], hop["relation_type"], hop["to"]["name"]) for hop in hops][0][:2] == (
+            "handle_checkout", "CALLS")
+        assert hops[-1]["to"]["name"] == "write_invoice"
+        assert hops[0]["file_path"] == "app/handlers.py"
+        assert hops[0]["line_no"] in (14, 15)
+        assert not any(hop["backward"] for hop in hops)
+    
+    def test_top_n_shortest_paths(self):
+        result = find_paths(_path_store(), "handle_checkout", "write_invoice", limit=5)
+        
+        middles = sorted(path["hops"][0]["to"]["name"] for path in result["paths"])
+        assert middles == ["charge", "create_order"]
+        assert {path["length"] for path in result["paths"]} == {2}
+    
+    def test_direction_matters(self):
+        db = _path_store()
+        
+        assert find_paths(db, "write_invoice", "handle_checkout")["status"] == "no_path"
+        reverse = find_paths(db, "write_invoice", "handle_checkout", direction="reverse")
+        assert reverse["status"] == "ok"
+        hop = reverse["paths"][0]["hops"][0]
+        # The edge runs from the caller, so the hop points against the traversal
+        assert hop["backward"] is True
+        assert hop["line_no"] in (9, 12)
+    
+    def test_undirected_and_edge_type_filter(self):
+        db = _path_store()
+        
+        # render <-CONTAINS- reports.py -IMPORTS_FROM-> billing.py
+        billing = "file:app/billing.py"
+        assert find_paths(db, "render", billing, edge_types=["CONTAINS", "IMPORTS"])["status"] == "no_path"
+        result = find_paths(db, "render", billing, edge_types=["CONTAINS", "IMPORTS"], direction="undirected")
+        assert [hop["relation_type"] for hop in result["paths"][0]["hops"]] == ["CONTAINS", "IMPORTS_FROM"]
+    
+    def test_max_depth_reports_no_path(self):
+        result = find_paths(_path_store(), "handle_checkout", "write_invoice", max_depth=1)
+        
+        assert result["status"] == "no_path"
+        assert result["paths"] == []
+        assert "at most 1 hops" in result["message"]
+    
+    def test_not_found_and_same_symbol(self):
+        db = _path_store()
+        
+        missing = find_paths(db, "handle_checkout", "drop_tables")
+        assert (missing["status"], missing["endpoint"]) == ("not_found", "target")
+        same = find_paths(db, "charge", "function:payments:charge:7")
+        assert same["paths"] == [{"length": 0, "hops": []}]
+
+
+class TestFindPathTool:
+    """The MCP tool wraps find_paths and reports errors as JSON."""
+    
+    @pytest.fixture
+    def find_path(self, make_server):
+        server = make_server(store=_path_store())
+        return server.mcp.tools["find_path"]
+    
+    def test_path_and_error(self, find_path):
+        result = json.loads(asyncio.run(find_path("handle_checkout", "write_invoice", edge_types=["CALLS"])))
+        assert result["status"] == "ok"
+        assert result["edge_types"] == ["CALLS"]
+        
+        error = json.loads(asyncio.run(find_path("handle_checkout", "write_invoice", direction="both")))
+        assert "Unknown direction" in error["error"]
@@ -0,0 +1,179 @@
+"""
+find_references MCP tool tests.
+
+The tool is registered on a capturing stand-in for FastMCP and queried
+against an InMemoryGraphStore seeded with two same-named functions and
+their callers.
+"""
+
+import asyncio
+import json
+import os
+import sys
+
+import pytest
+
+# Add project root to Python path
+sys.path.insert(0, os.path.dirname(os.path.dirname(os.path.abspath(__file__))))
+
+pytest.importorskip("mcp.server.fastmcp")
+
+from src.graph_store import InMemoryGraphStore
+from src.mcp.references import (
+    qualified_name,
+    qualifier_matches,
+    read_source_line,
+    relation_types_for_kind,
+    split_qualified_name,
+)
+
+
+@pytest.fixture
+def source_tree(tmp_path):
+    """Two packages defining Parse, and a caller file."""
+    (tmp_path / "jsonutil").mkdir()
+    (tmp_path / "xmlutil").mkdir()
+    (tmp_path / "jsonutil" / "parse.go").write_text("package jsonutil\n\nfunc Parse() {}\n")
+    (tmp_path / "xmlutil" / "parse.go").write_text("package xmlutil\n\nfunc Parse() {}\n")
+    (tmp_path / "main.go").write_text(
+        "package main\n\nfunc main() {\n\tjsonutil.Parse()\n\txmlutil.Parse()\n\tjsonutil.Parse()\n}\n"
+    )
+    return tmp_path
+
+
+def _parse_id(source_tree, package):
+    return f"function:{source_tree}/{package}/parse.go:Parse:3"
+
+
+@pytest.fixture
+def fake_db(source_tree):
+    """Both Parse functions, called by main and imported by main.go."""
+    json_parse = _parse_id(source_tree, "jsonutil")
+    xml_parse = _parse_id(source_tree, "xmlutil")
+    main_file = str(source_tree / "main.go")
+    main_func = f"function:{main_file}:main:3"
+    
+    store = InMemoryGraphStore()
+    store.batch_create_nodes([
+        {"labels": ["Base", "Function"],
+         "properties": {"id": json_parse, "name": "Parse", "file_path": str(source_tree / "jsonutil" / "parse.go"), "line_no": 3}},
+        {"labels": ["Base", "Function"],
+         "properties": {"id": xml_parse, "name": "Parse", "file_path": str(source_tree / "xmlutil" / "parse.go"), "line_no": 3}},
+        {"labels": ["Base", "Function"],
+         "properties": {"id": main_func, "name": "main", "file_path": main_file, "line_no": 3}},
+        {"labels": ["Base", "File"],
+         "properties": {"id": f"file:{main_file}", "name": "main.go", "file_path": main_file, "line_no": 1}},
+    ])
+    store.batch_create_relationships([
+        {"start_node_id": main_func, "end_node_id": json_parse, "type": "CALLS",
+         "properties": {"line_no": 4, "call_lines": [4, 6]}},
+        {"start_node_id": main_func, "end_node_id": xml_parse, "type": "CALLS",
+         "properties": {"line_no": 5, "call_lines": [5]}},
+        {"start_node_id": f"file:{main_file}", "end_node_id": json_parse, "type": "IMPORTS_DEFINITION", "properties": {}},
+    ])
+    return store
+
+
+@pytest.fixture
+def find_references(fake_db, make_server):
+    """The registered find_references coroutine, bound to fake_db."""
+    server = make_server(store=fake_db)
+    tool = server.mcp.tools["find_references"]
+    return lambda *args, **kwargs: json.loads(asyncio.run(tool(*args, **kwargs)))
+
+
+class TestReferenceHelpers:
+    """Pure helpers in src.mcp.references."""
+    
+    def test_split_qualified_name(self):
+        assert split_qualified_name("Parse") == (None, "Parse")
+        assert split_qualified_name("jsonutil.Parse") == ("jsonutil", "Parse")
+        assert split_qualified_name("github.com/acme/api.NewClient") == ("github.com/acme/api", "NewClient")
+    
+    def test_qualifier_forms(self):
+        method = {"name": "GetName", "owner": "Person", "file_path": "/repo/models/person.py"}
+        external = {"name": "Printf", "owner": None, "file_path": "", "import_path": "fmt"}
+        
+        assert qualifier_matches(method, "Person")
+        assert qualifier_matches(method, "person")
+        assert qualifier_matches(method, "models.person")
+        assert qualifier_matches(method, "models")
+        assert qualifier_matches(method, "person.Person")
+        assert not qualifier_matches(method, "other")
+        assert qualifier_matches(external, "fmt")
+        assert qualified_name(method) == "person.Person.GetName"
+        assert qualified_name(external) == "fmt.Printf"
+    
+    def test_unknown_kind(self):
+        with pytest.raises(ValueError):
+            relation_types_for_kind("reads")
+        assert relation_types_for_kind("calls") == ["CALLS"]
+    
+    def test_read_source_line(self, tmp_path):
+        source = tmp_path / "a.py"
+        source.write_text("x = 1\n    return very_long_name\n")
+        
+        assert read_source_line(str(source), 2) == "return very_long_name"
+        assert read_source_line(str(source), 9) is None
+        assert read_source_line(str(tmp_path / "missing.py"), 1) is None
+
+
+class TestFindReferencesTool:
+    """End-to-end behaviour of the tool."""
+    
+    def test_ambiguous_name_returns_candidates(self, find_references):
+        result = find_references("Parse")
+        
+        assert result["status"] == "ambiguous"
+        assert sorted(c["qualified_name"] for c in result["candidates"]) == ["jsonutil.Parse", "xmlutil.Parse"]
+        assert "references" not in result
+    
+    def test_qualified_name_selects_package(self, find_references, source_tree):
+        result = find_references("jsonutil.Parse")
+        
+        assert result["status"] == "ok"
+        assert result["target"]["qualified_name"] == "jsonutil.Parse"
+        assert result["total"] == 2
+        calls = [r for r in result["references"] if r["relation_type"] == "CALLS"]
+        assert calls == [{
+            "symbol_id": None,
+            "id": f"function:{source_tree / 'main.go'}:main:3",
+            "name": "main",
+            "node_type": "Function",
+            "relation_type": "CALLS",
+            "file_path": str(source_tree / "main.go"),
+            "line_no": 4,
+            "snippet": "jsonutil.Parse()",
+            "source": "ast",
+            "confidence": 1.0,
+            "indexer_version": None,
+            "call_lines": [4, 6],
+        }]
+    
+    def test_kind_filter(self, find_references):
+        result = find_references("jsonutil.Parse", kind="imports")
+        
+        assert result["total"] == 1
+        assert [r["relation_type"] for r in result["references"]] == ["IMPORTS_DEFINITION"]
+        assert result["references"][0]["snippet"] == "package main"
+    
+    def test_pagination(self, find_references):
+        first = find_references("jsonutil.Parse", limit=1)
+        second = find_references("jsonutil.Parse", limit=1, offset=1)
+        
+        assert first["has_more"] is True
+        assert second["has_more"] is False
+        assert first["references"][0]["relation_type"] != second["references"][0]["relation_type"]
+    
+    def test_node_id_lookup(self, find_references, source_tree):
+        node_id = _parse_id(source_tree, "xmlutil")
+        
+        result = find_references(node_id)
+        
+        assert result["status"] == "ok"
+        assert result["target"]["id"] == node_id
+        assert [r["line_no"] for r in result["references"]] == [5]
+    
+    def test_not_found_and_bad_kind(self, find_references):
+        assert find_references("Missing")["status"] == "not_found"
+        assert "error" in find_references("jsonutil.Parse", kind="reads")
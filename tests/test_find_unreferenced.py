@@ -0,0 +1,275 @@
+"""
+find_unreferenced tests.
+
+The graph is seeded into an InMemoryGraphStore: a Go service package with
+an entry point, tests, an interface implementation, a generated file and
+a public API directory, and a Python package. The end-to-end test indexes
+a Python tree.
+"""
+
+import asyncio
+import json
+import os
+import sys
+
+import pytest
+
+# Add project root to Python path
+sys.path.insert(0, os.path.dirname(os.path.dirname(os.path.abspath(__file__))))
+
+from src.analysis import DEFAULT_SUPPRESSIONS, find_unreferenced, format_unreferenced_report, load_config
+from src.graph_store import InMemoryGraphStore
+from conftest import ConstantEmbeddingProvider
+
+# (node type, file, name, line, properties)
+SYMBOLS = [
+    ("Function", "svc/main.go", "main", 5, {}),
+    ("Function", "svc/main.go", "init", 9, {}),
+    ("Function", "svc/main.go", "run", 12, {}),
+    ("Function", "svc/util.go", "parseFlags", 3, {}),
+    ("Function", "svc/util.go", "walk", 10, {}),
+    ("Function", "svc/util.go", "Format", 20, {}),
+    ("Function", "svc/util.go", "register", 30, {}),
+    ("Class", "svc/store.go", "Store", 4, {}),
+    ("Method", "svc/store.go", "Get", 8, {"receiver_type": "*Store"}),
+    ("Method", "svc/store.go", "Close", 14, {"receiver_type": "*Store"}),
+    ("Method", "svc/store.go", "reset", 20, {"receiver_type": "*Store"}),
+    ("Interface", "svc/store.go", "Closer", 30, {"methods": ["Close() error"]}),
+    ("Function", "svc/util_test.go", "TestFormat", 5, {}),
+    ("Function", "svc/util_test.go", "newFixture", 12, {}),
+    ("Function", "svc/gen/api.pb.go", "file_api_proto_init", 40, {}),
+    ("Function", "svc/gen/mock.go", "NewMock", 10, {}),
+    ("Function", "pkg/sdk/client.go", "NewClient", 7, {}),
+    ("Function", "pkg/sdk/client.go", "dial", 15, {}),
+    ("Function", "app/views.py", "handle_index", 3, {"decorators": ["app.route('/')"]}),
+    ("Function", "app/views.py", "_slugify", 10, {}),
+    ("Class", "app/models.py", "Order", 1, {"bases": ["Model"]}),
+    ("Method", "app/models.py", "__str__", 5, {}),
+    ("Method", "app/models.py", "total", 8, {}),
+]
+
+FILE_PROPERTIES = {"svc/gen/mock.go": {"header": "Code generated by mockgen. DO NOT EDIT."}}
+
+PACKAGES = {"svc": "example.com/svc", "svc/gen": "example.com/svc/gen", "pkg/sdk": "example.com/pkg/sdk"}
+
+
+def _id(node_type, file_path, name):
+    (line,) = [line for kind, path, symbol, line, _ in SYMBOLS if (kind, path, symbol) == (node_type, file_path, name)]
+    return f"{node_type}:{file_path}:{name}:{line}"
+
+
+# (source, target, type)
+RELATIONS = [
+    (_id("Function", "svc/main.go", "main"), _id("Function", "svc/main.go", "run"), "CALLS"),
+    (_id("Function", "svc/main.go", "run"), _id("Method", "svc/store.go", "Get"), "CALLS"),
+    (_id("Function", "svc/util.go", "walk"), _id("Function", "svc/util.go", "walk"), "CALLS"),
+    (_id("Function", "svc/util_test.go", "TestFormat"), _id("Function", "svc/util.go", "Format"), "CALLS"),
+    (_id("Function", "svc/gen/mock.go", "NewMock"), _id("Function", "svc/util.go", "register"), "CALLS"),
+    (_id("Method", "svc/store.go", "Get"), _id("Class", "svc/store.go", "Store"), "METHOD_OF"),
+    (_id("Method", "svc/store.go", "Close"), _id("Class", "svc/store.go", "Store"), "METHOD_OF"),
+    (_id("Method", "svc/store.go", "reset"), _id("Class", "svc/store.go", "Store"), "METHOD_OF"),
+    (_id("Class", "svc/store.go", "Store"), _id("Interface", "svc/store.go", "Closer"), "IMPLEMENTS"),
+    (_id("Class", "app/models.py", "Order"), _id("Method", "app/models.py", "__str__"), "DEFINES"),
+    (_id("Class", "app/models.py", "Order"), _id("Method", "app/models.py", "total"), "DEFINES"),
+]
+
+
+def _store():
+    store = InMemoryGraphStore()
+    files = sorted({path for _, path, _, _, _ in SYMBOLS})
+    store.batch_create_nodes(
+        [{"labels": ["Base", node_type],
+          "properties": {"id": f"{node_type}:{path}:{name}:{line}", "name": name, "file_path": path, "line_no": line,
+                         **properties}}
+         for node_type, path, name, line, properties in SYMBOLS]
+        + [{"labels": ["Base", "File"],
+            "properties": {"id": f"file:{path}", "name": os.path.basename(path), "file_path": path,
+                           **FILE_PROPERTIES.get(path, {})}}
+           for path in files]
+        + [{"labels": ["Base", "Package"],
+            "properties": {"id": f"package:{import_path}", "name": os.path.basename(path), "file_path": "",
+                           "path": path, "import_path": import_path}}
+           for path, import_path in PACKAGES.items()]
+        # A callee outside the index
+        + [{"labels": ["Base", "Function"], "properties": {"id": "Function::Println:0", "name": "Println"}}]
+    )
+    relationships = [{"start_node_id": source, "end_node_id": target, "type": relation_type, "properties": {}}
+                     for source, target, relation_type in RELATIONS]
+    for path in files:
+        package = next((import_path for directory, import_path in PACKAGES.items()
+                        if os.path.dirname(path) == directory), None)
+        if package:
+            relationships.append({"start_node_id": f"package:{package}", "end_node_id": f"file:{path}",
+                                  "type": "CONTAINS", "properties": {}})
+    store.batch_create_relationships(relationships)
+    return store
+
+
+def _reported(result):
+    return {symbol["name"]: symbol["confidence"] for group in result["packages"] for symbol in group["symbols"]}
+
+
+@pytest.fixture(autouse=True)
+def no_config(monkeypatch):
+    monkeypatch.delenv("UNREFERENCED_CONFIG", raising=False)
+    monkeypatch.delenv("UNREFERENCED_PUBLIC_API", raising=False)
+
+
+class TestFindUnreferenced:
+    
+    def test_report_and_suppressions(self):
+        result = find_unreferenced(_store())
+        
+        assert result["status"] == "ok"
+        assert result["symbol_count"] == len(SYMBOLS)
+        assert _reported(result) == {
+            "parseFlags": "high",
+            # Recursion is not a reference
+            "walk": "high",
+            "newFixture": "high",
+            "NewClient": "medium",
+            "dial": "high",
+            "reset": "medium",
+            # Decorated: a framework may call it
+            "handle_index": "low",
+            "_slugify": "high",
+            # Exported type; total may override a method of Model, outside the index
+            "Order": "low",
+            "total": "low",
+        }
+        assert result["suppressed"] == {
+            "dunder": 1, "entry_point": 2, "generated": 2, "interface_implementation": 1, "test": 1,
+        }
+        assert result["unreferenced_count"] == 10
+        # Store is referenced through its members; register only from a generated file
+        assert not {"Store", "Get", "register", "Format"} & set(_reported(result))
+    
+    def test_grouped_by_package(self):
+        result = find_unreferenced(_store())
+        
+        assert [group["package"] for group in result["packages"]] == [
+            "app", "example.com/pkg/sdk", "example.com/svc",
+        ]
+        symbols = result["packages"][2]["symbols"]
+        assert [(symbol["file_path"], symbol["line_no"]) for symbol in symbols] == [
+            ("svc/store.go", 20), ("svc/util.go", 3), ("svc/util.go", 10), ("svc/util_test.go", 12),
+        ]
+        assert symbols[0] == {
+            "symbol_id": None, "id": "Method:svc/store.go:reset:20", "name": "reset", "node_type": "Method",
+            "file_path": "svc/store.go", "line_no": 20, "exported": False, "confidence": "medium",
+        }
+    
+    def test_scope_and_min_confidence(self):
+        db = _store()
+        
+        assert set(_reported(find_unreferenced(db, scope="app"))) == {"handle_index", "_slugify", "Order", "total"}
+        assert set(_reported(find_unreferenced(db, scope="svc/util.go"))) == {"parseFlags", "walk"}
+        assert set(_reported(find_unreferenced(db, min_confidence="high"))) == {
+            "parseFlags", "walk", "newFixture", "dial", "_slugify",
+        }
+        with pytest.raises(ValueError, match="confidence"):
+            find_unreferenced(db, min_confidence="certain")
+        with pytest.raises(ValueError, match="max_results"):
+            find_unreferenced(db, max_results=0)
+    
+    def test_public_api(self, monkeypatch):
+        monkeypatch.setenv("UNREFERENCED_PUBLIC_API", "pkg/sdk")
+        result = find_unreferenced(_store())
+        
+        # Only the exported symbol of the public package is suppressed
+        assert "NewClient" not in _reported(result)
+        assert _reported(result)["dial"] == "high"
+        assert result["suppressed"]["public_api"] == 1
+        assert "NewClient" not in _reported(find_unreferenced(_store(), public_api=["/pkg/sdk/"]))
+    
+    def test_config_file_suppressions(self, tmp_path, monkeypatch):
+        config = tmp_path / "unreferenced.json"
+        config.write_text(json.dumps({
+            "public_api": ["pkg/sdk"],
+            "suppressions": [
+                {"reason": "route", "decorators": ["app.route"]},
+                {"reason": "fixture", "names": ["new*"], "paths": ["*_test.go"]},
+            ],
+        }))
+        monkeypatch.setenv("UNREFERENCED_CONFIG", str(config))
+        
+        result = find_unreferenced(_store())
+        assert not {"handle_index", "newFixture", "NewClient"} & set(_reported(result))
+        assert (result["suppressed"]["route"], result["suppressed"]["fixture"]) == (1, 1)
+        # The built-in rules still apply
+        assert result["suppressed"]["entry_point"] == 2
+        assert len(DEFAULT_SUPPRESSIONS) == 6
+    
+    def test_invalid_config(self, tmp_path):
+        config = tmp_path / "unreferenced.json"
+        for document, message in [
+            ({"suppressions": [{"names": ["x"]}]}, "needs a reason"),
+            ({"suppressions": [{"reason": "x"}]}, "no condition"),
+            ({"suppressions": [{"reason": "x", "name": ["x"]}]}, "Unknown suppression rule key 'name'"),
+            ({"suppressions": [{"reason": "x", "names": "x"}]}, "must be a list"),
+        ]:
+            config.write_text(json.dumps(document))
+            with pytest.raises(ValueError, match=message):
+                load_config(str(config))
+        config.write_text("{")
+        with pytest.raises(ValueError, match="Cannot read"):
+            load_config(str(config))
+    
+    def test_max_results_and_text_report(self):
+        result = find_unreferenced(_store(), max_results=3)
+        assert result["truncated"] is True
+        assert sum(len(group["symbols"]) for group in result["packages"]) == 3
+        assert result["unreferenced_count"] == 10
+        
+        report = format_unreferenced_report(find_unreferenced(_store(), scope="svc"))
+        assert report.startswith("Found 4 unreferenced symbols under svc")
+        assert "Suppressed: entry_point 2, generated 2, interface_implementation 1, test 1." in report
+        assert "  [high] Function parseFlags  svc/util.go:3" in report
+        assert format_unreferenced_report(find_unreferenced(InMemoryGraphStore())) == \
+            "No unreferenced symbols at low confidence or above among 0 symbols. Nothing suppressed.\n"
+
+
+class TestIndexedCodebase:
+    
+    def test_python_tree(self, tmp_path, monkeypatch):
+        monkeypatch.setenv("USE_AST_GREP", "false")
+        monkeypatch.setenv("ENABLE_JS_TS_PARSING", "false")
+        monkeypatch.setenv("PARALLEL_INDEXING_ENABLED", "false")
+        for path, source in {
+            "shop/__init__.py": "",
+            "shop/cart.py": "from shop.prices import discount\n\n\ndef checkout():\n    return discount()\n",
+            "shop/prices.py": "def discount():\n    return 1\n\n\ndef _legacy_rate():\n    return 2\n",
+            "tests/test_cart.py": "def test_checkout():\n    pass\n",
+        }.items():
+            (tmp_path / path).parent.mkdir(parents=True, exist_ok=True)
+            (tmp_path / path).write_text(source)
+        
+        from src.main import CodebaseKnowledgeGraph
+        
+        store = InMemoryGraphStore()
+        CodebaseKnowledgeGraph(store=store, embedding_provider=ConstantEmbeddingProvider()).process_codebase(str(tmp_path))
+        
+        result = find_unreferenced(store)
+        reported = _reported(result)
+        # discount is imported by cart.py
+        assert "discount" not in reported
+        assert reported["_legacy_rate"] == "high"
+        assert reported["checkout"] == "medium"
+        assert "test_checkout" not in reported
+        assert result["suppressed"]["test"] == 1
+        assert set(_reported(find_unreferenced(store, scope=str(tmp_path / "shop" / "prices.py")))) == {"_legacy_rate"}
+
+
+class TestFindUnreferencedTool:
+    
+    @pytest.fixture
+    def tool(self, make_server):
+        server = make_server(store=_store())
+        return server.mcp.tools["find_unreferenced"]
+    
+    def test_json_with_report(self, tool):
+        result = json.loads(asyncio.run(tool(scope="app", min_confidence="medium")))
+        assert _reported(result) == {"_slugify": "high"}
+        assert result["report"].startswith("Found 1 unreferenced symbol under app")
+        
+        assert "confidence" in json.loads(asyncio.run(tool(min_confidence="certain")))["error"]
@@ -0,0 +1,231 @@
+"""
+Git-aware indexing tests.
+
+Each test builds a small repository in tmp_path with the git command line
+and indexes it into an InMemoryGraphStore: commit metadata on the
+IndexMetadata and File nodes, --changed-only runs over added, modified,
+deleted and renamed files, detached and shallow checkouts, and the
+fallback to a full index outside a repository.
+"""
+
+import os
+import shutil
+import subprocess
+import sys
+
+import pytest
+
+# Add project root to Python path
+sys.path.insert(0, os.path.dirname(os.path.dirname(os.path.abspath(__file__))))
+
+from src.graph_store import InMemoryGraphStore
+from src.indexing.git import changed_files, find_repo_root, last_commits, read_head
+from conftest import ConstantEmbeddingProvider
+
+pytestmark = pytest.mark.skipif(shutil.which("git") is None, reason="git is not installed")
+
+GIT_ENV = {
+    "GIT_AUTHOR_NAME": "Ada",
+    "GIT_AUTHOR_EMAIL": "ada@example.com",
+    "GIT_COMMITTER_NAME": "Ada",
+    "GIT_COMMITTER_EMAIL": "ada@example.com",
+    "GIT_CONFIG_GLOBAL": os.devnull,
+    "GIT_CONFIG_NOSYSTEM": "1",
+}
+
+
+def _git(root, *args):
+    result = subprocess.run(["git", "-C", str(root), "-c", "commit.gpgsign=false", *args],
+                            capture_output=True, text=True, check=True, env={**os.environ, **GIT_ENV})
+    return result.stdout.strip()
+
+
+def _commit(root, files, message, author=None):
+    for path, source in files.items():
+        target = root / path
+        if source is None:
+            target.unlink()
+            continue
+        target.parent.mkdir(parents=True, exist_ok=True)
+        target.write_text(source)
+    _git(root, "add", "-A")
+    extra = ["--author", author] if author else []
+    _git(root, "commit", "-q", "-m", message, *extra)
+    return _git(root, "rev-parse", "HEAD")
+
+
+@pytest.fixture
+def repo(tmp_path):
+    root = tmp_path / "repo"
+    root.mkdir()
+    _git(root, "init", "-q", "-b", "main")
+    _commit(root, {
+        "app/__init__.py": "",
+        "app/models.py": "class User:\n    def name(self):\n        return 'u'\n",
+        "app/service.py": "from app.models import User\n\n\ndef load():\n    return User()\n",
+        "app/legacy.py": "def old():\n    return 1\n",
+    }, "Initial commit")
+    return root
+
+
+@pytest.fixture
+def kg(monkeypatch):
+    monkeypatch.setenv("USE_AST_GREP", "false")
+    monkeypatch.setenv("ENABLE_JS_TS_PARSING", "false")
+    monkeypatch.setenv("PARALLEL_INDEXING_ENABLED", "false")
+    from src.main import CodebaseKnowledgeGraph
+    
+    return CodebaseKnowledgeGraph(store=InMemoryGraphStore(), embedding_provider=ConstantEmbeddingProvider())
+
+
+def _metadata(store, root):
+    records = store.get_nodes([f"index:{os.path.realpath(str(root))}"])
+    return records[0]["properties"] if records else {}
+
+
+def _file(store, path):
+    records = store.get_nodes([f"file:{path}"])
+    return records[0]["properties"] if records else None
+
+
+def _names(store, label):
+    return sorted(record["properties"]["name"] for record in store.find_nodes(label=label))
+
+
+class TestGitHelpers:
+    
+    def test_repo_root_and_head(self, repo):
+        head = read_head(find_repo_root(str(repo / "app")))
+        
+        assert head.root == os.path.realpath(str(repo))
+        assert head.commit == _git(repo, "rev-parse", "HEAD")
+        assert (head.branch, head.shallow) == ("main", False)
+    
+    def test_detached_head_has_no_branch(self, repo):
+        _git(repo, "checkout", "-q", "--detach")
+        
+        head = read_head(str(repo))
+        
+        assert head.branch is None
+        assert head.metadata()["git_detached"] is True
+    
+    def test_not_a_repository(self, tmp_path):
+        assert find_repo_root(str(tmp_path)) is None
+    
+    def test_changed_files_by_status(self, repo):
+        base = _git(repo, "rev-parse", "HEAD")
+        _git(repo, "mv", "app/models.py", "app/entities.py")
+        _commit(repo, {
+            "app/service.py": "from app.entities import User\n\n\ndef load():\n    return [User()]\n",
+            "app/legacy.py": None,
+            "app/extra.py": "def extra():\n    pass\n",
+        }, "Rework")
+        
+        changes = changed_files(str(repo), base)
+        root = os.path.realpath(str(repo))
+        
+        assert changes.added == [os.path.join(root, "app/extra.py")]
+        assert changes.modified == [os.path.join(root, "app/service.py")]
+        assert changes.deleted == [os.path.join(root, "app/legacy.py")]
+        assert changes.renamed == [(os.path.join(root, "app/models.py"), os.path.join(root, "app/entities.py"))]
+    
+    def test_last_commits_per_file(self, repo):
+        first = _git(repo, "rev-parse", "HEAD")
+        second = _commit(repo, {"app/legacy.py": "def old():\n    return 2\n"}, "Bump", author="Bob <bob@example.com>")
+        (repo / "untracked.py").write_text("x = 1\n")
+        paths = [str(repo / "app" / "legacy.py"), str(repo / "app" / "models.py"), str(repo / "untracked.py")]
+        
+        commits = last_commits(os.path.realpath(str(repo)), paths)
+        
+        assert commits[paths[0]]["last_commit"] == second
+        assert commits[paths[0]]["last_author"] == "Bob <bob@example.com>"
+        assert commits[paths[1]]["last_commit"] == first
+        assert paths[2] not in commits
+
+
+class TestGitMetadata:
+    
+    def test_full_index_records_commit_and_file_authors(self, kg, repo):
+        kg.process_codebase(str(repo))
+        
+        metadata = _metadata(kg.db, repo)
+        assert metadata["git_commit"] == _git(repo, "rev-parse", "HEAD")
+        assert metadata["git_branch"] == "main"
+        assert metadata["git_shallow"] is False
+        models = _file(kg.db, str(repo / "app" / "models.py"))
+        assert models["last_author"] == "Ada <ada@example.com>"
+        assert models["last_commit"] == metadata["git_commit"]
+    
+    def test_shallow_clone(self, kg, repo, tmp_path):
+        _commit(repo, {"app/legacy.py": "def old():\n    return 2\n"}, "Bump")
+        clone = tmp_path / "clone"
+        _git(tmp_path, "clone", "-q", "--depth", "1", f"file://{repo}", str(clone))
+        
+        kg.process_codebase(str(clone))
+        
+        metadata = _metadata(kg.db, clone)
+        assert metadata["git_shallow"] is True
+        # Files not changed in the fetched history get its oldest commit
+        assert _file(kg.db, str(clone / "app" / "models.py"))["last_commit"] == metadata["git_commit"]
+    
+    def test_outside_a_repository(self, kg, tmp_path):
+        (tmp_path / "plain").mkdir()
+        (tmp_path / "plain" / "mod.py").write_text("def f():\n    pass\n")
+        
+        kg.process_codebase(str(tmp_path / "plain"))
+        
+        assert "git_commit" not in _metadata(kg.db, tmp_path / "plain")
+        assert "last_commit" not in _file(kg.db, str(tmp_path / "plain" / "mod.py"))
+
+
+class TestChangedOnly:
+    
+    def test_reindexes_exactly_the_diff(self, kg, repo):
+        kg.process_codebase(str(repo))
+        base = _git(repo, "rev-parse", "HEAD")
+        user_id = [r["properties"]["id"] for r in kg.db.find_nodes(name="User", label="Class")]
+        _git(repo, "mv", "app/models.py", "app/entities.py")
+        head = _commit(repo, {
+            "app/entities.py": "class User:\n    def name(self):\n        return 'user'\n",
+            "app/service.py": "from app.entities import User\n\n\ndef load():\n    return [User()]\n",
+            "app/legacy.py": None,
+            "app/extra.py": "def extra():\n    pass\n",
+        }, "Rework")
+        
+        kg.process_codebase(str(repo), changed_since=base)
+        
+        assert kg.last_run_stats["mode"] == "incremental"
+        assert (kg.last_run_stats["changed"], kg.last_run_stats["deleted"]) == (3, 2)
+        assert _names(kg.db, "Function") == ["extra", "load"]
+        assert _file(kg.db, str(repo / "app" / "models.py")) is None
+        # The renamed file keeps its symbols' IDs although its content changed too
+        entities = _file(kg.db, str(repo / "app" / "entities.py"))
+        assert entities["renamed_from"] == str(repo / "app" / "models.py")
+        assert [r["properties"]["id"] for r in kg.db.find_nodes(name="User", label="Class")] == user_id
+        assert _metadata(kg.db, repo)["git_commit"] == head
+    
+    def test_ignores_changes_outside_the_diff(self, kg, repo):
+        kg.process_codebase(str(repo))
+        base = _git(repo, "rev-parse", "HEAD")
+        (repo / "app" / "legacy.py").write_text("def older():\n    return 0\n")
+        
+        num_nodes, num_relations = kg.process_codebase(str(repo), changed_since=base)
+        
+        assert (num_nodes, num_relations) == (0, 0)
+        assert _names(kg.db, "Function") == ["load", "old"]
+    
+    def test_unknown_base_falls_back_to_a_full_index(self, kg, repo):
+        kg.process_codebase(str(repo))
+        
+        kg.process_codebase(str(repo), changed_since="no-such-ref")
+        
+        assert kg.last_run_stats["mode"] == "full"
+    
+    def test_outside_a_repository_falls_back_to_a_full_index(self, kg, tmp_path):
+        (tmp_path / "plain").mkdir()
+        (tmp_path / "plain" / "mod.py").write_text("def f():\n    pass\n")
+        
+        kg.process_codebase(str(tmp_path / "plain"), changed_since="main")
+        
+        assert kg.last_run_stats["mode"] == "full"
+        assert _names(kg.db, "Function") == ["f"]
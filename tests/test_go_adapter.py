@@ -0,0 +1,501 @@
+"""
+Go adapter tests.
+
+Parses the Go files in tests/fixtures/multi_lang_sample through
+MultiLanguageParser (so the second pass runs) and checks the graph
+structure produced for receivers, methods and generics, and the outline
+of sample.go.
+"""
+
+import json
+import os
+import sys
+import pytest
+
+# Add project root to Python path
+sys.path.insert(0, os.path.dirname(os.path.dirname(os.path.abspath(__file__))))
+
+pytest.importorskip("ast_grep_py")
+
+from src.ast_parser.multi_parser import MultiLanguageParser
+from src.mcp.outline import outline_from_parse
+from src.parallel.pipeline import ParserSettings
+
+
+FIXTURE_DIR = os.path.join(os.path.dirname(os.path.abspath(__file__)), "fixtures", "multi_lang_sample")
+
+
+class TestGoMethodReceivers:
+    """METHOD_OF edges from Go methods to their receiver types."""
+    
+    @pytest.fixture
+    def go_results(self):
+        """Parse the Go fixture package with the Go adapter enabled."""
+        coordinator = MultiLanguageParser(
+            use_ast_grep=True,
+            ast_grep_languages=['go'],
+            ast_grep_fallback=False
+        )
+        return coordinator.parse_directory(FIXTURE_DIR, build_index=True)
+    
+    def _type_node(self, nodes, name):
+        matches = [n for n in nodes.values() if n.node_type == "Class" and n.name == name]
+        assert len(matches) == 1, f"expected one {name} type node, got {len(matches)}"
+        return matches[0]
+    
+    def _methods_of(self, nodes, relations, type_node):
+        """Return {method name: receiver_kind} for METHOD_OF edges into type_node."""
+        return {
+            nodes[r.source_id].name: r.properties.get("receiver_kind")
+            for r in relations
+            if r.relation_type == "METHOD_OF" and r.target_id == type_node.node_id
+        }
+    
+    def test_person_has_all_methods(self, go_results):
+        """Pointer, value and cross-file methods all attach to Person."""
+        nodes, relations = go_results
+        person = self._type_node(nodes, "Person")
+        
+        assert self._methods_of(nodes, relations, person) == {
+            "GetName": "pointer",
+            "SetName": "pointer",
+            "GetAge": "pointer",
+            "String": "value",
+        }
+    
+    def test_methods_are_not_free_functions(self, go_results):
+        """Methods are Method nodes, never Function nodes."""
+        nodes, _ = go_results
+        function_names = {n.name for n in nodes.values() if n.node_type == "Function"}
+        
+        assert {"GetName", "SetName", "GetAge", "String"}.isdisjoint(function_names)
+        assert {"NewPerson", "Greet", "Add"} <= function_names
+    
+    def test_cross_file_method_also_defined_by_type(self, go_results):
+        """The second pass adds DEFINES for methods declared in another file."""
+        nodes, relations = go_results
+        person = self._type_node(nodes, "Person")
+        
+        defined = {
+            nodes[r.target_id].name
+            for r in relations
+            if r.relation_type == "DEFINES" and r.source_id == person.node_id
+        }
+        assert "String" in defined
+    
+    def test_named_builtin_type(self, go_results):
+        """`type ID string` is a type node that can own methods."""
+        nodes, relations = go_results
+        id_type = self._type_node(nodes, "ID")
+        
+        assert id_type.properties["type_kind"] == "named"
+        assert id_type.properties["underlying_type"] == "string"
+        assert self._methods_of(nodes, relations, id_type) == {"IsEmpty": "value"}
+    
+    def test_method_node_records_receiver(self, go_results):
+        """Method nodes keep the receiver type and kind as properties."""
+        nodes, _ = go_results
+        get_name = next(n for n in nodes.values() if n.node_type == "Method" and n.name == "GetName")
+        
+        assert get_name.properties["receiver_type"] == "Person"
+        assert get_name.properties["receiver_kind"] == "pointer"
+
+
+class TestGoCalls:
+    """CALLS edges between Go functions and methods."""
+    
+    @pytest.fixture
+    def go_results(self):
+        """Parse the Go fixture package with the Go adapter enabled."""
+        coordinator = MultiLanguageParser(
+            use_ast_grep=True,
+            ast_grep_languages=['go'],
+            ast_grep_fallback=False
+        )
+        return coordinator.parse_directory(FIXTURE_DIR, build_index=True)
+    
+    def _callees(self, nodes, relations, caller_name):
+        """Return {callee name: CALLS edge} for the named caller."""
+        callers = [n.node_id for n in nodes.values() if n.name == caller_name and n.node_type in ("Function", "Method")]
+        assert len(callers) == 1
+        return {
+            nodes[r.target_id].name: r
+            for r in relations
+            if r.relation_type == "CALLS" and r.source_id == callers[0]
+        }
+    
+    def test_constructor_calls_nothing(self, go_results):
+        """Composite literals are not calls."""
+        nodes, relations = go_results
+        assert self._callees(nodes, relations, "NewPerson") == {}
+    
+    def test_external_call_placeholder(self, go_results):
+        """fmt.Printf becomes a placeholder node that keeps its import path."""
+        nodes, relations = go_results
+        callees = self._callees(nodes, relations, "Greet")
+        
+        assert set(callees) == {"Printf"}
+        edge = callees["Printf"]
+        placeholder = nodes[edge.target_id]
+        assert placeholder.node_type == "ExternalFunction"
+        assert placeholder.properties["import_path"] == "fmt"
+        assert placeholder.properties["placeholder"] is True
+        assert edge.properties["line_no"] == 33
+    
+    def test_intra_package_and_receiver_calls(self, go_results):
+        """Cross-file functions and methods on a local variable's type resolve to real nodes."""
+        nodes, relations = go_results
+        callees = self._callees(nodes, relations, "Introduce")
+        
+        assert set(callees) == {"Greet", "GetName", "String"}
+        assert nodes[callees["Greet"].target_id].node_type == "Function"
+        assert nodes[callees["GetName"].target_id].node_type == "Method"
+        assert nodes[callees["String"].target_id].file_path.endswith("person_methods.go")
+        assert callees["Greet"].properties["call_lines"] == [18]
+        assert callees["String"].properties["line_no"] == 19
+    
+    def test_stdlib_call_from_method(self, go_results):
+        """strconv.Itoa from a value-receiver method is captured as external."""
+        nodes, relations = go_results
+        callees = self._callees(nodes, relations, "String")
+        
+        assert set(callees) == {"Itoa"}
+        assert nodes[callees["Itoa"].target_id].properties["import_path"] == "strconv"
+
+
+class TestGoInterfaces:
+    """Interface nodes and structural IMPLEMENTS edges."""
+    
+    def _parse(self):
+        coordinator = MultiLanguageParser(
+            use_ast_grep=True,
+            ast_grep_languages=['go'],
+            ast_grep_fallback=False
+        )
+        return coordinator.parse_directory(FIXTURE_DIR, build_index=True)
+    
+    @pytest.fixture
+    def go_results(self, monkeypatch):
+        """Parse the Go fixture package without near-miss reporting."""
+        monkeypatch.delenv("GO_IMPLEMENTS_NEAR_MISS", raising=False)
+        return self._parse()
+    
+    def _node(self, nodes, node_type, name):
+        matches = [n for n in nodes.values() if n.node_type == node_type and n.name == name]
+        assert len(matches) == 1, f"expected one {node_type} {name}, got {len(matches)}"
+        return matches[0]
+    
+    def _edges(self, nodes, relations, relation_type, type_name):
+        """Return {interface name: edge} for relation_type edges out of the named type."""
+        type_node = self._node(nodes, "Class", type_name)
+        return {
+            nodes[r.target_id].name: r
+            for r in relations
+            if r.relation_type == relation_type and r.source_id == type_node.node_id
+        }
+    
+    def test_interface_nodes_record_method_signatures(self, go_results):
+        """Parameter names are dropped from the recorded signatures."""
+        nodes, _ = go_results
+        shape = self._node(nodes, "Interface", "Shape")
+        resizer = self._node(nodes, "Interface", "Resizer")
+        
+        assert shape.properties["methods"] == ["Area() float64", "Perimeter() float64"]
+        assert resizer.properties["methods"] == ["Resize(float64) (bool, error)"]
+        assert not [n for n in nodes.values() if n.node_type == "Class" and n.name == "Shape"]
+    
+    def test_embedded_interfaces(self, go_results):
+        """Embedded interfaces become EMBEDS edges."""
+        nodes, relations = go_results
+        named_shape = self._node(nodes, "Interface", "NamedShape")
+        
+        embedded = {
+            nodes[r.target_id].name
+            for r in relations
+            if r.relation_type == "EMBEDS" and r.source_id == named_shape.node_id
+        }
+        assert named_shape.properties["methods"] == []
+        assert embedded == {"Shape", "Named"}
+    
+    def test_value_receivers_satisfy_via_value(self, go_results):
+        """Square satisfies Shape, Named and the flattened NamedShape with value receivers."""
+        nodes, relations = go_results
+        implements = self._edges(nodes, relations, "IMPLEMENTS", "Square")
+        
+        assert set(implements) == {"Shape", "Named", "NamedShape"}
+        assert {edge.properties["via"] for edge in implements.values()} == {"value"}
+    
+    def test_pointer_receivers_satisfy_only_via_pointer(self, go_results):
+        """Circle's methods all have pointer receivers, so only *Circle implements."""
+        nodes, relations = go_results
+        implements = self._edges(nodes, relations, "IMPLEMENTS", "Circle")
+        
+        assert set(implements) == {"Shape", "Named", "NamedShape", "Resizer"}
+        assert {edge.properties["via"] for edge in implements.values()} == {"pointer"}
+    
+    def test_partial_and_mismatched_implementations(self, go_results):
+        """A missing method or different result types do not satisfy an interface."""
+        nodes, relations = go_results
+        
+        assert set(self._edges(nodes, relations, "IMPLEMENTS", "Triangle")) == {"Named"}
+        assert self._edges(nodes, relations, "NEAR_IMPLEMENTS", "Triangle") == {}
+    
+    def test_unindexed_embedded_interface_is_skipped(self, go_results):
+        """Describer embeds fmt.Stringer, whose methods are unknown."""
+        nodes, relations = go_results
+        describer = self._node(nodes, "Interface", "Describer")
+        
+        assert describer.properties["embeds"] == ["fmt.Stringer"]
+        assert not [r for r in relations if r.relation_type == "IMPLEMENTS" and r.target_id == describer.node_id]
+    
+    def test_near_miss_mode_lists_missing_methods(self, monkeypatch):
+        """With GO_IMPLEMENTS_NEAR_MISS, types one method short are reported."""
+        monkeypatch.setenv("GO_IMPLEMENTS_NEAR_MISS", "true")
+        nodes, relations = self._parse()
+        near = self._edges(nodes, relations, "NEAR_IMPLEMENTS", "Triangle")
+        
+        assert set(near) == {"Shape", "NamedShape"}
+        assert near["Shape"].properties["missing_methods"] == ["Perimeter() float64"]
+        assert near["NamedShape"].properties["missing_methods"] == ["Perimeter() float64"]
+
+
+class TestGoStructEmbedding:
+    """EMBEDS edges for anonymous struct fields."""
+    
+    @pytest.fixture
+    def go_results(self, tmp_path):
+        """A module whose server package embeds types of its base package."""
+        (tmp_path / "go.mod").write_text("module example.com/app\n\ngo 1.21\n")
+        (tmp_path / "base").mkdir()
+        (tmp_path / "base" / "base.go").write_text(
+            "package base\n"
+            "\n"
+            "type Logger struct{}\n"
+            "\n"
+            "func (l *Logger) Log(msg string) {}\n"
+            "\n"
+            "type Store interface {\n"
+            "\tGet(key string) string\n"
+            "}\n"
+        )
+        (tmp_path / "server").mkdir()
+        (tmp_path / "server" / "server.go").write_text(
+            "package server\n"
+            "\n"
+            "import \"example.com/app/base\"\n"
+            "\n"
+            "type Core struct {\n"
+            "\t*base.Logger\n"
+            "\tName string\n"
+            "}\n"
+            "\n"
+            "func (c Core) Start() {}\n"
+            "\n"
+            "type Server struct {\n"
+            "\tCore\n"
+            "\tbase.Store\n"
+            "\terror\n"
+            "\tPort int `json:\"port\"`\n"
+            "}\n"
+        )
+        coordinator = MultiLanguageParser(
+            use_ast_grep=True,
+            ast_grep_languages=['go'],
+            ast_grep_fallback=False
+        )
+        return coordinator.parse_directory(str(tmp_path), build_index=True)
+    
+    def _embeds(self, nodes, relations, type_name):
+        """Return {embedded name: EMBEDS edge} for the named struct."""
+        source = [n for n in nodes.values() if n.node_type == "Class" and n.name == type_name]
+        assert len(source) == 1
+        return {
+            nodes[r.target_id].name: r
+            for r in relations
+            if r.relation_type == "EMBEDS" and r.source_id == source[0].node_id
+        }
+    
+    def test_value_and_interface_embeds(self, go_results):
+        """Named fields and predeclared types are not embeds."""
+        nodes, relations = go_results
+        embeds = self._embeds(nodes, relations, "Server")
+        
+        assert set(embeds) == {"Core", "Store"}
+        assert embeds["Core"].properties["embed_kind"] == "value"
+        assert nodes[embeds["Store"].target_id].node_type == "Interface"
+        server = [n for n in nodes.values() if n.name == "Server"][0]
+        assert server.properties["embeds"] == ["Core", "base.Store"]
+    
+    def test_pointer_embed_from_other_package(self, go_results):
+        """*base.Logger resolves through the import path to the base package."""
+        nodes, relations = go_results
+        embeds = self._embeds(nodes, relations, "Core")
+        
+        assert set(embeds) == {"Logger"}
+        edge = embeds["Logger"]
+        assert edge.properties["embed_kind"] == "pointer"
+        assert edge.properties["original_name"] == "*base.Logger"
+        assert nodes[edge.target_id].file_path.endswith(os.path.join("base", "base.go"))
+
+
+class TestGoSignatures:
+    """signature_json, arity and signature of Go functions and methods."""
+    
+    @pytest.fixture
+    def go_nodes(self, tmp_path):
+        (tmp_path / "go.mod").write_text("module example.com/app\n\ngo 1.21\n")
+        (tmp_path / "app.go").write_text(
+            "package app\n"
+            "\n"
+            "import \"context\"\n"
+            "\n"
+            "type Option func()\n"
+            "\n"
+            "type Client struct{}\n"
+            "\n"
+            "func Do[K comparable, V any](ctx context.Context, a, b int, opts ...Option) (n int, err error) {\n"
+            "\treturn 0, nil\n"
+            "}\n"
+            "\n"
+            "func (c *Client) Get(ctx context.Context, key string) (string, error) {\n"
+            "\treturn \"\", nil\n"
+            "}\n"
+        )
+        coordinator = MultiLanguageParser(
+            use_ast_grep=True,
+            ast_grep_languages=['go'],
+            ast_grep_fallback=False
+        )
+        nodes, _ = coordinator.parse_directory(str(tmp_path), build_index=True)
+        return {n.name: n for n in nodes.values() if n.node_type in ("Function", "Method")}
+    
+    def test_generic_function_with_named_results(self, go_nodes):
+        do = go_nodes["Do"]
+        signature = json.loads(do.properties["signature_json"])
+        
+        assert do.properties["arity"] == 4
+        assert [(p["name"], p["type"], p["variadic"]) for p in signature["parameters"]] == [
+            ("ctx", "context.Context", False), ("a", "int", False), ("b", "int", False), ("opts", "Option", True),
+        ]
+        assert signature["returns"] == [{"name": "n", "type": "int"}, {"name": "err", "type": "error"}]
+        assert signature["type_parameters"] == [
+            {"name": "K", "constraint": "comparable"}, {"name": "V", "constraint": "any"},
+        ]
+        assert do.properties["signature"] == (
+            "Do[K comparable, V any](ctx context.Context, a int, b int, opts ...Option) (n int, err error)"
+        )
+    
+    def test_method_keeps_normalized_signature(self, go_nodes):
+        get = go_nodes["Get"]
+        signature = json.loads(get.properties["signature_json"])
+        
+        assert get.properties["arity"] == 2
+        assert signature["returns"] == [{"name": None, "type": "string"}, {"name": None, "type": "error"}]
+        assert get.properties["signature"] == "Get(context.Context, string) (string, error)"
+
+
+class TestGoGenerics:
+    """Type parameters, constraint interfaces and instantiated calls of generics.go."""
+    
+    @pytest.fixture
+    def go_results(self):
+        coordinator = MultiLanguageParser(
+            use_ast_grep=True,
+            ast_grep_languages=['go'],
+            ast_grep_fallback=False
+        )
+        return coordinator.parse_directory(FIXTURE_DIR, build_index=True)
+    
+    def _node(self, nodes, node_type, name):
+        matches = [n for n in nodes.values() if n.node_type == node_type and n.name == name]
+        assert len(matches) == 1, f"expected one {node_type} {name}, got {len(matches)}"
+        return matches[0]
+    
+    def test_type_parameter_lists(self, go_results):
+        """`[T, U any]` is two type parameters, not part of the function name."""
+        nodes, _ = go_results
+        map_function = self._node(nodes, "Function", "Map")
+        signature = json.loads(map_function.properties["signature_json"])
+        
+        assert signature["type_parameters"] == [{"name": "T", "constraint": "any"}, {"name": "U", "constraint": "any"}]
+        assert map_function.properties["signature"] == "Map[T any, U any](xs []T, f func(T) U) []U"
+        assert not [n for n in nodes.values() if "[" in n.name]
+    
+    def test_generic_types_and_receivers(self, go_results):
+        """Methods on *Stack[T] belong to Stack."""
+        nodes, relations = go_results
+        stack = self._node(nodes, "Class", "Stack")
+        
+        assert stack.properties["type_parameters"] == ["T"]
+        assert self._node(nodes, "Class", "Pair").properties["type_parameters"] == ["K", "V"]
+        methods = {
+            nodes[r.source_id].name: r.properties["receiver_kind"]
+            for r in relations
+            if r.relation_type == "METHOD_OF" and r.target_id == stack.node_id
+        }
+        assert methods == {"Push": "pointer", "Pop": "pointer"}
+    
+    def test_constraint_interfaces_keep_type_sets(self, go_results):
+        nodes, relations = go_results
+        integer = self._node(nodes, "Interface", "Integer")
+        number = self._node(nodes, "Interface", "Number")
+        
+        assert integer.properties["constraint"] is True
+        assert integer.properties["type_set"] == ["~int | ~int8 | ~int16 | ~int32 | ~int64"]
+        assert number.properties["type_set"] == ["Integer | ~float32 | ~float64"]
+        constraints = {integer.node_id, number.node_id}
+        assert not [r for r in relations if r.relation_type == "IMPLEMENTS" and r.target_id in constraints]
+    
+    def test_constrained_by_edges(self, go_results):
+        """Only constraints declared in the repository get an edge."""
+        nodes, relations = go_results
+        number = self._node(nodes, "Interface", "Number")
+        
+        constrained = {
+            nodes[r.source_id].name: r.properties["type_parameters"]
+            for r in relations
+            if r.relation_type == "CONSTRAINED_BY"
+        }
+        assert constrained == {"Sum": ["N"], "Pair": ["V"]}
+        assert {r.target_id for r in relations if r.relation_type == "CONSTRAINED_BY"} == {number.node_id}
+    
+    def test_instantiated_calls_resolve_to_the_generic_declaration(self, go_results):
+        """Explicit and inferred instantiations share one CALLS edge per callee."""
+        nodes, relations = go_results
+        totals = self._node(nodes, "Function", "Totals")
+        
+        callees = {
+            nodes[r.target_id].name: r
+            for r in relations
+            if r.relation_type == "CALLS" and r.source_id == totals.node_id
+        }
+        assert set(callees) == {"Map", "NewStack", "Push", "Sum", "Pop"}
+        assert callees["Map"].target_id == self._node(nodes, "Function", "Map").node_id
+        assert callees["Map"].properties["call_lines"] == [63, 64]
+        assert callees["Sum"].properties["call_lines"] == [66, 67]
+        assert nodes[callees["Push"].target_id].node_type == "Method"
+
+
+class TestGoOutline:
+    """Line ranges and struct fields behind get_file_outline."""
+    
+    @pytest.fixture
+    def outline(self):
+        settings = ParserSettings(use_ast_grep=True, ast_grep_languages=("go",), ast_grep_fallback=False)
+        return outline_from_parse(os.path.join(FIXTURE_DIR, "sample.go"), settings)
+    
+    def test_struct_with_fields_and_methods(self, outline):
+        (person,) = outline["types"]
+        
+        assert (person["name"], person["line_no"], person["end_line_no"]) == ("Person", 8, 11)
+        assert [(f["name"], f["type"], f["line_no"]) for f in person["fields"]] == [
+            ("Name", "string", 9), ("Age", "int", 10),
+        ]
+        assert [(m["name"], m["line_no"], m["end_line_no"]) for m in person["methods"]] == [
+            ("GetName", 20, 22), ("SetName", 24, 26), ("GetAge", 28, 30),
+        ]
+    
+    def test_functions_span_their_bodies(self, outline):
+        assert [(f["name"], f["line_no"], f["end_line_no"]) for f in outline["functions"]] == [
+            ("NewPerson", 13, 18), ("Greet", 32, 34), ("Add", 36, 38),
+        ]
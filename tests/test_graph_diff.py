@@ -0,0 +1,252 @@
+"""
+Snapshot and graph diff tests.
+
+A small Python repository is built in tmp_path with the git command line:
+main holds app (models, service, legacy) and db, and the feature branch
+renames load to load_user, grows the complexity of check and makes it
+call into db, moves models.py to entities.py, deletes legacy.py and adds
+audit.py. Both refs are indexed as snapshots into one InMemoryGraphStore
+and compared.
+"""
+
+import asyncio
+import json
+import os
+import shutil
+import subprocess
+import sys
+from unittest.mock import MagicMock
+
+import pytest
+
+# Add project root to Python path
+sys.path.insert(0, os.path.dirname(os.path.dirname(os.path.abspath(__file__))))
+
+from src.analysis.graph_diff import delete_snapshots, diff_graphs, format_diff_report
+from src.graph_store import InMemoryGraphStore, RepositoryStore
+from src.indexing.git import GitError
+from src.indexing.snapshots import snapshot_repo_name
+from src.linking import link_repositories
+
+pytestmark = pytest.mark.skipif(shutil.which("git") is None, reason="git is not installed")
+
+GIT_ENV = {
+    "GIT_AUTHOR_NAME": "Ada",
+    "GIT_AUTHOR_EMAIL": "ada@example.com",
+    "GIT_COMMITTER_NAME": "Ada",
+    "GIT_COMMITTER_EMAIL": "ada@example.com",
+    "GIT_CONFIG_GLOBAL": os.devnull,
+    "GIT_CONFIG_NOSYSTEM": "1",
+}
+
+MAIN = {
+    "app/__init__.py": "",
+    "app/models.py": "class User:\n    def name(self):\n        return 'u'\n",
+    "app/service.py": "from app.models import User\n\n\ndef load():\n    return User()\n\n\ndef check(x):\n    return x\n",
+    "app/legacy.py": "def old():\n    return 1\n",
+    "db/__init__.py": "",
+    "db/store.py": "def save(x):\n    return x\n",
+}
+
+FEATURE = {
+    "app/models.py": None,
+    "app/entities.py": MAIN["app/models.py"],
+    "app/service.py": (
+        "from app.entities import User\nfrom db.store import save\n\n\n"
+        "def load_user():\n    return User()\n\n\n"
+        "def check(x):\n    if x > 1:\n        return save(x)\n    elif x < 0:\n        return 0\n"
+        "    for i in range(x):\n        if i:\n            return i\n    return x\n"
+    ),
+    "app/legacy.py": None,
+    "app/audit.py": "def audit():\n    return 2\n",
+}
+
+
+def _git(root, *args):
+    result = subprocess.run(["git", "-C", str(root), "-c", "commit.gpgsign=false", *args],
+                            capture_output=True, text=True, check=True, env={**os.environ, **GIT_ENV})
+    return result.stdout.strip()
+
+
+def _commit(root, files, message):
+    for path, source in files.items():
+        target = root / path
+        if source is None:
+            target.unlink()
+            continue
+        target.parent.mkdir(parents=True, exist_ok=True)
+        target.write_text(source)
+    _git(root, "add", "-A")
+    _git(root, "commit", "-q", "-m", message)
+    return _git(root, "rev-parse", "HEAD")
+
+
+@pytest.fixture
+def repo(tmp_path):
+    root = tmp_path / "repo"
+    root.mkdir()
+    _git(root, "init", "-q", "-b", "main")
+    _commit(root, MAIN, "Initial commit")
+    _git(root, "checkout", "-q", "-b", "feature")
+    _commit(root, FEATURE, "Feature")
+    _git(root, "checkout", "-q", "main")
+    return root
+
+
+@pytest.fixture
+def snapshots(monkeypatch, repo):
+    """Store holding the main and feature snapshots of the repository."""
+    monkeypatch.setenv("USE_AST_GREP", "false")
+    monkeypatch.setenv("ENABLE_JS_TS_PARSING", "false")
+    monkeypatch.setenv("PARALLEL_INDEXING_ENABLED", "false")
+    from src.main import CodebaseKnowledgeGraph
+    
+    store = InMemoryGraphStore()
+    for ref in ("main", "feature"):
+        kg = CodebaseKnowledgeGraph(store=store, embedding_provider=MagicMock(), repo=snapshot_repo_name(ref))
+        kg.process_snapshot(str(repo), ref)
+        kg.close()
+    return store
+
+
+def _names(entries):
+    return [entry["name"] for entry in entries]
+
+
+class TestSnapshots:
+    
+    def test_repo_names(self):
+        assert snapshot_repo_name("main") == "snap-main"
+        assert snapshot_repo_name("feature/login") == "snap-feature-login"
+        assert snapshot_repo_name("v1.2^{}") == "snap-v1.2"
+    
+    def test_snapshot_repositories(self, snapshots, repo):
+        (repository,) = RepositoryStore(snapshots, "snap-feature").find_nodes(label="Repository")
+        properties = repository["properties"]
+        
+        assert properties["snapshot_ref"] == "feature"
+        assert properties["snapshot_commit"] == _git(repo, "rev-parse", "feature")
+        assert properties["snapshot_of"] == os.path.realpath(str(repo))
+        assert not os.path.exists(properties["root"])
+        # The working copy keeps its branch and files, and no worktree is left behind
+        assert _git(repo, "symbolic-ref", "--short", "HEAD") == "main"
+        assert (repo / "app" / "legacy.py").exists()
+        assert len(_git(repo, "worktree", "list").splitlines()) == 1
+        feature = RepositoryStore(snapshots, "snap-feature")
+        assert [record["properties"]["name"] for record in feature.find_nodes(name="audit", label="Function")] == ["audit"]
+        assert RepositoryStore(snapshots, "snap-main").find_nodes(name="audit", label="Function") == []
+    
+    def test_unknown_ref(self, repo):
+        from src.indexing.snapshots import checkout_snapshot
+        
+        with pytest.raises(GitError):
+            with checkout_snapshot(str(repo), "no-such-branch"):
+                pass
+    
+    def test_snapshots_are_not_linked(self):
+        store = InMemoryGraphStore()
+        for repo, modules, extra in (("shop", ["shop"], {}), ("snap-shop", ["shop"], {"snapshot_ref": "main"}),
+                                     ("web", [], {})):
+            RepositoryStore(store, repo).batch_create_nodes([{
+                "labels": ["Base", "Repository"],
+                "properties": {"id": f"repository:{repo}", "name": repo, "modules": modules, **extra},
+            }])
+        for repo in ("web", "snap-shop"):
+            view = RepositoryStore(store, repo)
+            view.batch_create_nodes([
+                {"labels": ["Base", "Package"], "properties": {"id": "package:app", "name": "app", "path": "app"}},
+                {"labels": ["Base", "ExternalPackage"],
+                 "properties": {"id": "external_package:shop.cart", "name": "shop.cart", "module_path": "shop.cart"}},
+            ])
+            view.batch_create_relationships([{
+                "start_node_id": "package:app", "end_node_id": "external_package:shop.cart",
+                "type": "DEPENDS_ON", "properties": {"imports": 1, "files": 1},
+            }])
+        
+        assert link_repositories(store) == 1
+        (row,) = [row for row in store.neighbors(["web@package:app"], ["DEPENDS_ON"])
+                  if row["relationship"]["properties"].get("cross_repo")]
+        assert row["node"]["properties"]["id"] == "shop@repository:shop"
+
+
+class TestDiffGraphs:
+    
+    def test_package_dependencies(self, snapshots):
+        result = diff_graphs(snapshots, "snap-main", "snap-feature")
+        
+        assert result["dependencies"] == {"added": [{"from": "app", "to": "db", "imports": 1, "files": 1}], "removed": []}
+        assert (result["base"]["ref"], result["head"]["ref"]) == ("main", "feature")
+    
+    def test_symbols(self, snapshots):
+        symbols = diff_graphs(snapshots, "snap-main", "snap-feature")["symbols"]
+        
+        assert _names(symbols["added"]) == ["audit"]
+        assert _names(symbols["removed"]) == ["old"]
+        renamed = {(entry["from"]["name"], entry["to"]["name"], entry["kind"]) for entry in symbols["renamed"]}
+        assert renamed == {("load", "load_user", "renamed"), ("User", "User", "moved"), ("name", "name", "moved")}
+        (check,) = symbols["modified"]
+        assert (check["name"], check["file"], check["changes"]) == ("check", "app/service.py", ["body"])
+    
+    def test_complexity_and_edges(self, snapshots):
+        result = diff_graphs(snapshots, "snap-main", "snap-feature")
+        
+        (check,) = result["metrics"]["complexity_increased"]
+        assert (check["name"], check["before"]["complexity"], check["after"]["complexity"]) == ("check", 1, 5)
+        assert diff_graphs(snapshots, "snap-main", "snap-feature", complexity_threshold=4)["metrics"] == {
+            "complexity_increased": [],
+        }
+        # The import of User through its new module and the calls of renamed load_user are no changes
+        assert result["edges"]["added"] == [
+            {"type": "IMPORTS", "from": "app/service.py", "to": "save (db/store.py)"},
+            {"type": "CALLS", "from": "check (app/service.py)", "to": "save (db/store.py)"},
+        ]
+        assert result["edges"]["removed"] == []
+    
+    def test_path_prefix(self, snapshots):
+        result = diff_graphs(snapshots, "snap-main", "snap-feature", path_prefix="db/")
+        
+        assert result["path_prefix"] == "db"
+        assert result["symbols"] == {"added": [], "removed": [], "renamed": [], "modified": []}
+        assert len(result["dependencies"]["added"]) == 1
+        assert [edge["type"] for edge in result["edges"]["added"]] == ["IMPORTS", "CALLS"]
+    
+    def test_report_and_limits(self, snapshots):
+        result = diff_graphs(snapshots, "snap-main", "snap-feature", max_results=1)
+        
+        assert result["truncated"] == ["symbols.renamed", "edges.added"]
+        assert result["summary"]["symbols_renamed"] == 3 and len(result["symbols"]["renamed"]) == 1
+        assert "Lists cut to the first entries: symbols.renamed, edges.added" in format_diff_report(result)
+        report = format_diff_report(diff_graphs(snapshots, "snap-main", "snap-feature"))
+        assert "+ app -> db (1 import)" in report
+        assert "> Function load (app/service.py) renamed to load_user (app/service.py)" in report
+        with pytest.raises(ValueError):
+            diff_graphs(snapshots, "snap-main", "snap-main")
+        with pytest.raises(ValueError):
+            diff_graphs(snapshots, "snap-main", "snap-nothing")
+    
+    def test_delete_snapshots(self, snapshots):
+        RepositoryStore(snapshots, "default").batch_create_nodes([{
+            "labels": ["Base", "Repository"], "properties": {"id": "repository:default", "name": "default"},
+        }])
+        
+        assert delete_snapshots(snapshots, ["snap-main", "default", "snap-feature"]) == ["snap-main", "snap-feature"]
+        assert [record["properties"]["name"] for record in snapshots.find_nodes(label="Repository")] == ["default"]
+        assert snapshots.find_nodes(label="Function") == []
+
+
+def test_diff_graphs_tool(snapshots, make_server):
+    server = make_server(store=snapshots)
+    
+    def call(tool, **kwargs):
+        return json.loads(asyncio.run(server.mcp.tools[tool](**kwargs)))
+    
+    snapshots_listed = {repository["name"]: repository["snapshot_ref"] for repository in call("list_repositories")}
+    assert snapshots_listed == {"snap-feature": "feature", "snap-main": "main"}
+    
+    result = call("diff_graphs", base_repo="snap-main", head_repo="snap-feature", delete_snapshots=True)
+    
+    assert result["summary"]["dependencies_added"] == 1
+    assert result["report"].startswith("Graph diff snap-main (main @ ")
+    assert result["deleted"] == ["snap-main", "snap-feature"]
+    assert call("list_repositories") == []
+    assert "error" in call("diff_graphs", base_repo="snap-main", head_repo="snap-feature")
@@ -0,0 +1,155 @@
+"""
+Graph export tests.
+
+The serializers are checked for escaping (GraphML must parse as XML,
+DOT labels must keep quotes and backslashes literal), and the scope
+filters are run against a small graph in an InMemoryGraphStore.
+"""
+
+import os
+import sys
+import xml.etree.ElementTree as ET
+
+import pytest
+
+# Add project root to Python path
+sys.path.insert(0, os.path.dirname(os.path.dirname(os.path.abspath(__file__))))
+
+from src.export.graph_export import collect_subgraph, export_graph, quote_dot, to_dot, to_graphml
+from src.graph_store import InMemoryGraphStore
+
+GRAPHML_NS = "{http://graphml.graphdrawing.org/xmlns}"
+
+
+def _node(node_id, kind, name, file_path, line_no, **extra):
+    return dict(id=node_id, kind=kind, name=name, file_path=file_path, line_no=line_no, **extra)
+
+
+@pytest.fixture
+def fake_db():
+    nodes = [
+        _node("file:/repo/api/handler.py", "File", "handler.py", "/repo/api/handler.py", 1),
+        _node("function:/repo/api/handler.py:handle:3", "Function", "handle", "/repo/api/handler.py", 3,
+              doc='Handle a "quoted" request\nand return C:\\path'),
+        _node("function:/repo/core/parse.py:parse:1", "Function", "parse", "/repo/core/parse.py", 1),
+        _node("function:/repo/core/parse.py:tokenize:9", "Function", "tokenize", "/repo/core/parse.py", 9),
+        _node("function:/repo/apiary/bees.py:buzz:1", "Function", "buzz", "/repo/apiary/bees.py", 1),
+    ]
+    edges = [
+        {"source": "file:/repo/api/handler.py", "target": "function:/repo/api/handler.py:handle:3",
+         "type": "CONTAINS", "properties": {}},
+        {"source": "function:/repo/api/handler.py:handle:3", "target": "function:/repo/core/parse.py:parse:1",
+         "type": "CALLS", "properties": {"line_no": 4, "call_lines": [4, 6]}},
+        {"source": "function:/repo/core/parse.py:parse:1", "target": "function:/repo/core/parse.py:tokenize:9",
+         "type": "CALLS", "properties": {"line_no": 2}},
+    ]
+    store = InMemoryGraphStore()
+    store.batch_create_nodes([
+        {"labels": ["Base", node["kind"]], "properties": {key: value for key, value in node.items() if key != "kind"}}
+        for node in nodes
+    ])
+    store.batch_create_relationships([
+        {"start_node_id": edge["source"], "end_node_id": edge["target"], "type": edge["type"],
+         "properties": edge["properties"]}
+        for edge in edges
+    ])
+    return store
+
+
+class TestSerializers:
+    """Escaping and attributes of the GraphML and DOT output."""
+    
+    nodes = [
+        {"id": 'function:/a.py:say "hi":1', "kind": "Function", "name": 'say "hi"', "file_path": "/a.py",
+         "line_no": 1, "end_line_no": 2, "doc": 'Print <b> & "hi"\n\x01done'},
+        {"id": "external:fmt.Printf", "kind": "ExternalFunction", "name": "Printf", "file_path": None,
+         "line_no": None, "end_line_no": None, "doc": None},
+    ]
+    edges = [
+        {"source": 'function:/a.py:say "hi":1', "target": "external:fmt.Printf", "type": "CALLS",
+         "properties": {"line_no": 2, "call_lines": [2], "structural": True}},
+    ]
+    
+    def test_graphml_is_well_formed(self):
+        root = ET.fromstring(to_graphml(self.nodes, self.edges).encode("utf-8"))
+        
+        graph = root.find(f"{GRAPHML_NS}graph")
+        first = graph.findall(f"{GRAPHML_NS}node")[0]
+        data = {d.get("key"): d.text for d in first.findall(f"{GRAPHML_NS}data")}
+        
+        assert first.get("id") == 'function:/a.py:say "hi":1'
+        assert data["label"] == 'Function say "hi"'
+        assert data["file_path"] == "/a.py"
+        assert data["line_no"] == "1"
+        assert data["doc"] == 'Print <b> & "hi"\ndone'
+        
+        edge = graph.find(f"{GRAPHML_NS}edge")
+        edge_data = {d.get("key"): d.text for d in edge.findall(f"{GRAPHML_NS}data")}
+        assert edge_data == {"edge_label": "CALLS", "edge_call_lines": "[2]", "edge_line_no": "2",
+                             "edge_structural": "true"}
+        
+        key_types = {k.get("id"): k.get("attr.type") for k in root.findall(f"{GRAPHML_NS}key")}
+        assert key_types["line_no"] == "int"
+        assert key_types["edge_line_no"] == "long"
+        assert key_types["edge_structural"] == "boolean"
+    
+    def test_graphml_placeholder_keeps_file_path(self):
+        root = ET.fromstring(to_graphml(self.nodes, []).encode("utf-8"))
+        
+        placeholder = root.find(f"{GRAPHML_NS}graph").findall(f"{GRAPHML_NS}node")[1]
+        data = {d.get("key"): d.text for d in placeholder.findall(f"{GRAPHML_NS}data")}
+        
+        assert "file_path" in data
+        assert "line_no" not in data
+    
+    def test_dot_escaping(self):
+        dot = to_dot(self.nodes, self.edges)
+        
+        assert quote_dot('say "hi"') == '"say \\"hi\\""'
+        assert quote_dot("C:\\path\nnext") == '"C:\\\\path\\nnext"'
+        assert '"function:/a.py:say \\"hi\\":1" [label="Function say \\"hi\\"", kind="Function"' in dot
+        assert 'file_path="/a.py", line_no="1"' in dot
+        assert '"function:/a.py:say \\"hi\\":1" -> "external:fmt.Printf" [label="CALLS", call_lines="[2]"' in dot
+        # Line breaks inside a doc stay inside the quoted attribute
+        assert 'doc="Print <b> & \\"hi\\"\\ndone"' in dot
+        assert dot.count("\n") == 6
+        assert dot.startswith("digraph code_graph {") and dot.rstrip().endswith("}")
+
+
+class TestScopedExport:
+    """Directory and symbol neighbourhood filters."""
+    
+    def test_directory_subtree(self, fake_db):
+        nodes, edges = collect_subgraph(fake_db, path="/repo/api/")
+        
+        # /repo/apiary shares the prefix but is not under /repo/api
+        assert [node["id"] for node in nodes] == ["file:/repo/api/handler.py", "function:/repo/api/handler.py:handle:3"]
+        assert [edge["type"] for edge in edges] == ["CONTAINS"]
+    
+    def test_symbol_neighbourhood(self, fake_db):
+        one_hop, _ = collect_subgraph(fake_db, symbol="parse", hops=1)
+        two_hops, edges = collect_subgraph(fake_db, symbol="parse", hops=2)
+        
+        assert {node["name"] for node in one_hop} == {"handle", "parse", "tokenize"}
+        assert {node["name"] for node in two_hops} == {"handler.py", "handle", "parse", "tokenize"}
+        assert len(edges) == 3
+    
+    def test_symbol_and_directory_combine(self, fake_db):
+        nodes, edges = collect_subgraph(fake_db, path="/repo/core", symbol="parse", hops=1)
+        
+        assert [node["name"] for node in nodes] == ["parse", "tokenize"]
+        assert [edge["type"] for edge in edges] == ["CALLS"]
+    
+    def test_errors(self, fake_db):
+        with pytest.raises(ValueError, match="not found"):
+            collect_subgraph(fake_db, symbol="missing")
+        with pytest.raises(ValueError, match="hops"):
+            collect_subgraph(fake_db, symbol="parse", hops=-1)
+        with pytest.raises(ValueError, match="format"):
+            export_graph(fake_db, format="svg")
+    
+    def test_export_counts(self, fake_db):
+        document, node_count, edge_count = export_graph(fake_db, format="DOT", path="/repo/core")
+        
+        assert (node_count, edge_count) == (2, 1)
+        assert '[label="Function parse"' in document
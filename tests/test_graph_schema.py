@@ -0,0 +1,200 @@
+"""
+Graph schema version and migration tests.
+
+A graph written before versioning is seeded into an InMemoryGraphStore
+(docstring on symbols, nodes without a repo) and migrated step by step;
+the failure tests run a step that raises and check that the next run
+resumes from it. The end-to-end tests index a small Python codebase and
+read the schema version and the counts back through graph_info and the
+get_graph_info tool.
+"""
+
+import asyncio
+import json
+import os
+import sys
+from unittest.mock import MagicMock
+
+import pytest
+
+# Add project root to Python path
+sys.path.insert(0, os.path.dirname(os.path.dirname(os.path.abspath(__file__))))
+
+from src.graph_store import (
+    MIGRATIONS, SCHEMA_VERSION, InMemoryGraphStore, MigrationError, RepositoryStore, SchemaVersionError,
+    ensure_schema, migrate_graph, read_schema_version,
+)
+from src.graph_store.migrations import SCHEMA_NODE_ID, Migration, schema_metadata, write_schema_version
+from src.mcp.graph_info import graph_info
+
+SERVICE = "def charge(amount):\n    \"\"\"Charge the card.\"\"\"\n    return amount\n"
+
+
+def _legacy_store():
+    """A graph as written before schema versioning."""
+    store = InMemoryGraphStore()
+    store.batch_create_nodes([
+        {"labels": ["Base", "File"], "properties": {"id": "file:app/service.py", "name": "service.py"}},
+        {"labels": ["Base", "Function"], "properties": {
+            "id": "function:app/service.py:charge:1", "name": "charge", "docstring": "Charge the card.",
+        }},
+        {"labels": ["Base", "Class"], "properties": {
+            "id": "class:app/service.py:Card:5", "name": "Card", "docstring": "old", "doc": "A card.",
+        }},
+    ])
+    return store
+
+
+def _properties(store, node_id):
+    return store.get_nodes([node_id])[0]["properties"]
+
+
+class TestMigrations:
+    
+    def test_empty_graph(self):
+        store = InMemoryGraphStore()
+        
+        assert read_schema_version(store) is None
+        assert ensure_schema(store, auto_migrate=False) == {
+            "from_version": None, "schema_version": SCHEMA_VERSION, "applied": [],
+        }
+        # The server does not write into an empty graph
+        assert schema_metadata(store) == {}
+        
+        assert migrate_graph(store) == []
+        assert read_schema_version(store) == SCHEMA_VERSION
+    
+    def test_legacy_graph_is_migrated(self):
+        store = _legacy_store()
+        
+        assert read_schema_version(store) == 0
+        result = ensure_schema(store, auto_migrate=True)
+        
+        assert result == {"from_version": 0, "schema_version": SCHEMA_VERSION, "applied": [1, 2, 3]}
+        charge = _properties(store, "function:app/service.py:charge:1")
+        assert charge["doc"] == "Charge the card." and "docstring" not in charge
+        # A doc already in place wins over the old docstring
+        assert _properties(store, "class:app/service.py:Card:5")["doc"] == "A card."
+        assert _properties(store, "file:app/service.py")["repo"] == "default"
+        metadata = schema_metadata(store)
+        assert (metadata["schema_version"], metadata["repo"]) == (SCHEMA_VERSION, "all")
+        assert metadata["migrated_at"]
+        
+        assert migrate_graph(store) == []
+    
+    def test_failed_step_is_recorded_and_resumed(self):
+        store = _legacy_store()
+        
+        def broken(_store):
+            raise RuntimeError("disk full")
+        steps = [MIGRATIONS[0], Migration(2, "repo on every node", broken)]
+        
+        with pytest.raises(MigrationError, match="schema version 2") as error:
+            migrate_graph(store, steps)
+        
+        assert error.value.version == 2
+        metadata = schema_metadata(store)
+        assert metadata["schema_version"] == 1
+        assert (metadata["migration_failed_step"], metadata["migration_error"]) == (2, "disk full")
+        
+        assert migrate_graph(store) == [2, 3]
+        metadata = schema_metadata(store)
+        assert metadata["schema_version"] == SCHEMA_VERSION
+        assert "migration_failed_step" not in metadata and "migration_error" not in metadata
+    
+    def test_refused_without_auto_migrate(self, monkeypatch):
+        monkeypatch.setenv("GRAPH_AUTO_MIGRATE", "false")
+        store = _legacy_store()
+        
+        with pytest.raises(SchemaVersionError, match="--migrate"):
+            ensure_schema(store)
+        
+        assert read_schema_version(store) == 0
+    
+    def test_newer_graph_is_refused(self):
+        store = InMemoryGraphStore()
+        write_schema_version(store, SCHEMA_VERSION + 1)
+        
+        with pytest.raises(SchemaVersionError, match="newer"):
+            ensure_schema(store, auto_migrate=True)
+        with pytest.raises(SchemaVersionError, match="newer"):
+            migrate_graph(store)
+
+
+@pytest.fixture
+def indexed(monkeypatch, tmp_path):
+    monkeypatch.setenv("USE_AST_GREP", "false")
+    monkeypatch.setenv("ENABLE_JS_TS_PARSING", "false")
+    monkeypatch.setenv("PARALLEL_INDEXING_ENABLED", "false")
+    from src.main import CodebaseKnowledgeGraph
+    
+    (tmp_path / "service.py").write_text(SERVICE, encoding="utf-8")
+    store = InMemoryGraphStore()
+    kg = CodebaseKnowledgeGraph(store=store, embedding_provider=MagicMock())
+    kg._generate_embeddings = lambda *args, **kwargs: None
+    kg.process_codebase(str(tmp_path))
+    return kg, store, tmp_path
+
+
+class TestIndexedGraph:
+    
+    def test_indexer_records_the_version(self, indexed):
+        _, store, _ = indexed
+        
+        assert read_schema_version(store) == SCHEMA_VERSION
+        assert store.get_nodes([SCHEMA_NODE_ID])[0]["labels"] == ["Base", "SchemaMetadata"]
+        
+        RepositoryStore(store, "web").batch_create_nodes([
+            {"labels": ["Base", "File"], "properties": {"id": "file:web.py", "name": "web.py"}},
+        ])
+        store.delete_repository("web")
+        store.delete_repository("default")
+        # Nothing but the schema version is left
+        assert read_schema_version(store) == SCHEMA_VERSION
+    
+    def test_symbol_ids_are_migrated(self, indexed):
+        _, store, _ = indexed
+        indexed_ids = {node["properties"]["id"]: node["properties"]["symbol_id"]
+                       for node in store.find_nodes() if node["properties"].get("symbol_id")}
+        assert "default:service.py:function:charge" in indexed_ids.values()
+        store.update_node_properties([(node_id, {"symbol_id": None}) for node_id in indexed_ids])
+        write_schema_version(store, 2)
+        
+        assert migrate_graph(store) == [3]
+        assert {node["properties"]["id"]: node["properties"]["symbol_id"]
+                for node in store.find_nodes() if node["properties"].get("symbol_id")} == indexed_ids
+    
+    def test_graph_info(self, indexed):
+        _, store, _ = indexed
+        
+        info = graph_info(store)
+        
+        assert info["schema"] == {"version": SCHEMA_VERSION, "current_version": SCHEMA_VERSION, "up_to_date": True}
+        assert info["nodes_by_label"]["Function"] == 1
+        assert info["nodes_by_label"]["SchemaMetadata"] == 1
+        assert info["node_count"] == len(store.find_nodes())
+        assert info["relationship_count"] == sum(info["relationships_by_type"].values()) > 0
+        (index,) = info["indexes"]
+        assert (index["repo"], index["index_complete"]) == ("default", True)
+        assert index["indexed_at"]
+        
+        scoped = graph_info(store, repo="default")
+        assert "SchemaMetadata" not in scoped["nodes_by_label"]
+        assert scoped["node_count"] == info["node_count"] - 1
+        assert graph_info(store, repo="web")["node_count"] == 0
+
+
+class TestGraphInfoTool:
+    
+    def test_get_graph_info(self, indexed, make_server):
+        _, store, _ = indexed
+        server = make_server(store=store)
+        
+        def call(**kwargs):
+            return json.loads(asyncio.run(server.mcp.tools["get_graph_info"](**kwargs)))
+        
+        info = call()
+        assert info["schema"]["up_to_date"] is True
+        assert info["nodes_by_label"]["Function"] == 1
+        assert call(repo="default")["node_count"] == info["node_count"] - 1
+        assert call(repo="web")["indexes"] == []
@@ -0,0 +1,458 @@
+"""
+GraphStore conformance tests.
+
+The same graph is seeded into every backend and the query primitives and
+the MCP tools are checked against it, so a tool that works on one backend
+works on the other. The in-memory backend always runs; the Neo4j backend
+runs when GRAPH_STORE_TEST_NEO4J_URI points at a disposable database (it is
+cleared):
+
+    docker run -d --rm -p 7689:7687 -e NEO4J_AUTH=neo4j/conformance neo4j:latest
+    GRAPH_STORE_TEST_NEO4J_URI=bolt://localhost:7689 GRAPH_STORE_TEST_NEO4J_PASSWORD=conformance \\
+        pytest tests/test_graph_store_conformance.py
+"""
+
+import asyncio
+import json
+import os
+import sys
+from unittest.mock import MagicMock, patch
+
+import pytest
+
+# Add project root to Python path
+sys.path.insert(0, os.path.dirname(os.path.dirname(os.path.abspath(__file__))))
+
+from src.graph_store import InMemoryGraphStore, RepositoryStore, get_storage_backend
+from src.neo4j_storage.graph_db import Neo4jDatabase
+
+NEO4J_URI = os.getenv("GRAPH_STORE_TEST_NEO4J_URI")
+
+VECTOR_LABELS = ("Function", "Method", "Class", "File")
+
+# Query words -> vector dimension of KeywordProvider
+KEYWORDS = ("user", "valid", "handle", "start")
+
+
+def _node(label, node_id, name, file_path=None, line_no=None, **extra):
+    properties = {"id": node_id, "name": name, "file_path": file_path, "line_no": line_no, **extra}
+    return {"labels": ["Base", label], "properties": {k: v for k, v in properties.items() if v is not None}}
+
+
+def _rel(rel_type, start, end, **properties):
+    return {"start_node_id": start, "end_node_id": end, "type": rel_type, "properties": properties}
+
+
+MODELS_FILE = "file:app/models.py"
+SERVICE_FILE = "file:app/service.py"
+MODEL = "class:app/models.py:Model:1"
+USER = "class:app/models.py:User:10"
+SAVE = "method:app/models.py:User.save:12"
+CREATE_USER = "function:app/service.py:create_user:3"
+HANDLER = "function:app/service.py:handler:20"
+VALIDATE = "function:app/utils.py:validate:1"
+CORE = "class:app/core.go:Core:3"
+SERVER = "class:app/core.go:Server:10"
+START = "method:app/core.go:Core.Start:5"
+
+NODES = [
+    _node("File", MODELS_FILE, "models.py", "app/models.py", 0, path="app/models.py"),
+    _node("File", SERVICE_FILE, "service.py", "app/service.py", 0, path="app/service.py"),
+    _node("Module", "module:models", "models"),
+    _node("Class", MODEL, "Model", "app/models.py", 1),
+    _node("Class", USER, "User", "app/models.py", 10, embedding=[1.0, 0.0, 0.0, 0.0]),
+    _node("Method", SAVE, "save", "app/models.py", 12, embedding=[0.9, 0.1, 0.0, 0.0]),
+    _node("Function", CREATE_USER, "create_user", "app/service.py", 3, embedding=[0.5, 0.5, 0.0, 0.0],
+          code_snippet="def create_user(name):\n    user = User(name)\n    user.save()"),
+    _node("Function", HANDLER, "handler", "app/service.py", 20, embedding=[0.0, 0.0, 1.0, 0.0],
+          code_snippet="def handler(request):\n    return create_user(request.name)"),
+    _node("Function", VALIDATE, "validate", "app/utils.py", 1, embedding=[0.0, 1.0, 0.0, 0.0]),
+    _node("Class", CORE, "Core", "app/core.go", 3),
+    _node("Class", SERVER, "Server", "app/core.go", 10),
+    _node("Method", START, "Start", "app/core.go", 5),
+]
+
+RELATIONSHIPS = [
+    _rel("CONTAINS", MODELS_FILE, MODEL),
+    _rel("CONTAINS", MODELS_FILE, USER),
+    _rel("CONTAINS", SERVICE_FILE, CREATE_USER),
+    _rel("CONTAINS", SERVICE_FILE, HANDLER),
+    _rel("IMPORTS", SERVICE_FILE, "module:models"),
+    _rel("EXTENDS", USER, MODEL),
+    _rel("DEFINES", USER, SAVE),
+    _rel("CALLS", CREATE_USER, SAVE, line_no=5),
+    _rel("CALLS", CREATE_USER, VALIDATE, line_no=4),
+    _rel("CALLS", HANDLER, CREATE_USER, line_no=21, call_lines=[21]),
+    _rel("EMBEDS", SERVER, CORE, embed_kind="value", original_name="Core"),
+    _rel("DEFINES", CORE, START),
+    _rel("METHOD_OF", START, CORE, receiver_kind="pointer"),
+]
+
+
+class KeywordProvider:
+    """One dimension per keyword found in the text."""
+    
+    dimension = len(KEYWORDS)
+    
+    def embed_text(self, text):
+        words = text.lower()
+        return [1.0 if keyword in words else 0.0 for keyword in KEYWORDS]
+    
+    def embed_batch(self, texts):
+        return [self.embed_text(text) for text in texts]
+
+
+def _seed(store):
+    store.batch_create_nodes(NODES)
+    store.batch_create_relationships(RELATIONSHIPS)
+    return store
+
+
+def _neo4j_store():
+    db = Neo4jDatabase(
+        uri=NEO4J_URI,
+        user=os.getenv("GRAPH_STORE_TEST_NEO4J_USER", "neo4j"),
+        password=os.getenv("GRAPH_STORE_TEST_NEO4J_PASSWORD", "password"),
+    )
+    db.clear_database()
+    db.create_schema_constraints()
+    _seed(db)
+    for label in VECTOR_LABELS:
+        db.create_vector_index(f"{label.lower()}_vector_index", label, "embedding", dimension=KeywordProvider.dimension)
+    db.create_full_text_index("code_index", list(VECTOR_LABELS), ["name", "code_snippet"])
+    db.execute_cypher("CALL db.awaitIndexes()")
+    return db
+
+
+@pytest.fixture(params=["memory", "neo4j"])
+def store(request):
+    if request.param == "neo4j" and not NEO4J_URI:
+        pytest.skip("set GRAPH_STORE_TEST_NEO4J_URI to a disposable Neo4j to run the neo4j backend")
+    db = _seed(InMemoryGraphStore()) if request.param == "memory" else _neo4j_store()
+    yield db
+    db.close()
+
+
+@pytest.fixture
+def tools(store, make_server):
+    """Registered MCP tools bound to the store, returning parsed JSON."""
+    server = make_server(store=store, embedding_provider=KeywordProvider())
+    
+    def call(name, *args, **kwargs):
+        return json.loads(asyncio.run(server.mcp.tools[name](*args, **kwargs)))
+    return call
+
+
+def _ids(records):
+    return [record["properties"]["id"] for record in records]
+
+
+class TestQueryPrimitives:
+    """Lookup, neighbor, path and subgraph primitives."""
+    
+    def test_get_nodes_keeps_order_and_drops_vectors(self, store):
+        nodes = store.get_nodes([SAVE, "missing", USER])
+        
+        assert _ids(nodes) == [SAVE, USER]
+        assert nodes[0]["labels"] == ["Base", "Method"]
+        assert "embedding" not in nodes[1]["properties"]
+    
+    def test_find_nodes_filters(self, store):
+        assert _ids(store.find_nodes(path_prefixes=["app/models.py"])) == [MODELS_FILE, MODEL, USER, SAVE]
+        assert _ids(store.find_nodes(path_prefixes=["app/"], label="Function")) == [CREATE_USER, HANDLER, VALIDATE]
+        assert _ids(store.find_nodes(label="File", properties={"path": "app/service.py"})) == [SERVICE_FILE]
+        assert _ids(store.find_nodes(name="Core", label="Class")) == [CORE]
+        assert len(store.find_nodes(label="Class", limit=2)) == 2
+    
+    def test_node_names(self, store):
+        assert store.node_names(["Function", "Method"]) == ["Start", "create_user", "handler", "save", "validate"]
+        assert store.node_names(["Class"], limit=2) == ["Core", "Model"]
+        assert store.node_names(["Enum"]) == []
+    
+    def test_neighbors_directions(self, store):
+        both = store.neighbors([CORE], direction="both")
+        
+        assert [(row["relationship"]["type"], row["node"]["properties"]["name"]) for row in both] == [
+            ("DEFINES", "Start"), ("METHOD_OF", "Start"), ("EMBEDS", "Server"),
+        ]
+        calls = store.neighbors([CREATE_USER], ["CALLS"], direction="out")
+        assert [row["relationship"]["properties"]["line_no"] for row in calls] == [5, 4]
+        callers = store.neighbors([SAVE, VALIDATE], ["CALLS"], direction="in")
+        assert [(row["origin_id"], row["node"]["properties"]["id"]) for row in callers] == [
+            (SAVE, CREATE_USER), (VALIDATE, CREATE_USER),
+        ]
+        assert store.neighbors([USER], ["EXTENDS"], direction="out", label="File") == []
+    
+    def test_shortest_paths(self, store):
+        (path,) = store.shortest_paths(HANDLER, SAVE, ["CALLS"])
+        
+        assert _ids(path["nodes"]) == [HANDLER, CREATE_USER, SAVE]
+        assert [rel["start_node_id"] for rel in path["relationships"]] == [HANDLER, CREATE_USER]
+        assert store.shortest_paths(SAVE, HANDLER, ["CALLS"]) == []
+        assert store.shortest_paths(HANDLER, SAVE, ["CALLS"], max_depth=1) == []
+        reverse = store.shortest_paths(SAVE, HANDLER, ["CALLS"], direction="in")
+        assert _ids(reverse[0]["nodes"]) == [SAVE, CREATE_USER, HANDLER]
+        # save and validate both sit two undirected hops apart through create_user
+        assert len(store.shortest_paths(SAVE, VALIDATE, ["CALLS"], direction="both", limit=5)) == 1
+    
+    def test_edges_between(self, store):
+        edges = store.edges_between([SERVICE_FILE, CREATE_USER, HANDLER])
+        
+        assert sorted((edge["type"], edge["start_node_id"], edge["end_node_id"]) for edge in edges) == [
+            ("CALLS", HANDLER, CREATE_USER),
+            ("CONTAINS", SERVICE_FILE, CREATE_USER),
+            ("CONTAINS", SERVICE_FILE, HANDLER),
+        ]
+    
+    def test_delete_file_scope_removes_relationships(self, store):
+        assert store.delete_file_scope(["app/utils.py"]) == 1
+        
+        assert store.get_nodes([VALIDATE]) == []
+        assert [row["node"]["properties"]["name"] for row in store.neighbors([CREATE_USER], ["CALLS"])] == ["save"]
+    
+    def test_shortest_paths_min_confidence(self, store):
+        store.batch_create_relationships([
+            _rel("CALLS", HANDLER, SAVE, line_no=22, source="heuristic:interface_match", confidence=0.5),
+        ])
+        
+        (path,) = store.shortest_paths(HANDLER, SAVE, ["CALLS"])
+        assert _ids(path["nodes"]) == [HANDLER, SAVE]
+        # Edges without a confidence are exact facts
+        (exact,) = store.shortest_paths(HANDLER, SAVE, ["CALLS"], min_confidence=1.0)
+        assert _ids(exact["nodes"]) == [HANDLER, CREATE_USER, SAVE]
+    
+    def test_delete_stale_structural_relationships(self, store):
+        store.batch_create_relationships([
+            _rel("IMPLEMENTS", SERVER, CORE, structural=True, premises=json.dumps(["app/core.go"])),
+            _rel("DEPENDS_ON", SERVICE_FILE, MODELS_FILE, structural=True, premises=json.dumps(["app/service.py"])),
+            _rel("DEPENDS_ON", MODELS_FILE, SERVICE_FILE, structural=True, premises=json.dumps(["app/models.py"])),
+            _rel("DEPENDS_ON", CORE, SERVER, structural=True),
+        ])
+        
+        deleted = store.delete_stale_structural_relationships(["app/service.py"],
+                                                              [(MODELS_FILE, "DEPENDS_ON", SERVICE_FILE)])
+        
+        # Changed premises, a recomputed key and no premises at all make an edge stale
+        assert deleted == sorted([(SERVICE_FILE, "DEPENDS_ON", MODELS_FILE), (MODELS_FILE, "DEPENDS_ON", SERVICE_FILE),
+                                  (CORE, "DEPENDS_ON", SERVER)])
+        assert [row["node"]["properties"]["id"] for row in store.neighbors([SERVER], ["IMPLEMENTS"])] == [CORE]
+        assert store.neighbors([SERVICE_FILE, MODELS_FILE, CORE], ["DEPENDS_ON"]) == []
+        assert store.delete_stale_structural_relationships(["app/core.go"], [], repo="web") == []
+    
+    def test_repositories_are_separate(self, store):
+        web = RepositoryStore(store, "web")
+        web.batch_create_nodes([_node("File", MODELS_FILE, "models.py", "app/models.py", 0, path="app/models.py",
+                                      content_hash="web")])
+        
+        assert _ids(store.find_nodes(label="File", repo="web")) == ["web@" + MODELS_FILE]
+        assert _ids(web.find_nodes(label="File")) == [MODELS_FILE]
+        assert store.get_file_states(repo="web")["app/models.py"]["content_hash"] == "web"
+        assert sorted(store.get_file_states(repo="default")) == ["app/models.py", "app/service.py"]
+        assert store.delete_file_scope(["app/models.py"], repo="web") == 1
+        assert len(store.find_nodes(path_prefixes=["app/models.py"])) == 4
+        
+        web.batch_create_nodes([_node("File", SERVICE_FILE, "service.py", "app/service.py", 0)])
+        assert store.delete_repository("web") == 1
+        assert _ids(store.find_nodes(label="File")) == [MODELS_FILE, SERVICE_FILE]
+    
+    def test_update_node_properties(self, store):
+        updated = store.update_node_properties([
+            (SAVE, {"doc": "Persist the user.", "line_no": None, "id": "renamed"}),
+            ("missing", {"doc": "nowhere"}),
+        ])
+        
+        assert updated == 1
+        (node,) = store.get_nodes([SAVE])
+        assert node["properties"]["doc"] == "Persist the user."
+        assert "line_no" not in node["properties"]
+        assert node["properties"]["name"] == "save"
+        assert store.get_nodes(["renamed"]) == []
+        assert _ids(store.find_nodes(name="save", label="Method")) == [SAVE]
+    
+    def test_count_by_type(self, store):
+        counts = store.count_by_type()
+        
+        assert counts["nodes"]["Base"] == len(NODES)
+        assert counts["nodes"]["Function"] == 3
+        assert counts["nodes"]["File"] == 2
+        assert counts["relationships"]["CALLS"] == 3
+        assert sum(counts["relationships"].values()) == len(RELATIONSHIPS)
+        assert store.count_by_type(repo="web") == {"nodes": {}, "relationships": {}}
+
+
+class TestToolSuite:
+    """Every MCP tool answers the same on each backend."""
+    
+    def test_lookup_tools(self, tools):
+        (match,) = tools("get_code_by_name", "create_user")
+        assert match["n"]["id"] == CREATE_USER
+        assert "embedding" not in match["n"]
+        assert tools("get_code_by_name", "User", node_type="Function") == []
+        
+        assert [row["caller"]["name"] for row in tools("find_function_callers", "save")] == ["create_user"]
+        assert [row["callee"]["name"] for row in tools("find_function_callees", "create_user")] == ["save", "validate"]
+        assert [row["callee"]["name"] for row in tools("find_function_callees", "create_user", limit=1)] == ["save"]
+        # Edges written without provenance are reported as AST facts
+        assert tools("find_function_callers", "save")[0]["edge"] == {"source": "ast", "confidence": 1.0,
+                                                                     "indexer_version": None}
+    
+    def test_inheritance_and_dependencies(self, tools):
+        user = tools("find_class_inheritance", "User")
+        assert [row["super"]["name"] for row in user["superclasses"]] == ["Model"]
+        assert user["subclasses"] == []
+        assert [row["sub"]["name"] for row in tools("find_class_inheritance", "Model")["subclasses"]] == ["User"]
+        
+        assert [row["m"]["name"] for row in tools("find_file_dependencies", "app/service.py")["imports"]] == ["models"]
+        assert [row["f"]["name"] for row in tools("find_file_dependencies", "app/models.py")["imported_by"]] == ["service.py"]
+    
+    def test_symbols_and_members(self, tools):
+        (save,) = tools("find_symbol", "save")
+        assert (save["node_type"], save["owner"], save["receiver_kind"]) == ("Method", "User", None)
+        (start,) = tools("find_symbol", "Start")
+        assert (start["owner"], start["receiver_kind"]) == ("Core", "pointer")
+        
+        (user,) = tools("get_type_members", "User")
+        assert [member["name"] for member in user["members"]] == ["save"]
+        (server,) = tools("get_type_members", "Server")
+        assert server["members"] == []
+        assert [embed["name"] for embed in server["embeds"]] == ["Core"]
+        assert [(member["name"], member["receiver_kind"]) for member in server["promoted"]] == [("Start", "pointer")]
+    
+    def test_references_and_paths(self, tools):
+        refs = tools("find_references", "save")
+        assert (refs["status"], refs["total"]) == ("ok", 1)
+        assert (refs["references"][0]["name"], refs["references"][0]["line_no"]) == ("create_user", 5)
+        calls = tools("find_references", "create_user", kind="calls")
+        assert calls["references"][0]["call_lines"] == [21]
+        
+        path = tools("find_path", "handler", "save")
+        assert path["status"] == "ok"
+        assert [hop["relation_type"] for hop in path["paths"][0]["hops"]] == ["CALLS", "CALLS"]
+        assert tools("find_path", "save", "handler")["status"] == "no_path"
+    
+    def test_export(self, tools):
+        result = tools("export_graph", format="dot", path="app/service.py")
+        
+        assert (result["node_count"], result["edge_count"]) == (3, 3)
+        assert "create_user" in result["content"]
+        hops = tools("export_graph", symbol="Core", hops=1)
+        assert hops["node_count"] == 3
+    
+    def test_search(self, tools):
+        results = tools("semantic_search", "who saves the user", limit=2)["results"]
+        assert [result["name"] for result in results] == ["User", "save"]
+        
+        assert tools("search_code", "user", limit=1)[0]["node"]["name"] == "User"
+        assert tools("search_code", "handler", search_type="text")[0]["node"]["name"] == "handler"
+    
+    def test_watch_status(self, tools):
+        assert tools("get_watch_status")["running"] is False
+
+
+class TestMemoryBackend:
+    """Persistence and selection of the in-memory backend."""
+    
+    def test_json_round_trip(self, tmp_path):
+        graph_file = tmp_path / "graph" / "graph.json"
+        store = _seed(InMemoryGraphStore(str(graph_file)))
+        store.close()
+        
+        loaded = InMemoryGraphStore(str(graph_file))
+        assert _ids(loaded.find_nodes(label="Function")) == [CREATE_USER, HANDLER, VALIDATE]
+        assert loaded.search_similar_nodes([1.0, 0.0, 0.0, 0.0], ["Class"], limit=1)[0]["node"]["id"] == USER
+        assert len(loaded.edges_between(_ids(loaded.find_nodes()))) == len(RELATIONSHIPS)
+        assert [row["node"]["properties"]["id"] for row in loaded.neighbors([HANDLER], ["CALLS"])] == [CREATE_USER]
+    
+    def test_file_is_written_only_when_changed(self, tmp_path):
+        graph_file = tmp_path / "graph.json"
+        InMemoryGraphStore(str(graph_file)).close()
+        assert not graph_file.exists()
+        
+        store = _seed(InMemoryGraphStore(str(graph_file)))
+        store.flush()
+        mtime = graph_file.stat().st_mtime_ns
+        store.close()
+        assert graph_file.stat().st_mtime_ns == mtime
+    
+    def test_cypher_is_reported_as_unsupported(self, tmp_path, make_server):
+        server = make_server(storage="memory", embedding_provider=KeywordProvider())
+        
+        result = json.loads(asyncio.run(server.mcp.tools["execute_cypher_query"]("MATCH (n) RETURN n")))
+        assert "does not run Cypher" in result["error"]
+        result = json.loads(asyncio.run(server.mcp.tools["run_query"]("MATCH (n) RETURN n")))
+        assert result["unsupported"] is True
+        assert "unsupported for this backend" in result["error"]
+    
+    def test_storage_selection(self, monkeypatch):
+        assert get_storage_backend() == "neo4j"
+        monkeypatch.setenv("GRAPH_STORAGE", "Memory")
+        assert get_storage_backend() == "memory"
+        with pytest.raises(ValueError, match="Unknown storage backend"):
+            get_storage_backend("sqlite")
+    
+    def test_index_then_serve_from_file(self, tmp_path, monkeypatch, make_server):
+        monkeypatch.setenv("USE_AST_GREP", "false")
+        monkeypatch.setenv("ENABLE_JS_TS_PARSING", "false")
+        monkeypatch.setenv("PARALLEL_INDEXING_ENABLED", "false")
+        codebase = tmp_path / "code"
+        codebase.mkdir()
+        (codebase / "billing.py").write_text("def charge(amount):\n    return amount\n")
+        (codebase / "shop.py").write_text("from billing import charge\n\n\ndef checkout():\n    return charge(3)\n")
+        graph_file = str(tmp_path / "graph.json")
+        
+        from src.main import CodebaseKnowledgeGraph
+        
+        kg = CodebaseKnowledgeGraph(storage="memory", graph_file=graph_file, embedding_provider=KeywordProvider())
+        kg.process_codebase(str(codebase))
+        kg.close()
+        
+        server = make_server(storage="memory", graph_file=graph_file, embedding_provider=KeywordProvider())
+        callers = json.loads(asyncio.run(server.mcp.tools["find_function_callers"]("charge")))
+        assert [row["caller"]["name"] for row in callers] == ["checkout"]
+
+
+class FakeSession:
+    def __init__(self, queries):
+        self.queries = queries
+    
+    def __enter__(self):
+        return self
+    
+    def __exit__(self, *exc):
+        return False
+    
+    def run(self, query, parameters=None):
+        self.queries.append(query)
+        return []
+
+
+class FakeDriver:
+    def __init__(self):
+        self.queries = []
+    
+    def session(self, database=None):
+        return FakeSession(self.queries)
+
+
+class TestNeo4jQueries:
+    """Cypher built by the Neo4j primitives, checked without a database."""
+    
+    def test_shortest_path_patterns(self):
+        driver = FakeDriver()
+        with patch("src.neo4j_storage.graph_db.GraphDatabase.driver", return_value=driver):
+            db = Neo4jDatabase(uri="bolt://fake", user="neo4j", password="fake")
+        
+        db.shortest_paths("a", "b", ["CALLS", "IMPORTS_FROM"], direction="in", max_depth=3)
+        db.shortest_paths("a", "b", ["CALLS"], direction="both", max_depth=3, limit=2)
+        
+        assert "shortestPath((a)<-[:CALLS|IMPORTS_FROM*..3]-(b))" in driver.queries[0]
+        assert "allShortestPaths((a)-[:CALLS*..3]-(b))" in driver.queries[1]
+        assert "coalesce(r.confidence, 1.0)" not in driver.queries[1]
+        db.shortest_paths("a", "b", ["CALLS"], min_confidence=1.0)
+        assert "all(r IN relationships(p) WHERE coalesce(r.confidence, 1.0) >= $min_confidence)" in driver.queries[2]
+        with pytest.raises(ValueError, match="relationship type"):
+            db.shortest_paths("a", "b", ["CALLS]->() DELETE r //"])
+        with pytest.raises(ValueError, match="direction"):
+            db.neighbors(["a"], direction="sideways")
+        with pytest.raises(ValueError, match="label"):
+            db.find_nodes(label="Class) DETACH DELETE n //")
@@ -0,0 +1,290 @@
+"""
+Batched UNWIND write tests.
+
+The unit tests run Neo4jDatabase against a fake driver that records every
+statement, so they check grouping, transactions and error reporting
+without a database. The benchmark compares the old per-item statements
+with the UNWIND writer on a real, disposable Neo4j and only runs when
+RUN_BENCHMARKS and BENCHMARK_NEO4J_URI are set; it clears that database:
+
+    docker run -d --rm -p 7688:7687 -e NEO4J_AUTH=neo4j/benchmark neo4j:latest
+    RUN_BENCHMARKS=1 BENCHMARK_NEO4J_URI=bolt://localhost:7688 BENCHMARK_NEO4J_PASSWORD=benchmark \\
+        pytest tests/test_graph_write_batches.py -s
+"""
+
+import os
+import sys
+import time
+from unittest.mock import patch
+
+import pytest
+
+# Add project root to Python path
+sys.path.insert(0, os.path.dirname(os.path.dirname(os.path.abspath(__file__))))
+
+from src.neo4j_storage.graph_db import Neo4jDatabase
+from src.neo4j_storage.write_queries import (
+    GraphWriteError,
+    group_nodes,
+    node_batch_query,
+    relationship_batch_query,
+)
+
+# Nodes (and twice as many CALLS relationships) written by the benchmark
+BENCHMARK_NODES = int(os.getenv("BENCHMARK_WRITE_NODES", "10000"))
+
+
+class FakeResult:
+    def __init__(self, rows=None):
+        self.rows = rows or []
+    
+    def data(self):
+        return self.rows
+
+
+class FakeTransaction:
+    def __init__(self, driver):
+        self.driver = driver
+        self.runs = []
+    
+    def __enter__(self):
+        return self
+    
+    def __exit__(self, *exc):
+        return False
+    
+    def run(self, query, parameters=None):
+        if self.driver.fail_on and self.driver.fail_on in query:
+            raise RuntimeError(self.driver.error_message)
+        self.runs.append((query, parameters))
+        return FakeResult()
+    
+    def commit(self):
+        self.driver.transactions.append(self.runs)
+
+
+class FakeSession:
+    def __init__(self, driver):
+        self.driver = driver
+    
+    def __enter__(self):
+        return self
+    
+    def __exit__(self, *exc):
+        return False
+    
+    def begin_transaction(self):
+        return FakeTransaction(self.driver)
+    
+    def run(self, query, parameters=None):
+        self.driver.session_runs.append(query)
+        if query == "SHOW CONSTRAINTS":
+            return FakeResult([{"name": name} for name in self.driver.existing])
+        if query == "SHOW INDEXES":
+            return FakeResult([{"name": name} for name in self.driver.existing])
+        return FakeResult()
+
+
+class FakeDriver:
+    """Records committed transactions; a statement containing fail_on raises error_message."""
+    
+    def __init__(self, fail_on=None, error_message="", existing=()):
+        self.fail_on = fail_on
+        self.error_message = error_message
+        self.existing = list(existing)
+        self.transactions = []
+        self.session_runs = []
+    
+    def session(self, database=None):
+        return FakeSession(self)
+
+
+def _db(driver):
+    with patch("src.neo4j_storage.graph_db.GraphDatabase.driver", return_value=driver):
+        return Neo4jDatabase(uri="bolt://fake", user="neo4j", password="fake")
+
+
+def _node(node_type, name, line_no=1, file_path="app/models.py"):
+    node_id = f"{node_type.lower()}:{file_path}:{name}:{line_no}"
+    return {"labels": ["Base", node_type],
+            "properties": {"id": node_id, "name": name, "file_path": file_path, "line_no": line_no}}
+
+
+def _rel(rel_type, start, end, **props):
+    return {"start_node_id": start, "end_node_id": end, "type": rel_type, "properties": props}
+
+
+class TestQueries:
+    """Statement text and grouping."""
+    
+    def test_node_query_merges_on_base_id(self):
+        query = node_batch_query(("Base", "Function"))
+        assert query.startswith("UNWIND $batch AS row")
+        assert "MERGE (n:Base {id: row.id})" in query
+        assert "SET n:`Function`" in query
+    
+    def test_identifiers_are_validated(self):
+        with pytest.raises(ValueError, match="label"):
+            node_batch_query(("Base", "Function`) DETACH DELETE n //"))
+        with pytest.raises(ValueError, match="relationship type"):
+            relationship_batch_query("CALLS]->() DELETE r //")
+    
+    def test_grouping_keeps_order(self):
+        groups = group_nodes([_node("Function", "a"), _node("Class", "B"), _node("Function", "c")])
+        assert list(groups) == [("Base", "Function"), ("Base", "Class")]
+        assert [row["name"] for row in groups[("Base", "Function")]] == ["a", "c"]
+
+
+class TestBatchedWrites:
+    """Neo4jDatabase writes one statement per group, one transaction per batch."""
+    
+    def test_nodes_one_statement_per_label_set(self):
+        driver = FakeDriver()
+        nodes = [_node("Function", "a"), _node("Class", "B"), _node("Function", "c"),
+                 _node("Method", "d"), _node("Class", "E")]
+        
+        _db(driver).batch_create_nodes(nodes, batch_size=10)
+        
+        assert len(driver.transactions) == 1
+        runs = driver.transactions[0]
+        assert [len(params["batch"]) for _, params in runs] == [2, 2, 1]
+        assert all(query.count("UNWIND") == 1 for query, _ in runs)
+    
+    def test_batch_size_splits_transactions(self):
+        driver = FakeDriver()
+        
+        _db(driver).batch_create_nodes([_node("Function", f"f{i}", i) for i in range(5)], batch_size=2)
+        
+        assert [sum(len(params["batch"]) for _, params in runs) for runs in driver.transactions] == [2, 2, 1]
+    
+    def test_relationships_grouped_by_type(self):
+        driver = FakeDriver()
+        rels = [_rel("CALLS", "a", "b", line_no=3), _rel("CONTAINS", "f", "a"), _rel("CALLS", "b", "c")]
+        
+        _db(driver).batch_create_relationships(rels, batch_size=10)
+        
+        runs = driver.transactions[0]
+        assert ["[r:`CALLS`]" in query for query, _ in runs] == [True, False]
+        assert runs[0][1]["batch"][0] == {"start_id": "a", "end_id": "b", "props": {"line_no": 3}}
+
+
+class TestWriteErrors:
+    """GraphWriteError names the offending file and symbol."""
+    
+    def test_constraint_violation_names_symbol(self):
+        nodes = [_node("Function", "load", 3), _node("Function", "save", 12)]
+        driver = FakeDriver(
+            fail_on="SET n:`Function`",
+            error_message="Node(7) already exists with label `Base` and property `id` = "
+                          f"'{nodes[1]['properties']['id']}'",
+        )
+        
+        with pytest.raises(GraphWriteError) as excinfo:
+            _db(driver).batch_create_nodes(nodes)
+        
+        error = excinfo.value
+        assert (error.kind, error.group) == ("nodes", "Base:Function")
+        assert error.items == [f"'save' at app/models.py:12 (id {nodes[1]['properties']['id']!r})"]
+        assert driver.transactions == []
+    
+    def test_missing_id_and_invalid_property(self):
+        bad = _node("Class", "Broken")
+        bad["properties"]["id"] = None
+        driver = FakeDriver(fail_on="MERGE", error_message="Cannot merge node using null property value for id")
+        with pytest.raises(GraphWriteError, match="'Broken' at app/models.py:1"):
+            _db(driver).batch_create_nodes([_node("Class", "Fine"), bad])
+        
+        driver = FakeDriver(fail_on="CREATE (start)", error_message="Property values can only be of primitive types")
+        rels = [_rel("CALLS", "a", "b"), _rel("CALLS", "a", "c", args={"x": 1})]
+        with pytest.raises(GraphWriteError, match=r"'a' -> 'c' property 'args'"):
+            _db(driver).batch_create_relationships(rels)
+
+
+class TestSchemaConstraints:
+    """The node key constraint and symbol index are created once."""
+    
+    def test_created_when_missing(self):
+        driver = FakeDriver()
+        _db(driver).create_schema_constraints()
+        
+        assert any("CREATE CONSTRAINT base_id_constraint FOR (n:Base) REQUIRE n.id IS UNIQUE" in q
+                   for q in driver.session_runs)
+        assert any("CREATE INDEX base_symbol_idx FOR (n:Base) ON (n.file_path, n.name)" in q
+                   for q in driver.session_runs)
+    
+    def test_skipped_when_present(self):
+        driver = FakeDriver(existing=["base_id_constraint", "base_symbol_idx"])
+        _db(driver).create_schema_constraints()
+        
+        assert not any("base_id_constraint" in q or "base_symbol_idx" in q for q in driver.session_runs)
+
+
+def _legacy_write(db, nodes, relationships, batch_size):
+    """The per-item statements the writer used before UNWIND batching."""
+    with db.driver.session(database=db.database) as session:
+        for i in range(0, len(nodes), batch_size):
+            with session.begin_transaction() as tx:
+                for node in nodes[i:i+batch_size]:
+                    labels = "".join(f":{label}" for label in node["labels"])
+                    props = ", ".join(f"{key}: ${key}" for key in node["properties"])
+                    tx.run(f"CREATE (n{labels} {{{props}}}) RETURN n", node["properties"])
+                tx.commit()
+        for i in range(0, len(relationships), batch_size):
+            with session.begin_transaction() as tx:
+                for rel in relationships[i:i+batch_size]:
+                    tx.run(
+                        f"MATCH (start:Base {{id: $start_id}}) MATCH (end:Base {{id: $end_id}}) "
+                        f"CREATE (start)-[r:{rel['type']}]->(end) SET r = $props RETURN r",
+                        {"start_id": rel["start_node_id"], "end_id": rel["end_node_id"], "props": rel["properties"]},
+                    )
+                tx.commit()
+
+
+def _drop_schema(db):
+    for row in db.execute_cypher("SHOW CONSTRAINTS"):
+        db.execute_cypher(f"DROP CONSTRAINT {row['name']}")
+    for row in db.execute_cypher("SHOW INDEXES"):
+        if row.get("type") != "LOOKUP" and not row.get("owningConstraint"):
+            db.execute_cypher(f"DROP INDEX {row['name']}")
+
+
+@pytest.mark.skipif(not (os.getenv("RUN_BENCHMARKS") and os.getenv("BENCHMARK_NEO4J_URI")),
+                    reason="set RUN_BENCHMARKS=1 and BENCHMARK_NEO4J_URI to a disposable Neo4j to run the write benchmark")
+def test_write_benchmark():
+    """Per-item statements without the node key index vs UNWIND batches with it."""
+    db = Neo4jDatabase(
+        uri=os.environ["BENCHMARK_NEO4J_URI"],
+        user=os.getenv("BENCHMARK_NEO4J_USER", "neo4j"),
+        password=os.getenv("BENCHMARK_NEO4J_PASSWORD", "password"),
+    )
+    node_types = ["Function", "Method", "Class", "Variable"]
+    nodes = [_node(node_types[i % 4], f"symbol_{i}", i, f"pkg/module_{i // 50}.py") for i in range(BENCHMARK_NODES)]
+    ids = [node["properties"]["id"] for node in nodes]
+    rels = [_rel("CALLS", ids[i], ids[(i * 7 + k) % len(ids)], line_no=i) for i in range(len(ids)) for k in (1, 2)]
+    
+    try:
+        db.clear_database()
+        _drop_schema(db)
+        start = time.perf_counter()
+        _legacy_write(db, nodes, rels, batch_size=1000)
+        before = time.perf_counter() - start
+        
+        db.clear_database()
+        db.create_schema_constraints()
+        start = time.perf_counter()
+        db.batch_create_nodes(nodes, batch_size=1000)
+        db.batch_create_relationships(rels, batch_size=1000)
+        after = time.perf_counter() - start
+        
+        count = db.execute_cypher("MATCH ()-[r:CALLS]->() RETURN count(r) AS n")[0]["n"]
+        assert count == len(rels)
+        items = len(nodes) + len(rels)
+        print(
+            f"\n{len(nodes)} nodes, {len(rels)} relationships: per-item {before:.2f}s "
+            f"({items / before:.0f} items/s), UNWIND {after:.2f}s ({items / after:.0f} items/s, "
+            f"{before / after:.1f}x)"
+        )
+        assert after < before
+    finally:
+        db.clear_database()
+        db.close()
@@ -0,0 +1,190 @@
+"""
+HTTP transport tests.
+
+Listen addresses, the bearer token check (driven as a bare ASGI app) and
+the in-flight tool call counting a graceful shutdown waits on: draining
+refuses new calls, waits for the running ones and gives up after the
+timeout. The server's tools are checked to be counted whatever the
+transport.
+"""
+
+import asyncio
+import inspect
+import json
+import os
+import sys
+
+import pytest
+
+# Add project root to Python path
+sys.path.insert(0, os.path.dirname(os.path.dirname(os.path.abspath(__file__))))
+
+pytest.importorskip("mcp.server.fastmcp")
+
+from src.graph_store import InMemoryGraphStore
+from src.mcp.transport import (
+    BearerTokenMiddleware,
+    InFlightCalls,
+    ShuttingDownError,
+    get_auth_token,
+    get_shutdown_timeout,
+    parse_listen,
+    track_tool_calls,
+)
+
+
+def request(headers=None):
+    """Run one HTTP request through the middleware, return (status, messages sent, whether the app ran)."""
+    ran = []
+    
+    async def app(scope, receive, send):
+        ran.append(scope["path"])
+        await send({"type": "http.response.start", "status": 200, "headers": []})
+        await send({"type": "http.response.body", "body": b"ok"})
+    
+    sent = []
+    
+    async def send(message):
+        sent.append(message)
+    
+    async def receive():
+        return {"type": "http.request", "body": b""}
+    
+    scope = {"type": "http", "path": "/mcp", "headers": headers or []}
+    asyncio.run(BearerTokenMiddleware(app, "s3cret")(scope, receive, send))
+    return sent[0]["status"], sent, bool(ran)
+
+
+class TestConfiguration:
+    
+    @pytest.mark.parametrize("listen, expected", [
+        (":8432", ("0.0.0.0", 8432)),
+        ("127.0.0.1:9000", ("127.0.0.1", 9000)),
+        ("[::1]:8432", ("::1", 8432)),
+        ("mcp.internal:80", ("mcp.internal", 80)),
+    ])
+    def test_parse_listen(self, listen, expected):
+        assert parse_listen(listen) == expected
+    
+    @pytest.mark.parametrize("listen", ["8432", ":http", "host:", ":0", ":70000"])
+    def test_parse_listen_rejects(self, listen):
+        with pytest.raises(ValueError):
+            parse_listen(listen)
+    
+    def test_auth_token(self, monkeypatch):
+        monkeypatch.delenv("MCP_AUTH_TOKEN", raising=False)
+        assert get_auth_token() is None
+        monkeypatch.setenv("MCP_AUTH_TOKEN", "")
+        assert get_auth_token() is None
+        monkeypatch.setenv("MCP_AUTH_TOKEN", "s3cret")
+        assert get_auth_token() == "s3cret"
+        assert get_auth_token("other") == "other"
+    
+    def test_shutdown_timeout(self, monkeypatch):
+        monkeypatch.delenv("MCP_SHUTDOWN_TIMEOUT", raising=False)
+        assert get_shutdown_timeout() == 30.0
+        monkeypatch.setenv("MCP_SHUTDOWN_TIMEOUT", "5")
+        assert get_shutdown_timeout() == 5.0
+        monkeypatch.setenv("MCP_SHUTDOWN_TIMEOUT", "soon")
+        assert get_shutdown_timeout() == 30.0
+        assert get_shutdown_timeout(-1) == 0.0
+
+
+class TestBearerToken:
+    
+    def test_valid_token_passes(self):
+        status, _, ran = request([(b"authorization", b"Bearer s3cret")])
+        assert status == 200 and ran
+    
+    def test_scheme_is_case_insensitive(self):
+        status, _, ran = request([(b"Authorization", b"bearer s3cret")])
+        assert status == 200 and ran
+    
+    @pytest.mark.parametrize("headers", [
+        [],
+        [(b"authorization", b"Bearer wrong")],
+        [(b"authorization", b"Basic s3cret")],
+        [(b"authorization", b"s3cret")],
+    ])
+    def test_refused(self, headers):
+        status, sent, ran = request(headers)
+        assert status == 401 and not ran
+        assert (b"www-authenticate", b'Bearer realm="mcp"') in sent[0]["headers"]
+        assert "error" in json.loads(sent[1]["body"])
+    
+    def test_lifespan_passes(self):
+        scopes = []
+        
+        async def app(scope, receive, send):
+            scopes.append(scope["type"])
+        
+        asyncio.run(BearerTokenMiddleware(app, "s3cret")({"type": "lifespan"}, None, None))
+        assert scopes == ["lifespan"]
+
+
+class TestInFlightCalls:
+    
+    def test_drain_waits_for_running_calls(self):
+        calls = InFlightCalls()
+        
+        async def scenario():
+            calls.start()
+            
+            async def finish_later():
+                await asyncio.sleep(0.05)
+                calls.finish()
+            
+            task = asyncio.create_task(finish_later())
+            drained = await calls.drain(5)
+            await task
+            return drained
+        
+        assert asyncio.run(scenario()) is True
+        assert calls.count == 0
+    
+    def test_drain_refuses_new_calls(self):
+        calls = InFlightCalls()
+        assert asyncio.run(calls.drain(0)) is True
+        with pytest.raises(ShuttingDownError):
+            calls.start()
+    
+    def test_drain_gives_up_after_timeout(self):
+        calls = InFlightCalls()
+        calls.start()
+        assert asyncio.run(calls.drain(0.01)) is False
+        assert calls.count == 1
+    
+    def test_tracked_tools_keep_their_signature(self, capturing_mcp):
+        calls = InFlightCalls()
+        track_tool_calls(capturing_mcp, calls)
+        seen = []
+        
+        @capturing_mcp.tool()
+        async def lookup(symbol: str, limit: int = 10) -> str:
+            """Look a symbol up."""
+            seen.append(calls.count)
+            return symbol
+        
+        assert capturing_mcp.tools["lookup"] is lookup
+        assert lookup.__name__ == "lookup" and lookup.__doc__ == "Look a symbol up."
+        assert list(inspect.signature(lookup).parameters) == ["symbol", "limit"]
+        assert asyncio.run(lookup("Parse")) == "Parse"
+        assert seen == [1] and calls.count == 0
+
+
+class TestServer:
+    
+    @pytest.fixture
+    def server(self, make_server):
+        return make_server(store=InMemoryGraphStore(), auth_token="s3cret", shutdown_timeout=3)
+    
+    def test_settings(self, server):
+        assert server.auth_token == "s3cret"
+        assert server.shutdown_timeout == 3
+    
+    def test_tools_are_refused_while_draining(self, server):
+        get_graph_info = server.mcp.tools["get_graph_info"]
+        assert "schema" in json.loads(asyncio.run(get_graph_info()))
+        asyncio.run(server.tool_calls.drain(0))
+        with pytest.raises(ShuttingDownError):
+            asyncio.run(get_graph_info())
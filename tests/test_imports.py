@@ -0,0 +1,250 @@
+"""
+IMPORTS edge and package DEPENDS_ON tests.
+
+Python imports are checked by parsing small trees with the legacy parser,
+Go and TypeScript imports by feeding the second pass the pending imports
+their adapters queue. The aggregation into package DEPENDS_ON edges is
+checked directly and across incremental runs against an in-memory store.
+"""
+
+import asyncio
+import json
+import os
+import sys
+
+import pytest
+
+# Add project root to Python path
+sys.path.insert(0, os.path.dirname(os.path.dirname(os.path.abspath(__file__))))
+
+from src.ast_parser.packages import external_package_id, npm_package_name, package_id
+from src.ast_parser.parser import ASTParser, CodeNode, CodeRelation
+from src.graph_store import InMemoryGraphStore
+from src.indexing import compute_package_dependencies
+from src.indexing.incremental import collect_package_imports
+from conftest import ConstantEmbeddingProvider, index_tree, write_tree
+
+
+def _imports(relations):
+    """(source, target) -> properties of the IMPORTS relations."""
+    return {(r.source_id, r.target_id): r.properties for r in relations if r.relation_type == "IMPORTS"}
+
+
+class TestPythonImports:
+    
+    @pytest.fixture
+    def parsed(self, tmp_path):
+        write_tree(tmp_path, {
+            "app/__init__.py": "",
+            "app/models.py": "class User:\n    pass\n\n\ndef helper():\n    return 1\n",
+            "lib/__init__.py": "",
+            "lib/text.py": "def slug(value):\n    return value\n",
+            "app/service.py": (
+                "import os\n"
+                "import app.models\n"
+                "from app.models import User as Account\n"
+                "from .models import helper\n"
+                "from lib import text\n"
+                "from lib import VERSION\n"
+                "from requests.adapters import HTTPAdapter\n"
+            ),
+        })
+        nodes, relations = ASTParser().parse_directory(str(tmp_path))
+        return tmp_path, nodes, relations
+    
+    def test_edges_to_files_symbols_and_external_packages(self, parsed):
+        root, nodes, relations = parsed
+        service = f"file:{root / 'app/service.py'}"
+        models = f"file:{root / 'app/models.py'}"
+        user = next(node_id for node_id, node in nodes.items() if node.name == "User")
+        helper = next(node_id for node_id, node in nodes.items() if node.name == "helper")
+        imports = _imports(relations)
+        
+        assert imports[(service, external_package_id("os"))] == {"module": "os", "line_no": 1}
+        assert imports[(service, models)]["line_no"] == 2
+        assert imports[(service, user)] == {"module": "app.models", "symbol": "User", "alias": "Account", "line_no": 3}
+        assert imports[(service, helper)] == {"module": ".models", "symbol": "helper", "line_no": 4}
+        assert imports[(service, external_package_id("requests.adapters"))]["symbol"] == "HTTPAdapter"
+        assert nodes[external_package_id("os")].node_type == "ExternalPackage"
+        assert nodes[external_package_id("os")].properties["placeholder"] is True
+    
+    def test_submodules_and_unresolved_symbols(self, parsed):
+        root, _, relations = parsed
+        service = f"file:{root / 'app/service.py'}"
+        imports = _imports(relations)
+        
+        assert imports[(service, f"file:{root / 'lib/text.py'}")]["symbol"] == "text"
+        # VERSION is not defined anywhere, the edge goes to the package module
+        assert imports[(service, f"file:{root / 'lib/__init__.py'}")]["symbol"] == "VERSION"
+    
+    def test_files_belong_to_directory_packages(self, parsed):
+        root, nodes, relations = parsed
+        package = package_id(str(root / "app/models.py"))
+        
+        assert nodes[package].node_type == "Package"
+        assert nodes[package].name == "app"
+        contained = {r.target_id for r in relations if r.relation_type == "CONTAINS" and r.source_id == package}
+        assert contained == {f"file:{root / path}" for path in ("app/__init__.py", "app/models.py", "app/service.py")}
+
+
+class TestGoImports:
+    
+    def test_internal_aliased_dot_and_blank_imports(self):
+        parser = ASTParser()
+        for path, package, import_path in (("cmd/main.go", "main", "example.com/shop/cmd"),
+                                           ("internal/auth/auth.go", "auth", "example.com/shop/internal/auth")):
+            parser.nodes[f"file:{path}"] = CodeNode(f"file:{path}", "File", os.path.basename(path), path, 0,
+                                                    properties={"package": package, "import_path": import_path})
+        parser.module_definitions["goimport:example.com/shop/internal/auth"] = {}
+        base = {"type": "IMPORTS_MODULE", "source_id": "file:cmd/main.go"}
+        parser.pending_imports = [
+            dict(base, imported_module="auth", import_path="example.com/shop/internal/auth",
+                 package_key="goimport:example.com/shop/internal/auth", alias="a", line_no=3),
+            dict(base, imported_module="fmt", import_path="fmt", package_key="goimport:fmt", dot=True, line_no=4),
+            dict(base, imported_module="pq", import_path="github.com/lib/pq",
+                 package_key="goimport:github.com/lib/pq", blank=True, line_no=5),
+        ]
+        parser._process_pending_imports()
+        imports = _imports(parser.relations)
+        
+        assert imports[("file:cmd/main.go", "package:example.com/shop/internal/auth")] == {
+            "import_path": "example.com/shop/internal/auth", "alias": "a", "line_no": 3,
+        }
+        assert imports[("file:cmd/main.go", external_package_id("fmt"))]["dot"] is True
+        assert imports[("file:cmd/main.go", external_package_id("github.com/lib/pq"))]["blank"] is True
+        
+        auth = parser.nodes["package:example.com/shop/internal/auth"]
+        assert (auth.node_type, auth.name, auth.properties["path"]) == ("Package", "auth", "internal/auth")
+        assert ("package:example.com/shop/cmd", "file:cmd/main.go", "CONTAINS") in {
+            (r.source_id, r.target_id, r.relation_type) for r in parser.relations
+        }
+
+
+class TestTypeScriptImports:
+    
+    def test_resolved_files_and_npm_packages(self):
+        parser = ASTParser()
+        for path in ("src/app.ts", "src/util.ts"):
+            parser.nodes[f"file:{path}"] = CodeNode(f"file:{path}", "File", os.path.basename(path), path, 0)
+        parser.module_to_file["src/util"] = "file:src/util.ts"
+        base = {"type": "IMPORTS_MODULE", "source_id": "file:src/app.ts"}
+        parser.pending_imports = [
+            dict(base, imported_module="src/util", full_module_path="./util", line_no=1),
+            dict(base, imported_module=None, full_module_path="@scope/pkg/x", external_package="@scope/pkg", line_no=2),
+            dict(base, imported_module=None, full_module_path="lodash", external_package="lodash", line_no=3),
+        ]
+        parser._process_pending_imports()
+        
+        assert set(_imports(parser.relations)) == {
+            ("file:src/app.ts", "file:src/util.ts"),
+            ("file:src/app.ts", external_package_id("@scope/pkg")),
+            ("file:src/app.ts", external_package_id("lodash")),
+        }
+    
+    def test_npm_package_names(self):
+        assert npm_package_name("lodash/fp") == "lodash"
+        assert npm_package_name("@scope/pkg/x") == "@scope/pkg"
+        assert npm_package_name("./util") is None
+
+
+class TestPackageDependencies:
+    
+    def test_aggregation_counts_imports_and_files(self):
+        nodes = {
+            f"file:{path}": CodeNode(f"file:{path}", "File", os.path.basename(path), path, 0)
+            for path in ("api/a.py", "api/b.py", "auth/token.py", "auth/session.py")
+        }
+        relations = [
+            CodeRelation("file:api/a.py", "file:auth/token.py", "IMPORTS"),
+            CodeRelation("file:api/a.py", "function:auth/session.py:login:1", "IMPORTS"),
+            CodeRelation("file:api/b.py", "file:auth/token.py", "IMPORTS"),
+            CodeRelation("file:api/b.py", "file:api/a.py", "IMPORTS"),
+            CodeRelation("file:auth/token.py", external_package_id("jwt"), "IMPORTS"),
+        ]
+        imports = collect_package_imports(relations, nodes, {"function:auth/session.py:login:1": "auth/session.py"})
+        
+        assert imports["api/a.py"] == {"package": "package:api", "targets": {"package:auth": 2}}
+        dependencies = {(r.source_id, r.target_id): r.properties for r in compute_package_dependencies(imports)}
+        assert dependencies == {
+            ("package:api", "package:auth"): {"imports": 3, "files": 2, "structural": True,
+                                              "source": "derived:package_imports", "premises": ["api/a.py", "api/b.py"]},
+            ("package:auth", external_package_id("jwt")): {"imports": 1, "files": 1, "structural": True,
+                                                           "source": "derived:package_imports",
+                                                           "premises": ["auth/token.py"]},
+        }
+
+
+def _depends_on(store):
+    package_ids = [node["properties"]["id"] for node in store.find_nodes(label="Package")]
+    return {
+        (row["origin_id"], row["node"]["properties"]["id"]): row["relationship"]["properties"]["imports"]
+        for row in store.neighbors(package_ids, ["DEPENDS_ON"], direction="out")
+    }
+
+
+class TestIncrementalPackageDependencies:
+    
+    @pytest.fixture
+    def codebase(self, tmp_path, legacy_env):
+        write_tree(tmp_path, {
+            "auth/token.py": "def issue():\n    return 1\n",
+            "billing/charge.py": "def charge():\n    return 2\n",
+            "api/views.py": "from auth.token import issue\n",
+            "api/admin.py": "from auth.token import issue\n",
+        })
+        return tmp_path
+    
+    def _index(self, codebase, store=None):
+        """Full index into a new store, or incremental index into the given one."""
+        return index_tree(codebase, store, incremental=store is not None).db
+    
+    def test_changed_imports_update_dependencies(self, codebase):
+        api, auth, billing = (package_id(str(codebase / path)) for path in ("api/x.py", "auth/x.py", "billing/x.py"))
+        store = self._index(codebase)
+        assert _depends_on(store) == {(api, auth): 2}
+        
+        (codebase / "api/admin.py").write_text("from billing.charge import charge\n")
+        self._index(codebase, store)
+        assert _depends_on(store) == {(api, auth): 1, (api, billing): 1}
+        
+        (codebase / "api/views.py").unlink()
+        self._index(codebase, store)
+        assert _depends_on(store) == {(api, billing): 1}
+        assert _depends_on(store) == _depends_on(self._index(codebase))
+    
+    def test_deleted_package_is_removed(self, codebase):
+        store = self._index(codebase)
+        (codebase / "billing/charge.py").unlink()
+        self._index(codebase, store)
+        
+        assert package_id(str(codebase / "billing/charge.py")) not in {
+            node["properties"]["id"] for node in store.find_nodes(label="Package")
+        }
+
+
+class TestFileDependenciesTool:
+    
+    def test_imports_and_importers(self, make_server):
+        store = InMemoryGraphStore()
+        store.batch_create_nodes([
+            {"labels": ["Base", "File"], "properties": {"id": "file:api.py", "name": "api.py", "file_path": "api.py"}},
+            {"labels": ["Base", "File"], "properties": {"id": "file:auth.py", "name": "auth.py", "file_path": "auth.py"}},
+            {"labels": ["Base", "Function"],
+             "properties": {"id": "function:auth.py:issue:1", "name": "issue", "file_path": "auth.py", "line_no": 1}},
+            {"labels": ["Base", "ExternalPackage"],
+             "properties": {"id": "external_package:jwt", "name": "jwt", "module_path": "jwt", "placeholder": True}},
+        ])
+        store.batch_create_relationships([
+            {"start_node_id": "file:api.py", "end_node_id": "function:auth.py:issue:1", "type": "IMPORTS",
+             "properties": {"module": "auth", "symbol": "issue", "line_no": 1}},
+            {"start_node_id": "file:auth.py", "end_node_id": "external_package:jwt", "type": "IMPORTS",
+             "properties": {"module": "jwt", "line_no": 1}},
+        ])
+        server = make_server(store=store, embedding_provider=ConstantEmbeddingProvider())
+        tool = server.mcp.tools["find_file_dependencies"]
+        
+        auth = json.loads(asyncio.run(tool("auth.py")))
+        assert [(row["m"]["name"], row["node_type"]) for row in auth["imports"]] == [("jwt", "ExternalPackage")]
+        assert [row["f"]["name"] for row in auth["imported_by"]] == ["api.py"]
+        api = json.loads(asyncio.run(tool("api.py")))
+        assert api["imports"][0]["edge"]["symbol"] == "issue"
@@ -0,0 +1,582 @@
+"""
+Incremental re-indexing tests.
+
+The pure helpers in src.indexing are tested directly. The end-to-end
+tests run CodebaseKnowledgeGraph.process_codebase against an in-memory
+stand-in for Neo4jDatabase and check that an incremental run leaves the
+same graph as a full rebuild.
+"""
+
+import json
+import os
+import sys
+import time
+from unittest.mock import patch
+
+import pytest
+
+# Add project root to Python path
+sys.path.insert(0, os.path.dirname(os.path.dirname(os.path.abspath(__file__))))
+
+from src.ast_parser.parser import ASTParser, CodeNode, CodeRelation
+from src.indexing import (
+    build_file_index_states,
+    compute_file_dependencies,
+    load_index_context,
+    plan_incremental_update,
+    select_incremental_writes,
+)
+from src.indexing.incremental import compute_content_hash, get_file_fingerprint
+from src.indexing.renames import body_hash, detect_file_moves
+
+
+class FakeGraphDatabase:
+    """In-memory implementation of the Neo4jDatabase methods used by the indexer."""
+    
+    def __init__(self, *args, **kwargs):
+        self.nodes = {}
+        self.relationships = []
+    
+    def verify_connection(self):
+        return True
+    
+    def clear_database(self):
+        self.nodes.clear()
+        self.relationships.clear()
+    
+    def create_schema_constraints(self):
+        pass
+    
+    def create_vector_index(self, **kwargs):
+        pass
+    
+    def create_full_text_index(self, **kwargs):
+        pass
+    
+    def close(self):
+        pass
+    
+    def flush(self):
+        pass
+    
+    def batch_create_nodes(self, nodes, batch_size=None):
+        for node in nodes:
+            self.nodes[node["properties"]["id"]] = node
+    
+    def batch_create_relationships(self, relationships, batch_size=None):
+        for rel in relationships:
+            # Same semantics as MATCH ... MATCH ... CREATE: dangling edges are dropped
+            if rel["start_node_id"] in self.nodes and rel["end_node_id"] in self.nodes:
+                self.relationships.append(rel)
+    
+    def get_file_states(self, repo=None):
+        return {
+            node["properties"]["file_path"]: {
+                key: node["properties"].get(key) for key in ("content_hash", "mtime", "size", "index_state", "parse_error")
+            }
+            for node in self.nodes.values()
+            if "File" in node["labels"]
+        }
+    
+    def get_dependent_files(self, file_paths, repo=None):
+        targets = {f"file:{path}" for path in file_paths}
+        return sorted({
+            self.nodes[rel["start_node_id"]]["properties"]["file_path"]
+            for rel in self.relationships
+            if rel["type"] == "DEPENDS_ON_FILE" and rel["end_node_id"] in targets
+        })
+    
+    def delete_file_scope(self, file_paths, repo=None):
+        doomed = {
+            node_id for node_id, node in self.nodes.items()
+            if node["properties"].get("file_path") in file_paths
+        }
+        for node_id in doomed:
+            del self.nodes[node_id]
+        self.relationships = [
+            rel for rel in self.relationships
+            if rel["start_node_id"] not in doomed and rel["end_node_id"] not in doomed
+        ]
+        return len(doomed)
+    
+    def get_node_embeddings(self, file_paths, repo=None):
+        return {
+            node["properties"]["embedding_key"]: node["properties"]["embedding"]
+            for node in self.nodes.values()
+            if node["properties"].get("file_path") in file_paths
+            and "embedding_key" in node["properties"] and "embedding" in node["properties"]
+        }
+    
+    def get_existing_node_ids(self, node_ids):
+        return {node_id for node_id in node_ids if node_id in self.nodes}
+    
+    def get_nodes(self, node_ids):
+        return [self.nodes[node_id] for node_id in node_ids if node_id in self.nodes]
+    
+    def find_nodes(self, label=None, repo=None, **kwargs):
+        return [node for node in self.nodes.values() if label is None or label in node["labels"]]
+    
+    def update_file_mtimes(self, updates, repo=None):
+        for file_path, mtime in updates:
+            self.nodes[f"file:{file_path}"]["properties"]["mtime"] = mtime
+    
+    def update_file_index_states(self, updates, repo=None):
+        for file_path, index_state in updates:
+            self.nodes[f"file:{file_path}"]["properties"]["index_state"] = index_state
+    
+    def delete_structural_relationships(self, repo=None):
+        before = len(self.relationships)
+        self.relationships = [rel for rel in self.relationships if not rel["properties"].get("structural")]
+        return before - len(self.relationships)
+    
+    def delete_stale_structural_relationships(self, premise_files, keys, repo=None):
+        def key_of(rel):
+            return rel["start_node_id"], rel["type"], rel["end_node_id"]
+        
+        stale = set()
+        for rel in self.relationships:
+            premises = set(json.loads(rel["properties"].get("premises") or "[]"))
+            if rel["properties"].get("structural") and (
+                key_of(rel) in set(keys) or not premises or premises & set(premise_files)
+            ):
+                stale.add(key_of(rel))
+        self.relationships = [rel for rel in self.relationships
+                              if not (rel["properties"].get("structural") and key_of(rel) in stale)]
+        return sorted(stale)
+    
+    def delete_cross_repo_relationships(self):
+        before = len(self.relationships)
+        self.relationships = [rel for rel in self.relationships if not rel["properties"].get("cross_repo")]
+        return before - len(self.relationships)
+    
+    def delete_repository(self, repo):
+        count = len(self.nodes)
+        self.clear_database()
+        return count
+    
+    def delete_orphan_placeholders(self, repo=None):
+        linked = {rel["start_node_id"] for rel in self.relationships} | {rel["end_node_id"] for rel in self.relationships}
+        orphans = [
+            node_id for node_id, node in self.nodes.items()
+            if (node["properties"].get("placeholder") or "Package" in node["labels"]) and node_id not in linked
+        ]
+        for node_id in orphans:
+            del self.nodes[node_id]
+        return len(orphans)
+    
+    def snapshot(self):
+        """Order-independent view of the stored graph."""
+        nodes = {
+            node_id: (tuple(node["labels"]), json.dumps(node["properties"], sort_keys=True))
+            for node_id, node in self.nodes.items()
+        }
+        relationships = sorted(
+            (rel["start_node_id"], rel["type"], rel["end_node_id"], json.dumps(rel["properties"], sort_keys=True))
+            for rel in self.relationships
+        )
+        return nodes, relationships
+
+
+class FakeEmbeddingProvider:
+    """Deterministic embeddings so graphs from separate runs compare equal."""
+    
+    dimension = 4
+    
+    def embed_text(self, text):
+        return [float(len(text)), 0.0, 0.0, 1.0]
+    
+    def embed_batch(self, texts):
+        return [self.embed_text(text) for text in texts]
+
+
+BASE_SOURCE = '''class Base:
+    def hello(self):
+        return "hi"
+
+
+def helper():
+    return 1
+'''
+
+CHILD_SOURCE = '''from base import Base, helper
+
+
+class Child(Base):
+    def run(self):
+        return helper()
+'''
+
+UTIL_SOURCE = '''def standalone():
+    return 42
+'''
+
+
+@pytest.fixture
+def codebase(tmp_path):
+    """Three-file Python codebase: child.py depends on base.py, util.py is independent."""
+    (tmp_path / "base.py").write_text(BASE_SOURCE)
+    (tmp_path / "child.py").write_text(CHILD_SOURCE)
+    (tmp_path / "util.py").write_text(UTIL_SOURCE)
+    return tmp_path
+
+
+def _build_graph(db):
+    """Create a CodebaseKnowledgeGraph bound to the given fake database."""
+    from src.main import CodebaseKnowledgeGraph
+    
+    with patch("src.main.Neo4jDatabase", return_value=db), \
+         patch("src.main.get_embedding_provider", return_value=FakeEmbeddingProvider()):
+        return CodebaseKnowledgeGraph(neo4j_uri="bolt://fake", neo4j_user="neo4j", neo4j_password="fake")
+
+
+def _full_rebuild(path):
+    db = FakeGraphDatabase()
+    _build_graph(db).process_codebase(str(path), clear_db=True)
+    return db
+
+
+class TestPlanIncrementalUpdate:
+    """Change detection against stored file states."""
+    
+    def test_new_unchanged_touched_and_deleted(self, tmp_path):
+        same = tmp_path / "same.py"
+        same.write_text("x = 1\n")
+        touched = tmp_path / "touched.py"
+        touched.write_text("y = 2\n")
+        new = tmp_path / "new.py"
+        new.write_text("z = 3\n")
+        
+        stored = {
+            str(same): get_file_fingerprint(str(same)),
+            str(touched): dict(get_file_fingerprint(str(touched)), mtime=0.0),
+            str(tmp_path / "gone.py"): {"content_hash": "abc", "mtime": 0.0, "size": 1},
+        }
+        
+        plan = plan_incremental_update([str(same), str(touched), str(new)], stored)
+        
+        assert plan.changed == [str(new)]
+        assert sorted(plan.unchanged) == sorted([str(same), str(touched)])
+        assert plan.deleted == [str(tmp_path / "gone.py")]
+        assert [path for path, _ in plan.touched] == [str(touched)]
+    
+    def test_modified_content_is_changed(self, tmp_path):
+        source = tmp_path / "mod.py"
+        source.write_text("a = 1\n")
+        stored = {str(source): dict(get_file_fingerprint(str(source)), mtime=0.0)}
+        source.write_text("a = 22\n")
+        
+        plan = plan_incremental_update([str(source)], stored)
+        
+        assert plan.changed == [str(source)]
+        assert plan.has_changes
+        assert compute_content_hash(str(source)) != stored[str(source)]["content_hash"]
+    
+    def test_file_deleted_after_the_walk(self, tmp_path):
+        source = tmp_path / "mod.py"
+        source.write_text("a = 1\n")
+        stored = {str(source): get_file_fingerprint(str(source))}
+        source.unlink()
+        
+        plan = plan_incremental_update([str(source), str(tmp_path / "new.py")], stored)
+        
+        assert (plan.changed, plan.unchanged, plan.deleted) == ([], [], [str(source)])
+
+
+class TestIndexStateHelpers:
+    """Per-file index state, dependency edges and write selection."""
+    
+    def _sample(self):
+        nodes = {
+            "file:a.py": CodeNode("file:a.py", "File", "a.py", "a.py", 0),
+            "class:a.py:A:1": CodeNode("class:a.py:A:1", "Class", "A", "a.py", 1),
+            "method:a.py:A.m:2": CodeNode("method:a.py:A.m:2", "Method", "m", "a.py", 2),
+            "file:b.py": CodeNode("file:b.py", "File", "b.py", "b.py", 0),
+            "function:b.py:f:1": CodeNode("function:b.py:f:1", "Function", "f", "b.py", 1),
+            "external:fmt:Println": CodeNode("external:fmt:Println", "ExternalFunction", "Println", "", 0),
+        }
+        relations = [
+            CodeRelation("class:a.py:A:1", "method:a.py:A.m:2", "DEFINES"),
+            CodeRelation("function:b.py:f:1", "method:a.py:A.m:2", "CALLS"),
+            CodeRelation("function:b.py:f:1", "external:fmt:Println", "CALLS"),
+        ]
+        module_definitions = {"a": {"A": "class:a.py:A:1"}, "b": {"f": "function:b.py:f:1"}}
+        module_to_file = {"a": "file:a.py", "b": "file:b.py"}
+        return nodes, relations, module_definitions, module_to_file
+    
+    def test_states_round_trip_through_load(self):
+        nodes, relations, module_definitions, module_to_file = self._sample()
+        states = build_file_index_states(nodes, relations, module_definitions, module_to_file)
+        stored = {path: {"index_state": json.dumps(state)} for path, state in states.items()}
+        
+        defs, files, stub_nodes, stub_relations, node_files = load_index_context(stored, ["a.py"])
+        
+        assert defs == {"a": {"A": "class:a.py:A:1"}}
+        assert files == {"a": "file:a.py"}
+        assert list(stub_nodes) == ["method:a.py:A.m:2"]
+        assert [(r.source_id, r.target_id) for r in stub_relations] == [("class:a.py:A:1", "method:a.py:A.m:2")]
+        assert node_files["class:a.py:A:1"] == "a.py"
+    
+    def test_file_dependencies_skip_placeholders(self):
+        nodes, relations, _, _ = self._sample()
+        
+        deps = compute_file_dependencies(relations, nodes)
+        
+        assert [(r.source_id, r.target_id, r.relation_type) for r in deps] == [
+            ("file:b.py", "file:a.py", "DEPENDS_ON_FILE")
+        ]
+    
+    def test_select_writes_for_changed_file(self):
+        nodes, relations, _, _ = self._sample()
+        
+        nodes_to_write, relations_to_write = select_incremental_writes(
+            nodes, relations, {"b.py"}, existing_shared_ids={"external:fmt:Println"}
+        )
+        
+        assert set(nodes_to_write) == {"file:b.py", "function:b.py:f:1"}
+        assert [r.relation_type for r in relations_to_write] == ["CALLS", "CALLS"]
+    
+    def test_method_sets_round_trip_and_implements_are_recomputed(self):
+        """Interfaces and method signatures of unchanged files are enough to recompute IMPLEMENTS."""
+        shape = "Interface:shape.go:Shape:3"
+        square = "Class:square.go:Square:3"
+        nodes = {
+            "file:shape.go": CodeNode("file:shape.go", "File", "shape.go", "shape.go", 0),
+            "file:square.go": CodeNode("file:square.go", "File", "square.go", "square.go", 0),
+            shape: CodeNode(shape, "Interface", "Shape", "shape.go", 3,
+                            properties={"type_kind": "interface", "methods": ["Area() float64"], "embeds": []}),
+            square: CodeNode(square, "Class", "Square", "square.go", 3, properties={"type_kind": "struct"}),
+            "Method:square.go:Area:5": CodeNode(
+                "Method:square.go:Area:5", "Method", "Area", "square.go", 5,
+                properties={"receiver_type": "Square", "receiver_kind": "pointer", "signature": "Area() float64"}
+            ),
+        }
+        relations = [
+            CodeRelation(square, "Method:square.go:Area:5", "DEFINES"),
+            CodeRelation("Method:square.go:Area:5", square, "METHOD_OF", {"receiver_kind": "pointer"}),
+        ]
+        states = build_file_index_states(nodes, relations, {}, {})
+        stored = {path: {"index_state": json.dumps(state)} for path, state in states.items()}
+        
+        _, _, stub_nodes, stub_relations, _ = load_index_context(stored, ["shape.go", "square.go"])
+        parser = ASTParser()
+        parser.nodes = dict(stub_nodes)
+        parser.relations = list(stub_relations)
+        parser._resolve_interface_implementations()
+        
+        implements = [r for r in parser.relations if r.relation_type == "IMPLEMENTS"]
+        assert [(r.source_id, r.target_id, r.properties["via"]) for r in implements] == [(square, shape, "pointer")]
+        # Derived from the whole index: no file dependency, always rewritten
+        assert compute_file_dependencies(implements, nodes) == []
+        _, relations_to_write = select_incremental_writes(
+            nodes, parser.relations, set(), existing_shared_ids=set(), stub_relations=stub_relations
+        )
+        assert relations_to_write == implements
+
+
+class TestIncrementalProcessing:
+    """process_codebase(incremental=True) against an in-memory graph."""
+    
+    def test_unchanged_tree_writes_nothing(self, codebase, legacy_env):
+        db = FakeGraphDatabase()
+        kg = _build_graph(db)
+        kg.process_codebase(str(codebase), clear_db=True)
+        before = db.snapshot()
+        
+        assert kg.process_codebase(str(codebase), incremental=True) == (0, 0)
+        assert kg.last_run_stats["changed"] == 0
+        assert db.snapshot() == before
+    
+    def test_changed_dependency_matches_full_rebuild(self, codebase, legacy_env):
+        db = FakeGraphDatabase()
+        kg = _build_graph(db)
+        kg.process_codebase(str(codebase), clear_db=True)
+        
+        (codebase / "base.py").write_text(BASE_SOURCE + '''
+
+def extra():
+    return helper()
+''')
+        kg.process_codebase(str(codebase), incremental=True)
+        
+        assert kg.last_run_stats["mode"] == "incremental"
+        assert kg.last_run_stats["changed"] == 1
+        assert kg.last_run_stats["dependents"] == 1
+        assert db.snapshot() == _full_rebuild(codebase).snapshot()
+    
+    def test_unchanged_nodes_are_not_re_embedded(self, codebase, legacy_env):
+        db = FakeGraphDatabase()
+        kg = _build_graph(db)
+        kg.process_codebase(str(codebase), clear_db=True)
+        assert kg.last_run_stats["embeddings"] == {"embedded": 9, "reused": 0, "failed": 0}
+        
+        (codebase / "base.py").write_text(BASE_SOURCE + '''
+
+def extra():
+    return helper()
+''')
+        kg.process_codebase(str(codebase), incremental=True)
+        
+        # Only extra() is new; the file, Base, hello and helper keep their stored vectors
+        assert kg.last_run_stats["embeddings"] == {"embedded": 1, "reused": 4, "failed": 0}
+        assert db.snapshot() == _full_rebuild(codebase).snapshot()
+    
+    def test_changed_leaf_keeps_incoming_edges(self, codebase, legacy_env):
+        db = FakeGraphDatabase()
+        kg = _build_graph(db)
+        kg.process_codebase(str(codebase), clear_db=True)
+        
+        (codebase / "child.py").write_text(CHILD_SOURCE + '''
+
+def another():
+    return Base()
+''')
+        kg.process_codebase(str(codebase), incremental=True)
+        
+        assert kg.last_run_stats["dependents"] == 0
+        assert db.snapshot() == _full_rebuild(codebase).snapshot()
+    
+    def test_deleted_file(self, codebase, legacy_env):
+        db = FakeGraphDatabase()
+        kg = _build_graph(db)
+        kg.process_codebase(str(codebase), clear_db=True)
+        
+        os.remove(codebase / "base.py")
+        kg.process_codebase(str(codebase), incremental=True)
+        
+        assert kg.last_run_stats["deleted"] == 1
+        assert not any(node_id.startswith(f"file:{codebase / 'base.py'}") for node_id in db.nodes)
+        assert db.snapshot() == _full_rebuild(codebase).snapshot()
+    
+    def test_watcher_move_keeps_node_ids(self, codebase, legacy_env):
+        from src.indexing.watcher import watch_codebase
+        
+        db = FakeGraphDatabase()
+        kg = _build_graph(db)
+        kg.process_codebase(str(codebase), clear_db=True)
+        symbol_ids = {node["properties"]["name"]: node_id for node_id, node in db.nodes.items()
+                      if node["properties"].get("file_path") == str(codebase / "base.py") and "File" not in node["labels"]}
+        
+        watcher = watch_codebase(kg, str(codebase), debounce_seconds=0.05, use_polling=True)
+        watcher.poll_interval = 0.05
+        watcher.start()
+        try:
+            os.rename(codebase / "base.py", codebase / "core.py")
+            deadline = time.monotonic() + 10
+            while watcher.status()["syncs"] == 0 and time.monotonic() < deadline:
+                time.sleep(0.02)
+        finally:
+            watcher.stop()
+        
+        status = watcher.status()
+        assert status["syncs"] == 1
+        assert status["last_sync_stats"]["deleted"] == 1
+        assert status["last_sync_stats"]["dependents"] == 1
+        assert f"file:{codebase / 'base.py'}" not in db.nodes
+        assert db.nodes[f"file:{codebase / 'core.py'}"]["properties"]["renamed_from"] == str(codebase / "base.py")
+        # The moved symbols keep their IDs and now live in core.py
+        for name, node_id in symbol_ids.items():
+            assert db.nodes[node_id]["properties"]["name"] == name
+            assert db.nodes[node_id]["properties"]["file_path"] == str(codebase / "core.py")
+        # child.py still imports from base, its edges into the old file are gone
+        assert all(rel["start_node_id"] in db.nodes and rel["end_node_id"] in db.nodes for rel in db.relationships)
+        rebuilt = _full_rebuild(codebase)
+        assert len(db.nodes) == len(rebuilt.nodes)
+        assert len(db.relationships) == len(rebuilt.relationships)
+    
+    def test_no_stored_state_falls_back_to_full(self, codebase, legacy_env):
+        db = FakeGraphDatabase()
+        kg = _build_graph(db)
+        
+        kg.process_codebase(str(codebase), incremental=True)
+        
+        assert kg.last_run_stats["mode"] == "full"
+        assert db.snapshot() == _full_rebuild(codebase).snapshot()
+
+
+class TestRenameDetection:
+    """Renamed symbols and moved files keep their node IDs across incremental runs."""
+    
+    def test_body_hash_ignores_layout_comments_and_name(self):
+        original = "def total(items):\n    return sum(items)  # add up\n"
+        
+        assert body_hash(original, "total") == body_hash("def  sum_all(items):\n\n    return sum(items)\n", "sum_all")
+        assert body_hash(original, "total") != body_hash("def total(items):\n    return max(items)\n", "total")
+        assert body_hash("   ", "total") is None
+    
+    def test_file_moves_need_a_unique_hash(self, tmp_path):
+        for name, text in {"moved.py": "a = 1\n", "copy1.py": "b = 2\n", "copy2.py": "b = 2\n"}.items():
+            (tmp_path / name).write_text(text)
+        stored = {
+            "old/moved.py": {"content_hash": compute_content_hash(str(tmp_path / "moved.py"))},
+            "old/copy.py": {"content_hash": compute_content_hash(str(tmp_path / "copy1.py"))},
+        }
+        changed = [str(tmp_path / name) for name in ("moved.py", "copy1.py", "copy2.py")]
+        
+        assert detect_file_moves(changed, ["old/moved.py", "old/copy.py"], stored) == {
+            str(tmp_path / "moved.py"): "old/moved.py",
+        }
+    
+    def _node(self, db, file_path, name):
+        (node_id,) = [node_id for node_id, node in db.nodes.items()
+                      if node["properties"].get("file_path") == str(file_path) and node["properties"]["name"] == name]
+        return node_id, db.nodes[node_id]["properties"]
+    
+    def test_renamed_function_keeps_id_on_later_runs(self, codebase, legacy_env):
+        db = FakeGraphDatabase()
+        kg = _build_graph(db)
+        kg.process_codebase(str(codebase), clear_db=True)
+        old_id, _ = self._node(db, codebase / "util.py", "standalone")
+        
+        (codebase / "util.py").write_text(UTIL_SOURCE.replace("standalone", "answer"))
+        kg.process_codebase(str(codebase), incremental=True)
+        
+        node_id, properties = self._node(db, codebase / "util.py", "answer")
+        assert node_id == old_id
+        assert properties["renamed_from"] == "standalone"
+        
+        # Unrelated edits keep the kept ID and the history
+        (codebase / "util.py").write_text("import os\n\n\n" + UTIL_SOURCE.replace("standalone", "answer"))
+        kg.process_codebase(str(codebase), incremental=True)
+        
+        node_id, properties = self._node(db, codebase / "util.py", "answer")
+        assert (node_id, properties["renamed_from"], properties["line_no"]) == (old_id, "standalone", 4)
+    
+    def test_inbound_edges_follow_the_kept_id(self, codebase, legacy_env):
+        db = FakeGraphDatabase()
+        kg = _build_graph(db)
+        kg.process_codebase(str(codebase), clear_db=True)
+        old_id, _ = self._node(db, codebase / "base.py", "helper")
+        
+        (codebase / "base.py").write_text(BASE_SOURCE.replace("helper", "assist"))
+        (codebase / "child.py").write_text(CHILD_SOURCE.replace("helper", "assist"))
+        kg.process_codebase(str(codebase), incremental=True)
+        
+        assert self._node(db, codebase / "base.py", "assist")[0] == old_id
+        run_id, _ = self._node(db, codebase / "child.py", "run")
+        assert any(rel["start_node_id"] == run_id and rel["type"] == "CALLS" and rel["end_node_id"] == old_id
+                   for rel in db.relationships)
+        assert all(rel["start_node_id"] in db.nodes and rel["end_node_id"] in db.nodes for rel in db.relationships)
+    
+    def test_ambiguous_rename_falls_back_to_new_ids(self, codebase, legacy_env):
+        (codebase / "util.py").write_text("def first():\n    return 1\n\n\ndef second():\n    return 1\n")
+        db = FakeGraphDatabase()
+        kg = _build_graph(db)
+        kg.process_codebase(str(codebase), clear_db=True)
+        
+        (codebase / "util.py").write_text("def third():\n    return 1\n\n\ndef fourth():\n    return 1\n")
+        kg.process_codebase(str(codebase), incremental=True)
+        
+        assert not any("renamed_from" in node["properties"] for node in db.nodes.values())
+        assert db.snapshot() == _full_rebuild(codebase).snapshot()
+    
+    def test_detection_failure_does_not_block_indexing(self, codebase, legacy_env):
+        db = FakeGraphDatabase()
+        kg = _build_graph(db)
+        kg.process_codebase(str(codebase), clear_db=True)
+        
+        (codebase / "util.py").write_text(UTIL_SOURCE.replace("standalone", "answer"))
+        with patch("src.main.find_renamed_symbols", side_effect=RuntimeError("boom")):
+            kg.process_codebase(str(codebase), incremental=True)
+        
+        assert kg.last_run_stats["changed"] == 1
+        assert self._node(db, codebase / "util.py", "answer")[1].get("renamed_from") is None
@@ -0,0 +1,184 @@
+"""
+Parse failure and index diagnostics tests.
+
+parse_with_limits is checked on the legacy Python parser with a syntax
+error, a file that is not UTF-8 and a parser that raises; the end-to-end
+tests index a small Python codebase with broken files into an
+InMemoryGraphStore, read the diagnostics report and check that the next
+incremental run parses the failed files again.
+"""
+
+import asyncio
+import json
+import os
+import sys
+
+import pytest
+
+# Add project root to Python path
+sys.path.insert(0, os.path.dirname(os.path.dirname(os.path.abspath(__file__))))
+
+from src.ast_parser.guards import ParseTimeout, classify_parse_error, count_parse_errors, parse_with_limits
+from src.ast_parser.parser import ASTParser
+from src.linking import read_module_names
+from src.mcp.diagnostics import index_diagnostics
+from conftest import index_tree, write_tree
+
+SERVICE = "def charge(amount):\n    return amount\n"
+BROKEN = "def refund(amount):\n    return amount\n\ndef void(:\n    pass\n"
+
+
+class RaisingParser:
+    """Lets its error through instead of swallowing it."""
+    
+    def __init__(self):
+        self.nodes = {}
+        self.relations = []
+    
+    def parse_file(self, file_path, build_index=False):
+        raise NotImplementedError("match statements are not supported")
+
+
+class TestParseErrors:
+    
+    def test_categories(self):
+        assert classify_parse_error(SyntaxError("invalid syntax")) == "syntax_error"
+        assert classify_parse_error(UnicodeDecodeError("utf-8", b"\xff", 0, 1, "invalid start byte")) == "encoding"
+        assert classify_parse_error(ValueError("source code string cannot contain null bytes")) == "encoding"
+        assert classify_parse_error(NotImplementedError()) == "unsupported_feature"
+        assert classify_parse_error(ParseTimeout("parse exceeded 1s")) == "timeout"
+        assert classify_parse_error(KeyError("name")) == "parser_error"
+    
+    def test_syntax_error_keeps_the_file_node_only(self, tmp_path):
+        path = tmp_path / "broken.py"
+        path.write_text(BROKEN, encoding="utf-8")
+        parser = ASTParser()
+        
+        nodes, relations = parse_with_limits(parser, str(path), True)
+        
+        (file_node,) = nodes.values()
+        assert file_node.properties == {
+            "parse_error": "invalid syntax",
+            "parse_error_category": "syntax_error",
+            "parse_error_analyzer": "ASTParser",
+            "parse_error_line": 4,
+            "parse_error_column": 10,
+            "size_bytes": len(BROKEN),
+        }
+        assert relations == [] and parser.module_definitions == {}
+    
+    def test_encoding_error_position_counts_from_the_file_start(self, tmp_path):
+        # Far enough into the file that the decoder reads it in several chunks
+        path = tmp_path / "latin1.py"
+        path.write_bytes(b"x = 1\n" * 5000 + b"name = '\xe9t\xe9'\n")
+        
+        nodes, _ = parse_with_limits(ASTParser(), str(path), True)
+        
+        (file_node,) = nodes.values()
+        assert file_node.properties["parse_error_category"] == "encoding"
+        assert (file_node.properties["parse_error_line"], file_node.properties["parse_error_column"]) == (5001, 9)
+    
+    def test_error_raised_through_the_parser(self, tmp_path):
+        path = tmp_path / "service.py"
+        path.write_text(SERVICE, encoding="utf-8")
+        
+        nodes, _ = parse_with_limits(RaisingParser(), str(path), True)
+        
+        (file_node,) = nodes.values()
+        assert file_node.properties == {
+            "parse_error": "match statements are not supported",
+            "parse_error_category": "unsupported_feature",
+            "parse_error_analyzer": "RaisingParser",
+            "size_bytes": len(SERVICE),
+        }
+        assert count_parse_errors(nodes) == {"unsupported_feature": 1}
+
+
+@pytest.fixture
+def indexed(legacy_env, tmp_path):
+    write_tree(tmp_path, {
+        "app/service.py": SERVICE,
+        "app/broken.py": BROKEN,
+        "app/legacy/latin1.py": b"name = '\xe9t\xe9'\n",
+        "app/fixtures.py": SERVICE + "# padding\n" * 200,
+        # A manifest that is not UTF-8 publishes no module names
+        "pyproject.toml": b"[project]\nname = 'caf\xe9'\n",
+    })
+    kg = index_tree(tmp_path, max_file_bytes=1000)
+    return kg, kg.db, tmp_path
+
+
+class TestIndexedDiagnostics:
+    
+    def test_broken_files_do_not_abort_the_run(self, indexed):
+        kg, store, root = indexed
+        
+        assert kg.last_run_stats["parse_errors"] == {"syntax_error": 1, "encoding": 1}
+        assert [record["properties"]["name"] for record in store.find_nodes(name="charge")] == ["charge"]
+        assert kg.progress.snapshot()["error_count"] == 2
+        assert read_module_names(str(root)) == []
+    
+    def test_report(self, indexed):
+        _, store, root = indexed
+        
+        report = index_diagnostics(store)
+        
+        assert report["by_severity"] == {"error": 2, "warning": 1}
+        assert report["by_category"] == {"encoding": 1, "syntax_error": 1, "too_large": 1}
+        assert [(d["severity"], os.path.basename(d["file_path"])) for d in report["diagnostics"]] == [
+            ("error", "broken.py"), ("error", "latin1.py"), ("warning", "fixtures.py"),
+        ]
+        broken = report["diagnostics"][0]
+        assert (broken["category"], broken["line"], broken["analyzer"]) == ("syntax_error", 4, "ASTParser")
+        assert broken["language"] == "python"
+    
+    def test_filters(self, indexed):
+        _, store, root = indexed
+        
+        errors = index_diagnostics(store, severity="error")
+        legacy = index_diagnostics(store, path_prefix=str(root / "app" / "legacy"))
+        
+        assert errors["total"] == 2 and {d["severity"] for d in errors["diagnostics"]} == {"error"}
+        assert [d["category"] for d in legacy["diagnostics"]] == ["encoding"]
+        assert index_diagnostics(store, path_prefix="app/legacy")["total"] == 1
+        with pytest.raises(ValueError, match="Unknown severity"):
+            index_diagnostics(store, severity="fatal")
+    
+    def test_incremental_run_retries_failed_files(self, indexed):
+        kg, store, root = indexed
+        
+        kg.process_codebase(str(root), incremental=True)
+        
+        # Unchanged content, parsed again all the same
+        assert kg.last_run_stats["changed"] == 2
+        assert kg.last_run_stats["parse_errors"] == {"syntax_error": 1, "encoding": 1}
+        
+        (root / "app" / "broken.py").write_text(BROKEN.replace("void(:", "void():"), encoding="utf-8")
+        kg.process_codebase(str(root), incremental=True)
+        
+        assert kg.last_run_stats["changed"] == 2 and kg.last_run_stats["parse_errors"] == {"encoding": 1}
+        assert store.find_nodes(name="void")
+        assert [d["category"] for d in index_diagnostics(store, severity="error")["diagnostics"]] == ["encoding"]
+        
+        (root / "app" / "legacy" / "latin1.py").write_text("name = 'été'\n", encoding="utf-8")
+        kg.process_codebase(str(root), incremental=True)
+        kg.process_codebase(str(root), incremental=True)
+        
+        # Nothing failed in the last run, so nothing is parsed again
+        assert kg.last_run_stats["changed"] == 0
+        assert index_diagnostics(store, severity="error")["total"] == 0
+
+
+class TestDiagnosticsTool:
+    
+    def test_get_index_diagnostics(self, indexed, make_server):
+        _, store, _ = indexed
+        server = make_server(store=store)
+        
+        def call(**kwargs):
+            return json.loads(asyncio.run(server.mcp.tools["get_index_diagnostics"](**kwargs)))
+        
+        assert call()["total"] == 3
+        assert call(severity="warning")["by_category"] == {"too_large": 1}
+        assert call(path_prefix="app/legacy")["diagnostics"][0]["category"] == "encoding"
+        assert "error" in call(severity="fatal")
@@ -0,0 +1,256 @@
+"""
+Background index job tests.
+
+IndexProgress and IndexJobManager are tested with fake run functions that
+block on events, so the tests control when a job finishes. The end-to-end
+tests cancel CodebaseKnowledgeGraph.process_codebase from a progress
+callback and check the IndexMetadata node left in an InMemoryGraphStore.
+"""
+
+import asyncio
+import json
+import os
+import sys
+import threading
+
+import pytest
+
+# Add project root to Python path
+sys.path.insert(0, os.path.dirname(os.path.dirname(os.path.abspath(__file__))))
+
+from src.graph_store import InMemoryGraphStore
+from src.indexing.jobs import IndexCancelled, IndexJobManager, IndexProgress, JobConflictError
+from conftest import ConstantEmbeddingProvider
+
+
+class BlockingRun:
+    """Run function that waits for release() or a cancellation."""
+    
+    def __init__(self):
+        self.started = threading.Event()
+        self.released = threading.Event()
+        self.roots = []
+    
+    def __call__(self, root, incremental, progress, repo=None):
+        self.roots.append(root)
+        progress.set_phase("parsing", total=2)
+        progress.advance(current_file=os.path.join(root, "a.py"))
+        self.started.set()
+        while not self.released.wait(0.01):
+            progress.check_cancelled()
+        progress.advance(current_file=os.path.join(root, "b.py"))
+        return {"codebase_path": root}
+    
+    def release(self):
+        self.released.set()
+
+
+class TestIndexProgress:
+    
+    def test_snapshot_counts_the_current_phase(self):
+        progress = IndexProgress()
+        progress.set_phase("parsing", total=4)
+        progress.advance(current_file="a.py")
+        progress.advance(current_file="b.py")
+        progress.add_error("b.py: invalid syntax")
+        
+        snapshot = progress.snapshot()
+        assert (snapshot["phase"], snapshot["processed"], snapshot["total"]) == ("parsing", 2, 4)
+        assert snapshot["current_file"] == "b.py"
+        assert snapshot["errors"] == ["b.py: invalid syntax"]
+        assert snapshot["eta_seconds"] is not None
+        
+        progress.set_phase("resolving")
+        snapshot = progress.snapshot()
+        assert (snapshot["processed"], snapshot["total"], snapshot["eta_seconds"]) == (0, None, None)
+        assert snapshot["error_count"] == 1
+    
+    def test_check_cancelled_raises_after_cancel(self):
+        progress = IndexProgress()
+        progress.check_cancelled()
+        
+        progress.cancel()
+        
+        assert progress.snapshot()["cancel_requested"] is True
+        with pytest.raises(IndexCancelled):
+            progress.check_cancelled()
+
+
+class TestIndexJobManager:
+    
+    def test_same_root_is_rejected_while_running(self, tmp_path):
+        run = BlockingRun()
+        manager = IndexJobManager(run)
+        job = manager.start(str(tmp_path))
+        assert run.started.wait(5)
+        
+        with pytest.raises(JobConflictError) as conflict:
+            manager.start(str(tmp_path / "."))
+        assert conflict.value.job_id == job.job_id
+        
+        run.release()
+        assert job.done.wait(5)
+        assert job.state == "completed"
+        assert job.status()["progress"]["phase"] == "done"
+        assert job.result == {"codebase_path": str(tmp_path)}
+        
+        # A finished job no longer blocks its root
+        run.released.clear()
+        again = manager.start(str(tmp_path))
+        run.release()
+        assert again.done.wait(5)
+    
+    def test_different_roots_run_concurrently(self, tmp_path):
+        (tmp_path / "one").mkdir()
+        (tmp_path / "two").mkdir()
+        run = BlockingRun()
+        manager = IndexJobManager(run)
+        
+        first = manager.start(str(tmp_path / "one"))
+        second = manager.start(str(tmp_path / "two"))
+        
+        assert first.running and second.running
+        status = second.status()
+        assert status["state"] == "running"
+        run.release()
+        assert first.done.wait(5) and second.done.wait(5)
+        assert sorted(run.roots) == [str(tmp_path / "one"), str(tmp_path / "two")]
+        assert [job.job_id for job in manager.jobs()] == [first.job_id, second.job_id]
+    
+    def test_cancel_stops_a_running_job(self, tmp_path):
+        run = BlockingRun()
+        manager = IndexJobManager(run)
+        job = manager.start(str(tmp_path))
+        assert run.started.wait(5)
+        
+        manager.cancel(job.job_id)
+        
+        assert job.done.wait(5)
+        status = job.status()
+        assert status["state"] == "cancelled"
+        assert status["progress"]["phase"] == "parsing"
+        assert status["progress"]["current_file"] == str(tmp_path / "a.py")
+        assert status["finished_at"] is not None
+    
+    def test_failed_run_records_the_error(self, tmp_path):
+        def run(root, incremental, progress, repo=None):
+            raise RuntimeError("database unavailable")
+        
+        job = IndexJobManager(run).start(str(tmp_path))
+        
+        assert job.done.wait(5)
+        assert (job.state, job.error) == ("failed", "database unavailable")
+    
+    def test_unknown_job(self):
+        with pytest.raises(KeyError):
+            IndexJobManager(BlockingRun()).cancel("missing")
+
+
+def _write_codebase(root):
+    for path, source in {
+        "app/__init__.py": "",
+        "app/models.py": "class User:\n    def name(self):\n        return 'u'\n",
+        "app/service.py": "from app.models import User\n\n\ndef load():\n    return User()\n",
+    }.items():
+        (root / path).parent.mkdir(parents=True, exist_ok=True)
+        (root / path).write_text(source)
+
+
+def _cancel_at(phase, processed=0):
+    """Progress that cancels once `processed` items of `phase` are done."""
+    def on_update(progress):
+        if progress.phase == phase and progress.processed >= processed:
+            progress.cancel()
+    return IndexProgress(on_update=on_update)
+
+
+def _metadata(store, root):
+    records = store.get_nodes([f"index:{os.path.realpath(str(root))}"])
+    return records[0]["properties"] if records else None
+
+
+class TestCancelledRun:
+    
+    @pytest.fixture
+    def kg(self, monkeypatch):
+        monkeypatch.setenv("USE_AST_GREP", "false")
+        monkeypatch.setenv("ENABLE_JS_TS_PARSING", "false")
+        monkeypatch.setenv("PARALLEL_INDEXING_ENABLED", "false")
+        from src.main import CodebaseKnowledgeGraph
+        
+        return CodebaseKnowledgeGraph(store=InMemoryGraphStore(), embedding_provider=ConstantEmbeddingProvider())
+    
+    def test_cancel_before_writing_leaves_the_graph_untouched(self, kg, tmp_path):
+        _write_codebase(tmp_path)
+        
+        with pytest.raises(IndexCancelled):
+            kg.process_codebase(str(tmp_path), progress=_cancel_at("parsing"))
+        
+        assert kg.db.get_file_states() == {}
+        assert _metadata(kg.db, tmp_path) is None
+    
+    def test_cancel_after_writing_marks_the_index_incomplete(self, kg, tmp_path):
+        _write_codebase(tmp_path)
+        
+        with pytest.raises(IndexCancelled):
+            kg.process_codebase(str(tmp_path), progress=_cancel_at("embedding", processed=1))
+        assert _metadata(kg.db, tmp_path)["index_complete"] is False
+        
+        # The next incremental run rebuilds the whole codebase
+        progress = IndexProgress()
+        kg.process_codebase(str(tmp_path), incremental=True, progress=progress)
+        
+        assert kg.last_run_stats.get("mode") != "incremental"
+        assert _metadata(kg.db, tmp_path)["index_complete"] is True
+        assert progress.snapshot()["files_total"] == 3
+        assert len(kg.db.get_file_states()) == 3
+
+
+class RecordingContext:
+    """Collects report_progress calls."""
+    
+    def __init__(self):
+        self.reports = []
+    
+    async def report_progress(self, progress, total=None):
+        self.reports.append((progress, total))
+
+
+class TestIndexTools:
+    
+    @pytest.fixture
+    def server(self, make_server):
+        server = make_server(store=InMemoryGraphStore())
+        server.run = BlockingRun()
+        server.index_jobs = IndexJobManager(server.run)
+        return server
+    
+    def test_start_status_and_cancel(self, server, tmp_path):
+        tools = server.mcp.tools
+        
+        started = json.loads(asyncio.run(tools["index_repository"](str(tmp_path))))
+        assert started["state"] == "running"
+        assert server.run.started.wait(5)
+        
+        conflict = json.loads(asyncio.run(tools["reindex"](str(tmp_path))))
+        assert conflict["job_id"] == started["job_id"]
+        assert "already indexing" in conflict["error"]
+        
+        status = json.loads(asyncio.run(tools["get_index_status"](started["job_id"])))
+        assert status["progress"]["processed"] == 1
+        
+        json.loads(asyncio.run(tools["cancel_index"](started["job_id"])))
+        assert server.index_jobs.get(started["job_id"]).done.wait(5)
+        listed = json.loads(asyncio.run(tools["get_index_status"]()))
+        assert [job["state"] for job in listed["jobs"]] == ["cancelled"]
+        
+        assert "error" in json.loads(asyncio.run(tools["cancel_index"]("missing")))
+    
+    def test_wait_reports_progress(self, server, tmp_path):
+        ctx = RecordingContext()
+        threading.Timer(0.7, server.run.release).start()
+        
+        result = json.loads(asyncio.run(server.mcp.tools["index_repository"](str(tmp_path), wait=True, ctx=ctx)))
+        
+        assert result["state"] == "completed"
+        assert ctx.reports and ctx.reports[0] == (1, 2)
@@ -0,0 +1,203 @@
+"""
+File watcher tests.
+
+Events are fed to IndexWatcher.on_event directly (as the watchdog handler
+does), or picked up by the polling fallback, and a recording sync function
+stands in for the incremental index run.
+"""
+
+import os
+import sys
+import threading
+import time
+
+import pytest
+
+# Add project root to Python path
+sys.path.insert(0, os.path.dirname(os.path.dirname(os.path.abspath(__file__))))
+
+from src.indexing import watcher as watcher_module
+from src.indexing.walker import SourceFileWalker
+from src.indexing.watcher import IndexWatcher, get_debounce_seconds
+
+
+class RecordingSync:
+    """Records the paths of every sync; optionally fails the first calls."""
+    
+    def __init__(self, failures=0):
+        self.calls = []
+        self.failures = failures
+        self.done = threading.Event()
+    
+    def __call__(self, paths):
+        if self.failures:
+            self.failures -= 1
+            raise RuntimeError("database unavailable")
+        self.calls.append(paths)
+        self.done.set()
+        return {"mode": "incremental", "changed": len(paths)}
+
+
+def _wait_for(condition, timeout=5.0):
+    deadline = time.monotonic() + timeout
+    while time.monotonic() < deadline:
+        if condition():
+            return True
+        time.sleep(0.01)
+    return False
+
+
+@pytest.fixture
+def tree(tmp_path):
+    (tmp_path / ".gitignore").write_text("vendor/\n")
+    (tmp_path / "pkg").mkdir()
+    (tmp_path / "pkg" / "mod.py").write_text("x = 1\n")
+    (tmp_path / "vendor").mkdir()
+    (tmp_path / "vendor" / "lib.py").write_text("y = 2\n")
+    return tmp_path
+
+
+def _make_watcher(root, sync, **options):
+    walker = SourceFileWalker((".py",))
+    options.setdefault("debounce_seconds", 0.05)
+    return IndexWatcher(
+        str(root),
+        sync,
+        accepts=lambda path, is_dir=False: walker.accepts(str(root), path, is_dir),
+        list_files=lambda: walker.walk(str(root), log_summary=False),
+        **options,
+    )
+
+
+class TestEventFiltering:
+    """Which events end up in the pending set."""
+    
+    def test_ignored_paths_are_dropped(self, tree):
+        watcher = _make_watcher(tree, RecordingSync())
+        
+        assert watcher.notify(str(tree / "pkg" / "mod.py"))
+        assert not watcher.notify(str(tree / "vendor" / "lib.py"))
+        assert not watcher.notify(str(tree / ".git" / "index.py"))
+        assert not watcher.notify(str(tree / "pkg" / "notes.txt"))
+        # A changed .gitignore can change the indexed set
+        assert watcher.notify(str(tree / ".gitignore"))
+        assert not watcher.notify(str(tree / "vendor" / ".gitignore"))
+        # READMEs feed the package summaries
+        assert watcher.notify(str(tree / "pkg" / "README.md"))
+        assert not watcher.notify(str(tree / "vendor" / "README.md"))
+        
+        assert watcher.status()["pending_paths"] == [
+            str(tree / ".gitignore"), str(tree / "pkg" / "README.md"), str(tree / "pkg" / "mod.py")]
+    
+    def test_atomic_rename_save(self, tree):
+        watcher = _make_watcher(tree, RecordingSync())
+        target = str(tree / "pkg" / "mod.py")
+        temp = str(tree / "pkg" / ".mod.py.tmp1234")
+        
+        watcher.on_event("created", temp)
+        watcher.on_event("modified", temp)
+        watcher.on_event("moved", temp, target)
+        
+        assert watcher.status()["pending_paths"] == [target]
+    
+    def test_directory_events(self, tree):
+        watcher = _make_watcher(tree, RecordingSync())
+        
+        watcher.on_event("created", str(tree / "new_pkg"), is_dir=True)
+        watcher.on_event("modified", str(tree / "pkg"), is_dir=True)
+        watcher.on_event("opened", str(tree / "pkg" / "mod.py"))
+        assert watcher.status()["pending_files"] == 0
+        
+        # Moving or deleting a directory removes all files under it
+        watcher.on_event("moved", str(tree / "pkg"), str(tree / "pkg2"), is_dir=True)
+        watcher.on_event("deleted", str(tree / "vendor"), is_dir=True)
+        assert watcher.status()["pending_paths"] == [str(tree / "pkg"), str(tree / "pkg2")]
+
+
+class TestDebouncedSync:
+    """Debounce window, retries and status."""
+    
+    def test_burst_is_coalesced(self, tree):
+        sync = RecordingSync()
+        watcher = _make_watcher(tree, sync, debounce_seconds=0.2, use_polling=True, poll_interval=60)
+        watcher.start()
+        try:
+            for _ in range(5):
+                watcher.notify(str(tree / "pkg" / "mod.py"))
+                time.sleep(0.02)
+            watcher.notify(str(tree / "pkg" / "other.py"))
+            assert watcher.status()["stale"] is True
+            
+            assert sync.done.wait(5)
+            assert _wait_for(lambda: watcher.status()["syncs"] == 1)
+        finally:
+            watcher.stop()
+        
+        assert sync.calls == [[str(tree / "pkg" / "mod.py"), str(tree / "pkg" / "other.py")]]
+        status = watcher.status()
+        assert status["stale"] is False
+        assert status["pending_files"] == 0
+        assert status["last_sync_stats"] == {"mode": "incremental", "changed": 2}
+        assert status["last_sync_time"] is not None
+        assert status["running"] is False
+    
+    def test_failed_sync_is_retried(self, tree, monkeypatch):
+        monkeypatch.setattr(watcher_module, "RETRY_DELAY_SECONDS", 0.05)
+        sync = RecordingSync(failures=1)
+        watcher = _make_watcher(tree, sync, use_polling=True, poll_interval=60)
+        watcher.start()
+        try:
+            watcher.notify(str(tree / "pkg" / "mod.py"))
+            assert sync.done.wait(5)
+            assert _wait_for(lambda: watcher.status()["syncs"] == 1)
+        finally:
+            watcher.stop()
+        
+        assert sync.calls == [[str(tree / "pkg" / "mod.py")]]
+        assert watcher.status()["last_error"] is None
+    
+    def test_initial_sync(self, tree):
+        sync = RecordingSync()
+        watcher = _make_watcher(tree, sync, use_polling=True, poll_interval=60)
+        watcher.start(initial_sync=True)
+        try:
+            assert sync.done.wait(5)
+        finally:
+            watcher.stop()
+        
+        assert sync.calls == [[]]
+    
+    def test_debounce_from_env(self, monkeypatch):
+        monkeypatch.setenv("INDEX_WATCH_DEBOUNCE_MS", "1200")
+        assert get_debounce_seconds() == 1.2
+        monkeypatch.setenv("INDEX_WATCH_DEBOUNCE_MS", "soon")
+        assert get_debounce_seconds() == 0.5
+
+
+class TestPolling:
+    """Polling fallback when watchdog is not installed."""
+    
+    def test_changes_and_deletes_are_detected(self, tree):
+        sync = RecordingSync()
+        watcher = _make_watcher(tree, sync, debounce_seconds=0.3, use_polling=True, poll_interval=0.05)
+        watcher.start()
+        try:
+            assert watcher.status()["mode"] == "polling"
+            
+            # Atomic-rename save: new inode under the same name
+            temp = tree / "pkg" / "mod.py.tmp"
+            temp.write_text("x = 10\n")
+            os.replace(temp, tree / "pkg" / "mod.py")
+            (tree / "pkg" / "added.py").write_text("z = 3\n")
+            (tree / "vendor" / "lib.py").write_text("ignored = True\n")
+            assert _wait_for(lambda: watcher.status()["syncs"] == 1)
+            
+            os.remove(tree / "pkg" / "added.py")
+            assert _wait_for(lambda: watcher.status()["syncs"] == 2)
+        finally:
+            watcher.stop()
+        
+        assert sync.calls == [
+            [str(tree / "pkg" / "added.py"), str(tree / "pkg" / "mod.py")],
+            [str(tree / "pkg" / "added.py")],
+        ]
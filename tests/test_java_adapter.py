@@ -0,0 +1,214 @@
+"""
+Java adapter tests.
+
+Parses the Java files in tests/fixtures/multi_lang_sample through
+MultiLanguageParser (so the second pass runs). The java/ tree holds the
+packages com.example.people and com.example.shapes; Triangle.java declares
+com.example.shapes although it sits in a legacy/ subdirectory.
+"""
+
+import os
+import sys
+import pytest
+
+# Add project root to Python path
+sys.path.insert(0, os.path.dirname(os.path.dirname(os.path.abspath(__file__))))
+
+pytest.importorskip("ast_grep_py")
+
+from src.ast_parser.multi_parser import MultiLanguageParser
+
+
+FIXTURE_DIR = os.path.join(os.path.dirname(os.path.abspath(__file__)), "fixtures", "multi_lang_sample")
+
+
+def _parse(languages):
+    coordinator = MultiLanguageParser(
+        use_ast_grep=True,
+        ast_grep_languages=languages,
+        ast_grep_fallback=False
+    )
+    return coordinator.parse_directory(FIXTURE_DIR, build_index=True)
+
+
+def _type_node(nodes, qualified_name):
+    matches = [n for n in nodes.values() if n.properties.get("qualified_name") == qualified_name]
+    assert len(matches) == 1, f"expected one {qualified_name} node, got {len(matches)}"
+    return matches[0]
+
+
+def _targets(nodes, relations, source, relation_type):
+    """Names of the nodes source points at with relation_type."""
+    return {nodes[r.target_id].name for r in relations
+            if r.source_id == source.node_id and r.relation_type == relation_type}
+
+
+def _members(nodes, relations, type_node, node_type):
+    return [nodes[r.target_id] for r in relations
+            if r.source_id == type_node.node_id and r.relation_type == "DEFINES"
+            and nodes[r.target_id].node_type == node_type]
+
+
+@pytest.fixture
+def java_results():
+    """Parse the fixture tree with the Java adapter enabled."""
+    return _parse(['java'])
+
+
+class TestJavaDeclarations:
+    """Types, methods and fields."""
+    
+    def test_type_kinds(self, java_results):
+        nodes, relations = java_results
+        
+        named = _type_node(nodes, "com.example.people.Named")
+        assert (named.node_type, named.properties["kind"]) == ("Interface", "interface")
+        assert named.properties["methods"] == ["getName()"]
+        audited = _type_node(nodes, "com.example.people.annotations.Audited")
+        assert (audited.node_type, audited.properties["kind"]) == ("Interface", "annotation")
+        color = _type_node(nodes, "com.example.shapes.Square$Color")
+        assert color.node_type == "Enum"
+        assert color.properties["members"] == ["RED", "GREEN"]
+        
+        point = _type_node(nodes, "com.example.shapes.Point")
+        assert (point.node_type, point.properties["kind"]) == ("Class", "record")
+        components = _members(nodes, relations, point, "ClassVariable")
+        assert [(f.name, f.properties["type"], f.properties.get("component")) for f in components] == [
+            ("x", "double", True), ("y", "double", True),
+        ]
+        constructor = next(m for m in _members(nodes, relations, point, "Method") if m.properties.get("constructor"))
+        assert constructor.properties["signature"] == "Point(double,double)"
+    
+    def test_overloads_have_distinct_signatures(self, java_results):
+        nodes, relations = java_results
+        person = _type_node(nodes, "com.example.people.Person")
+        methods = _members(nodes, relations, person, "Method")
+        
+        assert sorted(m.properties["signature"] for m in methods) == [
+            "Person(String,int)", "byAge()", "getAge()", "getName()",
+            "setName(String)", "setName(String,String)", "toString()",
+        ]
+        setters = [m for m in methods if m.name == "setName"]
+        assert len({m.node_id for m in setters}) == 2
+        assert all(m.properties["return_type"] == "void" for m in setters)
+        assert [m.name for m in methods if m.properties.get("constructor")] == ["Person"]
+    
+    def test_fields_and_docs(self, java_results):
+        nodes, relations = java_results
+        person = _type_node(nodes, "com.example.people.Person")
+        
+        fields = _members(nodes, relations, person, "ClassVariable")
+        assert [(f.name, f.properties["type"], f.properties["modifiers"]) for f in fields] == [
+            ("name", "String", ["private"]), ("age", "int", ["private"]),
+        ]
+        assert person.properties["doc"] == "A person with a name and an age."
+        assert person.properties["modifiers"] == ["public"]
+        assert _type_node(nodes, "com.example.people.Person$Address").properties["doc"] == "Where a person lives."
+    
+    def test_nested_and_anonymous_classes(self, java_results):
+        nodes, relations = java_results
+        person = _type_node(nodes, "com.example.people.Person")
+        
+        address = _type_node(nodes, "com.example.people.Person$Address")
+        assert (address.node_type, address.name) == ("Class", "Person$Address")
+        assert _targets(nodes, relations, person, "DEFINES") >= {"Person$Address", "Person$1"}
+        file_contains = {r.target_id for r in relations
+                         if r.source_id == f"file:{person.file_path}" and r.relation_type == "CONTAINS"}
+        assert file_contains == {person.node_id}
+        
+        comparator = _type_node(nodes, "com.example.people.Person$1")
+        assert comparator.properties["kind"] == "anonymous"
+        assert comparator.properties["supertype"] == "Comparator<Person>"
+        assert [m.properties["signature"] for m in _members(nodes, relations, comparator, "Method")] == [
+            "compare(Person,Person)",
+        ]
+        
+        # An enum constant with a body is an anonymous subclass of the enum
+        green = _type_node(nodes, "com.example.shapes.Square$Color$1")
+        assert green.properties["supertype"] == "Square$Color"
+        assert [m.name for m in _members(nodes, relations, green, "Method")] == ["next"]
+
+
+class TestJavaHierarchy:
+    """EXTENDS, IMPLEMENTS and annotation edges."""
+    
+    def test_extends_and_implements(self, java_results):
+        nodes, relations = java_results
+        square = _type_node(nodes, "com.example.shapes.Square")
+        polygon = _type_node(nodes, "com.example.shapes.Polygon")
+        
+        assert _targets(nodes, relations, square, "EXTENDS") == {"Polygon"}
+        assert _targets(nodes, relations, polygon, "IMPLEMENTS") == {"Shape"}
+        assert _targets(nodes, relations, _type_node(nodes, "com.example.people.Person"), "IMPLEMENTS") == {"Named"}
+        # Interface extends through a single-type import from another package
+        assert _targets(nodes, relations, _type_node(nodes, "com.example.shapes.Shape"), "EXTENDS") == {"Named"}
+        # Same package, other directory
+        assert _targets(nodes, relations, _type_node(nodes, "com.example.shapes.Triangle"), "EXTENDS") == {"Polygon"}
+        
+        # java.lang.Comparable is not indexed: kept as a property only
+        assert square.properties["implements"] == ["Comparable<Square>"]
+        assert _targets(nodes, relations, square, "IMPLEMENTS") == set()
+    
+    def test_annotations(self, java_results):
+        nodes, relations = java_results
+        person = _type_node(nodes, "com.example.people.Person")
+        
+        assert person.properties["annotations"] == ['Audited("people")']
+        # Resolved through the wildcard import of com.example.people.annotations
+        assert _targets(nodes, relations, person, "DECORATED_BY") == {"Audited"}
+        city = _members(nodes, relations, _type_node(nodes, "com.example.people.Person$Address"), "ClassVariable")[0]
+        assert _targets(nodes, relations, city, "DECORATED_BY") == {"Audited"}
+        
+        get_name = next(m for m in _members(nodes, relations, person, "Method") if m.name == "getName")
+        assert get_name.properties["annotations"] == ["Override"]
+        assert _targets(nodes, relations, get_name, "DECORATED_BY") == set()
+
+
+class TestJavaPackages:
+    """Package membership and IMPORTS edges."""
+    
+    def test_package_from_declaration(self, java_results):
+        nodes, relations = java_results
+        triangle = _type_node(nodes, "com.example.shapes.Triangle")
+        
+        file_node = nodes[f"file:{triangle.file_path}"]
+        assert os.path.basename(os.path.dirname(triangle.file_path)) == "legacy"
+        assert file_node.properties["package"] == "com.example.shapes"
+        package_files = {os.path.basename(nodes[r.target_id].file_path) for r in relations
+                         if r.source_id == "package:com.example.shapes" and r.relation_type == "CONTAINS"}
+        assert package_files == {"Point.java", "Shape.java", "Square.java", "Triangle.java"}
+        
+        # Without a package declaration the directory is the package
+        sample = nodes[f"file:{os.path.join(FIXTURE_DIR, 'Sample.java')}"]
+        assert "package" not in sample.properties
+    
+    def test_imports(self, java_results):
+        nodes, relations = java_results
+        person = _type_node(nodes, "com.example.people.Person")
+        shape = _type_node(nodes, "com.example.shapes.Shape")
+        
+        imports = {r.target_id: r.properties for r in relations
+                   if r.source_id == f"file:{person.file_path}" and r.relation_type == "IMPORTS"}
+        assert imports["package:com.example.people.annotations"]["wildcard"] is True
+        assert imports["external_package:java.util"]["symbol"] == "Comparator"
+        
+        (edge,) = [r for r in relations if r.source_id == f"file:{shape.file_path}" and r.relation_type == "IMPORTS"]
+        assert edge.target_id == _type_node(nodes, "com.example.people.Named").node_id
+        assert edge.properties["import_path"] == "com.example.people"
+
+
+class TestJavaGoParity:
+    """The Java Person fixture mirrors sample.go."""
+    
+    def test_person_methods_match(self):
+        nodes, relations = _parse(['go', 'java'])
+        go_person = next(n for n in nodes.values()
+                         if n.node_type == "Class" and n.name == "Person" and n.file_path.endswith("sample.go"))
+        java_person = _type_node(nodes, "com.example.people.Person")
+        
+        go_methods = {nodes[r.source_id].name.lower() for r in relations
+                      if r.relation_type == "METHOD_OF" and r.target_id == go_person.node_id}
+        java_methods = {m.name.lower() for m in _members(nodes, relations, java_person, "Method")
+                        if not m.properties.get("constructor")}
+        # String() is Go's toString(); byAge has no Go counterpart
+        assert {name.replace("tostring", "string") for name in java_methods} - {"byage"} == go_methods
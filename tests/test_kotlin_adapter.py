@@ -0,0 +1,264 @@
+"""
+Kotlin adapter tests.
+
+Parses the Java and Kotlin files in tests/fixtures/multi_lang_sample
+through MultiLanguageParser (so the second pass runs). The kotlin/ tree
+holds the com.example.billing package, where the Kotlin SavingsAccount
+extends the Java Account, and com.example.billing.audit, where the Java
+AuditedLedger extends the Kotlin Ledger and implements Auditable.
+TestSecondPass runs the second pass on hand-built nodes, the way the two
+adapters leave them, so it does not need ast-grep.
+"""
+
+import os
+import sys
+import pytest
+
+# Add project root to Python path
+sys.path.insert(0, os.path.dirname(os.path.dirname(os.path.abspath(__file__))))
+
+from src.ast_parser.parser import ASTParser, CodeNode
+from src.ast_parser.adapters.java_adapter import JavaAdapter
+from src.ast_parser.adapters.kotlin_adapter import KotlinAdapter
+
+
+FIXTURE_DIR = os.path.join(os.path.dirname(os.path.abspath(__file__)), "fixtures", "multi_lang_sample")
+KOTLIN_DIR = os.path.join(FIXTURE_DIR, "kotlin")
+
+
+def _parse(languages):
+    pytest.importorskip("ast_grep_py")
+    from src.ast_parser.multi_parser import MultiLanguageParser
+    
+    coordinator = MultiLanguageParser(
+        use_ast_grep=True,
+        ast_grep_languages=languages,
+        ast_grep_fallback=False
+    )
+    return coordinator.parse_directory(KOTLIN_DIR, build_index=True)
+
+
+def _type_node(nodes, qualified_name):
+    matches = [n for n in nodes.values()
+               if n.node_type in ("Class", "Interface", "TypeAlias")
+               and n.properties.get("qualified_name") == qualified_name]
+    assert len(matches) == 1, f"expected one {qualified_name} node, got {len(matches)}"
+    return matches[0]
+
+
+def _targets(nodes, relations, source, relation_type):
+    """Qualified names (or names) of the nodes source points at with relation_type."""
+    return sorted(nodes[r.target_id].properties.get("qualified_name") or nodes[r.target_id].name
+                  for r in relations if r.source_id == source.node_id and r.relation_type == relation_type)
+
+
+def _members(nodes, relations, type_node, node_type):
+    return [nodes[r.target_id] for r in relations
+            if r.source_id == type_node.node_id and r.relation_type == "DEFINES"
+            and nodes[r.target_id].node_type == node_type]
+
+
+def _top_level(nodes, file_name, name):
+    matches = [n for n in nodes.values()
+               if n.name == name and os.path.basename(n.file_path) == file_name and n.node_type != "Method"]
+    assert len(matches) == 1, f"expected one {name} in {file_name}, got {len(matches)}"
+    return matches[0]
+
+
+@pytest.fixture
+def kotlin_results():
+    """Parse the fixture tree with the Java and Kotlin adapters enabled."""
+    return _parse(['java', 'kotlin'])
+
+
+class TestKotlinDeclarations:
+    """Classes, objects, functions and properties."""
+    
+    def test_class_members(self, kotlin_results):
+        nodes, relations = kotlin_results
+        
+        account = _type_node(nodes, "com.example.billing.SavingsAccount")
+        assert account.properties["kind"] == "class"
+        assert account.properties["supertypes"] == ["Account", "Auditable"]
+        methods = {m.name: m.properties for m in _members(nodes, relations, account, "Method")}
+        assert methods["SavingsAccount"]["signature"] == "SavingsAccount(String,Long)"
+        assert methods["SavingsAccount"]["primary"] is True
+        assert methods["balance"]["return_type"] == "Long"
+        assert methods["balance"]["modifiers"] == ["override"]
+        assert methods["deposit"]["cyclomatic_complexity"] == 2
+        fields = {f.name: f.properties for f in _members(nodes, relations, account, "ClassVariable")}
+        # Only the var parameter is a property, id is a plain constructor parameter
+        assert set(fields) == {"cents", "isEmpty"}
+        assert fields["cents"]["constructor_property"] is True
+        assert fields["cents"]["mutable"] is True
+        assert fields["isEmpty"]["custom_getter"] is True
+    
+    def test_companion_object(self, kotlin_results):
+        nodes, relations = kotlin_results
+        
+        account = _type_node(nodes, "com.example.billing.SavingsAccount")
+        [companion] = _members(nodes, relations, account, "Class")
+        assert companion.properties["qualified_name"] == "com.example.billing.SavingsAccount$Companion"
+        assert companion.properties["kind"] == "companion_object"
+        fields = {f.name: f.properties for f in _members(nodes, relations, companion, "ClassVariable")}
+        assert fields["MIN_BALANCE"]["modifiers"] == ["const"]
+        assert [m.properties["signature"] for m in _members(nodes, relations, companion, "Method")] == [
+            "open(String)",
+        ]
+    
+    def test_sealed_hierarchy(self, kotlin_results):
+        nodes, relations = kotlin_results
+        
+        payment = _type_node(nodes, "com.example.billing.Payment")
+        assert payment.properties["sealed"] is True
+        assert payment.properties["doc"] == "Outcome of a payment."
+        settled = _type_node(nodes, "com.example.billing.Payment$Settled")
+        declined = _type_node(nodes, "com.example.billing.Payment$Declined")
+        assert (settled.properties["kind"], declined.properties["kind"]) == ("data_class", "object")
+        for subtype in (settled, declined):
+            assert _targets(nodes, relations, subtype, "EXTENDS") == ["com.example.billing.Payment"]
+        event = _type_node(nodes, "com.example.billing.Event")
+        assert (event.node_type, event.properties["sealed"]) == ("Interface", True)
+        charged = _type_node(nodes, "com.example.billing.Charged")
+        assert _targets(nodes, relations, charged, "IMPLEMENTS") == ["com.example.billing.Event"]
+        alias = _type_node(nodes, "com.example.billing.PaymentHandler")
+        assert alias.properties["kind"] == "typealias"
+    
+    def test_extensions(self, kotlin_results):
+        nodes, relations = kotlin_results
+        
+        describe = _top_level(nodes, "SavingsAccount.kt", "describe")
+        assert describe.node_type == "Function"
+        assert (describe.properties["receiver_type"], describe.properties["extension"]) == ("Account", True)
+        assert describe.properties["doc"] == "Extension on the Java base class."
+        assert _targets(nodes, relations, describe, "EXTENSION_OF") == ["com.example.billing.Account"]
+        overdrawn = _top_level(nodes, "SavingsAccount.kt", "overdrawn")
+        assert overdrawn.node_type == "Variable"
+        assert overdrawn.properties["custom_getter"] is True
+        assert _targets(nodes, relations, overdrawn, "EXTENSION_OF") == ["com.example.billing.SavingsAccount"]
+        report = _top_level(nodes, "Report.kt", "report")
+        assert report.properties["signature"] == "report(SavingsAccount,vararg String)"
+
+
+class TestKotlinJavaInterop:
+    """Types resolved across the two languages of one package tree."""
+    
+    def test_kotlin_extends_java(self, kotlin_results):
+        nodes, relations = kotlin_results
+        
+        account = _type_node(nodes, "com.example.billing.SavingsAccount")
+        assert _targets(nodes, relations, account, "EXTENDS") == ["com.example.billing.Account"]
+        assert _targets(nodes, relations, account, "IMPLEMENTS") == ["com.example.billing.Auditable"]
+    
+    def test_java_extends_kotlin(self, kotlin_results):
+        nodes, relations = kotlin_results
+        
+        ledger = _type_node(nodes, "com.example.billing.audit.AuditedLedger")
+        assert _targets(nodes, relations, ledger, "EXTENDS") == ["com.example.billing.Ledger"]
+        assert _targets(nodes, relations, ledger, "IMPLEMENTS") == ["com.example.billing.Auditable"]
+    
+    def test_aliased_import(self, kotlin_results):
+        nodes, relations = kotlin_results
+        
+        monthly = _type_node(nodes, "com.example.billing.audit.MonthlyLedger")
+        assert _targets(nodes, relations, monthly, "EXTENDS") == ["com.example.billing.Ledger"]
+    
+    def test_only_kotlin_enabled(self):
+        nodes, relations = _parse(['kotlin'])
+        
+        account = _type_node(nodes, "com.example.billing.SavingsAccount")
+        # Account.java is not parsed, the base class stays a placeholder
+        assert _targets(nodes, relations, account, "EXTENDS") == []
+        assert _targets(nodes, relations, account, "INHERITS_FROM") == ["Account"]
+
+
+class TestSecondPass:
+    """The second pass on hand-built nodes, the way the adapters leave them."""
+    
+    BILLING = "/repo/src/com/example/billing"
+    AUDIT = "/repo/src/com/example/billing/audit"
+    
+    def _node(self, parser, node_type, binary_name, file_path, line_no):
+        qualified_name = f"com.example.billing.{binary_name}"
+        node = CodeNode(f"{node_type}:{file_path}:{binary_name}:{line_no}", node_type,
+                        binary_name.rpartition("$")[2], file_path, line_no,
+                        properties={"qualified_name": qualified_name})
+        parser.nodes[node.node_id] = node
+        return node.node_id
+    
+    def _adapter(self, adapter, file_path, package, single_imports=None):
+        """An adapter in the state it has while parsing file_path."""
+        adapter.current_file = file_path
+        adapter.current_package_key = adapter.package_key(file_path, package)
+        adapter.file_types = {}
+        adapter.single_imports = single_imports or {}
+        adapter.wildcard_imports = []
+        return adapter
+    
+    @pytest.fixture
+    def resolved(self):
+        parser = ASTParser()
+        account = self._node(parser, "Class", "Account", f"{self.BILLING}/Account.java", 4)
+        savings = self._node(parser, "Class", "SavingsAccount", f"{self.BILLING}/SavingsAccount.kt", 3)
+        auditable = self._node(parser, "Interface", "Auditable", f"{self.BILLING}/Auditable.kt", 4)
+        ledger = self._node(parser, "Class", "Ledger", f"{self.BILLING}/Auditable.kt", 8)
+        payment = self._node(parser, "Class", "Payment", f"{self.BILLING}/Payment.kt", 4)
+        settled = self._node(parser, "Class", "Payment$Settled", f"{self.BILLING}/Payment.kt", 7)
+        describe = self._node(parser, "Function", "describe", f"{self.BILLING}/SavingsAccount.kt", 24)
+        audited = self._node(parser, "Class", "AuditedLedger", f"{self.AUDIT}/AuditedLedger.java", 6)
+        parser.module_definitions = {
+            "java:com.example.billing": {
+                "Account": account, "SavingsAccount": savings, "Auditable": auditable,
+                "Ledger": ledger, "Payment": payment, "Payment$Settled": settled,
+            },
+            "java:com.example.billing.audit": {"AuditedLedger": audited},
+        }
+        
+        kotlin = self._adapter(KotlinAdapter(), f"{self.BILLING}/SavingsAccount.kt", "com.example.billing")
+        for name in ("Account", "Auditable", "Closeable"):
+            kotlin._queue_type_reference("BASE_TYPE", savings, name, external_name=name)
+        kotlin._queue_type_reference("EXTENSION_OF", describe, "Account")
+        kotlin.current_file = f"{self.BILLING}/Payment.kt"
+        kotlin.file_types = {"Payment": "Payment", "Settled": "Payment$Settled"}
+        kotlin._queue_type_reference("BASE_TYPE", settled, "Payment", external_name="Payment")
+        
+        imports = {name: ["java:com.example.billing", name] for name in ("Ledger", "Auditable")}
+        java = self._adapter(JavaAdapter(), f"{self.AUDIT}/AuditedLedger.java", "com.example.billing.audit",
+                             imports)
+        java._queue_type_reference("EXTENDS", audited, "Ledger")
+        java._queue_type_reference("IMPLEMENTS", audited, "Auditable")
+        parser.pending_imports = kotlin.pending_imports + java.pending_imports
+        parser._process_pending_imports()
+        return parser, locals()
+    
+    def _targets(self, parser, source_id, relation_type):
+        return sorted(r.target_id for r in parser.relations
+                      if r.source_id == source_id and r.relation_type == relation_type)
+    
+    def test_kotlin_supertypes(self, resolved):
+        parser, ids = resolved
+        
+        assert self._targets(parser, ids["savings"], "EXTENDS") == [ids["account"]]
+        assert self._targets(parser, ids["savings"], "IMPLEMENTS") == [ids["auditable"]]
+        assert self._targets(parser, ids["settled"], "EXTENDS") == [ids["payment"]]
+    
+    def test_supertype_not_indexed(self, resolved):
+        parser, ids = resolved
+        
+        [target_id] = self._targets(parser, ids["savings"], "INHERITS_FROM")
+        assert parser.nodes[target_id].node_type == "ExternalType"
+        assert parser.nodes[target_id].name == "Closeable"
+    
+    def test_java_supertypes(self, resolved):
+        parser, ids = resolved
+        
+        assert self._targets(parser, ids["audited"], "EXTENDS") == [ids["ledger"]]
+        assert self._targets(parser, ids["audited"], "IMPLEMENTS") == [ids["auditable"]]
+    
+    def test_extension_receiver(self, resolved):
+        parser, ids = resolved
+        
+        [relation] = [r for r in parser.relations
+                      if r.source_id == ids["describe"] and r.relation_type == "EXTENSION_OF"]
+        assert relation.target_id == ids["account"]
+        assert relation.properties["original_name"] == "Account"
@@ -41,6 +41,9 @@ def run_test():
         mock_db_instance.create_vector_index.return_value = None
         mock_db_instance.create_full_text_index.return_value = None
         mock_db_instance.clear_database.return_value = None
+        # 空的圖譜 / An empty graph
+        mock_db_instance.get_nodes.return_value = []
+        mock_db_instance.find_nodes.return_value = []
 
         # 配置 Mock Embedder
         mock_embedder_instance = MockOpenAIEmbeddings.return_value
@@ -68,7 +71,12 @@ def run_test():
             print("- 成功: 創建結構已呼叫")
             mock_code_embedder_instance.embed_code_nodes_batch.assert_called()
             print("- 成功: 嵌入向量生成已呼叫")
-            mock_db_instance.batch_create_nodes.assert_called_once()
+            # 空圖譜的 schema 版本、節點一批寫入，之後是標記索引完成的 IndexMetadata 節點
+            # The schema version of the empty graph, one batch of nodes, then the IndexMetadata node marking the index complete
+            assert mock_db_instance.batch_create_nodes.call_count == 3
+            (metadata,) = mock_db_instance.batch_create_nodes.call_args[0][0]
+            assert metadata["labels"] == ["Base", "IndexMetadata"]
+            assert metadata["properties"]["index_complete"] is True
             print("- 成功: 批量創建節點已呼叫")
             mock_db_instance.batch_create_relationships.assert_called_once()
             print("- 成功: 批量創建關係已呼叫")
@@ -0,0 +1,572 @@
+"""
+Complexity metric tests.
+
+The counting rules of ast_parser/metrics.py are pinned per language by
+tables of small functions and their expected metrics: Python on ast
+nodes, the tree-sitter languages through ast-grep (skipped without it).
+syntax_metrics is also checked on hand-built stand-ins for ast-grep
+nodes. The end-to-end tests index Python files into an InMemoryGraphStore
+and query them with query_metrics.
+"""
+
+import ast
+import asyncio
+import json
+import os
+import sys
+import textwrap
+
+import pytest
+
+# Add project root to Python path
+sys.path.insert(0, os.path.dirname(os.path.dirname(os.path.abspath(__file__))))
+
+from src.ast_parser.metrics import (
+    C_METRIC_RULES,
+    CSHARP_METRIC_RULES,
+    GO_METRIC_RULES,
+    JAVA_METRIC_RULES,
+    JAVASCRIPT_METRIC_RULES,
+    KOTLIN_METRIC_RULES,
+    RUBY_METRIC_RULES,
+    RUST_METRIC_RULES,
+    python_metrics,
+    syntax_metrics,
+)
+from src.graph_store import InMemoryGraphStore
+from src.mcp.metrics import query_metrics
+from conftest import ConstantEmbeddingProvider
+
+
+def _expected(complexity, statements, nesting, lines):
+    return {"cyclomatic_complexity": complexity, "statement_count": statements, "max_nesting": nesting,
+            "line_count": lines}
+
+
+# (source, cyclomatic complexity, statements, max nesting, lines)
+PYTHON_CASES = [
+    ("def noop():\n    pass\n", 1, 1, 0, 2),
+    (
+        '''
+        def describe(n):
+            """Docstrings are not statements."""
+            if n < 0 and n != -1 or n > 100:
+                kind = "out of range"
+            elif n == 0:
+                kind = "zero"
+            else:
+                if n % 2:
+                    kind = "odd"
+                else:
+                    kind = "even"
+            return kind
+        ''',
+        6, 8, 2, 12,
+    ),
+    (
+        '''
+        def drain(queue, log):
+            with log:
+                while queue:
+                    try:
+                        for item in queue.pop():
+                            log.write(item)
+                    except (KeyError, ValueError):
+                        continue
+                    except OSError:
+                        break
+        ''',
+        5, 7, 4, 10,
+    ),
+    (
+        '''
+        def evens(rows):
+            keep = lambda row: row.ok if row else False
+            return [cell for row in rows if keep(row) for cell in row if cell % 2 == 0]
+        ''',
+        6, 2, 0, 3,
+    ),
+    (
+        '''
+        def command(action):
+            match action:
+                case "start" | "run":
+                    return 1
+                case str() if action:
+                    return 2
+                case _:
+                    return 0
+        ''',
+        3, 4, 1, 8,
+    ),
+    (
+        '''
+        def outer(items):
+            def inner(item):
+                if item:
+                    return item
+            class Local:
+                pass
+            return [inner(item) for item in items]
+        ''',
+        2, 3, 0, 7,
+    ),
+]
+
+
+class TestPythonMetrics:
+    
+    @pytest.mark.parametrize("source, complexity, statements, nesting, lines", PYTHON_CASES)
+    def test_counting_rules(self, source, complexity, statements, nesting, lines):
+        node = ast.parse(textwrap.dedent(source).strip("\n")).body[0]
+        
+        assert python_metrics(node) == _expected(complexity, statements, nesting, lines)
+    
+    def test_async_function(self):
+        node = ast.parse("async def fetch(urls):\n    async for url in urls:\n        await url\n").body[0]
+        
+        assert python_metrics(node) == _expected(2, 2, 1, 3)
+
+
+class FakeNode:
+    """The kind / field / children / text / is_named interface of an ast-grep node."""
+    
+    def __init__(self, kind, text="", children=(), named=True, **fields):
+        self._kind = kind
+        self._text = text
+        self._children = list(children) + [node for node in fields.values() if node not in children]
+        self._named = named
+        self._fields = fields
+    
+    def kind(self):
+        return self._kind
+    
+    def text(self):
+        return self._text
+    
+    def children(self):
+        return self._children
+    
+    def field(self, name):
+        return self._fields.get(name)
+    
+    def is_named(self):
+        return self._named
+
+
+def _statement(text="x();"):
+    return FakeNode("expression_statement", text)
+
+
+class TestSyntaxMetrics:
+    
+    def test_else_if_chain_stays_on_one_level(self):
+        # if (a && b) { if (c) { x(); } } else if (d) { x(); } else { if (e) { x(); } }
+        inner = FakeNode("if_statement", consequence=FakeNode("statement_block", children=[_statement()]))
+        last_else = FakeNode("else_clause", children=[
+            FakeNode("statement_block", children=[
+                FakeNode("if_statement", consequence=FakeNode("statement_block", children=[_statement()])),
+            ]),
+        ])
+        else_if = FakeNode("if_statement", consequence=FakeNode("statement_block", children=[_statement()]),
+                           alternative=last_else)
+        root_if = FakeNode(
+            "if_statement",
+            condition=FakeNode("binary_expression", operator=FakeNode("&&", "&&", named=False)),
+            consequence=FakeNode("statement_block", children=[inner]),
+            alternative=FakeNode("else_clause", children=[else_if]),
+        )
+        function = FakeNode("function_declaration", body=FakeNode("statement_block", children=[root_if]))
+        
+        assert syntax_metrics(function, JAVASCRIPT_METRIC_RULES, 1, 9) == _expected(6, 7, 2, 9)
+    
+    def test_nested_named_functions_are_one_statement(self):
+        nested = FakeNode("function_declaration",
+                          body=FakeNode("statement_block", children=[FakeNode("if_statement")]))
+        arrow = FakeNode("arrow_function", body=FakeNode("ternary_expression"))
+        function = FakeNode("function_declaration", body=FakeNode("statement_block", children=[
+            nested,
+            FakeNode("lexical_declaration", children=[arrow]),
+        ]))
+        
+        assert syntax_metrics(function, JAVASCRIPT_METRIC_RULES, 1, 4) == _expected(2, 2, 0, 4)
+    
+    def test_declarations_without_body_have_no_metrics(self):
+        assert syntax_metrics(FakeNode("method_signature"), JAVASCRIPT_METRIC_RULES, 1, 1) == {}
+    
+    def test_ruby_block_children_are_statements(self):
+        # def load; x = 1; def helper; y; end; if x then z end; rescue IOError; retry; end
+        helper = FakeNode("method", body=FakeNode("body_statement", children=[FakeNode("identifier", "y")]))
+        condition = FakeNode("if", consequence=FakeNode("then", children=[FakeNode("identifier", "z")]))
+        rescue = FakeNode("rescue", body=FakeNode("then", children=[FakeNode("retry", "retry")]))
+        method = FakeNode("method", body=FakeNode("body_statement", children=[
+            FakeNode("assignment", "x = 1"), FakeNode("comment", "# helper"), helper, condition, rescue,
+        ]))
+        
+        # The nested method is one statement, the rescue clause is not
+        assert syntax_metrics(method, RUBY_METRIC_RULES, 1, 8) == _expected(3, 5, 1, 8)
+    
+    def test_kotlin_statements_and_when_entries(self):
+        # fun pick(x: Int?): Int { fun helper() = if (x == null) 0 else 1
+        #     val y = x ?: 0; if (y > 0) { return y } else if (y < 0) { return -y }
+        #     return when (y) { 0 -> 1; else -> 2 } }
+        helper = FakeNode("function_declaration", children=[
+            FakeNode("function_body", children=[FakeNode("if_expression")]),
+        ])
+        else_if = FakeNode("if_expression", children=[
+            FakeNode("control_structure_body", children=[
+                FakeNode("block", children=[
+                    FakeNode("statements", children=[FakeNode("jump_expression", "return -y")]),
+                ]),
+            ]),
+        ])
+        condition = FakeNode("if_expression", children=[
+            FakeNode("control_structure_body", children=[
+                FakeNode("block", children=[
+                    FakeNode("statements", children=[FakeNode("jump_expression", "return y")]),
+                ]),
+            ]),
+            FakeNode("else", "else", named=False),
+            FakeNode("control_structure_body", children=[else_if]),
+        ])
+        when = FakeNode("when_expression", children=[
+            FakeNode("when_entry", "0 -> 1"), FakeNode("when_entry", "else -> 2"),
+        ])
+        function = FakeNode("function_declaration", children=[
+            FakeNode("simple_identifier", "pick"),
+            FakeNode("function_body", children=[FakeNode("block", children=[FakeNode("statements", children=[
+                helper,
+                FakeNode("property_declaration", children=[FakeNode("elvis_expression", "x ?: 0")]),
+                FakeNode("line_comment", "// sign"),
+                condition,
+                FakeNode("jump_expression", children=[when]),
+            ])])]),
+        ])
+        
+        # The nested function is one statement, the else entry and the else-if add no level
+        assert syntax_metrics(function, KOTLIN_METRIC_RULES, 1, 5) == _expected(5, 6, 1, 5)
+
+
+# language -> (rules, source, kind of the measured function, complexity, statements, max nesting, lines)
+SYNTAX_CASES = {
+    "go": (
+        GO_METRIC_RULES,
+        '''
+        package sample
+        
+        func Classify(n int, ok bool) string {
+        	if n < 0 && ok {
+        		return "negative"
+        	} else if n == 0 {
+        		return "zero"
+        	}
+        	for i := 0; i < n; i++ {
+        		switch {
+        		case i%2 == 0:
+        			continue
+        		default:
+        			break
+        		}
+        	}
+        	return "positive"
+        }
+        ''',
+        "function_declaration", 6, 11, 2, 16,
+    ),
+    "java": (
+        JAVA_METRIC_RULES,
+        '''
+        class Scores {
+            int score(int[] xs, boolean strict) {
+                int total = 0;
+                for (int x : xs) {
+                    if (x > 0 || strict) {
+                        total += x;
+                    } else if (x == 0) {
+                        continue;
+                    }
+                }
+                try {
+                    total = total > 100 ? 100 : total;
+                } catch (RuntimeException e) {
+                    total = 0;
+                }
+                return total;
+            }
+        }
+        ''',
+        "method_declaration", 7, 10, 2, 16,
+    ),
+    "javascript": (
+        JAVASCRIPT_METRIC_RULES,
+        '''
+        function route(req, handlers) {
+          const pick = (item) => item.ok ? item : null;
+          if (!handlers) {
+            return null;
+          } else if (req.method === "GET" && req.cached) {
+            return handlers.cached ?? null;
+          }
+          switch (req.method) {
+            case "POST":
+              return handlers.post(req);
+            default:
+              for (const item of req.items) {
+                if (pick(item)) {
+                  return item;
+                }
+              }
+              return null;
+          }
+        }
+        ''',
+        "function_declaration", 9, 11, 3, 19,
+    ),
+    "typescript": (
+        JAVASCRIPT_METRIC_RULES,
+        '''
+        class Cart {
+          total(items: Item[], rate?: number): number {
+            let sum = 0;
+            for (const item of items) {
+              sum += item.price * (rate || 1);
+            }
+            return sum;
+          }
+        }
+        ''',
+        "method_definition", 3, 4, 1, 7,
+    ),
+    "rust": (
+        RUST_METRIC_RULES,
+        '''
+        fn parse(input: &str, strict: bool) -> Option<u32> {
+            let trimmed = input.trim();
+            if trimmed.is_empty() || strict {
+                return None;
+            } else if trimmed == "0" {
+                return Some(0);
+            }
+            match trimmed.parse::<u32>() {
+                Ok(n) if n > 10 => Some(10),
+                Ok(n) => Some(n),
+                _ => None,
+            }
+        }
+        ''',
+        "function_item", 6, 5, 1, 13,
+    ),
+    "cpp": (
+        C_METRIC_RULES,
+        '''
+        int clamp(int v, int lo, int hi) {
+            if (v < lo) {
+                return lo;
+            } else if (v > hi) {
+                return hi;
+            }
+            for (int i = 0; i < 3 && v > 0; ++i) {
+                while (v % 2 == 0) {
+                    v /= 2;
+                }
+            }
+            switch (v) {
+                case 1: return 1;
+                default: break;
+            }
+            return v > 0 ? v : -v;
+        }
+        ''',
+        "function_definition", 8, 12, 2, 17,
+    ),
+    "csharp": (
+        CSHARP_METRIC_RULES,
+        '''
+        class Scores
+        {
+            int Score(int[] xs, bool strict)
+            {
+                var total = 0;
+                foreach (var x in xs)
+                {
+                    if (x > 0 || strict)
+                    {
+                        total += x;
+                    }
+                    else if (x == 0)
+                    {
+                        continue;
+                    }
+                }
+                switch (total)
+                {
+                    case 0:
+                        return 0;
+                    default:
+                        break;
+                }
+                return total > 100 ? 100 : total;
+            }
+        }
+        ''',
+        "method_declaration", 7, 10, 2, 23,
+    ),
+    "ruby": (
+        RUBY_METRIC_RULES,
+        '''
+        class Scores
+          def score(xs, strict)
+            total = 0
+            xs.each do |x|
+              if x > 0 || strict
+                total += x
+              elsif x == 0
+                next
+              end
+            end
+            case total
+            when 0 then return 0
+            else puts "ok"
+            end
+            total > 100 ? 100 : total
+          end
+        end
+        ''',
+        "method", 6, 9, 2, 15,
+    ),
+}
+
+
+class TestSyntaxMetricTables:
+    
+    @pytest.mark.parametrize("language", sorted(SYNTAX_CASES))
+    def test_counting_rules(self, language):
+        ast_grep_py = pytest.importorskip("ast_grep_py")
+        rules, source, kind, complexity, statements, nesting, lines = SYNTAX_CASES[language]
+        root = ast_grep_py.SgRoot(textwrap.dedent(source).strip("\n"), language).root()
+        (function,) = root.find_all(kind=kind)
+        
+        metrics = syntax_metrics(function, rules, function.range().start.line + 1, function.range().end.line + 1)
+        
+        assert metrics == _expected(complexity, statements, nesting, lines)
+
+
+CODEBASE = {
+    "services/__init__.py": "",
+    "services/payments/__init__.py": "",
+    "services/payments/charge.py": (
+        "class Charger:\n"
+        "    def charge(self, card, amount, currency):\n"
+        "        if not card or amount <= 0:\n"
+        "            return None\n"
+        "        for attempt in range(3):\n"
+        "            if self.send(card, amount):\n"
+        "                return attempt\n"
+        "        return None\n"
+        "\n"
+        "    def send(self, card, amount):\n"
+        "        return True\n"
+        "\n"
+        "\n"
+        "def refund(charge):\n"
+        "    return charge.undo() if charge else None\n"
+    ),
+    "services/report.py": (
+        "def summarize(rows):\n"
+        "    total = 0\n"
+        "    for row in rows:\n"
+        "        total += row\n"
+        "    return total\n"
+    ),
+}
+
+
+@pytest.fixture
+def kg(monkeypatch, tmp_path):
+    monkeypatch.setenv("USE_AST_GREP", "false")
+    monkeypatch.setenv("ENABLE_JS_TS_PARSING", "false")
+    monkeypatch.setenv("PARALLEL_INDEXING_ENABLED", "false")
+    from src.main import CodebaseKnowledgeGraph
+    
+    for path, source in CODEBASE.items():
+        (tmp_path / path).parent.mkdir(parents=True, exist_ok=True)
+        (tmp_path / path).write_text(source)
+    kg = CodebaseKnowledgeGraph(store=InMemoryGraphStore(), embedding_provider=ConstantEmbeddingProvider())
+    kg.process_codebase(str(tmp_path))
+    return kg
+
+
+def _names(result):
+    return [entry["name"] for entry in result["results"]]
+
+
+class TestQueryMetrics:
+    
+    def test_indexed_functions_store_metrics(self, kg):
+        (charge,) = kg.db.find_nodes(name="charge", label="Method")
+        
+        assert {key: charge["properties"][key] for key in ("cyclomatic_complexity", "statement_count",
+                                                           "max_nesting", "line_count", "arity")} == {
+            "cyclomatic_complexity": 5, "statement_count": 6, "max_nesting": 2, "line_count": 7, "arity": 3,
+        }
+    
+    def test_ranks_by_metric_with_stable_ties(self, kg):
+        result = query_metrics(kg.db)
+        
+        assert _names(result) == ["charge", "refund", "summarize", "send"]
+        assert result["total"] == 4
+        assert result["results"][0]["metrics"] == {
+            "complexity": 5, "statements": 6, "lines": 7, "parameters": 3, "nesting": 2,
+        }
+        assert _names(query_metrics(kg.db, sort_by="lines", order="asc", limit=2)) == ["send", "refund"]
+        assert _names(query_metrics(kg.db, sort_by="lines", order="asc", limit=2, offset=2)) == ["summarize",
+                                                                                                  "charge"]
+    
+    def test_scope_node_type_and_thresholds(self, kg):
+        assert _names(query_metrics(kg.db, scope="services/payments")) == ["charge", "refund", "send"]
+        assert _names(query_metrics(kg.db, node_type="Function")) == ["refund", "summarize"]
+        assert _names(query_metrics(kg.db, at_least={"lines": 5})) == ["charge", "summarize"]
+        assert _names(query_metrics(kg.db, at_least={"complexity": 2}, at_most={"parameters": 1})) == [
+            "refund", "summarize",
+        ]
+    
+    def test_nodes_indexed_without_metrics_are_counted(self, kg):
+        kg.db.batch_create_nodes([{
+            "labels": ["Base", "Function"],
+            "properties": {"id": "Function:old.py:legacy:1", "name": "legacy", "file_path": "old.py", "line_no": 1},
+        }])
+        
+        result = query_metrics(kg.db)
+        
+        assert result["unmeasured"] == 1
+        assert "legacy" not in _names(result)
+    
+    @pytest.mark.parametrize("arguments", [
+        {"sort_by": "size"},
+        {"order": "down"},
+        {"node_type": "Class"},
+        {"limit": 0},
+        {"offset": -1},
+        {"at_least": {"depth": 3}},
+        {"at_most": {"lines": "many"}},
+    ])
+    def test_invalid_arguments(self, kg, arguments):
+        with pytest.raises(ValueError):
+            query_metrics(kg.db, **arguments)
+
+
+class TestQueryMetricsTool:
+    
+    @pytest.fixture
+    def tool(self, kg, make_server):
+        server = make_server(store=kg.db)
+        return lambda **kwargs: json.loads(asyncio.run(server.mcp.tools["query_metrics"](**kwargs)))
+    
+    def test_top_functions_under_a_directory(self, tool):
+        result = tool(scope="services/payments", limit=1)
+        
+        assert [entry["name"] for entry in result["results"]] == ["charge"]
+        assert result["total"] == 3
+    
+    def test_errors_are_returned(self, tool):
+        assert "error" in tool(sort_by="size")
@@ -96,14 +96,14 @@ class Person:
         self.assertEqual(type(parser).__name__, 'TypeScriptParser')
 
     def test_parser_routing_typescript(self):
-        """Test that TypeScript files are routed to TypeScriptParser."""
+        """Test that TypeScript files are routed to TypeScriptAdapter."""
         self._create_test_file("test.ts", "function test(): void {}")
         
         processor = CodebaseKnowledgeGraph(self.mock_embedder, self.mock_graph_db)
         parser = processor._get_parser_for_file(os.path.join(self.test_dir, "test.ts"))
         
-        # Should be TypeScriptParser
-        self.assertEqual(type(parser).__name__, 'TypeScriptParser')
+        # Should be TypeScriptAdapter, also without USE_AST_GREP
+        self.assertEqual(type(parser).__name__, 'TypeScriptAdapter')
 
     def test_mixed_codebase_python_and_js(self):
         """Test processing a codebase with both Python and JavaScript."""
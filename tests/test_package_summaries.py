@@ -0,0 +1,235 @@
+"""
+Package summary and get_package_overview tests.
+
+README extraction is checked on Markdown and reStructuredText documents
+with badges, HTML, directives and code; compute_package_summaries on
+hand-made package docs. get_package_overview runs on a Go package seeded
+into an InMemoryGraphStore. The end-to-end tests index a small Python
+codebase, full and incremental, and edit its README and __init__.py.
+"""
+
+import os
+import sys
+
+import pytest
+
+# Add project root to Python path
+sys.path.insert(0, os.path.dirname(os.path.dirname(os.path.abspath(__file__))))
+
+from src.ast_parser.parser import CodeNode
+from src.graph_store import InMemoryGraphStore
+from src.indexing.summaries import compute_package_summaries, package_doc_entry, readme_excerpt
+from src.mcp.overview import get_package_overview
+from conftest import index_tree, write_tree
+
+MARKDOWN_README = """\
+---
+title: ignored
+---
+<!-- generated badges -->
+[![Build](https://ci.example.com/badge.svg)](https://ci.example.com) ![Coverage](cov.svg)
+
+<h1 align="center">Auth</h1>
+
+<p align="center">Sessions and <b>tokens</b> for the
+[API server](../server/README.md), see **Usage**.</p>
+
+```go
+auth.New()
+```
+
+## Usage
+"""
+
+RST_README = """\
+.. image:: https://ci.example.com/badge.svg
+   :target: https://ci.example.com
+
+=======
+Parsers
+=======
+
+|build| Parses ``config`` files with `PyYAML <https://pyyaml.org>`_,
+see :func:`load`.
+
+.. code-block:: python
+    
+    load("x")
+"""
+
+
+class TestReadmeExcerpt:
+    
+    def test_markdown_strips_badges_html_and_links(self):
+        assert readme_excerpt(MARKDOWN_README) == "Auth\nSessions and tokens for the API server, see Usage."
+    
+    def test_markdown_setext_heading_and_no_heading(self):
+        assert readme_excerpt("Shop\n====\n\n    code\n\n- item\n\nThe shop.\n") == "Shop\nThe shop."
+        assert readme_excerpt("Just a paragraph\nover two lines.\n") == "Just a paragraph over two lines."
+        assert readme_excerpt("```\nonly code\n```\n") is None
+    
+    def test_rst_skips_directives(self):
+        assert readme_excerpt(RST_README, rst=True) == "Parsers\nParses config files with PyYAML, see load."
+
+
+def _file(file_path, doc, **properties):
+    return CodeNode(f"file:{file_path}", "File", os.path.basename(file_path), file_path, 0,
+                    properties=dict(properties, doc=doc))
+
+
+class TestComputeSummaries:
+    
+    def test_package_doc_entries(self):
+        assert package_doc_entry(_file("/r/auth/doc.go", "Package auth.", import_path="ex.com/auth")) == {
+            "package": "package:ex.com/auth", "rank": 0, "doc": "Package auth."}
+        assert package_doc_entry(_file("/r/auth/auth.go", "Package auth.", import_path="ex.com/auth"))["rank"] == 1
+        assert package_doc_entry(_file("/r/auth/auth_test.go", "Package auth.", import_path="ex.com/auth")) is None
+        assert package_doc_entry(_file("/r/shop/cart.py", "Cart module.")) is None
+        assert package_doc_entry(_file("/r/shop/__init__.py", "   ")) is None
+    
+    def test_doc_go_wins_and_readme_follows(self, tmp_path):
+        (tmp_path / "Readme.MD").write_text("# Auth\n\nTokens.\n", encoding="utf-8")
+        docs = {
+            "/r/auth/auth.go": {"package": "package:auth", "rank": 1, "doc": "Package auth, from auth.go."},
+            "/r/auth/doc.go": {"package": "package:auth", "rank": 0, "doc": "Package auth issues tokens."},
+        }
+        
+        summaries = compute_package_summaries({"package:auth": str(tmp_path), "package:empty": ""}, docs, 1000)
+        assert summaries["package:auth"] == {
+            "summary": "Package auth issues tokens.\n\nAuth\nTokens.",
+            "summary_sources": ["/r/auth/doc.go", str(tmp_path / "Readme.MD")],
+        }
+        assert summaries["package:empty"] == {"summary": None, "summary_sources": None}
+    
+    def test_length_cap(self, tmp_path, monkeypatch):
+        (tmp_path / "README.md").write_text("# Title\n\n" + "word " * 100, encoding="utf-8")
+        monkeypatch.setenv("PACKAGE_SUMMARY_MAX_LENGTH", "20")
+        
+        (summary,) = compute_package_summaries({"package:p": str(tmp_path)}, {}).values()
+        assert summary["summary"].startswith("Title\nword word") and len(summary["summary"]) <= 23
+
+
+NODES = {
+    # id: (name, type, file_path, properties)
+    "package:ex.com/auth": ("auth", "Package", "", {"path": "/r/auth", "import_path": "ex.com/auth",
+                                                    "summary": "Package auth issues tokens.",
+                                                    "summary_sources": '["/r/auth/doc.go"]'}),
+    "package:ex.com/api": ("api", "Package", "", {"path": "/r/api", "import_path": "ex.com/api"}),
+    "package:ex.com/api/auth": ("auth", "Package", "", {"path": "/r/api/auth", "import_path": "ex.com/api/auth"}),
+    "external:golang.org/x/crypto": ("golang.org/x/crypto", "ExternalPackage", "",
+                                     {"module_path": "golang.org/x/crypto", "placeholder": True}),
+    "Class:/r/auth/token.go:Token:3": ("Token", "Class", "/r/auth/token.go", {"doc": "Token is a session.\n\nMore."}),
+    "Function:/r/auth/token.go:Issue:8": ("Issue", "Function", "/r/auth/token.go",
+                                          {"signature": "func Issue(user string) Token"}),
+    "Function:/r/auth/token.go:sign:12": ("sign", "Function", "/r/auth/token.go", {}),
+    "Method:/r/auth/token.go:Valid:15": ("Valid", "Method", "/r/auth/token.go", {}),
+    "Constant:/r/auth/token.go:TTL:1": ("TTL", "Constant", "/r/auth/token.go", {}),
+    "Function:/r/auth/token_test.go:TestIssue:5": ("TestIssue", "Function", "/r/auth/token_test.go", {"is_test": True}),
+    "Function:/r/auth/token.pb.go:Marshal:4": ("Marshal", "Function", "/r/auth/token.pb.go", {}),
+}
+
+FILES = ["/r/auth/token.go", "/r/auth/token_test.go", "/r/auth/token.pb.go"]
+
+EDGES = [
+    ("package:ex.com/auth", "external:golang.org/x/crypto", "DEPENDS_ON", {"imports": 2, "files": 1}),
+    ("package:ex.com/api", "package:ex.com/auth", "DEPENDS_ON", {"imports": 3, "files": 2}),
+] + [
+    ("package:ex.com/auth", f"file:{path}", "CONTAINS", {}) for path in FILES
+] + [
+    (f"file:{file_path}", node_id, "CONTAINS", {})
+    for node_id, (_, _, file_path, _) in NODES.items() if file_path
+]
+
+
+def _overview_store():
+    store = InMemoryGraphStore()
+    store.batch_create_nodes([
+        {"labels": ["Base", "File"], "properties": {"id": f"file:{path}", "name": os.path.basename(path),
+                                                    "file_path": path, "line_no": 0}}
+        for path in FILES
+    ] + [
+        {"labels": ["Base", node_type],
+         "properties": dict(properties, id=node_id, name=name, file_path=file_path,
+                            line_no=int(node_id.split(":")[-1]) if file_path else 0)}
+        for node_id, (name, node_type, file_path, properties) in NODES.items()
+    ])
+    store.batch_create_relationships([
+        {"start_node_id": source, "end_node_id": target, "type": rel_type, "properties": properties}
+        for source, target, rel_type, properties in EDGES
+    ])
+    return store
+
+
+class TestPackageOverview:
+    
+    def test_overview(self):
+        result = get_package_overview(_overview_store(), "ex.com/auth")
+        
+        assert result["status"] == "ok" and result["package"] == "ex.com/auth"
+        assert result["summary"] == "Package auth issues tokens."
+        assert result["summary_sources"] == ["/r/auth/doc.go"]
+        # Unexported, test, generated and method nodes are left out
+        assert [(symbol["name"], symbol["node_type"]) for symbol in result["symbols"]] == [
+            ("TTL", "Constant"), ("Token", "Class"), ("Issue", "Function")]
+        assert result["symbols"][1]["doc"] == "Token is a session."
+        assert result["symbols"][2]["signature"] == "func Issue(user string) Token"
+        assert result["more_symbols"] == 0
+        assert [(entry["package"], entry["node_type"], entry["imports"]) for entry in result["dependencies"]] == [
+            ("golang.org/x/crypto", "ExternalPackage", 2)]
+        assert [(entry["package"], entry["files"]) for entry in result["dependents"]] == [("ex.com/api", 2)]
+    
+    def test_symbol_cap(self):
+        result = get_package_overview(_overview_store(), "/r/auth", max_symbols=1)
+        assert [symbol["name"] for symbol in result["symbols"]] == ["TTL"] and result["more_symbols"] == 2
+        with pytest.raises(ValueError):
+            get_package_overview(_overview_store(), "/r/auth", max_symbols=0)
+    
+    def test_ambiguous_and_not_found(self):
+        store = _overview_store()
+        assert get_package_overview(store, "auth") == {
+            "status": "ambiguous", "package": "auth", "candidates": ["ex.com/api/auth", "ex.com/auth"]}
+        # An exact directory picks one of the packages its trailing segments match
+        assert get_package_overview(store, "/r/api/auth")["package"] == "ex.com/api/auth"
+        assert get_package_overview(store, "billing")["status"] == "not_found"
+
+
+CODEBASE = {
+    "shop/__init__.py": '"""Shopping cart and checkout.\n\nSee cart.py.\n"""\n',
+    "shop/cart.py": "def checkout(items):\n    \"\"\"Total of the items.\"\"\"\n    return sum(items)\n\n\n"
+                    "def _round(value):\n    return value\n",
+    "shop/README.md": "[![CI](https://ci/badge.svg)](https://ci)\n\n# Shop\n\nThe <em>shop</em> service.\n",
+    "app.py": "from shop.cart import checkout\n\n\ndef main():\n    return checkout([1])\n",
+}
+
+
+class TestIndexedSummaries:
+    
+    def test_full_run(self, legacy_env, tmp_path):
+        write_tree(tmp_path, CODEBASE)
+        db = index_tree(tmp_path).db
+        
+        result = get_package_overview(db, "shop")
+        assert result["summary"] == "Shopping cart and checkout.\n\nSee cart.py.\n\nShop\nThe shop service."
+        assert result["summary_sources"] == [str(tmp_path / "shop/__init__.py"), str(tmp_path / "shop/README.md")]
+        assert [symbol["name"] for symbol in result["symbols"]] == ["checkout"]
+        assert [entry["package"] for entry in result["dependents"]] == [str(tmp_path)]
+    
+    def test_incremental_runs_refresh_the_summary(self, legacy_env, tmp_path):
+        write_tree(tmp_path, CODEBASE)
+        store = InMemoryGraphStore()
+        index_tree(tmp_path, store)
+        
+        # Only the README changed: no file is re-parsed, the summary is still refreshed
+        (tmp_path / "shop/README.md").write_text("Shop\n====\n\nSells things.\n", encoding="utf-8")
+        kg = index_tree(tmp_path, store, incremental=True)
+        assert get_package_overview(kg.db, "shop")["summary"].endswith("\n\nShop\nSells things.")
+        
+        (tmp_path / "shop/__init__.py").write_text('"""Online shop."""\n', encoding="utf-8")
+        (tmp_path / "shop/README.md").unlink()
+        kg = index_tree(tmp_path, store, incremental=True)
+        result = get_package_overview(kg.db, "shop")
+        assert result["summary"] == "Online shop."
+        assert result["summary_sources"] == [str(tmp_path / "shop/__init__.py")]
+        
+        full = get_package_overview(index_tree(tmp_path).db, "shop")
+        assert (full["summary"], full["summary_sources"]) == (result["summary"], result["summary_sources"])
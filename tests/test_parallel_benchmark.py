@@ -0,0 +1,178 @@
+"""
+Parallel parse pipeline and batched writer tests.
+
+The determinism tests check that the bounded worker pool produces the same
+nodes and relations, in the same order, as the sequential path. The
+benchmark copies example_codebase a few hundred times and compares
+serial and parallel wall time; it only runs when RUN_BENCHMARKS is set.
+"""
+
+import os
+import shutil
+import sys
+import time
+from pathlib import Path
+from unittest.mock import patch
+
+import pytest
+
+# Add project root to Python path
+sys.path.insert(0, os.path.dirname(os.path.dirname(os.path.abspath(__file__))))
+
+from src.ast_parser.packages import EXTERNAL_PACKAGE_PREFIX
+from src.neo4j_storage.batch_writer import GraphBatchWriter
+from src.parallel.pipeline import ParserSettings, iter_parse_results, parse_source_file
+from src.parallel.pool_manager import ProcessingPoolManager
+
+EXAMPLE_CODEBASE = Path(__file__).parent.parent / "example_codebase"
+
+# Copies of example_codebase used by the benchmark
+BENCHMARK_COPIES = int(os.getenv("BENCHMARK_COPIES", "300"))
+
+
+class RecordingDatabase:
+    """Records the batches handed to batch_create_nodes / batch_create_relationships."""
+    
+    def __init__(self, fail_on_relationships=False):
+        self.calls = []
+        self.fail_on_relationships = fail_on_relationships
+    
+    def batch_create_nodes(self, nodes, batch_size=None):
+        self.calls.append(("nodes", [node["properties"]["id"] for node in nodes]))
+    
+    def batch_create_relationships(self, relationships, batch_size=None):
+        if self.fail_on_relationships:
+            raise RuntimeError("write failed")
+        self.calls.append(("relationships", [rel["start_node_id"] for rel in relationships]))
+
+
+def _copy_fixtures(destination, copies):
+    """Copy example_codebase into `copies` sibling packages."""
+    for i in range(copies):
+        package = destination / f"copy_{i:03d}"
+        package.mkdir()
+        for source in sorted(EXAMPLE_CODEBASE.glob("*.py")):
+            shutil.copy(source, package / source.name)
+    return destination
+
+
+def _build_graph():
+    from src.main import CodebaseKnowledgeGraph
+    
+    with patch("src.main.Neo4jDatabase"), patch("src.main.get_embedding_provider"):
+        return CodebaseKnowledgeGraph(neo4j_uri="bolt://fake", neo4j_user="neo4j", neo4j_password="fake", max_workers=4)
+
+
+def _snapshot(nodes, relations):
+    return list(nodes), [(r.source_id, r.target_id, r.relation_type) for r in relations]
+
+
+@pytest.fixture
+def parallel_env(monkeypatch):
+    """Legacy Python parser; parallel mode even for small inputs."""
+    monkeypatch.setenv("USE_AST_GREP", "false")
+    monkeypatch.setenv("ENABLE_JS_TS_PARSING", "false")
+    monkeypatch.setenv("PARALLEL_INDEXING_ENABLED", "true")
+    monkeypatch.setenv("MIN_FILES_FOR_PARALLEL", "1")
+
+
+class TestParsePipeline:
+    """Ordering and error handling of iter_parse_results."""
+    
+    def test_results_follow_input_order(self, tmp_path):
+        _copy_fixtures(tmp_path, 5)
+        files = sorted(str(path) for path in tmp_path.rglob("*.py"))
+        settings = ParserSettings()
+        
+        with ProcessingPoolManager(max_workers=4, force_executor_type="thread") as pool:
+            results = list(iter_parse_results(pool, files, settings, max_in_flight=3))
+        
+        assert [file_path for file_path, _ in results] == files
+        expected = [list(parse_source_file(file_path, settings)[0]) for file_path in files]
+        assert [list(result[0]) for _, result in results] == expected
+    
+    def test_unparsable_file_yields_its_file_node_only(self, tmp_path):
+        broken = tmp_path / "broken.py"
+        broken.write_text("def broken(:\n")
+        
+        with ProcessingPoolManager(use_sequential=True) as pool:
+            results = list(iter_parse_results(pool, [str(broken)], ParserSettings(), max_in_flight=2))
+        
+        ((file_path, (nodes, relations, module_definitions, pending, module_to_file)),) = results
+        assert file_path == str(broken)
+        assert [node.properties["parse_error_category"] for node in nodes.values()] == ["syntax_error"]
+        assert (relations, module_definitions, pending, module_to_file) == ([], {}, [], {})
+    
+    def test_parallel_graph_matches_sequential(self, tmp_path, parallel_env):
+        _copy_fixtures(tmp_path, 20)
+        kg = _build_graph()
+        files = kg._collect_source_files(str(tmp_path))
+        
+        sequential = _snapshot(*kg._process_directory_with_routing(str(tmp_path), files))
+        parallel = _snapshot(*kg._process_files_parallel(files, str(tmp_path)))
+        
+        assert len(parallel[0]) > 0
+        assert parallel == sequential
+        # Node IDs are derived from path and symbol (imported packages from the module path), not from parse order
+        assert all(str(tmp_path) in node_id for node_id in parallel[0]
+                   if ":" in node_id and not node_id.startswith(EXTERNAL_PACKAGE_PREFIX))
+
+
+class TestGraphBatchWriter:
+    """Batching and error propagation of the background writer."""
+    
+    def test_batches_and_node_first_order(self):
+        db = RecordingDatabase()
+        nodes = [{"labels": ["Base"], "properties": {"id": f"n{i}"}} for i in range(5)]
+        
+        with GraphBatchWriter(db, batch_size=2) as writer:
+            writer.add_nodes(nodes[:3])
+            writer.add_nodes(nodes[3:])
+            writer.add_relationships([{"start_node_id": "n0", "end_node_id": "n1", "type": "CALLS", "properties": {}}])
+        
+        assert db.calls == [
+            ("nodes", ["n0", "n1"]),
+            ("nodes", ["n2", "n3"]),
+            ("nodes", ["n4"]),
+            ("relationships", ["n0"]),
+        ]
+        assert writer.nodes_written == 5
+        assert writer.relationships_written == 1
+    
+    def test_write_error_is_raised_on_close(self):
+        db = RecordingDatabase(fail_on_relationships=True)
+        writer = GraphBatchWriter(db, batch_size=10)
+        writer.add_relationships([{"start_node_id": "a", "end_node_id": "b", "type": "CALLS", "properties": {}}])
+        
+        with pytest.raises(RuntimeError, match="write failed"):
+            writer.close()
+    
+    def test_batch_size_from_env(self, monkeypatch):
+        monkeypatch.setenv("INDEX_WRITE_BATCH_SIZE", "250")
+        
+        writer = GraphBatchWriter(RecordingDatabase())
+        writer.close()
+        
+        assert writer.batch_size == 250
+
+
+@pytest.mark.skipif(not os.getenv("RUN_BENCHMARKS"), reason="set RUN_BENCHMARKS=1 to run the parse benchmark")
+def test_parse_benchmark(tmp_path, parallel_env):
+    """Serial vs parallel parse of example_codebase copied BENCHMARK_COPIES times."""
+    _copy_fixtures(tmp_path, BENCHMARK_COPIES)
+    kg = _build_graph()
+    files = kg._collect_source_files(str(tmp_path))
+    
+    start = time.perf_counter()
+    sequential = _snapshot(*kg._process_directory_with_routing(str(tmp_path), files))
+    serial_time = time.perf_counter() - start
+    
+    start = time.perf_counter()
+    parallel = _snapshot(*kg._process_files_parallel(files, str(tmp_path)))
+    parallel_time = time.perf_counter() - start
+    
+    assert parallel == sequential
+    print(
+        f"\n{len(files)} files, {len(sequential[0])} nodes: serial {serial_time:.2f}s, "
+        f"parallel {parallel_time:.2f}s ({serial_time / parallel_time:.2f}x, {kg.max_workers} workers)"
+    )
@@ -0,0 +1,291 @@
+"""
+Huge, slow and generated file tests.
+
+parse_with_limits is checked on the legacy Python parser and on a fake
+parser that sleeps past its deadline; the end-to-end tests index a small
+Python codebase with a generated and an oversized file into an
+InMemoryGraphStore and query it with and without include_generated.
+"""
+
+import asyncio
+import json
+import os
+import sys
+import time
+from unittest.mock import patch
+
+import pytest
+
+# Add project root to Python path
+sys.path.insert(0, os.path.dirname(os.path.dirname(os.path.abspath(__file__))))
+
+from src.ast_parser.guards import (
+    ParseDeadline,
+    get_max_file_bytes,
+    get_parse_timeout,
+    has_generated_notice,
+    is_generated_name,
+    is_generated_source,
+    parse_with_limits,
+)
+from src.ast_parser.parser import ASTParser
+from src.graph_store import InMemoryGraphStore
+from src.mcp.generated import without_generated
+from src.mcp.metrics import query_metrics
+from src.mcp.semantic_search import semantic_search
+from src.parallel.pipeline import ParserSettings, parse_source_file
+from conftest import ConstantEmbeddingProvider
+
+SERVICE = (
+    "def charge(amount):\n"
+    "    if amount > 0:\n"
+    "        return amount\n"
+    "    return 0\n"
+)
+
+GENERATED = (
+    "# Code generated by sqlc. DO NOT EDIT.\n"
+    "def get_account(conn, account_id):\n"
+    "    if account_id:\n"
+    "        return conn.fetch(account_id)\n"
+    "    return None\n"
+)
+
+
+class TestGeneratedDetection:
+    
+    def test_by_name(self):
+        assert is_generated_name("api/payments.pb.go")
+        assert is_generated_name("db/queries_gen.go")
+        assert is_generated_name("proto/payments_pb2.py")
+        assert not is_generated_name("api/payments.go")
+    
+    def test_by_notice_in_a_comment(self):
+        assert has_generated_notice("// Code generated by protoc-gen-go. DO NOT EDIT.\n\npackage api\n")
+        assert has_generated_notice("/*\n * @generated by codegen\n */\n")
+        assert has_generated_notice("# @generated\nimport os\n")
+        # A mention in code or a docstring is not a notice
+        assert not has_generated_notice('"""Files with an @generated marker are skipped."""\n')
+        assert not has_generated_notice("package api\n")
+    
+    def test_notice_near_the_top_only(self, tmp_path):
+        top = tmp_path / "top.py"
+        top.write_text("# Copyright\n" + GENERATED, encoding="utf-8")
+        late = tmp_path / "late.py"
+        late.write_text("x = 1\n" * 2000 + GENERATED, encoding="utf-8")
+        
+        assert is_generated_source(str(top))
+        assert not is_generated_source(str(late))
+        assert not is_generated_source(str(tmp_path / "missing.py"))
+
+
+class TestSettings:
+    
+    def test_defaults_and_environment(self, monkeypatch):
+        monkeypatch.delenv("INDEX_MAX_FILE_BYTES", raising=False)
+        monkeypatch.delenv("INDEX_PARSE_TIMEOUT", raising=False)
+        assert get_max_file_bytes() == 1_000_000
+        assert get_parse_timeout() == 60.0
+        
+        monkeypatch.setenv("INDEX_MAX_FILE_BYTES", "2048")
+        monkeypatch.setenv("INDEX_PARSE_TIMEOUT", "0")
+        assert get_max_file_bytes() == 2048
+        assert get_parse_timeout() == 0.0
+        assert ParserSettings.from_env().max_file_bytes == 2048
+        # Arguments win over the environment
+        assert get_max_file_bytes(10) == 10
+    
+    def test_invalid_values_use_the_defaults(self, monkeypatch):
+        monkeypatch.setenv("INDEX_MAX_FILE_BYTES", "1MB")
+        monkeypatch.setenv("INDEX_PARSE_TIMEOUT", "soon")
+        
+        assert get_max_file_bytes() == 1_000_000
+        assert get_parse_timeout() == 60.0
+
+
+def _write(tmp_path, name, source):
+    path = tmp_path / name
+    path.write_text(source, encoding="utf-8")
+    return str(path)
+
+
+class SlowParser:
+    """Checks its deadline after a sleep, like a parser stuck on one symbol."""
+    
+    parse_deadline = None
+    
+    def __init__(self):
+        self.nodes = {}
+        self.relations = []
+        self.module_definitions = {"slow": {"partial": "Function:slow"}}
+    
+    def parse_file(self, file_path, build_index=False):
+        time.sleep(0.05)
+        self.parse_deadline.check()
+        return self.nodes, self.relations
+
+
+class TestParseWithLimits:
+    
+    def test_normal_file_is_unchanged(self, tmp_path):
+        path = _write(tmp_path, "service.py", SERVICE)
+        plain = ASTParser()
+        plain.parse_file(path, build_index=True)
+        guarded = ASTParser()
+        
+        nodes, _ = parse_with_limits(guarded, path, True)
+        
+        assert {node_id: node.properties for node_id, node in nodes.items()} == {
+            node_id: node.properties for node_id, node in plain.nodes.items()
+        }
+        assert guarded.module_definitions == plain.module_definitions
+        assert guarded.parse_deadline is None
+    
+    def test_too_large_file_keeps_its_file_node_only(self, tmp_path):
+        path = _write(tmp_path, "service.py", SERVICE)
+        parser = ASTParser()
+        
+        nodes, relations = parse_with_limits(parser, path, True, max_file_bytes=10)
+        
+        (file_node,) = nodes.values()
+        assert file_node.node_id == f"file:{path}"
+        assert file_node.properties == {"skipped_reason": "too_large", "size_bytes": len(SERVICE)}
+        assert relations == [] and parser.module_definitions == {}
+        # 0 disables the limit
+        assert len(parse_with_limits(ASTParser(), path, True, max_file_bytes=0)[0]) > 1
+    
+    def test_timeout_raised_through_the_parser(self, tmp_path):
+        path = _write(tmp_path, "slow.py", SERVICE)
+        parser = SlowParser()
+        
+        nodes, _ = parse_with_limits(parser, path, True, parse_timeout=0.01)
+        
+        assert [node.properties["skipped_reason"] for node in nodes.values()] == ["parse_timeout"]
+        assert parser.module_definitions == {}
+    
+    def test_timeout_swallowed_by_the_parser(self, tmp_path):
+        path = _write(tmp_path, "service.py", SERVICE)
+        # The deadline is taken at 0, every later clock read is past it
+        clock = iter([0.0] + [100.0] * 50)
+        
+        with patch("src.ast_parser.guards.time.monotonic", lambda: next(clock)):
+            nodes, _ = parse_with_limits(ASTParser(), path, True, parse_timeout=1)
+        
+        (file_node,) = nodes.values()
+        assert file_node.properties["skipped_reason"] == "parse_timeout"
+    
+    def test_deadline_disabled_by_zero(self):
+        deadline = ParseDeadline(0)
+        deadline.check()
+        
+        assert not deadline.expired
+    
+    def test_generated_file_nodes_are_tagged(self, tmp_path):
+        path = _write(tmp_path, "queries.py", GENERATED)
+        
+        nodes, _ = parse_with_limits(ASTParser(), path, True)
+        large, _ = parse_with_limits(ASTParser(), path, True, max_file_bytes=10)
+        
+        local = [node for node in nodes.values() if node.file_path == path]
+        assert {node.node_type for node in local} >= {"File", "Function"}
+        assert all(node.properties.get("generated") is True for node in local)
+        assert next(iter(large.values())).properties["generated"] is True
+    
+    def test_pipeline_worker(self, tmp_path):
+        path = _write(tmp_path, "service.py", SERVICE)
+        
+        nodes, relations, module_definitions, pending, module_to_file = parse_source_file(
+            path, ParserSettings(max_file_bytes=10)
+        )
+        
+        assert [node.properties["skipped_reason"] for node in nodes.values()] == ["too_large"]
+        assert (relations, module_definitions, pending, module_to_file) == ([], {}, [], {})
+
+
+class TestWithoutGenerated:
+    
+    def test_window_grows_until_enough_are_found(self):
+        candidates = [{"node": {"id": i, "generated": i < 6}} for i in range(10)]
+        requested = []
+        
+        def search(count):
+            requested.append(count)
+            return candidates[:count]
+        
+        assert [match["node"]["id"] for match in without_generated(search, 3)] == [6, 7, 8]
+        assert requested == [3, 12]
+        assert len(without_generated(search, 3, include_generated=True)) == 3
+    
+    def test_runs_out_of_candidates(self):
+        candidates = [{"node": {"id": 0, "generated": True}}, {"node": {"id": 1}}]
+        
+        assert without_generated(lambda count: candidates[:count], 5) == [candidates[1]]
+
+
+CODEBASE = {
+    "app/__init__.py": "",
+    "app/service.py": SERVICE,
+    "app/queries.py": GENERATED,
+    "app/fixtures.py": "def load_fixture():\n    return 1\n" + "# padding\n" * 200,
+}
+
+
+@pytest.fixture
+def kg(monkeypatch, tmp_path):
+    monkeypatch.setenv("USE_AST_GREP", "false")
+    monkeypatch.setenv("ENABLE_JS_TS_PARSING", "false")
+    monkeypatch.setenv("PARALLEL_INDEXING_ENABLED", "false")
+    from src.main import CodebaseKnowledgeGraph
+    
+    for path, source in CODEBASE.items():
+        (tmp_path / path).parent.mkdir(parents=True, exist_ok=True)
+        (tmp_path / path).write_text(source, encoding="utf-8")
+    kg = CodebaseKnowledgeGraph(store=InMemoryGraphStore(), embedding_provider=ConstantEmbeddingProvider(), max_file_bytes=1000)
+    kg.process_codebase(str(tmp_path))
+    return kg
+
+
+def _file(kg, name):
+    (record,) = kg.db.find_nodes(name=name, label="File")
+    return record["properties"]
+
+
+class TestIndexedGuards:
+    
+    def test_file_nodes(self, kg):
+        assert _file(kg, "fixtures.py")["skipped_reason"] == "too_large"
+        assert _file(kg, "queries.py")["generated"] is True
+        assert "generated" not in _file(kg, "service.py") and "skipped_reason" not in _file(kg, "service.py")
+        assert kg.db.find_nodes(name="load_fixture") == []
+    
+    def test_semantic_search_and_metrics(self, kg):
+        def names(results):
+            return sorted(result["name"] for result in results)
+        
+        assert names(semantic_search(kg.db, ConstantEmbeddingProvider(), "accounts", node_types=["Function"])) == ["charge"]
+        assert names(semantic_search(kg.db, ConstantEmbeddingProvider(), "accounts", node_types=["Function"],
+                                     include_generated=True)) == ["charge", "get_account"]
+        assert names(query_metrics(kg.db)["results"]) == ["charge"]
+        assert names(query_metrics(kg.db, include_generated=True)["results"]) == ["charge", "get_account"]
+
+
+class TestIncludeGeneratedTools:
+    
+    @pytest.fixture
+    def tools(self, kg, make_server):
+        server = make_server(store=kg.db, embedding_provider=ConstantEmbeddingProvider())
+        return lambda tool, **kwargs: json.loads(asyncio.run(server.mcp.tools[tool](**kwargs)))
+    
+    def test_find_symbol(self, tools):
+        assert tools("find_symbol", name="get_account") == []
+        (symbol,) = tools("find_symbol", name="get_account", include_generated=True)
+        assert symbol["file_path"].endswith("queries.py")
+        assert len(tools("find_symbol", arity=2, include_generated=True)) == 1
+    
+    def test_search_code(self, tools):
+        names = {result["node"]["name"] for result in tools("search_code", query="account", limit=20)}
+        everything = {result["node"]["name"] for result in tools("search_code", query="account", limit=20,
+                                                                   include_generated=True)}
+        
+        assert "get_account" not in names and "queries.py" not in names
+        assert {"get_account", "queries.py"} <= everything
@@ -0,0 +1,178 @@
+"""
+Python parser tests for async defs, decorators, dataclasses, nested and
+conditional definitions.
+
+Parses the files in tests/fixtures/python_sample with the legacy
+ASTParser and with the ast-grep adapter (both passes) and checks that each
+records the same nodes, properties and edges.
+"""
+
+import json
+import os
+import sys
+
+import pytest
+
+# Add project root to Python path
+sys.path.insert(0, os.path.dirname(os.path.dirname(os.path.abspath(__file__))))
+
+from src.ast_parser.multi_parser import MultiLanguageParser
+from src.ast_parser.parser import ASTParser
+
+FIXTURE_DIR = os.path.join(os.path.dirname(os.path.abspath(__file__)), "fixtures", "python_sample")
+
+
+@pytest.fixture(params=["legacy", "ast-grep"])
+def parse_directory(request):
+    """parse_directory of the legacy parser or the ast-grep adapter."""
+    if request.param == "legacy":
+        return ASTParser().parse_directory
+    pytest.importorskip("ast_grep_py")
+    parser = MultiLanguageParser(use_ast_grep=True, ast_grep_languages=["python"], ast_grep_fallback=False)
+    return parser.parse_directory
+
+
+@pytest.fixture
+def parsed(parse_directory):
+    nodes, relations = parse_directory(FIXTURE_DIR)
+    return nodes, relations
+
+
+def _find(nodes, name, node_type=None):
+    matches = [n for n in nodes.values() if n.name == name and (node_type is None or n.node_type == node_type)]
+    assert len(matches) == 1, f"expected one {node_type or 'node'} named {name}, found {len(matches)}"
+    return matches[0]
+
+
+def _edges(relations, relation_type):
+    return [r for r in relations if r.relation_type == relation_type]
+
+
+class TestAsyncAndDecorators:
+    """async def and decorator handling."""
+    
+    def test_async_functions_and_methods(self, parsed):
+        nodes, relations = parsed
+        
+        list_users = _find(nodes, "list_users", "Function")
+        load = _find(nodes, "load", "Method")
+        
+        assert list_users.properties["is_async"] is True
+        assert list_users.properties["doc"] == "List users."
+        assert json.loads(list_users.properties["args"]) == [{"name": "request", "type": "Request"}]
+        assert load.properties["is_async"] is True
+        assert "is_async" not in _find(nodes, "create", "Method").properties
+        
+        # Calls inside async bodies are still recorded
+        assert any(r.source_id == list_users.node_id and r.target_id.endswith(":fetch_users:0")
+                   for r in _edges(relations, "CALLS"))
+    
+    def test_decorators_property(self, parsed):
+        nodes, _ = parsed
+        
+        assert _find(nodes, "list_users").properties["decorators"] == ["app.route('/users')", "traced"]
+        assert _find(nodes, "fetch_users").properties["decorators"] == ["registry.register('fetch')"]
+        assert _find(nodes, "create").properties["decorators"] == ["staticmethod"]
+        assert "decorators" not in _find(nodes, "local_decorator").properties
+    
+    def test_decorated_by_resolves_codebase_symbols(self, parsed):
+        nodes, relations = parsed
+        traced = _find(nodes, "traced", "Function").node_id
+        register = _find(nodes, "register", "Function").node_id
+        local = _find(nodes, "local_decorator", "Function").node_id
+        
+        edges = {(nodes[r.source_id].name, r.target_id) for r in _edges(relations, "DECORATED_BY")}
+        
+        assert edges == {
+            ("list_users", traced),
+            ("load", traced),
+            ("fetch_users", register),
+            ("decorated_locally", local),
+        }
+        # app.route, staticmethod, dataclass and functools.wraps do not resolve into the codebase
+        decorated = next(r for r in _edges(relations, "DECORATED_BY") if r.target_id == register)
+        assert decorated.properties == {"decorator": "registry.register('fetch')", "line_no": 41}
+
+
+class TestDataclassFields:
+    """Annotated class attributes."""
+    
+    def test_fields_are_captured(self, parsed):
+        nodes, relations = parsed
+        request = _find(nodes, "Request", "Class")
+        
+        assert request.properties["dataclass"] is True
+        assert request.properties["decorators"] == ["dataclass(frozen=True)"]
+        
+        members = {nodes[r.target_id].name: nodes[r.target_id] for r in _edges(relations, "DEFINES")
+                   if r.source_id == request.node_id}
+        assert set(members) == {"path", "headers", "retries", "MAX_RETRIES"}
+        assert members["path"].node_type == "ClassVariable"
+        assert members["path"].properties == {"annotation": "str", "dataclass_field": True}
+        assert members["headers"].properties["has_default"] is True
+        assert "dataclass_field" not in members["MAX_RETRIES"].properties
+
+
+class TestNestedAndConditional:
+    """Nested functions and definitions under if/try blocks."""
+    
+    def test_nested_functions(self, parsed):
+        nodes, relations = parsed
+        fetch_users = _find(nodes, "fetch_users", "Function")
+        build_query = _find(nodes, "build_query", "Function")
+        wrapper = _find(nodes, "wrapper", "Function")
+        
+        assert build_query.properties["nested"] is True
+        assert wrapper.properties["nested"] is True
+        assert wrapper.properties["is_async"] is True
+        assert "nested" not in fetch_users.properties
+        
+        defines = {(r.source_id, r.target_id) for r in _edges(relations, "DEFINES")}
+        assert (fetch_users.node_id, build_query.node_id) in defines
+        contains = {r.target_id for r in _edges(relations, "CONTAINS")}
+        assert build_query.node_id not in contains
+        
+        # The call in fetch_users' body belongs to fetch_users, the nested body to build_query
+        calls = [r for r in _edges(relations, "CALLS") if r.target_id.endswith(":build_query:0")]
+        assert [r.source_id for r in calls] == [fetch_users.node_id]
+    
+    def test_type_checking_definitions(self, parsed):
+        nodes, _ = parsed
+        helper = _find(nodes, "type_only_helper", "Function")
+        debug = _find(nodes, "debug", "Method")
+        
+        assert helper.properties["conditional"] is True
+        assert helper.properties["condition"] == "TYPE_CHECKING"
+        assert debug.properties["conditional"] is True
+        assert "conditional" not in _find(nodes, "list_users").properties
+    
+    def test_try_and_else_branches(self, parse_directory, tmp_path):
+        (tmp_path / "compat.py").write_text(
+            "try:\n"
+            "    from fast import loads\n"
+            "except ImportError:\n"
+            "    def loads(data):\n"
+            "        return data\n"
+            "\n"
+            "if VERSION > 2:\n"
+            "    def run():\n"
+            "        pass\n"
+            "elif VERSION == 2:\n"
+            "    def run():\n"
+            "        pass\n"
+            "else:\n"
+            "    def run():\n"
+            "        pass\n"
+        )
+        
+        nodes, _ = parse_directory(str(tmp_path))
+        
+        loads = _find(nodes, "loads", "Function")
+        runs = sorted((n for n in nodes.values() if n.name == "run"), key=lambda n: n.line_no)
+        assert loads.properties["conditional"] is True
+        assert "condition" not in loads.properties
+        assert [n.properties["condition"] for n in runs] == [
+            "VERSION > 2",
+            "not (VERSION > 2) and VERSION == 2",
+            "not (VERSION > 2) and not (VERSION == 2)",
+        ]
@@ -0,0 +1,480 @@
+"""
+Query cache tests.
+
+QueryCache is tested on its own: keys over canonical parameters and the
+graph generation, hit and miss counters, LRU eviction, results computed
+across a change, and the SQLite layer across two instances (kept while
+the graph stamp matches, dropped once it does not). ScopeRecorder is
+checked to record the files, keys, prefixes and labels a result reads,
+and GraphChange to invalidate the entries they intersect. The index
+tests copy the python_sample fixture, add caller.py (setup calls
+registry.register), cache the callers of register and the path from
+setup to it, and re-index incrementally: editing caller.py drops them,
+editing an unrelated file keeps them. The MCP tools are checked to serve
+cached results, to compute afresh with no_cache, and to report the
+counters in get_graph_info.
+"""
+
+import asyncio
+import json
+import os
+import shutil
+import sys
+import threading
+from pathlib import Path
+from unittest.mock import MagicMock
+
+import pytest
+
+# Add project root to Python path
+sys.path.insert(0, os.path.dirname(os.path.dirname(os.path.abspath(__file__))))
+
+from src.graph_store import InMemoryGraphStore, RepositoryStore
+from src.indexing.coordination import GraphChange, GraphCoordinator, get_coordinator
+from src.mcp.call_hierarchy import call_hierarchy
+from src.mcp.paths import find_paths
+from src.mcp.query_cache import QueryCache, Scope, ScopeRecorder
+
+FIXTURES = Path(__file__).parent / "fixtures"
+
+# Added to python_sample: setup calls registry.register, helper is on no path
+CALLER = '"""Calls into registry."""\n\nfrom registry import register\n\n\ndef setup():\n    return register("setup")\n'
+UNRELATED = '"""Nothing on the call path."""\n\n\ndef helper():\n    return 1\n'
+
+
+def _store():
+    """render (app/render.go) calls log (app/log.go); Package app has no file."""
+    store = InMemoryGraphStore()
+    store.batch_create_nodes([
+        {"labels": ["Base", "Function"],
+         "properties": {"id": "function:app/render.go:render:3", "name": "render", "file_path": "app/render.go"}},
+        {"labels": ["Base", "Function"],
+         "properties": {"id": "function:app/log.go:log:1", "name": "log", "file_path": "app/log.go"}},
+        {"labels": ["Base", "Package"], "properties": {"id": "package:app", "name": "app", "path": "app"}},
+    ])
+    store.batch_create_relationships([
+        {"start_node_id": "function:app/render.go:render:3", "end_node_id": "function:app/log.go:log:1",
+         "type": "CALLS", "properties": {}},
+    ])
+    return store
+
+
+def _change(files=(), keys=(), labels=(), shared=False, repo="default"):
+    return GraphChange(repo, files=set(files), keys=set(keys), labels=set(labels), shared=shared)
+
+
+class TestQueryCache:
+    def test_key_is_canonical_and_carries_the_generation(self):
+        cache = QueryCache(enabled=True)
+        key = cache.key("get_call_hierarchy", {"symbol": "render", "max_depth": 3})
+        assert key == cache.key("get_call_hierarchy", {"max_depth": 3, "symbol": "render"})
+        assert key != cache.key("analyze_impact", {"symbol": "render", "max_depth": 3})
+        assert key != cache.key("get_call_hierarchy", {"symbol": "render", "max_depth": 4})
+        cache.clear()
+        assert cache.generation == 1
+        assert key != cache.key("get_call_hierarchy", {"symbol": "render", "max_depth": 3})
+    
+    def test_hits_misses_and_refresh(self):
+        cache = QueryCache(enabled=True)
+        calls = []
+        
+        def compute(db):
+            calls.append(1)
+            return {"status": "ok", "count": len(calls)}
+        
+        assert cache.run("tool", {"a": 1}, _store(), compute) == {"status": "ok", "count": 1}
+        assert cache.run("tool", {"a": 1}, _store(), compute) == {"status": "ok", "count": 1}
+        assert cache.run("tool", {"a": 2}, _store(), compute) == {"status": "ok", "count": 2}
+        # no_cache computes afresh and stores the fresh result
+        assert cache.run("tool", {"a": 1}, _store(), compute, refresh=True) == {"status": "ok", "count": 3}
+        assert cache.run("tool", {"a": 1}, _store(), compute) == {"status": "ok", "count": 3}
+        stats = cache.stats()
+        assert (stats["hits"], stats["misses"], stats["entries"]) == (2, 3, 2)
+    
+    def test_hits_are_copies(self):
+        cache = QueryCache(enabled=True)
+        cache.run("tool", {}, _store(), lambda db: {"items": [1]})
+        cache.run("tool", {}, _store(), lambda db: None)["items"].append(2)
+        assert cache.run("tool", {}, _store(), lambda db: None) == {"items": [1]}
+    
+    def test_disabled_cache_always_computes(self):
+        cache = QueryCache(enabled=False)
+        calls = []
+        for _ in range(2):
+            cache.run("tool", {}, _store(), lambda db: calls.append(1))
+        assert len(calls) == 2
+        assert cache.stats()["entries"] == 0
+    
+    def test_least_recently_used_entry_is_evicted(self):
+        cache = QueryCache(enabled=True, max_entries=2)
+        for name in ("a", "b"):
+            cache.run(name, {}, _store(), lambda db: name)
+        cache.run("a", {}, _store(), lambda db: None)
+        cache.run("c", {}, _store(), lambda db: "c")
+        assert cache.run("a", {}, _store(), lambda db: "again") == "a"
+        assert cache.run("b", {}, _store(), lambda db: "again") == "again"
+    
+    def test_result_computed_across_a_change_is_not_stored(self):
+        cache = QueryCache(enabled=True)
+        
+        def compute(db):
+            cache.invalidate(_change(files=["other.go"]))
+            return "stale"
+        
+        assert cache.run("tool", {}, _store(), compute) == "stale"
+        assert cache.run("tool", {}, _store(), lambda db: "fresh") == "fresh"
+    
+    def test_max_entries_from_environment(self, monkeypatch):
+        monkeypatch.setenv("QUERY_CACHE_MAX_ENTRIES", "7")
+        assert QueryCache().max_entries == 7
+        monkeypatch.setenv("QUERY_CACHE_MAX_ENTRIES", "many")
+        assert QueryCache().max_entries == 1000
+        monkeypatch.setenv("QUERY_CACHE", "false")
+        assert not QueryCache().enabled
+
+
+class TestScopeRecorder:
+    def test_records_files_and_keys_of_what_it_reads(self):
+        recorder = ScopeRecorder(_store())
+        assert recorder.find_nodes(name="render")
+        rows = recorder.neighbors(["function:app/render.go:render:3"], ["CALLS"])
+        assert [row["node"]["properties"]["name"] for row in rows] == ["log"]
+        scope = recorder.scope
+        assert scope.files == {"app/render.go", "app/log.go"}
+        assert {"render", "function:app/render.go:render:3", "function:app/log.go:log:1"} <= scope.keys
+        assert not scope.shared and not scope.whole_graph
+    
+    def test_listings_record_prefixes_and_labels(self):
+        recorder = ScopeRecorder(_store())
+        recorder.find_nodes(path_prefixes=["app"])
+        recorder.find_nodes(label="Package")
+        recorder.node_names(["Function", "Method"])
+        recorder.find_nodes()
+        scope = recorder.scope
+        assert scope.prefixes == {"app"}
+        assert scope.labels == {"Package", "Function", "Method", "*"}
+        # The Package node has no file
+        assert scope.shared
+    
+    def test_unscoped_methods_read_the_whole_graph(self):
+        recorder = ScopeRecorder(_store())
+        recorder.count_by_type()
+        assert recorder.scope.whole_graph
+    
+    def test_shortest_paths_record_the_nodes_on_the_path(self):
+        recorder = ScopeRecorder(_store())
+        paths = recorder.shortest_paths("function:app/render.go:render:3", "function:app/log.go:log:1", ["CALLS"])
+        assert len(paths) == 1
+        scope = recorder.scope
+        assert scope.files == {"app/render.go", "app/log.go"}
+        assert {"function:app/render.go:render:3", "function:app/log.go:log:1"} <= scope.keys
+        assert not scope.whole_graph
+    
+    def test_no_path_reads_the_whole_graph(self):
+        recorder = ScopeRecorder(_store())
+        assert recorder.shortest_paths("function:app/log.go:log:1", "function:app/render.go:render:3", ["CALLS"]) == []
+        assert recorder.scope.whole_graph
+    
+    def test_close_leaves_the_store_open(self):
+        store = MagicMock()
+        ScopeRecorder(store).close()
+        store.close.assert_not_called()
+    
+    def test_ids_of_a_repository_view_compare_by_local_id(self):
+        store = InMemoryGraphStore()
+        RepositoryStore(store, "frontend").batch_create_nodes([
+            {"labels": ["Base", "Function"],
+             "properties": {"id": "function:ui.ts:draw:1", "name": "draw", "file_path": "ui.ts"}},
+        ])
+        recorder = ScopeRecorder(store)
+        recorder.get_nodes(["frontend@function:ui.ts:draw:1"])
+        assert recorder.scope.touched_by(_change(keys=["function:ui.ts:draw:1"], repo="frontend"))
+
+
+class TestInvalidation:
+    def test_scoped_changes_drop_only_intersecting_entries(self):
+        store, cache = _store(), QueryCache(enabled=True)
+        cache.run("by_file", {}, store, lambda db: len(db.get_nodes(["function:app/render.go:render:3"])))
+        cache.run("by_name", {}, store, lambda db: len(db.find_nodes(name="missing")))
+        cache.run("by_prefix", {}, store, lambda db: len(db.find_nodes(path_prefixes=["lib"])))
+        cache.run("by_label", {}, store, lambda db: len(db.find_nodes(label="Interface")))
+        
+        assert cache.invalidate(_change(files=["app/other.go"], keys=["function:app/other.go:other:1"])) == 0
+        assert cache.invalidate(_change(files=["app/render.go"])) == 1
+        assert cache.invalidate(_change(files=["app/x.go"], keys=["missing"])) == 1
+        assert cache.invalidate(_change(files=["lib/util.go"])) == 1
+        assert cache.invalidate(_change(files=["app/y.go"], labels=["Interface"])) == 1
+        assert cache.stats()["invalidations"] == 4
+    
+    def test_new_edges_of_a_node_read_invalidate(self):
+        store, cache = _store(), QueryCache(enabled=True)
+        cache.run("callees", {}, store, lambda db: len(db.neighbors(["function:app/log.go:log:1"], ["CALLS"])))
+        # log calls nothing, so no other file was read; an edge out of it names it as an endpoint
+        assert cache.invalidate(_change(files=["app/z.go"], keys=["function:app/log.go:log:1"])) == 1
+    
+    def test_shared_changes_drop_entries_that_read_nodes_without_a_file(self):
+        store, cache = _store(), QueryCache(enabled=True)
+        cache.run("package", {}, store, lambda db: len(db.get_nodes(["package:app"])))
+        cache.run("function", {}, store, lambda db: len(db.get_nodes(["function:app/log.go:log:1"])))
+        assert cache.invalidate(_change(files=["app/z.go"], shared=True)) == 1
+        assert cache.stats()["entries"] == 1
+    
+    def test_unscoped_change_clears_everything(self):
+        store, cache = _store(), QueryCache(enabled=True)
+        cache.run("one", {}, store, lambda db: 1)
+        cache.run("two", {}, store, lambda db: 2)
+        assert cache.invalidate(GraphChange("default")) == 2
+        assert cache.generation == 1
+        assert cache.stats()["entries"] == 0
+    
+    def test_whole_graph_entries_are_dropped_by_any_change(self):
+        store, cache = _store(), QueryCache(enabled=True)
+        cache.run("counts", {}, store, lambda db: db.count_by_type())
+        assert cache.invalidate(_change(files=["elsewhere.go"])) == 1
+    
+    def test_paths_are_dropped_by_changes_on_the_path_only(self):
+        store, cache = _store(), QueryCache(enabled=True)
+        cache.run("path", {}, store, lambda db: len(db.shortest_paths(
+            "function:app/render.go:render:3", "function:app/log.go:log:1", ["CALLS"])))
+        assert cache.invalidate(_change(files=["elsewhere.go"])) == 0
+        assert cache.invalidate(_change(files=["app/log.go"])) == 1
+    
+    def test_scope_round_trips(self):
+        recorder = ScopeRecorder(_store())
+        recorder.find_nodes(path_prefixes=["app"])
+        recorder.find_nodes(label="Package")
+        scope = Scope.from_dict(json.loads(json.dumps(recorder.scope.to_dict())))
+        assert scope.to_dict() == recorder.scope.to_dict()
+
+
+class TestCoordinatorChanges:
+    def test_apply_reports_its_change_before_queries_resume(self):
+        coordinator = GraphCoordinator()
+        seen, queried = [], threading.Event()
+        
+        def query():
+            with coordinator.reading():
+                queried.set()
+        
+        def listener(change):
+            thread.start()
+            seen.append((change, queried.wait(0.2)))
+        thread = threading.Thread(target=query, daemon=True)
+        coordinator.add_listener(listener)
+        change = _change(files=["a.py"])
+        with coordinator.applying(change):
+            pass
+        thread.join(5)
+        assert seen == [(change, False)]
+        assert queried.is_set()
+    
+    def test_failed_apply_reports_an_unscoped_change(self):
+        coordinator = GraphCoordinator()
+        seen = []
+        coordinator.add_listener(seen.append)
+        with pytest.raises(RuntimeError):
+            with coordinator.applying(_change(files=["a.py"], repo="backend")):
+                raise RuntimeError("write failed")
+        assert len(seen) == 1
+        assert not seen[0].scoped and seen[0].repo == "backend"
+    
+    def test_failing_listener_does_not_fail_the_apply(self):
+        coordinator = GraphCoordinator()
+        seen = []
+        
+        def broken(change):
+            raise ValueError("listener bug")
+        coordinator.add_listener(broken)
+        coordinator.add_listener(seen.append)
+        with coordinator.applying():
+            pass
+        assert len(seen) == 1
+        with coordinator.reading():
+            pass
+
+
+class TestPersistentCache:
+    def test_entries_survive_a_restart(self, tmp_path):
+        path = str(tmp_path / "cache.sqlite")
+        store = _store()
+        get_coordinator(store)
+        first = QueryCache(store, path=path, enabled=True)
+        first.run("tool", {"symbol": "render"}, store, lambda db: {"calls": len(db.find_nodes(name="render"))})
+        first.close()
+        
+        second = QueryCache(store, path=path, enabled=True)
+        assert second.stats()["entries"] == 1
+        assert second.run("tool", {"symbol": "render"}, store, lambda db: "recomputed") == {"calls": 1}
+        # The invalidation scope is kept too
+        assert second.invalidate(_change(files=["app/render.go"])) == 1
+        second.close()
+        assert QueryCache(store, path=path, enabled=True).stats()["entries"] == 0
+    
+    def test_entries_of_another_graph_state_are_dropped(self, tmp_path):
+        path = str(tmp_path / "cache.sqlite")
+        store = _store()
+        store.batch_create_nodes([{"labels": ["Base", "IndexMetadata"],
+                                   "properties": {"id": "index:/code", "root": "/code", "indexed_at": "1"}}])
+        first = QueryCache(store, path=path, enabled=True)
+        first.run("tool", {}, store, lambda db: "old")
+        first.close()
+        
+        # Re-indexed by another process while the server was down
+        store.batch_create_nodes([{"labels": ["Base", "IndexMetadata"],
+                                   "properties": {"id": "index:/code", "root": "/code", "indexed_at": "2"}}])
+        second = QueryCache(store, path=path, enabled=True)
+        assert second.stats()["entries"] == 0
+        assert second.generation == first.generation + 1
+        assert second.run("tool", {}, store, lambda db: "new") == "new"
+    
+    def test_unreadable_file_falls_back_to_memory(self, tmp_path):
+        path = tmp_path / "cache.sqlite"
+        path.write_text("not a database")
+        cache = QueryCache(path=str(path), enabled=True)
+        cache.run("tool", {}, _store(), lambda db: 1)
+        assert cache.stats()["entries"] == 1
+
+
+@pytest.fixture
+def codebase(tmp_path):
+    root = tmp_path / "codebase"
+    shutil.copytree(FIXTURES / "python_sample", root)
+    (root / "caller.py").write_text(CALLER)
+    (root / "unrelated.py").write_text(UNRELATED)
+    return root
+
+
+def _graph(store):
+    from src.main import CodebaseKnowledgeGraph
+    
+    kg = CodebaseKnowledgeGraph(store=store, embedding_provider=MagicMock())
+    kg._generate_embeddings = lambda *args, **kwargs: None
+    return kg
+
+
+class TestIncrementalInvalidation:
+    @pytest.fixture
+    def indexed(self, legacy_env, codebase):
+        store = InMemoryGraphStore()
+        cache = QueryCache(store, enabled=True)
+        get_coordinator(store).add_listener(cache.invalidate)
+        kg = _graph(store)
+        kg.process_codebase(str(codebase), clear_db=True)
+        return store, cache, kg
+    
+    def _callers(self, store, cache, calls):
+        def compute(db):
+            calls.append(1)
+            return call_hierarchy(db, "register", direction="callers", max_depth=3)
+        return cache.run("get_call_hierarchy", {"symbol": "register", "direction": "callers"}, store, compute)
+    
+    def _path(self, store, cache, calls):
+        def compute(db):
+            calls.append(1)
+            return find_paths(db, "setup", "register")
+        return cache.run("find_path", {"source": "setup", "target": "register"}, store, compute)
+    
+    def _edit_caller(self, codebase):
+        caller = codebase / "caller.py"
+        caller.write_text(caller.read_text().replace('register("setup")', 'register("init")'))
+    
+    def _edit_unrelated(self, codebase):
+        (codebase / "unrelated.py").write_text(UNRELATED.replace("return 1", "return 2"))
+    
+    def test_change_on_the_path_invalidates_the_hierarchy(self, indexed, codebase):
+        store, cache, kg = indexed
+        calls = []
+        first = self._callers(store, cache, calls)
+        assert [child["name"] for child in first["tree"]["children"]] == ["setup"]
+        assert self._callers(store, cache, calls) == first and len(calls) == 1
+        
+        self._edit_caller(codebase)
+        kg.process_codebase(str(codebase), incremental=True)
+        assert cache.stats()["invalidations"] >= 1
+        self._callers(store, cache, calls)
+        assert len(calls) == 2
+    
+    def test_unrelated_change_keeps_the_hierarchy(self, indexed, codebase):
+        store, cache, kg = indexed
+        calls = []
+        first = self._callers(store, cache, calls)
+        
+        self._edit_unrelated(codebase)
+        kg.process_codebase(str(codebase), incremental=True)
+        assert kg.last_run_stats["mode"] == "incremental" and kg.last_run_stats["changed"] == 1
+        assert self._callers(store, cache, calls) == first
+        assert len(calls) == 1
+        assert cache.stats()["hits"] == 1
+    
+    def test_change_on_the_path_invalidates_the_path(self, indexed, codebase):
+        store, cache, kg = indexed
+        calls = []
+        first = self._path(store, cache, calls)
+        assert first["status"] == "ok" and len(first["paths"][0]["hops"]) == 1
+        
+        self._edit_caller(codebase)
+        kg.process_codebase(str(codebase), incremental=True)
+        assert self._path(store, cache, calls)["status"] == "ok"
+        assert len(calls) == 2
+    
+    def test_unrelated_change_keeps_the_path(self, indexed, codebase):
+        store, cache, kg = indexed
+        calls = []
+        first = self._path(store, cache, calls)
+        
+        self._edit_unrelated(codebase)
+        kg.process_codebase(str(codebase), incremental=True)
+        assert self._path(store, cache, calls) == first
+        assert len(calls) == 1
+    
+    def test_full_run_clears_the_cache(self, indexed, codebase):
+        store, cache, kg = indexed
+        calls = []
+        self._callers(store, cache, calls)
+        kg.process_codebase(str(codebase), clear_db=True)
+        assert cache.stats()["entries"] == 0
+        self._callers(store, cache, calls)
+        assert len(calls) == 2
+
+
+class TestCachedTools:
+    @pytest.fixture
+    def server(self, make_server):
+        store = _store()
+        return make_server(store=store, query_cache=QueryCache(store, enabled=True))
+    
+    def test_cached_result_no_cache_and_counters(self, server):
+        tools = server.mcp.tools
+        
+        async def calls():
+            first = json.loads(await tools["get_call_hierarchy"]("render", direction="callees"))
+            # Written behind the cache's back: only no_cache sees it
+            server.db.batch_create_nodes([{"labels": ["Base", "Function"], "properties": {
+                "id": "function:app/fmt.go:fmt:1", "name": "fmt", "file_path": "app/fmt.go"}}])
+            server.db.batch_create_relationships([{"start_node_id": "function:app/render.go:render:3",
+                                                   "end_node_id": "function:app/fmt.go:fmt:1",
+                                                   "type": "CALLS", "properties": {}}])
+            cached = json.loads(await tools["get_call_hierarchy"]("render", direction="callees"))
+            fresh = json.loads(await tools["get_call_hierarchy"]("render", direction="callees", no_cache=True))
+            info = json.loads(await tools["get_graph_info"]())
+            return first, cached, fresh, info
+        
+        first, cached, fresh, info = asyncio.run(calls())
+        assert cached == first
+        assert len(fresh["tree"]["children"]) == len(first["tree"]["children"]) + 1
+        assert info["query_cache"]["hits"] == 1
+        assert info["query_cache"]["misses"] == 2
+        assert info["query_cache"]["entries"] == 1
+    
+    def test_index_apply_invalidates_through_the_coordinator(self, server):
+        tools = server.mcp.tools
+        
+        async def call():
+            return json.loads(await tools["get_call_hierarchy"]("log", direction="callers"))
+        
+        asyncio.run(call())
+        with server.coordinator.applying(_change(files=["app/render.go"])):
+            pass
+        asyncio.run(call())
+        stats = server.query_cache.stats()
+        assert (stats["hits"], stats["misses"], stats["invalidations"]) == (0, 2, 1)
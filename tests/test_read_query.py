@@ -0,0 +1,138 @@
+"""
+run_query tests.
+
+The read-only check is run on write clauses, procedure calls and keywords
+inside literals and names. run_query is checked against a fake backend
+recording how it was called: the row limit, truncation, the timeout and
+the metadata. execute_cypher_query goes through the same check and limit.
+"""
+
+import asyncio
+import json
+import os
+import sys
+
+import pytest
+
+# Add project root to Python path
+sys.path.insert(0, os.path.dirname(os.path.dirname(os.path.abspath(__file__))))
+
+from src.graph_store import InMemoryGraphStore, UnsupportedQueryError
+from src.mcp.read_query import check_read_only, get_query_timeout, run_query
+
+
+class RowStore:
+    """Backend returning numbered rows, up to max_rows, and recording its calls."""
+    
+    def __init__(self, count):
+        self.count = count
+        self.calls = []
+    
+    def execute_read_query(self, query, parameters=None, max_rows=None, timeout=None):
+        self.calls.append({"query": query, "parameters": parameters, "max_rows": max_rows, "timeout": timeout})
+        return [{"n": index} for index in range(min(self.count, max_rows))]
+
+
+class TestReadOnlyCheck:
+    
+    @pytest.mark.parametrize("query", [
+        "MATCH (n:Function) RETURN n.name LIMIT 5",
+        "MATCH (f:File) WHERE f.file_path STARTS WITH 'src/' RETURN count(f)",
+        "MATCH (n) WHERE n.name = 'CREATE TABLE' RETURN n",
+        "MATCH (n) RETURN n.set, n.`delete me`, $create // SET n.x = 1",
+        "MATCH (n:Set) RETURN n /* MERGE */",
+        "CALL db.labels() YIELD label RETURN label",
+        "CALL db.index.fulltext.queryNodes('code_index', 'parse') YIELD node RETURN node.name",
+        "CALL { MATCH (n:Class) RETURN n } RETURN count(n)",
+        "SHOW INDEXES",
+    ])
+    def test_reads_pass(self, query):
+        check_read_only(query)
+    
+    @pytest.mark.parametrize("query, clause", [
+        ("CREATE (n:Function {name: 'x'})", "CREATE"),
+        ("MATCH (n) DETACH DELETE n", "DETACH"),
+        ("MATCH (n) WITH n LIMIT 1 set n.name = 'x'", "SET"),
+        ("MATCH (n) REMOVE n.embedding", "REMOVE"),
+        ("MERGE (n:File {path: $path})", "MERGE"),
+        ("MATCH (n) CALL { WITH n DELETE n }", "DELETE"),
+        ("UNWIND [1] AS x FOREACH (y IN [x] | CREATE ())", "FOREACH"),
+        ("LOAD  CSV FROM 'file:///x.csv' AS row RETURN row", "LOAD CSV"),
+        ("DROP INDEX code_index", "DROP"),
+    ])
+    def test_writes_are_rejected(self, query, clause):
+        with pytest.raises(ValueError, match=f"contains {clause}$"):
+            check_read_only(query)
+    
+    def test_procedures_must_be_known_read_only(self):
+        for query in ("CALL apoc.create.node(['X'], {})", "CALL apoc.cypher.runWrite('CREATE ()', {})",
+                      "call dbms.setConfigValue('x', 'y')"):
+            with pytest.raises(ValueError, match="not a known read-only procedure"):
+                check_read_only(query)
+        check_read_only("CALL apoc.meta.schema() YIELD value RETURN value")
+    
+    def test_empty_query(self):
+        with pytest.raises(ValueError):
+            check_read_only("  // nothing\n")
+
+
+class TestRunQuery:
+    
+    def test_rows_and_metadata(self):
+        db = RowStore(3)
+        
+        result = run_query(db, "MATCH (n) RETURN n", {"name": "x"}, limit=10, timeout=5)
+        
+        assert result["rows"] == [{"n": 0}, {"n": 1}, {"n": 2}]
+        assert (result["row_count"], result["truncated"]) == (3, False)
+        metadata = result["metadata"]
+        assert (metadata["query"], metadata["parameters"]) == ("MATCH (n) RETURN n", {"name": "x"})
+        assert (metadata["limit"], metadata["timeout_seconds"]) == (10, 5)
+        assert metadata["elapsed_ms"] >= 0
+        assert db.calls[0]["max_rows"] == 11
+    
+    def test_limit_truncates(self):
+        result = run_query(RowStore(600), "MATCH (n) RETURN n")
+        
+        assert (result["row_count"], result["truncated"]) == (500, True)
+        assert result["rows"][-1] == {"n": 499}
+    
+    def test_limits_from_environment(self, monkeypatch):
+        monkeypatch.setenv("QUERY_MAX_ROWS", "50")
+        monkeypatch.setenv("QUERY_TIMEOUT", "2")
+        db = RowStore(1)
+        
+        with pytest.raises(ValueError, match="between 1 and 50"):
+            run_query(db, "MATCH (n) RETURN n", limit=51)
+        run_query(db, "MATCH (n) RETURN n", limit=50, timeout=60)
+        assert db.calls[-1]["timeout"] == 2
+        run_query(db, "MATCH (n) RETURN n", limit=50)
+        assert db.calls[-1]["timeout"] == 2
+        monkeypatch.setenv("QUERY_TIMEOUT", "never")
+        assert get_query_timeout() == 30
+    
+    def test_writes_never_reach_the_backend(self):
+        db = RowStore(1)
+        
+        with pytest.raises(ValueError):
+            run_query(db, "MATCH (n) SET n.x = 1")
+        assert db.calls == []
+    
+    def test_memory_backend_is_unsupported(self):
+        with pytest.raises(UnsupportedQueryError):
+            run_query(InMemoryGraphStore(), "MATCH (n) RETURN n")
+
+
+class TestExecuteCypherQuery:
+    
+    def test_goes_through_run_query(self, server):
+        server.db = RowStore(600)
+        tool = server.mcp.tools["execute_cypher_query"]
+        
+        rows = json.loads(asyncio.run(tool("MATCH (n) RETURN n")))
+        assert len(rows) == 500 and rows[0] == {"n": 0}
+        assert server.db.calls[0]["timeout"] == 30
+        
+        result = json.loads(asyncio.run(tool("MATCH (n) SET n.x = 1")))
+        assert "contains SET" in result["error"]
+        assert len(server.db.calls) == 1
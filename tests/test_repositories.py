@@ -0,0 +1,243 @@
+"""
+Repository namespace tests.
+
+The name and ID helpers and the RepositoryStore view are checked on an
+InMemoryGraphStore. The indexed tests write two small Python codebases
+into one store: "shop" publishes the shop package (pyproject.toml) and
+"web" imports it; both have a helper() in util.py importing the
+unindexed requests package, whose placeholder nodes share their ID.
+"""
+
+import asyncio
+import json
+import os
+import sys
+from unittest.mock import MagicMock
+
+import pytest
+
+# Add project root to Python path
+sys.path.insert(0, os.path.dirname(os.path.dirname(os.path.abspath(__file__))))
+
+from src.graph_store import InMemoryGraphStore, RepositoryStore, get_repo_name, same_repo_id, scoped_id, split_id
+from src.linking import link_repositories, read_module_names
+
+
+def _node(label, node_id, name, file_path=None, **extra):
+    properties = {"id": node_id, "name": name, "file_path": file_path, **extra}
+    return {"labels": ["Base", label], "properties": {k: v for k, v in properties.items() if v is not None}}
+
+
+def _rel(rel_type, start, end, **properties):
+    return {"start_node_id": start, "end_node_id": end, "type": rel_type, "properties": properties}
+
+
+class TestNames:
+    
+    def test_repo_name_resolution(self, monkeypatch):
+        monkeypatch.delenv("INDEX_REPO", raising=False)
+        assert get_repo_name() == "default"
+        monkeypatch.setenv("INDEX_REPO", "backend")
+        assert get_repo_name() == "backend"
+        assert get_repo_name("web.v2") == "web.v2"
+        for name in ("all", "a b", "-web", "a@b", "a:b"):
+            with pytest.raises(ValueError):
+                get_repo_name(name)
+    
+    def test_ids(self):
+        assert scoped_id("default", "file:a.py") == "file:a.py"
+        assert scoped_id("web", "file:a.py") == "web@file:a.py"
+        assert scoped_id("web", "web@file:a.py") == "web@file:a.py"
+        assert split_id("web@file:a.py") == ("web", "file:a.py")
+        # "@" after the kind is part of the local ID (npm scopes)
+        assert split_id("external_package:@org/types") == ("default", "external_package:@org/types")
+        assert same_repo_id("web@function:a.py:f", "file:a.py") == "web@file:a.py"
+        assert same_repo_id("function:a.py:f", "file:a.py") == "file:a.py"
+    
+    def test_module_names(self, tmp_path):
+        (tmp_path / "go.mod").write_text("module github.com/org/lib\n\ngo 1.22\n", encoding="utf-8")
+        (tmp_path / "package.json").write_text('{"name": "@org/types"}', encoding="utf-8")
+        (tmp_path / "Cargo.toml").write_text('[package]\nname = "geo-shapes"\n', encoding="utf-8")
+        
+        assert read_module_names(str(tmp_path)) == ["github.com/org/lib", "@org/types", "geo_shapes"]
+
+
+class TestRepositoryStore:
+    
+    @pytest.fixture
+    def store(self):
+        store = InMemoryGraphStore()
+        for repo in ("default", "web"):
+            view = RepositoryStore(store, repo)
+            view.batch_create_nodes([
+                _node("File", "file:util.py", "util.py", "util.py", content_hash=repo),
+                _node("Function", "function:util.py:helper", "helper", "util.py"),
+            ])
+            view.batch_create_relationships([_rel("CONTAINS", "file:util.py", "function:util.py:helper")])
+        return store
+    
+    def test_writes_are_scoped(self, store):
+        assert sorted(record["properties"]["id"] for record in store.find_nodes(label="File")) == [
+            "file:util.py", "web@file:util.py",
+        ]
+        (record,) = store.get_nodes(["web@file:util.py"])
+        assert record["properties"]["repo"] == "web"
+        assert [row["node"]["properties"]["id"] for row in store.neighbors(["web@file:util.py"])] == [
+            "web@function:util.py:helper",
+        ]
+    
+    def test_view_reads_one_repository(self, store):
+        web = RepositoryStore(store, "web")
+        
+        assert web.get_file_states()["util.py"]["content_hash"] == "web"
+        assert [record["properties"]["id"] for record in web.find_nodes(name="helper")] == ["function:util.py:helper"]
+        (row,) = web.neighbors(["file:util.py"], ["CONTAINS"])
+        assert (row["origin_id"], row["relationship"]["end_node_id"]) == ("file:util.py", "function:util.py:helper")
+        assert web.get_existing_node_ids(["file:util.py", "file:missing.py"]) == {"file:util.py"}
+    
+    def test_delete_file_scope_and_repository(self, store):
+        web = RepositoryStore(store, "web")
+        
+        assert web.delete_file_scope(["util.py"]) == 2
+        assert len(store.find_nodes(path_prefixes=["util.py"])) == 2
+        web.batch_create_nodes([_node("File", "file:util.py", "util.py", "util.py")])
+        web.clear_database()
+        assert [record["properties"]["id"] for record in store.find_nodes()] == [
+            "file:util.py", "function:util.py:helper",
+        ]
+    
+    def test_link_go_import_path(self):
+        store = InMemoryGraphStore()
+        lib, api = RepositoryStore(store, "lib"), RepositoryStore(store, "api")
+        for view in (lib, api):
+            view.batch_create_nodes([_node("Repository", f"repository:{view.repo}", view.repo)])
+        lib.batch_create_nodes([_node("Package", "package:github.com/org/lib/util", "util",
+                                      import_path="github.com/org/lib/util")])
+        api.batch_create_nodes([
+            _node("Package", "package:github.com/org/api", "api", import_path="github.com/org/api"),
+            _node("ExternalPackage", "external_package:github.com/org/lib/util", "util",
+                  module_path="github.com/org/lib/util", placeholder=True),
+        ])
+        api.batch_create_relationships([_rel("DEPENDS_ON", "package:github.com/org/api",
+                                             "external_package:github.com/org/lib/util", imports=2, files=1)])
+        
+        assert link_repositories(store) == 1
+        (row,) = [row for row in store.neighbors(["api@package:github.com/org/api"], ["DEPENDS_ON"])
+                  if row["relationship"]["properties"].get("cross_repo")]
+        assert row["node"]["properties"]["id"] == "lib@package:github.com/org/lib/util"
+        assert row["relationship"]["properties"]["imports"] == 2
+        # A second pass replaces the edges instead of adding more
+        assert link_repositories(store) == 1
+        assert store.delete_cross_repo_relationships() == 1
+
+
+CODEBASES = {
+    "shop": {
+        "pyproject.toml": '[project]\nname = "shop"\n',
+        "shop/__init__.py": "",
+        "shop/cart.py": "def checkout(items):\n    return sum(items)\n",
+        "util.py": "import requests\n\n\ndef helper():\n    return 1\n",
+    },
+    "web": {
+        "app.py": "from shop.cart import checkout\n\n\ndef handle(items):\n    return checkout(items)\n",
+        "util.py": "import requests\n\n\ndef helper():\n    return 2\n",
+    },
+}
+
+
+@pytest.fixture
+def indexed(monkeypatch, tmp_path):
+    """Store holding both codebases, and a function indexing one of them again."""
+    monkeypatch.setenv("USE_AST_GREP", "false")
+    monkeypatch.setenv("ENABLE_JS_TS_PARSING", "false")
+    monkeypatch.setenv("PARALLEL_INDEXING_ENABLED", "false")
+    from src.main import CodebaseKnowledgeGraph
+    
+    store = InMemoryGraphStore()
+    
+    def index(repo, incremental=False, clear_db=False):
+        kg = CodebaseKnowledgeGraph(store=store, embedding_provider=MagicMock(), repo=repo)
+        kg.process_codebase(str(tmp_path / repo), clear_db, incremental)
+        return kg
+    
+    for repo, files in CODEBASES.items():
+        for path, source in files.items():
+            (tmp_path / repo / path).parent.mkdir(parents=True, exist_ok=True)
+            (tmp_path / repo / path).write_text(source, encoding="utf-8")
+        index(repo)
+    return store, index
+
+
+def _cross_repo_edges(store):
+    packages = [record["properties"]["id"] for record in store.find_nodes(label="Package")]
+    return sorted(
+        (row["origin_id"], row["node"]["properties"]["id"], row["relationship"]["properties"]["module"])
+        for row in store.neighbors(packages, ["DEPENDS_ON"])
+        if row["relationship"]["properties"].get("cross_repo")
+    )
+
+
+class TestIndexedRepositories:
+    
+    def test_same_ids_stay_separate(self, indexed):
+        store, _ = indexed
+        
+        placeholders = store.find_nodes(name="requests", label="ExternalPackage")
+        assert sorted(record["properties"]["id"] for record in placeholders) == [
+            "shop@external_package:requests", "web@external_package:requests",
+        ]
+        web = RepositoryStore(store, "web")
+        assert sorted(os.path.basename(path) for path in web.get_file_states()) == ["app.py", "util.py"]
+        (placeholder,) = web.find_nodes(name="requests", label="ExternalPackage")
+        assert placeholder["properties"]["id"] == "external_package:requests"
+    
+    def test_imports_link_to_the_publishing_repository(self, indexed):
+        store, index = indexed
+        
+        (repository,) = RepositoryStore(store, "shop").find_nodes(label="Repository")
+        assert repository["properties"]["modules"] == ["shop"]
+        assert [edge[1:] for edge in _cross_repo_edges(store)] == [("shop@repository:shop", "shop")]
+        
+        # Re-indexing either side recomputes the same edges
+        index("web", incremental=True)
+        index("shop")
+        assert [edge[1:] for edge in _cross_repo_edges(store)] == [("shop@repository:shop", "shop")]
+    
+    def test_clear_keeps_other_repositories(self, indexed):
+        store, index = indexed
+        
+        index("web", clear_db=True)
+        
+        assert {record["properties"]["repo"] for record in store.find_nodes(label="File")} == {"shop", "web"}
+        assert len(_cross_repo_edges(store)) == 1
+
+
+class TestRepositoryTools:
+    
+    @pytest.fixture
+    def tools(self, indexed, make_server):
+        store, _ = indexed
+        
+        server = make_server(store=store, repo="web")
+        return lambda tool, **kwargs: json.loads(asyncio.run(server.mcp.tools[tool](**kwargs)))
+    
+    def test_repo_filter(self, tools):
+        assert len(tools("find_symbol", name="helper")) == 2
+        (helper,) = tools("find_symbol", name="helper", repo="shop")
+        assert not helper["id"].startswith("shop@")
+        assert helper["file_path"].endswith(os.path.join("shop", "util.py"))
+        assert tools("find_symbol", name="checkout", repo="web") == []
+        outline = tools("get_file_outline", file_path=helper["file_path"])
+        functions = outline["outline"]["functions"]
+        assert (outline["source"], [function["name"] for function in functions]) == ("graph", ["helper"])
+        assert "error" in tools("get_code_by_name", name="helper", repo="no repo")
+    
+    def test_list_and_delete_repositories(self, tools):
+        assert [repository["name"] for repository in tools("list_repositories")] == ["shop", "web"]
+        
+        result = tools("delete_repository", repo="shop")
+        
+        assert result["repo"] == "shop" and result["nodes_deleted"] > 0
+        assert [repository["name"] for repository in tools("list_repositories")] == ["web"]
+        assert tools("find_symbol", name="checkout") == []
+        assert len(tools("find_symbol", name="helper")) == 1
@@ -0,0 +1,289 @@
+"""
+Ruby adapter tests.
+
+Parses the Ruby files in tests/fixtures/multi_lang_sample through
+MultiLanguageParser (so the second pass runs). The ruby/ tree holds the
+Trackable and Auditing concerns and the Blog::Accounts::User and
+Blog::Post models, both ApplicationRecord subclasses. TestSecondPass runs
+the second pass on hand-built nodes and TestInflections the naming
+helpers, so neither needs ast-grep.
+"""
+
+import os
+import sys
+import pytest
+
+# Add project root to Python path
+sys.path.insert(0, os.path.dirname(os.path.dirname(os.path.abspath(__file__))))
+
+from src.ast_parser.parser import ASTParser, CodeNode
+from src.ast_parser.adapters.ruby_adapter import (
+    RUBY_CONSTANTS,
+    RubyAdapter,
+    association_class_name,
+    camelize,
+    constant_candidates,
+    namespace_scopes,
+    singularize,
+)
+
+
+FIXTURE_DIR = os.path.join(os.path.dirname(os.path.abspath(__file__)), "fixtures", "multi_lang_sample")
+RUBY_DIR = os.path.join(FIXTURE_DIR, "ruby")
+
+
+def _parse(languages):
+    pytest.importorskip("ast_grep_py")
+    from src.ast_parser.multi_parser import MultiLanguageParser
+    
+    coordinator = MultiLanguageParser(
+        use_ast_grep=True,
+        ast_grep_languages=languages,
+        ast_grep_fallback=False
+    )
+    return coordinator.parse_directory(FIXTURE_DIR, build_index=True)
+
+
+def _type_node(nodes, qualified_name):
+    matches = [n for n in nodes.values()
+               if n.node_type == "Class" and n.properties.get("qualified_name") == qualified_name]
+    assert len(matches) == 1, f"expected one {qualified_name} node, got {len(matches)}"
+    return matches[0]
+
+
+def _edges(nodes, relations, source, relation_type):
+    """(target qualified name or name, properties) of the relation_type edges of source."""
+    return sorted(
+        (nodes[r.target_id].properties.get("qualified_name") or nodes[r.target_id].name, r.properties)
+        for r in relations if r.source_id == source.node_id and r.relation_type == relation_type
+    )
+
+
+def _members(nodes, relations, type_node, node_type):
+    return [nodes[r.target_id] for r in relations
+            if r.source_id == type_node.node_id and r.relation_type == "DEFINES"
+            and nodes[r.target_id].node_type == node_type]
+
+
+@pytest.fixture
+def ruby_results():
+    """Parse the fixture tree with the Ruby adapter enabled."""
+    return _parse(['ruby'])
+
+
+class TestRubyDeclarations:
+    """Classes, modules, methods, constants and attributes."""
+    
+    def test_nested_modules_qualify_names(self, ruby_results):
+        nodes, relations = ruby_results
+        
+        user = _type_node(nodes, "Blog::Accounts::User")
+        assert (user.name, user.properties["kind"]) == ("User", "class")
+        assert user.properties["doc"] == "A registered author."
+        accounts = _type_node(nodes, "Blog::Accounts")
+        assert accounts.properties["kind"] == "module"
+        assert [c.name for c in _members(nodes, relations, accounts, "Class")] == ["User"]
+        class_methods = _type_node(nodes, "Trackable::ClassMethods")
+        assert class_methods.properties["kind"] == "module"
+    
+    def test_methods(self, ruby_results):
+        nodes, relations = ruby_results
+        
+        user = _type_node(nodes, "Blog::Accounts::User")
+        methods = {m.name: m.properties for m in _members(nodes, relations, user, "Method")}
+        assert methods["find_by_nickname"]["qualified_name"] == "Blog::Accounts::User.find_by_nickname"
+        assert methods["find_by_nickname"]["class_method"] is True
+        assert methods["roles"]["class_method"] is True
+        assert methods["display_name"]["qualified_name"] == "Blog::Accounts::User#display_name"
+        assert "class_method" not in methods["display_name"]
+        assert methods["display_name"]["arity"] == 2
+        assert {name: properties["visibility"] for name, properties in methods.items()} == {
+            "find_by_nickname": "public", "roles": "public", "display_name": "public",
+            "to_json": "public", "generate_token": "private",
+        }
+    
+    def test_constants(self, ruby_results):
+        nodes, relations = ruby_results
+        
+        user = _type_node(nodes, "Blog::Accounts::User")
+        constants = {c.name: c.properties for c in _members(nodes, relations, user, "Constant")}
+        assert constants["MAX_POSTS"] == {"qualified_name": "Blog::Accounts::User::MAX_POSTS", "value": 100}
+        assert "value" not in constants["ROLES"]
+    
+    def test_attributes(self, ruby_results):
+        nodes, relations = ruby_results
+        
+        user = _type_node(nodes, "Blog::Accounts::User")
+        attributes = [(a.name, a.properties["macro"], a.properties["getter"], a.properties["setter"])
+                      for a in _members(nodes, relations, user, "ClassVariable")]
+        assert attributes == [
+            ("nickname", "attr_accessor", True, True), ("bio", "attr_accessor", True, True),
+            ("token", "attr_reader", True, False), ("password", "attr_writer", False, True),
+        ]
+        post = _type_node(nodes, "Blog::Post")
+        assert [a.name for a in _members(nodes, relations, post, "ClassVariable")] == ["draft"]
+
+
+class TestRubyRelations:
+    """Superclasses, mixins, associations and requires."""
+    
+    def test_superclasses(self, ruby_results):
+        nodes, relations = ruby_results
+        
+        user = _type_node(nodes, "Blog::Accounts::User")
+        assert [name for name, _ in _edges(nodes, relations, user, "EXTENDS")] == ["ApplicationRecord"]
+        record = _type_node(nodes, "ApplicationRecord")
+        assert [name for name, _ in _edges(nodes, relations, record, "INHERITS_FROM")] == ["ActiveRecord::Base"]
+    
+    def test_mixins(self, ruby_results):
+        nodes, relations = ruby_results
+        
+        user = _type_node(nodes, "Blog::Accounts::User")
+        assert [(name, properties["mixin"]) for name, properties in _edges(nodes, relations, user, "MIXES_IN")] == [
+            ("Auditing", "prepend"), ("Trackable", "include"), ("Trackable::ClassMethods", "extend"),
+        ]
+        assert user.properties["mixins"] == [
+            "include Trackable", "extend Trackable::ClassMethods", "prepend Auditing",
+        ]
+    
+    def test_associations(self, ruby_results):
+        nodes, relations = ruby_results
+        
+        user = _type_node(nodes, "Blog::Accounts::User")
+        assert [(name, properties["name"]) for name, properties in _edges(nodes, relations, user, "HAS_MANY")] == [
+            ("Blog::Post", "posts"),
+        ]
+        # No Profile class is indexed
+        assert _edges(nodes, relations, user, "HAS_ONE") == []
+        post = _type_node(nodes, "Blog::Post")
+        assert [(name, properties["class_name"])
+                for name, properties in _edges(nodes, relations, post, "BELONGS_TO")] == [
+            ("Blog::Accounts::User", "Accounts::User"),
+        ]
+    
+    def test_requires(self, ruby_results):
+        nodes, relations = ruby_results
+        
+        user_file = os.path.join(RUBY_DIR, "models", "user.rb")
+        imports = sorted(
+            (nodes[r.target_id].node_type, nodes[r.target_id].name) for r in relations
+            if r.source_id == f"file:{user_file}" and r.relation_type == "IMPORTS"
+        )
+        assert imports == [
+            ("ExternalPackage", "json"), ("File", "application_record.rb"), ("File", "trackable.rb"),
+        ]
+
+
+class TestSecondPass:
+    """The second pass on hand-built nodes, the way the adapter leaves them."""
+    
+    USER = "/repo/app/models/user.rb"
+    POST = "/repo/app/models/post.rb"
+    
+    def _node(self, parser, node_type, qualified_name, file_path, line_no):
+        node = CodeNode(f"{node_type}:{file_path}:{qualified_name}:{line_no}", node_type,
+                        qualified_name.rpartition("::")[2], file_path, line_no,
+                        properties={"qualified_name": qualified_name})
+        parser.nodes[node.node_id] = node
+        return node.node_id
+    
+    def _pending(self, relation_type, source_id, written, scopes, **extra):
+        candidates = constant_candidates(written, scopes)
+        return dict({"type": relation_type, "source_id": source_id, "imported_module": RUBY_CONSTANTS,
+                     "imported_name": candidates[0], "candidates": [[RUBY_CONSTANTS, c] for c in candidates],
+                     "original_name": written, "external_name": written}, **extra)
+    
+    @pytest.fixture
+    def resolved(self):
+        parser = ASTParser()
+        for file_path in (self.USER, self.POST):
+            parser.nodes[f"file:{file_path}"] = CodeNode(f"file:{file_path}", "File", os.path.basename(file_path),
+                                                         file_path, 0)
+        user = self._node(parser, "Class", "Blog::User", self.USER, 2)
+        post = self._node(parser, "Class", "Blog::Post", self.POST, 2)
+        trackable = self._node(parser, "Class", "Blog::Trackable", self.POST, 20)
+        parser.module_definitions = {
+            RUBY_CONSTANTS: {"Blog::User": user, "Blog::Post": post, "Blog::Trackable": trackable},
+        }
+        parser.module_to_file = {RubyAdapter.file_key(self.POST): f"file:{self.POST}"}
+        scopes = ["Blog", "Blog::User"]
+        parser.pending_imports = [
+            self._pending("BASE_TYPE", user, "ApplicationRecord", ["Blog"]),
+            self._pending("MIXIN", user, "Trackable", scopes, mixin="include", line_no=3),
+            self._pending("MIXIN", user, "Trackable", scopes, mixin="extend", line_no=4),
+            self._pending("MIXIN", user, "Comparable", scopes, mixin="include", line_no=5),
+            self._pending("ASSOCIATION", user, "Post", scopes, association="has_many", name="posts", line_no=6),
+            self._pending("ASSOCIATION", user, "Post", scopes, association="has_many", name="drafts",
+                          class_name="Post", line_no=7),
+            self._pending("ASSOCIATION", user, "Post", scopes, association="has_many", name="posts", line_no=8),
+            self._pending("ASSOCIATION", user, "Profile", scopes, association="has_one", name="profile", line_no=9),
+            {"type": "IMPORTS_MODULE", "source_id": f"file:{self.USER}", "imported_module": RubyAdapter.file_key(
+                "/repo/app/models/../models/post.rb"), "full_module_path": "post", "line_no": 1},
+            {"type": "IMPORTS_MODULE", "source_id": f"file:{self.USER}", "imported_module": None,
+             "external_package": "json", "full_module_path": "json/add/core", "line_no": 2},
+        ]
+        parser._process_pending_imports()
+        return parser, user, post, trackable
+    
+    def _edges(self, parser, source_id, relation_type):
+        return sorted((r.target_id, tuple(sorted(r.properties.items()))) for r in parser.relations
+                      if r.source_id == source_id and r.relation_type == relation_type)
+    
+    def test_superclass_not_indexed(self, resolved):
+        parser, user, post, trackable = resolved
+        
+        [(target_id, properties)] = self._edges(parser, user, "INHERITS_FROM")
+        assert parser.nodes[target_id].node_type == "ExternalType"
+        assert parser.nodes[target_id].name == "ApplicationRecord"
+    
+    def test_mixins(self, resolved):
+        parser, user, post, trackable = resolved
+        
+        edges = [(parser.nodes[target_id].name, dict(properties)["mixin"])
+                 for target_id, properties in self._edges(parser, user, "MIXES_IN")]
+        # Trackable resolves in the enclosing Blog module, Comparable is a placeholder
+        assert sorted(edges) == [("Comparable", "include"), ("Trackable", "extend"), ("Trackable", "include")]
+    
+    def test_associations(self, resolved):
+        parser, user, post, trackable = resolved
+        
+        edges = [(target_id, dict(properties)) for target_id, properties in self._edges(parser, user, "HAS_MANY")]
+        assert [(target_id, properties["name"], properties.get("class_name")) for target_id, properties in edges] == [
+            (post, "drafts", "Post"), (post, "posts", None),
+        ]
+        assert self._edges(parser, user, "HAS_ONE") == []
+    
+    def test_requires(self, resolved):
+        parser, user, post, trackable = resolved
+        
+        imports = sorted((r.target_id, r.properties["module"]) for r in parser.relations
+                         if r.source_id == f"file:{self.USER}" and r.relation_type == "IMPORTS")
+        assert imports == [("external_package:json", "json/add/core"), (f"file:{self.POST}", "post")]
+
+
+class TestInflections:
+    """Constant lookup and the class names of Rails associations."""
+    
+    def test_constant_candidates(self):
+        assert constant_candidates("Trackable", ["Blog", "Blog::User"]) == [
+            "Blog::User::Trackable", "Blog::Trackable", "Trackable",
+        ]
+        assert constant_candidates("::Trackable", ["Blog"]) == ["Trackable"]
+        assert constant_candidates("Accounts::User", []) == ["Accounts::User"]
+        assert namespace_scopes("A::B::C") == ["A", "A::B", "A::B::C"]
+    
+    @pytest.mark.parametrize("plural, singular", [
+        ("posts", "post"), ("categories", "category"), ("addresses", "address"), ("boxes", "box"),
+        ("wolves", "wolf"), ("knives", "knife"), ("people", "person"), ("line_items", "line_item"),
+        ("statuses", "status"), ("quizzes", "quiz"), ("series", "series"), ("admin_people", "admin_person"),
+    ])
+    def test_singularize(self, plural, singular):
+        assert singularize(plural) == singular
+    
+    def test_class_names(self):
+        assert camelize("line_item") == "LineItem"
+        assert camelize("admin/user") == "Admin::User"
+        assert association_class_name("has_many", "line_items") == "LineItem"
+        assert association_class_name("has_and_belongs_to_many", "categories") == "Category"
+        assert association_class_name("belongs_to", "address") == "Address"
+        assert association_class_name("has_one", "status") == "Status"
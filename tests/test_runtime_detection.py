@@ -44,9 +44,9 @@ class TestRuntimeDetection:
         result = get_optimal_worker_count()
         assert isinstance(result, int)
         assert result >= 1
-        # Should be min(cpu_count, 8)
+        # Should be one worker per CPU core
         cpu_count = os.cpu_count() or 4
-        assert result == min(cpu_count, 8)
+        assert result == cpu_count
     
     def test_get_optimal_worker_count_explicit(self):
         """Test optimal worker count with explicit value."""
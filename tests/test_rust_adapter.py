@@ -0,0 +1,231 @@
+"""
+Rust adapter tests.
+
+Parses the Rust files in tests/fixtures/multi_lang_sample through
+MultiLanguageParser (so the second pass runs). The rust/ directory is the
+crate shapes: the trait Shape (with the supertrait Describe) in the module
+geometry, its implementers Circle and Square in geometry/circle.rs and
+geometry/square.rs, re-exported by geometry/mod.rs, and the module report
+calling into geometry.
+"""
+
+import os
+import sys
+import pytest
+
+# Add project root to Python path
+sys.path.insert(0, os.path.dirname(os.path.dirname(os.path.abspath(__file__))))
+
+pytest.importorskip("ast_grep_py")
+
+from src.ast_parser.multi_parser import MultiLanguageParser
+
+
+FIXTURE_DIR = os.path.join(os.path.dirname(os.path.abspath(__file__)), "fixtures", "multi_lang_sample")
+CRATE_SRC = os.path.join(FIXTURE_DIR, "rust", "src")
+
+
+def _parse(languages, directory=FIXTURE_DIR):
+    coordinator = MultiLanguageParser(
+        use_ast_grep=True,
+        ast_grep_languages=languages,
+        ast_grep_fallback=False
+    )
+    return coordinator.parse_directory(directory, build_index=True)
+
+
+def _item(nodes, qualified_name):
+    matches = [n for n in nodes.values() if n.properties.get("qualified_name") == qualified_name]
+    assert len(matches) == 1, f"expected one {qualified_name} node, got {len(matches)}"
+    return matches[0]
+
+
+def _targets(nodes, relations, source, relation_type):
+    """Names of the nodes source points at with relation_type."""
+    return {nodes[r.target_id].name for r in relations
+            if r.source_id == source.node_id and r.relation_type == relation_type}
+
+
+def _members(nodes, relations, type_node, node_type):
+    return [nodes[r.target_id] for r in relations
+            if r.source_id == type_node.node_id and r.relation_type == "DEFINES"
+            and nodes[r.target_id].node_type == node_type]
+
+
+def _file(nodes, *parts):
+    return nodes[f"file:{os.path.join(CRATE_SRC, *parts)}"]
+
+
+@pytest.fixture
+def rust_results():
+    """Parse the fixture tree with the Rust adapter enabled."""
+    return _parse(['rust'])
+
+
+class TestRustDeclarations:
+    """Structs, enums, traits and functions."""
+    
+    def test_type_kinds(self, rust_results):
+        nodes, relations = rust_results
+        
+        circle = _item(nodes, "shapes::geometry::circle::Circle")
+        assert (circle.node_type, circle.properties["kind"]) == ("Class", "struct")
+        assert circle.properties["doc"] == "A circle around the origin."
+        fields = _members(nodes, relations, circle, "ClassVariable")
+        assert [(f.name, f.properties["type"], f.properties["visibility"]) for f in fields] == [("radius", "f64", "pub")]
+        
+        line = _item(nodes, "shapes::report::Line")
+        assert line.node_type == "Enum"
+        assert line.properties["members"] == ["Title", "Entry", "Blank"]
+        
+        shape = _item(nodes, "shapes::geometry::Shape")
+        assert (shape.node_type, shape.properties["kind"]) == ("Interface", "trait")
+        assert shape.properties["methods"] == ["area() -> f64", "scaled_area(factor: f64) -> f64"]
+        methods = {m.name: m for m in _members(nodes, relations, shape, "Method")}
+        assert "default" not in methods["area"].properties
+        assert methods["scaled_area"].properties["default"] is True
+    
+    def test_generics_and_lifetimes(self, rust_results):
+        nodes, relations = rust_results
+        
+        square = _item(nodes, "shapes::geometry::square::Square")
+        assert square.properties["type_parameters"] == ["'a", "T"]
+        label = next(f for f in _members(nodes, relations, square, "ClassVariable") if f.name == "label")
+        assert label.properties["type"] == "&'a str"
+        
+        largest = _item(nodes, "shapes::report::largest")
+        assert largest.properties["signature"] == "largest<'a, T: Area + ?Sized>(shapes: &[&'a T]) -> Option<&'a T>"
+        assert largest.properties["arity"] == 1
+    
+    def test_derives_and_attributes(self, rust_results):
+        nodes, relations = rust_results
+        circle = _item(nodes, "shapes::geometry::circle::Circle")
+        
+        assert circle.properties["derives"] == ["Debug", "Clone", "PartialEq"]
+        assert circle.properties["attributes"] == ["non_exhaustive"]
+        # Standard library traits are not indexed: no IMPLEMENTS edge for them
+        assert _targets(nodes, relations, circle, "IMPLEMENTS") == {"Shape", "Describe"}
+
+
+class TestRustImpls:
+    """impl blocks: METHOD_OF and IMPLEMENTS edges."""
+    
+    def test_methods_of_the_type(self, rust_results):
+        nodes, relations = rust_results
+        circle = _item(nodes, "shapes::geometry::circle::Circle")
+        
+        methods = {m.name: m for m in _members(nodes, relations, circle, "Method")}
+        assert set(methods) == {"new", "doubled", "area", "name"}
+        assert "receiver_kind" not in methods["new"].properties
+        assert methods["doubled"].properties["receiver_kind"] == "value"
+        assert methods["area"].properties["receiver_kind"] == "ref"
+        assert methods["area"].properties["trait"] == "Shape"
+        assert methods["new"].properties["signature"] == "new(radius: f64) -> Self"
+        method_of = [r for r in relations if r.relation_type == "METHOD_OF" and r.target_id == circle.node_id]
+        assert {nodes[r.source_id].name for r in method_of} == set(methods)
+        
+        square = _item(nodes, "shapes::geometry::square::Square")
+        grow = next(m for m in _members(nodes, relations, square, "Method") if m.name == "grow")
+        assert grow.properties["receiver_kind"] == "mut_ref"
+    
+    def test_trait_implementations(self, rust_results):
+        nodes, relations = rust_results
+        shape = _item(nodes, "shapes::geometry::Shape")
+        
+        implementers = {nodes[r.source_id].name for r in relations
+                        if r.relation_type == "IMPLEMENTS" and r.target_id == shape.node_id}
+        assert implementers == {"Circle", "Square"}
+        assert _targets(nodes, relations, shape, "EXTENDS") == {"Describe"}
+        
+        # fmt::Display is not indexed: kept as a property only
+        square = _item(nodes, "shapes::geometry::square::Square")
+        assert square.properties["implements"] == ["Shape", "Describe", "fmt::Display"]
+        assert _targets(nodes, relations, square, "IMPLEMENTS") == {"Shape", "Describe"}
+
+
+class TestRustModules:
+    """Module layout, use declarations and calls across modules."""
+    
+    def test_modules_from_file_layout(self, rust_results):
+        nodes, relations = rust_results
+        
+        assert _file(nodes, "lib.rs").properties["import_path"] == "shapes"
+        assert _file(nodes, "lib.rs").properties["doc"].startswith("Shapes and the reports built from them.")
+        geometry = _file(nodes, "geometry", "mod.rs")
+        assert (geometry.properties["package"], geometry.properties["import_path"]) == ("geometry", "shapes::geometry")
+        assert geometry.properties["modules"] == ["circle", "square"]
+        assert _file(nodes, "geometry", "square.rs").properties["import_path"] == "shapes::geometry::square"
+        
+        contains = {nodes[r.target_id].name for r in relations
+                    if r.source_id == "package:shapes::geometry" and r.relation_type == "CONTAINS"}
+        assert contains == {"mod.rs"}
+    
+    def test_use_declarations(self, rust_results):
+        nodes, relations = rust_results
+        report = _file(nodes, "report.rs")
+        
+        imports = {r.target_id: r.properties for r in relations
+                   if r.source_id == report.node_id and r.relation_type == "IMPORTS"}
+        # Renamed, and resolved through the `pub use` of geometry/mod.rs
+        shape = _item(nodes, "shapes::geometry::Shape")
+        assert imports[shape.node_id]["alias"] == "Area"
+        assert _item(nodes, "shapes::geometry::circle::Circle").node_id in imports
+        assert imports["package:shapes::geometry::square"]["wildcard"] is True
+        # `self` in a group imports the module itself
+        assert "package:shapes::geometry" in imports
+        
+        square_imports = {r.target_id for r in relations
+                          if r.source_id == _file(nodes, "geometry", "square.rs").node_id
+                          and r.relation_type == "IMPORTS"}
+        assert "external_package:std" in square_imports
+    
+    def test_calls_across_modules(self, rust_results):
+        nodes, relations = rust_results
+        summary = _item(nodes, "shapes::report::summary")
+        
+        calls = {(nodes[r.target_id].name, nodes[r.target_id].file_path): r.properties
+                 for r in relations if r.source_id == summary.node_id and r.relation_type == "CALLS"}
+        total_area = _item(nodes, "shapes::geometry::total_area")
+        assert calls[("total_area", total_area.file_path)]["line_no"] == 15
+        assert ("unit_circle", summary.file_path) in calls
+        # Square::new through the glob import of the square module
+        assert ("new", os.path.join(CRATE_SRC, "geometry", "square.rs")) in calls
+        # Macro arguments are not parsed
+        assert not any(name == "from" for name, _ in calls)
+        
+        unit_circle = _item(nodes, "shapes::report::unit_circle")
+        (new,) = [nodes[r.target_id] for r in relations if r.source_id == unit_circle.node_id and r.relation_type == "CALLS"]
+        assert new.properties["receiver_type"] == "Circle"
+    
+    def test_inline_modules_and_external_calls(self, tmp_path):
+        crate = tmp_path / "tool"
+        (crate / "src").mkdir(parents=True)
+        (crate / "Cargo.toml").write_text('[package]\nname = "my-tool"\nversion = "0.1.0"\n')
+        (crate / "src" / "main.rs").write_text(
+            "mod util {\n"
+            "    pub trait Debug {}\n"
+            "\n"
+            "    pub fn helper() -> u8 {\n"
+            "        1\n"
+            "    }\n"
+            "}\n"
+            "\n"
+            "#[derive(util::Debug)]\n"
+            "struct Config;\n"
+            "\n"
+            "fn main() {\n"
+            "    util::helper();\n"
+            "    serde_json::to_string(&1);\n"
+            "}\n"
+        )
+        
+        nodes, relations = _parse(['rust'], str(tmp_path))
+        
+        util = nodes["package:my_tool::util"]
+        assert util.properties["inline"] is True
+        assert _targets(nodes, relations, util, "CONTAINS") == {"Debug", "helper"}
+        config = _item(nodes, "my_tool::Config")
+        (edge,) = [r for r in relations if r.source_id == config.node_id and r.relation_type == "IMPLEMENTS"]
+        assert edge.properties["derived"] is True
+        main = _item(nodes, "my_tool::main")
+        assert _targets(nodes, relations, main, "CALLS") == {"helper", "to_string"}
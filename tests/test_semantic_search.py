@@ -0,0 +1,286 @@
+"""
+Semantic search tests.
+
+A deterministic bag-of-words provider stands in for the embedding API: the
+indexer embeds a small codebase into an in-memory database, and queries are
+answered by cosine similarity over the stored vectors.
+"""
+
+import hashlib
+import io
+import json
+import math
+import os
+import re
+import sys
+from unittest.mock import patch
+
+import pytest
+
+# Add project root to Python path
+sys.path.insert(0, os.path.dirname(os.path.dirname(os.path.abspath(__file__))))
+
+from src.ast_parser.parser import CodeNode
+from src.embeddings.base import EmbeddingProvider
+from src.embeddings.embedder import CodeEmbedder
+from src.embeddings.node_text import SourceLines, build_node_text, format_signature
+from src.embeddings.ollama import OllamaProvider
+from src.mcp.semantic_search import semantic_search
+
+
+class BagOfWordsProvider(EmbeddingProvider):
+    """Hashes lower-cased words (camelCase split) into a fixed number of buckets."""
+    
+    dimension = 64
+    model = "bag-of-words"
+    
+    def __init__(self):
+        self.batches = []
+    
+    def embed_text(self, text):
+        vector = [0.0] * self.dimension
+        words = re.sub(r"([a-z])([A-Z])", r"\1 \2", text).lower()
+        for word in re.findall(r"[a-z]+", words):
+            bucket = int(hashlib.md5(word.encode()).hexdigest(), 16) % self.dimension
+            vector[bucket] += 1.0
+        return vector
+    
+    def embed_batch(self, texts):
+        self.batches.append(len(texts))
+        return [self.embed_text(text) for text in texts]
+
+
+class FlakyProvider(BagOfWordsProvider):
+    """Fails the first `failures` batch requests."""
+    
+    def __init__(self, failures):
+        super().__init__()
+        self.failures = failures
+    
+    def embed_batch(self, texts):
+        if self.failures:
+            self.failures -= 1
+            raise RuntimeError("429 Too Many Requests")
+        return super().embed_batch(texts)
+
+
+def _cosine(a, b):
+    norm = math.sqrt(sum(x * x for x in a)) * math.sqrt(sum(x * x for x in b))
+    return sum(x * y for x, y in zip(a, b)) / norm if norm else 0.0
+
+
+class VectorDatabase:
+    """In-memory stand-in for the Neo4jDatabase methods used by indexing and semantic search."""
+    
+    def __init__(self, *args, **kwargs):
+        self.nodes = {}
+    
+    def verify_connection(self):
+        return True
+    
+    def create_schema_constraints(self):
+        pass
+    
+    def create_vector_index(self, **kwargs):
+        pass
+    
+    def create_full_text_index(self, **kwargs):
+        pass
+    
+    def flush(self):
+        pass
+    
+    def batch_create_nodes(self, nodes, batch_size=None):
+        for node in nodes:
+            self.nodes[node["properties"]["id"]] = node
+    
+    def batch_create_relationships(self, relationships, batch_size=None):
+        pass
+    
+    def get_nodes(self, node_ids):
+        return [self.nodes[node_id] for node_id in node_ids if node_id in self.nodes]
+    
+    def find_nodes(self, label=None, repo=None, **kwargs):
+        return [node for node in self.nodes.values() if label is None or label in node["labels"]]
+    
+    def delete_cross_repo_relationships(self):
+        return 0
+    
+    def search_similar_nodes(self, vector, node_labels, limit=10):
+        matches = [
+            {
+                "node": {k: v for k, v in node["properties"].items() if k != "embedding"},
+                "labels": node["labels"],
+                "score": (1 + _cosine(node["properties"]["embedding"], vector)) / 2,
+            }
+            for node in self.nodes.values()
+            if "embedding" in node["properties"] and set(node["labels"]) & set(node_labels)
+        ]
+        return sorted(matches, key=lambda match: match["score"], reverse=True)[:limit]
+
+
+VALIDATORS_SOURCE = '''import re
+
+
+def checkAddr(value):
+    """Validate an email address before it is stored."""
+    return re.match(r"[^@]+@[^@]+", value) is not None
+
+
+def render_page(template):
+    """Render an HTML template to a string."""
+    return template.format()
+
+
+class InvoiceTotals:
+    """Sum the line items of an invoice."""
+    
+    def total(self, items):
+        return sum(item.price for item in items)
+'''
+
+
+@pytest.fixture
+def indexed(tmp_path, monkeypatch):
+    """validators.py indexed with the bag-of-words provider."""
+    monkeypatch.setenv("USE_AST_GREP", "false")
+    monkeypatch.setenv("ENABLE_JS_TS_PARSING", "false")
+    monkeypatch.setenv("PARALLEL_INDEXING_ENABLED", "false")
+    monkeypatch.setenv("EMBEDDING_BATCH_SIZE", "2")
+    (tmp_path / "validators.py").write_text(VALIDATORS_SOURCE)
+    
+    from src.main import CodebaseKnowledgeGraph
+    
+    db = VectorDatabase()
+    provider = BagOfWordsProvider()
+    with patch("src.main.Neo4jDatabase", return_value=db):
+        kg = CodebaseKnowledgeGraph(neo4j_uri="bolt://fake", neo4j_user="neo4j", neo4j_password="fake",
+                                    embedding_provider=provider)
+    kg.process_codebase(str(tmp_path))
+    return kg, db, provider, tmp_path
+
+
+class TestNodeText:
+    """Text representation embedded for each node."""
+    
+    def test_signature_doc_and_truncated_body(self, tmp_path):
+        source = tmp_path / "mod.py"
+        source.write_text("async def fetch(url: str, retries=3):\n    '''Fetch a URL.'''\n    return await get(url)\n")
+        node = CodeNode("function:mod:fetch:1", "Function", "fetch", str(source), 1, 3,
+                        {"args": json.dumps([{"name": "url", "type": "str"}, {"name": "retries", "has_default": True}]),
+                         "is_async": True, "doc": "Fetch a URL."})
+        
+        text = build_node_text(node, SourceLines(), body_chars=25)
+        
+        assert text.split("\n") == ["async fetch(url: str, retries)", "Fetch a URL.", "async def fetch(url: str,"]
+    
+    def test_go_signature_and_snippet(self):
+        node = CodeNode("method:x", "Method", "Read", "/repo/r.go", 10, 12,
+                        {"signature": "Read([]byte) (int, error)", "receiver_type": "Reader"})
+        node.code_snippet = "func (r *Reader) Read(p []byte) (int, error) { return 0, nil }"
+        
+        assert format_signature(node) == "Reader.Read([]byte) (int, error)"
+        assert build_node_text(node, body_chars=9).endswith("\nfunc (r *")
+
+
+class TestIndexingPipeline:
+    """Embeddings stored on nodes during indexing."""
+    
+    def test_vectors_and_keys_are_stored(self, indexed):
+        kg, db, provider, _ = indexed
+        
+        embedded = {node["properties"]["name"]: node["properties"] for node in db.nodes.values()
+                    if "embedding" in node["properties"]}
+        
+        assert set(embedded) == {"validators.py", "checkAddr", "render_page", "InvoiceTotals", "total"}
+        assert all(len(props["embedding"]) == 64 and props["embedding_key"] for props in embedded.values())
+        # EMBEDDING_BATCH_SIZE=2 splits the five nodes into three requests
+        assert provider.batches == [2, 2, 1]
+        assert kg.last_run_stats["embeddings"] == {"embedded": 5, "reused": 0, "failed": 0}
+    
+    def test_failed_batches_leave_nodes_without_vectors(self, tmp_path, monkeypatch):
+        monkeypatch.setenv("USE_AST_GREP", "false")
+        monkeypatch.setenv("ENABLE_JS_TS_PARSING", "false")
+        monkeypatch.setenv("EMBEDDING_MAX_RETRIES", "0")
+        (tmp_path / "validators.py").write_text(VALIDATORS_SOURCE)
+        
+        from src.main import CodebaseKnowledgeGraph
+        
+        db = VectorDatabase()
+        with patch("src.main.Neo4jDatabase", return_value=db):
+            kg = CodebaseKnowledgeGraph(neo4j_uri="bolt://fake", neo4j_user="neo4j", neo4j_password="fake",
+                                        embedding_provider=FlakyProvider(failures=100))
+        kg.process_codebase(str(tmp_path))
+        
+        assert kg.last_run_stats["embeddings"]["failed"] == 5
+        assert not any("embedding_key" in node["properties"] for node in db.nodes.values())
+
+
+class TestRetry:
+    """Retry with exponential backoff in CodeEmbedder."""
+    
+    def test_backoff_then_success(self):
+        sleeps = []
+        embedder = CodeEmbedder(FlakyProvider(failures=2), max_retries=3, backoff_seconds=0.5, sleep=sleeps.append)
+        
+        vectors = embedder.embed_code_nodes_batch(["def f(): pass"], ["Function"], ["f"])
+        
+        assert len(vectors) == 1
+        assert sleeps == [0.5, 1.0]
+    
+    def test_gives_up_after_max_retries(self):
+        sleeps = []
+        embedder = CodeEmbedder(FlakyProvider(failures=5), max_retries=2, sleep=sleeps.append)
+        
+        with pytest.raises(RuntimeError, match="429"):
+            embedder.embed_code_nodes_batch(["x"], ["Function"], ["f"])
+        assert sleeps == [1.0, 2.0]
+
+
+class TestSemanticSearch:
+    """Query embedding and ranking."""
+    
+    def test_finds_function_by_meaning(self, indexed):
+        _, db, provider, root = indexed
+        
+        results = semantic_search(db, provider, "where do we validate email addresses", limit=3)
+        
+        best = results[0]
+        assert best["name"] == "checkAddr"
+        assert best["node_type"] == "Function"
+        assert best["file_path"] == str(root / "validators.py")
+        assert (best["line_no"], best["end_line_no"]) == (4, 6)
+        assert best["doc"] == "Validate an email address before it is stored."
+        assert len(results) == 3
+        assert [r["score"] for r in results] == sorted((r["score"] for r in results), reverse=True)
+        assert "embedding" not in best
+    
+    def test_node_type_filter(self, indexed):
+        _, db, provider, _ = indexed
+        
+        results = semantic_search(db, provider, "invoice line items", node_types=["Class"])
+        
+        assert [r["name"] for r in results] == ["InvoiceTotals"]
+        with pytest.raises(ValueError, match="Unknown node types"):
+            semantic_search(db, provider, "x", node_types=["Variable"])
+        with pytest.raises(ValueError, match="empty"):
+            semantic_search(db, provider, "  ")
+
+
+class TestOllamaProvider:
+    """Local Ollama provider request and response handling."""
+    
+    def test_embed_batch(self):
+        requests = []
+        
+        def fake_urlopen(request, timeout=None):
+            requests.append((request.full_url, json.loads(request.data)))
+            return io.BytesIO(json.dumps({"embeddings": [[0.1, 0.2, 0.3], [0.4, 0.5, 0.6]]}).encode())
+        
+        provider = OllamaProvider(base_url="http://localhost:11434/", model="nomic-embed-text")
+        with patch("src.embeddings.ollama.urllib.request.urlopen", fake_urlopen):
+            vectors = provider.embed_batch(["a", "b"])
+        
+        assert vectors == [[0.1, 0.2, 0.3], [0.4, 0.5, 0.6]]
+        assert requests == [("http://localhost:11434/api/embed", {"model": "nomic-embed-text", "input": ["a", "b"]})]
+        assert provider.dimension == 3
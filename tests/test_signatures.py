@@ -0,0 +1,220 @@
+"""
+Structured signature tests.
+
+python_signature is checked on ast nodes and typescript_signature on
+small stand-ins for ast-grep nodes; the end-to-end tests index Python
+files into an InMemoryGraphStore and query find_symbol. The Go and
+TypeScript adapters are covered in their own test files.
+"""
+
+import ast
+import asyncio
+import json
+import os
+import sys
+
+import pytest
+
+# Add project root to Python path
+sys.path.insert(0, os.path.dirname(os.path.dirname(os.path.abspath(__file__))))
+
+from src.ast_parser.signatures import matches_signature, python_signature, signature_properties, typescript_signature
+from src.graph_store import InMemoryGraphStore
+from src.indexing.incremental import plan_incremental_update
+from conftest import ConstantEmbeddingProvider
+
+
+def _python(source, is_method=False):
+    return python_signature(ast.parse(source).body[0], is_method=is_method)
+
+
+class FakeNode:
+    """The kind / field / children / text interface of an ast-grep node."""
+    
+    def __init__(self, kind, text="", children=(), **fields):
+        self._kind = kind
+        self._text = text
+        self._children = list(children)
+        self._fields = fields
+    
+    def kind(self):
+        return self._kind
+    
+    def text(self):
+        return self._text
+    
+    def children(self):
+        return self._children
+    
+    def field(self, name):
+        return self._fields.get(name)
+
+
+class TestPythonSignature:
+    
+    def test_parameters_defaults_and_returns(self):
+        properties = _python("def load(path: str, retries: int = 3, *paths, strict=False, **options) -> Config: pass")
+        signature = json.loads(properties["signature_json"])
+        
+        assert properties["arity"] == 5
+        assert [(p["name"], p["type"], p["default"], p["variadic"]) for p in signature["parameters"]] == [
+            ("path", "str", None, False),
+            ("retries", "int", "3", False),
+            ("paths", None, None, True),
+            ("strict", None, "False", False),
+            ("options", None, None, True),
+        ]
+        assert signature["returns"] == [{"name": None, "type": "Config"}]
+        assert properties["signature"] == "load(path: str, retries: int = 3, *paths, strict=False, **options) -> Config"
+    
+    def test_bound_parameter_is_left_out(self):
+        method = _python("def save(self, item, /, *, force: bool = False): pass", is_method=True)
+        static = _python("@staticmethod\ndef build(config): pass", is_method=True)
+        
+        assert method["arity"] == 2
+        assert method["signature"] == "save(item, /, *, force: bool = False)"
+        assert static["arity"] == 1
+        assert static["signature"] == "build(config)"
+
+
+class TestTypeScriptSignature:
+    
+    def test_optional_default_and_rest_parameters(self):
+        url = FakeNode("required_parameter", pattern=FakeNode("identifier", "url"),
+                       type=FakeNode("type_annotation", ": string"))
+        page = FakeNode("optional_parameter", pattern=FakeNode("identifier", "page"),
+                        type=FakeNode("type_annotation", ": number"))
+        size = FakeNode("required_parameter", pattern=FakeNode("identifier", "size"), value=FakeNode("number", "20"))
+        ids = FakeNode("required_parameter", pattern=FakeNode("rest_pattern", "...ids"),
+                       type=FakeNode("type_annotation", ": T[]"))
+        type_parameter = FakeNode("type_parameter", name=FakeNode("type_identifier", "T"),
+                                  constraint=FakeNode("constraint", "extends Entity"))
+        func = FakeNode(
+            "function_declaration",
+            parameters=FakeNode("formal_parameters", children=[FakeNode("("), url, page, size, ids, FakeNode(")")]),
+            return_type=FakeNode("type_annotation", ": Promise<T[]>"),
+            type_parameters=FakeNode("type_parameters", children=[type_parameter]),
+        )
+        
+        properties = typescript_signature(func, "fetchAll")
+        signature = json.loads(properties["signature_json"])
+        
+        assert properties["arity"] == 4
+        assert signature["parameters"][1]["optional"] is True
+        assert signature["parameters"][3]["variadic"] is True
+        assert signature["type_parameters"] == [{"name": "T", "constraint": "Entity"}]
+        assert properties["signature"] == (
+            "fetchAll<T extends Entity>(url: string, page?: number, size = 20, ...ids: T[]): Promise<T[]>"
+        )
+    
+    def test_bare_arrow_parameter(self):
+        arrow = FakeNode("arrow_function", parameter=FakeNode("identifier", "x"))
+        
+        assert typescript_signature(arrow, "double")["signature"] == "double(x)"
+
+
+class TestMatchesSignature:
+    
+    @pytest.fixture
+    def properties(self):
+        return signature_properties([
+            {"name": "ctx", "type": "context.Context", "default": None, "variadic": False},
+            {"name": "key", "type": "string", "default": None, "variadic": False},
+        ])
+    
+    def test_arity_and_param_type(self, properties):
+        assert matches_signature(properties, arity=2)
+        assert not matches_signature(properties, arity=1)
+        assert matches_signature(properties, param_type="Context")
+        assert matches_signature(properties, arity=2, param_type="string")
+    
+    def test_param_index(self, properties):
+        assert matches_signature(properties, param_type="context.Context", param_index=0)
+        assert not matches_signature(properties, param_type="context.Context", param_index=1)
+        assert not matches_signature(properties, param_type="string", param_index=5)
+    
+    def test_nodes_without_signature_never_match(self):
+        assert not matches_signature({"name": "User"}, arity=0)
+
+
+CODEBASE = {
+    "app/__init__.py": "",
+    "app/service.py": (
+        "class Service:\n"
+        "    def fetch(self, session: Session, key: str):\n"
+        "        return key\n"
+        "\n"
+        "\n"
+        "def connect(session: Session):\n"
+        "    return session\n"
+        "\n"
+        "\n"
+        "def close():\n"
+        "    pass\n"
+    ),
+}
+
+
+@pytest.fixture
+def kg(monkeypatch, tmp_path):
+    monkeypatch.setenv("USE_AST_GREP", "false")
+    monkeypatch.setenv("ENABLE_JS_TS_PARSING", "false")
+    monkeypatch.setenv("PARALLEL_INDEXING_ENABLED", "false")
+    from src.main import CodebaseKnowledgeGraph
+    
+    for path, source in CODEBASE.items():
+        (tmp_path / path).parent.mkdir(parents=True, exist_ok=True)
+        (tmp_path / path).write_text(source)
+    kg = CodebaseKnowledgeGraph(store=InMemoryGraphStore(), embedding_provider=ConstantEmbeddingProvider())
+    kg.process_codebase(str(tmp_path))
+    return kg
+
+
+class TestIndexedSignatures:
+    
+    def test_functions_and_methods_store_signatures(self, kg):
+        signatures = {
+            record["properties"]["name"]: (record["properties"]["arity"], record["properties"]["signature"])
+            for label in ("Function", "Method")
+            for record in kg.db.find_nodes(label=label)
+        }
+        
+        assert signatures == {
+            "fetch": (2, "fetch(session: Session, key: str)"),
+            "connect": (1, "connect(session: Session)"),
+            "close": (0, "close()"),
+        }
+    
+    def test_states_from_before_signatures_are_reparsed(self, kg, tmp_path):
+        stored_states = kg.db.get_file_states()
+        service = str(tmp_path / "app" / "service.py")
+        assert service in plan_incremental_update(list(stored_states), stored_states).unchanged
+        
+        state = json.loads(stored_states[service]["index_state"])
+        del state["version"]
+        stored_states[service]["index_state"] = json.dumps(state)
+        
+        assert plan_incremental_update(list(stored_states), stored_states).changed == [service]
+
+
+class TestFindSymbolBySignature:
+    
+    @pytest.fixture
+    def find_symbol(self, kg, make_server):
+        server = make_server(store=kg.db)
+        return lambda **kwargs: json.loads(asyncio.run(server.mcp.tools["find_symbol"](**kwargs)))
+    
+    def test_filters_without_name(self, find_symbol):
+        assert [s["name"] for s in find_symbol(arity=0)] == ["close"]
+        assert [s["name"] for s in find_symbol(param_type="Session")] == ["fetch", "connect"]
+        assert [s["name"] for s in find_symbol(param_type="str", param_index=1)] == ["fetch"]
+        assert [s["name"] for s in find_symbol(arity=1, param_type="Session", node_type="Function")] == ["connect"]
+    
+    def test_results_include_signature(self, find_symbol):
+        (fetch,) = find_symbol(name="fetch")
+        
+        assert fetch["signature"] == "fetch(session: Session, key: str)"
+        assert fetch["owner"] == "Service"
+    
+    def test_a_filter_is_required(self, find_symbol):
+        assert "error" in find_symbol()
@@ -0,0 +1,186 @@
+"""
+Stored source tests.
+
+truncate_utf8 and slice_source are checked on byte strings; the
+end-to-end tests index a small Python codebase into an InMemoryGraphStore
+with store_source enabled and call find_symbol, find_references and
+semantic_search with include_source.
+"""
+
+import asyncio
+import json
+import os
+import sys
+
+import pytest
+
+# Add project root to Python path
+sys.path.insert(0, os.path.dirname(os.path.dirname(os.path.abspath(__file__))))
+
+from src.indexing.source import (
+    _line_offsets,
+    get_store_source,
+    slice_source,
+    stored_source_line,
+    truncate_utf8,
+)
+from conftest import ConstantEmbeddingProvider, index_tree, write_tree
+
+
+def _slice(source, line_no, end_line_no, mode="true", max_bytes=8192):
+    data = source.encode("utf-8")
+    return slice_source(data, _line_offsets(data), line_no, end_line_no, mode, max_bytes)
+
+
+class TestTruncation:
+    
+    def test_multi_byte_rune_is_not_split(self):
+        data = "añ€😀".encode("utf-8")
+        
+        # a (1) ñ (2) € (3) 😀 (4): every cut inside a rune backs off to its start
+        assert [truncate_utf8(data, limit).decode("utf-8") for limit in range(0, 11)] == [
+            "", "a", "a", "añ", "añ", "añ", "añ€", "añ€", "añ€", "añ€", "añ€😀",
+        ]
+    
+    def test_cut_source_is_flagged(self):
+        source = "def greet():\n    return '你好世界'\n"
+        
+        whole = _slice(source, 1, 2)
+        cut = _slice(source, 1, 2, max_bytes=len("def greet():\n    return '你好".encode("utf-8")) + 1)
+        
+        assert whole == {"source": source.rstrip("\n"), "source_truncated": False}
+        assert cut == {"source": "def greet():\n    return '你好", "source_truncated": True}
+    
+    def test_lines_outside_the_file(self):
+        assert _slice("x = 1\n", 3, 4) is None
+        assert _slice("x = 1\n", 1, 9)["source"] == "x = 1"
+
+
+class TestSignaturesOnly:
+    
+    def test_header_ends_where_the_body_opens(self):
+        python = "def load(path,\n         retries=3):\n    return path\n"
+        go = "func (s *Server) Start(\n\tctx context.Context,\n) error {\n\treturn nil\n}\n"
+        rust = "fn area(&self) -> f64;\n"
+        
+        assert _slice(python, 1, 3, "signatures_only")["source"] == "def load(path,\n         retries=3):"
+        assert _slice(go, 1, 5, "signatures_only")["source"] == "func (s *Server) Start(\n\tctx context.Context,\n) error {"
+        assert _slice(rust, 1, 1, "signatures_only")["source"] == "fn area(&self) -> f64;"
+    
+    def test_unknown_mode(self):
+        with pytest.raises(ValueError):
+            get_store_source("all")
+
+
+class TestStoredSourceLine:
+    
+    def test_line_relative_to_the_node(self):
+        properties = {"line_no": 10, "source": "def run():\n    step()\n"}
+        
+        assert stored_source_line(properties, 11) == "step()"
+        assert stored_source_line(properties, 9) is None
+        assert stored_source_line({"line_no": 10}, 10) is None
+
+
+CODEBASE = {
+    "app/__init__.py": "",
+    "app/service.py": (
+        "def greet(name):\n"
+        "    # 問候 😀\n"
+        "    return 'hello ' + name\n"
+        "\n"
+        "\n"
+        "class Greeter:\n"
+        "    pass\n"
+        "\n"
+        "\n"
+        "class LoudGreeter(Greeter):\n"
+        "    def shout(self, name):\n"
+        "        return greet(name).upper()\n"
+    ),
+}
+
+
+def _index(tmp_path, store_source, source_max_bytes=None):
+    write_tree(tmp_path, CODEBASE)
+    return index_tree(tmp_path, store_source=store_source, source_max_bytes=source_max_bytes)
+
+
+def _function(kg, name):
+    (record,) = kg.db.find_nodes(name=name, label="Function")
+    return record["properties"]
+
+
+class TestIndexedSource:
+    
+    def test_symbol_nodes_store_their_source(self, legacy_env, tmp_path):
+        kg = _index(tmp_path, "true")
+        greet = _function(kg, "greet")
+        
+        assert greet["source"] == "def greet(name):\n    # 問候 😀\n    return 'hello ' + name"
+        assert "source_truncated" not in greet
+        # Only symbols get a source, and the parsed nodes do not keep it once written
+        assert all("source" not in record["properties"] for record in kg.db.find_nodes(label="File"))
+    
+    def test_byte_cap_and_signatures_only(self, legacy_env, tmp_path):
+        capped = _function(_index(tmp_path, "true", source_max_bytes=27), "greet")
+        header = _function(_index(tmp_path, "signatures_only"), "greet")
+        
+        # The cap falls inside 候 (bytes 26-28 of the source)
+        assert capped["source"] == "def greet(name):\n    # 問"
+        assert capped["source_truncated"] is True
+        assert header["source"] == "def greet(name):"
+    
+    def test_disabled_by_default(self, legacy_env, monkeypatch, tmp_path):
+        monkeypatch.delenv("INDEX_STORE_SOURCE", raising=False)
+        
+        assert "source" not in _function(_index(tmp_path, None), "greet")
+
+
+class TestIncludeSource:
+    
+    @pytest.fixture
+    def tools(self, legacy_env, tmp_path, make_server):
+        kg = _index(tmp_path, "true")
+        # Same-file calls are not resolved by the Python parser, add the edge the other adapters would emit
+        (method,) = kg.db.find_nodes(name="shout", label="Method")
+        kg.db.batch_create_relationships([
+            {"start_node_id": method["properties"]["id"], "end_node_id": _function(kg, "greet")["id"],
+             "type": "CALLS", "properties": {"line_no": 12}},
+        ])
+        
+        server = make_server(store=kg.db, embedding_provider=ConstantEmbeddingProvider())
+        return lambda tool, **kwargs: json.loads(asyncio.run(server.mcp.tools[tool](**kwargs)))
+    
+    def test_find_symbol(self, tools):
+        (plain,) = tools("find_symbol", name="LoudGreeter")
+        (loud,) = tools("find_symbol", name="LoudGreeter", include_source=True)
+        
+        assert "source" not in plain
+        assert loud["source"] == (
+            "class LoudGreeter(Greeter):\n"
+            "    def shout(self, name):\n"
+            "        return greet(name).upper()"
+        )
+        assert loud["source_truncated"] is False
+    
+    def test_find_references(self, tools, tmp_path):
+        result = tools("find_references", symbol="greet", kind="calls", include_source=True)
+        
+        assert result["target"]["source"].startswith("def greet(name):\n")
+        (reference,) = result["references"]
+        # Sliced from the file as is, indentation included
+        assert reference["source"] == "    def shout(self, name):\n        return greet(name).upper()"
+        
+        # With the file gone the snippet comes from the stored source
+        os.remove(tmp_path / "app" / "service.py")
+        (reference,) = tools("find_references", symbol="greet", kind="calls")["references"]
+        assert reference["snippet"] == "return greet(name).upper()"
+    
+    def test_semantic_search(self, tools):
+        results = tools("semantic_search", query="greeting", node_types=["Function", "Method"],
+                        include_source=True)["results"]
+        
+        assert sorted(r["source"].split("\n")[0].strip() for r in results) == [
+            "def greet(name):", "def shout(self, name):",
+        ]
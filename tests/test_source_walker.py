@@ -0,0 +1,187 @@
+"""
+Source file walker tests.
+
+Builds small directory trees under tmp_path and checks which files the
+gitignore-aware walker collects and what it reports as skipped.
+"""
+
+import os
+import sys
+
+import pytest
+
+# Add project root to Python path
+sys.path.insert(0, os.path.dirname(os.path.dirname(os.path.abspath(__file__))))
+
+from src.indexing.walker import SourceFileWalker, compile_pattern, is_ignored
+
+
+def _write(root, rel_path, content=""):
+    path = root / rel_path
+    path.parent.mkdir(parents=True, exist_ok=True)
+    path.write_text(content)
+    return path
+
+
+def _collected(walker, root):
+    """Walk root and return the collected paths relative to it."""
+    return sorted(os.path.relpath(path, root).replace(os.sep, "/") for path in walker.walk(str(root)))
+
+
+class TestGitignorePatterns:
+    """Pattern compilation and precedence."""
+    
+    @pytest.mark.parametrize("pattern,path,is_dir,expected", [
+        ("*.pb.go", "api/v1/service.pb.go", False, True),
+        ("build/", "build", True, True),
+        ("build/", "build", False, False),
+        ("/dist", "dist", True, True),
+        ("/dist", "pkg/dist", True, False),
+        ("docs/**/*.md", "docs/a/b/readme.md", False, True),
+        ("**/generated", "x/y/generated", True, True),
+        ("foo/**", "foo/bar/baz.py", False, True),
+        ("test_?.py", "test_1.py", False, True),
+        ("[!a]*.py", "abc.py", False, False),
+        ("\\#notes.py", "#notes.py", False, True),
+    ])
+    def test_pattern_matching(self, pattern, path, is_dir, expected):
+        rule = compile_pattern(pattern)
+        assert rule.matches(path, is_dir) is expected
+    
+    def test_comments_and_blank_lines(self):
+        assert compile_pattern("# comment") is None
+        assert compile_pattern("   ") is None
+    
+    def test_last_matching_rule_wins(self):
+        rules = [compile_pattern("*.py"), compile_pattern("!keep.py")]
+        assert is_ignored(rules, "drop.py", False)
+        assert not is_ignored(rules, "keep.py", False)
+
+
+class TestSourceFileWalker:
+    """Directory traversal."""
+    
+    def test_nested_gitignore_precedence_and_negation(self, tmp_path):
+        _write(tmp_path, ".gitignore", "*.gen.py\nnode_modules/\n")
+        _write(tmp_path, "app.py")
+        _write(tmp_path, "models.gen.py")
+        _write(tmp_path, "node_modules/lib/index.js")
+        _write(tmp_path, "pkg/.gitignore", "!schema.gen.py\nlocal_*.py\n")
+        _write(tmp_path, "pkg/schema.gen.py")
+        _write(tmp_path, "pkg/other.gen.py")
+        _write(tmp_path, "pkg/local_settings.py")
+        _write(tmp_path, "pkg/core.py")
+        # Rules of pkg/.gitignore do not leak into sibling directories
+        _write(tmp_path, "tools/local_run.py")
+        
+        walker = SourceFileWalker((".py", ".js"))
+        
+        assert _collected(walker, tmp_path) == [
+            "app.py", "pkg/core.py", "pkg/schema.gen.py", "tools/local_run.py",
+        ]
+        assert walker.stats.skipped_dirs["gitignore"] == 1
+        assert walker.stats.skipped_files["gitignore"] == 3
+    
+    def test_git_directory_is_always_skipped(self, tmp_path):
+        _write(tmp_path, ".git/hooks/pre-commit.py")
+        _write(tmp_path, "main.py")
+        
+        walker = SourceFileWalker((".py",))
+        
+        assert _collected(walker, tmp_path) == ["main.py"]
+        assert walker.stats.skipped_dirs["vcs_directory"] == 1
+    
+    def test_gitignore_can_be_disabled(self, tmp_path):
+        _write(tmp_path, ".gitignore", "*.py\n")
+        _write(tmp_path, "main.py")
+        
+        assert _collected(SourceFileWalker((".py",), respect_gitignore=False), tmp_path) == ["main.py"]
+    
+    def test_exclude_patterns(self, tmp_path):
+        _write(tmp_path, "src/app.go")
+        _write(tmp_path, "src/app.pb.go")
+        _write(tmp_path, "vendor/dep/dep.go")
+        
+        walker = SourceFileWalker((".go",), exclude="vendor/, *.pb.go")
+        
+        assert _collected(walker, tmp_path) == ["src/app.go"]
+        assert walker.stats.skipped_dirs["exclude"] == 1
+        assert walker.stats.skipped_files["exclude"] == 1
+    
+    def test_include_only_allowlist(self, tmp_path):
+        _write(tmp_path, "src/a.py")
+        _write(tmp_path, "src/sub/b.py")
+        _write(tmp_path, "scripts/c.py")
+        _write(tmp_path, "setup.py")
+        
+        walker = SourceFileWalker((".py",), include_only=["src/**"])
+        
+        assert _collected(walker, tmp_path) == ["src/a.py", "src/sub/b.py"]
+        assert walker.stats.skipped_files["include_only"] == 2
+    
+    def test_summary_reports_reasons(self, tmp_path):
+        _write(tmp_path, ".gitignore", "ignored.py\n")
+        _write(tmp_path, "ignored.py")
+        _write(tmp_path, "kept.py")
+        
+        walker = SourceFileWalker((".py",))
+        walker.walk(str(tmp_path))
+        
+        assert walker.stats.summary() == "collected 1 files, skipped 1 files and 0 directories (gitignore: 1)"
+    
+    def test_accepts_matches_walk(self, tmp_path):
+        _write(tmp_path, ".gitignore", "vendor/\n")
+        _write(tmp_path, "pkg/.gitignore", "*_gen.py\n")
+        _write(tmp_path, "pkg/mod.py")
+        _write(tmp_path, "pkg/mod_gen.py")
+        _write(tmp_path, "vendor/lib.py")
+        
+        walker = SourceFileWalker((".py",), exclude="build/**")
+        accepted = sorted(
+            os.path.relpath(str(path), tmp_path).replace(os.sep, "/")
+            for path in tmp_path.rglob("*.py")
+            if walker.accepts(str(tmp_path), str(path))
+        )
+        
+        assert accepted == _collected(walker, tmp_path) == ["pkg/mod.py"]
+        # Paths that do not exist (deleted or renamed away) are checked by name
+        assert walker.accepts(str(tmp_path), str(tmp_path / "pkg" / "gone.py"))
+        assert not walker.accepts(str(tmp_path), str(tmp_path / "build" / "out.py"))
+        assert not walker.accepts(str(tmp_path), str(tmp_path / ".git" / "hooks" / "x.py"))
+        assert not walker.accepts(str(tmp_path), str(tmp_path / "pkg" / ".mod.py.swp"))
+        assert not walker.accepts(str(tmp_path), str(tmp_path.parent / "other.py"))
+        assert walker.accepts(str(tmp_path), str(tmp_path / "pkg"), is_dir=True)
+        assert not walker.accepts(str(tmp_path), str(tmp_path / "vendor"), is_dir=True)
+
+
+@pytest.mark.skipif(not hasattr(os, "symlink"), reason="symlinks not supported")
+class TestSymlinks:
+    """Symlinked directories."""
+    
+    @pytest.fixture
+    def tree(self, tmp_path):
+        root = tmp_path / "repo"
+        outside = tmp_path / "shared"
+        _write(root, "main.py")
+        _write(root, "lib/util.py")
+        _write(outside, "shared.py")
+        try:
+            os.symlink(outside, root / "external", target_is_directory=True)
+            os.symlink(root / "lib", root / "lib_alias", target_is_directory=True)
+        except OSError as e:
+            pytest.skip(f"cannot create symlinks: {e}")
+        return root
+    
+    def test_symlinks_skipped_by_default(self, tree):
+        walker = SourceFileWalker((".py",))
+        
+        assert _collected(walker, tree) == ["lib/util.py", "main.py"]
+        assert walker.stats.skipped_dirs["symlink_outside_root"] == 1
+        assert walker.stats.skipped_dirs["symlink_inside_root"] == 1
+    
+    def test_follow_symlinks_outside_root(self, tree):
+        walker = SourceFileWalker((".py",), follow_symlinks=True)
+        
+        assert _collected(walker, tree) == ["external/shared.py", "lib/util.py", "main.py"]
+        # Links back into the root are still skipped, the target is walked directly
+        assert walker.stats.skipped_dirs["symlink_inside_root"] == 1
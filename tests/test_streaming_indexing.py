@@ -0,0 +1,223 @@
+"""
+Streaming (memory-bounded) indexing tests.
+
+SpillBuffer is tested on its own: records come back in order, from
+memory and from the spill file. The fixtures are then indexed into an
+InMemoryGraphStore twice, once as usual and once streaming in batches of
+two files with a zero memory budget, so every record is spilled: the
+exported GraphML and every stored node and relationship must be equal.
+Fixtures in languages the legacy parsers do not handle need ast-grep.
+
+The memory benchmark indexes copies of example_codebase in a subprocess
+per mode and compares their peak RSS; it only runs when RUN_BENCHMARKS
+is set.
+"""
+
+import json
+import os
+import pickle
+import shutil
+import subprocess
+import sys
+from pathlib import Path
+
+import pytest
+
+# Add project root to Python path
+sys.path.insert(0, os.path.dirname(os.path.dirname(os.path.abspath(__file__))))
+
+from src.ast_parser.parser import CodeNode, CodeRelation
+from src.export.graph_export import export_graph
+from src.graph_store import InMemoryGraphStore
+from src.indexing.streaming import SpillBuffer, get_memory_budget_mb, get_streaming, iter_batches, light_node
+from conftest import index_tree
+
+FIXTURES = Path(__file__).parent / "fixtures"
+EXAMPLE_CODEBASE = Path(__file__).parent.parent / "example_codebase"
+
+# Copies of example_codebase used by the benchmark
+BENCHMARK_COPIES = int(os.getenv("BENCHMARK_COPIES", "300"))
+
+# Properties that differ between any two runs
+RUN_PROPERTIES = ("indexed_at",)
+
+
+def _record(index):
+    node = CodeNode(f"Function:/repo/f{index}.py:f:1", "Function", "f", f"/repo/f{index}.py", 1)
+    return (f"/repo/f{index}.py", {node.node_id: node}, [CodeRelation(f"file:/repo/f{index}.py", node.node_id, "DEFINES")],
+            [{"type": "CALLS", "source_id": node.node_id, "candidates": (("m", "g"),)}], False)
+
+
+class TestSpillBuffer:
+    
+    def test_in_memory_under_budget(self):
+        with SpillBuffer(1024 * 1024) as buffer:
+            for index in range(3):
+                buffer.append(_record(index))
+            
+            assert buffer.spilled_records == 0 and buffer.memory_bytes > 0
+            assert [record[0] for record in buffer] == ["/repo/f0.py", "/repo/f1.py", "/repo/f2.py"]
+    
+    def test_spills_and_reads_back_in_order(self):
+        with SpillBuffer(0) as buffer:
+            for index in range(5):
+                buffer.append(_record(index))
+            
+            assert buffer.spilled_records == 5 and buffer.memory_bytes == 0
+            first = list(buffer)
+            assert [record[0] for record in first] == [f"/repo/f{index}.py" for index in range(5)]
+            # Iterating again reads the spill file from the start; tuples survive the round trip
+            assert [record[0] for record in buffer] == [record[0] for record in first]
+            assert first[2][3][0]["candidates"] == (("m", "g"),)
+            assert first[2][2][0].relation_type == "DEFINES"
+    
+    def test_spilled_and_in_memory_records_keep_their_order(self):
+        record_bytes = len(pickle.dumps(_record(0), protocol=pickle.HIGHEST_PROTOCOL))
+        with SpillBuffer(record_bytes * 2) as buffer:
+            for index in range(4):
+                buffer.append(_record(index))
+            
+            assert 0 < buffer.spilled_records < 4
+            assert [record[0] for record in buffer] == [f"/repo/f{index}.py" for index in range(4)]
+    
+    def test_light_node_drops_heavy_properties(self):
+        node = CodeNode("Function:/repo/a.py:f:1", "Function", "f", "/repo/a.py", 1, 3,
+                        properties={"doc": "Docs.", "embedding": [0.1], "signature": "f()", "is_test": True})
+        node.code_snippet = "def f(): pass"
+        
+        light = light_node(node)
+        assert light.properties == {"signature": "f()", "is_test": True}
+        assert light.code_snippet == "" and light.end_line_no == 3
+        assert "doc" in node.properties
+    
+    def test_settings(self, monkeypatch):
+        monkeypatch.delenv("INDEX_STREAMING", raising=False)
+        monkeypatch.setenv("INDEX_MEMORY_BUDGET_MB", "many")
+        assert get_streaming() is False and get_streaming(True) is True
+        assert get_memory_budget_mb() == 256 and get_memory_budget_mb(-1) == 0
+        monkeypatch.setenv("INDEX_STREAMING", "true")
+        monkeypatch.setenv("INDEX_MEMORY_BUDGET_MB", "32")
+        assert get_streaming() is True and get_memory_budget_mb() == 32
+        assert list(iter_batches(range(5), 2)) == [[0, 1], [2, 3], [4]]
+
+
+def _snapshot(store):
+    """Every node and relationship of the store, sorted."""
+    nodes = []
+    for record in store.find_nodes():
+        properties = {key: value for key, value in record["properties"].items() if key not in RUN_PROPERTIES}
+        nodes.append(json.dumps({"labels": sorted(record["labels"]), "properties": properties}, sort_keys=True))
+    node_ids = [record["properties"]["id"] for record in store.find_nodes()]
+    relationships = [json.dumps(rel, sort_keys=True) for rel in store.edges_between(node_ids)]
+    return sorted(nodes), sorted(relationships)
+
+
+@pytest.fixture
+def streaming_env(monkeypatch):
+    monkeypatch.setenv("ENABLE_JS_TS_PARSING", "false")
+    monkeypatch.setenv("PARALLEL_INDEXING_ENABLED", "false")
+    monkeypatch.setenv("INDEX_STREAM_BATCH_FILES", "2")
+    monkeypatch.setenv("GRAPH_STORAGE", "memory")
+    return monkeypatch
+
+
+def _copy_example_codebase(destination, copies):
+    """Copy example_codebase into `copies` sibling packages, so module names collide."""
+    for i in range(copies):
+        package = destination / f"copy_{i:03d}"
+        package.mkdir(parents=True)
+        for source in sorted(EXAMPLE_CODEBASE.glob("*.py")):
+            shutil.copy(source, package / source.name)
+    return destination
+
+
+class TestStreamingMatchesFull:
+    
+    def _assert_same_graph(self, codebase):
+        full_store = index_tree(codebase).db
+        kg = index_tree(codebase, streaming=True, memory_budget_mb=0)
+        streamed_store = kg.db
+        
+        assert kg.last_run_stats["streaming"] is True
+        assert kg.last_run_stats["spilled_files"] == kg.last_run_stats["files"] > 0
+        assert export_graph(streamed_store)[0] == export_graph(full_store)[0]
+        assert _snapshot(streamed_store) == _snapshot(full_store)
+    
+    @pytest.mark.parametrize("fixture", ["python_sample", "doc_comments", "grpc_sample"])
+    def test_legacy_parsers(self, streaming_env, fixture):
+        streaming_env.setenv("USE_AST_GREP", "false")
+        self._assert_same_graph(FIXTURES / fixture)
+    
+    def test_colliding_module_names(self, streaming_env, tmp_path):
+        streaming_env.setenv("USE_AST_GREP", "false")
+        self._assert_same_graph(_copy_example_codebase(tmp_path / "copies", 3))
+    
+    @pytest.mark.parametrize("fixture", ["multi_lang_sample", "js_ts_sample", "doc_comments", "grpc_sample"])
+    def test_ast_grep(self, streaming_env, fixture):
+        pytest.importorskip("ast_grep_py")
+        streaming_env.setenv("USE_AST_GREP", "true")
+        streaming_env.setenv("AST_GREP_LANGUAGES", "python,javascript,typescript,go,java,rust,csharp")
+        self._assert_same_graph(FIXTURES / fixture)
+    
+    def test_parallel_parse(self, streaming_env, tmp_path):
+        streaming_env.setenv("USE_AST_GREP", "false")
+        streaming_env.setenv("PARALLEL_INDEXING_ENABLED", "true")
+        streaming_env.setenv("MIN_FILES_FOR_PARALLEL", "1")
+        self._assert_same_graph(_copy_example_codebase(tmp_path / "copies", 2))
+    
+    def test_rerun_replaces_the_earlier_index(self, streaming_env, tmp_path):
+        streaming_env.setenv("USE_AST_GREP", "false")
+        codebase = _copy_example_codebase(tmp_path / "copies", 2)
+        
+        def index_twice(second_streaming):
+            store = InMemoryGraphStore()
+            for streaming in (False, second_streaming):
+                index_tree(codebase, store, streaming=streaming)
+            return store
+        
+        # A streaming run over a full index leaves what a second full run does
+        assert _snapshot(index_twice(True)) == _snapshot(index_twice(False))
+
+
+# Indexes a codebase in one mode and prints the peak RSS of the process in kilobytes
+_BENCHMARK_SCRIPT = """
+import resource, sys
+from unittest.mock import MagicMock
+sys.path.insert(0, sys.argv[1])
+from src.graph_store import InMemoryGraphStore
+from src.main import CodebaseKnowledgeGraph
+
+
+class LeanStore(InMemoryGraphStore):
+    # Keeps the graph, not its snippets and vectors, so the indexer's own memory is measured
+    def batch_create_nodes(self, nodes, batch_size=None):
+        for node in nodes:
+            for key in ("code_snippet", "embedding", "doc"):
+                node["properties"].pop(key, None)
+        super().batch_create_nodes(nodes, batch_size)
+
+
+kg = CodebaseKnowledgeGraph(store=LeanStore(), embedding_provider=MagicMock(), streaming=sys.argv[3] == "streaming")
+kg._generate_embeddings = lambda *args, **kwargs: None
+kg.process_codebase(sys.argv[2])
+print(resource.getrusage(resource.RUSAGE_SELF).ru_maxrss)
+"""
+
+
+@pytest.mark.skipif(not os.getenv("RUN_BENCHMARKS"), reason="set RUN_BENCHMARKS=1 to run the benchmark")
+def test_streaming_peak_memory_benchmark(tmp_path):
+    pytest.importorskip("resource")
+    codebase = _copy_example_codebase(tmp_path / "copies", BENCHMARK_COPIES)
+    env = dict(os.environ, USE_AST_GREP="false", ENABLE_JS_TS_PARSING="false", PARALLEL_INDEXING_ENABLED="false")
+    
+    peaks = {}
+    for mode in ("full", "streaming"):
+        output = subprocess.run(
+            [sys.executable, "-c", _BENCHMARK_SCRIPT, str(Path(__file__).parent.parent), str(codebase), mode],
+            capture_output=True, text=True, env=env, check=True,
+        ).stdout
+        peaks[mode] = int(output.strip().splitlines()[-1])
+    
+    print(f"\nPeak RSS over {BENCHMARK_COPIES * 4} files: full {peaks['full'] / 1024:.1f} MB, "
+          f"streaming {peaks['streaming'] / 1024:.1f} MB")
+    assert peaks["streaming"] < peaks["full"]
@@ -0,0 +1,222 @@
+"""
+Stable symbol ID tests.
+
+annotate_symbol_ids is run on hand-built nodes (collisions, overloads, the
+signature scheme, ordering), then a small Python codebase is indexed into
+an InMemoryGraphStore: IDs survive edits above a symbol and come out the
+same from incremental and full runs, and find_symbol_candidates and
+resolve_symbol look them up. The resolve_symbol tool runs on a capturing
+stand-in for FastMCP.
+"""
+
+import asyncio
+import json
+import os
+import random
+import sys
+
+import pytest
+
+# Add project root to Python path
+sys.path.insert(0, os.path.dirname(os.path.dirname(os.path.abspath(__file__))))
+
+from src.ast_parser.parser import CodeNode, CodeRelation
+from src.indexing.symbol_ids import annotate_symbol_ids, get_symbol_id_scheme
+from src.mcp.references import find_symbol_candidates
+from src.mcp.resolve import parse_query, resolve_symbol
+from conftest import index_tree, write_tree
+
+PERSON = '''class Person:
+    def __init__(self, name):
+        self.name = name
+
+    def GetName(self):
+        return self.name
+
+
+def outer():
+    def inner():
+        return 1
+    return inner()
+'''
+
+OTHER_PERSON = '''class Person:
+    def GetName(self):
+        return "other"
+'''
+
+
+def _method(name, line_no, parameters, file_path="/repo/src/Shape.java"):
+    signature = {"parameters": [{"name": f"p{i}", "type": t} for i, t in enumerate(parameters)], "returns": []}
+    return CodeNode(f"Method:{file_path}:{name}:{line_no}", "Method", name, file_path, line_no,
+                    properties={"signature_json": json.dumps(signature)})
+
+
+def _shape_nodes():
+    """A Java class with two overloads of area and one scale."""
+    shape = CodeNode("Class:/repo/src/Shape.java:Shape:1", "Class", "Shape", "/repo/src/Shape.java", 1)
+    methods = [_method("area", 2, []), _method("area", 3, ["double"]), _method("scale", 4, ["double"])]
+    nodes = {node.node_id: node for node in [shape] + methods}
+    relations = [CodeRelation(shape.node_id, method.node_id, "DEFINES") for method in methods]
+    return nodes, relations
+
+
+def _symbol_ids(nodes):
+    return {node.node_id: node.properties.get("symbol_id") for node in nodes.values()}
+
+
+class TestAnnotate:
+    
+    def test_qualified_and_overloaded(self):
+        nodes, relations = _shape_nodes()
+        
+        assert annotate_symbol_ids(nodes, relations, "default", "/repo") == 4
+        ids = _symbol_ids(nodes)
+        assert ids["Class:/repo/src/Shape.java:Shape:1"] == "default:src/Shape.java:class:Shape"
+        assert ids["Method:/repo/src/Shape.java:scale:4"] == "default:src/Shape.java:method:Shape.scale"
+        # Overloads share a name; the signature hash tells them apart
+        area = [ids["Method:/repo/src/Shape.java:area:2"], ids["Method:/repo/src/Shape.java:area:3"]]
+        assert all(symbol_id.startswith("default:src/Shape.java:method:Shape.area:") for symbol_id in area)
+        assert len(set(area)) == 2
+    
+    def test_signature_scheme_hashes_every_callable(self):
+        nodes, relations = _shape_nodes()
+        annotate_symbol_ids(nodes, relations, "web", "/repo", scheme="signature")
+        
+        ids = _symbol_ids(nodes)
+        assert ids["Class:/repo/src/Shape.java:Shape:1"] == "web:src/Shape.java:class:Shape"
+        scale = ids["Method:/repo/src/Shape.java:scale:4"]
+        assert scale.startswith("web:src/Shape.java:method:Shape.scale:") and len(scale.rsplit(":", 1)[1]) == 8
+    
+    def test_same_signature_is_told_apart_by_position(self):
+        first, second = _method("area", 2, ["int"]), _method("area", 9, ["int"])
+        nodes = {first.node_id: first, second.node_id: second}
+        annotate_symbol_ids(nodes, [], "default", "/repo")
+        
+        assert first.properties["symbol_id"] != second.properties["symbol_id"]
+    
+    def test_independent_of_node_order(self):
+        nodes, relations = _shape_nodes()
+        annotate_symbol_ids(nodes, relations, "default", "/repo")
+        expected = _symbol_ids(nodes)
+        
+        shuffled_nodes, shuffled_relations = _shape_nodes()
+        items = list(shuffled_nodes.items())
+        random.Random(7).shuffle(items)
+        shuffled_relations.reverse()
+        shuffled = dict(items)
+        annotate_symbol_ids(shuffled, shuffled_relations, "default", "/repo")
+        assert _symbol_ids(shuffled) == expected
+    
+    def test_scheme(self, monkeypatch):
+        monkeypatch.delenv("SYMBOL_ID_SCHEME", raising=False)
+        assert get_symbol_id_scheme() == "qualified"
+        monkeypatch.setenv("SYMBOL_ID_SCHEME", "Signature")
+        assert get_symbol_id_scheme() == "signature"
+        assert get_symbol_id_scheme("qualified") == "qualified"
+        with pytest.raises(ValueError):
+            get_symbol_id_scheme("hashed")
+
+
+@pytest.fixture
+def codebase(legacy_env, tmp_path):
+    write_tree(tmp_path, {"sample/person.py": PERSON, "other/person.py": OTHER_PERSON})
+    return tmp_path
+
+
+def _index(codebase, store=None, incremental=False):
+    return index_tree(codebase, store, incremental).db
+
+
+def _stored_ids(store):
+    return {node["properties"]["symbol_id"]: node["properties"]["id"]
+            for node in store.find_nodes() if node["properties"].get("symbol_id")}
+
+
+class TestIndexedIds:
+    
+    def test_ids(self, codebase):
+        ids = _stored_ids(_index(codebase))
+        
+        assert {
+            "default:sample:package:sample",
+            "default:sample/person.py:file:person.py",
+            "default:sample/person.py:class:Person",
+            "default:sample/person.py:method:Person.GetName",
+            "default:sample/person.py:function:outer.inner",
+            "default:other/person.py:method:Person.GetName",
+        } <= set(ids)
+    
+    def test_ids_survive_edits_above(self, codebase):
+        store = _index(codebase)
+        before = _stored_ids(store)
+        
+        (codebase / "sample" / "person.py").write_text("import os\n\n\n" + PERSON, encoding="utf-8")
+        _index(codebase, store, incremental=True)
+        after = _stored_ids(store)
+        
+        assert set(after) == set(before)
+        get_name = "default:sample/person.py:method:Person.GetName"
+        assert after[get_name] != before[get_name]
+        assert after[get_name].endswith(":GetName:8")
+    
+    def test_incremental_matches_full(self, codebase):
+        store = _index(codebase)
+        (codebase / "sample" / "person.py").write_text(PERSON + "\n\ndef extra():\n    return outer()\n", encoding="utf-8")
+        _index(codebase, store, incremental=True)
+        
+        assert _stored_ids(store) == _stored_ids(_index(codebase))
+    
+    def test_find_symbol_candidates(self, codebase):
+        store = _index(codebase)
+        
+        (candidate,) = find_symbol_candidates(store, "default:other/person.py:method:Person.GetName")
+        assert candidate["file_path"] == str(codebase / "other" / "person.py")
+        assert candidate["symbol_id"] == "default:other/person.py:method:Person.GetName"
+        assert len(find_symbol_candidates(store, "GetName")) == 2
+
+
+class TestResolveSymbol:
+    
+    def test_parse_query(self):
+        assert parse_query("Person.GetName in the sample package") == {
+            "name": "GetName", "qualifier": "Person", "scopes": ["sample"], "kinds": [],
+        }
+        assert parse_query("the parse function of utils") == {
+            "name": "parse", "qualifier": None, "scopes": ["utils"], "kinds": ["Function"],
+        }
+        assert parse_query("Parser::new")["qualifier"] == "Parser"
+        with pytest.raises(ValueError):
+            parse_query("the function")
+    
+    def test_ranking(self, codebase):
+        result = resolve_symbol(_index(codebase), "Person.GetName in the sample package")
+        
+        assert result["status"] == "ok" and result["total"] == 2
+        best, other = result["matches"]
+        assert best["symbol_id"] == "default:sample/person.py:method:Person.GetName"
+        assert best["score"] == 1.0 and best["matched"] == ["name", "qualifier", "scope"]
+        assert other["symbol_id"] == "default:other/person.py:method:Person.GetName"
+        assert other["score"] < best["score"]
+    
+    def test_other_case_and_exact_id(self, codebase):
+        store = _index(codebase)
+        
+        lowered = resolve_symbol(store, "getname")
+        assert lowered["total"] == 2 and all(match["score"] < 1 for match in lowered["matches"])
+        exact = resolve_symbol(store, "default:sample/person.py:function:outer")
+        assert exact["matches"][0]["name"] == "outer" and exact["matches"][0]["score"] == 1.0
+        assert resolve_symbol(store, "Missing")["status"] == "not_found"
+
+
+class TestResolveSymbolTool:
+    
+    def test_tool(self, codebase, make_server):
+        store = _index(codebase)
+        server = make_server(store=store)
+        
+        result = json.loads(asyncio.run(server.mcp.tools["resolve_symbol"]("GetName of other", limit=1)))
+        assert [match["symbol_id"] for match in result["matches"]] == ["default:other/person.py:method:Person.GetName"]
+        # The symbol ID selects the node wherever a tool takes a name
+        (symbol,) = json.loads(asyncio.run(server.mcp.tools["find_symbol"](result["matches"][0]["symbol_id"])))
+        assert (symbol["owner"], symbol["file_path"]) == ("Person", str(codebase / "other" / "person.py"))
@@ -0,0 +1,210 @@
+"""
+Fuzzy symbol search tests.
+
+match_name is checked strategy by strategy, then rank_symbols ranks the
+symbols of a small mixed Go and Python store for a table of tricky
+queries: other cases and naming conventions, typos, subsequences, a
+package hint, and the demotion of private, test and generated symbols.
+Each row pins the full ranking order. find_symbol is called through a
+captured FastMCP server for the score and match fields and paging.
+"""
+
+import asyncio
+import json
+import os
+import sys
+
+import pytest
+
+# Add project root to Python path
+sys.path.insert(0, os.path.dirname(os.path.dirname(os.path.abspath(__file__))))
+
+from src.graph_store import InMemoryGraphStore
+from src.mcp.symbol_search import match_name, rank_symbols, split_words
+
+
+def _node(label, name, file_path, line_no, **extra):
+    node_id = f"{label.lower()}:{file_path}:{name}:{line_no}"
+    return {"labels": ["Base", label],
+            "properties": {"id": node_id, "name": name, "file_path": file_path, "line_no": line_no, **extra}}
+
+
+NODES = [
+    _node("Function", "GetUserByID", "api/v1/users.go", 10),
+    _node("Function", "getUserCache", "api/v1/users.go", 30),
+    _node("Function", "get_user_by_id", "app/users.py", 1),
+    _node("Function", "fetch_user_by_id", "app/users.py", 10),
+    _node("Function", "_get_user", "app/users.py", 20),
+    _node("Function", "get_user_by_id", "tests/test_users.py", 1),
+    _node("Function", "test_get_user_by_id", "tests/test_users.py", 10, is_test=True),
+    _node("Function", "GetUserById", "gen/users.pb.go", 5, generated=True),
+    _node("Class", "HTTPServer", "app/server.py", 1),
+    _node("Function", "parse", "app/config.py", 1),
+    _node("Function", "parse_config", "app/config.py", 10),
+    _node("Function", "parse_config_file", "app/config.py", 20),
+    _node("File", "users.py", "app/users.py", 0),
+]
+
+
+@pytest.fixture
+def store():
+    store = InMemoryGraphStore()
+    store.batch_create_nodes(NODES)
+    return store
+
+
+def _ranking(store, query, **kwargs):
+    return [(entry["node"]["properties"]["name"], entry["node"]["properties"]["file_path"], entry["match"])
+            for entry in rank_symbols(store, query, **kwargs)]
+
+
+@pytest.mark.parametrize("name, words", [
+    ("getUserByID", ["get", "user", "by", "id"]),
+    ("HTTPServer", ["http", "server"]),
+    ("parse_config-file", ["parse", "config", "file"]),
+    ("__init__", ["init"]),
+    ("parseJSON2", ["parse", "json", "2"]),
+])
+def test_split_words(name, words):
+    assert split_words(name) == words
+
+
+@pytest.mark.parametrize("query, name, expected", [
+    ("parse", "parse", "exact"),
+    ("httpserver", "HTTPServer", "case_insensitive"),
+    ("getUserByID", "get_user_by_id", "normalized"),
+    ("parse", "parse_config", "prefix"),
+    ("config", "parse_config", "substring"),
+    ("user_by_name", "find_user_by_id", "words"),
+    ("getUsrByID", "GetUserByID", "edit_distance"),
+    ("getsuerbyid", "get_user_by_id", "edit_distance"),
+    ("gubi", "GetUserByID", "subsequence"),
+    ("pasre", "parse", "edit_distance"),
+    ("xyz", "GetUserByID", None),
+    # Queries under four characters get no typo tolerance
+    ("xet", "get", None),
+])
+def test_match_name(query, name, expected):
+    match = match_name(query, name)
+    
+    assert (match[0] if match else None) == expected
+
+
+def test_edit_tolerance():
+    assert match_name("getUsrByD", "GetUserByID")[0] == "edit_distance"
+    # Two edits away: only the shared words match with one edit allowed, or with typos off
+    assert match_name("getUsrByD", "GetUserByID", max_edits=1)[0] == "words"
+    assert match_name("getUsrByID", "GetUserByID", max_edits=0)[0] == "words"
+
+
+RANKINGS = [
+    # Another case wins over another convention; hand-written source over generated and test code
+    ("getUserByID", {}, [
+        ("GetUserByID", "api/v1/users.go", "case_insensitive"),
+        ("get_user_by_id", "app/users.py", "normalized"),
+        ("GetUserById", "gen/users.pb.go", "case_insensitive"),
+        ("get_user_by_id", "tests/test_users.py", "normalized"),
+        ("fetch_user_by_id", "app/users.py", "words"),
+        ("test_get_user_by_id", "tests/test_users.py", "words"),
+        ("_get_user", "app/users.py", "words"),
+        ("getUserCache", "api/v1/users.go", "words"),
+    ]),
+    # Exact names only when there are any
+    ("get_user_by_id", {}, [
+        ("get_user_by_id", "app/users.py", "exact"),
+        ("get_user_by_id", "tests/test_users.py", "exact"),
+    ]),
+    # A matching package outranks an exact name elsewhere
+    ("get_user_by_id", {"match": "fuzzy", "package": "api/v1"}, [
+        ("GetUserByID", "api/v1/users.go", "normalized"),
+        ("get_user_by_id", "app/users.py", "exact"),
+        ("get_user_by_id", "tests/test_users.py", "exact"),
+        ("GetUserById", "gen/users.pb.go", "normalized"),
+        ("fetch_user_by_id", "app/users.py", "words"),
+        ("getUserCache", "api/v1/users.go", "words"),
+        ("test_get_user_by_id", "tests/test_users.py", "words"),
+        ("_get_user", "app/users.py", "words"),
+    ]),
+    ("getUsrByID", {}, [
+        ("GetUserByID", "api/v1/users.go", "edit_distance"),
+        ("get_user_by_id", "app/users.py", "edit_distance"),
+        ("GetUserById", "gen/users.pb.go", "edit_distance"),
+        ("get_user_by_id", "tests/test_users.py", "edit_distance"),
+        ("fetch_user_by_id", "app/users.py", "words"),
+        ("test_get_user_by_id", "tests/test_users.py", "words"),
+    ]),
+    ("gubi", {}, [
+        ("GetUserByID", "api/v1/users.go", "subsequence"),
+        ("get_user_by_id", "app/users.py", "subsequence"),
+        ("GetUserById", "gen/users.pb.go", "subsequence"),
+        ("get_user_by_id", "tests/test_users.py", "subsequence"),
+        ("test_get_user_by_id", "tests/test_users.py", "subsequence"),
+    ]),
+    # Unexported getUserCache falls behind generated and test code
+    ("getUser", {}, [
+        ("_get_user", "app/users.py", "normalized"),
+        ("GetUserByID", "api/v1/users.go", "prefix"),
+        ("get_user_by_id", "app/users.py", "prefix"),
+        ("GetUserById", "gen/users.pb.go", "prefix"),
+        ("get_user_by_id", "tests/test_users.py", "prefix"),
+        ("getUserCache", "api/v1/users.go", "prefix"),
+        ("fetch_user_by_id", "app/users.py", "words"),
+        ("test_get_user_by_id", "tests/test_users.py", "substring"),
+    ]),
+    ("parse", {"match": "fuzzy"}, [
+        ("parse", "app/config.py", "exact"),
+        ("parse_config", "app/config.py", "prefix"),
+        ("parse_config_file", "app/config.py", "prefix"),
+    ]),
+    ("http_server", {"node_type": "Class"}, [
+        ("HTTPServer", "app/server.py", "normalized"),
+    ]),
+    # File nodes are no symbols
+    ("users", {"match": "fuzzy"}, []),
+]
+
+
+@pytest.mark.parametrize("query, kwargs, expected", RANKINGS, ids=[
+    "conventions", "exact", "package", "typo", "subsequence", "prefix", "parse", "node_type", "files",
+])
+def test_ranking(store, query, kwargs, expected):
+    assert _ranking(store, query, **kwargs) == expected
+
+
+def test_scores(store):
+    ranked = rank_symbols(store, "getUserByID")
+    scores = [entry["score"] for entry in ranked]
+    
+    assert scores == sorted(scores, reverse=True)
+    assert all(0 < score <= 1 for score in scores)
+    (exact,) = rank_symbols(store, "GetUserByID")
+    assert (exact["match"], exact["score"]) == ("exact", 1.0)
+    # A symbol ID is matched exactly
+    store.batch_create_nodes([_node("Function", "load", "app/config.py", 30, symbol_id="python:config.load")])
+    (by_id,) = rank_symbols(store, "python:config.load")
+    assert (by_id["match"], by_id["node"]["properties"]["name"]) == ("id", "load")
+    with pytest.raises(ValueError):
+        rank_symbols(store, "parse", match="prefix")
+
+
+def test_find_symbol_tool(store, make_server):
+    server = make_server(store=store)
+    
+    def find_symbol(**kwargs):
+        return json.loads(asyncio.run(server.mcp.tools["find_symbol"](**kwargs)))
+    
+    symbols = find_symbol(name="getUserByID")
+    
+    # Generated code is left out unless asked for
+    assert [(symbol["name"], symbol["match"]) for symbol in symbols] == [
+        ("GetUserByID", "case_insensitive"), ("get_user_by_id", "normalized"), ("get_user_by_id", "normalized"),
+        ("fetch_user_by_id", "words"), ("test_get_user_by_id", "words"), ("_get_user", "words"),
+        ("getUserCache", "words"),
+    ]
+    assert symbols[0]["score"] > symbols[1]["score"]
+    page = find_symbol(name="getUserByID", offset=1, limit=2)
+    assert [symbol["id"] for symbol in page] == [symbol["id"] for symbol in symbols[1:3]]
+    assert len(find_symbol(name="getUserByID", include_generated=True)) == 8
+    assert find_symbol(name="getUserByID", match="exact") == []
+    assert {symbol["match"] for symbol in find_symbol(name="getUsrByID", max_edits=0)} == {"words"}
+    assert "error" in find_symbol(name="parse", match="prefix")
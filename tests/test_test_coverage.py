@@ -0,0 +1,385 @@
+"""
+TESTS edge and test coverage tests.
+
+link_tests is checked on hand-built nodes: direct calls, calls through an
+interface and symbols replaced with @patch. get_tests_for and
+get_untested run on a Go call graph seeded into an InMemoryGraphStore,
+with one test reaching Store.Save over two hops, one only through the
+Saver interface and one whose callee is mocked. The end-to-end tests
+index a small pytest codebase, full and incremental. Jest test nodes
+need ast-grep.
+"""
+
+import asyncio
+import json
+import os
+import sys
+
+import pytest
+
+# Add project root to Python path
+sys.path.insert(0, os.path.dirname(os.path.dirname(os.path.abspath(__file__))))
+
+from src.ast_parser.parser import CodeNode, CodeRelation
+from src.graph_store import InMemoryGraphStore
+from src.indexing.testcode import annotate_tests, link_tests
+from src.mcp.coverage import get_tests_for, get_untested
+from conftest import index_tree, write_tree
+
+
+def _node(node_type, name, file_path, **properties):
+    return CodeNode(f"{node_type}:{file_path}:{name}:1", node_type, name, file_path, 1, properties=properties)
+
+
+def _edges(relations):
+    return {(r.source_id.split(":")[2], r.target_id.split(":")[2]): r.properties for r in relations}
+
+
+class TestLinkTests:
+    
+    def test_direct_calls_of_production_code(self):
+        test = _node("Function", "TestSave", "pkg/store_test.go", is_test=True)
+        save = _node("Method", "Save", "pkg/store.go")
+        fixture = _node("Function", "newFixture", "pkg/store_test.go")
+        nodes = {node.node_id: node for node in (test, save, fixture)}
+        relations = [
+            CodeRelation(test.node_id, save.node_id, "CALLS", {"line_no": 8, "call_lines": [8, 12]}),
+            CodeRelation(test.node_id, fixture.node_id, "CALLS", {"line_no": 7}),
+            CodeRelation(save.node_id, fixture.node_id, "CALLS"),
+        ]
+        
+        edges = _edges(link_tests(nodes, relations))
+        
+        # Helpers of the test file are not production code
+        assert edges == {("TestSave", "Save"): {"source": "heuristic:test_link", "confidence": 0.9,
+                                                  "line_no": 8, "call_lines": [8, 12]}}
+    
+    def test_calls_through_an_interface_are_low_confidence(self):
+        test = _node("Function", "TestFlush", "pkg/service_test.go", is_test=True)
+        nodes = {test.node_id: test}
+        relations = [CodeRelation(test.node_id, "Interface:pkg/store.go:Saver:3", "CALLS",
+                                  {"line_no": 6, "method": "Save", "via_interface": True}),
+                     CodeRelation(test.node_id, "Class:pkg/store.go:Store:7", "CALLS")]
+        
+        # Targets of files not re-parsed are known from node_files
+        edges = _edges(link_tests(nodes, relations, {"Interface:pkg/store.go:Saver:3": "pkg/store.go",
+                                                     "Class:pkg/store.go:Store:7": "pkg/store.go"}))
+        
+        assert edges == {("TestFlush", "Saver"): {"source": "heuristic:test_link", "confidence": 0.5,
+                                                  "reason": "interface", "method": "Save", "line_no": 6}}
+    
+    def test_patched_symbols_are_low_confidence(self):
+        mailer = _node("Class", "Mailer", "app/mail.py")
+        send = _node("Method", "send", "app/mail.py")
+        notify = _node("Function", "notify", "app/mail.py")
+        render = _node("Function", "render", "app/views.py")
+        test = _node("Function", "test_notify", "tests/test_mail.py", is_test=True,
+                     decorators=['@patch("app.mail.notify")', '@mock.patch.object(Mailer, "send")'])
+        nodes = {node.node_id: node for node in (mailer, send, notify, render, test)}
+        relations = [CodeRelation(mailer.node_id, send.node_id, "DEFINES")] + [
+            CodeRelation(test.node_id, target.node_id, "CALLS") for target in (send, notify, render)
+        ]
+        
+        edges = _edges(link_tests(nodes, relations))
+        
+        assert edges[("test_notify", "notify")] == {"source": "heuristic:test_link", "confidence": 0.5,
+                                                    "reason": "mocked"}
+        assert edges[("test_notify", "send")] == {"source": "heuristic:test_link", "confidence": 0.5,
+                                                  "reason": "mocked"}
+        assert edges[("test_notify", "render")] == {"source": "heuristic:test_link", "confidence": 0.9}
+    
+    def test_parametrized_and_jest_tests_are_tagged(self):
+        cases = _node("Function", "test_total", "tests/test_cart.py",
+                      decorators=['@pytest.mark.parametrize("items, total", CASES)'])
+        jest = _node("Function", "Cart > total > sums items", "web/cart.test.ts", test_framework="jest")
+        helper = _node("Function", "makeCart", "web/cart.test.ts")
+        nodes = {node.node_id: node for node in (cases, jest, helper)}
+        
+        tagged = annotate_tests(nodes, [])
+        
+        assert tagged == {cases.node_id, jest.node_id}
+        assert cases.properties["parametrized"] is True
+        assert "parametrized" not in jest.properties
+
+
+NODES = {
+    "interface:app/store.go:Saver:3": ("Saver", "Interface", "app/store.go", {"methods": ["Save(key string) error"]}),
+    "class:app/store.go:Store:7": ("Store", "Class", "app/store.go", {}),
+    "method:app/store.go:Save:9": ("Save", "Method", "app/store.go", {}),
+    "function:app/service.go:Persist:3": ("Persist", "Function", "app/service.go", {}),
+    "function:app/service.go:Flush:9": ("Flush", "Function", "app/service.go", {}),
+    "function:app/service.go:Notify:15": ("Notify", "Function", "app/service.go", {}),
+    "function:app/service.go:Orphan:21": ("Orphan", "Function", "app/service.go", {}),
+    "function:app/service.go:validate:27": ("validate", "Function", "app/service.go", {}),
+    "function:app/service_test.go:TestPersist:5": ("TestPersist", "Function", "app/service_test.go",
+                                                   {"is_test": True, "parametrized": True}),
+    "function:app/service_test.go:TestFlush:15": ("TestFlush", "Function", "app/service_test.go", {"is_test": True}),
+    "function:app/service_test.go:TestNotify:25": ("TestNotify", "Function", "app/service_test.go", {"is_test": True}),
+    "package:app": ("app", "Package", None, {"import_path": "example.com/app", "path": "app"}),
+}
+
+EDGES = [
+    ("class:app/store.go:Store:7", "method:app/store.go:Save:9", "DEFINES", {}),
+    ("class:app/store.go:Store:7", "interface:app/store.go:Saver:3", "IMPLEMENTS", {"via": "pointer"}),
+    ("function:app/service.go:Persist:3", "method:app/store.go:Save:9", "CALLS", {"line_no": 4}),
+    ("function:app/service.go:Flush:9", "interface:app/store.go:Saver:3", "CALLS",
+     {"line_no": 10, "method": "Save", "via_interface": True}),
+    ("function:app/service.go:Notify:15", "function:app/service.go:validate:27", "CALLS", {"line_no": 16}),
+    ("function:app/service_test.go:TestPersist:5", "function:app/service.go:Persist:3", "CALLS", {"line_no": 6}),
+    ("function:app/service_test.go:TestPersist:5", "function:app/service.go:Persist:3", "TESTS",
+     {"confidence": "high", "line_no": 6}),
+    ("function:app/service_test.go:TestFlush:15", "function:app/service.go:Flush:9", "CALLS", {"line_no": 16}),
+    ("function:app/service_test.go:TestFlush:15", "function:app/service.go:Flush:9", "TESTS",
+     {"confidence": "high", "line_no": 16}),
+    ("function:app/service_test.go:TestNotify:25", "function:app/service.go:Notify:15", "CALLS", {"line_no": 26}),
+    ("function:app/service_test.go:TestNotify:25", "function:app/service.go:Notify:15", "TESTS",
+     {"confidence": "low", "reason": "mocked"}),
+    ("package:app", "file:app/store.go", "CONTAINS", {}),
+    ("package:app", "file:app/service.go", "CONTAINS", {}),
+    ("package:app", "file:app/service_test.go", "CONTAINS", {}),
+]
+
+
+def _coverage_store():
+    """A store holding NODES, EDGES and a File node per file."""
+    store = InMemoryGraphStore()
+    file_paths = {file_path for _, _, file_path, _ in NODES.values() if file_path}
+    store.batch_create_nodes([
+        {"labels": ["Base", "File"], "properties": {"id": f"file:{path}", "name": os.path.basename(path),
+                                                    "file_path": path, "line_no": 0}}
+        for path in sorted(file_paths)
+    ] + [
+        {"labels": ["Base", node_type],
+         "properties": dict(properties, id=node_id, name=name, file_path=file_path,
+                            line_no=int(node_id.split(":")[-1]) if file_path else 0)}
+        for node_id, (name, node_type, file_path, properties) in NODES.items()
+    ])
+    store.batch_create_relationships([
+        {"start_node_id": source, "end_node_id": target, "type": rel_type, "properties": properties}
+        for source, target, rel_type, properties in EDGES
+    ])
+    return store
+
+
+def _tests(result):
+    return [(test["name"], test["distance"], test["confidence"], test.get("reason")) for test in result["tests"]]
+
+
+class TestGetTestsFor:
+    
+    def test_tests_within_the_call_hops(self):
+        result = get_tests_for(_coverage_store(), "Store.Save")
+        
+        assert result["status"] == "ok"
+        assert _tests(result) == [("TestPersist", 2, "high", None), ("TestFlush", 2, "low", "interface")]
+        assert (result["total"], result["high_confidence"], result["more"]) == (2, 1, 0)
+        persist = result["tests"][0]
+        assert (persist["through"], persist["parametrized"]) == ("app.Persist", True)
+    
+    def test_direct_tests(self):
+        result = get_tests_for(_coverage_store(), "Persist")
+        
+        assert _tests(result) == [("TestPersist", 1, "high", None)]
+        assert "through" not in result["tests"][0]
+    
+    def test_mocked_callees_are_low_confidence(self):
+        result = get_tests_for(_coverage_store(), "validate")
+        
+        assert _tests(result) == [("TestNotify", 2, "low", "mocked")]
+    
+    def test_depth_and_results_limits(self):
+        db = _coverage_store()
+        
+        assert get_tests_for(db, "Store.Save", max_depth=1)["tests"] == []
+        capped = get_tests_for(db, "Store.Save", max_results=1)
+        assert _names(capped["tests"]) == ["TestPersist"]
+        assert (capped["more"], capped["truncated"]) == (1, True)
+    
+    def test_invalid_arguments_and_unknown_symbols(self):
+        db = _coverage_store()
+        
+        with pytest.raises(ValueError, match="max_depth"):
+            get_tests_for(db, "Persist", max_depth=0)
+        with pytest.raises(ValueError, match="max_results"):
+            get_tests_for(db, "Persist", max_results=0)
+        assert get_tests_for(db, "Delete")["status"] == "not_found"
+
+
+def _names(entries):
+    return [entry["name"] for entry in entries]
+
+
+class TestGetUntested:
+    
+    def test_untested_exported_symbols(self):
+        result = get_untested(_coverage_store(), "example.com/app")
+        
+        assert result["status"] == "ok"
+        assert result["packages"] == ["example.com/app"]
+        # validate is not exported, the tests are test code
+        assert result["symbol_count"] == 5
+        assert _names(result["untested"]) == ["Orphan"]
+        assert [(entry["name"], entry["reason"]) for entry in result["low_confidence"]] == [("Notify", "mocked")]
+        assert result["covered"] == 3
+    
+    def test_depth_limits_coverage(self):
+        result = get_untested(_coverage_store(), "app", max_depth=1)
+        
+        assert _names(result["untested"]) == ["Orphan", "Save"]
+    
+    def test_unknown_package(self):
+        assert get_untested(_coverage_store(), "example.com/other")["status"] == "not_found"
+
+
+CODEBASE = {
+    "shop/__init__.py": "",
+    "shop/pricing.py": (
+        "def price(items):\n"
+        "    return sum(items)\n"
+    ),
+    "shop/cart.py": (
+        "from shop.pricing import price\n"
+        "\n"
+        "\n"
+        "def checkout(items):\n"
+        "    return price(items) * 2\n"
+        "\n"
+        "\n"
+        "def refund(items):\n"
+        "    return -price(items)\n"
+    ),
+    "shop/mail.py": (
+        "def send(address):\n"
+        "    return address\n"
+    ),
+    "tests/__init__.py": "",
+    "tests/test_cart.py": (
+        "import pytest\n"
+        "from unittest.mock import patch\n"
+        "from shop.cart import checkout\n"
+        "from shop.pricing import price\n"
+        "from shop.mail import send\n"
+        "\n"
+        "\n"
+        "@pytest.mark.parametrize(\"items, total\", [([1], 2), ([], 0)])\n"
+        "def test_checkout(items, total):\n"
+        "    assert checkout(items) == total\n"
+        "\n"
+        "\n"
+        "@patch(\"shop.mail.send\")\n"
+        "def test_send(mocked):\n"
+        "    assert send(\"a\") == mocked.return_value\n"
+    ),
+}
+
+
+def _stored_tests_edges(db):
+    tests = [record["properties"]["id"] for record in db.find_nodes(label="Function")
+             if record["properties"].get("is_test")]
+    return {(row["origin_id"].split(":")[-2], row["node"]["properties"]["name"],
+             row["relationship"]["properties"]["confidence"])
+            for row in db.neighbors(tests, ["TESTS"], direction="out")}
+
+
+class TestIndexedCoverage:
+    
+    def test_tests_edges(self, legacy_env, tmp_path):
+        write_tree(tmp_path, CODEBASE)
+        db = index_tree(tmp_path).db
+        
+        assert _stored_tests_edges(db) == {("test_checkout", "checkout", 0.9), ("test_send", "send", 0.5)}
+        (test_checkout,) = db.find_nodes(name="test_checkout")
+        assert test_checkout["properties"]["parametrized"] is True
+    
+    def test_tests_for_and_untested(self, legacy_env, tmp_path):
+        write_tree(tmp_path, CODEBASE)
+        db = index_tree(tmp_path).db
+        
+        result = get_tests_for(db, "price")
+        assert _tests(result) == [("test_checkout", 2, "high", None)]
+        assert result["tests"][0]["through"] == "cart.checkout"
+        
+        untested = get_untested(db, "shop")
+        assert _names(untested["untested"]) == ["refund"]
+        assert [(entry["name"], entry["reason"]) for entry in untested["low_confidence"]] == [("send", "mocked")]
+    
+    def test_incremental_run_matches_full_rebuild(self, legacy_env, tmp_path):
+        write_tree(tmp_path, CODEBASE)
+        store = InMemoryGraphStore()
+        index_tree(tmp_path, store)
+        
+        (tmp_path / "tests/test_cart.py").write_text(
+            CODEBASE["tests/test_cart.py"] + "\n\ndef test_price():\n    assert price([1]) == 1\n", encoding="utf-8")
+        index_tree(tmp_path, store, incremental=True)
+        
+        full = index_tree(tmp_path).db
+        assert _stored_tests_edges(store) == _stored_tests_edges(full)
+        assert ("test_price", "price", 0.9) in _stored_tests_edges(store)
+
+
+JEST_SOURCE = '''\
+import { total } from "./cart";
+
+describe("Cart", () => {
+  describe("total", () => {
+    it("sums items", () => {
+      expect(total([1, 2])).toBe(3);
+    });
+    
+    it.each([[1], [2]])("handles %i", (n) => {
+      expect(total([n])).toBe(n);
+    });
+  });
+});
+
+function helper() {
+  it("is not registered", () => {});
+}
+'''
+
+
+class TestJest:
+    
+    @pytest.fixture
+    def jest_graph(self, tmp_path):
+        pytest.importorskip("ast_grep_py")
+        from src.ast_parser.multi_parser import MultiLanguageParser
+        
+        write_tree(tmp_path, {"src/cart.ts": "export function total(items: number[]) {\n  return 0;\n}\n",
+                          "src/cart.test.ts": JEST_SOURCE})
+        coordinator = MultiLanguageParser(use_ast_grep=True, ast_grep_languages=["typescript"],
+                                          ast_grep_fallback=False)
+        nodes, relations = coordinator.parse_directory(str(tmp_path), build_index=True)
+        annotate_tests(nodes, relations)
+        return nodes, relations + link_tests(nodes, relations)
+    
+    def test_test_nodes(self, jest_graph):
+        nodes, _ = jest_graph
+        
+        tests = {node.name: node.properties for node in nodes.values() if node.properties.get("test_framework")}
+        assert sorted(tests) == ["Cart > total > handles %i", "Cart > total > sums items"]
+        assert tests["Cart > total > sums items"]["describe"] == ["Cart", "total"]
+        assert tests["Cart > total > handles %i"]["parametrized"] is True
+        assert all(properties["is_test"] for properties in tests.values())
+    
+    def test_tests_edges(self, jest_graph):
+        nodes, relations = jest_graph
+        
+        edges = {(nodes[r.source_id].name, r.properties["line_no"]) for r in relations if r.relation_type == "TESTS"}
+        assert edges == {("Cart > total > sums items", 6), ("Cart > total > handles %i", 10)}
+
+
+class TestCoverageTools:
+    
+    @pytest.fixture
+    def tools(self, make_server):
+        server = make_server(store=_coverage_store())
+        return lambda name, **kwargs: json.loads(asyncio.run(server.mcp.tools[name](**kwargs)))
+    
+    def test_structured_results(self, tools):
+        assert _names(tools("get_tests_for", symbol="Persist")["tests"]) == ["TestPersist"]
+        assert _names(tools("get_untested", package="example.com/app")["untested"]) == ["Orphan"]
+    
+    def test_errors_are_returned(self, tools):
+        assert "error" in tools("get_tests_for", symbol="Persist", max_depth=20)
+        assert "error" in tools("get_untested", package="app", max_depth=0)
@@ -0,0 +1,152 @@
+"""
+get_type_members tests for Go embedding.
+
+The types, their members and EMBEDS edges are seeded into an
+InMemoryGraphStore; promoted methods are collected by following the edges
+transitively.
+"""
+
+import asyncio
+import json
+import os
+import sys
+from unittest.mock import patch
+
+import pytest
+
+# Add project root to Python path
+sys.path.insert(0, os.path.dirname(os.path.dirname(os.path.abspath(__file__))))
+
+from src.graph_store import InMemoryGraphStore
+from src.mcp.type_members import collect_promoted_members
+
+
+def _method(name, file_path, line_no, receiver_kind="pointer"):
+    return {"id": f"method:{name}:{line_no}", "name": name, "kind": "Method",
+            "file_path": file_path, "line_no": line_no, "receiver_kind": receiver_kind}
+
+
+# type id -> node properties and declared members
+TYPES = {
+    "class:Server": {"name": "Server", "labels": ["Base", "Class"], "file_path": "server/server.go", "line_no": 12,
+                     "members": [_method("Close", "server/server.go", 20)]},
+    "class:Core": {"name": "Core", "labels": ["Base", "Class"], "file_path": "server/server.go", "line_no": 5,
+                   "members": [_method("Start", "server/server.go", 10, "value"),
+                               _method("Close", "server/server.go", 11)]},
+    "class:Logger": {"name": "Logger", "labels": ["Base", "Class"], "file_path": "base/base.go", "line_no": 3,
+                     "members": [_method("Log", "base/base.go", 5), _method("Reset", "base/base.go", 6)]},
+    "interface:Store": {"name": "Store", "labels": ["Base", "Interface"], "file_path": "base/base.go", "line_no": 8,
+                        "methods": ["Get(string) string", "Reset()"], "members": []},
+}
+
+# (outer, inner, embed_kind); Logger embedding Core closes a cycle
+EMBEDS = [
+    ("class:Server", "class:Core", "value"),
+    ("class:Server", "interface:Store", "value"),
+    ("class:Core", "class:Logger", "pointer"),
+    ("class:Logger", "class:Core", "value"),
+]
+
+
+class FakeEmbedsDatabase(InMemoryGraphStore):
+    """An in-memory store holding TYPES and EMBEDS that counts EMBEDS lookups."""
+    
+    def __init__(self):
+        super().__init__()
+        self.embeds_lookups = 0
+        nodes, relationships = [], []
+        for type_id, props in TYPES.items():
+            nodes.append({"labels": props["labels"],
+                          "properties": {"id": type_id, "name": props["name"], "file_path": props["file_path"],
+                                         "line_no": props["line_no"], "methods": props.get("methods")}})
+            for member in props["members"]:
+                nodes.append({"labels": ["Base", member["kind"]],
+                              "properties": {key: member[key] for key in ("id", "name", "file_path", "line_no")}})
+                relationships.append({"start_node_id": type_id, "end_node_id": member["id"], "type": "DEFINES",
+                                      "properties": {}})
+                relationships.append({"start_node_id": member["id"], "end_node_id": type_id, "type": "METHOD_OF",
+                                      "properties": {"receiver_kind": member["receiver_kind"]}})
+        for outer, inner, kind in EMBEDS:
+            relationships.append({"start_node_id": outer, "end_node_id": inner, "type": "EMBEDS",
+                                  "properties": {"embed_kind": kind, "original_name": TYPES[inner]["name"]}})
+        self.batch_create_nodes(nodes)
+        self.batch_create_relationships(relationships)
+    
+    def neighbors(self, node_ids, relation_types=None, direction="out", label=None):
+        if relation_types == ["EMBEDS"]:
+            self.embeds_lookups += 1
+        return super().neighbors(node_ids, relation_types, direction, label)
+
+
+def _by_name(members):
+    return {member["name"]: member for member in members}
+
+
+class TestPromotedMembers:
+    """Transitive EMBEDS traversal."""
+    
+    def test_promoted_through_pointer_embed(self):
+        result = collect_promoted_members(FakeEmbedsDatabase(), "class:Server", TYPES["class:Server"]["members"])
+        promoted = _by_name(result["promoted"])
+        
+        assert promoted["Start"]["promoted_from"] == "Core"
+        assert promoted["Start"]["depth"] == 1
+        assert promoted["Log"]["via"] == ["Core", "Logger"]
+        assert promoted["Log"]["depth"] == 2
+        assert promoted["Get"]["signature"] == "Get(string) string"
+        # Embedded types are listed by location
+        assert [(e["name"], e["kind"], e["embed_kind"]) for e in result["embeds"]] == [
+            ("Store", "Interface", "value"), ("Core", "Class", "value"),
+        ]
+    
+    def test_shadowing_and_ambiguity(self):
+        result = collect_promoted_members(FakeEmbedsDatabase(), "class:Server", TYPES["class:Server"]["members"])
+        promoted = _by_name(result["promoted"])
+        
+        # Server.Close shadows Core.Close
+        assert "Close" not in promoted
+        # Store.Reset at depth 1 shadows Logger.Reset at depth 2
+        assert promoted["Reset"]["promoted_from"] == "Store"
+    
+    def test_same_depth_conflict_is_not_promoted(self):
+        with patch.dict(TYPES, {"class:Core": {**TYPES["class:Core"], "members": [_method("Get", "server/server.go", 10)]}}):
+            db = FakeEmbedsDatabase()
+            promoted = _by_name(collect_promoted_members(db, "class:Server", [])["promoted"])
+        
+        assert "Get" not in promoted
+        assert "Log" in promoted
+    
+    def test_cycle_terminates(self):
+        db = FakeEmbedsDatabase()
+        result = collect_promoted_members(db, "class:Core", TYPES["class:Core"]["members"])
+        
+        assert [m["name"] for m in result["promoted"]] == ["Log", "Reset"]
+        # Core -> Logger -> Core: the second level finds only visited types
+        assert db.embeds_lookups == 2
+    
+    def test_max_depth(self):
+        result = collect_promoted_members(FakeEmbedsDatabase(), "class:Server", [], max_depth=1)
+        
+        assert "Log" not in _by_name(result["promoted"])
+
+
+class TestGetTypeMembersTool:
+    """The MCP tool adds embeds and promoted members to each type."""
+    
+    @pytest.fixture
+    def get_type_members(self, make_server):
+        server = make_server(store=FakeEmbedsDatabase())
+        return server.mcp.tools["get_type_members"]
+    
+    def test_promoted_members_in_response(self, get_type_members):
+        result = json.loads(asyncio.run(get_type_members("Server")))
+        
+        assert len(result) == 1
+        assert {m["name"] for m in result[0]["members"]} == {"Close"}
+        assert {m["name"] for m in result[0]["promoted"]} == {"Start", "Log", "Get", "Reset"}
+        assert [e["name"] for e in result[0]["embeds"]] == ["Store", "Core"]
+    
+    def test_declared_members_only(self, get_type_members):
+        result = json.loads(asyncio.run(get_type_members("Server", include_promoted=False)))
+        
+        assert "promoted" not in result[0]
@@ -0,0 +1,301 @@
+"""
+TypeScript adapter tests.
+
+Module resolution is checked against small trees in tmp_path. The adapter
+tests parse the TypeScript files in tests/fixtures/multi_lang_sample
+through MultiLanguageParser (so re-exports and imports are resolved in the
+second pass): sample.ts imports through the ts_lib index file and the
+`@lib/*` alias from tsconfig.json, and ShapeCard.tsx holds React components.
+The same fixture is indexed with the default USE_AST_GREP=false, which
+routes TypeScript files to the adapter as well.
+"""
+
+import json
+import os
+import shutil
+import sys
+from unittest.mock import MagicMock
+
+import pytest
+
+# Add project root to Python path
+sys.path.insert(0, os.path.dirname(os.path.dirname(os.path.abspath(__file__))))
+
+from src.ast_parser.adapters.typescript_adapter import TypeScriptAdapter
+from src.ast_parser.ts_module_resolver import TsModuleResolver, load_jsonc
+from src.ast_parser.typescript_parser import TypeScriptParser
+from src.graph_store import InMemoryGraphStore
+from src.parallel.pipeline import ParserSettings, create_parser
+
+
+FIXTURE_DIR = os.path.join(os.path.dirname(os.path.abspath(__file__)), "fixtures", "multi_lang_sample")
+
+
+def _write(root, relative_path, content=""):
+    path = root / relative_path
+    path.parent.mkdir(parents=True, exist_ok=True)
+    path.write_text(content)
+    return str(path)
+
+
+class TestTsModuleResolver:
+    """Specifier to file resolution."""
+    
+    def test_relative_index_and_esm_extension(self, tmp_path):
+        importer = _write(tmp_path, "src/main.ts")
+        user = _write(tmp_path, "src/user.ts")
+        models = _write(tmp_path, "src/models/index.ts")
+        widget = _write(tmp_path, "src/widget.tsx")
+        resolver = TsModuleResolver()
+        
+        assert resolver.resolve("./user", importer) == user
+        assert resolver.resolve("./user.js", importer) == user
+        assert resolver.resolve("./models", importer) == models
+        assert resolver.resolve("./widget", importer) == widget
+        assert resolver.resolve("../src/user", importer) == user
+        assert resolver.resolve("./missing", importer) is None
+    
+    def test_paths_from_extended_config(self, tmp_path):
+        _write(tmp_path, "tsconfig.base.json", """{
+            // paths without baseUrl are relative to this file
+            "compilerOptions": {"paths": {"@app/*": ["src/app/*"], "config": ["src/config.ts"],}},
+        }""")
+        _write(tmp_path, "tsconfig.json", '{"extends": "./tsconfig.base", "compilerOptions": {"strict": true}}')
+        importer = _write(tmp_path, "src/features/page.ts")
+        service = _write(tmp_path, "src/app/services/user.service.ts")
+        config = _write(tmp_path, "src/config.ts")
+        resolver = TsModuleResolver()
+        
+        assert resolver.resolve("@app/services/user.service", importer) == service
+        assert resolver.resolve("config", importer) == config
+        assert resolver.resolve("react", importer) is None
+    
+    def test_base_url(self, tmp_path):
+        _write(tmp_path, "tsconfig.json", '{"compilerOptions": {"baseUrl": "src"}}')
+        importer = _write(tmp_path, "src/a/b.ts")
+        helpers = _write(tmp_path, "src/utils/helpers/index.ts")
+        
+        assert TsModuleResolver().resolve("utils/helpers", importer) == helpers
+    
+    def test_jsonc_keeps_strings(self):
+        assert load_jsonc('{"url": "http://x//y", /* note */ "list": [1, 2,],}') == {
+            "url": "http://x//y", "list": [1, 2],
+        }
+
+
+class TestTypeScriptAdapter:
+    """Declarations, imports, decorators and components from the fixture."""
+    
+    @pytest.fixture(scope="class")
+    def ts_results(self):
+        """Parse the TypeScript fixture files with the TypeScript adapter enabled."""
+        pytest.importorskip("ast_grep_py")
+        from src.ast_parser.multi_parser import MultiLanguageParser
+        
+        coordinator = MultiLanguageParser(
+            use_ast_grep=True,
+            ast_grep_languages=['typescript'],
+            ast_grep_fallback=False
+        )
+        return coordinator.parse_directory(FIXTURE_DIR, build_index=True)
+    
+    def _node(self, nodes, node_type, name):
+        matches = [n for n in nodes.values() if n.node_type == node_type and n.name == name]
+        assert len(matches) == 1, f"expected one {node_type} {name}, got {len(matches)}"
+        return matches[0]
+    
+    def _targets(self, nodes, relations, source, relation_type):
+        return {
+            (nodes[r.target_id].node_type, nodes[r.target_id].name)
+            for r in relations
+            if r.source_id == source.node_id and r.relation_type == relation_type and r.target_id in nodes
+        }
+    
+    def test_interfaces_aliases_and_enums(self, ts_results):
+        nodes, _ = ts_results
+        
+        shape = self._node(nodes, "Interface", "Shape")
+        assert shape.properties["methods"] == ["area(): number"]
+        assert shape.properties["properties"] == ["kind: ShapeKind"]
+        assert shape.properties["extends"] == ["Named", "Entity"]
+        assert self._node(nodes, "TypeAlias", "ShapeKind").properties["type"] == "'circle' | 'square'"
+        assert self._node(nodes, "Enum", "Color").properties["members"] == ["Red", "Green", "Blue"]
+        assert shape.properties["exported"] is True
+    
+    def test_class_members(self, ts_results):
+        nodes, relations = ts_results
+        
+        repository = self._node(nodes, "Class", "Repository")
+        assert repository.properties["is_abstract"] is True
+        assert self._node(nodes, "Method", "save").properties["parent_class"] == "Repository"
+        
+        shape_repository = self._node(nodes, "Class", "ShapeRepository")
+        members = {n.name: n for n in (nodes[r.target_id] for r in relations
+                                       if r.source_id == shape_repository.node_id and r.relation_type == "DEFINES")}
+        assert set(members) == {"color", "constructor", "logger", "validate"}
+        assert members["color"].node_type == "ClassVariable"
+        assert members["color"].properties["annotation"] == "Color"
+        assert members["logger"].properties["parameter_property"] is True
+        assert members["logger"].properties["accessibility"] == "private"
+        assert members["logger"].properties["is_readonly"] is True
+        
+        abstract_validate = [n for n in nodes.values() if n.name == "validate" and n.properties.get("is_abstract")]
+        assert [n.properties["parent_class"] for n in abstract_validate] == ["Repository"]
+    
+    def test_imports_resolve_through_index_and_alias(self, ts_results):
+        nodes, relations = ts_results
+        sample = nodes["file:" + os.path.join(FIXTURE_DIR, "sample.ts")]
+        
+        imported_files = {nodes[r.target_id].file_path for r in relations
+                          if r.source_id == sample.node_id and r.relation_type == "IMPORTS_FROM"}
+        assert imported_files == {
+            os.path.join(FIXTURE_DIR, "ts_lib", "index.ts"),
+            os.path.join(FIXTURE_DIR, "ts_lib", "logger.ts"),
+            os.path.join(FIXTURE_DIR, "ts_lib", "decorators.ts"),
+        }
+        # Entity and Repository are re-exported by the index file
+        definitions = {r.target_id: r for r in relations
+                       if r.source_id == sample.node_id and r.relation_type == "IMPORTS_DEFINITION"}
+        assert {(nodes[t].name, os.path.basename(nodes[t].file_path)) for t in definitions} == {
+            ("Entity", "entity.ts"), ("Repository", "repository.ts"), ("Logger", "logger.ts"),
+        }
+    
+    def test_heritage(self, ts_results):
+        nodes, relations = ts_results
+        
+        assert self._targets(nodes, relations, self._node(nodes, "Class", "ShapeRepository"), "EXTENDS") == {
+            ("Class", "Repository")}
+        assert self._targets(nodes, relations, self._node(nodes, "Interface", "Shape"), "EXTENDS") == {
+            ("Interface", "Named"), ("Interface", "Entity")}
+        assert self._targets(nodes, relations, self._node(nodes, "Class", "ShapeComponent"), "IMPLEMENTS") == {
+            ("Interface", "Named")}
+    
+    def test_decorators(self, ts_results):
+        nodes, relations = ts_results
+        
+        shape_repository = self._node(nodes, "Class", "ShapeRepository")
+        assert shape_repository.properties["decorators"] == ["decorators.Injectable()"]
+        assert self._targets(nodes, relations, shape_repository, "DECORATED_BY") == {("Function", "Injectable")}
+        
+        color = self._node(nodes, "ClassVariable", "color")
+        assert color.properties["decorators"] == ["decorators.Column('shape_color')"]
+        assert self._targets(nodes, relations, color, "DECORATED_BY") == {("Function", "Column")}
+        
+        # @angular/core is a package: the decorator is recorded but not linked
+        component = self._node(nodes, "Class", "ShapeComponent")
+        assert component.properties["decorators"] == ["Component({ selector: 'app-shape' })"]
+        assert not self._targets(nodes, relations, component, "DECORATED_BY")
+    
+    def test_react_components(self, ts_results):
+        nodes, relations = ts_results
+        card_file = os.path.join(FIXTURE_DIR, "ShapeCard.tsx")
+        functions = {n.name: n for n in nodes.values() if n.node_type == "Function" and n.file_path == card_file}
+        
+        assert functions["ShapeCard"].properties.get("component") is True
+        assert functions["ShapeList"].properties.get("component") is True
+        # Lower-case helpers and functions that only build JSX in a nested function are not components
+        assert "component" not in functions["label"].properties
+        assert "component" not in functions["AreaText"].properties
+        
+        imported = {nodes[r.target_id].name for r in relations
+                    if r.source_id == "file:" + card_file and r.relation_type == "IMPORTS_DEFINITION"}
+        assert imported == {"Shape"}
+
+
+class TestDefaultPipeline:
+    """TypeScript files indexed without USE_AST_GREP."""
+    
+    def test_routing(self, tmp_path):
+        settings = ParserSettings()
+        
+        assert isinstance(create_parser(str(tmp_path / "a.ts"), settings), TypeScriptAdapter)
+        assert create_parser(str(tmp_path / "a.tsx"), settings).use_tsx is True
+        assert isinstance(create_parser(str(tmp_path / "a.js"), settings), TypeScriptParser)
+    
+    @pytest.fixture
+    def store(self, monkeypatch, tmp_path):
+        """Index a copy of the fixture directory with the default settings."""
+        pytest.importorskip("ast_grep_py")
+        monkeypatch.setenv("USE_AST_GREP", "false")
+        from src.main import CodebaseKnowledgeGraph
+        
+        codebase = tmp_path / "multi_lang_sample"
+        shutil.copytree(FIXTURE_DIR, codebase)
+        store = InMemoryGraphStore()
+        kg = CodebaseKnowledgeGraph(store=store, embedding_provider=MagicMock())
+        kg.process_codebase(str(codebase))
+        kg.close()
+        return store, str(codebase)
+    
+    def _names(self, store, label):
+        return {record["properties"]["name"] for record in store.find_nodes(label=label)}
+    
+    def test_declarations(self, store):
+        store, _ = store
+        
+        assert {"Named", "Shape"} <= self._names(store, "Interface")
+        assert "Color" in self._names(store, "Enum")
+        assert "ShapeKind" in self._names(store, "TypeAlias")
+    
+    def test_alias_import(self, store):
+        store, codebase = store
+        (sample,) = [record for record in store.find_nodes(label="File")
+                     if record["properties"]["file_path"] == os.path.join(codebase, "sample.ts")]
+        
+        imported_files = {row["node"]["properties"]["file_path"]
+                          for row in store.neighbors([sample["properties"]["id"]], ["IMPORTS_FROM"])}
+        # `@lib/logger` resolves through the paths of tsconfig.json
+        assert os.path.join(codebase, "ts_lib", "logger.ts") in imported_files
+    
+    def test_components(self, store):
+        store, _ = store
+        (card,) = store.find_nodes(name="ShapeCard", label="Function")
+        
+        assert card["properties"]["component"] is True
+
+
+class TestTypeScriptSignatures:
+    """signature_json, arity and signature of functions and methods."""
+    
+    @pytest.fixture
+    def ts_nodes(self, tmp_path):
+        pytest.importorskip("ast_grep_py")
+        from src.ast_parser.multi_parser import MultiLanguageParser
+        
+        _write(tmp_path, "api.ts",
+               "export async function fetchAll<T extends Entity>(url: string, page?: number, size = 20, ...ids: T[]): Promise<T[]> {\n"
+               "    return [];\n"
+               "}\n"
+               "\n"
+               "export class Client {\n"
+               "    get(key: string): string {\n"
+               "        return key;\n"
+               "    }\n"
+               "}\n")
+        coordinator = MultiLanguageParser(
+            use_ast_grep=True,
+            ast_grep_languages=['typescript'],
+            ast_grep_fallback=False
+        )
+        nodes, _ = coordinator.parse_directory(str(tmp_path), build_index=True)
+        return {n.name: n for n in nodes.values() if n.node_type in ("Function", "Method")}
+    
+    def test_function_parameters_and_generics(self, ts_nodes):
+        fetch_all = ts_nodes["fetchAll"]
+        signature = json.loads(fetch_all.properties["signature_json"])
+        
+        assert fetch_all.properties["arity"] == 4
+        assert [(p["name"], p["type"], p["default"], p["variadic"]) for p in signature["parameters"]] == [
+            ("url", "string", None, False), ("page", "number", None, False),
+            ("size", None, "20", False), ("ids", "T[]", None, True),
+        ]
+        assert signature["parameters"][1]["optional"] is True
+        assert signature["returns"] == [{"name": None, "type": "Promise<T[]>"}]
+        assert signature["type_parameters"] == [{"name": "T", "constraint": "Entity"}]
+        assert fetch_all.properties["signature"] == (
+            "fetchAll<T extends Entity>(url: string, page?: number, size = 20, ...ids: T[]): Promise<T[]>"
+        )
+    
+    def test_method(self, ts_nodes):
+        assert ts_nodes["get"].properties["arity"] == 1
+        assert ts_nodes["get"].properties["signature"] == "get(key: string): string"
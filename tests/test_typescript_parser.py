@@ -173,9 +173,10 @@ const config = {
         file_path = self._create_test_file("test.js", content)
         nodes, relations = self.parser.parse_file(file_path)
 
-        # Check variable nodes
-        var_nodes = [n for n in nodes.values() if n.node_type == "Variable"]
+        # Check variable nodes; `const` bindings are Constant nodes
+        var_nodes = [n for n in nodes.values() if n.node_type in ("Constant", "Variable")]
         self.assertEqual(len(var_nodes), 4)
+        self.assertEqual({n.name for n in var_nodes if n.node_type == "Constant"}, {"API_KEY", "config"})
         
         # Check variable names and types
         var_info = {n.name: n.properties["declaration_type"] for n in var_nodes}
@@ -0,0 +1,334 @@
+"""
+Package-level constant and variable tests.
+
+The Python tests parse a small codebase with ASTParser and check the
+Constant and Variable nodes of module-level assignments and the USES
+edges, by kind of use, from the functions reading them; find_references
+is queried on the same codebase indexed into an InMemoryGraphStore. The
+Go and TypeScript tests parse through MultiLanguageParser and need
+ast-grep.
+"""
+
+import asyncio
+import json
+import os
+import sys
+
+import pytest
+
+# Add project root to Python path
+sys.path.insert(0, os.path.dirname(os.path.dirname(os.path.abspath(__file__))))
+
+from src.ast_parser.parser import ASTParser
+from src.ast_parser.variables import is_constant_name, is_variable_id, literal_value
+from conftest import index_tree, write_tree
+
+CONFIG = '''\
+from typing import Final
+
+MAX_RETRIES = 3
+TIMEOUT: float = 2.5
+LIMIT: Final = -5
+BANNER = "x" * 3
+WIDTH, HEIGHT = 80, 24
+counter = 0
+'''
+
+SERVICE = '''\
+import config
+from config import MAX_RETRIES, counter
+
+DEFAULT_NAME = "svc"
+
+
+def retry(attempt):
+    if attempt >= MAX_RETRIES:
+        return config.TIMEOUT
+    return DEFAULT_NAME
+
+
+def bump():
+    global DEFAULT_NAME
+    DEFAULT_NAME = "other"
+    return len(DEFAULT_NAME)
+
+
+def shadowed(MAX_RETRIES):
+    DEFAULT_NAME = "local"
+    return MAX_RETRIES, DEFAULT_NAME
+
+
+class Client:
+    def size(self):
+        return config.WIDTH * config.HEIGHT + counter
+'''
+
+GO_CONSTANTS = '''\
+package status
+
+import "errors"
+
+type Kind int
+
+// Kinds of records.
+const (
+	KindUnknown Kind = iota
+	KindFile
+	KindDir
+	_
+	KindLink
+)
+
+const (
+	KB = 1 << (10 * (iota + 1))
+	MB
+)
+
+const Name = "status"
+
+var ErrMissing = errors.New("missing")
+
+var retries = 3
+
+func Check(err error, kind Kind) (bool, int) {
+	if errors.Is(err, ErrMissing) {
+		return false, KB
+	}
+	switch kind {
+	case KindFile, KindDir:
+		retries++
+	}
+	return kind == KindLink, retries
+}
+
+func Local() int {
+	retries := 1
+	return retries
+}
+'''
+
+GO_CALLER = '''\
+package app
+
+import "example.com/demo/status"
+
+func Size() int {
+	return status.MB
+}
+'''
+
+TS_CONFIG = '''\
+export const API_URL = "https://example.com";
+export let retries = 2;
+const local = 5;
+'''
+
+TS_CLIENT = '''\
+import { API_URL, retries } from './config';
+
+export function fetchAll(limit: number) {
+    if (retries > limit) {
+        retries = limit;
+    }
+    return [1, 2].map(n => API_URL + n);
+}
+'''
+
+
+def _uses(relations, nodes):
+    """{(source name, target name, usage): use_lines} of the USES edges."""
+    return {
+        (nodes[r.source_id].name, nodes[r.target_id].name, r.properties["usage"]): r.properties["use_lines"]
+        for r in relations if r.relation_type == "USES"
+    }
+
+
+class TestHelpers:
+    
+    def test_constant_names(self):
+        assert is_constant_name("MAX_RETRIES") and is_constant_name("_PRIVATE") and is_constant_name("V2")
+        assert not is_constant_name("counter") and not is_constant_name("Config") and not is_constant_name("_")
+    
+    def test_variable_ids(self):
+        assert is_variable_id("Constant:a.py:X:1") and is_variable_id("Variable:a.go:x:3")
+        assert not is_variable_id("Function:a.py:X:1") and not is_variable_id(None)
+    
+    def test_literal_values(self):
+        assert literal_value(True) is True and literal_value(2.5) == 2.5
+        assert literal_value(2 ** 63) is None and literal_value(-2 ** 63) == -2 ** 63
+        assert literal_value("x" * 201) is None and literal_value(["x"]) is None
+
+
+@pytest.fixture
+def python_graph(tmp_path):
+    write_tree(tmp_path, {"config.py": CONFIG, "service.py": SERVICE})
+    parser = ASTParser()
+    nodes, relations = parser.parse_directory(str(tmp_path))
+    return nodes, relations
+
+
+class TestPython:
+    
+    def test_module_level_nodes(self, python_graph):
+        nodes, relations = python_graph
+        
+        declared = {(n.name, n.node_type): n.properties.get("value")
+                    for n in nodes.values() if n.node_type in ("Constant", "Variable")}
+        assert declared == {
+            ("MAX_RETRIES", "Constant"): 3,
+            ("TIMEOUT", "Constant"): 2.5,
+            ("LIMIT", "Constant"): -5,
+            # Only simple literals are stored
+            ("BANNER", "Constant"): None,
+            ("WIDTH", "Constant"): 80,
+            ("HEIGHT", "Constant"): 24,
+            ("counter", "Variable"): 0,
+            ("DEFAULT_NAME", "Constant"): "svc",
+        }
+        limit = next(n for n in nodes.values() if n.name == "LIMIT")
+        assert limit.properties["annotation"] == "Final"
+        # Locals of function bodies are not indexed
+        assert not [n for n in nodes.values() if n.node_type in ("Variable", "LocalVariable") and n.name == "attempt"]
+        defines = {(nodes[r.source_id].node_type, nodes[r.target_id].name)
+                   for r in relations if r.relation_type == "DEFINES" and r.target_id in nodes}
+        assert ("File", "MAX_RETRIES") in defines
+    
+    def test_uses_by_kind(self, python_graph):
+        nodes, relations = python_graph
+        
+        uses = _uses(relations, nodes)
+        
+        assert uses[("retry", "MAX_RETRIES", "compare")] == [8]
+        assert uses[("retry", "TIMEOUT", "return")] == [9]
+        assert uses[("retry", "DEFAULT_NAME", "return")] == [10]
+        assert uses[("bump", "DEFAULT_NAME", "write")] == [15]
+        # len() is a builtin call, and its argument a read
+        assert uses[("bump", "DEFAULT_NAME", "read")] == [16]
+        assert uses[("size", "WIDTH", "read")] == [26]
+        assert uses[("size", "counter", "read")] == [26]
+        # Parameters and assigned names shadow the module-level ones
+        assert not [key for key in uses if key[0] == "shadowed"]
+    
+    def test_incremental_targets_by_id(self, tmp_path):
+        write_tree(tmp_path, {"config.py": CONFIG, "service.py": SERVICE})
+        parser = ASTParser()
+        parser.parse_directory(str(tmp_path))
+        # Only service.py is parsed again; config.py is known from the index alone
+        parser.nodes = {node_id: node for node_id, node in parser.nodes.items()
+                        if not node.file_path.endswith("config.py")}
+        parser.relations, parser.established_relations, parser.pending_imports = [], set(), []
+        parser.parse_file(str(tmp_path / "service.py"), build_index=True)
+        parser._process_pending_imports()
+        
+        targets = {r.target_id.split(":")[0] + ":" + r.target_id.split(":")[-2]
+                   for r in parser.relations if r.relation_type == "USES"}
+        assert "Constant:MAX_RETRIES" in targets and "Variable:counter" in targets
+
+
+@pytest.fixture
+def find_references(legacy_env, tmp_path, make_server):
+    write_tree(tmp_path, {"config.py": CONFIG, "service.py": SERVICE})
+    server = make_server(store=index_tree(tmp_path).db)
+    tool = server.mcp.tools["find_references"]
+    return lambda *args, **kwargs: json.loads(asyncio.run(tool(*args, **kwargs)))
+
+
+class TestFindReferences:
+    
+    def test_uses_kind_and_usage_filter(self, find_references):
+        result = find_references("DEFAULT_NAME", kind="uses")
+        
+        assert result["status"] == "ok"
+        assert result["target"]["node_type"] == "Constant"
+        assert [(r["name"], r["usage"], r["use_lines"]) for r in result["references"]] == [
+            ("retry", "return", [10]), ("bump", "write", [15]), ("bump", "read", [16]),
+        ]
+        
+        written = find_references("DEFAULT_NAME", usage="write")
+        assert [(r["name"], r["relation_type"]) for r in written["references"]] == [("bump", "USES")]
+        assert written["usage"] == "write"
+    
+    def test_unknown_usage(self, find_references):
+        assert "error" in find_references("DEFAULT_NAME", usage="assign")
+
+
+@pytest.fixture
+def go_graph(tmp_path):
+    pytest.importorskip("ast_grep_py")
+    from src.ast_parser.multi_parser import MultiLanguageParser
+    
+    write_tree(tmp_path, {
+        "go.mod": "module example.com/demo\n\ngo 1.21\n",
+        "status/status.go": GO_CONSTANTS,
+        "app/app.go": GO_CALLER,
+    })
+    coordinator = MultiLanguageParser(use_ast_grep=True, ast_grep_languages=["go"], ast_grep_fallback=False)
+    return coordinator.parse_directory(str(tmp_path), build_index=True)
+
+
+class TestGo:
+    
+    def test_grouped_constants_resolve_iota(self, go_graph):
+        nodes, _ = go_graph
+        
+        constants = {n.name: n.properties.get("value") for n in nodes.values() if n.node_type == "Constant"}
+        assert constants == {
+            "KindUnknown": 0, "KindFile": 1, "KindDir": 2, "KindLink": 4,
+            "KB": 1024, "MB": 1024 ** 2, "Name": "status",
+        }
+        kind_dir = next(n for n in nodes.values() if n.name == "KindDir")
+        # The implicit spec repeats the type of the one above it
+        assert kind_dir.properties["type"] == "Kind"
+        assert next(n for n in nodes.values() if n.name == "KindUnknown").properties["doc"] == "Kinds of records."
+        
+        variables = {n.name: n.properties.get("value") for n in nodes.values() if n.node_type == "Variable"}
+        assert variables == {"ErrMissing": None, "retries": 3}
+    
+    def test_uses_by_kind(self, go_graph):
+        nodes, relations = go_graph
+        
+        uses = _uses(relations, nodes)
+        
+        assert uses[("Check", "ErrMissing", "compare")] == [30]
+        assert uses[("Check", "KB", "return")] == [31]
+        assert uses[("Check", "KindFile", "compare")] == [34]
+        assert uses[("Check", "retries", "write")] == [35]
+        assert uses[("Check", "KindLink", "compare")] == [37]
+        assert uses[("Check", "retries", "return")] == [37]
+        assert uses[("Size", "MB", "return")] == [6]
+        # A local declaration shadows the package variable
+        assert not [key for key in uses if key[0] == "Local"]
+
+
+@pytest.fixture
+def ts_graph(tmp_path):
+    pytest.importorskip("ast_grep_py")
+    from src.ast_parser.multi_parser import MultiLanguageParser
+    
+    write_tree(tmp_path, {"src/config.ts": TS_CONFIG, "src/client.ts": TS_CLIENT})
+    coordinator = MultiLanguageParser(use_ast_grep=True, ast_grep_languages=["typescript"], ast_grep_fallback=False)
+    return coordinator.parse_directory(str(tmp_path), build_index=True)
+
+
+class TestTypeScript:
+    
+    def test_bindings(self, ts_graph):
+        nodes, _ = ts_graph
+        
+        bindings = {n.name: (n.node_type, n.properties.get("value"), n.properties.get("exported"))
+                    for n in nodes.values() if n.node_type in ("Constant", "Variable")}
+        assert bindings == {
+            "API_URL": ("Constant", "https://example.com", True),
+            "retries": ("Variable", 2, True),
+            "local": ("Constant", 5, None),
+        }
+    
+    def test_uses_through_imports(self, ts_graph):
+        nodes, relations = ts_graph
+        
+        uses = _uses(relations, nodes)
+        
+        assert uses[("fetchAll", "retries", "compare")] == [4]
+        assert uses[("fetchAll", "retries", "write")] == [5]
+        # Inside the callback passed to map
+        assert uses[("fetchAll", "API_URL", "read")] == [7]